@@ -11,7 +11,10 @@ import (
 	"time"
 
 	"github.com/whitewalker-sa/ehass/internal/config"
+	"github.com/whitewalker-sa/ehass/internal/migrations"
 	"github.com/whitewalker-sa/ehass/internal/router"
+	"github.com/whitewalker-sa/ehass/internal/sandbox"
+	"github.com/whitewalker-sa/ehass/pkg/backup"
 	"github.com/whitewalker-sa/ehass/pkg/database"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -29,14 +32,37 @@ func main() {
 		logger.Fatal("Failed to load configuration", zap.Error(err))
 	}
 
+	// Override credentials from the secrets store, if configured
+	secretsManager, err := config.NewSecretsManager(cfg, logger)
+	if err != nil {
+		logger.Fatal("Failed to set up secrets manager", zap.Error(err))
+	}
+	if secretsManager != nil {
+		if err := config.ApplySecrets(context.Background(), cfg, secretsManager); err != nil {
+			logger.Fatal("Failed to load secrets", zap.Error(err))
+		}
+	}
+
 	// Check if running migration commands
 	if len(os.Args) > 1 && os.Args[1] == "migrate" {
 		handleMigrations(cfg, logger, os.Args)
 		return
 	}
 
+	// Check if provisioning a sandbox
+	if len(os.Args) > 1 && os.Args[1] == "sandbox" {
+		handleSandbox(cfg, logger, os.Args)
+		return
+	}
+
+	// Check if taking or restoring a backup
+	if len(os.Args) > 1 && (os.Args[1] == "backup" || os.Args[1] == "restore") {
+		handleBackup(cfg, logger, os.Args)
+		return
+	}
+
 	// Setup router with all dependencies
-	r, cleanup, err := router.Setup(cfg, logger)
+	r, cleanup, err := router.Setup(cfg, secretsManager, logger)
 	if err != nil {
 		logger.Fatal("Failed to setup router", zap.Error(err))
 	}
@@ -183,14 +209,28 @@ func handleMigrations(cfg *config.Config, logger *zap.Logger, args []string) {
 	defer sqlDB.Close()
 
 	// Determine migration action
-	isRollback := len(args) > 2 && args[2] == "rollback"
+	action := ""
+	if len(args) > 2 {
+		action = args[2]
+	}
 
-	if isRollback {
+	switch action {
+	case "rollback":
 		logger.Info("Rolling back the last migration")
-		// For simplicity, we don't implement actual rollback logic here
-		// In a real app, you would track migrations in a migrations table
-		logger.Info("Migration rollback is not implemented")
-	} else {
+		if err := migrations.Rollback(db, logger); err != nil {
+			logger.Fatal("Migration rollback failed", zap.Error(err))
+			return
+		}
+		logger.Info("Migration rolled back successfully")
+	case "rotate-keys":
+		logger.Info("Re-encrypting data with the active encryption key",
+			zap.String("key_id", cfg.Encryption.ActiveKeyID))
+		if err := migrations.RotateEncryptionKeys(db, logger); err != nil {
+			logger.Fatal("Key rotation failed", zap.Error(err))
+			return
+		}
+		logger.Info("Key rotation completed successfully")
+	default:
 		logger.Info("Running migrations")
 		if err := runMigrations(db, logger); err != nil {
 			logger.Fatal("Migration failed", zap.Error(err))
@@ -200,9 +240,100 @@ func handleMigrations(cfg *config.Config, logger *zap.Logger, args []string) {
 	}
 }
 
+// handleSandbox provisions a sandbox database with synthetic data
+func handleSandbox(cfg *config.Config, logger *zap.Logger, args []string) {
+	if len(args) < 3 || args[2] != "provision" {
+		logger.Fatal("Usage: sandbox provision")
+		return
+	}
+	if !cfg.Sandbox.Enabled {
+		logger.Fatal("Sandbox mode is not enabled; set sandbox.enabled to provision synthetic data")
+		return
+	}
+
+	db, err := database.NewDatabase(cfg, logger)
+	if err != nil {
+		logger.Fatal("Failed to connect to database", zap.Error(err))
+		return
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		logger.Fatal("Failed to get database connection", zap.Error(err))
+		return
+	}
+	defer sqlDB.Close()
+
+	if err := runMigrations(db, logger); err != nil {
+		logger.Fatal("Migration failed", zap.Error(err))
+		return
+	}
+
+	result, err := sandbox.Provision(db, sandbox.Options{
+		Doctors:  cfg.Sandbox.Doctors,
+		Patients: cfg.Sandbox.Patients,
+		Password: cfg.Sandbox.Password,
+		Seed:     cfg.Sandbox.Seed,
+	}, logger)
+	if err != nil {
+		logger.Fatal("Sandbox provisioning failed", zap.Error(err))
+		return
+	}
+	logger.Info("Sign in to the sandbox with the provisioned accounts",
+		zap.String("admin", result.AdminEmail),
+		zap.String("doctors", "doctor01@"+sandbox.EmailDomain),
+		zap.String("patients", "patient01@"+sandbox.EmailDomain))
+}
+
+// handleBackup takes an encrypted backup of the database, or restores one over it
+func handleBackup(cfg *config.Config, logger *zap.Logger, args []string) {
+	if args[1] == "restore" && len(args) < 3 {
+		logger.Fatal("Usage: restore <backup name|latest>")
+		return
+	}
+
+	opts, err := config.BackupOptions(cfg)
+	if err != nil {
+		logger.Fatal("Invalid backup configuration", zap.Error(err))
+		return
+	}
+	store, err := config.NewBackupStore(cfg)
+	if err != nil {
+		logger.Fatal("Invalid backup configuration", zap.Error(err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Backup.Timeout)
+	defer cancel()
+
+	if args[1] == "backup" {
+		logger.Info("Backing up the database", zap.String("database", cfg.Database.Name))
+		name, err := backup.Backup(ctx, store, opts)
+		if err != nil {
+			logger.Fatal("Backup failed", zap.Error(err))
+			return
+		}
+		logger.Info("Backup uploaded", zap.String("backup", name))
+		return
+	}
+
+	logger.Warn("Restoring a backup over the database", zap.String("database", cfg.Database.Name), zap.String("backup", args[2]))
+	name, err := backup.Restore(ctx, store, opts, args[2])
+	if err != nil {
+		logger.Fatal("Restore failed", zap.Error(err))
+		return
+	}
+	logger.Info("Backup restored", zap.String("backup", name))
+}
+
 // runMigrations performs the actual database migrations
 func runMigrations(db *gorm.DB, logger *zap.Logger) error {
 	// Auto-migrate all models
 	logger.Info("Running auto-migrations for all models")
-	return database.AutoMigrate(db, logger)
+	if err := database.AutoMigrate(db, logger); err != nil {
+		return err
+	}
+
+	// Apply data migrations registered in internal/migrations
+	return migrations.Run(db, logger)
 }
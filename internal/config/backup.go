@@ -0,0 +1,47 @@
+package config
+
+import (
+	"errors"
+
+	"github.com/whitewalker-sa/ehass/pkg/awssig"
+	"github.com/whitewalker-sa/ehass/pkg/backup"
+)
+
+// NewBackupStore creates the object store backups are kept in
+func NewBackupStore(cfg *Config) (*backup.Store, error) {
+	return backup.NewStore(
+		cfg.Backup.S3.Bucket,
+		cfg.Backup.S3.Prefix,
+		cfg.Backup.S3.Region,
+		cfg.Backup.S3.Endpoint,
+		awssig.Credentials{
+			AccessKeyID:     cfg.Backup.S3.AccessKeyID,
+			SecretAccessKey: cfg.Backup.S3.SecretAccessKey,
+			SessionToken:    cfg.Backup.S3.SessionToken,
+		},
+	)
+}
+
+// BackupOptions returns the database connection, key and tools used to back up and restore
+func BackupOptions(cfg *Config) (backup.Options, error) {
+	if cfg.Backup.Key == "" {
+		return backup.Options{}, errors.New("backup.key is required")
+	}
+	key, err := backup.ParseKey(cfg.Backup.Key)
+	if err != nil {
+		return backup.Options{}, err
+	}
+	return backup.Options{
+		Database: backup.Database{
+			Host:     cfg.Database.Host,
+			Port:     cfg.Database.Port,
+			User:     cfg.Database.User,
+			Password: cfg.Database.Password,
+			Name:     cfg.Database.Name,
+			SSLMode:  cfg.Database.SSLMode,
+		},
+		Key:       key,
+		PgDump:    cfg.Backup.PgDump,
+		PgRestore: cfg.Backup.PgRestore,
+	}, nil
+}
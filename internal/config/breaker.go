@@ -0,0 +1,17 @@
+package config
+
+import (
+	"github.com/whitewalker-sa/ehass/pkg/breaker"
+)
+
+// Settings converts the configuration to breaker settings
+func (c BreakerConfig) Settings() breaker.Settings {
+	return breaker.Settings{
+		Timeout:          c.Timeout,
+		Retries:          c.Retries,
+		RetryBackoff:     c.RetryBackoff,
+		FailureThreshold: c.FailureThreshold,
+		OpenTimeout:      c.OpenTimeout,
+		MaxConcurrent:    c.MaxConcurrent,
+	}
+}
@@ -1,6 +1,9 @@
 package config
 
 import (
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/spf13/viper"
@@ -8,12 +11,45 @@ import (
 
 // Config holds all configuration for the application
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	Auth     AuthConfig
-	Redis    RedisConfig
-	OAuth    OAuthConfig
-	Email    EmailConfig
+	Environment  string // GO_ENV: development, staging or production; defaults to production
+	Server       ServerConfig
+	Database     DatabaseConfig
+	Auth         AuthConfig
+	Redis        RedisConfig
+	OAuth        OAuthConfig
+	Email        EmailConfig
+	Consent      ConsentConfig
+	BreakGlass   BreakGlassConfig
+	Security     SecurityConfig
+	Encryption   EncryptionConfig
+	Secrets      SecretsConfig
+	SIEM         SIEMConfig
+	SMS          SMSConfig
+	NoShow       NoShowConfig
+	Analytics    AnalyticsConfig
+	Reminders    RemindersConfig
+	Care         CareRemindersConfig
+	SlotHold     SlotHoldConfig
+	Booking      BookingConfig
+	Calendar     CalendarSyncConfig
+	Reviews      ReviewsConfig
+	Sandbox      SandboxConfig
+	Cleanup      CleanupConfig
+	Outbox       OutboxConfig
+	Operations   OperationsConfig
+	Breakers     BreakersConfig
+	Search       SearchConfig
+	Suggest      SuggestConfig
+	Slots        SlotsConfig
+	Content      ContentConfig
+	Metrics      MetricsConfig
+	Timeouts     TimeoutsConfig
+	Backup       BackupConfig
+	Runtime      RuntimeConfig
+	Attachments  AttachmentsConfig
+	Telehealth   TelehealthConfig
+	Labs         LabsConfig
+	Integrations IntegrationsConfig
 }
 
 // ServerConfig holds server-specific configuration
@@ -41,10 +77,20 @@ type DatabaseConfig struct {
 
 // AuthConfig holds authentication related configuration
 type AuthConfig struct {
-	AccessTokenSecret  string
-	RefreshTokenSecret string
-	AccessTokenExpiry  time.Duration
-	RefreshTokenExpiry time.Duration
+	AccessTokenSecret      string
+	LinkSigningSecret      string // Signs portal invitation and attachment download links; kept apart from the token secret
+	AccessTokenExpiry      time.Duration
+	RefreshTokenExpiry     time.Duration
+	Issuer                 string            // Value of the "iss" claim on issued tokens
+	Audience               string            // Value of the "aud" claim on issued tokens
+	StepUpMaxAge           time.Duration     // Maximum age of the last authentication for sensitive operations
+	IntrospectionClients   map[string]string // Client ID to secret for services calling /auth/introspect
+	SessionIdleTimeout     time.Duration     // Session ends after this long without requests; 0 disables
+	SessionAbsoluteTimeout time.Duration     // Session ends this long after login regardless of activity; 0 disables
+	InviteExpiry           time.Duration     // How long portal invitation links and codes for clinic-created patients work
+	InviteResendAfter      time.Duration     // How soon the same patient can be invited again
+	BlockDisposableEmails  bool              // Refuse signups with addresses at DisposableEmailDomains
+	DisposableEmailDomains []string          // Throwaway email domains; subdomains are refused too
 }
 
 // RedisConfig holds Redis connection details
@@ -77,11 +123,387 @@ type GoogleConfig struct {
 
 // EmailConfig holds email service configuration
 type EmailConfig struct {
-	SMTPHost     string
-	SMTPPort     int
-	SMTPUsername string
-	SMTPPassword string
-	FromEmail    string
+	SMTPHost      string
+	SMTPPort      int
+	SMTPUsername  string
+	SMTPPassword  string
+	FromEmail     string
+	WebhookSecret string // Shared secret for delivery event webhooks; webhooks are disabled when empty
+}
+
+// ConsentConfig holds the currently published policy versions users must accept
+type ConsentConfig struct {
+	Policies []PolicyConfig
+}
+
+// PolicyConfig describes a single published policy version
+type PolicyConfig struct {
+	Type     string
+	Version  string
+	Title    string
+	URL      string
+	Required bool
+}
+
+// BreakGlassConfig holds emergency access configuration
+type BreakGlassConfig struct {
+	AccessDuration time.Duration // How long an emergency access grant stays valid
+}
+
+// SecurityConfig holds anomaly detection on authentication events. Each threshold is a count
+// within Window that raises an alert; 0 turns that rule off.
+type SecurityConfig struct {
+	Enabled                  bool
+	Interval                 time.Duration // How often recent authentication events are checked
+	Window                   time.Duration // Period events are counted over
+	FailedLoginsPerIP        int           // Failed logins from one IP address
+	AccountsPerIP            int           // Distinct accounts failing to log in from one IP address, as in credential stuffing
+	FailedLoginsPerUser      int           // Failed logins to one account from any address
+	PasswordResetsPerIP      int           // Password reset requests from one IP address
+	TwoFactorFailuresPerUser int           // Wrong 2FA codes for one account
+}
+
+// Development keys shipped in configs/config.yaml. They are public, so startup refuses them
+// outside development.
+const (
+	DevelopmentEncryptionKey = "ZWhhc3MtZGV2ZWxvcG1lbnQtZW5jcnlwdGlvbi1rZXk="
+	DevelopmentIndexKey      = "ZWhhc3MtZGV2ZWxvcG1lbnQtYmxpbmRpbmRleC1rZXk="
+)
+
+// PlaceholderIntrospectionSecret is the example client secret in configs/config.yaml. Startup
+// refuses it in every environment, as anyone holding it could introspect tokens.
+const PlaceholderIntrospectionSecret = "your-introspection-secret-here"
+
+// EncryptionConfig holds the keys used to encrypt PHI columns at the application layer
+type EncryptionConfig struct {
+	ActiveKeyID string            // Key used to encrypt new values
+	Keys        map[string]string // Key ID to base64-encoded 256-bit key; keep retired keys until rotated
+	IndexKey    string            // Base64-encoded 256-bit key of the blind index encrypted columns are searched by; changing it requires rebuilding the index
+}
+
+// SecretsConfig selects an external secrets store that overrides credentials from config and env.
+// Each path names a secret holding the fields below; fields missing from the secret keep their configured value.
+//
+//	auth:     accessTokenSecret, linkSigningSecret
+//	database: user, password
+//	email:    smtpUsername, smtpPassword, webhookSecret
+//	oauth:    githubClientId, githubClientSecret, googleClientId, googleClientSecret
+type SecretsConfig struct {
+	Provider        string        // "vault", "aws" or empty to use config/env values only
+	CacheTTL        time.Duration // How long fetched secrets are served from cache
+	RefreshInterval time.Duration // How often secrets are re-fetched to detect rotation; 0 disables
+	Timeout         time.Duration // Timeout for requests to the secrets store
+	Vault           VaultConfig
+	AWS             AWSSecretsConfig
+	Paths           SecretPathsConfig
+}
+
+// VaultConfig holds HashiCorp Vault connection details
+type VaultConfig struct {
+	Address   string // Falls back to VAULT_ADDR
+	Token     string // Falls back to VAULT_TOKEN
+	MountPath string // KV v2 mount path
+	Namespace string
+}
+
+// AWSSecretsConfig holds AWS Secrets Manager connection details
+type AWSSecretsConfig struct {
+	Region          string // Falls back to AWS_REGION
+	AccessKeyID     string // Falls back to AWS_ACCESS_KEY_ID
+	SecretAccessKey string // Falls back to AWS_SECRET_ACCESS_KEY
+	SessionToken    string // Falls back to AWS_SESSION_TOKEN
+}
+
+// SecretPathsConfig holds the secret path or name for each group of credentials
+type SecretPathsConfig struct {
+	Auth     string
+	Database string
+	Email    string
+	OAuth    string
+}
+
+// SIEMConfig holds audit log export configuration
+type SIEMConfig struct {
+	Enabled    bool
+	Sink       string        // "syslog", "splunk" or "s3"
+	BatchSize  int           // Maximum number of events sent per request
+	Interval   time.Duration // How often to poll for new events once caught up
+	MaxBackoff time.Duration // Upper bound for the retry delay while the sink is failing
+	Timeout    time.Duration // Timeout for each delivery
+	Syslog     SyslogConfig
+	Splunk     SplunkConfig
+	S3         S3ExportConfig
+}
+
+// SyslogConfig holds syslog (RFC 5424) delivery details
+type SyslogConfig struct {
+	Network string // "tcp" or "udp"
+	Address string
+	AppName string
+}
+
+// SplunkConfig holds Splunk HTTP Event Collector delivery details
+type SplunkConfig struct {
+	URL        string
+	Token      string
+	Index      string
+	SourceType string
+}
+
+// S3ExportConfig holds S3 delivery details
+type S3ExportConfig struct {
+	Bucket          string
+	Prefix          string
+	Region          string // Falls back to AWS_REGION
+	Endpoint        string // Optional, for S3-compatible stores
+	AccessKeyID     string // Falls back to AWS_ACCESS_KEY_ID
+	SecretAccessKey string // Falls back to AWS_SECRET_ACCESS_KEY
+	SessionToken    string // Falls back to AWS_SESSION_TOKEN
+}
+
+// SMSConfig holds text message delivery configuration
+type SMSConfig struct {
+	Provider      string        // "twilio", or empty to log messages instead of sending them
+	Timeout       time.Duration // Timeout for requests to the provider
+	DefaultRegion string        // ISO 3166-1 alpha-2 region of phone numbers entered without a country calling code
+	Twilio        TwilioConfig
+}
+
+// TwilioConfig holds Twilio credentials
+type TwilioConfig struct {
+	AccountSID string
+	AuthToken  string
+	From       string // Sender number in E.164 format
+}
+
+// NoShowConfig holds no-show risk scoring and detection configuration
+type NoShowConfig struct {
+	HighRiskThreshold float64       // Score from 0 to 1 at which a patient is considered high risk
+	DetectAfter       time.Duration // How long after its end an appointment nobody completed or cancelled becomes a no-show; 0 disables detection
+	Interval          time.Duration // How often overdue appointments are checked
+	BatchSize         int           // Appointments marked per run
+}
+
+// AnalyticsConfig holds clinic analytics configuration
+type AnalyticsConfig struct {
+	SettlePeriod     time.Duration // How long after a bucket ends it is stored instead of recomputed
+	PunctualityGrace time.Duration // How late a doctor can start a visit and still be on time
+}
+
+// RemindersConfig holds appointment reminder configuration
+type RemindersConfig struct {
+	Enabled  bool
+	LeadTime time.Duration // How long before an appointment the reminder is sent
+	Interval time.Duration // How often due reminders and bounced reminder emails are checked
+	// Steps taken, in order, while the patient has not confirmed they will attend
+	Escalation []ReminderEscalationConfig
+}
+
+// ReminderEscalationConfig is one step of escalating an unconfirmed appointment reminder
+type ReminderEscalationConfig struct {
+	After  time.Duration // Time since the first reminder
+	Action string        // "sms", "email" or "other_channel" to remind again, or "follow_up" to flag the appointment for the front desk
+}
+
+// CareRemindersConfig holds preventive care reminder configuration
+type CareRemindersConfig struct {
+	Enabled   bool
+	LeadTime  time.Duration // How long before a care item is due the patient is reminded
+	Interval  time.Duration // How often care rules are evaluated
+	BatchSize int           // Patients reminded per rule and run
+}
+
+// SlotHoldConfig holds slot hold configuration
+type SlotHoldConfig struct {
+	Store string        // "redis" to share holds between instances, or "memory"
+	TTL   time.Duration // How long a slot stays reserved while a patient completes a booking
+}
+
+// BookingConfig holds how concurrent bookings of a doctor are serialized
+type BookingConfig struct {
+	Lock     string        // "row" to rely on the doctor row lock every booking takes, or "redis" to also queue bookings in Redis
+	LockWait time.Duration // How long a booking waits for the Redis lock before failing as a conflict
+	LockTTL  time.Duration // When a Redis lock whose holder never released it expires
+}
+
+// CalendarSyncConfig holds two-way Google Calendar sync configuration. Doctors connect their
+// calendars with the Google OAuth client.
+type CalendarSyncConfig struct {
+	Enabled     bool
+	RedirectURL string        // Page Google returns doctors to after granting access; it posts the code to the API
+	Interval    time.Duration // How often connected calendars are synced
+	Window      time.Duration // How far ahead appointments are pushed and busy times pulled
+}
+
+// ReviewsConfig holds doctor review configuration
+type ReviewsConfig struct {
+	Window     time.Duration // How long after a completed visit the patient can review it
+	DailyLimit int           // Reviews a patient can write in 24 hours
+}
+
+// CleanupConfig holds configuration of the job deleting expired tokens and sessions
+type CleanupConfig struct {
+	Interval time.Duration
+}
+
+// OperationsConfig holds configuration of long requests run in the background
+type OperationsConfig struct {
+	Interval  time.Duration // How often to poll for submitted operations
+	Lease     time.Duration // Longest an operation may run; one running longer is assumed lost and started again
+	Retention time.Duration // How long finished operations and their results are kept
+}
+
+// OutboxConfig holds configuration of the dispatcher delivering outbox events
+type OutboxConfig struct {
+	Publisher   string        // "webhook", or empty to log events instead of publishing them
+	BatchSize   int           // Maximum number of events claimed per run
+	Interval    time.Duration // How often to poll for due events once caught up
+	MaxAttempts int           // Deliveries tried before an event is marked failed
+	MaxBackoff  time.Duration // Upper bound for the delay between attempts
+	Retention   time.Duration // How long delivered events are kept
+	Timeout     time.Duration // Timeout for each delivery to the publisher
+	Webhook     EventWebhookConfig
+}
+
+// EventWebhookConfig holds the subscriber events are posted to
+type EventWebhookConfig struct {
+	URL    string
+	Secret string // Key for the HMAC-SHA256 signature of each request
+}
+
+// BreakersConfig holds the timeouts, retries and circuit breakers guarding external dependencies
+type BreakersConfig struct {
+	OAuth    BreakerConfig // Each OAuth provider gets its own breaker with these settings
+	SMTP     BreakerConfig
+	SMS      BreakerConfig
+	Search   BreakerConfig
+	Calendar BreakerConfig
+}
+
+// BreakerConfig holds the settings of one circuit breaker
+type BreakerConfig struct {
+	Timeout          time.Duration // Deadline for each attempt
+	Retries          int           // Attempts after the first; keep at 0 for calls that are not idempotent
+	RetryBackoff     time.Duration // Delay before the first retry, doubled for each further retry
+	FailureThreshold int           // Consecutive failures that open the breaker
+	OpenTimeout      time.Duration // How long calls fail fast before the dependency is tried again
+	MaxConcurrent    int           // Calls allowed in flight at once; 0 for no limit
+}
+
+// SearchConfig selects the search backend for doctor and patient search. Without one, searches
+// run against the database.
+type SearchConfig struct {
+	Backend     string // "opensearch" or "elasticsearch", or empty to search with SQL
+	URL         string
+	Username    string
+	Password    string
+	IndexPrefix string // Prepended to index names so deployments can share a cluster
+}
+
+// SuggestConfig holds configuration of the typeahead endpoints
+type SuggestConfig struct {
+	CacheTTL  time.Duration // How long suggestions are served from memory, and by clients from their cache; 0 disables caching
+	CacheSize int           // Maximum number of cached prefixes per instance
+}
+
+// SlotsConfig holds configuration of the free slot endpoints
+type SlotsConfig struct {
+	CacheTTL  time.Duration // How long a doctor's free slots for a day are served from memory; 0 disables caching
+	CacheSize int           // Maximum number of cached doctor days per instance
+}
+
+// ContentConfig holds configuration of doctor bios and specialty names
+type ContentConfig struct {
+	Locale string // Language the original content is written in; translations are only served to clients preferring another
+}
+
+// MetricsConfig holds configuration of the Prometheus metrics endpoint
+type MetricsConfig struct {
+	Token         string              // Bearer token scrapers must present; the endpoint is disabled when empty
+	Timezone      string              // Timezone whose midnight starts the day for "today" metrics
+	LatencyBudget time.Duration       // Latency budget of routes without their own; 0 tracks only the routes listed
+	RouteBudgets  []RouteBudgetConfig // Latency budgets of individual routes
+}
+
+// RouteBudgetConfig sets the latency budget of one route
+type RouteBudgetConfig struct {
+	Route  string        // Method and route pattern, e.g. "GET /api/v1/doctors/:id/slots"
+	Budget time.Duration // Requests taking longer count as latency SLO violations
+}
+
+// TimeoutsConfig holds the operation timeouts of API requests
+type TimeoutsConfig struct {
+	Request time.Duration        // Timeout of routes without their own; 0 leaves them unbounded
+	Routes  []RouteTimeoutConfig // Timeouts of individual routes
+}
+
+// RouteTimeoutConfig sets the operation timeout of one route
+type RouteTimeoutConfig struct {
+	Route   string        // Method and route pattern, e.g. "GET /api/v1/doctors/:id/slots"
+	Timeout time.Duration // Database queries and external calls still running are abandoned after this
+}
+
+// RuntimeConfig holds the defaults of operational settings admins can change at runtime. Values
+// set through the admin API are stored in the database, take precedence over these and reach
+// every instance within RefreshInterval.
+type RuntimeConfig struct {
+	RefreshInterval      time.Duration   // How often each instance reloads settings changed on another
+	MaintenanceMode      bool            // Reject requests outside authentication and admin routes with 503
+	MaintenanceMessage   string          // Shown to clients while in maintenance mode
+	RequestsPerMinute    int             // API requests per client IP and instance; 0 for no limit
+	LoginsPerMinute      int             // Login attempts per client IP and instance; 0 for no limit
+	MaxBookingWindowDays int             // How far ahead any clinic takes bookings, capping their own window; 0 for no cap
+	Features             map[string]bool // Feature flags by snake_case name
+}
+
+// AttachmentsConfig holds where medical record attachments are stored and what uploads are accepted
+type AttachmentsConfig struct {
+	Storage      string        // "disk" or "s3"
+	Dir          string        // Directory of the disk backend
+	MaxSize      int64         // Largest accepted upload in bytes
+	AllowedTypes []string      // MIME types accepted, detected from the file's contents
+	URLExpiry    time.Duration // How long signed download URLs work
+	Timeout      time.Duration // Upper bound for one transfer to or from S3
+	S3           S3ExportConfig
+}
+
+// TelehealthConfig holds video visit waiting room settings
+type TelehealthConfig struct {
+	JoinBefore     time.Duration // How long before the start patients can enter the waiting room
+	PollInterval   time.Duration // How often event streams check for changes made on other instances
+	StreamDuration time.Duration // How long one event stream stays open before the client reconnects
+	Heartbeat      time.Duration // Interval of keep-alive comments on idle event streams
+}
+
+// LabsConfig holds the settings of lab result ingestion
+type LabsConfig struct {
+	WebhookSecret string // Shared secret labs report results with; ingestion is disabled when empty
+}
+
+// IntegrationsConfig holds the default limits of partner API keys. Admins can set other limits
+// per key.
+type IntegrationsConfig struct {
+	RequestsPerMinute int // Requests one key can make per minute, counted per instance
+	DailyQuota        int // Requests one key can make per UTC day
+}
+
+// BackupConfig holds the settings of the backup and restore commands
+type BackupConfig struct {
+	Key       string        // Base64-encoded 256-bit key backups are encrypted with; store it apart from the backups
+	PgDump    string        // pg_dump binary, looked up in PATH unless a path is given
+	PgRestore string        // pg_restore binary, looked up in PATH unless a path is given
+	Timeout   time.Duration // Upper bound for one backup or restore, transfer included
+	S3        S3ExportConfig
+}
+
+// SandboxConfig holds sandbox mode configuration. A sandbox instance runs against its own
+// database filled with synthetic data and never sends real emails or text messages.
+type SandboxConfig struct {
+	Enabled  bool
+	Doctors  int    // Number of synthetic doctors to provision
+	Patients int    // Number of synthetic patients to provision
+	Password string // Password of every provisioned account
+	Seed     int64  // Seed for the generated data, so a sandbox can be rebuilt identically
 }
 
 // Load loads configuration from file and environment variables
@@ -90,6 +512,7 @@ func Load() (*Config, error) {
 	viper.SetConfigType("yaml")
 	viper.AddConfigPath("./configs")
 	viper.AddConfigPath(".")
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_")) // e.g. AUTH_SESSIONIDLETIMEOUT overrides auth.sessionIdleTimeout
 	viper.AutomaticEnv()
 
 	// Set default values
@@ -104,11 +527,53 @@ func Load() (*Config, error) {
 	if err := viper.Unmarshal(&cfg); err != nil {
 		return nil, err
 	}
+	if err := cfg.checkEncryptionKeys(); err != nil {
+		return nil, err
+	}
+	if err := cfg.checkIntrospectionClients(); err != nil {
+		return nil, err
+	}
 
 	return &cfg, nil
 }
 
+// IsDevelopment reports whether the application runs in development
+func (c *Config) IsDevelopment() bool {
+	return strings.EqualFold(c.Environment, "development")
+}
+
+// checkEncryptionKeys refuses the public development keys outside development, so a deployment
+// that kept the sample configuration does not encrypt patient data with a key anyone can read
+func (c *Config) checkEncryptionKeys() error {
+	if c.IsDevelopment() {
+		return nil
+	}
+	for id, key := range c.Encryption.Keys {
+		if key == DevelopmentEncryptionKey {
+			return fmt.Errorf("encryption key %q is the development key; configure a key of your own or set GO_ENV=development", id)
+		}
+	}
+	if c.Encryption.IndexKey == DevelopmentIndexKey {
+		return errors.New("encryption.indexKey is the development key; configure a key of your own or set GO_ENV=development")
+	}
+	return nil
+}
+
+// checkIntrospectionClients refuses introspection clients still using the example secret
+func (c *Config) checkIntrospectionClients() error {
+	for id, secret := range c.Auth.IntrospectionClients {
+		if secret == PlaceholderIntrospectionSecret {
+			return fmt.Errorf("introspection client %q uses the example secret; configure a secret of your own", id)
+		}
+	}
+	return nil
+}
+
 func setDefaults() {
+	// The environment is set by GO_ENV, as in the Docker setup
+	viper.SetDefault("environment", "production")
+	_ = viper.BindEnv("environment", "GO_ENV")
+
 	// Server defaults
 	viper.SetDefault("server.port", "8080")
 	viper.SetDefault("server.readTimeout", time.Second*10)
@@ -128,12 +593,174 @@ func setDefaults() {
 	// Auth defaults
 	viper.SetDefault("auth.accessTokenExpiry", time.Hour)
 	viper.SetDefault("auth.refreshTokenExpiry", time.Hour*24*7)
+	viper.SetDefault("auth.issuer", "ehass-api")
+	viper.SetDefault("auth.audience", "ehass-clients")
+	viper.SetDefault("auth.stepUpMaxAge", time.Minute*10)
+	viper.SetDefault("auth.sessionIdleTimeout", time.Minute*15)
+	viper.SetDefault("auth.sessionAbsoluteTimeout", time.Hour*12)
+	viper.SetDefault("auth.inviteExpiry", time.Hour*24*7)
+	viper.SetDefault("auth.inviteResendAfter", time.Minute)
+	viper.SetDefault("auth.blockDisposableEmails", false)
+	viper.SetDefault("auth.disposableEmailDomains", []string{
+		"10minutemail.com", "guerrillamail.com", "mailinator.com", "maildrop.cc", "sharklasers.com",
+		"temp-mail.org", "tempmail.com", "throwawaymail.com", "trashmail.com", "yopmail.com",
+	})
 
 	// Redis defaults
 	viper.SetDefault("redis.host", "localhost")
 	viper.SetDefault("redis.port", "6379")
 	viper.SetDefault("redis.db", 0)
 
+	// Break-glass defaults
+	viper.SetDefault("breakGlass.accessDuration", time.Hour)
+
+	// Security defaults
+	viper.SetDefault("security.enabled", true)
+	viper.SetDefault("security.interval", time.Minute)
+	viper.SetDefault("security.window", time.Minute*15)
+	viper.SetDefault("security.failedLoginsPerIP", 30)
+	viper.SetDefault("security.accountsPerIP", 10)
+	viper.SetDefault("security.failedLoginsPerUser", 10)
+	viper.SetDefault("security.passwordResetsPerIP", 10)
+	viper.SetDefault("security.twoFactorFailuresPerUser", 5)
+
+	// Secrets defaults
+	viper.SetDefault("secrets.cacheTTL", time.Minute*5)
+	viper.SetDefault("secrets.refreshInterval", time.Minute*5)
+	viper.SetDefault("secrets.timeout", time.Second*10)
+	viper.SetDefault("secrets.vault.mountPath", "secret")
+
+	// SIEM export defaults
+	viper.SetDefault("siem.batchSize", 500)
+	viper.SetDefault("siem.interval", time.Second*10)
+	viper.SetDefault("siem.maxBackoff", time.Minute*5)
+	viper.SetDefault("siem.timeout", time.Second*10)
+	viper.SetDefault("siem.syslog.network", "tcp")
+	viper.SetDefault("siem.syslog.appName", "ehass")
+	viper.SetDefault("siem.splunk.sourceType", "ehass:audit")
+	viper.SetDefault("siem.s3.prefix", "audit")
+
+	// SMS defaults
+	viper.SetDefault("sms.timeout", time.Second*10)
+	viper.SetDefault("sms.defaultRegion", "ZA")
+
+	// No-show risk defaults
+	viper.SetDefault("noShow.highRiskThreshold", 0.3)
+	viper.SetDefault("noShow.detectAfter", time.Minute*30)
+	viper.SetDefault("noShow.interval", time.Minute*5)
+	viper.SetDefault("noShow.batchSize", 200)
+
+	// Runtime setting defaults
+	viper.SetDefault("runtime.refreshInterval", time.Second*15)
+	viper.SetDefault("runtime.maintenanceMessage", "The service is down for maintenance. Please try again later.")
+	viper.SetDefault("runtime.features", map[string]bool{"no_show_confirmation": false})
+
+	// Attachment defaults
+	viper.SetDefault("attachments.storage", "disk")
+	viper.SetDefault("attachments.dir", "data/attachments")
+	viper.SetDefault("attachments.maxSize", 10<<20)
+	viper.SetDefault("attachments.allowedTypes", []string{"application/pdf", "image/png", "image/jpeg"})
+	viper.SetDefault("attachments.urlExpiry", time.Minute*5)
+	viper.SetDefault("attachments.timeout", time.Minute)
+	viper.SetDefault("attachments.s3.prefix", "attachments")
+
+	// Telehealth defaults
+	viper.SetDefault("telehealth.joinBefore", time.Minute*15)
+	viper.SetDefault("telehealth.pollInterval", time.Second*2)
+	viper.SetDefault("telehealth.streamDuration", time.Minute*5)
+	viper.SetDefault("telehealth.heartbeat", time.Second*15)
+
+	// Integration defaults
+	viper.SetDefault("integrations.requestsPerMinute", 60)
+	viper.SetDefault("integrations.dailyQuota", 10000)
+
+	// Analytics defaults
+	viper.SetDefault("analytics.settlePeriod", time.Hour*48)
+	viper.SetDefault("analytics.punctualityGrace", time.Minute*5)
+
+	// Reminder defaults
+	viper.SetDefault("reminders.leadTime", time.Hour*24)
+	viper.SetDefault("reminders.interval", time.Minute*5)
+
+	// Care reminder defaults
+	viper.SetDefault("care.leadTime", time.Hour*24*30)
+	viper.SetDefault("care.interval", time.Hour*24)
+	viper.SetDefault("care.batchSize", 500)
+
+	// Slot hold defaults
+	viper.SetDefault("slotHold.store", "memory")
+	viper.SetDefault("slotHold.ttl", time.Minute*5)
+
+	// Review defaults
+	viper.SetDefault("reviews.window", time.Hour*24*30)
+	viper.SetDefault("reviews.dailyLimit", 3)
+
+	// Cleanup defaults
+	viper.SetDefault("cleanup.interval", time.Hour)
+
+	// Outbox defaults
+	viper.SetDefault("outbox.batchSize", 100)
+	viper.SetDefault("outbox.interval", time.Second*5)
+	viper.SetDefault("outbox.maxAttempts", 10)
+	viper.SetDefault("outbox.maxBackoff", time.Minute*30)
+	viper.SetDefault("outbox.retention", time.Hour*24*7)
+	viper.SetDefault("outbox.timeout", time.Second*10)
+
+	// Operations defaults
+	viper.SetDefault("operations.interval", time.Second*2)
+	viper.SetDefault("operations.lease", time.Minute*30)
+	viper.SetDefault("operations.retention", time.Hour*24*7)
+
+	// Breaker defaults
+	for _, name := range []string{"oauth", "smtp", "sms", "search", "calendar"} {
+		viper.SetDefault("breakers."+name+".timeout", time.Second*10)
+		viper.SetDefault("breakers."+name+".failureThreshold", 5)
+		viper.SetDefault("breakers."+name+".openTimeout", time.Second*30)
+		viper.SetDefault("breakers."+name+".maxConcurrent", 50)
+	}
+	viper.SetDefault("breakers.oauth.retries", 2)
+	viper.SetDefault("breakers.oauth.retryBackoff", time.Millisecond*200)
+	viper.SetDefault("breakers.smtp.timeout", time.Second*30)
+	viper.SetDefault("breakers.search.timeout", time.Second*5)
+	viper.SetDefault("breakers.search.retries", 1)
+	viper.SetDefault("breakers.search.retryBackoff", time.Millisecond*100)
+
+	// Search defaults
+	viper.SetDefault("search.indexPrefix", "ehass_")
+	viper.SetDefault("suggest.cacheTTL", time.Minute*5)
+	viper.SetDefault("suggest.cacheSize", 10000)
+	viper.SetDefault("slots.cacheTTL", time.Second*30)
+	viper.SetDefault("slots.cacheSize", 20000)
+
+	// Content defaults
+	viper.SetDefault("content.locale", "en")
+
+	// Metrics defaults
+	viper.SetDefault("metrics.timezone", "UTC")
+
+	// Booking defaults
+	viper.SetDefault("booking.lock", "row")
+	viper.SetDefault("booking.lockWait", time.Second*2)
+	viper.SetDefault("booking.lockTTL", time.Second*15)
+
+	// Calendar sync defaults
+	viper.SetDefault("calendar.interval", time.Minute*5)
+	viper.SetDefault("calendar.window", time.Hour*24*60)
+
+	// Timeouts defaults
+	viper.SetDefault("timeouts.request", time.Second*8)
+
+	// Backup defaults
+	viper.SetDefault("backup.pgDump", "pg_dump")
+	viper.SetDefault("backup.pgRestore", "pg_restore")
+	viper.SetDefault("backup.timeout", time.Hour)
+	viper.SetDefault("backup.s3.prefix", "backups")
+
+	// Sandbox defaults
+	viper.SetDefault("sandbox.doctors", 8)
+	viper.SetDefault("sandbox.patients", 40)
+	viper.SetDefault("sandbox.seed", 1)
+
 	// Email defaults
 	viper.SetDefault("email.smtpPort", 587)
 	viper.SetDefault("email.fromEmail", "noreply@ehass.com")
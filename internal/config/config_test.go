@@ -0,0 +1,42 @@
+package config
+
+import "testing"
+
+func TestCheckEncryptionKeysRefusesDevelopmentKeys(t *testing.T) {
+	const ownKey = "b3duLWtleS1vZi10aGUtZGVwbG95bWVudC0zMmJ5dGU="
+	tests := []struct {
+		name        string
+		environment string
+		keys        map[string]string
+		indexKey    string
+		wantErr     bool
+	}{
+		{"own keys in production", "production", map[string]string{"prod": ownKey}, ownKey, false},
+		{"development key in production", "production", map[string]string{"dev": DevelopmentEncryptionKey}, ownKey, true},
+		{"retired development key in production", "production", map[string]string{"prod": ownKey, "dev": DevelopmentEncryptionKey}, ownKey, true},
+		{"development index key in production", "production", map[string]string{"prod": ownKey}, DevelopmentIndexKey, true},
+		{"development keys in development", "Development", map[string]string{"dev": DevelopmentEncryptionKey}, DevelopmentIndexKey, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{Environment: tt.environment}
+			cfg.Encryption.Keys = tt.keys
+			cfg.Encryption.IndexKey = tt.indexKey
+			if err := cfg.checkEncryptionKeys(); (err != nil) != tt.wantErr {
+				t.Errorf("checkEncryptionKeys() = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckIntrospectionClientsRefusesExampleSecret(t *testing.T) {
+	cfg := &Config{Environment: "development"}
+	cfg.Auth.IntrospectionClients = map[string]string{"api-gateway": "s3cr3t-of-the-gateway"}
+	if err := cfg.checkIntrospectionClients(); err != nil {
+		t.Errorf("own secret refused: %v", err)
+	}
+	cfg.Auth.IntrospectionClients["billing"] = PlaceholderIntrospectionSecret
+	if err := cfg.checkIntrospectionClients(); err == nil {
+		t.Error("example secret accepted in development")
+	}
+}
@@ -0,0 +1,25 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/whitewalker-sa/ehass/pkg/events"
+	"go.uber.org/zap"
+)
+
+// NewEventPublisher creates the publisher outbox events are delivered to.
+// Without a publisher, events are logged instead of published.
+func NewEventPublisher(cfg *Config, logger *zap.Logger) (events.Publisher, error) {
+	switch cfg.Outbox.Publisher {
+	case "":
+		return events.NewLogPublisher(logger), nil
+	case "webhook":
+		return events.NewWebhookPublisher(
+			cfg.Outbox.Webhook.URL,
+			cfg.Outbox.Webhook.Secret,
+			cfg.Outbox.Timeout,
+		)
+	default:
+		return nil, fmt.Errorf("unknown event publisher %q", cfg.Outbox.Publisher)
+	}
+}
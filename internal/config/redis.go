@@ -0,0 +1,26 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/pkg/redis"
+)
+
+// NewRedisClient connects to the configured Redis server
+func NewRedisClient(cfg *Config) (*redis.Client, error) {
+	address := net.JoinHostPort(cfg.Redis.Host, cfg.Redis.Port)
+	client, err := redis.NewClient(address, cfg.Redis.Password, cfg.Redis.DB, 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx); err != nil {
+		return nil, fmt.Errorf("failed to reach redis at %s: %w", address, err)
+	}
+	return client, nil
+}
@@ -0,0 +1,26 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/whitewalker-sa/ehass/pkg/search"
+)
+
+// NewSearchClient creates the client of the configured search backend, or returns nil when
+// searches should run against the database
+func NewSearchClient(cfg *Config) (*search.Client, error) {
+	switch cfg.Search.Backend {
+	case "":
+		return nil, nil
+	case "opensearch", "elasticsearch":
+		return search.NewClient(search.Config{
+			URL:         cfg.Search.URL,
+			Username:    cfg.Search.Username,
+			Password:    cfg.Search.Password,
+			IndexPrefix: cfg.Search.IndexPrefix,
+			Timeout:     cfg.Breakers.Search.Timeout,
+		})
+	default:
+		return nil, fmt.Errorf("unknown search backend %q", cfg.Search.Backend)
+	}
+}
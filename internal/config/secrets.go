@@ -0,0 +1,107 @@
+package config
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/whitewalker-sa/ehass/pkg/secrets"
+	"go.uber.org/zap"
+)
+
+// NewSecretsManager creates a secrets manager for the configured provider.
+// It returns nil when no provider is configured.
+func NewSecretsManager(cfg *Config, logger *zap.Logger) (*secrets.Manager, error) {
+	var (
+		provider secrets.Provider
+		err      error
+	)
+
+	switch cfg.Secrets.Provider {
+	case "":
+		return nil, nil
+	case "vault":
+		provider, err = secrets.NewVaultProvider(
+			cfg.Secrets.Vault.Address,
+			cfg.Secrets.Vault.Token,
+			cfg.Secrets.Vault.MountPath,
+			cfg.Secrets.Vault.Namespace,
+			cfg.Secrets.Timeout,
+		)
+	case "aws":
+		provider, err = secrets.NewAWSProvider(
+			cfg.Secrets.AWS.Region,
+			cfg.Secrets.AWS.AccessKeyID,
+			cfg.Secrets.AWS.SecretAccessKey,
+			cfg.Secrets.AWS.SessionToken,
+			cfg.Secrets.Timeout,
+		)
+	default:
+		return nil, fmt.Errorf("unknown secrets provider %q", cfg.Secrets.Provider)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return secrets.NewManager(provider, cfg.Secrets.CacheTTL, logger), nil
+}
+
+// ApplySecrets overrides credentials in cfg with values from the secrets manager
+func ApplySecrets(ctx context.Context, cfg *Config, manager *secrets.Manager) error {
+	groups := []struct {
+		path  string
+		apply func(*Config, map[string]string)
+	}{
+		{cfg.Secrets.Paths.Auth, ApplyAuthSecrets},
+		{cfg.Secrets.Paths.Database, ApplyDatabaseSecrets},
+		{cfg.Secrets.Paths.Email, ApplyEmailSecrets},
+		{cfg.Secrets.Paths.OAuth, ApplyOAuthSecrets},
+	}
+
+	for _, group := range groups {
+		if group.path == "" {
+			continue
+		}
+
+		values, err := manager.Get(ctx, group.path)
+		if err != nil {
+			return fmt.Errorf("failed to load secret %s: %w", group.path, err)
+		}
+		group.apply(cfg, values)
+	}
+
+	return nil
+}
+
+// ApplyAuthSecrets overrides the token and link signing secrets
+func ApplyAuthSecrets(cfg *Config, values map[string]string) {
+	setIfPresent(&cfg.Auth.AccessTokenSecret, values, "accessTokenSecret")
+	setIfPresent(&cfg.Auth.LinkSigningSecret, values, "linkSigningSecret")
+}
+
+// ApplyDatabaseSecrets overrides database credentials
+func ApplyDatabaseSecrets(cfg *Config, values map[string]string) {
+	setIfPresent(&cfg.Database.User, values, "user")
+	setIfPresent(&cfg.Database.Password, values, "password")
+}
+
+// ApplyEmailSecrets overrides SMTP credentials and the delivery webhook secret
+func ApplyEmailSecrets(cfg *Config, values map[string]string) {
+	setIfPresent(&cfg.Email.SMTPUsername, values, "smtpUsername")
+	setIfPresent(&cfg.Email.SMTPPassword, values, "smtpPassword")
+	setIfPresent(&cfg.Email.WebhookSecret, values, "webhookSecret")
+}
+
+// ApplyOAuthSecrets overrides OAuth client credentials
+func ApplyOAuthSecrets(cfg *Config, values map[string]string) {
+	setIfPresent(&cfg.OAuth.GitHub.ClientID, values, "githubClientId")
+	setIfPresent(&cfg.OAuth.GitHub.ClientSecret, values, "githubClientSecret")
+	setIfPresent(&cfg.OAuth.Google.ClientID, values, "googleClientId")
+	setIfPresent(&cfg.OAuth.Google.ClientSecret, values, "googleClientSecret")
+}
+
+// setIfPresent sets *field to values[key] when the key exists and is non-empty
+func setIfPresent(field *string, values map[string]string, key string) {
+	if value, ok := values[key]; ok && value != "" {
+		*field = value
+	}
+}
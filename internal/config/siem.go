@@ -0,0 +1,49 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/whitewalker-sa/ehass/pkg/awssig"
+	"github.com/whitewalker-sa/ehass/pkg/siem"
+)
+
+// NewSIEMSink creates the sink audit logs are exported to.
+// It returns nil when SIEM export is disabled.
+func NewSIEMSink(cfg *Config) (siem.Sink, error) {
+	if !cfg.SIEM.Enabled {
+		return nil, nil
+	}
+
+	switch cfg.SIEM.Sink {
+	case "syslog":
+		return siem.NewSyslogSink(
+			cfg.SIEM.Syslog.Network,
+			cfg.SIEM.Syslog.Address,
+			cfg.SIEM.Syslog.AppName,
+			cfg.SIEM.Timeout,
+		)
+	case "splunk":
+		return siem.NewSplunkSink(
+			cfg.SIEM.Splunk.URL,
+			cfg.SIEM.Splunk.Token,
+			cfg.SIEM.Splunk.Index,
+			cfg.SIEM.Splunk.SourceType,
+			cfg.SIEM.Timeout,
+		)
+	case "s3":
+		return siem.NewS3Sink(
+			cfg.SIEM.S3.Bucket,
+			cfg.SIEM.S3.Prefix,
+			cfg.SIEM.S3.Region,
+			cfg.SIEM.S3.Endpoint,
+			awssig.Credentials{
+				AccessKeyID:     cfg.SIEM.S3.AccessKeyID,
+				SecretAccessKey: cfg.SIEM.S3.SecretAccessKey,
+				SessionToken:    cfg.SIEM.S3.SessionToken,
+			},
+			cfg.SIEM.Timeout,
+		)
+	default:
+		return nil, fmt.Errorf("unknown SIEM sink %q", cfg.SIEM.Sink)
+	}
+}
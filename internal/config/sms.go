@@ -0,0 +1,26 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/whitewalker-sa/ehass/pkg/sms"
+	"go.uber.org/zap"
+)
+
+// NewSMSSender creates the sender for the configured SMS provider.
+// Without a provider, messages are logged instead of sent.
+func NewSMSSender(cfg *Config, logger *zap.Logger) (sms.Sender, error) {
+	switch cfg.SMS.Provider {
+	case "":
+		return sms.NewLogSender(logger), nil
+	case "twilio":
+		return sms.NewTwilioSender(
+			cfg.SMS.Twilio.AccountSID,
+			cfg.SMS.Twilio.AuthToken,
+			cfg.SMS.Twilio.From,
+			cfg.SMS.Timeout,
+		)
+	default:
+		return nil, fmt.Errorf("unknown SMS provider %q", cfg.SMS.Provider)
+	}
+}
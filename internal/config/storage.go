@@ -0,0 +1,31 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/whitewalker-sa/ehass/pkg/awssig"
+	"github.com/whitewalker-sa/ehass/pkg/storage"
+)
+
+// NewAttachmentStore creates the store medical record attachments are kept in
+func NewAttachmentStore(cfg *Config) (storage.Store, error) {
+	switch cfg.Attachments.Storage {
+	case "disk":
+		return storage.NewDiskStore(cfg.Attachments.Dir)
+	case "s3":
+		return storage.NewS3Store(
+			cfg.Attachments.S3.Bucket,
+			cfg.Attachments.S3.Prefix,
+			cfg.Attachments.S3.Region,
+			cfg.Attachments.S3.Endpoint,
+			awssig.Credentials{
+				AccessKeyID:     cfg.Attachments.S3.AccessKeyID,
+				SecretAccessKey: cfg.Attachments.S3.SecretAccessKey,
+				SessionToken:    cfg.Attachments.S3.SessionToken,
+			},
+			cfg.Attachments.Timeout,
+		)
+	default:
+		return nil, fmt.Errorf("unknown attachment storage %q", cfg.Attachments.Storage)
+	}
+}
@@ -0,0 +1,267 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/service"
+	"go.uber.org/zap"
+)
+
+// AccountHandler handles patient account HTTP requests
+type AccountHandler struct {
+	service service.AccountService
+	logger  *zap.Logger
+}
+
+// NewAccountHandler creates a new patient account handler
+func NewAccountHandler(service service.AccountService, logger *zap.Logger) *AccountHandler {
+	return &AccountHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// PostEntry godoc
+// @Summary Post payment or credit
+// @Description Post a payment received from a patient, or a credit granted to them, to their account. Requires the billing:manage permission.
+// @Tags patients,billing
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Patient ID (UUID)"
+// @Param request body accountEntryRequest true "Payment or credit"
+// @Success 201 {object} accountEntryResponse "Posted entry"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /patients/{id}/account/entries [post]
+func (h *AccountHandler) PostEntry(c *gin.Context) {
+	patientID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+		return
+	}
+
+	var req accountEntryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	input := service.AccountEntryInput{
+		Kind:        model.AccountEntryKind(req.Kind),
+		AmountCents: req.AmountCents,
+		Currency:    req.Currency,
+		Method:      req.Method,
+		Reference:   req.Reference,
+		Note:        req.Note,
+	}
+	if req.PostedAt != "" {
+		loc, err := inputLocation(c, req.Timezone)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid timezone"})
+			return
+		}
+		if input.PostedAt, err = parseRequestTime(req.PostedAt, loc); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid posted_at, use RFC3339 or YYYY-MM-DDTHH:MM format"})
+			return
+		}
+	}
+
+	entry, err := h.service.PostEntry(c.Request.Context(), c.GetUint("userID"), uint(patientID), input)
+	if err != nil {
+		h.accountError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, toAccountEntryResponse(entry, requestLocation(c)))
+}
+
+// GetStatement godoc
+// @Summary Get statement of account
+// @Description A patient's statement of account over a period: the opening balance, the appointments invoiced and the payments and credits posted, with a running balance, and the balance due. Dates are in the patient's timezone and amounts are per currency, positive when the patient owes the clinic. Patients can view their own and their dependents' statements; staff need the billing:read permission.
+// @Tags patients,billing
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Patient ID (UUID)"
+// @Param from query string true "First day, YYYY-MM-DD"
+// @Param to query string true "Last day, YYYY-MM-DD"
+// @Success 200 {object} statementResponse "Statement"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /patients/{id}/statement [get]
+func (h *AccountHandler) GetStatement(c *gin.Context) {
+	patientID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+		return
+	}
+
+	role, _ := c.Get("userRole")
+	userRole, _ := role.(model.Role)
+	statement, err := h.service.GetStatement(c.Request.Context(), c.GetUint("userID"), userRole,
+		hasPermission(c, model.PermissionBillingRead), uint(patientID), c.Query("from"), c.Query("to"))
+	if err != nil {
+		h.accountError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, toStatementResponse(statement))
+}
+
+// GetStatementPDF godoc
+// @Summary Print statement of account
+// @Description Download a printable PDF of a patient's statement of account over a period, with the clinic's details. Access is as for the JSON statement.
+// @Tags patients,billing
+// @Produce application/pdf
+// @Security BearerAuth
+// @Param id path string true "Patient ID (UUID)"
+// @Param from query string true "First day, YYYY-MM-DD"
+// @Param to query string true "Last day, YYYY-MM-DD"
+// @Success 200 {file} file "Statement PDF"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /patients/{id}/statement/pdf [get]
+func (h *AccountHandler) GetStatementPDF(c *gin.Context) {
+	patientID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+		return
+	}
+
+	role, _ := c.Get("userRole")
+	userRole, _ := role.(model.Role)
+	document, err := h.service.RenderStatement(c.Request.Context(), c.GetUint("userID"), userRole,
+		hasPermission(c, model.PermissionBillingRead), uint(patientID), c.Query("from"), c.Query("to"))
+	if err != nil {
+		h.accountError(c, err)
+		return
+	}
+
+	c.Header("Content-Disposition", `inline; filename="statement.pdf"`)
+	c.Header("Cache-Control", "private, no-store")
+	c.Data(http.StatusOK, "application/pdf", document)
+}
+
+// accountError maps a patient account service error to a response
+func (h *AccountHandler) accountError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrNotOwnAccount):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case strings.HasSuffix(err.Error(), "not found"):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidAccountEntry), errors.Is(err, service.ErrInvalidStatementPeriod):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		h.logger.Error("Account request failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to process account request"})
+	}
+}
+
+type accountEntryRequest struct {
+	Kind        string `json:"kind" binding:"required,oneof=payment credit"`
+	AmountCents int64  `json:"amount_cents" binding:"required,gt=0"`
+	Currency    string `json:"currency"`  // Defaults to USD
+	Method      string `json:"method"`    // e.g. card, cash or insurance
+	Reference   string `json:"reference"` // Receipt, transaction or claim number
+	Note        string `json:"note"`
+	PostedAt    string `json:"posted_at"` // Defaults to now
+	Timezone    string `json:"timezone"`  // Timezone of a posted_at without an offset
+}
+
+type accountEntryResponse struct {
+	ID          string `json:"id"`
+	Kind        string `json:"kind"`
+	AmountCents int64  `json:"amount_cents"`
+	Currency    string `json:"currency"`
+	Method      string `json:"method,omitempty"`
+	Reference   string `json:"reference,omitempty"`
+	Note        string `json:"note,omitempty"`
+	PostedAt    string `json:"posted_at"`
+}
+
+func toAccountEntryResponse(entry *model.AccountEntry, loc *time.Location) accountEntryResponse {
+	return accountEntryResponse{
+		ID:          entry.PublicID,
+		Kind:        string(entry.Kind),
+		AmountCents: entry.AmountCents,
+		Currency:    entry.Currency,
+		Method:      entry.Method,
+		Reference:   entry.Reference,
+		Note:        entry.Note,
+		PostedAt:    entry.PostedAt.In(loc).Format(time.RFC3339),
+	}
+}
+
+type statementResponse struct {
+	PatientID   string                     `json:"patient_id"`
+	PatientName string                     `json:"patient_name"`
+	From        string                     `json:"from"`
+	To          string                     `json:"to"`
+	Timezone    string                     `json:"timezone"`
+	Accounts    []statementAccountResponse `json:"accounts"` // One per currency
+}
+
+type statementAccountResponse struct {
+	Currency            string                  `json:"currency"`
+	OpeningBalanceCents int64                   `json:"opening_balance_cents"`
+	InvoicedCents       int64                   `json:"invoiced_cents"`
+	PaidCents           int64                   `json:"paid_cents"`
+	CreditedCents       int64                   `json:"credited_cents"`
+	ClosingBalanceCents int64                   `json:"closing_balance_cents"`
+	Lines               []statementLineResponse `json:"lines"`
+}
+
+type statementLineResponse struct {
+	Date         string `json:"date"`
+	Kind         string `json:"kind"`      // invoice, payment or credit
+	Reference    string `json:"reference"` // Appointment ID for invoices, entry ID otherwise
+	Description  string `json:"description"`
+	AmountCents  int64  `json:"amount_cents"`
+	BalanceCents int64  `json:"balance_cents"`
+}
+
+func toStatementResponse(statement *service.Statement) statementResponse {
+	response := statementResponse{
+		PatientID:   statement.Patient.PublicID,
+		PatientName: statement.Patient.User.Name,
+		From:        statement.From.Format("2006-01-02"),
+		To:          statement.To.Format("2006-01-02"),
+		Timezone:    statement.Location.String(),
+		Accounts:    make([]statementAccountResponse, 0, len(statement.Accounts)),
+	}
+	for _, account := range statement.Accounts {
+		lines := make([]statementLineResponse, 0, len(account.Lines))
+		for _, line := range account.Lines {
+			lines = append(lines, statementLineResponse{
+				Date:         line.Date.Format(time.RFC3339),
+				Kind:         line.Kind,
+				Reference:    line.Reference,
+				Description:  line.Description,
+				AmountCents:  line.AmountCents,
+				BalanceCents: line.BalanceCents,
+			})
+		}
+		response.Accounts = append(response.Accounts, statementAccountResponse{
+			Currency:            account.Currency,
+			OpeningBalanceCents: account.OpeningBalanceCents,
+			InvoicedCents:       account.InvoicedCents,
+			PaidCents:           account.PaidCents,
+			CreditedCents:       account.CreditedCents,
+			ClosingBalanceCents: account.ClosingBalanceCents,
+			Lines:               lines,
+		})
+	}
+	return response
+}
@@ -0,0 +1,270 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/service"
+	"go.uber.org/zap"
+)
+
+// AnalyticsHandler handles clinic analytics HTTP requests
+type AnalyticsHandler struct {
+	service    service.AnalyticsService
+	operations service.OperationService
+	logger     *zap.Logger
+}
+
+// NewAnalyticsHandler creates a new analytics handler and registers the background run of
+// analytics requests
+func NewAnalyticsHandler(service service.AnalyticsService, operations service.OperationService, logger *zap.Logger) *AnalyticsHandler {
+	h := &AnalyticsHandler{
+		service:    service,
+		operations: operations,
+		logger:     logger,
+	}
+	operations.Register(model.OperationClinicAnalytics, h.runClinicAnalytics)
+	return h
+}
+
+// GetClinicAnalytics godoc
+// @Summary Get clinic analytics
+// @Description Get bookings, cancellations, new patients and revenue of a clinic bucketed by day, week or month for charting. Buckets are aligned to the clinic's timezone and weeks start on Monday.
+// @Tags admin,analytics
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Organization ID"
+// @Param granularity query string false "day, week or month" default(day)
+// @Param from query string true "First date (YYYY-MM-DD)"
+// @Param to query string true "Last date (YYYY-MM-DD), inclusive"
+// @Param refresh query bool false "Recompute stored buckets"
+// @Param Prefer header string false "respond-async to compute the series in the background"
+// @Success 200 {object} clinicAnalyticsResponse "Analytics series"
+// @Success 202 {object} operationResponse "Operation computing the series"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Router /admin/organizations/{id}/analytics [get]
+func (h *AnalyticsHandler) GetClinicAnalytics(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid organization ID"})
+		return
+	}
+
+	from, to := c.Query("from"), c.Query("to")
+	if from == "" || to == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from and to dates are required"})
+		return
+	}
+	granularity := model.AnalyticsGranularity(c.DefaultQuery("granularity", string(model.GranularityDay)))
+	refresh, _ := strconv.ParseBool(c.Query("refresh"))
+
+	if prefersAsync(c) {
+		submitOperation(c, h.operations, h.logger, model.OperationClinicAnalytics, clinicAnalyticsParams{
+			OrganizationID: uint(id),
+			Granularity:    granularity,
+			From:           from,
+			To:             to,
+			Refresh:        refresh,
+		})
+		return
+	}
+
+	analytics, err := h.service.GetClinicAnalytics(c.Request.Context(), uint(id), granularity, from, to, refresh)
+	if err != nil {
+		h.logger.Warn("Failed to get clinic analytics", zap.Uint64("organizationID", id), zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, toClinicAnalyticsResponse(analytics))
+}
+
+// GetVisitDurations godoc
+// @Summary Get visit durations and punctuality
+// @Description Compare how long a clinic's visits were booked for with how long they took, by appointment type, with a suggested slot length covering 80% of visits, and how promptly each doctor started visits. Delays count from the scheduled start or from when the patient checked in, whichever was later. Only visits with a recorded start and end are counted.
+// @Tags admin,analytics
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Organization ID"
+// @Param from query string true "First date (YYYY-MM-DD)"
+// @Param to query string true "Last date (YYYY-MM-DD), inclusive"
+// @Success 200 {object} visitDurationReportResponse "Visit duration report"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Router /admin/organizations/{id}/visit-durations [get]
+func (h *AnalyticsHandler) GetVisitDurations(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid organization ID"})
+		return
+	}
+
+	from, to := c.Query("from"), c.Query("to")
+	if from == "" || to == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from and to dates are required"})
+		return
+	}
+
+	report, err := h.service.GetVisitDurations(c.Request.Context(), uint(id), from, to)
+	if err != nil {
+		h.logger.Warn("Failed to get visit durations", zap.Uint64("organizationID", id), zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, toVisitDurationReportResponse(report))
+}
+
+// runClinicAnalytics computes a clinic analytics series requested with Prefer: respond-async
+func (h *AnalyticsHandler) runClinicAnalytics(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var p clinicAnalyticsParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+
+	analytics, err := h.service.GetClinicAnalytics(ctx, p.OrganizationID, p.Granularity, p.From, p.To, p.Refresh)
+	if err != nil {
+		return nil, err
+	}
+	return toClinicAnalyticsResponse(analytics), nil
+}
+
+// Request and response models
+type clinicAnalyticsParams struct {
+	OrganizationID uint                       `json:"organization_id"`
+	Granularity    model.AnalyticsGranularity `json:"granularity"`
+	From           string                     `json:"from"`
+	To             string                     `json:"to"`
+	Refresh        bool                       `json:"refresh"`
+}
+
+type clinicAnalyticsResponse struct {
+	OrganizationID uint                      `json:"organization_id"`
+	Granularity    string                    `json:"granularity"`
+	Timezone       string                    `json:"timezone"`
+	Buckets        []analyticsBucketResponse `json:"buckets"`
+}
+
+type analyticsBucketResponse struct {
+	Start         string           `json:"start"`
+	End           string           `json:"end"`
+	Bookings      int64            `json:"bookings"`
+	Cancellations int64            `json:"cancellations"`
+	NewPatients   int64            `json:"new_patients"`
+	Revenue       map[string]int64 `json:"revenue"` // Minor units keyed by currency
+}
+
+// Helper function to convert analytics to response
+func toClinicAnalyticsResponse(analytics *service.ClinicAnalytics) clinicAnalyticsResponse {
+	loc, err := time.LoadLocation(analytics.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+
+	buckets := make([]analyticsBucketResponse, 0, len(analytics.Buckets))
+	for _, bucket := range analytics.Buckets {
+		revenue := bucket.Revenue
+		if revenue == nil {
+			revenue = map[string]int64{}
+		}
+		buckets = append(buckets, analyticsBucketResponse{
+			Start:         bucket.BucketStart.In(loc).Format(time.RFC3339),
+			End:           bucket.BucketEnd.In(loc).Format(time.RFC3339),
+			Bookings:      bucket.Bookings,
+			Cancellations: bucket.Cancellations,
+			NewPatients:   bucket.NewPatients,
+			Revenue:       revenue,
+		})
+	}
+
+	return clinicAnalyticsResponse{
+		OrganizationID: analytics.OrganizationID,
+		Granularity:    string(analytics.Granularity),
+		Timezone:       analytics.Timezone,
+		Buckets:        buckets,
+	}
+}
+
+type visitDurationReportResponse struct {
+	OrganizationID uint                               `json:"organization_id"`
+	From           string                             `json:"from"`
+	To             string                             `json:"to"` // Exclusive
+	Timezone       string                             `json:"timezone"`
+	Visits         int                                `json:"visits"`
+	Types          []appointmentTypeDurationsResponse `json:"appointment_types"`
+	Doctors        []doctorPunctualityResponse        `json:"doctors"`
+}
+
+type appointmentTypeDurationsResponse struct {
+	AppointmentTypeID        *uint  `json:"appointment_type_id"` // Null for visits booked without a type
+	Name                     string `json:"name,omitempty"`
+	Visits                   int    `json:"visits"`
+	AverageScheduledSeconds  int64  `json:"average_scheduled_seconds"`
+	AverageDurationSeconds   int64  `json:"average_duration_seconds"`
+	P80DurationSeconds       int64  `json:"p80_duration_seconds"`
+	Overran                  int    `json:"overran"`
+	SuggestedDurationMinutes int    `json:"suggested_duration_minutes,omitempty"` // Omitted with too few visits
+}
+
+type doctorPunctualityResponse struct {
+	DoctorID            string `json:"doctor_id"`
+	Name                string `json:"name"`
+	Visits              int    `json:"visits"`
+	AverageDelaySeconds int64  `json:"average_delay_seconds"`
+	P80DelaySeconds     int64  `json:"p80_delay_seconds"`
+	Late                int    `json:"late"`
+	FinishedLate        int    `json:"finished_late"`
+}
+
+// Helper function to convert a visit duration report to response
+func toVisitDurationReportResponse(report *service.VisitDurationReport) visitDurationReportResponse {
+	loc, err := time.LoadLocation(report.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+
+	types := make([]appointmentTypeDurationsResponse, 0, len(report.Types))
+	for _, entry := range report.Types {
+		types = append(types, appointmentTypeDurationsResponse{
+			AppointmentTypeID:        entry.AppointmentTypeID,
+			Name:                     entry.Name,
+			Visits:                   entry.Visits,
+			AverageScheduledSeconds:  int64(entry.ScheduledDuration.Seconds()),
+			AverageDurationSeconds:   int64(entry.ActualDuration.Seconds()),
+			P80DurationSeconds:       int64(entry.P80Duration.Seconds()),
+			Overran:                  entry.Overran,
+			SuggestedDurationMinutes: int(entry.SuggestedDuration.Minutes()),
+		})
+	}
+
+	doctors := make([]doctorPunctualityResponse, 0, len(report.Doctors))
+	for _, doctor := range report.Doctors {
+		doctors = append(doctors, doctorPunctualityResponse{
+			DoctorID:            doctor.DoctorID,
+			Name:                doctor.Name,
+			Visits:              doctor.Visits,
+			AverageDelaySeconds: int64(doctor.AverageDelay.Seconds()),
+			P80DelaySeconds:     int64(doctor.P80Delay.Seconds()),
+			Late:                doctor.Late,
+			FinishedLate:        doctor.FinishedLate,
+		})
+	}
+
+	return visitDurationReportResponse{
+		OrganizationID: report.OrganizationID,
+		From:           report.From.In(loc).Format(time.RFC3339),
+		To:             report.To.In(loc).Format(time.RFC3339),
+		Timezone:       report.Timezone,
+		Visits:         report.Visits,
+		Types:          types,
+		Doctors:        doctors,
+	}
+}
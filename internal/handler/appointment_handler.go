@@ -1,6 +1,9 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
+	"math"
 	"net/http"
 	"strconv"
 	"time"
@@ -8,26 +11,36 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/whitewalker-sa/ehass/internal/model"
 	"github.com/whitewalker-sa/ehass/internal/service"
+	"github.com/whitewalker-sa/ehass/pkg/utils"
 	"go.uber.org/zap"
 )
 
 // AppointmentHandler handles HTTP requests for appointments
 type AppointmentHandler struct {
 	appointmentService service.AppointmentService
+	noShowService      service.NoShowService
+	publicIDs          service.PublicIDService
 	logger             *zap.Logger
 }
 
 // NewAppointmentHandler creates a new appointment handler
-func NewAppointmentHandler(appointmentService service.AppointmentService, logger *zap.Logger) *AppointmentHandler {
+func NewAppointmentHandler(
+	appointmentService service.AppointmentService,
+	noShowService service.NoShowService,
+	publicIDs service.PublicIDService,
+	logger *zap.Logger,
+) *AppointmentHandler {
 	return &AppointmentHandler{
 		appointmentService: appointmentService,
+		noShowService:      noShowService,
+		publicIDs:          publicIDs,
 		logger:             logger,
 	}
 }
 
 // CreateAppointment godoc
 // @Summary Create a new appointment
-// @Description Create a new appointment for a patient with a doctor
+// @Description Create a new appointment for a patient with a doctor. Patients book for themselves or their dependants; staff with appointments:book for any patient. patient_ids can add other patients of the same family, such as a parent's children, to the same slot.
 // @Tags appointments
 // @Accept json
 // @Produce json
@@ -36,9 +49,35 @@ func NewAppointmentHandler(appointmentService service.AppointmentService, logger
 // @Success 201 {object} map[string]string "Appointment created successfully"
 // @Failure 400 {object} map[string]string "Bad request"
 // @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Not the caller's own or dependant's booking, or patients not of one family"
+// @Failure 409 {object} map[string]string "Doctor or patient already booked"
 // @Failure 500 {object} map[string]string "Internal server error"
 // @Router /appointments [post]
 func (h *AppointmentHandler) CreateAppointment(c *gin.Context) {
+	h.createAppointment(c, 0)
+}
+
+// BookOnBehalf godoc
+// @Summary Book an appointment on a patient's behalf
+// @Description Book an appointment for a patient as clinic staff, such as a receptionist taking a booking over the phone. The booking follows the same rules as one the patient makes, records who booked it in booked_by, and is audit logged. Requires appointments:book.
+// @Tags front-desk,appointments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param appointment body createAppointmentRequest true "Appointment Details"
+// @Success 201 {object} map[string]string "Appointment created successfully"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 409 {object} map[string]string "Doctor or patient already booked"
+// @Router /front-desk/appointments [post]
+func (h *AppointmentHandler) BookOnBehalf(c *gin.Context) {
+	h.createAppointment(c, c.GetUint("userID"))
+}
+
+// createAppointment books an appointment from the request body. staffID is the staff member
+// booking on the patient's behalf, or 0 when the caller books for themselves.
+func (h *AppointmentHandler) createAppointment(c *gin.Context, staffID uint) {
 	var req createAppointmentRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
@@ -46,52 +85,107 @@ func (h *AppointmentHandler) CreateAppointment(c *gin.Context) {
 	}
 
 	// Parse appointment times for validation
-	_, err := time.Parse(time.RFC3339, req.ScheduledStart)
+	loc, err := inputLocation(c, req.Timezone)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scheduled start time format"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid timezone"})
 		return
 	}
-
-	_, err = time.Parse(time.RFC3339, req.ScheduledEnd)
+	startTime, err := parseRequestTime(req.ScheduledStart, loc)
 	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scheduled start time format"})
+		return
+	}
+	if _, err := parseRequestTime(req.ScheduledEnd, loc); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scheduled end time format"})
 		return
 	}
 
-	// Extract date and time from RFC3339 format
-	startTime, _ := time.Parse(time.RFC3339, req.ScheduledStart)
+	// The service takes the start as a UTC date and time
 	date := startTime.Format("2006-01-02")
 	timeStr := startTime.Format("15:04")
 
+	// Resolve the public patient and doctor IDs
+	patientID, err := h.publicIDs.ResolveID(c.Request.Context(), model.ResourcePatient, req.PatientID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	doctorID, err := h.publicIDs.ResolveID(c.Request.Context(), model.ResourceDoctor, req.DoctorID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	participantIDs := make([]uint, 0, len(req.PatientIDs))
+	for _, publicID := range req.PatientIDs {
+		id, err := h.publicIDs.ResolveID(c.Request.Context(), model.ResourcePatient, publicID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		participantIDs = append(participantIDs, id)
+	}
+
 	// Create appointment
-	appointment, err := h.appointmentService.CreateAppointment(
-		c.Request.Context(),
-		req.PatientID,
-		req.DoctorID,
-		date,
-		timeStr,
-		req.Reason,
-	)
+	var appointment *model.Appointment
+	if staffID != 0 {
+		appointment, err = h.appointmentService.BookOnBehalf(
+			c.Request.Context(),
+			staffID,
+			patientID,
+			doctorID,
+			req.AppointmentTypeID,
+			req.VisitReasonID,
+			participantIDs,
+			date,
+			timeStr,
+			req.Reason,
+			req.IntakeAnswers,
+		)
+	} else {
+		appointment, err = h.appointmentService.CreateAppointment(
+			c.Request.Context(),
+			c.GetUint("userID"),
+			hasPermission(c, model.PermissionAppointmentsBook),
+			patientID,
+			doctorID,
+			req.AppointmentTypeID,
+			req.VisitReasonID,
+			participantIDs,
+			date,
+			timeStr,
+			req.Reason,
+			req.IntakeAnswers,
+		)
+	}
 	if err != nil {
+		if errors.Is(err, service.ErrScheduleConflict) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, service.ErrNotSameFamily) || errors.Is(err, service.ErrNotOwnBooking) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
 		h.logger.Error("Failed to create appointment", zap.Error(err))
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusCreated, gin.H{
-		"message": "Appointment created successfully",
-		"id":      appointment.ID,
+		"message":               "Appointment created successfully",
+		"id":                    appointment.PublicID,
+		"confirmation_required": appointment.ConfirmationRequired,
 	})
 }
 
 // GetAppointmentByID godoc
 // @Summary Get appointment by ID
-// @Description Get appointment details by ID
+// @Description Get appointment details by ID. Staff also see the patient's no-show risk.
 // @Tags appointments
 // @Accept json
 // @Produce json
 // @Security BearerAuth
-// @Param id path int true "Appointment ID"
+// @Param id path string true "Appointment ID (UUID)"
 // @Success 200 {object} appointmentResponse "Appointment"
 // @Failure 400 {object} map[string]string "Bad request"
 // @Failure 401 {object} map[string]string "Unauthorized"
@@ -115,8 +209,450 @@ func (h *AppointmentHandler) GetAppointmentByID(c *gin.Context) {
 		return
 	}
 
+	response := formatAppointmentResponse(appointment, requestLocation(c))
+
+	// Show the no-show risk to staff only
+	if role, _ := c.Get("userRole"); role != model.RolePatient {
+		risk, err := h.noShowService.AssessAppointment(c.Request.Context(), appointment)
+		if err != nil {
+			h.logger.Error("Failed to assess no-show risk", zap.Error(err))
+		} else {
+			response.NoShowRisk = toNoShowRiskResponse(risk)
+		}
+	}
+
 	// Return appointment
-	c.JSON(http.StatusOK, formatAppointmentResponse(appointment))
+	c.JSON(http.StatusOK, response)
+}
+
+// BatchGetAppointments godoc
+// @Summary Get several appointments
+// @Description Get up to 100 appointments by ID in one call, in the order requested. IDs that match no appointment are returned in not_found. Unlike the single read, the no-show risk is not included.
+// @Tags appointments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param ids body batchGetRequest true "Appointment IDs (UUIDs)"
+// @Success 200 {object} map[string]interface{} "Appointments and IDs not found"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /appointments/batch-get [post]
+func (h *AppointmentHandler) BatchGetAppointments(c *gin.Context) {
+	var req batchGetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	appointments, missing, err := h.appointmentService.GetAppointmentsByPublicIDs(c.Request.Context(), req.IDs)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidPublicID) || errors.Is(err, service.ErrBatchTooLarge) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.Error("Failed to get appointments by ID", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get appointments"})
+		return
+	}
+
+	loc := requestLocation(c)
+	response := make([]appointmentResponse, 0, len(appointments))
+	for _, appointment := range appointments {
+		response = append(response, formatAppointmentResponse(appointment, loc))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"appointments": response,
+		"not_found":    missing,
+	})
+}
+
+// ConfirmAppointment godoc
+// @Summary Confirm appointment
+// @Description Doctors confirm a pending booking of their own, and the patient is emailed; no body is needed. Patients confirm a booking flagged as high no-show risk with the code sent to them by SMS, within 24 hours and before the appointment starts; after 5 codes the booking is locked.
+// @Tags appointments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Appointment ID (UUID)"
+// @Param data body confirmAppointmentRequest false "Confirmation code, for patients"
+// @Success 200 {object} appointmentResponse "Confirmed appointment"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not found"
+// @Failure 409 {object} map[string]string "Appointment is not pending or its intake checklist is incomplete"
+// @Failure 410 {object} map[string]string "Confirmation code expired"
+// @Failure 429 {object} map[string]string "Too many confirmation attempts"
+// @Router /appointments/{id}/confirm [post]
+func (h *AppointmentHandler) ConfirmAppointment(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid appointment ID"})
+		return
+	}
+
+	if role, _ := c.Get("userRole"); role == model.RoleDoctor {
+		appointment, err := h.appointmentService.ConfirmAppointment(c.Request.Context(), uint(id), c.GetUint("userID"))
+		if err != nil {
+			h.respondDoctorReviewError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, formatAppointmentResponse(appointment, requestLocation(c)))
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req confirmAppointmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	appointment, err := h.noShowService.ConfirmBooking(c.Request.Context(), uint(id), userID.(uint), req.Code)
+	if err != nil {
+		h.logger.Warn("Failed to confirm appointment", zap.Error(err))
+		switch {
+		case errors.Is(err, service.ErrConfirmationCodeExpired):
+			c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrConfirmationLocked):
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, formatAppointmentResponse(appointment, requestLocation(c)))
+}
+
+// DeclineAppointment godoc
+// @Summary Decline appointment
+// @Description Decline a pending booking of the signed-in doctor's. The appointment is cancelled and the patient is emailed the reason. Confirmed appointments are cancelled instead.
+// @Tags appointments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Appointment ID (UUID)"
+// @Param data body declineAppointmentRequest true "Reason"
+// @Success 200 {object} appointmentResponse "Declined appointment"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not found"
+// @Failure 409 {object} map[string]string "Appointment is not pending"
+// @Router /appointments/{id}/decline [post]
+func (h *AppointmentHandler) DeclineAppointment(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid appointment ID"})
+		return
+	}
+
+	var req declineAppointmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	appointment, err := h.appointmentService.DeclineAppointment(c.Request.Context(), uint(id), c.GetUint("userID"), req.Reason)
+	if err != nil {
+		h.respondDoctorReviewError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, formatAppointmentResponse(appointment, requestLocation(c)))
+}
+
+// respondDoctorReviewError writes the response for a doctor's confirmation or decline that failed
+func (h *AppointmentHandler) respondDoctorReviewError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrNotOwnAppointment):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case err.Error() == "appointment not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidTransition), errors.Is(err, service.ErrChecklistIncomplete):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		h.logger.Error("Failed to review appointment", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update appointment"})
+	}
+}
+
+// CheckInAppointment godoc
+// @Summary Check in a patient
+// @Description Front-desk staff or a kiosk mark that the patient of a confirmed appointment has arrived, adding them to the doctor's waiting queue. Patients can be checked in on the day of the appointment in the clinic's timezone; a patient marked as a no-show who turns up can still be checked in.
+// @Tags appointments
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Appointment ID (UUID)"
+// @Success 200 {object} appointmentResponse "Checked-in appointment"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not found"
+// @Failure 409 {object} map[string]string "Appointment is not confirmed or not today"
+// @Router /appointments/{id}/check-in [post]
+func (h *AppointmentHandler) CheckInAppointment(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid appointment ID"})
+		return
+	}
+
+	appointment, err := h.appointmentService.CheckInAppointment(c.Request.Context(), uint(id))
+	if err != nil {
+		switch {
+		case err.Error() == "appointment not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrInvalidTransition), errors.Is(err, service.ErrCheckInNotToday):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			h.logger.Error("Failed to check in appointment", zap.Uint("appointmentID", uint(id)), zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check in appointment"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, formatAppointmentResponse(appointment, requestLocation(c)))
+}
+
+// ConfirmAttendance godoc
+// @Summary Confirm attendance
+// @Description Patients confirm they will attend an upcoming appointment, for example after a reminder. This stops further reminders and clears the front-desk follow-up flag; no body is needed.
+// @Tags appointments
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Appointment ID (UUID)"
+// @Success 200 {object} appointmentResponse "Appointment with its attendance confirmed"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Not the patient's appointment"
+// @Failure 404 {object} map[string]string "Not found"
+// @Failure 409 {object} map[string]string "Appointment is not upcoming"
+// @Router /appointments/{id}/attendance [post]
+func (h *AppointmentHandler) ConfirmAttendance(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid appointment ID"})
+		return
+	}
+
+	appointment, err := h.appointmentService.ConfirmAttendance(c.Request.Context(), uint(id), c.GetUint("userID"))
+	if err != nil {
+		switch {
+		case err.Error() == "appointment not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrNotPatientAppointment):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrNotUpcoming):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			h.logger.Error("Failed to confirm attendance", zap.Uint("appointmentID", uint(id)), zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to confirm attendance"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, formatAppointmentResponse(appointment, requestLocation(c)))
+}
+
+// StartAppointment godoc
+// @Summary Start a visit
+// @Description The signed-in doctor admits a checked-in patient, recording when the visit started and taking them off the waiting queue. Video visits start when the doctor admits patients from the waiting room instead. Starting a started visit changes nothing.
+// @Tags appointments
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Appointment ID (UUID)"
+// @Success 200 {object} appointmentResponse "Started appointment"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not found"
+// @Failure 409 {object} map[string]string "Patient is not checked in"
+// @Router /appointments/{id}/start [post]
+func (h *AppointmentHandler) StartAppointment(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid appointment ID"})
+		return
+	}
+
+	appointment, err := h.appointmentService.StartAppointment(c.Request.Context(), uint(id), c.GetUint("userID"))
+	if err != nil {
+		h.respondDoctorReviewError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, formatAppointmentResponse(appointment, requestLocation(c)))
+}
+
+// GetCheckInQueue godoc
+// @Summary Get a doctor's waiting queue
+// @Description List the patients checked in today for a doctor's appointments in the order they arrived, with their place in the queue and how long they have waited
+// @Tags appointments,doctors
+// @Produce json
+// @Security BearerAuth
+// @Param doctorID path string true "Doctor ID (UUID)"
+// @Success 200 {object} checkInQueueResponse "Waiting queue"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /appointments/doctor/{doctorID}/queue [get]
+func (h *AppointmentHandler) GetCheckInQueue(c *gin.Context) {
+	doctorID, err := strconv.ParseUint(c.Param("doctorID"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid doctor ID"})
+		return
+	}
+
+	appointments, err := h.appointmentService.GetCheckInQueue(c.Request.Context(), uint(doctorID))
+	if err != nil {
+		h.logger.Error("Failed to get check-in queue", zap.Uint("doctorID", uint(doctorID)), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get queue"})
+		return
+	}
+
+	now := time.Now()
+	loc := requestLocation(c)
+	items := make([]queueEntryResponse, 0, len(appointments))
+	for i, appt := range appointments {
+		entry := queueEntryResponse{
+			Position:    i + 1,
+			Appointment: formatAppointmentResponse(appt, loc),
+		}
+		if appt.CheckedInAt != nil {
+			entry.WaitingMinutes = int(now.Sub(*appt.CheckedInAt).Minutes())
+		}
+		items = append(items, entry)
+	}
+	c.JSON(http.StatusOK, checkInQueueResponse{Items: items, Waiting: len(items)})
+}
+
+// ListAppointments godoc
+// @Summary List appointments
+// @Description List appointments across doctors and patients for schedule views. Each filter takes comma-separated values and matches any of them, except tags, which must all match; filters combine with AND. Dates are days in the requester's timezone, with to inclusive, or RFC3339 times.
+// @Tags appointments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param status query string false "Statuses, e.g. pending,confirmed"
+// @Param type query string false "Appointment type IDs"
+// @Param modality query string false "Modalities: in_person, telehealth"
+// @Param doctor_id query string false "Doctor IDs (UUID)"
+// @Param patient_id query string false "Patient IDs (UUID)"
+// @Param from query string false "Earliest scheduled start (YYYY-MM-DD or RFC3339)"
+// @Param to query string false "Latest scheduled day (YYYY-MM-DD) or exclusive end time (RFC3339)"
+// @Param patient_name query string false "Part of the patient's name, at least 2 characters"
+// @Param tag query string false "Tags the appointment must all carry"
+// @Param metadata[key] query string false "Metadata value the appointment must have for key, e.g. metadata[referral_source]=gp"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(10)
+// @Param fields query string false "Comma-separated fields to return, e.g. scheduled_start,status; id is always included"
+// @Param sort query string false "Comma-separated sort keys, - for descending: scheduled_start, scheduled_end, status, created_at, updated_at, patient_name, doctor_name" default(scheduled_start)
+// @Success 200 {object} paginatedAppointmentsResponse "Appointments"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /appointments [get]
+func (h *AppointmentHandler) ListAppointments(c *gin.Context) {
+	filter := service.AppointmentFilter{
+		Statuses:    splitQuery(c, "status"),
+		Modalities:  splitQuery(c, "modality"),
+		PatientName: c.Query("patient_name"),
+		Tags:        splitQuery(c, "tag"),
+		Metadata:    c.QueryMap("metadata"),
+	}
+	for _, raw := range splitQuery(c, "type") {
+		typeID, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid appointment type ID"})
+			return
+		}
+		filter.AppointmentTypeIDs = append(filter.AppointmentTypeIDs, uint(typeID))
+	}
+
+	// Resolve the public doctor and patient IDs
+	var err error
+	if filter.DoctorIDs, err = h.resolveQueryIDs(c, "doctor_id", model.ResourceDoctor); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if filter.PatientIDs, err = h.resolveQueryIDs(c, "patient_id", model.ResourcePatient); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Interpret days in the requester's timezone
+	loc := requestLocation(c)
+	if raw := c.Query("from"); raw != "" {
+		from, err := parseQueryTime(raw, loc, false)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from, expected YYYY-MM-DD or RFC3339"})
+			return
+		}
+		filter.From = &from
+	}
+	if raw := c.Query("to"); raw != "" {
+		to, err := parseQueryTime(raw, loc, true)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to, expected YYYY-MM-DD or RFC3339"})
+			return
+		}
+		filter.To = &to
+	}
+
+	page, pageSize := h.getPaginationParams(c)
+	query := parseListQuery(c)
+	appointments, totalCount, err := h.appointmentService.ListAppointments(c.Request.Context(), filter, page, pageSize, query)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidFilter) || isListQueryError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.Error("Failed to list appointments", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get appointments"})
+		return
+	}
+
+	h.respondAppointmentPage(c, appointments, totalCount, page, pageSize, query.Fields)
+}
+
+// resolveQueryIDs resolves the comma-separated public IDs of a query parameter
+func (h *AppointmentHandler) resolveQueryIDs(c *gin.Context, name string, resource model.PublicResource) ([]uint, error) {
+	var ids []uint
+	for _, publicID := range splitQuery(c, name) {
+		id, err := h.publicIDs.ResolveID(c.Request.Context(), resource, publicID)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// parseQueryTime parses an RFC3339 time or a YYYY-MM-DD day in loc. A day used as the end of a
+// range runs to the start of the next day, so the whole day is included.
+func parseQueryTime(raw string, loc *time.Location, end bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	day, err := time.ParseInLocation("2006-01-02", raw, loc)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if end {
+		day = day.AddDate(0, 0, 1)
+	}
+	return day, nil
 }
 
 // GetPatientAppointments godoc
@@ -126,17 +662,19 @@ func (h *AppointmentHandler) GetAppointmentByID(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Security BearerAuth
-// @Param patient_id path int true "Patient ID"
+// @Param patientID path string true "Patient ID (UUID)"
 // @Param page query int false "Page number" default(1)
 // @Param page_size query int false "Page size" default(10)
+// @Param fields query string false "Comma-separated fields to return, e.g. scheduled_start,status; id is always included"
+// @Param sort query string false "Comma-separated sort keys, - for descending: scheduled_start, scheduled_end, status, created_at, updated_at, patient_name, doctor_name" default(-scheduled_start)
 // @Success 200 {object} paginatedAppointmentsResponse "Patient appointments"
 // @Failure 400 {object} map[string]string "Bad request"
 // @Failure 401 {object} map[string]string "Unauthorized"
 // @Failure 500 {object} map[string]string "Internal server error"
-// @Router /patients/{patient_id}/appointments [get]
+// @Router /appointments/patient/{patientID} [get]
 func (h *AppointmentHandler) GetPatientAppointments(c *gin.Context) {
 	// Parse patient ID
-	patientIDStr := c.Param("patient_id")
+	patientIDStr := c.Param("patientID")
 	patientID, err := strconv.ParseUint(patientIDStr, 10, 32)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient ID"})
@@ -147,247 +685,792 @@ func (h *AppointmentHandler) GetPatientAppointments(c *gin.Context) {
 	page, pageSize := h.getPaginationParams(c)
 
 	// Get appointments
-	appointments, totalCount, err := h.appointmentService.GetPatientAppointments(c.Request.Context(), uint(patientID), page, pageSize)
+	query := parseListQuery(c)
+	appointments, totalCount, err := h.appointmentService.GetPatientAppointments(c.Request.Context(), uint(patientID), page, pageSize, query)
 	if err != nil {
+		if isListQueryError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		h.logger.Error("Failed to get patient appointments", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get appointments"})
 		return
 	}
 
-	// Format response
-	responseItems := make([]appointmentResponse, 0, len(appointments))
-	for _, appt := range appointments {
-		responseItems = append(responseItems, formatAppointmentResponse(appt))
+	h.respondAppointmentPage(c, appointments, totalCount, page, pageSize, query.Fields)
+}
+
+// GetPatientNoShows godoc
+// @Summary Get patient no-show counts
+// @Description Count the patient's appointments that have started, and how many of them were completed, cancelled or missed. Appointments nobody completed or cancelled are marked as no-shows shortly after they end.
+// @Tags appointments,patients
+// @Produce json
+// @Security BearerAuth
+// @Param patientID path string true "Patient ID (UUID)"
+// @Success 200 {object} patientNoShowsResponse "No-show counts"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Patient not found"
+// @Router /appointments/patient/{patientID}/no-shows [get]
+func (h *AppointmentHandler) GetPatientNoShows(c *gin.Context) {
+	patientID, err := strconv.ParseUint(c.Param("patientID"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+		return
+	}
+
+	summary, err := h.noShowService.GetPatientNoShows(c.Request.Context(), uint(patientID))
+	if err != nil {
+		if err.Error() == "patient not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.Error("Failed to count patient no-shows", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to count patient no-shows"})
+		return
+	}
+
+	c.JSON(http.StatusOK, patientNoShowsResponse{
+		Appointments: summary.Appointments,
+		Completed:    summary.Completed,
+		Cancelled:    summary.Cancelled,
+		NoShows:      summary.NoShows,
+	})
+}
+
+// GetDoctorAppointments godoc
+// @Summary Get doctor appointments
+// @Description Get appointments for the specified doctor
+// @Tags appointments,doctors
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param doctorID path string true "Doctor ID (UUID)"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(10)
+// @Param fields query string false "Comma-separated fields to return, e.g. scheduled_start,status; id is always included"
+// @Param sort query string false "Comma-separated sort keys, - for descending: scheduled_start, scheduled_end, status, created_at, updated_at, patient_name, doctor_name" default(-scheduled_start)
+// @Success 200 {object} paginatedAppointmentsResponse "Doctor appointments"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /appointments/doctor/{doctorID} [get]
+func (h *AppointmentHandler) GetDoctorAppointments(c *gin.Context) {
+	// Parse doctor ID
+	doctorIDStr := c.Param("doctorID")
+	doctorID, err := strconv.ParseUint(doctorIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid doctor ID"})
+		return
+	}
+
+	// Parse pagination params
+	page, pageSize := h.getPaginationParams(c)
+
+	// Get appointments
+	query := parseListQuery(c)
+	appointments, totalCount, err := h.appointmentService.GetDoctorAppointments(c.Request.Context(), uint(doctorID), page, pageSize, query)
+	if err != nil {
+		if isListQueryError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.Error("Failed to get doctor appointments", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get appointments"})
+		return
+	}
+
+	h.respondAppointmentPage(c, appointments, totalCount, page, pageSize, query.Fields)
+}
+
+// respondAppointmentPage writes a page of appointments, reduced to the selected fields if any
+func (h *AppointmentHandler) respondAppointmentPage(c *gin.Context, appointments []*model.Appointment, totalCount int64, page, pageSize int, fields []string) {
+	responseItems := make([]appointmentResponse, 0, len(appointments))
+	loc := requestLocation(c)
+	for _, appt := range appointments {
+		responseItems = append(responseItems, formatAppointmentResponse(appt, loc))
+	}
+
+	if fields == nil {
+		c.JSON(http.StatusOK, paginatedAppointmentsResponse{
+			Items:      responseItems,
+			TotalCount: totalCount,
+			Page:       page,
+			PageSize:   pageSize,
+		})
+		return
+	}
+
+	items, err := sparseItems(responseItems, fields)
+	if err != nil {
+		h.logger.Error("Failed to select appointment fields", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get appointments"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"items":       items,
+		"total_count": totalCount,
+		"page":        page,
+		"page_size":   pageSize,
+	})
+}
+
+// GetDoctorSchedule godoc
+// @Summary Get doctor schedule
+// @Description Get doctor's schedule for a date range
+// @Tags appointments,doctors
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param doctorID path string true "Doctor ID (UUID)"
+// @Param start_date query string false "Start date (RFC3339 format)"
+// @Param end_date query string false "End date (RFC3339 format)"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(10)
+// @Success 200 {object} paginatedAppointmentsResponse "Doctor schedule"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /appointments/doctor/{doctorID}/schedule [get]
+func (h *AppointmentHandler) GetDoctorSchedule(c *gin.Context) {
+	// Parse doctor ID
+	doctorIDStr := c.Param("doctorID")
+	doctorID, err := strconv.ParseUint(doctorIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid doctor ID"})
+		return
+	}
+
+	// Get date range params
+	startDate := c.Query("start_date")
+	endDate := c.Query("end_date")
+
+	// Parse pagination params
+	page, pageSize := h.getPaginationParams(c)
+
+	// Get appointments
+	appointments, totalCount, err := h.appointmentService.GetDoctorAppointmentsByDateRange(
+		c.Request.Context(),
+		uint(doctorID),
+		startDate,
+		endDate,
+		page,
+		pageSize,
+	)
+	if err != nil {
+		h.logger.Error("Failed to get doctor schedule", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get schedule"})
+		return
+	}
+
+	// Format response
+	responseItems := make([]appointmentResponse, 0, len(appointments))
+	loc := requestLocation(c)
+	for _, appt := range appointments {
+		responseItems = append(responseItems, formatAppointmentResponse(appt, loc))
+	}
+
+	c.JSON(http.StatusOK, paginatedAppointmentsResponse{
+		Items:      responseItems,
+		TotalCount: totalCount,
+		Page:       page,
+		PageSize:   pageSize,
+	})
+}
+
+// GetDoctorDaySheet godoc
+// @Summary Get doctor day sheet
+// @Description Download a printable PDF of a doctor's appointments for one day, in the requester's timezone. Doctors can only download their own; admins and front desk staff any doctor's.
+// @Tags appointments,doctors
+// @Produce application/pdf
+// @Security BearerAuth
+// @Param doctorID path string true "Doctor ID (UUID)"
+// @Param date query string false "Day (YYYY-MM-DD), defaults to today"
+// @Success 200 {file} file "Day sheet PDF"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Doctor not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /appointments/doctor/{doctorID}/day-sheet [get]
+func (h *AppointmentHandler) GetDoctorDaySheet(c *gin.Context) {
+	// Parse doctor ID
+	doctorID, err := strconv.ParseUint(c.Param("doctorID"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid doctor ID"})
+		return
+	}
+
+	// Interpret the date in the requester's timezone
+	loc := requestLocation(c)
+	day := time.Now().In(loc)
+	if date := c.Query("date"); date != "" {
+		day, err = time.ParseInLocation("2006-01-02", date, loc)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format, expected YYYY-MM-DD"})
+			return
+		}
+	}
+
+	role, _ := c.Get("userRole")
+	userRole, _ := role.(model.Role)
+	frontDesk := userRole == model.RoleAdmin || hasPermission(c, model.PermissionAppointmentsBook)
+
+	sheet, err := h.appointmentService.GenerateDaySheet(c.Request.Context(), c.GetUint("userID"), frontDesk, uint(doctorID), day)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrNotOwnDaySheet):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case err.Error() == "doctor not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			h.logger.Error("Failed to generate day sheet", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate day sheet"})
+		}
+		return
+	}
+
+	filename := fmt.Sprintf("day-sheet-%s.pdf", day.Format("2006-01-02"))
+	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=%q", filename))
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "application/pdf", sheet)
+}
+
+// GetConfirmationLetter godoc
+// @Summary Get appointment confirmation letter
+// @Description Download a printable PDF confirming an appointment, with directions to the clinic and preparation instructions for the appointment type. The same letter is attached to booking and reschedule emails.
+// @Tags appointments
+// @Produce application/pdf
+// @Security BearerAuth
+// @Param id path string true "Appointment ID (UUID)"
+// @Success 200 {file} file "Confirmation letter PDF"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Router /appointments/{id}/confirmation-letter [get]
+func (h *AppointmentHandler) GetConfirmationLetter(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid appointment ID"})
+		return
+	}
+
+	letter, err := h.appointmentService.GenerateConfirmationLetter(c.Request.Context(), uint(id))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="appointment-confirmation.pdf"`)
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "application/pdf", letter)
+}
+
+// UpdateAppointment godoc
+// @Summary Update appointment
+// @Description Update an existing appointment
+// @Tags appointments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Appointment ID (UUID)"
+// @Param appointment body updateAppointmentRequest true "Appointment Details"
+// @Success 200 {object} map[string]string "Appointment updated successfully"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "Not found"
+// @Failure 409 {object} map[string]string "Doctor or patient already booked, intake checklist incomplete, or status cannot change as requested"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /appointments/{id} [put]
+func (h *AppointmentHandler) UpdateAppointment(c *gin.Context) {
+	// Parse appointment ID
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid appointment ID"})
+		return
+	}
+
+	var req updateAppointmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	// Extract date and time if provided
+	var date, timeStr string
+	if req.ScheduledStart != "" {
+		loc, err := inputLocation(c, req.Timezone)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid timezone"})
+			return
+		}
+		startTime, err := parseRequestTime(req.ScheduledStart, loc)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scheduled start time format"})
+			return
+		}
+		date = startTime.Format("2006-01-02")
+		timeStr = startTime.Format("15:04")
+	}
+
+	// Update appointment
+	appointment, err := h.appointmentService.UpdateAppointment(
+		c.Request.Context(),
+		uint(id),
+		date,
+		timeStr,
+		req.Status,
+		req.Reason,
+	)
+	if err != nil {
+		if errors.Is(err, service.ErrScheduleConflict) || errors.Is(err, service.ErrChecklistIncomplete) ||
+			errors.Is(err, service.ErrRescheduleLimit) || errors.Is(err, service.ErrInvalidTransition) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.Error("Failed to update appointment", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Appointment updated successfully",
+		"id":      appointment.PublicID,
+	})
+}
+
+// RescheduleAppointment godoc
+// @Summary Reschedule appointment
+// @Description Move a pending or confirmed appointment to a new time, keeping its length. The new time is checked like a new booking, the move is recorded in the appointment's history and both the patient and the doctor are emailed. Each clinic limits how many times one appointment can be rescheduled.
+// @Tags appointments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Appointment ID (UUID)"
+// @Param reschedule body rescheduleAppointmentRequest true "New time"
+// @Success 200 {object} appointmentResponse "Rescheduled appointment"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 409 {object} map[string]string "Doctor or patient already booked, or reschedule limit reached"
+// @Router /appointments/{id}/reschedule [post]
+func (h *AppointmentHandler) RescheduleAppointment(c *gin.Context) {
+	h.rescheduleAppointment(c, false)
+}
+
+// RescheduleOnBehalf godoc
+// @Summary Reschedule an appointment on a patient's behalf
+// @Description Move a patient's pending or confirmed appointment as clinic staff. The move is checked like one the patient makes, recorded in the appointment's history as made by the staff member, and audit logged. Requires appointments:book.
+// @Tags front-desk,appointments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Appointment ID (UUID)"
+// @Param reschedule body rescheduleAppointmentRequest true "New time"
+// @Success 200 {object} appointmentResponse "Rescheduled appointment"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 409 {object} map[string]string "Doctor or patient already booked, or reschedule limit reached"
+// @Router /front-desk/appointments/{id}/reschedule [post]
+func (h *AppointmentHandler) RescheduleOnBehalf(c *gin.Context) {
+	h.rescheduleAppointment(c, true)
+}
+
+// rescheduleAppointment moves an appointment to the time in the request body, for the caller
+// or, with onBehalf, for the patient by a staff member
+func (h *AppointmentHandler) rescheduleAppointment(c *gin.Context, onBehalf bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid appointment ID"})
+		return
+	}
+
+	var req rescheduleAppointmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	loc, err := inputLocation(c, req.Timezone)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid timezone"})
+		return
+	}
+	startTime, err := parseRequestTime(req.ScheduledStart, loc)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scheduled start time format"})
+		return
+	}
+
+	reschedule := h.appointmentService.RescheduleAppointment
+	if onBehalf {
+		reschedule = h.appointmentService.RescheduleOnBehalf
+	}
+	appointment, err := reschedule(
+		c.Request.Context(),
+		uint(id),
+		c.GetUint("userID"),
+		startTime.Format("2006-01-02"),
+		startTime.Format("15:04"),
+		req.Reason,
+	)
+	if err != nil {
+		if errors.Is(err, service.ErrScheduleConflict) || errors.Is(err, service.ErrRescheduleLimit) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, formatAppointmentResponse(appointment, requestLocation(c)))
+}
+
+// GetAppointmentHistory godoc
+// @Summary Get appointment reschedule history
+// @Description List every time an appointment was moved, oldest first, with the previous and new times, the reason and who moved it
+// @Tags appointments
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Appointment ID (UUID)"
+// @Success 200 {array} appointmentHistoryResponse "Reschedule history"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /appointments/{id}/history [get]
+func (h *AppointmentHandler) GetAppointmentHistory(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid appointment ID"})
+		return
+	}
+
+	history, err := h.appointmentService.GetAppointmentHistory(c.Request.Context(), uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	loc := requestLocation(c)
+	response := make([]appointmentHistoryResponse, 0, len(history))
+	for _, entry := range history {
+		item := appointmentHistoryResponse{
+			PreviousStart: entry.PreviousStart.In(loc).Format(time.RFC3339),
+			PreviousEnd:   entry.PreviousEnd.In(loc).Format(time.RFC3339),
+			NewStart:      entry.NewStart.In(loc).Format(time.RFC3339),
+			NewEnd:        entry.NewEnd.In(loc).Format(time.RFC3339),
+			Reason:        entry.Reason,
+			ChangedAt:     entry.CreatedAt.In(loc).Format(time.RFC3339),
+		}
+		if entry.ChangedBy != nil {
+			item.ChangedBy = entry.ChangedBy.Name
+		}
+		response = append(response, item)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// SubmitIntake godoc
+// @Summary Submit intake form
+// @Description Answer the intake form of an appointment booked without one. Clinics that require the intake form keep the appointment from being confirmed until it is answered.
+// @Tags appointments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Appointment ID (UUID)"
+// @Param intake body submitIntakeRequest true "Intake answers"
+// @Success 200 {object} appointmentResponse "Updated appointment"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Router /appointments/{id}/intake [put]
+func (h *AppointmentHandler) SubmitIntake(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid appointment ID"})
+		return
+	}
+
+	var req submitIntakeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	appointment, err := h.appointmentService.SubmitIntake(c.Request.Context(), uint(id), req.IntakeAnswers)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
-	c.JSON(http.StatusOK, paginatedAppointmentsResponse{
-		Items:      responseItems,
-		TotalCount: totalCount,
-		Page:       page,
-		PageSize:   pageSize,
-	})
+	c.JSON(http.StatusOK, formatAppointmentResponse(appointment, requestLocation(c)))
 }
 
-// GetDoctorAppointments godoc
-// @Summary Get doctor appointments
-// @Description Get appointments for the specified doctor
-// @Tags appointments,doctors
+// SetChecklistItem godoc
+// @Summary Update intake checklist item
+// @Description Check an item of an appointment's intake checklist off, such as verified insurance or a paid deposit, or reopen it
+// @Tags appointments
 // @Accept json
 // @Produce json
 // @Security BearerAuth
-// @Param doctor_id path int true "Doctor ID"
-// @Param page query int false "Page number" default(1)
-// @Param page_size query int false "Page size" default(10)
-// @Success 200 {object} paginatedAppointmentsResponse "Doctor appointments"
+// @Param id path string true "Appointment ID (UUID)"
+// @Param item path string true "Requirement (intake_form, insurance_verified or deposit_paid)"
+// @Param item body checklistItemRequest true "Item state"
+// @Success 200 {object} appointmentResponse "Updated appointment"
 // @Failure 400 {object} map[string]string "Bad request"
 // @Failure 401 {object} map[string]string "Unauthorized"
-// @Failure 500 {object} map[string]string "Internal server error"
-// @Router /doctors/{doctor_id}/appointments [get]
-func (h *AppointmentHandler) GetDoctorAppointments(c *gin.Context) {
-	// Parse doctor ID
-	doctorIDStr := c.Param("doctor_id")
-	doctorID, err := strconv.ParseUint(doctorIDStr, 10, 32)
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Router /appointments/{id}/checklist/{item} [put]
+func (h *AppointmentHandler) SetChecklistItem(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid doctor ID"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid appointment ID"})
 		return
 	}
 
-	// Parse pagination params
-	page, pageSize := h.getPaginationParams(c)
-
-	// Get appointments
-	appointments, totalCount, err := h.appointmentService.GetDoctorAppointments(c.Request.Context(), uint(doctorID), page, pageSize)
-	if err != nil {
-		h.logger.Error("Failed to get doctor appointments", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get appointments"})
+	var req checklistItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
 		return
 	}
 
-	// Format response
-	responseItems := make([]appointmentResponse, 0, len(appointments))
-	for _, appt := range appointments {
-		responseItems = append(responseItems, formatAppointmentResponse(appt))
+	appointment, err := h.appointmentService.SetChecklistItem(
+		c.Request.Context(),
+		uint(id),
+		c.GetUint("userID"),
+		model.IntakeRequirement(c.Param("item")),
+		*req.Done,
+	)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
-	c.JSON(http.StatusOK, paginatedAppointmentsResponse{
-		Items:      responseItems,
-		TotalCount: totalCount,
-		Page:       page,
-		PageSize:   pageSize,
-	})
+	c.JSON(http.StatusOK, formatAppointmentResponse(appointment, requestLocation(c)))
 }
 
-// GetDoctorSchedule godoc
-// @Summary Get doctor schedule
-// @Description Get doctor's schedule for a date range
-// @Tags appointments,doctors
+// SetTags godoc
+// @Summary Set appointment tags
+// @Description Replace the free-form tags of an appointment. Tags are trimmed, lowercased and deduplicated; at most 20 of up to 50 characters each.
+// @Tags appointments
 // @Accept json
 // @Produce json
 // @Security BearerAuth
-// @Param doctor_id path int true "Doctor ID"
-// @Param start_date query string false "Start date (RFC3339 format)"
-// @Param end_date query string false "End date (RFC3339 format)"
-// @Param page query int false "Page number" default(1)
-// @Param page_size query int false "Page size" default(10)
-// @Success 200 {object} paginatedAppointmentsResponse "Doctor schedule"
+// @Param id path string true "Appointment ID (UUID)"
+// @Param tags body appointmentTagsRequest true "Tags"
+// @Success 200 {object} appointmentResponse "Updated appointment"
 // @Failure 400 {object} map[string]string "Bad request"
 // @Failure 401 {object} map[string]string "Unauthorized"
-// @Failure 500 {object} map[string]string "Internal server error"
-// @Router /doctors/{doctor_id}/schedule [get]
-func (h *AppointmentHandler) GetDoctorSchedule(c *gin.Context) {
-	// Parse doctor ID
-	doctorIDStr := c.Param("doctor_id")
-	doctorID, err := strconv.ParseUint(doctorIDStr, 10, 32)
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /appointments/{id}/tags [put]
+func (h *AppointmentHandler) SetTags(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid doctor ID"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid appointment ID"})
 		return
 	}
 
-	// Get date range params
-	startDate := c.Query("start_date")
-	endDate := c.Query("end_date")
-
-	// Parse pagination params
-	page, pageSize := h.getPaginationParams(c)
-
-	// Get appointments
-	appointments, totalCount, err := h.appointmentService.GetDoctorAppointmentsByDateRange(
-		c.Request.Context(),
-		uint(doctorID),
-		startDate,
-		endDate,
-		page,
-		pageSize,
-	)
-	if err != nil {
-		h.logger.Error("Failed to get doctor schedule", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get schedule"})
+	var req appointmentTagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
 		return
 	}
 
-	// Format response
-	responseItems := make([]appointmentResponse, 0, len(appointments))
-	for _, appt := range appointments {
-		responseItems = append(responseItems, formatAppointmentResponse(appt))
+	appointment, err := h.appointmentService.SetTags(c.Request.Context(), uint(id), req.Tags)
+	if err != nil {
+		h.attributesError(c, err)
+		return
 	}
 
-	c.JSON(http.StatusOK, paginatedAppointmentsResponse{
-		Items:      responseItems,
-		TotalCount: totalCount,
-		Page:       page,
-		PageSize:   pageSize,
-	})
+	c.JSON(http.StatusOK, formatAppointmentResponse(appointment, requestLocation(c)))
 }
 
-// UpdateAppointment godoc
-// @Summary Update appointment
-// @Description Update an existing appointment
+// SetMetadata godoc
+// @Summary Set appointment metadata
+// @Description Set values of an appointment's metadata. Keys must be among the appointment metadata keys of the doctor's clinic; an empty value removes its key, and keys left out keep their values.
 // @Tags appointments
 // @Accept json
 // @Produce json
 // @Security BearerAuth
-// @Param id path int true "Appointment ID"
-// @Param appointment body updateAppointmentRequest true "Appointment Details"
-// @Success 200 {object} map[string]string "Appointment updated successfully"
+// @Param id path string true "Appointment ID (UUID)"
+// @Param metadata body appointmentMetadataRequest true "Metadata values"
+// @Success 200 {object} appointmentResponse "Updated appointment"
 // @Failure 400 {object} map[string]string "Bad request"
 // @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
 // @Failure 404 {object} map[string]string "Not found"
-// @Failure 500 {object} map[string]string "Internal server error"
-// @Router /appointments/{id} [put]
-func (h *AppointmentHandler) UpdateAppointment(c *gin.Context) {
-	// Parse appointment ID
-	idStr := c.Param("id")
-	id, err := strconv.ParseUint(idStr, 10, 32)
+// @Router /appointments/{id}/metadata [put]
+func (h *AppointmentHandler) SetMetadata(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid appointment ID"})
 		return
 	}
 
-	var req updateAppointmentRequest
+	var req appointmentMetadataRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
 		return
 	}
 
-	// Extract date and time if provided
-	var date, timeStr string
-	if req.ScheduledStart != "" {
-		startTime, err := time.Parse(time.RFC3339, req.ScheduledStart)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scheduled start time format"})
-			return
-		}
-		date = startTime.Format("2006-01-02")
-		timeStr = startTime.Format("15:04")
-	}
-
-	// Update appointment
-	appointment, err := h.appointmentService.UpdateAppointment(
-		c.Request.Context(),
-		uint(id),
-		date,
-		timeStr,
-		req.Status,
-		req.Reason,
-	)
+	appointment, err := h.appointmentService.SetMetadata(c.Request.Context(), uint(id), req.Metadata)
 	if err != nil {
-		h.logger.Error("Failed to update appointment", zap.Error(err))
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		h.attributesError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Appointment updated successfully",
-		"id":      appointment.ID,
-	})
+	c.JSON(http.StatusOK, formatAppointmentResponse(appointment, requestLocation(c)))
+}
+
+// attributesError writes the response for a failed tag or metadata update
+func (h *AppointmentHandler) attributesError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidTags), errors.Is(err, service.ErrInvalidMetadata):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case err.Error() == "appointment not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		h.logger.Error("Failed to update appointment", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update appointment"})
+	}
 }
 
 // CancelAppointment godoc
 // @Summary Cancel appointment
-// @Description Cancel an existing appointment
+// @Description Cancel an existing appointment under its cancellation policy. Within the cutoff the cancellation is refused with code cancellation_cutoff_passed, unless the clinic charges for late cancellations, in which case it is accepted and flagged with late_cancellation. Errors carry a code and, for the cutoff, the policy to show the patient.
 // @Tags appointments
 // @Accept json
 // @Produce json
 // @Security BearerAuth
-// @Param id path int true "Appointment ID"
-// @Success 200 {object} map[string]string "Appointment cancelled successfully"
-// @Failure 400 {object} map[string]string "Bad request"
+// @Param id path string true "Appointment ID (UUID)"
+// @Success 200 {object} cancellationResponse "Appointment cancelled"
+// @Failure 400 {object} cancellationErrorResponse "Bad request"
 // @Failure 401 {object} map[string]string "Unauthorized"
-// @Failure 404 {object} map[string]string "Not found"
-// @Failure 500 {object} map[string]string "Internal server error"
+// @Failure 404 {object} cancellationErrorResponse "Not found"
+// @Failure 409 {object} cancellationErrorResponse "Cutoff passed or appointment not cancellable"
+// @Failure 500 {object} cancellationErrorResponse "Internal server error"
 // @Router /appointments/{id}/cancel [post]
 func (h *AppointmentHandler) CancelAppointment(c *gin.Context) {
+	h.cancelAppointment(c, false)
+}
+
+// CancelOnBehalf godoc
+// @Summary Cancel an appointment on a patient's behalf
+// @Description Cancel a patient's appointment as clinic staff, under the same cancellation policy as the patient. The cancellation and its optional reason are audit logged. Requires appointments:book.
+// @Tags front-desk,appointments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Appointment ID (UUID)"
+// @Param request body cancelOnBehalfRequest false "Reason for the cancellation"
+// @Success 200 {object} cancellationResponse "Appointment cancelled"
+// @Failure 400 {object} cancellationErrorResponse "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} cancellationErrorResponse "Not found"
+// @Failure 409 {object} cancellationErrorResponse "Cutoff passed or appointment not cancellable"
+// @Failure 500 {object} cancellationErrorResponse "Internal server error"
+// @Router /front-desk/appointments/{id}/cancel [post]
+func (h *AppointmentHandler) CancelOnBehalf(c *gin.Context) {
+	h.cancelAppointment(c, true)
+}
+
+// cancelAppointment cancels an appointment for the caller or, with onBehalf, for the patient by
+// a staff member
+func (h *AppointmentHandler) cancelAppointment(c *gin.Context, onBehalf bool) {
 	// Parse appointment ID
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid appointment ID"})
+		c.JSON(http.StatusBadRequest, cancellationErrorResponse{Error: "Invalid appointment ID", Code: "invalid_appointment_id"})
 		return
 	}
 
 	// Cancel appointment
-	if err := h.appointmentService.CancelAppointment(c.Request.Context(), uint(id)); err != nil {
-		h.logger.Error("Failed to cancel appointment", zap.Error(err))
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	var policy *service.CancellationPolicy
+	if onBehalf {
+		var req cancelOnBehalfRequest
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				c.JSON(http.StatusBadRequest, cancellationErrorResponse{Error: "Invalid request format", Code: "invalid_request"})
+				return
+			}
+		}
+		policy, err = h.appointmentService.CancelOnBehalf(c.Request.Context(), uint(id), c.GetUint("userID"), req.Reason)
+	} else {
+		policy, err = h.appointmentService.CancelAppointment(c.Request.Context(), uint(id))
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrCancellationCutoff):
+			response := toCancellationPolicyResponse(policy, requestLocation(c))
+			c.JSON(http.StatusConflict, cancellationErrorResponse{Error: err.Error(), Code: "cancellation_cutoff_passed", Policy: &response})
+		case errors.Is(err, service.ErrInvalidTransition):
+			c.JSON(http.StatusConflict, cancellationErrorResponse{Error: err.Error(), Code: "appointment_not_cancellable"})
+		case err.Error() == "appointment not found":
+			c.JSON(http.StatusNotFound, cancellationErrorResponse{Error: err.Error(), Code: "appointment_not_found"})
+		default:
+			h.logger.Error("Failed to cancel appointment", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, cancellationErrorResponse{Error: "failed to cancel appointment", Code: "internal_error"})
+		}
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Appointment cancelled successfully"})
+	c.JSON(http.StatusOK, cancellationResponse{
+		Message:          "Appointment cancelled successfully",
+		LateCancellation: policy.Late,
+		Policy:           toCancellationPolicyResponse(policy, requestLocation(c)),
+	})
+}
+
+// GetCancellationPolicy godoc
+// @Summary Get cancellation policy
+// @Description Get the cancellation policy of an appointment: the cutoff, the last time it can be cancelled on time, whether late cancellations are charged instead of refused, and whether cancelling now would be late
+// @Tags appointments
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Appointment ID (UUID)"
+// @Success 200 {object} cancellationPolicyResponse "Cancellation policy"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /appointments/{id}/cancellation-policy [get]
+func (h *AppointmentHandler) GetCancellationPolicy(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid appointment ID"})
+		return
+	}
+
+	policy, err := h.appointmentService.GetCancellationPolicy(c.Request.Context(), uint(id))
+	if err != nil {
+		if err.Error() == "appointment not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.Error("Failed to get cancellation policy", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get cancellation policy"})
+		return
+	}
+
+	c.JSON(http.StatusOK, toCancellationPolicyResponse(policy, requestLocation(c)))
 }
 
 // CompleteAppointment godoc
 // @Summary Complete appointment
-// @Description Mark an appointment as completed
+// @Description Mark a confirmed appointment, or one marked as a no-show, as completed
 // @Tags appointments
 // @Accept json
 // @Produce json
 // @Security BearerAuth
-// @Param id path int true "Appointment ID"
+// @Param id path string true "Appointment ID (UUID)"
 // @Param data body completeAppointmentRequest true "Completion Details"
 // @Success 200 {object} map[string]string "Appointment completed successfully"
 // @Failure 400 {object} map[string]string "Bad request"
 // @Failure 401 {object} map[string]string "Unauthorized"
 // @Failure 404 {object} map[string]string "Not found"
+// @Failure 409 {object} map[string]string "Appointment was not confirmed"
 // @Failure 500 {object} map[string]string "Internal server error"
 // @Router /appointments/{id}/complete [post]
 func (h *AppointmentHandler) CompleteAppointment(c *gin.Context) {
@@ -407,6 +1490,10 @@ func (h *AppointmentHandler) CompleteAppointment(c *gin.Context) {
 
 	// Call the dedicated CompleteAppointment service method
 	if err := h.appointmentService.CompleteAppointment(c.Request.Context(), uint(id), req.Notes); err != nil {
+		if errors.Is(err, service.ErrInvalidTransition) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 		h.logger.Error("Failed to complete appointment", zap.Error(err))
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -441,7 +1528,44 @@ func (h *AppointmentHandler) getPaginationParams(c *gin.Context) (page, pageSize
 	return page, pageSize
 }
 
-func formatAppointmentResponse(appointment *model.Appointment) appointmentResponse {
+// requestLocation returns the preferred timezone of the authenticated user, used to format times
+func requestLocation(c *gin.Context) *time.Location {
+	if value, exists := c.Get("user"); exists {
+		if user, ok := value.(*model.User); ok {
+			return utils.LoadLocation(user.Timezone)
+		}
+	}
+	return time.UTC
+}
+
+// inputLocation returns the timezone request times without an offset are read in: the request's
+// timezone if it names one, otherwise the authenticated user's preferred timezone
+func inputLocation(c *gin.Context, timezone string) (*time.Location, error) {
+	if timezone == "" {
+		return requestLocation(c), nil
+	}
+	if !utils.ValidTimezone(timezone) {
+		return nil, errors.New("invalid timezone")
+	}
+	return utils.LoadLocation(timezone), nil
+}
+
+// parseRequestTime parses an RFC3339 time, or a local date and time such as 2026-03-02T09:30
+// read in loc, and returns it in UTC. Clients can book the times slots are listed in without
+// working out the offset themselves, including across daylight saving changes.
+func parseRequestTime(raw string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t.UTC(), nil
+	}
+	for _, layout := range []string{"2006-01-02T15:04:05", "2006-01-02T15:04"} {
+		if t, err := time.ParseInLocation(layout, raw, loc); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q, use RFC3339 or YYYY-MM-DDTHH:MM", raw)
+}
+
+func formatAppointmentResponse(appointment *model.Appointment, loc *time.Location) appointmentResponse {
 	var patientName, doctorName string
 
 	if appointment.Patient.User.ID > 0 {
@@ -452,62 +1576,277 @@ func formatAppointmentResponse(appointment *model.Appointment) appointmentRespon
 		doctorName = appointment.Doctor.User.Name
 	}
 
+	var typeName string
+	if appointment.AppointmentType != nil {
+		typeName = appointment.AppointmentType.Name
+	}
+
+	var seriesID string
+	if appointment.Series != nil {
+		seriesID = appointment.Series.PublicID
+	}
+
+	var checkedInAt, startedAt, endedAt, attendanceConfirmedAt string
+	if appointment.CheckedInAt != nil {
+		checkedInAt = appointment.CheckedInAt.In(loc).Format(time.RFC3339)
+	}
+	if appointment.StartedAt != nil {
+		startedAt = appointment.StartedAt.In(loc).Format(time.RFC3339)
+	}
+	if appointment.EndedAt != nil {
+		endedAt = appointment.EndedAt.In(loc).Format(time.RFC3339)
+	}
+	if appointment.AttendanceConfirmedAt != nil {
+		attendanceConfirmedAt = appointment.AttendanceConfirmedAt.In(loc).Format(time.RFC3339)
+	}
+
+	var participants []participantResponse
+	for _, participant := range appointment.Participants {
+		participants = append(participants, participantResponse{
+			PatientID: participant.Patient.PublicID,
+			Name:      participant.Patient.User.Name,
+		})
+	}
+
+	var bookedBy, bookedByName string
+	if appointment.BookedBy != nil {
+		bookedBy = appointment.BookedBy.PublicID
+		bookedByName = appointment.BookedBy.Name
+	}
+
+	var checklist []checklistItemResponse
+	for _, item := range appointment.Checklist {
+		entry := checklistItemResponse{Requirement: string(item.Requirement)}
+		if item.CompletedAt != nil {
+			completedAt := item.CompletedAt.In(loc).Format(time.RFC3339)
+			entry.CompletedAt = &completedAt
+		}
+		checklist = append(checklist, entry)
+	}
+
 	return appointmentResponse{
-		ID:             appointment.ID,
-		PatientID:      appointment.PatientID,
-		PatientName:    patientName,
-		DoctorID:       appointment.DoctorID,
-		DoctorName:     doctorName,
-		ScheduledStart: appointment.ScheduledStart.Format(time.RFC3339),
-		ScheduledEnd:   appointment.ScheduledEnd.Format(time.RFC3339),
-		Status:         string(appointment.Status),
-		Type:           appointment.Type,
-		Reason:         appointment.Reason,
-		Notes:          appointment.Notes,
-		CreatedAt:      appointment.CreatedAt.Format(time.RFC3339),
-		UpdatedAt:      appointment.UpdatedAt.Format(time.RFC3339),
+		ID:                    appointment.PublicID,
+		PatientID:             appointment.Patient.PublicID,
+		PatientName:           patientName,
+		DoctorID:              appointment.Doctor.PublicID,
+		DoctorName:            doctorName,
+		Participants:          participants,
+		ActiveAllergies:       toActiveAllergies(&appointment.Patient),
+		ScheduledStart:        appointment.ScheduledStart.In(loc).Format(time.RFC3339),
+		ScheduledEnd:          appointment.ScheduledEnd.In(loc).Format(time.RFC3339),
+		Timezone:              loc.String(),
+		Status:                string(appointment.Status),
+		Modality:              string(appointment.Modality),
+		AppointmentTypeID:     appointment.AppointmentTypeID,
+		VisitReasonID:         appointment.VisitReasonID,
+		AppointmentTypeName:   typeName,
+		SeriesID:              seriesID,
+		Reason:                appointment.Reason,
+		BookedBy:              bookedBy,
+		BookedByName:          bookedByName,
+		Notes:                 appointment.Notes,
+		ConfirmationRequired:  appointment.ConfirmationRequired,
+		AttendanceConfirmedAt: attendanceConfirmedAt,
+		FollowUpRequired:      appointment.FollowUpRequired,
+		CheckedInAt:           checkedInAt,
+		StartedAt:             startedAt,
+		EndedAt:               endedAt,
+		LateCancellation:      appointment.LateCancellation,
+		Checklist:             checklist,
+		Tags:                  appointment.Tags,
+		Metadata:              appointment.Metadata,
+		CreatedAt:             appointment.CreatedAt.In(loc).Format(time.RFC3339),
+		UpdatedAt:             appointment.UpdatedAt.In(loc).Format(time.RFC3339),
 	}
 }
 
 // Request and response types
 
 type createAppointmentRequest struct {
-	PatientID      uint   `json:"patient_id" binding:"required"`
-	DoctorID       uint   `json:"doctor_id" binding:"required"`
-	ScheduledStart string `json:"scheduled_start" binding:"required"` // RFC3339 format
-	ScheduledEnd   string `json:"scheduled_end" binding:"required"`   // RFC3339 format
-	Reason         string `json:"reason"`
-	Type           string `json:"type"` // in_person, video, phone
-	Notes          string `json:"notes"`
+	PatientID         string            `json:"patient_id" binding:"required"`      // Public patient ID
+	PatientIDs        []string          `json:"patient_ids"`                        // Other patients of the family seen in the same slot
+	DoctorID          string            `json:"doctor_id" binding:"required"`       // Public doctor ID
+	ScheduledStart    string            `json:"scheduled_start" binding:"required"` // RFC3339, or a local time in timezone
+	ScheduledEnd      string            `json:"scheduled_end" binding:"required"`   // RFC3339, or a local time in timezone
+	Timezone          string            `json:"timezone"`                           // IANA timezone of local times; defaults to the user's
+	Reason            string            `json:"reason"`
+	AppointmentTypeID uint              `json:"appointment_type_id"` // Optional; see GET /doctors/{id}/appointment-types
+	VisitReasonID     uint              `json:"visit_reason_id"`     // Optional; see GET /visit-reasons
+	IntakeAnswers     map[string]string `json:"intake_answers"`      // Answers keyed by intake question key
+	Notes             string            `json:"notes"`
+}
+
+type cancellationPolicyResponse struct {
+	CutoffMinutes       int    `json:"cutoff_minutes"`        // Minutes before the start after which cancelling is late
+	CancelBy            string `json:"cancel_by"`             // Last time the appointment can be cancelled on time
+	LateCancellationFee bool   `json:"late_cancellation_fee"` // Late cancellations are accepted and charged rather than refused
+	Late                bool   `json:"late"`                  // Cancelling now is late
+}
+
+type cancellationResponse struct {
+	Message          string                     `json:"message"`
+	LateCancellation bool                       `json:"late_cancellation"`
+	Policy           cancellationPolicyResponse `json:"policy"`
+}
+
+type cancellationErrorResponse struct {
+	Error  string                      `json:"error"`
+	Code   string                      `json:"code"` // Stable error code for frontends, e.g. cancellation_cutoff_passed
+	Policy *cancellationPolicyResponse `json:"policy,omitempty"`
+}
+
+func toCancellationPolicyResponse(policy *service.CancellationPolicy, loc *time.Location) cancellationPolicyResponse {
+	return cancellationPolicyResponse{
+		CutoffMinutes:       policy.CutoffMinutes,
+		CancelBy:            policy.CancelBy.In(loc).Format(time.RFC3339),
+		LateCancellationFee: policy.LateCancellationFee,
+		Late:                policy.Late,
+	}
 }
 
 type updateAppointmentRequest struct {
-	ScheduledStart string `json:"scheduled_start,omitempty"` // RFC3339 format
-	ScheduledEnd   string `json:"scheduled_end,omitempty"`   // RFC3339 format
+	ScheduledStart string `json:"scheduled_start,omitempty"` // RFC3339, or a local time in timezone
+	ScheduledEnd   string `json:"scheduled_end,omitempty"`   // RFC3339, or a local time in timezone
+	Timezone       string `json:"timezone,omitempty"`        // IANA timezone of local times; defaults to the user's
 	Status         string `json:"status,omitempty"`
 	Reason         string `json:"reason,omitempty"`
-	Type           string `json:"type,omitempty"` // in_person, video, phone
 	Notes          string `json:"notes,omitempty"`
 }
 
+type rescheduleAppointmentRequest struct {
+	ScheduledStart string `json:"scheduled_start" binding:"required"` // RFC3339, or a local time in timezone
+	Timezone       string `json:"timezone"`                           // IANA timezone of a local time; defaults to the user's
+	Reason         string `json:"reason" binding:"max=255"`
+}
+
+type cancelOnBehalfRequest struct {
+	Reason string `json:"reason" binding:"max=255"` // Why the patient cancelled, for the audit log
+}
+
+type appointmentHistoryResponse struct {
+	PreviousStart string `json:"previous_start"`
+	PreviousEnd   string `json:"previous_end"`
+	NewStart      string `json:"new_start"`
+	NewEnd        string `json:"new_end"`
+	Reason        string `json:"reason,omitempty"`
+	ChangedBy     string `json:"changed_by,omitempty"` // Name of the user who moved the appointment
+	ChangedAt     string `json:"changed_at"`
+}
+
+type submitIntakeRequest struct {
+	IntakeAnswers map[string]string `json:"intake_answers" binding:"required"`
+}
+
+type checklistItemRequest struct {
+	Done *bool `json:"done" binding:"required"`
+}
+
+type appointmentTagsRequest struct {
+	Tags []string `json:"tags"` // Replaces the appointment's tags; empty clears them
+}
+
+type appointmentMetadataRequest struct {
+	Metadata map[string]string `json:"metadata" binding:"required"` // Values by key; an empty value removes the key
+}
+
 type completeAppointmentRequest struct {
 	Notes string `json:"notes"`
 }
 
+type batchGetRequest struct {
+	IDs []string `json:"ids" binding:"required,min=1"`
+}
+
+type confirmAppointmentRequest struct {
+	Code string `json:"code" binding:"required,len=6"`
+}
+
+type declineAppointmentRequest struct {
+	Reason string `json:"reason" binding:"required,max=255"`
+}
+
 type appointmentResponse struct {
-	ID             uint   `json:"id"`
-	PatientID      uint   `json:"patient_id"`
-	PatientName    string `json:"patient_name,omitempty"`
-	DoctorID       uint   `json:"doctor_id"`
-	DoctorName     string `json:"doctor_name,omitempty"`
-	ScheduledStart string `json:"scheduled_start"`
-	ScheduledEnd   string `json:"scheduled_end"`
-	Status         string `json:"status"`
-	Type           string `json:"type,omitempty"`
-	Reason         string `json:"reason,omitempty"`
-	Notes          string `json:"notes,omitempty"`
-	CreatedAt      string `json:"created_at"`
-	UpdatedAt      string `json:"updated_at"`
+	ID                    string                  `json:"id"`
+	PatientID             string                  `json:"patient_id"`
+	PatientName           string                  `json:"patient_name,omitempty"`
+	DoctorID              string                  `json:"doctor_id"`
+	DoctorName            string                  `json:"doctor_name,omitempty"`
+	Participants          []participantResponse   `json:"participants,omitempty"`     // Other patients seen in a group booking
+	ActiveAllergies       []activeAllergySummary  `json:"active_allergies,omitempty"` // The patient's allergies that apply, most severe first
+	ScheduledStart        string                  `json:"scheduled_start"`
+	ScheduledEnd          string                  `json:"scheduled_end"`
+	Timezone              string                  `json:"timezone"` // Timezone the times are expressed in
+	Status                string                  `json:"status"`
+	Modality              string                  `json:"modality"`
+	AppointmentTypeID     *uint                   `json:"appointment_type_id,omitempty"`
+	AppointmentTypeName   string                  `json:"appointment_type_name,omitempty"`
+	VisitReasonID         *uint                   `json:"visit_reason_id,omitempty"`
+	SeriesID              string                  `json:"series_id,omitempty"` // Recurring series the appointment belongs to
+	Reason                string                  `json:"reason,omitempty"`
+	Notes                 string                  `json:"notes,omitempty"`
+	ConfirmationRequired  bool                    `json:"confirmation_required"`
+	AttendanceConfirmedAt string                  `json:"attendance_confirmed_at,omitempty"` // When the patient confirmed they will attend
+	FollowUpRequired      bool                    `json:"follow_up_required"`                // Unconfirmed after every reminder; the front desk should call the patient
+	CheckedInAt           string                  `json:"checked_in_at,omitempty"`           // When the patient arrived
+	StartedAt             string                  `json:"started_at,omitempty"`              // When the doctor admitted the patient
+	EndedAt               string                  `json:"ended_at,omitempty"`                // When the visit ended
+	LateCancellation      bool                    `json:"late_cancellation,omitempty"`       // Cancelled within the cutoff; the clinic may charge a fee
+	Checklist             []checklistItemResponse `json:"checklist,omitempty"`               // Intake requirements to complete before confirmation
+	Tags                  []string                `json:"tags,omitempty"`
+	Metadata              map[string]string       `json:"metadata,omitempty"`     // Values of the clinic's appointment metadata keys
+	NoShowRisk            *noShowRiskResponse     `json:"no_show_risk,omitempty"` // Staff only
+	BookedBy              string                  `json:"booked_by,omitempty"`    // Staff member who booked on the patient's behalf
+	BookedByName          string                  `json:"booked_by_name,omitempty"`
+	CreatedAt             string                  `json:"created_at"`
+	UpdatedAt             string                  `json:"updated_at"`
+}
+
+type patientNoShowsResponse struct {
+	Appointments int `json:"appointments"` // Appointments that have started
+	Completed    int `json:"completed"`
+	Cancelled    int `json:"cancelled"`
+	NoShows      int `json:"no_shows"`
+}
+
+type queueEntryResponse struct {
+	Position       int                 `json:"position"` // 1 for the patient who arrived first
+	WaitingMinutes int                 `json:"waiting_minutes"`
+	Appointment    appointmentResponse `json:"appointment"`
+}
+
+type checkInQueueResponse struct {
+	Items   []queueEntryResponse `json:"items"`
+	Waiting int                  `json:"waiting"`
+}
+
+type participantResponse struct {
+	PatientID string `json:"patient_id"`
+	Name      string `json:"name,omitempty"`
+}
+
+type checklistItemResponse struct {
+	Requirement string  `json:"requirement"`
+	CompletedAt *string `json:"completed_at,omitempty"`
+}
+
+type noShowRiskResponse struct {
+	Score             float64 `json:"score"`
+	Level             string  `json:"level"` // low, medium or high
+	PastAppointments  int     `json:"past_appointments"`
+	NoShows           int     `json:"no_shows"`
+	LateCancellations int     `json:"late_cancellations"`
+	LeadTimeDays      int     `json:"lead_time_days"`
+}
+
+func toNoShowRiskResponse(risk *service.NoShowRisk) *noShowRiskResponse {
+	return &noShowRiskResponse{
+		Score:             math.Round(risk.Score*100) / 100,
+		Level:             risk.Level,
+		PastAppointments:  risk.PastAppointments,
+		NoShows:           risk.NoShows,
+		LateCancellations: risk.LateCancellations,
+		LeadTimeDays:      int(risk.LeadTime.Hours() / 24),
+	}
 }
 
 type paginatedAppointmentsResponse struct {
@@ -0,0 +1,269 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/service"
+	"go.uber.org/zap"
+)
+
+// AppointmentTypeHandler handles appointment type HTTP requests
+type AppointmentTypeHandler struct {
+	service service.AppointmentTypeService
+	logger  *zap.Logger
+}
+
+// NewAppointmentTypeHandler creates a new appointment type handler
+func NewAppointmentTypeHandler(service service.AppointmentTypeService, logger *zap.Logger) *AppointmentTypeHandler {
+	return &AppointmentTypeHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// CreateAppointmentType godoc
+// @Summary Create appointment type
+// @Description Define a bookable appointment type with its modality, duration, price, color and intake form
+// @Tags admin,appointment-types
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body appointmentTypeRequest true "Appointment type"
+// @Success 201 {object} appointmentTypeResponse "Created appointment type"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Router /admin/appointment-types [post]
+func (h *AppointmentTypeHandler) CreateAppointmentType(c *gin.Context) {
+	var req appointmentTypeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	appointmentType, err := h.service.CreateAppointmentType(c.Request.Context(), req.toModel())
+	if err != nil {
+		h.logger.Warn("Failed to create appointment type", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, toAppointmentTypeResponse(appointmentType))
+}
+
+// ListAppointmentTypes godoc
+// @Summary List appointment types
+// @Description List all appointment types, including archived ones
+// @Tags admin,appointment-types
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} appointmentTypeResponse "Appointment types"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /admin/appointment-types [get]
+func (h *AppointmentTypeHandler) ListAppointmentTypes(c *gin.Context) {
+	types, err := h.service.ListAppointmentTypes(c.Request.Context())
+	if err != nil {
+		h.logger.Error("Failed to list appointment types", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list appointment types"})
+		return
+	}
+
+	c.JSON(http.StatusOK, toAppointmentTypeResponses(types))
+}
+
+// GetAppointmentType godoc
+// @Summary Get appointment type
+// @Description Get an appointment type by ID
+// @Tags admin,appointment-types
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Appointment type ID"
+// @Success 200 {object} appointmentTypeResponse "Appointment type"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /admin/appointment-types/{id} [get]
+func (h *AppointmentTypeHandler) GetAppointmentType(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid appointment type ID"})
+		return
+	}
+
+	appointmentType, err := h.service.GetAppointmentType(c.Request.Context(), uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, toAppointmentTypeResponse(appointmentType))
+}
+
+// UpdateAppointmentType godoc
+// @Summary Update appointment type
+// @Description Replace the settings of an appointment type; existing appointments are not changed
+// @Tags admin,appointment-types
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Appointment type ID"
+// @Param request body appointmentTypeRequest true "Appointment type"
+// @Success 200 {object} appointmentTypeResponse "Updated appointment type"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Router /admin/appointment-types/{id} [put]
+func (h *AppointmentTypeHandler) UpdateAppointmentType(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid appointment type ID"})
+		return
+	}
+
+	var req appointmentTypeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	appointmentType, err := h.service.UpdateAppointmentType(c.Request.Context(), uint(id), req.toModel())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, toAppointmentTypeResponse(appointmentType))
+}
+
+// ArchiveAppointmentType godoc
+// @Summary Archive appointment type
+// @Description Stop an appointment type from being booked; appointments already using it keep it
+// @Tags admin,appointment-types
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Appointment type ID"
+// @Success 200 {object} map[string]string "Appointment type archived"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /admin/appointment-types/{id} [delete]
+func (h *AppointmentTypeHandler) ArchiveAppointmentType(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid appointment type ID"})
+		return
+	}
+
+	if err := h.service.ArchiveAppointmentType(c.Request.Context(), uint(id)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "appointment type archived"})
+}
+
+// ListDoctorAppointmentTypes godoc
+// @Summary List a doctor's appointment types
+// @Description List the appointment types that can be booked with a doctor
+// @Tags doctors,appointment-types
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Doctor ID (UUID)"
+// @Success 200 {array} appointmentTypeResponse "Appointment types"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /doctors/{id}/appointment-types [get]
+func (h *AppointmentTypeHandler) ListDoctorAppointmentTypes(c *gin.Context) {
+	doctorID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid doctor ID"})
+		return
+	}
+
+	types, err := h.service.ListDoctorAppointmentTypes(c.Request.Context(), uint(doctorID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, toAppointmentTypeResponses(types))
+}
+
+// Request and response models
+type appointmentTypeRequest struct {
+	OrganizationID *uint                  `json:"organization_id"` // Omit to offer the type at all clinics
+	Name           string                 `json:"name" binding:"required,max=100"`
+	Modality       string                 `json:"modality" binding:"required"` // in_person, video, phone
+	Duration       int                    `json:"duration" binding:"required"` // Minutes
+	PriceCents     int64                  `json:"price_cents"`
+	Currency       string                 `json:"currency"`
+	Color          string                 `json:"color"`
+	IntakeForm     []model.IntakeQuestion `json:"intake_form"`
+	Preparation    string                 `json:"preparation"` // Instructions printed on confirmation letters
+}
+
+func (r appointmentTypeRequest) toModel() *model.AppointmentType {
+	return &model.AppointmentType{
+		OrganizationID: r.OrganizationID,
+		Name:           r.Name,
+		Modality:       model.AppointmentModality(r.Modality),
+		Duration:       r.Duration,
+		PriceCents:     r.PriceCents,
+		Currency:       r.Currency,
+		Color:          r.Color,
+		IntakeForm:     r.IntakeForm,
+		Preparation:    r.Preparation,
+	}
+}
+
+type appointmentTypeResponse struct {
+	ID             uint                   `json:"id"`
+	OrganizationID *uint                  `json:"organization_id"`
+	Name           string                 `json:"name"`
+	Modality       string                 `json:"modality"`
+	Duration       int                    `json:"duration"`
+	PriceCents     int64                  `json:"price_cents"`
+	Currency       string                 `json:"currency"`
+	Color          string                 `json:"color,omitempty"`
+	IntakeForm     []model.IntakeQuestion `json:"intake_form"`
+	Preparation    string                 `json:"preparation,omitempty"`
+	Active         bool                   `json:"active"`
+	CreatedAt      string                 `json:"created_at"`
+	UpdatedAt      string                 `json:"updated_at"`
+}
+
+// Helper function to convert model to response
+func toAppointmentTypeResponse(appointmentType *model.AppointmentType) appointmentTypeResponse {
+	intakeForm := appointmentType.IntakeForm
+	if intakeForm == nil {
+		intakeForm = []model.IntakeQuestion{}
+	}
+
+	return appointmentTypeResponse{
+		ID:             appointmentType.ID,
+		OrganizationID: appointmentType.OrganizationID,
+		Name:           appointmentType.Name,
+		Modality:       string(appointmentType.Modality),
+		Duration:       appointmentType.Duration,
+		PriceCents:     appointmentType.PriceCents,
+		Currency:       appointmentType.Currency,
+		Color:          appointmentType.Color,
+		IntakeForm:     intakeForm,
+		Preparation:    appointmentType.Preparation,
+		Active:         appointmentType.Active,
+		CreatedAt:      appointmentType.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:      appointmentType.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+func toAppointmentTypeResponses(types []*model.AppointmentType) []appointmentTypeResponse {
+	response := make([]appointmentTypeResponse, 0, len(types))
+	for _, appointmentType := range types {
+		response = append(response, toAppointmentTypeResponse(appointmentType))
+	}
+	return response
+}
@@ -1,7 +1,9 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/whitewalker-sa/ehass/internal/model"
@@ -11,12 +13,14 @@ import (
 // AuthHandler handles authentication-related requests
 type AuthHandler struct {
 	authService service.AuthService
+	publicIDs   service.PublicIDService
 }
 
 // NewAuthHandler creates a new auth handler
-func NewAuthHandler(authService service.AuthService) *AuthHandler {
+func NewAuthHandler(authService service.AuthService, publicIDs service.PublicIDService) *AuthHandler {
 	return &AuthHandler{
 		authService: authService,
+		publicIDs:   publicIDs,
 	}
 }
 
@@ -79,25 +83,67 @@ type Disable2FARequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
+// ChangeEmailRequest represents request body for changing the login email
+type ChangeEmailRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
 // Verify2FARequest represents request body for 2FA verification
 type Verify2FARequest struct {
-	UserID uint   `json:"userId" binding:"required"`
+	UserID string `json:"userId" binding:"required"` // Public user ID from the login response
 	Token  string `json:"token" binding:"required"`
 }
 
+// ReauthenticateRequest represents request body for step-up re-authentication
+type ReauthenticateRequest struct {
+	Password string `json:"password"`
+	Token    string `json:"token"` // 2FA code
+}
+
 // LinkOAuthRequest represents request body for linking OAuth account
 type LinkOAuthRequest struct {
 	Provider      model.AuthProvider `json:"provider" binding:"required"`
 	ProviderToken string             `json:"providerToken" binding:"required"`
 }
 
+// IntrospectRequest represents request body for token introspection
+type IntrospectRequest struct {
+	Token string `json:"token" form:"token" binding:"required"`
+}
+
+// IntrospectResponse represents response body for token introspection (RFC 7662)
+type IntrospectResponse struct {
+	Active    bool       `json:"active"`
+	Subject   string     `json:"sub,omitempty"`
+	Email     string     `json:"email,omitempty"`
+	Role      model.Role `json:"role,omitempty"`
+	Issuer    string     `json:"iss,omitempty"`
+	Audience  string     `json:"aud,omitempty"`
+	IssuedAt  int64      `json:"iat,omitempty"`
+	ExpiresAt int64      `json:"exp,omitempty"`
+	AuthTime  int64      `json:"auth_time,omitempty"`
+	TokenType string     `json:"token_type,omitempty"`
+}
+
+// UserInfoResponse represents the authenticated principal using OpenID Connect standard claims
+type UserInfoResponse struct {
+	Subject       string     `json:"sub"`
+	Name          string     `json:"name"`
+	Email         string     `json:"email"`
+	EmailVerified bool       `json:"email_verified"`
+	PhoneNumber   string     `json:"phone_number,omitempty"`
+	Picture       string     `json:"picture,omitempty"`
+	Role          model.Role `json:"role"`
+	UpdatedAt     int64      `json:"updated_at"`
+}
+
 // TokenResponse represents response body for token generation
 type TokenResponse struct {
 	AccessToken  string      `json:"accessToken"`
 	RefreshToken string      `json:"refreshToken"`
 	User         interface{} `json:"user"`
 	Require2FA   bool        `json:"require2fa"`
-	UserID       uint        `json:"userId,omitempty"`
+	UserID       string      `json:"userId,omitempty"`
 }
 
 // Register handles user registration
@@ -110,6 +156,18 @@ func (h *AuthHandler) Register(c *gin.Context) {
 
 	user, err := h.authService.Register(c.Request.Context(), req.Name, req.Email, req.Password, req.Role)
 	if err != nil {
+		if errors.Is(err, service.ErrAccountClaimRequired) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "claim_required": true})
+			return
+		}
+		if errors.Is(err, service.ErrEmailRegistered) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, service.ErrDisposableEmail) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -134,7 +192,7 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		if err.Error() == "two-factor authentication required" {
 			c.JSON(http.StatusOK, TokenResponse{
 				Require2FA: true,
-				UserID:     user.ID,
+				UserID:     user.PublicID,
 			})
 			return
 		}
@@ -165,10 +223,14 @@ func (h *AuthHandler) OAuthLogin(c *gin.Context) {
 		if err.Error() == "two-factor authentication required" {
 			c.JSON(http.StatusOK, TokenResponse{
 				Require2FA: true,
-				UserID:     user.ID,
+				UserID:     user.PublicID,
 			})
 			return
 		}
+		if errors.Is(err, service.ErrDisposableEmail) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 
 		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 		return
@@ -317,6 +379,30 @@ func (h *AuthHandler) Disable2FA(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled successfully"})
 }
 
+// ChangeEmail handles changing the login email, after step-up authentication
+func (h *AuthHandler) ChangeEmail(c *gin.Context) {
+	var req ChangeEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := h.authService.ChangeEmail(c.Request.Context(), c.GetUint("userID"), req.Email)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrEmailRegistered):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrDisposableEmail):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email changed. Please check your new email to verify it."})
+}
+
 // Verify2FA handles 2FA verification
 func (h *AuthHandler) Verify2FA(c *gin.Context) {
 	var req Verify2FARequest
@@ -325,7 +411,13 @@ func (h *AuthHandler) Verify2FA(c *gin.Context) {
 		return
 	}
 
-	valid, err := h.authService.Verify2FA(c.Request.Context(), req.UserID, req.Token)
+	userID, err := h.publicIDs.ResolveID(c.Request.Context(), model.ResourceUser, req.UserID)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid 2FA token"})
+		return
+	}
+
+	valid, err := h.authService.Verify2FA(c.Request.Context(), userID, req.Token)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -402,3 +494,104 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
 }
+
+// LogoutAll revokes all refresh tokens and sessions of the current user
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	if err := h.authService.LogoutAll(c.Request.Context(), userID.(uint)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out from all devices successfully"})
+}
+
+// Reauthenticate handles step-up re-authentication before sensitive operations
+func (h *AuthHandler) Reauthenticate(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req ReauthenticateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	accessToken, refreshToken, err := h.authService.Reauthenticate(c.Request.Context(), userID.(uint),
+		strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "), req.Password, req.Token)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"accessToken":  accessToken,
+		"refreshToken": refreshToken,
+	})
+}
+
+// Introspect reports whether a token is active for sibling services and gateways
+func (h *AuthHandler) Introspect(c *gin.Context) {
+	var req IntrospectRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result := h.authService.Introspect(c.Request.Context(), strings.TrimPrefix(req.Token, "Bearer "))
+	if !result.Active {
+		c.JSON(http.StatusOK, IntrospectResponse{Active: false})
+		return
+	}
+
+	resp := IntrospectResponse{
+		Active:    true,
+		Subject:   result.Subject,
+		Email:     result.Email,
+		Role:      result.Role,
+		Issuer:    result.Issuer,
+		Audience:  result.Audience,
+		IssuedAt:  result.IssuedAt.Unix(),
+		ExpiresAt: result.ExpiresAt.Unix(),
+		TokenType: "Bearer",
+	}
+	if !result.AuthTime.IsZero() {
+		resp.AuthTime = result.AuthTime.Unix()
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// UserInfo returns the principal authenticated by the bearer token
+func (h *AuthHandler) UserInfo(c *gin.Context) {
+	value, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	user, ok := value.(*model.User)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user type"})
+		return
+	}
+
+	c.JSON(http.StatusOK, UserInfoResponse{
+		Subject:       user.PublicID,
+		Name:          user.Name,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerified,
+		PhoneNumber:   user.Phone,
+		Picture:       user.Avatar,
+		Role:          user.Role,
+		UpdatedAt:     user.UpdatedAt.Unix(),
+	})
+}
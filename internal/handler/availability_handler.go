@@ -0,0 +1,575 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/service"
+	"go.uber.org/zap"
+)
+
+// AvailabilityHandler handles doctor availability HTTP requests
+type AvailabilityHandler struct {
+	service       service.AvailabilityService
+	doctorService service.DoctorService
+	publicIDs     service.PublicIDService
+	logger        *zap.Logger
+}
+
+// NewAvailabilityHandler creates a new availability handler
+func NewAvailabilityHandler(service service.AvailabilityService, doctorService service.DoctorService, publicIDs service.PublicIDService, logger *zap.Logger) *AvailabilityHandler {
+	return &AvailabilityHandler{
+		service:       service,
+		doctorService: doctorService,
+		publicIDs:     publicIDs,
+		logger:        logger,
+	}
+}
+
+// GetAvailability godoc
+// @Summary Get doctor availability
+// @Description Get a doctor's weekly availability windows, including those applied from shift templates for a range of dates
+// @Tags doctors,availability
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Doctor ID (UUID)"
+// @Success 200 {array} availabilityResponse "Availability windows"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /doctors/{id}/availability [get]
+func (h *AvailabilityHandler) GetAvailability(c *gin.Context) {
+	doctorID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid doctor ID"})
+		return
+	}
+
+	windows, err := h.service.GetDoctorAvailability(c.Request.Context(), uint(doctorID))
+	if err != nil {
+		h.logger.Error("Failed to get availability", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get availability"})
+		return
+	}
+
+	response := make([]availabilityResponse, len(windows))
+	for i, window := range windows {
+		response[i] = toAvailabilityResponse(window)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// AddAvailability godoc
+// @Summary Add availability
+// @Description Add a weekly availability window for a doctor. Windows on the same day cannot overlap. Only the doctor or an admin may change it.
+// @Tags doctors,availability
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Doctor ID (UUID)"
+// @Param request body availabilityRequest true "Availability window"
+// @Success 201 {object} availabilityResponse "Created availability window"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Router /doctors/{id}/availability [post]
+func (h *AvailabilityHandler) AddAvailability(c *gin.Context) {
+	doctorID, ok := h.authorizeDoctor(c)
+	if !ok {
+		return
+	}
+
+	var req availabilityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	availability, err := h.service.AddAvailability(c.Request.Context(), doctorID, req.DayOfWeek, req.StartTime, req.EndTime, req.Duration)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, toAvailabilityResponse(availability))
+}
+
+// UpdateAvailability godoc
+// @Summary Update availability
+// @Description Change a weekly availability window. Windows on the same day cannot overlap. If booked appointments would fall outside the doctor's availability, the change is rejected with 409 and the affected appointments unless confirm=true is passed.
+// @Tags doctors,availability
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Doctor ID (UUID)"
+// @Param availabilityID path int true "Availability ID"
+// @Param confirm query bool false "Apply the change even if appointments are left outside the availability"
+// @Param request body availabilityRequest true "Availability window"
+// @Success 200 {object} availabilityChangeResponse "Updated availability window"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not found"
+// @Failure 409 {object} availabilityConflictResponse "Appointments left outside the availability"
+// @Router /doctors/{id}/availability/{availabilityID} [put]
+func (h *AvailabilityHandler) UpdateAvailability(c *gin.Context) {
+	doctorID, ok := h.authorizeDoctor(c)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("availabilityID"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid availability ID"})
+		return
+	}
+
+	var req availabilityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	availability, affected, err := h.service.UpdateAvailability(
+		c.Request.Context(), doctorID, uint(id), req.DayOfWeek, req.StartTime, req.EndTime, req.Duration, c.Query("confirm") == "true",
+	)
+	if err != nil {
+		h.availabilityError(c, err, affected)
+		return
+	}
+
+	window := toAvailabilityResponse(availability)
+	c.JSON(http.StatusOK, availabilityChangeResponse{
+		Availability:         &window,
+		AffectedAppointments: formatAppointmentResponses(affected, requestLocation(c)),
+	})
+}
+
+// RemoveAvailability godoc
+// @Summary Remove availability
+// @Description Remove a weekly availability window. If booked appointments would fall outside the doctor's availability, the removal is rejected with 409 and the affected appointments unless confirm=true is passed.
+// @Tags doctors,availability
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Doctor ID (UUID)"
+// @Param availabilityID path int true "Availability ID"
+// @Param confirm query bool false "Remove the window even if appointments are left outside the availability"
+// @Success 200 {object} availabilityChangeResponse "Appointments left outside the availability"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not found"
+// @Failure 409 {object} availabilityConflictResponse "Appointments left outside the availability"
+// @Router /doctors/{id}/availability/{availabilityID} [delete]
+func (h *AvailabilityHandler) RemoveAvailability(c *gin.Context) {
+	doctorID, ok := h.authorizeDoctor(c)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("availabilityID"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid availability ID"})
+		return
+	}
+
+	affected, err := h.service.RemoveAvailability(c.Request.Context(), doctorID, uint(id), c.Query("confirm") == "true")
+	if err != nil {
+		h.availabilityError(c, err, affected)
+		return
+	}
+
+	c.JSON(http.StatusOK, availabilityChangeResponse{
+		AffectedAppointments: formatAppointmentResponses(affected, requestLocation(c)),
+	})
+}
+
+// ApplyShiftTemplate godoc
+// @Summary Apply shift template
+// @Description Add the windows of a shift template to a doctor's availability for a range of dates, instead of entering them one by one. With replace, the doctor's other windows stop applying on those dates; otherwise the template's windows must not overlap them. If booked appointments would fall outside the doctor's availability, the change is rejected with 409 and the affected appointments unless confirm=true is passed. Only the doctor or an admin may change it.
+// @Tags doctors,availability
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Doctor ID (UUID)"
+// @Param confirm query bool false "Apply the template even if appointments are left outside the availability"
+// @Param request body applyShiftTemplateRequest true "Template and dates"
+// @Success 200 {object} shiftTemplateAppliedResponse "Windows added"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not found"
+// @Failure 409 {object} availabilityConflictResponse "Appointments left outside the availability"
+// @Router /doctors/{id}/availability/apply-template [post]
+func (h *AvailabilityHandler) ApplyShiftTemplate(c *gin.Context) {
+	doctorID, ok := h.authorizeDoctor(c)
+	if !ok {
+		return
+	}
+
+	var req applyShiftTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	windows, affected, err := h.service.ApplyShiftTemplate(
+		c.Request.Context(), doctorID, req.TemplateID, req.From, req.Until, req.Replace, c.Query("confirm") == "true",
+	)
+	if err != nil {
+		h.availabilityError(c, err, affected)
+		return
+	}
+
+	response := shiftTemplateAppliedResponse{
+		Availability:         make([]availabilityResponse, len(windows)),
+		AffectedAppointments: formatAppointmentResponses(affected, requestLocation(c)),
+	}
+	for i, window := range windows {
+		response.Availability[i] = toAvailabilityResponse(window)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// GetTimeOff godoc
+// @Summary Get doctor time off
+// @Description Get a doctor's current and upcoming time off
+// @Tags doctors,availability
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Doctor ID (UUID)"
+// @Success 200 {array} timeOffResponse "Time off"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /doctors/{id}/time-off [get]
+func (h *AvailabilityHandler) GetTimeOff(c *gin.Context) {
+	doctorID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid doctor ID"})
+		return
+	}
+
+	timeOff, err := h.service.GetDoctorTimeOff(c.Request.Context(), uint(doctorID))
+	if err != nil {
+		h.logger.Error("Failed to get time off", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get time off"})
+		return
+	}
+
+	response := make([]timeOffResponse, len(timeOff))
+	for i, blocked := range timeOff {
+		response[i] = toTimeOffResponse(blocked, requestLocation(c))
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// AddTimeOff godoc
+// @Summary Add time off
+// @Description Block a range of a doctor's time, such as a vacation or conference. No slots are offered and no bookings accepted during it. Patient messages sent during it go to the covering doctor, if one is given, and are answered automatically with the absence period. Appointments already booked in the range are returned, and cancelled with their patients emailed if cancel_appointments is set. Only the doctor or an admin may change it.
+// @Tags doctors,availability
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Doctor ID (UUID)"
+// @Param request body timeOffRequest true "Time off"
+// @Success 201 {object} timeOffChangeResponse "Created time off"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Router /doctors/{id}/time-off [post]
+func (h *AvailabilityHandler) AddTimeOff(c *gin.Context) {
+	doctorID, ok := h.authorizeDoctor(c)
+	if !ok {
+		return
+	}
+
+	req, start, end, ok := bindTimeOffRequest(c)
+	if !ok {
+		return
+	}
+	coveringDoctorID, ok := h.resolveCoveringDoctor(c, req)
+	if !ok {
+		return
+	}
+
+	timeOff, affected, err := h.service.AddTimeOff(c.Request.Context(), doctorID, start, end, req.Reason, coveringDoctorID, req.CancelAppointments)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	loc := requestLocation(c)
+	c.JSON(http.StatusCreated, timeOffChangeResponse{
+		TimeOff:              toTimeOffResponse(timeOff, loc),
+		AffectedAppointments: formatAppointmentResponses(affected, loc),
+	})
+}
+
+// UpdateTimeOff godoc
+// @Summary Update time off
+// @Description Change a doctor's time off and its covering doctor. Appointments booked in the new range are returned, and cancelled with their patients emailed if cancel_appointments is set.
+// @Tags doctors,availability
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Doctor ID (UUID)"
+// @Param timeOffID path int true "Time off ID"
+// @Param request body timeOffRequest true "Time off"
+// @Success 200 {object} timeOffChangeResponse "Updated time off"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /doctors/{id}/time-off/{timeOffID} [put]
+func (h *AvailabilityHandler) UpdateTimeOff(c *gin.Context) {
+	doctorID, ok := h.authorizeDoctor(c)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("timeOffID"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid time off ID"})
+		return
+	}
+
+	req, start, end, ok := bindTimeOffRequest(c)
+	if !ok {
+		return
+	}
+	coveringDoctorID, ok := h.resolveCoveringDoctor(c, req)
+	if !ok {
+		return
+	}
+
+	timeOff, affected, err := h.service.UpdateTimeOff(c.Request.Context(), doctorID, uint(id), start, end, req.Reason, coveringDoctorID, req.CancelAppointments)
+	if err != nil {
+		h.timeOffError(c, err)
+		return
+	}
+
+	loc := requestLocation(c)
+	c.JSON(http.StatusOK, timeOffChangeResponse{
+		TimeOff:              toTimeOffResponse(timeOff, loc),
+		AffectedAppointments: formatAppointmentResponses(affected, loc),
+	})
+}
+
+// RemoveTimeOff godoc
+// @Summary Remove time off
+// @Description Remove a doctor's time off, opening its time for booking again
+// @Tags doctors,availability
+// @Security BearerAuth
+// @Param id path string true "Doctor ID (UUID)"
+// @Param timeOffID path int true "Time off ID"
+// @Success 204 "Time off removed"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /doctors/{id}/time-off/{timeOffID} [delete]
+func (h *AvailabilityHandler) RemoveTimeOff(c *gin.Context) {
+	doctorID, ok := h.authorizeDoctor(c)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("timeOffID"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid time off ID"})
+		return
+	}
+
+	if err := h.service.RemoveTimeOff(c.Request.Context(), doctorID, uint(id)); err != nil {
+		h.timeOffError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// authorizeDoctor parses the doctor ID and checks the caller is that doctor or an admin
+func (h *AvailabilityHandler) authorizeDoctor(c *gin.Context) (uint, bool) {
+	doctorID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid doctor ID"})
+		return 0, false
+	}
+
+	doctor, err := h.doctorService.GetDoctorByID(c.Request.Context(), uint(doctorID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return 0, false
+	}
+
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("userRole")
+	if role != model.RoleAdmin && doctor.UserID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the doctor or an admin can change availability"})
+		return 0, false
+	}
+	return doctor.ID, true
+}
+
+func (h *AvailabilityHandler) availabilityError(c *gin.Context, err error, affected []*model.Appointment) {
+	switch {
+	case errors.Is(err, service.ErrAvailabilityConflict):
+		c.JSON(http.StatusConflict, availabilityConflictResponse{
+			Error:                   err.Error(),
+			ConflictingAppointments: formatAppointmentResponses(affected, requestLocation(c)),
+		})
+	case err.Error() == "availability not found", err.Error() == "shift template not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
+
+func (h *AvailabilityHandler) timeOffError(c *gin.Context, err error) {
+	if err.Error() == "time off not found" {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
+
+// bindTimeOffRequest binds a time-off request and parses its range
+func bindTimeOffRequest(c *gin.Context) (timeOffRequest, time.Time, time.Time, bool) {
+	var req timeOffRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return req, time.Time{}, time.Time{}, false
+	}
+	loc, err := inputLocation(c, req.Timezone)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid timezone"})
+		return req, time.Time{}, time.Time{}, false
+	}
+	start, err := parseRequestTime(req.Start, loc)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start, use RFC3339 or YYYY-MM-DDTHH:MM format"})
+		return req, time.Time{}, time.Time{}, false
+	}
+	end, err := parseRequestTime(req.End, loc)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid end, use RFC3339 or YYYY-MM-DDTHH:MM format"})
+		return req, time.Time{}, time.Time{}, false
+	}
+	return req, start, end, true
+}
+
+// resolveCoveringDoctor resolves the public ID of a time-off request's covering doctor; 0 when
+// none is given
+func (h *AvailabilityHandler) resolveCoveringDoctor(c *gin.Context, req timeOffRequest) (uint, bool) {
+	if req.CoveringDoctorID == "" {
+		return 0, true
+	}
+	id, err := h.publicIDs.ResolveID(c.Request.Context(), model.ResourceDoctor, req.CoveringDoctorID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return 0, false
+	}
+	return id, true
+}
+
+// Request and response models
+
+type availabilityRequest struct {
+	DayOfWeek string `json:"day_of_week" binding:"required" example:"monday"`
+	StartTime string `json:"start_time" binding:"required" example:"09:00"`
+	EndTime   string `json:"end_time" binding:"required" example:"17:00"`
+	Duration  int    `json:"duration" example:"30"` // Slot length in minutes; 0 for the clinic's appointment length, or to keep it on update
+}
+
+type availabilityResponse struct {
+	ID              uint   `json:"id"`
+	DayOfWeek       int    `json:"day_of_week"`
+	Day             string `json:"day"`
+	StartTime       string `json:"start_time"`
+	EndTime         string `json:"end_time"`
+	Duration        int    `json:"duration"`
+	ValidFrom       string `json:"valid_from,omitempty"`        // First date the window applies on; omitted when it always has
+	ValidUntil      string `json:"valid_until,omitempty"`       // Last date the window applies on; omitted when it has no end
+	ShiftTemplateID *uint  `json:"shift_template_id,omitempty"` // Template the window was applied from
+}
+
+type availabilityChangeResponse struct {
+	Availability         *availabilityResponse `json:"availability,omitempty"`
+	AffectedAppointments []appointmentResponse `json:"affected_appointments"`
+}
+
+type applyShiftTemplateRequest struct {
+	TemplateID uint   `json:"template_id" binding:"required"`
+	From       string `json:"from" binding:"required" example:"2026-11-02"`  // First date, in the clinic's timezone
+	Until      string `json:"until" binding:"required" example:"2026-11-29"` // Last date, inclusive
+	Replace    bool   `json:"replace"`                                       // Stop the doctor's other windows applying on these dates
+}
+
+type shiftTemplateAppliedResponse struct {
+	Availability         []availabilityResponse `json:"availability"` // Windows added
+	AffectedAppointments []appointmentResponse  `json:"affected_appointments"`
+}
+
+type availabilityConflictResponse struct {
+	Error                   string                `json:"error"`
+	ConflictingAppointments []appointmentResponse `json:"conflicting_appointments"`
+}
+
+type timeOffRequest struct {
+	Start              string `json:"start" binding:"required" example:"2026-12-21T00:00:00+02:00"` // RFC3339, or a local time in timezone
+	End                string `json:"end" binding:"required" example:"2027-01-04T00:00:00+02:00"`   // RFC3339, or a local time in timezone
+	Timezone           string `json:"timezone"`                                                     // IANA timezone of local times; defaults to the user's
+	Reason             string `json:"reason" example:"Vacation"`
+	CancelAppointments bool   `json:"cancel_appointments"` // Cancel appointments booked in the range and email their patients
+	CoveringDoctorID   string `json:"covering_doctor_id"`  // Public ID of the doctor taking patient messages during the range, if any
+}
+
+type timeOffResponse struct {
+	ID                 uint   `json:"id"`
+	Start              string `json:"start"`
+	End                string `json:"end"`
+	Reason             string `json:"reason,omitempty"`
+	CoveringDoctorID   string `json:"covering_doctor_id,omitempty"`
+	CoveringDoctorName string `json:"covering_doctor_name,omitempty"`
+}
+
+type timeOffChangeResponse struct {
+	TimeOff              timeOffResponse       `json:"time_off"`
+	AffectedAppointments []appointmentResponse `json:"affected_appointments"`
+}
+
+func toTimeOffResponse(timeOff *model.TimeOff, loc *time.Location) timeOffResponse {
+	response := timeOffResponse{
+		ID:     timeOff.ID,
+		Start:  timeOff.Start.In(loc).Format(time.RFC3339),
+		End:    timeOff.End.In(loc).Format(time.RFC3339),
+		Reason: timeOff.Reason,
+	}
+	if timeOff.CoveringDoctor != nil {
+		response.CoveringDoctorID = timeOff.CoveringDoctor.PublicID
+		response.CoveringDoctorName = timeOff.CoveringDoctor.User.Name
+	}
+	return response
+}
+
+func toAvailabilityResponse(availability *model.Availability) availabilityResponse {
+	response := availabilityResponse{
+		ID:              availability.ID,
+		DayOfWeek:       availability.DayOfWeek,
+		Day:             time.Weekday(availability.DayOfWeek).String(),
+		StartTime:       availability.StartTime,
+		EndTime:         availability.EndTime,
+		Duration:        availability.Duration,
+		ShiftTemplateID: availability.ShiftTemplateID,
+	}
+	if availability.ValidFrom != nil {
+		response.ValidFrom = availability.ValidFrom.Format("2006-01-02")
+	}
+	if availability.ValidUntil != nil {
+		response.ValidUntil = availability.ValidUntil.Format("2006-01-02")
+	}
+	return response
+}
+
+func formatAppointmentResponses(appointments []*model.Appointment, loc *time.Location) []appointmentResponse {
+	responses := make([]appointmentResponse, len(appointments))
+	for i, appointment := range appointments {
+		responses[i] = formatAppointmentResponse(appointment, loc)
+	}
+	return responses
+}
@@ -0,0 +1,175 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/service"
+	"go.uber.org/zap"
+)
+
+// BreakGlassHandler handles emergency access HTTP requests
+type BreakGlassHandler struct {
+	service service.BreakGlassService
+	logger  *zap.Logger
+}
+
+// NewBreakGlassHandler creates a new break-glass handler
+func NewBreakGlassHandler(service service.BreakGlassService, logger *zap.Logger) *BreakGlassHandler {
+	return &BreakGlassHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// RequestAccess godoc
+// @Summary Request emergency access
+// @Description Grant the authenticated clinician time-limited emergency access to a patient record. The access is audited and administrators are notified.
+// @Tags patients,break-glass
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Patient ID (UUID)"
+// @Param request body breakGlassRequest true "Emergency reason"
+// @Success 201 {object} breakGlassResponse "Emergency access grant"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Router /patients/{id}/break-glass [post]
+func (h *BreakGlassHandler) RequestAccess(c *gin.Context) {
+	patientID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req breakGlassRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	access, err := h.service.RequestAccess(c.Request.Context(), userID.(uint), uint(patientID), req.Reason, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		h.logger.Warn("Break-glass access denied", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, toBreakGlassResponse(access))
+}
+
+// GetEmergencyRecord godoc
+// @Summary Get emergency patient record
+// @Description Get the full patient record under an active emergency access grant
+// @Tags patients,break-glass
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Patient ID (UUID)"
+// @Success 200 {object} map[string]interface{} "Patient record and grant"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Router /patients/{id}/emergency-record [get]
+func (h *BreakGlassHandler) GetEmergencyRecord(c *gin.Context) {
+	patientID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	patient, access, err := h.service.GetEmergencyRecord(c.Request.Context(), userID.(uint), uint(patientID), c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"patient": toPatientResponse(patient),
+		"access":  toBreakGlassResponse(access),
+	})
+}
+
+// ListAccesses godoc
+// @Summary List emergency accesses
+// @Description List all break-glass emergency access grants for review (admin only)
+// @Tags admin,break-glass
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param pageSize query int false "Page size" default(10)
+// @Success 200 {array} breakGlassResponse "Emergency access grants"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /admin/break-glass [get]
+func (h *BreakGlassHandler) ListAccesses(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
+
+	accesses, total, err := h.service.GetAccessLog(c.Request.Context(), page, pageSize)
+	if err != nil {
+		h.logger.Error("Failed to get break-glass accesses", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get emergency accesses"})
+		return
+	}
+
+	response := make([]breakGlassResponse, 0, len(accesses))
+	for _, access := range accesses {
+		response = append(response, toBreakGlassResponse(access))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"accesses": response,
+		"total":    total,
+		"page":     page,
+		"size":     pageSize,
+	})
+}
+
+// Request and response models
+type breakGlassRequest struct {
+	Reason string `json:"reason" binding:"required,min=10"`
+}
+
+type breakGlassResponse struct {
+	ID            uint   `json:"id"`
+	UserID        string `json:"user_id"`
+	ClinicianName string `json:"clinician_name,omitempty"`
+	PatientID     string `json:"patient_id"`
+	PatientName   string `json:"patient_name,omitempty"`
+	Reason        string `json:"reason"`
+	ExpiresAt     string `json:"expires_at"`
+	Active        bool   `json:"active"`
+	CreatedAt     string `json:"created_at"`
+}
+
+// Helper function to convert model to response
+func toBreakGlassResponse(access *model.BreakGlassAccess) breakGlassResponse {
+	return breakGlassResponse{
+		ID:            access.ID,
+		UserID:        access.User.PublicID,
+		ClinicianName: access.User.Name,
+		PatientID:     access.Patient.PublicID,
+		PatientName:   access.Patient.User.Name,
+		Reason:        access.Reason,
+		ExpiresAt:     access.ExpiresAt.Format(time.RFC3339),
+		Active:        access.IsActive(),
+		CreatedAt:     access.CreatedAt.Format(time.RFC3339),
+	}
+}
@@ -0,0 +1,311 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/whitewalker-sa/ehass/internal/service"
+	"go.uber.org/zap"
+)
+
+// icsContentType is the media type of iCalendar files
+const icsContentType = "text/calendar; charset=utf-8"
+
+// CalendarHandler handles iCalendar export and calendar sync HTTP requests
+type CalendarHandler struct {
+	calendarService     service.CalendarService
+	calendarSyncService service.CalendarSyncService
+	logger              *zap.Logger
+}
+
+// NewCalendarHandler creates a new calendar handler
+func NewCalendarHandler(calendarService service.CalendarService, calendarSyncService service.CalendarSyncService, logger *zap.Logger) *CalendarHandler {
+	return &CalendarHandler{
+		calendarService:     calendarService,
+		calendarSyncService: calendarSyncService,
+		logger:              logger,
+	}
+}
+
+// GetAppointmentICS godoc
+// @Summary Download appointment as iCalendar
+// @Description Download an appointment as an RFC 5545 iCalendar file to add it to Google Calendar, Outlook or another calendar. Patients get an alarm matching the appointment reminder; cancelled appointments are marked cancelled.
+// @Tags appointments
+// @Produce text/calendar
+// @Security BearerAuth
+// @Param id path string true "Appointment ID (UUID)"
+// @Success 200 {file} file "iCalendar file"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "Appointment not found"
+// @Router /appointments/{id}/ics [get]
+func (h *CalendarHandler) GetAppointmentICS(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid appointment ID"})
+		return
+	}
+
+	calendar, err := h.calendarService.GetAppointmentCalendar(c.Request.Context(), uint(id), userID.(uint))
+	if err != nil {
+		if err.Error() == "appointment not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export appointment"})
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="appointment.ics"`)
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, icsContentType, calendar)
+}
+
+// CreateCalendarFeed godoc
+// @Summary Issue calendar feed
+// @Description Issue a calendar feed URL the authenticated user can subscribe to from Google Calendar, Outlook or another calendar. Patients get their appointments, doctors the appointments booked with them. The URL carries a secret token; issuing a new feed stops the previous URL from working.
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID (UUID)"
+// @Success 201 {object} calendarFeedResponse "Feed URL"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Router /users/{id}/calendar-feed [post]
+func (h *CalendarHandler) CreateCalendarFeed(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	feedURL, err := h.calendarService.CreateFeed(c.Request.Context(), userID.(uint))
+	if err != nil {
+		h.logger.Error("Failed to issue calendar feed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue calendar feed"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, calendarFeedResponse{URL: feedURL})
+}
+
+// RevokeCalendarFeed godoc
+// @Summary Revoke calendar feed
+// @Description Stop the authenticated user's calendar feed; subscribed calendars stop receiving updates
+// @Tags users
+// @Security BearerAuth
+// @Param id path string true "User ID (UUID)"
+// @Success 204 "Feed revoked"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Router /users/{id}/calendar-feed [delete]
+func (h *CalendarHandler) RevokeCalendarFeed(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	if err := h.calendarService.RevokeFeed(c.Request.Context(), userID.(uint)); err != nil {
+		h.logger.Error("Failed to revoke calendar feed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke calendar feed"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetCalendarFeed godoc
+// @Summary Calendar feed
+// @Description iCalendar feed of a user's appointments from a month ago to a year ahead, fetched by calendar applications subscribed to the URL from POST /users/{id}/calendar-feed. Authenticated by the token in the URL rather than a bearer token.
+// @Tags users
+// @Produce text/calendar
+// @Param id path string true "User ID (UUID)"
+// @Param token query string true "Feed token"
+// @Success 200 {file} file "iCalendar feed"
+// @Failure 404 {object} map[string]string "Feed not found"
+// @Router /users/{id}/calendar.ics [get]
+func (h *CalendarHandler) GetCalendarFeed(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Calendar feed not found"})
+		return
+	}
+
+	calendar, err := h.calendarService.GetFeed(c.Request.Context(), uint(id), c.Query("token"))
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidCalendarToken) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Calendar feed not found"})
+			return
+		}
+		h.logger.Error("Failed to render calendar feed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render calendar feed"})
+		return
+	}
+
+	c.Header("Cache-Control", "private, no-cache")
+	c.Data(http.StatusOK, icsContentType, calendar)
+}
+
+// GetCalendarSync godoc
+// @Summary Calendar sync status
+// @Description Get the authenticated doctor's Google Calendar connection: when it last synced and why the last sync failed, if it did
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID (UUID)"
+// @Success 200 {object} model.CalendarConnection "Calendar connection"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "No calendar connected"
+// @Router /users/{id}/calendar-sync [get]
+func (h *CalendarHandler) GetCalendarSync(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	connection, err := h.calendarSyncService.GetConnection(c.Request.Context(), userID.(uint))
+	if err != nil {
+		if err.Error() == "calendar connection not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.Error("Failed to get calendar connection", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get calendar connection"})
+		return
+	}
+
+	c.JSON(http.StatusOK, connection)
+}
+
+// AuthorizeCalendarSync godoc
+// @Summary Start calendar sync
+// @Description Get the Google consent page to send the authenticated doctor to. Google redirects back to the configured page with a code and the state; check the state matches, then post the code to POST /users/{id}/calendar-sync.
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID (UUID)"
+// @Success 200 {object} calendarSyncAuthorizationResponse "Consent page"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "Doctor not found"
+// @Failure 503 {object} map[string]string "Calendar sync is not enabled"
+// @Router /users/{id}/calendar-sync/authorize [get]
+func (h *CalendarHandler) AuthorizeCalendarSync(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	authURL, state, err := h.calendarSyncService.AuthorizationURL(c.Request.Context(), userID.(uint))
+	if err != nil {
+		h.calendarSyncError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, calendarSyncAuthorizationResponse{URL: authURL, State: state})
+}
+
+// ConnectCalendarSync godoc
+// @Summary Connect calendar
+// @Description Connect the authenticated doctor's Google Calendar with the code Google returned from the consent page. Confirmed appointments are then added to the calendar, and the times it is busy are no longer offered as slots. Events name no patient.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID (UUID)"
+// @Param request body connectCalendarSyncRequest true "Authorization code"
+// @Success 201 {object} model.CalendarConnection "Calendar connected"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "Doctor not found"
+// @Failure 503 {object} map[string]string "Calendar sync is not enabled"
+// @Router /users/{id}/calendar-sync [post]
+func (h *CalendarHandler) ConnectCalendarSync(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req connectCalendarSyncRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	connection, err := h.calendarSyncService.Connect(c.Request.Context(), userID.(uint), req.Code)
+	if err != nil {
+		h.calendarSyncError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, connection)
+}
+
+// DisconnectCalendarSync godoc
+// @Summary Disconnect calendar
+// @Description Disconnect the authenticated doctor's Google Calendar. The events added for appointments are removed from it, and its busy times stop blocking slots.
+// @Tags users
+// @Security BearerAuth
+// @Param id path string true "User ID (UUID)"
+// @Success 204 "Calendar disconnected"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "No calendar connected"
+// @Router /users/{id}/calendar-sync [delete]
+func (h *CalendarHandler) DisconnectCalendarSync(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	if err := h.calendarSyncService.Disconnect(c.Request.Context(), userID.(uint)); err != nil {
+		if err.Error() == "calendar connection not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.Error("Failed to disconnect calendar", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disconnect calendar"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// calendarSyncError responds to a failure to connect a calendar
+func (h *CalendarHandler) calendarSyncError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrCalendarSyncDisabled):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrCalendarAuthorization):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case err.Error() == "doctor not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		h.logger.Error("Failed to connect calendar", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to connect calendar"})
+	}
+}
+
+// Request and response types
+
+type calendarFeedResponse struct {
+	URL string `json:"url"` // Subscription URL; keep it secret, it grants read access to the calendar
+}
+
+type calendarSyncAuthorizationResponse struct {
+	URL   string `json:"url"`   // Google consent page
+	State string `json:"state"` // Returned by Google with the code; check it before posting the code
+}
+
+type connectCalendarSyncRequest struct {
+	Code string `json:"code" binding:"required,max=2048"`
+}
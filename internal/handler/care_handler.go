@@ -0,0 +1,313 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/service"
+	"go.uber.org/zap"
+)
+
+// CareHandler handles preventive care rule and reminder HTTP requests
+type CareHandler struct {
+	service service.CareService
+	logger  *zap.Logger
+}
+
+// NewCareHandler creates a new care handler
+func NewCareHandler(service service.CareService, logger *zap.Logger) *CareHandler {
+	return &CareHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// CreateCareRule godoc
+// @Summary Create care rule
+// @Description Define a recurring preventive care item, such as an annual physical or an age- and sex-based screening. Eligible patients are reminded when it comes due.
+// @Tags admin,care
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body careRuleRequest true "Care rule"
+// @Success 201 {object} careRuleResponse "Created care rule"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /admin/care-rules [post]
+func (h *CareHandler) CreateCareRule(c *gin.Context) {
+	var req careRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rule, err := h.service.CreateRule(c.Request.Context(), req.toModel())
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidCareRule) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.Error("Failed to create care rule", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create care rule"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, toCareRuleResponse(rule))
+}
+
+// ListCareRules godoc
+// @Summary List care rules
+// @Description List all care rules, including archived ones
+// @Tags admin,care
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} careRuleResponse "Care rules"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /admin/care-rules [get]
+func (h *CareHandler) ListCareRules(c *gin.Context) {
+	rules, err := h.service.ListRules(c.Request.Context())
+	if err != nil {
+		h.logger.Error("Failed to list care rules", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list care rules"})
+		return
+	}
+
+	response := make([]careRuleResponse, 0, len(rules))
+	for _, rule := range rules {
+		response = append(response, toCareRuleResponse(rule))
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// GetCareRule godoc
+// @Summary Get care rule
+// @Description Get a care rule by ID
+// @Tags admin,care
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Care rule ID"
+// @Success 200 {object} careRuleResponse "Care rule"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /admin/care-rules/{id} [get]
+func (h *CareHandler) GetCareRule(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid care rule ID"})
+		return
+	}
+
+	rule, err := h.service.GetRule(c.Request.Context(), uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, toCareRuleResponse(rule))
+}
+
+// UpdateCareRule godoc
+// @Summary Update care rule
+// @Description Replace the criteria of a care rule; reminders already sent are not changed
+// @Tags admin,care
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Care rule ID"
+// @Param request body careRuleRequest true "Care rule"
+// @Success 200 {object} careRuleResponse "Updated care rule"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /admin/care-rules/{id} [put]
+func (h *CareHandler) UpdateCareRule(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid care rule ID"})
+		return
+	}
+
+	var req careRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rule, err := h.service.UpdateRule(c.Request.Context(), uint(id), req.toModel())
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidCareRule) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, toCareRuleResponse(rule))
+}
+
+// ArchiveCareRule godoc
+// @Summary Archive care rule
+// @Description Stop reminding patients of a care rule
+// @Tags admin,care
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Care rule ID"
+// @Success 200 {object} map[string]string "Care rule archived"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /admin/care-rules/{id} [delete]
+func (h *CareHandler) ArchiveCareRule(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid care rule ID"})
+		return
+	}
+
+	if err := h.service.ArchiveRule(c.Request.Context(), uint(id)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "care rule archived"})
+}
+
+// GetPatientCareReminders godoc
+// @Summary List a patient's care reminders
+// @Description List the preventive care a patient has been reminded of and not booked since, soonest due first, each with the appointment to book for it
+// @Tags patients,care
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Patient ID (UUID)"
+// @Success 200 {array} careReminderResponse "Care reminders"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /patients/{id}/care-reminders [get]
+func (h *CareHandler) GetPatientCareReminders(c *gin.Context) {
+	patientID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+		return
+	}
+
+	reminders, err := h.service.GetPatientReminders(c.Request.Context(), uint(patientID))
+	if err != nil {
+		h.logger.Error("Failed to list care reminders", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list care reminders"})
+		return
+	}
+
+	response := make([]careReminderResponse, 0, len(reminders))
+	for _, reminder := range reminders {
+		response = append(response, toCareReminderResponse(reminder))
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// Request and response models
+type careRuleRequest struct {
+	Name              string `json:"name" binding:"required,max=100"`
+	Description       string `json:"description" binding:"max=255"`
+	Sex               string `json:"sex"`     // female, male, or empty for everyone
+	MinAge            int    `json:"min_age"` // Years, inclusive
+	MaxAge            int    `json:"max_age"` // Years, inclusive; 0 for no upper bound
+	IntervalMonths    int    `json:"interval_months" binding:"required"`
+	AppointmentTypeID uint   `json:"appointment_type_id" binding:"required"`
+	Specialty         string `json:"specialty" binding:"max=100"`
+}
+
+func (r careRuleRequest) toModel() *model.CareRule {
+	return &model.CareRule{
+		Name:              r.Name,
+		Description:       r.Description,
+		Sex:               r.Sex,
+		MinAge:            r.MinAge,
+		MaxAge:            r.MaxAge,
+		IntervalMonths:    r.IntervalMonths,
+		AppointmentTypeID: r.AppointmentTypeID,
+		Specialty:         r.Specialty,
+	}
+}
+
+type careRuleResponse struct {
+	ID                uint   `json:"id"`
+	Name              string `json:"name"`
+	Description       string `json:"description,omitempty"`
+	Sex               string `json:"sex,omitempty"`
+	MinAge            int    `json:"min_age"`
+	MaxAge            int    `json:"max_age"`
+	IntervalMonths    int    `json:"interval_months"`
+	AppointmentTypeID uint   `json:"appointment_type_id"`
+	Specialty         string `json:"specialty,omitempty"`
+	Active            bool   `json:"active"`
+	CreatedAt         string `json:"created_at"`
+	UpdatedAt         string `json:"updated_at"`
+}
+
+type careReminderResponse struct {
+	RuleID           uint                     `json:"rule_id"`
+	Name             string                   `json:"name"`
+	Description      string                   `json:"description,omitempty"`
+	DueAt            string                   `json:"due_at"`
+	LastVisitAt      *string                  `json:"last_visit_at,omitempty"`
+	NotifiedAt       string                   `json:"notified_at"`
+	SuggestedBooking suggestedBookingResponse `json:"suggested_booking"`
+}
+
+type suggestedBookingResponse struct {
+	AppointmentTypeID uint   `json:"appointment_type_id"`
+	AppointmentType   string `json:"appointment_type,omitempty"`
+	Duration          int    `json:"duration,omitempty"` // Minutes
+	Specialty         string `json:"specialty,omitempty"`
+}
+
+// Helper functions to convert models to responses
+func toCareRuleResponse(rule *model.CareRule) careRuleResponse {
+	return careRuleResponse{
+		ID:                rule.ID,
+		Name:              rule.Name,
+		Description:       rule.Description,
+		Sex:               rule.Sex,
+		MinAge:            rule.MinAge,
+		MaxAge:            rule.MaxAge,
+		IntervalMonths:    rule.IntervalMonths,
+		AppointmentTypeID: rule.AppointmentTypeID,
+		Specialty:         rule.Specialty,
+		Active:            rule.Active,
+		CreatedAt:         rule.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:         rule.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+func toCareReminderResponse(reminder *model.CareReminder) careReminderResponse {
+	response := careReminderResponse{
+		RuleID:      reminder.RuleID,
+		Name:        reminder.Rule.Name,
+		Description: reminder.Rule.Description,
+		DueAt:       reminder.DueAt.Format(time.RFC3339),
+		NotifiedAt:  reminder.NotifiedAt.Format(time.RFC3339),
+		SuggestedBooking: suggestedBookingResponse{
+			AppointmentTypeID: reminder.Rule.AppointmentTypeID,
+			Specialty:         reminder.Rule.Specialty,
+		},
+	}
+	if reminder.LastVisitAt != nil {
+		lastVisit := reminder.LastVisitAt.Format(time.RFC3339)
+		response.LastVisitAt = &lastVisit
+	}
+	if appointmentType := reminder.Rule.AppointmentType; appointmentType != nil {
+		response.SuggestedBooking.AppointmentType = appointmentType.Name
+		response.SuggestedBooking.Duration = appointmentType.Duration
+	}
+	return response
+}
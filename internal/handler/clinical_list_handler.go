@@ -0,0 +1,512 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/service"
+	"go.uber.org/zap"
+)
+
+// ClinicalListHandler handles allergy and medication list HTTP requests
+type ClinicalListHandler struct {
+	service service.ClinicalListService
+	logger  *zap.Logger
+}
+
+// NewClinicalListHandler creates a new allergy and medication list handler
+func NewClinicalListHandler(service service.ClinicalListService, logger *zap.Logger) *ClinicalListHandler {
+	return &ClinicalListHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// CreateAllergy godoc
+// @Summary Record allergy
+// @Description Record a substance a patient is allergic to, with its category, reaction, severity and onset date. Doctors treating the patient, the patient and their guardian can record allergies.
+// @Tags patients,allergies
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Patient ID (UUID)"
+// @Param request body allergyRequest true "Allergy"
+// @Success 201 {object} allergyResponse "Recorded allergy"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Router /patients/{id}/allergies [post]
+func (h *ClinicalListHandler) CreateAllergy(c *gin.Context) {
+	patientID, ok := clinicalListPatient(c)
+	if !ok {
+		return
+	}
+	input, ok := bindAllergyInput(c)
+	if !ok {
+		return
+	}
+
+	role, _ := c.Get("userRole")
+	userRole, _ := role.(model.Role)
+	allergy, err := h.service.CreateAllergy(c.Request.Context(), c.GetUint("userID"), userRole, patientID, input)
+	if err != nil {
+		h.clinicalListError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, toAllergyResponse(allergy))
+}
+
+// ListAllergies godoc
+// @Summary List allergies
+// @Description List a patient's allergies: active ones most severe first, then resolved ones. Access follows the patient's medical records.
+// @Tags patients,allergies
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Patient ID (UUID)"
+// @Param active query bool false "Only allergies that still apply"
+// @Success 200 {object} map[string]interface{} "Allergies"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Router /patients/{id}/allergies [get]
+func (h *ClinicalListHandler) ListAllergies(c *gin.Context) {
+	patientID, ok := clinicalListPatient(c)
+	if !ok {
+		return
+	}
+
+	role, _ := c.Get("userRole")
+	userRole, _ := role.(model.Role)
+	allergies, err := h.service.ListAllergies(c.Request.Context(), c.GetUint("userID"), userRole, patientID, c.Query("active") == "true")
+	if err != nil {
+		h.clinicalListError(c, err)
+		return
+	}
+
+	response := make([]allergyResponse, 0, len(allergies))
+	for _, allergy := range allergies {
+		response = append(response, toAllergyResponse(allergy))
+	}
+	c.JSON(http.StatusOK, gin.H{"allergies": response})
+}
+
+// UpdateAllergy godoc
+// @Summary Update allergy
+// @Description Replace what is recorded about one of a patient's allergies. Set resolved_date when it no longer applies.
+// @Tags patients,allergies
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Patient ID (UUID)"
+// @Param allergyID path string true "Allergy ID (UUID)"
+// @Param request body allergyRequest true "Allergy"
+// @Success 200 {object} allergyResponse "Updated allergy"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /patients/{id}/allergies/{allergyID} [put]
+func (h *ClinicalListHandler) UpdateAllergy(c *gin.Context) {
+	patientID, ok := clinicalListPatient(c)
+	if !ok {
+		return
+	}
+	allergyID, err := strconv.ParseUint(c.Param("allergyID"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid allergy ID"})
+		return
+	}
+	input, ok := bindAllergyInput(c)
+	if !ok {
+		return
+	}
+
+	role, _ := c.Get("userRole")
+	userRole, _ := role.(model.Role)
+	allergy, err := h.service.UpdateAllergy(c.Request.Context(), c.GetUint("userID"), userRole, patientID, uint(allergyID), input)
+	if err != nil {
+		h.clinicalListError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, toAllergyResponse(allergy))
+}
+
+// DeleteAllergy godoc
+// @Summary Delete allergy
+// @Description Delete an allergy recorded in error. Allergies that no longer apply should be given a resolved_date instead.
+// @Tags patients,allergies
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Patient ID (UUID)"
+// @Param allergyID path string true "Allergy ID (UUID)"
+// @Success 200 {object} map[string]string "Deleted"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /patients/{id}/allergies/{allergyID} [delete]
+func (h *ClinicalListHandler) DeleteAllergy(c *gin.Context) {
+	patientID, ok := clinicalListPatient(c)
+	if !ok {
+		return
+	}
+	allergyID, err := strconv.ParseUint(c.Param("allergyID"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid allergy ID"})
+		return
+	}
+
+	role, _ := c.Get("userRole")
+	userRole, _ := role.(model.Role)
+	if err := h.service.DeleteAllergy(c.Request.Context(), c.GetUint("userID"), userRole, patientID, uint(allergyID)); err != nil {
+		h.clinicalListError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "allergy deleted"})
+}
+
+// CreateMedication godoc
+// @Summary Add medication
+// @Description Add a medication the patient takes to their medication list, from the catalog with medication_id or by name, with its dosage, frequency, reason and start date. Doctors treating the patient, the patient and their guardian can maintain the list.
+// @Tags patients,medications
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Patient ID (UUID)"
+// @Param request body patientMedicationRequest true "Medication"
+// @Success 201 {object} patientMedicationResponse "Added medication"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Router /patients/{id}/medications [post]
+func (h *ClinicalListHandler) CreateMedication(c *gin.Context) {
+	patientID, ok := clinicalListPatient(c)
+	if !ok {
+		return
+	}
+	input, ok := bindMedicationInput(c)
+	if !ok {
+		return
+	}
+
+	role, _ := c.Get("userRole")
+	userRole, _ := role.(model.Role)
+	medication, err := h.service.CreateMedication(c.Request.Context(), c.GetUint("userID"), userRole, patientID, input)
+	if err != nil {
+		h.clinicalListError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, toPatientMedicationResponse(medication))
+}
+
+// ListMedications godoc
+// @Summary List medications
+// @Description List a patient's medication list: medications still taken first, then stopped ones. Access follows the patient's medical records.
+// @Tags patients,medications
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Patient ID (UUID)"
+// @Param active query bool false "Only medications still taken"
+// @Success 200 {object} map[string]interface{} "Medications"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Router /patients/{id}/medications [get]
+func (h *ClinicalListHandler) ListMedications(c *gin.Context) {
+	patientID, ok := clinicalListPatient(c)
+	if !ok {
+		return
+	}
+
+	role, _ := c.Get("userRole")
+	userRole, _ := role.(model.Role)
+	medications, err := h.service.ListMedications(c.Request.Context(), c.GetUint("userID"), userRole, patientID, c.Query("active") == "true")
+	if err != nil {
+		h.clinicalListError(c, err)
+		return
+	}
+
+	response := make([]patientMedicationResponse, 0, len(medications))
+	for _, medication := range medications {
+		response = append(response, toPatientMedicationResponse(medication))
+	}
+	c.JSON(http.StatusOK, gin.H{"medications": response})
+}
+
+// UpdateMedication godoc
+// @Summary Update medication
+// @Description Replace what is recorded about a medication on a patient's list. Set stop_date when the patient stops taking it.
+// @Tags patients,medications
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Patient ID (UUID)"
+// @Param medicationID path string true "Medication list entry ID (UUID)"
+// @Param request body patientMedicationRequest true "Medication"
+// @Success 200 {object} patientMedicationResponse "Updated medication"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /patients/{id}/medications/{medicationID} [put]
+func (h *ClinicalListHandler) UpdateMedication(c *gin.Context) {
+	patientID, ok := clinicalListPatient(c)
+	if !ok {
+		return
+	}
+	medicationID, err := strconv.ParseUint(c.Param("medicationID"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid medication ID"})
+		return
+	}
+	input, ok := bindMedicationInput(c)
+	if !ok {
+		return
+	}
+
+	role, _ := c.Get("userRole")
+	userRole, _ := role.(model.Role)
+	medication, err := h.service.UpdateMedication(c.Request.Context(), c.GetUint("userID"), userRole, patientID, uint(medicationID), input)
+	if err != nil {
+		h.clinicalListError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, toPatientMedicationResponse(medication))
+}
+
+// DeleteMedication godoc
+// @Summary Delete medication
+// @Description Remove a medication added to a patient's list in error. Medications the patient stopped taking should be given a stop_date instead.
+// @Tags patients,medications
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Patient ID (UUID)"
+// @Param medicationID path string true "Medication list entry ID (UUID)"
+// @Success 200 {object} map[string]string "Deleted"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /patients/{id}/medications/{medicationID} [delete]
+func (h *ClinicalListHandler) DeleteMedication(c *gin.Context) {
+	patientID, ok := clinicalListPatient(c)
+	if !ok {
+		return
+	}
+	medicationID, err := strconv.ParseUint(c.Param("medicationID"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid medication ID"})
+		return
+	}
+
+	role, _ := c.Get("userRole")
+	userRole, _ := role.(model.Role)
+	if err := h.service.DeleteMedication(c.Request.Context(), c.GetUint("userID"), userRole, patientID, uint(medicationID)); err != nil {
+		h.clinicalListError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "medication deleted"})
+}
+
+// clinicalListError maps an allergy or medication list service error to a response
+func (h *ClinicalListHandler) clinicalListError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrNotTreatingDoctor), errors.Is(err, service.ErrNotOwnMedicalRecord):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidAllergy), errors.Is(err, service.ErrInvalidPatientMedication):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case strings.HasSuffix(err.Error(), "not found"):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		h.logger.Error("Clinical list request failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to process request"})
+	}
+}
+
+// clinicalListPatient reads the patient ID from the path
+func clinicalListPatient(c *gin.Context) (uint, bool) {
+	patientID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+		return 0, false
+	}
+	return uint(patientID), true
+}
+
+// bindAllergyInput reads an allergy from the request body
+func bindAllergyInput(c *gin.Context) (service.AllergyInput, bool) {
+	var req allergyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return service.AllergyInput{}, false
+	}
+	onset, ok := parseListDate(c, "onset_date", req.OnsetDate)
+	if !ok {
+		return service.AllergyInput{}, false
+	}
+	resolved, ok := parseListDate(c, "resolved_date", req.ResolvedDate)
+	if !ok {
+		return service.AllergyInput{}, false
+	}
+	return service.AllergyInput{
+		Substance:    req.Substance,
+		Category:     model.AllergyCategory(req.Category),
+		Reaction:     req.Reaction,
+		Severity:     model.AllergySeverity(req.Severity),
+		OnsetDate:    onset,
+		ResolvedDate: resolved,
+	}, true
+}
+
+// bindMedicationInput reads a medication list entry from the request body
+func bindMedicationInput(c *gin.Context) (service.PatientMedicationInput, bool) {
+	var req patientMedicationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return service.PatientMedicationInput{}, false
+	}
+	start, ok := parseListDate(c, "start_date", req.StartDate)
+	if !ok {
+		return service.PatientMedicationInput{}, false
+	}
+	stop, ok := parseListDate(c, "stop_date", req.StopDate)
+	if !ok {
+		return service.PatientMedicationInput{}, false
+	}
+	return service.PatientMedicationInput{
+		MedicationID: req.MedicationID,
+		Name:         req.Name,
+		Dosage:       req.Dosage,
+		Frequency:    req.Frequency,
+		Reason:       req.Reason,
+		StartDate:    start,
+		StopDate:     stop,
+	}, true
+}
+
+// parseListDate parses an optional YYYY-MM-DD date of an allergy or medication
+func parseListDate(c *gin.Context, field, raw string) (*time.Time, bool) {
+	if raw == "" {
+		return nil, true
+	}
+	date, err := time.Parse("2006-01-02", raw)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + field + ", expected YYYY-MM-DD"})
+		return nil, false
+	}
+	return &date, true
+}
+
+// formatListDate formats an optional date of an allergy or medication
+func formatListDate(date *time.Time) string {
+	if date == nil {
+		return ""
+	}
+	return date.Format("2006-01-02")
+}
+
+type allergyRequest struct {
+	Substance    string `json:"substance" binding:"required"` // Generic drug name for medication allergies
+	Category     string `json:"category"`                     // medication, food, environmental or other (default)
+	Reaction     string `json:"reaction"`
+	Severity     string `json:"severity"`      // mild, moderate or severe
+	OnsetDate    string `json:"onset_date"`    // YYYY-MM-DD
+	ResolvedDate string `json:"resolved_date"` // YYYY-MM-DD, first day it no longer applies
+}
+
+type allergyResponse struct {
+	ID           string `json:"id"`
+	Substance    string `json:"substance"`
+	Category     string `json:"category"`
+	Reaction     string `json:"reaction,omitempty"`
+	Severity     string `json:"severity,omitempty"`
+	OnsetDate    string `json:"onset_date,omitempty"`
+	ResolvedDate string `json:"resolved_date,omitempty"`
+	Active       bool   `json:"active"`
+}
+
+func toAllergyResponse(allergy *model.Allergy) allergyResponse {
+	return allergyResponse{
+		ID:           allergy.PublicID,
+		Substance:    allergy.Substance,
+		Category:     string(allergy.Category),
+		Reaction:     allergy.Reaction,
+		Severity:     string(allergy.Severity),
+		OnsetDate:    formatListDate(allergy.OnsetDate),
+		ResolvedDate: formatListDate(allergy.ResolvedDate),
+		Active:       allergy.IsActive(time.Now()),
+	}
+}
+
+// activeAllergySummary is an active allergy shown alongside an appointment or medical record, so
+// clients can warn before prescribing
+type activeAllergySummary struct {
+	ID        string `json:"id"`
+	Substance string `json:"substance"`
+	Category  string `json:"category"`
+	Severity  string `json:"severity,omitempty"`
+	Reaction  string `json:"reaction,omitempty"`
+}
+
+// toActiveAllergies summarizes the patient's allergies, which repositories load with the active
+// ones only
+func toActiveAllergies(patient *model.Patient) []activeAllergySummary {
+	var summaries []activeAllergySummary
+	for _, allergy := range patient.Allergies {
+		summaries = append(summaries, activeAllergySummary{
+			ID:        allergy.PublicID,
+			Substance: allergy.Substance,
+			Category:  string(allergy.Category),
+			Severity:  string(allergy.Severity),
+			Reaction:  allergy.Reaction,
+		})
+	}
+	return summaries
+}
+
+type patientMedicationRequest struct {
+	MedicationID uint   `json:"medication_id"` // Catalog product; see GET /medications
+	Name         string `json:"name"`          // As reported, when not in the catalog
+	Dosage       string `json:"dosage"`
+	Frequency    string `json:"frequency"`
+	Reason       string `json:"reason"`
+	StartDate    string `json:"start_date"` // YYYY-MM-DD
+	StopDate     string `json:"stop_date"`  // YYYY-MM-DD, first day it is no longer taken
+}
+
+type patientMedicationResponse struct {
+	ID           string `json:"id"`
+	MedicationID *uint  `json:"medication_id,omitempty"`
+	Name         string `json:"name"`
+	Dosage       string `json:"dosage,omitempty"`
+	Frequency    string `json:"frequency,omitempty"`
+	Reason       string `json:"reason,omitempty"`
+	StartDate    string `json:"start_date,omitempty"`
+	StopDate     string `json:"stop_date,omitempty"`
+	Active       bool   `json:"active"`
+}
+
+func toPatientMedicationResponse(medication *model.PatientMedication) patientMedicationResponse {
+	return patientMedicationResponse{
+		ID:           medication.PublicID,
+		MedicationID: medication.MedicationID,
+		Name:         medication.Name,
+		Dosage:       medication.Dosage,
+		Frequency:    medication.Frequency,
+		Reason:       medication.Reason,
+		StartDate:    formatListDate(medication.StartDate),
+		StopDate:     formatListDate(medication.StopDate),
+		Active:       medication.IsActive(time.Now()),
+	}
+}
@@ -0,0 +1,239 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/service"
+	"go.uber.org/zap"
+)
+
+// ConsentHandler handles policy and consent related HTTP requests
+type ConsentHandler struct {
+	service service.ConsentService
+	logger  *zap.Logger
+}
+
+// NewConsentHandler creates a new consent handler
+func NewConsentHandler(service service.ConsentService, logger *zap.Logger) *ConsentHandler {
+	return &ConsentHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// GetPolicies godoc
+// @Summary Get current policies
+// @Description Get the currently published terms of service and privacy policy versions
+// @Tags consents
+// @Produce json
+// @Success 200 {array} model.Policy "Current policies"
+// @Router /policies [get]
+func (h *ConsentHandler) GetPolicies(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"policies": h.service.GetCurrentPolicies(c.Request.Context())})
+}
+
+// GetMyConsents godoc
+// @Summary Get my consents
+// @Description Get the consents recorded for the authenticated user and any outstanding required policies
+// @Tags consents
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "Consents"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /consents [get]
+func (h *ConsentHandler) GetMyConsents(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	consents, err := h.service.GetUserConsents(c.Request.Context(), userID.(uint))
+	if err != nil {
+		h.logger.Error("Failed to get consents", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get consents"})
+		return
+	}
+
+	missing, err := h.service.GetMissingConsents(c.Request.Context(), userID.(uint))
+	if err != nil {
+		h.logger.Error("Failed to get missing consents", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get consents"})
+		return
+	}
+
+	response := make([]consentResponse, 0, len(consents))
+	for _, consent := range consents {
+		response = append(response, toConsentResponse(consent))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"consents":         response,
+		"missing_policies": missing,
+	})
+}
+
+// AcceptPolicy godoc
+// @Summary Accept a policy
+// @Description Record the authenticated user's acceptance of the current version of a policy
+// @Tags consents
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param consent body acceptPolicyRequest true "Policy to accept"
+// @Success 201 {object} consentResponse "Recorded consent"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Router /consents [post]
+func (h *ConsentHandler) AcceptPolicy(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req acceptPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	consent, err := h.service.AcceptPolicy(
+		c.Request.Context(),
+		userID.(uint),
+		model.PolicyType(req.PolicyType),
+		req.Version,
+		c.ClientIP(),
+		c.Request.UserAgent(),
+	)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, toConsentResponse(consent))
+}
+
+// GetMarketingConsents godoc
+// @Summary Get my marketing preferences
+// @Description Get whether the authenticated user agreed to receive promotional messages on each channel. Appointment reminders and other transactional messages do not depend on these.
+// @Tags consents
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} marketingConsentResponse "Choice per channel"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /consents/marketing [get]
+func (h *ConsentHandler) GetMarketingConsents(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	consents, err := h.service.GetMarketingConsents(c.Request.Context(), userID.(uint))
+	if err != nil {
+		h.logger.Error("Failed to get marketing consents", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get marketing consents"})
+		return
+	}
+
+	// Channels the user never chose for are opted out
+	response := []marketingConsentResponse{{Channel: model.ChannelEmail}, {Channel: model.ChannelSMS}}
+	for _, consent := range consents {
+		for i := range response {
+			if response[i].Channel == consent.Channel {
+				response[i] = toMarketingConsentResponse(consent)
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// SetMarketingConsent godoc
+// @Summary Opt in to or out of marketing
+// @Description Record whether the authenticated user agrees to receive promotional messages on a channel. Every change is kept with the time and where it was made from.
+// @Tags consents
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param channel path string true "email or sms"
+// @Param consent body marketingConsentRequest true "Choice"
+// @Success 200 {object} marketingConsentResponse "Recorded choice"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Router /consents/marketing/{channel} [put]
+func (h *ConsentHandler) SetMarketingConsent(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req marketingConsentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	consent, err := h.service.SetMarketingConsent(
+		c.Request.Context(),
+		userID.(uint),
+		c.Param("channel"),
+		*req.Granted,
+		c.ClientIP(),
+		c.Request.UserAgent(),
+	)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, toMarketingConsentResponse(consent))
+}
+
+// Request and response models
+type acceptPolicyRequest struct {
+	PolicyType string `json:"policy_type" binding:"required"`
+	Version    string `json:"version" binding:"required"`
+}
+
+type consentResponse struct {
+	ID         uint   `json:"id"`
+	PolicyType string `json:"policy_type"`
+	Version    string `json:"version"`
+	AcceptedAt string `json:"accepted_at"`
+}
+
+type marketingConsentRequest struct {
+	Granted *bool `json:"granted" binding:"required"`
+}
+
+type marketingConsentResponse struct {
+	Channel   string `json:"channel"`
+	Granted   bool   `json:"granted"`
+	UpdatedAt string `json:"updated_at,omitempty"` // When the choice was made; empty if never
+}
+
+func toMarketingConsentResponse(consent *model.MarketingConsent) marketingConsentResponse {
+	return marketingConsentResponse{
+		Channel:   consent.Channel,
+		Granted:   consent.Granted,
+		UpdatedAt: consent.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+// Helper function to convert model to response
+func toConsentResponse(consent *model.Consent) consentResponse {
+	return consentResponse{
+		ID:         consent.ID,
+		PolicyType: string(consent.PolicyType),
+		Version:    consent.Version,
+		AcceptedAt: consent.AcceptedAt.Format(time.RFC3339),
+	}
+}
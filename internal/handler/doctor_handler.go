@@ -1,8 +1,11 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/whitewalker-sa/ehass/internal/model"
@@ -12,15 +15,17 @@ import (
 
 // DoctorHandler handles doctor-related HTTP requests
 type DoctorHandler struct {
-	service service.DoctorService
-	logger  *zap.Logger
+	service      service.DoctorService
+	translations service.TranslationService
+	logger       *zap.Logger
 }
 
 // NewDoctorHandler creates a new doctor handler
-func NewDoctorHandler(service service.DoctorService, logger *zap.Logger) *DoctorHandler {
+func NewDoctorHandler(service service.DoctorService, translations service.TranslationService, logger *zap.Logger) *DoctorHandler {
 	return &DoctorHandler{
-		service: service,
-		logger:  logger,
+		service:      service,
+		translations: translations,
+		logger:       logger,
 	}
 }
 
@@ -78,7 +83,7 @@ func (h *DoctorHandler) CreateDoctor(c *gin.Context) {
 // @Description Get a doctor profile by ID
 // @Tags doctors
 // @Produce json
-// @Param id path int true "Doctor ID"
+// @Param id path string true "Doctor ID (UUID)"
 // @Success 200 {object} doctorResponse "Doctor profile"
 // @Failure 400 {object} map[string]string "Bad request"
 // @Failure 404 {object} map[string]string "Not found"
@@ -97,6 +102,7 @@ func (h *DoctorHandler) GetDoctor(c *gin.Context) {
 		return
 	}
 
+	localizeDoctors(c, h.translations, h.logger, doctor)
 	c.JSON(http.StatusOK, toDoctorResponse(doctor))
 }
 
@@ -105,14 +111,14 @@ func (h *DoctorHandler) GetDoctor(c *gin.Context) {
 // @Description Get a doctor profile by user ID
 // @Tags doctors
 // @Produce json
-// @Param userId path int true "User ID"
+// @Param userID path string true "User ID (UUID)"
 // @Success 200 {object} doctorResponse "Doctor profile"
 // @Failure 400 {object} map[string]string "Bad request"
 // @Failure 404 {object} map[string]string "Not found"
 // @Failure 500 {object} map[string]string "Internal server error"
-// @Router /doctors/user/{userId} [get]
+// @Router /doctors/user/{userID} [get]
 func (h *DoctorHandler) GetDoctorByUser(c *gin.Context) {
-	id, err := strconv.ParseUint(c.Param("userId"), 10, 32)
+	id, err := strconv.ParseUint(c.Param("userID"), 10, 32)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
 		return
@@ -124,43 +130,104 @@ func (h *DoctorHandler) GetDoctorByUser(c *gin.Context) {
 		return
 	}
 
+	localizeDoctors(c, h.translations, h.logger, doctor)
 	c.JSON(http.StatusOK, toDoctorResponse(doctor))
 }
 
 // ListDoctors godoc
 // @Summary List all doctors
-// @Description Get a paginated list of all doctors
+// @Description Get a paginated list of all doctors, or with ids the listed doctors in one call. IDs that match no doctor are returned in not_found.
 // @Tags doctors
 // @Produce json
 // @Param page query int false "Page number" default(1)
 // @Param pageSize query int false "Page size" default(10)
+// @Param ids query string false "Comma-separated doctor IDs (UUIDs), at most 100; disables pagination"
+// @Param fields query string false "Comma-separated fields to return, e.g. name,specialty; id is always included"
+// @Param sort query string false "Comma-separated sort keys, - for descending: name, specialty, experience, created_at"
 // @Success 200 {array} doctorResponse "List of doctors"
+// @Failure 400 {object} map[string]string "Bad request"
 // @Failure 500 {object} map[string]string "Internal server error"
 // @Router /doctors [get]
 func (h *DoctorHandler) ListDoctors(c *gin.Context) {
+	if ids := c.Query("ids"); ids != "" {
+		h.listDoctorsByIDs(c, strings.Split(ids, ","))
+		return
+	}
+
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
 
-	doctors, total, err := h.service.GetAllDoctors(c.Request.Context(), page, pageSize)
+	query := parseListQuery(c)
+	doctors, total, err := h.service.GetAllDoctors(c.Request.Context(), page, pageSize, query)
 	if err != nil {
+		if isListQueryError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		h.logger.Error("Failed to get doctors", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get doctors"})
 		return
 	}
 
+	h.respondDoctorPage(c, doctors, total, page, pageSize, query.Fields)
+}
+
+// respondDoctorPage writes a page of doctors, reduced to the selected fields if any
+func (h *DoctorHandler) respondDoctorPage(c *gin.Context, doctors []*model.Doctor, total int64, page, pageSize int, fields []string) {
+	localizeDoctors(c, h.translations, h.logger, doctors...)
 	response := make([]doctorResponse, 0, len(doctors))
 	for _, doctor := range doctors {
 		response = append(response, toDoctorResponse(doctor))
 	}
 
+	var items interface{} = response
+	if fields != nil {
+		sparse, err := sparseItems(response, fields)
+		if err != nil {
+			h.logger.Error("Failed to select doctor fields", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get doctors"})
+			return
+		}
+		items = sparse
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"doctors": response,
+		"doctors": items,
 		"total":   total,
 		"page":    page,
 		"size":    pageSize,
 	})
 }
 
+// listDoctorsByIDs responds with the requested doctors in the order requested
+func (h *DoctorHandler) listDoctorsByIDs(c *gin.Context, ids []string) {
+	for i := range ids {
+		ids[i] = strings.TrimSpace(ids[i])
+	}
+
+	doctors, missing, err := h.service.GetDoctorsByPublicIDs(c.Request.Context(), ids)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidPublicID) || errors.Is(err, service.ErrBatchTooLarge) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.Error("Failed to get doctors by ID", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get doctors"})
+		return
+	}
+
+	localizeDoctors(c, h.translations, h.logger, doctors...)
+	response := make([]doctorResponse, 0, len(doctors))
+	for _, doctor := range doctors {
+		response = append(response, toDoctorResponse(doctor))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"doctors":   response,
+		"not_found": missing,
+	})
+}
+
 // ListDoctorsBySpecialty godoc
 // @Summary List doctors by specialty
 // @Description Get a paginated list of doctors by specialty
@@ -169,7 +236,10 @@ func (h *DoctorHandler) ListDoctors(c *gin.Context) {
 // @Param specialty path string true "Specialty"
 // @Param page query int false "Page number" default(1)
 // @Param pageSize query int false "Page size" default(10)
+// @Param fields query string false "Comma-separated fields to return, e.g. name,specialty; id is always included"
+// @Param sort query string false "Comma-separated sort keys, - for descending: name, specialty, experience, created_at"
 // @Success 200 {array} doctorResponse "List of doctors"
+// @Failure 400 {object} map[string]string "Bad request"
 // @Failure 500 {object} map[string]string "Internal server error"
 // @Router /doctors/specialty/{specialty} [get]
 func (h *DoctorHandler) ListDoctorsBySpecialty(c *gin.Context) {
@@ -177,24 +247,19 @@ func (h *DoctorHandler) ListDoctorsBySpecialty(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
 
-	doctors, total, err := h.service.GetDoctorsBySpecialty(c.Request.Context(), specialty, page, pageSize)
+	query := parseListQuery(c)
+	doctors, total, err := h.service.GetDoctorsBySpecialty(c.Request.Context(), specialty, page, pageSize, query)
 	if err != nil {
+		if isListQueryError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		h.logger.Error("Failed to get doctors by specialty", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get doctors"})
 		return
 	}
 
-	response := make([]doctorResponse, 0, len(doctors))
-	for _, doctor := range doctors {
-		response = append(response, toDoctorResponse(doctor))
-	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"doctors": response,
-		"total":   total,
-		"page":    page,
-		"size":    pageSize,
-	})
+	h.respondDoctorPage(c, doctors, total, page, pageSize, query.Fields)
 }
 
 // UpdateDoctor godoc
@@ -204,7 +269,7 @@ func (h *DoctorHandler) ListDoctorsBySpecialty(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Security BearerAuth
-// @Param id path int true "Doctor ID"
+// @Param id path string true "Doctor ID (UUID)"
 // @Param doctor body updateDoctorRequest true "Doctor Information"
 // @Success 200 {object} doctorResponse "Updated doctor profile"
 // @Failure 400 {object} map[string]string "Bad request"
@@ -310,7 +375,7 @@ func (h *DoctorHandler) UpdateDoctorProfile(c *gin.Context) {
 // @Description Delete a doctor profile by ID
 // @Tags doctors
 // @Security BearerAuth
-// @Param id path int true "Doctor ID"
+// @Param id path string true "Doctor ID (UUID)"
 // @Success 200 {object} map[string]string "Success message"
 // @Failure 400 {object} map[string]string "Bad request"
 // @Failure 401 {object} map[string]string "Unauthorized"
@@ -349,6 +414,198 @@ func (h *DoctorHandler) DeleteDoctor(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "doctor deleted successfully"})
 }
 
+// SetStatus godoc
+// @Summary Set doctor status
+// @Description Set the signed-in doctor's status for today: available, in_consultation, on_break or off_site. An empty status clears it, and the status shown on the front-desk view is derived from the doctor's schedule and appointments again, as it is on days the doctor has not set one.
+// @Tags doctors
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Doctor ID (UUID)"
+// @Param request body doctorStatusRequest true "Status"
+// @Success 200 {object} doctorStatusResponse "Doctor status"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /doctors/{id}/status [put]
+func (h *DoctorHandler) SetStatus(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid doctor ID"})
+		return
+	}
+
+	var req doctorStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	doctor, err := h.service.SetStatus(c.Request.Context(), uint(id), c.GetUint("userID"), model.DoctorStatus(req.Status))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrNotOwnStatus):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case err.Error() == "doctor not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	response := doctorStatusResponse{ID: doctor.PublicID, Status: string(doctor.Status)}
+	if doctor.StatusSetAt != nil {
+		setAt := doctor.StatusSetAt.In(requestLocation(c)).Format(time.RFC3339)
+		response.SetAt = &setAt
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// SetAutoConfirm godoc
+// @Summary Set booking auto-confirm
+// @Description Set whether new bookings with the signed-in doctor are confirmed straight away, as long as their intake checklist is complete, instead of waiting for the doctor to confirm or decline them
+// @Tags doctors
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Doctor ID (UUID)"
+// @Param request body autoConfirmRequest true "Auto-confirm setting"
+// @Success 200 {object} autoConfirmRequest "Auto-confirm setting"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /doctors/{id}/auto-confirm [put]
+func (h *DoctorHandler) SetAutoConfirm(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid doctor ID"})
+		return
+	}
+
+	var req autoConfirmRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	doctor, err := h.service.SetAutoConfirm(c.Request.Context(), uint(id), c.GetUint("userID"), *req.AutoConfirm)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrNotOwnSettings):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case err.Error() == "doctor not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			h.logger.Error("Failed to set auto-confirm", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update doctor"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, autoConfirmRequest{AutoConfirm: &doctor.AutoConfirm})
+}
+
+// SetScheduling godoc
+// @Summary Set consultation length, buffer and overbooking
+// @Description Set the signed-in doctor's consultation length, used for bookings without an appointment type and for availability windows without a slot duration, the buffer time kept free after each appointment, and how many bookings they accept for the same time. A slot_length of 0 uses the clinic's default appointment length. Clinics that overbook to make up for no-shows set max_parallel_bookings above 1.
+// @Tags doctors
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Doctor ID (UUID)"
+// @Param request body schedulingRequest true "Scheduling settings"
+// @Success 200 {object} schedulingRequest "Scheduling settings"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /doctors/{id}/scheduling [put]
+func (h *DoctorHandler) SetScheduling(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid doctor ID"})
+		return
+	}
+
+	var req schedulingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	doctor, err := h.service.SetScheduling(c.Request.Context(), uint(id), c.GetUint("userID"), req.SlotLength, req.BufferMinutes, req.MaxParallelBookings)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrNotOwnSettings):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrInvalidScheduling):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case err.Error() == "doctor not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			h.logger.Error("Failed to set scheduling", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update doctor"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, schedulingRequest{
+		SlotLength:          doctor.SlotLength,
+		BufferMinutes:       doctor.BufferMinutes,
+		MaxParallelBookings: doctor.Capacity(),
+	})
+}
+
+// SetCancellationCutoff godoc
+// @Summary Set cancellation cutoff
+// @Description Set how many minutes before the start the signed-in doctor's appointments can be cancelled on time, overriding the clinic's cutoff. Whether late cancellations are refused or charged stays a clinic setting. A null cutoff goes back to the clinic's.
+// @Tags doctors
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Doctor ID (UUID)"
+// @Param request body cancellationCutoffRequest true "Cancellation cutoff"
+// @Success 200 {object} cancellationCutoffRequest "Cancellation cutoff"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /doctors/{id}/cancellation-cutoff [put]
+func (h *DoctorHandler) SetCancellationCutoff(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid doctor ID"})
+		return
+	}
+
+	var req cancellationCutoffRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	doctor, err := h.service.SetCancellationCutoff(c.Request.Context(), uint(id), c.GetUint("userID"), req.CutoffMinutes)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrNotOwnSettings):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrInvalidScheduling):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case err.Error() == "doctor not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			h.logger.Error("Failed to set cancellation cutoff", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update doctor"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, cancellationCutoffRequest{CutoffMinutes: doctor.CancellationCutoff})
+}
+
 // Request and response models
 type createDoctorRequest struct {
 	Specialty   string `json:"specialty" binding:"required"`
@@ -374,9 +631,33 @@ type updateDoctorProfileRequest struct {
 	Experience int    `json:"experience"`
 }
 
+type doctorStatusRequest struct {
+	Status string `json:"status"` // Empty to clear
+}
+
+type autoConfirmRequest struct {
+	AutoConfirm *bool `json:"auto_confirm" binding:"required"`
+}
+
+type schedulingRequest struct {
+	SlotLength          int `json:"slot_length"`           // Consultation length in minutes; 0 for the clinic's default
+	BufferMinutes       int `json:"buffer_minutes"`        // Time kept free after each appointment
+	MaxParallelBookings int `json:"max_parallel_bookings"` // Bookings accepted for the same time; 0 or 1 for no overbooking
+}
+
+type cancellationCutoffRequest struct {
+	CutoffMinutes *int `json:"cutoff_minutes"` // Minutes before the start after which cancelling is late; null for the clinic's cutoff
+}
+
+type doctorStatusResponse struct {
+	ID     string  `json:"id"`
+	Status string  `json:"status"`
+	SetAt  *string `json:"set_at,omitempty"`
+}
+
 type doctorResponse struct {
-	ID          uint   `json:"id"`
-	UserID      uint   `json:"user_id"`
+	ID          string `json:"id"`
+	UserID      string `json:"user_id"`
 	Name        string `json:"name"`
 	Email       string `json:"email"`
 	Specialty   string `json:"specialty"`
@@ -390,8 +671,8 @@ type doctorResponse struct {
 // Helper function to convert model to response
 func toDoctorResponse(doctor *model.Doctor) doctorResponse {
 	return doctorResponse{
-		ID:          doctor.ID,
-		UserID:      doctor.UserID,
+		ID:          doctor.PublicID,
+		UserID:      doctor.User.PublicID,
 		Name:        doctor.User.Name,
 		Email:       doctor.User.Email,
 		Specialty:   doctor.Specialty,
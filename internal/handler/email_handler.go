@@ -0,0 +1,209 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/service"
+	"go.uber.org/zap"
+)
+
+// EmailHandler handles email delivery webhooks and the delivery status admin view
+type EmailHandler struct {
+	service service.EmailDeliveryService
+	logger  *zap.Logger
+}
+
+// NewEmailHandler creates a new email handler
+func NewEmailHandler(service service.EmailDeliveryService, logger *zap.Logger) *EmailHandler {
+	return &EmailHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// ReceiveEvents godoc
+// @Summary Receive email delivery events
+// @Description Webhook for the email provider to report deliveries, bounces and complaints. Hard bounces and complaints suppress the address. Authenticated by the X-Webhook-Secret header.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param X-Webhook-Secret header string true "Shared webhook secret"
+// @Param request body emailEventsRequest true "Delivery events"
+// @Success 200 {object} map[string]int "Number of events applied"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /webhooks/email [post]
+func (h *EmailHandler) ReceiveEvents(c *gin.Context) {
+	var req emailEventsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	events := make([]service.EmailEvent, len(req.Events))
+	for i, event := range req.Events {
+		events[i] = service.EmailEvent{
+			Type:       service.EmailEventType(event.Type),
+			MessageID:  event.MessageID,
+			Email:      event.Email,
+			HardBounce: event.BounceType == "hard",
+			Reason:     event.Reason,
+		}
+	}
+
+	applied, err := h.service.ProcessEvents(c.Request.Context(), events)
+	if err != nil {
+		h.logger.Error("Failed to process email events", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to process email events"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"applied": applied})
+}
+
+// ListMessages godoc
+// @Summary List outbound emails
+// @Description List recorded outbound emails with their delivery status, newest first
+// @Tags admin,emails
+// @Produce json
+// @Security BearerAuth
+// @Param recipient query string false "Recipient address"
+// @Param status query string false "Delivery status (sent, failed, suppressed, delivered, bounced, complained)"
+// @Param page query int false "Page number" default(1)
+// @Param pageSize query int false "Page size" default(20)
+// @Success 200 {object} map[string]interface{} "Outbound emails"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /admin/emails [get]
+func (h *EmailHandler) ListMessages(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
+
+	messages, total, err := h.service.ListMessages(c.Request.Context(), c.Query("recipient"),
+		model.EmailStatus(c.Query("status")), page, pageSize)
+	if err != nil {
+		h.logger.Error("Failed to list emails", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list emails"})
+		return
+	}
+
+	response := make([]emailMessageResponse, 0, len(messages))
+	for _, message := range messages {
+		response = append(response, emailMessageResponse{
+			Recipient: message.Recipient,
+			Template:  message.Template,
+			Subject:   message.Subject,
+			Status:    string(message.Status),
+			Detail:    message.Detail,
+			SentAt:    message.CreatedAt.Format(time.RFC3339),
+			UpdatedAt: message.UpdatedAt.Format(time.RFC3339),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"emails": response,
+		"total":  total,
+		"page":   page,
+		"size":   pageSize,
+	})
+}
+
+// ListSuppressions godoc
+// @Summary List suppressed addresses
+// @Description List addresses that no longer receive email after a hard bounce or complaint
+// @Tags admin,emails
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param pageSize query int false "Page size" default(20)
+// @Success 200 {object} map[string]interface{} "Suppressed addresses"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /admin/email-suppressions [get]
+func (h *EmailHandler) ListSuppressions(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
+
+	suppressions, total, err := h.service.ListSuppressions(c.Request.Context(), page, pageSize)
+	if err != nil {
+		h.logger.Error("Failed to list email suppressions", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list email suppressions"})
+		return
+	}
+
+	response := make([]emailSuppressionResponse, 0, len(suppressions))
+	for _, suppression := range suppressions {
+		response = append(response, emailSuppressionResponse{
+			Email:     suppression.Email,
+			Reason:    string(suppression.Reason),
+			Detail:    suppression.Detail,
+			CreatedAt: suppression.CreatedAt.Format(time.RFC3339),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"suppressions": response,
+		"total":        total,
+		"page":         page,
+		"size":         pageSize,
+	})
+}
+
+// RemoveSuppression godoc
+// @Summary Remove suppression
+// @Description Let a suppressed address receive email again
+// @Tags admin,emails
+// @Produce json
+// @Security BearerAuth
+// @Param email path string true "Email address"
+// @Success 200 {object} map[string]string "Suppression removed"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /admin/email-suppressions/{email} [delete]
+func (h *EmailHandler) RemoveSuppression(c *gin.Context) {
+	if err := h.service.RemoveSuppression(c.Request.Context(), c.Param("email")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Suppression removed"})
+}
+
+// Request and response models
+
+type emailEventRequest struct {
+	Type       string `json:"type" binding:"required,oneof=delivered bounce complaint"`
+	MessageID  string `json:"message_id"`
+	Email      string `json:"email"`
+	BounceType string `json:"bounce_type" binding:"omitempty,oneof=hard soft"`
+	Reason     string `json:"reason"`
+}
+
+type emailEventsRequest struct {
+	Events []emailEventRequest `json:"events" binding:"required,dive"`
+}
+
+type emailMessageResponse struct {
+	Recipient string `json:"recipient"`
+	Template  string `json:"template"`
+	Subject   string `json:"subject"`
+	Status    string `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	SentAt    string `json:"sent_at"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+type emailSuppressionResponse struct {
+	Email     string `json:"email"`
+	Reason    string `json:"reason"`
+	Detail    string `json:"detail,omitempty"`
+	CreatedAt string `json:"created_at"`
+}
@@ -0,0 +1,269 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/service"
+	"github.com/whitewalker-sa/ehass/pkg/fhir"
+	"go.uber.org/zap"
+)
+
+// FHIRHandler handles FHIR R4 read and search HTTP requests from EHR systems
+type FHIRHandler struct {
+	service service.FHIRService
+	logger  *zap.Logger
+}
+
+// NewFHIRHandler creates a new FHIR handler
+func NewFHIRHandler(service service.FHIRService, logger *zap.Logger) *FHIRHandler {
+	return &FHIRHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// GetCapabilityStatement godoc
+// @Summary FHIR capability statement
+// @Description Describe the FHIR R4 resources served, their interactions and search parameters
+// @Tags fhir
+// @Produce application/fhir+json
+// @Success 200 {object} fhir.CapabilityStatement "Capability statement"
+// @Router /fhir/metadata [get]
+func (h *FHIRHandler) GetCapabilityStatement(c *gin.Context) {
+	h.respond(c, h.service.CapabilityStatement())
+}
+
+// ReadPatient godoc
+// @Summary Read FHIR Patient
+// @Description Read a patient as a FHIR R4 Patient resource
+// @Tags fhir
+// @Produce application/fhir+json
+// @Security BearerAuth
+// @Param id path string true "Patient ID (UUID)"
+// @Success 200 {object} fhir.Patient "Patient"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} fhir.OperationOutcome "Not found"
+// @Router /fhir/Patient/{id} [get]
+func (h *FHIRHandler) ReadPatient(c *gin.Context) {
+	patient, err := h.service.ReadPatient(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.fhirError(c, err)
+		return
+	}
+	h.respond(c, patient)
+}
+
+// SearchPatients godoc
+// @Summary Search FHIR Patients
+// @Description Search patients by _id, or by name, email, phone or birthdate, all given parameters having to match; gender narrows a search by the others
+// @Tags fhir
+// @Produce application/fhir+json
+// @Security BearerAuth
+// @Param _id query string false "Patient IDs, comma-separated"
+// @Param name query string false "Part of the name"
+// @Param email query string false "Part of the email address"
+// @Param phone query string false "Digits of the phone number"
+// @Param birthdate query string false "Day of birth, YYYY-MM-DD"
+// @Param gender query string false "male, female, other or unknown"
+// @Param _count query int false "Results (default 20, max 100)"
+// @Success 200 {object} fhir.Bundle "Search results"
+// @Failure 400 {object} fhir.OperationOutcome "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Router /fhir/Patient [get]
+func (h *FHIRHandler) SearchPatients(c *gin.Context) {
+	bundle, err := h.service.SearchPatients(c.Request.Context(), fhirSearch(c))
+	if err != nil {
+		h.fhirError(c, err)
+		return
+	}
+	h.respond(c, bundle)
+}
+
+// ReadPractitioner godoc
+// @Summary Read FHIR Practitioner
+// @Description Read a doctor as a FHIR R4 Practitioner resource
+// @Tags fhir
+// @Produce application/fhir+json
+// @Security BearerAuth
+// @Param id path string true "Doctor ID (UUID)"
+// @Success 200 {object} fhir.Practitioner "Practitioner"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} fhir.OperationOutcome "Not found"
+// @Router /fhir/Practitioner/{id} [get]
+func (h *FHIRHandler) ReadPractitioner(c *gin.Context) {
+	practitioner, err := h.service.ReadPractitioner(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.fhirError(c, err)
+		return
+	}
+	h.respond(c, practitioner)
+}
+
+// SearchPractitioners godoc
+// @Summary Search FHIR Practitioners
+// @Description Search doctors by _id or name, or page through them all by name
+// @Tags fhir
+// @Produce application/fhir+json
+// @Security BearerAuth
+// @Param _id query string false "Doctor IDs, comma-separated"
+// @Param name query string false "Part of the name"
+// @Param _count query int false "Results per page (default 20, max 100)"
+// @Param _offset query int false "Results to skip"
+// @Success 200 {object} fhir.Bundle "Search results"
+// @Failure 400 {object} fhir.OperationOutcome "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Router /fhir/Practitioner [get]
+func (h *FHIRHandler) SearchPractitioners(c *gin.Context) {
+	bundle, err := h.service.SearchPractitioners(c.Request.Context(), fhirSearch(c))
+	if err != nil {
+		h.fhirError(c, err)
+		return
+	}
+	h.respond(c, bundle)
+}
+
+// ReadAppointment godoc
+// @Summary Read FHIR Appointment
+// @Description Read an appointment as a FHIR R4 Appointment resource. Access follows the patient's medical records.
+// @Tags fhir
+// @Produce application/fhir+json
+// @Security BearerAuth
+// @Param id path string true "Appointment ID (UUID)"
+// @Success 200 {object} fhir.Appointment "Appointment"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} fhir.OperationOutcome "Forbidden"
+// @Failure 404 {object} fhir.OperationOutcome "Not found"
+// @Router /fhir/Appointment/{id} [get]
+func (h *FHIRHandler) ReadAppointment(c *gin.Context) {
+	role, _ := c.Get("userRole")
+	userRole, _ := role.(model.Role)
+	appointment, err := h.service.ReadAppointment(c.Request.Context(), c.GetUint("userID"), userRole, c.Param("id"))
+	if err != nil {
+		h.fhirError(c, err)
+		return
+	}
+	h.respond(c, appointment)
+}
+
+// SearchAppointments godoc
+// @Summary Search FHIR Appointments
+// @Description Search appointments by patient, practitioner, status and scheduled start, earliest first. Dates without a time are read in your timezone. Access follows the patients' medical records: only admins can search without naming patients, and appointments looked up by _id of patients you cannot read are left out.
+// @Tags fhir
+// @Produce application/fhir+json
+// @Security BearerAuth
+// @Param _id query string false "Appointment IDs, comma-separated"
+// @Param patient query string false "Patient reference, Patient/{id} or the ID"
+// @Param practitioner query string false "Practitioner reference, Practitioner/{id} or the ID"
+// @Param status query string false "pending, booked, checked-in, fulfilled, cancelled or noshow, comma-separated"
+// @Param date query string false "Scheduled start with an eq, gt, ge, lt or le prefix, e.g. ge2025-06-01; repeatable"
+// @Param _count query int false "Results per page (default 20, max 100)"
+// @Param _offset query int false "Results to skip"
+// @Success 200 {object} fhir.Bundle "Search results"
+// @Failure 400 {object} fhir.OperationOutcome "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} fhir.OperationOutcome "Forbidden"
+// @Router /fhir/Appointment [get]
+func (h *FHIRHandler) SearchAppointments(c *gin.Context) {
+	role, _ := c.Get("userRole")
+	userRole, _ := role.(model.Role)
+	bundle, err := h.service.SearchAppointments(c.Request.Context(), c.GetUint("userID"), userRole, fhirSearch(c))
+	if err != nil {
+		h.fhirError(c, err)
+		return
+	}
+	h.respond(c, bundle)
+}
+
+// ReadObservation godoc
+// @Summary Read FHIR Observation
+// @Description Read a vital sign reading as a FHIR R4 Observation coded in LOINC. Access follows the patient's medical records.
+// @Tags fhir
+// @Produce application/fhir+json
+// @Security BearerAuth
+// @Param id path string true "Vital sign ID (UUID)"
+// @Success 200 {object} fhir.Observation "Observation"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} fhir.OperationOutcome "Forbidden"
+// @Failure 404 {object} fhir.OperationOutcome "Not found"
+// @Router /fhir/Observation/{id} [get]
+func (h *FHIRHandler) ReadObservation(c *gin.Context) {
+	role, _ := c.Get("userRole")
+	userRole, _ := role.(model.Role)
+	observation, err := h.service.ReadObservation(c.Request.Context(), c.GetUint("userID"), userRole, c.Param("id"))
+	if err != nil {
+		h.fhirError(c, err)
+		return
+	}
+	h.respond(c, observation)
+}
+
+// SearchObservations godoc
+// @Summary Search FHIR Observations
+// @Description List a patient's vital sign readings oldest first, optionally of one LOINC code and measured in a range. Only the latest readings up to _count are listed. Access follows the patient's medical records.
+// @Tags fhir
+// @Produce application/fhir+json
+// @Security BearerAuth
+// @Param patient query string true "Patient reference, Patient/{id} or the ID"
+// @Param code query string false "LOINC code, e.g. 8867-4 or http://loinc.org|8867-4"
+// @Param date query string false "Measured at, with an eq, gt, ge, lt or le prefix; repeatable"
+// @Param _count query int false "Latest readings (default 20, max 100)"
+// @Success 200 {object} fhir.Bundle "Search results"
+// @Failure 400 {object} fhir.OperationOutcome "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} fhir.OperationOutcome "Forbidden"
+// @Router /fhir/Observation [get]
+func (h *FHIRHandler) SearchObservations(c *gin.Context) {
+	role, _ := c.Get("userRole")
+	userRole, _ := role.(model.Role)
+	bundle, err := h.service.SearchObservations(c.Request.Context(), c.GetUint("userID"), userRole, fhirSearch(c))
+	if err != nil {
+		h.fhirError(c, err)
+		return
+	}
+	h.respond(c, bundle)
+}
+
+// respond writes a FHIR resource
+func (h *FHIRHandler) respond(c *gin.Context, resource interface{}) {
+	h.write(c, http.StatusOK, resource)
+}
+
+// write writes a resource with the FHIR media type
+func (h *FHIRHandler) write(c *gin.Context, status int, resource interface{}) {
+	body, err := json.Marshal(resource)
+	if err != nil {
+		h.logger.Error("Failed to encode FHIR resource", zap.Error(err))
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	c.Data(status, fhir.ContentType, body)
+}
+
+// fhirError maps a FHIR service error to an OperationOutcome
+func (h *FHIRHandler) fhirError(c *gin.Context, err error) {
+	status, code := http.StatusInternalServerError, "exception"
+	switch {
+	case errors.Is(err, service.ErrInvalidFHIRSearch):
+		status, code = http.StatusBadRequest, "invalid"
+	case errors.Is(err, service.ErrNotTreatingDoctor), errors.Is(err, service.ErrNotOwnMedicalRecord):
+		status, code = http.StatusForbidden, "forbidden"
+	case strings.HasSuffix(err.Error(), "not found"):
+		status, code = http.StatusNotFound, "not-found"
+	default:
+		h.logger.Error("FHIR request failed", zap.Error(err))
+		err = errors.New("failed to process request")
+	}
+	h.write(c, status, fhir.NewOperationOutcome(code, err.Error()))
+}
+
+// fhirSearch reads the search parameters of a request
+func fhirSearch(c *gin.Context) service.FHIRSearch {
+	return service.FHIRSearch{Params: c.Request.URL.Query(), Location: requestLocation(c)}
+}
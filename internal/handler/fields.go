@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/whitewalker-sa/ehass/internal/service"
+)
+
+// parseListQuery reads the fields and sort query parameters of a list request
+func parseListQuery(c *gin.Context) service.ListQuery {
+	return service.ListQuery{
+		Fields: splitQuery(c, "fields"),
+		Sort:   splitQuery(c, "sort"),
+	}
+}
+
+// isListQueryError reports whether a list failed because of its fields or sort parameters
+func isListQueryError(err error) bool {
+	return errors.Is(err, service.ErrUnknownField) || errors.Is(err, service.ErrInvalidSort)
+}
+
+// splitQuery returns the values of a comma-separated query parameter, or nil when it is absent
+func splitQuery(c *gin.Context, name string) []string {
+	raw := c.Query(name)
+	if raw == "" {
+		return nil
+	}
+
+	var values []string
+	for _, value := range strings.Split(raw, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// sparseItems reduces each item of a response list to the selected fields, plus its id. Fields
+// left empty in an item are omitted as they would be in the full representation.
+func sparseItems(items interface{}, fields []string) ([]map[string]json.RawMessage, error) {
+	body, err := json.Marshal(items)
+	if err != nil {
+		return nil, err
+	}
+	var full []map[string]json.RawMessage
+	if err := json.Unmarshal(body, &full); err != nil {
+		return nil, err
+	}
+
+	sparse := make([]map[string]json.RawMessage, len(full))
+	for i, item := range full {
+		sparse[i] = map[string]json.RawMessage{"id": item["id"]}
+		for _, field := range fields {
+			if value, ok := item[field]; ok {
+				sparse[i][field] = value
+			}
+		}
+	}
+	return sparse, nil
+}
@@ -0,0 +1,194 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/whitewalker-sa/ehass/internal/service"
+	"github.com/whitewalker-sa/ehass/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// FrontDeskHandler handles reception screen HTTP requests
+type FrontDeskHandler struct {
+	service service.FrontDeskService
+	logger  *zap.Logger
+}
+
+// NewFrontDeskHandler creates a new front-desk handler
+func NewFrontDeskHandler(service service.FrontDeskService, logger *zap.Logger) *FrontDeskHandler {
+	return &FrontDeskHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// GetToday godoc
+// @Summary Front-desk today view
+// @Description Get everything the reception screen needs for the clinic's day: today's appointments, each doctor's status and current and next appointment, the free gaps left in their schedules, later bookings short enough to be brought forward into each gap, and upcoming appointments flagged for follow-up because the patient never confirmed them. Times are in the clinic's timezone.
+// @Tags front-desk
+// @Produce json
+// @Security BearerAuth
+// @Param organization_id query int false "Clinic ID, defaults to the default clinic"
+// @Success 200 {object} todayViewResponse "Today view"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Organization not found"
+// @Router /front-desk/today [get]
+func (h *FrontDeskHandler) GetToday(c *gin.Context) {
+	var orgID uint64
+	if value := c.Query("organization_id"); value != "" {
+		var err error
+		if orgID, err = strconv.ParseUint(value, 10, 32); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid organization ID"})
+			return
+		}
+	}
+
+	view, err := h.service.GetToday(c.Request.Context(), uint(orgID))
+	if err != nil {
+		if err.Error() == "organization not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.Error("Failed to build front-desk view", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to build front-desk view"})
+		return
+	}
+
+	c.JSON(http.StatusOK, toTodayViewResponse(view))
+}
+
+// ResolveFollowUp godoc
+// @Summary Resolve appointment follow-up
+// @Description Clear the follow-up flag reminder escalation set on an appointment the patient never confirmed, once reception has reached them. Set attending if the patient confirmed they will come; to cancel or reschedule, use the front-desk booking endpoints.
+// @Tags front-desk
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Appointment ID (UUID)"
+// @Param request body resolveFollowUpRequest false "Outcome"
+// @Success 200 {object} appointmentResponse "Appointment"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not found"
+// @Failure 409 {object} map[string]string "Appointment is not flagged for follow-up"
+// @Router /front-desk/appointments/{id}/follow-up [post]
+func (h *FrontDeskHandler) ResolveFollowUp(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid appointment ID"})
+		return
+	}
+	var req resolveFollowUpRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	appointment, err := h.service.ResolveFollowUp(c.Request.Context(), uint(id), req.Attending)
+	if err != nil {
+		switch {
+		case err.Error() == "appointment not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrNoFollowUp):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			h.logger.Error("Failed to resolve follow-up", zap.Uint("appointmentID", uint(id)), zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to resolve follow-up"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, formatAppointmentResponse(appointment, requestLocation(c)))
+}
+
+func toTodayViewResponse(view *service.TodayView) todayViewResponse {
+	loc := utils.LoadLocation(view.Timezone)
+	response := todayViewResponse{
+		OrganizationID: view.Organization.ID,
+		Organization:   view.Organization.DisplayName(),
+		Date:           view.Date.Format("2006-01-02"),
+		Timezone:       view.Timezone,
+		Doctors:        make([]todayDoctorResponse, len(view.Doctors)),
+		Appointments:   formatAppointmentResponses(view.Appointments, loc),
+		Gaps:           make([]scheduleGapResponse, len(view.Gaps)),
+		FollowUps:      formatAppointmentResponses(view.FollowUps, loc),
+	}
+
+	for i, d := range view.Doctors {
+		entry := todayDoctorResponse{
+			ID:        d.Doctor.PublicID,
+			Name:      d.Doctor.User.Name,
+			Specialty: d.Doctor.Specialty,
+			Status:    string(d.Status),
+			StatusSet: d.StatusSet,
+			Booked:    d.Booked,
+		}
+		if d.Current != nil {
+			entry.CurrentAppointmentID = d.Current.PublicID
+		}
+		if d.Next != nil {
+			entry.NextAppointmentID = d.Next.PublicID
+			entry.NextStart = d.Next.ScheduledStart.In(loc).Format(time.RFC3339)
+		}
+		response.Doctors[i] = entry
+	}
+
+	for i, gap := range view.Gaps {
+		response.Gaps[i] = scheduleGapResponse{
+			DoctorID:   gap.Doctor.PublicID,
+			DoctorName: gap.Doctor.User.Name,
+			Start:      gap.Start.In(loc).Format(time.RFC3339),
+			End:        gap.End.In(loc).Format(time.RFC3339),
+			Minutes:    int(gap.End.Sub(gap.Start).Minutes()),
+			Candidates: formatAppointmentResponses(gap.Candidates, loc),
+		}
+	}
+	return response
+}
+
+// Request and response types
+
+type todayViewResponse struct {
+	OrganizationID uint                  `json:"organization_id,omitempty"` // Omitted when no clinic is configured
+	Organization   string                `json:"organization"`
+	Date           string                `json:"date"`
+	Timezone       string                `json:"timezone"`
+	Doctors        []todayDoctorResponse `json:"doctors"`
+	Appointments   []appointmentResponse `json:"appointments"`
+	Gaps           []scheduleGapResponse `json:"gaps"`
+	FollowUps      []appointmentResponse `json:"follow_ups"` // Upcoming appointments unconfirmed after every reminder, to call the patient about
+}
+
+type resolveFollowUpRequest struct {
+	Attending bool `json:"attending"` // The patient confirmed they will attend
+}
+
+type todayDoctorResponse struct {
+	ID                   string `json:"id"`
+	Name                 string `json:"name"`
+	Specialty            string `json:"specialty"`
+	Status               string `json:"status"`     // available, in_consultation, on_break or off_site
+	StatusSet            bool   `json:"status_set"` // Set by the doctor rather than derived from their schedule
+	CurrentAppointmentID string `json:"current_appointment_id,omitempty"`
+	NextAppointmentID    string `json:"next_appointment_id,omitempty"`
+	NextStart            string `json:"next_start,omitempty"`
+	Booked               int    `json:"booked"` // Active appointments today
+}
+
+type scheduleGapResponse struct {
+	DoctorID   string                `json:"doctor_id"`
+	DoctorName string                `json:"doctor_name"`
+	Start      string                `json:"start"`
+	End        string                `json:"end"`
+	Minutes    int                   `json:"minutes"`
+	Candidates []appointmentResponse `json:"candidates"` // Later bookings that fit the gap, earliest first
+}
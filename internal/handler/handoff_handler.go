@@ -0,0 +1,165 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/service"
+	"go.uber.org/zap"
+)
+
+// HandoffHandler handles internal handoff note HTTP requests
+type HandoffHandler struct {
+	service   service.HandoffService
+	publicIDs service.PublicIDService
+	logger    *zap.Logger
+}
+
+// NewHandoffHandler creates a new handoff note handler
+func NewHandoffHandler(service service.HandoffService, publicIDs service.PublicIDService, logger *zap.Logger) *HandoffHandler {
+	return &HandoffHandler{
+		service:   service,
+		publicIDs: publicIDs,
+		logger:    logger,
+	}
+}
+
+// AddNote godoc
+// @Summary Add handoff note
+// @Description Write an internal note on a patient for the care team, optionally handing the patient over to another doctor. Notes are never shown to the patient. Only doctors with a booking with the patient, or to whom the patient was handed over, can write them.
+// @Tags patients,handoff
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Patient ID (UUID)"
+// @Param request body handoffNoteRequest true "Note"
+// @Success 201 {object} handoffNoteResponse "Created note"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Router /patients/{id}/handoff-notes [post]
+func (h *HandoffHandler) AddNote(c *gin.Context) {
+	patientID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+		return
+	}
+
+	var req handoffNoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var recipientID uint
+	if req.RecipientID != "" {
+		recipientID, err = h.publicIDs.ResolveID(c.Request.Context(), model.ResourceDoctor, req.RecipientID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	note, err := h.service.AddNote(c.Request.Context(), c.GetUint("userID"), uint(patientID), recipientID, req.Body)
+	if err != nil {
+		if errors.Is(err, service.ErrNoTreatmentRelationship) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, toHandoffNoteResponse(note))
+}
+
+// ListNotes godoc
+// @Summary List handoff notes
+// @Description List the internal handoff notes on a patient, most recent first, for a doctor on the patient's care team
+// @Tags patients,handoff
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Patient ID (UUID)"
+// @Param page query int false "Page number" default(1)
+// @Param pageSize query int false "Page size" default(20)
+// @Success 200 {object} map[string]interface{} "Notes"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /patients/{id}/handoff-notes [get]
+func (h *HandoffHandler) ListNotes(c *gin.Context) {
+	patientID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+		return
+	}
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	pageSize, err := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
+	if err != nil || pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	notes, total, err := h.service.ListNotes(c.Request.Context(), c.GetUint("userID"), uint(patientID), page, pageSize)
+	if err != nil {
+		if errors.Is(err, service.ErrNoTreatmentRelationship) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.Error("Failed to list handoff notes", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list handoff notes"})
+		return
+	}
+
+	response := make([]handoffNoteResponse, 0, len(notes))
+	for _, note := range notes {
+		response = append(response, toHandoffNoteResponse(note))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"notes": response,
+		"total": total,
+		"page":  page,
+		"size":  pageSize,
+	})
+}
+
+// Request and response models
+type handoffNoteRequest struct {
+	Body        string `json:"body" binding:"required"`
+	RecipientID string `json:"recipient_id"` // Public ID of the doctor the patient is handed over to, if any
+}
+
+type handoffNoteResponse struct {
+	ID            string `json:"id"`
+	AuthorID      string `json:"author_id"`
+	AuthorName    string `json:"author_name"`
+	RecipientID   string `json:"recipient_id,omitempty"`
+	RecipientName string `json:"recipient_name,omitempty"`
+	Body          string `json:"body"`
+	CreatedAt     string `json:"created_at"`
+}
+
+// Helper function to convert model to response
+func toHandoffNoteResponse(note *model.HandoffNote) handoffNoteResponse {
+	response := handoffNoteResponse{
+		ID:         note.PublicID,
+		AuthorID:   note.Author.PublicID,
+		AuthorName: note.Author.User.Name,
+		Body:       note.Body,
+		CreatedAt:  note.CreatedAt.Format(time.RFC3339),
+	}
+	if note.Recipient != nil {
+		response.RecipientID = note.Recipient.PublicID
+		response.RecipientName = note.Recipient.User.Name
+	}
+	return response
+}
@@ -0,0 +1,233 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/service"
+	"go.uber.org/zap"
+)
+
+// ImmunizationHandler handles immunization record HTTP requests
+type ImmunizationHandler struct {
+	service service.ImmunizationService
+	logger  *zap.Logger
+}
+
+// NewImmunizationHandler creates a new immunization handler
+func NewImmunizationHandler(service service.ImmunizationService, logger *zap.Logger) *ImmunizationHandler {
+	return &ImmunizationHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// ListVaccines godoc
+// @Summary List vaccines
+// @Description List the vaccines immunizations can be recorded for, by CVX code, with the doses of their primary series and the days between doses and to boosters
+// @Tags immunizations
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "Vaccines"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Router /vaccines [get]
+func (h *ImmunizationHandler) ListVaccines(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"vaccines": model.VaccineSchedule})
+}
+
+// RecordDose godoc
+// @Summary Record vaccine dose
+// @Description Record a vaccine dose given to a patient, with the signed-in doctor as the administering doctor. Only doctors treating the patient can record doses.
+// @Tags patients,immunizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Patient ID (UUID)"
+// @Param request body immunizationRequest true "Dose"
+// @Success 201 {object} immunizationResponse "Recorded dose"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Router /patients/{id}/immunizations [post]
+func (h *ImmunizationHandler) RecordDose(c *gin.Context) {
+	patientID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+		return
+	}
+	var req immunizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	administered, err := time.Parse("2006-01-02", req.AdministeredDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid administered_date, expected YYYY-MM-DD"})
+		return
+	}
+
+	immunization, err := h.service.RecordDose(c.Request.Context(), c.GetUint("userID"), uint(patientID), service.ImmunizationInput{
+		VaccineCode:      req.VaccineCode,
+		DoseNumber:       req.DoseNumber,
+		AdministeredDate: administered,
+		LotNumber:        req.LotNumber,
+		Notes:            req.Notes,
+	})
+	if err != nil {
+		h.immunizationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, toImmunizationResponse(immunization))
+}
+
+// ListImmunizations godoc
+// @Summary List immunizations
+// @Description List a patient's vaccine doses by vaccine and dose, with the next dose due of each vaccine they have started, soonest first. Access follows the patient's medical records.
+// @Tags patients,immunizations
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Patient ID (UUID)"
+// @Success 200 {object} immunizationRecordResponse "Immunizations and doses due"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /patients/{id}/immunizations [get]
+func (h *ImmunizationHandler) ListImmunizations(c *gin.Context) {
+	patientID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+		return
+	}
+
+	role, _ := c.Get("userRole")
+	userRole, _ := role.(model.Role)
+	record, err := h.service.GetRecord(c.Request.Context(), c.GetUint("userID"), userRole, uint(patientID))
+	if err != nil {
+		h.immunizationError(c, err)
+		return
+	}
+
+	response := immunizationRecordResponse{
+		Immunizations: make([]immunizationResponse, 0, len(record.Immunizations)),
+		Due:           make([]immunizationDueResponse, 0, len(record.Due)),
+	}
+	for _, immunization := range record.Immunizations {
+		response.Immunizations = append(response.Immunizations, toImmunizationResponse(immunization))
+	}
+	for _, due := range record.Due {
+		response.Due = append(response.Due, immunizationDueResponse{
+			VaccineCode: due.Vaccine.Code,
+			VaccineName: due.Vaccine.Name,
+			DoseNumber:  due.DoseNumber,
+			Booster:     due.Booster,
+			DueDate:     due.DueDate.Format("2006-01-02"),
+			Overdue:     due.Overdue,
+		})
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// GetCertificate godoc
+// @Summary Download vaccination certificate
+// @Description Download a printable PDF certificate of a patient's vaccine doses and next doses due, with the clinic's details. Access follows the patient's medical records.
+// @Tags patients,immunizations
+// @Produce application/pdf
+// @Security BearerAuth
+// @Param id path string true "Patient ID (UUID)"
+// @Success 200 {file} file "Certificate PDF"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /patients/{id}/immunizations/certificate [get]
+func (h *ImmunizationHandler) GetCertificate(c *gin.Context) {
+	patientID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+		return
+	}
+
+	role, _ := c.Get("userRole")
+	userRole, _ := role.(model.Role)
+	document, err := h.service.RenderCertificate(c.Request.Context(), c.GetUint("userID"), userRole, uint(patientID))
+	if err != nil {
+		h.immunizationError(c, err)
+		return
+	}
+
+	c.Header("Content-Disposition", `inline; filename="vaccination-certificate.pdf"`)
+	c.Header("Cache-Control", "private, no-store")
+	c.Data(http.StatusOK, "application/pdf", document)
+}
+
+// immunizationError maps an immunization service error to a response
+func (h *ImmunizationHandler) immunizationError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrNotTreatingDoctor), errors.Is(err, service.ErrNotOwnMedicalRecord):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidImmunization):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case strings.HasSuffix(err.Error(), "not found"):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		h.logger.Error("Immunization request failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to process request"})
+	}
+}
+
+type immunizationRequest struct {
+	VaccineCode      string `json:"vaccine_code" binding:"required"`      // CVX code; see GET /vaccines
+	DoseNumber       int    `json:"dose_number" binding:"required"`       // 1 for the first dose; doses past the primary series are boosters
+	AdministeredDate string `json:"administered_date" binding:"required"` // YYYY-MM-DD
+	LotNumber        string `json:"lot_number"`
+	Notes            string `json:"notes"`
+}
+
+type immunizationResponse struct {
+	ID               string `json:"id"`
+	VaccineCode      string `json:"vaccine_code"`
+	VaccineName      string `json:"vaccine_name"`
+	DoseNumber       int    `json:"dose_number"`
+	AdministeredDate string `json:"administered_date"`
+	DoctorID         string `json:"doctor_id"` // Administering doctor
+	DoctorName       string `json:"doctor_name,omitempty"`
+	LotNumber        string `json:"lot_number,omitempty"`
+	Notes            string `json:"notes,omitempty"`
+	CreatedAt        string `json:"created_at"`
+}
+
+type immunizationDueResponse struct {
+	VaccineCode string `json:"vaccine_code"`
+	VaccineName string `json:"vaccine_name"`
+	DoseNumber  int    `json:"dose_number"`
+	Booster     bool   `json:"booster"`  // The dose follows a complete primary series
+	DueDate     string `json:"due_date"` // YYYY-MM-DD
+	Overdue     bool   `json:"overdue"`
+}
+
+type immunizationRecordResponse struct {
+	Immunizations []immunizationResponse    `json:"immunizations"`
+	Due           []immunizationDueResponse `json:"due"` // Next dose of each vaccine started, soonest first
+}
+
+func toImmunizationResponse(immunization *model.Immunization) immunizationResponse {
+	return immunizationResponse{
+		ID:               immunization.PublicID,
+		VaccineCode:      immunization.VaccineCode,
+		VaccineName:      immunization.VaccineName,
+		DoseNumber:       immunization.DoseNumber,
+		AdministeredDate: immunization.AdministeredDate.Format("2006-01-02"),
+		DoctorID:         immunization.Doctor.PublicID,
+		DoctorName:       immunization.Doctor.User.Name,
+		LotNumber:        immunization.LotNumber,
+		Notes:            immunization.Notes,
+		CreatedAt:        immunization.CreatedAt.Format(time.RFC3339),
+	}
+}
@@ -0,0 +1,267 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/service"
+	"go.uber.org/zap"
+)
+
+// IntegrationHandler handles partner API key and usage HTTP requests
+type IntegrationHandler struct {
+	service   service.APIKeyService
+	publicIDs service.PublicIDService
+	logger    *zap.Logger
+}
+
+// NewIntegrationHandler creates a new integration handler
+func NewIntegrationHandler(service service.APIKeyService, publicIDs service.PublicIDService, logger *zap.Logger) *IntegrationHandler {
+	return &IntegrationHandler{
+		service:   service,
+		publicIDs: publicIDs,
+		logger:    logger,
+	}
+}
+
+// GetUsage godoc
+// @Summary Get API usage
+// @Description Report the limits and daily usage of your API keys over a period of UTC days, the last 30 by default and at most 92. Holders of integrations:manage see every key.
+// @Tags integrations
+// @Produce json
+// @Security BearerAuth
+// @Param from query string false "First day, YYYY-MM-DD"
+// @Param to query string false "Last day, YYYY-MM-DD"
+// @Success 200 {object} apiUsageResponse "Usage"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Router /integrations/usage [get]
+func (h *IntegrationHandler) GetUsage(c *gin.Context) {
+	report, err := h.service.Usage(c.Request.Context(), c.GetUint("userID"),
+		hasPermission(c, model.PermissionIntegrationsManage), c.Query("from"), c.Query("to"))
+	if err != nil {
+		h.integrationError(c, err)
+		return
+	}
+
+	response := apiUsageResponse{
+		From: report.From.Format("2006-01-02"),
+		To:   report.To.Format("2006-01-02"),
+		Keys: make([]apiKeyUsageResponse, 0, len(report.Keys)),
+	}
+	for _, entry := range report.Keys {
+		usage := apiKeyUsageResponse{
+			apiKeyResponse:    toAPIKeyResponse(entry.Key),
+			RequestsPerMinute: entry.RequestsPerMinute,
+			DailyQuota:        entry.DailyQuota,
+			Today:             entry.Today,
+			QuotaRemaining:    entry.QuotaRemaining,
+			Requests:          entry.Requests,
+			Rejected:          entry.Rejected,
+			Days:              make([]apiDailyUsageResponse, 0, len(entry.Days)),
+		}
+		for _, day := range entry.Days {
+			usage.Days = append(usage.Days, apiDailyUsageResponse{
+				Day:      day.Day.Format("2006-01-02"),
+				Requests: day.Requests,
+				Rejected: day.Rejected,
+			})
+		}
+		response.Keys = append(response.Keys, usage)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// IssueKey godoc
+// @Summary Issue API key
+// @Description Issue an API key to a partner's user account. The key acts as that user, with their role and permissions, and is returned only in this response. Limits left at 0 use the configured defaults.
+// @Tags integrations,admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body issueAPIKeyRequest true "API key"
+// @Success 201 {object} issuedAPIKeyResponse "Issued key with its secret"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /admin/api-keys [post]
+func (h *IntegrationHandler) IssueKey(c *gin.Context) {
+	var req issueAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userID, err := h.publicIDs.ResolveID(c.Request.Context(), model.ResourceUser, req.UserID)
+	if err != nil {
+		h.integrationError(c, err)
+		return
+	}
+
+	key, secret, err := h.service.IssueKey(c.Request.Context(), c.GetUint("userID"), service.APIKeyInput{
+		UserID:            userID,
+		Name:              req.Name,
+		RequestsPerMinute: req.RequestsPerMinute,
+		DailyQuota:        req.DailyQuota,
+	})
+	if err != nil {
+		h.integrationError(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusCreated, issuedAPIKeyResponse{apiKeyResponse: toAPIKeyResponse(key), Key: secret})
+}
+
+// UpdateKey godoc
+// @Summary Update API key limits
+// @Description Set the requests per minute and daily quota of an API key, e.g. to throttle an abusive integration, or suspend it. Limits of 0 use the configured defaults.
+// @Tags integrations,admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "API key ID (UUID)"
+// @Param request body apiKeyLimitsRequest true "Limits"
+// @Success 200 {object} apiKeyResponse "Updated key"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /admin/api-keys/{id} [put]
+func (h *IntegrationHandler) UpdateKey(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid API key ID"})
+		return
+	}
+	var req apiKeyLimitsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	key, err := h.service.UpdateKey(c.Request.Context(), c.GetUint("userID"), uint(id), service.APIKeyLimits{
+		RequestsPerMinute: req.RequestsPerMinute,
+		DailyQuota:        req.DailyQuota,
+		Suspended:         req.Suspended,
+		SuspendedReason:   req.SuspendedReason,
+	})
+	if err != nil {
+		h.integrationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, toAPIKeyResponse(key))
+}
+
+// RevokeKey godoc
+// @Summary Revoke API key
+// @Description Revoke an API key for good. Requests made with it are refused from then on.
+// @Tags integrations,admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "API key ID (UUID)"
+// @Success 200 {object} map[string]string "Revoked"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /admin/api-keys/{id} [delete]
+func (h *IntegrationHandler) RevokeKey(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid API key ID"})
+		return
+	}
+
+	if err := h.service.RevokeKey(c.Request.Context(), c.GetUint("userID"), uint(id)); err != nil {
+		h.integrationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "API key revoked"})
+}
+
+// integrationError maps an API key service error to a response
+func (h *IntegrationHandler) integrationError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidAPIKey), errors.Is(err, service.ErrInvalidUsagePeriod):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case strings.HasSuffix(err.Error(), "not found"):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		h.logger.Error("Integration request failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to process request"})
+	}
+}
+
+type issueAPIKeyRequest struct {
+	UserID            string `json:"user_id" binding:"required"` // Public ID of the partner's user account
+	Name              string `json:"name" binding:"required"`
+	RequestsPerMinute int    `json:"requests_per_minute"` // 0 uses the default
+	DailyQuota        int    `json:"daily_quota"`         // Requests per UTC day; 0 uses the default
+}
+
+type apiKeyLimitsRequest struct {
+	RequestsPerMinute int    `json:"requests_per_minute"` // 0 uses the default
+	DailyQuota        int    `json:"daily_quota"`         // Requests per UTC day; 0 uses the default
+	Suspended         bool   `json:"suspended"`
+	SuspendedReason   string `json:"suspended_reason"`
+}
+
+type apiKeyResponse struct {
+	ID              string `json:"id"`
+	Name            string `json:"name"`
+	Prefix          string `json:"prefix"` // Start of the key
+	UserID          string `json:"user_id"`
+	UserName        string `json:"user_name,omitempty"`
+	Suspended       bool   `json:"suspended"`
+	SuspendedReason string `json:"suspended_reason,omitempty"`
+	CreatedAt       string `json:"created_at"`
+}
+
+type issuedAPIKeyResponse struct {
+	apiKeyResponse
+	Key string `json:"key"` // Send in the X-API-Key header; shown only once
+}
+
+type apiDailyUsageResponse struct {
+	Day      string `json:"day"`
+	Requests int64  `json:"requests"` // Refused ones included
+	Rejected int64  `json:"rejected"`
+}
+
+type apiKeyUsageResponse struct {
+	apiKeyResponse
+	RequestsPerMinute int                     `json:"requests_per_minute"` // Limit in force
+	DailyQuota        int                     `json:"daily_quota"`         // Limit in force
+	Today             int64                   `json:"today"`               // Requests served today (UTC)
+	QuotaRemaining    int64                   `json:"quota_remaining"`
+	Requests          int64                   `json:"requests"` // Over the period, refused ones included
+	Rejected          int64                   `json:"rejected"` // Refused for the rate limit or the quota
+	Days              []apiDailyUsageResponse `json:"days"`
+}
+
+type apiUsageResponse struct {
+	From string                `json:"from"`
+	To   string                `json:"to"`
+	Keys []apiKeyUsageResponse `json:"keys"`
+}
+
+func toAPIKeyResponse(key *model.APIKey) apiKeyResponse {
+	return apiKeyResponse{
+		ID:              key.PublicID,
+		Name:            key.Name,
+		Prefix:          key.Prefix,
+		UserID:          key.User.PublicID,
+		UserName:        key.User.Name,
+		Suspended:       key.Suspended,
+		SuspendedReason: key.SuspendedReason,
+		CreatedAt:       key.CreatedAt.Format(time.RFC3339),
+	}
+}
@@ -0,0 +1,432 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/service"
+	"go.uber.org/zap"
+)
+
+// LabHandler handles lab order and result HTTP requests
+type LabHandler struct {
+	service   service.LabService
+	publicIDs service.PublicIDService
+	logger    *zap.Logger
+}
+
+// NewLabHandler creates a new lab handler
+func NewLabHandler(service service.LabService, publicIDs service.PublicIDService, logger *zap.Logger) *LabHandler {
+	return &LabHandler{
+		service:   service,
+		publicIDs: publicIDs,
+		logger:    logger,
+	}
+}
+
+// CreateOrder godoc
+// @Summary Order lab tests
+// @Description Order lab tests for a patient. Only doctors treating the patient can order; a linked medical record must be one the doctor wrote.
+// @Tags patients,labs
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Patient ID (UUID)"
+// @Param request body labOrderRequest true "Order"
+// @Success 201 {object} labOrderResponse "Created order"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /patients/{id}/lab-orders [post]
+func (h *LabHandler) CreateOrder(c *gin.Context) {
+	patientID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+		return
+	}
+
+	var req labOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	input := service.LabOrderInput{Notes: req.Notes}
+	for _, test := range req.Tests {
+		input.Tests = append(input.Tests, model.LabTest{Code: test.Code, Name: test.Name})
+	}
+	if req.RecordID != "" {
+		recordID, err := h.publicIDs.ResolveID(c.Request.Context(), model.ResourceRecord, req.RecordID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid record_id"})
+			return
+		}
+		input.RecordID = recordID
+	}
+
+	order, err := h.service.CreateOrder(c.Request.Context(), c.GetUint("userID"), uint(patientID), input)
+	if err != nil {
+		h.labError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, toLabOrderResponse(order, requestLocation(c)))
+}
+
+// ListOrders godoc
+// @Summary List lab orders
+// @Description List a patient's lab orders with their results, most recent first. Access follows the patient's medical records.
+// @Tags patients,labs
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Patient ID (UUID)"
+// @Param page query int false "Page number" default(1)
+// @Param pageSize query int false "Page size" default(20)
+// @Success 200 {object} map[string]interface{} "Lab orders"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Router /patients/{id}/lab-orders [get]
+func (h *LabHandler) ListOrders(c *gin.Context) {
+	patientID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+		return
+	}
+	page, pageSize := labPagination(c)
+
+	role, _ := c.Get("userRole")
+	userRole, _ := role.(model.Role)
+	orders, total, err := h.service.ListOrders(c.Request.Context(), c.GetUint("userID"), userRole, uint(patientID), page, pageSize)
+	if err != nil {
+		h.labError(c, err)
+		return
+	}
+
+	loc := requestLocation(c)
+	response := make([]labOrderResponse, 0, len(orders))
+	for _, order := range orders {
+		response = append(response, toLabOrderResponse(order, loc))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"orders": response,
+		"total":  total,
+		"page":   page,
+		"size":   pageSize,
+	})
+}
+
+// GetOrder godoc
+// @Summary Get lab order
+// @Description Get one of a patient's lab orders with its results
+// @Tags patients,labs
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Patient ID (UUID)"
+// @Param orderID path string true "Lab order ID (UUID)"
+// @Success 200 {object} labOrderResponse "Lab order"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /patients/{id}/lab-orders/{orderID} [get]
+func (h *LabHandler) GetOrder(c *gin.Context) {
+	patientID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+		return
+	}
+	orderID, err := strconv.ParseUint(c.Param("orderID"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid lab order ID"})
+		return
+	}
+
+	role, _ := c.Get("userRole")
+	userRole, _ := role.(model.Role)
+	order, err := h.service.GetOrder(c.Request.Context(), c.GetUint("userID"), userRole, uint(patientID), uint(orderID))
+	if err != nil {
+		h.labError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, toLabOrderResponse(order, requestLocation(c)))
+}
+
+// IngestResults godoc
+// @Summary Receive lab results
+// @Description Webhook for lab systems to report the results of an order. A result for a test that already has one replaces it as a correction. Numeric values outside their reference range are flagged low or high unless the lab flagged them, and abnormal results go to the ordering doctor's inbox. Authenticated by the X-Webhook-Secret header.
+// @Tags webhooks,labs
+// @Accept json
+// @Produce json
+// @Param X-Webhook-Secret header string true "Shared webhook secret"
+// @Param request body labResultsRequest true "Results"
+// @Success 200 {object} labOrderResponse "Order with its results"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "Order not found"
+// @Router /webhooks/lab-results [post]
+func (h *LabHandler) IngestResults(c *gin.Context) {
+	var req labResultsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	orderID, err := h.publicIDs.ResolveID(c.Request.Context(), model.ResourceLabOrder, req.OrderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "lab order not found"})
+		return
+	}
+
+	inputs := make([]service.LabResultInput, 0, len(req.Results))
+	for _, result := range req.Results {
+		input := service.LabResultInput{
+			TestCode:       result.TestCode,
+			TestName:       result.TestName,
+			Value:          labValue(result.Value),
+			Unit:           result.Unit,
+			ReferenceLow:   result.ReferenceLow,
+			ReferenceHigh:  result.ReferenceHigh,
+			ReferenceRange: result.ReferenceRange,
+			Flag:           model.LabFlag(result.Flag),
+		}
+		if result.ObservedAt != "" {
+			if input.ObservedAt, err = time.Parse(time.RFC3339, result.ObservedAt); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid observed_at of " + result.TestCode + ", use RFC3339"})
+				return
+			}
+		}
+		inputs = append(inputs, input)
+	}
+
+	order, err := h.service.IngestResults(c.Request.Context(), orderID, inputs)
+	if err != nil {
+		h.logger.Warn("Rejected lab results", zap.Uint("orderID", orderID), zap.Error(err))
+		h.labError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, toLabOrderResponse(order, time.UTC))
+}
+
+// GetAbnormalInbox godoc
+// @Summary Get abnormal lab results inbox
+// @Description List the abnormal results of the signed-in doctor's lab orders that no one has reviewed yet, critical ones first and then most recent first
+// @Tags labs
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param pageSize query int false "Page size" default(20)
+// @Success 200 {object} map[string]interface{} "Abnormal results"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Router /lab-results/abnormal [get]
+func (h *LabHandler) GetAbnormalInbox(c *gin.Context) {
+	page, pageSize := labPagination(c)
+
+	results, total, err := h.service.AbnormalInbox(c.Request.Context(), c.GetUint("userID"), page, pageSize)
+	if err != nil {
+		h.labError(c, err)
+		return
+	}
+
+	loc := requestLocation(c)
+	response := make([]labInboxItemResponse, 0, len(results))
+	for _, result := range results {
+		item := labInboxItemResponse{labResultResponse: toLabResultResponse(result, loc)}
+		if result.Order != nil {
+			item.OrderID = result.Order.PublicID
+			item.PatientID = result.Order.Patient.PublicID
+			item.PatientName = result.Order.Patient.User.Name
+		}
+		response = append(response, item)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"results": response,
+		"total":   total,
+		"page":    page,
+		"size":    pageSize,
+	})
+}
+
+// ReviewResult godoc
+// @Summary Review a lab result
+// @Description Mark a lab result as reviewed, taking it out of the abnormal results inbox. Only doctors treating the patient can review.
+// @Tags labs
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Lab result ID (UUID)"
+// @Success 200 {object} labResultResponse "Reviewed result"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not found"
+// @Failure 409 {object} map[string]string "Already reviewed"
+// @Router /lab-results/{id}/review [post]
+func (h *LabHandler) ReviewResult(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid lab result ID"})
+		return
+	}
+
+	result, err := h.service.ReviewResult(c.Request.Context(), c.GetUint("userID"), uint(id))
+	if err != nil {
+		h.labError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, toLabResultResponse(result, requestLocation(c)))
+}
+
+// labError writes the response for a failed lab request
+func (h *LabHandler) labError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrNotTreatingDoctor),
+		errors.Is(err, service.ErrNotOwnMedicalRecord),
+		errors.Is(err, service.ErrNotRecordAuthor):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrLabResultReviewed):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case strings.HasSuffix(err.Error(), "not found"):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidLabOrder), errors.Is(err, service.ErrInvalidLabResult):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		h.logger.Error("Lab request failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to process lab request"})
+	}
+}
+
+// labPagination reads the page and page size of a lab listing
+func labPagination(c *gin.Context) (int, int) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	pageSize, err := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
+	if err != nil || pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	return page, pageSize
+}
+
+// labValue reads a result value sent as a JSON string or number
+func labValue(raw json.RawMessage) string {
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text
+	}
+	return strings.TrimSpace(string(raw))
+}
+
+// Request and response models
+type labOrderRequest struct {
+	Tests []struct {
+		Code string `json:"code" binding:"required"` // Usually LOINC
+		Name string `json:"name"`
+	} `json:"tests" binding:"required,min=1,dive"`
+	RecordID string `json:"record_id"` // Medical record of the visit (UUID), optional
+	Notes    string `json:"notes"`     // Clinical notes for the lab
+}
+
+type labResultsRequest struct {
+	OrderID string `json:"order_id" binding:"required"` // Lab order ID (UUID)
+	Results []struct {
+		TestCode       string          `json:"test_code" binding:"required"`
+		TestName       string          `json:"test_name"`
+		Value          json.RawMessage `json:"value" binding:"required"` // Number or text
+		Unit           string          `json:"unit"`
+		ReferenceLow   *float64        `json:"reference_low"`
+		ReferenceHigh  *float64        `json:"reference_high"`
+		ReferenceRange string          `json:"reference_range"` // e.g. "3.5-5.0", "<200"; read when no bounds are given
+		Flag           string          `json:"flag"`            // N, L, H, LL, HH or A
+		ObservedAt     string          `json:"observed_at"`     // RFC3339; defaults to when received
+	} `json:"results" binding:"required,min=1,dive"`
+}
+
+type labOrderResponse struct {
+	ID         string              `json:"id"`
+	DoctorID   string              `json:"doctor_id"`
+	DoctorName string              `json:"doctor_name"`
+	Tests      []model.LabTest     `json:"tests"`
+	Notes      string              `json:"notes,omitempty"`
+	Status     string              `json:"status"`
+	OrderedAt  string              `json:"ordered_at"`
+	ResultedAt string              `json:"resulted_at,omitempty"`
+	Results    []labResultResponse `json:"results"`
+}
+
+type labResultResponse struct {
+	ID             string   `json:"id"`
+	TestCode       string   `json:"test_code"`
+	TestName       string   `json:"test_name,omitempty"`
+	Value          string   `json:"value"`
+	Unit           string   `json:"unit,omitempty"`
+	ReferenceLow   *float64 `json:"reference_low,omitempty"`
+	ReferenceHigh  *float64 `json:"reference_high,omitempty"`
+	ReferenceRange string   `json:"reference_range,omitempty"`
+	Flag           string   `json:"flag,omitempty"`
+	Abnormal       bool     `json:"abnormal"`
+	ObservedAt     string   `json:"observed_at"`
+	ReceivedAt     string   `json:"received_at"`
+	ReviewedAt     string   `json:"reviewed_at,omitempty"`
+}
+
+type labInboxItemResponse struct {
+	labResultResponse
+	OrderID     string `json:"order_id"`
+	PatientID   string `json:"patient_id"`
+	PatientName string `json:"patient_name"`
+}
+
+func toLabOrderResponse(order *model.LabOrder, loc *time.Location) labOrderResponse {
+	response := labOrderResponse{
+		ID:         order.PublicID,
+		DoctorID:   order.Doctor.PublicID,
+		DoctorName: order.Doctor.User.Name,
+		Tests:      order.Tests,
+		Notes:      order.Notes,
+		Status:     string(order.Status),
+		OrderedAt:  order.OrderedAt.In(loc).Format(time.RFC3339),
+		Results:    make([]labResultResponse, 0, len(order.Results)),
+	}
+	if order.ResultedAt != nil {
+		response.ResultedAt = order.ResultedAt.In(loc).Format(time.RFC3339)
+	}
+	for i := range order.Results {
+		response.Results = append(response.Results, toLabResultResponse(&order.Results[i], loc))
+	}
+	return response
+}
+
+func toLabResultResponse(result *model.LabResult, loc *time.Location) labResultResponse {
+	response := labResultResponse{
+		ID:             result.PublicID,
+		TestCode:       result.TestCode,
+		TestName:       result.TestName,
+		Value:          result.Value,
+		Unit:           result.Unit,
+		ReferenceLow:   result.ReferenceLow,
+		ReferenceHigh:  result.ReferenceHigh,
+		ReferenceRange: result.ReferenceRange,
+		Flag:           string(result.Flag),
+		Abnormal:       result.Abnormal,
+		ObservedAt:     result.ObservedAt.In(loc).Format(time.RFC3339),
+		ReceivedAt:     result.ReceivedAt.In(loc).Format(time.RFC3339),
+	}
+	if result.ReviewedAt != nil {
+		response.ReviewedAt = result.ReviewedAt.In(loc).Format(time.RFC3339)
+	}
+	return response
+}
@@ -0,0 +1,1396 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/service"
+	"go.uber.org/zap"
+)
+
+// MedicalRecordHandler handles medical record HTTP requests
+type MedicalRecordHandler struct {
+	service   service.MedicalRecordService
+	publicIDs service.PublicIDService
+	maxUpload int64
+	logger    *zap.Logger
+}
+
+// NewMedicalRecordHandler creates a new medical record handler. Attachment uploads larger than
+// maxUpload bytes are rejected before they are read in full.
+func NewMedicalRecordHandler(service service.MedicalRecordService, publicIDs service.PublicIDService, maxUpload int64, logger *zap.Logger) *MedicalRecordHandler {
+	return &MedicalRecordHandler{
+		service:   service,
+		publicIDs: publicIDs,
+		maxUpload: maxUpload,
+		logger:    logger,
+	}
+}
+
+// CreateMedicalRecord godoc
+// @Summary Create medical record
+// @Description Record a diagnosis, prescription and notes for a patient. Only doctors treating the patient, through a booking that was not cancelled or a handover, can create records.
+// @Tags patients,medical-records
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Patient ID (UUID)"
+// @Param request body medicalRecordRequest true "Medical record"
+// @Success 201 {object} medicalRecordResponse "Created medical record"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /patients/{id}/medical-records [post]
+func (h *MedicalRecordHandler) CreateMedicalRecord(c *gin.Context) {
+	patientID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+		return
+	}
+
+	var req medicalRecordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Prescription != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "free-text prescriptions are no longer accepted, issue one with POST /patients/{id}/prescriptions"})
+		return
+	}
+
+	var visitDate time.Time
+	if req.VisitDate != "" {
+		loc, err := inputLocation(c, req.Timezone)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid timezone"})
+			return
+		}
+		if visitDate, err = parseRequestTime(req.VisitDate, loc); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid visit_date, use RFC3339 or YYYY-MM-DDTHH:MM format"})
+			return
+		}
+	}
+
+	record, err := h.service.CreateMedicalRecord(
+		c.Request.Context(), c.GetUint("userID"), uint(patientID), visitDate, req.Diagnosis, req.Notes,
+	)
+	if err != nil {
+		h.medicalRecordError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, toMedicalRecordResponse(record, requestLocation(c)))
+}
+
+// ListMedicalRecords godoc
+// @Summary List medical records
+// @Description List a patient's medical records, most recent visit first. Patients can read their own records and those of the patients their account manages, doctors those of the patients they treat, and admins all records.
+// @Tags patients,medical-records
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Patient ID (UUID)"
+// @Param page query int false "Page number" default(1)
+// @Param pageSize query int false "Page size" default(20)
+// @Success 200 {object} map[string]interface{} "Medical records"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /patients/{id}/medical-records [get]
+func (h *MedicalRecordHandler) ListMedicalRecords(c *gin.Context) {
+	patientID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+		return
+	}
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	pageSize, err := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
+	if err != nil || pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	role, _ := c.Get("userRole")
+	userRole, _ := role.(model.Role)
+	records, total, err := h.service.GetPatientMedicalRecords(c.Request.Context(), c.GetUint("userID"), userRole, uint(patientID), page, pageSize)
+	if err != nil {
+		h.medicalRecordError(c, err)
+		return
+	}
+
+	loc := requestLocation(c)
+	response := make([]medicalRecordResponse, 0, len(records))
+	for _, record := range records {
+		response = append(response, toMedicalRecordResponse(record, loc))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"records": response,
+		"total":   total,
+		"page":    page,
+		"size":    pageSize,
+	})
+}
+
+// GetMedicalRecord godoc
+// @Summary Get medical record
+// @Description Get one of a patient's medical records, with the same access rules as listing them
+// @Tags patients,medical-records
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Patient ID (UUID)"
+// @Param recordID path string true "Medical record ID (UUID)"
+// @Success 200 {object} medicalRecordResponse "Medical record"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /patients/{id}/medical-records/{recordID} [get]
+func (h *MedicalRecordHandler) GetMedicalRecord(c *gin.Context) {
+	patientID, recordID, ok := medicalRecordParams(c)
+	if !ok {
+		return
+	}
+
+	role, _ := c.Get("userRole")
+	userRole, _ := role.(model.Role)
+	record, err := h.service.GetMedicalRecord(c.Request.Context(), c.GetUint("userID"), userRole, patientID, recordID)
+	if err != nil {
+		h.medicalRecordError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, toMedicalRecordResponse(record, requestLocation(c)))
+}
+
+// UpdateMedicalRecord godoc
+// @Summary Update medical record
+// @Description Change the diagnosis and notes of a medical record. Only the doctor who wrote the record can change it, and only until they sign it. Every change is kept as a new version; see the record's history.
+// @Tags patients,medical-records
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Patient ID (UUID)"
+// @Param recordID path string true "Medical record ID (UUID)"
+// @Param request body medicalRecordRequest true "Medical record; visit_date is ignored"
+// @Success 200 {object} medicalRecordResponse "Updated medical record"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not found"
+// @Failure 409 {object} map[string]string "Record is signed"
+// @Router /patients/{id}/medical-records/{recordID} [put]
+func (h *MedicalRecordHandler) UpdateMedicalRecord(c *gin.Context) {
+	patientID, recordID, ok := medicalRecordParams(c)
+	if !ok {
+		return
+	}
+
+	var req medicalRecordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Prescription != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "free-text prescriptions are no longer accepted, issue one with POST /patients/{id}/prescriptions"})
+		return
+	}
+
+	record, err := h.service.UpdateMedicalRecord(
+		c.Request.Context(), c.GetUint("userID"), patientID, recordID, req.Diagnosis, req.Notes,
+	)
+	if err != nil {
+		h.medicalRecordError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, toMedicalRecordResponse(record, requestLocation(c)))
+}
+
+// DeleteMedicalRecord godoc
+// @Summary Delete medical record
+// @Description Delete a medical record. Only the doctor who wrote the record can delete it, and only until they sign it.
+// @Tags patients,medical-records
+// @Security BearerAuth
+// @Param id path string true "Patient ID (UUID)"
+// @Param recordID path string true "Medical record ID (UUID)"
+// @Success 204 "Deleted"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not found"
+// @Failure 409 {object} map[string]string "Record is signed"
+// @Router /patients/{id}/medical-records/{recordID} [delete]
+func (h *MedicalRecordHandler) DeleteMedicalRecord(c *gin.Context) {
+	patientID, recordID, ok := medicalRecordParams(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteMedicalRecord(c.Request.Context(), c.GetUint("userID"), patientID, recordID); err != nil {
+		h.medicalRecordError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// SignMedicalRecord godoc
+// @Summary Sign medical record
+// @Description Sign a medical record, locking it. Only the doctor who wrote the record can sign it. A signed record can no longer be changed or deleted; later notes are added as addenda.
+// @Tags patients,medical-records
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Patient ID (UUID)"
+// @Param recordID path string true "Medical record ID (UUID)"
+// @Success 200 {object} medicalRecordResponse "Signed medical record"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not found"
+// @Failure 409 {object} map[string]string "Already signed"
+// @Router /patients/{id}/medical-records/{recordID}/sign [post]
+func (h *MedicalRecordHandler) SignMedicalRecord(c *gin.Context) {
+	patientID, recordID, ok := medicalRecordParams(c)
+	if !ok {
+		return
+	}
+
+	record, err := h.service.SignMedicalRecord(c.Request.Context(), c.GetUint("userID"), patientID, recordID)
+	if err != nil {
+		h.medicalRecordError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, toMedicalRecordResponse(record, requestLocation(c)))
+}
+
+// AddAddendum godoc
+// @Summary Add addendum to medical record
+// @Description Add a note to a signed medical record. Doctors treating the patient can add addenda; they are kept with the signed record and never changed.
+// @Tags patients,medical-records
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Patient ID (UUID)"
+// @Param recordID path string true "Medical record ID (UUID)"
+// @Param request body addendumRequest true "Addendum"
+// @Success 201 {object} addendumResponse "Addendum"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not found"
+// @Failure 409 {object} map[string]string "Record is not signed"
+// @Router /patients/{id}/medical-records/{recordID}/addenda [post]
+func (h *MedicalRecordHandler) AddAddendum(c *gin.Context) {
+	patientID, recordID, ok := medicalRecordParams(c)
+	if !ok {
+		return
+	}
+	var req addendumRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	addendum, err := h.service.AddAddendum(c.Request.Context(), c.GetUint("userID"), patientID, recordID, req.Text)
+	if err != nil {
+		h.medicalRecordError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, toAddendumResponse(addendum, requestLocation(c)))
+}
+
+// GetMedicalRecordHistory godoc
+// @Summary Get medical record history
+// @Description Get every version of a medical record, oldest first, with the doctor who wrote each and when, and the addenda added after the record was signed. Access is as for reading the record.
+// @Tags patients,medical-records
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Patient ID (UUID)"
+// @Param recordID path string true "Medical record ID (UUID)"
+// @Success 200 {object} medicalRecordHistoryResponse "History"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /patients/{id}/medical-records/{recordID}/history [get]
+func (h *MedicalRecordHandler) GetMedicalRecordHistory(c *gin.Context) {
+	patientID, recordID, ok := medicalRecordParams(c)
+	if !ok {
+		return
+	}
+
+	role, _ := c.Get("userRole")
+	userRole, _ := role.(model.Role)
+	history, err := h.service.GetMedicalRecordHistory(c.Request.Context(), c.GetUint("userID"), userRole, patientID, recordID)
+	if err != nil {
+		h.medicalRecordError(c, err)
+		return
+	}
+
+	loc := requestLocation(c)
+	response := medicalRecordHistoryResponse{
+		ID:       history.Record.PublicID,
+		Version:  history.Record.Version,
+		Versions: make([]medicalRecordVersionResponse, 0, len(history.Versions)),
+		Addenda:  make([]addendumResponse, 0, len(history.Addenda)),
+	}
+	if history.Record.SignedAt != nil {
+		response.SignedAt = history.Record.SignedAt.In(loc).Format(time.RFC3339)
+	}
+	for _, version := range history.Versions {
+		response.Versions = append(response.Versions, medicalRecordVersionResponse{
+			Version:    version.Version,
+			Diagnosis:  version.Diagnosis,
+			Notes:      version.Notes,
+			DoctorID:   version.Doctor.PublicID,
+			DoctorName: version.Doctor.User.Name,
+			CreatedAt:  version.CreatedAt.In(loc).Format(time.RFC3339),
+		})
+	}
+	for _, addendum := range history.Addenda {
+		response.Addenda = append(response.Addenda, toAddendumResponse(addendum, loc))
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// UploadAttachment godoc
+// @Summary Attach a file to a medical record
+// @Description Attach a lab result, imaging report or other file to a medical record. Only doctors treating the patient can attach files. The type is detected from the file's contents and must be one of attachments.allowedTypes (PDF, PNG and JPEG by default).
+// @Tags patients,medical-records
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Patient ID (UUID)"
+// @Param recordID path string true "Medical record ID (UUID)"
+// @Param file formData file true "File to attach"
+// @Success 201 {object} attachmentResponse "Attachment"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not found"
+// @Failure 413 {object} map[string]string "File too large"
+// @Failure 415 {object} map[string]string "File type not accepted"
+// @Router /patients/{id}/medical-records/{recordID}/attachments [post]
+func (h *MedicalRecordHandler) UploadAttachment(c *gin.Context) {
+	patientID, recordID, ok := medicalRecordParams(c)
+	if !ok {
+		return
+	}
+
+	// Leave room for the multipart framing around the file
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.maxUpload+1<<20)
+	header, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			h.medicalRecordError(c, service.ErrAttachmentTooLarge)
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+		return
+	}
+	if header.Size > h.maxUpload {
+		h.medicalRecordError(c, service.ErrAttachmentTooLarge)
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read file"})
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, h.maxUpload+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read file"})
+		return
+	}
+
+	attachment, err := h.service.AddAttachment(c.Request.Context(), c.GetUint("userID"), patientID, recordID, header.Filename, data)
+	if err != nil {
+		h.medicalRecordError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, toAttachmentResponse(attachment, requestLocation(c)))
+}
+
+// ListAttachments godoc
+// @Summary List medical record attachments
+// @Description List the files attached to a medical record, with the same access rules as reading the record
+// @Tags patients,medical-records
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Patient ID (UUID)"
+// @Param recordID path string true "Medical record ID (UUID)"
+// @Success 200 {object} map[string]interface{} "Attachments"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /patients/{id}/medical-records/{recordID}/attachments [get]
+func (h *MedicalRecordHandler) ListAttachments(c *gin.Context) {
+	patientID, recordID, ok := medicalRecordParams(c)
+	if !ok {
+		return
+	}
+
+	role, _ := c.Get("userRole")
+	userRole, _ := role.(model.Role)
+	attachments, err := h.service.ListAttachments(c.Request.Context(), c.GetUint("userID"), userRole, patientID, recordID)
+	if err != nil {
+		h.medicalRecordError(c, err)
+		return
+	}
+
+	loc := requestLocation(c)
+	response := make([]attachmentResponse, 0, len(attachments))
+	for _, attachment := range attachments {
+		response = append(response, toAttachmentResponse(attachment, loc))
+	}
+	c.JSON(http.StatusOK, gin.H{"attachments": response})
+}
+
+// GetAttachmentURL godoc
+// @Summary Get attachment download link
+// @Description Get a signed link that downloads an attached file without signing in, valid for attachments.urlExpiry (5 minutes by default). Each link issued is audit logged.
+// @Tags patients,medical-records
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Patient ID (UUID)"
+// @Param recordID path string true "Medical record ID (UUID)"
+// @Param attachmentID path string true "Attachment ID (UUID)"
+// @Success 200 {object} attachmentURLResponse "Download link"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /patients/{id}/medical-records/{recordID}/attachments/{attachmentID}/url [get]
+func (h *MedicalRecordHandler) GetAttachmentURL(c *gin.Context) {
+	patientID, recordID, ok := medicalRecordParams(c)
+	if !ok {
+		return
+	}
+	attachmentID, ok := attachmentParam(c)
+	if !ok {
+		return
+	}
+
+	role, _ := c.Get("userRole")
+	userRole, _ := role.(model.Role)
+	url, expiresAt, err := h.service.AttachmentDownloadURL(c.Request.Context(), c.GetUint("userID"), userRole, patientID, recordID, attachmentID)
+	if err != nil {
+		h.medicalRecordError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, attachmentURLResponse{
+		URL:       url,
+		ExpiresAt: expiresAt.In(requestLocation(c)).Format(time.RFC3339),
+	})
+}
+
+// DeleteAttachment godoc
+// @Summary Remove a medical record attachment
+// @Description Remove a file from a medical record. Only the doctor who attached the file can remove it.
+// @Tags patients,medical-records
+// @Security BearerAuth
+// @Param id path string true "Patient ID (UUID)"
+// @Param recordID path string true "Medical record ID (UUID)"
+// @Param attachmentID path string true "Attachment ID (UUID)"
+// @Success 204 "Deleted"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /patients/{id}/medical-records/{recordID}/attachments/{attachmentID} [delete]
+func (h *MedicalRecordHandler) DeleteAttachment(c *gin.Context) {
+	patientID, recordID, ok := medicalRecordParams(c)
+	if !ok {
+		return
+	}
+	attachmentID, ok := attachmentParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteAttachment(c.Request.Context(), c.GetUint("userID"), patientID, recordID, attachmentID); err != nil {
+		h.medicalRecordError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// DownloadAttachment godoc
+// @Summary Download an attachment
+// @Description Download a medical record attachment through a signed link from the download link endpoint. No sign-in is needed; the link stops working when it expires.
+// @Tags medical-records
+// @Produce octet-stream
+// @Param token path string true "Signed download token"
+// @Success 200 {file} file "Attached file"
+// @Failure 403 {object} map[string]string "Invalid or expired link"
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /attachments/{token} [get]
+func (h *MedicalRecordHandler) DownloadAttachment(c *gin.Context) {
+	attachment, body, err := h.service.OpenAttachment(c.Request.Context(), c.Param("token"))
+	if err != nil {
+		h.medicalRecordError(c, err)
+		return
+	}
+	defer body.Close()
+
+	c.DataFromReader(http.StatusOK, attachment.Size, attachment.ContentType, body, map[string]string{
+		"Content-Disposition":    mime.FormatMediaType("attachment", map[string]string{"filename": attachment.FileName}),
+		"Cache-Control":          "private, no-store",
+		"X-Content-Type-Options": "nosniff",
+	})
+}
+
+// SearchMedications godoc
+// @Summary Search medications
+// @Description Find active products in the medication catalog by the start of their generic name
+// @Tags prescriptions
+// @Produce json
+// @Security BearerAuth
+// @Param q query string false "Start of the generic name"
+// @Param limit query int false "Maximum results, at most 100" default(20)
+// @Success 200 {array} model.Medication "Matching medications"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /medications [get]
+func (h *MedicalRecordHandler) SearchMedications(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+
+	medications, err := h.service.SearchMedications(c.Request.Context(), c.Query("q"), limit)
+	if err != nil {
+		h.logger.Error("Failed to search medications", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to search medications"})
+		return
+	}
+
+	c.JSON(http.StatusOK, medications)
+}
+
+// ImportMedications godoc
+// @Summary Import medications
+// @Description Add products to the medication catalog or update their route and status. Products are identified by name, form and strength; those not in the request are unchanged.
+// @Tags admin,prescriptions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body []medicationRequest true "Medications"
+// @Success 200 {object} map[string]int "Number of medications imported"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /admin/medications [put]
+func (h *MedicalRecordHandler) ImportMedications(c *gin.Context) {
+	var req []medicationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	medications := make([]*model.Medication, 0, len(req))
+	for _, entry := range req {
+		active := true
+		if entry.Active != nil {
+			active = *entry.Active
+		}
+		medications = append(medications, &model.Medication{
+			Name:     entry.Name,
+			Form:     entry.Form,
+			Strength: entry.Strength,
+			Route:    entry.Route,
+			Active:   active,
+		})
+	}
+
+	count, err := h.service.ImportMedications(c.Request.Context(), medications)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidPrescription) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.Error("Failed to import medications", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to import medications"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"imported": count})
+}
+
+// RetireMedication godoc
+// @Summary Retire a medication
+// @Description Stop a product from being prescribed; prescriptions already issued keep it
+// @Tags admin,prescriptions
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Medication ID"
+// @Success 204 "Medication retired"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /admin/medications/{id} [delete]
+func (h *MedicalRecordHandler) RetireMedication(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid medication ID"})
+		return
+	}
+
+	if err := h.service.RetireMedication(c.Request.Context(), uint(id)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// IssuePrescription godoc
+// @Summary Issue prescription
+// @Description Prescribe a medication from the catalog to a patient. Only doctors treating the patient can prescribe; a linked medical record must be one the doctor wrote.
+// @Tags patients,prescriptions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Patient ID (UUID)"
+// @Param request body prescriptionRequest true "Prescription"
+// @Success 201 {object} prescriptionResponse "Issued prescription"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /patients/{id}/prescriptions [post]
+func (h *MedicalRecordHandler) IssuePrescription(c *gin.Context) {
+	patientID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+		return
+	}
+
+	var req prescriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	input := service.PrescriptionInput{
+		MedicationID: req.MedicationID,
+		Dosage:       req.Dosage,
+		Frequency:    req.Frequency,
+		DurationDays: req.DurationDays,
+		Refills:      req.Refills,
+		Instructions: req.Instructions,
+	}
+	if req.RecordID != "" {
+		recordID, err := h.publicIDs.ResolveID(c.Request.Context(), model.ResourceRecord, req.RecordID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid record_id"})
+			return
+		}
+		input.RecordID = recordID
+	}
+
+	prescription, err := h.service.IssuePrescription(c.Request.Context(), c.GetUint("userID"), uint(patientID), input)
+	if err != nil {
+		h.medicalRecordError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, toPrescriptionResponse(prescription, requestLocation(c)))
+}
+
+// ListPrescriptions godoc
+// @Summary List prescriptions
+// @Description List a patient's prescriptions, most recently issued first. Only active prescriptions are listed unless all=true. Access follows the patient's medical records.
+// @Tags patients,prescriptions
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Patient ID (UUID)"
+// @Param all query bool false "Include cancelled and finished prescriptions"
+// @Param page query int false "Page number" default(1)
+// @Param pageSize query int false "Page size" default(20)
+// @Success 200 {object} map[string]interface{} "Prescriptions"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Router /patients/{id}/prescriptions [get]
+func (h *MedicalRecordHandler) ListPrescriptions(c *gin.Context) {
+	patientID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+		return
+	}
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	pageSize, err := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
+	if err != nil || pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	all := c.Query("all") == "true"
+
+	role, _ := c.Get("userRole")
+	userRole, _ := role.(model.Role)
+	prescriptions, total, err := h.service.ListPrescriptions(c.Request.Context(), c.GetUint("userID"), userRole, uint(patientID), all, page, pageSize)
+	if err != nil {
+		h.medicalRecordError(c, err)
+		return
+	}
+
+	loc := requestLocation(c)
+	response := make([]prescriptionResponse, 0, len(prescriptions))
+	for _, prescription := range prescriptions {
+		response = append(response, toPrescriptionResponse(prescription, loc))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"prescriptions": response,
+		"total":         total,
+		"page":          page,
+		"size":          pageSize,
+	})
+}
+
+// GetPrescription godoc
+// @Summary Get prescription
+// @Description Get one of a patient's prescriptions
+// @Tags patients,prescriptions
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Patient ID (UUID)"
+// @Param prescriptionID path string true "Prescription ID (UUID)"
+// @Success 200 {object} prescriptionResponse "Prescription"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /patients/{id}/prescriptions/{prescriptionID} [get]
+func (h *MedicalRecordHandler) GetPrescription(c *gin.Context) {
+	patientID, prescriptionID, ok := prescriptionParams(c)
+	if !ok {
+		return
+	}
+
+	role, _ := c.Get("userRole")
+	userRole, _ := role.(model.Role)
+	prescription, err := h.service.GetPrescription(c.Request.Context(), c.GetUint("userID"), userRole, patientID, prescriptionID)
+	if err != nil {
+		h.medicalRecordError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, toPrescriptionResponse(prescription, requestLocation(c)))
+}
+
+// GetPrescriptionPDF godoc
+// @Summary Print prescription
+// @Description Download a printable PDF of one of a patient's prescriptions, with the clinic's details and the prescriber's license number
+// @Tags patients,prescriptions
+// @Produce application/pdf
+// @Security BearerAuth
+// @Param id path string true "Patient ID (UUID)"
+// @Param prescriptionID path string true "Prescription ID (UUID)"
+// @Success 200 {file} file "Prescription PDF"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /patients/{id}/prescriptions/{prescriptionID}/pdf [get]
+func (h *MedicalRecordHandler) GetPrescriptionPDF(c *gin.Context) {
+	patientID, prescriptionID, ok := prescriptionParams(c)
+	if !ok {
+		return
+	}
+
+	role, _ := c.Get("userRole")
+	userRole, _ := role.(model.Role)
+	document, err := h.service.RenderPrescription(c.Request.Context(), c.GetUint("userID"), userRole, patientID, prescriptionID)
+	if err != nil {
+		h.medicalRecordError(c, err)
+		return
+	}
+
+	c.Header("Content-Disposition", `inline; filename="prescription.pdf"`)
+	c.Header("Cache-Control", "private, no-store")
+	c.Data(http.StatusOK, "application/pdf", document)
+}
+
+// CancelPrescription godoc
+// @Summary Cancel prescription
+// @Description Stop a prescription, with an optional reason. Only the doctor who issued it can cancel it.
+// @Tags patients,prescriptions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Patient ID (UUID)"
+// @Param prescriptionID path string true "Prescription ID (UUID)"
+// @Param request body cancelPrescriptionRequest false "Reason"
+// @Success 200 {object} prescriptionResponse "Cancelled prescription"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not found"
+// @Failure 409 {object} map[string]string "Already cancelled"
+// @Router /patients/{id}/prescriptions/{prescriptionID}/cancel [post]
+func (h *MedicalRecordHandler) CancelPrescription(c *gin.Context) {
+	patientID, prescriptionID, ok := prescriptionParams(c)
+	if !ok {
+		return
+	}
+
+	var req cancelPrescriptionRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	prescription, err := h.service.CancelPrescription(c.Request.Context(), c.GetUint("userID"), patientID, prescriptionID, req.Reason)
+	if err != nil {
+		h.medicalRecordError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, toPrescriptionResponse(prescription, requestLocation(c)))
+}
+
+func (h *MedicalRecordHandler) medicalRecordError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrNotTreatingDoctor),
+		errors.Is(err, service.ErrNotOwnMedicalRecord),
+		errors.Is(err, service.ErrNotRecordAuthor),
+		errors.Is(err, service.ErrNotAttachmentUploader),
+		errors.Is(err, service.ErrInvalidDownloadLink),
+		errors.Is(err, service.ErrNotPrescriber),
+		errors.Is(err, service.ErrNotVitalRecorder):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrPrescriptionCancelled),
+		errors.Is(err, service.ErrRecordSigned),
+		errors.Is(err, service.ErrRecordNotSigned):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrAttachmentTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrAttachmentType):
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+	case strings.HasSuffix(err.Error(), "not found"):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
+
+// medicalRecordParams parses the patient and record IDs of a medical record route
+func medicalRecordParams(c *gin.Context) (uint, uint, bool) {
+	patientID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+		return 0, 0, false
+	}
+	recordID, err := strconv.ParseUint(c.Param("recordID"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid medical record ID"})
+		return 0, 0, false
+	}
+	return uint(patientID), uint(recordID), true
+}
+
+// RecordVital godoc
+// @Summary Record a vital sign
+// @Description Record a blood pressure, heart rate, weight, glucose or temperature reading. Doctors treating the patient record readings taken at a visit and can link them to a medical record they wrote; patients and guardians report readings taken at home. Weight in lb, glucose in mmol/L and temperature in F are converted to kg, mg/dL and C.
+// @Tags patients,vitals
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Patient ID (UUID)"
+// @Param request body vitalRequest true "Reading"
+// @Success 201 {object} vitalResponse "Recorded reading"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /patients/{id}/vitals [post]
+func (h *MedicalRecordHandler) RecordVital(c *gin.Context) {
+	patientID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+		return
+	}
+
+	var req vitalRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	input := service.VitalInput{
+		Type:      model.VitalType(req.Type),
+		Value:     *req.Value,
+		Diastolic: req.Diastolic,
+		Unit:      req.Unit,
+		Note:      req.Note,
+	}
+	if req.MeasuredAt != "" {
+		loc, err := inputLocation(c, req.Timezone)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid timezone"})
+			return
+		}
+		if input.MeasuredAt, err = parseRequestTime(req.MeasuredAt, loc); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid measured_at, use RFC3339 or YYYY-MM-DDTHH:MM format"})
+			return
+		}
+	}
+	if req.RecordID != "" {
+		recordID, err := h.publicIDs.ResolveID(c.Request.Context(), model.ResourceRecord, req.RecordID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid record_id"})
+			return
+		}
+		input.RecordID = recordID
+	}
+
+	role, _ := c.Get("userRole")
+	userRole, _ := role.(model.Role)
+	vital, err := h.service.RecordVital(c.Request.Context(), c.GetUint("userID"), userRole, uint(patientID), input)
+	if err != nil {
+		h.medicalRecordError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, toVitalResponse(vital, requestLocation(c)))
+}
+
+// ListVitals godoc
+// @Summary List vital signs
+// @Description List a patient's readings oldest first for charting, optionally of one type and measured in a range. Only the latest readings up to limit are listed. Access follows the patient's medical records.
+// @Tags patients,vitals
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Patient ID (UUID)"
+// @Param type query string false "blood_pressure, heart_rate, weight, glucose or temperature"
+// @Param from query string false "Measured from (YYYY-MM-DD or RFC3339)"
+// @Param to query string false "Measured until (YYYY-MM-DD, inclusive, or RFC3339)"
+// @Param limit query int false "Maximum readings (default 500, max 2000)"
+// @Success 200 {object} map[string]interface{} "Readings"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Router /patients/{id}/vitals [get]
+func (h *MedicalRecordHandler) ListVitals(c *gin.Context) {
+	patientID, filter, ok := vitalQuery(c)
+	if !ok {
+		return
+	}
+	limit, _ := strconv.Atoi(c.Query("limit"))
+
+	role, _ := c.Get("userRole")
+	userRole, _ := role.(model.Role)
+	vitals, err := h.service.ListVitals(c.Request.Context(), c.GetUint("userID"), userRole, patientID, filter, limit)
+	if err != nil {
+		h.medicalRecordError(c, err)
+		return
+	}
+
+	loc := requestLocation(c)
+	response := make([]vitalResponse, 0, len(vitals))
+	for _, vital := range vitals {
+		response = append(response, toVitalResponse(vital, loc))
+	}
+
+	c.JSON(http.StatusOK, gin.H{"vitals": response})
+}
+
+// SummarizeVitals godoc
+// @Summary Summarize vital signs
+// @Description Get the count, minimum, maximum and average of a patient's readings by type, optionally of one type and measured in a range. Access follows the patient's medical records.
+// @Tags patients,vitals
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Patient ID (UUID)"
+// @Param type query string false "blood_pressure, heart_rate, weight, glucose or temperature"
+// @Param from query string false "Measured from (YYYY-MM-DD or RFC3339)"
+// @Param to query string false "Measured until (YYYY-MM-DD, inclusive, or RFC3339)"
+// @Success 200 {object} map[string]interface{} "Summaries"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Router /patients/{id}/vitals/summary [get]
+func (h *MedicalRecordHandler) SummarizeVitals(c *gin.Context) {
+	patientID, filter, ok := vitalQuery(c)
+	if !ok {
+		return
+	}
+
+	role, _ := c.Get("userRole")
+	userRole, _ := role.(model.Role)
+	summaries, err := h.service.SummarizeVitals(c.Request.Context(), c.GetUint("userID"), userRole, patientID, filter)
+	if err != nil {
+		h.medicalRecordError(c, err)
+		return
+	}
+
+	loc := requestLocation(c)
+	response := make([]vitalSummaryResponse, 0, len(summaries))
+	for _, summary := range summaries {
+		response = append(response, toVitalSummaryResponse(summary, loc))
+	}
+
+	c.JSON(http.StatusOK, gin.H{"summaries": response})
+}
+
+// DeleteVital godoc
+// @Summary Delete a vital sign
+// @Description Delete a reading recorded in error. Only the user who recorded it can delete it.
+// @Tags patients,vitals
+// @Security BearerAuth
+// @Param id path string true "Patient ID (UUID)"
+// @Param vitalID path string true "Reading ID (UUID)"
+// @Success 204 "Deleted"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /patients/{id}/vitals/{vitalID} [delete]
+func (h *MedicalRecordHandler) DeleteVital(c *gin.Context) {
+	patientID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+		return
+	}
+	vitalID, err := strconv.ParseUint(c.Param("vitalID"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid vital sign ID"})
+		return
+	}
+
+	if err := h.service.DeleteVital(c.Request.Context(), c.GetUint("userID"), uint(patientID), uint(vitalID)); err != nil {
+		h.medicalRecordError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// vitalQuery parses the patient ID and the filter of a vital sign listing. Days are read in the
+// requester's timezone.
+func vitalQuery(c *gin.Context) (uint, service.VitalFilter, bool) {
+	var filter service.VitalFilter
+	patientID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+		return 0, filter, false
+	}
+
+	filter.Type = model.VitalType(c.Query("type"))
+	loc := requestLocation(c)
+	if raw := c.Query("from"); raw != "" {
+		if filter.From, err = parseQueryTime(raw, loc, false); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from, expected YYYY-MM-DD or RFC3339"})
+			return 0, filter, false
+		}
+	}
+	if raw := c.Query("to"); raw != "" {
+		if filter.To, err = parseQueryTime(raw, loc, true); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to, expected YYYY-MM-DD or RFC3339"})
+			return 0, filter, false
+		}
+	}
+	return uint(patientID), filter, true
+}
+
+// prescriptionParams parses the patient and prescription IDs of a prescription route
+func prescriptionParams(c *gin.Context) (uint, uint, bool) {
+	patientID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+		return 0, 0, false
+	}
+	prescriptionID, err := strconv.ParseUint(c.Param("prescriptionID"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid prescription ID"})
+		return 0, 0, false
+	}
+	return uint(patientID), uint(prescriptionID), true
+}
+
+// attachmentParam parses the attachment ID of an attachment route
+func attachmentParam(c *gin.Context) (uint, bool) {
+	attachmentID, err := strconv.ParseUint(c.Param("attachmentID"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid attachment ID"})
+		return 0, false
+	}
+	return uint(attachmentID), true
+}
+
+// Request and response models
+type medicalRecordRequest struct {
+	Diagnosis    string `json:"diagnosis" binding:"required"`
+	Prescription string `json:"prescription"` // No longer accepted; issue a prescription instead
+	Notes        string `json:"notes"`
+	VisitDate    string `json:"visit_date"` // Defaults to now
+	Timezone     string `json:"timezone"`   // Timezone of a visit_date without an offset
+}
+
+type medicalRecordResponse struct {
+	ID           string `json:"id"`
+	DoctorID     string `json:"doctor_id"`
+	DoctorName   string `json:"doctor_name"`
+	Diagnosis    string `json:"diagnosis"`
+	Prescription string `json:"prescription,omitempty"`
+	Notes        string `json:"notes,omitempty"`
+	VisitDate    string `json:"visit_date"`
+	Version      int    `json:"version"`             // Latest version; see the record's history
+	SignedAt     string `json:"signed_at,omitempty"` // Set once the author signed the record
+	CreatedAt    string `json:"created_at"`
+	UpdatedAt    string `json:"updated_at"`
+
+	ActiveAllergies []activeAllergySummary `json:"active_allergies,omitempty"` // The patient's allergies that apply now, most severe first
+}
+
+// Helper function to convert model to response
+func toMedicalRecordResponse(record *model.MedicalRecord, loc *time.Location) medicalRecordResponse {
+	response := medicalRecordResponse{
+		ID:           record.PublicID,
+		DoctorID:     record.Doctor.PublicID,
+		DoctorName:   record.Doctor.User.Name,
+		Diagnosis:    record.Diagnosis,
+		Prescription: record.Prescription,
+		Notes:        record.Notes,
+		VisitDate:    record.VisitDate.In(loc).Format(time.RFC3339),
+		Version:      record.Version,
+		CreatedAt:    record.CreatedAt.In(loc).Format(time.RFC3339),
+		UpdatedAt:    record.UpdatedAt.In(loc).Format(time.RFC3339),
+
+		ActiveAllergies: toActiveAllergies(&record.Patient),
+	}
+	if record.SignedAt != nil {
+		response.SignedAt = record.SignedAt.In(loc).Format(time.RFC3339)
+	}
+	return response
+}
+
+type addendumRequest struct {
+	Text string `json:"text" binding:"required"`
+}
+
+type addendumResponse struct {
+	ID         string `json:"id"`
+	Text       string `json:"text"`
+	DoctorID   string `json:"doctor_id"`
+	DoctorName string `json:"doctor_name"`
+	CreatedAt  string `json:"created_at"`
+}
+
+type medicalRecordVersionResponse struct {
+	Version    int    `json:"version"`
+	Diagnosis  string `json:"diagnosis"`
+	Notes      string `json:"notes,omitempty"`
+	DoctorID   string `json:"doctor_id"` // Doctor who wrote the version
+	DoctorName string `json:"doctor_name"`
+	CreatedAt  string `json:"created_at"`
+}
+
+type medicalRecordHistoryResponse struct {
+	ID       string                         `json:"id"`
+	Version  int                            `json:"version"` // Latest version
+	SignedAt string                         `json:"signed_at,omitempty"`
+	Versions []medicalRecordVersionResponse `json:"versions"` // Oldest first
+	Addenda  []addendumResponse             `json:"addenda"`  // Oldest first
+}
+
+func toAddendumResponse(addendum *model.MedicalRecordAddendum, loc *time.Location) addendumResponse {
+	return addendumResponse{
+		ID:         addendum.PublicID,
+		Text:       addendum.Text,
+		DoctorID:   addendum.Doctor.PublicID,
+		DoctorName: addendum.Doctor.User.Name,
+		CreatedAt:  addendum.CreatedAt.In(loc).Format(time.RFC3339),
+	}
+}
+
+type medicationRequest struct {
+	Name     string `json:"name" binding:"required"` // Generic drug name
+	Form     string `json:"form" binding:"required"`
+	Strength string `json:"strength" binding:"required"`
+	Route    string `json:"route"`
+	Active   *bool  `json:"active"` // Defaults to true
+}
+
+type prescriptionRequest struct {
+	MedicationID uint   `json:"medication_id" binding:"required"`
+	RecordID     string `json:"record_id"` // Medical record of the visit (UUID), optional
+	Dosage       string `json:"dosage" binding:"required"`
+	Frequency    string `json:"frequency" binding:"required"`
+	DurationDays int    `json:"duration_days" binding:"required"`
+	Refills      int    `json:"refills"`
+	Instructions string `json:"instructions"`
+}
+
+type cancelPrescriptionRequest struct {
+	Reason string `json:"reason"`
+}
+
+type prescriptionResponse struct {
+	ID           string           `json:"id"`
+	DoctorID     string           `json:"doctor_id"`
+	DoctorName   string           `json:"doctor_name"`
+	Medication   model.Medication `json:"medication"`
+	Dosage       string           `json:"dosage"`
+	Frequency    string           `json:"frequency"`
+	DurationDays int              `json:"duration_days"`
+	Refills      int              `json:"refills"`
+	Instructions string           `json:"instructions,omitempty"`
+	Active       bool             `json:"active"`
+	IssuedAt     string           `json:"issued_at"`
+	EndsAt       string           `json:"ends_at"`
+	CancelledAt  string           `json:"cancelled_at,omitempty"`
+	CancelReason string           `json:"cancel_reason,omitempty"`
+}
+
+func toPrescriptionResponse(prescription *model.Prescription, loc *time.Location) prescriptionResponse {
+	response := prescriptionResponse{
+		ID:           prescription.PublicID,
+		DoctorID:     prescription.Doctor.PublicID,
+		DoctorName:   prescription.Doctor.User.Name,
+		Medication:   prescription.Medication,
+		Dosage:       prescription.Dosage,
+		Frequency:    prescription.Frequency,
+		DurationDays: prescription.DurationDays,
+		Refills:      prescription.Refills,
+		Instructions: prescription.Instructions,
+		Active:       prescription.IsActive(time.Now()),
+		IssuedAt:     prescription.IssuedAt.In(loc).Format(time.RFC3339),
+		EndsAt:       prescription.EndsAt.In(loc).Format(time.RFC3339),
+		CancelReason: prescription.CancelReason,
+	}
+	if prescription.CancelledAt != nil {
+		response.CancelledAt = prescription.CancelledAt.In(loc).Format(time.RFC3339)
+	}
+	return response
+}
+
+type vitalRequest struct {
+	Type       string   `json:"type" binding:"required"`  // blood_pressure, heart_rate, weight, glucose or temperature
+	Value      *float64 `json:"value" binding:"required"` // Systolic for blood pressure
+	Diastolic  *float64 `json:"diastolic"`                // Blood pressure only
+	Unit       string   `json:"unit"`                     // Defaults to the type's unit: mmHg, bpm, kg, mg/dL or C
+	MeasuredAt string   `json:"measured_at"`              // Defaults to now
+	Timezone   string   `json:"timezone"`                 // Timezone of a measured_at without an offset
+	RecordID   string   `json:"record_id"`                // Medical record of the visit (UUID), doctors only
+	Note       string   `json:"note"`
+}
+
+type vitalResponse struct {
+	ID         string   `json:"id"`
+	Type       string   `json:"type"`
+	Value      float64  `json:"value"`
+	Diastolic  *float64 `json:"diastolic,omitempty"`
+	Unit       string   `json:"unit"`
+	MeasuredAt string   `json:"measured_at"`
+	Source     string   `json:"source"` // visit or self_reported
+	Note       string   `json:"note,omitempty"`
+	CreatedAt  string   `json:"created_at"`
+}
+
+type vitalSummaryResponse struct {
+	Type         string   `json:"type"`
+	Unit         string   `json:"unit"`
+	Count        int64    `json:"count"`
+	Min          float64  `json:"min"`
+	Max          float64  `json:"max"`
+	Avg          float64  `json:"avg"`
+	MinDiastolic *float64 `json:"min_diastolic,omitempty"`
+	MaxDiastolic *float64 `json:"max_diastolic,omitempty"`
+	AvgDiastolic *float64 `json:"avg_diastolic,omitempty"`
+	First        string   `json:"first_measured_at"`
+	Last         string   `json:"last_measured_at"`
+}
+
+func toVitalResponse(vital *model.Vital, loc *time.Location) vitalResponse {
+	return vitalResponse{
+		ID:         vital.PublicID,
+		Type:       string(vital.Type),
+		Value:      roundVital(vital.Value),
+		Diastolic:  vital.Diastolic,
+		Unit:       model.VitalUnits[vital.Type],
+		MeasuredAt: vital.MeasuredAt.In(loc).Format(time.RFC3339),
+		Source:     string(vital.Source),
+		Note:       vital.Note,
+		CreatedAt:  vital.CreatedAt.In(loc).Format(time.RFC3339),
+	}
+}
+
+func toVitalSummaryResponse(summary *service.VitalSummary, loc *time.Location) vitalSummaryResponse {
+	response := vitalSummaryResponse{
+		Type:         string(summary.Type),
+		Unit:         model.VitalUnits[summary.Type],
+		Count:        summary.Count,
+		Min:          roundVital(summary.Min),
+		Max:          roundVital(summary.Max),
+		Avg:          roundVital(summary.Avg),
+		MinDiastolic: summary.MinDiastolic,
+		MaxDiastolic: summary.MaxDiastolic,
+		First:        summary.First.In(loc).Format(time.RFC3339),
+		Last:         summary.Last.In(loc).Format(time.RFC3339),
+	}
+	if summary.AvgDiastolic != nil {
+		avg := roundVital(*summary.AvgDiastolic)
+		response.AvgDiastolic = &avg
+	}
+	return response
+}
+
+// roundVital rounds a reading to one decimal, which converted units and averages exceed
+func roundVital(value float64) float64 {
+	return math.Round(value*10) / 10
+}
+
+type attachmentResponse struct {
+	ID          string `json:"id"`
+	FileName    string `json:"file_name"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	Checksum    string `json:"checksum"` // Hex-encoded SHA-256
+	CreatedAt   string `json:"created_at"`
+}
+
+type attachmentURLResponse struct {
+	URL       string `json:"url"`
+	ExpiresAt string `json:"expires_at"`
+}
+
+func toAttachmentResponse(attachment *model.MedicalRecordAttachment, loc *time.Location) attachmentResponse {
+	return attachmentResponse{
+		ID:          attachment.PublicID,
+		FileName:    attachment.FileName,
+		ContentType: attachment.ContentType,
+		Size:        attachment.Size,
+		Checksum:    attachment.Checksum,
+		CreatedAt:   attachment.CreatedAt.In(loc).Format(time.RFC3339),
+	}
+}
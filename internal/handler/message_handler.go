@@ -0,0 +1,222 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/service"
+	"go.uber.org/zap"
+)
+
+// MessageHandler handles secure messaging HTTP requests
+type MessageHandler struct {
+	service   service.MessageService
+	publicIDs service.PublicIDService
+	logger    *zap.Logger
+}
+
+// NewMessageHandler creates a new secure messaging handler
+func NewMessageHandler(service service.MessageService, publicIDs service.PublicIDService, logger *zap.Logger) *MessageHandler {
+	return &MessageHandler{
+		service:   service,
+		publicIDs: publicIDs,
+		logger:    logger,
+	}
+}
+
+// SendMessage godoc
+// @Summary Send message
+// @Description Send a secure message in a patient's conversation with a doctor. Patients and their guardians write to a doctor treating the patient; doctors write as themselves to patients they treat or took messages from while covering. A message to a doctor on time off goes to their covering doctor, if any, and is answered with an automatic reply giving the absence period, returned after the message.
+// @Tags patients,messages
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Patient ID (UUID)"
+// @Param request body messageRequest true "Message"
+// @Success 201 {array} messageResponse "Sent message and any automatic reply"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Doctor not found"
+// @Router /patients/{id}/messages [post]
+func (h *MessageHandler) SendMessage(c *gin.Context) {
+	patientID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+		return
+	}
+
+	var req messageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	role, _ := c.Get("userRole")
+	userRole, _ := role.(model.Role)
+	var doctorID uint
+	if userRole != model.RoleDoctor {
+		if req.DoctorID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "doctor_id is required"})
+			return
+		}
+		doctorID, err = h.publicIDs.ResolveID(c.Request.Context(), model.ResourceDoctor, req.DoctorID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	messages, err := h.service.SendMessage(c.Request.Context(), c.GetUint("userID"), userRole, uint(patientID), doctorID, req.Body)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrNotOwnMessages), errors.Is(err, service.ErrNotMessagingDoctor),
+			errors.Is(err, service.ErrNotMessagingPatient):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case err.Error() == "doctor not found", err.Error() == "patient not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, toMessageResponses(messages))
+}
+
+// ListPatientMessages godoc
+// @Summary List patient messages
+// @Description List a patient's secure messages, most recent first. Patients and their guardians see every conversation; doctors only their own with the patient.
+// @Tags patients,messages
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Patient ID (UUID)"
+// @Param page query int false "Page number" default(1)
+// @Param pageSize query int false "Page size" default(20)
+// @Success 200 {object} map[string]interface{} "Messages"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /patients/{id}/messages [get]
+func (h *MessageHandler) ListPatientMessages(c *gin.Context) {
+	patientID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+		return
+	}
+
+	role, _ := c.Get("userRole")
+	userRole, _ := role.(model.Role)
+	page, pageSize := messagePage(c)
+	messages, total, err := h.service.ListPatientMessages(c.Request.Context(), c.GetUint("userID"), userRole, uint(patientID), page, pageSize)
+	if err != nil {
+		if errors.Is(err, service.ErrNotOwnMessages) || errors.Is(err, service.ErrNotMessagingPatient) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.Error("Failed to list patient messages", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list messages"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"messages": toMessageResponses(messages),
+		"total":    total,
+		"page":     page,
+		"size":     pageSize,
+	})
+}
+
+// ListDoctorMessages godoc
+// @Summary List doctor messages
+// @Description List the signed-in doctor's secure messages with patients, most recent first, including those taken while covering for a colleague and those that went to their covering doctor while they were away
+// @Tags doctors,messages
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param pageSize query int false "Page size" default(20)
+// @Success 200 {object} map[string]interface{} "Messages"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /messages [get]
+func (h *MessageHandler) ListDoctorMessages(c *gin.Context) {
+	page, pageSize := messagePage(c)
+	messages, total, err := h.service.ListDoctorMessages(c.Request.Context(), c.GetUint("userID"), page, pageSize)
+	if err != nil {
+		h.logger.Error("Failed to list doctor messages", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list messages"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"messages": toMessageResponses(messages),
+		"total":    total,
+		"page":     page,
+		"size":     pageSize,
+	})
+}
+
+// messagePage reads the page and page size of a message list
+func messagePage(c *gin.Context) (int, int) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	pageSize, err := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
+	if err != nil || pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	return page, pageSize
+}
+
+// Request and response models
+type messageRequest struct {
+	Body     string `json:"body" binding:"required"`
+	DoctorID string `json:"doctor_id"` // Public ID of the doctor a patient writes to; ignored for doctors
+}
+
+type messageResponse struct {
+	ID              string `json:"id"`
+	PatientID       string `json:"patient_id,omitempty"`
+	DoctorID        string `json:"doctor_id,omitempty"` // Doctor the message is with; the covering doctor when routed
+	DoctorName      string `json:"doctor_name,omitempty"`
+	AddressedToID   string `json:"addressed_to_id,omitempty"` // Doctor the patient wrote to, when routed to a covering doctor
+	AddressedToName string `json:"addressed_to_name,omitempty"`
+	FromPatient     bool   `json:"from_patient"`
+	AutoReply       bool   `json:"auto_reply"`
+	Body            string `json:"body"`
+	CreatedAt       string `json:"created_at"`
+}
+
+// Helper functions to convert models to responses
+func toMessageResponse(message *model.Message) messageResponse {
+	response := messageResponse{
+		ID:          message.PublicID,
+		PatientID:   message.Patient.PublicID,
+		DoctorID:    message.Doctor.PublicID,
+		DoctorName:  message.Doctor.User.Name,
+		FromPatient: message.FromPatient,
+		AutoReply:   message.AutoReply,
+		Body:        message.Body,
+		CreatedAt:   message.CreatedAt.Format(time.RFC3339),
+	}
+	if message.AddressedTo != nil {
+		response.AddressedToID = message.AddressedTo.PublicID
+		response.AddressedToName = message.AddressedTo.User.Name
+	}
+	return response
+}
+
+func toMessageResponses(messages []*model.Message) []messageResponse {
+	response := make([]messageResponse, 0, len(messages))
+	for _, message := range messages {
+		response = append(response, toMessageResponse(message))
+	}
+	return response
+}
@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/whitewalker-sa/ehass/internal/service"
+	"github.com/whitewalker-sa/ehass/pkg/metrics"
+	"go.uber.org/zap"
+)
+
+// MetricsHandler serves metrics to Prometheus
+type MetricsHandler struct {
+	service service.MetricsService
+	logger  *zap.Logger
+}
+
+// NewMetricsHandler creates a new metrics handler
+func NewMetricsHandler(service service.MetricsService, logger *zap.Logger) *MetricsHandler {
+	return &MetricsHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// Metrics godoc
+// @Summary Prometheus metrics
+// @Description Business and operating metrics in the Prometheus text format: appointments booked and scheduled today, unverified doctors, queue depths, open circuit breakers and failing jobs
+// @Tags operations
+// @Produce plain
+// @Param Authorization header string true "Bearer followed by metrics.token"
+// @Success 200 {string} string "Metrics"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /metrics [get]
+func (h *MetricsHandler) Metrics(c *gin.Context) {
+	families, err := h.service.Collect(c.Request.Context())
+	if err != nil {
+		h.logger.Error("Failed to collect metrics", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to collect metrics"})
+		return
+	}
+
+	c.Header("Content-Type", metrics.ContentType)
+	c.Status(http.StatusOK)
+	if err := metrics.Write(c.Writer, families); err != nil {
+		h.logger.Warn("Failed to write metrics", zap.Error(err))
+	}
+}
@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/service"
+	"go.uber.org/zap"
+)
+
+// NotificationHandler handles notification log HTTP requests
+type NotificationHandler struct {
+	service   service.NotificationService
+	publicIDs service.PublicIDService
+	logger    *zap.Logger
+}
+
+// NewNotificationHandler creates a new notification handler
+func NewNotificationHandler(service service.NotificationService, publicIDs service.PublicIDService, logger *zap.Logger) *NotificationHandler {
+	return &NotificationHandler{
+		service:   service,
+		publicIDs: publicIDs,
+		logger:    logger,
+	}
+}
+
+// GetAppointmentNotifications godoc
+// @Summary Get appointment notifications
+// @Description List the reminder attempts for an appointment, including fallbacks to the secondary channel
+// @Tags appointments,notifications
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Appointment ID (UUID)"
+// @Success 200 {array} notificationResponse "Notification attempts"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /appointments/{id}/notifications [get]
+func (h *NotificationHandler) GetAppointmentNotifications(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid appointment ID"})
+		return
+	}
+
+	notifications, err := h.service.GetAppointmentNotifications(c.Request.Context(), uint(id))
+	if err != nil {
+		h.logger.Error("Failed to get notifications", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get notifications"})
+		return
+	}
+
+	response := make([]notificationResponse, 0, len(notifications))
+	for _, notification := range notifications {
+		response = append(response, notificationResponse{
+			ID:            notification.ID,
+			Kind:          notification.Kind,
+			Channel:       notification.Channel,
+			Status:        string(notification.Status),
+			FallbackForID: notification.FallbackForID,
+			Detail:        notification.Detail,
+			CreatedAt:     notification.CreatedAt.Format(time.RFC3339),
+		})
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// SendMarketingMessage godoc
+// @Summary Send a promotional message
+// @Description Send a promotional message to users who opted in to marketing, on their preferred channel if they opted in to it and otherwise the other one. Users without marketing consent are skipped and sent nothing.
+// @Tags notifications,admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body marketingMessageRequest true "Message and recipients"
+// @Success 200 {object} marketingMessageResponse "Outcome per recipient"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /admin/marketing/messages [post]
+func (h *NotificationHandler) SendMarketingMessage(c *gin.Context) {
+	var req marketingMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userIDs := make([]uint, 0, len(req.UserIDs))
+	for _, publicID := range req.UserIDs {
+		id, err := h.publicIDs.ResolveID(c.Request.Context(), model.ResourceUser, publicID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		userIDs = append(userIDs, id)
+	}
+
+	response := marketingMessageResponse{Skipped: []string{}, Failed: []string{}}
+	for i, userID := range userIDs {
+		notification, err := h.service.SendMarketingMessage(c.Request.Context(), userID, req.Subject, req.Message)
+		switch {
+		case errors.Is(err, service.ErrNoMarketingConsent):
+			response.Skipped = append(response.Skipped, req.UserIDs[i])
+		case err != nil:
+			h.logger.Error("Failed to send marketing message", zap.Uint("userID", userID), zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to send marketing message"})
+			return
+		case notification.Status == model.NotificationStatusSent:
+			response.Sent++
+		default:
+			response.Failed = append(response.Failed, req.UserIDs[i])
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// Request and response models
+
+type marketingMessageRequest struct {
+	UserIDs []string `json:"user_ids" binding:"required,min=1,max=100"`
+	Subject string   `json:"subject" binding:"required,max=200"` // Email subject; not part of text messages
+	Message string   `json:"message" binding:"required,max=2000"`
+}
+
+type marketingMessageResponse struct {
+	Sent    int      `json:"sent"`
+	Skipped []string `json:"skipped"` // Users without marketing consent on a channel they can be reached on
+	Failed  []string `json:"failed"`  // Users whose message could not be delivered
+}
+
+type notificationResponse struct {
+	ID            uint   `json:"id"`
+	Kind          string `json:"kind"`
+	Channel       string `json:"channel"`
+	Status        string `json:"status"`
+	FallbackForID *uint  `json:"fallback_for_id,omitempty"`
+	Detail        string `json:"detail,omitempty"`
+	CreatedAt     string `json:"created_at"`
+}
@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/service"
+	"go.uber.org/zap"
+)
+
+// OperationHandler handles the status of long requests run in the background
+type OperationHandler struct {
+	service service.OperationService
+	logger  *zap.Logger
+}
+
+// NewOperationHandler creates a new operation handler
+func NewOperationHandler(service service.OperationService, logger *zap.Logger) *OperationHandler {
+	return &OperationHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// GetOperation godoc
+// @Summary Get an operation
+// @Description Status of a request accepted with Prefer: respond-async, and its result once it has succeeded. Only the user who made the request can see it.
+// @Tags operations
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Operation ID (UUID)"
+// @Success 200 {object} operationResponse "Operation"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /operations/{id} [get]
+func (h *OperationHandler) GetOperation(c *gin.Context) {
+	operation, err := h.service.Get(c.Request.Context(), c.Param("id"), c.GetUint("userID"))
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidPublicID) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !operation.Status.IsFinished() {
+		c.Header("Retry-After", "2")
+	}
+	c.JSON(http.StatusOK, toOperationResponse(operation))
+}
+
+// prefersAsync reports whether the client asked for the request to be run in the background
+// with the respond-async preference (RFC 7240)
+func prefersAsync(c *gin.Context) bool {
+	for _, preference := range strings.Split(c.GetHeader("Prefer"), ",") {
+		if strings.EqualFold(strings.TrimSpace(preference), "respond-async") {
+			return true
+		}
+	}
+	return false
+}
+
+// submitOperation queues a request to run in the background and responds 202 Accepted with the
+// operation to poll
+func submitOperation(c *gin.Context, operations service.OperationService, logger *zap.Logger, kind string, params interface{}) {
+	operation, err := operations.Submit(c.Request.Context(), kind, c.GetUint("userID"), params)
+	if err != nil {
+		logger.Error("Failed to submit operation", zap.String("kind", kind), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to submit operation"})
+		return
+	}
+
+	c.Header("Preference-Applied", "respond-async")
+	c.Header("Location", "/api/v1/operations/"+operation.PublicID)
+	c.JSON(http.StatusAccepted, toOperationResponse(operation))
+}
+
+// Request and response models
+type operationResponse struct {
+	ID          string          `json:"id"`
+	Kind        string          `json:"kind"`
+	Status      string          `json:"status"`                                // pending, running, succeeded or failed
+	Result      json.RawMessage `json:"result,omitempty" swaggertype:"object"` // The response the request would have returned, once succeeded
+	Error       string          `json:"error,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+	StartedAt   *time.Time      `json:"started_at,omitempty"`
+	CompletedAt *time.Time      `json:"completed_at,omitempty"`
+}
+
+// Helper function to convert model to response
+func toOperationResponse(operation *model.Operation) operationResponse {
+	response := operationResponse{
+		ID:          operation.PublicID,
+		Kind:        operation.Kind,
+		Status:      string(operation.Status),
+		Error:       operation.Error,
+		CreatedAt:   operation.CreatedAt,
+		StartedAt:   operation.StartedAt,
+		CompletedAt: operation.CompletedAt,
+	}
+	if operation.Result != "" {
+		response.Result = json.RawMessage(operation.Result)
+	}
+	return response
+}
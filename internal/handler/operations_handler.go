@@ -0,0 +1,220 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/whitewalker-sa/ehass/internal/service"
+	"go.uber.org/zap"
+)
+
+// OperationsHandler handles the runbook endpoints for background jobs
+type OperationsHandler struct {
+	service service.OperationsService
+	logger  *zap.Logger
+}
+
+// NewOperationsHandler creates a new operations handler
+func NewOperationsHandler(service service.OperationsService, logger *zap.Logger) *OperationsHandler {
+	return &OperationsHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// GetQueues godoc
+// @Summary Get background queue depths
+// @Description Number of items waiting in each background queue: due and failed reminders, failed emails and the SIEM export backlog
+// @Tags admin,operations
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string][]queueResponse "Queue depths"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /admin/ops/queues [get]
+func (h *OperationsHandler) GetQueues(c *gin.Context) {
+	queues, err := h.service.GetQueues(c.Request.Context())
+	if err != nil {
+		h.logger.Error("Failed to get queue depths", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get queue depths"})
+		return
+	}
+
+	items := make([]queueResponse, len(queues))
+	for i, queue := range queues {
+		items[i] = queueResponse{Name: queue.Name, Depth: queue.Depth, Description: queue.Description}
+	}
+	c.JSON(http.StatusOK, gin.H{"queues": items})
+}
+
+// GetJobs godoc
+// @Summary Get scheduled job status
+// @Description Last run, last success and failure counts of the scheduled jobs on the instance serving the request
+// @Tags admin,operations
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string][]jobResponse "Scheduled jobs"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Router /admin/ops/jobs [get]
+func (h *OperationsHandler) GetJobs(c *gin.Context) {
+	jobs := h.service.GetJobs()
+	items := make([]jobResponse, len(jobs))
+	for i, job := range jobs {
+		items[i] = toJobResponse(job)
+	}
+	c.JSON(http.StatusOK, gin.H{"jobs": items})
+}
+
+// GetBreakers godoc
+// @Summary Get circuit breaker status
+// @Description State and counters of the circuit breakers guarding OAuth providers, SMTP and SMS on the instance serving the request
+// @Tags admin,operations
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string][]breakerResponse "Circuit breakers"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Router /admin/ops/breakers [get]
+func (h *OperationsHandler) GetBreakers(c *gin.Context) {
+	breakers := h.service.GetBreakers()
+	items := make([]breakerResponse, len(breakers))
+	for i, b := range breakers {
+		items[i] = breakerResponse{
+			Name:                b.Name,
+			State:               string(b.State),
+			Calls:               b.Calls,
+			Failures:            b.Failures,
+			Rejected:            b.Rejected,
+			ConsecutiveFailures: b.ConsecutiveFailures,
+			OpenedAt:            b.OpenedAt,
+			LastFailureAt:       b.LastFailureAt,
+			LastError:           b.LastError,
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"breakers": items})
+}
+
+// RunJob godoc
+// @Summary Run a scheduled job now
+// @Description Run a job immediately, e.g. to retry after a failed run. Waits for the run to finish and returns the job status; a failed run is reported in last_error.
+// @Tags admin,operations
+// @Produce json
+// @Security BearerAuth
+// @Param name path string true "Job name" Enums(reminders, siem_export, outbox, cleanup)
+// @Success 200 {object} jobResponse "Job status after the run"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Job not scheduled on this instance"
+// @Failure 409 {object} map[string]string "Job already running"
+// @Router /admin/ops/jobs/{name}/run [post]
+func (h *OperationsHandler) RunJob(c *gin.Context) {
+	status, err := h.service.RunJob(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrJobNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrJobRunning):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, toJobResponse(*status))
+}
+
+// RetryFailedReminders godoc
+// @Summary Retry failed reminders
+// @Description Send reminders again for upcoming appointments whose reminder could not be delivered on any channel
+// @Tags admin,operations
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]int "Number of reminders delivered"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /admin/ops/reminders/retry [post]
+func (h *OperationsHandler) RetryFailedReminders(c *gin.Context) {
+	delivered, err := h.service.RetryFailedReminders(c.Request.Context())
+	if err != nil {
+		h.logger.Error("Failed to retry reminders", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retry reminders"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"delivered": delivered})
+}
+
+// RetryFailedEvents godoc
+// @Summary Retry failed outbox events
+// @Description Dispatch appointment events and emails again that were given up on after the maximum number of attempts
+// @Tags admin,operations
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]int "Number of events scheduled for dispatch"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /admin/ops/outbox/retry [post]
+func (h *OperationsHandler) RetryFailedEvents(c *gin.Context) {
+	count, err := h.service.RetryFailedEvents(c.Request.Context())
+	if err != nil {
+		h.logger.Error("Failed to retry outbox events", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retry outbox events"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"scheduled": count})
+}
+
+// Request and response models
+type queueResponse struct {
+	Name        string `json:"name"`
+	Depth       int64  `json:"depth"`
+	Description string `json:"description"`
+}
+
+type jobResponse struct {
+	Name                string     `json:"name"`
+	Interval            string     `json:"interval"`
+	Running             bool       `json:"running"`
+	Runs                int        `json:"runs"`
+	Failures            int        `json:"failures"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LastRunAt           *time.Time `json:"last_run_at"`
+	LastDurationMs      int64      `json:"last_duration_ms"`
+	LastSuccessAt       *time.Time `json:"last_success_at"`
+	LastError           string     `json:"last_error,omitempty"`
+}
+
+type breakerResponse struct {
+	Name                string     `json:"name"`
+	State               string     `json:"state"`
+	Calls               int64      `json:"calls"`
+	Failures            int64      `json:"failures"`
+	Rejected            int64      `json:"rejected"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	OpenedAt            *time.Time `json:"opened_at"`
+	LastFailureAt       *time.Time `json:"last_failure_at"`
+	LastError           string     `json:"last_error,omitempty"`
+}
+
+func toJobResponse(job service.JobStatus) jobResponse {
+	return jobResponse{
+		Name:                job.Name,
+		Interval:            job.Interval.String(),
+		Running:             job.Running,
+		Runs:                job.Runs,
+		Failures:            job.Failures,
+		ConsecutiveFailures: job.ConsecutiveFailures,
+		LastRunAt:           job.LastRunAt,
+		LastDurationMs:      job.LastDuration.Milliseconds(),
+		LastSuccessAt:       job.LastSuccessAt,
+		LastError:           job.LastError,
+	}
+}
@@ -0,0 +1,328 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/service"
+	"go.uber.org/zap"
+)
+
+// OrganizationHandler handles clinic settings HTTP requests
+type OrganizationHandler struct {
+	service service.OrganizationService
+	logger  *zap.Logger
+}
+
+// NewOrganizationHandler creates a new organization handler
+func NewOrganizationHandler(service service.OrganizationService, logger *zap.Logger) *OrganizationHandler {
+	return &OrganizationHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// CreateOrganization godoc
+// @Summary Create clinic
+// @Description Create a clinic with its business hours, booking rules, branding and contact details
+// @Tags admin,organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body organizationRequest true "Clinic settings"
+// @Success 201 {object} organizationResponse "Created clinic"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Router /admin/organizations [post]
+func (h *OrganizationHandler) CreateOrganization(c *gin.Context) {
+	var req organizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	org, err := h.service.CreateOrganization(c.Request.Context(), req.toModel())
+	if err != nil {
+		h.logger.Warn("Failed to create organization", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, toOrganizationResponse(org))
+}
+
+// ListOrganizations godoc
+// @Summary List clinics
+// @Description List all clinics and their settings
+// @Tags admin,organizations
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} organizationResponse "Clinics"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /admin/organizations [get]
+func (h *OrganizationHandler) ListOrganizations(c *gin.Context) {
+	orgs, err := h.service.ListOrganizations(c.Request.Context())
+	if err != nil {
+		h.logger.Error("Failed to list organizations", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list organizations"})
+		return
+	}
+
+	response := make([]organizationResponse, 0, len(orgs))
+	for _, org := range orgs {
+		response = append(response, toOrganizationResponse(org))
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetOrganization godoc
+// @Summary Get clinic
+// @Description Get a clinic and its settings by ID
+// @Tags admin,organizations
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Organization ID"
+// @Success 200 {object} organizationResponse "Clinic"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /admin/organizations/{id} [get]
+func (h *OrganizationHandler) GetOrganization(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid organization ID"})
+		return
+	}
+
+	org, err := h.service.GetOrganization(c.Request.Context(), uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, toOrganizationResponse(org))
+}
+
+// UpdateOrganization godoc
+// @Summary Update clinic
+// @Description Replace the settings of a clinic
+// @Tags admin,organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Organization ID"
+// @Param request body organizationRequest true "Clinic settings"
+// @Success 200 {object} organizationResponse "Updated clinic"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Router /admin/organizations/{id} [put]
+func (h *OrganizationHandler) UpdateOrganization(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid organization ID"})
+		return
+	}
+
+	var req organizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	org, err := h.service.UpdateOrganization(c.Request.Context(), uint(id), req.toModel())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, toOrganizationResponse(org))
+}
+
+// DeleteOrganization godoc
+// @Summary Delete clinic
+// @Description Delete a clinic; its doctors fall back to the default clinic's settings
+// @Tags admin,organizations
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Organization ID"
+// @Success 200 {object} map[string]string "Clinic deleted"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /admin/organizations/{id} [delete]
+func (h *OrganizationHandler) DeleteOrganization(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid organization ID"})
+		return
+	}
+
+	if err := h.service.DeleteOrganization(c.Request.Context(), uint(id)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "organization deleted"})
+}
+
+// AssignDoctor godoc
+// @Summary Assign doctor to clinic
+// @Description Make a doctor part of a clinic so its scheduling rules apply to their appointments
+// @Tags admin,organizations
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Organization ID"
+// @Param doctorID path string true "Doctor ID (UUID)"
+// @Success 200 {object} map[string]string "Doctor assigned"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Router /admin/organizations/{id}/doctors/{doctorID} [put]
+func (h *OrganizationHandler) AssignDoctor(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid organization ID"})
+		return
+	}
+	doctorID, err := strconv.ParseUint(c.Param("doctorID"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid doctor ID"})
+		return
+	}
+
+	if err := h.service.AssignDoctor(c.Request.Context(), uint(id), uint(doctorID)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "doctor assigned"})
+}
+
+// Request and response models
+type organizationRequest struct {
+	Name                     string                    `json:"name" binding:"required,max=100"`
+	BrandName                string                    `json:"brand_name" binding:"max=100"`
+	LogoURL                  string                    `json:"logo_url" binding:"omitempty,url,max=255"`
+	PrimaryColor             string                    `json:"primary_color"`                            // Hex color of headings, such as #0b5fa5
+	AccentColor              string                    `json:"accent_color"`                             // Hex color of email buttons and links
+	EmailSenderName          string                    `json:"email_sender_name" binding:"max=100"`      // Name emails are sent from
+	EmailReplyTo             string                    `json:"email_reply_to" binding:"omitempty,email"` // Address replies to emails go to
+	ContactEmail             string                    `json:"contact_email" binding:"omitempty,email"`
+	ContactPhone             string                    `json:"contact_phone" binding:"max=20"`
+	Address                  string                    `json:"address" binding:"max=255"`
+	Directions               string                    `json:"directions" binding:"max=2000"`           // Printed on confirmation letters and sent with reminders
+	MapURL                   string                    `json:"map_url" binding:"omitempty,url,max=512"` // Map link sent with reminders; defaults to directions to the address
+	Timezone                 string                    `json:"timezone"`
+	BusinessHours            []model.BusinessHours     `json:"business_hours"`
+	BookingWindowDays        int                       `json:"booking_window_days"`
+	MinBookingNotice         int                       `json:"min_booking_notice"`
+	DefaultAppointmentLength int                       `json:"default_appointment_length"`
+	IntakeRequirements       []model.IntakeRequirement `json:"intake_requirements"` // Items an appointment needs before it can be confirmed
+	MaxReschedules           int                       `json:"max_reschedules"`     // Times one appointment can be rescheduled; defaults to 3
+	CancellationCutoff       int                       `json:"cancellation_cutoff"` // Minutes before the start after which cancelling is late; defaults to 60
+	LateCancellationFee      bool                      `json:"late_cancellation_fee"`
+	AppointmentMetadataKeys  []string                  `json:"appointment_metadata_keys"` // Keys staff may set in appointment metadata
+}
+
+func (r organizationRequest) toModel() *model.Organization {
+	return &model.Organization{
+		Name:                     r.Name,
+		BrandName:                r.BrandName,
+		LogoURL:                  r.LogoURL,
+		PrimaryColor:             r.PrimaryColor,
+		AccentColor:              r.AccentColor,
+		EmailSenderName:          r.EmailSenderName,
+		EmailReplyTo:             r.EmailReplyTo,
+		ContactEmail:             r.ContactEmail,
+		ContactPhone:             r.ContactPhone,
+		Address:                  r.Address,
+		Directions:               r.Directions,
+		MapURL:                   r.MapURL,
+		Timezone:                 r.Timezone,
+		BusinessHours:            r.BusinessHours,
+		BookingWindowDays:        r.BookingWindowDays,
+		MinBookingNotice:         r.MinBookingNotice,
+		DefaultAppointmentLength: r.DefaultAppointmentLength,
+		IntakeRequirements:       r.IntakeRequirements,
+		MaxReschedules:           r.MaxReschedules,
+		CancellationCutoff:       r.CancellationCutoff,
+		LateCancellationFee:      r.LateCancellationFee,
+		AppointmentMetadataKeys:  r.AppointmentMetadataKeys,
+	}
+}
+
+type organizationResponse struct {
+	ID                       uint                      `json:"id"`
+	Name                     string                    `json:"name"`
+	BrandName                string                    `json:"brand_name"`
+	LogoURL                  string                    `json:"logo_url"`
+	PrimaryColor             string                    `json:"primary_color"`
+	AccentColor              string                    `json:"accent_color"`
+	EmailSenderName          string                    `json:"email_sender_name"`
+	EmailReplyTo             string                    `json:"email_reply_to"`
+	ContactEmail             string                    `json:"contact_email"`
+	ContactPhone             string                    `json:"contact_phone"`
+	Address                  string                    `json:"address"`
+	Directions               string                    `json:"directions"`
+	MapURL                   string                    `json:"map_url"`
+	Timezone                 string                    `json:"timezone"`
+	BusinessHours            []model.BusinessHours     `json:"business_hours"`
+	BookingWindowDays        int                       `json:"booking_window_days"`
+	MinBookingNotice         int                       `json:"min_booking_notice"`
+	DefaultAppointmentLength int                       `json:"default_appointment_length"`
+	IntakeRequirements       []model.IntakeRequirement `json:"intake_requirements"`
+	MaxReschedules           int                       `json:"max_reschedules"`
+	CancellationCutoff       int                       `json:"cancellation_cutoff"`
+	LateCancellationFee      bool                      `json:"late_cancellation_fee"`
+	AppointmentMetadataKeys  []string                  `json:"appointment_metadata_keys"`
+	CreatedAt                string                    `json:"created_at"`
+	UpdatedAt                string                    `json:"updated_at"`
+}
+
+// Helper function to convert model to response
+func toOrganizationResponse(org *model.Organization) organizationResponse {
+	hours := org.BusinessHours
+	if hours == nil {
+		hours = []model.BusinessHours{}
+	}
+	requirements := org.IntakeRequirements
+	if requirements == nil {
+		requirements = []model.IntakeRequirement{}
+	}
+	metadataKeys := org.AppointmentMetadataKeys
+	if metadataKeys == nil {
+		metadataKeys = []string{}
+	}
+
+	return organizationResponse{
+		ID:                       org.ID,
+		Name:                     org.Name,
+		BrandName:                org.BrandName,
+		LogoURL:                  org.LogoURL,
+		PrimaryColor:             org.PrimaryColor,
+		AccentColor:              org.AccentColor,
+		EmailSenderName:          org.EmailSenderName,
+		EmailReplyTo:             org.EmailReplyTo,
+		ContactEmail:             org.ContactEmail,
+		ContactPhone:             org.ContactPhone,
+		Address:                  org.Address,
+		Directions:               org.Directions,
+		MapURL:                   org.MapURL,
+		Timezone:                 org.Timezone,
+		BusinessHours:            hours,
+		BookingWindowDays:        org.BookingWindowDays,
+		MinBookingNotice:         org.MinBookingNotice,
+		DefaultAppointmentLength: org.DefaultAppointmentLength,
+		IntakeRequirements:       requirements,
+		MaxReschedules:           org.RescheduleLimit(),
+		CancellationCutoff:       org.CancellationCutoffMinutes(),
+		LateCancellationFee:      org.LateCancellationFee,
+		AppointmentMetadataKeys:  metadataKeys,
+		CreatedAt:                org.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:                org.UpdatedAt.Format(time.RFC3339),
+	}
+}
@@ -0,0 +1,204 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/service"
+	"go.uber.org/zap"
+)
+
+// PatientAccountHandler handles clinic-created patient records and how patients claim them
+type PatientAccountHandler struct {
+	service service.PatientAccountService
+	logger  *zap.Logger
+}
+
+// NewPatientAccountHandler creates a new patient account handler
+func NewPatientAccountHandler(service service.PatientAccountService, logger *zap.Logger) *PatientAccountHandler {
+	return &PatientAccountHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// CreateRecord godoc
+// @Summary Create patient record
+// @Description Create a patient record for someone without an account, e.g. at the front desk. The patient can later be invited to claim it.
+// @Tags patients
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param record body createPatientRecordRequest true "Patient details; email or phone is required"
+// @Success 201 {object} patientResponse "Created patient record"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Router /patients/records [post]
+func (h *PatientAccountHandler) CreateRecord(c *gin.Context) {
+	var req createPatientRecordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	patient, err := h.service.CreateClinicRecord(c.Request.Context(), req.Name, req.Email, req.Phone, req.DateOfBirth)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, toPatientResponse(patient))
+}
+
+// InvitePatient godoc
+// @Summary Invite patient to claim their record
+// @Description Invite the patient to the portal. Records with an email get a signed link that sets a password in one step, plus a one-time code; records without one get the code by SMS. Sending again resends the invitation and the previous link and code stop working; it is refused for a minute after the last invitation.
+// @Tags patients
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Patient ID (UUID)"
+// @Success 200 {object} map[string]string "Invitation sent"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not found"
+// @Failure 409 {object} map[string]string "Record already has an account"
+// @Failure 429 {object} map[string]string "Invited moments ago"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /patients/{id}/invite [post]
+func (h *PatientAccountHandler) InvitePatient(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid patient ID"})
+		return
+	}
+
+	invitation, err := h.service.InvitePatient(c.Request.Context(), uint(id))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrAlreadyClaimed):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrInvitationTooSoon):
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		case err.Error() == "patient not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			h.logger.Error("Failed to invite patient", zap.Uint64("patient_id", id), zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to send invitation"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Invitation sent",
+		"channel":    invitation.Channel,
+		"expires_at": invitation.ExpiresAt,
+	})
+}
+
+// ClaimAccount godoc
+// @Summary Claim patient record
+// @Description Set up a login for a clinic-created record using the invitation code, instead of registering a duplicate account. Details already on the record are kept.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param claim body claimAccountRequest true "Contact the invitation went to, code and registration details"
+// @Success 200 {object} map[string]interface{} "Account claimed"
+// @Failure 400 {object} map[string]string "Bad request or invalid code"
+// @Failure 409 {object} map[string]string "Record already has an account"
+// @Router /auth/claim-account [post]
+func (h *PatientAccountHandler) ClaimAccount(c *gin.Context) {
+	var req claimAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.service.ClaimAccount(c.Request.Context(), service.AccountClaim{
+		Email:    req.Email,
+		Phone:    req.Phone,
+		Code:     req.Code,
+		Password: req.Password,
+		Name:     req.Name,
+		Address:  req.Address,
+		NewEmail: req.NewEmail,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrAlreadyClaimed) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Account claimed. You can now sign in.",
+		"user":    model.SanitizeUser(*user),
+	})
+}
+
+// SetUpAccount godoc
+// @Summary Set up account from invitation
+// @Description Give a clinic-created record a login with the password the patient chose, using the token from the invitation link. The link proves the email it was sent to.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param setup body setUpAccountRequest true "Token from the invitation link and the new password"
+// @Success 200 {object} map[string]interface{} "Account set up"
+// @Failure 400 {object} map[string]string "Bad request or invalid link"
+// @Failure 409 {object} map[string]string "Record already has an account"
+// @Router /auth/setup-account [post]
+func (h *PatientAccountHandler) SetUpAccount(c *gin.Context) {
+	var req setUpAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.service.SetUpAccount(c.Request.Context(), req.Token, req.Password)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrAlreadyClaimed):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrInvalidInvitation):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			h.logger.Error("Failed to set up account", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to set up account"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Account set up. You can now sign in.",
+		"user":    model.SanitizeUser(*user),
+	})
+}
+
+// Request and response models
+type createPatientRecordRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Email       string `json:"email" binding:"omitempty,email"`
+	Phone       string `json:"phone"`
+	DateOfBirth string `json:"date_of_birth" binding:"required"`
+}
+
+type claimAccountRequest struct {
+	Email    string `json:"email" binding:"omitempty,email"`
+	Phone    string `json:"phone"`
+	Code     string `json:"code" binding:"required"`
+	Password string `json:"password" binding:"required,min=8"`
+	Name     string `json:"name"`
+	Address  string `json:"address"`
+	NewEmail string `json:"new_email" binding:"omitempty,email"`
+}
+
+type setUpAccountRequest struct {
+	Token    string `json:"token" binding:"required,max=512"`
+	Password string `json:"password" binding:"required,min=8"`
+}
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
@@ -13,15 +14,17 @@ import (
 
 // PatientHandler handles patient-related HTTP requests
 type PatientHandler struct {
-	service service.PatientService
-	logger  *zap.Logger
+	service   service.PatientService
+	publicIDs service.PublicIDService
+	logger    *zap.Logger
 }
 
 // NewPatientHandler creates a new patient handler
-func NewPatientHandler(service service.PatientService, logger *zap.Logger) *PatientHandler {
+func NewPatientHandler(service service.PatientService, publicIDs service.PublicIDService, logger *zap.Logger) *PatientHandler {
 	return &PatientHandler{
-		service: service,
-		logger:  logger,
+		service:   service,
+		publicIDs: publicIDs,
+		logger:    logger,
 	}
 }
 
@@ -68,7 +71,7 @@ func (h *PatientHandler) CreatePatient(c *gin.Context) {
 // @Description Get a patient profile by ID
 // @Tags patients
 // @Produce json
-// @Param id path int true "Patient ID"
+// @Param id path string true "Patient ID (UUID)"
 // @Success 200 {object} patientResponse "Patient profile"
 // @Failure 400 {object} map[string]string "Bad request"
 // @Failure 404 {object} map[string]string "Not found"
@@ -95,14 +98,14 @@ func (h *PatientHandler) GetPatient(c *gin.Context) {
 // @Description Get a patient profile by user ID
 // @Tags patients
 // @Produce json
-// @Param userId path int true "User ID"
+// @Param userID path string true "User ID (UUID)"
 // @Success 200 {object} patientResponse "Patient profile"
 // @Failure 400 {object} map[string]string "Bad request"
 // @Failure 404 {object} map[string]string "Not found"
 // @Failure 500 {object} map[string]string "Internal server error"
-// @Router /patients/user/{userId} [get]
+// @Router /patients/user/{userID} [get]
 func (h *PatientHandler) GetPatientByUser(c *gin.Context) {
-	id, err := strconv.ParseUint(c.Param("userId"), 10, 32)
+	id, err := strconv.ParseUint(c.Param("userID"), 10, 32)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
 		return
@@ -124,7 +127,7 @@ func (h *PatientHandler) GetPatientByUser(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Security BearerAuth
-// @Param id path int true "Patient ID"
+// @Param id path string true "Patient ID (UUID)"
 // @Param patient body updatePatientRequest true "Patient Information"
 // @Success 200 {object} patientResponse "Updated patient profile"
 // @Failure 400 {object} map[string]string "Bad request"
@@ -160,7 +163,17 @@ func (h *PatientHandler) UpdatePatient(c *gin.Context) {
 	}
 
 	// Update patient using interface-compatible method
-	updatedPatient, err := h.service.UpdatePatientProfile(c.Request.Context(), uint(id), req.DateOfBirth, req.MedicalHistory)
+	updatedPatient, err := h.service.UpdatePatientProfile(c.Request.Context(), uint(id), service.PatientProfileUpdate{
+		DateOfBirth:           req.DateOfBirth,
+		MedicalHistory:        req.MedicalHistory,
+		EmergencyContact:      req.EmergencyContact,
+		EmergencyPhone:        req.EmergencyPhone,
+		EmergencyPhoneCountry: req.EmergencyPhoneCountry,
+	})
+	if errors.Is(err, service.ErrInvalidPhone) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		h.logger.Error("Failed to update patient profile", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update patient profile"})
@@ -170,12 +183,66 @@ func (h *PatientHandler) UpdatePatient(c *gin.Context) {
 	c.JSON(http.StatusOK, toPatientResponse(updatedPatient))
 }
 
+// SetGuardian godoc
+// @Summary Set a patient's guardian
+// @Description Link a patient to the guardian whose account manages their record, such as a parent for a child. A guardian and their dependents form one family, whose members can be booked together into one slot. An empty guardian_id removes the link.
+// @Tags patients
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Patient ID (UUID)"
+// @Param request body setGuardianRequest true "Guardian"
+// @Success 200 {object} patientResponse "Updated patient"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Patient not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /patients/{id}/guardian [put]
+func (h *PatientHandler) SetGuardian(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patient ID"})
+		return
+	}
+
+	var req setGuardianRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var guardianID uint
+	if req.GuardianID != "" {
+		guardianID, err = h.publicIDs.ResolveID(c.Request.Context(), model.ResourcePatient, req.GuardianID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	patient, err := h.service.SetGuardian(c.Request.Context(), uint(id), guardianID)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidGuardian):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case err.Error() == "patient not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": "patient not found"})
+		default:
+			h.logger.Error("Failed to set guardian", zap.Uint("patientID", uint(id)), zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to set guardian"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, toPatientResponse(patient))
+}
+
 // DeletePatient godoc
 // @Summary Delete patient profile
 // @Description Delete a patient profile by ID
 // @Tags patients
 // @Security BearerAuth
-// @Param id path int true "Patient ID"
+// @Param id path string true "Patient ID (UUID)"
 // @Success 200 {object} map[string]string "Success message"
 // @Failure 400 {object} map[string]string "Bad request"
 // @Failure 401 {object} map[string]string "Unauthorized"
@@ -210,52 +277,54 @@ func (h *PatientHandler) DeletePatient(c *gin.Context) {
 
 // Request and response models
 type createPatientRequest struct {
-	DateOfBirth       string `json:"date_of_birth" binding:"required"`
-	Gender            string `json:"gender" binding:"required"`
-	BloodGroup        string `json:"blood_group"`
-	EmergencyContact  string `json:"emergency_contact"`
-	MedicalHistory    string `json:"medical_history"`
-	Allergies         string `json:"allergies"`
-	CurrentMedication string `json:"current_medication"`
+	DateOfBirth      string `json:"date_of_birth" binding:"required"`
+	Gender           string `json:"gender" binding:"required"`
+	BloodGroup       string `json:"blood_group"`
+	EmergencyContact string `json:"emergency_contact"`
+	MedicalHistory   string `json:"medical_history"`
 }
 
 type updatePatientRequest struct {
-	DateOfBirth       string `json:"date_of_birth"`
-	Gender            string `json:"gender"`
-	BloodGroup        string `json:"blood_group"`
-	EmergencyContact  string `json:"emergency_contact"`
-	MedicalHistory    string `json:"medical_history"`
-	Allergies         string `json:"allergies"`
-	CurrentMedication string `json:"current_medication"`
+	DateOfBirth           string `json:"date_of_birth"`
+	Gender                string `json:"gender"`
+	BloodGroup            string `json:"blood_group"`
+	EmergencyContact      string `json:"emergency_contact"`       // Name of the emergency contact
+	EmergencyPhone        string `json:"emergency_phone"`         // International, e.g. +27 82 123 4567, or national
+	EmergencyPhoneCountry string `json:"emergency_phone_country"` // ISO 3166-1 alpha-2 region of a national emergency_phone
+	MedicalHistory        string `json:"medical_history"`
+}
+
+type setGuardianRequest struct {
+	GuardianID string `json:"guardian_id"` // Empty to remove the guardian
 }
 
 type patientResponse struct {
-	ID                uint      `json:"id"`
-	UserID            uint      `json:"user_id"`
-	Name              string    `json:"name"`
-	Email             string    `json:"email"`
-	DateOfBirth       time.Time `json:"date_of_birth"`
-	Gender            string    `json:"gender"`
-	BloodGroup        string    `json:"blood_group"`
-	EmergencyContact  string    `json:"emergency_contact"`
-	MedicalHistory    string    `json:"medical_history"`
-	Allergies         string    `json:"allergies"`
-	CurrentMedication string    `json:"current_medication"`
+	ID                    string    `json:"id"`
+	UserID                string    `json:"user_id"`
+	Name                  string    `json:"name"`
+	Email                 string    `json:"email"`
+	DateOfBirth           time.Time `json:"date_of_birth"`
+	Gender                string    `json:"gender"`
+	BloodGroup            string    `json:"blood_group"`
+	EmergencyContact      string    `json:"emergency_contact"`
+	EmergencyPhone        string    `json:"emergency_phone,omitempty"` // E.164
+	EmergencyPhoneCountry string    `json:"emergency_phone_country,omitempty"`
+	MedicalHistory        string    `json:"medical_history"`
 }
 
 // Helper function to convert model to response
 func toPatientResponse(patient *model.Patient) patientResponse {
 	return patientResponse{
-		ID:                patient.ID,
-		UserID:            patient.UserID,
-		Name:              patient.User.Name,
-		Email:             patient.User.Email,
-		DateOfBirth:       patient.DateOfBirth,
-		Gender:            patient.Gender,
-		BloodGroup:        patient.BloodGroup,
-		EmergencyContact:  patient.EmergencyContact,
-		MedicalHistory:    patient.MedicalHistory,
-		Allergies:         patient.Allergies,
-		CurrentMedication: patient.CurrentMedication,
+		ID:                    patient.PublicID,
+		UserID:                patient.User.PublicID,
+		Name:                  patient.User.Name,
+		Email:                 patient.User.Email,
+		DateOfBirth:           patient.DateOfBirth,
+		Gender:                patient.Gender,
+		BloodGroup:            patient.BloodGroup,
+		EmergencyContact:      patient.EmergencyContact,
+		EmergencyPhone:        patient.EmergencyPhone,
+		EmergencyPhoneCountry: patient.EmergencyPhoneCountry,
+		MedicalHistory:        patient.MedicalHistory,
 	}
 }
@@ -0,0 +1,344 @@
+package handler
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/service"
+	"go.uber.org/zap"
+)
+
+// ProcedureHandler handles procedure coding HTTP requests
+type ProcedureHandler struct {
+	service service.ProcedureService
+	logger  *zap.Logger
+}
+
+// NewProcedureHandler creates a new procedure handler
+func NewProcedureHandler(service service.ProcedureService, logger *zap.Logger) *ProcedureHandler {
+	return &ProcedureHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// SearchCodes godoc
+// @Summary Search procedure codes
+// @Description Find active CPT and HCPCS codes in the catalog by code prefix or description
+// @Tags procedures
+// @Produce json
+// @Security BearerAuth
+// @Param q query string false "Code prefix or text in the description"
+// @Param system query string false "CPT or HCPCS"
+// @Param limit query int false "Maximum results, at most 100" default(20)
+// @Success 200 {array} model.ProcedureCode "Matching codes"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /procedure-codes [get]
+func (h *ProcedureHandler) SearchCodes(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	system := model.ProcedureCodeSystem(strings.ToUpper(c.Query("system")))
+
+	codes, err := h.service.SearchCodes(c.Request.Context(), c.Query("q"), system, limit)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidProcedure) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.Error("Failed to search procedure codes", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to search procedure codes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, codes)
+}
+
+// ImportCodes godoc
+// @Summary Import procedure codes
+// @Description Add codes to the catalog or update their system, description and status. Codes not in the request are unchanged.
+// @Tags admin,procedures
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body []procedureCodeRequest true "Codes"
+// @Success 200 {object} map[string]int "Number of codes imported"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /admin/procedure-codes [put]
+func (h *ProcedureHandler) ImportCodes(c *gin.Context) {
+	var req []procedureCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	codes := make([]*model.ProcedureCode, 0, len(req))
+	for _, entry := range req {
+		active := true
+		if entry.Active != nil {
+			active = *entry.Active
+		}
+		codes = append(codes, &model.ProcedureCode{
+			Code:        entry.Code,
+			System:      entry.System,
+			Description: entry.Description,
+			Active:      active,
+		})
+	}
+
+	count, err := h.service.ImportCodes(c.Request.Context(), codes)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidProcedure) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.Error("Failed to import procedure codes", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to import procedure codes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"imported": count})
+}
+
+// DeactivateCode godoc
+// @Summary Retire a procedure code
+// @Description Stop a code from being attached to appointments; procedures already recorded keep it
+// @Tags admin,procedures
+// @Produce json
+// @Security BearerAuth
+// @Param code path string true "Procedure code"
+// @Success 204 "Code retired"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /admin/procedure-codes/{code} [delete]
+func (h *ProcedureHandler) DeactivateCode(c *gin.Context) {
+	if err := h.service.DeactivateCode(c.Request.Context(), c.Param("code")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListAppointmentProcedures godoc
+// @Summary List appointment procedures
+// @Description List the coded procedures recorded on an appointment
+// @Tags appointments,procedures
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Appointment ID (UUID)"
+// @Success 200 {array} model.AppointmentProcedure "Procedures"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /appointments/{id}/procedures [get]
+func (h *ProcedureHandler) ListAppointmentProcedures(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid appointment ID"})
+		return
+	}
+
+	procedures, err := h.service.ListAppointmentProcedures(c.Request.Context(), uint(id))
+	if err != nil {
+		h.logger.Error("Failed to list procedures", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list procedures"})
+		return
+	}
+
+	c.JSON(http.StatusOK, procedures)
+}
+
+// AddAppointmentProcedure godoc
+// @Summary Record a procedure
+// @Description Record a procedure performed during a completed appointment with an active CPT or HCPCS code, billing modifiers and units
+// @Tags appointments,procedures
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Appointment ID (UUID)"
+// @Param request body appointmentProcedureRequest true "Procedure"
+// @Success 201 {object} model.AppointmentProcedure "Recorded procedure"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 409 {object} map[string]string "Appointment not completed"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /appointments/{id}/procedures [post]
+func (h *ProcedureHandler) AddAppointmentProcedure(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid appointment ID"})
+		return
+	}
+
+	var req appointmentProcedureRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	procedure, err := h.service.AddAppointmentProcedure(c.Request.Context(), uint(id), c.GetUint("userID"), &model.AppointmentProcedure{
+		Code:      req.Code,
+		Modifiers: req.Modifiers,
+		Units:     req.Units,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidProcedure):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrAppointmentNotCompleted):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			h.logger.Error("Failed to record procedure", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to record procedure"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, procedure)
+}
+
+// RemoveAppointmentProcedure godoc
+// @Summary Remove a procedure
+// @Description Remove a procedure recorded on an appointment by mistake
+// @Tags appointments,procedures
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Appointment ID (UUID)"
+// @Param procedureID path int true "Procedure ID"
+// @Success 204 "Procedure removed"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /appointments/{id}/procedures/{procedureID} [delete]
+func (h *ProcedureHandler) RemoveAppointmentProcedure(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid appointment ID"})
+		return
+	}
+	procedureID, err := strconv.ParseUint(c.Param("procedureID"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid procedure ID"})
+		return
+	}
+
+	if err := h.service.RemoveAppointmentProcedure(c.Request.Context(), uint(id), uint(procedureID)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ExportClaims godoc
+// @Summary Export claims
+// @Description Download a CSV claim line for each coded procedure of the clinic's completed appointments between two dates, inclusive, in the clinic's timezone
+// @Tags admin,procedures
+// @Produce text/csv
+// @Security BearerAuth
+// @Param id path int true "Organization ID"
+// @Param from query string true "First day (YYYY-MM-DD)"
+// @Param to query string true "Last day (YYYY-MM-DD)"
+// @Success 200 {file} file "Claim lines"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /admin/organizations/{id}/claims [get]
+func (h *ProcedureHandler) ExportClaims(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid organization ID"})
+		return
+	}
+
+	from, to := c.Query("from"), c.Query("to")
+	if from == "" || to == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from and to dates are required"})
+		return
+	}
+
+	lines, err := h.service.ExportClaims(c.Request.Context(), uint(id), from, to)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidProcedure) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.Error("Failed to export claims", zap.Uint64("organizationID", id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to export claims"})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("claims-%s-%s.csv", from, to)))
+	c.Header("Cache-Control", "no-store")
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Status(http.StatusOK)
+
+	w := csv.NewWriter(c.Writer)
+	_ = w.Write(claimColumns)
+	for _, line := range lines {
+		_ = w.Write([]string{
+			line.AppointmentID,
+			line.ServiceDate.Format("2006-01-02"),
+			line.PatientID,
+			line.PatientName,
+			line.DateOfBirth.Format("2006-01-02"),
+			line.DoctorID,
+			line.DoctorName,
+			line.LicenseNo,
+			string(line.System),
+			line.Code,
+			line.Description,
+			strings.Join(line.Modifiers, " "),
+			strconv.Itoa(line.Units),
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		h.logger.Warn("Failed to write claims export", zap.Error(err))
+	}
+}
+
+// claimColumns is the header row of the claims export
+var claimColumns = []string{
+	"appointment_id",
+	"service_date",
+	"patient_id",
+	"patient_name",
+	"patient_date_of_birth",
+	"doctor_id",
+	"doctor_name",
+	"doctor_license_no",
+	"code_system",
+	"code",
+	"description",
+	"modifiers",
+	"units",
+}
+
+// Request and response models
+type procedureCodeRequest struct {
+	Code        string                    `json:"code" binding:"required"`
+	System      model.ProcedureCodeSystem `json:"system" binding:"required"`
+	Description string                    `json:"description" binding:"required"`
+	Active      *bool                     `json:"active"` // Defaults to true
+}
+
+type appointmentProcedureRequest struct {
+	Code      string   `json:"code" binding:"required"`
+	Modifiers []string `json:"modifiers"`
+	Units     int      `json:"units"` // Defaults to 1
+}
@@ -0,0 +1,375 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/service"
+	"go.uber.org/zap"
+)
+
+// RecurringAppointmentHandler handles HTTP requests for recurring appointment series
+type RecurringAppointmentHandler struct {
+	service   service.RecurringAppointmentService
+	publicIDs service.PublicIDService
+	logger    *zap.Logger
+}
+
+// NewRecurringAppointmentHandler creates a new recurring appointment handler
+func NewRecurringAppointmentHandler(
+	service service.RecurringAppointmentService,
+	publicIDs service.PublicIDService,
+	logger *zap.Logger,
+) *RecurringAppointmentHandler {
+	return &RecurringAppointmentHandler{
+		service:   service,
+		publicIDs: publicIDs,
+		logger:    logger,
+	}
+}
+
+// CreateSeries godoc
+// @Summary Book a recurring appointment series
+// @Description Book weekly, biweekly or monthly appointments in one call. Every occurrence is checked like a single booking; if any one is unavailable or conflicts, nothing is booked. Patients book for themselves or their dependants; staff with appointments:book for any patient.
+// @Tags appointments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param series body createSeriesRequest true "Series details"
+// @Success 201 {object} seriesResponse "Booked series"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Not the caller's own or dependant's booking"
+// @Failure 409 {object} map[string]string "Doctor or patient already booked for an occurrence"
+// @Router /appointments/series [post]
+func (h *RecurringAppointmentHandler) CreateSeries(c *gin.Context) {
+	var req createSeriesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	loc, err := inputLocation(c, req.Timezone)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid timezone"})
+		return
+	}
+	startTime, err := parseRequestTime(req.ScheduledStart, loc)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scheduled start time format"})
+		return
+	}
+
+	// Resolve the public patient and doctor IDs
+	patientID, err := h.publicIDs.ResolveID(c.Request.Context(), model.ResourcePatient, req.PatientID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	doctorID, err := h.publicIDs.ResolveID(c.Request.Context(), model.ResourceDoctor, req.DoctorID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	series, err := h.service.CreateSeries(
+		c.Request.Context(),
+		c.GetUint("userID"),
+		hasPermission(c, model.PermissionAppointmentsBook),
+		patientID,
+		doctorID,
+		req.AppointmentTypeID,
+		startTime.Format("2006-01-02"),
+		startTime.Format("15:04"),
+		model.RecurrenceFrequency(req.Frequency),
+		req.Occurrences,
+		req.Reason,
+		req.IntakeAnswers,
+	)
+	if err != nil {
+		if errors.Is(err, service.ErrScheduleConflict) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, service.ErrNotOwnBooking) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.Warn("Failed to create appointment series", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, toSeriesResponse(series, requestLocation(c)))
+}
+
+// GetSeries godoc
+// @Summary Get appointment series
+// @Description Get a recurring series with all its occurrences in date order. Only its patient, their guardian, its doctor or staff managing appointments can view it.
+// @Tags appointments
+// @Produce json
+// @Security BearerAuth
+// @Param seriesID path string true "Series ID (UUID)"
+// @Success 200 {object} seriesResponse "Series"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Not the patient or doctor of the series"
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /appointments/series/{seriesID} [get]
+func (h *RecurringAppointmentHandler) GetSeries(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("seriesID"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid series ID"})
+		return
+	}
+
+	series, err := h.service.GetSeries(c.Request.Context(), uint(id), c.GetUint("userID"), hasPermission(c, model.PermissionAppointmentsManage))
+	if err != nil {
+		if errors.Is(err, service.ErrNotOwnSeries) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, toSeriesResponse(series, requestLocation(c)))
+}
+
+// ListOccurrences godoc
+// @Summary List appointment series occurrences
+// @Description List every occurrence of a series in date order, including those cancelled, with the changes made to an occurrence on its own
+// @Tags appointments
+// @Produce json
+// @Security BearerAuth
+// @Param seriesID path string true "Series ID (UUID)"
+// @Success 200 {array} seriesOccurrenceResponse "Occurrences"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Not the patient or doctor of the series"
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /appointments/series/{seriesID}/occurrences [get]
+func (h *RecurringAppointmentHandler) ListOccurrences(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("seriesID"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid series ID"})
+		return
+	}
+
+	occurrences, err := h.service.ListOccurrences(c.Request.Context(), uint(id), c.GetUint("userID"), hasPermission(c, model.PermissionAppointmentsManage))
+	if err != nil {
+		if errors.Is(err, service.ErrNotOwnSeries) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	loc := requestLocation(c)
+	response := make([]seriesOccurrenceResponse, 0, len(occurrences))
+	for n, appointment := range occurrences {
+		occurrence := seriesOccurrenceResponse{
+			Number:      n + 1,
+			Appointment: formatAppointmentResponse(appointment, loc),
+		}
+		if exception := appointment.SeriesException; exception != nil {
+			occurrence.Exception = &seriesExceptionResponse{
+				Kind:          string(exception.Kind),
+				OriginalStart: exception.OriginalStart.In(loc).Format(time.RFC3339),
+			}
+		}
+		response = append(response, occurrence)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// UpdateSeries godoc
+// @Summary Update appointment series
+// @Description Move every upcoming occurrence of a series: the next one moves to scheduled_start and the others by the same number of days, at the same time of day. Occurrences changed on their own are left as they are. With from, only that occurrence and the ones after it change, and they are split off into a new series, which is returned. To change one occurrence only, update that appointment instead.
+// @Tags appointments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param seriesID path string true "Series ID (UUID)"
+// @Param from query string false "Appointment ID (UUID) of the first occurrence to change"
+// @Param series body updateSeriesRequest true "Series changes"
+// @Success 200 {object} seriesResponse "Updated series"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Not the patient or doctor of the series"
+// @Failure 409 {object} map[string]string "Doctor or patient already booked for an occurrence"
+// @Router /appointments/series/{seriesID} [put]
+func (h *RecurringAppointmentHandler) UpdateSeries(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("seriesID"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid series ID"})
+		return
+	}
+
+	from, err := h.resolveFrom(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req updateSeriesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	var date, timeStr string
+	if req.ScheduledStart != "" {
+		loc, err := inputLocation(c, req.Timezone)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid timezone"})
+			return
+		}
+		startTime, err := parseRequestTime(req.ScheduledStart, loc)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scheduled start time format"})
+			return
+		}
+		date = startTime.Format("2006-01-02")
+		timeStr = startTime.Format("15:04")
+	}
+
+	series, err := h.service.UpdateSeries(c.Request.Context(), uint(id), from,
+		c.GetUint("userID"), hasPermission(c, model.PermissionAppointmentsManage), date, timeStr, req.Reason)
+	if err != nil {
+		if errors.Is(err, service.ErrScheduleConflict) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, service.ErrNotOwnSeries) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.Warn("Failed to update appointment series", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, toSeriesResponse(series, requestLocation(c)))
+}
+
+// CancelSeries godoc
+// @Summary Cancel appointment series
+// @Description Cancel every upcoming occurrence of a series. Occurrences starting within the hour are kept. With from, only that occurrence and the ones after it are cancelled. To cancel one occurrence only, cancel that appointment instead.
+// @Tags appointments
+// @Produce json
+// @Security BearerAuth
+// @Param seriesID path string true "Series ID (UUID)"
+// @Param from query string false "Appointment ID (UUID) of the first occurrence to cancel"
+// @Success 200 {object} map[string]string "Series cancelled successfully"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Not the patient or doctor of the series"
+// @Router /appointments/series/{seriesID}/cancel [post]
+func (h *RecurringAppointmentHandler) CancelSeries(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("seriesID"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid series ID"})
+		return
+	}
+
+	from, err := h.resolveFrom(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.service.CancelSeries(c.Request.Context(), uint(id), from, c.GetUint("userID"), hasPermission(c, model.PermissionAppointmentsManage)); err != nil {
+		if errors.Is(err, service.ErrNotOwnSeries) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.Error("Failed to cancel appointment series", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Series cancelled successfully"})
+}
+
+// resolveFrom resolves the occurrence a series change starts from, given as the public ID of an
+// appointment in the from query parameter. It returns 0 if none is given.
+func (h *RecurringAppointmentHandler) resolveFrom(c *gin.Context) (uint, error) {
+	from := c.Query("from")
+	if from == "" {
+		return 0, nil
+	}
+	return h.publicIDs.ResolveID(c.Request.Context(), model.ResourceAppointment, from)
+}
+
+// Request and response types
+
+type createSeriesRequest struct {
+	PatientID         string            `json:"patient_id" binding:"required"`      // Public patient ID
+	DoctorID          string            `json:"doctor_id" binding:"required"`       // Public doctor ID
+	ScheduledStart    string            `json:"scheduled_start" binding:"required"` // First occurrence, RFC3339 or a local time in timezone
+	Timezone          string            `json:"timezone"`                           // IANA timezone of a local time; defaults to the user's
+	Frequency         string            `json:"frequency" binding:"required"`       // weekly, biweekly or monthly
+	Occurrences       int               `json:"occurrences" binding:"required"`     // 2 to 52
+	Reason            string            `json:"reason"`
+	AppointmentTypeID uint              `json:"appointment_type_id"`
+	IntakeAnswers     map[string]string `json:"intake_answers"`
+}
+
+type updateSeriesRequest struct {
+	ScheduledStart string `json:"scheduled_start,omitempty"` // New start of the next occurrence, RFC3339 or a local time in timezone
+	Timezone       string `json:"timezone,omitempty"`        // IANA timezone of a local time; defaults to the user's
+	Reason         string `json:"reason,omitempty"`
+}
+
+type seriesResponse struct {
+	ID                string                `json:"id"`
+	PatientID         string                `json:"patient_id"`
+	DoctorID          string                `json:"doctor_id"`
+	AppointmentTypeID *uint                 `json:"appointment_type_id,omitempty"`
+	Frequency         string                `json:"frequency"`
+	Occurrences       int                   `json:"occurrences"`
+	Reason            string                `json:"reason,omitempty"`
+	CancelledAt       *string               `json:"cancelled_at,omitempty"`
+	Appointments      []appointmentResponse `json:"appointments"`
+	CreatedAt         string                `json:"created_at"`
+}
+
+type seriesOccurrenceResponse struct {
+	Number      int                      `json:"number"` // Position in the series, from 1
+	Appointment appointmentResponse      `json:"appointment"`
+	Exception   *seriesExceptionResponse `json:"exception,omitempty"` // Set when the occurrence was changed on its own
+}
+
+type seriesExceptionResponse struct {
+	Kind          string `json:"kind"`           // moved, edited or cancelled
+	OriginalStart string `json:"original_start"` // Start the series gave the occurrence
+}
+
+func toSeriesResponse(series *model.RecurringAppointment, loc *time.Location) seriesResponse {
+	response := seriesResponse{
+		ID:                series.PublicID,
+		PatientID:         series.Patient.PublicID,
+		DoctorID:          series.Doctor.PublicID,
+		AppointmentTypeID: series.AppointmentTypeID,
+		Frequency:         string(series.Frequency),
+		Occurrences:       series.Occurrences,
+		Reason:            series.Reason,
+		Appointments:      make([]appointmentResponse, 0, len(series.Appointments)),
+		CreatedAt:         series.CreatedAt.In(loc).Format(time.RFC3339),
+	}
+	if series.CancelledAt != nil {
+		cancelledAt := series.CancelledAt.In(loc).Format(time.RFC3339)
+		response.CancelledAt = &cancelledAt
+	}
+	for _, appointment := range series.Appointments {
+		response.Appointments = append(response.Appointments, formatAppointmentResponse(appointment, loc))
+	}
+	return response
+}
@@ -0,0 +1,227 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/service"
+	"go.uber.org/zap"
+)
+
+// ReviewHandler handles doctor review HTTP requests
+type ReviewHandler struct {
+	service   service.ReviewService
+	publicIDs service.PublicIDService
+	logger    *zap.Logger
+}
+
+// NewReviewHandler creates a new doctor review handler
+func NewReviewHandler(service service.ReviewService, publicIDs service.PublicIDService, logger *zap.Logger) *ReviewHandler {
+	return &ReviewHandler{
+		service:   service,
+		publicIDs: publicIDs,
+		logger:    logger,
+	}
+}
+
+// CreateReview godoc
+// @Summary Review a doctor
+// @Description Rate a doctor from 1 to 5 after a completed visit with them. Only the patient of the appointment can review it, within the review window after the visit, and each appointment can be reviewed once. Patients can write a limited number of reviews a day.
+// @Tags doctors,reviews
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Doctor ID (UUID)"
+// @Param request body createReviewRequest true "Review"
+// @Success 201 {object} reviewResponse "Created review"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "No recent completed visit with the doctor"
+// @Failure 409 {object} map[string]string "Appointment already reviewed"
+// @Failure 429 {object} map[string]string "Daily review limit reached"
+// @Router /doctors/{id}/reviews [post]
+func (h *ReviewHandler) CreateReview(c *gin.Context) {
+	doctorID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid doctor ID"})
+		return
+	}
+
+	var req createReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	appointmentID, err := h.publicIDs.ResolveID(c.Request.Context(), model.ResourceAppointment, req.AppointmentID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	review, err := h.service.CreateReview(c.Request.Context(), c.GetUint("userID"), uint(doctorID), appointmentID, req.Rating, req.Comment)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrReviewNotAllowed):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrAlreadyReviewed):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrReviewLimitReached):
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, toReviewResponse(review))
+}
+
+// ListReviews godoc
+// @Summary List doctor reviews
+// @Description List a doctor's reviews, most recent first, with their average rating
+// @Tags doctors,reviews
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Doctor ID (UUID)"
+// @Param page query int false "Page number" default(1)
+// @Param pageSize query int false "Page size" default(20)
+// @Success 200 {object} map[string]interface{} "Reviews"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "Doctor not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /doctors/{id}/reviews [get]
+func (h *ReviewHandler) ListReviews(c *gin.Context) {
+	doctorID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid doctor ID"})
+		return
+	}
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	pageSize, err := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
+	if err != nil || pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	reviews, total, average, err := h.service.ListReviews(c.Request.Context(), uint(doctorID), page, pageSize)
+	if err != nil {
+		if err.Error() == "doctor not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.Error("Failed to list reviews", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list reviews"})
+		return
+	}
+
+	response := make([]reviewResponse, 0, len(reviews))
+	for _, review := range reviews {
+		response = append(response, toReviewResponse(review))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"reviews":        response,
+		"average_rating": average,
+		"total":          total,
+		"page":           page,
+		"size":           pageSize,
+	})
+}
+
+// RespondToReview godoc
+// @Summary Respond to a review
+// @Description Answer one of your reviews as the reviewed doctor. Responding again replaces the earlier response.
+// @Tags doctors,reviews
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Doctor ID (UUID)"
+// @Param reviewID path string true "Review ID (UUID)"
+// @Param request body reviewResponseRequest true "Response"
+// @Success 200 {object} reviewResponse "Updated review"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Review not found"
+// @Router /doctors/{id}/reviews/{reviewID}/response [put]
+func (h *ReviewHandler) RespondToReview(c *gin.Context) {
+	doctorID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid doctor ID"})
+		return
+	}
+	reviewID, err := strconv.ParseUint(c.Param("reviewID"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid review ID"})
+		return
+	}
+
+	var req reviewResponseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	review, err := h.service.RespondToReview(c.Request.Context(), c.GetUint("userID"), uint(doctorID), uint(reviewID), req.Response)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrNotOwnReview):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case err.Error() == "review not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, toReviewResponse(review))
+}
+
+// Request and response models
+type createReviewRequest struct {
+	AppointmentID string `json:"appointment_id" binding:"required"` // Public ID of the completed appointment being reviewed
+	Rating        int    `json:"rating" binding:"required,min=1,max=5"`
+	Comment       string `json:"comment"`
+}
+
+type reviewResponseRequest struct {
+	Response string `json:"response" binding:"required"`
+}
+
+type reviewResponse struct {
+	ID           string `json:"id"`
+	ReviewerName string `json:"reviewer_name"` // Patient's first name only
+	Rating       int    `json:"rating"`
+	Comment      string `json:"comment,omitempty"`
+	Response     string `json:"response,omitempty"`
+	RespondedAt  string `json:"responded_at,omitempty"`
+	CreatedAt    string `json:"created_at"`
+}
+
+// Helper function to convert model to response
+func toReviewResponse(review *model.DoctorReview) reviewResponse {
+	response := reviewResponse{
+		ID:        review.PublicID,
+		Rating:    review.Rating,
+		Comment:   review.Comment,
+		Response:  review.Response,
+		CreatedAt: review.CreatedAt.Format(time.RFC3339),
+	}
+	if names := strings.Fields(review.Patient.User.Name); len(names) > 0 {
+		response.ReviewerName = names[0]
+	}
+	if review.RespondedAt != nil {
+		response.RespondedAt = review.RespondedAt.Format(time.RFC3339)
+	}
+	return response
+}
@@ -0,0 +1,328 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/service"
+	"go.uber.org/zap"
+)
+
+// RoleHandler handles custom role management HTTP requests
+type RoleHandler struct {
+	service service.RoleService
+	logger  *zap.Logger
+}
+
+// NewRoleHandler creates a new role handler
+func NewRoleHandler(service service.RoleService, logger *zap.Logger) *RoleHandler {
+	return &RoleHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// ListPermissions godoc
+// @Summary List permissions
+// @Description List every permission that can be granted and the permissions of each built-in role
+// @Tags admin,roles
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "Permissions and built-in roles"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Router /admin/permissions [get]
+func (h *RoleHandler) ListPermissions(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"permissions":    model.AllPermissions,
+		"built_in_roles": model.RolePermissions,
+	})
+}
+
+// CreateRole godoc
+// @Summary Create custom role
+// @Description Create a custom role, such as receptionist or billing clerk, composed of permissions
+// @Tags admin,roles
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body createRoleRequest true "Role details"
+// @Success 201 {object} roleResponse "Created role"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Router /admin/roles [post]
+func (h *RoleHandler) CreateRole(c *gin.Context) {
+	var req createRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	role, err := h.service.CreateRole(c.Request.Context(), req.Name, req.Description, req.Permissions)
+	if err != nil {
+		h.logger.Warn("Failed to create role", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, toRoleResponse(role))
+}
+
+// ListRoles godoc
+// @Summary List custom roles
+// @Description List all custom roles
+// @Tags admin,roles
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} roleResponse "Custom roles"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /admin/roles [get]
+func (h *RoleHandler) ListRoles(c *gin.Context) {
+	roles, err := h.service.ListRoles(c.Request.Context())
+	if err != nil {
+		h.logger.Error("Failed to list roles", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list roles"})
+		return
+	}
+
+	c.JSON(http.StatusOK, toRoleResponses(roles))
+}
+
+// GetRole godoc
+// @Summary Get custom role
+// @Description Get a custom role by ID
+// @Tags admin,roles
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Role ID"
+// @Success 200 {object} roleResponse "Custom role"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /admin/roles/{id} [get]
+func (h *RoleHandler) GetRole(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid role ID"})
+		return
+	}
+
+	role, err := h.service.GetRole(c.Request.Context(), uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, toRoleResponse(role))
+}
+
+// UpdateRole godoc
+// @Summary Update custom role
+// @Description Replace the description and permissions of a custom role; users holding it are affected immediately
+// @Tags admin,roles
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Role ID"
+// @Param request body updateRoleRequest true "Role details"
+// @Success 200 {object} roleResponse "Updated role"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Router /admin/roles/{id} [put]
+func (h *RoleHandler) UpdateRole(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid role ID"})
+		return
+	}
+
+	var req updateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	role, err := h.service.UpdateRole(c.Request.Context(), uint(id), req.Description, req.Permissions)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, toRoleResponse(role))
+}
+
+// DeleteRole godoc
+// @Summary Delete custom role
+// @Description Delete a custom role and revoke it from all users
+// @Tags admin,roles
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Role ID"
+// @Success 200 {object} map[string]string "Role deleted"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /admin/roles/{id} [delete]
+func (h *RoleHandler) DeleteRole(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid role ID"})
+		return
+	}
+
+	if err := h.service.DeleteRole(c.Request.Context(), uint(id)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "role deleted"})
+}
+
+// GetUserRoles godoc
+// @Summary Get user roles
+// @Description Get the custom roles assigned to a user and their effective permissions
+// @Tags admin,roles
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID (UUID)"
+// @Success 200 {object} map[string]interface{} "Roles and effective permissions"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /admin/users/{id}/roles [get]
+func (h *RoleHandler) GetUserRoles(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	roles, err := h.service.GetUserRoles(c.Request.Context(), uint(userID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	permissions, err := h.service.GetUserPermissions(c.Request.Context(), uint(userID))
+	if err != nil {
+		h.logger.Error("Failed to resolve permissions", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to resolve permissions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"roles":       toRoleResponses(roles),
+		"permissions": permissions,
+	})
+}
+
+// AssignRole godoc
+// @Summary Assign custom role
+// @Description Grant a custom role to a user
+// @Tags admin,roles
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID (UUID)"
+// @Param roleID path int true "Role ID"
+// @Success 200 {object} map[string]string "Role assigned"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Router /admin/users/{id}/roles/{roleID} [put]
+func (h *RoleHandler) AssignRole(c *gin.Context) {
+	userID, roleID, ok := parseUserRoleParams(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.AssignRole(c.Request.Context(), userID, roleID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "role assigned"})
+}
+
+// UnassignRole godoc
+// @Summary Unassign custom role
+// @Description Revoke a custom role from a user
+// @Tags admin,roles
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID (UUID)"
+// @Param roleID path int true "Role ID"
+// @Success 200 {object} map[string]string "Role unassigned"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Router /admin/users/{id}/roles/{roleID} [delete]
+func (h *RoleHandler) UnassignRole(c *gin.Context) {
+	userID, roleID, ok := parseUserRoleParams(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.UnassignRole(c.Request.Context(), userID, roleID); err != nil {
+		h.logger.Error("Failed to unassign role", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to unassign role"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "role unassigned"})
+}
+
+// parseUserRoleParams reads the user and role IDs from the path, responding with 400 if invalid
+func parseUserRoleParams(c *gin.Context) (uint, uint, bool) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return 0, 0, false
+	}
+	roleID, err := strconv.ParseUint(c.Param("roleID"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid role ID"})
+		return 0, 0, false
+	}
+	return uint(userID), uint(roleID), true
+}
+
+// Request and response models
+type createRoleRequest struct {
+	Name        string             `json:"name" binding:"required"`
+	Description string             `json:"description"`
+	Permissions []model.Permission `json:"permissions" binding:"required,min=1"`
+}
+
+type updateRoleRequest struct {
+	Description string             `json:"description"`
+	Permissions []model.Permission `json:"permissions" binding:"required,min=1"`
+}
+
+type roleResponse struct {
+	ID          uint               `json:"id"`
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	Permissions []model.Permission `json:"permissions"`
+	CreatedAt   string             `json:"created_at"`
+	UpdatedAt   string             `json:"updated_at"`
+}
+
+// Helper function to convert model to response
+func toRoleResponse(role *model.CustomRole) roleResponse {
+	return roleResponse{
+		ID:          role.ID,
+		Name:        role.Name,
+		Description: role.Description,
+		Permissions: role.Permissions,
+		CreatedAt:   role.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:   role.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+func toRoleResponses(roles []*model.CustomRole) []roleResponse {
+	response := make([]roleResponse, 0, len(roles))
+	for _, role := range roles {
+		response = append(response, toRoleResponse(role))
+	}
+	return response
+}
@@ -0,0 +1,224 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/whitewalker-sa/ehass/internal/service"
+	"github.com/whitewalker-sa/ehass/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// ScheduleHandler handles bookable slot HTTP requests
+type ScheduleHandler struct {
+	service service.ScheduleService
+	logger  *zap.Logger
+}
+
+// NewScheduleHandler creates a new schedule handler
+func NewScheduleHandler(service service.ScheduleService, logger *zap.Logger) *ScheduleHandler {
+	return &ScheduleHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// GetSlots godoc
+// @Summary Get available slots
+// @Description List a doctor's free appointment slots on a date or between two dates. Slots are cut from the doctor's weekly availability at each window's slot duration, less booked and held times. Dates are resolved in the clinic's timezone and accept YYYY-MM-DD, today, tomorrow, a weekday name or an offset such as +7d or +2w. Times are returned in the caller's timezone unless another is given, so staff can offer slots in the patient's own time.
+// @Tags doctors,schedule
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Doctor ID (UUID)"
+// @Param date query string false "Single date; shorthand for from and to on the same day"
+// @Param from query string false "First date" default(today)
+// @Param to query string false "Last date, inclusive; defaults to a week from the first date"
+// @Param timezone query string false "IANA timezone to express the slots in, such as the patient's"
+// @Success 200 {object} slotRangeResponse "Available slots"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Router /doctors/{id}/slots [get]
+func (h *ScheduleHandler) GetSlots(c *gin.Context) {
+	doctorID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid doctor ID"})
+		return
+	}
+
+	from, to := c.Query("from"), c.Query("to")
+	if date := c.Query("date"); date != "" {
+		if from != "" || to != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "date cannot be combined with from or to"})
+			return
+		}
+		from, to = date, date
+	}
+
+	loc := requestLocation(c)
+	if tz := c.Query("timezone"); tz != "" {
+		if !utils.ValidTimezone(tz) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid timezone"})
+			return
+		}
+		loc = utils.LoadLocation(tz)
+	}
+
+	slotRange, err := h.service.GetAvailableSlots(c.Request.Context(), uint(doctorID), from, to)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	slots := make([]slotResponse, len(slotRange.Slots))
+	for i, slot := range slotRange.Slots {
+		slots[i] = toSlotResponse(slot, loc)
+	}
+	c.JSON(http.StatusOK, slotRangeResponse{
+		From:           slotRange.From.Format("2006-01-02"),
+		To:             slotRange.To.Format("2006-01-02"),
+		ClinicTimezone: slotRange.Timezone,
+		Timezone:       loc.String(),
+		Slots:          slots,
+	})
+}
+
+// GetNextSlot godoc
+// @Summary Get next available slot
+// @Description Find a doctor's earliest free appointment slot on or after a date, within the clinic's booking window
+// @Tags doctors,schedule
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Doctor ID (UUID)"
+// @Param after query string false "Earliest date; accepts the same values as the slots query" default(today)
+// @Success 200 {object} slotResponse "Next available slot"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "No available slot"
+// @Router /doctors/{id}/slots/next [get]
+func (h *ScheduleHandler) GetNextSlot(c *gin.Context) {
+	doctorID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid doctor ID"})
+		return
+	}
+
+	slot, err := h.service.GetNextAvailableSlot(c.Request.Context(), uint(doctorID), c.Query("after"))
+	if err != nil {
+		if errors.Is(err, service.ErrNoAvailableSlot) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, toSlotResponse(*slot, requestLocation(c)))
+}
+
+// SuggestWorkload godoc
+// @Summary Suggest workload distribution
+// @Description Spread a number of proposed bookings across the doctors of a specialty by current load, so walk-in demand can be distributed evenly. Each booking goes to the least loaded doctor with a free slot, at their earliest one. Nothing is booked.
+// @Tags doctors,schedule
+// @Produce json
+// @Security BearerAuth
+// @Param specialty query string true "Specialty"
+// @Param from query string false "First date; accepts the same values as the slots query" default(today)
+// @Param to query string false "Last date, inclusive; defaults to a week from the first date"
+// @Param count query int false "Number of bookings to place" default(1)
+// @Success 200 {object} workloadPlanResponse "Proposed distribution"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Router /doctors/workload [get]
+func (h *ScheduleHandler) SuggestWorkload(c *gin.Context) {
+	specialty := c.Query("specialty")
+	if specialty == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "specialty is required"})
+		return
+	}
+	count, err := strconv.Atoi(c.DefaultQuery("count", "1"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid count"})
+		return
+	}
+
+	plan, err := h.service.SuggestDistribution(c.Request.Context(), specialty, c.Query("from"), c.Query("to"), count)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	loc := requestLocation(c)
+	response := workloadPlanResponse{
+		Specialty:  plan.Specialty,
+		Doctors:    make([]doctorWorkloadResponse, 0, len(plan.Doctors)),
+		Proposals:  make([]workloadProposalResponse, 0, len(plan.Proposals)),
+		Unassigned: plan.Unassigned,
+	}
+	for _, workload := range plan.Doctors {
+		response.Doctors = append(response.Doctors, doctorWorkloadResponse{
+			DoctorID:   workload.Doctor.PublicID,
+			DoctorName: workload.Doctor.User.Name,
+			Booked:     workload.Booked,
+			FreeSlots:  workload.FreeSlots,
+			Proposed:   workload.Proposed,
+		})
+	}
+	for _, proposal := range plan.Proposals {
+		response.Proposals = append(response.Proposals, workloadProposalResponse{
+			DoctorID:   proposal.Doctor.PublicID,
+			DoctorName: proposal.Doctor.User.Name,
+			Slot:       toSlotResponse(proposal.Slot, loc),
+		})
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// Request and response models
+
+type slotResponse struct {
+	Start    string `json:"start"`
+	End      string `json:"end"`
+	Timezone string `json:"timezone"`
+}
+
+type slotRangeResponse struct {
+	From           string         `json:"from"`
+	To             string         `json:"to"`
+	ClinicTimezone string         `json:"clinic_timezone"`
+	Timezone       string         `json:"timezone"`
+	Slots          []slotResponse `json:"slots"`
+}
+
+func toSlotResponse(slot service.Slot, loc *time.Location) slotResponse {
+	return slotResponse{
+		Start:    slot.Start.In(loc).Format(time.RFC3339),
+		End:      slot.End.In(loc).Format(time.RFC3339),
+		Timezone: loc.String(),
+	}
+}
+
+type doctorWorkloadResponse struct {
+	DoctorID   string `json:"doctor_id"`
+	DoctorName string `json:"doctor_name"`
+	Booked     int    `json:"booked"`
+	FreeSlots  int    `json:"free_slots"`
+	Proposed   int    `json:"proposed"`
+}
+
+type workloadProposalResponse struct {
+	DoctorID   string       `json:"doctor_id"`
+	DoctorName string       `json:"doctor_name"`
+	Slot       slotResponse `json:"slot"`
+}
+
+type workloadPlanResponse struct {
+	Specialty  string                     `json:"specialty"`
+	Doctors    []doctorWorkloadResponse   `json:"doctors"`
+	Proposals  []workloadProposalResponse `json:"proposals"`
+	Unassigned int                        `json:"unassigned"`
+}
@@ -0,0 +1,362 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/service"
+	"go.uber.org/zap"
+)
+
+// SearchHandler handles doctor and patient search
+type SearchHandler struct {
+	service      service.SearchService
+	translations service.TranslationService
+	operations   service.OperationService
+	suggestTTL   time.Duration
+	logger       *zap.Logger
+}
+
+// NewSearchHandler creates a new search handler and registers the background reindex. Clients
+// may reuse suggestions for suggestTTL.
+func NewSearchHandler(
+	service service.SearchService,
+	translations service.TranslationService,
+	operations service.OperationService,
+	suggestTTL time.Duration,
+	logger *zap.Logger,
+) *SearchHandler {
+	h := &SearchHandler{
+		service:      service,
+		translations: translations,
+		operations:   operations,
+		suggestTTL:   suggestTTL,
+		logger:       logger,
+	}
+	operations.Register(model.OperationSearchReindex, h.runReindex)
+	return h
+}
+
+// SearchDoctors godoc
+// @Summary Search doctors
+// @Description Find doctors by name, specialty or bio, with the number of matches per specialty. Tolerates typos when a search backend is enabled.
+// @Tags doctors
+// @Produce json
+// @Security BearerAuth
+// @Param q query string true "Search text, at least 2 characters"
+// @Param specialty query string false "Only return doctors with this specialty"
+// @Param limit query int false "Maximum results, at most 50" default(20)
+// @Success 200 {object} doctorSearchResponse "Matching doctors"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /doctors/search [get]
+func (h *SearchHandler) SearchDoctors(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+
+	result, err := h.service.SearchDoctors(c.Request.Context(), c.Query("q"), c.Query("specialty"), limit)
+	if err != nil {
+		if errors.Is(err, service.ErrSearchQueryTooShort) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.Error("Failed to search doctors", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to search doctors"})
+		return
+	}
+
+	localizeDoctors(c, h.translations, h.logger, result.Doctors...)
+	doctors := make([]doctorResponse, 0, len(result.Doctors))
+	for _, doctor := range result.Doctors {
+		doctors = append(doctors, toDoctorResponse(doctor))
+	}
+
+	c.JSON(http.StatusOK, doctorSearchResponse{
+		Doctors:     doctors,
+		Total:       result.Total,
+		Specialties: result.Specialties,
+	})
+}
+
+// SearchPatients godoc
+// @Summary Search patients
+// @Description Find patients by name, email, phone or date of birth (YYYY-MM-DD) for clinic staff. Returns the best matches with minimal details.
+// @Tags patients
+// @Produce json
+// @Security BearerAuth
+// @Param q query string true "Search text, at least 2 characters"
+// @Param limit query int false "Maximum results, at most 50" default(20)
+// @Success 200 {object} patientSearchResponse "Matching patients"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /patients/search [get]
+func (h *SearchHandler) SearchPatients(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+
+	patients, err := h.service.SearchPatients(c.Request.Context(), c.Query("q"), limit)
+	if err != nil {
+		if errors.Is(err, service.ErrSearchQueryTooShort) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.Error("Failed to search patients", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to search patients"})
+		return
+	}
+
+	results := make([]patientSearchResult, 0, len(patients))
+	for _, patient := range patients {
+		results = append(results, patientSearchResult{
+			ID:          patient.PublicID,
+			Name:        patient.User.Name,
+			Email:       patient.User.Email,
+			Phone:       patient.User.Phone,
+			DateOfBirth: patient.DateOfBirth.Format("2006-01-02"),
+		})
+	}
+
+	c.JSON(http.StatusOK, patientSearchResponse{Patients: results})
+}
+
+// SearchEncounters godoc
+// @Summary Search past encounters
+// @Description Find appointments by words of their reason or notes, and medical records by their diagnosis, e.g. chest pain or amoxicillin. Best matches come first. Quote words to match a phrase and prefix a word with - to exclude it. Doctors only find their own encounters, diagnoses are only searched for users allowed to read medical records, and only encounters of patients whose records the caller may read are returned.
+// @Tags appointments
+// @Produce json
+// @Security BearerAuth
+// @Param q query string true "Search text, at least 2 characters"
+// @Param limit query int false "Maximum results, at most 50" default(20)
+// @Success 200 {object} encounterSearchResponse "Matching encounters"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /appointments/search [get]
+func (h *SearchHandler) SearchEncounters(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	role, _ := c.Get("userRole")
+	userRole, _ := role.(model.Role)
+	includeDiagnoses := hasPermission(c, model.PermissionMedicalRecordsRead)
+
+	encounters, err := h.service.SearchEncounters(c.Request.Context(), c.GetUint("userID"), userRole, c.Query("q"), includeDiagnoses, limit)
+	if err != nil {
+		if errors.Is(err, service.ErrSearchQueryTooShort) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.Error("Failed to search encounters", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to search encounters"})
+		return
+	}
+
+	loc := requestLocation(c)
+	results := make([]encounterResult, 0, len(encounters))
+	for _, encounter := range encounters {
+		results = append(results, toEncounterResult(encounter, loc))
+	}
+	c.JSON(http.StatusOK, encounterSearchResponse{Encounters: results})
+}
+
+// hasPermission reports whether the permissions resolved for the request include p
+func hasPermission(c *gin.Context, p model.Permission) bool {
+	value, _ := c.Get("permissions")
+	permissions, _ := value.([]model.Permission)
+	for _, granted := range permissions {
+		if granted == p {
+			return true
+		}
+	}
+	return false
+}
+
+// SuggestDoctors godoc
+// @Summary Suggest doctors
+// @Description Doctors with a word of their name starting with the typed text, for booking-form typeahead. Responses may be cached.
+// @Tags doctors
+// @Produce json
+// @Security BearerAuth
+// @Param q query string true "Typed text, at least 2 characters"
+// @Param limit query int false "Maximum suggestions, at most 10" default(5)
+// @Success 200 {object} doctorSuggestResponse "Suggested doctors"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /doctors/suggest [get]
+func (h *SearchHandler) SuggestDoctors(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+
+	doctors, err := h.service.SuggestDoctors(c.Request.Context(), c.Query("q"), limit)
+	if err != nil {
+		if errors.Is(err, service.ErrSearchQueryTooShort) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.Error("Failed to suggest doctors", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to suggest doctors"})
+		return
+	}
+
+	h.cacheSuggestions(c)
+	c.JSON(http.StatusOK, doctorSuggestResponse{Doctors: doctors})
+}
+
+// SuggestSpecialties godoc
+// @Summary Suggest specialties
+// @Description Specialties with a word starting with the typed text and their number of doctors, most common first, for booking-form typeahead. Responses may be cached.
+// @Tags doctors
+// @Produce json
+// @Security BearerAuth
+// @Param q query string true "Typed text, at least 2 characters"
+// @Param limit query int false "Maximum suggestions, at most 10" default(5)
+// @Success 200 {object} specialtySuggestResponse "Suggested specialties"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /specialties/suggest [get]
+func (h *SearchHandler) SuggestSpecialties(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+
+	specialties, err := h.service.SuggestSpecialties(c.Request.Context(), c.Query("q"), limit)
+	if err != nil {
+		if errors.Is(err, service.ErrSearchQueryTooShort) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.Error("Failed to suggest specialties", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to suggest specialties"})
+		return
+	}
+
+	h.cacheSuggestions(c)
+	c.JSON(http.StatusOK, specialtySuggestResponse{Specialties: specialties})
+}
+
+// cacheSuggestions lets the browser reuse suggestions while the user edits their input. They
+// are private because the request is authenticated.
+func (h *SearchHandler) cacheSuggestions(c *gin.Context) {
+	if h.suggestTTL > 0 {
+		c.Header("Cache-Control", fmt.Sprintf("private, max-age=%d", int(h.suggestTTL.Seconds())))
+	}
+}
+
+// Reindex godoc
+// @Summary Rebuild the search index
+// @Description Write every doctor and patient to the search backend, e.g. after enabling it. Changes made afterwards are synced through the outbox.
+// @Tags admin,operations
+// @Produce json
+// @Security BearerAuth
+// @Param Prefer header string false "respond-async to rebuild the index in the background"
+// @Success 200 {object} service.ReindexResult "Number of records indexed"
+// @Success 202 {object} operationResponse "Operation rebuilding the index"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 409 {object} map[string]string "No search backend is enabled"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /admin/ops/search/reindex [post]
+func (h *SearchHandler) Reindex(c *gin.Context) {
+	if prefersAsync(c) {
+		submitOperation(c, h.operations, h.logger, model.OperationSearchReindex, nil)
+		return
+	}
+
+	result, err := h.service.Reindex(c.Request.Context())
+	if err != nil {
+		if errors.Is(err, service.ErrSearchDisabled) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.Error("Failed to rebuild search index", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to rebuild search index"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// runReindex rebuilds the search index for a reindex requested with Prefer: respond-async
+func (h *SearchHandler) runReindex(ctx context.Context, _ json.RawMessage) (interface{}, error) {
+	return h.service.Reindex(ctx)
+}
+
+// Request and response models
+type doctorSearchResponse struct {
+	Doctors     []doctorResponse     `json:"doctors"`
+	Total       int64                `json:"total"`       // All matches, including those beyond the limit
+	Specialties []service.FacetCount `json:"specialties"` // Matches per specialty, ignoring the specialty filter
+}
+
+type doctorSuggestResponse struct {
+	Doctors []service.DoctorSuggestion `json:"doctors"`
+}
+
+type specialtySuggestResponse struct {
+	Specialties []service.FacetCount `json:"specialties"` // Count is the number of doctors
+}
+
+type encounterSearchResponse struct {
+	Encounters []encounterResult `json:"encounters"`
+}
+
+// encounterResult is an appointment or medical record found by an encounter search
+type encounterResult struct {
+	Type        string `json:"type"` // appointment or medical_record
+	ID          string `json:"id"`
+	PatientID   string `json:"patient_id"`
+	PatientName string `json:"patient_name"`
+	DoctorID    string `json:"doctor_id"`
+	DoctorName  string `json:"doctor_name"`
+	Date        string `json:"date"` // Scheduled start of an appointment, visit date of a record
+	Reason      string `json:"reason,omitempty"`
+	Notes       string `json:"notes,omitempty"`
+	Diagnosis   string `json:"diagnosis,omitempty"`
+}
+
+func toEncounterResult(encounter service.Encounter, loc *time.Location) encounterResult {
+	if record := encounter.MedicalRecord; record != nil {
+		return encounterResult{
+			Type:        "medical_record",
+			ID:          record.PublicID,
+			PatientID:   record.Patient.PublicID,
+			PatientName: record.Patient.User.Name,
+			DoctorID:    record.Doctor.PublicID,
+			DoctorName:  record.Doctor.User.Name,
+			Date:        record.VisitDate.In(loc).Format(time.RFC3339),
+			Notes:       record.Notes,
+			Diagnosis:   record.Diagnosis,
+		}
+	}
+	appointment := encounter.Appointment
+	return encounterResult{
+		Type:        "appointment",
+		ID:          appointment.PublicID,
+		PatientID:   appointment.Patient.PublicID,
+		PatientName: appointment.Patient.User.Name,
+		DoctorID:    appointment.Doctor.PublicID,
+		DoctorName:  appointment.Doctor.User.Name,
+		Date:        appointment.ScheduledStart.In(loc).Format(time.RFC3339),
+		Reason:      appointment.Reason,
+		Notes:       appointment.Notes,
+	}
+}
+
+type patientSearchResponse struct {
+	Patients []patientSearchResult `json:"patients"`
+}
+
+// patientSearchResult is the minimal view of a patient shown in search results
+type patientSearchResult struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Email       string `json:"email"`
+	Phone       string `json:"phone,omitempty"`
+	DateOfBirth string `json:"date_of_birth"`
+}
@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/service"
+	"go.uber.org/zap"
+)
+
+// SecurityHandler handles security monitoring HTTP requests
+type SecurityHandler struct {
+	service service.SecurityService
+	logger  *zap.Logger
+}
+
+// NewSecurityHandler creates a new security handler
+func NewSecurityHandler(service service.SecurityService, logger *zap.Logger) *SecurityHandler {
+	return &SecurityHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// GetAuthEvents godoc
+// @Summary Authentication security dashboard
+// @Description Summarize authentication events over a window: totals by event, the addresses and accounts with the most failed logins, wrong 2FA codes and password reset requests, and the latest anomaly alerts
+// @Tags admin,security
+// @Produce json
+// @Security BearerAuth
+// @Param window query string false "Period to summarize, as a duration up to 720h" default(24h)
+// @Success 200 {object} authDashboardResponse "Authentication dashboard"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /admin/security/auth-events [get]
+func (h *SecurityHandler) GetAuthEvents(c *gin.Context) {
+	window, err := time.ParseDuration(c.DefaultQuery("window", "24h"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "window must be a duration such as 24h"})
+		return
+	}
+
+	dashboard, err := h.service.GetAuthDashboard(c.Request.Context(), window)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidDashboardWindow) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.Error("Failed to get authentication dashboard", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get authentication events"})
+		return
+	}
+
+	alerts := make([]securityAlertResponse, 0, len(dashboard.Alerts))
+	for _, alert := range dashboard.Alerts {
+		alerts = append(alerts, toSecurityAlertResponse(alert))
+	}
+
+	c.JSON(http.StatusOK, authDashboardResponse{
+		Since:              dashboard.Since.Format(time.RFC3339),
+		Totals:             dashboard.Totals,
+		FailedLoginsByIP:   toAuthEventGroups(dashboard.FailedByIP),
+		FailedLoginsByUser: toAuthEventGroups(dashboard.FailedByUser),
+		TwoFactorFailures:  toAuthEventGroups(dashboard.TwoFactorFails),
+		PasswordResetsByIP: toAuthEventGroups(dashboard.ResetsByIP),
+		Alerts:             alerts,
+	})
+}
+
+// Response models
+type authDashboardResponse struct {
+	Since              string                   `json:"since"`
+	Totals             map[string]int64         `json:"totals"`
+	FailedLoginsByIP   []authEventGroupResponse `json:"failed_logins_by_ip"`
+	FailedLoginsByUser []authEventGroupResponse `json:"failed_logins_by_user"`
+	TwoFactorFailures  []authEventGroupResponse `json:"two_factor_failures"`
+	PasswordResetsByIP []authEventGroupResponse `json:"password_resets_by_ip"`
+	Alerts             []securityAlertResponse  `json:"alerts"`
+}
+
+type authEventGroupResponse struct {
+	IP       string `json:"ip,omitempty"`
+	UserID   string `json:"user_id,omitempty"`
+	Email    string `json:"email,omitempty"`
+	Count    int64  `json:"count"`
+	Accounts int64  `json:"accounts,omitempty"`
+}
+
+type securityAlertResponse struct {
+	ID          uint   `json:"id"`
+	Kind        string `json:"kind"`
+	Subject     string `json:"subject"`
+	Count       int64  `json:"count"`
+	Threshold   int64  `json:"threshold"`
+	WindowStart string `json:"window_start"`
+	CreatedAt   string `json:"created_at"`
+}
+
+func toAuthEventGroups(counts []*service.AuthEventCount) []authEventGroupResponse {
+	response := make([]authEventGroupResponse, 0, len(counts))
+	for _, count := range counts {
+		response = append(response, authEventGroupResponse{
+			IP:       count.IP,
+			UserID:   count.UserID,
+			Email:    count.Email,
+			Count:    count.Count,
+			Accounts: count.Accounts,
+		})
+	}
+	return response
+}
+
+func toSecurityAlertResponse(alert *model.SecurityAlert) securityAlertResponse {
+	return securityAlertResponse{
+		ID:          alert.ID,
+		Kind:        alert.Kind,
+		Subject:     alert.Subject,
+		Count:       alert.Count,
+		Threshold:   alert.Threshold,
+		WindowStart: alert.WindowStart.Format(time.RFC3339),
+		CreatedAt:   alert.CreatedAt.Format(time.RFC3339),
+	}
+}
@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/whitewalker-sa/ehass/internal/service"
+	"go.uber.org/zap"
+)
+
+// SettingsHandler handles runtime settings HTTP requests
+type SettingsHandler struct {
+	service service.SettingsService
+	logger  *zap.Logger
+}
+
+// NewSettingsHandler creates a new settings handler
+func NewSettingsHandler(service service.SettingsService, logger *zap.Logger) *SettingsHandler {
+	return &SettingsHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// ListSettings godoc
+// @Summary List runtime settings
+// @Description List the operational settings that can be changed without a restart, with their effective and configured default values
+// @Tags admin,settings
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} settingsResponse "Settings"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /admin/settings [get]
+func (h *SettingsHandler) ListSettings(c *gin.Context) {
+	values, err := h.service.ListSettings(c.Request.Context())
+	if err != nil {
+		h.logger.Error("Failed to list settings", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list settings"})
+		return
+	}
+
+	response := settingsResponse{Settings: make([]settingResponse, 0, len(values))}
+	for _, value := range values {
+		setting := settingResponse{Key: value.Key, Value: value.Value, Default: value.Default}
+		if value.UpdatedAt != nil {
+			setting.UpdatedAt = value.UpdatedAt.Format(time.RFC3339)
+		}
+		response.Settings = append(response.Settings, setting)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// UpdateSettings godoc
+// @Summary Change runtime settings
+// @Description Change operational settings, such as {"maintenance_mode": true, "features.no_show_confirmation": true}. All values are checked before any is stored. Changes apply on this instance at once and on the others within the refresh interval.
+// @Tags admin,settings
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body map[string]interface{} true "Settings by key"
+// @Success 200 {object} service.RuntimeSettings "Settings in effect"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /admin/settings [patch]
+func (h *SettingsHandler) UpdateSettings(c *gin.Context) {
+	var values map[string]json.RawMessage
+	if err := c.ShouldBindJSON(&values); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	settings, err := h.service.UpdateSettings(c.Request.Context(), c.GetUint("userID"), values)
+	if err != nil {
+		h.settingsError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, settings)
+}
+
+// ResetSetting godoc
+// @Summary Reset runtime setting
+// @Description Remove the value set for a setting through the API, so its configured default applies again
+// @Tags admin,settings
+// @Produce json
+// @Security BearerAuth
+// @Param key path string true "Setting key"
+// @Success 200 {object} service.RuntimeSettings "Settings in effect"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /admin/settings/{key} [delete]
+func (h *SettingsHandler) ResetSetting(c *gin.Context) {
+	settings, err := h.service.ResetSetting(c.Request.Context(), c.GetUint("userID"), c.Param("key"))
+	if err != nil {
+		h.settingsError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, settings)
+}
+
+// GetFeatures godoc
+// @Summary Get feature flags
+// @Description Get the feature flags in effect and whether the service is in maintenance mode, so clients can adapt without a release
+// @Tags settings
+// @Produce json
+// @Success 200 {object} featuresResponse "Feature flags"
+// @Router /features [get]
+func (h *SettingsHandler) GetFeatures(c *gin.Context) {
+	settings := h.service.Current()
+	response := featuresResponse{Features: settings.Features, Maintenance: settings.MaintenanceMode}
+	if settings.MaintenanceMode {
+		response.MaintenanceMessage = settings.MaintenanceMessage
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *SettingsHandler) settingsError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrUnknownSetting):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidSetting):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		h.logger.Error("Failed to change settings", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to change settings"})
+	}
+}
+
+// Response models
+type settingsResponse struct {
+	Settings []settingResponse `json:"settings"`
+}
+
+type settingResponse struct {
+	Key       string          `json:"key"`
+	Value     json.RawMessage `json:"value"`
+	Default   json.RawMessage `json:"default"`
+	UpdatedAt string          `json:"updated_at,omitempty"` // Set while the value comes from the API rather than the configuration
+}
+
+type featuresResponse struct {
+	Features           map[string]bool `json:"features"`
+	Maintenance        bool            `json:"maintenance"`
+	MaintenanceMessage string          `json:"maintenance_message,omitempty"`
+}
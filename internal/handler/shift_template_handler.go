@@ -0,0 +1,255 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/service"
+	"go.uber.org/zap"
+)
+
+// ShiftTemplateHandler handles shift template HTTP requests
+type ShiftTemplateHandler struct {
+	service service.ShiftTemplateService
+	logger  *zap.Logger
+}
+
+// NewShiftTemplateHandler creates a new shift template handler
+func NewShiftTemplateHandler(service service.ShiftTemplateService, logger *zap.Logger) *ShiftTemplateHandler {
+	return &ShiftTemplateHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// CreateShiftTemplate godoc
+// @Summary Create shift template
+// @Description Add a reusable week of working windows, such as "Morning clinic" or "Surgery day", to apply to doctors' availability for a range of dates. Windows on the same day cannot overlap.
+// @Tags admin,availability
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body shiftTemplateRequest true "Shift template"
+// @Success 201 {object} shiftTemplateResponse "Created shift template"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 409 {object} map[string]string "Name already in use"
+// @Router /admin/shift-templates [post]
+func (h *ShiftTemplateHandler) CreateShiftTemplate(c *gin.Context) {
+	var req shiftTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	template, err := h.service.CreateShiftTemplate(c.Request.Context(), req.toInput())
+	if err != nil {
+		h.shiftTemplateError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, toShiftTemplateResponse(template))
+}
+
+// ListShiftTemplates godoc
+// @Summary List shift templates
+// @Description List the shift templates doctors' availability can be filled from, by name
+// @Tags availability
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} shiftTemplateResponse "Shift templates"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /shift-templates [get]
+func (h *ShiftTemplateHandler) ListShiftTemplates(c *gin.Context) {
+	templates, err := h.service.ListShiftTemplates(c.Request.Context())
+	if err != nil {
+		h.logger.Error("Failed to list shift templates", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list shift templates"})
+		return
+	}
+
+	response := make([]shiftTemplateResponse, 0, len(templates))
+	for _, template := range templates {
+		response = append(response, toShiftTemplateResponse(template))
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// GetShiftTemplate godoc
+// @Summary Get shift template
+// @Description Get a shift template by ID
+// @Tags admin,availability
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Shift template ID"
+// @Success 200 {object} shiftTemplateResponse "Shift template"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /admin/shift-templates/{id} [get]
+func (h *ShiftTemplateHandler) GetShiftTemplate(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid shift template ID"})
+		return
+	}
+
+	template, err := h.service.GetShiftTemplate(c.Request.Context(), uint(id))
+	if err != nil {
+		h.shiftTemplateError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, toShiftTemplateResponse(template))
+}
+
+// UpdateShiftTemplate godoc
+// @Summary Update shift template
+// @Description Replace the name and windows of a shift template. Availability already applied from it is not changed.
+// @Tags admin,availability
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Shift template ID"
+// @Param request body shiftTemplateRequest true "Shift template"
+// @Success 200 {object} shiftTemplateResponse "Updated shift template"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not found"
+// @Failure 409 {object} map[string]string "Name already in use"
+// @Router /admin/shift-templates/{id} [put]
+func (h *ShiftTemplateHandler) UpdateShiftTemplate(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid shift template ID"})
+		return
+	}
+
+	var req shiftTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	template, err := h.service.UpdateShiftTemplate(c.Request.Context(), uint(id), req.toInput())
+	if err != nil {
+		h.shiftTemplateError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, toShiftTemplateResponse(template))
+}
+
+// DeleteShiftTemplate godoc
+// @Summary Delete shift template
+// @Description Delete a shift template. Availability already applied from it is kept.
+// @Tags admin,availability
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Shift template ID"
+// @Success 200 {object} map[string]string "Shift template deleted"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /admin/shift-templates/{id} [delete]
+func (h *ShiftTemplateHandler) DeleteShiftTemplate(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid shift template ID"})
+		return
+	}
+
+	if err := h.service.DeleteShiftTemplate(c.Request.Context(), uint(id)); err != nil {
+		h.shiftTemplateError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "shift template deleted"})
+}
+
+// shiftTemplateError maps a shift template service error to a response
+func (h *ShiftTemplateHandler) shiftTemplateError(c *gin.Context, err error) {
+	switch {
+	case err.Error() == "shift template not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrShiftTemplateExists):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
+
+// Request and response models
+type shiftWindowRequest struct {
+	DayOfWeek string `json:"day_of_week" binding:"required" example:"monday"`
+	StartTime string `json:"start_time" binding:"required" example:"08:00"`
+	EndTime   string `json:"end_time" binding:"required" example:"12:00"`
+	Duration  int    `json:"duration" example:"15"` // Slot length in minutes; 0 for the doctor's consultation length
+}
+
+type shiftTemplateRequest struct {
+	Name        string               `json:"name" binding:"required,max=100" example:"Morning clinic"`
+	Description string               `json:"description"`
+	Windows     []shiftWindowRequest `json:"windows" binding:"required,dive"`
+}
+
+func (r shiftTemplateRequest) toInput() service.ShiftTemplateInput {
+	input := service.ShiftTemplateInput{
+		Name:        r.Name,
+		Description: r.Description,
+		Windows:     make([]service.ShiftWindowInput, len(r.Windows)),
+	}
+	for i, window := range r.Windows {
+		input.Windows[i] = service.ShiftWindowInput{
+			Day:       window.DayOfWeek,
+			StartTime: window.StartTime,
+			EndTime:   window.EndTime,
+			Duration:  window.Duration,
+		}
+	}
+	return input
+}
+
+type shiftWindowResponse struct {
+	DayOfWeek int    `json:"day_of_week"`
+	Day       string `json:"day"`
+	StartTime string `json:"start_time"`
+	EndTime   string `json:"end_time"`
+	Duration  int    `json:"duration"` // 0 uses the doctor's consultation length
+}
+
+type shiftTemplateResponse struct {
+	ID          uint                  `json:"id"`
+	Name        string                `json:"name"`
+	Description string                `json:"description,omitempty"`
+	Windows     []shiftWindowResponse `json:"windows"`
+	CreatedAt   string                `json:"created_at"`
+	UpdatedAt   string                `json:"updated_at"`
+}
+
+func toShiftTemplateResponse(template *model.ShiftTemplate) shiftTemplateResponse {
+	response := shiftTemplateResponse{
+		ID:          template.ID,
+		Name:        template.Name,
+		Description: template.Description,
+		Windows:     make([]shiftWindowResponse, len(template.Windows)),
+		CreatedAt:   template.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:   template.UpdatedAt.Format(time.RFC3339),
+	}
+	for i, window := range template.Windows {
+		response.Windows[i] = shiftWindowResponse{
+			DayOfWeek: window.DayOfWeek,
+			Day:       time.Weekday(window.DayOfWeek).String(),
+			StartTime: window.StartTime,
+			EndTime:   window.EndTime,
+			Duration:  window.Duration,
+		}
+	}
+	return response
+}
@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/service"
+	"go.uber.org/zap"
+)
+
+// SlotHoldHandler handles slot hold HTTP requests
+type SlotHoldHandler struct {
+	service   service.SlotHoldService
+	publicIDs service.PublicIDService
+	logger    *zap.Logger
+}
+
+// NewSlotHoldHandler creates a new slot hold handler
+func NewSlotHoldHandler(service service.SlotHoldService, publicIDs service.PublicIDService, logger *zap.Logger) *SlotHoldHandler {
+	return &SlotHoldHandler{
+		service:   service,
+		publicIDs: publicIDs,
+		logger:    logger,
+	}
+}
+
+// HoldSlot godoc
+// @Summary Hold a slot
+// @Description Reserve a free slot for a patient while they complete the booking. Other patients cannot book or hold the slot until the hold is released, used to book, or expires.
+// @Tags appointments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body slotHoldRequest true "Slot to hold"
+// @Success 201 {object} slotHoldResponse "Slot hold"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 409 {object} map[string]string "Slot held by another patient"
+// @Router /appointments/holds [post]
+func (h *SlotHoldHandler) HoldSlot(c *gin.Context) {
+	var req slotHoldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	loc, err := inputLocation(c, req.Timezone)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid timezone"})
+		return
+	}
+	startTime, err := parseRequestTime(req.ScheduledStart, loc)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scheduled start time format"})
+		return
+	}
+
+	// Resolve the public patient and doctor IDs
+	patientID, err := h.publicIDs.ResolveID(c.Request.Context(), model.ResourcePatient, req.PatientID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	doctorID, err := h.publicIDs.ResolveID(c.Request.Context(), model.ResourceDoctor, req.DoctorID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	hold, err := h.service.HoldSlot(c.Request.Context(), patientID, doctorID,
+		startTime.Format("2006-01-02"), startTime.Format("15:04"))
+	if err != nil {
+		if errors.Is(err, service.ErrSlotHeld) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Answer in the timezone the slot was asked for
+	c.JSON(http.StatusCreated, slotHoldResponse{
+		Token:          hold.Token,
+		DoctorID:       req.DoctorID,
+		ScheduledStart: hold.Start.In(loc).Format(time.RFC3339),
+		ExpiresAt:      hold.ExpiresAt.In(loc).Format(time.RFC3339),
+	})
+}
+
+// ReleaseHold godoc
+// @Summary Release a slot hold
+// @Description Release a slot hold before it expires, for example when the patient abandons the booking
+// @Tags appointments
+// @Produce json
+// @Security BearerAuth
+// @Param token path string true "Hold token"
+// @Success 200 {object} map[string]string "Hold released"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /appointments/holds/{token} [delete]
+func (h *SlotHoldHandler) ReleaseHold(c *gin.Context) {
+	if err := h.service.ReleaseHold(c.Request.Context(), c.Param("token")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Hold released"})
+}
+
+// Request and response models
+
+type slotHoldRequest struct {
+	PatientID      string `json:"patient_id" binding:"required"`      // Public patient ID
+	DoctorID       string `json:"doctor_id" binding:"required"`       // Public doctor ID
+	ScheduledStart string `json:"scheduled_start" binding:"required"` // RFC3339, or a local time in timezone
+	Timezone       string `json:"timezone"`                           // IANA timezone of a local time; defaults to the user's
+}
+
+type slotHoldResponse struct {
+	Token          string `json:"token"`
+	DoctorID       string `json:"doctor_id"`
+	ScheduledStart string `json:"scheduled_start"`
+	ExpiresAt      string `json:"expires_at"`
+}
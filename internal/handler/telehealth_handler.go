@@ -0,0 +1,477 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/whitewalker-sa/ehass/internal/config"
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/service"
+	"go.uber.org/zap"
+)
+
+// streamWriteGrace is how long past the end of an event stream its last write may take
+const streamWriteGrace = 10 * time.Second
+
+// TelehealthHandler handles video visit waiting room HTTP requests
+type TelehealthHandler struct {
+	service   service.TelehealthService
+	publicIDs service.PublicIDService
+	config    config.TelehealthConfig
+	logger    *zap.Logger
+}
+
+// NewTelehealthHandler creates a new telehealth handler
+func NewTelehealthHandler(service service.TelehealthService, publicIDs service.PublicIDService, cfg config.TelehealthConfig, logger *zap.Logger) *TelehealthHandler {
+	return &TelehealthHandler{
+		service:   service,
+		publicIDs: publicIDs,
+		config:    cfg,
+		logger:    logger,
+	}
+}
+
+// GetWaitingRoom godoc
+// @Summary Get video visit waiting room
+// @Description Get who is waiting for and who was admitted to a video appointment, with the times recorded so far. Available to the appointment's patients and their guardians, its doctor and admins.
+// @Tags appointments,telehealth
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Appointment ID (UUID)"
+// @Success 200 {object} waitingRoomResponse "Waiting room"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /appointments/{id}/waiting-room [get]
+func (h *TelehealthHandler) GetWaitingRoom(c *gin.Context) {
+	id, ok := waitingRoomParam(c)
+	if !ok {
+		return
+	}
+
+	role, _ := c.Get("userRole")
+	userRole, _ := role.(model.Role)
+	room, err := h.service.GetWaitingRoom(c.Request.Context(), id, c.GetUint("userID"), userRole)
+	if err != nil {
+		h.telehealthError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, toWaitingRoomResponse(room, requestLocation(c)))
+}
+
+// JoinWaitingRoom godoc
+// @Summary Join video visit waiting room
+// @Description Enter the waiting room of a confirmed or checked-in video appointment, from shortly before its start until its end. The first patient to join notifies the doctor. Available to the appointment's patients and their guardians.
+// @Tags appointments,telehealth
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Appointment ID (UUID)"
+// @Success 200 {object} waitingRoomResponse "Waiting room"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not found"
+// @Failure 409 {object} map[string]string "Waiting room is not open"
+// @Router /appointments/{id}/waiting-room/join [post]
+func (h *TelehealthHandler) JoinWaitingRoom(c *gin.Context) {
+	h.updateWaitingRoom(c, h.service.JoinWaitingRoom)
+}
+
+// LeaveWaitingRoom godoc
+// @Summary Leave video visit waiting room
+// @Description Leave the waiting room or the visit. Available to the appointment's patients and their guardians.
+// @Tags appointments,telehealth
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Appointment ID (UUID)"
+// @Success 200 {object} waitingRoomResponse "Waiting room"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /appointments/{id}/waiting-room/leave [post]
+func (h *TelehealthHandler) LeaveWaitingRoom(c *gin.Context) {
+	h.updateWaitingRoom(c, h.service.LeaveWaitingRoom)
+}
+
+// AdmitPatients godoc
+// @Summary Admit waiting patients
+// @Description Let everyone in the waiting room into the visit. The first admission records when the visit started. Only the appointment's doctor can admit patients.
+// @Tags appointments,telehealth
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Appointment ID (UUID)"
+// @Success 200 {object} waitingRoomResponse "Waiting room"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not found"
+// @Failure 409 {object} map[string]string "Nobody is waiting or the visit ended"
+// @Router /appointments/{id}/waiting-room/admit [post]
+func (h *TelehealthHandler) AdmitPatients(c *gin.Context) {
+	h.updateWaitingRoom(c, h.service.AdmitPatients)
+}
+
+// EndVisit godoc
+// @Summary End video visit
+// @Description Close the waiting room and the visit, recording when the visit ended. Only the appointment's doctor can end the visit.
+// @Tags appointments,telehealth
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Appointment ID (UUID)"
+// @Success 200 {object} waitingRoomResponse "Waiting room"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /appointments/{id}/waiting-room/end [post]
+func (h *TelehealthHandler) EndVisit(c *gin.Context) {
+	h.updateWaitingRoom(c, h.service.EndVisit)
+}
+
+// StreamWaitingRoomEvents godoc
+// @Summary Stream video visit waiting room events
+// @Description Receive an appointment's waiting room events as server-sent events while they happen. The stream closes after a few minutes; clients reconnect with the Last-Event-ID header to resume after the last event they received.
+// @Tags appointments,telehealth
+// @Produce text/event-stream
+// @Security BearerAuth
+// @Param id path string true "Appointment ID (UUID)"
+// @Param Last-Event-ID header int false "Resume after this event"
+// @Success 200 {object} waitingRoomEventResponse "Event stream"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /appointments/{id}/waiting-room/events [get]
+func (h *TelehealthHandler) StreamWaitingRoomEvents(c *gin.Context) {
+	id, ok := waitingRoomParam(c)
+	if !ok {
+		return
+	}
+
+	role, _ := c.Get("userRole")
+	userRole, _ := role.(model.Role)
+	filter, err := h.service.AppointmentEventFilter(c.Request.Context(), id, c.GetUint("userID"), userRole)
+	if err != nil {
+		h.telehealthError(c, err)
+		return
+	}
+	h.streamEvents(c, filter)
+}
+
+// StreamDoctorEvents godoc
+// @Summary Stream waiting room events of the doctor's visits
+// @Description Receive the waiting room events of all of the signed-in doctor's video appointments as server-sent events, e.g. to be told when a patient starts waiting. Reconnect with the Last-Event-ID header to resume.
+// @Tags telehealth
+// @Produce text/event-stream
+// @Security BearerAuth
+// @Param Last-Event-ID header int false "Resume after this event"
+// @Success 200 {object} waitingRoomEventResponse "Event stream"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /telehealth/events [get]
+func (h *TelehealthHandler) StreamDoctorEvents(c *gin.Context) {
+	filter, err := h.service.DoctorEventFilter(c.Request.Context(), c.GetUint("userID"))
+	if err != nil {
+		h.telehealthError(c, err)
+		return
+	}
+	h.streamEvents(c, filter)
+}
+
+// GetVisitReport godoc
+// @Summary Get video visit report
+// @Description Report how long patients waited to be admitted and how long visits lasted, for the video visits whose waiting room opened in a date range (UTC)
+// @Tags admin,telehealth
+// @Produce json
+// @Security BearerAuth
+// @Param from query string true "First day (YYYY-MM-DD)"
+// @Param to query string true "Last day (YYYY-MM-DD)"
+// @Param doctor_id query string false "Doctor ID (UUID)"
+// @Success 200 {object} visitReportResponse "Visit report"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /admin/telehealth/visits [get]
+func (h *TelehealthHandler) GetVisitReport(c *gin.Context) {
+	var doctorID uint
+	if publicID := c.Query("doctor_id"); publicID != "" {
+		id, err := h.publicIDs.ResolveID(c.Request.Context(), model.ResourceDoctor, publicID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid doctor_id"})
+			return
+		}
+		doctorID = id
+	}
+
+	report, err := h.service.VisitReport(c.Request.Context(), c.Query("from"), c.Query("to"), doctorID)
+	if err != nil {
+		h.telehealthError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, toVisitReportResponse(report, requestLocation(c)))
+}
+
+// updateWaitingRoom applies a waiting room action as the signed-in user and returns the room
+func (h *TelehealthHandler) updateWaitingRoom(c *gin.Context, action func(ctx context.Context, appointmentID, userID uint) (*service.WaitingRoom, error)) {
+	id, ok := waitingRoomParam(c)
+	if !ok {
+		return
+	}
+
+	room, err := action(c.Request.Context(), id, c.GetUint("userID"))
+	if err != nil {
+		h.telehealthError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, toWaitingRoomResponse(room, requestLocation(c)))
+}
+
+// streamEvents writes the events matching filter as server-sent events until the client goes
+// away or the stream has been open for the configured duration. Events recorded on this
+// instance are sent at once; those recorded on other instances within the poll interval.
+func (h *TelehealthHandler) streamEvents(c *gin.Context, filter service.WaitingRoomFilter) {
+	ctx := c.Request.Context()
+
+	var after uint
+	if lastID := c.GetHeader("Last-Event-ID"); lastID != "" {
+		id, err := strconv.ParseUint(lastID, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid Last-Event-ID"})
+			return
+		}
+		after = uint(id)
+	} else {
+		latest, err := h.service.LatestWaitingRoomEvent(ctx, filter)
+		if err != nil {
+			h.logger.Error("Failed to start waiting room event stream", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start event stream"})
+			return
+		}
+		after = latest
+	}
+
+	// The stream outlives the server's write timeout
+	controller := http.NewResponseController(c.Writer)
+	if err := controller.SetWriteDeadline(time.Now().Add(h.config.StreamDuration + streamWriteGrace)); err != nil {
+		h.logger.Warn("Failed to extend event stream write deadline", zap.Error(err))
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	fmt.Fprintf(c.Writer, "retry: %d\n\n", h.config.PollInterval.Milliseconds())
+	c.Writer.Flush()
+
+	loc := requestLocation(c)
+	end := time.NewTimer(h.config.StreamDuration)
+	defer end.Stop()
+	poll := time.NewTicker(h.config.PollInterval)
+	defer poll.Stop()
+	heartbeat := time.NewTicker(h.config.Heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		events, changed, err := h.service.WaitingRoomEvents(ctx, filter, after)
+		if err != nil {
+			if ctx.Err() == nil {
+				h.logger.Error("Failed to read waiting room events", zap.Error(err))
+			}
+			return
+		}
+		for _, event := range events {
+			data, err := json.Marshal(toWaitingRoomEventResponse(event, loc))
+			if err != nil {
+				h.logger.Error("Failed to encode waiting room event", zap.Uint("eventID", event.ID), zap.Error(err))
+				return
+			}
+			fmt.Fprintf(c.Writer, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+			after = event.ID
+		}
+		if len(events) > 0 {
+			c.Writer.Flush()
+			// Read on in case more events are pending
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-end.C:
+			return
+		case <-changed:
+		case <-poll.C:
+		case <-heartbeat.C:
+			fmt.Fprint(c.Writer, ": keep-alive\n\n")
+			c.Writer.Flush()
+		}
+	}
+}
+
+// telehealthError writes the response for a waiting room error
+func (h *TelehealthHandler) telehealthError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrNotVisitParticipant),
+		errors.Is(err, service.ErrNotVisitDoctor):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrWaitingRoomClosed),
+		errors.Is(err, service.ErrNobodyWaiting):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case strings.HasSuffix(err.Error(), "not found"):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
+
+// waitingRoomParam parses the appointment ID of a waiting room route
+func waitingRoomParam(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid appointment ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// optionalTime formats a time that may not have been recorded yet, empty when it has not
+func optionalTime(t *time.Time, loc *time.Location) string {
+	if t == nil {
+		return ""
+	}
+	return t.In(loc).Format(time.RFC3339)
+}
+
+// Response types
+
+type waitingRoomResponse struct {
+	AppointmentID    string                       `json:"appointment_id"`
+	Status           string                       `json:"status"`
+	ScheduledStart   string                       `json:"scheduled_start"`
+	ScheduledEnd     string                       `json:"scheduled_end"`
+	WaitingSince     string                       `json:"waiting_since,omitempty"`
+	DoctorNotifiedAt string                       `json:"doctor_notified_at,omitempty"`
+	AdmittedAt       string                       `json:"admitted_at,omitempty"`
+	EndedAt          string                       `json:"ended_at,omitempty"`
+	Participants     []waitingRoomParticipantItem `json:"participants"`
+}
+
+type waitingRoomParticipantItem struct {
+	UserID string `json:"user_id"`
+	Name   string `json:"name"`
+	State  string `json:"state"`
+	Since  string `json:"since"`
+}
+
+type waitingRoomEventResponse struct {
+	ID            uint   `json:"id"`
+	Type          string `json:"type"`
+	AppointmentID string `json:"appointment_id"`
+	UserID        string `json:"user_id"`
+	Name          string `json:"name"`
+	CreatedAt     string `json:"created_at"`
+}
+
+type visitReportResponse struct {
+	From                   string            `json:"from"`
+	To                     string            `json:"to"`
+	Visits                 []visitTimingItem `json:"visits"`
+	AverageWaitSeconds     int64             `json:"average_wait_seconds"`
+	AverageDurationSeconds int64             `json:"average_duration_seconds"`
+}
+
+type visitTimingItem struct {
+	AppointmentID   string `json:"appointment_id"`
+	DoctorName      string `json:"doctor_name"`
+	PatientName     string `json:"patient_name"`
+	ScheduledStart  string `json:"scheduled_start"`
+	WaitingSince    string `json:"waiting_since,omitempty"`
+	AdmittedAt      string `json:"admitted_at,omitempty"`
+	EndedAt         string `json:"ended_at,omitempty"`
+	WaitSeconds     *int64 `json:"wait_seconds,omitempty"`
+	DurationSeconds *int64 `json:"duration_seconds,omitempty"`
+}
+
+// Helper functions to convert waiting rooms to responses
+
+func toWaitingRoomResponse(room *service.WaitingRoom, loc *time.Location) waitingRoomResponse {
+	response := waitingRoomResponse{
+		AppointmentID:  room.Appointment.PublicID,
+		Status:         string(room.Appointment.Status),
+		ScheduledStart: room.Appointment.ScheduledStart.In(loc).Format(time.RFC3339),
+		ScheduledEnd:   room.Appointment.ScheduledEnd.In(loc).Format(time.RFC3339),
+		Participants:   make([]waitingRoomParticipantItem, 0, len(room.Participants)),
+	}
+	if room.Visit != nil {
+		response.WaitingSince = optionalTime(room.Visit.WaitingSince, loc)
+		response.DoctorNotifiedAt = optionalTime(room.Visit.DoctorNotifiedAt, loc)
+		response.AdmittedAt = optionalTime(room.Visit.AdmittedAt, loc)
+		response.EndedAt = optionalTime(room.Visit.EndedAt, loc)
+	}
+	for _, participant := range room.Participants {
+		response.Participants = append(response.Participants, waitingRoomParticipantItem{
+			UserID: participant.User.PublicID,
+			Name:   participant.User.Name,
+			State:  participant.State,
+			Since:  participant.Since.In(loc).Format(time.RFC3339),
+		})
+	}
+	return response
+}
+
+func toWaitingRoomEventResponse(event *model.WaitingRoomEvent, loc *time.Location) waitingRoomEventResponse {
+	return waitingRoomEventResponse{
+		ID:            event.ID,
+		Type:          event.Type,
+		AppointmentID: event.Appointment.PublicID,
+		UserID:        event.User.PublicID,
+		Name:          event.User.Name,
+		CreatedAt:     event.CreatedAt.In(loc).Format(time.RFC3339),
+	}
+}
+
+func toVisitReportResponse(report *service.VisitReport, loc *time.Location) visitReportResponse {
+	response := visitReportResponse{
+		From:                   report.From.Format("2006-01-02"),
+		To:                     report.To.AddDate(0, 0, -1).Format("2006-01-02"),
+		Visits:                 make([]visitTimingItem, 0, len(report.Visits)),
+		AverageWaitSeconds:     int64(report.AverageWait.Seconds()),
+		AverageDurationSeconds: int64(report.AverageDuration.Seconds()),
+	}
+	for _, timing := range report.Visits {
+		visit := timing.Visit
+		item := visitTimingItem{
+			AppointmentID:  visit.Appointment.PublicID,
+			DoctorName:     visit.Appointment.Doctor.User.Name,
+			PatientName:    visit.Appointment.Patient.User.Name,
+			ScheduledStart: visit.Appointment.ScheduledStart.In(loc).Format(time.RFC3339),
+			WaitingSince:   optionalTime(visit.WaitingSince, loc),
+			AdmittedAt:     optionalTime(visit.AdmittedAt, loc),
+			EndedAt:        optionalTime(visit.EndedAt, loc),
+		}
+		if timing.Wait != nil {
+			seconds := int64(timing.Wait.Seconds())
+			item.WaitSeconds = &seconds
+		}
+		if timing.Duration != nil {
+			seconds := int64(timing.Duration.Seconds())
+			item.DurationSeconds = &seconds
+		}
+		response.Visits = append(response.Visits, item)
+	}
+	return response
+}
@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/whitewalker-sa/ehass/internal/service"
+	"go.uber.org/zap"
+)
+
+// TemplateHandler handles email and SMS template preview HTTP requests
+type TemplateHandler struct {
+	service service.TemplateService
+	logger  *zap.Logger
+}
+
+// NewTemplateHandler creates a new template handler
+func NewTemplateHandler(service service.TemplateService, logger *zap.Logger) *TemplateHandler {
+	return &TemplateHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// ListTemplates godoc
+// @Summary List message templates
+// @Description List the email and SMS templates that can be previewed and test-sent
+// @Tags admin,emails
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} messageTemplateResponse "Templates"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Router /admin/templates [get]
+func (h *TemplateHandler) ListTemplates(c *gin.Context) {
+	templates := h.service.ListTemplates()
+	response := make([]messageTemplateResponse, len(templates))
+	for i, t := range templates {
+		response[i] = messageTemplateResponse{
+			Name:        t.Name,
+			Channel:     t.Channel,
+			Description: t.Description,
+		}
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// PreviewTemplate godoc
+// @Summary Preview a message template
+// @Description Render a template with sample data without sending it. With format=html an email's body is returned as a page that can be opened in a browser.
+// @Tags admin,emails
+// @Produce json,html
+// @Security BearerAuth
+// @Param name path string true "Template name"
+// @Param format query string false "html for the raw email body"
+// @Success 200 {object} renderedMessageResponse "Rendered template"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Template not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /admin/templates/{name}/preview [get]
+func (h *TemplateHandler) PreviewTemplate(c *gin.Context) {
+	rendered, err := h.service.Preview(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		if errors.Is(err, service.ErrTemplateNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.Error("Failed to preview template", zap.String("template", c.Param("name")), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to preview template"})
+		return
+	}
+
+	if c.Query("format") == "html" && rendered.Channel == service.TemplateChannelEmail {
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(rendered.Body))
+		return
+	}
+	c.JSON(http.StatusOK, toRenderedMessageResponse(rendered))
+}
+
+// SendTestTemplate godoc
+// @Summary Send a test message
+// @Description Render a template with sample data and send it to the given recipient: an email address for email templates, or a phone number in E.164 format for SMS templates. Test emails appear in the delivery log.
+// @Tags admin,emails
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param name path string true "Template name"
+// @Param request body sendTestTemplateRequest true "Recipient"
+// @Success 200 {object} renderedMessageResponse "Message sent"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Template not found"
+// @Failure 502 {object} map[string]string "Message could not be sent"
+// @Router /admin/templates/{name}/test [post]
+func (h *TemplateHandler) SendTestTemplate(c *gin.Context) {
+	var req sendTestTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rendered, err := h.service.SendTest(c.Request.Context(), c.Param("name"), req.To)
+	if err != nil {
+		if errors.Is(err, service.ErrTemplateNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, service.ErrInvalidRecipient) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.Warn("Failed to send test message", zap.String("template", c.Param("name")), zap.Error(err))
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.logger.Info("Test message requested", zap.String("template", rendered.Template), zap.Uint("userID", c.GetUint("userID")))
+	c.JSON(http.StatusOK, toRenderedMessageResponse(rendered))
+}
+
+func toRenderedMessageResponse(rendered *service.RenderedMessage) renderedMessageResponse {
+	return renderedMessageResponse{
+		Template:    rendered.Template,
+		Channel:     rendered.Channel,
+		Subject:     rendered.Subject,
+		Body:        rendered.Body,
+		Attachments: rendered.Attachments,
+	}
+}
+
+// Request and response types
+
+type sendTestTemplateRequest struct {
+	To string `json:"to" binding:"required"` // Email address, or E.164 phone number for SMS templates
+}
+
+type messageTemplateResponse struct {
+	Name        string `json:"name"`
+	Channel     string `json:"channel"` // email or sms
+	Description string `json:"description"`
+}
+
+type renderedMessageResponse struct {
+	Template    string   `json:"template"`
+	Channel     string   `json:"channel"`
+	Subject     string   `json:"subject,omitempty"`
+	Body        string   `json:"body"`
+	Attachments []string `json:"attachments,omitempty"`
+}
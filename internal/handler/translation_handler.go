@@ -0,0 +1,221 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/service"
+	"github.com/whitewalker-sa/ehass/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// TranslationHandler handles doctor bios and specialty names in other languages
+type TranslationHandler struct {
+	service service.TranslationService
+	logger  *zap.Logger
+}
+
+// NewTranslationHandler creates a new translation handler
+func NewTranslationHandler(service service.TranslationService, logger *zap.Logger) *TranslationHandler {
+	return &TranslationHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// ListDoctorBios godoc
+// @Summary List bio translations
+// @Description List the translations of a doctor's bio
+// @Tags doctors,translations
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Doctor ID (UUID)"
+// @Success 200 {array} model.Translation "Translations"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /doctors/{id}/translations [get]
+func (h *TranslationHandler) ListDoctorBios(c *gin.Context) {
+	doctorID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid doctor ID"})
+		return
+	}
+
+	translations, err := h.service.ListDoctorBios(c.Request.Context(), uint(doctorID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "doctor not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, translations)
+}
+
+// SetDoctorBio godoc
+// @Summary Translate a doctor's bio
+// @Description Create or replace the translation of a doctor's bio into a locale
+// @Tags doctors,translations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Doctor ID (UUID)"
+// @Param locale path string true "Language tag, e.g. fr or fr-CA"
+// @Param translation body translationRequest true "Translated bio"
+// @Success 200 {object} model.Translation "Saved translation"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /doctors/{id}/translations/{locale} [put]
+func (h *TranslationHandler) SetDoctorBio(c *gin.Context) {
+	doctorID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid doctor ID"})
+		return
+	}
+
+	var req translationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	translation, err := h.service.SetDoctorBio(c.Request.Context(), uint(doctorID), c.Param("locale"), req.Text)
+	if err != nil {
+		h.respondSaveError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, translation)
+}
+
+// DeleteDoctorBio godoc
+// @Summary Remove a bio translation
+// @Description Remove the translation of a doctor's bio into a locale
+// @Tags doctors,translations
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Doctor ID (UUID)"
+// @Param locale path string true "Language tag, e.g. fr or fr-CA"
+// @Success 200 {object} map[string]string "Translation removed"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /doctors/{id}/translations/{locale} [delete]
+func (h *TranslationHandler) DeleteDoctorBio(c *gin.Context) {
+	doctorID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid doctor ID"})
+		return
+	}
+
+	if err := h.service.DeleteDoctorBio(c.Request.Context(), uint(doctorID), c.Param("locale")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "translation removed"})
+}
+
+// ListSpecialtyNames godoc
+// @Summary List specialty translations
+// @Description List the translations of a specialty name
+// @Tags doctors,translations
+// @Produce json
+// @Security BearerAuth
+// @Param specialty path string true "Specialty as stored on doctors"
+// @Success 200 {array} model.Translation "Translations"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /specialties/{specialty}/translations [get]
+func (h *TranslationHandler) ListSpecialtyNames(c *gin.Context) {
+	translations, err := h.service.ListSpecialtyNames(c.Request.Context(), c.Param("specialty"))
+	if err != nil {
+		h.logger.Error("Failed to list specialty translations", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list translations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, translations)
+}
+
+// SetSpecialtyName godoc
+// @Summary Translate a specialty
+// @Description Create or replace the translation of a specialty name into a locale. It applies to every doctor with the specialty.
+// @Tags doctors,translations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param specialty path string true "Specialty as stored on doctors"
+// @Param locale path string true "Language tag, e.g. fr or fr-CA"
+// @Param translation body translationRequest true "Translated name"
+// @Success 200 {object} model.Translation "Saved translation"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /specialties/{specialty}/translations/{locale} [put]
+func (h *TranslationHandler) SetSpecialtyName(c *gin.Context) {
+	var req translationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	translation, err := h.service.SetSpecialtyName(c.Request.Context(), c.Param("specialty"), c.Param("locale"), req.Text)
+	if err != nil {
+		h.respondSaveError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, translation)
+}
+
+// DeleteSpecialtyName godoc
+// @Summary Remove a specialty translation
+// @Description Remove the translation of a specialty name into a locale
+// @Tags doctors,translations
+// @Produce json
+// @Security BearerAuth
+// @Param specialty path string true "Specialty as stored on doctors"
+// @Param locale path string true "Language tag, e.g. fr or fr-CA"
+// @Success 200 {object} map[string]string "Translation removed"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /specialties/{specialty}/translations/{locale} [delete]
+func (h *TranslationHandler) DeleteSpecialtyName(c *gin.Context) {
+	if err := h.service.DeleteSpecialtyName(c.Request.Context(), c.Param("specialty"), c.Param("locale")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "translation removed"})
+}
+
+// respondSaveError writes the response for a translation that could not be saved
+func (h *TranslationHandler) respondSaveError(c *gin.Context, err error) {
+	if errors.Is(err, service.ErrInvalidTranslation) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	h.logger.Error("Failed to save translation", zap.Error(err))
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save translation"})
+}
+
+// localizeDoctors translates the bios and specialties of doctors for the Accept-Language of the
+// request. Responses then vary by that header. Doctors are left untranslated if it fails.
+func localizeDoctors(c *gin.Context, translations service.TranslationService, logger *zap.Logger, doctors ...*model.Doctor) {
+	c.Header("Vary", "Accept-Language")
+	preferred := utils.PreferredLocales(c.GetHeader("Accept-Language"))
+	if err := translations.LocalizeDoctors(c.Request.Context(), doctors, preferred); err != nil {
+		logger.Warn("Failed to translate doctors", zap.Error(err))
+	}
+}
+
+// Request and response models
+type translationRequest struct {
+	Text string `json:"text" binding:"required"`
+}
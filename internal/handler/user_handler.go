@@ -1,10 +1,12 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/whitewalker-sa/ehass/internal/model"
 	"github.com/whitewalker-sa/ehass/internal/service"
 	"go.uber.org/zap"
 )
@@ -51,19 +53,12 @@ func (h *UserHandler) GetProfile(c *gin.Context) {
 	}
 
 	// Return user info
-	c.JSON(http.StatusOK, userResponse{
-		ID:      user.ID,
-		Name:    user.Name,
-		Email:   user.Email,
-		Role:    string(user.Role),
-		Phone:   user.Phone,
-		Address: user.Address,
-	})
+	c.JSON(http.StatusOK, toUserResponse(user))
 }
 
 // UpdateProfile godoc
 // @Summary Update user profile
-// @Description Update authenticated user's profile
+// @Description Update authenticated user's profile. The phone number is stored in E.164; one without a country calling code is read as a number of phone_country, or of the configured default region.
 // @Tags users
 // @Accept json
 // @Produce json
@@ -102,7 +97,11 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
 	user.Address = req.Address
 
 	// Update user using the correct method from the interface
-	updatedUser, err := h.userService.UpdateUserProfile(c.Request.Context(), user.ID, user.Name, user.Phone, user.Address)
+	updatedUser, err := h.userService.UpdateUserProfile(c.Request.Context(), user.ID, user.Name, user.Phone, req.PhoneCountry, user.Address)
+	if errors.Is(err, service.ErrInvalidPhone) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		h.logger.Error("Failed to update user", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update profile"})
@@ -110,14 +109,7 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
 	}
 
 	// Return updated user info
-	c.JSON(http.StatusOK, userResponse{
-		ID:      updatedUser.ID,
-		Name:    updatedUser.Name,
-		Email:   updatedUser.Email,
-		Role:    string(updatedUser.Role),
-		Phone:   updatedUser.Phone,
-		Address: updatedUser.Address,
-	})
+	c.JSON(http.StatusOK, toUserResponse(updatedUser))
 }
 
 // ChangePassword godoc
@@ -169,7 +161,7 @@ func (h *UserHandler) ChangePassword(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Security BearerAuth
-// @Param id path int true "User ID"
+// @Param id path string true "User ID (UUID)"
 // @Success 200 {object} userResponse "User"
 // @Failure 400 {object} map[string]string "Bad request"
 // @Failure 401 {object} map[string]string "Unauthorized"
@@ -195,31 +187,85 @@ func (h *UserHandler) GetUserByID(c *gin.Context) {
 	}
 
 	// Return user info
-	c.JSON(http.StatusOK, userResponse{
-		ID:      user.ID,
-		Name:    user.Name,
-		Email:   user.Email,
-		Role:    string(user.Role),
-		Phone:   user.Phone,
-		Address: user.Address,
-	})
+	c.JSON(http.StatusOK, toUserResponse(user))
+}
+
+// UpdatePreferences godoc
+// @Summary Update display preferences
+// @Description Update the timezone and locale used to display times to the authenticated user, and the channel reminders are sent on first
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param preferences body updatePreferencesRequest true "Preferences"
+// @Success 200 {object} userResponse "Updated user profile"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Router /users/{id}/preferences [put]
+func (h *UserHandler) UpdatePreferences(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req updatePreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	user, err := h.userService.UpdatePreferences(c.Request.Context(), userID.(uint), req.Timezone, req.Locale, req.PreferredChannel)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, toUserResponse(user))
+}
+
+func toUserResponse(user *model.User) userResponse {
+	return userResponse{
+		ID:               user.PublicID,
+		Name:             user.Name,
+		Email:            user.Email,
+		Role:             string(user.Role),
+		Phone:            user.Phone,
+		PhoneCountry:     user.PhoneCountry,
+		Address:          user.Address,
+		Timezone:         user.Timezone,
+		Locale:           user.Locale,
+		PreferredChannel: user.PreferredChannel,
+	}
 }
 
 // Request and response types
 
 type userResponse struct {
-	ID      uint   `json:"id"`
-	Name    string `json:"name"`
-	Email   string `json:"email"`
-	Role    string `json:"role"`
-	Phone   string `json:"phone,omitempty"`
-	Address string `json:"address,omitempty"`
+	ID               string `json:"id"`
+	Name             string `json:"name"`
+	Email            string `json:"email"`
+	Role             string `json:"role"`
+	Phone            string `json:"phone,omitempty"`         // E.164
+	PhoneCountry     string `json:"phone_country,omitempty"` // ISO 3166-1 alpha-2
+	Address          string `json:"address,omitempty"`
+	Timezone         string `json:"timezone,omitempty"`
+	Locale           string `json:"locale,omitempty"`
+	PreferredChannel string `json:"preferred_channel,omitempty"`
 }
 
 type updateProfileRequest struct {
-	Name    string `json:"name" binding:"required"`
-	Phone   string `json:"phone"`
-	Address string `json:"address"`
+	Name         string `json:"name" binding:"required"`
+	Phone        string `json:"phone"`         // International, e.g. +27 82 123 4567, or national
+	PhoneCountry string `json:"phone_country"` // ISO 3166-1 alpha-2 region of a national phone number
+	Address      string `json:"address"`
+}
+
+type updatePreferencesRequest struct {
+	Timezone         string `json:"timezone"`          // IANA name, e.g. Africa/Johannesburg
+	Locale           string `json:"locale"`            // Language tag, e.g. en-ZA
+	PreferredChannel string `json:"preferred_channel"` // email or sms; reminders fall back to the other
 }
 
 type changePasswordRequest struct {
@@ -0,0 +1,307 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/service"
+	"go.uber.org/zap"
+)
+
+// VisitReasonHandler handles visit reason HTTP requests
+type VisitReasonHandler struct {
+	service      service.VisitReasonService
+	translations service.TranslationService
+	logger       *zap.Logger
+}
+
+// NewVisitReasonHandler creates a new visit reason handler
+func NewVisitReasonHandler(service service.VisitReasonService, translations service.TranslationService, logger *zap.Logger) *VisitReasonHandler {
+	return &VisitReasonHandler{
+		service:      service,
+		translations: translations,
+		logger:       logger,
+	}
+}
+
+// CreateVisitReason godoc
+// @Summary Create visit reason
+// @Description Add a reason patients can book for, with the specialty of the doctors who see it and the appointment length it needs
+// @Tags admin,visit-reasons
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body visitReasonRequest true "Visit reason"
+// @Success 201 {object} visitReasonResponse "Created visit reason"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Router /admin/visit-reasons [post]
+func (h *VisitReasonHandler) CreateVisitReason(c *gin.Context) {
+	var req visitReasonRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	reason, err := h.service.CreateVisitReason(c.Request.Context(), req.toModel())
+	if err != nil {
+		h.logger.Warn("Failed to create visit reason", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, toVisitReasonResponse(reason))
+}
+
+// ListVisitReasons godoc
+// @Summary List all visit reasons
+// @Description List all visit reasons, including retired ones
+// @Tags admin,visit-reasons
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} visitReasonResponse "Visit reasons"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /admin/visit-reasons [get]
+func (h *VisitReasonHandler) ListVisitReasons(c *gin.Context) {
+	reasons, err := h.service.ListVisitReasons(c.Request.Context())
+	if err != nil {
+		h.logger.Error("Failed to list visit reasons", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list visit reasons"})
+		return
+	}
+
+	c.JSON(http.StatusOK, toVisitReasonResponses(reasons))
+}
+
+// GetVisitReason godoc
+// @Summary Get visit reason
+// @Description Get a visit reason by ID
+// @Tags admin,visit-reasons
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Visit reason ID"
+// @Success 200 {object} visitReasonResponse "Visit reason"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /admin/visit-reasons/{id} [get]
+func (h *VisitReasonHandler) GetVisitReason(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid visit reason ID"})
+		return
+	}
+
+	reason, err := h.service.GetVisitReason(c.Request.Context(), uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, toVisitReasonResponse(reason))
+}
+
+// UpdateVisitReason godoc
+// @Summary Update visit reason
+// @Description Replace the settings of a visit reason; existing appointments are not changed
+// @Tags admin,visit-reasons
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Visit reason ID"
+// @Param request body visitReasonRequest true "Visit reason"
+// @Success 200 {object} visitReasonResponse "Updated visit reason"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Router /admin/visit-reasons/{id} [put]
+func (h *VisitReasonHandler) UpdateVisitReason(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid visit reason ID"})
+		return
+	}
+
+	var req visitReasonRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	reason, err := h.service.UpdateVisitReason(c.Request.Context(), uint(id), req.toModel())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, toVisitReasonResponse(reason))
+}
+
+// RetireVisitReason godoc
+// @Summary Retire visit reason
+// @Description Stop patients from booking for a reason; appointments already booked for it keep it
+// @Tags admin,visit-reasons
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Visit reason ID"
+// @Success 200 {object} map[string]string "Visit reason retired"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /admin/visit-reasons/{id} [delete]
+func (h *VisitReasonHandler) RetireVisitReason(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid visit reason ID"})
+		return
+	}
+
+	if err := h.service.RetireVisitReason(c.Request.Context(), uint(id)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "visit reason retired"})
+}
+
+// ListActiveVisitReasons godoc
+// @Summary List visit reasons
+// @Description List the reasons patients can book for, to pick from when booking
+// @Tags visit-reasons
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} visitReasonResponse "Visit reasons"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /visit-reasons [get]
+func (h *VisitReasonHandler) ListActiveVisitReasons(c *gin.Context) {
+	reasons, err := h.service.ListActiveVisitReasons(c.Request.Context())
+	if err != nil {
+		h.logger.Error("Failed to list visit reasons", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list visit reasons"})
+		return
+	}
+
+	c.JSON(http.StatusOK, toVisitReasonResponses(reasons))
+}
+
+// ListReasonDoctors godoc
+// @Summary List doctors for a visit reason
+// @Description List the doctors who see a visit reason, those of its specialty, so the patient can pick one and book with visit_reason_id
+// @Tags visit-reasons,doctors
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Visit reason ID"
+// @Param page query int false "Page number" default(1)
+// @Param pageSize query int false "Page size" default(10)
+// @Param fields query string false "Comma-separated fields to return, e.g. name,specialty; id is always included"
+// @Param sort query string false "Comma-separated sort keys, - for descending: name, specialty, experience, created_at"
+// @Success 200 {object} map[string]interface{} "Visit reason and doctors"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 404 {object} map[string]string "Not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /visit-reasons/{id}/doctors [get]
+func (h *VisitReasonHandler) ListReasonDoctors(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid visit reason ID"})
+		return
+	}
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	pageSize, err := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
+	if err != nil || pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	query := parseListQuery(c)
+	reason, doctors, total, err := h.service.ListReasonDoctors(c.Request.Context(), uint(id), page, pageSize, query)
+	if err != nil {
+		switch {
+		case isListQueryError(err):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case err.Error() == "visit reason not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			h.logger.Error("Failed to list doctors for visit reason", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get doctors"})
+		}
+		return
+	}
+
+	localizeDoctors(c, h.translations, h.logger, doctors...)
+	response := make([]doctorResponse, 0, len(doctors))
+	for _, doctor := range doctors {
+		response = append(response, toDoctorResponse(doctor))
+	}
+	var items interface{} = response
+	if query.Fields != nil {
+		sparse, err := sparseItems(response, query.Fields)
+		if err != nil {
+			h.logger.Error("Failed to select doctor fields", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get doctors"})
+			return
+		}
+		items = sparse
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"visit_reason": toVisitReasonResponse(reason),
+		"doctors":      items,
+		"total":        total,
+		"page":         page,
+		"size":         pageSize,
+	})
+}
+
+// Request and response models
+type visitReasonRequest struct {
+	Name      string `json:"name" binding:"required,max=100"`
+	Specialty string `json:"specialty" binding:"required,max=100"` // Specialty of the doctors who see it
+	Duration  int    `json:"duration" binding:"required"`          // Minutes
+}
+
+func (r visitReasonRequest) toModel() *model.VisitReason {
+	return &model.VisitReason{
+		Name:      r.Name,
+		Specialty: r.Specialty,
+		Duration:  r.Duration,
+	}
+}
+
+type visitReasonResponse struct {
+	ID        uint   `json:"id"`
+	Name      string `json:"name"`
+	Specialty string `json:"specialty"`
+	Duration  int    `json:"duration"`
+	Active    bool   `json:"active"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+// Helper function to convert model to response
+func toVisitReasonResponse(reason *model.VisitReason) visitReasonResponse {
+	return visitReasonResponse{
+		ID:        reason.ID,
+		Name:      reason.Name,
+		Specialty: reason.Specialty,
+		Duration:  reason.Duration,
+		Active:    reason.Active,
+		CreatedAt: reason.CreatedAt.Format(time.RFC3339),
+		UpdatedAt: reason.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+func toVisitReasonResponses(reasons []*model.VisitReason) []visitReasonResponse {
+	response := make([]visitReasonResponse, 0, len(reasons))
+	for _, reason := range reasons {
+		response = append(response, toVisitReasonResponse(reason))
+	}
+	return response
+}
@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/whitewalker-sa/ehass/internal/service"
+	"go.uber.org/zap"
+)
+
+// APIKeyAuth creates a middleware that authenticates partner integrations by the API key in the
+// X-API-Key header, as the user the key was issued to, and hands requests without one to bearer.
+// Each key is held to its requests per minute, counted per instance like RateLimit, and to its
+// daily quota; requests over either are answered with 429. Usage counters failing to update do
+// not refuse requests.
+func APIKeyAuth(keys service.APIKeyService, bearer gin.HandlerFunc, logger *zap.Logger) gin.HandlerFunc {
+	requests := newRateWindow()
+
+	return func(c *gin.Context) {
+		secret := c.GetHeader("X-API-Key")
+		if secret == "" {
+			bearer(c)
+			return
+		}
+
+		ctx := c.Request.Context()
+		key, err := keys.Authenticate(ctx, secret)
+		if errors.Is(err, service.ErrAPIKeySuspended) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			logger.Warn("API key authentication failed", zap.Error(err))
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid API key"})
+			return
+		}
+
+		now := time.Now()
+		perMinute, quota := keys.Limits(key)
+		if perMinute > 0 {
+			c.Header("X-RateLimit-Limit", strconv.Itoa(perMinute))
+		}
+		if ok, retry := requests.allow(key.PublicID, perMinute, now); !ok {
+			if err := keys.RecordRejected(ctx, key, now); err != nil {
+				logger.Error("Failed to count refused API request", zap.Uint("apiKeyID", key.ID), zap.Error(err))
+			}
+			tooManyRequests(c, retry)
+			return
+		}
+
+		remaining, err := keys.Meter(ctx, key, now)
+		switch {
+		case errors.Is(err, service.ErrQuotaExceeded):
+			midnight := time.Date(now.UTC().Year(), now.UTC().Month(), now.UTC().Day()+1, 0, 0, 0, 0, time.UTC)
+			c.Header("X-Quota-Limit", strconv.Itoa(quota))
+			c.Header("X-Quota-Remaining", "0")
+			c.Header("Retry-After", strconv.Itoa(int(midnight.Sub(now).Seconds())+1))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+			return
+		case err != nil:
+			logger.Error("Failed to meter API request", zap.Uint("apiKeyID", key.ID), zap.Error(err))
+		case quota > 0:
+			c.Header("X-Quota-Limit", strconv.Itoa(quota))
+			c.Header("X-Quota-Remaining", strconv.FormatInt(remaining, 10))
+		}
+
+		c.Set("user", &key.User)
+		c.Set("userID", key.User.ID)
+		c.Set("email", key.User.Email)
+		c.Set("role", key.User.Role)
+		c.Set("userRole", key.User.Role) // Read by RoleMiddleware
+		c.Set("apiKeyID", key.ID)
+		c.Next()
+	}
+}
@@ -1,9 +1,11 @@
 package middleware
 
 import (
+	"crypto/subtle"
 	"errors"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v4"
@@ -58,6 +60,12 @@ func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
 			return
 		}
 
+		// Reject tokens minted for other environments or services
+		if !claims.VerifyIssuer(cfg.Auth.Issuer, true) || !claims.VerifyAudience(cfg.Auth.Audience, true) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token issuer or audience"})
+			return
+		}
+
 		// Set user information in context
 		userID, ok := claims["id"].(float64)
 		if !ok {
@@ -123,6 +131,7 @@ func NewAuthMiddleware(authService service.AuthService, logger *zap.Logger) gin.
 		c.Set("userID", user.ID)
 		c.Set("email", user.Email)
 		c.Set("role", user.Role)
+		c.Set("userRole", user.Role) // Read by RoleMiddleware
 
 		logger.Debug("Authentication successful",
 			zap.Uint("userID", user.ID),
@@ -133,6 +142,72 @@ func NewAuthMiddleware(authService service.AuthService, logger *zap.Logger) gin.
 	}
 }
 
+// RequireRecentAuth creates a middleware that requires the user to have authenticated
+// within maxAge, used to guard sensitive operations behind step-up authentication
+func RequireRecentAuth(authService service.AuthService, maxAge time.Duration, logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// The auth middleware has already validated the header format
+		tokenString := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+
+		authTime, err := authService.AuthTime(c.Request.Context(), tokenString)
+		if err != nil || time.Since(authTime) > maxAge {
+			logger.Info("Step-up authentication required", zap.Uint("userID", c.GetUint("userID")))
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error":           "recent authentication required",
+				"step_up_max_age": maxAge.String(),
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// IntrospectionClientAuth creates a middleware that authenticates services calling the
+// token introspection endpoint with HTTP Basic client credentials
+func IntrospectionClientAuth(clients map[string]string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		clientID, clientSecret, ok := c.Request.BasicAuth()
+		// Client IDs are matched case-insensitively since config keys are lowercased
+		expected, known := clients[strings.ToLower(clientID)]
+		if !ok || !known || expected == "" ||
+			subtle.ConstantTimeCompare([]byte(clientSecret), []byte(expected)) != 1 {
+			c.Header("WWW-Authenticate", `Basic realm="introspection"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid client credentials"})
+			return
+		}
+
+		c.Set("clientID", clientID)
+		c.Next()
+	}
+}
+
+// WebhookSecretAuth authenticates provider webhooks by a shared secret in the X-Webhook-Secret
+// header. Webhooks are rejected when no secret is configured.
+func WebhookSecretAuth(secret string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		provided := c.GetHeader("X-Webhook-Secret")
+		if secret == "" || subtle.ConstantTimeCompare([]byte(provided), []byte(secret)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid webhook secret"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// BearerTokenAuth authenticates machine clients such as a Prometheus scraper by a static token
+// in the Authorization header. Requests are rejected when no token is configured.
+func BearerTokenAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		provided, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if token == "" || !ok || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+			return
+		}
+		c.Next()
+	}
+}
+
 // RoleMiddleware creates a middleware for role-based access control
 func RoleMiddleware(roles ...model.Role) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -165,3 +240,49 @@ func RoleMiddleware(roles ...model.Role) gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// PermissionChecker builds middlewares that require the authenticated user to hold permissions
+type PermissionChecker func(permissions ...model.Permission) gin.HandlerFunc
+
+// NewPermissionChecker creates a PermissionChecker that resolves the user's effective
+// permissions from their built-in role and assigned custom roles on every request,
+// so role changes apply without a new login
+func NewPermissionChecker(roleService service.RoleService, logger *zap.Logger) PermissionChecker {
+	return func(required ...model.Permission) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			userID, idExists := c.Get("userID")
+			userRole, roleExists := c.Get("userRole")
+			if !idExists || !roleExists {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+				return
+			}
+
+			role, ok := userRole.(model.Role)
+			if !ok {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "invalid role type"})
+				return
+			}
+
+			permissions, err := roleService.EffectivePermissions(c.Request.Context(), userID.(uint), role)
+			if err != nil {
+				logger.Error("Failed to resolve permissions", zap.Error(err))
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to resolve permissions"})
+				return
+			}
+
+			granted := make(map[model.Permission]bool, len(permissions))
+			for _, p := range permissions {
+				granted[p] = true
+			}
+			for _, p := range required {
+				if !granted[p] {
+					c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "access denied", "missing_permission": p})
+					return
+				}
+			}
+
+			c.Set("permissions", permissions)
+			c.Next()
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/whitewalker-sa/ehass/pkg/utils"
+)
+
+// ClientInfo creates a middleware that stores the client IP and user agent in the
+// request context so services can record them in audit logs
+func ClientInfo() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := utils.WithClientInfo(c.Request.Context(), utils.ClientInfo{
+			IP:        c.ClientIP(),
+			UserAgent: c.Request.UserAgent(),
+		})
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
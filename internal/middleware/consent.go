@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/whitewalker-sa/ehass/internal/service"
+	"go.uber.org/zap"
+)
+
+// ConsentMiddleware blocks API usage until the user has accepted all required policy versions
+func ConsentMiddleware(consentService service.ConsentService, logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			return
+		}
+
+		missing, err := consentService.GetMissingConsents(c.Request.Context(), userID.(uint))
+		if err != nil {
+			logger.Error("Failed to check user consents", zap.Error(err))
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to check consents"})
+			return
+		}
+
+		if len(missing) > 0 {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":            "consent required",
+				"missing_policies": missing,
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
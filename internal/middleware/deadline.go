@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/whitewalker-sa/ehass/internal/service"
+	"go.uber.org/zap"
+)
+
+// Deadline creates a middleware that gives each request's context its route's operation timeout.
+// Handlers pass the request context down to services, repositories and external clients, so
+// once it expires the database query or call in flight is abandoned. Requests that time out are
+// logged with the route and, if the handler has not responded yet, answered with 504.
+func Deadline(timeouts *service.OperationTimeouts, logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.FullPath() == "" {
+			c.Next()
+			return
+		}
+		route := c.Request.Method + " " + c.FullPath()
+		ctx, cancel := timeouts.Bound(c.Request.Context(), route)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return
+		}
+		logger.Warn("Request timed out",
+			zap.String("route", route),
+			zap.Int("status", c.Writer.Status()),
+			zap.Duration("timeout", timeouts.Timeout(route)),
+		)
+		if !c.Writer.Written() {
+			c.AbortWithStatusJSON(http.StatusGatewayTimeout, gin.H{"error": "request timed out"})
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/whitewalker-sa/ehass/internal/service"
+	"go.uber.org/zap"
+)
+
+// LatencyBudget creates a middleware that times each request against its route's latency budget.
+// Requests over budget are counted by the monitor for the metrics endpoint and logged with the
+// route, so a slow endpoint shows up as one series rather than per path. Requests that matched
+// no route are not tracked.
+func LatencyBudget(monitor *service.LatencyMonitor, logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		if c.FullPath() == "" {
+			return
+		}
+		route := c.Request.Method + " " + c.FullPath()
+		elapsed := time.Since(start)
+		if budget, exceeded := monitor.Observe(route, elapsed); exceeded {
+			logger.Warn("Request exceeded latency budget",
+				zap.String("route", route),
+				zap.Int("status", c.Writer.Status()),
+				zap.Duration("latency", elapsed),
+				zap.Duration("budget", budget),
+			)
+		}
+	}
+}
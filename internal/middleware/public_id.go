@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/service"
+	"go.uber.org/zap"
+)
+
+// PublicIDResolver builds middlewares that translate public IDs in path parameters
+type PublicIDResolver func(params map[string]model.PublicResource) gin.HandlerFunc
+
+// NewPublicIDResolver creates a PublicIDResolver. The returned middlewares replace the UUID in
+// each named path parameter with the resource's internal ID, so handlers keep parsing numeric
+// IDs while clients only ever see public ones. Parameters not present on a route are skipped.
+func NewPublicIDResolver(publicIDService service.PublicIDService, logger *zap.Logger) PublicIDResolver {
+	return func(params map[string]model.PublicResource) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			for i, param := range c.Params {
+				resource, ok := params[param.Key]
+				if !ok {
+					continue
+				}
+
+				id, err := publicIDService.ResolveID(c.Request.Context(), resource, param.Value)
+				if err != nil {
+					if errors.Is(err, service.ErrInvalidPublicID) {
+						c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+						return
+					}
+					logger.Debug("Failed to resolve public ID", zap.String("param", param.Key), zap.Error(err))
+					c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": resource.Name() + " not found"})
+					return
+				}
+
+				c.Params[i].Value = strconv.FormatUint(uint64(id), 10)
+			}
+			c.Next()
+		}
+	}
+}
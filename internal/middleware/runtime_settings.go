@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/whitewalker-sa/ehass/internal/service"
+)
+
+// maintenanceExempt are the path prefixes served during maintenance, so admins can still sign in
+// and turn maintenance mode off again
+var maintenanceExempt = []string{"/api/v1/auth/", "/api/v1/admin/", "/api/v1/features", "/metrics"}
+
+// Maintenance creates a middleware that answers requests with 503 and the maintenance message
+// while the maintenance_mode setting is on. Authentication, admin and feature flag routes stay
+// available.
+func Maintenance(settings service.SettingsService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		current := settings.Current()
+		if !current.MaintenanceMode {
+			c.Next()
+			return
+		}
+		for _, prefix := range maintenanceExempt {
+			if strings.HasPrefix(c.Request.URL.Path, prefix) {
+				c.Next()
+				return
+			}
+		}
+		c.Header("Retry-After", "300")
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+			"error":       current.MaintenanceMessage,
+			"maintenance": true,
+		})
+	}
+}
+
+// RateLimit creates a middleware that limits each client IP to the requests_per_minute setting,
+// and login attempts to logins_per_minute, answering requests over the limit with 429. Counts
+// are kept per instance in fixed one-minute windows.
+func RateLimit(settings service.SettingsService) gin.HandlerFunc {
+	requests := newRateWindow()
+	logins := newRateWindow()
+
+	return func(c *gin.Context) {
+		current := settings.Current()
+		now := time.Now()
+		ip := c.ClientIP()
+
+		if c.Request.Method == http.MethodPost && c.FullPath() == "/api/v1/auth/login" {
+			if ok, retry := logins.allow(ip, current.LoginsPerMinute, now); !ok {
+				tooManyRequests(c, retry)
+				return
+			}
+		}
+		if ok, retry := requests.allow(ip, current.RequestsPerMinute, now); !ok {
+			tooManyRequests(c, retry)
+			return
+		}
+		c.Next()
+	}
+}
+
+func tooManyRequests(c *gin.Context, retry time.Duration) {
+	c.Header("Retry-After", strconv.Itoa(int(retry.Seconds())+1))
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "too many requests, please try again later"})
+}
+
+// rateWindow counts requests by client in the current one-minute window
+type rateWindow struct {
+	mu     sync.Mutex
+	start  time.Time
+	counts map[string]int
+}
+
+func newRateWindow() *rateWindow {
+	return &rateWindow{counts: map[string]int{}}
+}
+
+// allow counts a request from key and reports whether it is within limit, and if not, how long
+// until the window resets. A limit of 0 or less allows every request.
+func (w *rateWindow) allow(key string, limit int, now time.Time) (bool, time.Duration) {
+	if limit <= 0 {
+		return true, 0
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if now.Sub(w.start) >= time.Minute {
+		w.start = now.Truncate(time.Minute)
+		w.counts = map[string]int{}
+	}
+	w.counts[key]++
+	if w.counts[key] > limit {
+		return false, w.start.Add(time.Minute).Sub(now)
+	}
+	return true, 0
+}
@@ -0,0 +1,20 @@
+package migrations
+
+import (
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func init() {
+	registerMigration("20261015090000_encrypt_phi_columns", up20261015090000, down20261015090000)
+}
+
+// up20261015090000 encrypts PHI values that were stored before field-level encryption
+func up20261015090000(tx *gorm.DB) error {
+	return RotateEncryptionKeys(tx, zap.NewNop())
+}
+
+// down20261015090000 restores PHI values to plaintext
+func down20261015090000(tx *gorm.DB) error {
+	return decryptEncryptedColumns(tx, zap.NewNop())
+}
@@ -0,0 +1,93 @@
+package migrations
+
+import (
+	"encoding/hex"
+
+	"github.com/whitewalker-sa/ehass/pkg/encryption"
+	"github.com/whitewalker-sa/ehass/pkg/utils"
+	"gorm.io/gorm"
+)
+
+func init() {
+	registerMigration("20261015100000_hash_stored_tokens", up20261015100000, down20261015100000)
+}
+
+// up20261015100000 replaces plaintext refresh, verification and password reset tokens with their SHA-256 hashes
+func up20261015100000(tx *gorm.DB) error {
+	var users []columnValue
+	if err := tx.Table("users").
+		Select("id", "refresh_token AS value").
+		Where("refresh_token IS NOT NULL AND refresh_token <> ''").
+		Find(&users).Error; err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		if isTokenHash(user.Value) {
+			continue
+		}
+
+		// Refresh tokens were briefly stored with field-level encryption
+		token := user.Value
+		if encryption.IsEncrypted(token) {
+			keyring := encryption.Default()
+			if keyring == nil {
+				// Without the key the token cannot be hashed; force the user to log in again
+				token = ""
+			} else {
+				plaintext, err := keyring.Decrypt(token)
+				if err != nil {
+					return err
+				}
+				token = plaintext
+			}
+		}
+
+		hash := ""
+		if token != "" {
+			hash = utils.HashToken(token)
+		}
+		if err := tx.Table("users").Where("id = ?", user.ID).
+			UpdateColumn("refresh_token", hash).Error; err != nil {
+			return err
+		}
+	}
+
+	var tokens []columnValue
+	if err := tx.Table("verification_tokens").
+		Select("id", "token AS value").
+		Find(&tokens).Error; err != nil {
+		return err
+	}
+
+	for _, token := range tokens {
+		if isTokenHash(token.Value) {
+			continue
+		}
+		if err := tx.Table("verification_tokens").Where("id = ?", token.ID).
+			UpdateColumn("token", utils.HashToken(token.Value)).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// down20261015100000 cannot recover the original tokens, so it revokes them instead:
+// users must log in again and request new verification or reset emails
+func down20261015100000(tx *gorm.DB) error {
+	if err := tx.Table("users").Where("refresh_token <> ''").
+		UpdateColumn("refresh_token", "").Error; err != nil {
+		return err
+	}
+	return tx.Exec("DELETE FROM verification_tokens").Error
+}
+
+// isTokenHash reports whether value is already a hex-encoded SHA-256 hash
+func isTokenHash(value string) bool {
+	if len(value) != 64 {
+		return false
+	}
+	_, err := hex.DecodeString(value)
+	return err == nil
+}
@@ -0,0 +1,32 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+func init() {
+	registerMigration("20261016090000_patient_search_indexes", up20261016090000, down20261016090000)
+}
+
+// up20261016090000 adds the indexes behind patient search: trigram indexes so substring matches
+// on names, emails and phone digits do not scan the users table, and a date of birth index
+func up20261016090000(tx *gorm.DB) error {
+	statements := []string{
+		"CREATE EXTENSION IF NOT EXISTS pg_trgm",
+		"CREATE INDEX IF NOT EXISTS idx_users_name_trgm ON users USING gin (name gin_trgm_ops)",
+		"CREATE INDEX IF NOT EXISTS idx_users_email_trgm ON users USING gin (email gin_trgm_ops)",
+		"CREATE INDEX IF NOT EXISTS idx_users_phone_digits_trgm ON users USING gin ((regexp_replace(phone, '[^0-9]', '', 'g')) gin_trgm_ops)",
+		"CREATE INDEX IF NOT EXISTS idx_patients_date_of_birth ON patients (date_of_birth)",
+	}
+	for _, statement := range statements {
+		if err := tx.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// down20261016090000 drops the patient search indexes; the extension is left in place
+func down20261016090000(tx *gorm.DB) error {
+	return tx.Exec("DROP INDEX IF EXISTS idx_users_name_trgm, idx_users_email_trgm, idx_users_phone_digits_trgm, idx_patients_date_of_birth").Error
+}
@@ -0,0 +1,29 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+func init() {
+	registerMigration("20261016100000_appointment_query_indexes", up20261016100000, down20261016100000)
+}
+
+// up20261016100000 adds the indexes behind appointment list filters: schedules of a few doctors
+// and lists by status are read in scheduled order from a composite index each
+func up20261016100000(tx *gorm.DB) error {
+	statements := []string{
+		"CREATE INDEX IF NOT EXISTS idx_appointments_doctor_start ON appointments (doctor_id, scheduled_start)",
+		"CREATE INDEX IF NOT EXISTS idx_appointments_status_start ON appointments (status, scheduled_start)",
+	}
+	for _, statement := range statements {
+		if err := tx.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// down20261016100000 drops the appointment list indexes
+func down20261016100000(tx *gorm.DB) error {
+	return tx.Exec("DROP INDEX IF EXISTS idx_appointments_doctor_start, idx_appointments_status_start").Error
+}
@@ -0,0 +1,42 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+func init() {
+	registerMigration("20261016110000_encounter_search_indexes", up20261016110000, down20261016110000)
+}
+
+// up20261016110000 adds the full-text index behind encounter search. Appointment reasons and
+// notes are plaintext, so their vector is a generated column with reasons weighted above notes.
+// Diagnoses are encrypted and searched through a blind index instead; see
+// 20261016180000_diagnosis_blind_index.
+func up20261016110000(tx *gorm.DB) error {
+	statements := []string{
+		`ALTER TABLE appointments ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+			setweight(to_tsvector('english', coalesce(reason, '')), 'A') ||
+			setweight(to_tsvector('english', coalesce(notes, '')), 'B')) STORED`,
+		"CREATE INDEX IF NOT EXISTS idx_appointments_search_vector ON appointments USING gin (search_vector)",
+	}
+	for _, statement := range statements {
+		if err := tx.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// down20261016110000 drops the encounter search index and the generated appointment vector
+func down20261016110000(tx *gorm.DB) error {
+	statements := []string{
+		"DROP INDEX IF EXISTS idx_appointments_search_vector",
+		"ALTER TABLE appointments DROP COLUMN IF EXISTS search_vector",
+	}
+	for _, statement := range statements {
+		if err := tx.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	registerMigration("20261016120000_seed_medication_catalog", up20261016120000, down20261016120000)
+}
+
+// starterMedications are common generic products the catalog is seeded with so doctors can
+// prescribe on a fresh deployment. Clinics extend or retire them through the admin import.
+var starterMedications = []struct {
+	name, form, strength, route string
+}{
+	{"amoxicillin", "capsule", "500 mg", "oral"},
+	{"amoxicillin", "oral suspension", "250 mg/5 ml", "oral"},
+	{"amoxicillin and clavulanic acid", "tablet", "875 mg/125 mg", "oral"},
+	{"azithromycin", "tablet", "250 mg", "oral"},
+	{"cefalexin", "capsule", "500 mg", "oral"},
+	{"ibuprofen", "tablet", "400 mg", "oral"},
+	{"paracetamol", "tablet", "500 mg", "oral"},
+	{"paracetamol", "oral suspension", "120 mg/5 ml", "oral"},
+	{"naproxen", "tablet", "500 mg", "oral"},
+	{"omeprazole", "capsule", "20 mg", "oral"},
+	{"metformin", "tablet", "500 mg", "oral"},
+	{"amlodipine", "tablet", "5 mg", "oral"},
+	{"lisinopril", "tablet", "10 mg", "oral"},
+	{"atorvastatin", "tablet", "20 mg", "oral"},
+	{"levothyroxine", "tablet", "50 mcg", "oral"},
+	{"sertraline", "tablet", "50 mg", "oral"},
+	{"cetirizine", "tablet", "10 mg", "oral"},
+	{"prednisolone", "tablet", "5 mg", "oral"},
+	{"salbutamol", "inhaler", "100 mcg/dose", "inhaled"},
+	{"hydrocortisone", "cream", "1%", "topical"},
+}
+
+// up20261016120000 seeds the medication catalog with starterMedications, leaving products that
+// are already in it untouched
+func up20261016120000(tx *gorm.DB) error {
+	now := time.Now()
+	for _, m := range starterMedications {
+		if err := tx.Exec(
+			`INSERT INTO medications (name, form, strength, route, active, created_at, updated_at)
+			VALUES (?, ?, ?, ?, true, ?, ?)
+			ON CONFLICT (name, form, strength) DO NOTHING`,
+			m.name, m.form, m.strength, m.route, now, now,
+		).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// down20261016120000 removes the seeded products that no prescription refers to
+func down20261016120000(tx *gorm.DB) error {
+	for _, m := range starterMedications {
+		if err := tx.Exec(
+			`DELETE FROM medications
+			WHERE name = ? AND form = ? AND strength = ?
+			AND NOT EXISTS (SELECT 1 FROM prescriptions WHERE prescriptions.medication_id = medications.id)`,
+			m.name, m.form, m.strength,
+		).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+func init() {
+	registerMigration("20261016130000_structured_allergy_medication_lists", up20261016130000, down20261016130000)
+}
+
+// up20261016130000 carries the free-text allergies and current medication of patients over to
+// the allergy and medication list tables, one entry per comma, semicolon or line separated item,
+// and drops the free-text columns. Items saying there is nothing to list are skipped. Carried
+// over entries have no severity, dates or recording user. Databases created without the
+// columns have nothing to carry over.
+func up20261016130000(tx *gorm.DB) error {
+	if !tx.Migrator().HasColumn("patients", "allergies") {
+		return nil
+	}
+	statements := []string{
+		`INSERT INTO allergies (public_id, patient_id, substance, category, created_at, updated_at)
+		SELECT gen_random_uuid(), patients.id, left(trim(item), 150), 'other', now(), now()
+		FROM patients, regexp_split_to_table(patients.allergies, '[,;\n]') AS item
+		WHERE patients.allergies IS NOT NULL
+		AND trim(item) <> ''
+		AND lower(trim(item)) NOT IN ('none', 'nil', 'n/a', 'na', 'nka', 'nkda', 'no known allergies', 'no known drug allergies')`,
+		`INSERT INTO patient_medications (public_id, patient_id, name, created_at, updated_at)
+		SELECT gen_random_uuid(), patients.id, left(trim(item), 150), now(), now()
+		FROM patients, regexp_split_to_table(patients.current_medication, '[,;\n]') AS item
+		WHERE patients.current_medication IS NOT NULL
+		AND trim(item) <> ''
+		AND lower(trim(item)) NOT IN ('none', 'nil', 'n/a', 'na')`,
+		"ALTER TABLE patients DROP COLUMN IF EXISTS allergies, DROP COLUMN IF EXISTS current_medication",
+	}
+	for _, statement := range statements {
+		if err := tx.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// down20261016130000 restores the free-text columns, filled with the patients' active allergies
+// and medications. The list tables belong to their models and are left in place.
+func down20261016130000(tx *gorm.DB) error {
+	statements := []string{
+		"ALTER TABLE patients ADD COLUMN IF NOT EXISTS allergies text, ADD COLUMN IF NOT EXISTS current_medication text",
+		`UPDATE patients SET allergies = listed.items
+		FROM (
+			SELECT patient_id, string_agg(substance, ', ' ORDER BY substance) AS items
+			FROM allergies
+			WHERE resolved_date IS NULL OR resolved_date > CURRENT_DATE
+			GROUP BY patient_id
+		) AS listed
+		WHERE listed.patient_id = patients.id`,
+		`UPDATE patients SET current_medication = listed.items
+		FROM (
+			SELECT patient_id, string_agg(name, ', ' ORDER BY name) AS items
+			FROM patient_medications
+			WHERE stop_date IS NULL OR stop_date > CURRENT_DATE
+			GROUP BY patient_id
+		) AS listed
+		WHERE listed.patient_id = patients.id`,
+	}
+	for _, statement := range statements {
+		if err := tx.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
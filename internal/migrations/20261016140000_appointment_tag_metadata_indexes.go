@@ -0,0 +1,38 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+func init() {
+	registerMigration("20261016140000_appointment_tag_metadata_indexes", up20261016140000, down20261016140000)
+}
+
+// up20261016140000 indexes appointment tags and metadata for the containment filters of the
+// appointment list
+func up20261016140000(tx *gorm.DB) error {
+	statements := []string{
+		"CREATE INDEX IF NOT EXISTS idx_appointments_tags ON appointments USING gin (tags jsonb_path_ops)",
+		"CREATE INDEX IF NOT EXISTS idx_appointments_metadata ON appointments USING gin (metadata jsonb_path_ops)",
+	}
+	for _, statement := range statements {
+		if err := tx.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// down20261016140000 drops the tag and metadata indexes
+func down20261016140000(tx *gorm.DB) error {
+	statements := []string{
+		"DROP INDEX IF EXISTS idx_appointments_metadata",
+		"DROP INDEX IF EXISTS idx_appointments_tags",
+	}
+	for _, statement := range statements {
+		if err := tx.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+func init() {
+	registerMigration("20261016150000_canonical_emails", up20261016150000, down20261016150000)
+}
+
+// up20261016150000 fills in the canonical email of existing users, as model.CanonicalEmail does
+// for new ones, and indexes lowercased emails for the case-insensitive email lookups. Existing
+// emails keep their case.
+func up20261016150000(tx *gorm.DB) error {
+	statements := []string{
+		`UPDATE users SET canonical_email = CASE
+			WHEN split_part(lower(trim(email)), '@', 2) IN ('gmail.com', 'googlemail.com')
+			THEN replace(split_part(split_part(lower(trim(email)), '@', 1), '+', 1), '.', '') || '@gmail.com'
+			ELSE lower(trim(email))
+		END
+		WHERE canonical_email IS NULL OR canonical_email = ''`,
+		"CREATE INDEX IF NOT EXISTS idx_users_email_lower ON users (lower(email))",
+	}
+	for _, statement := range statements {
+		if err := tx.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// down20261016150000 drops the lowercased email index. Canonical emails stay filled in.
+func down20261016150000(tx *gorm.DB) error {
+	return tx.Exec("DROP INDEX IF EXISTS idx_users_email_lower").Error
+}
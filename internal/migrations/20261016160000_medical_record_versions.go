@@ -0,0 +1,28 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+func init() {
+	registerMigration("20261016160000_medical_record_versions", up20261016160000, down20261016160000)
+}
+
+// up20261016160000 records the current content of every medical record written before versions
+// were kept as its first version, by the record's doctor at its last update. The diagnosis is
+// copied encrypted as it is.
+func up20261016160000(tx *gorm.DB) error {
+	return tx.Exec(`INSERT INTO medical_record_versions (record_id, version, diagnosis, notes, doctor_id, created_at)
+		SELECT medical_records.id, medical_records.version, medical_records.diagnosis, medical_records.notes,
+			medical_records.doctor_id, medical_records.updated_at
+		FROM medical_records
+		WHERE NOT EXISTS (
+			SELECT 1 FROM medical_record_versions WHERE medical_record_versions.record_id = medical_records.id
+		)`).Error
+}
+
+// down20261016160000 leaves the versions in place. The table belongs to its model, and carried
+// over versions cannot be told from those of records written since.
+func down20261016160000(tx *gorm.DB) error {
+	return nil
+}
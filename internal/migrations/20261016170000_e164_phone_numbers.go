@@ -0,0 +1,63 @@
+package migrations
+
+import (
+	"github.com/whitewalker-sa/ehass/pkg/phone"
+	"gorm.io/gorm"
+)
+
+func init() {
+	registerMigration("20261016170000_e164_phone_numbers", up20261016170000, down20261016170000)
+}
+
+// up20261016170000 rewrites the phone numbers of users in E.164 with their region, and moves
+// emergency contacts that are just a phone number to the emergency phone. Only numbers written
+// with their country calling code are rewritten; the region of the others is not known here, so
+// they are kept as written until they are next edited.
+func up20261016170000(tx *gorm.DB) error {
+	var users []struct {
+		ID    uint
+		Phone string
+	}
+	if err := tx.Table("users").Select("id, phone").Where("phone <> '' AND (phone_country IS NULL OR phone_country = '')").Scan(&users).Error; err != nil {
+		return err
+	}
+	for _, user := range users {
+		number, err := phone.Parse(user.Phone, "")
+		if err != nil {
+			continue
+		}
+		if err := tx.Table("users").Where("id = ?", user.ID).
+			Updates(map[string]interface{}{"phone": number.E164, "phone_country": number.Region}).Error; err != nil {
+			return err
+		}
+	}
+
+	var patients []struct {
+		ID               uint
+		EmergencyContact string
+	}
+	if err := tx.Table("patients").Select("id, emergency_contact").Where("emergency_contact <> '' AND (emergency_phone IS NULL OR emergency_phone = '')").Scan(&patients).Error; err != nil {
+		return err
+	}
+	for _, patient := range patients {
+		number, err := phone.Parse(patient.EmergencyContact, "")
+		if err != nil {
+			continue
+		}
+		if err := tx.Table("patients").Where("id = ?", patient.ID).Updates(map[string]interface{}{
+			"emergency_contact":       "",
+			"emergency_phone":         number.E164,
+			"emergency_phone_country": number.Region,
+		}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// down20261016170000 moves emergency phones without a contact name back to the emergency
+// contact. Phone numbers stay in E.164, which they were already valid as.
+func down20261016170000(tx *gorm.DB) error {
+	return tx.Exec(`UPDATE patients SET emergency_contact = emergency_phone
+		WHERE (emergency_contact IS NULL OR emergency_contact = '') AND emergency_phone <> ''`).Error
+}
@@ -0,0 +1,83 @@
+package migrations
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/whitewalker-sa/ehass/pkg/encryption"
+	"gorm.io/gorm"
+)
+
+func init() {
+	registerMigration("20261016180000_diagnosis_blind_index", up20261016180000, down20261016180000)
+}
+
+// up20261016180000 replaces the full-text vector of diagnoses, which held their words in the
+// clear next to the encrypted column, with blind index tokens, and builds the tokens of every
+// diagnosis from its decrypted value.
+func up20261016180000(tx *gorm.DB) error {
+	statements := []string{
+		"DROP INDEX IF EXISTS idx_medical_records_diagnosis_search",
+		"ALTER TABLE medical_records DROP COLUMN IF EXISTS diagnosis_search",
+		"ALTER TABLE medical_records ADD COLUMN IF NOT EXISTS diagnosis_tokens text[]",
+	}
+	for _, statement := range statements {
+		if err := tx.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+
+	if err := backfillDiagnosisTokens(tx); err != nil {
+		return err
+	}
+	return tx.Exec("CREATE INDEX IF NOT EXISTS idx_medical_records_diagnosis_tokens ON medical_records USING gin (diagnosis_tokens)").Error
+}
+
+// backfillDiagnosisTokens writes the blind index tokens of every diagnosis recorded before them
+func backfillDiagnosisTokens(tx *gorm.DB) error {
+	keyring := encryption.Default()
+	if keyring == nil {
+		return errors.New("no encryption keyring registered")
+	}
+	index := encryption.DefaultIndex()
+	if index == nil {
+		return errors.New("no blind index registered")
+	}
+
+	var rows []columnValue
+	return tx.Table("medical_records").
+		Select("id", "diagnosis AS value").
+		Where("diagnosis IS NOT NULL AND diagnosis <> '' AND diagnosis_tokens IS NULL").
+		FindInBatches(&rows, 100, func(batch *gorm.DB, _ int) error {
+			for _, row := range rows {
+				diagnosis := row.Value
+				if encryption.IsEncrypted(diagnosis) {
+					var err error
+					if diagnosis, err = keyring.Decrypt(diagnosis); err != nil {
+						return fmt.Errorf("medical_records.diagnosis id=%d: %w", row.ID, err)
+					}
+				}
+				if err := tx.Exec("UPDATE medical_records SET diagnosis_tokens = CAST(? AS text[]) WHERE id = ?",
+					"{"+strings.Join(index.Tokens(diagnosis), ",")+"}", row.ID).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+}
+
+// down20261016180000 drops the blind index of diagnoses. The plaintext vector is not restored,
+// so diagnoses are not searchable after rolling back.
+func down20261016180000(tx *gorm.DB) error {
+	statements := []string{
+		"DROP INDEX IF EXISTS idx_medical_records_diagnosis_tokens",
+		"ALTER TABLE medical_records DROP COLUMN IF EXISTS diagnosis_tokens",
+	}
+	for _, statement := range statements {
+		if err := tx.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package migrations
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/whitewalker-sa/ehass/pkg/encryption"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// encryptedColumn identifies a column stored with the encrypted serializer
+type encryptedColumn struct {
+	table  string
+	column string
+}
+
+// encryptedColumns lists every column tagged `serializer:encrypted` in the models
+var encryptedColumns = []encryptedColumn{
+	{table: "users", column: "secret2_fa"},
+	{table: "patients", column: "medical_history"},
+	{table: "medical_records", column: "diagnosis"},
+	{table: "medical_records", column: "prescription"},
+	{table: "medical_record_versions", column: "diagnosis"},
+	{table: "calendar_connections", column: "access_token"},
+	{table: "calendar_connections", column: "refresh_token"},
+	{table: "prescriptions", column: "instructions"},
+	{table: "vitals", column: "note"},
+	{table: "lab_orders", column: "notes"},
+	{table: "messages", column: "body"},
+}
+
+// columnValue is a single encrypted column value read without the serializer
+type columnValue struct {
+	ID    uint
+	Value string
+}
+
+// RotateEncryptionKeys re-encrypts every PHI value that is plaintext or uses a retired key
+func RotateEncryptionKeys(db *gorm.DB, logger *zap.Logger) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		return transformEncryptedColumns(tx, logger, func(keyring *encryption.Keyring, value string) (string, bool, error) {
+			if !keyring.NeedsRotation(value) {
+				return "", false, nil
+			}
+
+			plaintext, err := keyring.Decrypt(value)
+			if err != nil {
+				return "", false, err
+			}
+
+			encrypted, err := keyring.Encrypt(plaintext)
+			return encrypted, true, err
+		})
+	})
+}
+
+// decryptEncryptedColumns restores every PHI value to plaintext
+func decryptEncryptedColumns(tx *gorm.DB, logger *zap.Logger) error {
+	return transformEncryptedColumns(tx, logger, func(keyring *encryption.Keyring, value string) (string, bool, error) {
+		if !encryption.IsEncrypted(value) {
+			return "", false, nil
+		}
+
+		plaintext, err := keyring.Decrypt(value)
+		return plaintext, true, err
+	})
+}
+
+// transformEncryptedColumns rewrites every non-empty encrypted column value using fn
+func transformEncryptedColumns(
+	tx *gorm.DB,
+	logger *zap.Logger,
+	fn func(keyring *encryption.Keyring, value string) (string, bool, error),
+) error {
+	keyring := encryption.Default()
+	if keyring == nil {
+		return errors.New("no encryption keyring registered")
+	}
+
+	for _, col := range encryptedColumns {
+		var rows []columnValue
+		updated := 0
+
+		err := tx.Table(col.table).
+			Select("id", col.column+" AS value").
+			Where(col.column+" IS NOT NULL AND "+col.column+" <> ''").
+			FindInBatches(&rows, 100, func(batch *gorm.DB, _ int) error {
+				for _, row := range rows {
+					newValue, changed, err := fn(keyring, row.Value)
+					if err != nil {
+						return fmt.Errorf("%s.%s id=%d: %w", col.table, col.column, row.ID, err)
+					}
+					if !changed {
+						continue
+					}
+
+					if err := tx.Table(col.table).Where("id = ?", row.ID).
+						UpdateColumn(col.column, newValue).Error; err != nil {
+						return err
+					}
+					updated++
+				}
+				return nil
+			}).Error
+		if err != nil {
+			return err
+		}
+
+		logger.Info("Transformed encrypted column",
+			zap.String("table", col.table),
+			zap.String("column", col.column),
+			zap.Int("updated", updated))
+	}
+
+	return nil
+}
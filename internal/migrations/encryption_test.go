@@ -0,0 +1,127 @@
+package migrations
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"gorm.io/gorm/schema"
+)
+
+// TestEncryptedColumnsCoverModels checks encryptedColumns against the fields tagged
+// `serializer:encrypted` in the models, so key rotation and decryption reach every one of them
+func TestEncryptedColumnsCoverModels(t *testing.T) {
+	paths, err := filepath.Glob("../model/*.go")
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("no model sources found: %v", err)
+	}
+
+	tables := map[string]string{}
+	fields := map[string][]string{}
+	fset := token.NewFileSet()
+	for _, path := range paths {
+		file, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			t.Fatalf("parse %s: %v", path, err)
+		}
+		for _, decl := range file.Decls {
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				if name, table, ok := tableNameMethod(decl); ok {
+					tables[name] = table
+				}
+			case *ast.GenDecl:
+				for _, spec := range decl.Specs {
+					if typeSpec, ok := spec.(*ast.TypeSpec); ok {
+						if columns := encryptedFields(typeSpec); len(columns) > 0 {
+							fields[typeSpec.Name.Name] = columns
+						}
+					}
+				}
+			}
+		}
+	}
+
+	naming := schema.NamingStrategy{}
+	tagged := map[encryptedColumn]bool{}
+	for model, columns := range fields {
+		table, ok := tables[model]
+		if !ok {
+			table = naming.TableName(model)
+		}
+		for _, column := range columns {
+			tagged[encryptedColumn{table: table, column: column}] = true
+		}
+	}
+
+	listed := map[encryptedColumn]bool{}
+	for _, col := range encryptedColumns {
+		listed[col] = true
+		if !tagged[col] {
+			t.Errorf("%s.%s is listed but not tagged serializer:encrypted", col.table, col.column)
+		}
+	}
+	for col := range tagged {
+		if !listed[col] {
+			t.Errorf("%s.%s is tagged serializer:encrypted but missing from encryptedColumns", col.table, col.column)
+		}
+	}
+}
+
+// tableNameMethod reads a TableName method returning a constant name
+func tableNameMethod(decl *ast.FuncDecl) (model, table string, ok bool) {
+	if decl.Name.Name != "TableName" || decl.Recv == nil || len(decl.Recv.List) != 1 || len(decl.Body.List) != 1 {
+		return "", "", false
+	}
+	recv := decl.Recv.List[0].Type
+	if star, isStar := recv.(*ast.StarExpr); isStar {
+		recv = star.X
+	}
+	ident, isIdent := recv.(*ast.Ident)
+	ret, isReturn := decl.Body.List[0].(*ast.ReturnStmt)
+	if !isIdent || !isReturn || len(ret.Results) != 1 {
+		return "", "", false
+	}
+	lit, isLit := ret.Results[0].(*ast.BasicLit)
+	if !isLit || lit.Kind != token.STRING {
+		return "", "", false
+	}
+	table, err := strconv.Unquote(lit.Value)
+	return ident.Name, table, err == nil
+}
+
+// encryptedFields returns the columns of the fields of a struct type stored with the encrypted
+// serializer
+func encryptedFields(spec *ast.TypeSpec) []string {
+	structType, ok := spec.Type.(*ast.StructType)
+	if !ok {
+		return nil
+	}
+	naming := schema.NamingStrategy{}
+	var columns []string
+	for _, field := range structType.Fields.List {
+		if field.Tag == nil {
+			continue
+		}
+		tag, err := strconv.Unquote(field.Tag.Value)
+		if err != nil {
+			continue
+		}
+		settings := schema.ParseTagSetting(reflect.StructTag(tag).Get("gorm"), ";")
+		if settings["SERIALIZER"] != "encrypted" {
+			continue
+		}
+		for _, name := range field.Names {
+			column := settings["COLUMN"]
+			if column == "" {
+				column = naming.ColumnName("", name.Name)
+			}
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
@@ -0,0 +1,102 @@
+package migrations
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// migration is a named schema or data change applied after auto-migration
+type migration struct {
+	name string
+	up   func(tx *gorm.DB) error
+	down func(tx *gorm.DB) error
+}
+
+var registry []migration
+
+// registerMigration registers a migration. Names are timestamp-prefixed so they sort in apply order.
+func registerMigration(name string, up, down func(tx *gorm.DB) error) {
+	registry = append(registry, migration{name: name, up: up, down: down})
+}
+
+// SchemaMigration records an applied migration
+type SchemaMigration struct {
+	Name      string    `gorm:"primaryKey;size:255"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+// TableName overrides the table name
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// Run applies all pending migrations in order, each in its own transaction
+func Run(db *gorm.DB, logger *zap.Logger) error {
+	if err := db.AutoMigrate(&SchemaMigration{}); err != nil {
+		return fmt.Errorf("failed to create migrations table: %w", err)
+	}
+
+	var applied []SchemaMigration
+	if err := db.Find(&applied).Error; err != nil {
+		return fmt.Errorf("failed to load applied migrations: %w", err)
+	}
+
+	done := make(map[string]bool, len(applied))
+	for _, m := range applied {
+		done[m.Name] = true
+	}
+
+	sort.Slice(registry, func(i, j int) bool { return registry[i].name < registry[j].name })
+
+	for _, m := range registry {
+		if done[m.name] {
+			continue
+		}
+
+		logger.Info("Applying migration", zap.String("migration", m.name))
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := m.up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&SchemaMigration{Name: m.name, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("migration %s failed: %w", m.name, err)
+		}
+	}
+
+	return nil
+}
+
+// Rollback reverts the most recently applied migration
+func Rollback(db *gorm.DB, logger *zap.Logger) error {
+	var last SchemaMigration
+	if err := db.Order("name DESC").First(&last).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.Info("No migrations to roll back")
+			return nil
+		}
+		return fmt.Errorf("failed to find last migration: %w", err)
+	}
+
+	for _, m := range registry {
+		if m.name != last.Name {
+			continue
+		}
+
+		logger.Info("Rolling back migration", zap.String("migration", m.name))
+		return db.Transaction(func(tx *gorm.DB) error {
+			if err := m.down(tx); err != nil {
+				return fmt.Errorf("rollback of %s failed: %w", m.name, err)
+			}
+			return tx.Delete(&SchemaMigration{}, "name = ?", m.name).Error
+		})
+	}
+
+	return fmt.Errorf("migration %s is not registered", last.Name)
+}
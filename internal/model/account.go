@@ -0,0 +1,52 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AccountEntryKind is how an entry moves a patient's account balance
+type AccountEntryKind string
+
+// Account entry kinds. Both reduce the amount the patient owes.
+const (
+	AccountEntryPayment AccountEntryKind = "payment" // Money received from the patient or on their behalf
+	AccountEntryCredit  AccountEntryKind = "credit"  // Amount written off or credited, e.g. a goodwill discount
+)
+
+// IsValid reports whether the kind is known
+func (k AccountEntryKind) IsValid() bool {
+	return k == AccountEntryPayment || k == AccountEntryCredit
+}
+
+// AccountEntry is a payment or credit posted to a patient's account by front-desk staff. The
+// charges are the patient's completed appointments, priced by their appointment type.
+type AccountEntry struct {
+	ID           uint             `json:"-" gorm:"primaryKey"`
+	PublicID     string           `json:"id" gorm:"type:uuid;uniqueIndex;not null;default:gen_random_uuid()"`
+	PatientID    uint             `json:"-" gorm:"not null;index:idx_account_entry_posted,priority:1"`
+	Patient      Patient          `json:"-" gorm:"foreignKey:PatientID"`
+	Kind         AccountEntryKind `json:"kind" gorm:"size:20;not null"`
+	AmountCents  int64            `json:"amount_cents" gorm:"not null"` // Always positive
+	Currency     string           `json:"currency" gorm:"size:3;not null"`
+	Method       string           `json:"method,omitempty" gorm:"size:30"`     // How a payment was made, e.g. card, cash or insurance
+	Reference    string           `json:"reference,omitempty" gorm:"size:100"` // Receipt, transaction or claim number
+	Note         string           `json:"note,omitempty" gorm:"size:255"`
+	PostedAt     time.Time        `json:"posted_at" gorm:"not null;index:idx_account_entry_posted,priority:2"`
+	RecordedByID uint             `json:"-" gorm:"index;not null"`
+	CreatedAt    time.Time        `json:"created_at"`
+}
+
+// TableName overrides the table name
+func (AccountEntry) TableName() string {
+	return "account_entries"
+}
+
+// BeforeCreate assigns the public ID
+func (e *AccountEntry) BeforeCreate(tx *gorm.DB) error {
+	if e.PublicID == "" {
+		e.PublicID = NewPublicID()
+	}
+	return nil
+}
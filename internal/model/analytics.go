@@ -0,0 +1,43 @@
+package model
+
+import (
+	"time"
+)
+
+// AnalyticsGranularity is the width of the time buckets in an analytics series
+type AnalyticsGranularity string
+
+const (
+	GranularityDay   AnalyticsGranularity = "day"
+	GranularityWeek  AnalyticsGranularity = "week"
+	GranularityMonth AnalyticsGranularity = "month"
+)
+
+// IsValid reports whether g is a supported granularity
+func (g AnalyticsGranularity) IsValid() bool {
+	switch g {
+	case GranularityDay, GranularityWeek, GranularityMonth:
+		return true
+	}
+	return false
+}
+
+// AnalyticsBucket holds a clinic's metrics for one time bucket. Buckets are only stored once
+// they are settled, i.e. late changes such as completions can no longer affect them.
+type AnalyticsBucket struct {
+	ID             uint                 `json:"id" gorm:"primaryKey"`
+	OrganizationID uint                 `json:"organization_id" gorm:"uniqueIndex:idx_analytics_bucket;not null"`
+	Granularity    AnalyticsGranularity `json:"granularity" gorm:"uniqueIndex:idx_analytics_bucket;size:10;not null"`
+	BucketStart    time.Time            `json:"bucket_start" gorm:"uniqueIndex:idx_analytics_bucket;not null"`
+	BucketEnd      time.Time            `json:"bucket_end" gorm:"not null"`
+	Bookings       int64                `json:"bookings"`
+	Cancellations  int64                `json:"cancellations"`
+	NewPatients    int64                `json:"new_patients"`
+	Revenue        map[string]int64     `json:"revenue" gorm:"type:text;serializer:json"` // Minor units keyed by currency
+	CreatedAt      time.Time            `json:"created_at"`
+}
+
+// TableName overrides the table name
+func (AnalyticsBucket) TableName() string {
+	return "analytics_buckets"
+}
@@ -0,0 +1,54 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// APIKey lets a partner integration call the API as the user the key was issued to, sending it
+// in the X-API-Key header. Only a hash of the key is stored; the key itself is shown once, when
+// it is issued.
+type APIKey struct {
+	ID                uint       `json:"-" gorm:"primaryKey"`
+	PublicID          string     `json:"id" gorm:"type:uuid;uniqueIndex;not null;default:gen_random_uuid()"`
+	UserID            uint       `json:"-" gorm:"index;not null"` // The key acts as this user, with their role and permissions
+	User              User       `json:"-" gorm:"foreignKey:UserID"`
+	Name              string     `json:"name" gorm:"size:100;not null"`
+	Prefix            string     `json:"prefix" gorm:"size:16;not null"`                // Start of the key, shown so partners can tell their keys apart
+	KeyHash           string     `json:"-" gorm:"size:64;uniqueIndex;not null"`         // SHA-256 of the key
+	RequestsPerMinute int        `json:"requests_per_minute" gorm:"not null;default:0"` // 0 uses integrations.requestsPerMinute
+	DailyQuota        int        `json:"daily_quota" gorm:"not null;default:0"`         // Requests per UTC day; 0 uses integrations.dailyQuota
+	Suspended         bool       `json:"suspended" gorm:"not null;default:false"`       // Refused until an admin lifts the suspension
+	SuspendedReason   string     `json:"suspended_reason,omitempty" gorm:"size:255"`
+	RevokedAt         *time.Time `json:"revoked_at,omitempty"`
+	CreatedByID       uint       `json:"-" gorm:"not null"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+// TableName overrides the table name
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+// BeforeCreate assigns the public ID
+func (k *APIKey) BeforeCreate(tx *gorm.DB) error {
+	if k.PublicID == "" {
+		k.PublicID = NewPublicID()
+	}
+	return nil
+}
+
+// APIKeyUsage counts the requests made with an API key on one UTC day
+type APIKeyUsage struct {
+	APIKeyID uint      `json:"-" gorm:"primaryKey;autoIncrement:false"`
+	Day      time.Time `json:"day" gorm:"primaryKey;type:date"`
+	Requests int64     `json:"requests" gorm:"not null;default:0"` // Every request, refused ones included
+	Rejected int64     `json:"rejected" gorm:"not null;default:0"` // Refused for the rate limit or the daily quota
+}
+
+// TableName overrides the table name
+func (APIKeyUsage) TableName() string {
+	return "api_key_usage"
+}
@@ -2,6 +2,8 @@ package model
 
 import (
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // AppointmentStatus represents the status of an appointment
@@ -10,6 +12,7 @@ type AppointmentStatus string
 const (
 	AppointmentStatusPending   AppointmentStatus = "pending"
 	AppointmentStatusConfirmed AppointmentStatus = "confirmed"
+	AppointmentStatusCheckedIn AppointmentStatus = "checked_in" // The patient has arrived and is waiting to be seen
 	AppointmentStatusCancelled AppointmentStatus = "cancelled"
 	AppointmentStatusCompleted AppointmentStatus = "completed"
 	AppointmentStatusNoShow    AppointmentStatus = "no_show"
@@ -17,19 +20,48 @@ const (
 
 // Appointment represents a medical appointment in the system
 type Appointment struct {
-	ID             uint              `json:"id" gorm:"primaryKey"`
-	PatientID      uint              `json:"patient_id" gorm:"index;not null"`
-	Patient        Patient           `json:"patient" gorm:"foreignKey:PatientID"`
-	DoctorID       uint              `json:"doctor_id" gorm:"index;not null"`
-	Doctor         Doctor            `json:"doctor" gorm:"foreignKey:DoctorID"`
-	ScheduledStart time.Time         `json:"scheduled_start" gorm:"index;not null"`
-	ScheduledEnd   time.Time         `json:"scheduled_end" gorm:"not null"`
-	Status         AppointmentStatus `json:"status" gorm:"size:20;default:'pending'"`
-	Notes          string            `json:"notes" gorm:"type:text"`
-	Reason         string            `json:"reason" gorm:"size:255"`
-	Type           string            `json:"type" gorm:"size:50;default:'in_person'"` // in_person, video, phone
-	CreatedAt      time.Time         `json:"created_at"`
-	UpdatedAt      time.Time         `json:"updated_at"`
+	ID                    uint                           `json:"-" gorm:"primaryKey"`
+	PublicID              string                         `json:"id" gorm:"type:uuid;uniqueIndex;not null;default:gen_random_uuid()"`
+	PatientID             uint                           `json:"-" gorm:"index;not null"`
+	Patient               Patient                        `json:"patient" gorm:"foreignKey:PatientID"`
+	DoctorID              uint                           `json:"-" gorm:"index;not null"`
+	Doctor                Doctor                         `json:"doctor" gorm:"foreignKey:DoctorID"`
+	ScheduledStart        time.Time                      `json:"scheduled_start" gorm:"index;not null"`
+	ScheduledEnd          time.Time                      `json:"scheduled_end" gorm:"not null"`
+	Status                AppointmentStatus              `json:"status" gorm:"size:20;default:'pending'"`
+	Notes                 string                         `json:"notes" gorm:"type:text"`
+	Reason                string                         `json:"reason" gorm:"size:255"`
+	AppointmentTypeID     *uint                          `json:"appointment_type_id" gorm:"index"`
+	AppointmentType       *AppointmentType               `json:"appointment_type,omitempty" gorm:"foreignKey:AppointmentTypeID"`
+	VisitReasonID         *uint                          `json:"visit_reason_id,omitempty" gorm:"index"`                  // Reason the patient booked for, from the managed list
+	Modality              AppointmentModality            `json:"modality" gorm:"column:type;size:50;default:'in_person'"` // Copied from the appointment type when booked
+	IntakeAnswers         map[string]string              `json:"intake_answers,omitempty" gorm:"type:text;serializer:json"`
+	Checklist             []ChecklistItem                `json:"checklist,omitempty" gorm:"type:text;serializer:json"` // Clinic's intake requirements when booked
+	CancelledAt           *time.Time                     `json:"cancelled_at,omitempty"`
+	LateCancellation      bool                           `json:"late_cancellation" gorm:"default:false"`   // Cancelled within the cancellation cutoff; the clinic may charge a fee
+	DeclineReason         string                         `json:"decline_reason,omitempty" gorm:"size:255"` // Set when the doctor declined the booking
+	ReminderSentAt        *time.Time                     `json:"reminder_sent_at,omitempty"`
+	ReminderEscalations   int                            `json:"reminder_escalations" gorm:"not null;default:0"` // Escalation steps taken since the reminder
+	AttendanceConfirmedAt *time.Time                     `json:"attendance_confirmed_at,omitempty"`              // When the patient, or staff on their behalf, confirmed they will attend
+	FollowUpRequired      bool                           `json:"follow_up_required" gorm:"index;default:false"`  // Unconfirmed after every reminder; the front desk should call the patient
+	FollowUpFlaggedAt     *time.Time                     `json:"follow_up_flagged_at,omitempty"`
+	CheckedInAt           *time.Time                     `json:"checked_in_at,omitempty" gorm:"index"`       // When the patient arrived; orders the doctor's waiting queue
+	StartedAt             *time.Time                     `json:"started_at,omitempty"`                       // When the doctor admitted the patient, in person or from the video waiting room
+	EndedAt               *time.Time                     `json:"ended_at,omitempty"`                         // When the video visit ended or, failing that, when the appointment was completed
+	ConfirmationRequired  bool                           `json:"confirmation_required" gorm:"default:false"` // High-risk booking awaiting confirmation by SMS code
+	ConfirmationCodeHash  string                         `json:"-" gorm:"size:64"`
+	ConfirmationExpiresAt *time.Time                     `json:"-"`                           // The confirmation code is rejected after this
+	ConfirmationAttempts  int                            `json:"-" gorm:"not null;default:0"` // Codes entered since the code was sent
+	SeriesID              *uint                          `json:"-" gorm:"index"`              // Recurring series the appointment was booked in
+	Series                *RecurringAppointment          `json:"-" gorm:"foreignKey:SeriesID"`
+	SeriesException       *RecurringAppointmentException `json:"-" gorm:"foreignKey:AppointmentID"`                      // Set once the occurrence is changed apart from its series
+	Participants          []AppointmentParticipant       `json:"participants,omitempty" gorm:"foreignKey:AppointmentID"` // Patients seen in the slot besides the booking patient
+	BookedByID            *uint                          `json:"-" gorm:"index"`                                         // Staff member who booked on the patient's behalf; nil when patients booked themselves
+	BookedBy              *User                          `json:"booked_by,omitempty" gorm:"foreignKey:BookedByID"`
+	Tags                  []string                       `json:"tags,omitempty" gorm:"type:jsonb;serializer:json"`     // Free-form labels staff track workflow with, e.g. interpreter-needed
+	Metadata              map[string]string              `json:"metadata,omitempty" gorm:"type:jsonb;serializer:json"` // Values of the clinic's appointment metadata keys
+	CreatedAt             time.Time                      `json:"created_at"`
+	UpdatedAt             time.Time                      `json:"updated_at"`
 }
 
 // TableName overrides the table name
@@ -37,17 +69,41 @@ func (Appointment) TableName() string {
 	return "appointments"
 }
 
+// BeforeCreate assigns the public ID
+func (a *Appointment) BeforeCreate(tx *gorm.DB) error {
+	if a.PublicID == "" {
+		a.PublicID = NewPublicID()
+	}
+	return nil
+}
+
+// AppointmentParticipant is an additional patient seen in a group booking, such as a
+// sibling attending the same vaccination slot
+type AppointmentParticipant struct {
+	ID            uint      `json:"-" gorm:"primaryKey"`
+	AppointmentID uint      `json:"-" gorm:"index;not null"`
+	PatientID     uint      `json:"-" gorm:"index;not null"`
+	Patient       Patient   `json:"patient" gorm:"foreignKey:PatientID"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// TableName overrides the table name
+func (AppointmentParticipant) TableName() string {
+	return "appointment_participants"
+}
+
 // Session represents a user session
 type Session struct {
-	ID        uint      `json:"id" gorm:"primaryKey"`
-	UserID    uint      `json:"user_id" gorm:"index;not null"`
-	User      User      `json:"-" gorm:"foreignKey:UserID"`
-	Token     string    `json:"-" gorm:"size:500;not null"`
-	ExpiresAt time.Time `json:"expires_at"`
-	UserAgent string    `json:"user_agent" gorm:"size:255"`
-	IP        string    `json:"ip" gorm:"size:50"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	UserID         uint      `json:"user_id" gorm:"index;not null"`
+	User           User      `json:"-" gorm:"foreignKey:UserID"`
+	TokenHash      string    `json:"-" gorm:"column:token;size:500;index;not null"` // SHA-256 of the session ID carried in the "sid" claim
+	ExpiresAt      time.Time `json:"expires_at"`                                    // Absolute end of the session
+	LastActivityAt time.Time `json:"last_activity_at"`                              // Used for the idle timeout
+	UserAgent      string    `json:"user_agent" gorm:"size:255"`
+	IP             string    `json:"ip" gorm:"size:50"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
 }
 
 // TableName overrides the table name
@@ -0,0 +1,26 @@
+package model
+
+import (
+	"time"
+)
+
+// AppointmentHistory records one reschedule of an appointment: the time it moved from, the time
+// it moved to and who moved it. Rows are never changed, so they form the audit trail of the
+// appointment's times and count towards the clinic's reschedule limit.
+type AppointmentHistory struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	AppointmentID uint      `json:"-" gorm:"index;not null"`
+	PreviousStart time.Time `json:"previous_start" gorm:"not null"`
+	PreviousEnd   time.Time `json:"previous_end" gorm:"not null"`
+	NewStart      time.Time `json:"new_start" gorm:"not null"`
+	NewEnd        time.Time `json:"new_end" gorm:"not null"`
+	Reason        string    `json:"reason" gorm:"size:255"`
+	ChangedByID   *uint     `json:"-" gorm:"index"` // User who rescheduled; nil when unknown
+	ChangedBy     *User     `json:"changed_by,omitempty" gorm:"foreignKey:ChangedByID"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// TableName overrides the table name
+func (AppointmentHistory) TableName() string {
+	return "appointment_history"
+}
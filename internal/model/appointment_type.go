@@ -0,0 +1,78 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// AppointmentModality represents how an appointment takes place
+type AppointmentModality string
+
+const (
+	ModalityInPerson AppointmentModality = "in_person"
+	ModalityVideo    AppointmentModality = "video"
+	ModalityPhone    AppointmentModality = "phone"
+)
+
+// IsValid reports whether m is a known modality
+func (m AppointmentModality) IsValid() bool {
+	switch m {
+	case ModalityInPerson, ModalityVideo, ModalityPhone:
+		return true
+	}
+	return false
+}
+
+// IntakeQuestion is a question patients answer when booking an appointment type
+type IntakeQuestion struct {
+	Key      string `json:"key"`
+	Label    string `json:"label"`
+	Required bool   `json:"required"`
+}
+
+// AppointmentType is an administrator-defined kind of appointment, such as a
+// new patient consultation or a video follow-up
+type AppointmentType struct {
+	ID             uint                `json:"id" gorm:"primaryKey"`
+	OrganizationID *uint               `json:"organization_id" gorm:"index"` // Clinic offering the type; nil for all clinics
+	Name           string              `json:"name" gorm:"size:100;not null"`
+	Modality       AppointmentModality `json:"modality" gorm:"size:20;not null"`
+	Duration       int                 `json:"duration" gorm:"not null"` // Duration in minutes
+	PriceCents     int64               `json:"price_cents"`
+	Currency       string              `json:"currency" gorm:"size:3"`
+	Color          string              `json:"color" gorm:"size:7"` // Calendar color, e.g. #4CAF50
+	IntakeForm     []IntakeQuestion    `json:"intake_form" gorm:"type:text;serializer:json"`
+	Preparation    string              `json:"preparation" gorm:"type:text"` // What patients should do before the appointment; printed on confirmation letters
+	Active         bool                `json:"active"`                       // Inactive types are kept for history but cannot be booked
+	CreatedAt      time.Time           `json:"created_at"`
+	UpdatedAt      time.Time           `json:"updated_at"`
+}
+
+// TableName overrides the table name
+func (AppointmentType) TableName() string {
+	return "appointment_types"
+}
+
+// OfferedBy reports whether the type can be booked with doctors of the given clinic
+func (t *AppointmentType) OfferedBy(orgID uint) bool {
+	return t.OrganizationID == nil || *t.OrganizationID == orgID
+}
+
+// ValidateIntake checks that every required intake question has an answer and
+// that no unknown questions were answered
+func (t *AppointmentType) ValidateIntake(answers map[string]string) error {
+	known := make(map[string]bool, len(t.IntakeForm))
+	for _, q := range t.IntakeForm {
+		known[q.Key] = true
+		if q.Required && strings.TrimSpace(answers[q.Key]) == "" {
+			return fmt.Errorf("intake question %q is required", q.Label)
+		}
+	}
+	for key := range answers {
+		if !known[key] {
+			return fmt.Errorf("unknown intake question %q", key)
+		}
+	}
+	return nil
+}
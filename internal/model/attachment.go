@@ -0,0 +1,35 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MedicalRecordAttachment is a file attached to a medical record, such as a lab result or an
+// imaging report. The file itself is kept in attachment storage under StorageKey.
+type MedicalRecordAttachment struct {
+	ID          uint      `json:"-" gorm:"primaryKey"`
+	PublicID    string    `json:"id" gorm:"type:uuid;uniqueIndex;not null;default:gen_random_uuid()"`
+	RecordID    uint      `json:"-" gorm:"index;not null"`
+	FileName    string    `json:"file_name" gorm:"size:255;not null"`
+	ContentType string    `json:"content_type" gorm:"size:100;not null"` // Detected from the file's contents
+	Size        int64     `json:"size" gorm:"not null"`
+	Checksum    string    `json:"checksum" gorm:"size:64;not null"` // Hex-encoded SHA-256 of the file
+	StorageKey  string    `json:"-" gorm:"size:255;not null"`
+	UploadedBy  uint      `json:"-" gorm:"index;not null"` // Doctor who attached the file
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// TableName overrides the table name
+func (MedicalRecordAttachment) TableName() string {
+	return "medical_record_attachments"
+}
+
+// BeforeCreate assigns the public ID
+func (a *MedicalRecordAttachment) BeforeCreate(tx *gorm.DB) error {
+	if a.PublicID == "" {
+		a.PublicID = NewPublicID()
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package model
+
+import (
+	"time"
+)
+
+// BreakGlassAccess records a clinician's time-limited emergency access to a patient record
+// outside their normal treatment relationship
+type BreakGlassAccess struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"index;not null"`
+	User      User      `json:"-" gorm:"foreignKey:UserID"`
+	PatientID uint      `json:"patient_id" gorm:"index;not null"`
+	Patient   Patient   `json:"-" gorm:"foreignKey:PatientID"`
+	Reason    string    `json:"reason" gorm:"type:text;not null"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"index;not null"`
+	IP        string    `json:"ip" gorm:"size:50"`
+	UserAgent string    `json:"user_agent" gorm:"size:255"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName overrides the table name
+func (BreakGlassAccess) TableName() string {
+	return "break_glass_accesses"
+}
+
+// IsActive reports whether the access grant has not yet expired
+func (a *BreakGlassAccess) IsActive() bool {
+	return time.Now().Before(a.ExpiresAt)
+}
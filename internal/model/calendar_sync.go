@@ -0,0 +1,62 @@
+package model
+
+import (
+	"time"
+)
+
+// CalendarProviderGoogle is Google Calendar
+const CalendarProviderGoogle = "google"
+
+// CalendarConnection links a doctor's external calendar. Confirmed appointments are pushed to
+// it and the times it is busy are pulled back to block slots.
+type CalendarConnection struct {
+	ID           uint       `json:"-" gorm:"primaryKey"`
+	UserID       uint       `json:"-" gorm:"uniqueIndex;not null"`
+	User         User       `json:"-" gorm:"foreignKey:UserID"`
+	DoctorID     uint       `json:"-" gorm:"index;not null"`
+	Provider     string     `json:"provider" gorm:"size:20;not null"`
+	CalendarID   string     `json:"calendar_id" gorm:"size:255;not null;default:'primary'"`
+	AccessToken  string     `json:"-" gorm:"type:text;serializer:encrypted"`
+	RefreshToken string     `json:"-" gorm:"type:text;serializer:encrypted"`
+	TokenExpiry  time.Time  `json:"-"`
+	SyncedAt     *time.Time `json:"synced_at"`                            // Last sync that completed
+	LastError    string     `json:"last_error,omitempty" gorm:"size:500"` // Why the last sync failed; cleared by the next one to succeed
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// TableName overrides the table name
+func (CalendarConnection) TableName() string {
+	return "calendar_connections"
+}
+
+// CalendarEvent is the external event pushed for an appointment
+type CalendarEvent struct {
+	ID            uint      `gorm:"primaryKey"`
+	ConnectionID  uint      `gorm:"uniqueIndex:idx_calendar_event_appointment;not null"`
+	AppointmentID uint      `gorm:"uniqueIndex:idx_calendar_event_appointment;not null"`
+	EventID       string    `gorm:"size:255;not null"`
+	Version       time.Time // Appointment's UpdatedAt when it was pushed; newer changes are pushed again
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// TableName overrides the table name
+func (CalendarEvent) TableName() string {
+	return "calendar_events"
+}
+
+// CalendarBusy is a period a doctor's external calendar is busy. Slots in it are not offered
+// and cannot be booked.
+type CalendarBusy struct {
+	ID           uint      `json:"-" gorm:"primaryKey"`
+	ConnectionID uint      `json:"-" gorm:"index;not null"`
+	DoctorID     uint      `json:"-" gorm:"index;not null"`
+	Start        time.Time `json:"start" gorm:"index;not null"`
+	End          time.Time `json:"end" gorm:"index;not null"`
+}
+
+// TableName overrides the table name
+func (CalendarBusy) TableName() string {
+	return "calendar_busy"
+}
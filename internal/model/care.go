@@ -0,0 +1,49 @@
+package model
+
+import (
+	"time"
+)
+
+// CareRule is a recurring preventive care item, such as an annual physical or a screening for
+// patients of a given age and sex. A completed appointment of the rule's type satisfies it for
+// IntervalMonths.
+type CareRule struct {
+	ID                uint             `json:"id" gorm:"primaryKey"`
+	Name              string           `json:"name" gorm:"size:100;not null"`
+	Description       string           `json:"description" gorm:"size:255"`
+	Sex               string           `json:"sex" gorm:"size:20"` // female or male; empty for everyone
+	MinAge            int              `json:"min_age"`            // In years, inclusive
+	MaxAge            int              `json:"max_age"`            // In years, inclusive; 0 for no upper bound
+	IntervalMonths    int              `json:"interval_months" gorm:"not null"`
+	AppointmentTypeID uint             `json:"appointment_type_id" gorm:"not null;index"`
+	AppointmentType   *AppointmentType `json:"appointment_type,omitempty" gorm:"foreignKey:AppointmentTypeID"`
+	Specialty         string           `json:"specialty" gorm:"size:100"` // Suggested specialty to book with, if any
+	Active            bool             `json:"active"`
+	CreatedAt         time.Time        `json:"created_at"`
+	UpdatedAt         time.Time        `json:"updated_at"`
+}
+
+// TableName overrides the table name
+func (CareRule) TableName() string {
+	return "care_rules"
+}
+
+// CareReminder records that a patient was told a care rule is due. There is one per patient and
+// rule; it is replaced when the rule comes due again after the next visit.
+type CareReminder struct {
+	ID          uint       `json:"-" gorm:"primaryKey"`
+	PatientID   uint       `json:"-" gorm:"not null;uniqueIndex:idx_care_reminders_patient_rule"`
+	Patient     Patient    `json:"-" gorm:"foreignKey:PatientID"`
+	RuleID      uint       `json:"-" gorm:"not null;uniqueIndex:idx_care_reminders_patient_rule"`
+	Rule        CareRule   `json:"-" gorm:"foreignKey:RuleID"`
+	DueAt       time.Time  `json:"due_at"`
+	LastVisitAt *time.Time `json:"last_visit_at,omitempty"` // Last completed appointment satisfying the rule
+	NotifiedAt  time.Time  `json:"notified_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// TableName overrides the table name
+func (CareReminder) TableName() string {
+	return "care_reminders"
+}
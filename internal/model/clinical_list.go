@@ -0,0 +1,118 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AllergyCategory is the kind of substance a patient is allergic to
+type AllergyCategory string
+
+// Allergy categories
+const (
+	AllergyCategoryMedication    AllergyCategory = "medication"
+	AllergyCategoryFood          AllergyCategory = "food"
+	AllergyCategoryEnvironmental AllergyCategory = "environmental" // e.g. pollen, latex or insect stings
+	AllergyCategoryOther         AllergyCategory = "other"
+)
+
+// IsValid reports whether the category is known
+func (c AllergyCategory) IsValid() bool {
+	switch c {
+	case AllergyCategoryMedication, AllergyCategoryFood, AllergyCategoryEnvironmental, AllergyCategoryOther:
+		return true
+	}
+	return false
+}
+
+// AllergySeverity is how severe a patient's reaction to a substance is
+type AllergySeverity string
+
+// Allergy severities. Entries carried over from the free-text allergies field have none.
+const (
+	AllergySeverityMild     AllergySeverity = "mild"
+	AllergySeverityModerate AllergySeverity = "moderate"
+	AllergySeveritySevere   AllergySeverity = "severe" // Including anaphylaxis
+)
+
+// IsValid reports whether the severity is known
+func (s AllergySeverity) IsValid() bool {
+	switch s {
+	case AllergySeverityMild, AllergySeverityModerate, AllergySeveritySevere:
+		return true
+	}
+	return false
+}
+
+// Allergy is a substance a patient is allergic or intolerant to. It is active until its
+// resolved date.
+type Allergy struct {
+	ID           uint            `json:"-" gorm:"primaryKey"`
+	PublicID     string          `json:"id" gorm:"type:uuid;uniqueIndex;not null;default:gen_random_uuid()"`
+	PatientID    uint            `json:"-" gorm:"index;not null"`
+	Substance    string          `json:"substance" gorm:"size:150;not null"` // Generic drug name for medication allergies, so prescriptions can be checked against it
+	Category     AllergyCategory `json:"category" gorm:"size:20;not null;default:'other'"`
+	Reaction     string          `json:"reaction,omitempty" gorm:"size:255"` // e.g. hives or anaphylaxis
+	Severity     AllergySeverity `json:"severity,omitempty" gorm:"size:20"`
+	OnsetDate    *time.Time      `json:"onset_date,omitempty" gorm:"type:date"`
+	ResolvedDate *time.Time      `json:"resolved_date,omitempty" gorm:"type:date"` // First day the allergy no longer applies, e.g. after desensitisation
+	RecordedByID *uint           `json:"-" gorm:"index"`                           // Nil for entries carried over from the free-text field
+	CreatedAt    time.Time       `json:"created_at"`
+	UpdatedAt    time.Time       `json:"updated_at"`
+}
+
+// TableName overrides the table name
+func (Allergy) TableName() string {
+	return "allergies"
+}
+
+// BeforeCreate assigns the public ID
+func (a *Allergy) BeforeCreate(tx *gorm.DB) error {
+	if a.PublicID == "" {
+		a.PublicID = NewPublicID()
+	}
+	return nil
+}
+
+// IsActive reports whether the allergy applies on the day of t
+func (a *Allergy) IsActive(t time.Time) bool {
+	return a.ResolvedDate == nil || a.ResolvedDate.After(t)
+}
+
+// PatientMedication is a medication on a patient's medication list: what they take, whether
+// prescribed here or elsewhere. It is active until its stop date.
+type PatientMedication struct {
+	ID           uint        `json:"-" gorm:"primaryKey"`
+	PublicID     string      `json:"id" gorm:"type:uuid;uniqueIndex;not null;default:gen_random_uuid()"`
+	PatientID    uint        `json:"-" gorm:"index;not null"`
+	MedicationID *uint       `json:"-" gorm:"index"` // Catalog product, when the medication is in the catalog
+	Medication   *Medication `json:"medication,omitempty" gorm:"foreignKey:MedicationID"`
+	Name         string      `json:"name" gorm:"size:150;not null"` // Catalog product name, or as the patient reported it
+	Dosage       string      `json:"dosage,omitempty" gorm:"size:100"`
+	Frequency    string      `json:"frequency,omitempty" gorm:"size:100"`
+	Reason       string      `json:"reason,omitempty" gorm:"size:255"` // What it is taken for
+	StartDate    *time.Time  `json:"start_date,omitempty" gorm:"type:date"`
+	StopDate     *time.Time  `json:"stop_date,omitempty" gorm:"type:date"` // First day the patient no longer takes it
+	RecordedByID *uint       `json:"-" gorm:"index"`                       // Nil for entries carried over from the free-text field
+	CreatedAt    time.Time   `json:"created_at"`
+	UpdatedAt    time.Time   `json:"updated_at"`
+}
+
+// TableName overrides the table name
+func (PatientMedication) TableName() string {
+	return "patient_medications"
+}
+
+// BeforeCreate assigns the public ID
+func (m *PatientMedication) BeforeCreate(tx *gorm.DB) error {
+	if m.PublicID == "" {
+		m.PublicID = NewPublicID()
+	}
+	return nil
+}
+
+// IsActive reports whether the patient takes the medication on the day of t
+func (m *PatientMedication) IsActive(t time.Time) bool {
+	return m.StopDate == nil || m.StopDate.After(t)
+}
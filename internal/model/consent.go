@@ -0,0 +1,60 @@
+package model
+
+import (
+	"time"
+)
+
+// PolicyType represents the kind of policy document a user can consent to
+type PolicyType string
+
+const (
+	PolicyTypeTermsOfService PolicyType = "terms_of_service"
+	PolicyTypePrivacy        PolicyType = "privacy_policy"
+)
+
+// Policy describes the currently published version of a policy document
+type Policy struct {
+	Type     PolicyType `json:"type"`
+	Version  string     `json:"version"`
+	Title    string     `json:"title"`
+	URL      string     `json:"url"`
+	Required bool       `json:"required"`
+}
+
+// Consent records a user's acceptance of a specific policy version
+type Consent struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	UserID     uint       `json:"user_id" gorm:"index:idx_consent_user_policy;not null"`
+	User       User       `json:"-" gorm:"foreignKey:UserID"`
+	PolicyType PolicyType `json:"policy_type" gorm:"size:50;index:idx_consent_user_policy;not null"`
+	Version    string     `json:"version" gorm:"size:20;index:idx_consent_user_policy;not null"`
+	AcceptedAt time.Time  `json:"accepted_at" gorm:"not null"`
+	IP         string     `json:"ip" gorm:"size:50"`
+	UserAgent  string     `json:"user_agent" gorm:"size:255"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// TableName overrides the table name
+func (Consent) TableName() string {
+	return "consents"
+}
+
+// MarketingConsent records a user opting in to or out of promotional messages on one channel.
+// It is separate from transactional notifications such as reminders, which need no consent. The
+// latest record for a channel is the user's current choice; earlier ones are kept as evidence of
+// when consent was given and withdrawn.
+type MarketingConsent struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"index:idx_marketing_consent_user_channel;not null"`
+	User      User      `json:"-" gorm:"foreignKey:UserID"`
+	Channel   string    `json:"channel" gorm:"size:10;index:idx_marketing_consent_user_channel;not null"`
+	Granted   bool      `json:"granted" gorm:"not null"`
+	IP        string    `json:"ip" gorm:"size:50"`
+	UserAgent string    `json:"user_agent" gorm:"size:255"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName overrides the table name
+func (MarketingConsent) TableName() string {
+	return "marketing_consents"
+}
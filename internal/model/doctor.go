@@ -2,21 +2,51 @@ package model
 
 import (
 	"time"
+
+	"gorm.io/gorm"
+)
+
+// DoctorStatus is where a doctor is in their working day
+type DoctorStatus string
+
+const (
+	DoctorStatusAvailable      DoctorStatus = "available"
+	DoctorStatusInConsultation DoctorStatus = "in_consultation"
+	DoctorStatusOnBreak        DoctorStatus = "on_break"
+	DoctorStatusOffSite        DoctorStatus = "off_site"
 )
 
+// IsValid reports whether the status is one of the known statuses
+func (s DoctorStatus) IsValid() bool {
+	switch s {
+	case DoctorStatusAvailable, DoctorStatusInConsultation, DoctorStatusOnBreak, DoctorStatusOffSite:
+		return true
+	}
+	return false
+}
+
 // Doctor represents a doctor in the system
 type Doctor struct {
-	ID          uint      `json:"id" gorm:"primaryKey"`
-	UserID      uint      `json:"user_id" gorm:"uniqueIndex;not null"`
-	User        User      `json:"user" gorm:"foreignKey:UserID"`
-	Specialty   string    `json:"specialty" gorm:"size:100;not null"`
-	Designation string    `json:"designation" gorm:"size:100"`
-	Education   string    `json:"education" gorm:"size:255"`
-	Experience  int       `json:"experience" gorm:"default:0"`
-	LicenseNo   string    `json:"license_no" gorm:"size:100"`
-	Bio         string    `json:"bio" gorm:"type:text"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID                 uint         `json:"-" gorm:"primaryKey"`
+	PublicID           string       `json:"id" gorm:"type:uuid;uniqueIndex;not null;default:gen_random_uuid()"`
+	UserID             uint         `json:"-" gorm:"uniqueIndex;not null"`
+	User               User         `json:"user" gorm:"foreignKey:UserID"`
+	OrganizationID     *uint        `json:"organization_id" gorm:"index"` // Clinic whose settings apply; nil for the default clinic
+	Specialty          string       `json:"specialty" gorm:"size:100;not null"`
+	Designation        string       `json:"designation" gorm:"size:100"`
+	Education          string       `json:"education" gorm:"size:255"`
+	Experience         int          `json:"experience" gorm:"default:0"`
+	LicenseNo          string       `json:"license_no" gorm:"size:100"`
+	Bio                string       `json:"bio" gorm:"type:text"`
+	Status             DoctorStatus `json:"status,omitempty" gorm:"size:20"` // Set by the doctor; empty when derived from their schedule
+	StatusSetAt        *time.Time   `json:"status_set_at,omitempty"`
+	AutoConfirm        bool         `json:"auto_confirm" gorm:"default:false"`      // Bookings are confirmed without waiting for the doctor
+	SlotLength         int          `json:"slot_length" gorm:"default:0"`           // Consultation length in minutes; 0 for the clinic's default
+	BufferMinutes      int          `json:"buffer_minutes" gorm:"default:0"`        // Time kept free after each appointment
+	MaxParallel        int          `json:"max_parallel_bookings" gorm:"default:1"` // Bookings accepted for the same time; above 1 to overbook for expected no-shows
+	CancellationCutoff *int         `json:"cancellation_cutoff,omitempty"`          // Minutes before the start after which cancelling is late; nil for the clinic's
+	CreatedAt          time.Time    `json:"created_at"`
+	UpdatedAt          time.Time    `json:"updated_at"`
 }
 
 // TableName overrides the table name
@@ -24,20 +54,106 @@ func (Doctor) TableName() string {
 	return "doctors"
 }
 
-// Availability represents a doctor's available time slots
+// BeforeCreate assigns the public ID
+func (d *Doctor) BeforeCreate(tx *gorm.DB) error {
+	if d.PublicID == "" {
+		d.PublicID = NewPublicID()
+	}
+	return nil
+}
+
+// AppointmentLength returns the doctor's consultation length, falling back to the clinic's
+// default appointment length
+func (d *Doctor) AppointmentLength(org *Organization) time.Duration {
+	if d.SlotLength > 0 {
+		return time.Duration(d.SlotLength) * time.Minute
+	}
+	return org.AppointmentLength()
+}
+
+// Buffer returns the time kept free after each of the doctor's appointments
+func (d *Doctor) Buffer() time.Duration {
+	return time.Duration(d.BufferMinutes) * time.Minute
+}
+
+// Capacity returns how many bookings the doctor accepts for the same time
+func (d *Doctor) Capacity() int {
+	if d.MaxParallel < 1 {
+		return 1
+	}
+	return d.MaxParallel
+}
+
+// Availability represents a doctor's available time slots. A window repeats every week, or only
+// between its valid dates when it was applied from a shift template for a date range.
 type Availability struct {
-	ID        uint      `json:"id" gorm:"primaryKey"`
-	DoctorID  uint      `json:"doctor_id" gorm:"index"`
-	Doctor    Doctor    `json:"-" gorm:"foreignKey:DoctorID"`
-	DayOfWeek int       `json:"day_of_week" gorm:"type:smallint"` // 0-6 for Sunday-Saturday
-	StartTime string    `json:"start_time" gorm:"type:time"`      // Format: HH:MM:SS
-	EndTime   string    `json:"end_time" gorm:"type:time"`        // Format: HH:MM:SS
-	Duration  int       `json:"duration" gorm:"default:30"`       // Duration in minutes
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID              uint       `json:"id" gorm:"primaryKey"`
+	DoctorID        uint       `json:"doctor_id" gorm:"index"`
+	Doctor          Doctor     `json:"-" gorm:"foreignKey:DoctorID"`
+	DayOfWeek       int        `json:"day_of_week" gorm:"type:smallint"`         // 0-6 for Sunday-Saturday
+	StartTime       string     `json:"start_time" gorm:"type:time"`              // Format: HH:MM:SS
+	EndTime         string     `json:"end_time" gorm:"type:time"`                // Format: HH:MM:SS
+	Duration        int        `json:"duration" gorm:"default:30"`               // Duration in minutes
+	ValidFrom       *time.Time `json:"valid_from,omitempty" gorm:"type:date"`    // First clinic-local date the window applies on; nil when it always has
+	ValidUntil      *time.Time `json:"valid_until,omitempty" gorm:"type:date"`   // Last clinic-local date the window applies on; nil when it has no end
+	ShiftTemplateID *uint      `json:"shift_template_id,omitempty" gorm:"index"` // Template the window was applied from
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
 }
 
 // TableName overrides the table name
 func (Availability) TableName() string {
 	return "availability"
 }
+
+// AppliesOn reports whether the window applies on a clinic-local date, given as YYYY-MM-DD
+func (a *Availability) AppliesOn(date string) bool {
+	if a.ValidFrom != nil && date < a.ValidFrom.Format("2006-01-02") {
+		return false
+	}
+	return a.ValidUntil == nil || date <= a.ValidUntil.Format("2006-01-02")
+}
+
+// ShiftTemplate is a reusable week of working windows, such as "Morning clinic" or "Surgery
+// day", applied to a doctor's availability for a range of dates in one go
+type ShiftTemplate struct {
+	ID          uint          `json:"id" gorm:"primaryKey"`
+	Name        string        `json:"name" gorm:"size:100;uniqueIndex;not null"`
+	Description string        `json:"description" gorm:"type:text"`
+	Windows     []ShiftWindow `json:"windows" gorm:"type:jsonb;serializer:json"`
+	CreatedAt   time.Time     `json:"created_at"`
+	UpdatedAt   time.Time     `json:"updated_at"`
+}
+
+// TableName overrides the table name
+func (ShiftTemplate) TableName() string {
+	return "shift_templates"
+}
+
+// ShiftWindow is one weekly working window of a shift template
+type ShiftWindow struct {
+	DayOfWeek int    `json:"day_of_week"` // 0-6 for Sunday-Saturday
+	StartTime string `json:"start_time"`  // Format: HH:MM:SS
+	EndTime   string `json:"end_time"`    // Format: HH:MM:SS
+	Duration  int    `json:"duration"`    // Slot length in minutes; 0 for the doctor's consultation length
+}
+
+// TimeOff blocks a range of a doctor's time, such as a vacation or a conference, on top of their
+// weekly availability. Messages patients send the doctor during it go to the covering doctor.
+type TimeOff struct {
+	ID               uint      `json:"id" gorm:"primaryKey"`
+	DoctorID         uint      `json:"doctor_id" gorm:"index"`
+	Doctor           Doctor    `json:"-" gorm:"foreignKey:DoctorID"`
+	Start            time.Time `json:"start" gorm:"index"`
+	End              time.Time `json:"end" gorm:"index"`
+	Reason           string    `json:"reason"`
+	CoveringDoctorID *uint     `json:"-" gorm:"index"` // Doctor who takes the doctor's patient messages during the time off, if any
+	CoveringDoctor   *Doctor   `json:"-" gorm:"foreignKey:CoveringDoctorID"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// TableName overrides the table name
+func (TimeOff) TableName() string {
+	return "time_off"
+}
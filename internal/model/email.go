@@ -0,0 +1,57 @@
+package model
+
+import (
+	"time"
+)
+
+// EmailStatus is the delivery state of an outbound email
+type EmailStatus string
+
+const (
+	EmailStatusSent       EmailStatus = "sent"       // Accepted by the SMTP server
+	EmailStatusFailed     EmailStatus = "failed"     // Rejected by the SMTP server
+	EmailStatusSuppressed EmailStatus = "suppressed" // Not sent because the address is suppressed
+	EmailStatusDelivered  EmailStatus = "delivered"
+	EmailStatusBounced    EmailStatus = "bounced"
+	EmailStatusComplained EmailStatus = "complained"
+)
+
+// EmailMessage records an outbound email and its delivery status
+type EmailMessage struct {
+	ID        uint        `json:"id" gorm:"primaryKey"`
+	MessageID string      `json:"message_id" gorm:"size:255;index"` // Message-ID header, used to match provider events
+	Recipient string      `json:"recipient" gorm:"size:255;index;not null"`
+	Template  string      `json:"template" gorm:"size:50;not null"`
+	Subject   string      `json:"subject" gorm:"size:255"`
+	Status    EmailStatus `json:"status" gorm:"size:20;index;not null"`
+	Detail    string      `json:"detail" gorm:"type:text"` // SMTP error or provider bounce reason
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+}
+
+// TableName overrides the table name
+func (EmailMessage) TableName() string {
+	return "email_messages"
+}
+
+// SuppressionReason is why an address no longer receives email
+type SuppressionReason string
+
+const (
+	SuppressionHardBounce SuppressionReason = "hard_bounce"
+	SuppressionComplaint  SuppressionReason = "complaint"
+)
+
+// EmailSuppression is an address that outbound email is no longer sent to
+type EmailSuppression struct {
+	ID        uint              `json:"id" gorm:"primaryKey"`
+	Email     string            `json:"email" gorm:"size:255;uniqueIndex;not null"`
+	Reason    SuppressionReason `json:"reason" gorm:"size:20;not null"`
+	Detail    string            `json:"detail" gorm:"type:text"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+// TableName overrides the table name
+func (EmailSuppression) TableName() string {
+	return "email_suppressions"
+}
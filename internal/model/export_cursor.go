@@ -0,0 +1,17 @@
+package model
+
+import (
+	"time"
+)
+
+// ExportCursor records how far an exporter has delivered a stream of records
+type ExportCursor struct {
+	Name      string    `json:"name" gorm:"primaryKey;size:100"`
+	LastID    uint      `json:"last_id" gorm:"not null;default:0"` // ID of the last record delivered
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName overrides the table name
+func (ExportCursor) TableName() string {
+	return "export_cursors"
+}
@@ -0,0 +1,36 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// HandoffNote is an internal note about a patient for coordinating the care team. Notes are
+// never shown to the patient and cannot be edited once written. A note addressed to a doctor
+// hands the patient over to them.
+type HandoffNote struct {
+	ID          uint      `json:"-" gorm:"primaryKey"`
+	PublicID    string    `json:"id" gorm:"type:uuid;uniqueIndex;not null;default:gen_random_uuid()"`
+	PatientID   uint      `json:"-" gorm:"index;not null"`
+	Patient     Patient   `json:"-" gorm:"foreignKey:PatientID"`
+	AuthorID    uint      `json:"-" gorm:"index;not null"` // Doctor who wrote the note
+	Author      Doctor    `json:"author" gorm:"foreignKey:AuthorID"`
+	RecipientID *uint     `json:"-" gorm:"index"` // Doctor the patient is handed over to, if any
+	Recipient   *Doctor   `json:"recipient,omitempty" gorm:"foreignKey:RecipientID"`
+	Body        string    `json:"body" gorm:"type:text;not null"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// TableName overrides the table name
+func (HandoffNote) TableName() string {
+	return "handoff_notes"
+}
+
+// BeforeCreate assigns the public ID
+func (n *HandoffNote) BeforeCreate(tx *gorm.DB) error {
+	if n.PublicID == "" {
+		n.PublicID = NewPublicID()
+	}
+	return nil
+}
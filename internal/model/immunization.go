@@ -0,0 +1,88 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// VaccineSeries is how a vaccine is given: the doses of its primary series, the days to wait
+// after each dose before the next one is due, and the days after the last dose until a booster
+// is due, if any
+type VaccineSeries struct {
+	Code         string `json:"code"` // CVX code
+	Name         string `json:"name"`
+	Doses        int    `json:"doses"`                   // Doses in the primary series
+	IntervalDays []int  `json:"interval_days,omitempty"` // Days from dose n to dose n+1; one fewer than Doses
+	BoosterDays  int    `json:"booster_days,omitempty"`  // Days from the last dose to the next booster; 0 when none is given
+}
+
+// VaccineSchedule lists the vaccines immunizations can be recorded for, by CVX code, with the
+// intervals of the routine schedule
+var VaccineSchedule = []VaccineSeries{
+	{Code: "08", Name: "Hepatitis B, pediatric", Doses: 3, IntervalDays: []int{28, 140}},
+	{Code: "43", Name: "Hepatitis B, adult", Doses: 3, IntervalDays: []int{28, 140}},
+	{Code: "83", Name: "Hepatitis A, pediatric", Doses: 2, IntervalDays: []int{180}},
+	{Code: "20", Name: "DTaP", Doses: 5, IntervalDays: []int{56, 56, 180, 1095}},
+	{Code: "115", Name: "Tdap", Doses: 1, BoosterDays: 3650},
+	{Code: "10", Name: "Polio (IPV)", Doses: 4, IntervalDays: []int{56, 56, 1095}},
+	{Code: "03", Name: "MMR", Doses: 2, IntervalDays: []int{28}},
+	{Code: "21", Name: "Varicella", Doses: 2, IntervalDays: []int{84}},
+	{Code: "165", Name: "HPV, 9-valent", Doses: 3, IntervalDays: []int{60, 120}},
+	{Code: "133", Name: "Pneumococcal conjugate (PCV13)", Doses: 4, IntervalDays: []int{56, 56, 180}},
+	{Code: "187", Name: "Zoster, recombinant", Doses: 2, IntervalDays: []int{60}},
+	{Code: "88", Name: "Influenza, seasonal", Doses: 1, BoosterDays: 365},
+	{Code: "208", Name: "COVID-19, mRNA", Doses: 2, IntervalDays: []int{21}, BoosterDays: 365},
+}
+
+// FindVaccineSeries finds a vaccine of the schedule by CVX code
+func FindVaccineSeries(code string) (VaccineSeries, bool) {
+	for _, series := range VaccineSchedule {
+		if series.Code == code {
+			return series, true
+		}
+	}
+	return VaccineSeries{}, false
+}
+
+// DueAfter returns how many days after dose the next dose is due, or false when the series is
+// complete and no booster is given. Doses past the primary series are boosters.
+func (v VaccineSeries) DueAfter(dose int) (int, bool) {
+	if dose < v.Doses && dose-1 < len(v.IntervalDays) {
+		return v.IntervalDays[dose-1], true
+	}
+	if v.BoosterDays > 0 {
+		return v.BoosterDays, true
+	}
+	return 0, false
+}
+
+// Immunization is a vaccine dose given to a patient
+type Immunization struct {
+	ID               uint      `json:"-" gorm:"primaryKey"`
+	PublicID         string    `json:"id" gorm:"type:uuid;uniqueIndex;not null;default:gen_random_uuid()"`
+	PatientID        uint      `json:"-" gorm:"uniqueIndex:idx_immunization_dose;not null"`
+	VaccineCode      string    `json:"vaccine_code" gorm:"size:10;uniqueIndex:idx_immunization_dose;not null"` // CVX code
+	VaccineName      string    `json:"vaccine_name" gorm:"size:100;not null"`
+	DoseNumber       int       `json:"dose_number" gorm:"uniqueIndex:idx_immunization_dose;not null"` // 1 for the first dose; doses past the primary series are boosters
+	AdministeredDate time.Time `json:"administered_date" gorm:"type:date;not null"`
+	DoctorID         uint      `json:"-" gorm:"index;not null"` // Administering doctor
+	Doctor           Doctor    `json:"doctor,omitempty" gorm:"foreignKey:DoctorID"`
+	LotNumber        string    `json:"lot_number,omitempty" gorm:"size:50"`
+	Notes            string    `json:"notes,omitempty" gorm:"type:text"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// TableName overrides the table name
+func (Immunization) TableName() string {
+	return "immunizations"
+}
+
+// BeforeCreate assigns the public ID
+func (i *Immunization) BeforeCreate(tx *gorm.DB) error {
+	if i.PublicID == "" {
+		i.PublicID = NewPublicID()
+	}
+	return nil
+}
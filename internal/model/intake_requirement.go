@@ -0,0 +1,77 @@
+package model
+
+import (
+	"time"
+)
+
+// IntakeRequirement is an item a clinic requires before an appointment can be confirmed
+type IntakeRequirement string
+
+const (
+	RequirementIntakeForm        IntakeRequirement = "intake_form"        // The appointment type's intake form is answered
+	RequirementInsuranceVerified IntakeRequirement = "insurance_verified" // Staff verified the patient's insurance
+	RequirementDepositPaid       IntakeRequirement = "deposit_paid"       // Staff recorded the deposit as paid
+)
+
+// IsValid reports whether r is a known intake requirement
+func (r IntakeRequirement) IsValid() bool {
+	switch r {
+	case RequirementIntakeForm, RequirementInsuranceVerified, RequirementDepositPaid:
+		return true
+	}
+	return false
+}
+
+// ChecklistItem is the state of one intake requirement on an appointment
+type ChecklistItem struct {
+	Requirement IntakeRequirement `json:"requirement"`
+	CompletedAt *time.Time        `json:"completed_at,omitempty"`
+	CompletedBy uint              `json:"-"` // User who checked the item off; 0 when the system did
+}
+
+// NewChecklist returns an open checklist for the given requirements
+func NewChecklist(requirements []IntakeRequirement) []ChecklistItem {
+	if len(requirements) == 0 {
+		return nil
+	}
+	items := make([]ChecklistItem, 0, len(requirements))
+	for _, requirement := range requirements {
+		items = append(items, ChecklistItem{Requirement: requirement})
+	}
+	return items
+}
+
+// SetRequirement checks an item of the appointment's checklist off, or reopens it when
+// completedAt is nil. It reports whether the item is on the checklist.
+func (a *Appointment) SetRequirement(requirement IntakeRequirement, completedAt *time.Time, by uint) bool {
+	for i := range a.Checklist {
+		if a.Checklist[i].Requirement == requirement {
+			a.Checklist[i].CompletedAt = completedAt
+			a.Checklist[i].CompletedBy = by
+			return true
+		}
+	}
+	return false
+}
+
+// OutstandingRequirements returns the checklist items still to be completed before the
+// appointment can be confirmed
+func (a *Appointment) OutstandingRequirements() []IntakeRequirement {
+	var outstanding []IntakeRequirement
+	for _, item := range a.Checklist {
+		if item.CompletedAt == nil {
+			outstanding = append(outstanding, item.Requirement)
+		}
+	}
+	return outstanding
+}
+
+// Requires reports whether the clinic requires an item before appointments are confirmed
+func (o *Organization) Requires(requirement IntakeRequirement) bool {
+	for _, r := range o.IntakeRequirements {
+		if r == requirement {
+			return true
+		}
+	}
+	return false
+}
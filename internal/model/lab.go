@@ -0,0 +1,120 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// LabOrderStatus is how far the results of a lab order have come in
+type LabOrderStatus string
+
+// Lab order statuses
+const (
+	LabOrderOrdered  LabOrderStatus = "ordered"  // No results yet
+	LabOrderPartial  LabOrderStatus = "partial"  // Some ordered tests have results
+	LabOrderResulted LabOrderStatus = "resulted" // Every ordered test has a result
+)
+
+// LabFlag is the interpretation flag of a lab result, as in HL7 v2 OBX-8
+type LabFlag string
+
+// Lab result flags
+const (
+	LabFlagNormal       LabFlag = "N"
+	LabFlagLow          LabFlag = "L"
+	LabFlagHigh         LabFlag = "H"
+	LabFlagCriticalLow  LabFlag = "LL"
+	LabFlagCriticalHigh LabFlag = "HH"
+	LabFlagAbnormal     LabFlag = "A" // Abnormal non-numeric result, e.g. a positive culture
+)
+
+// IsValid reports whether the flag is known
+func (f LabFlag) IsValid() bool {
+	switch f {
+	case LabFlagNormal, LabFlagLow, LabFlagHigh, LabFlagCriticalLow, LabFlagCriticalHigh, LabFlagAbnormal:
+		return true
+	}
+	return false
+}
+
+// IsAbnormal reports whether the flag marks a result outside its reference range
+func (f LabFlag) IsAbnormal() bool {
+	return f != "" && f != LabFlagNormal
+}
+
+// LabTest is a test requested on a lab order, identified by its code, usually LOINC
+type LabTest struct {
+	Code string `json:"code"`
+	Name string `json:"name,omitempty"`
+}
+
+// LabOrder is a set of lab tests a doctor ordered for a patient
+type LabOrder struct {
+	ID         uint           `json:"-" gorm:"primaryKey"`
+	PublicID   string         `json:"id" gorm:"type:uuid;uniqueIndex;not null;default:gen_random_uuid()"`
+	PatientID  uint           `json:"-" gorm:"index;not null"`
+	Patient    Patient        `json:"-" gorm:"foreignKey:PatientID"`
+	DoctorID   uint           `json:"-" gorm:"index;not null"` // Ordering doctor, whose inbox abnormal results go to
+	Doctor     Doctor         `json:"-" gorm:"foreignKey:DoctorID"`
+	RecordID   *uint          `json:"-" gorm:"index"` // Medical record of the visit it was ordered at, if any
+	Tests      []LabTest      `json:"tests" gorm:"type:text;serializer:json"`
+	Notes      string         `json:"notes,omitempty" gorm:"type:text;serializer:encrypted"` // Clinical notes for the lab
+	Status     LabOrderStatus `json:"status" gorm:"size:20;not null;default:'ordered'"`
+	OrderedAt  time.Time      `json:"ordered_at" gorm:"not null"`
+	ResultedAt *time.Time     `json:"resulted_at,omitempty"` // When the latest results came in
+	Results    []LabResult    `json:"results,omitempty" gorm:"foreignKey:OrderID"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+}
+
+// TableName overrides the table name
+func (LabOrder) TableName() string {
+	return "lab_orders"
+}
+
+// BeforeCreate assigns the public ID
+func (o *LabOrder) BeforeCreate(tx *gorm.DB) error {
+	if o.PublicID == "" {
+		o.PublicID = NewPublicID()
+	}
+	return nil
+}
+
+// LabResult is the result of one test of a lab order as reported by the lab. A corrected result
+// replaces the earlier one for the same test.
+type LabResult struct {
+	ID             uint       `json:"-" gorm:"primaryKey"`
+	PublicID       string     `json:"id" gorm:"type:uuid;uniqueIndex;not null;default:gen_random_uuid()"`
+	OrderID        uint       `json:"-" gorm:"not null;uniqueIndex:idx_lab_result_test"`
+	Order          *LabOrder  `json:"-" gorm:"foreignKey:OrderID"`
+	PatientID      uint       `json:"-" gorm:"index;not null"`
+	TestCode       string     `json:"test_code" gorm:"size:50;not null;uniqueIndex:idx_lab_result_test"`
+	TestName       string     `json:"test_name" gorm:"size:200"`
+	Value          string     `json:"value" gorm:"size:100;not null"` // As reported; numeric or text such as "positive"
+	Unit           string     `json:"unit,omitempty" gorm:"size:30"`
+	ReferenceLow   *float64   `json:"reference_low,omitempty"`
+	ReferenceHigh  *float64   `json:"reference_high,omitempty"`
+	ReferenceRange string     `json:"reference_range,omitempty" gorm:"size:100"` // As reported
+	Flag           LabFlag    `json:"flag,omitempty" gorm:"size:2"`
+	Abnormal       bool       `json:"abnormal" gorm:"index"`
+	ObservedAt     time.Time  `json:"observed_at"` // When the specimen was taken
+	ReceivedAt     time.Time  `json:"received_at"`
+	ReviewedAt     *time.Time `json:"reviewed_at,omitempty"` // When a doctor reviewed an abnormal result
+	ReviewedByID   *uint      `json:"-"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// TableName overrides the table name
+func (LabResult) TableName() string {
+	return "lab_results"
+}
+
+// BeforeCreate assigns the public ID
+func (r *LabResult) BeforeCreate(tx *gorm.DB) error {
+	if r.PublicID == "" {
+		r.PublicID = NewPublicID()
+	}
+	return nil
+}
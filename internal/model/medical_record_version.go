@@ -0,0 +1,50 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MedicalRecordVersion is the content of a medical record as written or edited at one point.
+// Every write of the record adds a version, numbered from 1, and versions are never changed.
+type MedicalRecordVersion struct {
+	ID        uint      `json:"-" gorm:"primaryKey"`
+	RecordID  uint      `json:"-" gorm:"uniqueIndex:idx_medical_record_versions_record_version;not null"`
+	Version   int       `json:"version" gorm:"uniqueIndex:idx_medical_record_versions_record_version;not null"`
+	Diagnosis string    `json:"diagnosis" gorm:"type:text;serializer:encrypted"`
+	Notes     string    `json:"notes" gorm:"type:text"`
+	DoctorID  uint      `json:"-" gorm:"index;not null"` // Doctor who wrote this version
+	Doctor    Doctor    `json:"-" gorm:"foreignKey:DoctorID"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName overrides the table name
+func (MedicalRecordVersion) TableName() string {
+	return "medical_record_versions"
+}
+
+// MedicalRecordAddendum is a note added to a medical record after it was signed, when the record
+// itself can no longer be changed
+type MedicalRecordAddendum struct {
+	ID        uint      `json:"-" gorm:"primaryKey"`
+	PublicID  string    `json:"id" gorm:"type:uuid;uniqueIndex;not null;default:gen_random_uuid()"`
+	RecordID  uint      `json:"-" gorm:"index;not null"`
+	DoctorID  uint      `json:"-" gorm:"index;not null"` // Doctor who added the addendum
+	Doctor    Doctor    `json:"-" gorm:"foreignKey:DoctorID"`
+	Text      string    `json:"text" gorm:"type:text;not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName overrides the table name
+func (MedicalRecordAddendum) TableName() string {
+	return "medical_record_addenda"
+}
+
+// BeforeCreate assigns the public ID
+func (a *MedicalRecordAddendum) BeforeCreate(tx *gorm.DB) error {
+	if a.PublicID == "" {
+		a.PublicID = NewPublicID()
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Message is a secure message between a patient and a doctor. A message a patient sends to a
+// doctor on time off is routed to the doctor covering for them, and answered with an automatic
+// reply giving the absence period and the covering doctor.
+type Message struct {
+	ID            uint      `json:"-" gorm:"primaryKey"`
+	PublicID      string    `json:"id" gorm:"type:uuid;uniqueIndex;not null;default:gen_random_uuid()"`
+	PatientID     uint      `json:"-" gorm:"index;not null"`
+	Patient       Patient   `json:"-" gorm:"foreignKey:PatientID"`
+	DoctorID      uint      `json:"-" gorm:"index;not null"` // Doctor the patient is talking to; the covering doctor once routed
+	Doctor        Doctor    `json:"-" gorm:"foreignKey:DoctorID"`
+	AddressedToID *uint     `json:"-" gorm:"index"` // Doctor the patient wrote to, when the message was routed to a covering doctor
+	AddressedTo   *Doctor   `json:"-" gorm:"foreignKey:AddressedToID"`
+	SenderID      uint      `json:"-" gorm:"index;not null"` // User who wrote the message; the absent doctor's for automatic replies
+	FromPatient   bool      `json:"from_patient"`
+	AutoReply     bool      `json:"auto_reply" gorm:"default:false"`
+	Body          string    `json:"body" gorm:"type:text;serializer:encrypted"`
+	CreatedAt     time.Time `json:"created_at" gorm:"index"`
+}
+
+// TableName overrides the table name
+func (Message) TableName() string {
+	return "messages"
+}
+
+// BeforeCreate assigns the public ID
+func (m *Message) BeforeCreate(tx *gorm.DB) error {
+	if m.PublicID == "" {
+		m.PublicID = NewPublicID()
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package model
+
+import (
+	"time"
+)
+
+// Notification channels
+const (
+	ChannelEmail = "email"
+	ChannelSMS   = "sms"
+)
+
+// Notification kinds
+const (
+	NotificationAppointmentReminder = "appointment_reminder"
+	NotificationMarketing           = "marketing" // Promotional message, sent only with marketing consent
+)
+
+// NotificationStatus is the outcome of a notification attempt
+type NotificationStatus string
+
+const (
+	NotificationStatusSent    NotificationStatus = "sent"
+	NotificationStatusFailed  NotificationStatus = "failed"
+	NotificationStatusBounced NotificationStatus = "bounced" // Accepted, then reported undeliverable by the provider
+)
+
+// Notification logs one attempt to notify a user on one channel. A fallback attempt on the
+// other channel points at the attempt it replaces.
+type Notification struct {
+	ID            uint               `json:"id" gorm:"primaryKey"`
+	UserID        uint               `json:"-" gorm:"index;not null"`
+	AppointmentID *uint              `json:"-" gorm:"index"`
+	Kind          string             `json:"kind" gorm:"size:50;not null"`
+	Channel       string             `json:"channel" gorm:"size:10;not null"`
+	Status        NotificationStatus `json:"status" gorm:"size:20;not null"`
+	FallbackForID *uint              `json:"fallback_for_id"`
+	MessageID     string             `json:"-" gorm:"size:255;index"` // Email Message-ID, to pick up later bounces
+	Detail        string             `json:"detail" gorm:"type:text"`
+	CreatedAt     time.Time          `json:"created_at"`
+	UpdatedAt     time.Time          `json:"updated_at"`
+}
+
+// TableName overrides the table name
+func (Notification) TableName() string {
+	return "notifications"
+}
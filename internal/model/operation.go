@@ -0,0 +1,60 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Kinds of asynchronous operations
+const (
+	OperationClinicAnalytics = "clinic_analytics"
+	OperationSearchReindex   = "search_reindex"
+)
+
+// OperationStatus is the progress of an asynchronous operation
+type OperationStatus string
+
+const (
+	OperationStatusPending   OperationStatus = "pending"
+	OperationStatusRunning   OperationStatus = "running"
+	OperationStatusSucceeded OperationStatus = "succeeded"
+	OperationStatusFailed    OperationStatus = "failed"
+)
+
+// IsFinished reports whether the operation will not change any more
+func (s OperationStatus) IsFinished() bool {
+	return s == OperationStatusSucceeded || s == OperationStatusFailed
+}
+
+// Operation is a long-running request accepted by the API and carried out in the background.
+// The client polls it by its public ID until it has finished.
+type Operation struct {
+	ID           uint            `json:"-" gorm:"primaryKey"`
+	PublicID     string          `json:"id" gorm:"type:uuid;uniqueIndex;not null;default:gen_random_uuid()"`
+	Kind         string          `json:"kind" gorm:"size:50;not null"`
+	Status       OperationStatus `json:"status" gorm:"size:20;not null;index:idx_operations_due,priority:1"`
+	RequestedBy  uint            `json:"-" gorm:"index;not null"` // User who started the operation; only they can see it
+	Params       string          `json:"-" gorm:"type:text"`      // JSON
+	Result       string          `json:"-" gorm:"type:text"`      // JSON, set once succeeded
+	Error        string          `json:"error,omitempty" gorm:"size:500"`
+	Attempts     int             `json:"-" gorm:"default:0"`
+	ClaimedUntil *time.Time      `json:"-" gorm:"index:idx_operations_due,priority:2"` // A running operation whose claim ran out is picked up again
+	StartedAt    *time.Time      `json:"started_at,omitempty"`
+	CompletedAt  *time.Time      `json:"completed_at,omitempty"`
+	CreatedAt    time.Time       `json:"created_at"`
+	UpdatedAt    time.Time       `json:"updated_at"`
+}
+
+// TableName overrides the table name
+func (Operation) TableName() string {
+	return "operations"
+}
+
+// BeforeCreate assigns the public ID
+func (o *Operation) BeforeCreate(tx *gorm.DB) error {
+	if o.PublicID == "" {
+		o.PublicID = NewPublicID()
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package model
+
+import (
+	"time"
+)
+
+// BusinessHours are the opening hours of a clinic on one day of the week
+type BusinessHours struct {
+	DayOfWeek int    `json:"day_of_week"` // 0-6 for Sunday-Saturday
+	Open      string `json:"open"`        // Format: HH:MM, clinic local time
+	Close     string `json:"close"`       // Format: HH:MM, clinic local time
+}
+
+// Organization is a clinic with its own scheduling rules, branding and contact details
+type Organization struct {
+	ID                       uint                `json:"id" gorm:"primaryKey"`
+	Name                     string              `json:"name" gorm:"size:100;uniqueIndex;not null"`
+	BrandName                string              `json:"brand_name" gorm:"size:100"` // Shown in emails; falls back to Name
+	LogoURL                  string              `json:"logo_url" gorm:"size:255"`
+	PrimaryColor             string              `json:"primary_color" gorm:"size:7"`       // Hex color of headings in emails and letters
+	AccentColor              string              `json:"accent_color" gorm:"size:7"`        // Hex color of buttons and links in emails
+	EmailSenderName          string              `json:"email_sender_name" gorm:"size:100"` // Name emails are sent from
+	EmailReplyTo             string              `json:"email_reply_to" gorm:"size:100"`    // Address replies to emails go to
+	ContactEmail             string              `json:"contact_email" gorm:"size:100"`
+	ContactPhone             string              `json:"contact_phone" gorm:"size:20"`
+	Address                  string              `json:"address" gorm:"size:255"`
+	Directions               string              `json:"directions" gorm:"type:text"`           // How to find the clinic, parking and where to report on arrival
+	MapURL                   string              `json:"map_url" gorm:"size:512"`               // Link to the clinic on a map; reminders link to directions to Address when empty
+	Timezone                 string              `json:"timezone" gorm:"size:64;default:'UTC'"` // IANA name business hours are expressed in
+	BusinessHours            []BusinessHours     `json:"business_hours" gorm:"type:text;serializer:json"`
+	BookingWindowDays        int                 `json:"booking_window_days"`                                        // How far ahead appointments can be booked; 0 for no limit
+	MinBookingNotice         int                 `json:"min_booking_notice" gorm:"default:0"`                        // Minimum minutes between booking and appointment start
+	DefaultAppointmentLength int                 `json:"default_appointment_length" gorm:"default:30"`               // Appointment length in minutes
+	IntakeRequirements       []IntakeRequirement `json:"intake_requirements" gorm:"type:text;serializer:json"`       // Items required before an appointment can be confirmed
+	MaxReschedules           int                 `json:"max_reschedules" gorm:"default:3"`                           // Times one appointment can be rescheduled
+	CancellationCutoff       int                 `json:"cancellation_cutoff" gorm:"default:60"`                      // Minutes before the start after which cancelling is late
+	LateCancellationFee      bool                `json:"late_cancellation_fee" gorm:"default:false"`                 // Late cancellations are accepted and flagged for a fee instead of refused
+	AppointmentMetadataKeys  []string            `json:"appointment_metadata_keys" gorm:"type:text;serializer:json"` // Keys staff may set in the metadata of the clinic's appointments
+	CreatedAt                time.Time           `json:"created_at"`
+	UpdatedAt                time.Time           `json:"updated_at"`
+}
+
+// TableName overrides the table name
+func (Organization) TableName() string {
+	return "organizations"
+}
+
+// DisplayName returns the name shown to patients
+func (o *Organization) DisplayName() string {
+	if o.BrandName != "" {
+		return o.BrandName
+	}
+	return o.Name
+}
+
+// AppointmentLength returns the default appointment length
+func (o *Organization) AppointmentLength() time.Duration {
+	if o.DefaultAppointmentLength <= 0 {
+		return 30 * time.Minute
+	}
+	return time.Duration(o.DefaultAppointmentLength) * time.Minute
+}
+
+// RescheduleLimit returns how many times one appointment can be rescheduled
+func (o *Organization) RescheduleLimit() int {
+	if o.MaxReschedules <= 0 {
+		return 3
+	}
+	return o.MaxReschedules
+}
+
+// CancellationCutoffMinutes returns how many minutes before the start an appointment can be
+// cancelled on time
+func (o *Organization) CancellationCutoffMinutes() int {
+	if o.CancellationCutoff <= 0 {
+		return 60
+	}
+	return o.CancellationCutoff
+}
+
+// DefaultOrganization returns the settings used when no clinic has been configured
+func DefaultOrganization() *Organization {
+	return &Organization{
+		Name:                     "EHASS",
+		Timezone:                 "UTC",
+		DefaultAppointmentLength: 30,
+		MaxReschedules:           3,
+		CancellationCutoff:       60,
+	}
+}
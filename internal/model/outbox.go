@@ -0,0 +1,96 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Types of domain events written to the outbox
+const (
+	EventAppointmentBooked      = "appointment.booked"
+	EventAppointmentConfirmed   = "appointment.confirmed"
+	EventAppointmentRescheduled = "appointment.rescheduled"
+	EventAppointmentUpdated     = "appointment.updated"
+	EventAppointmentCancelled   = "appointment.cancelled"
+	EventAppointmentDeclined    = "appointment.declined"   // Pending booking turned down by the doctor
+	EventAppointmentCheckedIn   = "appointment.checked_in" // Patient arrived at the clinic
+	EventAppointmentCompleted   = "appointment.completed"
+	EventAppointmentNoShow      = "appointment.no_show" // Marked by the no-show job
+
+	// Changes to searchable records, delivered only to the search index
+	EventDoctorUpdated  = "doctor.updated"
+	EventDoctorDeleted  = "doctor.deleted"
+	EventPatientUpdated = "patient.updated"
+	EventPatientDeleted = "patient.deleted"
+	EventUserUpdated    = "user.updated" // Names, emails and phones are kept on users
+
+	// A doctor set or cleared their status
+	EventDoctorStatusChanged = "doctor.status_changed"
+
+	// A preventive care item came due; the patient is emailed with a suggestion to book
+	EventCareReminderDue = "care.reminder_due"
+
+	// An asynchronous operation succeeded or failed
+	EventOperationCompleted = "operation.completed"
+
+	// Authentication events crossed an anomaly threshold, e.g. a credential-stuffing pattern
+	EventSecurityAlert = "security.alert"
+)
+
+// OutboxDestination is where the dispatcher delivers an outbox event
+type OutboxDestination string
+
+const (
+	OutboxDestinationEvents      OutboxDestination = "events"       // The configured event publisher
+	OutboxDestinationEmail       OutboxDestination = "email"        // An email to the patient
+	OutboxDestinationDoctorEmail OutboxDestination = "doctor_email" // An email to the doctor
+	OutboxDestinationSearch      OutboxDestination = "search"       // The search index, when a search backend is enabled
+)
+
+// OutboxStatus represents the delivery status of an outbox event
+type OutboxStatus string
+
+const (
+	OutboxStatusPending    OutboxStatus = "pending"
+	OutboxStatusDispatched OutboxStatus = "dispatched"
+	OutboxStatusFailed     OutboxStatus = "failed" // Gave up after the maximum number of attempts
+)
+
+// OutboxEvent is a domain event stored in the same transaction as the change it describes and
+// delivered afterwards by the outbox dispatcher, so a crash between the two cannot lose it.
+// Each destination gets its own row and is retried independently.
+type OutboxEvent struct {
+	ID            uint              `json:"-" gorm:"primaryKey"`
+	EventID       string            `json:"id" gorm:"size:36;index;not null"` // Shared by the rows of one event; receivers de-duplicate on it
+	Type          string            `json:"type" gorm:"size:100;not null"`
+	Destination   OutboxDestination `json:"destination" gorm:"size:20;not null"`
+	AggregateType string            `json:"aggregate_type" gorm:"size:50;not null"`
+	AggregateID   uint              `json:"-" gorm:"index;not null"`
+	Payload       string            `json:"payload" gorm:"type:text"` // JSON
+	Status        OutboxStatus      `json:"status" gorm:"size:20;index:idx_outbox_due,priority:1;default:'pending'"`
+	Attempts      int               `json:"attempts" gorm:"default:0"`
+	NextAttemptAt time.Time         `json:"next_attempt_at" gorm:"index:idx_outbox_due,priority:2"`
+	LastError     string            `json:"last_error,omitempty" gorm:"size:500"`
+	DispatchedAt  *time.Time        `json:"dispatched_at,omitempty"`
+	CreatedAt     time.Time         `json:"created_at"`
+}
+
+// TableName overrides the table name
+func (OutboxEvent) TableName() string {
+	return "outbox_events"
+}
+
+// BeforeCreate makes a new event due immediately
+func (e *OutboxEvent) BeforeCreate(tx *gorm.DB) error {
+	if e.EventID == "" {
+		e.EventID = NewPublicID()
+	}
+	if e.Status == "" {
+		e.Status = OutboxStatusPending
+	}
+	if e.NextAttemptAt.IsZero() {
+		e.NextAttemptAt = time.Now()
+	}
+	return nil
+}
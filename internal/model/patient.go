@@ -2,22 +2,27 @@ package model
 
 import (
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // Patient represents a patient in the system
 type Patient struct {
-	ID                uint      `json:"id" gorm:"primaryKey"`
-	UserID            uint      `json:"user_id" gorm:"uniqueIndex;not null"`
-	User              User      `json:"user" gorm:"foreignKey:UserID"`
-	DateOfBirth       time.Time `json:"date_of_birth"`
-	Gender            string    `json:"gender" gorm:"size:20"`
-	BloodGroup        string    `json:"blood_group" gorm:"size:10"`
-	EmergencyContact  string    `json:"emergency_contact" gorm:"size:100"`
-	MedicalHistory    string    `json:"medical_history" gorm:"type:text"`
-	Allergies         string    `json:"allergies" gorm:"type:text"`
-	CurrentMedication string    `json:"current_medication" gorm:"type:text"`
-	CreatedAt         time.Time `json:"created_at"`
-	UpdatedAt         time.Time `json:"updated_at"`
+	ID                    uint      `json:"-" gorm:"primaryKey"`
+	PublicID              string    `json:"id" gorm:"type:uuid;uniqueIndex;not null;default:gen_random_uuid()"`
+	UserID                uint      `json:"-" gorm:"uniqueIndex;not null"`
+	User                  User      `json:"user" gorm:"foreignKey:UserID"`
+	DateOfBirth           time.Time `json:"date_of_birth"`
+	Gender                string    `json:"gender" gorm:"size:20"`
+	BloodGroup            string    `json:"blood_group" gorm:"size:10"`
+	EmergencyContact      string    `json:"emergency_contact" gorm:"size:100"`
+	EmergencyPhone        string    `json:"emergency_phone" gorm:"size:20"`        // E.164 number of the emergency contact
+	EmergencyPhoneCountry string    `json:"emergency_phone_country" gorm:"size:2"` // ISO 3166-1 alpha-2 region of EmergencyPhone
+	MedicalHistory        string    `json:"medical_history" gorm:"type:text;serializer:encrypted"`
+	GuardianID            *uint     `json:"-" gorm:"index"`                // Patient whose account manages this one, e.g. a parent for a child
+	Allergies             []Allergy `json:"-" gorm:"foreignKey:PatientID"` // Loaded with the active allergies only, where shown alongside appointments and records
+	CreatedAt             time.Time `json:"created_at"`
+	UpdatedAt             time.Time `json:"updated_at"`
 }
 
 // TableName overrides the table name
@@ -25,22 +30,59 @@ func (Patient) TableName() string {
 	return "patients"
 }
 
+// BeforeCreate assigns the public ID
+func (p *Patient) BeforeCreate(tx *gorm.DB) error {
+	if p.PublicID == "" {
+		p.PublicID = NewPublicID()
+	}
+	return nil
+}
+
+// FamilyID identifies the family the patient belongs to: their guardian, or the patient
+// themselves when nobody manages their record
+func (p *Patient) FamilyID() uint {
+	if p.GuardianID != nil {
+		return *p.GuardianID
+	}
+	return p.ID
+}
+
 // MedicalRecord represents a patient's medical record
 type MedicalRecord struct {
-	ID           uint      `json:"id" gorm:"primaryKey"`
-	PatientID    uint      `json:"patient_id" gorm:"index;not null"`
-	Patient      Patient   `json:"-" gorm:"foreignKey:PatientID"`
-	DoctorID     uint      `json:"doctor_id" gorm:"index;not null"`
-	Doctor       Doctor    `json:"-" gorm:"foreignKey:DoctorID"`
-	Diagnosis    string    `json:"diagnosis" gorm:"type:text"`
-	Prescription string    `json:"prescription" gorm:"type:text"`
-	Notes        string    `json:"notes" gorm:"type:text"`
-	VisitDate    time.Time `json:"visit_date"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID           uint       `json:"-" gorm:"primaryKey"`
+	PublicID     string     `json:"id" gorm:"type:uuid;uniqueIndex;not null;default:gen_random_uuid()"`
+	PatientID    uint       `json:"patient_id" gorm:"index;not null"`
+	Patient      Patient    `json:"-" gorm:"foreignKey:PatientID"`
+	DoctorID     uint       `json:"doctor_id" gorm:"index;not null"`
+	Doctor       Doctor     `json:"-" gorm:"foreignKey:DoctorID"`
+	Diagnosis    string     `json:"diagnosis" gorm:"type:text;serializer:encrypted"`
+	Prescription string     `json:"prescription" gorm:"type:text;serializer:encrypted"` // Free text of records written before structured prescriptions; no longer set
+	Notes        string     `json:"notes" gorm:"type:text"`
+	VisitDate    time.Time  `json:"visit_date"`
+	Version      int        `json:"version" gorm:"not null;default:1"` // Number of the latest MedicalRecordVersion
+	SignedAt     *time.Time `json:"signed_at,omitempty"`               // Set when the author signs the record, which locks it
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+
+	// DiagnosisTokens indexes the words of the diagnosis for encounter search; it is written, never read
+	DiagnosisTokens BlindTokens `json:"-" gorm:"type:text[];->:false;<-"`
 }
 
 // TableName overrides the table name
 func (MedicalRecord) TableName() string {
 	return "medical_records"
 }
+
+// BeforeCreate assigns the public ID
+func (r *MedicalRecord) BeforeCreate(tx *gorm.DB) error {
+	if r.PublicID == "" {
+		r.PublicID = NewPublicID()
+	}
+	return nil
+}
+
+// BeforeSave indexes the diagnosis while it is still plaintext
+func (r *MedicalRecord) BeforeSave(tx *gorm.DB) error {
+	r.DiagnosisTokens = BlindTokens(r.Diagnosis)
+	return nil
+}
@@ -0,0 +1,122 @@
+package model
+
+import (
+	"time"
+)
+
+// Permission is a single action a user may be allowed to perform
+type Permission string
+
+const (
+	PermissionUsersRead           Permission = "users:read"
+	PermissionUsersManage         Permission = "users:manage"
+	PermissionDoctorsManage       Permission = "doctors:manage"
+	PermissionPatientsRead        Permission = "patients:read"
+	PermissionPatientsManage      Permission = "patients:manage"
+	PermissionAppointmentsRead    Permission = "appointments:read"
+	PermissionAppointmentsManage  Permission = "appointments:manage"
+	PermissionAppointmentsBook    Permission = "appointments:book"
+	PermissionSchedulesRead       Permission = "schedules:read"
+	PermissionMedicalRecordsRead  Permission = "medical_records:read"
+	PermissionMedicalRecordsWrite Permission = "medical_records:write"
+	PermissionBreakGlassRequest   Permission = "break_glass:request"
+	PermissionBreakGlassReview    Permission = "break_glass:review"
+	PermissionAuditLogsRead       Permission = "audit_logs:read"
+	PermissionRolesManage         Permission = "roles:manage"
+	PermissionOrganizationsManage Permission = "organizations:manage"
+	PermissionAnalyticsRead       Permission = "analytics:read"
+	PermissionEmailsManage        Permission = "emails:manage"
+	PermissionOperationsManage    Permission = "operations:manage"
+	PermissionBillingRead         Permission = "billing:read"
+	PermissionBillingManage       Permission = "billing:manage"
+	PermissionMarketingSend       Permission = "marketing:send"
+	PermissionSettingsManage      Permission = "settings:manage"
+	PermissionIntegrationsManage  Permission = "integrations:manage"
+)
+
+// AllPermissions lists every permission that can be granted
+var AllPermissions = []Permission{
+	PermissionUsersRead,
+	PermissionUsersManage,
+	PermissionDoctorsManage,
+	PermissionPatientsRead,
+	PermissionPatientsManage,
+	PermissionAppointmentsRead,
+	PermissionAppointmentsManage,
+	PermissionAppointmentsBook,
+	PermissionSchedulesRead,
+	PermissionMedicalRecordsRead,
+	PermissionMedicalRecordsWrite,
+	PermissionBreakGlassRequest,
+	PermissionBreakGlassReview,
+	PermissionAuditLogsRead,
+	PermissionRolesManage,
+	PermissionOrganizationsManage,
+	PermissionAnalyticsRead,
+	PermissionEmailsManage,
+	PermissionOperationsManage,
+	PermissionBillingRead,
+	PermissionBillingManage,
+	PermissionMarketingSend,
+	PermissionSettingsManage,
+	PermissionIntegrationsManage,
+}
+
+// RolePermissions holds the permissions granted by each built-in role
+var RolePermissions = map[Role][]Permission{
+	RolePatient: {},
+	RoleDoctor: {
+		PermissionPatientsRead,
+		PermissionAppointmentsRead,
+		PermissionAppointmentsManage,
+		PermissionSchedulesRead,
+		PermissionMedicalRecordsRead,
+		PermissionMedicalRecordsWrite,
+		PermissionBreakGlassRequest,
+	},
+	RoleAdmin: AllPermissions,
+}
+
+// IsValidPermission reports whether p is a known permission
+func IsValidPermission(p Permission) bool {
+	for _, known := range AllPermissions {
+		if p == known {
+			return true
+		}
+	}
+	return false
+}
+
+// IsBuiltInRole reports whether name is one of the built-in roles
+func IsBuiltInRole(name string) bool {
+	_, ok := RolePermissions[Role(name)]
+	return ok
+}
+
+// CustomRole is an administrator-defined role composed of permissions, such as
+// receptionist or billing clerk, granted to users on top of their built-in role
+type CustomRole struct {
+	ID          uint         `json:"id" gorm:"primaryKey"`
+	Name        string       `json:"name" gorm:"size:50;uniqueIndex;not null"`
+	Description string       `json:"description" gorm:"size:255"`
+	Permissions []Permission `json:"permissions" gorm:"type:text;serializer:json"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+}
+
+// TableName overrides the table name
+func (CustomRole) TableName() string {
+	return "custom_roles"
+}
+
+// UserCustomRole assigns a custom role to a user
+type UserCustomRole struct {
+	UserID       uint      `json:"user_id" gorm:"primaryKey"`
+	CustomRoleID uint      `json:"custom_role_id" gorm:"primaryKey;index"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// TableName overrides the table name
+func (UserCustomRole) TableName() string {
+	return "user_custom_roles"
+}
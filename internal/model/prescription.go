@@ -0,0 +1,69 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Medication is an entry of the medication catalog prescriptions are written from: one product
+// of a drug, such as amoxicillin 500 mg capsules
+type Medication struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Name      string    `json:"name" gorm:"size:150;not null;uniqueIndex:idx_medication_product"` // Generic drug name
+	Form      string    `json:"form" gorm:"size:50;not null;uniqueIndex:idx_medication_product"`  // e.g. tablet, capsule, oral suspension
+	Strength  string    `json:"strength" gorm:"size:50;not null;uniqueIndex:idx_medication_product"`
+	Route     string    `json:"route" gorm:"size:50"` // e.g. oral, topical, inhaled
+	Active    bool      `json:"active"`               // Retired products are kept for history but cannot be prescribed
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName overrides the table name
+func (Medication) TableName() string {
+	return "medications"
+}
+
+// Prescription is a medication a doctor prescribed to a patient. It runs for DurationDays from
+// when it was issued, and each refill adds another course.
+type Prescription struct {
+	ID           uint       `json:"-" gorm:"primaryKey"`
+	PublicID     string     `json:"id" gorm:"type:uuid;uniqueIndex;not null;default:gen_random_uuid()"`
+	PatientID    uint       `json:"-" gorm:"index;not null"`
+	Patient      Patient    `json:"-" gorm:"foreignKey:PatientID"`
+	DoctorID     uint       `json:"-" gorm:"index;not null"`
+	Doctor       Doctor     `json:"-" gorm:"foreignKey:DoctorID"`
+	RecordID     *uint      `json:"-" gorm:"index"` // Medical record of the visit it was prescribed at, if any
+	MedicationID uint       `json:"-" gorm:"index;not null"`
+	Medication   Medication `json:"medication" gorm:"foreignKey:MedicationID"`
+	Dosage       string     `json:"dosage" gorm:"size:100;not null"`    // Amount per dose, e.g. 1 tablet
+	Frequency    string     `json:"frequency" gorm:"size:100;not null"` // e.g. twice daily
+	DurationDays int        `json:"duration_days" gorm:"not null"`
+	Refills      int        `json:"refills" gorm:"not null;default:0"`
+	Instructions string     `json:"instructions" gorm:"type:text;serializer:encrypted"`
+	IssuedAt     time.Time  `json:"issued_at" gorm:"not null"`
+	EndsAt       time.Time  `json:"ends_at" gorm:"index;not null"` // End of the last course, refills included
+	CancelledAt  *time.Time `json:"cancelled_at,omitempty"`
+	CancelReason string     `json:"cancel_reason,omitempty" gorm:"size:255"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// TableName overrides the table name
+func (Prescription) TableName() string {
+	return "prescriptions"
+}
+
+// BeforeCreate assigns the public ID
+func (p *Prescription) BeforeCreate(tx *gorm.DB) error {
+	if p.PublicID == "" {
+		p.PublicID = NewPublicID()
+	}
+	return nil
+}
+
+// IsActive reports whether the prescription is still being taken at t: not cancelled and not
+// past the end of its last course
+func (p *Prescription) IsActive(t time.Time) bool {
+	return p.CancelledAt == nil && t.Before(p.EndsAt)
+}
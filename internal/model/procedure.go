@@ -0,0 +1,65 @@
+package model
+
+import (
+	"regexp"
+	"time"
+)
+
+// ProcedureCodeSystem is the coding system a procedure code belongs to
+type ProcedureCodeSystem string
+
+const (
+	ProcedureCodeCPT   ProcedureCodeSystem = "CPT"   // AMA Current Procedural Terminology, including category II and III codes
+	ProcedureCodeHCPCS ProcedureCodeSystem = "HCPCS" // HCPCS Level II codes for supplies, drugs and services outside CPT
+)
+
+var (
+	cptCodePattern   = regexp.MustCompile(`^[0-9]{4}[0-9FTU]$`)
+	hcpcsCodePattern = regexp.MustCompile(`^[A-V][0-9]{4}$`)
+)
+
+// IsValidCode reports whether code is well formed in the system
+func (s ProcedureCodeSystem) IsValidCode(code string) bool {
+	switch s {
+	case ProcedureCodeCPT:
+		return cptCodePattern.MatchString(code)
+	case ProcedureCodeHCPCS:
+		return hcpcsCodePattern.MatchString(code)
+	}
+	return false
+}
+
+// ProcedureCode is an entry of the clinic's procedure code catalog. CPT descriptions are
+// licensed, so the catalog ships empty and is imported by each deployment.
+type ProcedureCode struct {
+	Code        string              `json:"code" gorm:"primaryKey;size:5"` // CPT and HCPCS codes do not overlap
+	System      ProcedureCodeSystem `json:"system" gorm:"size:10;not null;index"`
+	Description string              `json:"description" gorm:"size:255;not null"`
+	Active      bool                `json:"active"` // Retired codes are kept for history but cannot be attached
+	CreatedAt   time.Time           `json:"created_at"`
+	UpdatedAt   time.Time           `json:"updated_at"`
+}
+
+// TableName overrides the table name
+func (ProcedureCode) TableName() string {
+	return "procedure_codes"
+}
+
+// AppointmentProcedure is a procedure performed during a completed appointment, one claim
+// line when billed
+type AppointmentProcedure struct {
+	ID            uint          `json:"id" gorm:"primaryKey"`
+	AppointmentID uint          `json:"-" gorm:"index;not null"`
+	Appointment   *Appointment  `json:"-" gorm:"foreignKey:AppointmentID"`
+	Code          string        `json:"code" gorm:"size:5;not null;index"`
+	ProcedureCode ProcedureCode `json:"procedure_code" gorm:"foreignKey:Code;references:Code"`
+	Modifiers     []string      `json:"modifiers" gorm:"type:text;serializer:json"` // Two-character billing modifiers, e.g. 25 or LT
+	Units         int           `json:"units" gorm:"not null;default:1"`
+	RecordedBy    uint          `json:"-" gorm:"not null"`
+	CreatedAt     time.Time     `json:"created_at"`
+}
+
+// TableName overrides the table name
+func (AppointmentProcedure) TableName() string {
+	return "appointment_procedures"
+}
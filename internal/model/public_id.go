@@ -0,0 +1,76 @@
+package model
+
+import (
+	"github.com/google/uuid"
+)
+
+// PublicResource is a resource addressed in the API by its public UUID. Numeric primary keys
+// stay internal so they cannot be enumerated or used to estimate volumes.
+type PublicResource string
+
+const (
+	ResourceUser         PublicResource = "users"
+	ResourceDoctor       PublicResource = "doctors"
+	ResourcePatient      PublicResource = "patients"
+	ResourceAppointment  PublicResource = "appointments"
+	ResourceSeries       PublicResource = "recurring_appointments"
+	ResourceReview       PublicResource = "doctor_reviews"
+	ResourceRecord       PublicResource = "medical_records"
+	ResourceAttachment   PublicResource = "medical_record_attachments"
+	ResourcePrescription PublicResource = "prescriptions"
+	ResourceVital        PublicResource = "vitals"
+	ResourceLabOrder     PublicResource = "lab_orders"
+	ResourceLabResult    PublicResource = "lab_results"
+	ResourceAllergy      PublicResource = "allergies"
+	ResourceMedication   PublicResource = "patient_medications"
+	ResourceAPIKey       PublicResource = "api_keys"
+)
+
+// Name returns the singular resource name used in error messages
+func (r PublicResource) Name() string {
+	switch r {
+	case ResourceUser:
+		return "user"
+	case ResourceDoctor:
+		return "doctor"
+	case ResourcePatient:
+		return "patient"
+	case ResourceAppointment:
+		return "appointment"
+	case ResourceSeries:
+		return "appointment series"
+	case ResourceReview:
+		return "review"
+	case ResourceRecord:
+		return "medical record"
+	case ResourceAttachment:
+		return "attachment"
+	case ResourcePrescription:
+		return "prescription"
+	case ResourceVital:
+		return "vital sign"
+	case ResourceLabOrder:
+		return "lab order"
+	case ResourceLabResult:
+		return "lab result"
+	case ResourceAllergy:
+		return "allergy"
+	case ResourceMedication:
+		return "medication list entry"
+	case ResourceAPIKey:
+		return "API key"
+	default:
+		return string(r)
+	}
+}
+
+// NewPublicID generates a random public identifier
+func NewPublicID() string {
+	return uuid.NewString()
+}
+
+// IsValidPublicID reports whether id is a well-formed public identifier
+func IsValidPublicID(id string) bool {
+	_, err := uuid.Parse(id)
+	return err == nil && len(id) == 36
+}
@@ -0,0 +1,105 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RecurrenceFrequency is how often the appointments of a series repeat
+type RecurrenceFrequency string
+
+const (
+	FrequencyWeekly   RecurrenceFrequency = "weekly"
+	FrequencyBiweekly RecurrenceFrequency = "biweekly"
+	FrequencyMonthly  RecurrenceFrequency = "monthly"
+)
+
+// IsValid reports whether f is a supported frequency
+func (f RecurrenceFrequency) IsValid() bool {
+	switch f {
+	case FrequencyWeekly, FrequencyBiweekly, FrequencyMonthly:
+		return true
+	}
+	return false
+}
+
+// RecurringAppointment is a series of appointments booked together, such as a course of
+// physiotherapy. Each occurrence is an ordinary appointment pointing back at the series, so it
+// can be moved or cancelled on its own.
+type RecurringAppointment struct {
+	ID                uint                `json:"-" gorm:"primaryKey"`
+	PublicID          string              `json:"id" gorm:"type:uuid;uniqueIndex;not null;default:gen_random_uuid()"`
+	PatientID         uint                `json:"-" gorm:"index;not null"`
+	Patient           Patient             `json:"-" gorm:"foreignKey:PatientID"`
+	DoctorID          uint                `json:"-" gorm:"index;not null"`
+	Doctor            Doctor              `json:"-" gorm:"foreignKey:DoctorID"`
+	AppointmentTypeID *uint               `json:"appointment_type_id"`
+	AppointmentType   *AppointmentType    `json:"appointment_type,omitempty" gorm:"foreignKey:AppointmentTypeID"`
+	Frequency         RecurrenceFrequency `json:"frequency" gorm:"size:20;not null"`
+	Occurrences       int                 `json:"occurrences" gorm:"not null"` // Number of appointments booked
+	Reason            string              `json:"reason" gorm:"size:255"`
+	CancelledAt       *time.Time          `json:"cancelled_at,omitempty"`
+	Appointments      []*Appointment      `json:"appointments,omitempty" gorm:"foreignKey:SeriesID"`
+	CreatedAt         time.Time           `json:"created_at"`
+	UpdatedAt         time.Time           `json:"updated_at"`
+}
+
+// TableName overrides the table name
+func (RecurringAppointment) TableName() string {
+	return "recurring_appointments"
+}
+
+// BeforeCreate assigns the public ID
+func (r *RecurringAppointment) BeforeCreate(tx *gorm.DB) error {
+	if r.PublicID == "" {
+		r.PublicID = NewPublicID()
+	}
+	return nil
+}
+
+// SeriesExceptionKind is how an occurrence was changed apart from its series
+type SeriesExceptionKind string
+
+const (
+	SeriesExceptionMoved     SeriesExceptionKind = "moved"     // Moved to another time
+	SeriesExceptionEdited    SeriesExceptionKind = "edited"    // Reason or status changed
+	SeriesExceptionCancelled SeriesExceptionKind = "cancelled" // Cancelled on its own
+)
+
+// RecurringAppointmentException records that one occurrence of a series was changed on its own.
+// Changes made to the whole series leave excepted occurrences as they are, so an occurrence moved
+// to suit the patient is not moved back by a later series update.
+type RecurringAppointmentException struct {
+	ID            uint                `json:"-" gorm:"primaryKey"`
+	AppointmentID uint                `json:"-" gorm:"uniqueIndex;not null"`
+	Kind          SeriesExceptionKind `json:"kind" gorm:"size:20;not null"`
+	OriginalStart time.Time           `json:"original_start"` // Start the series gave the occurrence before it was first changed
+	CreatedAt     time.Time           `json:"created_at"`
+	UpdatedAt     time.Time           `json:"updated_at"`
+}
+
+// TableName overrides the table name
+func (RecurringAppointmentException) TableName() string {
+	return "recurring_appointment_exceptions"
+}
+
+// OccurrenceStart returns the start of the nth occurrence, counting from 0, of a series whose
+// first occurrence starts at first. Dates are counted in first's location, so occurrences keep
+// their local time across daylight saving changes. Monthly occurrences fall on the last day of
+// shorter months.
+func (f RecurrenceFrequency) OccurrenceStart(first time.Time, n int) time.Time {
+	switch f {
+	case FrequencyBiweekly:
+		return first.AddDate(0, 0, 14*n)
+	case FrequencyMonthly:
+		year, month, day := first.Date()
+		lastDay := time.Date(year, month+time.Month(n)+1, 0, 0, 0, 0, 0, first.Location()).Day()
+		if day > lastDay {
+			day = lastDay
+		}
+		return time.Date(year, month+time.Month(n), day, first.Hour(), first.Minute(), first.Second(), 0, first.Location())
+	default:
+		return first.AddDate(0, 0, 7*n)
+	}
+}
@@ -0,0 +1,38 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DoctorReview is a patient's rating of a doctor after a completed visit. Each appointment can
+// be reviewed once, and the doctor may answer the review.
+type DoctorReview struct {
+	ID            uint       `json:"-" gorm:"primaryKey"`
+	PublicID      string     `json:"id" gorm:"type:uuid;uniqueIndex;not null;default:gen_random_uuid()"`
+	DoctorID      uint       `json:"-" gorm:"index;not null"`
+	Doctor        Doctor     `json:"-" gorm:"foreignKey:DoctorID"`
+	PatientID     uint       `json:"-" gorm:"index;not null"`
+	Patient       Patient    `json:"-" gorm:"foreignKey:PatientID"`
+	AppointmentID uint       `json:"-" gorm:"uniqueIndex;not null"` // Visit the review is about
+	Rating        int        `json:"rating" gorm:"type:smallint;not null"`
+	Comment       string     `json:"comment" gorm:"type:text"`
+	Response      string     `json:"response,omitempty" gorm:"type:text"` // Doctor's answer
+	RespondedAt   *time.Time `json:"responded_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// TableName overrides the table name
+func (DoctorReview) TableName() string {
+	return "doctor_reviews"
+}
+
+// BeforeCreate assigns the public ID
+func (r *DoctorReview) BeforeCreate(tx *gorm.DB) error {
+	if r.PublicID == "" {
+		r.PublicID = NewPublicID()
+	}
+	return nil
+}
@@ -0,0 +1,19 @@
+package model
+
+import (
+	"time"
+)
+
+// RuntimeSetting overrides the configured default of an operational setting, such as maintenance
+// mode or a rate limit, without a restart. Value is JSON.
+type RuntimeSetting struct {
+	Key       string    `json:"key" gorm:"primaryKey;size:100"`
+	Value     string    `json:"value" gorm:"type:text;not null"`
+	UpdatedBy uint      `json:"-"` // User who last changed the setting
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName overrides the table name
+func (RuntimeSetting) TableName() string {
+	return "runtime_settings"
+}
@@ -0,0 +1,35 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/whitewalker-sa/ehass/pkg/encryption"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SearchConfig is the Postgres text search configuration documents and queries are parsed with
+const SearchConfig = "english"
+
+// BlindTokens is text written to a text[] column as the blind index tokens of its words, so a
+// column encrypted at rest can be searched by word. The tokens are keyed hashes and hold nothing
+// of the text itself.
+type BlindTokens string
+
+// GormValue converts the text to its tokens with the registered blind index
+func (t BlindTokens) GormValue(_ context.Context, db *gorm.DB) clause.Expr {
+	index := encryption.DefaultIndex()
+	if index == nil {
+		_ = db.AddError(errors.New("no blind index registered"))
+		return clause.Expr{SQL: "NULL"}
+	}
+	return clause.Expr{SQL: "CAST(? AS text[])", Vars: []interface{}{TextArray(index.Tokens(string(t)))}}
+}
+
+// TextArray formats values as a Postgres text array literal. Values must not contain quotes,
+// commas or braces, as blind index tokens do not.
+func TextArray(values []string) string {
+	return "{" + strings.Join(values, ",") + "}"
+}
@@ -0,0 +1,31 @@
+package model
+
+import (
+	"time"
+)
+
+// Kinds of security alert raised from authentication events
+const (
+	SecurityAlertFailedLoginsIP     = "failed_logins_ip"    // Many failed logins from one address
+	SecurityAlertCredentialStuffing = "credential_stuffing" // Failed logins to many accounts from one address
+	SecurityAlertBruteForce         = "brute_force"         // Many failed logins to one account
+	SecurityAlertPasswordResets     = "password_resets_ip"  // Many password reset requests from one address
+	SecurityAlertTwoFactorFailures  = "two_factor_failures" // Many wrong 2FA codes for one account
+)
+
+// SecurityAlert records authentication events crossing an anomaly threshold. The same kind of
+// alert is raised for a subject at most once per detection window.
+type SecurityAlert struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	Kind        string    `json:"kind" gorm:"size:50;index:idx_security_alert_subject;not null"`
+	Subject     string    `json:"subject" gorm:"size:100;index:idx_security_alert_subject;not null"` // IP address, or the user's public ID
+	Count       int64     `json:"count"`                                                             // Events, or accounts for credential stuffing, in the window
+	Threshold   int64     `json:"threshold"`
+	WindowStart time.Time `json:"window_start"`
+	CreatedAt   time.Time `json:"created_at" gorm:"index"`
+}
+
+// TableName overrides the table name
+func (SecurityAlert) TableName() string {
+	return "security_alerts"
+}
@@ -0,0 +1,15 @@
+package model
+
+import (
+	"time"
+)
+
+// SlotHold reserves an appointment slot for a patient while they complete a booking. Holds are
+// short-lived and kept in the hold store rather than the database.
+type SlotHold struct {
+	Token     string    `json:"token"`
+	DoctorID  uint      `json:"doctor_id"`
+	PatientID uint      `json:"patient_id"`
+	Start     time.Time `json:"start"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
@@ -0,0 +1,52 @@
+package model
+
+import (
+	"time"
+)
+
+// Waiting room event types, sent to clients as they happen
+const (
+	WaitingRoomJoined         = "participant_joined" // A patient entered the waiting room
+	WaitingRoomLeft           = "participant_left"   // A patient left the waiting room or the visit
+	WaitingRoomDoctorNotified = "doctor_notified"    // The doctor was told the first patient is waiting
+	WaitingRoomAdmitted       = "admitted"           // The doctor let a waiting patient into the visit
+	WaitingRoomVisitEnded     = "visit_ended"        // The doctor ended the visit
+)
+
+// TelehealthVisit records the waiting room of a video appointment, with the times used to report
+// how long patients waited and how long visits lasted
+type TelehealthVisit struct {
+	ID               uint        `json:"-" gorm:"primaryKey"`
+	AppointmentID    uint        `json:"-" gorm:"uniqueIndex;not null"`
+	Appointment      Appointment `json:"-" gorm:"foreignKey:AppointmentID"`
+	DoctorID         uint        `json:"-" gorm:"index;not null"`
+	WaitingSince     *time.Time  `json:"waiting_since,omitempty" gorm:"index"` // When the first patient joined
+	DoctorNotifiedAt *time.Time  `json:"doctor_notified_at,omitempty"`
+	AdmittedAt       *time.Time  `json:"admitted_at,omitempty"` // When the doctor first admitted patients
+	EndedAt          *time.Time  `json:"ended_at,omitempty"`
+	CreatedAt        time.Time   `json:"created_at"`
+	UpdatedAt        time.Time   `json:"updated_at"`
+}
+
+// TableName overrides the table name
+func (TelehealthVisit) TableName() string {
+	return "telehealth_visits"
+}
+
+// WaitingRoomEvent is a change in a video appointment's waiting room. Events are numbered in the
+// order they happened, so clients can resume a stream after the last one they saw.
+type WaitingRoomEvent struct {
+	ID            uint        `json:"id" gorm:"primaryKey"`
+	AppointmentID uint        `json:"-" gorm:"index;not null"`
+	Appointment   Appointment `json:"-" gorm:"foreignKey:AppointmentID"`
+	DoctorID      uint        `json:"-" gorm:"index;not null"`
+	Type          string      `json:"type" gorm:"size:30;not null"`
+	UserID        uint        `json:"-"` // Patient the event is about, or the doctor for visit-wide events
+	User          User        `json:"-" gorm:"foreignKey:UserID"`
+	CreatedAt     time.Time   `json:"created_at"`
+}
+
+// TableName overrides the table name
+func (WaitingRoomEvent) TableName() string {
+	return "waiting_room_events"
+}
@@ -0,0 +1,28 @@
+package model
+
+import (
+	"time"
+)
+
+// Kinds of translated content
+const (
+	TranslationDoctorBio     = "doctor_bio" // Subject is the doctor's public ID
+	TranslationSpecialtyName = "specialty"  // Subject is the specialty as stored on doctors
+)
+
+// Translation holds doctor content in another language. The original text stays on the doctor;
+// responses use a translation when the client prefers its locale.
+type Translation struct {
+	ID        uint      `json:"-" gorm:"primaryKey"`
+	Kind      string    `json:"-" gorm:"size:20;not null;uniqueIndex:idx_translations_subject_locale"`
+	Subject   string    `json:"-" gorm:"size:100;not null;uniqueIndex:idx_translations_subject_locale"`
+	Locale    string    `json:"locale" gorm:"size:10;not null;uniqueIndex:idx_translations_subject_locale"` // Language tag, e.g. fr or fr-CA
+	Text      string    `json:"text" gorm:"type:text;not null"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName overrides the table name
+func (Translation) TableName() string {
+	return "translations"
+}
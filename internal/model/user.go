@@ -1,7 +1,10 @@
 package model
 
 import (
+	"strings"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // Role represents user roles in the system
@@ -24,23 +27,31 @@ const (
 
 // User represents a user in the system
 type User struct {
-	ID            uint         `json:"id" gorm:"primaryKey"`
-	Name          string       `json:"name" gorm:"size:100;not null"`
-	Email         string       `json:"email" gorm:"size:100;uniqueIndex;not null"`
-	EmailVerified bool         `json:"emailVerified" gorm:"default:false"`
-	PasswordHash  string       `json:"-" gorm:"size:255"`
-	Role          Role         `json:"role" gorm:"size:20;not null"`
-	Phone         string       `json:"phone" gorm:"size:20"`
-	Address       string       `json:"address" gorm:"size:255"`
-	Provider      AuthProvider `json:"provider" gorm:"size:20;default:'local'"`
-	ProviderID    string       `json:"providerId" gorm:"size:100"`
-	RefreshToken  string       `json:"-" gorm:"size:255"`
-	Avatar        string       `json:"avatar" gorm:"size:255"`
-	TwoFactorAuth bool         `json:"twoFactorAuth" gorm:"default:false"`
-	Secret2FA     string       `json:"-" gorm:"size:100"`
-	LastLogin     *time.Time   `json:"lastLogin"`
-	CreatedAt     time.Time    `json:"created_at"`
-	UpdatedAt     time.Time    `json:"updated_at"`
+	ID                uint         `json:"-" gorm:"primaryKey"`
+	PublicID          string       `json:"id" gorm:"type:uuid;uniqueIndex;not null;default:gen_random_uuid()"` // Exposed in the API instead of ID
+	Name              string       `json:"name" gorm:"size:100;not null"`
+	Email             string       `json:"email" gorm:"size:100;uniqueIndex;not null"`
+	CanonicalEmail    string       `json:"-" gorm:"size:100;index"` // Email without provider aliases, compared to find duplicate accounts
+	EmailVerified     bool         `json:"emailVerified" gorm:"default:false"`
+	PasswordHash      string       `json:"-" gorm:"size:255"`
+	Role              Role         `json:"role" gorm:"size:20;not null"`
+	Phone             string       `json:"phone" gorm:"size:20"`       // E.164, e.g. +27821234567
+	PhoneCountry      string       `json:"phoneCountry" gorm:"size:2"` // ISO 3166-1 alpha-2 region of the phone number
+	Address           string       `json:"address" gorm:"size:255"`
+	Provider          AuthProvider `json:"provider" gorm:"size:20;default:'local'"`
+	ProviderID        string       `json:"providerId" gorm:"size:100"`
+	RefreshTokenHash  string       `json:"-" gorm:"column:refresh_token;type:text"` // SHA-256 of the current refresh token
+	Avatar            string       `json:"avatar" gorm:"size:255"`
+	Timezone          string       `json:"timezone" gorm:"size:64;default:'UTC'"`           // IANA name used to display times
+	Locale            string       `json:"locale" gorm:"size:10;default:'en-US'"`           // Language tag used to format dates
+	PreferredChannel  string       `json:"preferredChannel" gorm:"size:10;default:'email'"` // Channel tried first for reminders
+	TwoFactorAuth     bool         `json:"twoFactorAuth" gorm:"default:false"`
+	Secret2FA         string       `json:"-" gorm:"type:text;serializer:encrypted"`
+	TokenVersion      int          `json:"-" gorm:"default:0"`                     // Bumped to revoke all issued tokens
+	CalendarTokenHash string       `json:"-" gorm:"column:calendar_token;size:64"` // SHA-256 of the calendar feed token; empty when no feed is issued
+	LastLogin         *time.Time   `json:"lastLogin"`
+	CreatedAt         time.Time    `json:"created_at"`
+	UpdatedAt         time.Time    `json:"updated_at"`
 }
 
 // TableName overrides the table name
@@ -48,21 +59,68 @@ func (User) TableName() string {
 	return "users"
 }
 
+// BeforeCreate assigns the public ID
+func (u *User) BeforeCreate(tx *gorm.DB) error {
+	if u.PublicID == "" {
+		u.PublicID = NewPublicID()
+	}
+	return nil
+}
+
+// BeforeSave keeps the canonical email in step with the email
+func (u *User) BeforeSave(tx *gorm.DB) error {
+	u.CanonicalEmail = CanonicalEmail(u.Email)
+	return nil
+}
+
+// HasLogin reports whether the user can sign in. Patient records created by clinic staff have no
+// login until the patient claims them.
+func (u *User) HasLogin() bool {
+	return u.PasswordHash != "" || u.ProviderID != ""
+}
+
+// NormalizeEmail trims an email address and lowercases it, the form emails are stored in
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// CanonicalEmail returns the normalized email without the aliases its provider delivers to the
+// same mailbox, so the aliases of one address compare equal. Gmail ignores dots and anything
+// after a plus in the local part and treats googlemail.com as gmail.com.
+func CanonicalEmail(email string) string {
+	email = NormalizeEmail(email)
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return email
+	}
+	local, domain := email[:at], email[at+1:]
+	if domain != "gmail.com" && domain != "googlemail.com" {
+		return email
+	}
+	if plus := strings.Index(local, "+"); plus >= 0 {
+		local = local[:plus]
+	}
+	return strings.ReplaceAll(local, ".", "") + "@gmail.com"
+}
+
 // SanitizeUser removes sensitive data from user for response
 func SanitizeUser(user User) map[string]interface{} {
 	return map[string]interface{}{
-		"id":            user.ID,
-		"name":          user.Name,
-		"email":         user.Email,
-		"emailVerified": user.EmailVerified,
-		"role":          user.Role,
-		"phone":         user.Phone,
-		"address":       user.Address,
-		"provider":      user.Provider,
-		"avatar":        user.Avatar,
-		"twoFactorAuth": user.TwoFactorAuth,
-		"lastLogin":     user.LastLogin,
-		"created_at":    user.CreatedAt,
-		"updated_at":    user.UpdatedAt,
+		"id":               user.PublicID,
+		"name":             user.Name,
+		"email":            user.Email,
+		"emailVerified":    user.EmailVerified,
+		"role":             user.Role,
+		"phone":            user.Phone,
+		"address":          user.Address,
+		"provider":         user.Provider,
+		"avatar":           user.Avatar,
+		"timezone":         user.Timezone,
+		"locale":           user.Locale,
+		"preferredChannel": user.PreferredChannel,
+		"twoFactorAuth":    user.TwoFactorAuth,
+		"lastLogin":        user.LastLogin,
+		"created_at":       user.CreatedAt,
+		"updated_at":       user.UpdatedAt,
 	}
 }
@@ -10,15 +10,18 @@ type TokenType string
 const (
 	TokenTypeEmailVerification TokenType = "email_verification"
 	TokenTypePasswordReset     TokenType = "password_reset"
+	TokenTypeAccountClaim      TokenType = "account_claim"
+	TokenTypePortalInvite      TokenType = "portal_invite" // Signed setup link sent with an account claim invitation
 )
 
 // VerificationToken represents tokens for email verification and password reset
 type VerificationToken struct {
 	ID        uint      `json:"id" gorm:"primaryKey"`
 	UserID    uint      `json:"userId" gorm:"not null"`
-	Token     string    `json:"token" gorm:"size:255;uniqueIndex;not null"`
+	TokenHash string    `json:"-" gorm:"column:token;size:255;uniqueIndex;not null"` // SHA-256 of the token sent to the user
 	Type      TokenType `json:"type" gorm:"size:50;not null"`
 	ExpiresAt time.Time `json:"expiresAt" gorm:"not null"`
+	Attempts  int       `json:"-" gorm:"default:0"` // Failed guesses, for short codes that are checked per user
 	CreatedAt time.Time `json:"createdAt"`
 	User      User      `json:"-" gorm:"foreignKey:UserID"`
 }
@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// VisitReason is an entry of the administrator-managed list of reasons patients book for, such
+// as a sore throat or a skin check. Each reason is seen by doctors of one specialty and sizes
+// the appointment.
+type VisitReason struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Name      string    `json:"name" gorm:"size:100;not null"`
+	Specialty string    `json:"specialty" gorm:"size:100;not null;index"` // Specialty of the doctors patients are routed to
+	Duration  int       `json:"duration" gorm:"not null"`                 // Appointment length in minutes
+	Active    bool      `json:"active"`                                   // Retired reasons are kept for history but cannot be booked
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName overrides the table name
+func (VisitReason) TableName() string {
+	return "visit_reasons"
+}
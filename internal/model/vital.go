@@ -0,0 +1,72 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// VitalType is the kind of measurement a vital sign reading is
+type VitalType string
+
+// Vital sign types. Values are stored in the unit of VitalUnits.
+const (
+	VitalBloodPressure VitalType = "blood_pressure" // Value is systolic, Diastolic diastolic
+	VitalHeartRate     VitalType = "heart_rate"
+	VitalWeight        VitalType = "weight"
+	VitalGlucose       VitalType = "glucose"
+	VitalTemperature   VitalType = "temperature"
+)
+
+// VitalUnits is the unit each vital sign type is stored in
+var VitalUnits = map[VitalType]string{
+	VitalBloodPressure: "mmHg",
+	VitalHeartRate:     "bpm",
+	VitalWeight:        "kg",
+	VitalGlucose:       "mg/dL",
+	VitalTemperature:   "C",
+}
+
+// IsValid reports whether the vital sign type is known
+func (t VitalType) IsValid() bool {
+	_, ok := VitalUnits[t]
+	return ok
+}
+
+// VitalSource is who took a vital sign reading
+type VitalSource string
+
+// Vital sign sources
+const (
+	VitalSourceVisit        VitalSource = "visit"         // Measured by the doctor
+	VitalSourceSelfReported VitalSource = "self_reported" // Measured at home by the patient or their guardian
+)
+
+// Vital is one reading of a patient's vital sign
+type Vital struct {
+	ID           uint        `json:"-" gorm:"primaryKey"`
+	PublicID     string      `json:"id" gorm:"type:uuid;uniqueIndex;not null;default:gen_random_uuid()"`
+	PatientID    uint        `json:"-" gorm:"not null;index:idx_vital_series,priority:1"`
+	Type         VitalType   `json:"type" gorm:"size:30;not null;index:idx_vital_series,priority:2"`
+	Value        float64     `json:"value" gorm:"not null"`
+	Diastolic    *float64    `json:"diastolic,omitempty"` // Blood pressure only
+	MeasuredAt   time.Time   `json:"measured_at" gorm:"not null;index:idx_vital_series,priority:3"`
+	Source       VitalSource `json:"source" gorm:"size:20;not null"`
+	RecordID     *uint       `json:"-" gorm:"index"` // Medical record of the visit it was measured at, if any
+	RecordedByID uint        `json:"-" gorm:"index;not null"`
+	Note         string      `json:"note,omitempty" gorm:"type:text;serializer:encrypted"`
+	CreatedAt    time.Time   `json:"created_at"`
+}
+
+// TableName overrides the table name
+func (Vital) TableName() string {
+	return "vitals"
+}
+
+// BeforeCreate assigns the public ID
+func (v *Vital) BeforeCreate(tx *gorm.DB) error {
+	if v.PublicID == "" {
+		v.PublicID = NewPublicID()
+	}
+	return nil
+}
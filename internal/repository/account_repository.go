@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"gorm.io/gorm"
+)
+
+type accountRepository struct {
+	db *gorm.DB
+}
+
+// NewAccountRepository creates a new patient account repository
+func NewAccountRepository(db *gorm.DB) AccountRepository {
+	return &accountRepository{
+		db: db,
+	}
+}
+
+// CreateEntry posts a payment or credit to a patient's account
+func (r *accountRepository) CreateEntry(ctx context.Context, entry *model.AccountEntry) error {
+	return r.db.WithContext(ctx).Create(entry).Error
+}
+
+// FindEntries lists the payments and credits posted to a patient's account before a time,
+// oldest first
+func (r *accountRepository) FindEntries(ctx context.Context, patientID uint, before time.Time) ([]*model.AccountEntry, error) {
+	var entries []*model.AccountEntry
+	err := r.db.WithContext(ctx).
+		Where("patient_id = ? AND posted_at < ?", patientID, before).
+		Order("posted_at, id").
+		Find(&entries).Error
+	return entries, err
+}
+
+// FindCharges lists a patient's completed appointments of a priced type scheduled before a time,
+// oldest first, with their type and doctor
+func (r *accountRepository) FindCharges(ctx context.Context, patientID uint, before time.Time) ([]*model.Appointment, error) {
+	var appointments []*model.Appointment
+	err := r.db.WithContext(ctx).
+		Joins("JOIN appointment_types ON appointment_types.id = appointments.appointment_type_id").
+		Where("appointments.patient_id = ? AND appointments.status = ? AND appointments.scheduled_start < ?",
+			patientID, model.AppointmentStatusCompleted, before).
+		Where("appointment_types.price_cents > 0").
+		Preload("AppointmentType").
+		Preload("Doctor.User").
+		Order("appointments.scheduled_start, appointments.id").
+		Find(&appointments).Error
+	return appointments, err
+}
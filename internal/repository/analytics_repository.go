@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AnalyticsScope selects the appointments that belong to a clinic
+type AnalyticsScope struct {
+	OrganizationID    uint
+	IncludeUnassigned bool // Also count doctors not assigned to any clinic, as the default clinic does
+}
+
+// RevenueEvent is the price of a completed appointment
+type RevenueEvent struct {
+	At         time.Time
+	Currency   string
+	PriceCents int64
+}
+
+type analyticsRepository struct {
+	db *gorm.DB
+}
+
+// NewAnalyticsRepository creates a new analytics repository
+func NewAnalyticsRepository(db *gorm.DB) AnalyticsRepository {
+	return &analyticsRepository{
+		db: db,
+	}
+}
+
+// clinicAppointments starts a query over the appointments of the clinic's doctors
+func (r *analyticsRepository) clinicAppointments(ctx context.Context, scope AnalyticsScope) *gorm.DB {
+	return r.db.WithContext(ctx).
+		Table("appointments").
+		Joins("JOIN doctors ON doctors.id = appointments.doctor_id").
+		Where("doctors.organization_id = ? OR (? AND doctors.organization_id IS NULL)", scope.OrganizationID, scope.IncludeUnassigned)
+}
+
+// FindBookingTimes finds when the clinic's appointments booked in [start, end) were made
+func (r *analyticsRepository) FindBookingTimes(ctx context.Context, scope AnalyticsScope, start, end time.Time) ([]time.Time, error) {
+	var times []time.Time
+	err := r.clinicAppointments(ctx, scope).
+		Where("appointments.created_at >= ? AND appointments.created_at < ?", start, end).
+		Pluck("appointments.created_at", &times).Error
+	return times, err
+}
+
+// FindCancellationTimes finds when the clinic's appointments cancelled in [start, end) were cancelled.
+// Appointments cancelled before cancellation times were recorded fall back to their last update.
+func (r *analyticsRepository) FindCancellationTimes(ctx context.Context, scope AnalyticsScope, start, end time.Time) ([]time.Time, error) {
+	var times []time.Time
+	err := r.clinicAppointments(ctx, scope).
+		Where("appointments.status = ?", model.AppointmentStatusCancelled).
+		Where("COALESCE(appointments.cancelled_at, appointments.updated_at) >= ? AND COALESCE(appointments.cancelled_at, appointments.updated_at) < ?", start, end).
+		Pluck("COALESCE(appointments.cancelled_at, appointments.updated_at)", &times).Error
+	return times, err
+}
+
+// FindRevenue finds the prices of the clinic's completed appointments scheduled in [start, end)
+func (r *analyticsRepository) FindRevenue(ctx context.Context, scope AnalyticsScope, start, end time.Time) ([]RevenueEvent, error) {
+	var events []RevenueEvent
+	err := r.clinicAppointments(ctx, scope).
+		Joins("JOIN appointment_types ON appointment_types.id = appointments.appointment_type_id").
+		Where("appointments.status = ?", model.AppointmentStatusCompleted).
+		Where("appointments.scheduled_start >= ? AND appointments.scheduled_start < ?", start, end).
+		Where("appointment_types.price_cents > 0").
+		Select("appointments.scheduled_start AS at, appointment_types.currency AS currency, appointment_types.price_cents AS price_cents").
+		Scan(&events).Error
+	return events, err
+}
+
+// FindFirstBookingTimes finds when patients whose first booking with the clinic was made in [start, end) booked it
+func (r *analyticsRepository) FindFirstBookingTimes(ctx context.Context, scope AnalyticsScope, start, end time.Time) ([]time.Time, error) {
+	var times []time.Time
+	err := r.clinicAppointments(ctx, scope).
+		Group("appointments.patient_id").
+		Having("MIN(appointments.created_at) >= ? AND MIN(appointments.created_at) < ?", start, end).
+		Pluck("MIN(appointments.created_at)", &times).Error
+	return times, err
+}
+
+// VisitTiming is when a visit was scheduled and when it actually took place
+type VisitTiming struct {
+	DoctorID          string // Public ID
+	DoctorName        string
+	AppointmentTypeID *uint
+	AppointmentType   string
+	ScheduledStart    time.Time
+	ScheduledEnd      time.Time
+	CheckedInAt       *time.Time
+	StartedAt         time.Time
+	EndedAt           time.Time
+}
+
+// FindVisitTimings finds the timings of the clinic's visits scheduled in [start, end) that
+// started and ended
+func (r *analyticsRepository) FindVisitTimings(ctx context.Context, scope AnalyticsScope, start, end time.Time) ([]VisitTiming, error) {
+	var timings []VisitTiming
+	err := r.clinicAppointments(ctx, scope).
+		Joins("JOIN users ON users.id = doctors.user_id").
+		Joins("LEFT JOIN appointment_types ON appointment_types.id = appointments.appointment_type_id").
+		Where("appointments.scheduled_start >= ? AND appointments.scheduled_start < ?", start, end).
+		Where("appointments.started_at IS NOT NULL AND appointments.ended_at > appointments.started_at").
+		Select("doctors.public_id AS doctor_id, users.name AS doctor_name, " +
+			"appointments.appointment_type_id AS appointment_type_id, COALESCE(appointment_types.name, '') AS appointment_type, " +
+			"appointments.scheduled_start, appointments.scheduled_end, appointments.checked_in_at, " +
+			"appointments.started_at, appointments.ended_at").
+		Order("appointments.scheduled_start").
+		Scan(&timings).Error
+	return timings, err
+}
+
+// FindBuckets finds the stored buckets of a clinic starting in [start, end)
+func (r *analyticsRepository) FindBuckets(ctx context.Context, orgID uint, granularity model.AnalyticsGranularity, start, end time.Time) ([]*model.AnalyticsBucket, error) {
+	var buckets []*model.AnalyticsBucket
+	if err := r.db.WithContext(ctx).
+		Where("organization_id = ? AND granularity = ? AND bucket_start >= ? AND bucket_start < ?", orgID, granularity, start, end).
+		Order("bucket_start").
+		Find(&buckets).Error; err != nil {
+		return nil, err
+	}
+	return buckets, nil
+}
+
+// SaveBuckets stores buckets, replacing any previously stored for the same clinic and time
+func (r *analyticsRepository) SaveBuckets(ctx context.Context, buckets []*model.AnalyticsBucket) error {
+	if len(buckets) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "organization_id"}, {Name: "granularity"}, {Name: "bucket_start"}},
+			DoUpdates: clause.AssignmentColumns([]string{"bucket_end", "bookings", "cancellations", "new_patients", "revenue", "created_at"}),
+		}).
+		Create(&buckets).Error
+}
@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"gorm.io/gorm"
+)
+
+type apiKeyRepository struct {
+	db *gorm.DB
+}
+
+// NewAPIKeyRepository creates a new API key repository
+func NewAPIKeyRepository(db *gorm.DB) APIKeyRepository {
+	return &apiKeyRepository{
+		db: db,
+	}
+}
+
+// Create stores an API key
+func (r *apiKeyRepository) Create(ctx context.Context, key *model.APIKey) error {
+	return r.db.WithContext(ctx).Omit("User").Create(key).Error
+}
+
+// FindByID finds an API key by ID with its user
+func (r *apiKeyRepository) FindByID(ctx context.Context, id uint) (*model.APIKey, error) {
+	var key model.APIKey
+	if err := r.db.WithContext(ctx).Preload("User").First(&key, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("API key not found")
+		}
+		return nil, err
+	}
+	return &key, nil
+}
+
+// FindByHash finds an API key that has not been revoked by the hash of the key, with its user
+func (r *apiKeyRepository) FindByHash(ctx context.Context, hash string) (*model.APIKey, error) {
+	var key model.APIKey
+	err := r.db.WithContext(ctx).
+		Preload("User").
+		Where("key_hash = ? AND revoked_at IS NULL", hash).
+		First(&key).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("API key not found")
+		}
+		return nil, err
+	}
+	return &key, nil
+}
+
+// FindActive lists the API keys that have not been revoked with their users, those of userID
+// only unless it is 0, most recently issued first
+func (r *apiKeyRepository) FindActive(ctx context.Context, userID uint) ([]*model.APIKey, error) {
+	query := r.db.WithContext(ctx).Preload("User").Where("revoked_at IS NULL")
+	if userID != 0 {
+		query = query.Where("user_id = ?", userID)
+	}
+
+	var keys []*model.APIKey
+	err := query.Order("created_at DESC").Find(&keys).Error
+	return keys, err
+}
+
+// Update saves an API key
+func (r *apiKeyRepository) Update(ctx context.Context, key *model.APIKey) error {
+	return r.db.WithContext(ctx).Omit("User").Save(key).Error
+}
+
+// CountRequest counts a request made with an API key on day and returns the day's requests so
+// far that were not refused, this one included
+func (r *apiKeyRepository) CountRequest(ctx context.Context, keyID uint, day time.Time) (int64, error) {
+	var served int64
+	err := r.db.WithContext(ctx).Raw(
+		`INSERT INTO api_key_usage (api_key_id, day, requests, rejected) VALUES (?, ?, 1, 0)
+		ON CONFLICT (api_key_id, day) DO UPDATE SET requests = api_key_usage.requests + 1
+		RETURNING requests - rejected`, keyID, day).Scan(&served).Error
+	return served, err
+}
+
+// CountRejected counts a refused request made with an API key on day. Requests refused before
+// they were counted, for the rate limit, are counted as requests as well.
+func (r *apiKeyRepository) CountRejected(ctx context.Context, keyID uint, day time.Time, counted bool) error {
+	if counted {
+		return r.db.WithContext(ctx).Exec(
+			"UPDATE api_key_usage SET rejected = rejected + 1 WHERE api_key_id = ? AND day = ?", keyID, day).Error
+	}
+	return r.db.WithContext(ctx).Exec(
+		`INSERT INTO api_key_usage (api_key_id, day, requests, rejected) VALUES (?, ?, 1, 1)
+		ON CONFLICT (api_key_id, day) DO UPDATE SET requests = api_key_usage.requests + 1, rejected = api_key_usage.rejected + 1`,
+		keyID, day).Error
+}
+
+// FindUsage lists the daily usage of the keys from the day of from to the day of to, both
+// included, by key and day
+func (r *apiKeyRepository) FindUsage(ctx context.Context, keyIDs []uint, from, to time.Time) ([]*model.APIKeyUsage, error) {
+	var usage []*model.APIKeyUsage
+	if len(keyIDs) == 0 {
+		return usage, nil
+	}
+	err := r.db.WithContext(ctx).
+		Where("api_key_id IN ? AND day BETWEEN ? AND ?", keyIDs, from, to).
+		Order("api_key_id, day").
+		Find(&usage).Error
+	return usage, err
+}
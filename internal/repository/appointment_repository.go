@@ -2,12 +2,20 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"time"
 
 	"github.com/whitewalker-sa/ehass/internal/model"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// ErrScheduleConflict is returned when an appointment would overlap another appointment of the
+// same doctor or patient
+var ErrScheduleConflict = errors.New("appointment time conflicts with an existing appointment")
+
 type appointmentRepository struct {
 	db *gorm.DB
 }
@@ -19,17 +27,122 @@ func NewAppointmentRepository(db *gorm.DB) AppointmentRepository {
 	}
 }
 
-// Create creates a new appointment
-func (r *appointmentRepository) Create(ctx context.Context, appointment *model.Appointment) error {
-	return r.db.WithContext(ctx).Create(appointment).Error
+// Create creates a new appointment and writes its outbox events in the same transaction. It
+// fails with ErrScheduleConflict if the doctor or patient is already booked at that time.
+func (r *appointmentRepository) Create(ctx context.Context, appointment *model.Appointment, events ...*model.OutboxEvent) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkScheduleConflicts(tx, appointment); err != nil {
+			return err
+		}
+		if err := tx.Create(appointment).Error; err != nil {
+			return err
+		}
+		return createOutboxEvents(tx, "appointment", appointment.ID, events)
+	})
+}
+
+// Reschedule saves an appointment moved to a new time together with the history row recording
+// the move, failing with ErrScheduleConflict like Create does
+func (r *appointmentRepository) Reschedule(ctx context.Context, appointment *model.Appointment, history *model.AppointmentHistory, events ...*model.OutboxEvent) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkScheduleConflicts(tx, appointment); err != nil {
+			return err
+		}
+		if err := tx.Omit("SeriesException").Save(appointment).Error; err != nil {
+			return err
+		}
+		if err := saveSeriesException(tx, appointment); err != nil {
+			return err
+		}
+		history.AppointmentID = appointment.ID
+		if err := tx.Create(history).Error; err != nil {
+			return err
+		}
+		return createOutboxEvents(tx, "appointment", appointment.ID, events)
+	})
 }
 
-// FindByID finds an appointment by ID
+// FindHistory lists the reschedules of an appointment, oldest first
+func (r *appointmentRepository) FindHistory(ctx context.Context, appointmentID uint) ([]*model.AppointmentHistory, error) {
+	var history []*model.AppointmentHistory
+	err := r.db.WithContext(ctx).
+		Preload("ChangedBy").
+		Where("appointment_id = ?", appointmentID).
+		Order("created_at ASC, id ASC").
+		Find(&history).Error
+	return history, err
+}
+
+// checkScheduleConflicts looks for active appointments of the doctor or of any patient seen in
+// the appointment overlapping it. The doctor's appointments must also leave the doctor's buffer
+// time free between them, and a doctor who overbooks accepts up to their number of parallel
+// bookings before the time counts as taken. The doctor row is locked first and then the patient
+// rows in ID order, so concurrent bookings for any of them wait for this transaction instead of
+// both finding the time free.
+func checkScheduleConflicts(tx *gorm.DB, appointment *model.Appointment) error {
+	var doctor model.Doctor
+	if err := tx.Select("id", "buffer_minutes", "max_parallel").
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ?", appointment.DoctorID).
+		Take(&doctor).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	buffer := doctor.Buffer()
+	patientIDs := []uint{appointment.PatientID}
+	for _, participant := range appointment.Participants {
+		patientIDs = append(patientIDs, participant.PatientID)
+	}
+	var locked []uint
+	if err := tx.Model(&model.Patient{}).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id IN ?", patientIDs).
+		Order("id").
+		Pluck("id", &locked).Error; err != nil {
+		return err
+	}
+
+	var booked int64
+	if err := tx.Model(&model.Appointment{}).
+		Where("id <> ? AND status <> ?", appointment.ID, model.AppointmentStatusCancelled).
+		Where("doctor_id = ? AND scheduled_start < ? AND scheduled_end > ?",
+			appointment.DoctorID, appointment.ScheduledEnd.Add(buffer), appointment.ScheduledStart.Add(-buffer)).
+		Count(&booked).Error; err != nil {
+		return err
+	}
+	if booked >= int64(doctor.Capacity()) {
+		return fmt.Errorf("%w: the doctor is already booked at this time", ErrScheduleConflict)
+	}
+
+	var existing model.Appointment
+	err := tx.Select("id").
+		Where("id <> ? AND status <> ?", appointment.ID, model.AppointmentStatusCancelled).
+		Where("(patient_id IN ? OR id IN (SELECT appointment_id FROM appointment_participants WHERE patient_id IN ?)) AND scheduled_start < ? AND scheduled_end > ?",
+			patientIDs, patientIDs, appointment.ScheduledEnd, appointment.ScheduledStart).
+		Take(&existing).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(patientIDs) > 1 {
+		return fmt.Errorf("%w: one of the patients already has an appointment at this time", ErrScheduleConflict)
+	}
+	return fmt.Errorf("%w: the patient already has an appointment at this time", ErrScheduleConflict)
+}
+
+// FindByID finds an appointment by ID with the patient's active allergies
 func (r *appointmentRepository) FindByID(ctx context.Context, id uint) (*model.Appointment, error) {
 	var appointment model.Appointment
 	err := r.db.WithContext(ctx).
 		Preload("Patient.User").
+		Preload("Patient.Allergies", activeAllergies).
 		Preload("Doctor.User").
+		Preload("AppointmentType").
+		Preload("Series").
+		Preload("SeriesException").
+		Preload("Participants.Patient.User").
+		Preload("BookedBy").
 		Where("id = ?", id).
 		First(&appointment).Error
 
@@ -42,11 +155,236 @@ func (r *appointmentRepository) FindByID(ctx context.Context, id uint) (*model.A
 	return &appointment, nil
 }
 
+// FindByPublicIDs finds the appointments with the given public IDs. IDs that match no
+// appointment are left out of the result, which is in no particular order.
+func (r *appointmentRepository) FindByPublicIDs(ctx context.Context, publicIDs []string) ([]*model.Appointment, error) {
+	var appointments []*model.Appointment
+	err := r.db.WithContext(ctx).
+		Preload("Patient.User").
+		Preload("Doctor.User").
+		Preload("AppointmentType").
+		Preload("Series").
+		Preload("BookedBy").
+		Where("public_id IN ?", publicIDs).
+		Find(&appointments).Error
+	return appointments, err
+}
+
+// appointmentSortKeys maps the keys appointment lists can be sorted by to SQL expressions
+var appointmentSortKeys = map[string]string{
+	"scheduled_start": "appointments.scheduled_start",
+	"scheduled_end":   "appointments.scheduled_end",
+	"status":          "appointments.status",
+	"created_at":      "appointments.created_at",
+	"updated_at":      "appointments.updated_at",
+	"patient_name":    "(SELECT users.name FROM patients JOIN users ON users.id = patients.user_id WHERE patients.id = appointments.patient_id)",
+	"doctor_name":     "(SELECT users.name FROM doctors JOIN users ON users.id = doctors.user_id WHERE doctors.id = appointments.doctor_id)",
+}
+
+// AppointmentFilter narrows an appointment list. Empty fields do not filter, and a list matches
+// any of its values, except tags and metadata, which must all match.
+type AppointmentFilter struct {
+	Statuses           []model.AppointmentStatus
+	AppointmentTypeIDs []uint
+	Modalities         []model.AppointmentModality
+	DoctorIDs          []uint
+	PatientIDs         []uint
+	From               *time.Time        // Earliest scheduled start
+	To                 *time.Time        // Scheduled start before this time
+	PatientName        string            // Part of the patient's name, matched case-insensitively
+	Tags               []string          // Tags the appointment must all carry
+	Metadata           map[string]string // Metadata values the appointment must all have
+}
+
+// apply adds the filter's conditions to a query. Each one is served by an index: doctors and
+// statuses by their composite indexes with the scheduled start, patient names by the trigram
+// index on user names, tags and metadata by their GIN indexes.
+func (f AppointmentFilter) apply(query *gorm.DB) *gorm.DB {
+	if len(f.Statuses) > 0 {
+		query = query.Where("appointments.status IN ?", f.Statuses)
+	}
+	if len(f.AppointmentTypeIDs) > 0 {
+		query = query.Where("appointments.appointment_type_id IN ?", f.AppointmentTypeIDs)
+	}
+	if len(f.Modalities) > 0 {
+		query = query.Where("appointments.type IN ?", f.Modalities)
+	}
+	if len(f.DoctorIDs) > 0 {
+		query = query.Where("appointments.doctor_id IN ?", f.DoctorIDs)
+	}
+	if len(f.PatientIDs) > 0 {
+		query = query.Where("appointments.patient_id IN ?", f.PatientIDs)
+	}
+	if f.From != nil {
+		query = query.Where("appointments.scheduled_start >= ?", *f.From)
+	}
+	if f.To != nil {
+		query = query.Where("appointments.scheduled_start < ?", *f.To)
+	}
+	if f.PatientName != "" {
+		query = query.Where("appointments.patient_id IN (SELECT patients.id FROM patients JOIN users ON users.id = patients.user_id WHERE users.name ILIKE ?)",
+			"%"+escapeLike(f.PatientName)+"%")
+	}
+	if len(f.Tags) > 0 {
+		tags, _ := json.Marshal(f.Tags)
+		query = query.Where("appointments.tags @> ?::jsonb", string(tags))
+	}
+	if len(f.Metadata) > 0 {
+		metadata, _ := json.Marshal(f.Metadata)
+		query = query.Where("appointments.metadata @> ?::jsonb", string(metadata))
+	}
+	return query
+}
+
+// Find finds the appointments matching filter with pagination, earliest first unless opts
+// sorts them otherwise
+func (r *appointmentRepository) Find(ctx context.Context, filter AppointmentFilter, limit, offset int, opts ListOptions) ([]*model.Appointment, int64, error) {
+	var appointments []*model.Appointment
+	var count int64
+
+	order, err := opts.orderBy("appointments", appointmentSortKeys, "appointments.scheduled_start ASC, appointments.id ASC")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if err := filter.apply(r.db.WithContext(ctx).Model(&model.Appointment{})).
+		Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if err := filter.apply(opts.apply(r.db.WithContext(ctx), "Patient.User", "Doctor.User", "AppointmentType")).
+		Order(order).
+		Limit(limit).
+		Offset(offset).
+		Find(&appointments).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return appointments, count, nil
+}
+
+// EncounterSearch is a full-text search over past encounters. Queries use web search syntax:
+// words must all match, "quoted words" match as a phrase and -word excludes a word. Diagnoses
+// are encrypted, so they are matched by the blind index tokens of the query's words instead.
+type EncounterSearch struct {
+	Query                   string
+	DoctorID                uint     // Only this doctor's appointments and records when non-zero
+	DiagnosisTokens         []string // Tokens a diagnosis must all contain; diagnoses are searched when set
+	ExcludedDiagnosisTokens []string // Tokens a diagnosis must not contain
+}
+
+// EncounterMatch is an appointment or a medical record matching an encounter search; exactly
+// one of them is set
+type EncounterMatch struct {
+	Appointment   *model.Appointment
+	MedicalRecord *model.MedicalRecord
+	Rank          float64
+}
+
+// encounterRow is a ranked match before its record is loaded
+type encounterRow struct {
+	Kind string
+	ID   uint
+	Rank float64
+}
+
+// SearchEncounters finds the appointments whose reason or notes, and optionally the medical
+// records whose diagnosis, match a search, best matches first and the most recent among equals.
+// Matches are found through the GIN indexes on the search vectors.
+func (r *appointmentRepository) SearchEncounters(ctx context.Context, search EncounterSearch, limit int) ([]EncounterMatch, error) {
+	tsquery := "websearch_to_tsquery('" + model.SearchConfig + "', @query)"
+	doctorScope := ""
+	if search.DoctorID != 0 {
+		doctorScope = " AND doctor_id = @doctor"
+	}
+
+	sql := "SELECT 'appointment' AS kind, id, ts_rank(search_vector, " + tsquery + ") AS rank, scheduled_start AS occurred_at" +
+		" FROM appointments WHERE search_vector @@ " + tsquery + doctorScope
+	if len(search.DiagnosisTokens) > 0 {
+		// Tokens carry no word frequencies to rank by, so a diagnosis holding every word ranks first
+		sql += " UNION ALL SELECT 'medical_record', id, 1, visit_date" +
+			" FROM medical_records WHERE diagnosis_tokens @> CAST(@tokens AS text[])" + doctorScope
+		if len(search.ExcludedDiagnosisTokens) > 0 {
+			sql += " AND NOT diagnosis_tokens && CAST(@excluded AS text[])"
+		}
+	}
+	sql += " ORDER BY rank DESC, occurred_at DESC LIMIT @limit"
+
+	var rows []encounterRow
+	if err := r.db.WithContext(ctx).Raw(sql, map[string]interface{}{
+		"query":    search.Query,
+		"doctor":   search.DoctorID,
+		"tokens":   model.TextArray(search.DiagnosisTokens),
+		"excluded": model.TextArray(search.ExcludedDiagnosisTokens),
+		"limit":    limit,
+	}).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	var appointmentIDs, recordIDs []uint
+	for _, row := range rows {
+		if row.Kind == "appointment" {
+			appointmentIDs = append(appointmentIDs, row.ID)
+		} else {
+			recordIDs = append(recordIDs, row.ID)
+		}
+	}
+
+	appointments := make(map[uint]*model.Appointment, len(appointmentIDs))
+	if len(appointmentIDs) > 0 {
+		var found []*model.Appointment
+		if err := r.db.WithContext(ctx).
+			Preload("Patient.User").
+			Preload("Doctor.User").
+			Where("id IN ?", appointmentIDs).
+			Find(&found).Error; err != nil {
+			return nil, err
+		}
+		for _, appointment := range found {
+			appointments[appointment.ID] = appointment
+		}
+	}
+	records := make(map[uint]*model.MedicalRecord, len(recordIDs))
+	if len(recordIDs) > 0 {
+		var found []*model.MedicalRecord
+		if err := r.db.WithContext(ctx).
+			Preload("Patient.User").
+			Preload("Doctor.User").
+			Where("id IN ?", recordIDs).
+			Find(&found).Error; err != nil {
+			return nil, err
+		}
+		for _, record := range found {
+			records[record.ID] = record
+		}
+	}
+
+	matches := make([]EncounterMatch, 0, len(rows))
+	for _, row := range rows {
+		match := EncounterMatch{Rank: row.Rank}
+		if row.Kind == "appointment" {
+			match.Appointment = appointments[row.ID]
+		} else {
+			match.MedicalRecord = records[row.ID]
+		}
+		// Records deleted since the search ran are skipped
+		if match.Appointment != nil || match.MedicalRecord != nil {
+			matches = append(matches, match)
+		}
+	}
+	return matches, nil
+}
+
 // FindByPatientID finds appointments by patient ID with pagination
-func (r *appointmentRepository) FindByPatientID(ctx context.Context, patientID uint, limit, offset int) ([]*model.Appointment, int64, error) {
+func (r *appointmentRepository) FindByPatientID(ctx context.Context, patientID uint, limit, offset int, opts ListOptions) ([]*model.Appointment, int64, error) {
 	var appointments []*model.Appointment
 	var count int64
 
+	order, err := opts.orderBy("appointments", appointmentSortKeys, "scheduled_start DESC")
+	if err != nil {
+		return nil, 0, err
+	}
+
 	// Count total records
 	if err := r.db.WithContext(ctx).
 		Model(&model.Appointment{}).
@@ -56,10 +394,9 @@ func (r *appointmentRepository) FindByPatientID(ctx context.Context, patientID u
 	}
 
 	// Get paginated results
-	if err := r.db.WithContext(ctx).
-		Preload("Doctor.User").
+	if err := opts.apply(r.db.WithContext(ctx), "Patient", "Doctor.User").
 		Where("patient_id = ?", patientID).
-		Order("scheduled_start DESC").
+		Order(order).
 		Limit(limit).
 		Offset(offset).
 		Find(&appointments).Error; err != nil {
@@ -70,10 +407,15 @@ func (r *appointmentRepository) FindByPatientID(ctx context.Context, patientID u
 }
 
 // FindByDoctorID finds appointments by doctor ID with pagination
-func (r *appointmentRepository) FindByDoctorID(ctx context.Context, doctorID uint, limit, offset int) ([]*model.Appointment, int64, error) {
+func (r *appointmentRepository) FindByDoctorID(ctx context.Context, doctorID uint, limit, offset int, opts ListOptions) ([]*model.Appointment, int64, error) {
 	var appointments []*model.Appointment
 	var count int64
 
+	order, err := opts.orderBy("appointments", appointmentSortKeys, "scheduled_start DESC")
+	if err != nil {
+		return nil, 0, err
+	}
+
 	// Count total records
 	if err := r.db.WithContext(ctx).
 		Model(&model.Appointment{}).
@@ -83,10 +425,9 @@ func (r *appointmentRepository) FindByDoctorID(ctx context.Context, doctorID uin
 	}
 
 	// Get paginated results
-	if err := r.db.WithContext(ctx).
-		Preload("Patient.User").
+	if err := opts.apply(r.db.WithContext(ctx), "Patient.User", "Doctor").
 		Where("doctor_id = ?", doctorID).
-		Order("scheduled_start DESC").
+		Order(order).
 		Limit(limit).
 		Offset(offset).
 		Find(&appointments).Error; err != nil {
@@ -119,6 +460,7 @@ func (r *appointmentRepository) FindByDateRange(ctx context.Context, doctorID ui
 	// Get paginated results with preloaded associations
 	queryPreloaded := r.db.WithContext(ctx).
 		Preload("Patient.User").
+		Preload("Doctor").
 		Where("doctor_id = ?", doctorID)
 
 	if start != "" {
@@ -140,12 +482,329 @@ func (r *appointmentRepository) FindByDateRange(ctx context.Context, doctorID ui
 	return appointments, count, nil
 }
 
-// Update updates an appointment
-func (r *appointmentRepository) Update(ctx context.Context, appointment *model.Appointment) error {
-	return r.db.WithContext(ctx).Save(appointment).Error
+// FindByDoctorBetween finds all appointments of a doctor starting in [start, end), ordered by start time
+func (r *appointmentRepository) FindByDoctorBetween(ctx context.Context, doctorID uint, start, end time.Time) ([]*model.Appointment, error) {
+	var appointments []*model.Appointment
+	if err := r.db.WithContext(ctx).
+		Preload("Patient.User").
+		Preload("AppointmentType").
+		Where("doctor_id = ? AND scheduled_start >= ? AND scheduled_start < ?", doctorID, start, end).
+		Order("scheduled_start ASC").
+		Find(&appointments).Error; err != nil {
+		return nil, err
+	}
+	return appointments, nil
+}
+
+// FindByDoctorsBetween finds the appointments of several doctors starting in [start, end), with
+// their patients, doctors and types, in start order
+func (r *appointmentRepository) FindByDoctorsBetween(ctx context.Context, doctorIDs []uint, start, end time.Time) ([]*model.Appointment, error) {
+	if len(doctorIDs) == 0 {
+		return nil, nil
+	}
+	var appointments []*model.Appointment
+	if err := r.db.WithContext(ctx).
+		Preload("Patient.User").
+		Preload("Doctor.User").
+		Preload("AppointmentType").
+		Where("doctor_id IN ? AND scheduled_start >= ? AND scheduled_start < ?", doctorIDs, start, end).
+		Order("scheduled_start ASC").
+		Find(&appointments).Error; err != nil {
+		return nil, err
+	}
+	return appointments, nil
+}
+
+// FindPatientHistory finds a patient's most recent appointments scheduled before the given time
+func (r *appointmentRepository) FindPatientHistory(ctx context.Context, patientID uint, before time.Time, limit int) ([]*model.Appointment, error) {
+	var appointments []*model.Appointment
+	if err := r.db.WithContext(ctx).
+		Where("patient_id = ? AND scheduled_start < ?", patientID, before).
+		Order("scheduled_start DESC").
+		Limit(limit).
+		Find(&appointments).Error; err != nil {
+		return nil, err
+	}
+	return appointments, nil
+}
+
+// FindUpcomingByDoctor finds a doctor's active appointments starting at or after from
+func (r *appointmentRepository) FindUpcomingByDoctor(ctx context.Context, doctorID uint, from time.Time) ([]*model.Appointment, error) {
+	var appointments []*model.Appointment
+	if err := r.db.WithContext(ctx).
+		Preload("Patient.User").
+		Preload("Doctor.User").
+		Where("doctor_id = ? AND scheduled_start >= ? AND status IN ?", doctorID, from,
+			[]model.AppointmentStatus{model.AppointmentStatusPending, model.AppointmentStatusConfirmed, model.AppointmentStatusCheckedIn}).
+		Order("scheduled_start ASC").
+		Find(&appointments).Error; err != nil {
+		return nil, err
+	}
+	return appointments, nil
+}
+
+// FindCheckedIn finds a doctor's appointments checked in since the given time and not yet
+// started, in the order the patients arrived, with the patients' active allergies
+func (r *appointmentRepository) FindCheckedIn(ctx context.Context, doctorID uint, since time.Time) ([]*model.Appointment, error) {
+	var appointments []*model.Appointment
+	if err := r.db.WithContext(ctx).
+		Preload("Patient.User").
+		Preload("Patient.Allergies", activeAllergies).
+		Preload("Doctor.User").
+		Preload("AppointmentType").
+		Preload("Participants.Patient.User").
+		Where("doctor_id = ? AND status = ? AND checked_in_at >= ? AND started_at IS NULL", doctorID, model.AppointmentStatusCheckedIn, since).
+		Order("checked_in_at ASC, id ASC").
+		Find(&appointments).Error; err != nil {
+		return nil, err
+	}
+	return appointments, nil
+}
+
+// FindDueReminders finds pending and confirmed appointments starting in [from, to) that have not been reminded
+func (r *appointmentRepository) FindDueReminders(ctx context.Context, from, to time.Time) ([]*model.Appointment, error) {
+	var appointments []*model.Appointment
+	if err := r.db.WithContext(ctx).
+		Preload("Patient.User").
+		Preload("Doctor.User").
+		Where("scheduled_start >= ? AND scheduled_start < ? AND reminder_sent_at IS NULL AND status IN ?", from, to,
+			[]model.AppointmentStatus{model.AppointmentStatusPending, model.AppointmentStatusConfirmed}).
+		Order("scheduled_start ASC").
+		Find(&appointments).Error; err != nil {
+		return nil, err
+	}
+	return appointments, nil
+}
+
+// CountDueReminders counts the appointments FindDueReminders would return
+func (r *appointmentRepository) CountDueReminders(ctx context.Context, from, to time.Time) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&model.Appointment{}).
+		Where("scheduled_start >= ? AND scheduled_start < ? AND reminder_sent_at IS NULL AND status IN ?", from, to,
+			[]model.AppointmentStatus{model.AppointmentStatusPending, model.AppointmentStatusConfirmed}).
+		Count(&count).Error
+	return count, err
+}
+
+// CountCreatedSince counts the appointments booked since the given time, whatever their status now
+func (r *appointmentRepository) CountCreatedSince(ctx context.Context, since time.Time) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&model.Appointment{}).
+		Where("created_at >= ?", since).
+		Count(&count).Error
+	return count, err
+}
+
+// StatusCount is the number of appointments in a status
+type StatusCount struct {
+	Status model.AppointmentStatus
+	Count  int64
+}
+
+// CountScheduledByStatus counts the appointments starting in [from, to) by status
+func (r *appointmentRepository) CountScheduledByStatus(ctx context.Context, from, to time.Time) ([]StatusCount, error) {
+	var counts []StatusCount
+	err := r.db.WithContext(ctx).
+		Model(&model.Appointment{}).
+		Select("status, COUNT(*) AS count").
+		Where("scheduled_start >= ? AND scheduled_start < ?", from, to).
+		Group("status").
+		Scan(&counts).Error
+	return counts, err
+}
+
+// FindOverdue finds up to limit pending and confirmed appointments that ended before the given
+// time, with their patients and doctors, oldest first
+func (r *appointmentRepository) FindOverdue(ctx context.Context, endedBefore time.Time, limit int) ([]*model.Appointment, error) {
+	var appointments []*model.Appointment
+	if err := r.db.WithContext(ctx).
+		Preload("Patient").
+		Preload("Doctor").
+		Where("scheduled_end < ? AND status IN ?", endedBefore,
+			[]model.AppointmentStatus{model.AppointmentStatusPending, model.AppointmentStatusConfirmed}).
+		Order("scheduled_end ASC").
+		Limit(limit).
+		Find(&appointments).Error; err != nil {
+		return nil, err
+	}
+	return appointments, nil
+}
+
+// CountPatientByStatus counts a patient's appointments starting before the given time by status
+func (r *appointmentRepository) CountPatientByStatus(ctx context.Context, patientID uint, before time.Time) ([]StatusCount, error) {
+	var counts []StatusCount
+	err := r.db.WithContext(ctx).
+		Model(&model.Appointment{}).
+		Select("status, COUNT(*) AS count").
+		Where("patient_id = ? AND scheduled_start < ?", patientID, before).
+		Group("status").
+		Scan(&counts).Error
+	return counts, err
+}
+
+// FindFailedReminders finds pending and confirmed appointments starting after the given time
+// whose reminder was attempted but never sent on any channel
+func (r *appointmentRepository) FindFailedReminders(ctx context.Context, after time.Time) ([]*model.Appointment, error) {
+	var appointments []*model.Appointment
+	if err := r.db.WithContext(ctx).
+		Preload("Patient.User").
+		Preload("Doctor.User").
+		Where("scheduled_start > ? AND reminder_sent_at IS NOT NULL AND status IN ?", after,
+			[]model.AppointmentStatus{model.AppointmentStatusPending, model.AppointmentStatusConfirmed}).
+		Where("NOT EXISTS (?)", r.db.Model(&model.Notification{}).
+			Select("1").
+			Where("notifications.appointment_id = appointments.id AND notifications.kind = ? AND notifications.status = ?",
+				model.NotificationAppointmentReminder, model.NotificationStatusSent)).
+		Order("scheduled_start ASC").
+		Find(&appointments).Error; err != nil {
+		return nil, err
+	}
+	return appointments, nil
+}
+
+// MarkReminderSent records when the reminder for an appointment was sent
+func (r *appointmentRepository) MarkReminderSent(ctx context.Context, id uint, at time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&model.Appointment{}).
+		Where("id = ?", id).
+		UpdateColumn("reminder_sent_at", at).Error
+}
+
+// FindUnconfirmedReminders finds pending and confirmed appointments starting after the given
+// time that were reminded before remindedBefore, have taken exactly step escalation steps and
+// whose patient has not confirmed they will attend
+func (r *appointmentRepository) FindUnconfirmedReminders(ctx context.Context, step int, remindedBefore, after time.Time) ([]*model.Appointment, error) {
+	var appointments []*model.Appointment
+	if err := r.db.WithContext(ctx).
+		Preload("Patient.User").
+		Preload("Doctor.User").
+		Where("scheduled_start > ? AND reminder_sent_at <= ? AND reminder_escalations = ? AND attendance_confirmed_at IS NULL AND status IN ?",
+			after, remindedBefore, step,
+			[]model.AppointmentStatus{model.AppointmentStatusPending, model.AppointmentStatusConfirmed}).
+		Order("scheduled_start ASC").
+		Find(&appointments).Error; err != nil {
+		return nil, err
+	}
+	return appointments, nil
+}
+
+// ClaimReminderEscalation records that escalation step of an appointment is being taken. It
+// reports false if another instance took the step first or the patient confirmed in the meantime.
+func (r *appointmentRepository) ClaimReminderEscalation(ctx context.Context, id uint, step int) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&model.Appointment{}).
+		Where("id = ? AND reminder_escalations = ? AND attendance_confirmed_at IS NULL", id, step).
+		UpdateColumn("reminder_escalations", step+1)
+	return result.RowsAffected > 0, result.Error
+}
+
+// FlagFollowUp flags an appointment for the front desk to follow up with the patient
+func (r *appointmentRepository) FlagFollowUp(ctx context.Context, id uint, at time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&model.Appointment{}).
+		Where("id = ?", id).
+		UpdateColumns(map[string]interface{}{"follow_up_required": true, "follow_up_flagged_at": at}).Error
+}
+
+// FindFollowUps finds the doctors' pending and confirmed appointments starting after the given
+// time that are flagged for follow-up, soonest first
+func (r *appointmentRepository) FindFollowUps(ctx context.Context, doctorIDs []uint, after time.Time) ([]*model.Appointment, error) {
+	if len(doctorIDs) == 0 {
+		return nil, nil
+	}
+	var appointments []*model.Appointment
+	if err := r.db.WithContext(ctx).
+		Preload("Patient.User").
+		Preload("Doctor.User").
+		Preload("AppointmentType").
+		Where("doctor_id IN ? AND follow_up_required AND scheduled_start > ? AND status IN ?", doctorIDs, after,
+			[]model.AppointmentStatus{model.AppointmentStatusPending, model.AppointmentStatusConfirmed}).
+		Order("scheduled_start ASC").
+		Find(&appointments).Error; err != nil {
+		return nil, err
+	}
+	return appointments, nil
+}
+
+// MarkVisitStarted records when the visit of an appointment started, keeping the earlier time if
+// it was already recorded
+func (r *appointmentRepository) MarkVisitStarted(ctx context.Context, id uint, at time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&model.Appointment{}).
+		Where("id = ? AND started_at IS NULL", id).
+		UpdateColumn("started_at", at).Error
+}
+
+// MarkVisitEnded records when the visit of an appointment ended, keeping the earlier time if it
+// was already recorded
+func (r *appointmentRepository) MarkVisitEnded(ctx context.Context, id uint, at time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&model.Appointment{}).
+		Where("id = ? AND ended_at IS NULL", id).
+		UpdateColumn("ended_at", at).Error
+}
+
+// Update updates an appointment and writes its outbox events in the same transaction
+func (r *appointmentRepository) Update(ctx context.Context, appointment *model.Appointment, events ...*model.OutboxEvent) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("SeriesException").Save(appointment).Error; err != nil {
+			return err
+		}
+		if err := saveSeriesException(tx, appointment); err != nil {
+			return err
+		}
+		return createOutboxEvents(tx, "appointment", appointment.ID, events)
+	})
+}
+
+// MarkNoShow marks a pending or confirmed appointment as a no-show along with its outbox events.
+// It reports false without writing anything if the appointment was completed or cancelled in the
+// meantime.
+func (r *appointmentRepository) MarkNoShow(ctx context.Context, appointment *model.Appointment, events ...*model.OutboxEvent) (bool, error) {
+	marked := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.Appointment{}).
+			Where("id = ? AND status IN ?", appointment.ID,
+				[]model.AppointmentStatus{model.AppointmentStatusPending, model.AppointmentStatusConfirmed}).
+			Updates(map[string]interface{}{"status": model.AppointmentStatusNoShow, "updated_at": time.Now()})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		marked = true
+		appointment.Status = model.AppointmentStatusNoShow
+		return createOutboxEvents(tx, "appointment", appointment.ID, events)
+	})
+	return marked, err
+}
+
+// CountConfirmationAttempt counts one more confirmation code entered for an appointment and
+// returns the count. The increment is atomic, so concurrent guesses are all counted.
+func (r *appointmentRepository) CountConfirmationAttempt(ctx context.Context, id uint) (int, error) {
+	var appointment model.Appointment
+	result := r.db.WithContext(ctx).Model(&appointment).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "confirmation_attempts"}}}).
+		Where("id = ?", id).
+		UpdateColumn("confirmation_attempts", gorm.Expr("confirmation_attempts + 1"))
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return 0, errors.New("appointment not found")
+	}
+	return appointment.ConfirmationAttempts, nil
 }
 
 // Delete soft deletes an appointment
 func (r *appointmentRepository) Delete(ctx context.Context, id uint) error {
-	return r.db.WithContext(ctx).Delete(&model.Appointment{}, id).Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("appointment_id = ?", id).Delete(&model.AppointmentParticipant{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.Appointment{}, id).Error
+	})
 }
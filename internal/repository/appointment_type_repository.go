@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"gorm.io/gorm"
+)
+
+type appointmentTypeRepository struct {
+	db *gorm.DB
+}
+
+// NewAppointmentTypeRepository creates a new appointment type repository
+func NewAppointmentTypeRepository(db *gorm.DB) AppointmentTypeRepository {
+	return &appointmentTypeRepository{
+		db: db,
+	}
+}
+
+// Create creates a new appointment type
+func (r *appointmentTypeRepository) Create(ctx context.Context, appointmentType *model.AppointmentType) error {
+	return r.db.WithContext(ctx).Create(appointmentType).Error
+}
+
+// FindByID finds an appointment type by ID
+func (r *appointmentTypeRepository) FindByID(ctx context.Context, id uint) (*model.AppointmentType, error) {
+	var appointmentType model.AppointmentType
+	if err := r.db.WithContext(ctx).First(&appointmentType, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("appointment type not found")
+		}
+		return nil, err
+	}
+	return &appointmentType, nil
+}
+
+// FindAll finds all appointment types, including inactive ones
+func (r *appointmentTypeRepository) FindAll(ctx context.Context) ([]*model.AppointmentType, error) {
+	var types []*model.AppointmentType
+	if err := r.db.WithContext(ctx).Order("name").Find(&types).Error; err != nil {
+		return nil, err
+	}
+	return types, nil
+}
+
+// FindActiveByOrganization finds the bookable appointment types of a clinic, including those offered by all clinics
+func (r *appointmentTypeRepository) FindActiveByOrganization(ctx context.Context, orgID uint) ([]*model.AppointmentType, error) {
+	var types []*model.AppointmentType
+	err := r.db.WithContext(ctx).
+		Where("active = ? AND (organization_id IS NULL OR organization_id = ?)", true, orgID).
+		Order("name").
+		Find(&types).Error
+	if err != nil {
+		return nil, err
+	}
+	return types, nil
+}
+
+// Update updates an appointment type
+func (r *appointmentTypeRepository) Update(ctx context.Context, appointmentType *model.AppointmentType) error {
+	return r.db.WithContext(ctx).Save(appointmentType).Error
+}
@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"gorm.io/gorm"
+)
+
+type auditLogRepository struct {
+	db *gorm.DB
+}
+
+// NewAuditLogRepository creates a new audit log repository
+func NewAuditLogRepository(db *gorm.DB) AuditLogRepository {
+	return &auditLogRepository{
+		db: db,
+	}
+}
+
+// Create creates a new audit log entry
+func (r *auditLogRepository) Create(ctx context.Context, log *model.AuditLog) error {
+	return r.db.WithContext(ctx).Create(log).Error
+}
+
+// FindByUserID finds audit logs by user ID with pagination
+func (r *auditLogRepository) FindByUserID(ctx context.Context, userID uint, limit, offset int) ([]*model.AuditLog, int64, error) {
+	var logs []*model.AuditLog
+	var count int64
+
+	// Count total records
+	if err := r.db.WithContext(ctx).
+		Model(&model.AuditLog{}).
+		Where("user_id = ?", userID).
+		Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// Get paginated results
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&logs).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return logs, count, nil
+}
+
+// FindByEntityTypeAndID finds audit logs for an entity with pagination
+func (r *auditLogRepository) FindByEntityTypeAndID(ctx context.Context, entityType string, entityID uint, limit, offset int) ([]*model.AuditLog, int64, error) {
+	var logs []*model.AuditLog
+	var count int64
+
+	// Count total records
+	if err := r.db.WithContext(ctx).
+		Model(&model.AuditLog{}).
+		Where("entity_type = ? AND entity_id = ?", entityType, entityID).
+		Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// Get paginated results
+	if err := r.db.WithContext(ctx).
+		Where("entity_type = ? AND entity_id = ?", entityType, entityID).
+		Order("created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&logs).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return logs, count, nil
+}
+
+// FindAfterID finds up to limit audit logs with an ID greater than afterID, in ID order
+func (r *auditLogRepository) FindAfterID(ctx context.Context, afterID uint, limit int) ([]*model.AuditLog, error) {
+	var logs []*model.AuditLog
+	if err := r.db.WithContext(ctx).
+		Where("id > ?", afterID).
+		Order("id ASC").
+		Limit(limit).
+		Find(&logs).Error; err != nil {
+		return nil, err
+	}
+	return logs, nil
+}
+
+// CountAfterID counts audit logs with an ID greater than afterID
+func (r *auditLogRepository) CountAfterID(ctx context.Context, afterID uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&model.AuditLog{}).Where("id > ?", afterID).Count(&count).Error
+	return count, err
+}
+
+// AuthEventQuery selects audit log entries to count by client IP address or by user
+type AuthEventQuery struct {
+	Actions  []string
+	Since    time.Time
+	ByUser   bool  // Group by user rather than by IP address; entries without a user are left out
+	MinCount int64 // Groups with fewer entries are left out
+	MinUsers int64 // Groups with fewer distinct users are left out
+	Limit    int
+}
+
+// AuthEventGroup counts audit log entries for one IP address or user
+type AuthEventGroup struct {
+	Key   string // IP address, or the user's public ID
+	Email string // The user's email, when grouped by user
+	Count int64
+	Users int64 // Distinct known users, when grouped by IP address
+}
+
+// CountByAction counts audit log entries created since the given time for each of actions
+func (r *auditLogRepository) CountByAction(ctx context.Context, actions []string, since time.Time) (map[string]int64, error) {
+	var rows []struct {
+		Action string
+		Count  int64
+	}
+	if err := r.db.WithContext(ctx).
+		Model(&model.AuditLog{}).
+		Select("action, COUNT(*) AS count").
+		Where("action IN ? AND created_at >= ?", actions, since).
+		Group("action").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(actions))
+	for _, row := range rows {
+		counts[row.Action] = row.Count
+	}
+	return counts, nil
+}
+
+// GroupAuthEvents counts matching audit log entries by IP address or user, busiest first
+func (r *auditLogRepository) GroupAuthEvents(ctx context.Context, query AuthEventQuery) ([]*AuthEventGroup, error) {
+	db := r.db.WithContext(ctx).
+		Model(&model.AuditLog{}).
+		Where("audit_logs.action IN ? AND audit_logs.created_at >= ?", query.Actions, query.Since)
+
+	if query.ByUser {
+		db = db.Select("users.public_id AS key, users.email AS email, COUNT(*) AS count").
+			Joins("JOIN users ON users.id = audit_logs.user_id").
+			Group("users.public_id, users.email")
+	} else {
+		db = db.Select("audit_logs.ip AS key, COUNT(*) AS count, COUNT(DISTINCT NULLIF(audit_logs.user_id, 0)) AS users").
+			Where("audit_logs.ip <> ''").
+			Group("audit_logs.ip")
+	}
+	if query.MinCount > 0 {
+		db = db.Having("COUNT(*) >= ?", query.MinCount)
+	}
+	if query.MinUsers > 0 {
+		db = db.Having("COUNT(DISTINCT NULLIF(audit_logs.user_id, 0)) >= ?", query.MinUsers)
+	}
+	if query.Limit > 0 {
+		db = db.Limit(query.Limit)
+	}
+
+	var groups []*AuthEventGroup
+	if err := db.Order("count DESC").Scan(&groups).Error; err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
@@ -10,9 +10,11 @@ import (
 type AuthRepository interface {
 	RegisterUser(ctx context.Context, user *model.User) error
 	FindUserByEmail(ctx context.Context, email string) (*model.User, error)
+	FindUserByCanonicalEmail(ctx context.Context, email string) (*model.User, error)
+	FindUserByPhone(ctx context.Context, phone string) (*model.User, error)
 	FindUserByProviderID(ctx context.Context, provider model.AuthProvider, providerID string) (*model.User, error)
 	FindByID(ctx context.Context, id uint) (*model.User, error)
-	UpdateUser(ctx context.Context, user *model.User) error
+	UpdateUser(ctx context.Context, user *model.User, events ...*model.OutboxEvent) error
 	VerifyEmail(ctx context.Context, userID uint) error
 
 	// OAuth related
@@ -21,8 +23,11 @@ type AuthRepository interface {
 
 	// Token management
 	CreateVerificationToken(ctx context.Context, token *model.VerificationToken) error
-	FindVerificationToken(ctx context.Context, token string, tokenType model.TokenType) (*model.VerificationToken, error)
+	FindVerificationToken(ctx context.Context, tokenHash string, tokenType model.TokenType) (*model.VerificationToken, error)
 	DeleteVerificationToken(ctx context.Context, id uint) error
+	FindUserToken(ctx context.Context, userID uint, tokenType model.TokenType) (*model.VerificationToken, error)
+	IncrementTokenAttempts(ctx context.Context, id uint) error
+	DeleteUserTokens(ctx context.Context, userID uint, tokenType model.TokenType) error
 	DeleteExpiredTokens(ctx context.Context) error
 
 	// 2FA related
@@ -32,5 +37,6 @@ type AuthRepository interface {
 
 	// Session management
 	UpdateLastLogin(ctx context.Context, userID uint) error
-	UpdateRefreshToken(ctx context.Context, userID uint, token string) error
+	UpdateRefreshToken(ctx context.Context, userID uint, tokenHash string) error
+	RevokeAllSessions(ctx context.Context, userID uint) error
 }
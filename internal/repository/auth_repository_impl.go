@@ -22,9 +22,33 @@ func (r *authRepository) RegisterUser(ctx context.Context, user *model.User) err
 	return r.db.WithContext(ctx).Create(user).Error
 }
 
+// FindUserByEmail finds a user by email, ignoring case
 func (r *authRepository) FindUserByEmail(ctx context.Context, email string) (*model.User, error) {
 	var user model.User
-	err := r.db.WithContext(ctx).Where("email = ?", email).First(&user).Error
+	err := r.db.WithContext(ctx).Where("lower(email) = ?", model.NormalizeEmail(email)).First(&user).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// FindUserByCanonicalEmail finds a user whose email is the given email or one of its aliases
+func (r *authRepository) FindUserByCanonicalEmail(ctx context.Context, email string) (*model.User, error) {
+	canonical := model.CanonicalEmail(email)
+	if canonical == "" {
+		return nil, gorm.ErrRecordNotFound
+	}
+	var user model.User
+	err := r.db.WithContext(ctx).Where("canonical_email = ?", canonical).Order("id").First(&user).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *authRepository) FindUserByPhone(ctx context.Context, phone string) (*model.User, error) {
+	var user model.User
+	err := r.db.WithContext(ctx).Where("phone = ?", phone).First(&user).Error
 	if err != nil {
 		return nil, err
 	}
@@ -40,8 +64,13 @@ func (r *authRepository) FindUserByProviderID(ctx context.Context, provider mode
 	return &user, nil
 }
 
-func (r *authRepository) UpdateUser(ctx context.Context, user *model.User) error {
-	return r.db.WithContext(ctx).Save(user).Error
+func (r *authRepository) UpdateUser(ctx context.Context, user *model.User, events ...*model.OutboxEvent) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(user).Error; err != nil {
+			return err
+		}
+		return createOutboxEvents(tx, "user", user.ID, events)
+	})
 }
 
 func (r *authRepository) VerifyEmail(ctx context.Context, userID uint) error {
@@ -65,9 +94,9 @@ func (r *authRepository) CreateVerificationToken(ctx context.Context, token *mod
 	return r.db.WithContext(ctx).Create(token).Error
 }
 
-func (r *authRepository) FindVerificationToken(ctx context.Context, token string, tokenType model.TokenType) (*model.VerificationToken, error) {
+func (r *authRepository) FindVerificationToken(ctx context.Context, tokenHash string, tokenType model.TokenType) (*model.VerificationToken, error) {
 	var verificationToken model.VerificationToken
-	err := r.db.WithContext(ctx).Where("token = ? AND type = ? AND expires_at > ?", token, tokenType, time.Now()).First(&verificationToken).Error
+	err := r.db.WithContext(ctx).Where("token = ? AND type = ? AND expires_at > ?", tokenHash, tokenType, time.Now()).First(&verificationToken).Error
 	if err != nil {
 		return nil, err
 	}
@@ -78,29 +107,48 @@ func (r *authRepository) DeleteVerificationToken(ctx context.Context, id uint) e
 	return r.db.WithContext(ctx).Delete(&model.VerificationToken{}, id).Error
 }
 
+// FindUserToken returns the newest unexpired token of a type issued to the user
+func (r *authRepository) FindUserToken(ctx context.Context, userID uint, tokenType model.TokenType) (*model.VerificationToken, error) {
+	var verificationToken model.VerificationToken
+	err := r.db.WithContext(ctx).Where("user_id = ? AND type = ? AND expires_at > ?", userID, tokenType, time.Now()).
+		Order("created_at DESC").First(&verificationToken).Error
+	if err != nil {
+		return nil, err
+	}
+	return &verificationToken, nil
+}
+
+func (r *authRepository) IncrementTokenAttempts(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Model(&model.VerificationToken{}).Where("id = ?", id).
+		Update("attempts", gorm.Expr("attempts + 1")).Error
+}
+
+func (r *authRepository) DeleteUserTokens(ctx context.Context, userID uint, tokenType model.TokenType) error {
+	return r.db.WithContext(ctx).Where("user_id = ? AND type = ?", userID, tokenType).Delete(&model.VerificationToken{}).Error
+}
+
 func (r *authRepository) DeleteExpiredTokens(ctx context.Context) error {
 	return r.db.WithContext(ctx).Where("expires_at <= ?", time.Now()).Delete(&model.VerificationToken{}).Error
 }
 
+// Encrypted columns are updated through structs so the gorm serializer is applied
+
 func (r *authRepository) Enable2FA(ctx context.Context, userID uint, secret string) error {
 	return r.db.WithContext(ctx).Model(&model.User{}).Where("id = ?", userID).
-		Updates(map[string]interface{}{
-			"two_factor_auth": true,
-			"secret2fa":       secret,
-		}).Error
+		Select("TwoFactorAuth", "Secret2FA").
+		Updates(&model.User{TwoFactorAuth: true, Secret2FA: secret}).Error
 }
 
 func (r *authRepository) Disable2FA(ctx context.Context, userID uint) error {
 	return r.db.WithContext(ctx).Model(&model.User{}).Where("id = ?", userID).
-		Updates(map[string]interface{}{
-			"two_factor_auth": false,
-			"secret2fa":       "",
-		}).Error
+		Select("TwoFactorAuth", "Secret2FA").
+		Updates(&model.User{TwoFactorAuth: false, Secret2FA: ""}).Error
 }
 
 func (r *authRepository) Update2FASecret(ctx context.Context, userID uint, secret string) error {
 	return r.db.WithContext(ctx).Model(&model.User{}).Where("id = ?", userID).
-		Update("secret2fa", secret).Error
+		Select("Secret2FA").
+		Updates(&model.User{Secret2FA: secret}).Error
 }
 
 func (r *authRepository) UpdateLastLogin(ctx context.Context, userID uint) error {
@@ -109,9 +157,26 @@ func (r *authRepository) UpdateLastLogin(ctx context.Context, userID uint) error
 		Update("last_login", &now).Error
 }
 
-func (r *authRepository) UpdateRefreshToken(ctx context.Context, userID uint, token string) error {
+func (r *authRepository) UpdateRefreshToken(ctx context.Context, userID uint, tokenHash string) error {
 	return r.db.WithContext(ctx).Model(&model.User{}).Where("id = ?", userID).
-		Update("refresh_token", token).Error
+		Select("RefreshTokenHash").
+		Updates(&model.User{RefreshTokenHash: tokenHash}).Error
+}
+
+// RevokeAllSessions clears the refresh token, deletes all sessions and bumps the token version
+// so that every token previously issued to the user is rejected
+func (r *authRepository) RevokeAllSessions(ctx context.Context, userID uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.User{}).Where("id = ?", userID).
+			Updates(map[string]interface{}{
+				"refresh_token": "",
+				"token_version": gorm.Expr("token_version + 1"),
+			}).Error; err != nil {
+			return err
+		}
+
+		return tx.Where("user_id = ?", userID).Delete(&model.Session{}).Error
+	})
 }
 
 // FindByID finds a user by their ID
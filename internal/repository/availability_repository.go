@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"gorm.io/gorm"
+)
+
+type availabilityRepository struct {
+	db *gorm.DB
+}
+
+// NewAvailabilityRepository creates a new availability repository
+func NewAvailabilityRepository(db *gorm.DB) AvailabilityRepository {
+	return &availabilityRepository{
+		db: db,
+	}
+}
+
+// Create creates a new availability window
+func (r *availabilityRepository) Create(ctx context.Context, availability *model.Availability) error {
+	return r.db.WithContext(ctx).Create(availability).Error
+}
+
+// FindByID finds an availability window by ID
+func (r *availabilityRepository) FindByID(ctx context.Context, id uint) (*model.Availability, error) {
+	var availability model.Availability
+	if err := r.db.WithContext(ctx).First(&availability, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("availability not found")
+		}
+		return nil, err
+	}
+	return &availability, nil
+}
+
+// FindByDoctorID finds a doctor's availability windows ordered by day and start time
+func (r *availabilityRepository) FindByDoctorID(ctx context.Context, doctorID uint) ([]*model.Availability, error) {
+	var availabilities []*model.Availability
+	if err := r.db.WithContext(ctx).
+		Where("doctor_id = ?", doctorID).
+		Order("day_of_week, start_time").
+		Find(&availabilities).Error; err != nil {
+		return nil, err
+	}
+	return availabilities, nil
+}
+
+// FindByDoctorIDs finds the availability windows of several doctors
+func (r *availabilityRepository) FindByDoctorIDs(ctx context.Context, doctorIDs []uint) ([]*model.Availability, error) {
+	if len(doctorIDs) == 0 {
+		return nil, nil
+	}
+	var availabilities []*model.Availability
+	if err := r.db.WithContext(ctx).
+		Where("doctor_id IN ?", doctorIDs).
+		Order("doctor_id, day_of_week, start_time").
+		Find(&availabilities).Error; err != nil {
+		return nil, err
+	}
+	return availabilities, nil
+}
+
+// Update updates an availability window
+func (r *availabilityRepository) Update(ctx context.Context, availability *model.Availability) error {
+	return r.db.WithContext(ctx).Save(availability).Error
+}
+
+// Delete deletes an availability window
+func (r *availabilityRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&model.Availability{}, id).Error
+}
+
+// ReplaceWindows deletes availability windows and creates others in a single transaction
+func (r *availabilityRepository) ReplaceWindows(ctx context.Context, removeIDs []uint, windows []*model.Availability) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if len(removeIDs) > 0 {
+			if err := tx.Delete(&model.Availability{}, removeIDs).Error; err != nil {
+				return err
+			}
+		}
+		if len(windows) == 0 {
+			return nil
+		}
+		return tx.Create(&windows).Error
+	})
+}
+
+// CreateTimeOff creates a new time-off range
+func (r *availabilityRepository) CreateTimeOff(ctx context.Context, timeOff *model.TimeOff) error {
+	return r.db.WithContext(ctx).Omit("CoveringDoctor").Create(timeOff).Error
+}
+
+// FindTimeOffByID finds a time-off range by ID
+func (r *availabilityRepository) FindTimeOffByID(ctx context.Context, id uint) (*model.TimeOff, error) {
+	var timeOff model.TimeOff
+	if err := r.db.WithContext(ctx).First(&timeOff, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("time off not found")
+		}
+		return nil, err
+	}
+	return &timeOff, nil
+}
+
+// FindTimeOff finds a doctor's time-off ranges overlapping from to to, ordered by start
+func (r *availabilityRepository) FindTimeOff(ctx context.Context, doctorID uint, from, to time.Time) ([]*model.TimeOff, error) {
+	var timeOff []*model.TimeOff
+	if err := r.db.WithContext(ctx).
+		Preload("CoveringDoctor.User").
+		Where("doctor_id = ? AND start < ? AND \"end\" > ?", doctorID, to, from).
+		Order("start").
+		Find(&timeOff).Error; err != nil {
+		return nil, err
+	}
+	return timeOff, nil
+}
+
+// FindUpcomingTimeOff finds a doctor's time-off ranges ending after from, ordered by start
+func (r *availabilityRepository) FindUpcomingTimeOff(ctx context.Context, doctorID uint, from time.Time) ([]*model.TimeOff, error) {
+	var timeOff []*model.TimeOff
+	if err := r.db.WithContext(ctx).
+		Preload("CoveringDoctor.User").
+		Where("doctor_id = ? AND \"end\" > ?", doctorID, from).
+		Order("start").
+		Find(&timeOff).Error; err != nil {
+		return nil, err
+	}
+	return timeOff, nil
+}
+
+// UpdateTimeOff updates a time-off range
+func (r *availabilityRepository) UpdateTimeOff(ctx context.Context, timeOff *model.TimeOff) error {
+	return r.db.WithContext(ctx).Omit("CoveringDoctor").Save(timeOff).Error
+}
+
+// DeleteTimeOff deletes a time-off range
+func (r *availabilityRepository) DeleteTimeOff(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&model.TimeOff{}, id).Error
+}
+
+// FindCalendarBusy finds the busy times pulled from a doctor's external calendar overlapping from
+// to to, ordered by start
+func (r *availabilityRepository) FindCalendarBusy(ctx context.Context, doctorID uint, from, to time.Time) ([]*model.CalendarBusy, error) {
+	var busy []*model.CalendarBusy
+	if err := r.db.WithContext(ctx).
+		Where("doctor_id = ? AND start < ? AND \"end\" > ?", doctorID, to, from).
+		Order("start").
+		Find(&busy).Error; err != nil {
+		return nil, err
+	}
+	return busy, nil
+}
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/pkg/redis"
+)
+
+// bookingLockKey is the key holding the booking lock of a doctor
+func bookingLockKey(doctorID uint) string {
+	return fmt.Sprintf("booking-lock:%d", doctorID)
+}
+
+// releaseLockScript deletes a lock only if it is still held with the token, so a holder whose
+// lock expired does not release the next holder's
+const releaseLockScript = `
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0`
+
+type redisBookingLockRepository struct {
+	client *redis.Client
+}
+
+// NewRedisBookingLockRepository creates a booking lock repository backed by Redis
+func NewRedisBookingLockRepository(client *redis.Client) BookingLockRepository {
+	return &redisBookingLockRepository{
+		client: client,
+	}
+}
+
+// Acquire takes the doctor's lock with token for ttl and reports whether it was free
+func (r *redisBookingLockRepository) Acquire(ctx context.Context, doctorID uint, token string, ttl time.Duration) (bool, error) {
+	reply, err := r.client.Do(ctx, "SET", bookingLockKey(doctorID), token, "NX", "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	if err != nil {
+		return false, err
+	}
+	return reply == "OK", nil
+}
+
+// Release gives up the doctor's lock if it is still held with token
+func (r *redisBookingLockRepository) Release(ctx context.Context, doctorID uint, token string) error {
+	_, err := r.client.Eval(ctx, releaseLockScript, []string{bookingLockKey(doctorID)}, token)
+	return err
+}
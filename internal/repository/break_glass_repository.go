@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"gorm.io/gorm"
+)
+
+type breakGlassRepository struct {
+	db *gorm.DB
+}
+
+// NewBreakGlassRepository creates a new break-glass access repository
+func NewBreakGlassRepository(db *gorm.DB) BreakGlassRepository {
+	return &breakGlassRepository{
+		db: db,
+	}
+}
+
+// Create creates a new emergency access grant
+func (r *breakGlassRepository) Create(ctx context.Context, access *model.BreakGlassAccess) error {
+	return r.db.WithContext(ctx).Create(access).Error
+}
+
+// FindActive finds the latest unexpired grant of a user for a patient
+func (r *breakGlassRepository) FindActive(ctx context.Context, userID, patientID uint) (*model.BreakGlassAccess, error) {
+	var access model.BreakGlassAccess
+	err := r.db.WithContext(ctx).
+		Preload("User").
+		Preload("Patient.User").
+		Where("user_id = ? AND patient_id = ? AND expires_at > ?", userID, patientID, time.Now()).
+		Order("expires_at DESC").
+		First(&access).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("no active emergency access")
+		}
+		return nil, err
+	}
+	return &access, nil
+}
+
+// FindAll finds all emergency access grants with pagination, most recent first
+func (r *breakGlassRepository) FindAll(ctx context.Context, limit, offset int) ([]*model.BreakGlassAccess, int64, error) {
+	var accesses []*model.BreakGlassAccess
+	var count int64
+
+	// Count total records
+	if err := r.db.WithContext(ctx).Model(&model.BreakGlassAccess{}).Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// Get paginated results
+	if err := r.db.WithContext(ctx).
+		Preload("User").
+		Preload("Patient.User").
+		Order("created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&accesses).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return accesses, count, nil
+}
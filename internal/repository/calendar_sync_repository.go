@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"gorm.io/gorm"
+)
+
+type calendarSyncRepository struct {
+	db *gorm.DB
+}
+
+// NewCalendarSyncRepository creates a new calendar sync repository
+func NewCalendarSyncRepository(db *gorm.DB) CalendarSyncRepository {
+	return &calendarSyncRepository{
+		db: db,
+	}
+}
+
+// FindConnectionByUserID finds the calendar connection of a user
+func (r *calendarSyncRepository) FindConnectionByUserID(ctx context.Context, userID uint) (*model.CalendarConnection, error) {
+	var connection model.CalendarConnection
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Take(&connection).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("calendar connection not found")
+		}
+		return nil, err
+	}
+	return &connection, nil
+}
+
+// FindConnections lists every calendar connection, least recently synced first
+func (r *calendarSyncRepository) FindConnections(ctx context.Context) ([]*model.CalendarConnection, error) {
+	var connections []*model.CalendarConnection
+	if err := r.db.WithContext(ctx).
+		Order("synced_at ASC NULLS FIRST, id ASC").
+		Find(&connections).Error; err != nil {
+		return nil, err
+	}
+	return connections, nil
+}
+
+// SaveConnection creates or updates a calendar connection
+func (r *calendarSyncRepository) SaveConnection(ctx context.Context, connection *model.CalendarConnection) error {
+	return r.db.WithContext(ctx).Save(connection).Error
+}
+
+// DeleteConnection deletes a calendar connection with the events pushed through it and the busy
+// times pulled from it
+func (r *calendarSyncRepository) DeleteConnection(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("connection_id = ?", id).Delete(&model.CalendarEvent{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("connection_id = ?", id).Delete(&model.CalendarBusy{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.CalendarConnection{}, id).Error
+	})
+}
+
+// FindEvents lists the events pushed through a connection
+func (r *calendarSyncRepository) FindEvents(ctx context.Context, connectionID uint) ([]*model.CalendarEvent, error) {
+	var events []*model.CalendarEvent
+	if err := r.db.WithContext(ctx).Where("connection_id = ?", connectionID).Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// SaveEvent creates or updates a pushed event
+func (r *calendarSyncRepository) SaveEvent(ctx context.Context, event *model.CalendarEvent) error {
+	return r.db.WithContext(ctx).Save(event).Error
+}
+
+// DeleteEvent deletes a pushed event
+func (r *calendarSyncRepository) DeleteEvent(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&model.CalendarEvent{}, id).Error
+}
+
+// ReplaceBusy replaces the busy times pulled from a connection
+func (r *calendarSyncRepository) ReplaceBusy(ctx context.Context, connectionID uint, busy []*model.CalendarBusy) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("connection_id = ?", connectionID).Delete(&model.CalendarBusy{}).Error; err != nil {
+			return err
+		}
+		if len(busy) == 0 {
+			return nil
+		}
+		return tx.Create(busy).Error
+	})
+}
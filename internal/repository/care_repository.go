@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CareCandidate is a patient for whom a care rule is due, with the last completed appointment
+// that satisfied it, if any
+type CareCandidate struct {
+	PatientID       uint
+	PatientPublicID string
+	LastVisit       *time.Time
+}
+
+// CareEligibility selects the patients a care rule applies to and when it counts as due
+type CareEligibility struct {
+	Sex          string     // Matched against the patient's gender; empty for everyone
+	BornBefore   *time.Time // Patients born on or before, for a minimum age
+	BornAfter    *time.Time // Patients born after, for a maximum age
+	VisitsBefore time.Time  // Patients whose last satisfying visit is before this are due
+	Now          time.Time
+}
+
+type careRepository struct {
+	db *gorm.DB
+}
+
+// NewCareRepository creates a new care repository
+func NewCareRepository(db *gorm.DB) CareRepository {
+	return &careRepository{
+		db: db,
+	}
+}
+
+// CreateRule creates a care rule
+func (r *careRepository) CreateRule(ctx context.Context, rule *model.CareRule) error {
+	return r.db.WithContext(ctx).Create(rule).Error
+}
+
+// FindRuleByID finds a care rule by ID
+func (r *careRepository) FindRuleByID(ctx context.Context, id uint) (*model.CareRule, error) {
+	var rule model.CareRule
+	if err := r.db.WithContext(ctx).Preload("AppointmentType").First(&rule, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("care rule not found")
+		}
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// FindRules finds all care rules, including inactive ones
+func (r *careRepository) FindRules(ctx context.Context) ([]*model.CareRule, error) {
+	var rules []*model.CareRule
+	err := r.db.WithContext(ctx).Preload("AppointmentType").Order("name").Find(&rules).Error
+	return rules, err
+}
+
+// FindActiveRules finds the care rules reminders are sent for
+func (r *careRepository) FindActiveRules(ctx context.Context) ([]*model.CareRule, error) {
+	var rules []*model.CareRule
+	err := r.db.WithContext(ctx).Where("active = ?", true).Order("id").Find(&rules).Error
+	return rules, err
+}
+
+// UpdateRule updates a care rule
+func (r *careRepository) UpdateRule(ctx context.Context, rule *model.CareRule) error {
+	return r.db.WithContext(ctx).Omit("AppointmentType").Save(rule).Error
+}
+
+// FindDuePatients finds up to limit eligible patients for whom a rule is due: their last
+// completed appointment of the rule's type is before eligibility.VisitsBefore, or they have
+// none. Patients with an upcoming appointment of that type, and patients already reminded
+// since their last visit, are left out.
+func (r *careRepository) FindDuePatients(ctx context.Context, rule *model.CareRule, eligibility CareEligibility, limit int) ([]CareCandidate, error) {
+	patients := r.db.WithContext(ctx).
+		Table("patients").
+		Select("patients.id AS patient_id, patients.public_id AS patient_public_id, MAX(appointments.scheduled_start) AS last_visit").
+		Joins("LEFT JOIN appointments ON appointments.patient_id = patients.id AND appointments.appointment_type_id = ? AND appointments.status = ?",
+			rule.AppointmentTypeID, model.AppointmentStatusCompleted).
+		Group("patients.id")
+	if eligibility.Sex != "" {
+		patients = patients.Where("LOWER(patients.gender) = LOWER(?)", eligibility.Sex)
+	}
+	if eligibility.BornBefore != nil {
+		patients = patients.Where("patients.date_of_birth <= ?", *eligibility.BornBefore)
+	}
+	if eligibility.BornAfter != nil {
+		patients = patients.Where("patients.date_of_birth > ?", *eligibility.BornAfter)
+	}
+
+	var candidates []CareCandidate
+	err := r.db.WithContext(ctx).
+		Table("(?) AS visits", patients).
+		Select("visits.patient_id, visits.patient_public_id, visits.last_visit").
+		Where("visits.last_visit IS NULL OR visits.last_visit < ?", eligibility.VisitsBefore).
+		Where(`NOT EXISTS (SELECT 1 FROM appointments WHERE appointments.patient_id = visits.patient_id
+			AND appointments.appointment_type_id = ? AND appointments.status IN ? AND appointments.scheduled_start >= ?)`,
+			rule.AppointmentTypeID,
+			[]model.AppointmentStatus{model.AppointmentStatusPending, model.AppointmentStatusConfirmed, model.AppointmentStatusCheckedIn},
+			eligibility.Now).
+		Where(`NOT EXISTS (SELECT 1 FROM care_reminders WHERE care_reminders.patient_id = visits.patient_id
+			AND care_reminders.rule_id = ? AND care_reminders.notified_at > COALESCE(visits.last_visit, '-infinity'))`,
+			rule.ID).
+		Order("visits.patient_id").
+		Limit(limit).
+		Scan(&candidates).Error
+	return candidates, err
+}
+
+// SaveReminder records that a patient was reminded of a rule, replacing the previous reminder
+// for it, and writes its outbox events in the same transaction
+func (r *careRepository) SaveReminder(ctx context.Context, reminder *model.CareReminder, events ...*model.OutboxEvent) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Patient", "Rule").Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "patient_id"}, {Name: "rule_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"due_at", "last_visit_at", "notified_at", "updated_at"}),
+		}).Create(reminder).Error; err != nil {
+			return err
+		}
+		return createOutboxEvents(tx, "care_reminder", reminder.ID, events)
+	})
+}
+
+// FindReminderByID finds a reminder with its patient and rule
+func (r *careRepository) FindReminderByID(ctx context.Context, id uint) (*model.CareReminder, error) {
+	var reminder model.CareReminder
+	err := r.db.WithContext(ctx).
+		Preload("Patient.User").
+		Preload("Rule").
+		First(&reminder, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("care reminder not found")
+		}
+		return nil, err
+	}
+	return &reminder, nil
+}
+
+// FindOutstandingReminders finds a patient's reminders for active rules that nothing has been
+// booked for since, soonest due first
+func (r *careRepository) FindOutstandingReminders(ctx context.Context, patientID uint) ([]*model.CareReminder, error) {
+	var reminders []*model.CareReminder
+	err := r.db.WithContext(ctx).
+		Joins("JOIN care_rules ON care_rules.id = care_reminders.rule_id").
+		Preload("Rule.AppointmentType").
+		Where("care_reminders.patient_id = ? AND care_rules.active = ?", patientID, true).
+		Where(`NOT EXISTS (SELECT 1 FROM appointments WHERE appointments.patient_id = care_reminders.patient_id
+			AND appointments.appointment_type_id = care_rules.appointment_type_id AND appointments.status <> ?
+			AND appointments.created_at > care_reminders.notified_at)`, model.AppointmentStatusCancelled).
+		Order("care_reminders.due_at").
+		Find(&reminders).Error
+	return reminders, err
+}
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"gorm.io/gorm"
+)
+
+type clinicalListRepository struct {
+	db *gorm.DB
+}
+
+// NewClinicalListRepository creates a new allergy and medication list repository
+func NewClinicalListRepository(db *gorm.DB) ClinicalListRepository {
+	return &clinicalListRepository{
+		db: db,
+	}
+}
+
+// activeAllergies narrows an allergy query to the allergies that apply today, most severe first.
+// It is also used to preload the active allergies of patients shown alongside appointments and
+// medical records.
+func activeAllergies(db *gorm.DB) *gorm.DB {
+	return db.
+		Where("allergies.resolved_date IS NULL OR allergies.resolved_date > CURRENT_DATE").
+		Order("CASE allergies.severity WHEN 'severe' THEN 0 WHEN 'moderate' THEN 1 WHEN 'mild' THEN 2 ELSE 3 END, allergies.substance")
+}
+
+// CreateAllergy records an allergy
+func (r *clinicalListRepository) CreateAllergy(ctx context.Context, allergy *model.Allergy) error {
+	return r.db.WithContext(ctx).Create(allergy).Error
+}
+
+// FindAllergy finds an allergy by ID
+func (r *clinicalListRepository) FindAllergy(ctx context.Context, id uint) (*model.Allergy, error) {
+	var allergy model.Allergy
+	if err := r.db.WithContext(ctx).First(&allergy, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("allergy not found")
+		}
+		return nil, err
+	}
+	return &allergy, nil
+}
+
+// FindAllergies lists a patient's allergies, active ones most severe first. Resolved allergies
+// follow, most recently resolved first, unless activeOnly is set.
+func (r *clinicalListRepository) FindAllergies(ctx context.Context, patientID uint, activeOnly bool) ([]*model.Allergy, error) {
+	query := r.db.WithContext(ctx).Where("patient_id = ?", patientID)
+	if activeOnly {
+		query = activeAllergies(query)
+	} else {
+		query = query.Order("resolved_date DESC NULLS FIRST, CASE severity WHEN 'severe' THEN 0 WHEN 'moderate' THEN 1 WHEN 'mild' THEN 2 ELSE 3 END, substance")
+	}
+
+	var allergies []*model.Allergy
+	err := query.Find(&allergies).Error
+	return allergies, err
+}
+
+// UpdateAllergy saves an allergy
+func (r *clinicalListRepository) UpdateAllergy(ctx context.Context, allergy *model.Allergy) error {
+	return r.db.WithContext(ctx).Save(allergy).Error
+}
+
+// DeleteAllergy deletes an allergy
+func (r *clinicalListRepository) DeleteAllergy(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&model.Allergy{}, id).Error
+}
+
+// CreateMedication adds a medication to a patient's list
+func (r *clinicalListRepository) CreateMedication(ctx context.Context, medication *model.PatientMedication) error {
+	return r.db.WithContext(ctx).Omit("Medication").Create(medication).Error
+}
+
+// FindMedication finds a medication list entry by ID with its catalog product
+func (r *clinicalListRepository) FindMedication(ctx context.Context, id uint) (*model.PatientMedication, error) {
+	var medication model.PatientMedication
+	if err := r.db.WithContext(ctx).Preload("Medication").First(&medication, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("medication list entry not found")
+		}
+		return nil, err
+	}
+	return &medication, nil
+}
+
+// FindMedications lists a patient's medications, the ones still taken first and then by name.
+// Stopped medications are left out if activeOnly is set.
+func (r *clinicalListRepository) FindMedications(ctx context.Context, patientID uint, activeOnly bool) ([]*model.PatientMedication, error) {
+	query := r.db.WithContext(ctx).Preload("Medication").Where("patient_id = ?", patientID)
+	if activeOnly {
+		query = query.Where("stop_date IS NULL OR stop_date > CURRENT_DATE")
+	}
+
+	var medications []*model.PatientMedication
+	err := query.Order("stop_date DESC NULLS FIRST, name").Find(&medications).Error
+	return medications, err
+}
+
+// UpdateMedication saves a medication list entry
+func (r *clinicalListRepository) UpdateMedication(ctx context.Context, medication *model.PatientMedication) error {
+	return r.db.WithContext(ctx).Omit("Medication").Save(medication).Error
+}
+
+// DeleteMedication deletes a medication list entry
+func (r *clinicalListRepository) DeleteMedication(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&model.PatientMedication{}, id).Error
+}
@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"gorm.io/gorm"
+)
+
+type consentRepository struct {
+	db *gorm.DB
+}
+
+// NewConsentRepository creates a new consent repository
+func NewConsentRepository(db *gorm.DB) ConsentRepository {
+	return &consentRepository{
+		db: db,
+	}
+}
+
+// Create records a new consent
+func (r *consentRepository) Create(ctx context.Context, consent *model.Consent) error {
+	return r.db.WithContext(ctx).Create(consent).Error
+}
+
+// FindByUserID finds all consents given by a user, most recent first
+func (r *consentRepository) FindByUserID(ctx context.Context, userID uint) ([]*model.Consent, error) {
+	var consents []*model.Consent
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("accepted_at DESC").
+		Find(&consents).Error; err != nil {
+		return nil, err
+	}
+	return consents, nil
+}
+
+// Exists checks whether a user has accepted a specific policy version
+func (r *consentRepository) Exists(ctx context.Context, userID uint, policyType model.PolicyType, version string) (bool, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).
+		Model(&model.Consent{}).
+		Where("user_id = ? AND policy_type = ? AND version = ?", userID, policyType, version).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// CreateMarketingConsent records a user opting in to or out of marketing on a channel
+func (r *consentRepository) CreateMarketingConsent(ctx context.Context, consent *model.MarketingConsent) error {
+	return r.db.WithContext(ctx).Create(consent).Error
+}
+
+// FindMarketingConsents finds a user's current marketing choice on each channel they made one for
+func (r *consentRepository) FindMarketingConsents(ctx context.Context, userID uint) ([]*model.MarketingConsent, error) {
+	var consents []*model.MarketingConsent
+	if err := r.db.WithContext(ctx).
+		Where("id IN (SELECT MAX(id) FROM marketing_consents WHERE user_id = ? GROUP BY channel)", userID).
+		Order("channel").
+		Find(&consents).Error; err != nil {
+		return nil, err
+	}
+	return consents, nil
+}
+
+// HasMarketingConsent reports whether a user's latest marketing choice on a channel is an opt-in
+func (r *consentRepository) HasMarketingConsent(ctx context.Context, userID uint, channel string) (bool, error) {
+	var latest model.MarketingConsent
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND channel = ?", userID, channel).
+		Order("id DESC").
+		Limit(1).
+		Find(&latest).Error
+	if err != nil {
+		return false, err
+	}
+	return latest.ID != 0 && latest.Granted, nil
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type customRoleRepository struct {
+	db *gorm.DB
+}
+
+// NewCustomRoleRepository creates a new custom role repository
+func NewCustomRoleRepository(db *gorm.DB) CustomRoleRepository {
+	return &customRoleRepository{
+		db: db,
+	}
+}
+
+// Create creates a new custom role
+func (r *customRoleRepository) Create(ctx context.Context, role *model.CustomRole) error {
+	return r.db.WithContext(ctx).Create(role).Error
+}
+
+// FindByID finds a custom role by ID
+func (r *customRoleRepository) FindByID(ctx context.Context, id uint) (*model.CustomRole, error) {
+	var role model.CustomRole
+	if err := r.db.WithContext(ctx).First(&role, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("role not found")
+		}
+		return nil, err
+	}
+	return &role, nil
+}
+
+// FindAll finds all custom roles ordered by name
+func (r *customRoleRepository) FindAll(ctx context.Context) ([]*model.CustomRole, error) {
+	var roles []*model.CustomRole
+	if err := r.db.WithContext(ctx).Order("name").Find(&roles).Error; err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+// FindByUserID finds the custom roles assigned to a user
+func (r *customRoleRepository) FindByUserID(ctx context.Context, userID uint) ([]*model.CustomRole, error) {
+	var roles []*model.CustomRole
+	err := r.db.WithContext(ctx).
+		Joins("JOIN user_custom_roles ON user_custom_roles.custom_role_id = custom_roles.id").
+		Where("user_custom_roles.user_id = ?", userID).
+		Order("custom_roles.name").
+		Find(&roles).Error
+	if err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+// Update updates a custom role
+func (r *customRoleRepository) Update(ctx context.Context, role *model.CustomRole) error {
+	return r.db.WithContext(ctx).Save(role).Error
+}
+
+// Delete deletes a custom role and removes it from all users
+func (r *customRoleRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("custom_role_id = ?", id).Delete(&model.UserCustomRole{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.CustomRole{}, id).Error
+	})
+}
+
+// Assign grants a custom role to a user; assigning a role twice has no effect
+func (r *customRoleRepository) Assign(ctx context.Context, userID, roleID uint) error {
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&model.UserCustomRole{UserID: userID, CustomRoleID: roleID, CreatedAt: time.Now()}).Error
+}
+
+// Unassign removes a custom role from a user
+func (r *customRoleRepository) Unassign(ctx context.Context, userID, roleID uint) error {
+	return r.db.WithContext(ctx).
+		Where("user_id = ? AND custom_role_id = ?", userID, roleID).
+		Delete(&model.UserCustomRole{}).Error
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/whitewalker-sa/ehass/internal/model"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type doctorRepository struct {
@@ -19,9 +20,14 @@ func NewDoctorRepository(db *gorm.DB) DoctorRepository {
 	}
 }
 
-// Create creates a new doctor
-func (r *doctorRepository) Create(ctx context.Context, doctor *model.Doctor) error {
-	return r.db.WithContext(ctx).Create(doctor).Error
+// Create creates a new doctor, writing any outbox events in the same transaction
+func (r *doctorRepository) Create(ctx context.Context, doctor *model.Doctor, events ...*model.OutboxEvent) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(doctor).Error; err != nil {
+			return err
+		}
+		return createOutboxEvents(tx, "doctor", doctor.ID, events)
+	})
 }
 
 // FindByID finds a doctor by ID with preloaded user data
@@ -37,6 +43,14 @@ func (r *doctorRepository) FindByID(ctx context.Context, id uint) (*model.Doctor
 	return &doctor, nil
 }
 
+// FindByPublicIDs finds the doctors with the given public IDs. IDs that match no doctor are
+// left out of the result, which is in no particular order.
+func (r *doctorRepository) FindByPublicIDs(ctx context.Context, publicIDs []string) ([]*model.Doctor, error) {
+	var doctors []*model.Doctor
+	err := r.db.WithContext(ctx).Preload("User").Where("public_id IN ?", publicIDs).Find(&doctors).Error
+	return doctors, err
+}
+
 // FindByUserID finds a doctor by user ID
 func (r *doctorRepository) FindByUserID(ctx context.Context, userID uint) (*model.Doctor, error) {
 	var doctor model.Doctor
@@ -50,18 +64,31 @@ func (r *doctorRepository) FindByUserID(ctx context.Context, userID uint) (*mode
 	return &doctor, nil
 }
 
+// doctorSortKeys maps the keys doctor lists can be sorted by to SQL expressions
+var doctorSortKeys = map[string]string{
+	"name":       "(SELECT users.name FROM users WHERE users.id = doctors.user_id)",
+	"specialty":  "doctors.specialty",
+	"experience": "doctors.experience",
+	"created_at": "doctors.created_at",
+}
+
 // FindAll finds all doctors with pagination
-func (r *doctorRepository) FindAll(ctx context.Context, limit, offset int) ([]*model.Doctor, int64, error) {
+func (r *doctorRepository) FindAll(ctx context.Context, limit, offset int, opts ListOptions) ([]*model.Doctor, int64, error) {
 	var doctors []*model.Doctor
 	var count int64
 
+	order, err := opts.orderBy("doctors", doctorSortKeys, "")
+	if err != nil {
+		return nil, 0, err
+	}
+
 	// Count total records
 	if err := r.db.WithContext(ctx).Model(&model.Doctor{}).Count(&count).Error; err != nil {
 		return nil, 0, err
 	}
 
 	// Get paginated results
-	if err := r.db.WithContext(ctx).Preload("User").Limit(limit).Offset(offset).Find(&doctors).Error; err != nil {
+	if err := opts.apply(r.db.WithContext(ctx), "User").Order(order).Limit(limit).Offset(offset).Find(&doctors).Error; err != nil {
 		return nil, 0, err
 	}
 
@@ -69,29 +96,188 @@ func (r *doctorRepository) FindAll(ctx context.Context, limit, offset int) ([]*m
 }
 
 // FindBySpecialty finds doctors by specialty with pagination
-func (r *doctorRepository) FindBySpecialty(ctx context.Context, specialty string, limit, offset int) ([]*model.Doctor, int64, error) {
+func (r *doctorRepository) FindBySpecialty(ctx context.Context, specialty string, limit, offset int, opts ListOptions) ([]*model.Doctor, int64, error) {
 	var doctors []*model.Doctor
 	var count int64
 
+	order, err := opts.orderBy("doctors", doctorSortKeys, "")
+	if err != nil {
+		return nil, 0, err
+	}
+
 	// Count total records with this specialty
 	if err := r.db.WithContext(ctx).Model(&model.Doctor{}).Where("specialty = ?", specialty).Count(&count).Error; err != nil {
 		return nil, 0, err
 	}
 
 	// Get paginated results
-	if err := r.db.WithContext(ctx).Preload("User").Where("specialty = ?", specialty).Limit(limit).Offset(offset).Find(&doctors).Error; err != nil {
+	if err := opts.apply(r.db.WithContext(ctx), "User").Where("specialty = ?", specialty).Order(order).Limit(limit).Offset(offset).Find(&doctors).Error; err != nil {
 		return nil, 0, err
 	}
 
 	return doctors, count, nil
 }
 
-// Update updates a doctor
-func (r *doctorRepository) Update(ctx context.Context, doctor *model.Doctor) error {
-	return r.db.WithContext(ctx).Save(doctor).Error
+// FindByOrganization finds the doctors of a clinic with their users, ordered by name. With
+// includeUnassigned, doctors without a clinic are included too, as they belong to the default one.
+func (r *doctorRepository) FindByOrganization(ctx context.Context, orgID uint, includeUnassigned bool) ([]*model.Doctor, error) {
+	query := r.db.WithContext(ctx).
+		Preload("User").
+		Joins("JOIN users ON users.id = doctors.user_id")
+	if includeUnassigned {
+		query = query.Where("doctors.organization_id = ? OR doctors.organization_id IS NULL", orgID)
+	} else {
+		query = query.Where("doctors.organization_id = ?", orgID)
+	}
+
+	var doctors []*model.Doctor
+	if err := query.Order("users.name ASC").Find(&doctors).Error; err != nil {
+		return nil, err
+	}
+	return doctors, nil
+}
+
+// FindAfter returns up to limit doctors with IDs above afterID in ID order, for walking the table in batches
+func (r *doctorRepository) FindAfter(ctx context.Context, afterID uint, limit int) ([]*model.Doctor, error) {
+	var doctors []*model.Doctor
+	err := r.db.WithContext(ctx).
+		Preload("User").
+		Where("id > ?", afterID).
+		Order("id ASC").
+		Limit(limit).
+		Find(&doctors).Error
+	return doctors, err
+}
+
+// SpecialtyCount is the number of doctors with a specialty
+type SpecialtyCount struct {
+	Specialty string
+	Count     int64
+}
+
+// doctorTextMatch matches doctors whose name, specialty or bio contains the search text
+const doctorTextMatch = "(users.name ILIKE ? OR doctors.specialty ILIKE ? OR doctors.bio ILIKE ?)"
+
+// Search finds doctors whose name, specialty or bio contains text, optionally narrowed to one
+// specialty, and counts all matches. Closer name matches come first.
+func (r *doctorRepository) Search(ctx context.Context, text, specialty string, limit int) ([]*model.Doctor, int64, error) {
+	pattern := "%" + escapeLike(text) + "%"
+	matches := func() *gorm.DB {
+		query := r.db.WithContext(ctx).
+			Model(&model.Doctor{}).
+			Joins("JOIN users ON users.id = doctors.user_id").
+			Where(doctorTextMatch, pattern, pattern, pattern)
+		if specialty != "" {
+			query = query.Where("doctors.specialty = ?", specialty)
+		}
+		return query
+	}
+
+	var count int64
+	if err := matches().Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var doctors []*model.Doctor
+	err := matches().
+		Preload("User").
+		Order(clause.OrderBy{Expression: clause.Expr{
+			SQL:  "similarity(users.name, ?) DESC",
+			Vars: []interface{}{text},
+		}}).
+		Order("users.name ASC").
+		Limit(limit).
+		Find(&doctors).Error
+	return doctors, count, err
+}
+
+// CountBySpecialty counts the doctors matching the search text by specialty, most common first
+func (r *doctorRepository) CountBySpecialty(ctx context.Context, text string) ([]SpecialtyCount, error) {
+	pattern := "%" + escapeLike(text) + "%"
+	var counts []SpecialtyCount
+	err := r.db.WithContext(ctx).
+		Model(&model.Doctor{}).
+		Select("doctors.specialty AS specialty, COUNT(*) AS count").
+		Joins("JOIN users ON users.id = doctors.user_id").
+		Where(doctorTextMatch, pattern, pattern, pattern).
+		Group("doctors.specialty").
+		Order("count DESC, specialty ASC").
+		Scan(&counts).Error
+	return counts, err
+}
+
+// CountUnverified counts the doctors whose account email address has not been verified
+func (r *doctorRepository) CountUnverified(ctx context.Context) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&model.Doctor{}).
+		Joins("JOIN users ON users.id = doctors.user_id").
+		Where("users.email_verified = ?", false).
+		Count(&count).Error
+	return count, err
+}
+
+// DoctorSuggestion is the little of a doctor shown in typeahead suggestions
+type DoctorSuggestion struct {
+	PublicID  string
+	Name      string
+	Specialty string
+}
+
+// SuggestByName returns doctors with a word of their name starting with prefix, in name order
+func (r *doctorRepository) SuggestByName(ctx context.Context, prefix string, limit int) ([]DoctorSuggestion, error) {
+	pattern := escapeLike(prefix)
+	var suggestions []DoctorSuggestion
+	err := r.db.WithContext(ctx).
+		Model(&model.Doctor{}).
+		Select("doctors.public_id AS public_id, users.name AS name, doctors.specialty AS specialty").
+		Joins("JOIN users ON users.id = doctors.user_id").
+		Where("users.name ILIKE ? OR users.name ILIKE ?", pattern+"%", "% "+pattern+"%").
+		Order("users.name ASC").
+		Limit(limit).
+		Scan(&suggestions).Error
+	return suggestions, err
+}
+
+// SuggestSpecialties returns the specialties with a word starting with prefix and how many
+// doctors have each, most common first
+func (r *doctorRepository) SuggestSpecialties(ctx context.Context, prefix string, limit int) ([]SpecialtyCount, error) {
+	pattern := escapeLike(prefix)
+	var counts []SpecialtyCount
+	err := r.db.WithContext(ctx).
+		Model(&model.Doctor{}).
+		Select("specialty, COUNT(*) AS count").
+		Where("specialty ILIKE ? OR specialty ILIKE ?", pattern+"%", "% "+pattern+"%").
+		Group("specialty").
+		Order("count DESC, specialty ASC").
+		Limit(limit).
+		Scan(&counts).Error
+	return counts, err
 }
 
-// Delete soft deletes a doctor
-func (r *doctorRepository) Delete(ctx context.Context, id uint) error {
-	return r.db.WithContext(ctx).Delete(&model.Doctor{}, id).Error
+// Update updates a doctor along with its outbox events
+func (r *doctorRepository) Update(ctx context.Context, doctor *model.Doctor, events ...*model.OutboxEvent) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(doctor).Error; err != nil {
+			return err
+		}
+		return createOutboxEvents(tx, "doctor", doctor.ID, events)
+	})
+}
+
+// Delete soft deletes a doctor along with its outbox events
+func (r *doctorRepository) Delete(ctx context.Context, id uint, events ...*model.OutboxEvent) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		bio := tx.Model(&model.Doctor{}).Select("public_id::text").Where("id = ?", id)
+		if err := tx.Where("kind = ? AND subject = (?)", model.TranslationDoctorBio, bio).Delete(&model.Translation{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("doctor_id = ?", id).Delete(&model.DoctorReview{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&model.Doctor{}, id).Error; err != nil {
+			return err
+		}
+		return createOutboxEvents(tx, "doctor", id, events)
+	})
 }
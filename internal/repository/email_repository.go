@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type emailRepository struct {
+	db *gorm.DB
+}
+
+// NewEmailRepository creates a new email repository
+func NewEmailRepository(db *gorm.DB) EmailRepository {
+	return &emailRepository{
+		db: db,
+	}
+}
+
+// CreateMessage records an outbound email
+func (r *emailRepository) CreateMessage(ctx context.Context, message *model.EmailMessage) error {
+	return r.db.WithContext(ctx).Create(message).Error
+}
+
+// FindMessageByMessageID finds an outbound email by its Message-ID header
+func (r *emailRepository) FindMessageByMessageID(ctx context.Context, messageID string) (*model.EmailMessage, error) {
+	var message model.EmailMessage
+	err := r.db.WithContext(ctx).Where("message_id = ?", messageID).First(&message).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("email message not found")
+		}
+		return nil, err
+	}
+	return &message, nil
+}
+
+// UpdateMessage updates an outbound email record
+func (r *emailRepository) UpdateMessage(ctx context.Context, message *model.EmailMessage) error {
+	return r.db.WithContext(ctx).Save(message).Error
+}
+
+// FindMessages finds outbound emails, newest first, optionally filtered by recipient and status
+func (r *emailRepository) FindMessages(ctx context.Context, recipient string, status model.EmailStatus, limit, offset int) ([]*model.EmailMessage, int64, error) {
+	var messages []*model.EmailMessage
+	var count int64
+
+	query := r.db.WithContext(ctx).Model(&model.EmailMessage{})
+	if recipient != "" {
+		query = query.Where("recipient = ?", recipient)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	// Count total records
+	if err := query.Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// Get paginated results
+	if err := query.
+		Order("created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&messages).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return messages, count, nil
+}
+
+// IsSuppressed reports whether email is on the suppression list
+func (r *emailRepository) IsSuppressed(ctx context.Context, email string) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&model.EmailSuppression{}).
+		Where("email = ?", email).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// Suppress adds an address to the suppression list, keeping the original entry if it is already there
+func (r *emailRepository) Suppress(ctx context.Context, suppression *model.EmailSuppression) error {
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "email"}}, DoNothing: true}).
+		Create(suppression).Error
+}
+
+// FindSuppressions finds suppressed addresses, newest first
+func (r *emailRepository) FindSuppressions(ctx context.Context, limit, offset int) ([]*model.EmailSuppression, int64, error) {
+	var suppressions []*model.EmailSuppression
+	var count int64
+
+	if err := r.db.WithContext(ctx).Model(&model.EmailSuppression{}).Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if err := r.db.WithContext(ctx).
+		Order("created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&suppressions).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return suppressions, count, nil
+}
+
+// DeleteSuppression removes an address from the suppression list
+func (r *emailRepository) DeleteSuppression(ctx context.Context, email string) error {
+	result := r.db.WithContext(ctx).Where("email = ?", email).Delete(&model.EmailSuppression{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("suppression not found")
+	}
+	return nil
+}
+
+// CountMessages counts the emails with a status recorded since the given time
+func (r *emailRepository) CountMessages(ctx context.Context, status model.EmailStatus, since time.Time) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&model.EmailMessage{}).
+		Where("status = ? AND created_at >= ?", status, since).
+		Count(&count).Error
+	return count, err
+}
@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type exportCursorRepository struct {
+	db *gorm.DB
+}
+
+// NewExportCursorRepository creates a new export cursor repository
+func NewExportCursorRepository(db *gorm.DB) ExportCursorRepository {
+	return &exportCursorRepository{
+		db: db,
+	}
+}
+
+// Get returns the last delivered ID for an exporter, or 0 if it has not delivered anything yet
+func (r *exportCursorRepository) Get(ctx context.Context, name string) (uint, error) {
+	var cursor model.ExportCursor
+	if err := r.db.WithContext(ctx).Where("name = ?", name).First(&cursor).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return cursor.LastID, nil
+}
+
+// Save records the last delivered ID for an exporter
+func (r *exportCursorRepository) Save(ctx context.Context, name string, lastID uint) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"last_id", "updated_at"}),
+	}).Create(&model.ExportCursor{Name: name, LastID: lastID, UpdatedAt: time.Now()}).Error
+}
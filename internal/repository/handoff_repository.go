@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"gorm.io/gorm"
+)
+
+type handoffRepository struct {
+	db *gorm.DB
+}
+
+// NewHandoffRepository creates a new handoff note repository
+func NewHandoffRepository(db *gorm.DB) HandoffRepository {
+	return &handoffRepository{
+		db: db,
+	}
+}
+
+// Create creates a handoff note
+func (r *handoffRepository) Create(ctx context.Context, note *model.HandoffNote) error {
+	return r.db.WithContext(ctx).Omit("Patient", "Author", "Recipient").Create(note).Error
+}
+
+// FindByPatientID finds a patient's handoff notes with pagination, most recent first
+func (r *handoffRepository) FindByPatientID(ctx context.Context, patientID uint, limit, offset int) ([]*model.HandoffNote, int64, error) {
+	var notes []*model.HandoffNote
+	var count int64
+
+	query := r.db.WithContext(ctx).Model(&model.HandoffNote{}).Where("patient_id = ?", patientID)
+	if err := query.Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if err := query.
+		Preload("Author.User").
+		Preload("Recipient.User").
+		Order("created_at DESC, id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&notes).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return notes, count, nil
+}
+
+// HasTreatmentRelationship reports whether a doctor is part of a patient's care team: the
+// doctor has a confirmed, checked-in or completed appointment with the patient, or the patient
+// was handed over to them. A pending booking does not count, as anyone may request one.
+func (r *handoffRepository) HasTreatmentRelationship(ctx context.Context, doctorID, patientID uint) (bool, error) {
+	var related bool
+	err := r.db.WithContext(ctx).
+		Raw(`SELECT EXISTS (SELECT 1 FROM appointments WHERE doctor_id = ? AND patient_id = ? AND status IN ?)
+			OR EXISTS (SELECT 1 FROM handoff_notes WHERE recipient_id = ? AND patient_id = ?)`,
+			doctorID, patientID, []model.AppointmentStatus{
+				model.AppointmentStatusConfirmed,
+				model.AppointmentStatusCheckedIn,
+				model.AppointmentStatusCompleted,
+			}, doctorID, patientID).
+		Scan(&related).Error
+	return related, err
+}
@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"gorm.io/gorm"
+)
+
+type immunizationRepository struct {
+	db *gorm.DB
+}
+
+// NewImmunizationRepository creates a new immunization repository
+func NewImmunizationRepository(db *gorm.DB) ImmunizationRepository {
+	return &immunizationRepository{
+		db: db,
+	}
+}
+
+// Create records a vaccine dose
+func (r *immunizationRepository) Create(ctx context.Context, immunization *model.Immunization) error {
+	return r.db.WithContext(ctx).Omit("Doctor").Create(immunization).Error
+}
+
+// FindByPatientID lists a patient's vaccine doses with their administering doctors, by vaccine
+// and dose
+func (r *immunizationRepository) FindByPatientID(ctx context.Context, patientID uint) ([]*model.Immunization, error) {
+	var immunizations []*model.Immunization
+	err := r.db.WithContext(ctx).
+		Preload("Doctor.User").
+		Where("patient_id = ?", patientID).
+		Order("vaccine_name, dose_number").
+		Find(&immunizations).Error
+	return immunizations, err
+}
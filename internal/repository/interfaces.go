@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/whitewalker-sa/ehass/internal/model"
 )
@@ -11,55 +12,113 @@ type UserRepository interface {
 	Create(ctx context.Context, user *model.User) error
 	FindByID(ctx context.Context, id uint) (*model.User, error)
 	FindByEmail(ctx context.Context, email string) (*model.User, error)
-	Update(ctx context.Context, user *model.User) error
+	FindByRole(ctx context.Context, role model.Role) ([]*model.User, error)
+	Update(ctx context.Context, user *model.User, events ...*model.OutboxEvent) error
 	Delete(ctx context.Context, id uint) error
 }
 
 // DoctorRepository defines operations for doctor data access
 type DoctorRepository interface {
-	Create(ctx context.Context, doctor *model.Doctor) error
+	Create(ctx context.Context, doctor *model.Doctor, events ...*model.OutboxEvent) error
 	FindByID(ctx context.Context, id uint) (*model.Doctor, error)
+	FindByPublicIDs(ctx context.Context, publicIDs []string) ([]*model.Doctor, error)
 	FindByUserID(ctx context.Context, userID uint) (*model.Doctor, error)
-	FindAll(ctx context.Context, limit, offset int) ([]*model.Doctor, int64, error)
-	FindBySpecialty(ctx context.Context, specialty string, limit, offset int) ([]*model.Doctor, int64, error)
-	Update(ctx context.Context, doctor *model.Doctor) error
-	Delete(ctx context.Context, id uint) error
+	FindAll(ctx context.Context, limit, offset int, opts ListOptions) ([]*model.Doctor, int64, error)
+	FindBySpecialty(ctx context.Context, specialty string, limit, offset int, opts ListOptions) ([]*model.Doctor, int64, error)
+	FindByOrganization(ctx context.Context, orgID uint, includeUnassigned bool) ([]*model.Doctor, error)
+	FindAfter(ctx context.Context, afterID uint, limit int) ([]*model.Doctor, error)
+	Search(ctx context.Context, text, specialty string, limit int) ([]*model.Doctor, int64, error)
+	CountBySpecialty(ctx context.Context, text string) ([]SpecialtyCount, error)
+	SuggestByName(ctx context.Context, prefix string, limit int) ([]DoctorSuggestion, error)
+	SuggestSpecialties(ctx context.Context, prefix string, limit int) ([]SpecialtyCount, error)
+	CountUnverified(ctx context.Context) (int64, error)
+	Update(ctx context.Context, doctor *model.Doctor, events ...*model.OutboxEvent) error
+	Delete(ctx context.Context, id uint, events ...*model.OutboxEvent) error
 }
 
 // AvailabilityRepository defines operations for doctor availability data access
 type AvailabilityRepository interface {
 	Create(ctx context.Context, availability *model.Availability) error
+	FindByID(ctx context.Context, id uint) (*model.Availability, error)
 	FindByDoctorID(ctx context.Context, doctorID uint) ([]*model.Availability, error)
+	FindByDoctorIDs(ctx context.Context, doctorIDs []uint) ([]*model.Availability, error)
 	Update(ctx context.Context, availability *model.Availability) error
 	Delete(ctx context.Context, id uint) error
+	ReplaceWindows(ctx context.Context, removeIDs []uint, windows []*model.Availability) error
+	CreateTimeOff(ctx context.Context, timeOff *model.TimeOff) error
+	FindTimeOffByID(ctx context.Context, id uint) (*model.TimeOff, error)
+	FindTimeOff(ctx context.Context, doctorID uint, from, to time.Time) ([]*model.TimeOff, error)
+	FindUpcomingTimeOff(ctx context.Context, doctorID uint, from time.Time) ([]*model.TimeOff, error)
+	UpdateTimeOff(ctx context.Context, timeOff *model.TimeOff) error
+	DeleteTimeOff(ctx context.Context, id uint) error
+	FindCalendarBusy(ctx context.Context, doctorID uint, from, to time.Time) ([]*model.CalendarBusy, error)
 }
 
 // PatientRepository defines operations for patient data access
 type PatientRepository interface {
-	Create(ctx context.Context, patient *model.Patient) error
+	Create(ctx context.Context, patient *model.Patient, events ...*model.OutboxEvent) error
 	FindByID(ctx context.Context, id uint) (*model.Patient, error)
 	FindByUserID(ctx context.Context, userID uint) (*model.Patient, error)
-	Update(ctx context.Context, patient *model.Patient) error
-	Delete(ctx context.Context, id uint) error
+	FindByPublicIDs(ctx context.Context, publicIDs []string) ([]*model.Patient, error)
+	FindAfter(ctx context.Context, afterID uint, limit int) ([]*model.Patient, error)
+	Search(ctx context.Context, search PatientSearch, limit int) ([]*model.Patient, error)
+	Update(ctx context.Context, patient *model.Patient, events ...*model.OutboxEvent) error
+	CountDependents(ctx context.Context, guardianID uint) (int64, error)
+	Delete(ctx context.Context, id uint, events ...*model.OutboxEvent) error
 }
 
 // AppointmentRepository defines the repository interface for appointment operations
 type AppointmentRepository interface {
-	Create(ctx context.Context, appointment *model.Appointment) error
+	Create(ctx context.Context, appointment *model.Appointment, events ...*model.OutboxEvent) error
 	FindByID(ctx context.Context, id uint) (*model.Appointment, error)
-	FindByPatientID(ctx context.Context, patientID uint, limit, offset int) ([]*model.Appointment, int64, error)
-	FindByDoctorID(ctx context.Context, doctorID uint, limit, offset int) ([]*model.Appointment, int64, error)
+	FindByPublicIDs(ctx context.Context, publicIDs []string) ([]*model.Appointment, error)
+	FindByPatientID(ctx context.Context, patientID uint, limit, offset int, opts ListOptions) ([]*model.Appointment, int64, error)
+	FindByDoctorID(ctx context.Context, doctorID uint, limit, offset int, opts ListOptions) ([]*model.Appointment, int64, error)
+	Find(ctx context.Context, filter AppointmentFilter, limit, offset int, opts ListOptions) ([]*model.Appointment, int64, error)
+	SearchEncounters(ctx context.Context, search EncounterSearch, limit int) ([]EncounterMatch, error)
 	FindByDateRange(ctx context.Context, doctorID uint, startDate, endDate string, limit, offset int) ([]*model.Appointment, int64, error)
-	Update(ctx context.Context, appointment *model.Appointment) error
+	FindByDoctorBetween(ctx context.Context, doctorID uint, start, end time.Time) ([]*model.Appointment, error)
+	FindCheckedIn(ctx context.Context, doctorID uint, since time.Time) ([]*model.Appointment, error)
+	FindByDoctorsBetween(ctx context.Context, doctorIDs []uint, start, end time.Time) ([]*model.Appointment, error)
+	FindPatientHistory(ctx context.Context, patientID uint, before time.Time, limit int) ([]*model.Appointment, error)
+	FindUpcomingByDoctor(ctx context.Context, doctorID uint, from time.Time) ([]*model.Appointment, error)
+	FindDueReminders(ctx context.Context, from, to time.Time) ([]*model.Appointment, error)
+	CountDueReminders(ctx context.Context, from, to time.Time) (int64, error)
+	CountCreatedSince(ctx context.Context, since time.Time) (int64, error)
+	CountScheduledByStatus(ctx context.Context, from, to time.Time) ([]StatusCount, error)
+	FindFailedReminders(ctx context.Context, after time.Time) ([]*model.Appointment, error)
+	FindOverdue(ctx context.Context, endedBefore time.Time, limit int) ([]*model.Appointment, error)
+	CountPatientByStatus(ctx context.Context, patientID uint, before time.Time) ([]StatusCount, error)
+	MarkReminderSent(ctx context.Context, id uint, at time.Time) error
+	FindUnconfirmedReminders(ctx context.Context, step int, remindedBefore, after time.Time) ([]*model.Appointment, error)
+	ClaimReminderEscalation(ctx context.Context, id uint, step int) (bool, error)
+	FlagFollowUp(ctx context.Context, id uint, at time.Time) error
+	FindFollowUps(ctx context.Context, doctorIDs []uint, after time.Time) ([]*model.Appointment, error)
+	MarkVisitStarted(ctx context.Context, id uint, at time.Time) error
+	MarkVisitEnded(ctx context.Context, id uint, at time.Time) error
+	Update(ctx context.Context, appointment *model.Appointment, events ...*model.OutboxEvent) error
+	MarkNoShow(ctx context.Context, appointment *model.Appointment, events ...*model.OutboxEvent) (bool, error)
+	CountConfirmationAttempt(ctx context.Context, id uint) (int, error)
+	Reschedule(ctx context.Context, appointment *model.Appointment, history *model.AppointmentHistory, events ...*model.OutboxEvent) error
+	FindHistory(ctx context.Context, appointmentID uint) ([]*model.AppointmentHistory, error)
 	Delete(ctx context.Context, id uint) error
 }
 
+// RecurringAppointmentRepository defines operations for recurring appointment series
+type RecurringAppointmentRepository interface {
+	Create(ctx context.Context, series *model.RecurringAppointment, bookings []SeriesBooking) error
+	FindByID(ctx context.Context, id uint) (*model.RecurringAppointment, error)
+	Save(ctx context.Context, series *model.RecurringAppointment, bookings []SeriesBooking) error
+	Split(ctx context.Context, series, tail *model.RecurringAppointment, bookings []SeriesBooking) error
+}
+
 // SessionRepository defines operations for session data access
 type SessionRepository interface {
 	Create(ctx context.Context, session *model.Session) error
-	FindByToken(ctx context.Context, token string) (*model.Session, error)
+	FindByToken(ctx context.Context, tokenHash string) (*model.Session, error)
+	Touch(ctx context.Context, id uint, at time.Time) error
 	DeleteByUserID(ctx context.Context, userID uint) error
-	DeleteByToken(ctx context.Context, token string) error
+	DeleteByToken(ctx context.Context, tokenHash string) error
 	DeleteExpired(ctx context.Context) error
 }
 
@@ -68,13 +127,342 @@ type MedicalRecordRepository interface {
 	Create(ctx context.Context, record *model.MedicalRecord) error
 	FindByID(ctx context.Context, id uint) (*model.MedicalRecord, error)
 	FindByPatientID(ctx context.Context, patientID uint, limit, offset int) ([]*model.MedicalRecord, int64, error)
-	Update(ctx context.Context, record *model.MedicalRecord) error
+	Revise(ctx context.Context, id uint, apply func(record *model.MedicalRecord) (bool, error)) (*model.MedicalRecord, error)
+	Sign(ctx context.Context, id uint, at time.Time) (bool, error)
+	Delete(ctx context.Context, id uint) error
+	FindVersions(ctx context.Context, recordID uint) ([]*model.MedicalRecordVersion, error)
+	CreateAddendum(ctx context.Context, addendum *model.MedicalRecordAddendum) error
+	FindAddenda(ctx context.Context, recordID uint) ([]*model.MedicalRecordAddendum, error)
+	CreateAttachment(ctx context.Context, attachment *model.MedicalRecordAttachment) error
+	FindAttachmentByID(ctx context.Context, id uint) (*model.MedicalRecordAttachment, error)
+	FindAttachmentByPublicID(ctx context.Context, publicID string) (*model.MedicalRecordAttachment, error)
+	FindAttachmentsByRecordID(ctx context.Context, recordID uint) ([]*model.MedicalRecordAttachment, error)
+	DeleteAttachment(ctx context.Context, id uint) error
+}
+
+// PrescriptionRepository defines operations for the medication catalog and the prescriptions
+// written from it
+type PrescriptionRepository interface {
+	SaveMedications(ctx context.Context, medications []*model.Medication) error
+	FindMedication(ctx context.Context, id uint) (*model.Medication, error)
+	SearchMedications(ctx context.Context, text string, limit int) ([]*model.Medication, error)
+	DeactivateMedication(ctx context.Context, id uint) error
+	Create(ctx context.Context, prescription *model.Prescription) error
+	FindByID(ctx context.Context, id uint) (*model.Prescription, error)
+	FindByPatientID(ctx context.Context, patientID uint, activeAt *time.Time, limit, offset int) ([]*model.Prescription, int64, error)
+	Cancel(ctx context.Context, id uint, reason string, at time.Time) error
+}
+
+// VitalRepository defines operations for patients' vital sign readings
+type VitalRepository interface {
+	Create(ctx context.Context, vital *model.Vital) error
+	FindByID(ctx context.Context, id uint) (*model.Vital, error)
+	Find(ctx context.Context, filter VitalFilter, limit int) ([]*model.Vital, error)
+	Summarize(ctx context.Context, filter VitalFilter) ([]VitalSummary, error)
 	Delete(ctx context.Context, id uint) error
 }
 
+// ClinicalListRepository defines operations for patients' allergy and medication lists
+type ClinicalListRepository interface {
+	CreateAllergy(ctx context.Context, allergy *model.Allergy) error
+	FindAllergy(ctx context.Context, id uint) (*model.Allergy, error)
+	FindAllergies(ctx context.Context, patientID uint, activeOnly bool) ([]*model.Allergy, error)
+	UpdateAllergy(ctx context.Context, allergy *model.Allergy) error
+	DeleteAllergy(ctx context.Context, id uint) error
+	CreateMedication(ctx context.Context, medication *model.PatientMedication) error
+	FindMedication(ctx context.Context, id uint) (*model.PatientMedication, error)
+	FindMedications(ctx context.Context, patientID uint, activeOnly bool) ([]*model.PatientMedication, error)
+	UpdateMedication(ctx context.Context, medication *model.PatientMedication) error
+	DeleteMedication(ctx context.Context, id uint) error
+}
+
+// ImmunizationRepository defines operations for the vaccine doses given to patients
+type ImmunizationRepository interface {
+	Create(ctx context.Context, immunization *model.Immunization) error
+	FindByPatientID(ctx context.Context, patientID uint) ([]*model.Immunization, error)
+}
+
+// APIKeyRepository defines operations for partner API keys and their daily usage counters
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *model.APIKey) error
+	FindByID(ctx context.Context, id uint) (*model.APIKey, error)
+	FindByHash(ctx context.Context, hash string) (*model.APIKey, error)
+	FindActive(ctx context.Context, userID uint) ([]*model.APIKey, error)
+	Update(ctx context.Context, key *model.APIKey) error
+	CountRequest(ctx context.Context, keyID uint, day time.Time) (int64, error)
+	CountRejected(ctx context.Context, keyID uint, day time.Time, counted bool) error
+	FindUsage(ctx context.Context, keyIDs []uint, from, to time.Time) ([]*model.APIKeyUsage, error)
+}
+
+// LabRepository defines operations for lab orders and the results labs report for them
+type LabRepository interface {
+	CreateOrder(ctx context.Context, order *model.LabOrder) error
+	FindOrder(ctx context.Context, id uint) (*model.LabOrder, error)
+	FindOrdersByPatientID(ctx context.Context, patientID uint, limit, offset int) ([]*model.LabOrder, int64, error)
+	SaveResults(ctx context.Context, order *model.LabOrder, results []*model.LabResult) error
+	FindResult(ctx context.Context, id uint) (*model.LabResult, error)
+	FindUnreviewedAbnormal(ctx context.Context, doctorID uint, limit, offset int) ([]*model.LabResult, int64, error)
+	MarkReviewed(ctx context.Context, id, userID uint, at time.Time) (bool, error)
+}
+
+// AccountRepository defines operations for patients' accounts: the payments and credits posted
+// to them and the completed appointments they are charged for
+type AccountRepository interface {
+	CreateEntry(ctx context.Context, entry *model.AccountEntry) error
+	FindEntries(ctx context.Context, patientID uint, before time.Time) ([]*model.AccountEntry, error)
+	FindCharges(ctx context.Context, patientID uint, before time.Time) ([]*model.Appointment, error)
+}
+
+// TelehealthRepository defines operations for video visit waiting rooms
+type TelehealthRepository interface {
+	FindVisit(ctx context.Context, appointmentID uint) (*model.TelehealthVisit, error)
+	UpdateVisit(ctx context.Context, appointmentID, doctorID uint, apply func(*model.TelehealthVisit) ([]*model.WaitingRoomEvent, error)) (*model.TelehealthVisit, error)
+	FindEvents(ctx context.Context, appointmentID uint) ([]*model.WaitingRoomEvent, error)
+	FindEventsAfter(ctx context.Context, filter WaitingRoomEventFilter, afterID uint, limit int) ([]*model.WaitingRoomEvent, error)
+	LastEventID(ctx context.Context, filter WaitingRoomEventFilter) (uint, error)
+	FindVisits(ctx context.Context, from, to time.Time, doctorID uint) ([]*model.TelehealthVisit, error)
+}
+
+// HandoffRepository defines operations for internal handoff notes on patients
+type HandoffRepository interface {
+	Create(ctx context.Context, note *model.HandoffNote) error
+	FindByPatientID(ctx context.Context, patientID uint, limit, offset int) ([]*model.HandoffNote, int64, error)
+	HasTreatmentRelationship(ctx context.Context, doctorID, patientID uint) (bool, error)
+}
+
+// MessageRepository defines operations for secure message data access
+type MessageRepository interface {
+	Create(ctx context.Context, messages ...*model.Message) error
+	FindByPatientID(ctx context.Context, patientID, doctorID uint, limit, offset int) ([]*model.Message, int64, error)
+	FindByDoctorID(ctx context.Context, doctorID uint, limit, offset int) ([]*model.Message, int64, error)
+	HasConversation(ctx context.Context, doctorID, patientID uint) (bool, error)
+}
+
+// ReviewRepository defines operations for doctor review data access
+type ReviewRepository interface {
+	Create(ctx context.Context, review *model.DoctorReview) error
+	Update(ctx context.Context, review *model.DoctorReview) error
+	FindByID(ctx context.Context, id uint) (*model.DoctorReview, error)
+	FindByDoctorID(ctx context.Context, doctorID uint, limit, offset int) ([]*model.DoctorReview, int64, error)
+	ExistsForAppointment(ctx context.Context, appointmentID uint) (bool, error)
+	CountByPatientSince(ctx context.Context, patientID uint, since time.Time) (int64, error)
+	AverageRating(ctx context.Context, doctorID uint) (float64, error)
+}
+
 // AuditLogRepository defines operations for audit log data access
 type AuditLogRepository interface {
 	Create(ctx context.Context, log *model.AuditLog) error
 	FindByUserID(ctx context.Context, userID uint, limit, offset int) ([]*model.AuditLog, int64, error)
 	FindByEntityTypeAndID(ctx context.Context, entityType string, entityID uint, limit, offset int) ([]*model.AuditLog, int64, error)
+	FindAfterID(ctx context.Context, afterID uint, limit int) ([]*model.AuditLog, error)
+	CountAfterID(ctx context.Context, afterID uint) (int64, error)
+	CountByAction(ctx context.Context, actions []string, since time.Time) (map[string]int64, error)
+	GroupAuthEvents(ctx context.Context, query AuthEventQuery) ([]*AuthEventGroup, error)
+}
+
+// RuntimeSettingRepository defines operations for operational settings changed at runtime
+type RuntimeSettingRepository interface {
+	FindAll(ctx context.Context) ([]*model.RuntimeSetting, error)
+	Save(ctx context.Context, settings []*model.RuntimeSetting) error
+	Delete(ctx context.Context, key string) error
+}
+
+// SecurityAlertRepository defines operations for alerts raised on authentication events
+type SecurityAlertRepository interface {
+	Create(ctx context.Context, alert *model.SecurityAlert, events ...*model.OutboxEvent) error
+	FindRecent(ctx context.Context, limit int) ([]*model.SecurityAlert, error)
+	ExistsSince(ctx context.Context, kind, subject string, since time.Time) (bool, error)
+}
+
+// ExportCursorRepository defines operations for tracking export progress
+type ExportCursorRepository interface {
+	Get(ctx context.Context, name string) (uint, error)
+	Save(ctx context.Context, name string, lastID uint) error
+}
+
+// OperationRepository defines operations for asynchronous operation data access
+type OperationRepository interface {
+	Create(ctx context.Context, operation *model.Operation) error
+	FindByPublicID(ctx context.Context, publicID string) (*model.Operation, error)
+	ClaimNext(ctx context.Context, now time.Time, lease time.Duration) (*model.Operation, error)
+	Complete(ctx context.Context, operation *model.Operation, events ...*model.OutboxEvent) error
+	DeleteCompletedBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+// TranslationRepository defines operations for translated doctor content
+type TranslationRepository interface {
+	Save(ctx context.Context, translation *model.Translation) error
+	Delete(ctx context.Context, kind, subject, locale string) error
+	FindBySubject(ctx context.Context, kind, subject string) ([]*model.Translation, error)
+	FindForSubjects(ctx context.Context, kind string, subjects, locales []string) ([]*model.Translation, error)
+}
+
+// CustomRoleRepository defines operations for custom role data access
+type CustomRoleRepository interface {
+	Create(ctx context.Context, role *model.CustomRole) error
+	FindByID(ctx context.Context, id uint) (*model.CustomRole, error)
+	FindAll(ctx context.Context) ([]*model.CustomRole, error)
+	FindByUserID(ctx context.Context, userID uint) ([]*model.CustomRole, error)
+	Update(ctx context.Context, role *model.CustomRole) error
+	Delete(ctx context.Context, id uint) error
+	Assign(ctx context.Context, userID, roleID uint) error
+	Unassign(ctx context.Context, userID, roleID uint) error
+}
+
+// OrganizationRepository defines operations for clinic settings data access
+type OrganizationRepository interface {
+	Create(ctx context.Context, org *model.Organization) error
+	FindByID(ctx context.Context, id uint) (*model.Organization, error)
+	FindDefault(ctx context.Context) (*model.Organization, error)
+	FindAll(ctx context.Context) ([]*model.Organization, error)
+	Update(ctx context.Context, org *model.Organization) error
+	Delete(ctx context.Context, id uint) error
+	AssignDoctor(ctx context.Context, orgID, doctorID uint) error
+}
+
+// AppointmentTypeRepository defines operations for appointment type data access
+type AppointmentTypeRepository interface {
+	Create(ctx context.Context, appointmentType *model.AppointmentType) error
+	FindByID(ctx context.Context, id uint) (*model.AppointmentType, error)
+	FindAll(ctx context.Context) ([]*model.AppointmentType, error)
+	FindActiveByOrganization(ctx context.Context, orgID uint) ([]*model.AppointmentType, error)
+	Update(ctx context.Context, appointmentType *model.AppointmentType) error
+}
+
+// ShiftTemplateRepository defines operations for shift template data access
+type ShiftTemplateRepository interface {
+	Create(ctx context.Context, template *model.ShiftTemplate) error
+	FindByID(ctx context.Context, id uint) (*model.ShiftTemplate, error)
+	FindAll(ctx context.Context) ([]*model.ShiftTemplate, error)
+	Update(ctx context.Context, template *model.ShiftTemplate) error
+	Delete(ctx context.Context, id uint) error
+}
+
+// VisitReasonRepository defines operations for visit reason data access
+type VisitReasonRepository interface {
+	Create(ctx context.Context, reason *model.VisitReason) error
+	FindByID(ctx context.Context, id uint) (*model.VisitReason, error)
+	FindAll(ctx context.Context) ([]*model.VisitReason, error)
+	FindActive(ctx context.Context) ([]*model.VisitReason, error)
+	Update(ctx context.Context, reason *model.VisitReason) error
+}
+
+// ConsentRepository defines operations for policy consent data access
+type ConsentRepository interface {
+	Create(ctx context.Context, consent *model.Consent) error
+	FindByUserID(ctx context.Context, userID uint) ([]*model.Consent, error)
+	Exists(ctx context.Context, userID uint, policyType model.PolicyType, version string) (bool, error)
+	CreateMarketingConsent(ctx context.Context, consent *model.MarketingConsent) error
+	FindMarketingConsents(ctx context.Context, userID uint) ([]*model.MarketingConsent, error)
+	HasMarketingConsent(ctx context.Context, userID uint, channel string) (bool, error)
+}
+
+// BreakGlassRepository defines operations for emergency access data access
+type BreakGlassRepository interface {
+	Create(ctx context.Context, access *model.BreakGlassAccess) error
+	FindActive(ctx context.Context, userID, patientID uint) (*model.BreakGlassAccess, error)
+	FindAll(ctx context.Context, limit, offset int) ([]*model.BreakGlassAccess, int64, error)
+}
+
+// PublicIDRepository defines operations for resolving public identifiers
+type PublicIDRepository interface {
+	ResolveID(ctx context.Context, resource model.PublicResource, publicID string) (uint, error)
+}
+
+// AnalyticsRepository defines operations for clinic analytics data access
+type AnalyticsRepository interface {
+	FindBookingTimes(ctx context.Context, scope AnalyticsScope, start, end time.Time) ([]time.Time, error)
+	FindCancellationTimes(ctx context.Context, scope AnalyticsScope, start, end time.Time) ([]time.Time, error)
+	FindRevenue(ctx context.Context, scope AnalyticsScope, start, end time.Time) ([]RevenueEvent, error)
+	FindFirstBookingTimes(ctx context.Context, scope AnalyticsScope, start, end time.Time) ([]time.Time, error)
+	FindVisitTimings(ctx context.Context, scope AnalyticsScope, start, end time.Time) ([]VisitTiming, error)
+	FindBuckets(ctx context.Context, orgID uint, granularity model.AnalyticsGranularity, start, end time.Time) ([]*model.AnalyticsBucket, error)
+	SaveBuckets(ctx context.Context, buckets []*model.AnalyticsBucket) error
+}
+
+// ProcedureRepository defines operations for the procedure code catalog and the procedures
+// recorded on appointments
+type ProcedureRepository interface {
+	SaveCodes(ctx context.Context, codes []*model.ProcedureCode) error
+	FindCode(ctx context.Context, code string) (*model.ProcedureCode, error)
+	SearchCodes(ctx context.Context, text string, system model.ProcedureCodeSystem, limit int) ([]*model.ProcedureCode, error)
+	DeactivateCode(ctx context.Context, code string) error
+	CreateProcedure(ctx context.Context, procedure *model.AppointmentProcedure) error
+	FindByAppointmentID(ctx context.Context, appointmentID uint) ([]*model.AppointmentProcedure, error)
+	DeleteProcedure(ctx context.Context, appointmentID, id uint) error
+	FindClaims(ctx context.Context, scope AnalyticsScope, start, end time.Time) ([]*model.AppointmentProcedure, error)
+}
+
+// CareRepository defines operations for preventive care rules and the reminders sent for them
+type CareRepository interface {
+	CreateRule(ctx context.Context, rule *model.CareRule) error
+	FindRuleByID(ctx context.Context, id uint) (*model.CareRule, error)
+	FindRules(ctx context.Context) ([]*model.CareRule, error)
+	FindActiveRules(ctx context.Context) ([]*model.CareRule, error)
+	UpdateRule(ctx context.Context, rule *model.CareRule) error
+	FindDuePatients(ctx context.Context, rule *model.CareRule, eligibility CareEligibility, limit int) ([]CareCandidate, error)
+	SaveReminder(ctx context.Context, reminder *model.CareReminder, events ...*model.OutboxEvent) error
+	FindReminderByID(ctx context.Context, id uint) (*model.CareReminder, error)
+	FindOutstandingReminders(ctx context.Context, patientID uint) ([]*model.CareReminder, error)
+}
+
+// EmailRepository defines operations for outbound email records and suppressions
+type EmailRepository interface {
+	CreateMessage(ctx context.Context, message *model.EmailMessage) error
+	FindMessageByMessageID(ctx context.Context, messageID string) (*model.EmailMessage, error)
+	UpdateMessage(ctx context.Context, message *model.EmailMessage) error
+	FindMessages(ctx context.Context, recipient string, status model.EmailStatus, limit, offset int) ([]*model.EmailMessage, int64, error)
+	IsSuppressed(ctx context.Context, email string) (bool, error)
+	Suppress(ctx context.Context, suppression *model.EmailSuppression) error
+	FindSuppressions(ctx context.Context, limit, offset int) ([]*model.EmailSuppression, int64, error)
+	DeleteSuppression(ctx context.Context, email string) error
+	CountMessages(ctx context.Context, status model.EmailStatus, since time.Time) (int64, error)
+}
+
+// NotificationRepository defines operations for the notification log
+type NotificationRepository interface {
+	Create(ctx context.Context, notification *model.Notification) error
+	Update(ctx context.Context, notification *model.Notification) error
+	FindByAppointmentID(ctx context.Context, appointmentID uint) ([]*model.Notification, error)
+	FindBouncedEmails(ctx context.Context, kind string, since time.Time) ([]*model.Notification, error)
+}
+
+// OutboxRepository defines operations for domain events awaiting delivery. Events are written
+// by the repositories of the changes they describe, in the same transaction.
+type OutboxRepository interface {
+	ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*model.OutboxEvent, error)
+	Update(ctx context.Context, event *model.OutboxEvent) error
+	CountByStatus(ctx context.Context, status model.OutboxStatus) (int64, error)
+	RetryFailed(ctx context.Context) (int64, error)
+	DeleteDispatchedBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+// CalendarSyncRepository defines operations for external calendar connections, the events pushed
+// to them and the busy times pulled from them
+type CalendarSyncRepository interface {
+	FindConnectionByUserID(ctx context.Context, userID uint) (*model.CalendarConnection, error)
+	FindConnections(ctx context.Context) ([]*model.CalendarConnection, error)
+	SaveConnection(ctx context.Context, connection *model.CalendarConnection) error
+	DeleteConnection(ctx context.Context, id uint) error
+	FindEvents(ctx context.Context, connectionID uint) ([]*model.CalendarEvent, error)
+	SaveEvent(ctx context.Context, event *model.CalendarEvent) error
+	DeleteEvent(ctx context.Context, id uint) error
+	ReplaceBusy(ctx context.Context, connectionID uint, busy []*model.CalendarBusy) error
+}
+
+// BookingLockRepository defines per-doctor locks shared by API instances. Locks expire after
+// their TTL, so one left by a crashed instance does not block the doctor for long.
+type BookingLockRepository interface {
+	Acquire(ctx context.Context, doctorID uint, token string, ttl time.Duration) (bool, error)
+	Release(ctx context.Context, doctorID uint, token string) error
+}
+
+// SlotHoldRepository defines operations for short-lived slot holds. Expired holds are removed
+// by the store.
+type SlotHoldRepository interface {
+	Create(ctx context.Context, hold *model.SlotHold) (bool, error)
+	Find(ctx context.Context, doctorID uint, start time.Time) (*model.SlotHold, error)
+	FindHeld(ctx context.Context, doctorID uint, starts []time.Time) (map[int64]*model.SlotHold, error)
+	DeleteByToken(ctx context.Context, token string) (*model.SlotHold, error)
+	DeleteSlot(ctx context.Context, doctorID uint, start time.Time) error
 }
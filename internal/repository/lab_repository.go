@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type labRepository struct {
+	db *gorm.DB
+}
+
+// NewLabRepository creates a new lab order and result repository
+func NewLabRepository(db *gorm.DB) LabRepository {
+	return &labRepository{
+		db: db,
+	}
+}
+
+// CreateOrder records a lab order
+func (r *labRepository) CreateOrder(ctx context.Context, order *model.LabOrder) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Create(order).Error
+}
+
+// FindOrder finds a lab order by ID with its ordering doctor and results
+func (r *labRepository) FindOrder(ctx context.Context, id uint) (*model.LabOrder, error) {
+	var order model.LabOrder
+	if err := r.db.WithContext(ctx).
+		Preload("Doctor.User").
+		Preload("Results", func(db *gorm.DB) *gorm.DB { return db.Order("test_code") }).
+		First(&order, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("lab order not found")
+		}
+		return nil, err
+	}
+	return &order, nil
+}
+
+// FindOrdersByPatientID lists a patient's lab orders with their results, most recent first
+func (r *labRepository) FindOrdersByPatientID(ctx context.Context, patientID uint, limit, offset int) ([]*model.LabOrder, int64, error) {
+	query := r.db.WithContext(ctx).Model(&model.LabOrder{}).Where("patient_id = ?", patientID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var orders []*model.LabOrder
+	err := query.
+		Preload("Doctor.User").
+		Preload("Results", func(db *gorm.DB) *gorm.DB { return db.Order("test_code") }).
+		Order("ordered_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&orders).Error
+	return orders, total, err
+}
+
+// SaveResults records results of a lab order, replacing earlier results for the same tests, and
+// updates the order's status. A replaced result has to be reviewed again.
+func (r *labRepository) SaveResults(ctx context.Context, order *model.LabOrder, results []*model.LabResult) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "order_id"}, {Name: "test_code"}},
+			DoUpdates: clause.AssignmentColumns([]string{
+				"test_name", "value", "unit", "reference_low", "reference_high", "reference_range",
+				"flag", "abnormal", "observed_at", "received_at", "reviewed_at", "reviewed_by_id", "updated_at",
+			}),
+		}).Create(&results).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.LabOrder{}).
+			Where("id = ?", order.ID).
+			Updates(map[string]interface{}{
+				"status":      order.Status,
+				"resulted_at": order.ResultedAt,
+				"updated_at":  order.UpdatedAt,
+			}).Error
+	})
+}
+
+// FindResult finds a lab result by ID with its order
+func (r *labRepository) FindResult(ctx context.Context, id uint) (*model.LabResult, error) {
+	var result model.LabResult
+	if err := r.db.WithContext(ctx).Preload("Order").First(&result, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("lab result not found")
+		}
+		return nil, err
+	}
+	return &result, nil
+}
+
+// FindUnreviewedAbnormal lists the abnormal results of a doctor's orders no one has reviewed,
+// critical ones first and then most recently received first
+func (r *labRepository) FindUnreviewedAbnormal(ctx context.Context, doctorID uint, limit, offset int) ([]*model.LabResult, int64, error) {
+	query := r.db.WithContext(ctx).
+		Model(&model.LabResult{}).
+		Joins("JOIN lab_orders ON lab_orders.id = lab_results.order_id").
+		Where("lab_orders.doctor_id = ? AND lab_results.abnormal AND lab_results.reviewed_at IS NULL", doctorID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var results []*model.LabResult
+	err := query.
+		Preload("Order.Patient.User").
+		Order("CASE WHEN lab_results.flag IN ('LL', 'HH') THEN 0 ELSE 1 END, lab_results.received_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&results).Error
+	return results, total, err
+}
+
+// MarkReviewed records that a doctor reviewed a result. It reports false without writing
+// anything if the result was already reviewed.
+func (r *labRepository) MarkReviewed(ctx context.Context, id, userID uint, at time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&model.LabResult{}).
+		Where("id = ? AND reviewed_at IS NULL", id).
+		Updates(map[string]interface{}{"reviewed_at": at, "reviewed_by_id": userID, "updated_at": at})
+	return result.RowsAffected > 0, result.Error
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// ErrUnknownSortKey is returned when a list is asked to sort by a key it does not support
+var ErrUnknownSortKey = errors.New("unknown sort key")
+
+// SortField orders a list by one of its sort keys
+type SortField struct {
+	Key  string
+	Desc bool
+}
+
+// ListOptions shapes the records returned by a list query
+type ListOptions struct {
+	// Columns restricts the columns loaded for the listed records; all are loaded when empty.
+	// It must include the primary and foreign keys of any preloaded association.
+	Columns []string
+	// Preloads replaces the associations the query loads by default; nil keeps the defaults
+	Preloads []string
+	// Sort replaces the default ordering, most significant key first
+	Sort []SortField
+}
+
+// apply adds the column projection and preloads to a query
+func (o ListOptions) apply(query *gorm.DB, defaultPreloads ...string) *gorm.DB {
+	if len(o.Columns) > 0 {
+		query = query.Select(o.Columns)
+	}
+	preloads := defaultPreloads
+	if o.Preloads != nil {
+		preloads = o.Preloads
+	}
+	for _, preload := range preloads {
+		query = query.Preload(preload)
+	}
+	return query
+}
+
+// orderBy returns the ORDER BY expression for the requested sort. Keys are looked up in
+// sortKeys, which maps the keys a list supports to SQL expressions; requests are never
+// interpolated into the query. The table's id breaks ties so pages do not overlap. Without a
+// requested sort, defaultOrder is returned.
+func (o ListOptions) orderBy(table string, sortKeys map[string]string, defaultOrder string) (string, error) {
+	if len(o.Sort) == 0 {
+		return defaultOrder, nil
+	}
+
+	order := ""
+	for _, field := range o.Sort {
+		expr, ok := sortKeys[field.Key]
+		if !ok {
+			return "", fmt.Errorf("%w: %q", ErrUnknownSortKey, field.Key)
+		}
+		order += expr + direction(field.Desc) + ", "
+	}
+	return order + table + ".id" + direction(o.Sort[0].Desc), nil
+}
+
+func direction(desc bool) string {
+	if desc {
+		return " DESC"
+	}
+	return " ASC"
+}
@@ -0,0 +1,221 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type medicalRecordRepository struct {
+	db *gorm.DB
+}
+
+// NewMedicalRecordRepository creates a new medical record repository
+func NewMedicalRecordRepository(db *gorm.DB) MedicalRecordRepository {
+	return &medicalRecordRepository{
+		db: db,
+	}
+}
+
+// Create creates a medical record with its first version
+func (r *medicalRecordRepository) Create(ctx context.Context, record *model.MedicalRecord) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Patient", "Doctor").Create(record).Error; err != nil {
+			return err
+		}
+		return createRecordVersion(tx, record, record.CreatedAt)
+	})
+}
+
+// FindByID finds a medical record by ID with its doctor and the patient's active allergies
+func (r *medicalRecordRepository) FindByID(ctx context.Context, id uint) (*model.MedicalRecord, error) {
+	var record model.MedicalRecord
+	if err := r.db.WithContext(ctx).
+		Preload("Doctor.User").
+		Preload("Patient.Allergies", activeAllergies).
+		First(&record, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("medical record not found")
+		}
+		return nil, err
+	}
+	return &record, nil
+}
+
+// FindByPatientID finds a patient's medical records with pagination, most recent visit first,
+// with the patient's active allergies
+func (r *medicalRecordRepository) FindByPatientID(ctx context.Context, patientID uint, limit, offset int) ([]*model.MedicalRecord, int64, error) {
+	var records []*model.MedicalRecord
+	var count int64
+
+	query := r.db.WithContext(ctx).Model(&model.MedicalRecord{}).Where("patient_id = ?", patientID)
+	if err := query.Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if err := query.
+		Preload("Doctor.User").
+		Preload("Patient.Allergies", activeAllergies).
+		Order("visit_date DESC, id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&records).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return records, count, nil
+}
+
+// Revise changes a medical record under a row lock. apply checks the record and changes it,
+// reporting whether it did; a changed record is saved as its next version, dated now. The
+// record is returned as FindByID finds it.
+func (r *medicalRecordRepository) Revise(ctx context.Context, id uint, apply func(record *model.MedicalRecord) (bool, error)) (*model.MedicalRecord, error) {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var record model.MedicalRecord
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&record, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("medical record not found")
+			}
+			return err
+		}
+
+		changed, err := apply(&record)
+		if err != nil || !changed {
+			return err
+		}
+		record.Version++
+		record.UpdatedAt = time.Now()
+		if err := tx.Omit(clause.Associations).Save(&record).Error; err != nil {
+			return err
+		}
+		return createRecordVersion(tx, &record, record.UpdatedAt)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.FindByID(ctx, id)
+}
+
+// createRecordVersion adds the record's content as its version record.Version, written by its
+// doctor at the given time
+func createRecordVersion(tx *gorm.DB, record *model.MedicalRecord, at time.Time) error {
+	return tx.Create(&model.MedicalRecordVersion{
+		RecordID:  record.ID,
+		Version:   record.Version,
+		Diagnosis: record.Diagnosis,
+		Notes:     record.Notes,
+		DoctorID:  record.DoctorID,
+		CreatedAt: at,
+	}).Error
+}
+
+// Sign locks a medical record, unless it is already signed
+func (r *medicalRecordRepository) Sign(ctx context.Context, id uint, at time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.MedicalRecord{}).
+		Where("id = ? AND signed_at IS NULL", id).
+		UpdateColumn("signed_at", at)
+	return result.RowsAffected > 0, result.Error
+}
+
+// FindVersions finds the versions of a medical record, oldest first, with their doctors
+func (r *medicalRecordRepository) FindVersions(ctx context.Context, recordID uint) ([]*model.MedicalRecordVersion, error) {
+	var versions []*model.MedicalRecordVersion
+	err := r.db.WithContext(ctx).
+		Preload("Doctor.User").
+		Where("record_id = ?", recordID).
+		Order("version").
+		Find(&versions).Error
+	return versions, err
+}
+
+// CreateAddendum adds an addendum to a medical record
+func (r *medicalRecordRepository) CreateAddendum(ctx context.Context, addendum *model.MedicalRecordAddendum) error {
+	return r.db.WithContext(ctx).Omit("Doctor").Create(addendum).Error
+}
+
+// FindAddenda finds the addenda of a medical record, oldest first, with their doctors
+func (r *medicalRecordRepository) FindAddenda(ctx context.Context, recordID uint) ([]*model.MedicalRecordAddendum, error) {
+	var addenda []*model.MedicalRecordAddendum
+	err := r.db.WithContext(ctx).
+		Preload("Doctor.User").
+		Where("record_id = ?", recordID).
+		Order("created_at, id").
+		Find(&addenda).Error
+	return addenda, err
+}
+
+// Delete deletes a medical record with its attachment rows, versions and addenda, unlinking its prescriptions
+func (r *medicalRecordRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("record_id = ?", id).Delete(&model.MedicalRecordAttachment{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("record_id = ?", id).Delete(&model.MedicalRecordVersion{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("record_id = ?", id).Delete(&model.MedicalRecordAddendum{}).Error; err != nil {
+			return err
+		}
+		// Prescriptions, vital signs and lab orders stay valid without the record they were written with
+		if err := tx.Model(&model.Prescription{}).Where("record_id = ?", id).Update("record_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.Vital{}).Where("record_id = ?", id).Update("record_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.LabOrder{}).Where("record_id = ?", id).Update("record_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.MedicalRecord{}, id).Error
+	})
+}
+
+// CreateAttachment records a file attached to a medical record
+func (r *medicalRecordRepository) CreateAttachment(ctx context.Context, attachment *model.MedicalRecordAttachment) error {
+	return r.db.WithContext(ctx).Create(attachment).Error
+}
+
+// FindAttachmentByID finds an attachment by ID
+func (r *medicalRecordRepository) FindAttachmentByID(ctx context.Context, id uint) (*model.MedicalRecordAttachment, error) {
+	var attachment model.MedicalRecordAttachment
+	if err := r.db.WithContext(ctx).First(&attachment, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("attachment not found")
+		}
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+// FindAttachmentByPublicID finds an attachment by its public ID
+func (r *medicalRecordRepository) FindAttachmentByPublicID(ctx context.Context, publicID string) (*model.MedicalRecordAttachment, error) {
+	var attachment model.MedicalRecordAttachment
+	if err := r.db.WithContext(ctx).Where("public_id = ?", publicID).First(&attachment).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("attachment not found")
+		}
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+// FindAttachmentsByRecordID lists the files attached to a medical record, oldest first
+func (r *medicalRecordRepository) FindAttachmentsByRecordID(ctx context.Context, recordID uint) ([]*model.MedicalRecordAttachment, error) {
+	var attachments []*model.MedicalRecordAttachment
+	if err := r.db.WithContext(ctx).
+		Where("record_id = ?", recordID).
+		Order("created_at, id").
+		Find(&attachments).Error; err != nil {
+		return nil, err
+	}
+	return attachments, nil
+}
+
+// DeleteAttachment deletes an attachment row
+func (r *medicalRecordRepository) DeleteAttachment(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&model.MedicalRecordAttachment{}, id).Error
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"gorm.io/gorm"
+)
+
+type messageRepository struct {
+	db *gorm.DB
+}
+
+// NewMessageRepository creates a new secure message repository
+func NewMessageRepository(db *gorm.DB) MessageRepository {
+	return &messageRepository{
+		db: db,
+	}
+}
+
+// Create creates messages in one transaction, so a message and its automatic reply are saved
+// together
+func (r *messageRepository) Create(ctx context.Context, messages ...*model.Message) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, message := range messages {
+			if err := tx.Omit("Patient", "Doctor", "AddressedTo").Create(message).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// FindByPatientID finds a patient's messages with pagination, most recent first. A doctorID
+// other than 0 keeps only the conversation with that doctor, including messages addressed to
+// them that were routed to a covering doctor.
+func (r *messageRepository) FindByPatientID(ctx context.Context, patientID, doctorID uint, limit, offset int) ([]*model.Message, int64, error) {
+	query := r.db.WithContext(ctx).Model(&model.Message{}).Where("patient_id = ?", patientID)
+	if doctorID != 0 {
+		query = query.Where("doctor_id = ? OR addressed_to_id = ?", doctorID, doctorID)
+	}
+	return r.find(query, limit, offset)
+}
+
+// FindByDoctorID finds the messages of a doctor's conversations with pagination, most recent
+// first: those routed to them while covering for another doctor, and those addressed to them
+// that went to their covering doctor
+func (r *messageRepository) FindByDoctorID(ctx context.Context, doctorID uint, limit, offset int) ([]*model.Message, int64, error) {
+	query := r.db.WithContext(ctx).Model(&model.Message{}).
+		Where("doctor_id = ? OR addressed_to_id = ?", doctorID, doctorID)
+	return r.find(query, limit, offset)
+}
+
+// HasConversation reports whether a doctor has messages with a patient, such as messages routed
+// to them while covering for the patient's doctor
+func (r *messageRepository) HasConversation(ctx context.Context, doctorID, patientID uint) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&model.Message{}).
+		Where("doctor_id = ? AND patient_id = ?", doctorID, patientID).
+		Limit(1).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *messageRepository) find(query *gorm.DB, limit, offset int) ([]*model.Message, int64, error) {
+	var messages []*model.Message
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if err := query.
+		Preload("Patient.User").
+		Preload("Doctor.User").
+		Preload("AddressedTo.User").
+		Order("created_at DESC, id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&messages).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return messages, count, nil
+}
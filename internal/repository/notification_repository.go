@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"gorm.io/gorm"
+)
+
+type notificationRepository struct {
+	db *gorm.DB
+}
+
+// NewNotificationRepository creates a new notification repository
+func NewNotificationRepository(db *gorm.DB) NotificationRepository {
+	return &notificationRepository{
+		db: db,
+	}
+}
+
+// Create logs a notification attempt
+func (r *notificationRepository) Create(ctx context.Context, notification *model.Notification) error {
+	return r.db.WithContext(ctx).Create(notification).Error
+}
+
+// Update updates a notification attempt
+func (r *notificationRepository) Update(ctx context.Context, notification *model.Notification) error {
+	return r.db.WithContext(ctx).Save(notification).Error
+}
+
+// FindByAppointmentID finds the notification attempts for an appointment, oldest first
+func (r *notificationRepository) FindByAppointmentID(ctx context.Context, appointmentID uint) ([]*model.Notification, error) {
+	var notifications []*model.Notification
+	if err := r.db.WithContext(ctx).
+		Where("appointment_id = ?", appointmentID).
+		Order("created_at ASC").
+		Find(&notifications).Error; err != nil {
+		return nil, err
+	}
+	return notifications, nil
+}
+
+// FindBouncedEmails finds email notifications of a kind sent since the given time whose
+// message the provider has since reported as bounced
+func (r *notificationRepository) FindBouncedEmails(ctx context.Context, kind string, since time.Time) ([]*model.Notification, error) {
+	var notifications []*model.Notification
+	if err := r.db.WithContext(ctx).
+		Joins("JOIN email_messages ON email_messages.message_id = notifications.message_id").
+		Where("notifications.kind = ? AND notifications.channel = ? AND notifications.status = ? AND notifications.created_at >= ?",
+			kind, model.ChannelEmail, model.NotificationStatusSent, since).
+		Where("email_messages.status = ?", model.EmailStatusBounced).
+		Find(&notifications).Error; err != nil {
+		return nil, err
+	}
+	return notifications, nil
+}
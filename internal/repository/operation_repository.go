@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type operationRepository struct {
+	db *gorm.DB
+}
+
+// NewOperationRepository creates a new operation repository
+func NewOperationRepository(db *gorm.DB) OperationRepository {
+	return &operationRepository{
+		db: db,
+	}
+}
+
+// Create stores a new operation
+func (r *operationRepository) Create(ctx context.Context, operation *model.Operation) error {
+	return r.db.WithContext(ctx).Create(operation).Error
+}
+
+// FindByPublicID finds an operation by its public ID
+func (r *operationRepository) FindByPublicID(ctx context.Context, publicID string) (*model.Operation, error) {
+	var operation model.Operation
+	if err := r.db.WithContext(ctx).Where("public_id = ?", publicID).First(&operation).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("operation not found")
+		}
+		return nil, err
+	}
+	return &operation, nil
+}
+
+// ClaimNext marks the oldest pending operation, or a running one whose claim has run out, as
+// running until now plus lease and returns it. Rows locked by a concurrent claim are skipped.
+// It returns nil when there is nothing to run.
+func (r *operationRepository) ClaimNext(ctx context.Context, now time.Time, lease time.Duration) (*model.Operation, error) {
+	var operations []*model.Operation
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? OR (status = ? AND claimed_until <= ?)", model.OperationStatusPending, model.OperationStatusRunning, now).
+			Order("id ASC").
+			Limit(1).
+			Find(&operations).Error; err != nil {
+			return err
+		}
+		if len(operations) == 0 {
+			return nil
+		}
+
+		operation := operations[0]
+		claimedUntil := now.Add(lease)
+		operation.Status = model.OperationStatusRunning
+		operation.ClaimedUntil = &claimedUntil
+		operation.Attempts++
+		if operation.StartedAt == nil {
+			operation.StartedAt = &now
+		}
+		return tx.Save(operation).Error
+	})
+	if err != nil || len(operations) == 0 {
+		return nil, err
+	}
+	return operations[0], nil
+}
+
+// Complete saves the outcome of an operation together with the events announcing it
+func (r *operationRepository) Complete(ctx context.Context, operation *model.Operation, events ...*model.OutboxEvent) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(operation).Error; err != nil {
+			return err
+		}
+		return createOutboxEvents(tx, "operation", operation.ID, events)
+	})
+}
+
+// DeleteCompletedBefore deletes operations that finished before the given time
+func (r *operationRepository) DeleteCompletedBefore(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("status IN ? AND completed_at < ?",
+			[]model.OperationStatus{model.OperationStatusSucceeded, model.OperationStatusFailed}, before).
+		Delete(&model.Operation{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"gorm.io/gorm"
+)
+
+type organizationRepository struct {
+	db *gorm.DB
+}
+
+// NewOrganizationRepository creates a new organization repository
+func NewOrganizationRepository(db *gorm.DB) OrganizationRepository {
+	return &organizationRepository{
+		db: db,
+	}
+}
+
+// Create creates a new organization
+func (r *organizationRepository) Create(ctx context.Context, org *model.Organization) error {
+	return r.db.WithContext(ctx).Create(org).Error
+}
+
+// FindByID finds an organization by ID
+func (r *organizationRepository) FindByID(ctx context.Context, id uint) (*model.Organization, error) {
+	var org model.Organization
+	if err := r.db.WithContext(ctx).First(&org, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("organization not found")
+		}
+		return nil, err
+	}
+	return &org, nil
+}
+
+// FindDefault finds the first organization created, used when no clinic is specified
+func (r *organizationRepository) FindDefault(ctx context.Context) (*model.Organization, error) {
+	var org model.Organization
+	if err := r.db.WithContext(ctx).Order("id").First(&org).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("organization not found")
+		}
+		return nil, err
+	}
+	return &org, nil
+}
+
+// FindAll finds all organizations ordered by name
+func (r *organizationRepository) FindAll(ctx context.Context) ([]*model.Organization, error) {
+	var orgs []*model.Organization
+	if err := r.db.WithContext(ctx).Order("name").Find(&orgs).Error; err != nil {
+		return nil, err
+	}
+	return orgs, nil
+}
+
+// Update updates an organization
+func (r *organizationRepository) Update(ctx context.Context, org *model.Organization) error {
+	return r.db.WithContext(ctx).Save(org).Error
+}
+
+// Delete deletes an organization and detaches its doctors
+func (r *organizationRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.Doctor{}).Where("organization_id = ?", id).
+			Update("organization_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.Organization{}, id).Error
+	})
+}
+
+// AssignDoctor makes a doctor part of an organization
+func (r *organizationRepository) AssignDoctor(ctx context.Context, orgID, doctorID uint) error {
+	result := r.db.WithContext(ctx).Model(&model.Doctor{}).Where("id = ?", doctorID).
+		Update("organization_id", orgID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("doctor not found")
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type outboxRepository struct {
+	db *gorm.DB
+}
+
+// NewOutboxRepository creates a new outbox repository
+func NewOutboxRepository(db *gorm.DB) OutboxRepository {
+	return &outboxRepository{
+		db: db,
+	}
+}
+
+// createOutboxEvents stores events about an aggregate within the transaction that changed it
+func createOutboxEvents(tx *gorm.DB, aggregateType string, aggregateID uint, events []*model.OutboxEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	for _, event := range events {
+		event.AggregateType = aggregateType
+		event.AggregateID = aggregateID
+	}
+	return tx.Create(events).Error
+}
+
+// ClaimDue returns up to limit pending events that are due, oldest first, and pushes their next
+// attempt back by lease so other dispatchers skip them. Rows locked by a concurrent claim are
+// skipped rather than waited for. If the claiming dispatcher dies, the events become due again
+// once the lease runs out.
+func (r *outboxRepository) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*model.OutboxEvent, error) {
+	var events []*model.OutboxEvent
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", model.OutboxStatusPending, now).
+			Order("id ASC").
+			Limit(limit).
+			Find(&events).Error; err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			return nil
+		}
+
+		ids := make([]uint, len(events))
+		for i, event := range events {
+			ids[i] = event.ID
+		}
+		return tx.Model(&model.OutboxEvent{}).
+			Where("id IN ?", ids).
+			Update("next_attempt_at", now.Add(lease)).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// Update saves the delivery state of an event
+func (r *outboxRepository) Update(ctx context.Context, event *model.OutboxEvent) error {
+	return r.db.WithContext(ctx).Save(event).Error
+}
+
+// CountByStatus counts events with the given status
+func (r *outboxRepository) CountByStatus(ctx context.Context, status model.OutboxStatus) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&model.OutboxEvent{}).
+		Where("status = ?", status).
+		Count(&count).Error
+	return count, err
+}
+
+// RetryFailed makes events that were given up on due again with a fresh set of attempts
+func (r *outboxRepository) RetryFailed(ctx context.Context) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Model(&model.OutboxEvent{}).
+		Where("status = ?", model.OutboxStatusFailed).
+		Updates(map[string]interface{}{
+			"status":          model.OutboxStatusPending,
+			"attempts":        0,
+			"next_attempt_at": time.Now(),
+		})
+	return result.RowsAffected, result.Error
+}
+
+// DeleteDispatchedBefore deletes events delivered before the given time
+func (r *outboxRepository) DeleteDispatchedBefore(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("status = ? AND dispatched_at < ?", model.OutboxStatusDispatched, before).
+		Delete(&model.OutboxEvent{})
+	return result.RowsAffected, result.Error
+}
@@ -3,11 +3,22 @@ package repository
 import (
 	"context"
 	"errors"
+	"strings"
+	"time"
 
 	"github.com/whitewalker-sa/ehass/internal/model"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// PatientSearch describes what a patient search matches. Set fields are alternatives: a patient
+// matching any of them is found.
+type PatientSearch struct {
+	Text        string     // Substring of the name or email
+	Phone       string     // Digits contained in the phone number
+	DateOfBirth *time.Time // Day of birth
+}
+
 type patientRepository struct {
 	db *gorm.DB
 }
@@ -19,9 +30,14 @@ func NewPatientRepository(db *gorm.DB) PatientRepository {
 	}
 }
 
-// Create creates a new patient
-func (r *patientRepository) Create(ctx context.Context, patient *model.Patient) error {
-	return r.db.WithContext(ctx).Create(patient).Error
+// Create creates a new patient, writing any outbox events in the same transaction
+func (r *patientRepository) Create(ctx context.Context, patient *model.Patient, events ...*model.OutboxEvent) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(patient).Error; err != nil {
+			return err
+		}
+		return createOutboxEvents(tx, "patient", patient.ID, events)
+	})
 }
 
 // FindByID finds a patient by ID with preloaded user data
@@ -50,12 +66,110 @@ func (r *patientRepository) FindByUserID(ctx context.Context, userID uint) (*mod
 	return &patient, nil
 }
 
-// Update updates a patient
-func (r *patientRepository) Update(ctx context.Context, patient *model.Patient) error {
-	return r.db.WithContext(ctx).Save(patient).Error
+// FindByPublicIDs finds the patients with the given public IDs, in no particular order
+func (r *patientRepository) FindByPublicIDs(ctx context.Context, publicIDs []string) ([]*model.Patient, error) {
+	var patients []*model.Patient
+	err := r.db.WithContext(ctx).
+		Preload("User").
+		Where("public_id IN ?", publicIDs).
+		Find(&patients).Error
+	return patients, err
+}
+
+// FindAfter returns up to limit patients with IDs above afterID in ID order, for walking the table in batches
+func (r *patientRepository) FindAfter(ctx context.Context, afterID uint, limit int) ([]*model.Patient, error) {
+	var patients []*model.Patient
+	err := r.db.WithContext(ctx).
+		Preload("User").
+		Where("id > ?", afterID).
+		Order("id ASC").
+		Limit(limit).
+		Find(&patients).Error
+	return patients, err
+}
+
+// Search finds patients matching the search, best name matches first. Name, email and phone
+// matches are served by the trigram indexes from the patient search migration.
+func (r *patientRepository) Search(ctx context.Context, search PatientSearch, limit int) ([]*model.Patient, error) {
+	var conditions []string
+	var args []interface{}
+	if search.Text != "" {
+		pattern := "%" + escapeLike(search.Text) + "%"
+		conditions = append(conditions, "users.name ILIKE ?", "users.email ILIKE ?")
+		args = append(args, pattern, pattern)
+	}
+	if search.Phone != "" {
+		conditions = append(conditions, "regexp_replace(users.phone, '[^0-9]', '', 'g') LIKE ?")
+		args = append(args, "%"+search.Phone+"%")
+	}
+	if search.DateOfBirth != nil {
+		day := search.DateOfBirth.UTC().Truncate(24 * time.Hour)
+		conditions = append(conditions, "(patients.date_of_birth >= ? AND patients.date_of_birth < ?)")
+		args = append(args, day, day.AddDate(0, 0, 1))
+	}
+	if len(conditions) == 0 {
+		return nil, nil
+	}
+
+	query := r.db.WithContext(ctx).
+		Preload("User").
+		Joins("JOIN users ON users.id = patients.user_id").
+		Where(strings.Join(conditions, " OR "), args...)
+	if search.Text != "" {
+		query = query.Order(clause.OrderBy{Expression: clause.Expr{
+			SQL:  "similarity(users.name, ?) DESC",
+			Vars: []interface{}{search.Text},
+		}})
+	}
+
+	var patients []*model.Patient
+	err := query.Order("users.name ASC").Limit(limit).Find(&patients).Error
+	return patients, err
 }
 
-// Delete soft deletes a patient
-func (r *patientRepository) Delete(ctx context.Context, id uint) error {
-	return r.db.WithContext(ctx).Delete(&model.Patient{}, id).Error
+// escapeLike escapes the LIKE wildcards in s so it matches literally
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// Update updates a patient along with its outbox events
+func (r *patientRepository) Update(ctx context.Context, patient *model.Patient, events ...*model.OutboxEvent) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(patient).Error; err != nil {
+			return err
+		}
+		return createOutboxEvents(tx, "patient", patient.ID, events)
+	})
+}
+
+// CountDependents counts the patients whose records are managed by the patient
+func (r *patientRepository) CountDependents(ctx context.Context, guardianID uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&model.Patient{}).Where("guardian_id = ?", guardianID).Count(&count).Error
+	return count, err
+}
+
+// Delete soft deletes a patient along with its outbox events
+func (r *patientRepository) Delete(ctx context.Context, id uint, events ...*model.OutboxEvent) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("patient_id = ?", id).Delete(&model.CareReminder{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("patient_id = ?", id).Delete(&model.HandoffNote{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("patient_id = ?", id).Delete(&model.DoctorReview{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("patient_id = ?", id).Delete(&model.AppointmentParticipant{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.Patient{}).Where("guardian_id = ?", id).Update("guardian_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&model.Patient{}, id).Error; err != nil {
+			return err
+		}
+		return createOutboxEvents(tx, "patient", id, events)
+	})
 }
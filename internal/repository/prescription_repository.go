@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type prescriptionRepository struct {
+	db *gorm.DB
+}
+
+// NewPrescriptionRepository creates a new prescription repository
+func NewPrescriptionRepository(db *gorm.DB) PrescriptionRepository {
+	return &prescriptionRepository{
+		db: db,
+	}
+}
+
+// SaveMedications adds products to the medication catalog, replacing the route and status of
+// products already in it
+func (r *prescriptionRepository) SaveMedications(ctx context.Context, medications []*model.Medication) error {
+	if len(medications) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "name"}, {Name: "form"}, {Name: "strength"}},
+		DoUpdates: clause.AssignmentColumns([]string{"route", "active", "updated_at"}),
+	}).CreateInBatches(medications, 500).Error
+}
+
+// FindMedication finds a catalog entry by ID
+func (r *prescriptionRepository) FindMedication(ctx context.Context, id uint) (*model.Medication, error) {
+	var medication model.Medication
+	if err := r.db.WithContext(ctx).First(&medication, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("medication not found")
+		}
+		return nil, err
+	}
+	return &medication, nil
+}
+
+// SearchMedications finds active products whose name starts with text, ordered by name, form
+// and strength
+func (r *prescriptionRepository) SearchMedications(ctx context.Context, text string, limit int) ([]*model.Medication, error) {
+	query := r.db.WithContext(ctx).Where("active = ?", true)
+	if text != "" {
+		query = query.Where("name ILIKE ?", escapeLike(text)+"%")
+	}
+
+	var medications []*model.Medication
+	err := query.Order("name, form, strength").Limit(limit).Find(&medications).Error
+	return medications, err
+}
+
+// DeactivateMedication retires a product so it can no longer be prescribed
+func (r *prescriptionRepository) DeactivateMedication(ctx context.Context, id uint) error {
+	result := r.db.WithContext(ctx).
+		Model(&model.Medication{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{"active": false, "updated_at": time.Now()})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("medication not found")
+	}
+	return nil
+}
+
+// Create records a prescription
+func (r *prescriptionRepository) Create(ctx context.Context, prescription *model.Prescription) error {
+	return r.db.WithContext(ctx).Omit("Patient", "Doctor", "Medication").Create(prescription).Error
+}
+
+// FindByID finds a prescription by ID with its medication and prescribing doctor
+func (r *prescriptionRepository) FindByID(ctx context.Context, id uint) (*model.Prescription, error) {
+	var prescription model.Prescription
+	if err := r.db.WithContext(ctx).
+		Preload("Medication").
+		Preload("Doctor.User").
+		Preload("Patient.User").
+		First(&prescription, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("prescription not found")
+		}
+		return nil, err
+	}
+	return &prescription, nil
+}
+
+// FindByPatientID lists a patient's prescriptions, most recently issued first. With activeAt
+// set, only those not cancelled and still running at that time are listed.
+func (r *prescriptionRepository) FindByPatientID(ctx context.Context, patientID uint, activeAt *time.Time, limit, offset int) ([]*model.Prescription, int64, error) {
+	query := r.db.WithContext(ctx).Model(&model.Prescription{}).Where("patient_id = ?", patientID)
+	if activeAt != nil {
+		query = query.Where("cancelled_at IS NULL AND ends_at > ?", *activeAt)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var prescriptions []*model.Prescription
+	err := query.
+		Preload("Medication").
+		Preload("Doctor.User").
+		Order("issued_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&prescriptions).Error
+	return prescriptions, total, err
+}
+
+// Cancel stops a prescription. Cancelling one that was already cancelled fails.
+func (r *prescriptionRepository) Cancel(ctx context.Context, id uint, reason string, at time.Time) error {
+	result := r.db.WithContext(ctx).
+		Model(&model.Prescription{}).
+		Where("id = ? AND cancelled_at IS NULL", id).
+		Updates(map[string]interface{}{"cancelled_at": at, "cancel_reason": reason, "updated_at": at})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("prescription is already cancelled")
+	}
+	return nil
+}
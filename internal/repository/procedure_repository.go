@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type procedureRepository struct {
+	db *gorm.DB
+}
+
+// NewProcedureRepository creates a new procedure repository
+func NewProcedureRepository(db *gorm.DB) ProcedureRepository {
+	return &procedureRepository{
+		db: db,
+	}
+}
+
+// SaveCodes adds codes to the catalog, replacing the system, description and status of codes
+// already in it
+func (r *procedureRepository) SaveCodes(ctx context.Context, codes []*model.ProcedureCode) error {
+	if len(codes) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "code"}},
+		DoUpdates: clause.AssignmentColumns([]string{"system", "description", "active", "updated_at"}),
+	}).CreateInBatches(codes, 500).Error
+}
+
+// FindCode finds a catalog entry by code
+func (r *procedureRepository) FindCode(ctx context.Context, code string) (*model.ProcedureCode, error) {
+	var procedureCode model.ProcedureCode
+	if err := r.db.WithContext(ctx).Where("code = ?", code).First(&procedureCode).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("procedure code not found")
+		}
+		return nil, err
+	}
+	return &procedureCode, nil
+}
+
+// SearchCodes finds active codes starting with text or with text in their description,
+// optionally of one system, ordered by code
+func (r *procedureRepository) SearchCodes(ctx context.Context, text string, system model.ProcedureCodeSystem, limit int) ([]*model.ProcedureCode, error) {
+	query := r.db.WithContext(ctx).Where("active = ?", true)
+	if text != "" {
+		query = query.Where("code ILIKE ? OR description ILIKE ?", escapeLike(text)+"%", "%"+escapeLike(text)+"%")
+	}
+	if system != "" {
+		query = query.Where("system = ?", system)
+	}
+
+	var codes []*model.ProcedureCode
+	err := query.Order("code").Limit(limit).Find(&codes).Error
+	return codes, err
+}
+
+// DeactivateCode retires a code so it can no longer be attached
+func (r *procedureRepository) DeactivateCode(ctx context.Context, code string) error {
+	result := r.db.WithContext(ctx).
+		Model(&model.ProcedureCode{}).
+		Where("code = ?", code).
+		Updates(map[string]interface{}{"active": false, "updated_at": time.Now()})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("procedure code not found")
+	}
+	return nil
+}
+
+// CreateProcedure records a procedure performed during an appointment
+func (r *procedureRepository) CreateProcedure(ctx context.Context, procedure *model.AppointmentProcedure) error {
+	return r.db.WithContext(ctx).Create(procedure).Error
+}
+
+// FindByAppointmentID finds the procedures of an appointment in the order they were recorded
+func (r *procedureRepository) FindByAppointmentID(ctx context.Context, appointmentID uint) ([]*model.AppointmentProcedure, error) {
+	var procedures []*model.AppointmentProcedure
+	err := r.db.WithContext(ctx).
+		Preload("ProcedureCode").
+		Where("appointment_id = ?", appointmentID).
+		Order("id").
+		Find(&procedures).Error
+	return procedures, err
+}
+
+// DeleteProcedure removes a procedure from an appointment
+func (r *procedureRepository) DeleteProcedure(ctx context.Context, appointmentID, id uint) error {
+	result := r.db.WithContext(ctx).
+		Where("id = ? AND appointment_id = ?", id, appointmentID).
+		Delete(&model.AppointmentProcedure{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("procedure not found")
+	}
+	return nil
+}
+
+// FindClaims finds the procedures of the clinic's completed appointments scheduled in
+// [start, end), with the appointment, patient, doctor and code they are billed with
+func (r *procedureRepository) FindClaims(ctx context.Context, scope AnalyticsScope, start, end time.Time) ([]*model.AppointmentProcedure, error) {
+	var procedures []*model.AppointmentProcedure
+	err := r.db.WithContext(ctx).
+		Joins("JOIN appointments ON appointments.id = appointment_procedures.appointment_id").
+		Joins("JOIN doctors ON doctors.id = appointments.doctor_id").
+		Where("doctors.organization_id = ? OR (? AND doctors.organization_id IS NULL)", scope.OrganizationID, scope.IncludeUnassigned).
+		Where("appointments.status = ?", model.AppointmentStatusCompleted).
+		Where("appointments.scheduled_start >= ? AND appointments.scheduled_start < ?", start, end).
+		Preload("ProcedureCode").
+		Preload("Appointment.Patient.User").
+		Preload("Appointment.Doctor.User").
+		Order("appointments.scheduled_start, appointment_procedures.id").
+		Find(&procedures).Error
+	return procedures, err
+}
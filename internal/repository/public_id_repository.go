@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"gorm.io/gorm"
+)
+
+type publicIDRepository struct {
+	db *gorm.DB
+}
+
+// NewPublicIDRepository creates a new public ID repository
+func NewPublicIDRepository(db *gorm.DB) PublicIDRepository {
+	return &publicIDRepository{
+		db: db,
+	}
+}
+
+// ResolveID finds the primary key of the resource with the given public ID
+func (r *publicIDRepository) ResolveID(ctx context.Context, resource model.PublicResource, publicID string) (uint, error) {
+	var row struct{ ID uint }
+	err := r.db.WithContext(ctx).
+		Table(string(resource)).
+		Select("id").
+		Where("public_id = ?", publicID).
+		Take(&row).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, errors.New(resource.Name() + " not found")
+		}
+		return 0, err
+	}
+	return row.ID, nil
+}
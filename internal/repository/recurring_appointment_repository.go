@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SeriesBooking is an occurrence of a recurring series to save with its outbox events
+type SeriesBooking struct {
+	Appointment *model.Appointment
+	Events      []*model.OutboxEvent
+}
+
+type recurringAppointmentRepository struct {
+	db *gorm.DB
+}
+
+// NewRecurringAppointmentRepository creates a new recurring appointment repository
+func NewRecurringAppointmentRepository(db *gorm.DB) RecurringAppointmentRepository {
+	return &recurringAppointmentRepository{
+		db: db,
+	}
+}
+
+// Create creates a series with its occurrences in one transaction. Each occurrence is checked
+// for overlaps like a single booking, and if any one conflicts nothing is booked.
+func (r *recurringAppointmentRepository) Create(ctx context.Context, series *model.RecurringAppointment, bookings []SeriesBooking) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Create(series).Error; err != nil {
+			return err
+		}
+		for _, booking := range bookings {
+			booking.Appointment.SeriesID = &series.ID
+			if err := checkOccurrenceConflicts(tx, booking.Appointment); err != nil {
+				return err
+			}
+			if err := tx.Create(booking.Appointment).Error; err != nil {
+				return err
+			}
+			if err := createOutboxEvents(tx, "appointment", booking.Appointment.ID, booking.Events); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// FindByID finds a series with its occurrences in date order
+func (r *recurringAppointmentRepository) FindByID(ctx context.Context, id uint) (*model.RecurringAppointment, error) {
+	var series model.RecurringAppointment
+	err := r.db.WithContext(ctx).
+		Preload("Patient.User").
+		Preload("Doctor.User").
+		Preload("AppointmentType").
+		Preload("Appointments", func(db *gorm.DB) *gorm.DB {
+			return db.Order("scheduled_start ASC")
+		}).
+		Preload("Appointments.Patient.User").
+		Preload("Appointments.Doctor.User").
+		Preload("Appointments.AppointmentType").
+		Preload("Appointments.SeriesException").
+		First(&series, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("appointment series not found")
+		}
+		return nil, err
+	}
+	return &series, nil
+}
+
+// Save saves a series and the occurrences changed with it in one transaction. Occurrences that
+// are still active are checked for overlaps, so moving the series fails with
+// ErrScheduleConflict if any occurrence no longer fits.
+func (r *recurringAppointmentRepository) Save(ctx context.Context, series *model.RecurringAppointment, bookings []SeriesBooking) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Save(series).Error; err != nil {
+			return err
+		}
+		for _, booking := range bookings {
+			if booking.Appointment.Status != model.AppointmentStatusCancelled {
+				if err := checkOccurrenceConflicts(tx, booking.Appointment); err != nil {
+					return err
+				}
+			}
+			if err := tx.Omit(clause.Associations).Save(booking.Appointment).Error; err != nil {
+				return err
+			}
+			if err := createOutboxEvents(tx, "appointment", booking.Appointment.ID, booking.Events); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Split moves occurrences of a series to a new series, tail, in one transaction, saving both
+// series and the occurrences with their changes. Active occurrences are checked for overlaps as
+// in Save.
+func (r *recurringAppointmentRepository) Split(ctx context.Context, series, tail *model.RecurringAppointment, bookings []SeriesBooking) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Save(series).Error; err != nil {
+			return err
+		}
+		if err := tx.Omit(clause.Associations).Create(tail).Error; err != nil {
+			return err
+		}
+		for _, booking := range bookings {
+			booking.Appointment.SeriesID = &tail.ID
+			if booking.Appointment.Status != model.AppointmentStatusCancelled {
+				if err := checkOccurrenceConflicts(tx, booking.Appointment); err != nil {
+					return err
+				}
+			}
+			if err := tx.Omit(clause.Associations).Save(booking.Appointment).Error; err != nil {
+				return err
+			}
+			if err := createOutboxEvents(tx, "appointment", booking.Appointment.ID, booking.Events); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// saveSeriesException records the exception of an occurrence changed on its own, if it has one.
+// An occurrence changed again keeps the original start recorded the first time.
+func saveSeriesException(tx *gorm.DB, appointment *model.Appointment) error {
+	exception := appointment.SeriesException
+	if exception == nil || appointment.SeriesID == nil {
+		return nil
+	}
+	exception.AppointmentID = appointment.ID
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "appointment_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"kind", "updated_at"}),
+	}).Create(exception).Error
+}
+
+// checkOccurrenceConflicts checks an occurrence for overlaps, naming its date in the error
+func checkOccurrenceConflicts(tx *gorm.DB, appointment *model.Appointment) error {
+	if err := checkScheduleConflicts(tx, appointment); err != nil {
+		if errors.Is(err, ErrScheduleConflict) {
+			return fmt.Errorf("occurrence on %s: %w", appointment.ScheduledStart.Format("2006-01-02"), err)
+		}
+		return err
+	}
+	return nil
+}
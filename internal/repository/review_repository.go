@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"gorm.io/gorm"
+)
+
+type reviewRepository struct {
+	db *gorm.DB
+}
+
+// NewReviewRepository creates a new doctor review repository
+func NewReviewRepository(db *gorm.DB) ReviewRepository {
+	return &reviewRepository{
+		db: db,
+	}
+}
+
+// Create creates a review
+func (r *reviewRepository) Create(ctx context.Context, review *model.DoctorReview) error {
+	return r.db.WithContext(ctx).Omit("Doctor", "Patient").Create(review).Error
+}
+
+// Update updates a review
+func (r *reviewRepository) Update(ctx context.Context, review *model.DoctorReview) error {
+	return r.db.WithContext(ctx).Omit("Doctor", "Patient").Save(review).Error
+}
+
+// FindByID finds a review by ID
+func (r *reviewRepository) FindByID(ctx context.Context, id uint) (*model.DoctorReview, error) {
+	var review model.DoctorReview
+	if err := r.db.WithContext(ctx).Preload("Patient.User").First(&review, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("review not found")
+		}
+		return nil, err
+	}
+	return &review, nil
+}
+
+// FindByDoctorID finds a doctor's reviews with pagination, most recent first
+func (r *reviewRepository) FindByDoctorID(ctx context.Context, doctorID uint, limit, offset int) ([]*model.DoctorReview, int64, error) {
+	var reviews []*model.DoctorReview
+	var count int64
+
+	query := r.db.WithContext(ctx).Model(&model.DoctorReview{}).Where("doctor_id = ?", doctorID)
+	if err := query.Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if err := query.
+		Preload("Patient.User").
+		Order("created_at DESC, id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&reviews).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return reviews, count, nil
+}
+
+// ExistsForAppointment reports whether an appointment was already reviewed
+func (r *reviewRepository) ExistsForAppointment(ctx context.Context, appointmentID uint) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&model.DoctorReview{}).Where("appointment_id = ?", appointmentID).Count(&count).Error
+	return count > 0, err
+}
+
+// CountByPatientSince counts the reviews a patient wrote since the given time
+func (r *reviewRepository) CountByPatientSince(ctx context.Context, patientID uint, since time.Time) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&model.DoctorReview{}).
+		Where("patient_id = ? AND created_at >= ?", patientID, since).
+		Count(&count).Error
+	return count, err
+}
+
+// AverageRating returns a doctor's average rating, or 0 when they have no reviews
+func (r *reviewRepository) AverageRating(ctx context.Context, doctorID uint) (float64, error) {
+	var average float64
+	err := r.db.WithContext(ctx).
+		Model(&model.DoctorReview{}).
+		Select("COALESCE(AVG(rating), 0)").
+		Where("doctor_id = ?", doctorID).
+		Scan(&average).Error
+	return average, err
+}
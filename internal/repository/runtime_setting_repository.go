@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type runtimeSettingRepository struct {
+	db *gorm.DB
+}
+
+// NewRuntimeSettingRepository creates a new runtime setting repository
+func NewRuntimeSettingRepository(db *gorm.DB) RuntimeSettingRepository {
+	return &runtimeSettingRepository{
+		db: db,
+	}
+}
+
+// FindAll finds every stored setting
+func (r *runtimeSettingRepository) FindAll(ctx context.Context) ([]*model.RuntimeSetting, error) {
+	var settings []*model.RuntimeSetting
+	if err := r.db.WithContext(ctx).Order("key").Find(&settings).Error; err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// Save stores settings, replacing earlier values of the same keys, in one transaction
+func (r *runtimeSettingRepository) Save(ctx context.Context, settings []*model.RuntimeSetting) error {
+	if len(settings) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value", "updated_by", "updated_at"}),
+	}).Create(settings).Error
+}
+
+// Delete removes a stored setting, so its configured default applies again
+func (r *runtimeSettingRepository) Delete(ctx context.Context, key string) error {
+	return r.db.WithContext(ctx).Where("key = ?", key).Delete(&model.RuntimeSetting{}).Error
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"gorm.io/gorm"
+)
+
+type securityAlertRepository struct {
+	db *gorm.DB
+}
+
+// NewSecurityAlertRepository creates a new security alert repository
+func NewSecurityAlertRepository(db *gorm.DB) SecurityAlertRepository {
+	return &securityAlertRepository{
+		db: db,
+	}
+}
+
+// Create records a security alert and writes its outbox events in the same transaction
+func (r *securityAlertRepository) Create(ctx context.Context, alert *model.SecurityAlert, events ...*model.OutboxEvent) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(alert).Error; err != nil {
+			return err
+		}
+		return createOutboxEvents(tx, "security_alert", alert.ID, events)
+	})
+}
+
+// FindRecent finds the latest security alerts, newest first
+func (r *securityAlertRepository) FindRecent(ctx context.Context, limit int) ([]*model.SecurityAlert, error) {
+	var alerts []*model.SecurityAlert
+	if err := r.db.WithContext(ctx).
+		Order("created_at DESC, id DESC").
+		Limit(limit).
+		Find(&alerts).Error; err != nil {
+		return nil, err
+	}
+	return alerts, nil
+}
+
+// ExistsSince reports whether an alert of the given kind was raised for subject since the given time
+func (r *securityAlertRepository) ExistsSince(ctx context.Context, kind, subject string, since time.Time) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&model.SecurityAlert{}).
+		Where("kind = ? AND subject = ? AND created_at >= ?", kind, subject, since).
+		Count(&count).Error
+	return count > 0, err
+}
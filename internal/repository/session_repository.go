@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"gorm.io/gorm"
+)
+
+type sessionRepository struct {
+	db *gorm.DB
+}
+
+// NewSessionRepository creates a new session repository
+func NewSessionRepository(db *gorm.DB) SessionRepository {
+	return &sessionRepository{
+		db: db,
+	}
+}
+
+// Create creates a new session
+func (r *sessionRepository) Create(ctx context.Context, session *model.Session) error {
+	return r.db.WithContext(ctx).Create(session).Error
+}
+
+// FindByToken finds a session by the hash of its session ID
+func (r *sessionRepository) FindByToken(ctx context.Context, tokenHash string) (*model.Session, error) {
+	var session model.Session
+	if err := r.db.WithContext(ctx).Where("token = ?", tokenHash).First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("session not found")
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+// Touch records activity on a session
+func (r *sessionRepository) Touch(ctx context.Context, id uint, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&model.Session{}).Where("id = ?", id).
+		Update("last_activity_at", at).Error
+}
+
+// DeleteByUserID deletes all sessions of a user
+func (r *sessionRepository) DeleteByUserID(ctx context.Context, userID uint) error {
+	return r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&model.Session{}).Error
+}
+
+// DeleteByToken deletes a session by the hash of its session ID
+func (r *sessionRepository) DeleteByToken(ctx context.Context, tokenHash string) error {
+	return r.db.WithContext(ctx).Where("token = ?", tokenHash).Delete(&model.Session{}).Error
+}
+
+// DeleteExpired deletes sessions past their absolute expiry
+func (r *sessionRepository) DeleteExpired(ctx context.Context) error {
+	return r.db.WithContext(ctx).Where("expires_at <= ?", time.Now()).Delete(&model.Session{}).Error
+}
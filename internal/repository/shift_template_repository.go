@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"gorm.io/gorm"
+)
+
+type shiftTemplateRepository struct {
+	db *gorm.DB
+}
+
+// NewShiftTemplateRepository creates a new shift template repository
+func NewShiftTemplateRepository(db *gorm.DB) ShiftTemplateRepository {
+	return &shiftTemplateRepository{
+		db: db,
+	}
+}
+
+// Create creates a new shift template
+func (r *shiftTemplateRepository) Create(ctx context.Context, template *model.ShiftTemplate) error {
+	return r.db.WithContext(ctx).Create(template).Error
+}
+
+// FindByID finds a shift template by ID
+func (r *shiftTemplateRepository) FindByID(ctx context.Context, id uint) (*model.ShiftTemplate, error) {
+	var template model.ShiftTemplate
+	if err := r.db.WithContext(ctx).First(&template, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("shift template not found")
+		}
+		return nil, err
+	}
+	return &template, nil
+}
+
+// FindAll finds all shift templates by name
+func (r *shiftTemplateRepository) FindAll(ctx context.Context) ([]*model.ShiftTemplate, error) {
+	var templates []*model.ShiftTemplate
+	if err := r.db.WithContext(ctx).Order("name").Find(&templates).Error; err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+// Update updates a shift template
+func (r *shiftTemplateRepository) Update(ctx context.Context, template *model.ShiftTemplate) error {
+	return r.db.WithContext(ctx).Save(template).Error
+}
+
+// Delete deletes a shift template. Availability applied from it stays in place and no longer
+// refers to it.
+func (r *shiftTemplateRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.Availability{}).
+			Where("shift_template_id = ?", id).
+			UpdateColumn("shift_template_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.ShiftTemplate{}, id).Error
+	})
+}
@@ -0,0 +1,257 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/pkg/redis"
+)
+
+var errSlotHoldNotFound = errors.New("slot hold not found")
+
+// slotHoldKey is the key a hold is stored under; one hold per doctor and start time
+func slotHoldKey(doctorID uint, start time.Time) string {
+	return fmt.Sprintf("slot-hold:%d:%d", doctorID, start.Unix())
+}
+
+// slotHoldTokenKey maps a hold token back to its slot key
+func slotHoldTokenKey(token string) string {
+	return "slot-hold-token:" + token
+}
+
+// createHoldScript stores the hold only if the slot is free, along with the token lookup key
+const createHoldScript = `
+if redis.call('SET', KEYS[1], ARGV[1], 'NX', 'PX', ARGV[2]) then
+	redis.call('SET', KEYS[2], KEYS[1], 'PX', ARGV[2])
+	return 1
+end
+return 0`
+
+// deleteHoldScript removes a hold by token, leaving the slot alone if it has since been held again
+const deleteHoldScript = `
+local slot = redis.call('GET', KEYS[1])
+if not slot then
+	return false
+end
+redis.call('DEL', KEYS[1])
+local value = redis.call('GET', slot)
+if value and cjson.decode(value).token == ARGV[1] then
+	redis.call('DEL', slot)
+	return value
+end
+return false`
+
+type redisSlotHoldRepository struct {
+	client *redis.Client
+}
+
+// NewRedisSlotHoldRepository creates a slot hold repository backed by Redis, so holds are shared
+// by every API instance and expire through key TTLs
+func NewRedisSlotHoldRepository(client *redis.Client) SlotHoldRepository {
+	return &redisSlotHoldRepository{
+		client: client,
+	}
+}
+
+// Create stores a hold until its expiry and reports whether the slot was free
+func (r *redisSlotHoldRepository) Create(ctx context.Context, hold *model.SlotHold) (bool, error) {
+	ttl := time.Until(hold.ExpiresAt).Milliseconds()
+	if ttl <= 0 {
+		return false, errors.New("slot hold has already expired")
+	}
+	value, err := json.Marshal(hold)
+	if err != nil {
+		return false, err
+	}
+
+	reply, err := r.client.Eval(ctx, createHoldScript,
+		[]string{slotHoldKey(hold.DoctorID, hold.Start), slotHoldTokenKey(hold.Token)},
+		string(value), strconv.FormatInt(ttl, 10))
+	if err != nil {
+		return false, err
+	}
+	created, _ := reply.(int64)
+	return created == 1, nil
+}
+
+// Find finds the hold on a slot
+func (r *redisSlotHoldRepository) Find(ctx context.Context, doctorID uint, start time.Time) (*model.SlotHold, error) {
+	value, err := r.client.Get(ctx, slotHoldKey(doctorID, start))
+	if err != nil {
+		if errors.Is(err, redis.ErrNil) {
+			return nil, errSlotHoldNotFound
+		}
+		return nil, err
+	}
+	return decodeSlotHold(value)
+}
+
+// FindHeld finds the holds among the given slots, keyed by start time in Unix seconds
+func (r *redisSlotHoldRepository) FindHeld(ctx context.Context, doctorID uint, starts []time.Time) (map[int64]*model.SlotHold, error) {
+	held := make(map[int64]*model.SlotHold)
+	if len(starts) == 0 {
+		return held, nil
+	}
+
+	args := make([]string, 0, len(starts)+1)
+	args = append(args, "MGET")
+	for _, start := range starts {
+		args = append(args, slotHoldKey(doctorID, start))
+	}
+	reply, err := r.client.Do(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	values, _ := reply.([]interface{})
+	for _, item := range values {
+		value, ok := item.(string)
+		if !ok {
+			continue
+		}
+		hold, err := decodeSlotHold(value)
+		if err != nil {
+			return nil, err
+		}
+		held[hold.Start.Unix()] = hold
+	}
+	return held, nil
+}
+
+// DeleteByToken releases a hold and returns it
+func (r *redisSlotHoldRepository) DeleteByToken(ctx context.Context, token string) (*model.SlotHold, error) {
+	reply, err := r.client.Eval(ctx, deleteHoldScript, []string{slotHoldTokenKey(token)}, token)
+	if err != nil {
+		return nil, err
+	}
+	value, ok := reply.(string)
+	if !ok {
+		return nil, errSlotHoldNotFound
+	}
+	return decodeSlotHold(value)
+}
+
+// DeleteSlot releases whatever hold is on a slot
+func (r *redisSlotHoldRepository) DeleteSlot(ctx context.Context, doctorID uint, start time.Time) error {
+	_, err := r.client.Do(ctx, "DEL", slotHoldKey(doctorID, start))
+	return err
+}
+
+func decodeSlotHold(value string) (*model.SlotHold, error) {
+	var hold model.SlotHold
+	if err := json.Unmarshal([]byte(value), &hold); err != nil {
+		return nil, fmt.Errorf("invalid slot hold: %w", err)
+	}
+	return &hold, nil
+}
+
+type memorySlotHoldRepository struct {
+	mu     sync.Mutex
+	holds  map[string]*model.SlotHold
+	tokens map[string]string
+}
+
+// NewMemorySlotHoldRepository creates a slot hold repository kept in process memory. Holds are
+// not shared between API instances, so it is only suitable for development and single-instance
+// deployments.
+func NewMemorySlotHoldRepository() SlotHoldRepository {
+	return &memorySlotHoldRepository{
+		holds:  make(map[string]*model.SlotHold),
+		tokens: make(map[string]string),
+	}
+}
+
+// Create stores a hold until its expiry and reports whether the slot was free
+func (r *memorySlotHoldRepository) Create(ctx context.Context, hold *model.SlotHold) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.expire()
+
+	key := slotHoldKey(hold.DoctorID, hold.Start)
+	if _, held := r.holds[key]; held {
+		return false, nil
+	}
+	stored := *hold
+	r.holds[key] = &stored
+	r.tokens[hold.Token] = key
+	return true, nil
+}
+
+// Find finds the hold on a slot
+func (r *memorySlotHoldRepository) Find(ctx context.Context, doctorID uint, start time.Time) (*model.SlotHold, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.expire()
+
+	hold, ok := r.holds[slotHoldKey(doctorID, start)]
+	if !ok {
+		return nil, errSlotHoldNotFound
+	}
+	found := *hold
+	return &found, nil
+}
+
+// FindHeld finds the holds among the given slots, keyed by start time in Unix seconds
+func (r *memorySlotHoldRepository) FindHeld(ctx context.Context, doctorID uint, starts []time.Time) (map[int64]*model.SlotHold, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.expire()
+
+	held := make(map[int64]*model.SlotHold)
+	for _, start := range starts {
+		if hold, ok := r.holds[slotHoldKey(doctorID, start)]; ok {
+			found := *hold
+			held[start.Unix()] = &found
+		}
+	}
+	return held, nil
+}
+
+// DeleteByToken releases a hold and returns it
+func (r *memorySlotHoldRepository) DeleteByToken(ctx context.Context, token string) (*model.SlotHold, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.expire()
+
+	key, ok := r.tokens[token]
+	if !ok {
+		return nil, errSlotHoldNotFound
+	}
+	delete(r.tokens, token)
+	hold, ok := r.holds[key]
+	if !ok || hold.Token != token {
+		return nil, errSlotHoldNotFound
+	}
+	delete(r.holds, key)
+	return hold, nil
+}
+
+// DeleteSlot releases whatever hold is on a slot
+func (r *memorySlotHoldRepository) DeleteSlot(ctx context.Context, doctorID uint, start time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := slotHoldKey(doctorID, start)
+	if hold, ok := r.holds[key]; ok {
+		delete(r.tokens, hold.Token)
+		delete(r.holds, key)
+	}
+	return nil
+}
+
+// expire drops lapsed holds; callers hold the lock
+func (r *memorySlotHoldRepository) expire() {
+	now := time.Now()
+	for key, hold := range r.holds {
+		if !now.Before(hold.ExpiresAt) {
+			delete(r.tokens, hold.Token)
+			delete(r.holds, key)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// WaitingRoomEventFilter selects the waiting room events of one appointment or of all of a
+// doctor's appointments. Zero fields do not filter.
+type WaitingRoomEventFilter struct {
+	AppointmentID uint
+	DoctorID      uint
+}
+
+type telehealthRepository struct {
+	db *gorm.DB
+}
+
+// NewTelehealthRepository creates a new telehealth repository
+func NewTelehealthRepository(db *gorm.DB) TelehealthRepository {
+	return &telehealthRepository{
+		db: db,
+	}
+}
+
+// FindVisit finds the waiting room record of an appointment
+func (r *telehealthRepository) FindVisit(ctx context.Context, appointmentID uint) (*model.TelehealthVisit, error) {
+	var visit model.TelehealthVisit
+	if err := r.db.WithContext(ctx).Where("appointment_id = ?", appointmentID).First(&visit).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("telehealth visit not found")
+		}
+		return nil, err
+	}
+	return &visit, nil
+}
+
+// UpdateVisit applies a change to an appointment's waiting room, creating its record on first
+// use. The record is locked while apply runs, so concurrent joins and admissions see each
+// other's changes; the events apply returns are saved in the same transaction.
+func (r *telehealthRepository) UpdateVisit(ctx context.Context, appointmentID, doctorID uint, apply func(*model.TelehealthVisit) ([]*model.WaitingRoomEvent, error)) (*model.TelehealthVisit, error) {
+	var visit model.TelehealthVisit
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "appointment_id"}},
+			DoNothing: true,
+		}).Create(&model.TelehealthVisit{
+			AppointmentID: appointmentID,
+			DoctorID:      doctorID,
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("appointment_id = ?", appointmentID).
+			First(&visit).Error; err != nil {
+			return err
+		}
+
+		events, err := apply(&visit)
+		if err != nil {
+			return err
+		}
+		visit.UpdatedAt = now
+		if err := tx.Omit(clause.Associations).Save(&visit).Error; err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			return nil
+		}
+		return tx.Omit("Appointment", "User").Create(events).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &visit, nil
+}
+
+// FindEvents lists the waiting room events of an appointment in the order they happened
+func (r *telehealthRepository) FindEvents(ctx context.Context, appointmentID uint) ([]*model.WaitingRoomEvent, error) {
+	var events []*model.WaitingRoomEvent
+	err := r.db.WithContext(ctx).
+		Preload("User").
+		Where("appointment_id = ?", appointmentID).
+		Order("id").
+		Find(&events).Error
+	return events, err
+}
+
+// FindEventsAfter lists up to limit events matching filter that happened after the event
+// numbered afterID, oldest first
+func (r *telehealthRepository) FindEventsAfter(ctx context.Context, filter WaitingRoomEventFilter, afterID uint, limit int) ([]*model.WaitingRoomEvent, error) {
+	query := r.db.WithContext(ctx).
+		Preload("Appointment").
+		Preload("User").
+		Where("id > ?", afterID)
+	if filter.AppointmentID != 0 {
+		query = query.Where("appointment_id = ?", filter.AppointmentID)
+	}
+	if filter.DoctorID != 0 {
+		query = query.Where("doctor_id = ?", filter.DoctorID)
+	}
+
+	var events []*model.WaitingRoomEvent
+	err := query.Order("id").Limit(limit).Find(&events).Error
+	return events, err
+}
+
+// LastEventID returns the number of the latest event matching filter, or 0 when there is none
+func (r *telehealthRepository) LastEventID(ctx context.Context, filter WaitingRoomEventFilter) (uint, error) {
+	query := r.db.WithContext(ctx).Model(&model.WaitingRoomEvent{})
+	if filter.AppointmentID != 0 {
+		query = query.Where("appointment_id = ?", filter.AppointmentID)
+	}
+	if filter.DoctorID != 0 {
+		query = query.Where("doctor_id = ?", filter.DoctorID)
+	}
+
+	var id *uint
+	if err := query.Select("MAX(id)").Scan(&id).Error; err != nil {
+		return 0, err
+	}
+	if id == nil {
+		return 0, nil
+	}
+	return *id, nil
+}
+
+// FindVisits lists the visits whose waiting room opened in [from, to), optionally for one
+// doctor, with their appointments, earliest first
+func (r *telehealthRepository) FindVisits(ctx context.Context, from, to time.Time, doctorID uint) ([]*model.TelehealthVisit, error) {
+	query := r.db.WithContext(ctx).
+		Preload("Appointment.Patient.User").
+		Preload("Appointment.Doctor.User").
+		Where("waiting_since >= ? AND waiting_since < ?", from, to)
+	if doctorID != 0 {
+		query = query.Where("doctor_id = ?", doctorID)
+	}
+
+	var visits []*model.TelehealthVisit
+	err := query.Order("waiting_since").Find(&visits).Error
+	return visits, err
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type translationRepository struct {
+	db *gorm.DB
+}
+
+// NewTranslationRepository creates a new translation repository
+func NewTranslationRepository(db *gorm.DB) TranslationRepository {
+	return &translationRepository{
+		db: db,
+	}
+}
+
+// Save creates a translation or replaces the text of the existing one for its subject and locale
+func (r *translationRepository) Save(ctx context.Context, translation *model.Translation) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "kind"}, {Name: "subject"}, {Name: "locale"}},
+		DoUpdates: clause.AssignmentColumns([]string{"text", "updated_at"}),
+	}).Create(translation).Error
+}
+
+// Delete removes the translation of a subject into a locale
+func (r *translationRepository) Delete(ctx context.Context, kind, subject, locale string) error {
+	result := r.db.WithContext(ctx).
+		Where("kind = ? AND subject = ? AND locale = ?", kind, subject, locale).
+		Delete(&model.Translation{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("translation not found")
+	}
+	return nil
+}
+
+// FindBySubject returns every translation of a subject, ordered by locale
+func (r *translationRepository) FindBySubject(ctx context.Context, kind, subject string) ([]*model.Translation, error) {
+	var translations []*model.Translation
+	err := r.db.WithContext(ctx).
+		Where("kind = ? AND subject = ?", kind, subject).
+		Order("locale").
+		Find(&translations).Error
+	return translations, err
+}
+
+// FindForSubjects returns the translations of the given subjects into any of the given locales
+func (r *translationRepository) FindForSubjects(ctx context.Context, kind string, subjects, locales []string) ([]*model.Translation, error) {
+	var translations []*model.Translation
+	if len(subjects) == 0 || len(locales) == 0 {
+		return translations, nil
+	}
+	err := r.db.WithContext(ctx).
+		Where("kind = ? AND subject IN ? AND locale IN ?", kind, subjects, locales).
+		Find(&translations).Error
+	return translations, err
+}
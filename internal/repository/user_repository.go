@@ -37,10 +37,10 @@ func (r *userRepository) FindByID(ctx context.Context, id uint) (*model.User, er
 	return &user, nil
 }
 
-// FindByEmail finds a user by email
+// FindByEmail finds a user by email, ignoring case
 func (r *userRepository) FindByEmail(ctx context.Context, email string) (*model.User, error) {
 	var user model.User
-	err := r.db.WithContext(ctx).Where("email = ?", email).First(&user).Error
+	err := r.db.WithContext(ctx).Where("lower(email) = ?", model.NormalizeEmail(email)).First(&user).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("user not found")
@@ -50,9 +50,23 @@ func (r *userRepository) FindByEmail(ctx context.Context, email string) (*model.
 	return &user, nil
 }
 
-// Update updates a user
-func (r *userRepository) Update(ctx context.Context, user *model.User) error {
-	return r.db.WithContext(ctx).Save(user).Error
+// FindByRole finds all users with a given role
+func (r *userRepository) FindByRole(ctx context.Context, role model.Role) ([]*model.User, error) {
+	var users []*model.User
+	if err := r.db.WithContext(ctx).Where("role = ?", role).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// Update updates a user, writing any outbox events in the same transaction
+func (r *userRepository) Update(ctx context.Context, user *model.User, events ...*model.OutboxEvent) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(user).Error; err != nil {
+			return err
+		}
+		return createOutboxEvents(tx, "user", user.ID, events)
+	})
 }
 
 // Delete soft deletes a user
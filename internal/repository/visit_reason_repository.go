@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"gorm.io/gorm"
+)
+
+type visitReasonRepository struct {
+	db *gorm.DB
+}
+
+// NewVisitReasonRepository creates a new visit reason repository
+func NewVisitReasonRepository(db *gorm.DB) VisitReasonRepository {
+	return &visitReasonRepository{
+		db: db,
+	}
+}
+
+// Create creates a new visit reason
+func (r *visitReasonRepository) Create(ctx context.Context, reason *model.VisitReason) error {
+	return r.db.WithContext(ctx).Create(reason).Error
+}
+
+// FindByID finds a visit reason by ID
+func (r *visitReasonRepository) FindByID(ctx context.Context, id uint) (*model.VisitReason, error) {
+	var reason model.VisitReason
+	if err := r.db.WithContext(ctx).First(&reason, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("visit reason not found")
+		}
+		return nil, err
+	}
+	return &reason, nil
+}
+
+// FindAll finds all visit reasons, including retired ones
+func (r *visitReasonRepository) FindAll(ctx context.Context) ([]*model.VisitReason, error) {
+	var reasons []*model.VisitReason
+	if err := r.db.WithContext(ctx).Order("name").Find(&reasons).Error; err != nil {
+		return nil, err
+	}
+	return reasons, nil
+}
+
+// FindActive finds the visit reasons patients can book for
+func (r *visitReasonRepository) FindActive(ctx context.Context) ([]*model.VisitReason, error) {
+	var reasons []*model.VisitReason
+	if err := r.db.WithContext(ctx).Where("active = ?", true).Order("name").Find(&reasons).Error; err != nil {
+		return nil, err
+	}
+	return reasons, nil
+}
+
+// Update updates a visit reason
+func (r *visitReasonRepository) Update(ctx context.Context, reason *model.VisitReason) error {
+	return r.db.WithContext(ctx).Save(reason).Error
+}
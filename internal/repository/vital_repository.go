@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"gorm.io/gorm"
+)
+
+// VitalFilter selects a patient's vital sign readings. Zero fields match everything.
+type VitalFilter struct {
+	PatientID uint
+	Type      model.VitalType
+	From      time.Time // Measured at or after
+	To        time.Time // Measured before
+}
+
+// VitalSummary aggregates the readings of one vital sign type. The diastolic fields are set for
+// blood pressure only.
+type VitalSummary struct {
+	Type         model.VitalType
+	Count        int64
+	Min          float64
+	Max          float64
+	Avg          float64
+	MinDiastolic *float64
+	MaxDiastolic *float64
+	AvgDiastolic *float64
+	First        time.Time
+	Last         time.Time
+}
+
+type vitalRepository struct {
+	db *gorm.DB
+}
+
+// NewVitalRepository creates a new vital sign repository
+func NewVitalRepository(db *gorm.DB) VitalRepository {
+	return &vitalRepository{
+		db: db,
+	}
+}
+
+// Create records a vital sign reading
+func (r *vitalRepository) Create(ctx context.Context, vital *model.Vital) error {
+	return r.db.WithContext(ctx).Create(vital).Error
+}
+
+// FindByID finds a vital sign reading by ID
+func (r *vitalRepository) FindByID(ctx context.Context, id uint) (*model.Vital, error) {
+	var vital model.Vital
+	if err := r.db.WithContext(ctx).First(&vital, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("vital sign not found")
+		}
+		return nil, err
+	}
+	return &vital, nil
+}
+
+// Find lists the readings matching filter, oldest first. Only the latest limit readings are
+// returned.
+func (r *vitalRepository) Find(ctx context.Context, filter VitalFilter, limit int) ([]*model.Vital, error) {
+	var vitals []*model.Vital
+	if err := r.filtered(ctx, filter).
+		Order("measured_at DESC, id DESC").
+		Limit(limit).
+		Find(&vitals).Error; err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(vitals)-1; i < j; i, j = i+1, j-1 {
+		vitals[i], vitals[j] = vitals[j], vitals[i]
+	}
+	return vitals, nil
+}
+
+// Summarize aggregates the readings matching filter by type
+func (r *vitalRepository) Summarize(ctx context.Context, filter VitalFilter) ([]VitalSummary, error) {
+	var summaries []VitalSummary
+	err := r.filtered(ctx, filter).
+		Select("type, COUNT(*) AS count, MIN(value) AS min, MAX(value) AS max, AVG(value) AS avg, " +
+			"MIN(diastolic) AS min_diastolic, MAX(diastolic) AS max_diastolic, AVG(diastolic) AS avg_diastolic, " +
+			"MIN(measured_at) AS first, MAX(measured_at) AS last").
+		Group("type").
+		Order("type").
+		Scan(&summaries).Error
+	return summaries, err
+}
+
+// Delete removes a vital sign reading
+func (r *vitalRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&model.Vital{}, id).Error
+}
+
+// filtered selects the readings matching filter
+func (r *vitalRepository) filtered(ctx context.Context, filter VitalFilter) *gorm.DB {
+	query := r.db.WithContext(ctx).Model(&model.Vital{})
+	if filter.PatientID != 0 {
+		query = query.Where("patient_id = ?", filter.PatientID)
+	}
+	if filter.Type != "" {
+		query = query.Where("type = ?", filter.Type)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("measured_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("measured_at < ?", filter.To)
+	}
+	return query
+}
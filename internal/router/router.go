@@ -3,6 +3,8 @@ package router
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/whitewalker-sa/ehass/internal/handler"
+	"github.com/whitewalker-sa/ehass/internal/middleware"
+	"github.com/whitewalker-sa/ehass/internal/model"
 )
 
 // SetupRouter sets up the API routes
@@ -12,9 +14,63 @@ func SetupRouter(
 	doctorHandler *handler.DoctorHandler,
 	patientHandler *handler.PatientHandler,
 	appointmentHandler *handler.AppointmentHandler,
+	consentHandler *handler.ConsentHandler,
+	breakGlassHandler *handler.BreakGlassHandler,
+	roleHandler *handler.RoleHandler,
+	organizationHandler *handler.OrganizationHandler,
+	appointmentTypeHandler *handler.AppointmentTypeHandler,
+	analyticsHandler *handler.AnalyticsHandler,
+	availabilityHandler *handler.AvailabilityHandler,
+	scheduleHandler *handler.ScheduleHandler,
+	emailHandler *handler.EmailHandler,
+	notificationHandler *handler.NotificationHandler,
+	slotHoldHandler *handler.SlotHoldHandler,
+	patientAccountHandler *handler.PatientAccountHandler,
+	operationsHandler *handler.OperationsHandler,
+	searchHandler *handler.SearchHandler,
+	translationHandler *handler.TranslationHandler,
+	metricsHandler *handler.MetricsHandler,
+	operationHandler *handler.OperationHandler,
+	procedureHandler *handler.ProcedureHandler,
+	careHandler *handler.CareHandler,
+	seriesHandler *handler.RecurringAppointmentHandler,
+	handoffHandler *handler.HandoffHandler,
+	messageHandler *handler.MessageHandler,
+	frontDeskHandler *handler.FrontDeskHandler,
+	templateHandler *handler.TemplateHandler,
+	reviewHandler *handler.ReviewHandler,
+	visitReasonHandler *handler.VisitReasonHandler,
+	shiftTemplateHandler *handler.ShiftTemplateHandler,
+	calendarHandler *handler.CalendarHandler,
+	securityHandler *handler.SecurityHandler,
+	medicalRecordHandler *handler.MedicalRecordHandler,
+	settingsHandler *handler.SettingsHandler,
+	telehealthHandler *handler.TelehealthHandler,
+	labHandler *handler.LabHandler,
+	accountHandler *handler.AccountHandler,
+	clinicalListHandler *handler.ClinicalListHandler,
+	immunizationHandler *handler.ImmunizationHandler,
+	integrationHandler *handler.IntegrationHandler,
+	fhirHandler *handler.FHIRHandler,
 	authMiddleware gin.HandlerFunc,
+	stepUpMiddleware gin.HandlerFunc,
+	consentMiddleware gin.HandlerFunc,
+	introspectionMiddleware gin.HandlerFunc,
+	emailWebhookMiddleware gin.HandlerFunc,
+	labWebhookMiddleware gin.HandlerFunc,
+	metricsMiddleware gin.HandlerFunc,
+	latencyMiddleware gin.HandlerFunc,
+	deadlineMiddleware gin.HandlerFunc,
+	rateLimitMiddleware gin.HandlerFunc,
+	maintenanceMiddleware gin.HandlerFunc,
+	requirePermission middleware.PermissionChecker,
+	resolvePublicIDs middleware.PublicIDResolver,
 ) *gin.Engine {
 	r := gin.Default()
+	r.Use(middleware.ClientInfo(), latencyMiddleware, deadlineMiddleware, rateLimitMiddleware, maintenanceMiddleware)
+
+	// Prometheus scrape endpoint
+	r.GET("/metrics", metricsMiddleware, metricsHandler.Metrics)
 
 	// Public routes
 	v1 := r.Group("/api/v1")
@@ -30,58 +86,550 @@ func SetupRouter(
 			auth.POST("/reset-password", authHandler.ResetPassword)
 			auth.POST("/refresh-token", authHandler.RefreshToken)
 			auth.POST("/verify-2fa", authHandler.Verify2FA)
+			auth.POST("/claim-account", patientAccountHandler.ClaimAccount)
+			auth.POST("/setup-account", patientAccountHandler.SetUpAccount)
+			auth.POST("/introspect", introspectionMiddleware, authHandler.Introspect)
 		}
 
+		// Policy routes
+		v1.GET("/policies", consentHandler.GetPolicies)
+
+		// Medical record attachment downloads, authorized by the signed link
+		v1.GET("/attachments/:token", medicalRecordHandler.DownloadAttachment)
+
+		// Feature flags and maintenance status for clients
+		v1.GET("/features", settingsHandler.GetFeatures)
+
+		// Calendar feeds, fetched by calendar applications with the token in the URL
+		v1.GET("/users/:id/calendar.ics",
+			resolvePublicIDs(map[string]model.PublicResource{"id": model.ResourceUser}),
+			calendarHandler.GetCalendarFeed)
+
+		// Email provider delivery events
+		v1.POST("/webhooks/email", emailWebhookMiddleware, emailHandler.ReceiveEvents)
+
+		// Results reported by lab systems
+		v1.POST("/webhooks/lab-results", labWebhookMiddleware, labHandler.IngestResults)
+
+		// FHIR capability statement, fetched by EHR systems before connecting
+		v1.GET("/fhir/metadata", fhirHandler.GetCapabilityStatement)
+
 		// Protected routes
 		protected := v1.Group("/", authMiddleware)
 		{
-			// User routes
-			users := protected.Group("/users")
+			// Consent routes, reachable before the required policies are accepted
+			consents := protected.Group("/consents")
 			{
-				users.GET("/:id", userHandler.GetUserByID) // Changed to match actual implementation
-				users.PUT("/:id", userHandler.UpdateProfile)
-				users.PUT("/:id/change-password", userHandler.ChangePassword)
+				consents.GET("", consentHandler.GetMyConsents)
+				consents.POST("", consentHandler.AcceptPolicy)
+				consents.GET("/marketing", consentHandler.GetMarketingConsents)
+				consents.PUT("/marketing/:channel", consentHandler.SetMarketingConsent)
 			}
 
 			// Authentication management routes
 			authManagement := protected.Group("/auth")
 			{
 				authManagement.POST("/logout", authHandler.Logout)
+				authManagement.POST("/logout-all", authHandler.LogoutAll)
 				authManagement.POST("/setup-2fa", authHandler.Setup2FA)
 				authManagement.POST("/enable-2fa", authHandler.Enable2FA)
-				authManagement.POST("/disable-2fa", authHandler.Disable2FA)
+				authManagement.POST("/reauthenticate", authHandler.Reauthenticate)
+				authManagement.POST("/disable-2fa", stepUpMiddleware, authHandler.Disable2FA)
+				authManagement.POST("/change-email", stepUpMiddleware, authHandler.ChangeEmail)
 				authManagement.POST("/link-oauth", authHandler.LinkOAuth)
+				authManagement.GET("/userinfo", authHandler.UserInfo)
+			}
+
+			// Partner API usage, reachable with the API key it reports on
+			protected.GET("/integrations/usage", requirePermission(), integrationHandler.GetUsage)
+		}
+
+		// Protected routes that require accepted policies
+		consented := v1.Group("/", authMiddleware, consentMiddleware)
+		{
+			// Users, doctors, patients and appointments are addressed by public UUIDs
+			resolveUserID := resolvePublicIDs(map[string]model.PublicResource{"id": model.ResourceUser})
+
+			// User routes
+			users := consented.Group("/users", resolveUserID)
+			{
+				users.GET("/:id", userHandler.GetUserByID) // Changed to match actual implementation
+				users.PUT("/:id", userHandler.UpdateProfile)
+				users.PUT("/:id/change-password", userHandler.ChangePassword)
+				users.PUT("/:id/preferences", userHandler.UpdatePreferences)
+				users.POST("/:id/calendar-feed", calendarHandler.CreateCalendarFeed)
+				users.DELETE("/:id/calendar-feed", calendarHandler.RevokeCalendarFeed)
+				calendarSync := users.Group("/:id/calendar-sync", middleware.RoleMiddleware(model.RoleDoctor))
+				{
+					calendarSync.GET("", calendarHandler.GetCalendarSync)
+					calendarSync.GET("/authorize", calendarHandler.AuthorizeCalendarSync)
+					calendarSync.POST("", calendarHandler.ConnectCalendarSync)
+					calendarSync.DELETE("", calendarHandler.DisconnectCalendarSync)
+				}
 			}
 
 			// Doctor routes
-			doctors := protected.Group("/doctors")
+			doctors := consented.Group("/doctors", resolvePublicIDs(map[string]model.PublicResource{
+				"id":       model.ResourceDoctor,
+				"userID":   model.ResourceUser,
+				"reviewID": model.ResourceReview,
+			}))
 			{
 				doctors.POST("", doctorHandler.CreateDoctor)
 				doctors.GET("", doctorHandler.ListDoctors)
+				doctors.GET("/search", searchHandler.SearchDoctors)
+				doctors.GET("/suggest", searchHandler.SuggestDoctors)
 				doctors.GET("/:id", doctorHandler.GetDoctor)
 				doctors.PUT("/:id", doctorHandler.UpdateDoctor)
+				doctors.PUT("/:id/status", middleware.RoleMiddleware(model.RoleDoctor), doctorHandler.SetStatus)
+				doctors.PUT("/:id/auto-confirm", middleware.RoleMiddleware(model.RoleDoctor), doctorHandler.SetAutoConfirm)
+				doctors.PUT("/:id/scheduling", middleware.RoleMiddleware(model.RoleDoctor), doctorHandler.SetScheduling)
+				doctors.PUT("/:id/cancellation-cutoff", middleware.RoleMiddleware(model.RoleDoctor), doctorHandler.SetCancellationCutoff)
 				doctors.GET("/specialty/:specialty", doctorHandler.ListDoctorsBySpecialty)
 				doctors.GET("/user/:userID", doctorHandler.GetDoctorByUser)
+				doctors.GET("/workload", requirePermission(model.PermissionSchedulesRead), scheduleHandler.SuggestWorkload)
+				doctors.GET("/:id/appointment-types", appointmentTypeHandler.ListDoctorAppointmentTypes)
+				doctors.GET("/:id/availability", availabilityHandler.GetAvailability)
+				doctors.POST("/:id/availability", availabilityHandler.AddAvailability)
+				doctors.PUT("/:id/availability/:availabilityID", availabilityHandler.UpdateAvailability)
+				doctors.POST("/:id/availability/apply-template", availabilityHandler.ApplyShiftTemplate)
+				doctors.DELETE("/:id/availability/:availabilityID", availabilityHandler.RemoveAvailability)
+				doctors.GET("/:id/time-off", availabilityHandler.GetTimeOff)
+				doctors.POST("/:id/time-off", availabilityHandler.AddTimeOff)
+				doctors.PUT("/:id/time-off/:timeOffID", availabilityHandler.UpdateTimeOff)
+				doctors.DELETE("/:id/time-off/:timeOffID", availabilityHandler.RemoveTimeOff)
+				doctors.GET("/:id/slots", scheduleHandler.GetSlots)
+				doctors.GET("/:id/slots/next", scheduleHandler.GetNextSlot)
+				doctors.GET("/:id/reviews", reviewHandler.ListReviews)
+				doctors.POST("/:id/reviews", middleware.RoleMiddleware(model.RolePatient), reviewHandler.CreateReview)
+				doctors.PUT("/:id/reviews/:reviewID/response", middleware.RoleMiddleware(model.RoleDoctor), reviewHandler.RespondToReview)
+				doctors.GET("/:id/translations", translationHandler.ListDoctorBios)
+				doctors.PUT("/:id/translations/:locale", requirePermission(model.PermissionDoctorsManage), translationHandler.SetDoctorBio)
+				doctors.DELETE("/:id/translations/:locale", requirePermission(model.PermissionDoctorsManage), translationHandler.DeleteDoctorBio)
+			}
+
+			// Long requests run in the background
+			consented.GET("/operations/:id", operationHandler.GetOperation)
+
+			// Specialty routes
+			specialties := consented.Group("/specialties")
+			{
+				specialties.GET("/suggest", searchHandler.SuggestSpecialties)
+				specialties.GET("/:specialty/translations", translationHandler.ListSpecialtyNames)
+				specialties.PUT("/:specialty/translations/:locale", requirePermission(model.PermissionDoctorsManage), translationHandler.SetSpecialtyName)
+				specialties.DELETE("/:specialty/translations/:locale", requirePermission(model.PermissionDoctorsManage), translationHandler.DeleteSpecialtyName)
+			}
+
+			// Shift templates doctors' availability can be filled from
+			consented.GET("/shift-templates", middleware.RoleMiddleware(model.RoleDoctor, model.RoleAdmin), shiftTemplateHandler.ListShiftTemplates)
+
+			// Visit reasons patients pick when booking, and the doctors each is routed to
+			consented.GET("/visit-reasons", visitReasonHandler.ListActiveVisitReasons)
+			consented.GET("/visit-reasons/:id/doctors", visitReasonHandler.ListReasonDoctors)
+
+			// Procedure code lookup for coding appointments
+			consented.GET("/procedure-codes", requirePermission(model.PermissionMedicalRecordsWrite), procedureHandler.SearchCodes)
+
+			// Medication lookup for prescribing
+			consented.GET("/medications", requirePermission(model.PermissionMedicalRecordsWrite), medicalRecordHandler.SearchMedications)
+			consented.GET("/vaccines", immunizationHandler.ListVaccines)
+
+			// FHIR R4 reads and searches for EHR systems; observations follow medical record access
+			fhirRoutes := consented.Group("/fhir")
+			{
+				fhirRoutes.GET("/Patient", requirePermission(model.PermissionPatientsRead), fhirHandler.SearchPatients)
+				fhirRoutes.GET("/Patient/:id", requirePermission(model.PermissionPatientsRead), fhirHandler.ReadPatient)
+				fhirRoutes.GET("/Practitioner", fhirHandler.SearchPractitioners)
+				fhirRoutes.GET("/Practitioner/:id", fhirHandler.ReadPractitioner)
+				fhirRoutes.GET("/Appointment", requirePermission(model.PermissionAppointmentsRead), fhirHandler.SearchAppointments)
+				fhirRoutes.GET("/Appointment/:id", requirePermission(model.PermissionAppointmentsRead), fhirHandler.ReadAppointment)
+				fhirRoutes.GET("/Observation", fhirHandler.SearchObservations)
+				fhirRoutes.GET("/Observation/:id", fhirHandler.ReadObservation)
+			}
+
+			// Abnormal lab results awaiting review by the ordering doctor
+			labResults := consented.Group("/lab-results", middleware.RoleMiddleware(model.RoleDoctor),
+				resolvePublicIDs(map[string]model.PublicResource{"id": model.ResourceLabResult}))
+			{
+				labResults.GET("/abnormal", labHandler.GetAbnormalInbox)
+				labResults.POST("/:id/review", labHandler.ReviewResult)
 			}
 
 			// Patient routes
-			patients := protected.Group("/patients")
+			patients := consented.Group("/patients", resolvePublicIDs(map[string]model.PublicResource{
+				"id":             model.ResourcePatient,
+				"userID":         model.ResourceUser,
+				"recordID":       model.ResourceRecord,
+				"attachmentID":   model.ResourceAttachment,
+				"prescriptionID": model.ResourcePrescription,
+				"vitalID":        model.ResourceVital,
+				"orderID":        model.ResourceLabOrder,
+				"allergyID":      model.ResourceAllergy,
+				"medicationID":   model.ResourceMedication,
+			}))
 			{
 				patients.POST("", patientHandler.CreatePatient)
+				patients.POST("/records", requirePermission(model.PermissionPatientsManage), patientAccountHandler.CreateRecord)
+				patients.POST("/:id/invite", requirePermission(model.PermissionPatientsManage), patientAccountHandler.InvitePatient)
+				patients.PUT("/:id/guardian", requirePermission(model.PermissionPatientsManage), patientHandler.SetGuardian)
+				patients.GET("/search", requirePermission(model.PermissionPatientsRead), searchHandler.SearchPatients)
 				patients.GET("/:id", patientHandler.GetPatient)
 				patients.PUT("/:id", patientHandler.UpdatePatient)
 				patients.GET("/user/:userID", patientHandler.GetPatientByUser)
+				patients.GET("/:id/care-reminders", careHandler.GetPatientCareReminders)
+
+				// Break-glass emergency access
+				emergency := patients.Group("/:id", middleware.RoleMiddleware(model.RoleDoctor), stepUpMiddleware)
+				{
+					emergency.POST("/break-glass", breakGlassHandler.RequestAccess)
+					emergency.GET("/emergency-record", breakGlassHandler.GetEmergencyRecord)
+				}
+
+				// Medical records: written by treating doctors, read by them and the patient
+				records := patients.Group("/:id/medical-records")
+				{
+					records.GET("", medicalRecordHandler.ListMedicalRecords)
+					records.GET("/:recordID", medicalRecordHandler.GetMedicalRecord)
+					records.GET("/:recordID/history", medicalRecordHandler.GetMedicalRecordHistory)
+					records.GET("/:recordID/attachments", medicalRecordHandler.ListAttachments)
+					records.GET("/:recordID/attachments/:attachmentID/url", medicalRecordHandler.GetAttachmentURL)
+					writeRecords := records.Group("", middleware.RoleMiddleware(model.RoleDoctor), requirePermission(model.PermissionMedicalRecordsWrite))
+					{
+						writeRecords.POST("", medicalRecordHandler.CreateMedicalRecord)
+						writeRecords.PUT("/:recordID", medicalRecordHandler.UpdateMedicalRecord)
+						writeRecords.DELETE("/:recordID", medicalRecordHandler.DeleteMedicalRecord)
+						writeRecords.POST("/:recordID/sign", medicalRecordHandler.SignMedicalRecord)
+						writeRecords.POST("/:recordID/addenda", medicalRecordHandler.AddAddendum)
+						writeRecords.POST("/:recordID/attachments", medicalRecordHandler.UploadAttachment)
+						writeRecords.DELETE("/:recordID/attachments/:attachmentID", medicalRecordHandler.DeleteAttachment)
+					}
+				}
+
+				// Prescriptions: issued by treating doctors from the medication catalog
+				prescriptions := patients.Group("/:id/prescriptions")
+				{
+					prescriptions.GET("", medicalRecordHandler.ListPrescriptions)
+					prescriptions.GET("/:prescriptionID", medicalRecordHandler.GetPrescription)
+					prescriptions.GET("/:prescriptionID/pdf", medicalRecordHandler.GetPrescriptionPDF)
+					writePrescriptions := prescriptions.Group("", middleware.RoleMiddleware(model.RoleDoctor), requirePermission(model.PermissionMedicalRecordsWrite))
+					{
+						writePrescriptions.POST("", medicalRecordHandler.IssuePrescription)
+						writePrescriptions.POST("/:prescriptionID/cancel", medicalRecordHandler.CancelPrescription)
+					}
+				}
+
+				// Vital signs: measured at visits or reported by patients at home
+				vitals := patients.Group("/:id/vitals")
+				{
+					vitals.GET("", medicalRecordHandler.ListVitals)
+					vitals.GET("/summary", medicalRecordHandler.SummarizeVitals)
+					vitals.POST("", middleware.RoleMiddleware(model.RolePatient, model.RoleDoctor), medicalRecordHandler.RecordVital)
+					vitals.DELETE("/:vitalID", middleware.RoleMiddleware(model.RolePatient, model.RoleDoctor), medicalRecordHandler.DeleteVital)
+				}
+
+				// Allergy and medication lists: kept by treating doctors, the patient and their guardian
+				allergies := patients.Group("/:id/allergies")
+				{
+					allergies.GET("", clinicalListHandler.ListAllergies)
+					allergies.POST("", middleware.RoleMiddleware(model.RolePatient, model.RoleDoctor), clinicalListHandler.CreateAllergy)
+					allergies.PUT("/:allergyID", middleware.RoleMiddleware(model.RolePatient, model.RoleDoctor), clinicalListHandler.UpdateAllergy)
+					allergies.DELETE("/:allergyID", middleware.RoleMiddleware(model.RolePatient, model.RoleDoctor), clinicalListHandler.DeleteAllergy)
+				}
+				medicationList := patients.Group("/:id/medications")
+				{
+					medicationList.GET("", clinicalListHandler.ListMedications)
+					medicationList.POST("", middleware.RoleMiddleware(model.RolePatient, model.RoleDoctor), clinicalListHandler.CreateMedication)
+					medicationList.PUT("/:medicationID", middleware.RoleMiddleware(model.RolePatient, model.RoleDoctor), clinicalListHandler.UpdateMedication)
+					medicationList.DELETE("/:medicationID", middleware.RoleMiddleware(model.RolePatient, model.RoleDoctor), clinicalListHandler.DeleteMedication)
+				}
+
+				// Immunizations: doses recorded by treating doctors, certificates for the patient
+				immunizations := patients.Group("/:id/immunizations")
+				{
+					immunizations.GET("", immunizationHandler.ListImmunizations)
+					immunizations.GET("/certificate", immunizationHandler.GetCertificate)
+					immunizations.POST("", middleware.RoleMiddleware(model.RoleDoctor), requirePermission(model.PermissionMedicalRecordsWrite), immunizationHandler.RecordDose)
+				}
+
+				// Lab orders and their results
+				labOrders := patients.Group("/:id/lab-orders")
+				{
+					labOrders.GET("", labHandler.ListOrders)
+					labOrders.GET("/:orderID", labHandler.GetOrder)
+					labOrders.POST("", middleware.RoleMiddleware(model.RoleDoctor), requirePermission(model.PermissionMedicalRecordsWrite), labHandler.CreateOrder)
+				}
+
+				// Statements of account: viewed by the patient and billing staff, who post payments
+				patients.GET("/:id/statement", requirePermission(), accountHandler.GetStatement)
+				patients.GET("/:id/statement/pdf", requirePermission(), accountHandler.GetStatementPDF)
+				patients.POST("/:id/account/entries", requirePermission(model.PermissionBillingManage), accountHandler.PostEntry)
+
+				// Internal care-team notes, never shown to the patient
+				handoff := patients.Group("/:id/handoff-notes", middleware.RoleMiddleware(model.RoleDoctor))
+				{
+					handoff.GET("", handoffHandler.ListNotes)
+					handoff.POST("", handoffHandler.AddNote)
+				}
+
+				// Secure messages between patients and the doctors treating them
+				messages := patients.Group("/:id/messages", middleware.RoleMiddleware(model.RolePatient, model.RoleDoctor))
+				{
+					messages.GET("", messageHandler.ListPatientMessages)
+					messages.POST("", messageHandler.SendMessage)
+				}
 			}
 
+			// Doctor's inbox of patient messages
+			consented.GET("/messages", middleware.RoleMiddleware(model.RoleDoctor), messageHandler.ListDoctorMessages)
+
 			// Appointment routes
-			appointments := protected.Group("/appointments")
+			appointments := consented.Group("/appointments", resolvePublicIDs(map[string]model.PublicResource{
+				"id":        model.ResourceAppointment,
+				"patientID": model.ResourcePatient,
+				"doctorID":  model.ResourceDoctor,
+				"seriesID":  model.ResourceSeries,
+			}))
 			{
 				appointments.POST("", appointmentHandler.CreateAppointment)
+				appointments.GET("",
+					requirePermission(model.PermissionAppointmentsRead),
+					appointmentHandler.ListAppointments)
+				appointments.GET("/search",
+					requirePermission(model.PermissionAppointmentsRead),
+					searchHandler.SearchEncounters)
+				appointments.POST("/series", seriesHandler.CreateSeries)
+				appointments.GET("/series/:seriesID", seriesHandler.GetSeries)
+				appointments.GET("/series/:seriesID/occurrences", seriesHandler.ListOccurrences)
+				appointments.PUT("/series/:seriesID", seriesHandler.UpdateSeries)
+				appointments.POST("/series/:seriesID/cancel", seriesHandler.CancelSeries)
+				appointments.POST("/batch-get", appointmentHandler.BatchGetAppointments)
+				appointments.POST("/holds", slotHoldHandler.HoldSlot)
+				appointments.DELETE("/holds/:token", slotHoldHandler.ReleaseHold)
 				appointments.GET("/:id", appointmentHandler.GetAppointmentByID)
 				appointments.PUT("/:id", appointmentHandler.UpdateAppointment)
+				appointments.POST("/:id/confirm", middleware.RoleMiddleware(model.RolePatient, model.RoleDoctor), appointmentHandler.ConfirmAppointment)
+				appointments.POST("/:id/decline", middleware.RoleMiddleware(model.RoleDoctor), appointmentHandler.DeclineAppointment)
+				appointments.POST("/:id/check-in",
+					requirePermission(model.PermissionAppointmentsManage),
+					appointmentHandler.CheckInAppointment)
+				appointments.POST("/:id/attendance", middleware.RoleMiddleware(model.RolePatient), appointmentHandler.ConfirmAttendance)
+				appointments.POST("/:id/start", middleware.RoleMiddleware(model.RoleDoctor), appointmentHandler.StartAppointment)
+				appointments.GET("/:id/confirmation-letter", appointmentHandler.GetConfirmationLetter)
+				appointments.GET("/:id/ics", calendarHandler.GetAppointmentICS)
+				appointments.POST("/:id/reschedule", appointmentHandler.RescheduleAppointment)
+				appointments.POST("/:id/cancel", appointmentHandler.CancelAppointment)
+				appointments.GET("/:id/cancellation-policy", appointmentHandler.GetCancellationPolicy)
+				appointments.GET("/:id/history",
+					requirePermission(model.PermissionAppointmentsRead),
+					appointmentHandler.GetAppointmentHistory)
+				appointments.PUT("/:id/intake", appointmentHandler.SubmitIntake)
+				appointments.PUT("/:id/checklist/:item",
+					requirePermission(model.PermissionAppointmentsManage),
+					appointmentHandler.SetChecklistItem)
+				appointments.PUT("/:id/tags",
+					requirePermission(model.PermissionAppointmentsManage),
+					appointmentHandler.SetTags)
+				appointments.PUT("/:id/metadata",
+					requirePermission(model.PermissionAppointmentsManage),
+					appointmentHandler.SetMetadata)
+				appointments.GET("/:id/notifications",
+					requirePermission(model.PermissionAppointmentsRead),
+					notificationHandler.GetAppointmentNotifications)
+				appointments.GET("/:id/procedures",
+					requirePermission(model.PermissionMedicalRecordsRead),
+					procedureHandler.ListAppointmentProcedures)
+				appointments.POST("/:id/procedures",
+					requirePermission(model.PermissionMedicalRecordsWrite),
+					procedureHandler.AddAppointmentProcedure)
+				appointments.DELETE("/:id/procedures/:procedureID",
+					requirePermission(model.PermissionMedicalRecordsWrite),
+					procedureHandler.RemoveAppointmentProcedure)
 				appointments.GET("/patient/:patientID", appointmentHandler.GetPatientAppointments)
+				appointments.GET("/patient/:patientID/no-shows",
+					requirePermission(model.PermissionPatientsRead),
+					appointmentHandler.GetPatientNoShows)
 				appointments.GET("/doctor/:doctorID", appointmentHandler.GetDoctorAppointments)
 				appointments.GET("/doctor/:doctorID/schedule", appointmentHandler.GetDoctorSchedule)
+				appointments.GET("/doctor/:doctorID/queue",
+					requirePermission(model.PermissionSchedulesRead),
+					appointmentHandler.GetCheckInQueue)
+				appointments.GET("/doctor/:doctorID/day-sheet",
+					requirePermission(model.PermissionSchedulesRead),
+					appointmentHandler.GetDoctorDaySheet)
+
+				// Video visit waiting room
+				waitingRoom := appointments.Group("/:id/waiting-room")
+				{
+					waitingRoom.GET("", telehealthHandler.GetWaitingRoom)
+					waitingRoom.GET("/events", telehealthHandler.StreamWaitingRoomEvents)
+					waitingRoom.POST("/join", middleware.RoleMiddleware(model.RolePatient), telehealthHandler.JoinWaitingRoom)
+					waitingRoom.POST("/leave", middleware.RoleMiddleware(model.RolePatient), telehealthHandler.LeaveWaitingRoom)
+					waitingRoom.POST("/admit", middleware.RoleMiddleware(model.RoleDoctor), telehealthHandler.AdmitPatients)
+					waitingRoom.POST("/end", middleware.RoleMiddleware(model.RoleDoctor), telehealthHandler.EndVisit)
+				}
+			}
+
+			// Waiting room events of all of a doctor's video visits
+			consented.GET("/telehealth/events", middleware.RoleMiddleware(model.RoleDoctor), telehealthHandler.StreamDoctorEvents)
+
+			// Reception screen
+			consented.GET("/front-desk/today", requirePermission(model.PermissionSchedulesRead), frontDeskHandler.GetToday)
+
+			// Bookings made by reception staff on patients' behalf
+			onBehalf := consented.Group("/front-desk/appointments",
+				requirePermission(model.PermissionAppointmentsBook),
+				resolvePublicIDs(map[string]model.PublicResource{"id": model.ResourceAppointment}),
+			)
+			{
+				onBehalf.POST("", appointmentHandler.BookOnBehalf)
+				onBehalf.POST("/:id/reschedule", appointmentHandler.RescheduleOnBehalf)
+				onBehalf.POST("/:id/cancel", appointmentHandler.CancelOnBehalf)
+				onBehalf.POST("/:id/follow-up", frontDeskHandler.ResolveFollowUp)
+			}
+
+			// Admin routes, authorized by permission so custom roles can be granted access
+			admin := consented.Group("/admin")
+			{
+				admin.GET("/break-glass", requirePermission(model.PermissionBreakGlassReview), breakGlassHandler.ListAccesses)
+				admin.GET("/security/auth-events", requirePermission(model.PermissionAuditLogsRead), securityHandler.GetAuthEvents)
+
+				// Custom role management
+				roles := admin.Group("/", requirePermission(model.PermissionRolesManage))
+				{
+					roles.GET("/permissions", roleHandler.ListPermissions)
+					roles.POST("/roles", roleHandler.CreateRole)
+					roles.GET("/roles", roleHandler.ListRoles)
+					roles.GET("/roles/:id", roleHandler.GetRole)
+					roles.PUT("/roles/:id", roleHandler.UpdateRole)
+					roles.DELETE("/roles/:id", roleHandler.DeleteRole)
+					roles.GET("/users/:id/roles", resolveUserID, roleHandler.GetUserRoles)
+					roles.PUT("/users/:id/roles/:roleID", resolveUserID, roleHandler.AssignRole)
+					roles.DELETE("/users/:id/roles/:roleID", resolveUserID, roleHandler.UnassignRole)
+				}
+
+				// Partner API keys
+				apiKeys := admin.Group("/api-keys", requirePermission(model.PermissionIntegrationsManage),
+					resolvePublicIDs(map[string]model.PublicResource{"id": model.ResourceAPIKey}))
+				{
+					apiKeys.POST("", integrationHandler.IssueKey)
+					apiKeys.PUT("/:id", integrationHandler.UpdateKey)
+					apiKeys.DELETE("/:id", integrationHandler.RevokeKey)
+				}
+
+				// Clinic settings
+				organizations := admin.Group("/organizations", requirePermission(model.PermissionOrganizationsManage))
+				{
+					organizations.POST("", organizationHandler.CreateOrganization)
+					organizations.GET("", organizationHandler.ListOrganizations)
+					organizations.GET("/:id", organizationHandler.GetOrganization)
+					organizations.PUT("/:id", organizationHandler.UpdateOrganization)
+					organizations.DELETE("/:id", organizationHandler.DeleteOrganization)
+					organizations.PUT("/:id/doctors/:doctorID",
+						resolvePublicIDs(map[string]model.PublicResource{"doctorID": model.ResourceDoctor}),
+						organizationHandler.AssignDoctor)
+				}
+
+				admin.GET("/organizations/:id/analytics",
+					requirePermission(model.PermissionAnalyticsRead),
+					analyticsHandler.GetClinicAnalytics)
+				admin.GET("/organizations/:id/visit-durations",
+					requirePermission(model.PermissionAnalyticsRead),
+					analyticsHandler.GetVisitDurations)
+				admin.GET("/telehealth/visits",
+					requirePermission(model.PermissionAnalyticsRead),
+					telehealthHandler.GetVisitReport)
+				admin.GET("/organizations/:id/claims",
+					requirePermission(model.PermissionBillingRead),
+					stepUpMiddleware,
+					procedureHandler.ExportClaims)
+
+				// Procedure code catalog
+				procedureCodes := admin.Group("/procedure-codes", requirePermission(model.PermissionOrganizationsManage))
+				{
+					procedureCodes.PUT("", procedureHandler.ImportCodes)
+					procedureCodes.DELETE("/:code", procedureHandler.DeactivateCode)
+				}
+
+				// Medication catalog
+				medications := admin.Group("/medications", requirePermission(model.PermissionOrganizationsManage))
+				{
+					medications.PUT("", medicalRecordHandler.ImportMedications)
+					medications.DELETE("/:id", medicalRecordHandler.RetireMedication)
+				}
+
+				// Email delivery status and template previews
+				emails := admin.Group("/", requirePermission(model.PermissionEmailsManage))
+				{
+					emails.GET("/emails", emailHandler.ListMessages)
+					emails.GET("/email-suppressions", emailHandler.ListSuppressions)
+					emails.DELETE("/email-suppressions/:email", emailHandler.RemoveSuppression)
+					emails.GET("/templates", templateHandler.ListTemplates)
+					emails.GET("/templates/:name/preview", templateHandler.PreviewTemplate)
+					emails.POST("/templates/:name/test", templateHandler.SendTestTemplate)
+				}
+
+				// Promotional messages, sent only to users with marketing consent
+				admin.POST("/marketing/messages", requirePermission(model.PermissionMarketingSend), notificationHandler.SendMarketingMessage)
+
+				// Operational settings, changed without a restart
+				settings := admin.Group("/settings", requirePermission(model.PermissionSettingsManage))
+				{
+					settings.GET("", settingsHandler.ListSettings)
+					settings.PATCH("", settingsHandler.UpdateSettings)
+					settings.DELETE("/:key", settingsHandler.ResetSetting)
+				}
+
+				// Background job runbook
+				ops := admin.Group("/ops", requirePermission(model.PermissionOperationsManage))
+				{
+					ops.GET("/queues", operationsHandler.GetQueues)
+					ops.GET("/jobs", operationsHandler.GetJobs)
+					ops.GET("/breakers", operationsHandler.GetBreakers)
+					ops.POST("/jobs/:name/run", operationsHandler.RunJob)
+					ops.POST("/reminders/retry", operationsHandler.RetryFailedReminders)
+					ops.POST("/outbox/retry", operationsHandler.RetryFailedEvents)
+					ops.POST("/search/reindex", searchHandler.Reindex)
+				}
+
+				// Appointment types
+				appointmentTypes := admin.Group("/appointment-types", requirePermission(model.PermissionOrganizationsManage))
+				{
+					appointmentTypes.POST("", appointmentTypeHandler.CreateAppointmentType)
+					appointmentTypes.GET("", appointmentTypeHandler.ListAppointmentTypes)
+					appointmentTypes.GET("/:id", appointmentTypeHandler.GetAppointmentType)
+					appointmentTypes.PUT("/:id", appointmentTypeHandler.UpdateAppointmentType)
+					appointmentTypes.DELETE("/:id", appointmentTypeHandler.ArchiveAppointmentType)
+				}
+
+				// Shift templates applied to doctors' availability
+				shiftTemplates := admin.Group("/shift-templates", requirePermission(model.PermissionOrganizationsManage))
+				{
+					shiftTemplates.POST("", shiftTemplateHandler.CreateShiftTemplate)
+					shiftTemplates.GET("", shiftTemplateHandler.ListShiftTemplates)
+					shiftTemplates.GET("/:id", shiftTemplateHandler.GetShiftTemplate)
+					shiftTemplates.PUT("/:id", shiftTemplateHandler.UpdateShiftTemplate)
+					shiftTemplates.DELETE("/:id", shiftTemplateHandler.DeleteShiftTemplate)
+				}
+
+				// Visit reason taxonomy
+				visitReasons := admin.Group("/visit-reasons", requirePermission(model.PermissionOrganizationsManage))
+				{
+					visitReasons.POST("", visitReasonHandler.CreateVisitReason)
+					visitReasons.GET("", visitReasonHandler.ListVisitReasons)
+					visitReasons.GET("/:id", visitReasonHandler.GetVisitReason)
+					visitReasons.PUT("/:id", visitReasonHandler.UpdateVisitReason)
+					visitReasons.DELETE("/:id", visitReasonHandler.RetireVisitReason)
+				}
+
+				// Preventive care rules
+				careRules := admin.Group("/care-rules", requirePermission(model.PermissionOrganizationsManage))
+				{
+					careRules.POST("", careHandler.CreateCareRule)
+					careRules.GET("", careHandler.ListCareRules)
+					careRules.GET("/:id", careHandler.GetCareRule)
+					careRules.PUT("/:id", careHandler.UpdateCareRule)
+					careRules.DELETE("/:id", careHandler.ArchiveCareRule)
+				}
 			}
 		}
 	}
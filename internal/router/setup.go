@@ -1,7 +1,10 @@
 package router
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/whitewalker-sa/ehass/internal/config"
@@ -9,28 +12,156 @@ import (
 	"github.com/whitewalker-sa/ehass/internal/middleware"
 	"github.com/whitewalker-sa/ehass/internal/model"
 	"github.com/whitewalker-sa/ehass/internal/repository"
+	"github.com/whitewalker-sa/ehass/internal/sandbox"
 	"github.com/whitewalker-sa/ehass/internal/service"
+	"github.com/whitewalker-sa/ehass/pkg/breaker"
 	"github.com/whitewalker-sa/ehass/pkg/database"
+	"github.com/whitewalker-sa/ehass/pkg/events"
+	"github.com/whitewalker-sa/ehass/pkg/gcal"
+	"github.com/whitewalker-sa/ehass/pkg/redis"
+	"github.com/whitewalker-sa/ehass/pkg/secrets"
+	"github.com/whitewalker-sa/ehass/pkg/sms"
+	"github.com/whitewalker-sa/ehass/pkg/utils"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
 
-// Setup initializes all dependencies and returns the router
-func Setup(cfg *config.Config, logger *zap.Logger) (*gin.Engine, func(), error) {
+// eventStreamRoutes are the routes that hold responses open to push events
+var eventStreamRoutes = []string{
+	"GET /api/v1/appointments/:id/waiting-room/events",
+	"GET /api/v1/telehealth/events",
+}
+
+// Setup initializes all dependencies and returns the router.
+// secretsManager may be nil when credentials come from config/env only.
+func Setup(cfg *config.Config, secretsManager *secrets.Manager, logger *zap.Logger) (*gin.Engine, func(), error) {
 	// Connect to database
 	db, err := database.NewDatabase(cfg, logger)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
+	// A sandbox instance must never serve a production database
+	if cfg.Sandbox.Enabled {
+		provisioned, err := sandbox.IsProvisioned(db)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !provisioned {
+			return nil, nil, errors.New("sandbox mode requires a provisioned sandbox database; run `sandbox provision` first")
+		}
+	}
+
 	// Setup repositories
 	userRepo := repository.NewUserRepository(db)
 	doctorRepo := repository.NewDoctorRepository(db)
 	patientRepo := repository.NewPatientRepository(db)
 	appointmentRepo := repository.NewAppointmentRepository(db)
-	// Implement or comment out the availability repository for now
-	// availabilityRepo := repository.NewAvailabilityRepository(db)
+	availabilityRepo := repository.NewAvailabilityRepository(db)
+	shiftTemplateRepo := repository.NewShiftTemplateRepository(db)
+	calendarSyncRepo := repository.NewCalendarSyncRepository(db)
 	authRepo := repository.NewAuthRepository(db)
+	sessionRepo := repository.NewSessionRepository(db)
+	consentRepo := repository.NewConsentRepository(db)
+	auditLogRepo := repository.NewAuditLogRepository(db)
+	breakGlassRepo := repository.NewBreakGlassRepository(db)
+	securityAlertRepo := repository.NewSecurityAlertRepository(db)
+	runtimeSettingRepo := repository.NewRuntimeSettingRepository(db)
+	exportCursorRepo := repository.NewExportCursorRepository(db)
+	customRoleRepo := repository.NewCustomRoleRepository(db)
+	orgRepo := repository.NewOrganizationRepository(db)
+	appointmentTypeRepo := repository.NewAppointmentTypeRepository(db)
+	publicIDRepo := repository.NewPublicIDRepository(db)
+	analyticsRepo := repository.NewAnalyticsRepository(db)
+	emailRepo := repository.NewEmailRepository(db)
+	notificationRepo := repository.NewNotificationRepository(db)
+	outboxRepo := repository.NewOutboxRepository(db)
+	translationRepo := repository.NewTranslationRepository(db)
+	operationRepo := repository.NewOperationRepository(db)
+	procedureRepo := repository.NewProcedureRepository(db)
+	careRepo := repository.NewCareRepository(db)
+	seriesRepo := repository.NewRecurringAppointmentRepository(db)
+	handoffRepo := repository.NewHandoffRepository(db)
+	messageRepo := repository.NewMessageRepository(db)
+	medicalRecordRepo := repository.NewMedicalRecordRepository(db)
+	prescriptionRepo := repository.NewPrescriptionRepository(db)
+	vitalRepo := repository.NewVitalRepository(db)
+	labRepo := repository.NewLabRepository(db)
+	accountRepo := repository.NewAccountRepository(db)
+	clinicalListRepo := repository.NewClinicalListRepository(db)
+	immunizationRepo := repository.NewImmunizationRepository(db)
+	apiKeyRepo := repository.NewAPIKeyRepository(db)
+	telehealthRepo := repository.NewTelehealthRepository(db)
+	reviewRepo := repository.NewReviewRepository(db)
+	visitReasonRepo := repository.NewVisitReasonRepository(db)
+
+	smsSender, err := config.NewSMSSender(cfg, logger)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create SMS sender: %w", err)
+	}
+	// Guard calls to external providers so a slow one fails fast instead of piling up requests
+	breakers := breaker.NewRegistry()
+	if cfg.Sandbox.Enabled {
+		logger.Warn("Running in sandbox mode; emails and text messages are recorded but not sent")
+		smsSender = sms.NewLogSender(logger)
+	} else if cfg.SMS.Provider != "" {
+		smsSender = sms.NewBreakerSender(smsSender, breakers.Add("sms", cfg.Breakers.SMS.Settings()))
+	}
+
+	// Files attached to medical records
+	attachmentStore, err := config.NewAttachmentStore(cfg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create attachment storage: %w", err)
+	}
+
+	// Search doctors and patients in the search backend, if one is configured
+	searchClient, err := config.NewSearchClient(cfg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create search client: %w", err)
+	}
+	var searchBreaker *breaker.Breaker
+	if searchClient != nil {
+		searchBreaker = breakers.Add("search", cfg.Breakers.Search.Settings())
+		logger.Info("Search backend enabled", zap.String("backend", cfg.Search.Backend))
+	}
+
+	// Keep slot holds in Redis so every instance sees them
+	var redisClient *redis.Client
+	slotHoldRepo := repository.NewMemorySlotHoldRepository()
+	switch cfg.SlotHold.Store {
+	case "redis":
+		redisClient, err = config.NewRedisClient(cfg)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to connect to redis: %w", err)
+		}
+		slotHoldRepo = repository.NewRedisSlotHoldRepository(redisClient)
+	case "", "memory":
+		logger.Warn("Slot holds are kept in memory and not shared between instances")
+	default:
+		return nil, nil, fmt.Errorf("unknown slot hold store %q", cfg.SlotHold.Store)
+	}
+
+	// Queue each doctor's bookings in Redis when row locks alone see too much contention
+	var bookingLocks *service.BookingLocks
+	switch cfg.Booking.Lock {
+	case "redis":
+		if redisClient == nil {
+			redisClient, err = config.NewRedisClient(cfg)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to connect to redis: %w", err)
+			}
+		}
+		bookingLocks = service.NewBookingLocks(
+			repository.NewRedisBookingLockRepository(redisClient),
+			cfg.Booking.LockWait,
+			cfg.Booking.LockTTL,
+			logger,
+		)
+		logger.Info("Bookings are serialized per doctor in redis")
+	case "", "row":
+	default:
+		return nil, nil, fmt.Errorf("unknown booking lock %q", cfg.Booking.Lock)
+	}
 
 	// Setup services
 	emailService := service.NewEmailService(
@@ -40,6 +171,11 @@ func Setup(cfg *config.Config, logger *zap.Logger) (*gin.Engine, func(), error)
 		cfg.Email.SMTPPassword,
 		cfg.Email.FromEmail,
 		cfg.Server.BaseURL,
+		orgRepo,
+		emailRepo,
+		cfg.Sandbox.Enabled,
+		breakers.Add("smtp", cfg.Breakers.SMTP.Settings()),
+		logger,
 	)
 
 	oauthService := service.NewOAuthService(
@@ -47,31 +183,425 @@ func Setup(cfg *config.Config, logger *zap.Logger) (*gin.Engine, func(), error)
 		cfg.OAuth.GitHub.ClientSecret,
 		cfg.OAuth.Google.ClientID,
 		cfg.OAuth.Google.ClientSecret,
+		breakers.Add("oauth_github", cfg.Breakers.OAuth.Settings()),
+		breakers.Add("oauth_google", cfg.Breakers.OAuth.Settings()),
 	)
 
+	emailPolicy := service.NewEmailPolicy(cfg.Auth.BlockDisposableEmails, cfg.Auth.DisposableEmailDomains)
 	authService := service.NewAuthService(
 		authRepo,
+		sessionRepo,
+		auditLogRepo,
 		cfg.Auth.AccessTokenSecret,
 		int(cfg.Auth.AccessTokenExpiry.Minutes()),
+		cfg.Auth.Issuer,
+		cfg.Auth.Audience,
+		cfg.Auth.SessionIdleTimeout,
+		cfg.Auth.SessionAbsoluteTimeout,
 		emailService,
 		oauthService,
+		emailPolicy,
 	)
 
 	userService := service.NewUserService(userRepo, cfg, logger)
 	// Implement these services or use simpler constructors
-	doctorService := service.NewDoctorService(doctorRepo, logger)
-	patientService := service.NewPatientService(patientRepo, logger)
-	appointmentService := service.NewAppointmentService(appointmentRepo, doctorRepo, patientRepo, logger)
+	slotCache := service.NewSlotCache(cfg.Slots.CacheTTL, cfg.Slots.CacheSize)
+	doctorService := service.NewDoctorService(doctorRepo, slotCache, logger)
+	patientService := service.NewPatientService(patientRepo, cfg.SMS.DefaultRegion, logger)
+	searchService := service.NewSearchService(
+		searchClient,
+		searchBreaker,
+		doctorRepo,
+		patientRepo,
+		appointmentRepo,
+		handoffRepo,
+		patientService,
+		cfg.Suggest.CacheTTL,
+		cfg.Suggest.CacheSize,
+		logger,
+	)
+	translationService := service.NewTranslationService(translationRepo, doctorRepo, cfg.Content.Locale, logger)
+	// Operational settings admins change at runtime, starting from those stored by any instance
+	settingsService := service.NewSettingsService(runtimeSettingRepo, auditLogRepo, cfg.Runtime, logger)
+	if err := settingsService.Reload(context.Background()); err != nil {
+		logger.Warn("Failed to load runtime settings, using configured defaults", zap.Error(err))
+	}
+	orgService := service.NewOrganizationService(orgRepo, doctorRepo, settingsService, logger)
+	noShowService := service.NewNoShowService(
+		appointmentRepo,
+		patientRepo,
+		smsSender,
+		cfg.NoShow.HighRiskThreshold,
+		settingsService,
+		logger,
+	)
+	slotHoldService := service.NewSlotHoldService(slotHoldRepo, appointmentRepo, orgService, cfg.SlotHold.TTL, logger)
+	appointmentService := service.NewAppointmentService(appointmentRepo, doctorRepo, patientRepo, appointmentTypeRepo, visitReasonRepo, availabilityRepo, orgService, noShowService, slotHoldService, slotCache, bookingLocks, auditLogRepo, logger)
+	seriesService := service.NewRecurringAppointmentService(seriesRepo, doctorRepo, patientRepo, appointmentTypeRepo, availabilityRepo, orgService, noShowService, slotHoldService, slotCache, bookingLocks, logger)
+	availabilityService := service.NewAvailabilityService(availabilityRepo, shiftTemplateRepo, appointmentRepo, doctorRepo, orgService, slotCache, logger)
+	scheduleService := service.NewScheduleService(availabilityRepo, doctorRepo, appointmentRepo, slotHoldRepo, orgService, slotCache, logger)
+	appointmentTypeService := service.NewAppointmentTypeService(appointmentTypeRepo, orgRepo, orgService, logger)
+	consentService := service.NewConsentService(consentRepo, cfg, logger)
+	roleService := service.NewRoleService(customRoleRepo, userRepo, logger)
+	publicIDService := service.NewPublicIDService(publicIDRepo)
+	emailDeliveryService := service.NewEmailDeliveryService(emailRepo, logger)
+	notificationService := service.NewNotificationService(notificationRepo, appointmentRepo, userRepo, consentRepo, orgService, emailService, smsSender, logger)
+	// Links that work without signing in have their own secret, so a leaked link key cannot mint
+	// access tokens and the two rotate independently
+	if cfg.Auth.LinkSigningSecret == "" || cfg.Auth.LinkSigningSecret == cfg.Auth.AccessTokenSecret {
+		return nil, nil, errors.New("auth.linkSigningSecret must be set and differ from auth.accessTokenSecret")
+	}
+	linkKeys := service.NewSigningKeys(cfg.Auth.LinkSigningSecret)
+	patientAccountService := service.NewPatientAccountService(
+		authRepo,
+		patientRepo,
+		auditLogRepo,
+		emailService,
+		smsSender,
+		linkKeys,
+		cfg.Auth.InviteExpiry,
+		cfg.Auth.InviteResendAfter,
+		emailPolicy,
+		cfg.SMS.DefaultRegion,
+		logger,
+	)
+	analyticsService := service.NewAnalyticsService(analyticsRepo, orgRepo, cfg.Analytics.SettlePeriod, cfg.Analytics.PunctualityGrace, logger)
+	procedureService := service.NewProcedureService(procedureRepo, appointmentRepo, orgRepo, logger)
+	careService := service.NewCareService(careRepo, appointmentTypeRepo, logger)
+	handoffService := service.NewHandoffService(handoffRepo, doctorRepo, patientRepo, logger)
+	messageService := service.NewMessageService(messageRepo, handoffRepo, availabilityRepo, doctorRepo, patientRepo, logger)
+	labService := service.NewLabService(labRepo, medicalRecordRepo, handoffRepo, doctorRepo, patientRepo, auditLogRepo, logger)
+	clinicalListService := service.NewClinicalListService(clinicalListRepo, prescriptionRepo, handoffRepo, doctorRepo, patientRepo, auditLogRepo, logger)
+	immunizationService := service.NewImmunizationService(immunizationRepo, orgRepo, handoffRepo, doctorRepo, patientRepo, auditLogRepo, logger)
+	apiKeyService := service.NewAPIKeyService(apiKeyRepo, userRepo, auditLogRepo, cfg.Integrations.RequestsPerMinute, cfg.Integrations.DailyQuota, logger)
+	fhirService := service.NewFHIRService(patientRepo, doctorRepo, appointmentRepo, vitalRepo, handoffRepo, publicIDService, cfg.Server.BaseURL, logger)
+	accountService := service.NewAccountService(accountRepo, patientRepo, orgRepo, auditLogRepo, logger)
+	medicalRecordService := service.NewMedicalRecordService(
+		medicalRecordRepo,
+		prescriptionRepo,
+		vitalRepo,
+		handoffRepo,
+		doctorRepo,
+		patientRepo,
+		auditLogRepo,
+		orgService,
+		attachmentStore,
+		cfg.Attachments,
+		linkKeys,
+		cfg.Server.BaseURL,
+		logger,
+	)
+	telehealthService := service.NewTelehealthService(telehealthRepo, appointmentRepo, doctorRepo, patientRepo, cfg.Telehealth, logger)
+	frontDeskService := service.NewFrontDeskService(orgRepo, doctorRepo, availabilityRepo, appointmentRepo, logger)
+	templateService := service.NewTemplateService(emailService, smsSender, logger)
+	visitReasonService := service.NewVisitReasonService(visitReasonRepo, doctorRepo, logger)
+	shiftTemplateService := service.NewShiftTemplateService(shiftTemplateRepo, logger)
+	// Calendar alarms match the appointment reminders patients are sent
+	var calendarAlarm time.Duration
+	if cfg.Reminders.Enabled {
+		calendarAlarm = cfg.Reminders.LeadTime
+	}
+	calendarService := service.NewCalendarService(
+		userRepo,
+		patientRepo,
+		doctorRepo,
+		appointmentRepo,
+		orgService,
+		cfg.Server.BaseURL,
+		calendarAlarm,
+		logger,
+	)
+	// Sync doctors' Google Calendars through the Google OAuth client
+	var calendarClient *gcal.Client
+	if cfg.Calendar.Enabled {
+		calendarClient, err = gcal.NewClient(cfg.OAuth.Google.ClientID, cfg.OAuth.Google.ClientSecret, cfg.Breakers.Calendar.Timeout)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create Google Calendar client: %w", err)
+		}
+	}
+	calendarSyncService := service.NewCalendarSyncService(
+		calendarSyncRepo,
+		doctorRepo,
+		appointmentRepo,
+		orgService,
+		calendarClient,
+		breakers.Add("google_calendar", cfg.Breakers.Calendar.Settings()),
+		cfg.Calendar.RedirectURL,
+		cfg.Calendar.Window,
+		slotCache,
+		logger,
+	)
+	reviewService := service.NewReviewService(
+		reviewRepo,
+		appointmentRepo,
+		doctorRepo,
+		patientRepo,
+		cfg.Reviews.Window,
+		cfg.Reviews.DailyLimit,
+		logger,
+	)
+	breakGlassService := service.NewBreakGlassService(
+		breakGlassRepo,
+		patientRepo,
+		userRepo,
+		auditLogRepo,
+		emailService,
+		cfg.BreakGlass.AccessDuration,
+		logger,
+	)
+	securityService := service.NewSecurityService(auditLogRepo, securityAlertRepo, cfg.Security, logger)
+
+	// Watch for rotated secrets
+	stopSecretsRefresh := func() {}
+	if secretsManager != nil {
+		registerRotationHooks(cfg, secretsManager, authService, linkKeys, logger)
+		stopSecretsRefresh = secretsManager.Start(cfg.Secrets.RefreshInterval)
+	}
+
+	// Export audit logs to the SIEM
+	siemSink, err := config.NewSIEMSink(cfg)
+	if err != nil {
+		stopSecretsRefresh()
+		return nil, nil, fmt.Errorf("failed to create SIEM sink: %w", err)
+	}
+	// Scheduled jobs report their runs for the operations endpoints
+	jobMonitor := service.NewJobMonitor()
+
+	stopAuditExport := func() {}
+	var auditExporter *service.AuditExporter
+	if siemSink != nil {
+		auditExporter = service.NewAuditExporter(
+			auditLogRepo,
+			exportCursorRepo,
+			siemSink,
+			cfg.SIEM.BatchSize,
+			cfg.SIEM.Interval,
+			cfg.SIEM.MaxBackoff,
+			jobMonitor,
+			logger,
+		)
+		stopAuditExport = auditExporter.Start()
+		logger.Info("SIEM export enabled", zap.String("sink", siemSink.Name()))
+	}
+
+	// Remind patients of upcoming appointments
+	stopReminders := func() {}
+	if cfg.Reminders.Enabled {
+		reminderScheduler, err := service.NewReminderScheduler(
+			appointmentRepo,
+			notificationService,
+			cfg.Reminders.LeadTime,
+			cfg.Reminders.Interval,
+			cfg.Reminders.Escalation,
+			jobMonitor,
+			logger,
+		)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid reminder configuration: %w", err)
+		}
+		stopReminders = reminderScheduler.Start()
+		logger.Info("Appointment reminders enabled",
+			zap.Duration("leadTime", cfg.Reminders.LeadTime),
+			zap.Int("escalationSteps", len(cfg.Reminders.Escalation)))
+	}
+
+	// Remind patients of preventive care coming due
+	stopCareReminders := func() {}
+	if cfg.Care.Enabled {
+		stopCareReminders = service.NewCareReminderScheduler(
+			careRepo,
+			cfg.Care.LeadTime,
+			cfg.Care.BatchSize,
+			cfg.Care.Interval,
+			jobMonitor,
+			logger,
+		).Start()
+		logger.Info("Care reminders enabled", zap.Duration("leadTime", cfg.Care.LeadTime))
+	}
+
+	// Deliver appointment events, emails and search index updates written to the outbox
+	eventPublisher, err := config.NewEventPublisher(cfg, logger)
+	if err != nil {
+		stopSecretsRefresh()
+		stopAuditExport()
+		stopReminders()
+		stopCareReminders()
+		return nil, nil, fmt.Errorf("failed to create event publisher: %w", err)
+	}
+	if cfg.Sandbox.Enabled {
+		eventPublisher = events.NewLogPublisher(logger)
+	}
+	stopOutbox := service.NewOutboxDispatcher(
+		outboxRepo,
+		appointmentRepo,
+		careRepo,
+		orgService,
+		emailService,
+		searchService,
+		eventPublisher,
+		cfg.Outbox.BatchSize,
+		cfg.Outbox.Interval,
+		cfg.Outbox.MaxAttempts,
+		cfg.Outbox.MaxBackoff,
+		jobMonitor,
+		logger,
+	).Start()
+
+	// Mark appointments nobody completed or cancelled as no-shows
+	stopNoShows := func() {}
+	if cfg.NoShow.DetectAfter > 0 {
+		stopNoShows = service.NewNoShowDetector(
+			appointmentRepo,
+			cfg.NoShow.DetectAfter,
+			cfg.NoShow.BatchSize,
+			cfg.NoShow.Interval,
+			jobMonitor,
+			logger,
+		).Start()
+	}
+
+	// Push appointments to connected calendars and pull back their busy times
+	stopCalendarSync := func() {}
+	if calendarClient != nil {
+		stopCalendarSync = service.NewCalendarSyncer(
+			calendarSyncService,
+			cfg.Calendar.Interval,
+			jobMonitor,
+			logger,
+		).Start()
+	}
+
+	// Pick up settings changed through other instances
+	stopSettingsReload := service.NewSettingsReloader(settingsService, cfg.Runtime.RefreshInterval, jobMonitor, logger).Start()
+
+	// Raise security alerts on failed login, reset and 2FA patterns
+	stopAuthAnomalies := func() {}
+	if cfg.Security.Enabled {
+		stopAuthAnomalies = service.NewAuthAnomalyDetector(
+			securityService,
+			cfg.Security.Interval,
+			jobMonitor,
+			logger,
+		).Start()
+	}
+
+	// Delete expired tokens and sessions, delivered outbox events and finished operations
+	stopCleanup := service.NewCleanupJob(
+		authRepo,
+		sessionRepo,
+		outboxRepo,
+		operationRepo,
+		cfg.Outbox.Retention,
+		cfg.Operations.Retention,
+		cfg.Cleanup.Interval,
+		jobMonitor,
+		logger,
+	).Start()
+
+	// Handlers register the operations they run in the background before it starts
+	operationRunner := service.NewOperationRunner(operationRepo, cfg.Operations.Interval, cfg.Operations.Lease, jobMonitor, logger)
+
+	operationsService := service.NewOperationsService(
+		appointmentRepo,
+		emailRepo,
+		outboxRepo,
+		notificationService,
+		auditExporter,
+		jobMonitor,
+		breakers,
+		cfg.Reminders.LeadTime,
+		logger,
+	)
+	routeBudgets := make(map[string]time.Duration, len(cfg.Metrics.RouteBudgets))
+	for _, budget := range cfg.Metrics.RouteBudgets {
+		routeBudgets[budget.Route] = budget.Budget
+	}
+	// Event streams stay open for minutes, so they are not tracked unless configured
+	for _, route := range eventStreamRoutes {
+		if _, ok := routeBudgets[route]; !ok {
+			routeBudgets[route] = 0
+		}
+	}
+	latencyMonitor := service.NewLatencyMonitor(cfg.Metrics.LatencyBudget, routeBudgets)
+	metricsService := service.NewMetricsService(
+		appointmentRepo,
+		doctorRepo,
+		operationsService,
+		latencyMonitor,
+		utils.LoadLocation(cfg.Metrics.Timezone),
+		logger,
+	)
 
 	// Setup middleware
-	authMiddleware := middleware.NewAuthMiddleware(authService, logger)
+	authMiddleware := middleware.APIKeyAuth(apiKeyService, middleware.NewAuthMiddleware(authService, logger), logger)
+	stepUpMiddleware := middleware.RequireRecentAuth(authService, cfg.Auth.StepUpMaxAge, logger)
+	consentMiddleware := middleware.ConsentMiddleware(consentService, logger)
+	introspectionMiddleware := middleware.IntrospectionClientAuth(cfg.Auth.IntrospectionClients)
+	emailWebhookMiddleware := middleware.WebhookSecretAuth(cfg.Email.WebhookSecret)
+	labWebhookMiddleware := middleware.WebhookSecretAuth(cfg.Labs.WebhookSecret)
+	metricsMiddleware := middleware.BearerTokenAuth(cfg.Metrics.Token)
+	latencyMiddleware := middleware.LatencyBudget(latencyMonitor, logger)
+	routeTimeouts := make(map[string]time.Duration, len(cfg.Timeouts.Routes))
+	for _, timeout := range cfg.Timeouts.Routes {
+		routeTimeouts[timeout.Route] = timeout.Timeout
+	}
+	for _, route := range eventStreamRoutes {
+		if _, ok := routeTimeouts[route]; !ok {
+			routeTimeouts[route] = 0
+		}
+	}
+	operationTimeouts := service.NewOperationTimeouts(cfg.Timeouts.Request, routeTimeouts)
+	deadlineMiddleware := middleware.Deadline(operationTimeouts, logger)
+	maintenanceMiddleware := middleware.Maintenance(settingsService)
+	rateLimitMiddleware := middleware.RateLimit(settingsService)
+	requirePermission := middleware.NewPermissionChecker(roleService, logger)
+	resolvePublicIDs := middleware.NewPublicIDResolver(publicIDService, logger)
 
 	// Setup handlers
-	authHandler := handler.NewAuthHandler(authService)
+	authHandler := handler.NewAuthHandler(authService, publicIDService)
 	userHandler := handler.NewUserHandler(userService, logger)
-	doctorHandler := handler.NewDoctorHandler(doctorService, logger)
-	patientHandler := handler.NewPatientHandler(patientService, logger)
-	appointmentHandler := handler.NewAppointmentHandler(appointmentService, logger)
+	doctorHandler := handler.NewDoctorHandler(doctorService, translationService, logger)
+	patientHandler := handler.NewPatientHandler(patientService, publicIDService, logger)
+	searchHandler := handler.NewSearchHandler(searchService, translationService, operationRunner, cfg.Suggest.CacheTTL, logger)
+	appointmentHandler := handler.NewAppointmentHandler(appointmentService, noShowService, publicIDService, logger)
+	consentHandler := handler.NewConsentHandler(consentService, logger)
+	breakGlassHandler := handler.NewBreakGlassHandler(breakGlassService, logger)
+	roleHandler := handler.NewRoleHandler(roleService, logger)
+	organizationHandler := handler.NewOrganizationHandler(orgService, logger)
+	appointmentTypeHandler := handler.NewAppointmentTypeHandler(appointmentTypeService, logger)
+	analyticsHandler := handler.NewAnalyticsHandler(analyticsService, operationRunner, logger)
+	availabilityHandler := handler.NewAvailabilityHandler(availabilityService, doctorService, publicIDService, logger)
+	scheduleHandler := handler.NewScheduleHandler(scheduleService, logger)
+	emailHandler := handler.NewEmailHandler(emailDeliveryService, logger)
+	notificationHandler := handler.NewNotificationHandler(notificationService, publicIDService, logger)
+	slotHoldHandler := handler.NewSlotHoldHandler(slotHoldService, publicIDService, logger)
+	patientAccountHandler := handler.NewPatientAccountHandler(patientAccountService, logger)
+	operationsHandler := handler.NewOperationsHandler(operationsService, logger)
+	translationHandler := handler.NewTranslationHandler(translationService, logger)
+	metricsHandler := handler.NewMetricsHandler(metricsService, logger)
+	operationHandler := handler.NewOperationHandler(operationRunner, logger)
+	procedureHandler := handler.NewProcedureHandler(procedureService, logger)
+	careHandler := handler.NewCareHandler(careService, logger)
+	seriesHandler := handler.NewRecurringAppointmentHandler(seriesService, publicIDService, logger)
+	handoffHandler := handler.NewHandoffHandler(handoffService, publicIDService, logger)
+	messageHandler := handler.NewMessageHandler(messageService, publicIDService, logger)
+	frontDeskHandler := handler.NewFrontDeskHandler(frontDeskService, logger)
+	templateHandler := handler.NewTemplateHandler(templateService, logger)
+	reviewHandler := handler.NewReviewHandler(reviewService, publicIDService, logger)
+	visitReasonHandler := handler.NewVisitReasonHandler(visitReasonService, translationService, logger)
+	shiftTemplateHandler := handler.NewShiftTemplateHandler(shiftTemplateService, logger)
+	calendarHandler := handler.NewCalendarHandler(calendarService, calendarSyncService, logger)
+	securityHandler := handler.NewSecurityHandler(securityService, logger)
+	medicalRecordHandler := handler.NewMedicalRecordHandler(medicalRecordService, publicIDService, cfg.Attachments.MaxSize, logger)
+	settingsHandler := handler.NewSettingsHandler(settingsService, logger)
+	telehealthHandler := handler.NewTelehealthHandler(telehealthService, publicIDService, cfg.Telehealth, logger)
+	labHandler := handler.NewLabHandler(labService, publicIDService, logger)
+	accountHandler := handler.NewAccountHandler(accountService, logger)
+	clinicalListHandler := handler.NewClinicalListHandler(clinicalListService, logger)
+	immunizationHandler := handler.NewImmunizationHandler(immunizationService, logger)
+	integrationHandler := handler.NewIntegrationHandler(apiKeyService, publicIDService, logger)
+	fhirHandler := handler.NewFHIRHandler(fhirService, logger)
+	stopOperations := operationRunner.Start()
 
 	// Setup router
 	router := SetupRouter(
@@ -80,11 +610,91 @@ func Setup(cfg *config.Config, logger *zap.Logger) (*gin.Engine, func(), error)
 		doctorHandler,
 		patientHandler,
 		appointmentHandler,
+		consentHandler,
+		breakGlassHandler,
+		roleHandler,
+		organizationHandler,
+		appointmentTypeHandler,
+		analyticsHandler,
+		availabilityHandler,
+		scheduleHandler,
+		emailHandler,
+		notificationHandler,
+		slotHoldHandler,
+		patientAccountHandler,
+		operationsHandler,
+		searchHandler,
+		translationHandler,
+		metricsHandler,
+		operationHandler,
+		procedureHandler,
+		careHandler,
+		seriesHandler,
+		handoffHandler,
+		messageHandler,
+		frontDeskHandler,
+		templateHandler,
+		reviewHandler,
+		visitReasonHandler,
+		shiftTemplateHandler,
+		calendarHandler,
+		securityHandler,
+		medicalRecordHandler,
+		settingsHandler,
+		telehealthHandler,
+		labHandler,
+		accountHandler,
+		clinicalListHandler,
+		immunizationHandler,
+		integrationHandler,
+		fhirHandler,
 		authMiddleware,
+		stepUpMiddleware,
+		consentMiddleware,
+		introspectionMiddleware,
+		emailWebhookMiddleware,
+		labWebhookMiddleware,
+		metricsMiddleware,
+		latencyMiddleware,
+		deadlineMiddleware,
+		rateLimitMiddleware,
+		maintenanceMiddleware,
+		requirePermission,
+		resolvePublicIDs,
 	)
+	routes := make([]string, 0, len(router.Routes()))
+	for _, route := range router.Routes() {
+		routes = append(routes, route.Method+" "+route.Path)
+	}
+	for _, route := range latencyMonitor.Unknown(routes) {
+		logger.Warn("Latency budget set for an unknown route", zap.String("route", route))
+	}
+	for _, route := range operationTimeouts.Unknown(routes) {
+		logger.Warn("Operation timeout set for an unknown route", zap.String("route", route))
+	}
 
 	// Setup cleanup function
 	cleanup := func() {
+		stopSecretsRefresh()
+		stopAuditExport()
+		stopReminders()
+		stopCareReminders()
+		stopOutbox()
+		stopNoShows()
+		stopCalendarSync()
+		stopAuthAnomalies()
+		stopSettingsReload()
+		stopOperations()
+		stopCleanup()
+		if redisClient != nil {
+			redisClient.Close()
+		}
+		if siemSink != nil {
+			if err := siemSink.Close(); err != nil {
+				logger.Error("Failed to close SIEM sink", zap.Error(err))
+			}
+		}
+
 		sqlDB, err := db.DB()
 		if err != nil {
 			logger.Error("Failed to get database connection", zap.Error(err))
@@ -98,6 +708,44 @@ func Setup(cfg *config.Config, logger *zap.Logger) (*gin.Engine, func(), error)
 	return router, cleanup, nil
 }
 
+// registerRotationHooks applies rotated secrets to the running services
+func registerRotationHooks(
+	cfg *config.Config,
+	secretsManager *secrets.Manager,
+	authService service.AuthService,
+	linkKeys *service.SigningKeys,
+	logger *zap.Logger,
+) {
+	if path := cfg.Secrets.Paths.Auth; path != "" {
+		secretsManager.OnRotate(path, func(values map[string]string) {
+			config.ApplyAuthSecrets(cfg, values)
+			authService.RotateSigningSecret(cfg.Auth.AccessTokenSecret)
+			linkKeys.Rotate(cfg.Auth.LinkSigningSecret)
+			logger.Info("Token and link signing secrets rotated")
+		})
+	}
+
+	// Database, SMTP and OAuth clients are built once at startup, so rotated values apply on restart
+	restartOnly := []struct {
+		path  string
+		apply func(*config.Config, map[string]string)
+	}{
+		{cfg.Secrets.Paths.Database, config.ApplyDatabaseSecrets},
+		{cfg.Secrets.Paths.Email, config.ApplyEmailSecrets},
+		{cfg.Secrets.Paths.OAuth, config.ApplyOAuthSecrets},
+	}
+	for _, group := range restartOnly {
+		if group.path == "" {
+			continue
+		}
+		path, apply := group.path, group.apply
+		secretsManager.OnRotate(path, func(values map[string]string) {
+			apply(cfg, values)
+			logger.Warn("Secret rotated; restart the service to apply it", zap.String("path", path))
+		})
+	}
+}
+
 // AutoMigrate runs database migrations
 func AutoMigrate(db *gorm.DB) error {
 	// Add models to be migrated
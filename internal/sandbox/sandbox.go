@@ -0,0 +1,392 @@
+// Package sandbox provisions a database with synthetic clinic data for integrators to test against.
+package sandbox
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// EmailDomain is the domain of every provisioned account. The .test TLD is reserved and never
+// resolves, so synthetic addresses cannot reach a real mailbox.
+const EmailDomain = "sandbox.test"
+
+// OrganizationName is the name of the provisioned clinic
+const OrganizationName = "EHASS Sandbox Clinic"
+
+// appointmentDays is how many days before and after today appointments are spread over
+const appointmentDays = 14
+
+// ErrNotSandbox is returned when a database that was never provisioned as a sandbox already holds
+// users, so real patient data is never mixed with or replaced by synthetic data
+var ErrNotSandbox = errors.New("database contains users and is not a sandbox; use a dedicated sandbox database")
+
+// Options controls how much data is generated
+type Options struct {
+	Doctors  int
+	Patients int
+	Password string
+	Seed     int64
+}
+
+// Result summarizes a provisioned sandbox
+type Result struct {
+	AdminEmail   string
+	Doctors      int
+	Patients     int
+	Appointments int
+}
+
+var (
+	firstNames = []string{"Amara", "Ben", "Chloe", "Daniel", "Elif", "Farah", "Gabriel", "Hana", "Isaac", "Jade",
+		"Kofi", "Lena", "Mateo", "Nadia", "Omar", "Priya", "Quinn", "Rosa", "Samir", "Tara"}
+	lastNames = []string{"Abbott", "Banda", "Castillo", "Dubois", "Eriksen", "Fischer", "Garcia", "Haddad", "Ito",
+		"Jansen", "Khumalo", "Larsen", "Moreau", "Novak", "Okafor", "Petrov", "Rossi", "Silva", "Tanaka", "Weber"}
+	specialties  = []string{"general practice", "cardiology", "dermatology", "pediatrics", "orthopedics", "neurology"}
+	genders      = []string{"female", "male", "other"}
+	bloodGroups  = []string{"A+", "A-", "B+", "B-", "AB+", "O+", "O-"}
+	visitReasons = []string{"Annual check-up", "Follow-up visit", "Persistent cough", "Back pain", "Skin rash",
+		"Blood pressure review", "Headaches", "Vaccination"}
+)
+
+// Provision fills an empty or sandbox-only database with a clinic, an admin, doctors with weekday
+// availability, patients and appointments around today. Running it again replaces the previous
+// sandbox data; the same seed produces the same accounts and booking pattern.
+func Provision(db *gorm.DB, opts Options, logger *zap.Logger) (*Result, error) {
+	if opts.Password == "" {
+		return nil, errors.New("sandbox password is required")
+	}
+	if opts.Doctors < 1 || opts.Patients < 1 {
+		return nil, errors.New("at least one doctor and one patient are required")
+	}
+
+	provisioned, err := IsProvisioned(db)
+	if err != nil {
+		return nil, err
+	}
+	if !provisioned {
+		var users int64
+		if err := db.Model(&model.User{}).Count(&users).Error; err != nil {
+			return nil, fmt.Errorf("failed to check existing users: %w", err)
+		}
+		if users > 0 {
+			return nil, ErrNotSandbox
+		}
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(opts.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	result := &Result{AdminEmail: "admin@" + EmailDomain}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := reset(tx); err != nil {
+			return err
+		}
+
+		g := &generator{
+			tx:           tx,
+			rng:          rand.New(rand.NewSource(opts.Seed)),
+			passwordHash: string(hash),
+			now:          time.Now().UTC(),
+		}
+
+		org := &model.Organization{
+			Name:                     OrganizationName,
+			ContactEmail:             "clinic@" + EmailDomain,
+			Timezone:                 "UTC",
+			BusinessHours:            weekdayHours(),
+			DefaultAppointmentLength: 30,
+		}
+		if err := tx.Create(org).Error; err != nil {
+			return fmt.Errorf("failed to create clinic: %w", err)
+		}
+
+		if _, err := g.user("Sandbox Admin", result.AdminEmail, model.RoleAdmin); err != nil {
+			return err
+		}
+
+		doctors, err := g.doctors(opts.Doctors, org.ID)
+		if err != nil {
+			return err
+		}
+		patients, err := g.patients(opts.Patients)
+		if err != nil {
+			return err
+		}
+		count, err := g.appointments(doctors, patients)
+		if err != nil {
+			return err
+		}
+
+		result.Doctors = len(doctors)
+		result.Patients = len(patients)
+		result.Appointments = count
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Sandbox provisioned",
+		zap.Int("doctors", result.Doctors),
+		zap.Int("patients", result.Patients),
+		zap.Int("appointments", result.Appointments))
+	return result, nil
+}
+
+// IsProvisioned reports whether the database has been provisioned as a sandbox
+func IsProvisioned(db *gorm.DB) (bool, error) {
+	var count int64
+	if err := db.Model(&model.Organization{}).Where("name = ?", OrganizationName).Count(&count).Error; err != nil {
+		return false, fmt.Errorf("failed to check for sandbox clinic: %w", err)
+	}
+	return count > 0, nil
+}
+
+// reset deletes previously provisioned data along with anything integrators created while
+// testing. The database is a sandbox at this point. Tables are cleared children first.
+func reset(tx *gorm.DB) error {
+	tables := []interface{}{
+		&model.OutboxEvent{},
+		&model.Notification{},
+		&model.EmailMessage{},
+		&model.EmailSuppression{},
+		&model.MedicalRecordAttachment{},
+		&model.MedicalRecordVersion{},
+		&model.MedicalRecordAddendum{},
+		&model.Vital{},
+		&model.Allergy{},
+		&model.PatientMedication{},
+		&model.Immunization{},
+		&model.LabResult{},
+		&model.LabOrder{},
+		&model.AccountEntry{},
+		&model.Prescription{},
+		&model.MedicalRecord{},
+		&model.AppointmentProcedure{},
+		&model.HandoffNote{},
+		&model.Message{},
+		&model.DoctorReview{},
+		&model.AppointmentHistory{},
+		&model.BreakGlassAccess{},
+		&model.WaitingRoomEvent{},
+		&model.TelehealthVisit{},
+		&model.AppointmentParticipant{},
+		&model.Appointment{},
+		&model.RecurringAppointment{},
+		&model.RecurringAppointmentException{},
+		&model.CareReminder{},
+		&model.CareRule{},
+		&model.AppointmentType{},
+		&model.VisitReason{},
+		&model.AnalyticsBucket{},
+		&model.Availability{},
+		&model.ShiftTemplate{},
+		&model.TimeOff{},
+		&model.CalendarConnection{},
+		&model.CalendarEvent{},
+		&model.CalendarBusy{},
+		&model.Doctor{},
+		&model.Patient{},
+		&model.Consent{},
+		&model.MarketingConsent{},
+		&model.UserCustomRole{},
+		&model.APIKeyUsage{},
+		&model.APIKey{},
+		&model.AuditLog{},
+		&model.SecurityAlert{},
+		&model.VerificationToken{},
+		&model.Session{},
+		&model.User{},
+	}
+	for _, table := range tables {
+		if err := tx.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(table).Error; err != nil {
+			return fmt.Errorf("failed to clear sandbox data: %w", err)
+		}
+	}
+	if err := tx.Where("name = ?", OrganizationName).Delete(&model.Organization{}).Error; err != nil {
+		return fmt.Errorf("failed to clear sandbox clinic: %w", err)
+	}
+	return nil
+}
+
+type generator struct {
+	tx           *gorm.DB
+	rng          *rand.Rand
+	passwordHash string
+	now          time.Time
+}
+
+func (g *generator) name() string {
+	return firstNames[g.rng.Intn(len(firstNames))] + " " + lastNames[g.rng.Intn(len(lastNames))]
+}
+
+// phone returns a number from the 555-01xx range reserved for fictional use
+func (g *generator) phone() string {
+	return fmt.Sprintf("+1202555%04d", 100+g.rng.Intn(100))
+}
+
+func (g *generator) user(name, email string, role model.Role) (*model.User, error) {
+	user := &model.User{
+		Name:          name,
+		Email:         email,
+		EmailVerified: true,
+		PasswordHash:  g.passwordHash,
+		Role:          role,
+		Phone:         g.phone(),
+		PhoneCountry:  "US",
+		Provider:      model.AuthProviderLocal,
+	}
+	if err := g.tx.Create(user).Error; err != nil {
+		return nil, fmt.Errorf("failed to create user %s: %w", email, err)
+	}
+	return user, nil
+}
+
+func (g *generator) doctors(n int, orgID uint) ([]*model.Doctor, error) {
+	doctors := make([]*model.Doctor, 0, n)
+	for i := 1; i <= n; i++ {
+		user, err := g.user("Dr. "+g.name(), fmt.Sprintf("doctor%02d@%s", i, EmailDomain), model.RoleDoctor)
+		if err != nil {
+			return nil, err
+		}
+
+		doctor := &model.Doctor{
+			UserID:         user.ID,
+			OrganizationID: &orgID,
+			Specialty:      specialties[(i-1)%len(specialties)],
+			Designation:    "Consultant",
+			Experience:     2 + g.rng.Intn(25),
+			LicenseNo:      fmt.Sprintf("SANDBOX-%04d", i),
+			Bio:            "Synthetic sandbox doctor.",
+		}
+		if err := g.tx.Create(doctor).Error; err != nil {
+			return nil, fmt.Errorf("failed to create doctor: %w", err)
+		}
+		doctor.User = *user
+
+		for day := 1; day <= 5; day++ {
+			window := &model.Availability{DoctorID: doctor.ID, DayOfWeek: day, StartTime: "09:00:00", EndTime: "17:00:00", Duration: 30}
+			if err := g.tx.Create(window).Error; err != nil {
+				return nil, fmt.Errorf("failed to create availability: %w", err)
+			}
+		}
+		doctors = append(doctors, doctor)
+	}
+	return doctors, nil
+}
+
+func (g *generator) patients(n int) ([]*model.Patient, error) {
+	patients := make([]*model.Patient, 0, n)
+	for i := 1; i <= n; i++ {
+		user, err := g.user(g.name(), fmt.Sprintf("patient%02d@%s", i, EmailDomain), model.RolePatient)
+		if err != nil {
+			return nil, err
+		}
+
+		patient := &model.Patient{
+			UserID:                user.ID,
+			DateOfBirth:           g.now.AddDate(-(18 + g.rng.Intn(70)), -g.rng.Intn(12), -g.rng.Intn(28)).Truncate(24 * time.Hour),
+			Gender:                genders[g.rng.Intn(len(genders))],
+			BloodGroup:            bloodGroups[g.rng.Intn(len(bloodGroups))],
+			EmergencyContact:      g.name(),
+			EmergencyPhone:        g.phone(),
+			EmergencyPhoneCountry: "US",
+			MedicalHistory:        "Synthetic record, no real patient data.",
+		}
+		if err := g.tx.Create(patient).Error; err != nil {
+			return nil, fmt.Errorf("failed to create patient: %w", err)
+		}
+		patients = append(patients, patient)
+	}
+	return patients, nil
+}
+
+// appointments gives each patient one to three appointments on free weekday slots between
+// appointmentDays ago and appointmentDays ahead. Past ones are completed, no-shows or cancelled.
+func (g *generator) appointments(doctors []*model.Doctor, patients []*model.Patient) (int, error) {
+	taken := make(map[string]bool)
+	today := g.now.Truncate(24 * time.Hour)
+	count := 0
+
+	for _, patient := range patients {
+		for n := 1 + g.rng.Intn(3); n > 0; n-- {
+			doctor := doctors[g.rng.Intn(len(doctors))]
+
+			var start time.Time
+			for attempt := 0; attempt < 20; attempt++ {
+				day := today.AddDate(0, 0, g.rng.Intn(2*appointmentDays+1)-appointmentDays)
+				if day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+					continue
+				}
+				candidate := day.Add(9*time.Hour + time.Duration(g.rng.Intn(16))*30*time.Minute)
+				key := fmt.Sprintf("%d/%d", doctor.ID, candidate.Unix())
+				if !taken[key] {
+					taken[key] = true
+					start = candidate
+					break
+				}
+			}
+			if start.IsZero() {
+				continue
+			}
+
+			appointment := &model.Appointment{
+				PatientID:      patient.ID,
+				DoctorID:       doctor.ID,
+				ScheduledStart: start,
+				ScheduledEnd:   start.Add(30 * time.Minute),
+				Status:         g.status(start),
+				Reason:         visitReasons[g.rng.Intn(len(visitReasons))],
+				Modality:       model.ModalityInPerson,
+			}
+			if appointment.Status == model.AppointmentStatusCancelled {
+				cancelledAt := start.Add(-24 * time.Hour)
+				appointment.CancelledAt = &cancelledAt
+			}
+			if err := g.tx.Create(appointment).Error; err != nil {
+				return 0, fmt.Errorf("failed to create appointment: %w", err)
+			}
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (g *generator) status(start time.Time) model.AppointmentStatus {
+	roll := g.rng.Intn(10)
+	if start.Before(g.now) {
+		switch {
+		case roll < 7:
+			return model.AppointmentStatusCompleted
+		case roll < 9:
+			return model.AppointmentStatusNoShow
+		default:
+			return model.AppointmentStatusCancelled
+		}
+	}
+	switch {
+	case roll < 5:
+		return model.AppointmentStatusConfirmed
+	case roll < 9:
+		return model.AppointmentStatusPending
+	default:
+		return model.AppointmentStatusCancelled
+	}
+}
+
+func weekdayHours() []model.BusinessHours {
+	hours := make([]model.BusinessHours, 0, 5)
+	for day := 1; day <= 5; day++ {
+		hours = append(hours, model.BusinessHours{DayOfWeek: day, Open: "09:00", Close: "17:00"})
+	}
+	return hours
+}
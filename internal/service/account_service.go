@@ -0,0 +1,400 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/repository"
+	"github.com/whitewalker-sa/ehass/pkg/pdf"
+	"github.com/whitewalker-sa/ehass/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// AuditActionAccountEntryPosted is recorded when a payment or credit is posted to an account
+const AuditActionAccountEntryPosted = "account_entry.posted"
+
+const maxStatementRangeDays = 731
+
+var (
+	// ErrInvalidAccountEntry is returned when a payment or credit fails validation
+	ErrInvalidAccountEntry = errors.New("invalid account entry")
+	// ErrInvalidStatementPeriod is returned when a statement's period is malformed
+	ErrInvalidStatementPeriod = errors.New("invalid statement period")
+	// ErrNotOwnAccount is returned when a patient asks for another patient's statement
+	ErrNotOwnAccount = errors.New("not allowed to view this patient's account")
+)
+
+// Statement line kinds
+const (
+	StatementLineInvoice = "invoice"
+	StatementLinePayment = "payment"
+	StatementLineCredit  = "credit"
+)
+
+// AccountEntryInput is a payment or credit front-desk staff post to a patient's account.
+// PostedAt defaults to now.
+type AccountEntryInput struct {
+	Kind        model.AccountEntryKind
+	AmountCents int64
+	Currency    string
+	Method      string
+	Reference   string
+	Note        string
+	PostedAt    time.Time
+}
+
+// StatementLine is one invoice, payment or credit on a statement. Amounts are positive for
+// invoices and negative for payments and credits.
+type StatementLine struct {
+	Date         time.Time
+	Kind         string
+	Reference    string // Public ID of the appointment or account entry
+	Description  string
+	AmountCents  int64
+	BalanceCents int64 // Balance after the line
+}
+
+// StatementAccount is the activity of a patient's account in one currency over the period
+type StatementAccount struct {
+	Currency            string
+	OpeningBalanceCents int64
+	InvoicedCents       int64
+	PaidCents           int64
+	CreditedCents       int64
+	ClosingBalanceCents int64 // Positive when the patient owes the clinic
+	Lines               []StatementLine
+}
+
+// Statement is a patient's statement of account over the days From to To, inclusive, in the
+// patient's timezone
+type Statement struct {
+	Patient  *model.Patient
+	From     time.Time
+	To       time.Time
+	Location *time.Location
+	Accounts []*StatementAccount
+}
+
+type accountService struct {
+	repo         repository.AccountRepository
+	patientRepo  repository.PatientRepository
+	orgRepo      repository.OrganizationRepository
+	auditLogRepo repository.AuditLogRepository
+	logger       *zap.Logger
+}
+
+// NewAccountService creates a new patient account service
+func NewAccountService(
+	repo repository.AccountRepository,
+	patientRepo repository.PatientRepository,
+	orgRepo repository.OrganizationRepository,
+	auditLogRepo repository.AuditLogRepository,
+	logger *zap.Logger,
+) AccountService {
+	return &accountService{
+		repo:         repo,
+		patientRepo:  patientRepo,
+		orgRepo:      orgRepo,
+		auditLogRepo: auditLogRepo,
+		logger:       logger,
+	}
+}
+
+// PostEntry posts a payment or credit to a patient's account on behalf of the staff member
+// signed in as userID
+func (s *accountService) PostEntry(ctx context.Context, userID, patientID uint, input AccountEntryInput) (*model.AccountEntry, error) {
+	if _, err := s.patientRepo.FindByID(ctx, patientID); err != nil {
+		return nil, err
+	}
+	if !input.Kind.IsValid() {
+		return nil, fmt.Errorf("%w: kind must be payment or credit", ErrInvalidAccountEntry)
+	}
+	if input.AmountCents <= 0 {
+		return nil, fmt.Errorf("%w: amount must be positive", ErrInvalidAccountEntry)
+	}
+	currency := strings.ToUpper(strings.TrimSpace(input.Currency))
+	if currency == "" {
+		currency = "USD"
+	}
+	if len(currency) != 3 {
+		return nil, fmt.Errorf("%w: invalid currency %q", ErrInvalidAccountEntry, input.Currency)
+	}
+	method := strings.ToLower(strings.TrimSpace(input.Method))
+	reference := strings.TrimSpace(input.Reference)
+	note := strings.TrimSpace(input.Note)
+	switch {
+	case len([]rune(method)) > 30:
+		return nil, fmt.Errorf("%w: method must be at most 30 characters", ErrInvalidAccountEntry)
+	case len([]rune(reference)) > 100:
+		return nil, fmt.Errorf("%w: reference must be at most 100 characters", ErrInvalidAccountEntry)
+	case len([]rune(note)) > 255:
+		return nil, fmt.Errorf("%w: note must be at most 255 characters", ErrInvalidAccountEntry)
+	}
+
+	now := time.Now()
+	postedAt := input.PostedAt
+	if postedAt.IsZero() {
+		postedAt = now
+	}
+	if postedAt.After(now) {
+		return nil, fmt.Errorf("%w: posted_at must not be in the future", ErrInvalidAccountEntry)
+	}
+
+	entry := &model.AccountEntry{
+		PatientID:    patientID,
+		Kind:         input.Kind,
+		AmountCents:  input.AmountCents,
+		Currency:     currency,
+		Method:       method,
+		Reference:    reference,
+		Note:         note,
+		PostedAt:     postedAt,
+		RecordedByID: userID,
+	}
+	if err := s.repo.CreateEntry(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	client := utils.ClientInfoFromContext(ctx)
+	if err := s.auditLogRepo.Create(ctx, &model.AuditLog{
+		UserID:     userID,
+		Action:     AuditActionAccountEntryPosted,
+		EntityID:   entry.ID,
+		EntityType: "account_entry",
+		IP:         client.IP,
+		UserAgent:  client.UserAgent,
+		CreatedAt:  now,
+	}); err != nil {
+		s.logger.Error("Failed to write account entry audit log",
+			zap.Uint("entryID", entry.ID),
+			zap.Error(err))
+	}
+	return entry, nil
+}
+
+// GetStatement builds a patient's statement of account for the dates fromDate to toDate
+// (YYYY-MM-DD, inclusive) in the patient's timezone. Billing staff and admins can view any
+// patient's statement, patients their own and their dependents'.
+func (s *accountService) GetStatement(ctx context.Context, userID uint, role model.Role, billingStaff bool, patientID uint, fromDate, toDate string) (*Statement, error) {
+	patient, err := s.authorize(ctx, userID, role, billingStaff, patientID)
+	if err != nil {
+		return nil, err
+	}
+
+	loc := utils.LoadLocation(patient.User.Timezone)
+	from, to, err := parseDateRange(fromDate, toDate, loc)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidStatementPeriod, err)
+	}
+	if to.After(from.AddDate(0, 0, maxStatementRangeDays)) {
+		return nil, fmt.Errorf("%w: period must not exceed %d days", ErrInvalidStatementPeriod, maxStatementRangeDays)
+	}
+
+	charges, err := s.repo.FindCharges(ctx, patientID, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load charges: %w", err)
+	}
+	entries, err := s.repo.FindEntries(ctx, patientID, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load payments: %w", err)
+	}
+
+	var lines []StatementLine
+	currencies := make(map[string]string)
+	for _, appointment := range charges {
+		appointmentType := appointment.AppointmentType
+		description := appointmentType.Name
+		if appointment.Doctor.User.Name != "" {
+			description += " with " + appointment.Doctor.User.Name
+		}
+		lines = append(lines, StatementLine{
+			Date:        appointment.ScheduledStart,
+			Kind:        StatementLineInvoice,
+			Reference:   appointment.PublicID,
+			Description: description,
+			AmountCents: appointmentType.PriceCents,
+		})
+		currencies[appointment.PublicID] = appointmentType.Currency
+	}
+	for _, entry := range entries {
+		kind := StatementLinePayment
+		description := "Payment"
+		if entry.Kind == model.AccountEntryCredit {
+			kind = StatementLineCredit
+			description = "Credit"
+		}
+		if entry.Method != "" {
+			description += " by " + entry.Method
+		}
+		if entry.Reference != "" {
+			description += ", ref. " + entry.Reference
+		}
+		if entry.Note != "" {
+			description += ": " + entry.Note
+		}
+		lines = append(lines, StatementLine{
+			Date:        entry.PostedAt,
+			Kind:        kind,
+			Reference:   entry.PublicID,
+			Description: description,
+			AmountCents: -entry.AmountCents,
+		})
+		currencies[entry.PublicID] = entry.Currency
+	}
+	sort.SliceStable(lines, func(i, j int) bool { return lines[i].Date.Before(lines[j].Date) })
+
+	statement := &Statement{Patient: patient, From: from, To: to.AddDate(0, 0, -1), Location: loc}
+	accounts := make(map[string]*StatementAccount)
+	for _, line := range lines {
+		currency := currencies[line.Reference]
+		account, ok := accounts[currency]
+		if !ok {
+			account = &StatementAccount{Currency: currency, Lines: []StatementLine{}}
+			accounts[currency] = account
+			statement.Accounts = append(statement.Accounts, account)
+		}
+		account.ClosingBalanceCents += line.AmountCents
+		if line.Date.Before(from) {
+			account.OpeningBalanceCents = account.ClosingBalanceCents
+			continue
+		}
+		switch line.Kind {
+		case StatementLineInvoice:
+			account.InvoicedCents += line.AmountCents
+		case StatementLinePayment:
+			account.PaidCents -= line.AmountCents
+		case StatementLineCredit:
+			account.CreditedCents -= line.AmountCents
+		}
+		line.Date = line.Date.In(loc)
+		line.BalanceCents = account.ClosingBalanceCents
+		account.Lines = append(account.Lines, line)
+	}
+	sort.Slice(statement.Accounts, func(i, j int) bool { return statement.Accounts[i].Currency < statement.Accounts[j].Currency })
+	return statement, nil
+}
+
+// RenderStatement renders a patient's statement of account as a printable PDF, with the same
+// access rules as GetStatement
+func (s *accountService) RenderStatement(ctx context.Context, userID uint, role model.Role, billingStaff bool, patientID uint, fromDate, toDate string) ([]byte, error) {
+	statement, err := s.GetStatement(ctx, userID, role, billingStaff, patientID, fromDate, toDate)
+	if err != nil {
+		return nil, err
+	}
+	org, err := s.orgRepo.FindDefault(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return statementDocument(statement, org), nil
+}
+
+// authorize checks that the user signed in as userID may view the patient's account and returns
+// the patient
+func (s *accountService) authorize(ctx context.Context, userID uint, role model.Role, billingStaff bool, patientID uint) (*model.Patient, error) {
+	patient, err := s.patientRepo.FindByID(ctx, patientID)
+	if err != nil {
+		return nil, err
+	}
+	if role == model.RoleAdmin || billingStaff {
+		return patient, nil
+	}
+	if role != model.RolePatient {
+		return nil, ErrNotOwnAccount
+	}
+	caller, err := s.patientRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, ErrNotOwnAccount
+	}
+	if caller.ID != patient.ID && (patient.GuardianID == nil || *patient.GuardianID != caller.ID) {
+		return nil, ErrNotOwnAccount
+	}
+	return patient, nil
+}
+
+// statementDocument renders a statement of account for the patient to keep or pay from
+func statementDocument(statement *Statement, org *model.Organization) []byte {
+	patient := statement.Patient
+	loc := statement.Location
+
+	doc := pdf.New("Statement of account")
+	doc.SetHeadingColor(org.PrimaryColor)
+	doc.Heading(org.DisplayName())
+	var contact []string
+	for _, line := range []string{org.Address, org.ContactPhone, org.ContactEmail} {
+		if line != "" {
+			contact = append(contact, line)
+		}
+	}
+	if len(contact) > 0 {
+		doc.Text(strings.Join(contact, " | "))
+	}
+	if org.PrimaryColor != "" {
+		doc.Rule()
+	}
+	doc.Spacer(16)
+
+	doc.Heading("Statement of account")
+	doc.Table([]pdf.Column{
+		{Title: "Patient", Width: 0.25},
+		{Title: "", Width: 0.75},
+	}, [][]string{
+		{"Name", patient.User.Name},
+		{"Date of birth", patient.DateOfBirth.Format("2 January 2006")},
+		{"Period", statement.From.Format("2 January 2006") + " to " + statement.To.Format("2 January 2006")},
+	})
+
+	if len(statement.Accounts) == 0 {
+		doc.Spacer(12)
+		doc.Text("No charges or payments on this account.")
+	}
+	for _, account := range statement.Accounts {
+		doc.Spacer(12)
+		if len(statement.Accounts) > 1 {
+			doc.Heading("Amounts in " + account.Currency)
+		}
+		rows := [][]string{{"", "Opening balance", "", formatCents(account.OpeningBalanceCents, account.Currency)}}
+		for _, line := range account.Lines {
+			rows = append(rows, []string{
+				line.Date.Format("2 Jan 2006"),
+				line.Description,
+				formatCents(line.AmountCents, account.Currency),
+				formatCents(line.BalanceCents, account.Currency),
+			})
+		}
+		doc.Table([]pdf.Column{
+			{Title: "Date", Width: 0.15},
+			{Title: "Description", Width: 0.49},
+			{Title: "Amount", Width: 0.18},
+			{Title: "Balance", Width: 0.18},
+		}, rows)
+		doc.Spacer(8)
+		doc.Table([]pdf.Column{
+			{Title: "Summary", Width: 0.64},
+			{Title: "", Width: 0.36},
+		}, [][]string{
+			{"Invoiced", formatCents(account.InvoicedCents, account.Currency)},
+			{"Paid", formatCents(-account.PaidCents, account.Currency)},
+			{"Credited", formatCents(-account.CreditedCents, account.Currency)},
+			{"Balance due", formatCents(account.ClosingBalanceCents, account.Currency)},
+		})
+	}
+
+	doc.Spacer(12)
+	doc.Text("Printed " + time.Now().In(loc).Format("2006-01-02 15:04 MST") + ".")
+	return doc.Bytes()
+}
+
+// formatCents formats an amount in cents with its currency, e.g. -12.50 USD
+func formatCents(cents int64, currency string) string {
+	sign := ""
+	if cents < 0 {
+		sign = "-"
+		cents = -cents
+	}
+	return fmt.Sprintf("%s%d.%02d %s", sign, cents/100, cents%100, currency)
+}
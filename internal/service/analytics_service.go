@@ -0,0 +1,413 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/repository"
+	"github.com/whitewalker-sa/ehass/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// maxAnalyticsBuckets limits the length of a series
+const maxAnalyticsBuckets = 400
+
+// minVisitsForSlotSuggestion is how many visits of an appointment type a slot length is suggested
+// from, and slotSuggestionStep what suggestions are rounded up to
+const (
+	minVisitsForSlotSuggestion = 10
+	slotSuggestionStep         = 5 * time.Minute
+)
+
+// ClinicAnalytics is a clinic's metrics bucketed over time
+type ClinicAnalytics struct {
+	OrganizationID uint
+	Granularity    model.AnalyticsGranularity
+	Timezone       string // Timezone bucket boundaries are aligned to
+	Buckets        []*model.AnalyticsBucket
+}
+
+// VisitDurationReport compares how long a clinic's visits were booked for with how long they
+// took, and how promptly its doctors started them
+type VisitDurationReport struct {
+	OrganizationID uint
+	From           time.Time
+	To             time.Time
+	Timezone       string
+	Visits         int
+	Types          []*AppointmentTypeDurations // Most visited first
+	Doctors        []*DoctorPunctuality
+}
+
+// AppointmentTypeDurations are the durations of the visits of one appointment type, or of visits
+// booked without one
+type AppointmentTypeDurations struct {
+	AppointmentTypeID *uint
+	Name              string
+	Visits            int
+	ScheduledDuration time.Duration // Average booked slot
+	ActualDuration    time.Duration // Average time from start to end
+	P80Duration       time.Duration // 80% of visits took at most this long
+	Overran           int           // Visits that took longer than booked
+	SuggestedDuration time.Duration // Slot length covering P80Duration; zero with too few visits to tell
+}
+
+// DoctorPunctuality is how promptly a doctor started visits, counted from the scheduled start or
+// from when the patient arrived, whichever was later
+type DoctorPunctuality struct {
+	DoctorID     string // Public ID
+	Name         string
+	Visits       int
+	AverageDelay time.Duration
+	P80Delay     time.Duration
+	Late         int // Visits started later than the punctuality grace period
+	FinishedLate int // Visits that ended after the end of their slot
+}
+
+type analyticsService struct {
+	analyticsRepo    repository.AnalyticsRepository
+	orgRepo          repository.OrganizationRepository
+	settlePeriod     time.Duration
+	punctualityGrace time.Duration
+	logger           *zap.Logger
+}
+
+// NewAnalyticsService creates a new analytics service. Buckets that ended more than
+// settlePeriod ago are stored and served from the database instead of being recomputed. Visits
+// started more than punctualityGrace late count as late.
+func NewAnalyticsService(
+	analyticsRepo repository.AnalyticsRepository,
+	orgRepo repository.OrganizationRepository,
+	settlePeriod time.Duration,
+	punctualityGrace time.Duration,
+	logger *zap.Logger,
+) AnalyticsService {
+	return &analyticsService{
+		analyticsRepo:    analyticsRepo,
+		orgRepo:          orgRepo,
+		settlePeriod:     settlePeriod,
+		punctualityGrace: punctualityGrace,
+		logger:           logger,
+	}
+}
+
+// GetClinicAnalytics returns bookings, cancellations, new patients and revenue of a clinic for
+// each bucket overlapping the dates fromDate to toDate (YYYY-MM-DD, inclusive), aligned to the
+// clinic's timezone. With refresh, stored buckets are recomputed, e.g. after doctors moved
+// between clinics.
+func (s *analyticsService) GetClinicAnalytics(ctx context.Context, orgID uint, granularity model.AnalyticsGranularity, fromDate, toDate string, refresh bool) (*ClinicAnalytics, error) {
+	if !granularity.IsValid() {
+		return nil, errors.New("granularity must be day, week or month")
+	}
+
+	org, err := s.orgRepo.FindByID(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	loc := utils.LoadLocation(org.Timezone)
+
+	from, to, err := parseDateRange(fromDate, toDate, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	var starts []time.Time
+	for start := truncateToBucket(from, granularity); start.Before(to); start = nextBucket(start, granularity) {
+		if len(starts) == maxAnalyticsBuckets {
+			return nil, fmt.Errorf("range spans more than %d buckets, use a coarser granularity", maxAnalyticsBuckets)
+		}
+		starts = append(starts, start)
+	}
+	rangeEnd := nextBucket(starts[len(starts)-1], granularity)
+
+	// Serve settled buckets from storage
+	stored := make(map[int64]*model.AnalyticsBucket)
+	if !refresh {
+		buckets, err := s.analyticsRepo.FindBuckets(ctx, org.ID, granularity, starts[0], rangeEnd)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load stored analytics: %w", err)
+		}
+		for _, bucket := range buckets {
+			stored[bucket.BucketStart.Unix()] = bucket
+		}
+	}
+
+	var missing []time.Time
+	for _, start := range starts {
+		if _, ok := stored[start.Unix()]; !ok {
+			missing = append(missing, start)
+		}
+	}
+
+	if len(missing) > 0 {
+		computed, err := s.computeBuckets(ctx, org, granularity, missing)
+		if err != nil {
+			return nil, err
+		}
+
+		settledBefore := time.Now().Add(-s.settlePeriod)
+		var settled []*model.AnalyticsBucket
+		for _, bucket := range computed {
+			stored[bucket.BucketStart.Unix()] = bucket
+			if !bucket.BucketEnd.After(settledBefore) {
+				settled = append(settled, bucket)
+			}
+		}
+		if err := s.analyticsRepo.SaveBuckets(ctx, settled); err != nil {
+			// Serving the series matters more than storing it
+			s.logger.Warn("Failed to store analytics buckets", zap.Uint("organizationID", org.ID), zap.Error(err))
+		}
+	}
+
+	result := &ClinicAnalytics{
+		OrganizationID: org.ID,
+		Granularity:    granularity,
+		Timezone:       loc.String(),
+		Buckets:        make([]*model.AnalyticsBucket, 0, len(starts)),
+	}
+	for _, start := range starts {
+		result.Buckets = append(result.Buckets, stored[start.Unix()])
+	}
+	return result, nil
+}
+
+// GetVisitDurations reports how long a clinic's visits scheduled on the dates fromDate to toDate
+// (YYYY-MM-DD, inclusive, in the clinic's timezone) were booked for against how long they took,
+// by appointment type, and how promptly each doctor started them. Only visits with a recorded
+// start and end are counted.
+func (s *analyticsService) GetVisitDurations(ctx context.Context, orgID uint, fromDate, toDate string) (*VisitDurationReport, error) {
+	org, err := s.orgRepo.FindByID(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	loc := utils.LoadLocation(org.Timezone)
+	from, to, err := parseDateRange(fromDate, toDate, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	timings, err := s.analyticsRepo.FindVisitTimings(ctx, s.clinicScope(ctx, org), from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load visit timings: %w", err)
+	}
+
+	report := &VisitDurationReport{
+		OrganizationID: org.ID,
+		From:           from,
+		To:             to,
+		Timezone:       loc.String(),
+		Visits:         len(timings),
+		Types:          []*AppointmentTypeDurations{},
+		Doctors:        []*DoctorPunctuality{},
+	}
+
+	types := make(map[string]*AppointmentTypeDurations)
+	typeDurations := make(map[string][]time.Duration)
+	doctors := make(map[string]*DoctorPunctuality)
+	doctorDelays := make(map[string][]time.Duration)
+	for _, timing := range timings {
+		key := "untyped"
+		if timing.AppointmentTypeID != nil {
+			key = fmt.Sprint(*timing.AppointmentTypeID)
+		}
+		entry, ok := types[key]
+		if !ok {
+			entry = &AppointmentTypeDurations{AppointmentTypeID: timing.AppointmentTypeID, Name: timing.AppointmentType}
+			types[key] = entry
+			report.Types = append(report.Types, entry)
+		}
+		scheduled := timing.ScheduledEnd.Sub(timing.ScheduledStart)
+		actual := timing.EndedAt.Sub(timing.StartedAt)
+		entry.Visits++
+		entry.ScheduledDuration += scheduled
+		entry.ActualDuration += actual
+		if actual > scheduled {
+			entry.Overran++
+		}
+		typeDurations[key] = append(typeDurations[key], actual)
+
+		doctor, ok := doctors[timing.DoctorID]
+		if !ok {
+			doctor = &DoctorPunctuality{DoctorID: timing.DoctorID, Name: timing.DoctorName}
+			doctors[timing.DoctorID] = doctor
+			report.Doctors = append(report.Doctors, doctor)
+		}
+		// A patient who arrived late cannot be seen on time, so the delay counts from arrival
+		due := timing.ScheduledStart
+		if timing.CheckedInAt != nil && timing.CheckedInAt.After(due) {
+			due = *timing.CheckedInAt
+		}
+		delay := timing.StartedAt.Sub(due)
+		if delay < 0 {
+			delay = 0
+		}
+		doctor.Visits++
+		doctor.AverageDelay += delay
+		if delay > s.punctualityGrace {
+			doctor.Late++
+		}
+		if timing.EndedAt.After(timing.ScheduledEnd) {
+			doctor.FinishedLate++
+		}
+		doctorDelays[timing.DoctorID] = append(doctorDelays[timing.DoctorID], delay)
+	}
+
+	for key, entry := range types {
+		entry.ScheduledDuration /= time.Duration(entry.Visits)
+		entry.ActualDuration /= time.Duration(entry.Visits)
+		entry.P80Duration = percentile(typeDurations[key], 0.8)
+		if entry.Visits >= minVisitsForSlotSuggestion {
+			entry.SuggestedDuration = (entry.P80Duration + slotSuggestionStep - 1).Truncate(slotSuggestionStep)
+		}
+	}
+	for id, doctor := range doctors {
+		doctor.AverageDelay /= time.Duration(doctor.Visits)
+		doctor.P80Delay = percentile(doctorDelays[id], 0.8)
+	}
+	sort.SliceStable(report.Types, func(i, j int) bool { return report.Types[i].Visits > report.Types[j].Visits })
+	sort.SliceStable(report.Doctors, func(i, j int) bool { return report.Doctors[i].Name < report.Doctors[j].Name })
+	return report, nil
+}
+
+// clinicScope selects the appointments of a clinic's doctors; the default clinic also has the
+// doctors not assigned to any clinic
+func (s *analyticsService) clinicScope(ctx context.Context, org *model.Organization) repository.AnalyticsScope {
+	scope := repository.AnalyticsScope{OrganizationID: org.ID}
+	if defaultOrg, err := s.orgRepo.FindDefault(ctx); err == nil && defaultOrg.ID == org.ID {
+		scope.IncludeUnassigned = true
+	}
+	return scope
+}
+
+// computeBuckets computes the buckets starting at starts, which must be sorted
+func (s *analyticsService) computeBuckets(ctx context.Context, org *model.Organization, granularity model.AnalyticsGranularity, starts []time.Time) ([]*model.AnalyticsBucket, error) {
+	scope := s.clinicScope(ctx, org)
+
+	now := time.Now()
+	buckets := make([]*model.AnalyticsBucket, len(starts))
+	for i, start := range starts {
+		buckets[i] = &model.AnalyticsBucket{
+			OrganizationID: org.ID,
+			Granularity:    granularity,
+			BucketStart:    start,
+			BucketEnd:      nextBucket(start, granularity),
+			Revenue:        map[string]int64{},
+			CreatedAt:      now,
+		}
+	}
+	start, end := buckets[0].BucketStart, buckets[len(buckets)-1].BucketEnd
+
+	// find returns the bucket containing t, or nil if t falls in a bucket that is not being computed
+	find := func(t time.Time) *model.AnalyticsBucket {
+		i := sort.Search(len(buckets), func(i int) bool { return buckets[i].BucketStart.After(t) }) - 1
+		if i < 0 || !t.Before(buckets[i].BucketEnd) {
+			return nil
+		}
+		return buckets[i]
+	}
+
+	bookings, err := s.analyticsRepo.FindBookingTimes(ctx, scope, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count bookings: %w", err)
+	}
+	for _, t := range bookings {
+		if bucket := find(t); bucket != nil {
+			bucket.Bookings++
+		}
+	}
+
+	cancellations, err := s.analyticsRepo.FindCancellationTimes(ctx, scope, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count cancellations: %w", err)
+	}
+	for _, t := range cancellations {
+		if bucket := find(t); bucket != nil {
+			bucket.Cancellations++
+		}
+	}
+
+	newPatients, err := s.analyticsRepo.FindFirstBookingTimes(ctx, scope, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count new patients: %w", err)
+	}
+	for _, t := range newPatients {
+		if bucket := find(t); bucket != nil {
+			bucket.NewPatients++
+		}
+	}
+
+	revenue, err := s.analyticsRepo.FindRevenue(ctx, scope, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum revenue: %w", err)
+	}
+	for _, event := range revenue {
+		if bucket := find(event.At); bucket != nil {
+			bucket.Revenue[event.Currency] += event.PriceCents
+		}
+	}
+
+	return buckets, nil
+}
+
+// parseDateRange parses the dates fromDate to toDate (YYYY-MM-DD, inclusive) in loc into the
+// range [from, to)
+func parseDateRange(fromDate, toDate string, loc *time.Location) (time.Time, time.Time, error) {
+	from, err := time.ParseInLocation("2006-01-02", fromDate, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.New("invalid from date, expected YYYY-MM-DD")
+	}
+	lastDay, err := time.ParseInLocation("2006-01-02", toDate, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.New("invalid to date, expected YYYY-MM-DD")
+	}
+	to := lastDay.AddDate(0, 0, 1)
+	if !from.Before(to) {
+		return time.Time{}, time.Time{}, errors.New("from must not be after to")
+	}
+	return from, to, nil
+}
+
+// percentile returns the smallest duration at least the fraction p of durations do not exceed.
+// It sorts durations in place.
+func percentile(durations []time.Duration, p float64) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	i := int(math.Ceil(p*float64(len(durations)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	return durations[i]
+}
+
+// truncateToBucket returns the start of the bucket containing t, in t's location.
+// Weeks start on Monday.
+func truncateToBucket(t time.Time, granularity model.AnalyticsGranularity) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	switch granularity {
+	case model.GranularityWeek:
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case model.GranularityMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	default:
+		return day
+	}
+}
+
+// nextBucket returns the start of the bucket following the one starting at start
+func nextBucket(start time.Time, granularity model.AnalyticsGranularity) time.Time {
+	switch granularity {
+	case model.GranularityWeek:
+		return start.AddDate(0, 0, 7)
+	case model.GranularityMonth:
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
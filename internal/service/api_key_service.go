@@ -0,0 +1,365 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/repository"
+	"github.com/whitewalker-sa/ehass/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// Audit actions for partner API keys
+const (
+	AuditActionAPIKeyIssued  = "api_key.issued"
+	AuditActionAPIKeyUpdated = "api_key.updated"
+	AuditActionAPIKeyRevoked = "api_key.revoked"
+)
+
+const (
+	// apiKeyPrefix starts every API key, so leaked keys are easy to recognize
+	apiKeyPrefix = "ehk_"
+	// maxKeysPerUser is the most active API keys one user can hold
+	maxKeysPerUser = 10
+	// defaultUsageDays is the period usage is reported over when none is given
+	defaultUsageDays = 30
+	// maxUsageRangeDays is the longest period usage can be reported over
+	maxUsageRangeDays = 92
+)
+
+var (
+	// ErrInvalidAPIKey is returned when an API key or its limits fail validation
+	ErrInvalidAPIKey = errors.New("invalid API key")
+	// ErrAPIKeySuspended is returned when a request is made with a suspended API key
+	ErrAPIKeySuspended = errors.New("API key is suspended")
+	// ErrQuotaExceeded is returned when an API key has used up its daily quota
+	ErrQuotaExceeded = errors.New("daily API quota exceeded")
+	// ErrInvalidUsagePeriod is returned when a usage report's period is malformed
+	ErrInvalidUsagePeriod = errors.New("invalid usage period")
+)
+
+// APIKeyInput is an API key to issue. Limits of 0 use the configured defaults.
+type APIKeyInput struct {
+	UserID            uint
+	Name              string
+	RequestsPerMinute int
+	DailyQuota        int
+}
+
+// APIKeyLimits are the limits an admin sets on an API key, e.g. to throttle an abusive
+// integration. Limits of 0 use the configured defaults.
+type APIKeyLimits struct {
+	RequestsPerMinute int
+	DailyQuota        int
+	Suspended         bool
+	SuspendedReason   string
+}
+
+// APIUsageReport is the usage of API keys over a period of UTC days
+type APIUsageReport struct {
+	From time.Time // First day
+	To   time.Time // Last day, included
+	Keys []APIKeyUsageReport
+}
+
+// APIKeyUsageReport is one API key's limits and usage
+type APIKeyUsageReport struct {
+	Key               *model.APIKey
+	RequestsPerMinute int   // Limit in force, defaults applied
+	DailyQuota        int   // Limit in force, defaults applied
+	Today             int64 // Requests served today
+	QuotaRemaining    int64 // Requests left today
+	Requests          int64 // Requests over the period, refused ones included
+	Rejected          int64 // Requests refused over the period
+	Days              []*model.APIKeyUsage
+}
+
+type apiKeyService struct {
+	repo                     repository.APIKeyRepository
+	userRepo                 repository.UserRepository
+	auditLogRepo             repository.AuditLogRepository
+	defaultRequestsPerMinute int
+	defaultDailyQuota        int
+	logger                   *zap.Logger
+}
+
+// NewAPIKeyService creates a new API key service. Keys without limits of their own get
+// requestsPerMinute and dailyQuota.
+func NewAPIKeyService(
+	repo repository.APIKeyRepository,
+	userRepo repository.UserRepository,
+	auditLogRepo repository.AuditLogRepository,
+	requestsPerMinute, dailyQuota int,
+	logger *zap.Logger,
+) APIKeyService {
+	return &apiKeyService{
+		repo:                     repo,
+		userRepo:                 userRepo,
+		auditLogRepo:             auditLogRepo,
+		defaultRequestsPerMinute: requestsPerMinute,
+		defaultDailyQuota:        dailyQuota,
+		logger:                   logger,
+	}
+}
+
+// IssueKey issues an API key to a user on behalf of adminID. It returns the key with its secret,
+// which is not stored and cannot be shown again.
+func (s *apiKeyService) IssueKey(ctx context.Context, adminID uint, input APIKeyInput) (*model.APIKey, string, error) {
+	name := strings.TrimSpace(input.Name)
+	switch {
+	case name == "":
+		return nil, "", fmt.Errorf("%w: name is required", ErrInvalidAPIKey)
+	case len([]rune(name)) > 100:
+		return nil, "", fmt.Errorf("%w: name must be at most 100 characters", ErrInvalidAPIKey)
+	}
+	if err := checkAPIKeyLimits(input.RequestsPerMinute, input.DailyQuota); err != nil {
+		return nil, "", err
+	}
+
+	user, err := s.userRepo.FindByID(ctx, input.UserID)
+	if err != nil {
+		return nil, "", err
+	}
+	existing, err := s.repo.FindActive(ctx, user.ID)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(existing) >= maxKeysPerUser {
+		return nil, "", fmt.Errorf("%w: a user can hold at most %d API keys", ErrInvalidAPIKey, maxKeysPerUser)
+	}
+
+	secret := apiKeyPrefix + utils.GenerateRandomToken(32)
+	key := &model.APIKey{
+		UserID:            user.ID,
+		User:              *user,
+		Name:              name,
+		Prefix:            secret[:len(apiKeyPrefix)+8],
+		KeyHash:           utils.HashToken(secret),
+		RequestsPerMinute: input.RequestsPerMinute,
+		DailyQuota:        input.DailyQuota,
+		CreatedByID:       adminID,
+	}
+	if err := s.repo.Create(ctx, key); err != nil {
+		return nil, "", fmt.Errorf("failed to issue API key: %w", err)
+	}
+	s.audit(ctx, adminID, AuditActionAPIKeyIssued, key.ID)
+	return key, secret, nil
+}
+
+// UpdateKey sets the limits of an API key, or suspends it, on behalf of adminID
+func (s *apiKeyService) UpdateKey(ctx context.Context, adminID, id uint, limits APIKeyLimits) (*model.APIKey, error) {
+	if err := checkAPIKeyLimits(limits.RequestsPerMinute, limits.DailyQuota); err != nil {
+		return nil, err
+	}
+	reason := strings.TrimSpace(limits.SuspendedReason)
+	if len([]rune(reason)) > 255 {
+		return nil, fmt.Errorf("%w: suspended reason must be at most 255 characters", ErrInvalidAPIKey)
+	}
+	key, err := s.activeKey(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	key.RequestsPerMinute = limits.RequestsPerMinute
+	key.DailyQuota = limits.DailyQuota
+	key.Suspended = limits.Suspended
+	key.SuspendedReason = ""
+	if limits.Suspended {
+		key.SuspendedReason = reason
+	}
+	if err := s.repo.Update(ctx, key); err != nil {
+		return nil, fmt.Errorf("failed to update API key: %w", err)
+	}
+	s.audit(ctx, adminID, AuditActionAPIKeyUpdated, key.ID)
+	return key, nil
+}
+
+// RevokeKey revokes an API key for good on behalf of adminID
+func (s *apiKeyService) RevokeKey(ctx context.Context, adminID, id uint) error {
+	key, err := s.activeKey(ctx, id)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	key.RevokedAt = &now
+	if err := s.repo.Update(ctx, key); err != nil {
+		return fmt.Errorf("failed to revoke API key: %w", err)
+	}
+	s.audit(ctx, adminID, AuditActionAPIKeyRevoked, key.ID)
+	return nil
+}
+
+// Authenticate finds the API key a request was made with, with the user it acts as
+func (s *apiKeyService) Authenticate(ctx context.Context, secret string) (*model.APIKey, error) {
+	if !strings.HasPrefix(secret, apiKeyPrefix) {
+		return nil, errors.New("API key not found")
+	}
+	key, err := s.repo.FindByHash(ctx, utils.HashToken(secret))
+	if err != nil {
+		return nil, err
+	}
+	if key.Suspended {
+		return nil, ErrAPIKeySuspended
+	}
+	return key, nil
+}
+
+// Limits returns the requests per minute and per day an API key is allowed, defaults applied. A
+// limit of 0 or less does not limit.
+func (s *apiKeyService) Limits(key *model.APIKey) (int, int) {
+	perMinute, daily := key.RequestsPerMinute, key.DailyQuota
+	if perMinute == 0 {
+		perMinute = s.defaultRequestsPerMinute
+	}
+	if daily == 0 {
+		daily = s.defaultDailyQuota
+	}
+	return perMinute, daily
+}
+
+// Meter counts a request made with an API key at now against its daily quota, and returns how
+// many requests are left for the day. Refused requests do not use up the quota. Requests over
+// the quota are counted as refused and return ErrQuotaExceeded.
+func (s *apiKeyService) Meter(ctx context.Context, key *model.APIKey, now time.Time) (int64, error) {
+	day := usageDay(now)
+	served, err := s.repo.CountRequest(ctx, key.ID, day)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count API request: %w", err)
+	}
+	_, quota := s.Limits(key)
+	if quota <= 0 {
+		return 0, nil
+	}
+	if served > int64(quota) {
+		if err := s.repo.CountRejected(ctx, key.ID, day, true); err != nil {
+			return 0, fmt.Errorf("failed to count refused API request: %w", err)
+		}
+		return 0, ErrQuotaExceeded
+	}
+	return int64(quota) - served, nil
+}
+
+// RecordRejected counts a request made with an API key at now that was refused for its rate
+// limit
+func (s *apiKeyService) RecordRejected(ctx context.Context, key *model.APIKey, now time.Time) error {
+	return s.repo.CountRejected(ctx, key.ID, usageDay(now), false)
+}
+
+// Usage reports the usage of the API keys of userID over a period of UTC days, or of every key
+// when allKeys is set. The period defaults to the last 30 days.
+func (s *apiKeyService) Usage(ctx context.Context, userID uint, allKeys bool, fromDate, toDate string) (*APIUsageReport, error) {
+	today := usageDay(time.Now())
+	from, to := today.AddDate(0, 0, 1-defaultUsageDays), today
+	if fromDate != "" || toDate != "" {
+		start, end, err := parseDateRange(fromDate, toDate, time.UTC)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidUsagePeriod, err)
+		}
+		if end.After(start.AddDate(0, 0, maxUsageRangeDays)) {
+			return nil, fmt.Errorf("%w: the period can be at most %d days", ErrInvalidUsagePeriod, maxUsageRangeDays)
+		}
+		from, to = start, end.AddDate(0, 0, -1)
+	}
+
+	owner := userID
+	if allKeys {
+		owner = 0
+	}
+	keys, err := s.repo.FindActive(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uint, 0, len(keys))
+	for _, key := range keys {
+		ids = append(ids, key.ID)
+	}
+	first := from
+	if today.Before(first) {
+		first = today
+	}
+	last := to
+	if today.After(last) {
+		last = today
+	}
+	usage, err := s.repo.FindUsage(ctx, ids, first, last)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &APIUsageReport{From: from, To: to, Keys: make([]APIKeyUsageReport, 0, len(keys))}
+	for _, key := range keys {
+		perMinute, quota := s.Limits(key)
+		entry := APIKeyUsageReport{Key: key, RequestsPerMinute: perMinute, DailyQuota: quota}
+		for _, day := range usage {
+			if day.APIKeyID != key.ID {
+				continue
+			}
+			if day.Day.Equal(today) {
+				entry.Today = day.Requests - day.Rejected
+			}
+			if day.Day.Before(from) || day.Day.After(to) {
+				continue
+			}
+			entry.Requests += day.Requests
+			entry.Rejected += day.Rejected
+			entry.Days = append(entry.Days, day)
+		}
+		if quota > 0 && entry.Today < int64(quota) {
+			entry.QuotaRemaining = int64(quota) - entry.Today
+		}
+		report.Keys = append(report.Keys, entry)
+	}
+	return report, nil
+}
+
+// activeKey finds an API key that has not been revoked
+func (s *apiKeyService) activeKey(ctx context.Context, id uint) (*model.APIKey, error) {
+	key, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if key.RevokedAt != nil {
+		return nil, errors.New("API key not found")
+	}
+	return key, nil
+}
+
+// audit records a change to an API key
+func (s *apiKeyService) audit(ctx context.Context, adminID uint, action string, id uint) {
+	client := utils.ClientInfoFromContext(ctx)
+	if err := s.auditLogRepo.Create(ctx, &model.AuditLog{
+		UserID:     adminID,
+		Action:     action,
+		EntityID:   id,
+		EntityType: "api_key",
+		IP:         client.IP,
+		UserAgent:  client.UserAgent,
+		CreatedAt:  time.Now(),
+	}); err != nil {
+		s.logger.Error("Failed to write API key audit log",
+			zap.String("action", action),
+			zap.Uint("entityID", id),
+			zap.Error(err))
+	}
+}
+
+// checkAPIKeyLimits validates the limits set on an API key
+func checkAPIKeyLimits(requestsPerMinute, dailyQuota int) error {
+	if requestsPerMinute < 0 || requestsPerMinute > 100000 {
+		return fmt.Errorf("%w: requests per minute must be between 0 and 100000", ErrInvalidAPIKey)
+	}
+	if dailyQuota < 0 || dailyQuota > 100000000 {
+		return fmt.Errorf("%w: daily quota must be between 0 and 100000000", ErrInvalidAPIKey)
+	}
+	return nil
+}
+
+// usageDay returns the UTC day usage at t is counted on
+func usageDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -4,18 +4,103 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/whitewalker-sa/ehass/internal/model"
 	"github.com/whitewalker-sa/ehass/internal/repository"
+	"github.com/whitewalker-sa/ehass/pkg/pdf"
+	"github.com/whitewalker-sa/ehass/pkg/utils"
 	"go.uber.org/zap"
 )
 
+var (
+	// ErrScheduleConflict is returned when the doctor or patient is already booked at the
+	// requested time. Bookings are checked in the transaction that saves them.
+	ErrScheduleConflict = repository.ErrScheduleConflict
+	// ErrOutsideAvailability is returned when a booking falls outside the doctor's availability
+	ErrOutsideAvailability = errors.New("appointment time is outside the doctor's availability")
+	// ErrDoctorOnLeave is returned when a booking overlaps time the doctor has taken off
+	ErrDoctorOnLeave = errors.New("doctor is on time off at the requested time")
+	// ErrDoctorBusy is returned when a booking overlaps an event in the doctor's synced calendar
+	ErrDoctorBusy = errors.New("doctor is busy at the requested time")
+	// ErrChecklistIncomplete is returned when confirming an appointment whose intake checklist
+	// still has open items
+	ErrChecklistIncomplete = errors.New("appointment intake checklist is incomplete")
+	// ErrRescheduleLimit is returned when an appointment has been rescheduled as many times as
+	// its clinic allows
+	ErrRescheduleLimit = errors.New("appointment has reached its reschedule limit")
+	// ErrInvalidFilter is returned when an appointment list filter cannot be applied
+	ErrInvalidFilter = errors.New("invalid appointment filter")
+	// ErrNotSameFamily is returned when a group booking includes a patient outside the booking
+	// patient's family
+	ErrNotSameFamily = errors.New("all patients in a group booking must belong to the same family")
+	// ErrNotOwnBooking is returned when a patient books for someone other than themselves or
+	// their dependants
+	ErrNotOwnBooking = errors.New("patients can only book appointments for themselves or their dependants")
+	// ErrInvalidParticipants is returned when a group booking lists a patient twice or too many
+	// patients
+	ErrInvalidParticipants = errors.New("invalid group booking participants")
+	// ErrCheckInNotToday is returned when checking in a patient for an appointment on another day
+	// than the clinic's today
+	ErrCheckInNotToday = errors.New("patients can only be checked in on the day of their appointment")
+	// ErrNotOwnAppointment is returned when a doctor confirms or declines another doctor's booking
+	ErrNotOwnAppointment = errors.New("doctors can only confirm or decline their own appointments")
+	// ErrNotPatientAppointment is returned when a patient confirms attendance of someone else's
+	// appointment
+	ErrNotPatientAppointment = errors.New("patients can only confirm attendance of their own appointments")
+	// ErrNotUpcoming is returned when confirming attendance of an appointment that has started or
+	// is no longer pending or confirmed
+	ErrNotUpcoming = errors.New("attendance can only be confirmed for an upcoming pending or confirmed appointment")
+	// ErrInvalidTags is returned when appointment tags are too long or too many
+	ErrInvalidTags = errors.New("invalid appointment tags")
+	// ErrInvalidMetadata is returned when appointment metadata uses a key the clinic does not
+	// allow or a value that is too long
+	ErrInvalidMetadata = errors.New("invalid appointment metadata")
+	// ErrNotOwnDaySheet is returned when a doctor asks for the day sheet of another doctor
+	ErrNotOwnDaySheet = errors.New("doctors can only view their own day sheet")
+)
+
+// maxGroupSize is the most patients, the booking patient included, one group booking can seat
+const maxGroupSize = 5
+
+// Limits on appointment tags and metadata
+const (
+	maxAppointmentTags     = 20
+	maxTagLength           = 50
+	maxMetadataValueLength = 255
+)
+
+// AppointmentFilter narrows an appointment list for clinic staff. Empty fields do not filter,
+// and a list matches any of its values, except tags and metadata, which must all match.
+type AppointmentFilter struct {
+	Statuses           []string
+	AppointmentTypeIDs []uint
+	Modalities         []string
+	DoctorIDs          []uint
+	PatientIDs         []uint
+	From               *time.Time // Earliest scheduled start
+	To                 *time.Time // Scheduled start before this time
+	PatientName        string     // Part of the patient's name
+	Tags               []string
+	Metadata           map[string]string // Metadata values by key
+}
+
 type appointmentService struct {
-	appointmentRepo repository.AppointmentRepository
-	doctorRepo      repository.DoctorRepository
-	patientRepo     repository.PatientRepository
-	logger          *zap.Logger
+	appointmentRepo  repository.AppointmentRepository
+	doctorRepo       repository.DoctorRepository
+	patientRepo      repository.PatientRepository
+	typeRepo         repository.AppointmentTypeRepository
+	reasonRepo       repository.VisitReasonRepository
+	availabilityRepo repository.AvailabilityRepository
+	orgService       OrganizationService
+	noShowService    NoShowService
+	slotHolds        SlotHoldService
+	slotCache        *SlotCache
+	bookingLocks     *BookingLocks
+	auditLogRepo     repository.AuditLogRepository
+	logger           *zap.Logger
 }
 
 // NewAppointmentService creates a new appointment service
@@ -23,59 +108,330 @@ func NewAppointmentService(
 	appointmentRepo repository.AppointmentRepository,
 	doctorRepo repository.DoctorRepository,
 	patientRepo repository.PatientRepository,
+	typeRepo repository.AppointmentTypeRepository,
+	reasonRepo repository.VisitReasonRepository,
+	availabilityRepo repository.AvailabilityRepository,
+	orgService OrganizationService,
+	noShowService NoShowService,
+	slotHolds SlotHoldService,
+	slotCache *SlotCache,
+	bookingLocks *BookingLocks,
+	auditLogRepo repository.AuditLogRepository,
 	logger *zap.Logger,
 ) AppointmentService {
 	return &appointmentService{
-		appointmentRepo: appointmentRepo,
-		doctorRepo:      doctorRepo,
-		patientRepo:     patientRepo,
-		logger:          logger,
+		appointmentRepo:  appointmentRepo,
+		doctorRepo:       doctorRepo,
+		patientRepo:      patientRepo,
+		typeRepo:         typeRepo,
+		reasonRepo:       reasonRepo,
+		availabilityRepo: availabilityRepo,
+		orgService:       orgService,
+		noShowService:    noShowService,
+		slotHolds:        slotHolds,
+		slotCache:        slotCache,
+		bookingLocks:     bookingLocks,
+		auditLogRepo:     auditLogRepo,
+		logger:           logger,
 	}
 }
 
-// CreateAppointment creates a new appointment
-func (s *appointmentService) CreateAppointment(ctx context.Context, patientID, doctorID uint, date, timeStr string, reason string) (*model.Appointment, error) {
+// CreateAppointment creates a new appointment. appointmentTypeID may be 0 to book an in-person
+// appointment of the doctor's consultation length; otherwise the type must be offered by the
+// doctor's clinic and intakeAnswers must answer its required intake questions. visitReasonID may
+// name the reason the patient books for, which the doctor's specialty must see; without an
+// appointment type the appointment then takes the reason's length. participantIDs may add other
+// patients of the booking patient's family to the same slot.
+//
+// The user signed in as userID must be the patient or their guardian, unless bookingStaff is set
+// for staff allowed to book for any patient.
+func (s *appointmentService) CreateAppointment(ctx context.Context, userID uint, bookingStaff bool, patientID, doctorID, appointmentTypeID, visitReasonID uint, participantIDs []uint, date, timeStr, reason string, intakeAnswers map[string]string) (*model.Appointment, error) {
+	if !bookingStaff {
+		if err := authorizeBooking(ctx, s.patientRepo, userID, patientID); err != nil {
+			return nil, err
+		}
+	}
+	return s.createAppointment(ctx, 0, patientID, doctorID, appointmentTypeID, visitReasonID, participantIDs, date, timeStr, reason, intakeAnswers)
+}
+
+// createAppointment books an appointment like CreateAppointment. bookedBy is the staff member
+// booking on the patient's behalf, or 0 when patients book themselves.
+func (s *appointmentService) createAppointment(ctx context.Context, bookedBy, patientID, doctorID, appointmentTypeID, visitReasonID uint, participantIDs []uint, date, timeStr, reason string, intakeAnswers map[string]string) (*model.Appointment, error) {
 	// Parse date and time strings
 	dateTime, err := parseDateTime(date, timeStr)
 	if err != nil {
 		return nil, errors.New("invalid date or time format")
 	}
 
-	// Create appointment model
+	// Apply the clinic's scheduling rules
+	org, err := s.orgService.GetDoctorOrganization(ctx, doctorID)
+	if err != nil {
+		return nil, err
+	}
+	doctor, err := s.doctorRepo.FindByID(ctx, doctorID)
+	if err != nil {
+		return nil, err
+	}
+	booking, err := resolveBookingType(ctx, s.typeRepo, org, doctor, appointmentTypeID, intakeAnswers)
+	if err != nil {
+		return nil, err
+	}
+	visitReason, err := resolveVisitReason(ctx, s.reasonRepo, doctor, visitReasonID)
+	if err != nil {
+		return nil, err
+	}
+	var visitReasonRef *uint
+	if visitReason != nil {
+		visitReasonRef = &visitReason.ID
+		if appointmentTypeID == 0 {
+			booking.length = time.Duration(visitReason.Duration) * time.Minute
+		}
+		if strings.TrimSpace(reason) == "" {
+			reason = visitReason.Name
+		}
+	}
+
+	scheduledEnd := dateTime.Add(booking.length)
+	if err := checkBookingRules(org, dateTime, scheduledEnd, time.Now()); err != nil {
+		return nil, err
+	}
+	if err := checkAvailability(ctx, s.availabilityRepo, org, doctorID, dateTime, scheduledEnd); err != nil {
+		return nil, err
+	}
+	if err := s.slotHolds.CheckSlot(ctx, patientID, doctorID, dateTime); err != nil {
+		return nil, err
+	}
+
+	patient, err := s.patientRepo.FindByID(ctx, patientID)
+	if err != nil {
+		return nil, err
+	}
+	participants, err := s.groupParticipants(ctx, patient, participantIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create appointment model; the public ID is assigned up front for the booking event
 	appointment := &model.Appointment{
-		PatientID:      patientID,
-		DoctorID:       doctorID,
-		ScheduledStart: dateTime,
-		ScheduledEnd:   dateTime.Add(30 * time.Minute),
-		Reason:         reason,
-		Status:         model.AppointmentStatusPending,
-		CreatedAt:      time.Now(),
-		UpdatedAt:      time.Now(),
-	}
-
-	// Call repository to save appointment
-	if err := s.appointmentRepo.Create(ctx, appointment); err != nil {
+		PublicID:          model.NewPublicID(),
+		PatientID:         patientID,
+		DoctorID:          doctorID,
+		AppointmentTypeID: booking.typeID,
+		VisitReasonID:     visitReasonRef,
+		Modality:          booking.modality,
+		IntakeAnswers:     intakeAnswers,
+		ScheduledStart:    dateTime,
+		ScheduledEnd:      scheduledEnd,
+		Reason:            reason,
+		Status:            model.AppointmentStatusPending,
+		Participants:      participants,
+		CreatedAt:         time.Now(),
+		UpdatedAt:         time.Now(),
+	}
+	if bookedBy != 0 {
+		appointment.BookedByID = &bookedBy
+	}
+	booking.startChecklist(appointment, org)
+	autoConfirm(appointment, doctor)
+
+	data := newAppointmentEventData(appointment)
+	data.PatientID = patient.PublicID
+	data.DoctorID = doctor.PublicID
+	events, err := appointmentEvents(model.EventAppointmentBooked, data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Save the appointment with its booking confirmation, which the outbox dispatcher sends.
+	// Overlaps are checked as it is saved so concurrent bookings cannot both succeed.
+	err = s.bookingLocks.Do(ctx, doctorID, func() error {
+		return s.appointmentRepo.Create(ctx, appointment, events...)
+	})
+	if err != nil {
+		if errors.Is(err, ErrScheduleConflict) {
+			return nil, err
+		}
 		return nil, fmt.Errorf("failed to create appointment: %w", err)
 	}
+	s.slotHolds.ReleaseSlot(ctx, doctorID, dateTime)
+	s.slotCache.Invalidate(doctorID)
+
+	// Ask high-risk patients to confirm; the booking stands even if the code cannot be sent
+	if err := s.noShowService.ScreenBooking(ctx, appointment); err != nil {
+		s.logger.Warn("Failed to screen booking for no-show risk", zap.Uint("appointmentID", appointment.ID), zap.Error(err))
+	}
 
 	return appointment, nil
 }
 
+// groupParticipants checks the additional patients of a group booking: each may be listed once,
+// the group may not exceed maxGroupSize patients, and all must share the booking patient's family
+func (s *appointmentService) groupParticipants(ctx context.Context, patient *model.Patient, participantIDs []uint) ([]model.AppointmentParticipant, error) {
+	if len(participantIDs) == 0 {
+		return nil, nil
+	}
+	if len(participantIDs)+1 > maxGroupSize {
+		return nil, fmt.Errorf("%w: at most %d patients can share a booking", ErrInvalidParticipants, maxGroupSize)
+	}
+	seen := map[uint]bool{patient.ID: true}
+	participants := make([]model.AppointmentParticipant, 0, len(participantIDs))
+	for _, id := range participantIDs {
+		if seen[id] {
+			return nil, fmt.Errorf("%w: each patient can only be listed once", ErrInvalidParticipants)
+		}
+		seen[id] = true
+		participant, err := s.patientRepo.FindByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if participant.FamilyID() != patient.FamilyID() {
+			return nil, ErrNotSameFamily
+		}
+		participants = append(participants, model.AppointmentParticipant{PatientID: participant.ID})
+	}
+	return participants, nil
+}
+
+// authorizeBooking checks that the user signed in as userID is the patient or their guardian
+func authorizeBooking(ctx context.Context, patientRepo repository.PatientRepository, userID, patientID uint) error {
+	caller, err := patientRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		if isNotFound(err) {
+			return ErrNotOwnBooking
+		}
+		return err
+	}
+	if caller.ID == patientID {
+		return nil
+	}
+	patient, err := patientRepo.FindByID(ctx, patientID)
+	if err != nil {
+		return err
+	}
+	if patient.GuardianID == nil || *patient.GuardianID != caller.ID {
+		return ErrNotOwnBooking
+	}
+	return nil
+}
+
 // GetAppointmentByID gets an appointment by ID
 func (s *appointmentService) GetAppointmentByID(ctx context.Context, id uint) (*model.Appointment, error) {
 	return s.appointmentRepo.FindByID(ctx, id)
 }
 
-// GetPatientAppointments gets appointments for a patient with pagination
-func (s *appointmentService) GetPatientAppointments(ctx context.Context, patientID uint, page, pageSize int) ([]*model.Appointment, int64, error) {
+// GetAppointmentsByPublicIDs gets several appointments in one query. It returns the appointments
+// found in the order requested and the IDs that matched none.
+func (s *appointmentService) GetAppointmentsByPublicIDs(ctx context.Context, publicIDs []string) ([]*model.Appointment, []string, error) {
+	publicIDs, err := batchPublicIDs(model.ResourceAppointment, publicIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	found, err := s.appointmentRepo.FindByPublicIDs(ctx, publicIDs)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get appointments: %w", err)
+	}
+
+	byID := make(map[string]*model.Appointment, len(found))
+	for _, appointment := range found {
+		byID[appointment.PublicID] = appointment
+	}
+	appointments := make([]*model.Appointment, 0, len(found))
+	missing := []string{}
+	for _, id := range publicIDs {
+		if appointment, ok := byID[id]; ok {
+			appointments = append(appointments, appointment)
+		} else {
+			missing = append(missing, id)
+		}
+	}
+	return appointments, missing, nil
+}
+
+// GetPatientAppointments gets appointments for a patient with pagination, sorted and loading
+// only the fields the query asks for. Without a sort the latest appointments come first.
+func (s *appointmentService) GetPatientAppointments(ctx context.Context, patientID uint, page, pageSize int, query ListQuery) ([]*model.Appointment, int64, error) {
+	opts, err := listOptions("appointment", appointmentFields, query)
+	if err != nil {
+		return nil, 0, err
+	}
 	offset := (page - 1) * pageSize
-	return s.appointmentRepo.FindByPatientID(ctx, patientID, pageSize, offset)
+	appointments, total, err := s.appointmentRepo.FindByPatientID(ctx, patientID, pageSize, offset, opts)
+	return appointments, total, listError(err)
 }
 
-// GetDoctorAppointments gets appointments for a doctor with pagination
-func (s *appointmentService) GetDoctorAppointments(ctx context.Context, doctorID uint, page, pageSize int) ([]*model.Appointment, int64, error) {
+// GetDoctorAppointments gets appointments for a doctor with pagination, shaped by the list query
+func (s *appointmentService) GetDoctorAppointments(ctx context.Context, doctorID uint, page, pageSize int, query ListQuery) ([]*model.Appointment, int64, error) {
+	opts, err := listOptions("appointment", appointmentFields, query)
+	if err != nil {
+		return nil, 0, err
+	}
 	offset := (page - 1) * pageSize
-	return s.appointmentRepo.FindByDoctorID(ctx, doctorID, pageSize, offset)
+	appointments, total, err := s.appointmentRepo.FindByDoctorID(ctx, doctorID, pageSize, offset, opts)
+	return appointments, total, listError(err)
+}
+
+// ListAppointments gets the appointments matching filter across doctors and patients with
+// pagination, shaped by the list query. Without a sort the earliest appointments come first, as
+// schedule views show them.
+func (s *appointmentService) ListAppointments(ctx context.Context, filter AppointmentFilter, page, pageSize int, query ListQuery) ([]*model.Appointment, int64, error) {
+	repoFilter, err := filter.toRepository()
+	if err != nil {
+		return nil, 0, err
+	}
+	opts, err := listOptions("appointment", appointmentFields, query)
+	if err != nil {
+		return nil, 0, err
+	}
+	offset := (page - 1) * pageSize
+	appointments, total, err := s.appointmentRepo.Find(ctx, repoFilter, pageSize, offset, opts)
+	return appointments, total, listError(err)
+}
+
+// toRepository validates the filter and converts it for the repository
+func (f AppointmentFilter) toRepository() (repository.AppointmentFilter, error) {
+	filter := repository.AppointmentFilter{
+		AppointmentTypeIDs: f.AppointmentTypeIDs,
+		DoctorIDs:          f.DoctorIDs,
+		PatientIDs:         f.PatientIDs,
+		From:               f.From,
+		To:                 f.To,
+		PatientName:        strings.TrimSpace(f.PatientName),
+		Tags:               normalizeTags(f.Tags),
+	}
+	for key, value := range f.Metadata {
+		if !metadataKeyPattern.MatchString(key) {
+			return filter, fmt.Errorf("%w: invalid metadata key %q", ErrInvalidFilter, key)
+		}
+		if filter.Metadata == nil {
+			filter.Metadata = make(map[string]string, len(f.Metadata))
+		}
+		filter.Metadata[key] = strings.TrimSpace(value)
+	}
+	for _, status := range f.Statuses {
+		s := model.AppointmentStatus(status)
+		switch s {
+		case model.AppointmentStatusPending, model.AppointmentStatusConfirmed, model.AppointmentStatusCheckedIn,
+			model.AppointmentStatusCancelled, model.AppointmentStatusCompleted, model.AppointmentStatusNoShow:
+		default:
+			return filter, fmt.Errorf("%w: unknown status %q", ErrInvalidFilter, status)
+		}
+		filter.Statuses = append(filter.Statuses, s)
+	}
+	for _, modality := range f.Modalities {
+		m := model.AppointmentModality(modality)
+		if !m.IsValid() {
+			return filter, fmt.Errorf("%w: unknown modality %q", ErrInvalidFilter, modality)
+		}
+		filter.Modalities = append(filter.Modalities, m)
+	}
+	if f.From != nil && f.To != nil && !f.From.Before(*f.To) {
+		return filter, fmt.Errorf("%w: from must be before to", ErrInvalidFilter)
+	}
+	if filter.PatientName != "" && utf8.RuneCountInString(filter.PatientName) < minSearchLength {
+		return filter, fmt.Errorf("%w: patient name must be at least %d characters", ErrInvalidFilter, minSearchLength)
+	}
+	return filter, nil
 }
 
 // GetDoctorAppointmentsByDateRange gets a doctor's appointments for a specific date range
@@ -97,91 +453,540 @@ func (s *appointmentService) UpdateAppointment(ctx context.Context, id uint, dat
 		existingAppointment.Status == model.AppointmentStatusCancelled {
 		return nil, errors.New("cannot update a completed or cancelled appointment")
 	}
+	previousStart := existingAppointment.ScheduledStart
 
 	// Update fields that were provided
+	var move *model.AppointmentHistory
 	if date != "" && timeStr != "" {
-		scheduledStart, err := parseDateTime(date, timeStr)
-		if err != nil {
-			return nil, errors.New("invalid date or time format")
+		if existingAppointment.Status == model.AppointmentStatusNoShow {
+			return nil, errors.New("cannot move a missed appointment")
 		}
-
-		// Validate appointment time
-		if scheduledStart.Before(time.Now()) {
-			return nil, errors.New("appointment cannot be scheduled in the past")
-		}
-
-		existingAppointment.ScheduledStart = scheduledStart
-		existingAppointment.ScheduledEnd = scheduledStart.Add(30 * time.Minute)
-
-		// Check for overlapping appointments
-		overlappingAppointments, _, err := s.appointmentRepo.FindByDateRange(
-			ctx,
-			existingAppointment.DoctorID,
-			existingAppointment.ScheduledStart.Format(time.RFC3339),
-			existingAppointment.ScheduledEnd.Format(time.RFC3339),
-			100, 0, // Fetch up to 100 appointments in this range
-		)
-		if err != nil {
-			s.logger.Error("Failed to check overlapping appointments", zap.Error(err))
-			return nil, errors.New("failed to check doctor's schedule")
+		if move, err = s.moveAppointment(ctx, existingAppointment, date, timeStr); err != nil {
+			return nil, err
 		}
+	}
 
-		for _, existing := range overlappingAppointments {
-			if existing.ID != existingAppointment.ID &&
-				existing.Status != model.AppointmentStatusCancelled &&
-				((existingAppointment.ScheduledStart.Before(existing.ScheduledEnd) &&
-					existingAppointment.ScheduledEnd.After(existing.ScheduledStart)) ||
-					(existingAppointment.ScheduledStart.Equal(existing.ScheduledStart))) {
-				return nil, errors.New("appointment time conflicts with an existing appointment")
+	eventType := model.EventAppointmentUpdated
+	if status != "" && model.AppointmentStatus(status) != existingAppointment.Status {
+		if model.AppointmentStatus(status) == model.AppointmentStatusConfirmed {
+			if outstanding := existingAppointment.OutstandingRequirements(); len(outstanding) > 0 {
+				return nil, fmt.Errorf("%w: %s outstanding", ErrChecklistIncomplete, joinRequirements(outstanding))
 			}
 		}
-	}
-
-	if status != "" {
-		existingAppointment.Status = model.AppointmentStatus(status)
+		if eventType, err = transitionAppointment(existingAppointment, model.AppointmentStatus(status), time.Now()); err != nil {
+			return nil, err
+		}
 	}
 
 	if reason != "" {
 		existingAppointment.Reason = reason
 	}
 
-	// Update appointment
-	if err := s.appointmentRepo.Update(ctx, existingAppointment); err != nil {
+	// Keep changes to one occurrence of a series from being undone by updates to the series
+	switch {
+	case existingAppointment.Status == model.AppointmentStatusCancelled:
+		markSeriesException(existingAppointment, model.SeriesExceptionCancelled, previousStart, time.Now())
+	case move != nil:
+		markSeriesException(existingAppointment, model.SeriesExceptionMoved, previousStart, time.Now())
+	case reason != "":
+		markSeriesException(existingAppointment, model.SeriesExceptionEdited, previousStart, time.Now())
+	}
+
+	data := newAppointmentEventData(existingAppointment)
+	if eventType == model.EventAppointmentUpdated && !existingAppointment.ScheduledStart.Equal(previousStart) {
+		eventType = model.EventAppointmentRescheduled
+		data.PreviousStart = &previousStart
+	}
+	events, err := appointmentEvents(eventType, data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Update appointment, checking a new time for overlaps as it is saved
+	if move != nil {
+		err = s.bookingLocks.Do(ctx, existingAppointment.DoctorID, func() error {
+			return s.appointmentRepo.Reschedule(ctx, existingAppointment, move, events...)
+		})
+	} else {
+		err = s.appointmentRepo.Update(ctx, existingAppointment, events...)
+	}
+	if err != nil {
+		if errors.Is(err, ErrScheduleConflict) {
+			return nil, err
+		}
 		s.logger.Error("Failed to update appointment", zap.Error(err))
 		return nil, errors.New("failed to update appointment")
 	}
+	s.slotCache.Invalidate(existingAppointment.DoctorID)
 
 	return existingAppointment, nil
 }
 
-// CancelAppointment cancels an appointment
-func (s *appointmentService) CancelAppointment(ctx context.Context, id uint) error {
+// RescheduleAppointment moves a pending or confirmed appointment to date and time on behalf of
+// userID, recording the move with reason in the appointment's history. Both the patient and the
+// doctor are notified.
+func (s *appointmentService) RescheduleAppointment(ctx context.Context, id, userID uint, date, timeStr, reason string) (*model.Appointment, error) {
+	appointment, err := s.appointmentRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if appointment.Status != model.AppointmentStatusPending && appointment.Status != model.AppointmentStatusConfirmed {
+		return nil, errors.New("only pending or confirmed appointments can be rescheduled")
+	}
+
+	previousStart := appointment.ScheduledStart
+	move, err := s.moveAppointment(ctx, appointment, date, timeStr)
+	if err != nil {
+		return nil, err
+	}
+	if move == nil {
+		return nil, errors.New("appointment is already scheduled at that time")
+	}
+	move.Reason = reason
+	if userID != 0 {
+		move.ChangedByID = &userID
+	}
+	markSeriesException(appointment, model.SeriesExceptionMoved, previousStart, time.Now())
+
+	data := newAppointmentEventData(appointment)
+	data.PreviousStart = &previousStart
+	events, err := appointmentEvents(model.EventAppointmentRescheduled, data)
+	if err != nil {
+		return nil, err
+	}
+	err = s.bookingLocks.Do(ctx, appointment.DoctorID, func() error {
+		return s.appointmentRepo.Reschedule(ctx, appointment, move, events...)
+	})
+	if err != nil {
+		if errors.Is(err, ErrScheduleConflict) {
+			return nil, err
+		}
+		s.logger.Error("Failed to reschedule appointment", zap.Error(err))
+		return nil, errors.New("failed to reschedule appointment")
+	}
+	s.slotCache.Invalidate(appointment.DoctorID)
+
+	s.logger.Info("Appointment rescheduled",
+		zap.Uint("appointmentID", appointment.ID),
+		zap.Time("previousStart", previousStart),
+		zap.Time("newStart", appointment.ScheduledStart))
+	return appointment, nil
+}
+
+// GetAppointmentHistory lists the reschedules of an appointment, oldest first
+func (s *appointmentService) GetAppointmentHistory(ctx context.Context, id uint) ([]*model.AppointmentHistory, error) {
+	if _, err := s.appointmentRepo.FindByID(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.appointmentRepo.FindHistory(ctx, id)
+}
+
+// moveAppointment moves an appointment to date and time, keeping the length it was booked with,
+// and returns the history row recording the move, or nil if the time is unchanged. The new time
+// must pass the same checks as a new booking, and the appointment must not have reached its
+// clinic's reschedule limit; overlaps are checked when the move is saved.
+func (s *appointmentService) moveAppointment(ctx context.Context, appointment *model.Appointment, date, timeStr string) (*model.AppointmentHistory, error) {
+	scheduledStart, err := parseDateTime(date, timeStr)
+	if err != nil {
+		return nil, errors.New("invalid date or time format")
+	}
+	if scheduledStart.Equal(appointment.ScheduledStart) {
+		return nil, nil
+	}
+
+	org, err := s.orgService.GetDoctorOrganization(ctx, appointment.DoctorID)
+	if err != nil {
+		return nil, err
+	}
+	history, err := s.appointmentRepo.FindHistory(ctx, appointment.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get appointment history: %w", err)
+	}
+	if len(history) >= org.RescheduleLimit() {
+		return nil, fmt.Errorf("%w: the clinic allows %d", ErrRescheduleLimit, org.RescheduleLimit())
+	}
+
+	scheduledEnd := scheduledStart.Add(appointment.ScheduledEnd.Sub(appointment.ScheduledStart))
+	if err := checkBookingRules(org, scheduledStart, scheduledEnd, time.Now()); err != nil {
+		return nil, err
+	}
+	if err := checkAvailability(ctx, s.availabilityRepo, org, appointment.DoctorID, scheduledStart, scheduledEnd); err != nil {
+		return nil, err
+	}
+	if err := s.slotHolds.CheckSlot(ctx, appointment.PatientID, appointment.DoctorID, scheduledStart); err != nil {
+		return nil, err
+	}
+
+	move := &model.AppointmentHistory{
+		PreviousStart: appointment.ScheduledStart,
+		PreviousEnd:   appointment.ScheduledEnd,
+		NewStart:      scheduledStart,
+		NewEnd:        scheduledEnd,
+		CreatedAt:     time.Now(),
+	}
+	appointment.ScheduledStart = scheduledStart
+	appointment.ScheduledEnd = scheduledEnd
+	return move, nil
+}
+
+// CancelAppointment cancels an appointment under its cancellation policy, which is returned.
+// Within the cutoff the cancellation is refused with ErrCancellationCutoff, or accepted and
+// flagged as late if the clinic charges a fee for late cancellations.
+func (s *appointmentService) CancelAppointment(ctx context.Context, id uint) (*CancellationPolicy, error) {
 	// Get appointment
 	appointment, err := s.appointmentRepo.FindByID(ctx, id)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	return s.cancelAppointment(ctx, appointment)
+}
+
+// cancelAppointment cancels a loaded appointment like CancelAppointment
+func (s *appointmentService) cancelAppointment(ctx context.Context, appointment *model.Appointment) (*CancellationPolicy, error) {
 
 	// Check if appointment can be cancelled
 	if appointment.Status == model.AppointmentStatusCompleted ||
 		appointment.Status == model.AppointmentStatusCancelled {
-		return errors.New("appointment is already completed or cancelled")
+		return nil, fmt.Errorf("%w: appointment is already completed or cancelled", ErrInvalidTransition)
+	}
+
+	org, err := s.orgService.GetDoctorOrganization(ctx, appointment.DoctorID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	policy := cancellationPolicy(org, &appointment.Doctor, appointment.ScheduledStart, now)
+	if policy.Late && !policy.LateCancellationFee {
+		return &policy, fmt.Errorf("%w: appointments must be cancelled at least %d minutes before they start",
+			ErrCancellationCutoff, policy.CutoffMinutes)
+	}
+
+	eventType, err := transitionAppointment(appointment, model.AppointmentStatusCancelled, now)
+	if err != nil {
+		return nil, err
+	}
+	appointment.LateCancellation = policy.Late
+	markSeriesException(appointment, model.SeriesExceptionCancelled, appointment.ScheduledStart, now)
+	events, err := appointmentEvents(eventType, newAppointmentEventData(appointment))
+	if err != nil {
+		return nil, err
+	}
+	if err := s.appointmentRepo.Update(ctx, appointment, events...); err != nil {
+		return nil, err
+	}
+	s.slotCache.Invalidate(appointment.DoctorID)
+	return &policy, nil
+}
+
+// ConfirmAppointment confirms a pending booking on behalf of the doctor signed in as userID. The
+// patient is emailed once it is saved.
+func (s *appointmentService) ConfirmAppointment(ctx context.Context, id, userID uint) (*model.Appointment, error) {
+	appointment, err := s.appointmentRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if appointment.Doctor.UserID != userID {
+		return nil, ErrNotOwnAppointment
+	}
+	if outstanding := appointment.OutstandingRequirements(); len(outstanding) > 0 {
+		return nil, fmt.Errorf("%w: %s outstanding", ErrChecklistIncomplete, joinRequirements(outstanding))
+	}
+
+	eventType, err := transitionAppointment(appointment, model.AppointmentStatusConfirmed, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	events, err := appointmentEvents(eventType, newAppointmentEventData(appointment))
+	if err != nil {
+		return nil, err
+	}
+	if err := s.appointmentRepo.Update(ctx, appointment, events...); err != nil {
+		return nil, fmt.Errorf("failed to confirm appointment: %w", err)
+	}
+	return appointment, nil
+}
+
+// CheckInAppointment records that the patient of a confirmed appointment has arrived, adding them
+// to the doctor's waiting queue. Patients are checked in on the day of the appointment in the
+// clinic's timezone, early or late; a patient marked as a no-show who turns up can still be
+// checked in.
+func (s *appointmentService) CheckInAppointment(ctx context.Context, id uint) (*model.Appointment, error) {
+	appointment, err := s.appointmentRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	org, err := s.orgService.GetDoctorOrganization(ctx, appointment.DoctorID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	loc := utils.LoadLocation(org.Timezone)
+	today, _ := utils.ResolveDate("today", now.In(loc))
+	if appointment.ScheduledStart.Before(today) || !appointment.ScheduledStart.Before(today.AddDate(0, 0, 1)) {
+		return nil, ErrCheckInNotToday
+	}
+
+	eventType, err := transitionAppointment(appointment, model.AppointmentStatusCheckedIn, now)
+	if err != nil {
+		return nil, err
+	}
+	events, err := appointmentEvents(eventType, newAppointmentEventData(appointment))
+	if err != nil {
+		return nil, err
+	}
+	if err := s.appointmentRepo.Update(ctx, appointment, events...); err != nil {
+		return nil, fmt.Errorf("failed to check in appointment: %w", err)
+	}
+	return appointment, nil
+}
+
+// GetCheckInQueue lists the patients waiting for a doctor, first arrived first. Only patients
+// checked in today in the clinic's timezone are listed, so an appointment never completed does
+// not hold a place in the queue on later days.
+func (s *appointmentService) GetCheckInQueue(ctx context.Context, doctorID uint) ([]*model.Appointment, error) {
+	org, err := s.orgService.GetDoctorOrganization(ctx, doctorID)
+	if err != nil {
+		return nil, err
+	}
+	today, _ := utils.ResolveDate("today", time.Now().In(utils.LoadLocation(org.Timezone)))
+	appointments, err := s.appointmentRepo.FindCheckedIn(ctx, doctorID, today)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get check-in queue: %w", err)
+	}
+	return appointments, nil
+}
+
+// StartAppointment records that the doctor signed in as userID admitted the checked-in patient of
+// an appointment, taking them off the waiting queue. Video visits start when the doctor admits
+// patients from the waiting room instead. Starting a started visit changes nothing.
+func (s *appointmentService) StartAppointment(ctx context.Context, id, userID uint) (*model.Appointment, error) {
+	appointment, err := s.appointmentRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if appointment.Doctor.UserID != userID {
+		return nil, ErrNotOwnAppointment
+	}
+	if appointment.StartedAt != nil {
+		return appointment, nil
+	}
+	if appointment.Status != model.AppointmentStatusCheckedIn {
+		return nil, fmt.Errorf("%w: only checked-in appointments can be started", ErrInvalidTransition)
+	}
+
+	now := time.Now()
+	appointment.StartedAt = &now
+	appointment.UpdatedAt = now
+	if err := s.appointmentRepo.Update(ctx, appointment); err != nil {
+		return nil, fmt.Errorf("failed to start appointment: %w", err)
+	}
+	return appointment, nil
+}
+
+// DeclineAppointment cancels a pending booking on behalf of the doctor signed in as userID and
+// emails the patient the reason. Unlike cancellations, declines are allowed up to the start of the
+// appointment; confirmed appointments are cancelled instead.
+func (s *appointmentService) DeclineAppointment(ctx context.Context, id, userID uint, reason string) (*model.Appointment, error) {
+	appointment, err := s.appointmentRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if appointment.Doctor.UserID != userID {
+		return nil, ErrNotOwnAppointment
+	}
+	if appointment.Status != model.AppointmentStatusPending {
+		return nil, fmt.Errorf("%w: only pending appointments can be declined", ErrInvalidTransition)
+	}
+
+	if _, err := transitionAppointment(appointment, model.AppointmentStatusCancelled, time.Now()); err != nil {
+		return nil, err
 	}
+	appointment.DeclineReason = reason
 
-	// Check if it's too late to cancel
-	if time.Until(appointment.ScheduledStart) < time.Hour {
-		return errors.New("appointment cannot be cancelled less than 1 hour before the scheduled time")
+	data := newAppointmentEventData(appointment)
+	data.Reason = reason
+	events, err := appointmentEvents(model.EventAppointmentDeclined, data)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.appointmentRepo.Update(ctx, appointment, events...); err != nil {
+		return nil, fmt.Errorf("failed to decline appointment: %w", err)
+	}
+	s.slotCache.Invalidate(appointment.DoctorID)
+	return appointment, nil
+}
+
+// GenerateDaySheet renders a printable PDF of a doctor's appointments for the day starting at day.
+// Times are shown in day's location; cancelled appointments are left out. Unless frontDesk is set,
+// for admins and front desk staff, the sheet must be the requesting user's own.
+func (s *appointmentService) GenerateDaySheet(ctx context.Context, userID uint, frontDesk bool, doctorID uint, day time.Time) ([]byte, error) {
+	doctor, err := s.doctorRepo.FindByID(ctx, doctorID)
+	if err != nil {
+		return nil, err
+	}
+	if !frontDesk && doctor.UserID != userID {
+		return nil, ErrNotOwnDaySheet
+	}
+
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	appointments, err := s.appointmentRepo.FindByDoctorBetween(ctx, doctorID, start, start.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get appointments: %w", err)
+	}
+
+	rows := make([][]string, 0, len(appointments))
+	for _, appt := range appointments {
+		if appt.Status == model.AppointmentStatusCancelled {
+			continue
+		}
+		rows = append(rows, []string{
+			appt.ScheduledStart.In(day.Location()).Format("15:04") + " - " +
+				appt.ScheduledEnd.In(day.Location()).Format("15:04"),
+			appt.Patient.User.Name,
+			appt.Reason,
+			appointmentTypeLabel(appt),
+			string(appt.Status),
+			appt.Patient.User.Phone,
+		})
+	}
+
+	doc := pdf.New(fmt.Sprintf("Day sheet %s %s", doctor.User.Name, start.Format("2006-01-02")))
+	doc.Heading("Day Sheet: " + doctor.User.Name)
+	doc.Text(fmt.Sprintf("%s (%s)", start.Format("Monday, 2 January 2006"), day.Location()))
+	if doctor.Specialty != "" {
+		doc.Text("Specialty: " + doctor.Specialty)
+	}
+	doc.Text(fmt.Sprintf("%d appointments", len(rows)))
+	doc.Spacer(10)
+	doc.Table([]pdf.Column{
+		{Title: "Time", Width: 0.14},
+		{Title: "Patient", Width: 0.22},
+		{Title: "Reason", Width: 0.28},
+		{Title: "Type", Width: 0.1},
+		{Title: "Status", Width: 0.1},
+		{Title: "Contact", Width: 0.16},
+	}, rows)
+	doc.Spacer(10)
+	doc.Text("Printed " + time.Now().In(day.Location()).Format("2006-01-02 15:04 MST") + ". Contains patient information; dispose of securely.")
+
+	return doc.Bytes(), nil
+}
+
+// appointmentTypeLabel returns the name of the appointment's type, or its modality if it has none
+func appointmentTypeLabel(appt *model.Appointment) string {
+	if appt.AppointmentType != nil {
+		return appt.AppointmentType.Name
+	}
+	return strings.ReplaceAll(string(appt.Modality), "_", " ")
+}
+
+// bookingType is what an appointment takes from the type it is booked as
+type bookingType struct {
+	length        time.Duration
+	modality      model.AppointmentModality
+	typeID        *uint
+	intakePending bool // The intake form is left to be answered before confirmation
+}
+
+// startChecklist gives a new appointment the clinic's intake checklist, with the intake form
+// checked off unless it was left for later
+func (b bookingType) startChecklist(appointment *model.Appointment, org *model.Organization) {
+	appointment.Checklist = model.NewChecklist(org.IntakeRequirements)
+	if !b.intakePending {
+		appointment.SetRequirement(model.RequirementIntakeForm, &appointment.CreatedAt, 0)
+	}
+}
+
+// autoConfirm confirms a new booking straight away when its doctor does not review bookings and
+// nothing is left on its intake checklist
+func autoConfirm(appointment *model.Appointment, doctor *model.Doctor) {
+	if doctor.AutoConfirm && len(appointment.OutstandingRequirements()) == 0 {
+		appointment.Status = model.AppointmentStatusConfirmed
+	}
+}
+
+// resolveBookingType looks up the appointment type a booking is made as. appointmentTypeID may be
+// 0 for an in-person appointment of the doctor's consultation length; otherwise the type must be
+// offered by the clinic and intakeAnswers must answer its required intake questions. Clinics
+// that require the intake form before confirmation also accept bookings without answers.
+func resolveBookingType(ctx context.Context, typeRepo repository.AppointmentTypeRepository, org *model.Organization, doctor *model.Doctor, appointmentTypeID uint, intakeAnswers map[string]string) (bookingType, error) {
+	if appointmentTypeID == 0 {
+		if len(intakeAnswers) > 0 {
+			return bookingType{}, errors.New("intake answers require an appointment type")
+		}
+		return bookingType{length: doctor.AppointmentLength(org), modality: model.ModalityInPerson}, nil
+	}
+
+	appointmentType, err := typeRepo.FindByID(ctx, appointmentTypeID)
+	if err != nil {
+		return bookingType{}, err
+	}
+	if !appointmentType.Active || !appointmentType.OfferedBy(org.ID) {
+		return bookingType{}, errors.New("appointment type is not offered by this doctor's clinic")
+	}
+	booking := bookingType{
+		length:   time.Duration(appointmentType.Duration) * time.Minute,
+		modality: appointmentType.Modality,
+		typeID:   &appointmentType.ID,
+	}
+	if len(intakeAnswers) == 0 && len(appointmentType.IntakeForm) > 0 && org.Requires(model.RequirementIntakeForm) {
+		booking.intakePending = true
+		return booking, nil
+	}
+	if err := appointmentType.ValidateIntake(intakeAnswers); err != nil {
+		return bookingType{}, err
+	}
+	return booking, nil
+}
+
+// joinRequirements lists intake requirements for an error message
+func joinRequirements(requirements []model.IntakeRequirement) string {
+	names := make([]string, 0, len(requirements))
+	for _, requirement := range requirements {
+		names = append(names, string(requirement))
+	}
+	return strings.Join(names, ", ")
+}
+
+// checkAvailability rejects times outside the doctor's weekly availability windows, read in the
+// clinic's timezone, and times overlapping the doctor's time off or busy times pulled from their
+// calendar. Doctors who have not set up any windows are bound by business hours alone.
+func checkAvailability(ctx context.Context, availabilityRepo repository.AvailabilityRepository, org *model.Organization, doctorID uint, start, end time.Time) error {
+	timeOff, err := availabilityRepo.FindTimeOff(ctx, doctorID, start, end)
+	if err != nil {
+		return fmt.Errorf("failed to check doctor time off: %w", err)
+	}
+	if len(timeOff) > 0 {
+		return ErrDoctorOnLeave
+	}
+	busy, err := availabilityRepo.FindCalendarBusy(ctx, doctorID, start, end)
+	if err != nil {
+		return fmt.Errorf("failed to check doctor calendar: %w", err)
+	}
+	if len(busy) > 0 {
+		return ErrDoctorBusy
 	}
 
-	// Update status
-	appointment.Status = model.AppointmentStatusCancelled
-	return s.appointmentRepo.Update(ctx, appointment)
+	windows, err := availabilityRepo.FindByDoctorID(ctx, doctorID)
+	if err != nil {
+		return fmt.Errorf("failed to check doctor availability: %w", err)
+	}
+	if len(windows) == 0 {
+		return nil
+	}
+	candidate := &model.Appointment{ScheduledStart: start, ScheduledEnd: end}
+	if !availabilityCovers(windows, candidate, utils.LoadLocation(org.Timezone)) {
+		return ErrOutsideAvailability
+	}
+	return nil
 }
 
-// Helper function to parse date and time strings
+// parseDateTime parses a UTC date and time. Handlers resolve times given in the caller's
+// timezone or with an offset to UTC before passing them on, so the server's own timezone never
+// affects bookings.
 func parseDateTime(date, timeStr string) (time.Time, error) {
 	dateTimeStr := date + " " + timeStr
-	return time.Parse("2006-01-02 15:04", dateTimeStr)
+	return time.ParseInLocation("2006-01-02 15:04", dateTimeStr, time.UTC)
 }
 
 // CompleteAppointment marks an appointment as completed with notes
@@ -206,9 +1011,208 @@ func (s *appointmentService) CompleteAppointment(ctx context.Context, id uint, n
 		return errors.New("cannot complete an appointment before its scheduled time")
 	}
 
-	// Update status
-	appointment.Status = model.AppointmentStatusCompleted
+	eventType, err := transitionAppointment(appointment, model.AppointmentStatusCompleted, time.Now())
+	if err != nil {
+		return err
+	}
 	appointment.Notes = notes
 
-	return s.appointmentRepo.Update(ctx, appointment)
+	events, err := appointmentEvents(eventType, newAppointmentEventData(appointment))
+	if err != nil {
+		return err
+	}
+	return s.appointmentRepo.Update(ctx, appointment, events...)
+}
+
+// SubmitIntake answers the intake form of an appointment booked without one, checking the
+// intake form off its checklist
+func (s *appointmentService) SubmitIntake(ctx context.Context, id uint, answers map[string]string) (*model.Appointment, error) {
+	appointment, err := s.appointmentRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if appointment.Status != model.AppointmentStatusPending && appointment.Status != model.AppointmentStatusConfirmed {
+		return nil, errors.New("intake can only be submitted for a pending or confirmed appointment")
+	}
+	if appointment.AppointmentType == nil {
+		return nil, errors.New("appointment has no intake form")
+	}
+	if err := appointment.AppointmentType.ValidateIntake(answers); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	appointment.IntakeAnswers = answers
+	appointment.SetRequirement(model.RequirementIntakeForm, &now, 0)
+	appointment.UpdatedAt = now
+
+	events, err := appointmentEvents(model.EventAppointmentUpdated, newAppointmentEventData(appointment))
+	if err != nil {
+		return nil, err
+	}
+	if err := s.appointmentRepo.Update(ctx, appointment, events...); err != nil {
+		return nil, fmt.Errorf("failed to save intake answers: %w", err)
+	}
+	return appointment, nil
+}
+
+// ConfirmAttendance records that the patient will attend an upcoming appointment, which stops
+// reminder escalation and clears any front-desk follow-up flag. Confirming again keeps the time of
+// the first confirmation.
+func (s *appointmentService) ConfirmAttendance(ctx context.Context, id, userID uint) (*model.Appointment, error) {
+	appointment, err := s.appointmentRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if appointment.Patient.UserID != userID {
+		return nil, ErrNotPatientAppointment
+	}
+	now := time.Now()
+	if (appointment.Status != model.AppointmentStatusPending && appointment.Status != model.AppointmentStatusConfirmed) ||
+		!appointment.ScheduledStart.After(now) {
+		return nil, ErrNotUpcoming
+	}
+	if appointment.AttendanceConfirmedAt != nil && !appointment.FollowUpRequired {
+		return appointment, nil
+	}
+
+	if appointment.AttendanceConfirmedAt == nil {
+		appointment.AttendanceConfirmedAt = &now
+	}
+	appointment.FollowUpRequired = false
+	appointment.UpdatedAt = now
+
+	events, err := appointmentEvents(model.EventAppointmentUpdated, newAppointmentEventData(appointment))
+	if err != nil {
+		return nil, err
+	}
+	if err := s.appointmentRepo.Update(ctx, appointment, events...); err != nil {
+		return nil, fmt.Errorf("failed to confirm attendance: %w", err)
+	}
+	return appointment, nil
+}
+
+// SetTags replaces an appointment's tags. Tags are trimmed, lowercased and deduplicated.
+func (s *appointmentService) SetTags(ctx context.Context, id uint, tags []string) (*model.Appointment, error) {
+	tags = normalizeTags(tags)
+	if len(tags) > maxAppointmentTags {
+		return nil, fmt.Errorf("%w: at most %d tags", ErrInvalidTags, maxAppointmentTags)
+	}
+	for _, tag := range tags {
+		if utf8.RuneCountInString(tag) > maxTagLength || strings.Contains(tag, ",") {
+			return nil, fmt.Errorf("%w: %q must be at most %d characters without commas", ErrInvalidTags, tag, maxTagLength)
+		}
+	}
+
+	appointment, err := s.appointmentRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	appointment.Tags = tags
+	appointment.UpdatedAt = time.Now()
+
+	events, err := appointmentEvents(model.EventAppointmentUpdated, newAppointmentEventData(appointment))
+	if err != nil {
+		return nil, err
+	}
+	if err := s.appointmentRepo.Update(ctx, appointment, events...); err != nil {
+		return nil, fmt.Errorf("failed to update appointment tags: %w", err)
+	}
+	return appointment, nil
+}
+
+// SetMetadata sets values of an appointment's metadata. Keys must be among the appointment
+// metadata keys of the doctor's clinic; an empty value removes its key. Keys not in metadata are
+// left as they are.
+func (s *appointmentService) SetMetadata(ctx context.Context, id uint, metadata map[string]string) (*model.Appointment, error) {
+	appointment, err := s.appointmentRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	org, err := s.orgService.GetDoctorOrganization(ctx, appointment.DoctorID)
+	if err != nil {
+		return nil, err
+	}
+	allowed := make(map[string]bool, len(org.AppointmentMetadataKeys))
+	for _, key := range org.AppointmentMetadataKeys {
+		allowed[key] = true
+	}
+
+	updated := make(map[string]string, len(appointment.Metadata)+len(metadata))
+	for key, value := range appointment.Metadata {
+		updated[key] = value
+	}
+	for key, value := range metadata {
+		if !allowed[key] {
+			return nil, fmt.Errorf("%w: the clinic does not allow the key %q", ErrInvalidMetadata, key)
+		}
+		value = strings.TrimSpace(value)
+		if utf8.RuneCountInString(value) > maxMetadataValueLength {
+			return nil, fmt.Errorf("%w: the value of %q must be at most %d characters", ErrInvalidMetadata, key, maxMetadataValueLength)
+		}
+		if value == "" {
+			delete(updated, key)
+			continue
+		}
+		updated[key] = value
+	}
+	appointment.Metadata = updated
+	appointment.UpdatedAt = time.Now()
+
+	events, err := appointmentEvents(model.EventAppointmentUpdated, newAppointmentEventData(appointment))
+	if err != nil {
+		return nil, err
+	}
+	if err := s.appointmentRepo.Update(ctx, appointment, events...); err != nil {
+		return nil, fmt.Errorf("failed to update appointment metadata: %w", err)
+	}
+	return appointment, nil
+}
+
+// normalizeTags trims and lowercases tags, dropping empty and repeated ones
+func normalizeTags(tags []string) []string {
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
+
+// SetChecklistItem checks an item of an appointment's intake checklist off on behalf of
+// userID, or reopens it when done is false
+func (s *appointmentService) SetChecklistItem(ctx context.Context, id, userID uint, requirement model.IntakeRequirement, done bool) (*model.Appointment, error) {
+	appointment, err := s.appointmentRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if appointment.Status == model.AppointmentStatusCompleted || appointment.Status == model.AppointmentStatusCancelled {
+		return nil, errors.New("appointment is already completed or cancelled")
+	}
+
+	now := time.Now()
+	var completedAt *time.Time
+	if done {
+		completedAt = &now
+	} else {
+		userID = 0
+	}
+	if !appointment.SetRequirement(requirement, completedAt, userID) {
+		return nil, fmt.Errorf("%q is not on the appointment's checklist", requirement)
+	}
+	appointment.UpdatedAt = now
+
+	events, err := appointmentEvents(model.EventAppointmentUpdated, newAppointmentEventData(appointment))
+	if err != nil {
+		return nil, err
+	}
+	if err := s.appointmentRepo.Update(ctx, appointment, events...); err != nil {
+		return nil, fmt.Errorf("failed to update appointment checklist: %w", err)
+	}
+	return appointment, nil
 }
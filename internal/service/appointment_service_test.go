@@ -0,0 +1,30 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestAuthorizeBookingAllowsOwnPatientsOnly(t *testing.T) {
+	tests := []struct {
+		name      string
+		userID    uint
+		patientID uint
+		want      error
+	}{
+		{"patient", 10, 10, nil},
+		{"guardian", 10, 11, nil},
+		{"child booking for the guardian", 11, 10, ErrNotOwnBooking},
+		{"other patient", 12, 10, ErrNotOwnBooking},
+		{"user without a patient profile", 1, 10, ErrNotOwnBooking},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := authorizeBooking(context.Background(), newTestClinic().patients, tt.userID, tt.patientID)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
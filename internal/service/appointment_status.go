@@ -0,0 +1,85 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+)
+
+// ErrInvalidTransition is returned when an appointment cannot move from its status to the one
+// requested
+var ErrInvalidTransition = errors.New("invalid appointment status transition")
+
+// appointmentTransitions lists the statuses each appointment status can move to. Bookings are
+// confirmed before they are completed, and cancelled or missed ones stay that way, except that a
+// no-show can be checked in or completed when the patient turned up after it was marked. A
+// checked-in patient has arrived, so they can no longer be a no-show.
+var appointmentTransitions = map[model.AppointmentStatus][]model.AppointmentStatus{
+	model.AppointmentStatusPending: {
+		model.AppointmentStatusConfirmed,
+		model.AppointmentStatusCancelled,
+		model.AppointmentStatusNoShow,
+	},
+	model.AppointmentStatusConfirmed: {
+		model.AppointmentStatusCheckedIn,
+		model.AppointmentStatusCompleted,
+		model.AppointmentStatusCancelled,
+		model.AppointmentStatusNoShow,
+	},
+	model.AppointmentStatusCheckedIn: {
+		model.AppointmentStatusCompleted,
+		model.AppointmentStatusCancelled,
+	},
+	model.AppointmentStatusNoShow: {
+		model.AppointmentStatusCheckedIn,
+		model.AppointmentStatusCompleted,
+	},
+}
+
+// canTransitionAppointment reports whether an appointment can move from one status to another
+func canTransitionAppointment(from, to model.AppointmentStatus) bool {
+	for _, next := range appointmentTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// transitionAppointment moves an appointment to a new status at now and returns the event
+// describing the transition
+func transitionAppointment(appointment *model.Appointment, to model.AppointmentStatus, now time.Time) (string, error) {
+	if !canTransitionAppointment(appointment.Status, to) {
+		if _, known := appointmentStatusEvents[to]; !known && to != model.AppointmentStatusPending {
+			return "", fmt.Errorf("invalid appointment status %q", to)
+		}
+		return "", fmt.Errorf("%w from %s to %s", ErrInvalidTransition, appointment.Status, to)
+	}
+
+	appointment.Status = to
+	appointment.UpdatedAt = now
+	switch to {
+	case model.AppointmentStatusCancelled:
+		appointment.CancelledAt = &now
+	case model.AppointmentStatusCheckedIn:
+		appointment.CheckedInAt = &now
+	case model.AppointmentStatusCompleted:
+		// A video visit ends when the doctor ends it, usually before the appointment is completed
+		if appointment.EndedAt == nil {
+			appointment.EndedAt = &now
+		}
+	}
+	return appointmentStatusEvents[to], nil
+}
+
+// appointmentStatusEvents maps each status an appointment can move to onto the event published
+// when it does
+var appointmentStatusEvents = map[model.AppointmentStatus]string{
+	model.AppointmentStatusConfirmed: model.EventAppointmentConfirmed,
+	model.AppointmentStatusCheckedIn: model.EventAppointmentCheckedIn,
+	model.AppointmentStatusCompleted: model.EventAppointmentCompleted,
+	model.AppointmentStatusCancelled: model.EventAppointmentCancelled,
+	model.AppointmentStatusNoShow:    model.EventAppointmentNoShow,
+}
@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/repository"
+	"go.uber.org/zap"
+)
+
+// colorPattern matches hex colors such as #4CAF50
+var colorPattern = regexp.MustCompile(`^#[0-9A-Fa-f]{6}$`)
+
+type appointmentTypeService struct {
+	repo       repository.AppointmentTypeRepository
+	orgRepo    repository.OrganizationRepository
+	orgService OrganizationService
+	logger     *zap.Logger
+}
+
+// NewAppointmentTypeService creates a new appointment type service
+func NewAppointmentTypeService(
+	repo repository.AppointmentTypeRepository,
+	orgRepo repository.OrganizationRepository,
+	orgService OrganizationService,
+	logger *zap.Logger,
+) AppointmentTypeService {
+	return &appointmentTypeService{
+		repo:       repo,
+		orgRepo:    orgRepo,
+		orgService: orgService,
+		logger:     logger,
+	}
+}
+
+// CreateAppointmentType creates a bookable appointment type
+func (s *appointmentTypeService) CreateAppointmentType(ctx context.Context, appointmentType *model.AppointmentType) (*model.AppointmentType, error) {
+	if err := s.validate(ctx, appointmentType); err != nil {
+		return nil, err
+	}
+
+	appointmentType.ID = 0
+	appointmentType.Active = true
+	appointmentType.CreatedAt = time.Now()
+	appointmentType.UpdatedAt = time.Now()
+	if err := s.repo.Create(ctx, appointmentType); err != nil {
+		return nil, fmt.Errorf("failed to create appointment type: %w", err)
+	}
+
+	s.logger.Info("Appointment type created", zap.Uint("appointmentTypeID", appointmentType.ID), zap.String("name", appointmentType.Name))
+	return appointmentType, nil
+}
+
+// GetAppointmentType gets an appointment type by ID
+func (s *appointmentTypeService) GetAppointmentType(ctx context.Context, id uint) (*model.AppointmentType, error) {
+	return s.repo.FindByID(ctx, id)
+}
+
+// ListAppointmentTypes lists all appointment types, including archived ones
+func (s *appointmentTypeService) ListAppointmentTypes(ctx context.Context) ([]*model.AppointmentType, error) {
+	return s.repo.FindAll(ctx)
+}
+
+// ListDoctorAppointmentTypes lists the appointment types that can be booked with a doctor
+func (s *appointmentTypeService) ListDoctorAppointmentTypes(ctx context.Context, doctorID uint) ([]*model.AppointmentType, error) {
+	org, err := s.orgService.GetDoctorOrganization(ctx, doctorID)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.FindActiveByOrganization(ctx, org.ID)
+}
+
+// UpdateAppointmentType replaces the settings of an appointment type. Existing appointments
+// keep the modality and length they were booked with.
+func (s *appointmentTypeService) UpdateAppointmentType(ctx context.Context, id uint, appointmentType *model.AppointmentType) (*model.AppointmentType, error) {
+	existing, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.validate(ctx, appointmentType); err != nil {
+		return nil, err
+	}
+
+	appointmentType.ID = existing.ID
+	appointmentType.Active = existing.Active
+	appointmentType.CreatedAt = existing.CreatedAt
+	appointmentType.UpdatedAt = time.Now()
+	if err := s.repo.Update(ctx, appointmentType); err != nil {
+		return nil, fmt.Errorf("failed to update appointment type: %w", err)
+	}
+
+	return appointmentType, nil
+}
+
+// ArchiveAppointmentType stops an appointment type from being booked while keeping it
+// for the appointments that already use it
+func (s *appointmentTypeService) ArchiveAppointmentType(ctx context.Context, id uint) error {
+	appointmentType, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	appointmentType.Active = false
+	appointmentType.UpdatedAt = time.Now()
+	if err := s.repo.Update(ctx, appointmentType); err != nil {
+		return fmt.Errorf("failed to archive appointment type: %w", err)
+	}
+
+	return nil
+}
+
+// validate checks an appointment type before it is saved
+func (s *appointmentTypeService) validate(ctx context.Context, appointmentType *model.AppointmentType) error {
+	if strings.TrimSpace(appointmentType.Name) == "" {
+		return errors.New("appointment type name is required")
+	}
+	if !appointmentType.Modality.IsValid() {
+		return fmt.Errorf("invalid modality %q", appointmentType.Modality)
+	}
+	if appointmentType.Duration < 5 || appointmentType.Duration > 480 {
+		return errors.New("duration must be between 5 and 480 minutes")
+	}
+	if appointmentType.PriceCents < 0 {
+		return errors.New("price cannot be negative")
+	}
+	if appointmentType.Currency == "" {
+		appointmentType.Currency = "USD"
+	}
+	appointmentType.Currency = strings.ToUpper(appointmentType.Currency)
+	if len(appointmentType.Currency) != 3 {
+		return fmt.Errorf("invalid currency %q", appointmentType.Currency)
+	}
+	if appointmentType.Color != "" && !colorPattern.MatchString(appointmentType.Color) {
+		return fmt.Errorf("invalid color %q, expected #RRGGBB", appointmentType.Color)
+	}
+
+	keys := make(map[string]bool, len(appointmentType.IntakeForm))
+	for _, q := range appointmentType.IntakeForm {
+		if q.Key == "" || q.Label == "" {
+			return errors.New("intake questions need a key and a label")
+		}
+		if keys[q.Key] {
+			return fmt.Errorf("duplicate intake question %q", q.Key)
+		}
+		keys[q.Key] = true
+	}
+
+	if appointmentType.OrganizationID != nil {
+		if _, err := s.orgRepo.FindByID(ctx, *appointmentType.OrganizationID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/repository"
+	"github.com/whitewalker-sa/ehass/pkg/siem"
+	"go.uber.org/zap"
+)
+
+// AuditExporter ships audit log entries to a SIEM sink. Progress is tracked with a
+// persistent cursor that only advances after a batch is accepted, so entries are
+// delivered at least once across restarts and sink outages.
+type AuditExporter struct {
+	auditLogRepo repository.AuditLogRepository
+	cursorRepo   repository.ExportCursorRepository
+	sink         siem.Sink
+	batchSize    int
+	interval     time.Duration
+	maxBackoff   time.Duration
+	monitor      *JobMonitor
+	logger       *zap.Logger
+}
+
+// NewAuditExporter creates a new audit exporter
+func NewAuditExporter(
+	auditLogRepo repository.AuditLogRepository,
+	cursorRepo repository.ExportCursorRepository,
+	sink siem.Sink,
+	batchSize int,
+	interval time.Duration,
+	maxBackoff time.Duration,
+	monitor *JobMonitor,
+	logger *zap.Logger,
+) *AuditExporter {
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	if maxBackoff < interval {
+		maxBackoff = interval
+	}
+
+	e := &AuditExporter{
+		auditLogRepo: auditLogRepo,
+		cursorRepo:   cursorRepo,
+		sink:         sink,
+		batchSize:    batchSize,
+		interval:     interval,
+		maxBackoff:   maxBackoff,
+		monitor:      monitor,
+		logger:       logger,
+	}
+	monitor.Register(JobSIEMExport, interval, func(ctx context.Context) error {
+		_, err := e.ExportBatch(ctx)
+		return err
+	})
+	return e
+}
+
+// Start exports in the background until the returned function is called
+func (e *AuditExporter) Start() func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+		e.run(ctx)
+	}()
+
+	return func() {
+		cancel()
+		wg.Wait()
+	}
+}
+
+// run exports batches as fast as the sink accepts them while there is a backlog,
+// waits for the poll interval once caught up and backs off exponentially on failure
+func (e *AuditExporter) run(ctx context.Context) {
+	backoff := e.interval
+
+	for {
+		var sent int
+		err := e.monitor.Do(ctx, JobSIEMExport, func(ctx context.Context) error {
+			var err error
+			sent, err = e.ExportBatch(ctx)
+			return err
+		})
+
+		wait := e.interval
+		switch {
+		case err != nil:
+			if ctx.Err() != nil {
+				return
+			}
+			e.logger.Warn("SIEM export failed, retrying",
+				zap.String("sink", e.sink.Name()),
+				zap.Duration("backoff", backoff),
+				zap.Error(err),
+			)
+			wait = backoff
+			backoff *= 2
+			if backoff > e.maxBackoff {
+				backoff = e.maxBackoff
+			}
+		case sent == e.batchSize:
+			// More entries are waiting, keep draining
+			backoff = e.interval
+			wait = 0
+		default:
+			backoff = e.interval
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// ExportBatch sends the next batch of audit log entries and returns how many were sent
+func (e *AuditExporter) ExportBatch(ctx context.Context) (int, error) {
+	cursorName := e.cursorName()
+
+	lastID, err := e.cursorRepo.Get(ctx, cursorName)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load export cursor: %w", err)
+	}
+
+	logs, err := e.auditLogRepo.FindAfterID(ctx, lastID, e.batchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load audit logs: %w", err)
+	}
+	if len(logs) == 0 {
+		return 0, nil
+	}
+
+	events := make([]siem.Event, len(logs))
+	for i, log := range logs {
+		events[i] = toSIEMEvent(log)
+	}
+
+	if err := e.sink.Send(ctx, events); err != nil {
+		return 0, fmt.Errorf("failed to send %d events to %s: %w", len(events), e.sink.Name(), err)
+	}
+
+	newLastID := logs[len(logs)-1].ID
+	if err := e.cursorRepo.Save(ctx, cursorName, newLastID); err != nil {
+		return 0, fmt.Errorf("failed to save export cursor: %w", err)
+	}
+
+	if len(logs) == e.batchSize {
+		if lag, err := e.auditLogRepo.CountAfterID(ctx, newLastID); err == nil && lag > 0 {
+			e.logger.Info("SIEM export catching up",
+				zap.String("sink", e.sink.Name()),
+				zap.Int64("pending", lag),
+			)
+		}
+	}
+
+	return len(logs), nil
+}
+
+// Backlog returns the number of audit log entries not yet delivered to the sink
+func (e *AuditExporter) Backlog(ctx context.Context) (int64, error) {
+	lastID, err := e.cursorRepo.Get(ctx, e.cursorName())
+	if err != nil {
+		return 0, fmt.Errorf("failed to load export cursor: %w", err)
+	}
+	return e.auditLogRepo.CountAfterID(ctx, lastID)
+}
+
+// cursorName returns the name of the cursor tracking delivery to the sink
+func (e *AuditExporter) cursorName() string {
+	return "siem:" + e.sink.Name()
+}
+
+// toSIEMEvent converts an audit log entry to a SIEM event
+func toSIEMEvent(log *model.AuditLog) siem.Event {
+	source := "audit"
+	if strings.HasPrefix(log.Action, "auth.") {
+		source = "auth"
+	}
+
+	return siem.Event{
+		ID:         log.ID,
+		Time:       log.CreatedAt.UTC(),
+		Action:     log.Action,
+		UserID:     log.UserID,
+		EntityType: log.EntityType,
+		EntityID:   log.EntityID,
+		IP:         log.IP,
+		UserAgent:  log.UserAgent,
+		Source:     source,
+	}
+}
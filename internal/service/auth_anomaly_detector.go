@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// JobAuthAnomalies is the name of the job checking authentication events for anomalies
+const JobAuthAnomalies = "auth_anomalies"
+
+// AuthAnomalyDetector periodically checks recent authentication events, raising security alerts
+// for failed login and reset patterns such as credential stuffing
+type AuthAnomalyDetector struct {
+	securityService SecurityService
+	interval        time.Duration
+	monitor         *JobMonitor
+	logger          *zap.Logger
+}
+
+// NewAuthAnomalyDetector creates a new authentication anomaly detector running every interval
+func NewAuthAnomalyDetector(securityService SecurityService, interval time.Duration, monitor *JobMonitor, logger *zap.Logger) *AuthAnomalyDetector {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	s := &AuthAnomalyDetector{
+		securityService: securityService,
+		interval:        interval,
+		monitor:         monitor,
+		logger:          logger,
+	}
+	monitor.Register(JobAuthAnomalies, interval, securityService.DetectAuthAnomalies)
+	return s
+}
+
+// Start checks authentication events in the background until the returned function is called
+func (s *AuthAnomalyDetector) Start() func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			if err := s.monitor.Do(ctx, JobAuthAnomalies, s.securityService.DetectAuthAnomalies); err != nil && ctx.Err() == nil {
+				s.logger.Error("Failed to detect authentication anomalies", zap.Error(err))
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		wg.Wait()
+	}
+}
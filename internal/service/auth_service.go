@@ -3,9 +3,11 @@ package service
 import (
 	"context"
 	"crypto/rand"
+	"crypto/subtle"
 	"encoding/base32"
 	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
@@ -17,38 +19,92 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+// Audit actions recorded for authentication events
+const (
+	AuditActionLogin                = "auth.login"
+	AuditActionLoginFailed          = "auth.login_failed"
+	AuditActionLogout               = "auth.logout"
+	AuditActionLogoutAll            = "auth.logout_all"
+	AuditActionRefreshRejected      = "auth.refresh_rejected"
+	AuditActionPasswordResetRequest = "auth.password_reset_requested"
+	AuditActionPasswordReset        = "auth.password_reset"
+	AuditActionTwoFactorEnabled     = "auth.2fa_enabled"
+	AuditActionTwoFactorDisabled    = "auth.2fa_disabled"
+	AuditActionTwoFactorFailed      = "auth.2fa_failed"
+	AuditActionReauthenticated      = "auth.reauthenticated"
+	AuditActionReauthFailed         = "auth.reauthentication_failed"
+	AuditActionEmailChanged         = "auth.email_changed"
+)
+
+// refreshTokenLifetime is how long a refresh token is valid
+const refreshTokenLifetime = 30 * 24 * time.Hour
+
+// sessionTouchInterval limits how often session activity is written to the database
+const sessionTouchInterval = time.Minute
+
 // authService implements the AuthService interface
 type authService struct {
 	authRepo      repository.AuthRepository
+	sessionRepo   repository.SessionRepository
+	auditLogRepo  repository.AuditLogRepository
 	jwtSecret     string
+	prevSecret    string // Previous signing secret, still accepted for verification after rotation
+	secretMu      sync.RWMutex
 	jwtExpiration int
-	emailService  EmailService // Interface for sending emails
-	oauthService  OAuthService // Interface for handling OAuth providers
+	jwtIssuer     string
+	jwtAudience   string
+	idleTimeout   time.Duration // Session ends after this long without requests; 0 disables
+	maxLifetime   time.Duration // Session ends this long after login; 0 disables
+	emailService  EmailService  // Interface for sending emails
+	oauthService  OAuthService  // Interface for handling OAuth providers
+	emailPolicy   *EmailPolicy  // Decides which addresses can sign up
 }
 
 // NewAuthService creates a new auth service
 func NewAuthService(
 	authRepo repository.AuthRepository,
+	sessionRepo repository.SessionRepository,
+	auditLogRepo repository.AuditLogRepository,
 	jwtSecret string,
 	jwtExpiration int,
+	jwtIssuer string,
+	jwtAudience string,
+	idleTimeout time.Duration,
+	maxLifetime time.Duration,
 	emailService EmailService,
 	oauthService OAuthService,
+	emailPolicy *EmailPolicy,
 ) AuthService {
 	return &authService{
 		authRepo:      authRepo,
+		sessionRepo:   sessionRepo,
+		auditLogRepo:  auditLogRepo,
 		jwtSecret:     jwtSecret,
 		jwtExpiration: jwtExpiration,
+		jwtIssuer:     jwtIssuer,
+		jwtAudience:   jwtAudience,
+		idleTimeout:   idleTimeout,
+		maxLifetime:   maxLifetime,
 		emailService:  emailService,
 		oauthService:  oauthService,
+		emailPolicy:   emailPolicy,
 	}
 }
 
 // Register implements the user registration flow
 func (s *authService) Register(ctx context.Context, name, email, password string, role model.Role) (*model.User, error) {
-	// Check if user exists
-	existingUser, err := s.authRepo.FindUserByEmail(ctx, email)
+	email = model.NormalizeEmail(email)
+	if err := s.emailPolicy.Check(email); err != nil {
+		return nil, err
+	}
+
+	// Check if user exists, under this email or an alias of it
+	existingUser, err := s.authRepo.FindUserByCanonicalEmail(ctx, email)
 	if err == nil && existingUser != nil {
-		return nil, errors.New("email already registered")
+		if !existingUser.HasLogin() {
+			return nil, ErrAccountClaimRequired
+		}
+		return nil, ErrEmailRegistered
 	}
 
 	// Hash password
@@ -77,7 +133,7 @@ func (s *authService) Register(ctx context.Context, name, email, password string
 	token := utils.GenerateRandomToken(32)
 	verificationToken := &model.VerificationToken{
 		UserID:    user.ID,
-		Token:     token,
+		TokenHash: utils.HashToken(token),
 		Type:      model.TokenTypeEmailVerification,
 		ExpiresAt: time.Now().Add(24 * time.Hour), // Token valid for 24 hours
 		CreatedAt: time.Now(),
@@ -100,6 +156,7 @@ func (s *authService) Login(ctx context.Context, email, password string) (string
 	// Find user by email
 	user, err := s.authRepo.FindUserByEmail(ctx, email)
 	if err != nil {
+		s.audit(ctx, 0, AuditActionLoginFailed, "unknown email")
 		return "", "", nil, errors.New("invalid email or password")
 	}
 
@@ -111,6 +168,7 @@ func (s *authService) Login(ctx context.Context, email, password string) (string
 	// Verify password
 	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password))
 	if err != nil {
+		s.audit(ctx, user.ID, AuditActionLoginFailed, "invalid password")
 		return "", "", nil, errors.New("invalid email or password")
 	}
 
@@ -119,14 +177,19 @@ func (s *authService) Login(ctx context.Context, email, password string) (string
 		return "", "", nil, errors.New("email not verified, please verify your email first")
 	}
 
-	// Generate tokens
-	accessToken, refreshToken, err := s.generateTokens(user.ID)
+	// Start a session and generate tokens
+	sessionID, err := s.startSession(ctx, user.ID)
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	accessToken, refreshToken, err := s.generateTokens(user, time.Now(), sessionID)
 	if err != nil {
 		return "", "", nil, fmt.Errorf("failed to generate tokens: %w", err)
 	}
 
 	// Update refresh token and last login
-	if err := s.authRepo.UpdateRefreshToken(ctx, user.ID, refreshToken); err != nil {
+	if err := s.authRepo.UpdateRefreshToken(ctx, user.ID, utils.HashToken(refreshToken)); err != nil {
 		return "", "", nil, fmt.Errorf("failed to update refresh token: %w", err)
 	}
 
@@ -139,18 +202,15 @@ func (s *authService) Login(ctx context.Context, email, password string) (string
 		return "", "", user, errors.New("two-factor authentication required")
 	}
 
+	s.audit(ctx, user.ID, AuditActionLogin, string(model.AuthProviderLocal))
 	return accessToken, refreshToken, user, nil
 }
 
 // RefreshToken implements token refresh flow
 func (s *authService) RefreshToken(ctx context.Context, refreshToken string) (string, string, error) {
-	// Find user by refresh token
-	claims := &jwt.StandardClaims{}
-	token, err := jwt.ParseWithClaims(refreshToken, claims, func(token *jwt.Token) (interface{}, error) {
-		return []byte(s.jwtSecret), nil
-	})
-
-	if err != nil || !token.Valid {
+	// Parse refresh token
+	claims, err := s.parseToken(refreshToken)
+	if err != nil {
 		return "", "", errors.New("invalid refresh token")
 	}
 
@@ -160,14 +220,37 @@ func (s *authService) RefreshToken(ctx context.Context, refreshToken string) (st
 		return "", "", errors.New("invalid user ID in token")
 	}
 
+	// Reject refresh tokens issued before the user's sessions were revoked
+	user, err := s.authRepo.FindByID(ctx, userID)
+	if err != nil {
+		return "", "", errors.New("invalid refresh token")
+	}
+	if claims.TokenVersion != user.TokenVersion {
+		s.audit(ctx, user.ID, AuditActionRefreshRejected, "revoked")
+		return "", "", errors.New("refresh token has been revoked")
+	}
+
+	// Only the most recently issued refresh token is accepted; the stored hash is cleared on logout
+	storedHash := []byte(user.RefreshTokenHash)
+	if len(storedHash) == 0 || subtle.ConstantTimeCompare(storedHash, []byte(utils.HashToken(refreshToken))) != 1 {
+		// A superseded refresh token being replayed may indicate token theft
+		s.audit(ctx, user.ID, AuditActionRefreshRejected, "superseded")
+		return "", "", errors.New("invalid refresh token")
+	}
+
+	// Refreshing counts as activity, so an idle or expired session cannot be extended
+	if err := s.checkSession(ctx, claims); err != nil {
+		return "", "", err
+	}
+
 	// Generate new tokens
-	accessToken, newRefreshToken, err := s.generateTokens(userID)
+	accessToken, newRefreshToken, err := s.generateTokens(user, time.Unix(claims.AuthTime, 0), claims.SessionID)
 	if err != nil {
 		return "", "", fmt.Errorf("failed to generate tokens: %w", err)
 	}
 
 	// Update refresh token
-	if err := s.authRepo.UpdateRefreshToken(ctx, userID, newRefreshToken); err != nil {
+	if err := s.authRepo.UpdateRefreshToken(ctx, userID, utils.HashToken(newRefreshToken)); err != nil {
 		return "", "", fmt.Errorf("failed to update refresh token: %w", err)
 	}
 
@@ -177,7 +260,7 @@ func (s *authService) RefreshToken(ctx context.Context, refreshToken string) (st
 // VerifyEmail implements email verification flow
 func (s *authService) VerifyEmail(ctx context.Context, token string) error {
 	// Find verification token
-	verificationToken, err := s.authRepo.FindVerificationToken(ctx, token, model.TokenTypeEmailVerification)
+	verificationToken, err := s.authRepo.FindVerificationToken(ctx, utils.HashToken(token), model.TokenTypeEmailVerification)
 	if err != nil {
 		return errors.New("invalid or expired verification token")
 	}
@@ -201,6 +284,7 @@ func (s *authService) RequestPasswordReset(ctx context.Context, email string) er
 	user, err := s.authRepo.FindUserByEmail(ctx, email)
 	if err != nil {
 		// Don't reveal if user exists
+		s.audit(ctx, 0, AuditActionPasswordResetRequest, "unknown email")
 		return nil
 	}
 
@@ -208,7 +292,7 @@ func (s *authService) RequestPasswordReset(ctx context.Context, email string) er
 	token := utils.GenerateRandomToken(32)
 	resetToken := &model.VerificationToken{
 		UserID:    user.ID,
-		Token:     token,
+		TokenHash: utils.HashToken(token),
 		Type:      model.TokenTypePasswordReset,
 		ExpiresAt: time.Now().Add(1 * time.Hour), // Token valid for 1 hour
 		CreatedAt: time.Now(),
@@ -223,13 +307,14 @@ func (s *authService) RequestPasswordReset(ctx context.Context, email string) er
 		return fmt.Errorf("failed to send password reset email: %w", err)
 	}
 
+	s.audit(ctx, user.ID, AuditActionPasswordResetRequest, "")
 	return nil
 }
 
 // ResetPassword implements password reset flow
 func (s *authService) ResetPassword(ctx context.Context, token, newPassword string) error {
 	// Find reset token
-	resetToken, err := s.authRepo.FindVerificationToken(ctx, token, model.TokenTypePasswordReset)
+	resetToken, err := s.authRepo.FindVerificationToken(ctx, utils.HashToken(token), model.TokenTypePasswordReset)
 	if err != nil {
 		return errors.New("invalid or expired reset token")
 	}
@@ -258,6 +343,7 @@ func (s *authService) ResetPassword(ctx context.Context, token, newPassword stri
 		return fmt.Errorf("failed to delete reset token: %w", err)
 	}
 
+	s.audit(ctx, user.ID, AuditActionPasswordReset, "")
 	return nil
 }
 
@@ -274,7 +360,7 @@ func (s *authService) OAuthLogin(ctx context.Context, provider model.AuthProvide
 
 	// If user doesn't exist, check if email exists
 	if err != nil {
-		existingUser, err := s.authRepo.FindUserByEmail(ctx, oauthUser.Email)
+		existingUser, err := s.authRepo.FindUserByCanonicalEmail(ctx, oauthUser.Email)
 		if err == nil && existingUser != nil {
 			// Link OAuth account to existing user
 			if err := s.authRepo.LinkUserToProvider(ctx, existingUser.ID, provider, oauthUser.ID); err != nil {
@@ -283,9 +369,12 @@ func (s *authService) OAuthLogin(ctx context.Context, provider model.AuthProvide
 			user = existingUser
 		} else {
 			// Create new user with OAuth provider
+			if err := s.emailPolicy.Check(oauthUser.Email); err != nil {
+				return "", "", nil, err
+			}
 			user = &model.User{
 				Name:          oauthUser.Name,
-				Email:         oauthUser.Email,
+				Email:         model.NormalizeEmail(oauthUser.Email),
 				Provider:      provider,
 				ProviderID:    oauthUser.ID,
 				Role:          model.RolePatient, // Default role
@@ -300,14 +389,19 @@ func (s *authService) OAuthLogin(ctx context.Context, provider model.AuthProvide
 		}
 	}
 
-	// Generate tokens
-	accessToken, refreshToken, err := s.generateTokens(user.ID)
+	// Start a session and generate tokens
+	sessionID, err := s.startSession(ctx, user.ID)
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	accessToken, refreshToken, err := s.generateTokens(user, time.Now(), sessionID)
 	if err != nil {
 		return "", "", nil, fmt.Errorf("failed to generate tokens: %w", err)
 	}
 
 	// Update refresh token and last login
-	if err := s.authRepo.UpdateRefreshToken(ctx, user.ID, refreshToken); err != nil {
+	if err := s.authRepo.UpdateRefreshToken(ctx, user.ID, utils.HashToken(refreshToken)); err != nil {
 		return "", "", nil, fmt.Errorf("failed to update refresh token: %w", err)
 	}
 
@@ -320,6 +414,7 @@ func (s *authService) OAuthLogin(ctx context.Context, provider model.AuthProvide
 		return "", "", user, errors.New("two-factor authentication required")
 	}
 
+	s.audit(ctx, user.ID, AuditActionLogin, string(provider))
 	return accessToken, refreshToken, user, nil
 }
 
@@ -379,6 +474,9 @@ func (s *authService) Verify2FA(ctx context.Context, userID uint, token string)
 
 	// Verify token
 	valid := totp.Validate(token, user.Secret2FA)
+	if !valid {
+		s.audit(ctx, userID, AuditActionTwoFactorFailed, "invalid 2FA token")
+	}
 	return valid, nil
 }
 
@@ -395,6 +493,7 @@ func (s *authService) Enable2FA(ctx context.Context, userID uint, secret, token
 		return fmt.Errorf("failed to enable 2FA: %w", err)
 	}
 
+	s.audit(ctx, userID, AuditActionTwoFactorEnabled, "")
 	return nil
 }
 
@@ -417,16 +516,60 @@ func (s *authService) Disable2FA(ctx context.Context, userID uint, password stri
 		return fmt.Errorf("failed to disable 2FA: %w", err)
 	}
 
+	s.audit(ctx, userID, AuditActionTwoFactorDisabled, "")
+	return nil
+}
+
+// ChangeEmail moves a user's login to a new email address. The address must be verified again,
+// so a verification email is sent to it; callers guard this behind step-up authentication.
+func (s *authService) ChangeEmail(ctx context.Context, userID uint, email string) error {
+	email = model.NormalizeEmail(email)
+	if err := s.emailPolicy.Check(email); err != nil {
+		return err
+	}
+
+	user, err := s.authRepo.FindByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to find user: %w", err)
+	}
+	if existingUser, err := s.authRepo.FindUserByCanonicalEmail(ctx, email); err == nil && existingUser != nil && existingUser.ID != userID {
+		return ErrEmailRegistered
+	}
+
+	previous := user.Email
+	user.Email = email
+	user.EmailVerified = false
+	if err := s.authRepo.UpdateUser(ctx, user, searchSyncEvent(model.EventUserUpdated, user.PublicID)); err != nil {
+		return fmt.Errorf("failed to change email: %w", err)
+	}
+
+	// Links sent to the old address no longer verify the account
+	if err := s.authRepo.DeleteUserTokens(ctx, userID, model.TokenTypeEmailVerification); err != nil {
+		return fmt.Errorf("failed to delete verification tokens: %w", err)
+	}
+	token := utils.GenerateRandomToken(32)
+	verificationToken := &model.VerificationToken{
+		UserID:    user.ID,
+		TokenHash: utils.HashToken(token),
+		Type:      model.TokenTypeEmailVerification,
+		ExpiresAt: time.Now().Add(24 * time.Hour),
+		CreatedAt: time.Now(),
+	}
+	if err := s.authRepo.CreateVerificationToken(ctx, verificationToken); err != nil {
+		return fmt.Errorf("failed to create verification token: %w", err)
+	}
+	if err := s.emailService.SendVerificationEmail(ctx, user.Email, user.Name, token); err != nil {
+		return fmt.Errorf("failed to send verification email: %w", err)
+	}
+
+	s.audit(ctx, userID, AuditActionEmailChanged, "from "+previous)
 	return nil
 }
 
 // Logout implements logout flow
 func (s *authService) Logout(ctx context.Context, token string) error {
 	// Parse token
-	claims := &jwt.StandardClaims{}
-	_, err := jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
-		return []byte(s.jwtSecret), nil
-	})
+	claims, err := s.parseToken(token)
 	if err != nil {
 		return errors.New("invalid token")
 	}
@@ -442,16 +585,94 @@ func (s *authService) Logout(ctx context.Context, token string) error {
 		return fmt.Errorf("failed to clear refresh token: %w", err)
 	}
 
+	// End the session
+	if claims.SessionID != "" {
+		if err := s.sessionRepo.DeleteByToken(ctx, utils.HashToken(claims.SessionID)); err != nil {
+			return fmt.Errorf("failed to end session: %w", err)
+		}
+	}
+
+	s.audit(ctx, userID, AuditActionLogout, "")
+	return nil
+}
+
+// Reauthenticate confirms the user's identity with their password or a 2FA code and
+// issues new tokens carrying a fresh authentication time for sensitive operations. The tokens
+// stay in the session of token, the access token the user is signed in with, so signing out
+// still ends it and no second session is left behind.
+func (s *authService) Reauthenticate(ctx context.Context, userID uint, token, password, code string) (string, string, error) {
+	claims, err := s.parseToken(token)
+	if err != nil || claims.Subject != fmt.Sprintf("%d", userID) || claims.SessionID == "" {
+		return "", "", errors.New("invalid token")
+	}
+
+	user, err := s.authRepo.FindByID(ctx, userID)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to find user: %w", err)
+	}
+
+	switch {
+	case password != "":
+		if user.PasswordHash == "" {
+			return "", "", fmt.Errorf("please re-authenticate with %s", user.Provider)
+		}
+		if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+			s.audit(ctx, user.ID, AuditActionReauthFailed, "invalid password")
+			return "", "", errors.New("invalid password")
+		}
+	case code != "":
+		if !user.TwoFactorAuth {
+			return "", "", errors.New("two-factor authentication is not enabled")
+		}
+		if !totp.Validate(code, user.Secret2FA) {
+			s.audit(ctx, user.ID, AuditActionReauthFailed, "invalid 2FA token")
+			return "", "", errors.New("invalid 2FA token")
+		}
+	default:
+		return "", "", errors.New("password or 2FA token required")
+	}
+
+	accessToken, refreshToken, err := s.generateTokens(user, time.Now(), claims.SessionID)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate tokens: %w", err)
+	}
+
+	if err := s.authRepo.UpdateRefreshToken(ctx, user.ID, utils.HashToken(refreshToken)); err != nil {
+		return "", "", fmt.Errorf("failed to update refresh token: %w", err)
+	}
+
+	s.audit(ctx, user.ID, AuditActionReauthenticated, "")
+	return accessToken, refreshToken, nil
+}
+
+// AuthTime returns when the user last actively authenticated for the given token
+func (s *authService) AuthTime(ctx context.Context, token string) (time.Time, error) {
+	claims, err := s.parseToken(token)
+	if err != nil {
+		return time.Time{}, errors.New("invalid token")
+	}
+
+	if claims.AuthTime == 0 {
+		return time.Time{}, errors.New("token has no authentication time")
+	}
+
+	return time.Unix(claims.AuthTime, 0), nil
+}
+
+// LogoutAll revokes every refresh token and session of the user
+func (s *authService) LogoutAll(ctx context.Context, userID uint) error {
+	if err := s.authRepo.RevokeAllSessions(ctx, userID); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	s.audit(ctx, userID, AuditActionLogoutAll, "")
 	return nil
 }
 
 // ValidateToken implements token validation
 func (s *authService) ValidateToken(ctx context.Context, token string) (*model.User, error) {
 	// Parse token
-	claims := &jwt.StandardClaims{}
-	_, err := jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
-		return []byte(s.jwtSecret), nil
-	})
+	claims, err := s.parseToken(token)
 	if err != nil {
 		return nil, errors.New("invalid token")
 	}
@@ -468,33 +689,223 @@ func (s *authService) ValidateToken(ctx context.Context, token string) (*model.U
 		return nil, fmt.Errorf("failed to find user: %w", err)
 	}
 
+	// Reject tokens issued before the user logged out from all devices
+	if claims.TokenVersion != user.TokenVersion {
+		return nil, errors.New("token has been revoked")
+	}
+
+	// Enforce the session idle and absolute timeouts
+	if err := s.checkSession(ctx, claims); err != nil {
+		return nil, err
+	}
+
 	return user, nil
 }
 
-// generateTokens generates access and refresh tokens
-func (s *authService) generateTokens(userID uint) (string, string, error) {
+// Introspect reports whether a token is active and returns its claims, with the user's public ID
+// as the subject rather than the internal ID the token carries. Invalid, expired and revoked
+// tokens, and tokens of deleted users, are reported as inactive.
+func (s *authService) Introspect(ctx context.Context, token string) *TokenIntrospection {
+	claims, err := s.parseToken(token)
+	if err != nil {
+		return &TokenIntrospection{}
+	}
+
+	userID, err := utils.StringToUint(claims.Subject)
+	if err != nil {
+		return &TokenIntrospection{}
+	}
+
+	user, err := s.authRepo.FindByID(ctx, userID)
+	if err != nil || claims.TokenVersion != user.TokenVersion {
+		return &TokenIntrospection{}
+	}
+
+	if err := s.checkSession(ctx, claims); err != nil {
+		return &TokenIntrospection{}
+	}
+
+	introspection := &TokenIntrospection{
+		Active:    true,
+		Subject:   user.PublicID,
+		Email:     user.Email,
+		Role:      user.Role,
+		Issuer:    claims.Issuer,
+		Audience:  claims.Audience,
+		IssuedAt:  time.Unix(claims.IssuedAt, 0),
+		ExpiresAt: time.Unix(claims.ExpiresAt, 0),
+	}
+	if claims.AuthTime != 0 {
+		introspection.AuthTime = time.Unix(claims.AuthTime, 0)
+	}
+
+	return introspection
+}
+
+// tokenClaims are the claims carried by access and refresh tokens
+type tokenClaims struct {
+	jwt.StandardClaims
+	TokenVersion int    `json:"ver"`
+	AuthTime     int64  `json:"auth_time,omitempty"` // When the user last entered credentials
+	SessionID    string `json:"sid,omitempty"`       // Server-side session the token belongs to
+}
+
+// RotateSigningSecret switches token signing to a new secret while still accepting tokens signed with the old one
+func (s *authService) RotateSigningSecret(secret string) {
+	s.secretMu.Lock()
+	defer s.secretMu.Unlock()
+
+	if secret == "" || secret == s.jwtSecret {
+		return
+	}
+	s.prevSecret = s.jwtSecret
+	s.jwtSecret = secret
+}
+
+// signingSecrets returns the current and previous token signing secrets
+func (s *authService) signingSecrets() (string, string) {
+	s.secretMu.RLock()
+	defer s.secretMu.RUnlock()
+	return s.jwtSecret, s.prevSecret
+}
+
+// parseToken parses a signed token and verifies its signature, expiry, issuer and audience
+func (s *authService) parseToken(tokenString string) (*tokenClaims, error) {
+	current, previous := s.signingSecrets()
+
+	claims := &tokenClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		// Validate signing method
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+		return []byte(current), nil
+	})
+	if err != nil && previous != "" {
+		// Tokens issued before the last secret rotation remain valid until they expire
+		claims = &tokenClaims{}
+		token, err = jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, errors.New("unexpected signing method")
+			}
+			return []byte(previous), nil
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+
+	// Reject tokens minted for other environments or services
+	if !claims.VerifyIssuer(s.jwtIssuer, true) {
+		return nil, errors.New("invalid token issuer")
+	}
+	if !claims.VerifyAudience(s.jwtAudience, true) {
+		return nil, errors.New("invalid token audience")
+	}
+
+	return claims, nil
+}
+
+// startSession records a new server-side session for the user and returns its ID
+func (s *authService) startSession(ctx context.Context, userID uint) (string, error) {
+	now := time.Now()
+	lifetime := s.maxLifetime
+	if lifetime <= 0 {
+		lifetime = refreshTokenLifetime
+	}
+
+	sessionID := utils.GenerateRandomToken(32)
+	session := &model.Session{
+		UserID:         userID,
+		TokenHash:      utils.HashToken(sessionID),
+		ExpiresAt:      now.Add(lifetime),
+		LastActivityAt: now,
+	}
+	if err := s.sessionRepo.Create(ctx, session); err != nil {
+		return "", fmt.Errorf("failed to create session: %w", err)
+	}
+
+	return sessionID, nil
+}
+
+// checkSession verifies that the session a token belongs to has not ended, timed out
+// through inactivity or exceeded its absolute lifetime, and records the activity
+func (s *authService) checkSession(ctx context.Context, claims *tokenClaims) error {
+	if claims.SessionID == "" {
+		return errors.New("token has no session")
+	}
+
+	tokenHash := utils.HashToken(claims.SessionID)
+	session, err := s.sessionRepo.FindByToken(ctx, tokenHash)
+	if err != nil {
+		return errors.New("session has ended")
+	}
+	if fmt.Sprintf("%d", session.UserID) != claims.Subject {
+		return errors.New("session does not belong to token subject")
+	}
+
+	now := time.Now()
+	if now.After(session.ExpiresAt) {
+		_ = s.sessionRepo.DeleteByToken(ctx, tokenHash)
+		return errors.New("session has expired")
+	}
+	if s.idleTimeout > 0 && now.Sub(session.LastActivityAt) > s.idleTimeout {
+		_ = s.sessionRepo.DeleteByToken(ctx, tokenHash)
+		return errors.New("session timed out due to inactivity")
+	}
+
+	if now.Sub(session.LastActivityAt) > sessionTouchInterval {
+		if err := s.sessionRepo.Touch(ctx, session.ID, now); err != nil {
+			return fmt.Errorf("failed to record session activity: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// generateTokens generates access and refresh tokens carrying the time the user last authenticated
+func (s *authService) generateTokens(user *model.User, authTime time.Time, sessionID string) (string, string, error) {
 	// Generate access token
-	accessTokenClaims := jwt.StandardClaims{
-		Subject:   fmt.Sprintf("%d", userID),
-		ExpiresAt: time.Now().Add(time.Duration(s.jwtExpiration) * time.Minute).Unix(),
-		IssuedAt:  time.Now().Unix(),
+	accessTokenClaims := tokenClaims{
+		StandardClaims: jwt.StandardClaims{
+			Subject:   fmt.Sprintf("%d", user.ID),
+			Issuer:    s.jwtIssuer,
+			Audience:  s.jwtAudience,
+			ExpiresAt: time.Now().Add(time.Duration(s.jwtExpiration) * time.Minute).Unix(),
+			IssuedAt:  time.Now().Unix(),
+		},
+		TokenVersion: user.TokenVersion,
+		AuthTime:     authTime.Unix(),
+		SessionID:    sessionID,
 	}
 
 	accessToken := jwt.NewWithClaims(jwt.SigningMethodHS256, accessTokenClaims)
-	accessTokenString, err := accessToken.SignedString([]byte(s.jwtSecret))
+	secret, _ := s.signingSecrets()
+	accessTokenString, err := accessToken.SignedString([]byte(secret))
 	if err != nil {
 		return "", "", err
 	}
 
 	// Generate refresh token
-	refreshTokenClaims := jwt.StandardClaims{
-		Subject:   fmt.Sprintf("%d", userID),
-		ExpiresAt: time.Now().Add(30 * 24 * time.Hour).Unix(), // 30 days
-		IssuedAt:  time.Now().Unix(),
+	refreshTokenClaims := tokenClaims{
+		StandardClaims: jwt.StandardClaims{
+			Subject:   fmt.Sprintf("%d", user.ID),
+			Issuer:    s.jwtIssuer,
+			Audience:  s.jwtAudience,
+			ExpiresAt: time.Now().Add(refreshTokenLifetime).Unix(),
+			IssuedAt:  time.Now().Unix(),
+		},
+		TokenVersion: user.TokenVersion,
+		AuthTime:     authTime.Unix(),
+		SessionID:    sessionID,
 	}
 
 	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshTokenClaims)
-	refreshTokenString, err := refreshToken.SignedString([]byte(s.jwtSecret))
+	refreshTokenString, err := refreshToken.SignedString([]byte(secret))
 	if err != nil {
 		return "", "", err
 	}
@@ -522,13 +933,57 @@ type OAuthUserInfo struct {
 	Avatar string
 }
 
+// TokenIntrospection describes the state of a token for other services
+type TokenIntrospection struct {
+	Active    bool
+	Subject   string
+	Email     string
+	Role      model.Role
+	Issuer    string
+	Audience  string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+	AuthTime  time.Time
+}
+
 // EmailService defines operations for sending emails
 type EmailService interface {
 	SendVerificationEmail(ctx context.Context, email, name, token string) error
 	SendPasswordResetEmail(ctx context.Context, email, name, token string) error
+	SendBreakGlassAlert(ctx context.Context, email, name, clinicianName, patientName, reason, expiresAt string) error
+	SendAppointmentReminder(ctx context.Context, email, name, doctorName, startsAt string, directions *Directions) (string, error)
+	SendAppointmentConfirmation(ctx context.Context, email, name, doctorName, startsAt string, attachments ...EmailAttachment) error
+	SendAppointmentRescheduled(ctx context.Context, email, name, doctorName, startsAt string, attachments ...EmailAttachment) error
+	SendDoctorAppointmentRescheduled(ctx context.Context, email, name, patientName, previousStart, startsAt string) error
+	SendAppointmentCancellation(ctx context.Context, email, name, doctorName, startsAt string, attachments ...EmailAttachment) error
+	SendAppointmentConfirmed(ctx context.Context, email, name, doctorName, startsAt string, attachments ...EmailAttachment) error
+	SendAppointmentDeclined(ctx context.Context, email, name, doctorName, startsAt, reason string) error
+	SendAccountClaimInvite(ctx context.Context, email, name, code, setupToken string, validDays int) error
+	SendCareReminder(ctx context.Context, email, name, careName, dueOn string) error
+	SendMarketingEmail(ctx context.Context, email, name, subject, message string) (string, error)
 }
 
 // OAuthService defines operations for OAuth providers
 type OAuthService interface {
 	GetUserInfo(ctx context.Context, provider model.AuthProvider, token string) (*OAuthUserInfo, error)
 }
+
+// audit records an authentication event for the user. Failures are ignored so that
+// a logging outage never blocks sign-in
+func (s *authService) audit(ctx context.Context, userID uint, action, detail string) {
+	if s.auditLogRepo == nil {
+		return
+	}
+
+	client := utils.ClientInfoFromContext(ctx)
+	_ = s.auditLogRepo.Create(ctx, &model.AuditLog{
+		UserID:     userID,
+		Action:     action,
+		EntityID:   userID,
+		EntityType: "user",
+		NewValue:   detail,
+		IP:         client.IP,
+		UserAgent:  client.UserAgent,
+		CreatedAt:  time.Now(),
+	})
+}
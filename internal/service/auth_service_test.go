@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// newTestAuthService creates an auth service that only signs and parses tokens
+func newTestAuthService(secret, issuer, audience string) *authService {
+	return NewAuthService(nil, nil, nil, secret, 15, issuer, audience, 0, 0, nil, nil, nil).(*authService)
+}
+
+func TestParseTokenChecksIssuerAndAudience(t *testing.T) {
+	production := newTestAuthService("shared", "ehass", "ehass-api")
+	tests := []struct {
+		name     string
+		issuer   *authService
+		accepted bool
+	}{
+		{"same issuer and audience", newTestAuthService("shared", "ehass", "ehass-api"), true},
+		{"other issuer", newTestAuthService("shared", "ehass-staging", "ehass-api"), false},
+		{"other audience", newTestAuthService("shared", "ehass", "partner-api"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			accessToken, refreshToken, err := tt.issuer.generateTokens(&model.User{ID: 1}, time.Now(), "session")
+			if err != nil {
+				t.Fatalf("generateTokens: %v", err)
+			}
+			// The secret is shared, so only the issuer and audience tell the tokens apart
+			for _, token := range []string{accessToken, refreshToken} {
+				claims, err := production.parseToken(token)
+				if tt.accepted && (err != nil || claims.Subject != "1") {
+					t.Errorf("token rejected: %v", err)
+				}
+				if !tt.accepted && err == nil {
+					t.Error("token of another issuer or audience accepted")
+				}
+			}
+		})
+	}
+}
+
+func TestRotateSigningSecretKeepsPreviousTokensValid(t *testing.T) {
+	s := newTestAuthService("first", "ehass", "ehass-api")
+	user := &model.User{ID: 1}
+
+	accessToken, _, err := s.generateTokens(user, time.Now(), "session")
+	if err != nil {
+		t.Fatalf("generateTokens: %v", err)
+	}
+
+	s.RotateSigningSecret("")
+	s.RotateSigningSecret("second")
+	s.RotateSigningSecret("second")
+	if _, err := s.parseToken(accessToken); err != nil {
+		t.Fatalf("token from before the rotation rejected: %v", err)
+	}
+
+	newToken, _, err := s.generateTokens(user, time.Now(), "session")
+	if err != nil {
+		t.Fatalf("generateTokens: %v", err)
+	}
+	s.RotateSigningSecret("third")
+	if _, err := s.parseToken(newToken); err != nil {
+		t.Errorf("token signed with the previous secret rejected: %v", err)
+	}
+	if _, err := s.parseToken(accessToken); err == nil {
+		t.Error("token signed two rotations ago still accepted")
+	}
+}
+
+func TestReauthenticateKeepsTheSession(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := &fakeAuthRepo{users: map[uint]*model.User{1: {ID: 1, PasswordHash: string(hash)}}}
+	s := NewAuthService(repo, nil, nil, "secret", 15, "ehass", "ehass-api", 0, 0, nil, nil, nil).(*authService)
+
+	signedIn := time.Now().Add(-time.Hour)
+	token, _, err := s.generateTokens(repo.users[1], signedIn, "session-1")
+	if err != nil {
+		t.Fatalf("generateTokens: %v", err)
+	}
+
+	if _, _, err := s.Reauthenticate(context.Background(), 1, token, "wrong", ""); err == nil {
+		t.Error("wrong password accepted")
+	}
+	if _, _, err := s.Reauthenticate(context.Background(), 2, token, "correct horse", ""); err == nil {
+		t.Error("token of another user accepted")
+	}
+
+	accessToken, _, err := s.Reauthenticate(context.Background(), 1, token, "correct horse", "")
+	if err != nil {
+		t.Fatalf("Reauthenticate: %v", err)
+	}
+	claims, err := s.parseToken(accessToken)
+	if err != nil {
+		t.Fatalf("parseToken: %v", err)
+	}
+	if claims.SessionID != "session-1" {
+		t.Errorf("got session %q, want the session signed in with", claims.SessionID)
+	}
+	if !time.Unix(claims.AuthTime, 0).After(signedIn) {
+		t.Errorf("auth_time %d not refreshed", claims.AuthTime)
+	}
+}
@@ -0,0 +1,600 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/repository"
+	"github.com/whitewalker-sa/ehass/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// ErrAvailabilityConflict is returned when a change would leave booked appointments outside the
+// doctor's availability and was not confirmed
+var ErrAvailabilityConflict = errors.New("booked appointments fall outside the new availability")
+
+// ErrAvailabilityOverlap is returned when a window overlaps another of the doctor's windows on the
+// same day
+var ErrAvailabilityOverlap = errors.New("availability overlaps another window on the same day")
+
+type availabilityService struct {
+	availabilityRepo repository.AvailabilityRepository
+	templateRepo     repository.ShiftTemplateRepository
+	appointmentRepo  repository.AppointmentRepository
+	doctorRepo       repository.DoctorRepository
+	orgService       OrganizationService
+	slotCache        *SlotCache
+	logger           *zap.Logger
+}
+
+// NewAvailabilityService creates a new availability service
+func NewAvailabilityService(
+	availabilityRepo repository.AvailabilityRepository,
+	templateRepo repository.ShiftTemplateRepository,
+	appointmentRepo repository.AppointmentRepository,
+	doctorRepo repository.DoctorRepository,
+	orgService OrganizationService,
+	slotCache *SlotCache,
+	logger *zap.Logger,
+) AvailabilityService {
+	return &availabilityService{
+		availabilityRepo: availabilityRepo,
+		templateRepo:     templateRepo,
+		appointmentRepo:  appointmentRepo,
+		doctorRepo:       doctorRepo,
+		orgService:       orgService,
+		slotCache:        slotCache,
+		logger:           logger,
+	}
+}
+
+// AddAvailability adds a weekly availability window. day is a weekday name or 0-6 for
+// Sunday-Saturday; times are HH:MM in the doctor's clinic timezone. duration is the length of
+// the slots offered in the window in minutes, or 0 for the doctor's consultation length.
+func (s *availabilityService) AddAvailability(ctx context.Context, doctorID uint, day string, startTime, endTime string, duration int) (*model.Availability, error) {
+	availability := &model.Availability{DoctorID: doctorID}
+	if err := setAvailabilityWindow(availability, day, startTime, endTime); err != nil {
+		return nil, err
+	}
+
+	current, err := s.availabilityRepo.FindByDoctorID(ctx, doctorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get availability: %w", err)
+	}
+	if err := checkAvailabilityOverlap(current, availability); err != nil {
+		return nil, err
+	}
+
+	if duration == 0 {
+		org, err := s.orgService.GetDoctorOrganization(ctx, doctorID)
+		if err != nil {
+			return nil, err
+		}
+		doctor, err := s.doctorRepo.FindByID(ctx, doctorID)
+		if err != nil {
+			return nil, err
+		}
+		duration = int(doctor.AppointmentLength(org) / time.Minute)
+	}
+	if duration < 5 || duration > 480 {
+		return nil, errors.New("slot duration must be between 5 and 480 minutes")
+	}
+
+	availability.Duration = duration
+	availability.CreatedAt = time.Now()
+	availability.UpdatedAt = time.Now()
+	if err := s.availabilityRepo.Create(ctx, availability); err != nil {
+		return nil, fmt.Errorf("failed to add availability: %w", err)
+	}
+	s.slotCache.Invalidate(doctorID)
+	return availability, nil
+}
+
+// GetDoctorAvailability gets a doctor's weekly availability windows
+func (s *availabilityService) GetDoctorAvailability(ctx context.Context, doctorID uint) ([]*model.Availability, error) {
+	return s.availabilityRepo.FindByDoctorID(ctx, doctorID)
+}
+
+// UpdateAvailability changes an availability window. Upcoming appointments that the change
+// leaves outside the doctor's availability are returned; unless confirm is set, the change is
+// then not applied and ErrAvailabilityConflict is returned. A duration of 0 keeps the window's
+// slot duration.
+func (s *availabilityService) UpdateAvailability(ctx context.Context, doctorID, id uint, day string, startTime, endTime string, duration int, confirm bool) (*model.Availability, []*model.Appointment, error) {
+	current, err := s.availabilityRepo.FindByDoctorID(ctx, doctorID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get availability: %w", err)
+	}
+
+	var availability *model.Availability
+	updated := make([]*model.Availability, 0, len(current))
+	for _, window := range current {
+		if window.ID == id {
+			changed := *window
+			availability = &changed
+			window = &changed
+		}
+		updated = append(updated, window)
+	}
+	if availability == nil {
+		return nil, nil, errors.New("availability not found")
+	}
+	if err := setAvailabilityWindow(availability, day, startTime, endTime); err != nil {
+		return nil, nil, err
+	}
+	if err := checkAvailabilityOverlap(current, availability); err != nil {
+		return nil, nil, err
+	}
+	if duration != 0 {
+		if duration < 5 || duration > 480 {
+			return nil, nil, errors.New("slot duration must be between 5 and 480 minutes")
+		}
+		availability.Duration = duration
+	}
+
+	orphaned, err := s.orphanedAppointments(ctx, doctorID, current, updated)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(orphaned) > 0 && !confirm {
+		return nil, orphaned, ErrAvailabilityConflict
+	}
+
+	availability.UpdatedAt = time.Now()
+	if err := s.availabilityRepo.Update(ctx, availability); err != nil {
+		return nil, nil, fmt.Errorf("failed to update availability: %w", err)
+	}
+	s.slotCache.Invalidate(doctorID)
+	s.logOrphaned(doctorID, orphaned)
+	return availability, orphaned, nil
+}
+
+// RemoveAvailability removes an availability window. Upcoming appointments that the removal
+// leaves outside the doctor's availability are returned; unless confirm is set, the window is
+// then kept and ErrAvailabilityConflict is returned.
+func (s *availabilityService) RemoveAvailability(ctx context.Context, doctorID, id uint, confirm bool) ([]*model.Appointment, error) {
+	current, err := s.availabilityRepo.FindByDoctorID(ctx, doctorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get availability: %w", err)
+	}
+
+	found := false
+	remaining := make([]*model.Availability, 0, len(current))
+	for _, window := range current {
+		if window.ID == id {
+			found = true
+			continue
+		}
+		remaining = append(remaining, window)
+	}
+	if !found {
+		return nil, errors.New("availability not found")
+	}
+
+	orphaned, err := s.orphanedAppointments(ctx, doctorID, current, remaining)
+	if err != nil {
+		return nil, err
+	}
+	if len(orphaned) > 0 && !confirm {
+		return orphaned, ErrAvailabilityConflict
+	}
+
+	if err := s.availabilityRepo.Delete(ctx, id); err != nil {
+		return nil, fmt.Errorf("failed to remove availability: %w", err)
+	}
+	s.slotCache.Invalidate(doctorID)
+	s.logOrphaned(doctorID, orphaned)
+	return orphaned, nil
+}
+
+// ApplyShiftTemplate adds the windows of a shift template to a doctor's availability for the
+// dates from until, both YYYY-MM-DD and inclusive. With replace, the doctor's other windows stop
+// applying on those dates: windows within the range are removed and those reaching past it are
+// cut back to the dates outside it. Without it, the template's windows must not overlap the
+// doctor's windows on any of the dates. Upcoming appointments left outside the doctor's
+// availability are returned; unless confirm is set, nothing is then changed and
+// ErrAvailabilityConflict is returned.
+func (s *availabilityService) ApplyShiftTemplate(ctx context.Context, doctorID, templateID uint, from, until string, replace, confirm bool) ([]*model.Availability, []*model.Appointment, error) {
+	first, err := time.Parse("2006-01-02", from)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid from date %q, expected YYYY-MM-DD", from)
+	}
+	last, err := time.Parse("2006-01-02", until)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid until date %q, expected YYYY-MM-DD", until)
+	}
+	if last.Before(first) {
+		return nil, nil, errors.New("until must not be before from")
+	}
+
+	template, err := s.templateRepo.FindByID(ctx, templateID)
+	if err != nil {
+		return nil, nil, err
+	}
+	current, err := s.availabilityRepo.FindByDoctorID(ctx, doctorID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get availability: %w", err)
+	}
+
+	now := time.Now()
+	var removeIDs []uint
+	var create []*model.Availability
+	after := make([]*model.Availability, 0, len(current)+len(template.Windows))
+	dates := &model.Availability{ValidFrom: &first, ValidUntil: &last}
+	for _, window := range current {
+		if !replace || !validDatesOverlap(window, dates) {
+			after = append(after, window)
+			continue
+		}
+		// Keep the parts of the window before and after the range
+		removeIDs = append(removeIDs, window.ID)
+		if window.ValidFrom == nil || window.ValidFrom.Before(first) {
+			before := cutWindow(window, now)
+			dayBefore := first.AddDate(0, 0, -1)
+			before.ValidUntil = &dayBefore
+			create = append(create, before)
+			after = append(after, before)
+		}
+		if window.ValidUntil == nil || window.ValidUntil.After(last) {
+			later := cutWindow(window, now)
+			dayAfter := last.AddDate(0, 0, 1)
+			later.ValidFrom = &dayAfter
+			create = append(create, later)
+			after = append(after, later)
+		}
+	}
+
+	var consultation int
+	added := make([]*model.Availability, 0, len(template.Windows))
+	for _, shift := range template.Windows {
+		duration := shift.Duration
+		if duration == 0 {
+			if consultation == 0 {
+				org, err := s.orgService.GetDoctorOrganization(ctx, doctorID)
+				if err != nil {
+					return nil, nil, err
+				}
+				doctor, err := s.doctorRepo.FindByID(ctx, doctorID)
+				if err != nil {
+					return nil, nil, err
+				}
+				consultation = int(doctor.AppointmentLength(org) / time.Minute)
+			}
+			duration = consultation
+		}
+		validFrom, validUntil := first, last
+		window := &model.Availability{
+			DoctorID:        doctorID,
+			DayOfWeek:       shift.DayOfWeek,
+			StartTime:       shift.StartTime,
+			EndTime:         shift.EndTime,
+			Duration:        duration,
+			ValidFrom:       &validFrom,
+			ValidUntil:      &validUntil,
+			ShiftTemplateID: &template.ID,
+			CreatedAt:       now,
+			UpdatedAt:       now,
+		}
+		if err := checkAvailabilityOverlap(after, window); err != nil {
+			return nil, nil, err
+		}
+		added = append(added, window)
+	}
+	create = append(create, added...)
+	after = append(after, added...)
+
+	orphaned, err := s.orphanedAppointments(ctx, doctorID, current, after)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(orphaned) > 0 && !confirm {
+		return nil, orphaned, ErrAvailabilityConflict
+	}
+
+	if err := s.availabilityRepo.ReplaceWindows(ctx, removeIDs, create); err != nil {
+		return nil, nil, fmt.Errorf("failed to apply shift template: %w", err)
+	}
+	s.slotCache.Invalidate(doctorID)
+	s.logOrphaned(doctorID, orphaned)
+	s.logger.Info("Shift template applied",
+		zap.Uint("doctorID", doctorID),
+		zap.Uint("shiftTemplateID", template.ID),
+		zap.String("from", from),
+		zap.String("until", until),
+		zap.Int("windows", len(added)),
+	)
+	return added, orphaned, nil
+}
+
+// AddTimeOff blocks a range of the doctor's time. Upcoming appointments overlapping it are
+// returned; with cancelAppointments they are cancelled and their patients emailed, otherwise
+// they stay booked for the doctor to move. Patient messages sent during it are routed to
+// coveringDoctorID, unless it is 0.
+func (s *availabilityService) AddTimeOff(ctx context.Context, doctorID uint, start, end time.Time, reason string, coveringDoctorID uint, cancelAppointments bool) (*model.TimeOff, []*model.Appointment, error) {
+	timeOff := &model.TimeOff{DoctorID: doctorID}
+	if err := setTimeOffRange(timeOff, start, end, reason); err != nil {
+		return nil, nil, err
+	}
+	covering, err := s.coveringDoctor(ctx, timeOff, coveringDoctorID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	timeOff.CreatedAt = time.Now()
+	timeOff.UpdatedAt = time.Now()
+	if err := s.availabilityRepo.CreateTimeOff(ctx, timeOff); err != nil {
+		return nil, nil, fmt.Errorf("failed to add time off: %w", err)
+	}
+	timeOff.CoveringDoctor = covering
+	s.slotCache.Invalidate(doctorID)
+
+	affected, err := s.appointmentsDuring(ctx, timeOff, cancelAppointments)
+	if err != nil {
+		return nil, nil, err
+	}
+	return timeOff, affected, nil
+}
+
+// GetDoctorTimeOff gets a doctor's current and upcoming time off
+func (s *availabilityService) GetDoctorTimeOff(ctx context.Context, doctorID uint) ([]*model.TimeOff, error) {
+	return s.availabilityRepo.FindUpcomingTimeOff(ctx, doctorID, time.Now())
+}
+
+// UpdateTimeOff changes a time-off range and its covering doctor. Upcoming appointments
+// overlapping the new range are returned and, with cancelAppointments, cancelled as for AddTimeOff.
+func (s *availabilityService) UpdateTimeOff(ctx context.Context, doctorID, id uint, start, end time.Time, reason string, coveringDoctorID uint, cancelAppointments bool) (*model.TimeOff, []*model.Appointment, error) {
+	timeOff, err := s.availabilityRepo.FindTimeOffByID(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if timeOff.DoctorID != doctorID {
+		return nil, nil, errors.New("time off not found")
+	}
+	if err := setTimeOffRange(timeOff, start, end, reason); err != nil {
+		return nil, nil, err
+	}
+	covering, err := s.coveringDoctor(ctx, timeOff, coveringDoctorID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	timeOff.UpdatedAt = time.Now()
+	if err := s.availabilityRepo.UpdateTimeOff(ctx, timeOff); err != nil {
+		return nil, nil, fmt.Errorf("failed to update time off: %w", err)
+	}
+	timeOff.CoveringDoctor = covering
+	s.slotCache.Invalidate(doctorID)
+
+	affected, err := s.appointmentsDuring(ctx, timeOff, cancelAppointments)
+	if err != nil {
+		return nil, nil, err
+	}
+	return timeOff, affected, nil
+}
+
+// RemoveTimeOff removes a time-off range, opening its time for booking again
+func (s *availabilityService) RemoveTimeOff(ctx context.Context, doctorID, id uint) error {
+	timeOff, err := s.availabilityRepo.FindTimeOffByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if timeOff.DoctorID != doctorID {
+		return errors.New("time off not found")
+	}
+	if err := s.availabilityRepo.DeleteTimeOff(ctx, id); err != nil {
+		return fmt.Errorf("failed to remove time off: %w", err)
+	}
+	s.slotCache.Invalidate(doctorID)
+	return nil
+}
+
+// coveringDoctor sets the doctor covering for the time off, checking they exist and are not the
+// absent doctor, and returns them; 0 leaves no one covering
+func (s *availabilityService) coveringDoctor(ctx context.Context, timeOff *model.TimeOff, coveringDoctorID uint) (*model.Doctor, error) {
+	timeOff.CoveringDoctorID = nil
+	if coveringDoctorID == 0 {
+		return nil, nil
+	}
+	if coveringDoctorID == timeOff.DoctorID {
+		return nil, errors.New("a doctor cannot cover for themselves")
+	}
+	covering, err := s.doctorRepo.FindByID(ctx, coveringDoctorID)
+	if err != nil {
+		return nil, err
+	}
+	timeOff.CoveringDoctorID = &covering.ID
+	return covering, nil
+}
+
+// appointmentsDuring finds the doctor's upcoming appointments overlapping the time off and, when
+// cancel is set, cancels them. Cancellations write the usual outbox events, so patients get the
+// cancellation email.
+func (s *availabilityService) appointmentsDuring(ctx context.Context, timeOff *model.TimeOff, cancel bool) ([]*model.Appointment, error) {
+	upcoming, err := s.appointmentRepo.FindUpcomingByDoctor(ctx, timeOff.DoctorID, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get upcoming appointments: %w", err)
+	}
+
+	var affected []*model.Appointment
+	for _, appt := range upcoming {
+		if appt.ScheduledStart.Before(timeOff.End) && appt.ScheduledEnd.After(timeOff.Start) {
+			affected = append(affected, appt)
+		}
+	}
+	if !cancel {
+		return affected, nil
+	}
+
+	for _, appt := range affected {
+		eventType, err := transitionAppointment(appt, model.AppointmentStatusCancelled, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		events, err := appointmentEvents(eventType, newAppointmentEventData(appt))
+		if err != nil {
+			return nil, err
+		}
+		if err := s.appointmentRepo.Update(ctx, appt, events...); err != nil {
+			return nil, fmt.Errorf("failed to cancel appointment %d: %w", appt.ID, err)
+		}
+	}
+	if len(affected) > 0 {
+		s.slotCache.Invalidate(timeOff.DoctorID)
+		s.logger.Info("Appointments cancelled for doctor time off",
+			zap.Uint("doctorID", timeOff.DoctorID),
+			zap.Uint("timeOffID", timeOff.ID),
+			zap.Int("appointments", len(affected)),
+		)
+	}
+	return affected, nil
+}
+
+// orphanedAppointments finds upcoming appointments covered by the before windows but not by the
+// after windows. Appointments that were already outside the doctor's availability are ignored.
+func (s *availabilityService) orphanedAppointments(ctx context.Context, doctorID uint, before, after []*model.Availability) ([]*model.Appointment, error) {
+	org, err := s.orgService.GetDoctorOrganization(ctx, doctorID)
+	if err != nil {
+		return nil, err
+	}
+	loc := utils.LoadLocation(org.Timezone)
+
+	upcoming, err := s.appointmentRepo.FindUpcomingByDoctor(ctx, doctorID, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get upcoming appointments: %w", err)
+	}
+
+	var orphaned []*model.Appointment
+	for _, appt := range upcoming {
+		if availabilityCovers(before, appt, loc) && !availabilityCovers(after, appt, loc) {
+			orphaned = append(orphaned, appt)
+		}
+	}
+	return orphaned, nil
+}
+
+func (s *availabilityService) logOrphaned(doctorID uint, orphaned []*model.Appointment) {
+	if len(orphaned) > 0 {
+		s.logger.Info("Availability changed with appointments left outside it",
+			zap.Uint("doctorID", doctorID),
+			zap.Int("appointments", len(orphaned)),
+		)
+	}
+}
+
+// availabilityCovers reports whether the appointment lies entirely within one of the windows,
+// evaluated in the clinic's timezone
+func availabilityCovers(windows []*model.Availability, appt *model.Appointment, loc *time.Location) bool {
+	start, end := appt.ScheduledStart.In(loc), appt.ScheduledEnd.In(loc)
+	if start.YearDay() != end.YearDay() || start.Year() != end.Year() {
+		return false
+	}
+	startClock, endClock := start.Format("15:04:05"), end.Format("15:04:05")
+	date := start.Format("2006-01-02")
+
+	for _, window := range windows {
+		if window.DayOfWeek == int(start.Weekday()) && window.AppliesOn(date) &&
+			startClock >= window.StartTime && endClock <= window.EndTime {
+			return true
+		}
+	}
+	return false
+}
+
+// setAvailabilityWindow validates and applies a day and time range to an availability window
+func setAvailabilityWindow(availability *model.Availability, day, startTime, endTime string) error {
+	weekday, err := parseWeekday(day)
+	if err != nil {
+		return err
+	}
+	start, err := parseClock(startTime)
+	if err != nil {
+		return fmt.Errorf("invalid start time %q", startTime)
+	}
+	end, err := parseClock(endTime)
+	if err != nil {
+		return fmt.Errorf("invalid end time %q", endTime)
+	}
+	if !start.Before(end) {
+		return errors.New("start time must be before end time")
+	}
+
+	availability.DayOfWeek = int(weekday)
+	availability.StartTime = start.Format("15:04:05")
+	availability.EndTime = end.Format("15:04:05")
+	return nil
+}
+
+// checkAvailabilityOverlap returns ErrAvailabilityOverlap if availability overlaps any of the
+// other windows on its day while both apply. Windows that only touch, such as 09:00-12:00 and
+// 12:00-17:00, do not overlap.
+func checkAvailabilityOverlap(windows []*model.Availability, availability *model.Availability) error {
+	for _, window := range windows {
+		if window.ID == availability.ID || window.DayOfWeek != availability.DayOfWeek || !validDatesOverlap(window, availability) {
+			continue
+		}
+		// Times are zero-padded HH:MM:SS, so they compare as strings
+		if availability.StartTime < window.EndTime && window.StartTime < availability.EndTime {
+			return fmt.Errorf("%w (%s-%s)", ErrAvailabilityOverlap, window.StartTime, window.EndTime)
+		}
+	}
+	return nil
+}
+
+// cutWindow copies a window to be saved as a new one with different valid dates
+func cutWindow(window *model.Availability, now time.Time) *model.Availability {
+	cut := *window
+	cut.ID = 0
+	cut.CreatedAt = now
+	cut.UpdatedAt = now
+	return &cut
+}
+
+// validDatesOverlap reports whether two windows apply on at least one common date
+func validDatesOverlap(a, b *model.Availability) bool {
+	return (a.ValidUntil == nil || b.ValidFrom == nil || !a.ValidUntil.Before(*b.ValidFrom)) &&
+		(b.ValidUntil == nil || a.ValidFrom == nil || !b.ValidUntil.Before(*a.ValidFrom))
+}
+
+// setTimeOffRange validates and applies a time range and reason to a time-off entry
+func setTimeOffRange(timeOff *model.TimeOff, start, end time.Time, reason string) error {
+	if !start.Before(end) {
+		return errors.New("time off must start before it ends")
+	}
+	if !end.After(time.Now()) {
+		return errors.New("time off must end in the future")
+	}
+	timeOff.Start = start.UTC()
+	timeOff.End = end.UTC()
+	timeOff.Reason = strings.TrimSpace(reason)
+	return nil
+}
+
+// parseWeekday parses a weekday name, such as "monday" or "Mon", or a number from 0 (Sunday) to 6
+func parseWeekday(day string) (time.Weekday, error) {
+	day = strings.ToLower(strings.TrimSpace(day))
+	if n, err := strconv.Atoi(day); err == nil {
+		if n < 0 || n > 6 {
+			return 0, fmt.Errorf("invalid day of week %d", n)
+		}
+		return time.Weekday(n), nil
+	}
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		name := strings.ToLower(d.String())
+		if day == name || (len(day) >= 3 && strings.HasPrefix(name, day)) {
+			return d, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid day of week %q", day)
+}
+
+// parseClock parses a time of day as HH:MM or HH:MM:SS
+func parseClock(value string) (time.Time, error) {
+	if t, err := time.Parse("15:04", value); err == nil {
+		return t, nil
+	}
+	return time.Parse("15:04:05", value)
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/repository"
+	"github.com/whitewalker-sa/ehass/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// Audit actions recorded for break-glass access
+const (
+	AuditActionBreakGlassGranted = "break_glass.granted"
+	AuditActionBreakGlassViewed  = "break_glass.record_viewed"
+)
+
+type breakGlassService struct {
+	repo           repository.BreakGlassRepository
+	patientRepo    repository.PatientRepository
+	userRepo       repository.UserRepository
+	auditRepo      repository.AuditLogRepository
+	emailService   EmailService
+	accessDuration time.Duration
+	logger         *zap.Logger
+}
+
+// NewBreakGlassService creates a new break-glass emergency access service
+func NewBreakGlassService(
+	repo repository.BreakGlassRepository,
+	patientRepo repository.PatientRepository,
+	userRepo repository.UserRepository,
+	auditRepo repository.AuditLogRepository,
+	emailService EmailService,
+	accessDuration time.Duration,
+	logger *zap.Logger,
+) BreakGlassService {
+	return &breakGlassService{
+		repo:           repo,
+		patientRepo:    patientRepo,
+		userRepo:       userRepo,
+		auditRepo:      auditRepo,
+		emailService:   emailService,
+		accessDuration: accessDuration,
+		logger:         logger,
+	}
+}
+
+// RequestAccess grants a clinician time-limited access to a patient record, audits it and notifies admins
+func (s *breakGlassService) RequestAccess(ctx context.Context, userID, patientID uint, reason, ip, userAgent string) (*model.BreakGlassAccess, error) {
+	if reason == "" {
+		return nil, errors.New("a reason is required for emergency access")
+	}
+
+	clinician, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if clinician.Role != model.RoleDoctor {
+		return nil, errors.New("only clinicians can use emergency access")
+	}
+
+	patient, err := s.patientRepo.FindByID(ctx, patientID)
+	if err != nil {
+		return nil, err
+	}
+
+	access := &model.BreakGlassAccess{
+		UserID:    userID,
+		PatientID: patientID,
+		Reason:    reason,
+		ExpiresAt: time.Now().Add(s.accessDuration),
+		IP:        ip,
+		UserAgent: userAgent,
+		CreatedAt: time.Now(),
+	}
+
+	if err := s.repo.Create(ctx, access); err != nil {
+		return nil, fmt.Errorf("failed to grant emergency access: %w", err)
+	}
+
+	s.audit(ctx, userID, AuditActionBreakGlassGranted, patientID, reason, ip, userAgent)
+
+	s.logger.Warn("Break-glass emergency access granted",
+		zap.Uint("userID", userID),
+		zap.Uint("patientID", patientID),
+		zap.Time("expiresAt", access.ExpiresAt))
+
+	s.notifyAdmins(ctx, clinician, patient, access)
+
+	access.User = *clinician
+	access.Patient = *patient
+	return access, nil
+}
+
+// GetEmergencyRecord returns the full patient record if the clinician holds an active grant
+func (s *breakGlassService) GetEmergencyRecord(ctx context.Context, userID, patientID uint, ip, userAgent string) (*model.Patient, *model.BreakGlassAccess, error) {
+	access, err := s.repo.FindActive(ctx, userID, patientID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	patient, err := s.patientRepo.FindByID(ctx, patientID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s.audit(ctx, userID, AuditActionBreakGlassViewed, patientID, access.Reason, ip, userAgent)
+
+	return patient, access, nil
+}
+
+// GetAccessLog returns all emergency access grants for administrative review
+func (s *breakGlassService) GetAccessLog(ctx context.Context, page, pageSize int) ([]*model.BreakGlassAccess, int64, error) {
+	offset := (page - 1) * pageSize
+	if offset < 0 {
+		offset = 0
+	}
+
+	return s.repo.FindAll(ctx, pageSize, offset)
+}
+
+// audit records a break-glass audit log entry; failures are logged but do not block emergency care
+func (s *breakGlassService) audit(ctx context.Context, userID uint, action string, patientID uint, reason, ip, userAgent string) {
+	entry := &model.AuditLog{
+		UserID:     userID,
+		Action:     action,
+		EntityID:   patientID,
+		EntityType: "patient",
+		NewValue:   reason,
+		IP:         ip,
+		UserAgent:  userAgent,
+		CreatedAt:  time.Now(),
+	}
+
+	if err := s.auditRepo.Create(ctx, entry); err != nil {
+		s.logger.Error("Failed to write break-glass audit log",
+			zap.String("action", action),
+			zap.Uint("userID", userID),
+			zap.Uint("patientID", patientID),
+			zap.Error(err))
+	}
+}
+
+// notifyAdmins emails every administrator about the emergency access
+func (s *breakGlassService) notifyAdmins(ctx context.Context, clinician *model.User, patient *model.Patient, access *model.BreakGlassAccess) {
+	admins, err := s.userRepo.FindByRole(ctx, model.RoleAdmin)
+	if err != nil {
+		s.logger.Error("Failed to find admins for break-glass notification", zap.Error(err))
+		return
+	}
+
+	for _, admin := range admins {
+		if err := s.emailService.SendBreakGlassAlert(ctx, admin.Email, admin.Name, clinician.Name,
+			patient.User.Name, access.Reason, utils.FormatDateTime(access.ExpiresAt, admin.Timezone, admin.Locale)); err != nil {
+			s.logger.Error("Failed to send break-glass notification",
+				zap.Uint("adminID", admin.ID),
+				zap.Error(err))
+		}
+	}
+}
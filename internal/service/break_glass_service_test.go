@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"go.uber.org/zap"
+)
+
+func TestRequestAccessGrantsAuditsAndNotifies(t *testing.T) {
+	clinic := newTestClinic()
+	grants, email := &fakeBreakGlassRepo{}, &fakeEmailService{}
+	s := NewBreakGlassService(grants, clinic.patients, clinic.users, clinic.audit, email, time.Hour, zap.NewNop())
+
+	access, err := s.RequestAccess(context.Background(), 1, 10, "Unconscious in ER", "10.0.0.1", "test")
+	if err != nil {
+		t.Fatalf("RequestAccess: %v", err)
+	}
+	if until := time.Until(access.ExpiresAt); until <= 59*time.Minute || until > time.Hour {
+		t.Errorf("grant expires in %v, want one hour", until)
+	}
+	if got := clinic.audit.actions(); len(got) != 1 || got[0] != AuditActionBreakGlassGranted {
+		t.Errorf("audited %v, want the grant", got)
+	}
+	if got := email.breakGlassAlerts; len(got) != 1 || got[0] != "admin@example.com" {
+		t.Errorf("alerted %v, want the admin", got)
+	}
+}
+
+func TestRequestAccessRejectsInvalidRequests(t *testing.T) {
+	tests := []struct {
+		name      string
+		userID    uint
+		patientID uint
+		reason    string
+	}{
+		{"no reason", 1, 10, ""},
+		{"not a clinician", 12, 10, "Curious"},
+		{"unknown patient", 1, 404, "Unconscious in ER"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clinic := newTestClinic()
+			grants, email := &fakeBreakGlassRepo{}, &fakeEmailService{}
+			s := NewBreakGlassService(grants, clinic.patients, clinic.users, clinic.audit, email, time.Hour, zap.NewNop())
+
+			if _, err := s.RequestAccess(context.Background(), tt.userID, tt.patientID, tt.reason, "", ""); err == nil {
+				t.Fatal("RequestAccess succeeded, want an error")
+			}
+			if len(grants.grants) != 0 || len(clinic.audit.entries) != 0 || len(email.breakGlassAlerts) != 0 {
+				t.Error("a rejected request granted, audited or notified")
+			}
+		})
+	}
+}
+
+func TestGetEmergencyRecordRequiresActiveGrant(t *testing.T) {
+	clinic := newTestClinic()
+	grants := &fakeBreakGlassRepo{}
+	s := NewBreakGlassService(grants, clinic.patients, clinic.users, clinic.audit, &fakeEmailService{}, time.Hour, zap.NewNop())
+	ctx := context.Background()
+
+	if _, _, err := s.GetEmergencyRecord(ctx, 1, 10, "", ""); err == nil {
+		t.Fatal("record read without a grant")
+	}
+
+	grants.grants = append(grants.grants, &model.BreakGlassAccess{
+		UserID: 1, PatientID: 10, Reason: "Expired", ExpiresAt: time.Now().Add(-time.Minute),
+	})
+	if _, _, err := s.GetEmergencyRecord(ctx, 1, 10, "", ""); err == nil {
+		t.Fatal("record read with an expired grant")
+	}
+	if len(clinic.audit.entries) != 0 {
+		t.Errorf("refused reads were audited as views: %v", clinic.audit.actions())
+	}
+
+	if _, err := s.RequestAccess(ctx, 1, 10, "Unconscious in ER", "", ""); err != nil {
+		t.Fatalf("RequestAccess: %v", err)
+	}
+	patient, access, err := s.GetEmergencyRecord(ctx, 1, 10, "", "")
+	if err != nil {
+		t.Fatalf("GetEmergencyRecord: %v", err)
+	}
+	if patient.ID != 10 || access.Reason != "Unconscious in ER" {
+		t.Errorf("got patient %d under %q, want patient 10 under the active grant", patient.ID, access.Reason)
+	}
+	if got := clinic.audit.actions(); got[len(got)-1] != AuditActionBreakGlassViewed {
+		t.Errorf("audited %v, want the view last", got)
+	}
+
+	// A grant is for one clinician and one patient
+	if _, _, err := s.GetEmergencyRecord(ctx, 2, 10, "", ""); err == nil {
+		t.Error("another doctor read the record with the first doctor's grant")
+	}
+}
@@ -0,0 +1,226 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/repository"
+	"github.com/whitewalker-sa/ehass/pkg/ical"
+	"github.com/whitewalker-sa/ehass/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// ErrInvalidCalendarToken is returned for a calendar feed requested with a token that was not
+// issued to the user or has been revoked
+var ErrInvalidCalendarToken = errors.New("invalid calendar feed token")
+
+const (
+	calendarProdID = "-//EHASS//Appointments//EN"
+	// Appointments from this long ago are kept in feeds, so recent visits stay on the calendar
+	calendarFeedPast = 30 * 24 * time.Hour
+	// Appointments up to this far ahead are included in feeds
+	calendarFeedFuture = 365 * 24 * time.Hour
+	calendarFeedLimit  = 1000
+	// How often subscribed calendars are asked to refetch the feed
+	calendarFeedRefresh = time.Hour
+)
+
+type calendarService struct {
+	userRepo        repository.UserRepository
+	patientRepo     repository.PatientRepository
+	doctorRepo      repository.DoctorRepository
+	appointmentRepo repository.AppointmentRepository
+	orgService      OrganizationService
+	baseURL         string
+	reminderLead    time.Duration
+	logger          *zap.Logger
+}
+
+// NewCalendarService creates a calendar service. Events get an alarm reminderLead before they
+// start, matching the reminders the patient is sent; 0 adds no alarm.
+func NewCalendarService(
+	userRepo repository.UserRepository,
+	patientRepo repository.PatientRepository,
+	doctorRepo repository.DoctorRepository,
+	appointmentRepo repository.AppointmentRepository,
+	orgService OrganizationService,
+	baseURL string,
+	reminderLead time.Duration,
+	logger *zap.Logger,
+) CalendarService {
+	return &calendarService{
+		userRepo:        userRepo,
+		patientRepo:     patientRepo,
+		doctorRepo:      doctorRepo,
+		appointmentRepo: appointmentRepo,
+		orgService:      orgService,
+		baseURL:         strings.TrimRight(baseURL, "/"),
+		reminderLead:    reminderLead,
+		logger:          logger,
+	}
+}
+
+// GetAppointmentCalendar renders an appointment as an iCalendar file, described for the user
+// downloading it: doctors see the patient, everyone else the doctor
+func (s *calendarService) GetAppointmentCalendar(ctx context.Context, id, userID uint) ([]byte, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	appointment, err := s.appointmentRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	org, err := s.orgService.GetDoctorOrganization(ctx, appointment.DoctorID)
+	if err != nil {
+		return nil, err
+	}
+	calendar := ical.Calendar{
+		ProdID: calendarProdID,
+		Events: []ical.Event{s.event(appointment, org, user.Role == model.RoleDoctor)},
+	}
+	return calendar.Bytes(), nil
+}
+
+// CreateFeed issues the user a calendar feed token and returns the feed's URL. A feed issued
+// before stops working.
+func (s *calendarService) CreateFeed(ctx context.Context, userID uint) (string, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	token := utils.GenerateRandomToken(32)
+	user.CalendarTokenHash = utils.HashToken(token)
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return "", fmt.Errorf("failed to issue calendar feed: %w", err)
+	}
+	s.logger.Info("Calendar feed issued", zap.Uint("userID", user.ID))
+	return fmt.Sprintf("%s/api/v1/users/%s/calendar.ics?token=%s", s.baseURL, user.PublicID, url.QueryEscape(token)), nil
+}
+
+// RevokeFeed stops the user's calendar feed
+func (s *calendarService) RevokeFeed(ctx context.Context, userID uint) error {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.CalendarTokenHash == "" {
+		return nil
+	}
+	user.CalendarTokenHash = ""
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return fmt.Errorf("failed to revoke calendar feed: %w", err)
+	}
+	s.logger.Info("Calendar feed revoked", zap.Uint("userID", user.ID))
+	return nil
+}
+
+// GetFeed renders the calendar feed of a user: their appointments as a patient, or as a doctor
+// for doctors, from a month ago to a year ahead. Cancelled appointments stay in the feed marked
+// cancelled, so subscribed calendars remove them.
+func (s *calendarService) GetFeed(ctx context.Context, userID uint, token string) ([]byte, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, ErrInvalidCalendarToken
+	}
+	if user.CalendarTokenHash == "" ||
+		subtle.ConstantTimeCompare([]byte(user.CalendarTokenHash), []byte(utils.HashToken(token))) != 1 {
+		return nil, ErrInvalidCalendarToken
+	}
+
+	now := time.Now()
+	from, to := now.Add(-calendarFeedPast), now.Add(calendarFeedFuture)
+	filter := repository.AppointmentFilter{From: &from, To: &to}
+	asDoctor := user.Role == model.RoleDoctor
+	if asDoctor {
+		doctor, err := s.doctorRepo.FindByUserID(ctx, user.ID)
+		if err != nil {
+			return nil, err
+		}
+		filter.DoctorIDs = []uint{doctor.ID}
+	} else {
+		patient, err := s.patientRepo.FindByUserID(ctx, user.ID)
+		if err != nil {
+			return nil, err
+		}
+		filter.PatientIDs = []uint{patient.ID}
+	}
+	appointments, _, err := s.appointmentRepo.Find(ctx, filter, calendarFeedLimit, 0, repository.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	calendar := ical.Calendar{
+		ProdID:  calendarProdID,
+		Name:    "Appointments",
+		Refresh: calendarFeedRefresh,
+		Events:  make([]ical.Event, 0, len(appointments)),
+	}
+	orgs := make(map[uint]*model.Organization)
+	for _, appointment := range appointments {
+		org, ok := orgs[appointment.DoctorID]
+		if !ok {
+			if org, err = s.orgService.GetDoctorOrganization(ctx, appointment.DoctorID); err != nil {
+				return nil, err
+			}
+			orgs[appointment.DoctorID] = org
+		}
+		calendar.Events = append(calendar.Events, s.event(appointment, org, asDoctor))
+	}
+	return calendar.Bytes(), nil
+}
+
+// event describes an appointment as a calendar event. The appointment must be loaded with its
+// patient, doctor and type.
+func (s *calendarService) event(appointment *model.Appointment, org *model.Organization, asDoctor bool) ical.Event {
+	label := appointmentTypeLabel(appointment)
+	summary := fmt.Sprintf("%s with %s", label, appointment.Doctor.User.Name)
+	if asDoctor {
+		summary = fmt.Sprintf("%s: %s", label, appointment.Patient.User.Name)
+	}
+
+	description := []string{modalityLabel(appointment.Modality) + " at " + org.DisplayName()}
+	if appointment.Reason != "" {
+		description = append(description, "Reason: "+appointment.Reason)
+	}
+	if !asDoctor && appointment.AppointmentType != nil && appointment.AppointmentType.Preparation != "" {
+		description = append(description, appointment.AppointmentType.Preparation)
+	}
+	description = append(description, "Reference: "+appointment.PublicID)
+
+	event := ical.Event{
+		UID:          appointment.PublicID + "@ehass",
+		Start:        appointment.ScheduledStart,
+		End:          appointment.ScheduledEnd,
+		Summary:      summary,
+		Description:  strings.Join(description, "\n"),
+		Status:       calendarStatus(appointment.Status),
+		LastModified: appointment.UpdatedAt,
+	}
+	if appointment.Modality == model.ModalityInPerson {
+		event.Location = org.Address
+	}
+	if s.reminderLead > 0 && !asDoctor {
+		event.Alarms = []ical.Alarm{{Before: s.reminderLead, Description: summary}}
+	}
+	return event
+}
+
+// calendarStatus maps an appointment status to the status of its event. Appointments awaiting
+// the doctor's confirmation are tentative.
+func calendarStatus(status model.AppointmentStatus) string {
+	switch status {
+	case model.AppointmentStatusPending:
+		return ical.StatusTentative
+	case model.AppointmentStatusCancelled:
+		return ical.StatusCancelled
+	default:
+		return ical.StatusConfirmed
+	}
+}
@@ -0,0 +1,420 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/repository"
+	"github.com/whitewalker-sa/ehass/pkg/breaker"
+	"github.com/whitewalker-sa/ehass/pkg/gcal"
+	"github.com/whitewalker-sa/ehass/pkg/utils"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrCalendarSyncDisabled is returned when connecting a calendar while calendar sync is not
+	// configured
+	ErrCalendarSyncDisabled = errors.New("calendar sync is not enabled")
+	// ErrCalendarAuthorization is returned when Google does not grant access to a calendar with
+	// the code it returned from the consent page
+	ErrCalendarAuthorization = errors.New("calendar authorization failed")
+)
+
+const (
+	// Appointments that started this long ago are still kept in sync, so same-day changes such as
+	// a completed visit reach the calendar
+	calendarSyncPast = 24 * time.Hour
+	// Access tokens expiring within this long are refreshed before a sync
+	calendarTokenLeeway = time.Minute
+	// Longest sync error kept on a connection
+	calendarSyncErrorLength = 500
+)
+
+type calendarSyncService struct {
+	repo            repository.CalendarSyncRepository
+	doctorRepo      repository.DoctorRepository
+	appointmentRepo repository.AppointmentRepository
+	orgService      OrganizationService
+	client          *gcal.Client
+	breaker         *breaker.Breaker
+	redirectURL     string
+	window          time.Duration
+	slotCache       *SlotCache
+	logger          *zap.Logger
+}
+
+// NewCalendarSyncService creates a calendar sync service pushing appointments up to window ahead
+// and pulling busy times for the same period. Calls to Google go through the breaker. A nil client
+// disables connecting calendars.
+func NewCalendarSyncService(
+	repo repository.CalendarSyncRepository,
+	doctorRepo repository.DoctorRepository,
+	appointmentRepo repository.AppointmentRepository,
+	orgService OrganizationService,
+	client *gcal.Client,
+	calendarBreaker *breaker.Breaker,
+	redirectURL string,
+	window time.Duration,
+	slotCache *SlotCache,
+	logger *zap.Logger,
+) CalendarSyncService {
+	return &calendarSyncService{
+		repo:            repo,
+		doctorRepo:      doctorRepo,
+		appointmentRepo: appointmentRepo,
+		orgService:      orgService,
+		client:          client,
+		breaker:         calendarBreaker,
+		redirectURL:     redirectURL,
+		window:          window,
+		slotCache:       slotCache,
+		logger:          logger,
+	}
+}
+
+// AuthorizationURL returns the Google consent page a doctor is sent to, and the state Google
+// returns with the code. The caller checks the state it gets back matches before calling Connect.
+func (s *calendarSyncService) AuthorizationURL(ctx context.Context, userID uint) (string, string, error) {
+	if s.client == nil {
+		return "", "", ErrCalendarSyncDisabled
+	}
+	if _, err := s.doctorRepo.FindByUserID(ctx, userID); err != nil {
+		return "", "", err
+	}
+	state := utils.GenerateRandomToken(16)
+	return s.client.AuthURL(s.redirectURL, state), state, nil
+}
+
+// Connect links a doctor's calendar with the code Google returned from the consent page. A doctor
+// who connects again keeps the connection with the new credentials.
+func (s *calendarSyncService) Connect(ctx context.Context, userID uint, code string) (*model.CalendarConnection, error) {
+	if s.client == nil {
+		return nil, ErrCalendarSyncDisabled
+	}
+	doctor, err := s.doctorRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var token *gcal.Token
+	err = s.call(ctx, func(ctx context.Context) error {
+		var err error
+		token, err = s.client.Exchange(ctx, code, s.redirectURL)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, gcal.ErrUnauthorized) {
+			return nil, fmt.Errorf("%w: the authorization code is invalid or has expired", ErrCalendarAuthorization)
+		}
+		return nil, fmt.Errorf("failed to exchange authorization code: %w", err)
+	}
+	if token.RefreshToken == "" {
+		return nil, fmt.Errorf("%w: google did not grant offline access to the calendar", ErrCalendarAuthorization)
+	}
+
+	connection, err := s.repo.FindConnectionByUserID(ctx, userID)
+	if err != nil {
+		if err.Error() != "calendar connection not found" {
+			return nil, err
+		}
+		connection = &model.CalendarConnection{
+			UserID:     userID,
+			Provider:   model.CalendarProviderGoogle,
+			CalendarID: "primary",
+		}
+	}
+	connection.DoctorID = doctor.ID
+	connection.AccessToken = token.AccessToken
+	connection.RefreshToken = token.RefreshToken
+	connection.TokenExpiry = token.Expiry
+	connection.LastError = ""
+	if err := s.repo.SaveConnection(ctx, connection); err != nil {
+		return nil, fmt.Errorf("failed to save calendar connection: %w", err)
+	}
+	return connection, nil
+}
+
+// GetConnection returns the calendar connection of a user
+func (s *calendarSyncService) GetConnection(ctx context.Context, userID uint) (*model.CalendarConnection, error) {
+	return s.repo.FindConnectionByUserID(ctx, userID)
+}
+
+// Disconnect unlinks a user's calendar. The events pushed to it are deleted if Google still
+// accepts the credentials; the busy times pulled from it stop blocking slots either way.
+func (s *calendarSyncService) Disconnect(ctx context.Context, userID uint) error {
+	connection, err := s.repo.FindConnectionByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if s.client != nil {
+		if err := s.deleteEvents(ctx, connection); err != nil {
+			s.logger.Warn("Failed to delete synced calendar events", zap.Uint("userID", userID), zap.Error(err))
+		}
+	}
+	if err := s.repo.DeleteConnection(ctx, connection.ID); err != nil {
+		return fmt.Errorf("failed to delete calendar connection: %w", err)
+	}
+	s.slotCache.Invalidate(connection.DoctorID)
+	return nil
+}
+
+// Sync syncs every connected calendar. A calendar that fails to sync records the error on its
+// connection and does not stop the others.
+func (s *calendarSyncService) Sync(ctx context.Context) error {
+	if s.client == nil {
+		return ErrCalendarSyncDisabled
+	}
+	connections, err := s.repo.FindConnections(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to find calendar connections: %w", err)
+	}
+
+	failed := 0
+	for _, connection := range connections {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		err := s.syncConnection(ctx, connection)
+		if err != nil {
+			failed++
+			s.logger.Warn("Failed to sync calendar", zap.Uint("userID", connection.UserID), zap.Error(err))
+			connection.LastError = syncErrorMessage(err)
+		} else {
+			now := time.Now()
+			connection.SyncedAt = &now
+			connection.LastError = ""
+		}
+		if err := s.repo.SaveConnection(ctx, connection); err != nil {
+			return fmt.Errorf("failed to save calendar connection: %w", err)
+		}
+	}
+	if failed > 0 {
+		s.logger.Warn("Some calendars failed to sync", zap.Int("failed", failed), zap.Int("total", len(connections)))
+	}
+	return nil
+}
+
+// syncConnection pushes the doctor's appointments to the calendar and pulls its busy times
+func (s *calendarSyncService) syncConnection(ctx context.Context, connection *model.CalendarConnection) error {
+	accessToken, err := s.accessToken(ctx, connection)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	from, to := now.Add(-calendarSyncPast), now.Add(s.window)
+	if err := s.push(ctx, connection, accessToken, from, to); err != nil {
+		return err
+	}
+
+	var busy []gcal.Busy
+	err = s.call(ctx, func(ctx context.Context) error {
+		var err error
+		busy, err = s.client.ListBusy(ctx, accessToken, connection.CalendarID, from, to)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read calendar: %w", err)
+	}
+	periods := make([]*model.CalendarBusy, 0, len(busy))
+	for _, period := range busy {
+		periods = append(periods, &model.CalendarBusy{
+			ConnectionID: connection.ID,
+			DoctorID:     connection.DoctorID,
+			Start:        period.Start,
+			End:          period.End,
+		})
+	}
+	if err := s.repo.ReplaceBusy(ctx, connection.ID, periods); err != nil {
+		return fmt.Errorf("failed to save calendar busy times: %w", err)
+	}
+	s.slotCache.Invalidate(connection.DoctorID)
+	return nil
+}
+
+// push writes the doctor's appointments between from and to to the calendar. Confirmed
+// appointments get an event, which is rewritten when the appointment changes; the events of
+// appointments that are cancelled, no longer confirmed or missed are deleted.
+func (s *calendarSyncService) push(ctx context.Context, connection *model.CalendarConnection, accessToken string, from, to time.Time) error {
+	appointments, err := s.appointmentRepo.FindByDoctorBetween(ctx, connection.DoctorID, from, to)
+	if err != nil {
+		return fmt.Errorf("failed to get appointments: %w", err)
+	}
+	events, err := s.repo.FindEvents(ctx, connection.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get synced events: %w", err)
+	}
+	pushed := make(map[uint]*model.CalendarEvent, len(events))
+	for _, event := range events {
+		pushed[event.AppointmentID] = event
+	}
+
+	var org *model.Organization
+	for _, appointment := range appointments {
+		event, ok := pushed[appointment.ID]
+		if !syncsToCalendar(appointment.Status) {
+			if ok {
+				if err := s.deleteEvent(ctx, connection, accessToken, event); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if ok && !appointment.UpdatedAt.After(event.Version) {
+			continue
+		}
+
+		if org == nil {
+			if org, err = s.orgService.GetDoctorOrganization(ctx, connection.DoctorID); err != nil {
+				return fmt.Errorf("failed to get clinic: %w", err)
+			}
+		}
+		body := calendarSyncEvent(appointment, org)
+		if ok {
+			err := s.call(ctx, func(ctx context.Context) error {
+				return s.client.UpdateEvent(ctx, accessToken, connection.CalendarID, event.EventID, body)
+			})
+			// An event the doctor deleted is written again
+			if err != nil && !errors.Is(err, gcal.ErrNotFound) {
+				return fmt.Errorf("failed to update calendar event: %w", err)
+			}
+			if err == nil {
+				event.Version = appointment.UpdatedAt
+				if err := s.repo.SaveEvent(ctx, event); err != nil {
+					return fmt.Errorf("failed to save synced event: %w", err)
+				}
+				continue
+			}
+		} else {
+			event = &model.CalendarEvent{ConnectionID: connection.ID, AppointmentID: appointment.ID}
+		}
+
+		err := s.call(ctx, func(ctx context.Context) error {
+			var err error
+			event.EventID, err = s.client.InsertEvent(ctx, accessToken, connection.CalendarID, body)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create calendar event: %w", err)
+		}
+		event.Version = appointment.UpdatedAt
+		if err := s.repo.SaveEvent(ctx, event); err != nil {
+			return fmt.Errorf("failed to save synced event: %w", err)
+		}
+	}
+	return nil
+}
+
+// deleteEvents deletes every event pushed to a calendar
+func (s *calendarSyncService) deleteEvents(ctx context.Context, connection *model.CalendarConnection) error {
+	events, err := s.repo.FindEvents(ctx, connection.ID)
+	if err != nil || len(events) == 0 {
+		return err
+	}
+	accessToken, err := s.accessToken(ctx, connection)
+	if err != nil {
+		return err
+	}
+	for _, event := range events {
+		if err := s.deleteEvent(ctx, connection, accessToken, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *calendarSyncService) deleteEvent(ctx context.Context, connection *model.CalendarConnection, accessToken string, event *model.CalendarEvent) error {
+	err := s.call(ctx, func(ctx context.Context) error {
+		return s.client.DeleteEvent(ctx, accessToken, connection.CalendarID, event.EventID)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete calendar event: %w", err)
+	}
+	if err := s.repo.DeleteEvent(ctx, event.ID); err != nil {
+		return fmt.Errorf("failed to delete synced event: %w", err)
+	}
+	return nil
+}
+
+// accessToken returns a current access token for a connection, refreshing and saving it when it
+// is about to expire
+func (s *calendarSyncService) accessToken(ctx context.Context, connection *model.CalendarConnection) (string, error) {
+	if time.Now().Add(calendarTokenLeeway).Before(connection.TokenExpiry) {
+		return connection.AccessToken, nil
+	}
+
+	var token *gcal.Token
+	err := s.call(ctx, func(ctx context.Context) error {
+		var err error
+		token, err = s.client.Refresh(ctx, connection.RefreshToken)
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to refresh calendar access: %w", err)
+	}
+	connection.AccessToken = token.AccessToken
+	connection.TokenExpiry = token.Expiry
+	if err := s.repo.SaveConnection(ctx, connection); err != nil {
+		return "", fmt.Errorf("failed to save calendar connection: %w", err)
+	}
+	return token.AccessToken, nil
+}
+
+// call makes a Google API call through the breaker. Rejected credentials, missing events and
+// other client errors are failures of the request, not of Google, and are not retried.
+func (s *calendarSyncService) call(ctx context.Context, fn func(ctx context.Context) error) error {
+	return s.breaker.Do(ctx, func(ctx context.Context) error {
+		err := fn(ctx)
+		var status *gcal.StatusError
+		if errors.Is(err, gcal.ErrUnauthorized) || errors.Is(err, gcal.ErrNotFound) ||
+			(errors.As(err, &status) && !status.Temporary()) {
+			return breaker.Permanent(err)
+		}
+		return err
+	})
+}
+
+// syncsToCalendar reports whether appointments in a status are kept on the doctor's calendar.
+// Visits that took place stay on it.
+func syncsToCalendar(status model.AppointmentStatus) bool {
+	switch status {
+	case model.AppointmentStatusConfirmed, model.AppointmentStatusCheckedIn, model.AppointmentStatusCompleted:
+		return true
+	default:
+		return false
+	}
+}
+
+// calendarSyncEvent describes an appointment as an event in the doctor's calendar. Google is not
+// covered by the clinic's handling of patient data, so the event names no patient and carries no
+// reason; the reference finds the appointment in the app. The appointment must be loaded with
+// its type.
+func calendarSyncEvent(appointment *model.Appointment, org *model.Organization) gcal.Event {
+	event := gcal.Event{
+		Summary:       appointmentTypeLabel(appointment) + " appointment",
+		Description:   modalityLabel(appointment.Modality) + " at " + org.DisplayName() + "\nReference: " + appointment.PublicID,
+		Start:         appointment.ScheduledStart,
+		End:           appointment.ScheduledEnd,
+		AppointmentID: appointment.PublicID,
+	}
+	if appointment.Modality == model.ModalityInPerson {
+		event.Location = org.Address
+	}
+	return event
+}
+
+// syncErrorMessage describes a sync failure for the doctor
+func syncErrorMessage(err error) string {
+	if errors.Is(err, gcal.ErrUnauthorized) {
+		return "Google Calendar access was revoked or has expired; reconnect the calendar"
+	}
+	message := err.Error()
+	if len(message) > calendarSyncErrorLength {
+		message = message[:calendarSyncErrorLength]
+	}
+	return message
+}
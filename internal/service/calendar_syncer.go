@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// JobCalendarSync is the name of the job syncing doctors' external calendars
+const JobCalendarSync = "calendar_sync"
+
+// CalendarSyncer periodically syncs doctors' external calendars, so appointment changes reach
+// them and the times they are busy block slots
+type CalendarSyncer struct {
+	syncService CalendarSyncService
+	interval    time.Duration
+	monitor     *JobMonitor
+	logger      *zap.Logger
+}
+
+// NewCalendarSyncer creates a new calendar syncer running every interval
+func NewCalendarSyncer(syncService CalendarSyncService, interval time.Duration, monitor *JobMonitor, logger *zap.Logger) *CalendarSyncer {
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+
+	s := &CalendarSyncer{
+		syncService: syncService,
+		interval:    interval,
+		monitor:     monitor,
+		logger:      logger,
+	}
+	monitor.Register(JobCalendarSync, interval, syncService.Sync)
+	return s
+}
+
+// Start syncs calendars in the background until the returned function is called
+func (s *CalendarSyncer) Start() func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			if err := s.monitor.Do(ctx, JobCalendarSync, s.syncService.Sync); err != nil && ctx.Err() == nil {
+				s.logger.Error("Failed to sync calendars", zap.Error(err))
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		wg.Wait()
+	}
+}
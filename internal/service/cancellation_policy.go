@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+)
+
+// ErrCancellationCutoff is returned when an appointment is cancelled within its cancellation
+// cutoff at a clinic that does not accept late cancellations
+var ErrCancellationCutoff = errors.New("appointment can no longer be cancelled")
+
+// maxCancellationCutoff is the longest cancellation cutoff in minutes, one week
+const maxCancellationCutoff = 7 * 24 * 60
+
+// CancellationPolicy is the cancellation policy that applies to one appointment
+type CancellationPolicy struct {
+	CutoffMinutes       int       // Minutes before the start after which cancelling is late
+	CancelBy            time.Time // Last time the appointment can be cancelled on time
+	LateCancellationFee bool      // Late cancellations are accepted and flagged for a fee rather than refused
+	Late                bool      // Cancelling at the time the policy was worked out is late
+}
+
+// cancellationPolicy works out the policy of an appointment at now. The doctor's cutoff, if set,
+// takes precedence over the clinic's.
+func cancellationPolicy(org *model.Organization, doctor *model.Doctor, start, now time.Time) CancellationPolicy {
+	cutoff := org.CancellationCutoffMinutes()
+	if doctor.CancellationCutoff != nil {
+		cutoff = *doctor.CancellationCutoff
+	}
+	cancelBy := start.Add(-time.Duration(cutoff) * time.Minute)
+	return CancellationPolicy{
+		CutoffMinutes:       cutoff,
+		CancelBy:            cancelBy,
+		LateCancellationFee: org.LateCancellationFee,
+		Late:                now.After(cancelBy),
+	}
+}
+
+// GetCancellationPolicy returns the cancellation policy of an appointment as it applies now
+func (s *appointmentService) GetCancellationPolicy(ctx context.Context, id uint) (*CancellationPolicy, error) {
+	appointment, err := s.appointmentRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	org, err := s.orgService.GetDoctorOrganization(ctx, appointment.DoctorID)
+	if err != nil {
+		return nil, err
+	}
+	policy := cancellationPolicy(org, &appointment.Doctor, appointment.ScheduledStart, time.Now())
+	return &policy, nil
+}
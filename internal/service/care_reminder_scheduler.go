@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/repository"
+	"go.uber.org/zap"
+)
+
+// JobCareReminders is the name of the job sending preventive care reminders
+const JobCareReminders = "care_reminders"
+
+// careReminderEventData is the data of care reminder events
+type careReminderEventData struct {
+	PatientID         string     `json:"patient_id"`
+	RuleID            uint       `json:"rule_id"`
+	RuleName          string     `json:"rule_name"`
+	DueAt             time.Time  `json:"due_at"`
+	LastVisitAt       *time.Time `json:"last_visit_at,omitempty"`
+	AppointmentTypeID uint       `json:"appointment_type_id"` // Suggested booking
+	Specialty         string     `json:"specialty,omitempty"`
+}
+
+// CareReminderScheduler periodically evaluates the active care rules and reminds the patients
+// they have come due for. Reminders are sent through the outbox, which emails the patient and
+// publishes the event.
+type CareReminderScheduler struct {
+	repo      repository.CareRepository
+	leadTime  time.Duration
+	batchSize int
+	interval  time.Duration
+	monitor   *JobMonitor
+	logger    *zap.Logger
+}
+
+// NewCareReminderScheduler creates a new care reminder scheduler. Patients are reminded
+// leadTime before an item is due, at most batchSize per rule and run.
+func NewCareReminderScheduler(
+	repo repository.CareRepository,
+	leadTime time.Duration,
+	batchSize int,
+	interval time.Duration,
+	monitor *JobMonitor,
+	logger *zap.Logger,
+) *CareReminderScheduler {
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+	if interval <= 0 {
+		interval = 24 * time.Hour
+	}
+
+	s := &CareReminderScheduler{
+		repo:      repo,
+		leadTime:  leadTime,
+		batchSize: batchSize,
+		interval:  interval,
+		monitor:   monitor,
+		logger:    logger,
+	}
+	monitor.Register(JobCareReminders, interval, s.RunOnce)
+	return s
+}
+
+// Start sends care reminders in the background until the returned function is called
+func (s *CareReminderScheduler) Start() func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			_ = s.monitor.Do(ctx, JobCareReminders, s.RunOnce)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		wg.Wait()
+	}
+}
+
+// RunOnce reminds the patients each active rule is due for. Patients beyond the batch size are
+// reminded on the next run.
+func (s *CareReminderScheduler) RunOnce(ctx context.Context) error {
+	rules, err := s.repo.FindActiveRules(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load care rules: %w", err)
+	}
+
+	now := time.Now()
+	for _, rule := range rules {
+		candidates, err := s.repo.FindDuePatients(ctx, rule, careEligibility(rule, now, s.leadTime), s.batchSize)
+		if err != nil {
+			return fmt.Errorf("failed to find patients due for %q: %w", rule.Name, err)
+		}
+
+		for _, candidate := range candidates {
+			if err := s.remind(ctx, rule, candidate, now); err != nil {
+				return err
+			}
+		}
+		if len(candidates) > 0 {
+			s.logger.Info("Care reminders sent", zap.Uint("ruleID", rule.ID), zap.Int("patients", len(candidates)))
+		}
+	}
+	return nil
+}
+
+// remind records the reminder for a patient and queues its email and event
+func (s *CareReminderScheduler) remind(ctx context.Context, rule *model.CareRule, candidate repository.CareCandidate, now time.Time) error {
+	dueAt := now
+	if candidate.LastVisit != nil {
+		dueAt = candidate.LastVisit.AddDate(0, rule.IntervalMonths, 0)
+	}
+
+	payload, err := json.Marshal(careReminderEventData{
+		PatientID:         candidate.PatientPublicID,
+		RuleID:            rule.ID,
+		RuleName:          rule.Name,
+		DueAt:             dueAt.UTC(),
+		LastVisitAt:       candidate.LastVisit,
+		AppointmentTypeID: rule.AppointmentTypeID,
+		Specialty:         rule.Specialty,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+	eventID := model.NewPublicID()
+	events := []*model.OutboxEvent{
+		{EventID: eventID, Type: model.EventCareReminderDue, Destination: model.OutboxDestinationEvents, Payload: string(payload)},
+		{EventID: eventID, Type: model.EventCareReminderDue, Destination: model.OutboxDestinationEmail, Payload: string(payload)},
+	}
+
+	reminder := &model.CareReminder{
+		PatientID:   candidate.PatientID,
+		RuleID:      rule.ID,
+		DueAt:       dueAt,
+		LastVisitAt: candidate.LastVisit,
+		NotifiedAt:  now,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := s.repo.SaveReminder(ctx, reminder, events...); err != nil {
+		return fmt.Errorf("failed to save care reminder: %w", err)
+	}
+	return nil
+}
+
+// careEligibility returns the patients a rule applies to at now, due within leadTime
+func careEligibility(rule *model.CareRule, now time.Time, leadTime time.Duration) repository.CareEligibility {
+	eligibility := repository.CareEligibility{
+		Sex:          rule.Sex,
+		VisitsBefore: now.Add(leadTime).AddDate(0, -rule.IntervalMonths, 0),
+		Now:          now,
+	}
+	if rule.MinAge > 0 {
+		bornBefore := now.AddDate(-rule.MinAge, 0, 0)
+		eligibility.BornBefore = &bornBefore
+	}
+	if rule.MaxAge > 0 {
+		bornAfter := now.AddDate(-rule.MaxAge-1, 0, 0)
+		eligibility.BornAfter = &bornAfter
+	}
+	return eligibility
+}
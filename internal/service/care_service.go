@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/repository"
+	"go.uber.org/zap"
+)
+
+// ErrInvalidCareRule is returned when a care rule fails validation
+var ErrInvalidCareRule = errors.New("invalid care rule")
+
+type careService struct {
+	repo     repository.CareRepository
+	typeRepo repository.AppointmentTypeRepository
+	logger   *zap.Logger
+}
+
+// NewCareService creates a new care service
+func NewCareService(repo repository.CareRepository, typeRepo repository.AppointmentTypeRepository, logger *zap.Logger) CareService {
+	return &careService{
+		repo:     repo,
+		typeRepo: typeRepo,
+		logger:   logger,
+	}
+}
+
+// CreateRule creates an active care rule
+func (s *careService) CreateRule(ctx context.Context, rule *model.CareRule) (*model.CareRule, error) {
+	if err := s.validate(ctx, rule); err != nil {
+		return nil, err
+	}
+
+	rule.ID = 0
+	rule.Active = true
+	rule.CreatedAt = time.Now()
+	rule.UpdatedAt = time.Now()
+	if err := s.repo.CreateRule(ctx, rule); err != nil {
+		return nil, fmt.Errorf("failed to create care rule: %w", err)
+	}
+
+	s.logger.Info("Care rule created", zap.Uint("ruleID", rule.ID), zap.String("name", rule.Name))
+	return rule, nil
+}
+
+// GetRule gets a care rule by ID
+func (s *careService) GetRule(ctx context.Context, id uint) (*model.CareRule, error) {
+	return s.repo.FindRuleByID(ctx, id)
+}
+
+// ListRules lists all care rules, including archived ones
+func (s *careService) ListRules(ctx context.Context) ([]*model.CareRule, error) {
+	return s.repo.FindRules(ctx)
+}
+
+// UpdateRule replaces the criteria of a care rule. Reminders already sent are not revisited.
+func (s *careService) UpdateRule(ctx context.Context, id uint, rule *model.CareRule) (*model.CareRule, error) {
+	existing, err := s.repo.FindRuleByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validate(ctx, rule); err != nil {
+		return nil, err
+	}
+
+	rule.ID = existing.ID
+	rule.Active = existing.Active
+	rule.CreatedAt = existing.CreatedAt
+	rule.UpdatedAt = time.Now()
+	if err := s.repo.UpdateRule(ctx, rule); err != nil {
+		return nil, fmt.Errorf("failed to update care rule: %w", err)
+	}
+	return rule, nil
+}
+
+// ArchiveRule stops reminders for a care rule and hides its outstanding reminders
+func (s *careService) ArchiveRule(ctx context.Context, id uint) error {
+	rule, err := s.repo.FindRuleByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	rule.Active = false
+	rule.UpdatedAt = time.Now()
+	if err := s.repo.UpdateRule(ctx, rule); err != nil {
+		return fmt.Errorf("failed to archive care rule: %w", err)
+	}
+	return nil
+}
+
+// GetPatientReminders lists the care items a patient has been reminded of and not yet booked
+func (s *careService) GetPatientReminders(ctx context.Context, patientID uint) ([]*model.CareReminder, error) {
+	return s.repo.FindOutstandingReminders(ctx, patientID)
+}
+
+// validate checks a care rule before it is saved
+func (s *careService) validate(ctx context.Context, rule *model.CareRule) error {
+	rule.Name = strings.TrimSpace(rule.Name)
+	if rule.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidCareRule)
+	}
+	rule.Sex = strings.ToLower(strings.TrimSpace(rule.Sex))
+	if rule.Sex != "" && rule.Sex != "female" && rule.Sex != "male" {
+		return fmt.Errorf("%w: sex must be female, male or empty", ErrInvalidCareRule)
+	}
+	if rule.MinAge < 0 || rule.MaxAge < 0 || (rule.MaxAge > 0 && rule.MaxAge < rule.MinAge) {
+		return fmt.Errorf("%w: ages must not be negative and max_age must not be below min_age", ErrInvalidCareRule)
+	}
+	if rule.IntervalMonths < 1 || rule.IntervalMonths > 120 {
+		return fmt.Errorf("%w: interval_months must be between 1 and 120", ErrInvalidCareRule)
+	}
+
+	appointmentType, err := s.typeRepo.FindByID(ctx, rule.AppointmentTypeID)
+	if err != nil || !appointmentType.Active {
+		return fmt.Errorf("%w: appointment_type_id must be an active appointment type", ErrInvalidCareRule)
+	}
+	rule.AppointmentType = appointmentType
+	return nil
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/repository"
+	"go.uber.org/zap"
+)
+
+// CleanupJob periodically deletes expired verification tokens and sessions, and outbox events
+// and operations finished longer ago than their retention periods
+type CleanupJob struct {
+	authRepo           repository.AuthRepository
+	sessionRepo        repository.SessionRepository
+	outboxRepo         repository.OutboxRepository
+	operationRepo      repository.OperationRepository
+	outboxRetention    time.Duration
+	operationRetention time.Duration
+	interval           time.Duration
+	monitor            *JobMonitor
+	logger             *zap.Logger
+}
+
+// NewCleanupJob creates a new cleanup job
+func NewCleanupJob(
+	authRepo repository.AuthRepository,
+	sessionRepo repository.SessionRepository,
+	outboxRepo repository.OutboxRepository,
+	operationRepo repository.OperationRepository,
+	outboxRetention time.Duration,
+	operationRetention time.Duration,
+	interval time.Duration,
+	monitor *JobMonitor,
+	logger *zap.Logger,
+) *CleanupJob {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+
+	j := &CleanupJob{
+		authRepo:           authRepo,
+		sessionRepo:        sessionRepo,
+		outboxRepo:         outboxRepo,
+		operationRepo:      operationRepo,
+		outboxRetention:    outboxRetention,
+		operationRetention: operationRetention,
+		interval:           interval,
+		monitor:            monitor,
+		logger:             logger,
+	}
+	monitor.Register(JobCleanup, interval, j.RunOnce)
+	return j
+}
+
+// Start cleans up in the background until the returned function is called
+func (j *CleanupJob) Start() func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(j.interval)
+		defer ticker.Stop()
+
+		for {
+			_ = j.monitor.Do(ctx, JobCleanup, j.RunOnce)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		wg.Wait()
+	}
+}
+
+// RunOnce deletes expired tokens and sessions, and delivered outbox events and finished
+// operations past retention
+func (j *CleanupJob) RunOnce(ctx context.Context) error {
+	if err := j.authRepo.DeleteExpiredTokens(ctx); err != nil {
+		j.logger.Error("Failed to delete expired tokens", zap.Error(err))
+		return fmt.Errorf("failed to delete expired tokens: %w", err)
+	}
+	if err := j.sessionRepo.DeleteExpired(ctx); err != nil {
+		j.logger.Error("Failed to delete expired sessions", zap.Error(err))
+		return fmt.Errorf("failed to delete expired sessions: %w", err)
+	}
+	if j.outboxRetention > 0 {
+		if _, err := j.outboxRepo.DeleteDispatchedBefore(ctx, time.Now().Add(-j.outboxRetention)); err != nil {
+			j.logger.Error("Failed to delete dispatched outbox events", zap.Error(err))
+			return fmt.Errorf("failed to delete dispatched outbox events: %w", err)
+		}
+	}
+	if j.operationRetention > 0 {
+		if _, err := j.operationRepo.DeleteCompletedBefore(ctx, time.Now().Add(-j.operationRetention)); err != nil {
+			j.logger.Error("Failed to delete finished operations", zap.Error(err))
+			return fmt.Errorf("failed to delete finished operations: %w", err)
+		}
+	}
+	return nil
+}
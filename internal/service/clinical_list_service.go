@@ -0,0 +1,364 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/repository"
+	"github.com/whitewalker-sa/ehass/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// Audit actions for allergy and medication lists
+const (
+	AuditActionAllergyCreated    = "allergy.created"
+	AuditActionAllergyUpdated    = "allergy.updated"
+	AuditActionAllergyDeleted    = "allergy.deleted"
+	AuditActionMedicationCreated = "patient_medication.created"
+	AuditActionMedicationUpdated = "patient_medication.updated"
+	AuditActionMedicationDeleted = "patient_medication.deleted"
+)
+
+var (
+	// ErrInvalidAllergy is returned when an allergy fails validation
+	ErrInvalidAllergy = errors.New("invalid allergy")
+	// ErrInvalidPatientMedication is returned when a medication list entry fails validation
+	ErrInvalidPatientMedication = errors.New("invalid medication list entry")
+)
+
+// AllergyInput is what is recorded about an allergy. Category defaults to other; dates are
+// days, without a time of day.
+type AllergyInput struct {
+	Substance    string
+	Category     model.AllergyCategory
+	Reaction     string
+	Severity     model.AllergySeverity
+	OnsetDate    *time.Time
+	ResolvedDate *time.Time
+}
+
+// PatientMedicationInput is what is recorded about a medication a patient takes. Either
+// MedicationID picks a catalog product, or Name describes the medication as reported.
+type PatientMedicationInput struct {
+	MedicationID uint
+	Name         string
+	Dosage       string
+	Frequency    string
+	Reason       string
+	StartDate    *time.Time
+	StopDate     *time.Time
+}
+
+type clinicalListService struct {
+	repo             repository.ClinicalListRepository
+	prescriptionRepo repository.PrescriptionRepository
+	auditLogRepo     repository.AuditLogRepository
+	access           recordAccess
+	logger           *zap.Logger
+}
+
+// NewClinicalListService creates a new allergy and medication list service. Access to a
+// patient's lists follows their medical records.
+func NewClinicalListService(
+	repo repository.ClinicalListRepository,
+	prescriptionRepo repository.PrescriptionRepository,
+	handoffRepo repository.HandoffRepository,
+	doctorRepo repository.DoctorRepository,
+	patientRepo repository.PatientRepository,
+	auditLogRepo repository.AuditLogRepository,
+	logger *zap.Logger,
+) ClinicalListService {
+	return &clinicalListService{
+		repo:             repo,
+		prescriptionRepo: prescriptionRepo,
+		auditLogRepo:     auditLogRepo,
+		access: recordAccess{
+			doctorRepo:  doctorRepo,
+			patientRepo: patientRepo,
+			handoffRepo: handoffRepo,
+		},
+		logger: logger,
+	}
+}
+
+// CreateAllergy records an allergy of a patient
+func (s *clinicalListService) CreateAllergy(ctx context.Context, userID uint, role model.Role, patientID uint, input AllergyInput) (*model.Allergy, error) {
+	if err := s.authorizeWrite(ctx, userID, role, patientID); err != nil {
+		return nil, err
+	}
+	allergy := &model.Allergy{PatientID: patientID, RecordedByID: &userID}
+	if err := applyAllergyInput(allergy, input); err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateAllergy(ctx, allergy); err != nil {
+		return nil, fmt.Errorf("failed to record allergy: %w", err)
+	}
+	s.audit(ctx, userID, AuditActionAllergyCreated, "allergy", allergy.ID)
+	return allergy, nil
+}
+
+// ListAllergies lists a patient's allergies, active ones most severe first
+func (s *clinicalListService) ListAllergies(ctx context.Context, userID uint, role model.Role, patientID uint, activeOnly bool) ([]*model.Allergy, error) {
+	if err := s.access.authorizeRead(ctx, userID, role, patientID); err != nil {
+		return nil, err
+	}
+	return s.repo.FindAllergies(ctx, patientID, activeOnly)
+}
+
+// UpdateAllergy replaces what is recorded about one of a patient's allergies, e.g. to mark it
+// resolved
+func (s *clinicalListService) UpdateAllergy(ctx context.Context, userID uint, role model.Role, patientID, id uint, input AllergyInput) (*model.Allergy, error) {
+	if err := s.authorizeWrite(ctx, userID, role, patientID); err != nil {
+		return nil, err
+	}
+	allergy, err := s.patientAllergy(ctx, patientID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyAllergyInput(allergy, input); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateAllergy(ctx, allergy); err != nil {
+		return nil, fmt.Errorf("failed to update allergy: %w", err)
+	}
+	s.audit(ctx, userID, AuditActionAllergyUpdated, "allergy", allergy.ID)
+	return allergy, nil
+}
+
+// DeleteAllergy deletes an allergy recorded in error. Allergies that no longer apply are marked
+// resolved instead.
+func (s *clinicalListService) DeleteAllergy(ctx context.Context, userID uint, role model.Role, patientID, id uint) error {
+	if err := s.authorizeWrite(ctx, userID, role, patientID); err != nil {
+		return err
+	}
+	allergy, err := s.patientAllergy(ctx, patientID, id)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.DeleteAllergy(ctx, allergy.ID); err != nil {
+		return fmt.Errorf("failed to delete allergy: %w", err)
+	}
+	s.audit(ctx, userID, AuditActionAllergyDeleted, "allergy", allergy.ID)
+	return nil
+}
+
+// CreateMedication adds a medication to a patient's medication list
+func (s *clinicalListService) CreateMedication(ctx context.Context, userID uint, role model.Role, patientID uint, input PatientMedicationInput) (*model.PatientMedication, error) {
+	if err := s.authorizeWrite(ctx, userID, role, patientID); err != nil {
+		return nil, err
+	}
+	medication := &model.PatientMedication{PatientID: patientID, RecordedByID: &userID}
+	if err := s.applyMedicationInput(ctx, medication, input); err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateMedication(ctx, medication); err != nil {
+		return nil, fmt.Errorf("failed to add medication: %w", err)
+	}
+	s.audit(ctx, userID, AuditActionMedicationCreated, "patient_medication", medication.ID)
+	return medication, nil
+}
+
+// ListMedications lists a patient's medications, the ones still taken first
+func (s *clinicalListService) ListMedications(ctx context.Context, userID uint, role model.Role, patientID uint, activeOnly bool) ([]*model.PatientMedication, error) {
+	if err := s.access.authorizeRead(ctx, userID, role, patientID); err != nil {
+		return nil, err
+	}
+	return s.repo.FindMedications(ctx, patientID, activeOnly)
+}
+
+// UpdateMedication replaces what is recorded about a medication on a patient's list, e.g. to
+// record that they stopped taking it
+func (s *clinicalListService) UpdateMedication(ctx context.Context, userID uint, role model.Role, patientID, id uint, input PatientMedicationInput) (*model.PatientMedication, error) {
+	if err := s.authorizeWrite(ctx, userID, role, patientID); err != nil {
+		return nil, err
+	}
+	medication, err := s.patientMedication(ctx, patientID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyMedicationInput(ctx, medication, input); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateMedication(ctx, medication); err != nil {
+		return nil, fmt.Errorf("failed to update medication: %w", err)
+	}
+	s.audit(ctx, userID, AuditActionMedicationUpdated, "patient_medication", medication.ID)
+	return medication, nil
+}
+
+// DeleteMedication removes a medication added to a patient's list in error. Medications the
+// patient stopped taking are given a stop date instead.
+func (s *clinicalListService) DeleteMedication(ctx context.Context, userID uint, role model.Role, patientID, id uint) error {
+	if err := s.authorizeWrite(ctx, userID, role, patientID); err != nil {
+		return err
+	}
+	medication, err := s.patientMedication(ctx, patientID, id)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.DeleteMedication(ctx, medication.ID); err != nil {
+		return fmt.Errorf("failed to delete medication: %w", err)
+	}
+	s.audit(ctx, userID, AuditActionMedicationDeleted, "patient_medication", medication.ID)
+	return nil
+}
+
+// authorizeWrite checks that the user signed in as userID may maintain the patient's lists:
+// doctors treating the patient, and the patient or their guardian
+func (s *clinicalListService) authorizeWrite(ctx context.Context, userID uint, role model.Role, patientID uint) error {
+	switch role {
+	case model.RoleDoctor:
+		_, err := s.access.treatingDoctor(ctx, userID, patientID)
+		return err
+	case model.RolePatient:
+		return s.access.authorizeRead(ctx, userID, role, patientID)
+	default:
+		return ErrNotOwnMedicalRecord
+	}
+}
+
+// patientAllergy finds an allergy, treating one of another patient as not found
+func (s *clinicalListService) patientAllergy(ctx context.Context, patientID, id uint) (*model.Allergy, error) {
+	allergy, err := s.repo.FindAllergy(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if allergy.PatientID != patientID {
+		return nil, errors.New("allergy not found")
+	}
+	return allergy, nil
+}
+
+// patientMedication finds a medication list entry, treating one of another patient as not found
+func (s *clinicalListService) patientMedication(ctx context.Context, patientID, id uint) (*model.PatientMedication, error) {
+	medication, err := s.repo.FindMedication(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if medication.PatientID != patientID {
+		return nil, errors.New("medication list entry not found")
+	}
+	return medication, nil
+}
+
+// applyAllergyInput validates input and copies it onto allergy
+func applyAllergyInput(allergy *model.Allergy, input AllergyInput) error {
+	substance := strings.TrimSpace(input.Substance)
+	reaction := strings.TrimSpace(input.Reaction)
+	switch {
+	case substance == "":
+		return fmt.Errorf("%w: substance is required", ErrInvalidAllergy)
+	case len([]rune(substance)) > 150:
+		return fmt.Errorf("%w: substance must be at most 150 characters", ErrInvalidAllergy)
+	case len([]rune(reaction)) > 255:
+		return fmt.Errorf("%w: reaction must be at most 255 characters", ErrInvalidAllergy)
+	}
+
+	category := input.Category
+	if category == "" {
+		category = model.AllergyCategoryOther
+	}
+	if !category.IsValid() {
+		return fmt.Errorf("%w: category must be medication, food, environmental or other", ErrInvalidAllergy)
+	}
+	if input.Severity != "" && !input.Severity.IsValid() {
+		return fmt.Errorf("%w: severity must be mild, moderate or severe", ErrInvalidAllergy)
+	}
+	if err := checkListDates(input.OnsetDate, input.ResolvedDate); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidAllergy, err)
+	}
+
+	// Medication allergies are matched against generic drug names, which the catalog keeps in
+	// lowercase
+	if category == model.AllergyCategoryMedication {
+		substance = strings.ToLower(substance)
+	}
+	allergy.Substance = substance
+	allergy.Category = category
+	allergy.Reaction = reaction
+	allergy.Severity = input.Severity
+	allergy.OnsetDate = input.OnsetDate
+	allergy.ResolvedDate = input.ResolvedDate
+	return nil
+}
+
+// applyMedicationInput validates input and copies it onto medication, taking the name of a
+// catalog product from the catalog
+func (s *clinicalListService) applyMedicationInput(ctx context.Context, medication *model.PatientMedication, input PatientMedicationInput) error {
+	name := strings.TrimSpace(input.Name)
+	dosage := strings.TrimSpace(input.Dosage)
+	frequency := strings.TrimSpace(input.Frequency)
+	reason := strings.TrimSpace(input.Reason)
+	switch {
+	case len([]rune(dosage)) > 100:
+		return fmt.Errorf("%w: dosage must be at most 100 characters", ErrInvalidPatientMedication)
+	case len([]rune(frequency)) > 100:
+		return fmt.Errorf("%w: frequency must be at most 100 characters", ErrInvalidPatientMedication)
+	case len([]rune(reason)) > 255:
+		return fmt.Errorf("%w: reason must be at most 255 characters", ErrInvalidPatientMedication)
+	}
+	if err := checkListDates(input.StartDate, input.StopDate); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPatientMedication, err)
+	}
+
+	medication.MedicationID = nil
+	medication.Medication = nil
+	if input.MedicationID != 0 {
+		product, err := s.prescriptionRepo.FindMedication(ctx, input.MedicationID)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidPatientMedication, err)
+		}
+		medication.MedicationID = &product.ID
+		medication.Medication = product
+		name = fmt.Sprintf("%s %s %s", product.Name, product.Strength, product.Form)
+	}
+	switch {
+	case name == "":
+		return fmt.Errorf("%w: medication_id or name is required", ErrInvalidPatientMedication)
+	case len([]rune(name)) > 150:
+		return fmt.Errorf("%w: name must be at most 150 characters", ErrInvalidPatientMedication)
+	}
+
+	medication.Name = name
+	medication.Dosage = dosage
+	medication.Frequency = frequency
+	medication.Reason = reason
+	medication.StartDate = input.StartDate
+	medication.StopDate = input.StopDate
+	return nil
+}
+
+// checkListDates checks the start and end days of an allergy or medication: the start cannot be
+// in the future, and the end cannot come before the start
+func checkListDates(start, end *time.Time) error {
+	if start != nil && start.After(time.Now()) {
+		return errors.New("start date must not be in the future")
+	}
+	if start != nil && end != nil && end.Before(*start) {
+		return errors.New("end date must not be before the start date")
+	}
+	return nil
+}
+
+// audit records a change to an allergy or medication list; the entries themselves are not logged
+func (s *clinicalListService) audit(ctx context.Context, userID uint, action, entityType string, id uint) {
+	client := utils.ClientInfoFromContext(ctx)
+	if err := s.auditLogRepo.Create(ctx, &model.AuditLog{
+		UserID:     userID,
+		Action:     action,
+		EntityID:   id,
+		EntityType: entityType,
+		IP:         client.IP,
+		UserAgent:  client.UserAgent,
+		CreatedAt:  time.Now(),
+	}); err != nil {
+		s.logger.Error("Failed to write clinical list audit log",
+			zap.String("action", action),
+			zap.Uint("entityID", id),
+			zap.Error(err))
+	}
+}
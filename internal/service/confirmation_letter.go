@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/pkg/pdf"
+	"github.com/whitewalker-sa/ehass/pkg/utils"
+)
+
+// GenerateConfirmationLetter renders the printable confirmation letter of an appointment, with
+// times in the patient's timezone
+func (s *appointmentService) GenerateConfirmationLetter(ctx context.Context, id uint) ([]byte, error) {
+	appointment, err := s.appointmentRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if appointment.Status == model.AppointmentStatusCancelled {
+		return nil, errors.New("cannot issue a confirmation letter for a cancelled appointment")
+	}
+	org, err := s.orgService.GetDoctorOrganization(ctx, appointment.DoctorID)
+	if err != nil {
+		return nil, err
+	}
+	return confirmationLetter(appointment, org), nil
+}
+
+// confirmationLetterFilename names the confirmation letter of an appointment when it is
+// downloaded or attached to an email
+func confirmationLetterFilename(appointment *model.Appointment) string {
+	return fmt.Sprintf("appointment-%s.pdf", appointment.PublicID)
+}
+
+// confirmationLetter renders a letter confirming the appointment's details, how to reach the
+// clinic and how to prepare. The appointment must be loaded with its patient, doctor and type.
+func confirmationLetter(appointment *model.Appointment, org *model.Organization) []byte {
+	patient := &appointment.Patient.User
+	loc := utils.LoadLocation(patient.Timezone)
+	start := appointment.ScheduledStart.In(loc)
+
+	doc := pdf.New("Appointment confirmation " + appointment.PublicID)
+	doc.SetHeadingColor(org.PrimaryColor)
+	doc.Heading(org.DisplayName())
+	var contact []string
+	for _, line := range []string{org.Address, org.ContactPhone, org.ContactEmail} {
+		if line != "" {
+			contact = append(contact, line)
+		}
+	}
+	if len(contact) > 0 {
+		doc.Text(strings.Join(contact, " | "))
+	}
+	if org.PrimaryColor != "" {
+		doc.Rule()
+	}
+	doc.Spacer(16)
+
+	doc.Heading("Appointment Confirmation")
+	doc.Text(fmt.Sprintf("Dear %s,", patient.Name))
+	doc.Text("This letter confirms your appointment. Please keep it for your records.")
+	doc.Spacer(8)
+
+	doctor := appointment.Doctor.User.Name
+	if appointment.Doctor.Specialty != "" {
+		doctor += " (" + appointment.Doctor.Specialty + ")"
+	}
+	rows := [][]string{
+		{"Date", start.Format("Monday, 2 January 2006")},
+		{"Time", fmt.Sprintf("%s - %s (%s)", start.Format("15:04"), appointment.ScheduledEnd.In(loc).Format("15:04"), loc)},
+		{"Doctor", doctor},
+		{"Type", appointmentTypeLabel(appointment)},
+		{"How", modalityLabel(appointment.Modality)},
+	}
+	if appointment.Reason != "" {
+		rows = append(rows, []string{"Reason", appointment.Reason})
+	}
+	rows = append(rows, []string{"Reference", appointment.PublicID})
+	doc.Table([]pdf.Column{
+		{Title: "Appointment", Width: 0.25},
+		{Title: "", Width: 0.75},
+	}, rows)
+
+	if appointment.Modality == model.ModalityInPerson && (org.Address != "" || org.Directions != "") {
+		doc.Spacer(12)
+		doc.Heading("Getting Here")
+		if org.Address != "" {
+			doc.Text(org.Address)
+		}
+		if org.Directions != "" {
+			doc.Text(org.Directions)
+		}
+	}
+
+	if appointment.AppointmentType != nil && appointment.AppointmentType.Preparation != "" {
+		doc.Spacer(12)
+		doc.Heading("Before Your Appointment")
+		doc.Text(appointment.AppointmentType.Preparation)
+	}
+
+	doc.Spacer(12)
+	doc.Text("If you can no longer attend, please cancel or reschedule as early as possible.")
+	doc.Text("Issued " + time.Now().In(loc).Format("2006-01-02 15:04 MST") + ".")
+	return doc.Bytes()
+}
+
+// modalityLabel describes how an appointment takes place for patients
+func modalityLabel(modality model.AppointmentModality) string {
+	switch modality {
+	case model.ModalityVideo:
+		return "Video call"
+	case model.ModalityPhone:
+		return "Phone call"
+	default:
+		return "In person"
+	}
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/config"
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/repository"
+	"go.uber.org/zap"
+)
+
+type consentService struct {
+	repo     repository.ConsentRepository
+	policies []model.Policy
+	logger   *zap.Logger
+}
+
+// NewConsentService creates a new consent service for the configured policies
+func NewConsentService(repo repository.ConsentRepository, cfg *config.Config, logger *zap.Logger) ConsentService {
+	policies := make([]model.Policy, 0, len(cfg.Consent.Policies))
+	for _, p := range cfg.Consent.Policies {
+		policies = append(policies, model.Policy{
+			Type:     model.PolicyType(p.Type),
+			Version:  p.Version,
+			Title:    p.Title,
+			URL:      p.URL,
+			Required: p.Required,
+		})
+	}
+
+	return &consentService{
+		repo:     repo,
+		policies: policies,
+		logger:   logger,
+	}
+}
+
+// GetCurrentPolicies returns the currently published policy versions
+func (s *consentService) GetCurrentPolicies(ctx context.Context) []model.Policy {
+	return s.policies
+}
+
+// GetUserConsents returns all consents recorded for a user
+func (s *consentService) GetUserConsents(ctx context.Context, userID uint) ([]*model.Consent, error) {
+	return s.repo.FindByUserID(ctx, userID)
+}
+
+// AcceptPolicy records the user's acceptance of the current version of a policy
+func (s *consentService) AcceptPolicy(ctx context.Context, userID uint, policyType model.PolicyType, version, ip, userAgent string) (*model.Consent, error) {
+	policy, ok := s.findPolicy(policyType)
+	if !ok {
+		return nil, fmt.Errorf("unknown policy: %s", policyType)
+	}
+
+	if policy.Version != version {
+		return nil, fmt.Errorf("version %s is not the current version of %s", version, policyType)
+	}
+
+	accepted, err := s.repo.Exists(ctx, userID, policyType, version)
+	if err != nil {
+		s.logger.Error("Failed to check existing consent", zap.Error(err))
+		return nil, errors.New("failed to record consent")
+	}
+	if accepted {
+		return nil, errors.New("policy version already accepted")
+	}
+
+	consent := &model.Consent{
+		UserID:     userID,
+		PolicyType: policyType,
+		Version:    version,
+		AcceptedAt: time.Now(),
+		IP:         ip,
+		UserAgent:  userAgent,
+		CreatedAt:  time.Now(),
+	}
+
+	if err := s.repo.Create(ctx, consent); err != nil {
+		s.logger.Error("Failed to record consent", zap.Error(err))
+		return nil, errors.New("failed to record consent")
+	}
+
+	return consent, nil
+}
+
+// GetMissingConsents returns the required policies the user has not accepted in their current version
+func (s *consentService) GetMissingConsents(ctx context.Context, userID uint) ([]model.Policy, error) {
+	var missing []model.Policy
+	for _, policy := range s.policies {
+		if !policy.Required {
+			continue
+		}
+
+		accepted, err := s.repo.Exists(ctx, userID, policy.Type, policy.Version)
+		if err != nil {
+			return nil, err
+		}
+		if !accepted {
+			missing = append(missing, policy)
+		}
+	}
+
+	return missing, nil
+}
+
+// GetMarketingConsents returns the user's current marketing choice on each channel. Channels
+// without a choice are opted out.
+func (s *consentService) GetMarketingConsents(ctx context.Context, userID uint) ([]*model.MarketingConsent, error) {
+	return s.repo.FindMarketingConsents(ctx, userID)
+}
+
+// SetMarketingConsent records the user opting in to or out of promotional messages on a channel.
+// Every change is kept, with where it was made from.
+func (s *consentService) SetMarketingConsent(ctx context.Context, userID uint, channel string, granted bool, ip, userAgent string) (*model.MarketingConsent, error) {
+	if channel != model.ChannelEmail && channel != model.ChannelSMS {
+		return nil, fmt.Errorf("unknown channel: %s", channel)
+	}
+
+	consent := &model.MarketingConsent{
+		UserID:    userID,
+		Channel:   channel,
+		Granted:   granted,
+		IP:        ip,
+		UserAgent: userAgent,
+	}
+	if err := s.repo.CreateMarketingConsent(ctx, consent); err != nil {
+		s.logger.Error("Failed to record marketing consent", zap.Error(err))
+		return nil, errors.New("failed to record marketing consent")
+	}
+
+	s.logger.Info("Marketing consent changed",
+		zap.Uint("userID", userID),
+		zap.String("channel", channel),
+		zap.Bool("granted", granted))
+	return consent, nil
+}
+
+// findPolicy finds the current policy of the given type
+func (s *consentService) findPolicy(policyType model.PolicyType) (model.Policy, bool) {
+	for _, policy := range s.policies {
+		if policy.Type == policyType {
+			return policy, true
+		}
+	}
+	return model.Policy{}, false
+}
@@ -0,0 +1,34 @@
+package service
+
+import (
+	"net/url"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+)
+
+// Directions tell a patient how to get to an in-person appointment
+type Directions struct {
+	Address string
+	MapURL  string // Opens the clinic in a maps app, which routes there with the current travel time
+	Arrival string // Parking and where to report on arrival
+}
+
+// appointmentDirections returns the directions to the clinic of an in-person appointment, or
+// nil for video and phone appointments and for clinics without an address or directions
+func appointmentDirections(appointment *model.Appointment, org *model.Organization) *Directions {
+	if appointment.Modality != model.ModalityInPerson || org == nil {
+		return nil
+	}
+	if org.Address == "" && org.Directions == "" && org.MapURL == "" {
+		return nil
+	}
+	directions := &Directions{
+		Address: org.Address,
+		MapURL:  org.MapURL,
+		Arrival: org.Directions,
+	}
+	if directions.MapURL == "" && org.Address != "" {
+		directions.MapURL = "https://www.google.com/maps/dir/?api=1&destination=" + url.QueryEscape(org.Address)
+	}
+	return directions
+}
@@ -2,6 +2,8 @@ package service
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
@@ -10,16 +12,31 @@ import (
 	"go.uber.org/zap"
 )
 
+var (
+	// ErrNotOwnStatus is returned when a user sets the status of a doctor other than themselves
+	ErrNotOwnStatus = errors.New("doctors can only set their own status")
+	// ErrNotOwnSettings is returned when a user changes the settings of a doctor other than themselves
+	ErrNotOwnSettings = errors.New("doctors can only change their own settings")
+	// ErrInvalidScheduling is returned when a consultation length, buffer or number of parallel
+	// bookings is out of range
+	ErrInvalidScheduling = errors.New("invalid scheduling settings")
+)
+
+// maxParallelBookings caps how far a doctor can overbook one time
+const maxParallelBookings = 5
+
 type doctorService struct {
-	repo   repository.DoctorRepository
-	logger *zap.Logger
+	repo      repository.DoctorRepository
+	slotCache *SlotCache
+	logger    *zap.Logger
 }
 
 // NewDoctorService creates a new doctor service
-func NewDoctorService(repo repository.DoctorRepository, logger *zap.Logger) DoctorService {
+func NewDoctorService(repo repository.DoctorRepository, slotCache *SlotCache, logger *zap.Logger) DoctorService {
 	return &doctorService{
-		repo:   repo,
-		logger: logger,
+		repo:      repo,
+		slotCache: slotCache,
+		logger:    logger,
 	}
 }
 
@@ -27,6 +44,7 @@ func NewDoctorService(repo repository.DoctorRepository, logger *zap.Logger) Doct
 func (s *doctorService) CreateDoctor(ctx context.Context, userID uint, specialty, education string, experience int) (*model.Doctor, error) {
 	// Create doctor model
 	doctor := &model.Doctor{
+		PublicID:   model.NewPublicID(),
 		UserID:     userID,
 		Specialty:  specialty,
 		Education:  education,
@@ -36,7 +54,7 @@ func (s *doctorService) CreateDoctor(ctx context.Context, userID uint, specialty
 	}
 
 	// Call repository to save doctor
-	if err := s.repo.Create(ctx, doctor); err != nil {
+	if err := s.repo.Create(ctx, doctor, searchSyncEvent(model.EventDoctorUpdated, doctor.PublicID)); err != nil {
 		return nil, fmt.Errorf("failed to create doctor profile: %w", err)
 	}
 
@@ -48,31 +66,73 @@ func (s *doctorService) GetDoctorByID(ctx context.Context, id uint) (*model.Doct
 	return s.repo.FindByID(ctx, id)
 }
 
+// GetDoctorsByPublicIDs retrieves several doctors in one query. It returns the doctors found in
+// the order requested and the IDs that matched none.
+func (s *doctorService) GetDoctorsByPublicIDs(ctx context.Context, publicIDs []string) ([]*model.Doctor, []string, error) {
+	publicIDs, err := batchPublicIDs(model.ResourceDoctor, publicIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	found, err := s.repo.FindByPublicIDs(ctx, publicIDs)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get doctors: %w", err)
+	}
+
+	byID := make(map[string]*model.Doctor, len(found))
+	for _, doctor := range found {
+		byID[doctor.PublicID] = doctor
+	}
+	doctors := make([]*model.Doctor, 0, len(found))
+	missing := []string{}
+	for _, id := range publicIDs {
+		if doctor, ok := byID[id]; ok {
+			doctors = append(doctors, doctor)
+		} else {
+			missing = append(missing, id)
+		}
+	}
+	return doctors, missing, nil
+}
+
 // GetDoctorByUserID retrieves a doctor by user ID
 func (s *doctorService) GetDoctorByUserID(ctx context.Context, userID uint) (*model.Doctor, error) {
 	return s.repo.FindByUserID(ctx, userID)
 }
 
-// GetAllDoctors retrieves all doctors with pagination
-func (s *doctorService) GetAllDoctors(ctx context.Context, page, pageSize int) ([]*model.Doctor, int64, error) {
+// GetAllDoctors retrieves all doctors with pagination, sorted and loading only the fields the
+// query asks for
+func (s *doctorService) GetAllDoctors(ctx context.Context, page, pageSize int, query ListQuery) ([]*model.Doctor, int64, error) {
+	opts, err := listOptions("doctor", doctorFields, query)
+	if err != nil {
+		return nil, 0, err
+	}
+
 	// Calculate offset for pagination
 	offset := (page - 1) * pageSize
 	if offset < 0 {
 		offset = 0
 	}
 
-	return s.repo.FindAll(ctx, pageSize, offset)
+	doctors, total, err := s.repo.FindAll(ctx, pageSize, offset, opts)
+	return doctors, total, listError(err)
 }
 
-// GetDoctorsBySpecialty retrieves doctors by specialty with pagination
-func (s *doctorService) GetDoctorsBySpecialty(ctx context.Context, specialty string, page, pageSize int) ([]*model.Doctor, int64, error) {
+// GetDoctorsBySpecialty retrieves doctors by specialty with pagination, shaped by the list query
+func (s *doctorService) GetDoctorsBySpecialty(ctx context.Context, specialty string, page, pageSize int, query ListQuery) ([]*model.Doctor, int64, error) {
+	opts, err := listOptions("doctor", doctorFields, query)
+	if err != nil {
+		return nil, 0, err
+	}
+
 	// Calculate offset for pagination
 	offset := (page - 1) * pageSize
 	if offset < 0 {
 		offset = 0
 	}
 
-	return s.repo.FindBySpecialty(ctx, specialty, pageSize, offset)
+	doctors, total, err := s.repo.FindBySpecialty(ctx, specialty, pageSize, offset, opts)
+	return doctors, total, listError(err)
 }
 
 // UpdateDoctorProfile updates doctor profile information
@@ -86,7 +146,7 @@ func (s *doctorService) UpdateDoctorProfile(ctx context.Context, id uint, specia
 	doctor.Bio = bio
 	doctor.Experience = experience
 
-	err = s.repo.Update(ctx, doctor)
+	err = s.repo.Update(ctx, doctor, searchSyncEvent(model.EventDoctorUpdated, doctor.PublicID))
 	if err != nil {
 		return nil, err
 	}
@@ -96,10 +156,140 @@ func (s *doctorService) UpdateDoctorProfile(ctx context.Context, id uint, specia
 
 // UpdateDoctor updates doctor information
 func (s *doctorService) UpdateDoctor(ctx context.Context, doctor *model.Doctor) error {
-	return s.repo.Update(ctx, doctor)
+	return s.repo.Update(ctx, doctor, searchSyncEvent(model.EventDoctorUpdated, doctor.PublicID))
+}
+
+// SetStatus sets the status of the doctor signed in as userID, or clears it with an empty status
+// so it is derived from their schedule again. Subscribers are told of the change through a
+// doctor.status_changed event.
+func (s *doctorService) SetStatus(ctx context.Context, id, userID uint, status model.DoctorStatus) (*model.Doctor, error) {
+	if status != "" && !status.IsValid() {
+		return nil, fmt.Errorf("invalid doctor status %q", status)
+	}
+
+	doctor, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if doctor.UserID != userID {
+		return nil, ErrNotOwnStatus
+	}
+
+	now := time.Now()
+	doctor.Status = status
+	doctor.StatusSetAt = &now
+	if status == "" {
+		doctor.StatusSetAt = nil
+	}
+
+	payload, err := json.Marshal(doctorStatusEventData{
+		DoctorID:  doctor.PublicID,
+		Status:    string(status),
+		ChangedAt: now,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode event: %w", err)
+	}
+	event := &model.OutboxEvent{
+		Type:        model.EventDoctorStatusChanged,
+		Destination: model.OutboxDestinationEvents,
+		Payload:     string(payload),
+	}
+	if err := s.repo.Update(ctx, doctor, event); err != nil {
+		return nil, fmt.Errorf("failed to set doctor status: %w", err)
+	}
+	return doctor, nil
+}
+
+// doctorStatusEventData is the payload of doctor.status_changed events. Status is empty when the
+// doctor went back to a status derived from their schedule.
+type doctorStatusEventData struct {
+	DoctorID  string    `json:"doctor_id"`
+	Status    string    `json:"status"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+// SetAutoConfirm sets whether new bookings with the doctor signed in as userID are confirmed
+// without waiting for the doctor. Bookings already pending are left for the doctor to review.
+func (s *doctorService) SetAutoConfirm(ctx context.Context, id, userID uint, enabled bool) (*model.Doctor, error) {
+	doctor, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if doctor.UserID != userID {
+		return nil, ErrNotOwnSettings
+	}
+
+	doctor.AutoConfirm = enabled
+	if err := s.repo.Update(ctx, doctor, searchSyncEvent(model.EventDoctorUpdated, doctor.PublicID)); err != nil {
+		return nil, fmt.Errorf("failed to update doctor: %w", err)
+	}
+	return doctor, nil
+}
+
+// SetScheduling sets the consultation length, the buffer time kept free after each appointment
+// and the number of bookings accepted for the same time of the doctor signed in as userID. A slot
+// length of 0 goes back to the clinic's default, and a maxParallel of 0 to one booking at a time.
+// Existing appointments keep their times.
+func (s *doctorService) SetScheduling(ctx context.Context, id, userID uint, slotLength, bufferMinutes, maxParallel int) (*model.Doctor, error) {
+	if slotLength != 0 && (slotLength < 5 || slotLength > 480) {
+		return nil, fmt.Errorf("%w: slot length must be between 5 and 480 minutes", ErrInvalidScheduling)
+	}
+	if bufferMinutes < 0 || bufferMinutes > 120 {
+		return nil, fmt.Errorf("%w: buffer must be between 0 and 120 minutes", ErrInvalidScheduling)
+	}
+	if maxParallel < 0 || maxParallel > maxParallelBookings {
+		return nil, fmt.Errorf("%w: parallel bookings must be between 1 and %d", ErrInvalidScheduling, maxParallelBookings)
+	}
+	if maxParallel == 0 {
+		maxParallel = 1
+	}
+
+	doctor, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if doctor.UserID != userID {
+		return nil, ErrNotOwnSettings
+	}
+
+	doctor.SlotLength = slotLength
+	doctor.BufferMinutes = bufferMinutes
+	doctor.MaxParallel = maxParallel
+	if err := s.repo.Update(ctx, doctor, searchSyncEvent(model.EventDoctorUpdated, doctor.PublicID)); err != nil {
+		return nil, fmt.Errorf("failed to update doctor: %w", err)
+	}
+	s.slotCache.Invalidate(doctor.ID)
+	return doctor, nil
+}
+
+// SetCancellationCutoff sets how many minutes before the start the appointments of the doctor
+// signed in as userID can be cancelled on time. A nil cutoff goes back to the clinic's.
+func (s *doctorService) SetCancellationCutoff(ctx context.Context, id, userID uint, cutoff *int) (*model.Doctor, error) {
+	if cutoff != nil && (*cutoff < 0 || *cutoff > maxCancellationCutoff) {
+		return nil, fmt.Errorf("%w: cancellation cutoff must be between 0 and %d minutes", ErrInvalidScheduling, maxCancellationCutoff)
+	}
+
+	doctor, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if doctor.UserID != userID {
+		return nil, ErrNotOwnSettings
+	}
+
+	doctor.CancellationCutoff = cutoff
+	if err := s.repo.Update(ctx, doctor); err != nil {
+		return nil, fmt.Errorf("failed to update doctor: %w", err)
+	}
+	return doctor, nil
 }
 
 // DeleteDoctor deletes a doctor by ID
 func (s *doctorService) DeleteDoctor(ctx context.Context, id uint) error {
-	return s.repo.Delete(ctx, id)
+	doctor, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	return s.repo.Delete(ctx, id, searchSyncEvent(model.EventDoctorDeleted, doctor.PublicID))
 }
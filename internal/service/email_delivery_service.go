@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/repository"
+	"go.uber.org/zap"
+)
+
+// EmailEventType is the kind of delivery event reported by the email provider
+type EmailEventType string
+
+const (
+	EmailEventDelivered EmailEventType = "delivered"
+	EmailEventBounce    EmailEventType = "bounce"
+	EmailEventComplaint EmailEventType = "complaint"
+)
+
+// EmailEvent is a delivery event reported by the email provider. MessageID is the Message-ID
+// header of the outbound email; Email is the affected recipient.
+type EmailEvent struct {
+	Type       EmailEventType
+	MessageID  string
+	Email      string
+	HardBounce bool
+	Reason     string
+}
+
+type emailDeliveryService struct {
+	repo   repository.EmailRepository
+	logger *zap.Logger
+}
+
+// NewEmailDeliveryService creates a new email delivery service
+func NewEmailDeliveryService(repo repository.EmailRepository, logger *zap.Logger) EmailDeliveryService {
+	return &emailDeliveryService{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// ProcessEvents applies provider delivery events to the recorded emails. Hard bounces and
+// complaints suppress the recipient. Events for unknown messages still suppress the address they
+// name. It returns the number of events applied.
+func (s *emailDeliveryService) ProcessEvents(ctx context.Context, events []EmailEvent) (int, error) {
+	applied := 0
+	for _, event := range events {
+		var message *model.EmailMessage
+		if event.MessageID != "" {
+			if found, err := s.repo.FindMessageByMessageID(ctx, event.MessageID); err == nil {
+				message = found
+			}
+		}
+
+		email := strings.ToLower(strings.TrimSpace(event.Email))
+		if email == "" && message != nil {
+			email = message.Recipient
+		}
+
+		var status model.EmailStatus
+		var reason model.SuppressionReason
+		switch event.Type {
+		case EmailEventDelivered:
+			status = model.EmailStatusDelivered
+		case EmailEventBounce:
+			status = model.EmailStatusBounced
+			if event.HardBounce {
+				reason = model.SuppressionHardBounce
+			}
+		case EmailEventComplaint:
+			status = model.EmailStatusComplained
+			reason = model.SuppressionComplaint
+		default:
+			s.logger.Warn("Ignoring unknown email event", zap.String("type", string(event.Type)))
+			continue
+		}
+
+		if message != nil && canTransition(message.Status, status) {
+			message.Status = status
+			if event.Reason != "" {
+				message.Detail = event.Reason
+			}
+			if err := s.repo.UpdateMessage(ctx, message); err != nil {
+				return applied, fmt.Errorf("failed to update email status: %w", err)
+			}
+		}
+
+		if reason != "" && email != "" {
+			if err := s.repo.Suppress(ctx, &model.EmailSuppression{Email: email, Reason: reason, Detail: event.Reason}); err != nil {
+				return applied, fmt.Errorf("failed to suppress address: %w", err)
+			}
+			s.logger.Info("Email address suppressed", zap.String("reason", string(reason)))
+		}
+		applied++
+	}
+	return applied, nil
+}
+
+// canTransition keeps a late delivery report from overwriting a bounce or complaint
+func canTransition(current, next model.EmailStatus) bool {
+	if next != model.EmailStatusDelivered {
+		return true
+	}
+	return current == model.EmailStatusSent
+}
+
+// ListMessages lists recorded outbound emails, newest first
+func (s *emailDeliveryService) ListMessages(ctx context.Context, recipient string, status model.EmailStatus, page, pageSize int) ([]*model.EmailMessage, int64, error) {
+	offset := (page - 1) * pageSize
+	if offset < 0 {
+		offset = 0
+	}
+
+	return s.repo.FindMessages(ctx, strings.ToLower(strings.TrimSpace(recipient)), status, pageSize, offset)
+}
+
+// ListSuppressions lists suppressed addresses, newest first
+func (s *emailDeliveryService) ListSuppressions(ctx context.Context, page, pageSize int) ([]*model.EmailSuppression, int64, error) {
+	offset := (page - 1) * pageSize
+	if offset < 0 {
+		offset = 0
+	}
+
+	return s.repo.FindSuppressions(ctx, pageSize, offset)
+}
+
+// RemoveSuppression lets an address receive email again, for example after the patient fixed their mailbox
+func (s *emailDeliveryService) RemoveSuppression(ctx context.Context, email string) error {
+	return s.repo.DeleteSuppression(ctx, strings.ToLower(strings.TrimSpace(email)))
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+)
+
+// ErrDisposableEmail is returned when signing up with an address at a disposable email domain
+// while those are blocked
+var ErrDisposableEmail = errors.New("disposable email addresses are not accepted; use a permanent address")
+
+// EmailPolicy decides which email addresses can be used to sign up. With blocking on, addresses
+// at a listed disposable domain or any of its subdomains are refused. A nil policy accepts every
+// address.
+type EmailPolicy struct {
+	blockDisposable bool
+	disposable      map[string]bool
+}
+
+// NewEmailPolicy creates an email policy refusing the given disposable domains when
+// blockDisposable is set
+func NewEmailPolicy(blockDisposable bool, disposableDomains []string) *EmailPolicy {
+	disposable := make(map[string]bool, len(disposableDomains))
+	for _, domain := range disposableDomains {
+		if domain = strings.Trim(strings.ToLower(strings.TrimSpace(domain)), "."); domain != "" {
+			disposable[domain] = true
+		}
+	}
+	return &EmailPolicy{blockDisposable: blockDisposable, disposable: disposable}
+}
+
+// Check returns ErrDisposableEmail for an address the policy refuses
+func (p *EmailPolicy) Check(email string) error {
+	if p == nil || !p.blockDisposable {
+		return nil
+	}
+	email = model.NormalizeEmail(email)
+	domain := email[strings.LastIndex(email, "@")+1:]
+	for domain != "" {
+		if p.disposable[domain] {
+			return ErrDisposableEmail
+		}
+		dot := strings.Index(domain, ".")
+		if dot < 0 {
+			break
+		}
+		domain = domain[dot+1:]
+	}
+	return nil
+}
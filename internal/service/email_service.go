@@ -2,10 +2,69 @@ package service
 
 import (
 	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"html"
+	"net"
+	"net/mail"
 	"net/smtp"
+	"net/textproto"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/repository"
+	"github.com/whitewalker-sa/ehass/pkg/breaker"
+	"go.uber.org/zap"
+)
+
+// Email templates, recorded with each outbound message
+const (
+	EmailTemplateVerification    = "verification"
+	EmailTemplatePasswordReset   = "password_reset"
+	EmailTemplateBreakGlassAlert = "break_glass_alert"
+	EmailTemplateReminder        = "appointment_reminder"
+	EmailTemplateAccountClaim    = "account_claim"
+	EmailTemplateConfirmation    = "appointment_confirmation"
+	EmailTemplateRescheduled     = "appointment_rescheduled"
+	EmailTemplateDoctorMoved     = "doctor_appointment_rescheduled"
+	EmailTemplateCancellation    = "appointment_cancellation"
+	EmailTemplateConfirmed       = "appointment_confirmed"
+	EmailTemplateDeclined        = "appointment_declined"
+	EmailTemplateCareReminder    = "care_reminder"
+	EmailTemplateMarketing       = "marketing"
 )
 
+// EmailAttachment is a file sent along with an email
+type EmailAttachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// ErrEmailSuppressed is returned when an email is not sent because the recipient is suppressed
+var ErrEmailSuppressed = errors.New("email address is suppressed")
+
+// emailPreviewKey marks a context in which emails are rendered into a RenderedMessage rather
+// than sent or recorded
+type emailPreviewKey struct{}
+
+// withEmailPreview returns a context in which emails are rendered into preview
+func withEmailPreview(ctx context.Context, preview *RenderedMessage) context.Context {
+	return context.WithValue(ctx, emailPreviewKey{}, preview)
+}
+
+// emailOrganizationKey marks a context in which emails carry the branding of a clinic other
+// than the default one
+type emailOrganizationKey struct{}
+
+// withEmailOrganization returns a context in which emails are branded and sent as org
+func withEmailOrganization(ctx context.Context, org *model.Organization) context.Context {
+	return context.WithValue(ctx, emailOrganizationKey{}, org)
+}
+
 // emailService implements EmailService interface
 type emailService struct {
 	smtpHost     string
@@ -14,6 +73,11 @@ type emailService struct {
 	smtpPassword string
 	fromEmail    string
 	appBaseURL   string
+	orgRepo      repository.OrganizationRepository
+	emailRepo    repository.EmailRepository
+	sandbox      bool // Record emails without sending them
+	smtpBreaker  *breaker.Breaker
+	logger       *zap.Logger
 }
 
 // NewEmailService creates a new email service
@@ -24,6 +88,11 @@ func NewEmailService(
 	smtpPassword string,
 	fromEmail string,
 	appBaseURL string,
+	orgRepo repository.OrganizationRepository,
+	emailRepo repository.EmailRepository,
+	sandbox bool,
+	smtpBreaker *breaker.Breaker,
+	logger *zap.Logger,
 ) EmailService {
 	return &emailService{
 		smtpHost:     smtpHost,
@@ -32,6 +101,11 @@ func NewEmailService(
 		smtpPassword: smtpPassword,
 		fromEmail:    fromEmail,
 		appBaseURL:   appBaseURL,
+		orgRepo:      orgRepo,
+		emailRepo:    emailRepo,
+		sandbox:      sandbox,
+		smtpBreaker:  smtpBreaker,
+		logger:       logger,
 	}
 }
 
@@ -39,6 +113,7 @@ func NewEmailService(
 func (s *emailService) SendVerificationEmail(ctx context.Context, email, name, token string) error {
 	subject := "Verify Your Email Address"
 	verificationLink := fmt.Sprintf("%s/verify-email?token=%s", s.appBaseURL, token)
+	org := s.organization(ctx)
 
 	body := fmt.Sprintf(`
 	<!DOCTYPE html>
@@ -50,29 +125,32 @@ func (s *emailService) SendVerificationEmail(ctx context.Context, email, name, t
 			.container { max-width: 600px; margin: 0 auto; padding: 20px; }
 			.button { display: inline-block; padding: 10px 20px; background-color: #4CAF50; color: white; 
 				text-decoration: none; border-radius: 5px; }
+			%s
 		</style>
 	</head>
 	<body>
 		<div class="container">
-			<h2>Welcome to EHASS, %s!</h2>
+			%s
+			<h2>Welcome to %s, %s!</h2>
 			<p>Thank you for registering with us. Please verify your email address by clicking the button below:</p>
 			<p><a href="%s" class="button">Verify Email</a></p>
 			<p>Or copy and paste this link in your browser:</p>
 			<p>%s</p>
 			<p>If you didn't register for an account, you can safely ignore this email.</p>
-			<p>Best regards,<br>The EHASS Team</p>
+			%s
 		</div>
 	</body>
 	</html>
-	`, name, verificationLink, verificationLink)
+	`, emailStyle(org), emailHeader(org), html.EscapeString(org.DisplayName()), name, verificationLink, verificationLink, emailSignature(org))
 
-	return s.sendEmail(email, subject, body)
+	return s.sendEmail(ctx, email, EmailTemplateVerification, subject, body)
 }
 
 // SendPasswordResetEmail sends an email with password reset link
 func (s *emailService) SendPasswordResetEmail(ctx context.Context, email, name, token string) error {
 	subject := "Reset Your Password"
 	resetLink := fmt.Sprintf("%s/reset-password?token=%s", s.appBaseURL, token)
+	org := s.organization(ctx)
 
 	body := fmt.Sprintf(`
 	<!DOCTYPE html>
@@ -84,10 +162,12 @@ func (s *emailService) SendPasswordResetEmail(ctx context.Context, email, name,
 			.container { max-width: 600px; margin: 0 auto; padding: 20px; }
 			.button { display: inline-block; padding: 10px 20px; background-color: #4CAF50; color: white; 
 				text-decoration: none; border-radius: 5px; }
+			%s
 		</style>
 	</head>
 	<body>
 		<div class="container">
+			%s
 			<h2>Hello, %s!</h2>
 			<p>We received a request to reset your password. If you didn't make this request, you can safely ignore this email.</p>
 			<p>To reset your password, click the button below:</p>
@@ -95,29 +175,648 @@ func (s *emailService) SendPasswordResetEmail(ctx context.Context, email, name,
 			<p>Or copy and paste this link in your browser:</p>
 			<p>%s</p>
 			<p>This link will expire in 1 hour for security reasons.</p>
-			<p>Best regards,<br>The EHASS Team</p>
+			%s
+		</div>
+	</body>
+	</html>
+	`, emailStyle(org), emailHeader(org), name, resetLink, resetLink, emailSignature(org))
+
+	return s.sendEmail(ctx, email, EmailTemplatePasswordReset, subject, body)
+}
+
+// SendBreakGlassAlert notifies an administrator that a clinician used emergency access to a patient record.
+// expiresAt is already formatted in the recipient's timezone and locale.
+func (s *emailService) SendBreakGlassAlert(ctx context.Context, email, name, clinicianName, patientName, reason, expiresAt string) error {
+	subject := "Emergency Access to Patient Record"
+	org := s.organization(ctx)
+
+	body := fmt.Sprintf(`
+	<!DOCTYPE html>
+	<html>
+	<head>
+		<title>Emergency Access Alert</title>
+		<style>
+			body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+			.container { max-width: 600px; margin: 0 auto; padding: 20px; }
+			.alert { padding: 10px 20px; background-color: #FDECEA; border-left: 4px solid #D32F2F; }
+			%s
+		</style>
+	</head>
+	<body>
+		<div class="container">
+			%s
+			<h2>Hello, %s!</h2>
+			<div class="alert">
+				<p><strong>%s</strong> used break-glass emergency access to the record of <strong>%s</strong>.</p>
+				<p>Reason given: %s</p>
+				<p>Access expires at %s.</p>
+			</div>
+			<p>Please review this access in the audit log.</p>
+			%s
+		</div>
+	</body>
+	</html>
+	`, emailStyle(org), emailHeader(org), html.EscapeString(name), html.EscapeString(clinicianName), html.EscapeString(patientName),
+		html.EscapeString(reason), html.EscapeString(expiresAt), emailSignature(org))
+
+	return s.sendEmail(ctx, email, EmailTemplateBreakGlassAlert, subject, body)
+}
+
+// SendAppointmentReminder reminds a patient of an upcoming appointment and returns the email's
+// Message-ID. startsAt is already formatted in the recipient's timezone and locale. directions,
+// if not nil, are shown with a link to route to the clinic.
+func (s *emailService) SendAppointmentReminder(ctx context.Context, email, name, doctorName, startsAt string, directions *Directions) (string, error) {
+	subject := "Appointment Reminder"
+	org := s.organization(ctx)
+
+	body := fmt.Sprintf(`
+	<!DOCTYPE html>
+	<html>
+	<head>
+		<title>Appointment Reminder</title>
+		<style>
+			body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+			.container { max-width: 600px; margin: 0 auto; padding: 20px; }
+			.button { display: inline-block; padding: 10px 20px; background-color: #4CAF50; color: white;
+				text-decoration: none; border-radius: 5px; }
+			%s
+		</style>
+	</head>
+	<body>
+		<div class="container">
+			%s
+			<h2>Hello, %s!</h2>
+			<p>This is a reminder of your appointment with <strong>%s</strong> on <strong>%s</strong>.</p>
+			%s
+			<p>If you can no longer attend, please cancel or reschedule as early as possible.</p>
+			%s
+		</div>
+	</body>
+	</html>
+	`, emailStyle(org), emailHeader(org), html.EscapeString(name), html.EscapeString(doctorName), html.EscapeString(startsAt), emailDirections(directions), emailSignature(org))
+
+	return s.deliver(ctx, email, EmailTemplateReminder, subject, body)
+}
+
+// SendAppointmentConfirmation confirms a new booking to the patient. startsAt is already
+// formatted in the recipient's timezone and locale.
+func (s *emailService) SendAppointmentConfirmation(ctx context.Context, email, name, doctorName, startsAt string, attachments ...EmailAttachment) error {
+	subject := "Appointment Booked"
+	org := s.organization(ctx)
+
+	body := fmt.Sprintf(`
+	<!DOCTYPE html>
+	<html>
+	<head>
+		<title>Appointment Booked</title>
+		<style>
+			body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+			.container { max-width: 600px; margin: 0 auto; padding: 20px; }
+			%s
+		</style>
+	</head>
+	<body>
+		<div class="container">
+			%s
+			<h2>Hello, %s!</h2>
+			<p>Your appointment with <strong>%s</strong> on <strong>%s</strong> is booked.</p>
+			<p>If you can no longer attend, please cancel or reschedule as early as possible.</p>
+			%s
+		</div>
+	</body>
+	</html>
+	`, emailStyle(org), emailHeader(org), html.EscapeString(name), html.EscapeString(doctorName), html.EscapeString(startsAt), emailSignature(org))
+
+	return s.sendEmail(ctx, email, EmailTemplateConfirmation, subject, body, attachments...)
+}
+
+// SendAppointmentRescheduled tells the patient the new time of a moved appointment
+func (s *emailService) SendAppointmentRescheduled(ctx context.Context, email, name, doctorName, startsAt string, attachments ...EmailAttachment) error {
+	subject := "Appointment Rescheduled"
+	org := s.organization(ctx)
+
+	body := fmt.Sprintf(`
+	<!DOCTYPE html>
+	<html>
+	<head>
+		<title>Appointment Rescheduled</title>
+		<style>
+			body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+			.container { max-width: 600px; margin: 0 auto; padding: 20px; }
+			%s
+		</style>
+	</head>
+	<body>
+		<div class="container">
+			%s
+			<h2>Hello, %s!</h2>
+			<p>Your appointment with <strong>%s</strong> has moved to <strong>%s</strong>.</p>
+			<p>If the new time doesn't suit you, please contact us or reschedule online.</p>
+			%s
 		</div>
 	</body>
 	</html>
-	`, name, resetLink, resetLink)
+	`, emailStyle(org), emailHeader(org), html.EscapeString(name), html.EscapeString(doctorName), html.EscapeString(startsAt), emailSignature(org))
 
-	return s.sendEmail(email, subject, body)
+	return s.sendEmail(ctx, email, EmailTemplateRescheduled, subject, body, attachments...)
 }
 
-// sendEmail sends an email using SMTP
-func (s *emailService) sendEmail(to, subject, body string) error {
+// SendDoctorAppointmentRescheduled tells a doctor that an appointment with a patient has moved
+func (s *emailService) SendDoctorAppointmentRescheduled(ctx context.Context, email, name, patientName, previousStart, startsAt string) error {
+	subject := "Appointment Rescheduled"
+	org := s.organization(ctx)
+
+	body := fmt.Sprintf(`
+	<!DOCTYPE html>
+	<html>
+	<head>
+		<title>Appointment Rescheduled</title>
+		<style>
+			body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+			.container { max-width: 600px; margin: 0 auto; padding: 20px; }
+			%s
+		</style>
+	</head>
+	<body>
+		<div class="container">
+			%s
+			<h2>Hello, %s!</h2>
+			<p>Your appointment with <strong>%s</strong> on <strong>%s</strong> has moved to <strong>%s</strong>.</p>
+			%s
+		</div>
+	</body>
+	</html>
+	`, emailStyle(org), emailHeader(org), html.EscapeString(name), html.EscapeString(patientName), html.EscapeString(previousStart), html.EscapeString(startsAt), emailSignature(org))
+
+	return s.sendEmail(ctx, email, EmailTemplateDoctorMoved, subject, body)
+}
+
+// SendAppointmentCancellation tells the patient an appointment was cancelled
+func (s *emailService) SendAppointmentCancellation(ctx context.Context, email, name, doctorName, startsAt string, attachments ...EmailAttachment) error {
+	subject := "Appointment Cancelled"
+	org := s.organization(ctx)
+
+	body := fmt.Sprintf(`
+	<!DOCTYPE html>
+	<html>
+	<head>
+		<title>Appointment Cancelled</title>
+		<style>
+			body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+			.container { max-width: 600px; margin: 0 auto; padding: 20px; }
+			%s
+		</style>
+	</head>
+	<body>
+		<div class="container">
+			%s
+			<h2>Hello, %s!</h2>
+			<p>Your appointment with <strong>%s</strong> on <strong>%s</strong> has been cancelled.</p>
+			<p>You can book a new appointment online at any time.</p>
+			%s
+		</div>
+	</body>
+	</html>
+	`, emailStyle(org), emailHeader(org), html.EscapeString(name), html.EscapeString(doctorName), html.EscapeString(startsAt), emailSignature(org))
+
+	return s.sendEmail(ctx, email, EmailTemplateCancellation, subject, body, attachments...)
+}
+
+// SendAppointmentConfirmed tells the patient the doctor confirmed a pending booking
+func (s *emailService) SendAppointmentConfirmed(ctx context.Context, email, name, doctorName, startsAt string, attachments ...EmailAttachment) error {
+	subject := "Appointment Confirmed"
+	org := s.organization(ctx)
+
+	body := fmt.Sprintf(`
+	<!DOCTYPE html>
+	<html>
+	<head>
+		<title>Appointment Confirmed</title>
+		<style>
+			body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+			.container { max-width: 600px; margin: 0 auto; padding: 20px; }
+			%s
+		</style>
+	</head>
+	<body>
+		<div class="container">
+			%s
+			<h2>Hello, %s!</h2>
+			<p>Your appointment with <strong>%s</strong> on <strong>%s</strong> is confirmed.</p>
+			<p>We look forward to seeing you.</p>
+			%s
+		</div>
+	</body>
+	</html>
+	`, emailStyle(org), emailHeader(org), html.EscapeString(name), html.EscapeString(doctorName), html.EscapeString(startsAt), emailSignature(org))
+
+	return s.sendEmail(ctx, email, EmailTemplateConfirmed, subject, body, attachments...)
+}
+
+// SendAppointmentDeclined tells the patient the doctor could not take a booking, and why
+func (s *emailService) SendAppointmentDeclined(ctx context.Context, email, name, doctorName, startsAt, reason string) error {
+	subject := "Appointment Declined"
+	org := s.organization(ctx)
+
+	body := fmt.Sprintf(`
+	<!DOCTYPE html>
+	<html>
+	<head>
+		<title>Appointment Declined</title>
+		<style>
+			body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+			.container { max-width: 600px; margin: 0 auto; padding: 20px; }
+			%s
+		</style>
+	</head>
+	<body>
+		<div class="container">
+			%s
+			<h2>Hello, %s!</h2>
+			<p>Unfortunately <strong>%s</strong> cannot see you on <strong>%s</strong>, and your booking has been cancelled.</p>
+			<p>Reason: %s</p>
+			<p>Please book another time online.</p>
+			%s
+		</div>
+	</body>
+	</html>
+	`, emailStyle(org), emailHeader(org), html.EscapeString(name), html.EscapeString(doctorName), html.EscapeString(startsAt), html.EscapeString(reason), emailSignature(org))
+
+	return s.sendEmail(ctx, email, EmailTemplateDeclined, subject, body)
+}
+
+// SendCareReminder tells a patient a preventive care item such as an annual physical is due.
+// dueOn is already formatted in the recipient's timezone and locale.
+func (s *emailService) SendCareReminder(ctx context.Context, email, name, careName, dueOn string) error {
+	subject := careName + " Due"
+	org := s.organization(ctx)
+
+	body := fmt.Sprintf(`
+	<!DOCTYPE html>
+	<html>
+	<head>
+		<title>%s Due</title>
+		<style>
+			body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+			.container { max-width: 600px; margin: 0 auto; padding: 20px; }
+			%s
+		</style>
+	</head>
+	<body>
+		<div class="container">
+			%s
+			<h2>Hello, %s!</h2>
+			<p>Your <strong>%s</strong> is due on <strong>%s</strong>.</p>
+			<p>You can book an appointment online at any time.</p>
+			%s
+		</div>
+	</body>
+	</html>
+	`, html.EscapeString(careName), emailStyle(org), emailHeader(org), html.EscapeString(name), html.EscapeString(careName), html.EscapeString(dueOn), emailSignature(org))
+
+	return s.sendEmail(ctx, email, EmailTemplateCareReminder, subject, body)
+}
+
+// SendAccountClaimInvite invites a patient whose record was created by the clinic to set up
+// their online account, with a link that sets a password in one step and a one-time code for
+// the claim page. Both work for validDays days.
+func (s *emailService) SendAccountClaimInvite(ctx context.Context, email, name, code, setupToken string, validDays int) error {
+	subject := "Set Up Your Patient Account"
+	setupLink := fmt.Sprintf("%s/setup-account?token=%s", s.appBaseURL, setupToken)
+	claimLink := fmt.Sprintf("%s/claim-account", s.appBaseURL)
+	org := s.organization(ctx)
+
+	body := fmt.Sprintf(`
+	<!DOCTYPE html>
+	<html>
+	<head>
+		<title>Set Up Your Patient Account</title>
+		<style>
+			body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+			.container { max-width: 600px; margin: 0 auto; padding: 20px; }
+			.button { display: inline-block; padding: 10px 20px; background-color: #4CAF50; color: white; 
+				text-decoration: none; border-radius: 5px; }
+			.code { font-size: 24px; font-weight: bold; letter-spacing: 4px; }
+			%s
+		</style>
+	</head>
+	<body>
+		<div class="container">
+			%s
+			<h2>Hello, %s!</h2>
+			<p>Your clinic has created a patient record for you. You can now set up an online account to view and book appointments by choosing a password:</p>
+			<p><a href="%s" class="button">Set Up Account</a></p>
+			<p>Or go to <a href="%s">%s</a> and enter this code:</p>
+			<p class="code">%s</p>
+			<p>The link and code are valid for %d days and stop working if the clinic sends a new invitation. If you weren't expecting this, you can ignore this email.</p>
+			%s
+		</div>
+	</body>
+	</html>
+	`, emailStyle(org), emailHeader(org), html.EscapeString(name), setupLink, claimLink, claimLink, code, validDays, emailSignature(org))
+
+	return s.sendEmail(ctx, email, EmailTemplateAccountClaim, subject, body)
+}
+
+// SendMarketingEmail sends a promotional message written by the clinic, such as news of a
+// flu vaccination drive, and returns its Message-ID. Only the notification service calls it,
+// after checking the recipient opted in to marketing emails.
+func (s *emailService) SendMarketingEmail(ctx context.Context, email, name, subject, message string) (string, error) {
+	org := s.organization(ctx)
+	paragraphs := strings.Split(html.EscapeString(strings.TrimSpace(message)), "\n\n")
+	for i, paragraph := range paragraphs {
+		paragraphs[i] = "<p>" + strings.ReplaceAll(paragraph, "\n", "<br>") + "</p>"
+	}
+
+	body := fmt.Sprintf(`
+	<!DOCTYPE html>
+	<html>
+	<head>
+		<title>%s</title>
+		<style>
+			body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+			.container { max-width: 600px; margin: 0 auto; padding: 20px; }
+			%s
+		</style>
+	</head>
+	<body>
+		<div class="container">
+			%s
+			<h2>Hello, %s!</h2>
+			%s
+			%s
+			<p style="font-size: 12px; color: #777;">You are receiving this because you agreed to hear from %s by email. You can opt out at any time in your account settings.</p>
+		</div>
+	</body>
+	</html>
+	`, html.EscapeString(subject), emailStyle(org), emailHeader(org), html.EscapeString(name), strings.Join(paragraphs, "\n\t\t\t"), emailSignature(org), html.EscapeString(org.DisplayName()))
+
+	return s.deliver(ctx, email, EmailTemplateMarketing, subject, body)
+}
+
+// organization returns the clinic whose branding and contact details appear in emails
+func (s *emailService) organization(ctx context.Context) *model.Organization {
+	if org, ok := ctx.Value(emailOrganizationKey{}).(*model.Organization); ok {
+		return org
+	}
+	if s.orgRepo != nil {
+		if org, err := s.orgRepo.FindDefault(ctx); err == nil {
+			return org
+		}
+	}
+	return model.DefaultOrganization()
+}
+
+// emailHeader renders the clinic logo, if one is configured
+func emailHeader(org *model.Organization) string {
+	if org.LogoURL == "" {
+		return ""
+	}
+	return fmt.Sprintf(`<p><img src="%s" alt="%s" style="max-height: 60px;"></p>`,
+		html.EscapeString(org.LogoURL), html.EscapeString(org.DisplayName()))
+}
+
+// emailStyle renders the clinic's brand colors as rules overriding the template's own
+func emailStyle(org *model.Organization) string {
+	var rules []string
+	if org.PrimaryColor != "" {
+		rules = append(rules, fmt.Sprintf("h2 { color: %s; }", html.EscapeString(org.PrimaryColor)))
+	}
+	if org.AccentColor != "" {
+		accent := html.EscapeString(org.AccentColor)
+		rules = append(rules, fmt.Sprintf("a { color: %s; } .button { background-color: %s; color: white; }", accent, accent))
+	}
+	return strings.Join(rules, "\n\t\t\t")
+}
+
+// emailDirections renders the way to the clinic: its address, a button opening directions in a
+// maps app and the arrival instructions
+func emailDirections(directions *Directions) string {
+	if directions == nil {
+		return ""
+	}
+	parts := []string{"<h3>Getting there</h3>"}
+	if directions.Address != "" {
+		parts = append(parts, fmt.Sprintf("<p>%s</p>", html.EscapeString(directions.Address)))
+	}
+	if directions.MapURL != "" {
+		parts = append(parts, fmt.Sprintf(`<p><a href="%s" class="button">Get directions</a></p>`, html.EscapeString(directions.MapURL)))
+	}
+	if directions.Arrival != "" {
+		arrival := strings.ReplaceAll(html.EscapeString(directions.Arrival), "\n", "<br>")
+		parts = append(parts, fmt.Sprintf("<p>%s</p>", arrival))
+	}
+	return strings.Join(parts, "\n\t\t\t")
+}
+
+// emailSignature renders the sign-off with the clinic name and contact details
+func emailSignature(org *model.Organization) string {
+	signature := fmt.Sprintf("<p>Best regards,<br>The %s Team</p>", html.EscapeString(org.DisplayName()))
+
+	var contact []string
+	for _, line := range []string{org.Address, org.ContactPhone, org.ContactEmail} {
+		if line != "" {
+			contact = append(contact, html.EscapeString(line))
+		}
+	}
+	if len(contact) > 0 {
+		signature += fmt.Sprintf(`<p style="font-size: 12px; color: #777;">%s</p>`, strings.Join(contact, " &middot; "))
+	}
+	return signature
+}
+
+// sendEmail sends an email using SMTP and records it with its delivery status.
+// Suppressed addresses are skipped without an error so callers carry on as if the email was sent.
+func (s *emailService) sendEmail(ctx context.Context, to, template, subject, body string, attachments ...EmailAttachment) error {
+	_, err := s.deliver(ctx, to, template, subject, body, attachments...)
+	if errors.Is(err, ErrEmailSuppressed) {
+		return nil
+	}
+	return err
+}
+
+// deliver sends and records an email, returning its Message-ID header. It returns
+// ErrEmailSuppressed without sending when the recipient is suppressed.
+func (s *emailService) deliver(ctx context.Context, to, template, subject, body string, attachments ...EmailAttachment) (string, error) {
+	if preview, ok := ctx.Value(emailPreviewKey{}).(*RenderedMessage); ok {
+		preview.Subject = subject
+		preview.Body = body
+		for _, attachment := range attachments {
+			preview.Attachments = append(preview.Attachments, attachment.Filename)
+		}
+		return "", nil
+	}
+
+	message := &model.EmailMessage{
+		Recipient: strings.ToLower(strings.TrimSpace(to)),
+		Template:  template,
+		Subject:   subject,
+	}
+
+	if s.emailRepo != nil {
+		suppressed, err := s.emailRepo.IsSuppressed(ctx, message.Recipient)
+		if err != nil {
+			s.logger.Error("Failed to check email suppression", zap.Error(err))
+		} else if suppressed {
+			message.Status = model.EmailStatusSuppressed
+			s.record(ctx, message)
+			return "", ErrEmailSuppressed
+		}
+	}
+
+	if s.sandbox {
+		message.MessageID = fmt.Sprintf("<%s@%s>", uuid.NewString(), messageIDDomain(s.fromEmail))
+		message.Status = model.EmailStatusSent
+		message.Detail = "sandbox: not delivered"
+		s.record(ctx, message)
+		s.logger.Info("Email not sent in sandbox mode",
+			zap.String("template", template),
+			zap.String("to", message.Recipient))
+		return message.MessageID, nil
+	}
+
 	// Set up authentication information
 	auth := smtp.PlainAuth("", s.smtpUsername, s.smtpPassword, s.smtpHost)
 
-	// Construct email headers and body
+	// Construct email headers and body; the Message-ID lets provider events be matched to the record
+	message.MessageID = fmt.Sprintf("<%s@%s>", uuid.NewString(), messageIDDomain(s.fromEmail))
 	mime := "MIME-version: 1.0;\nContent-Type: text/html; charset=\"UTF-8\";\n\n"
-	msg := []byte("Subject: " + subject + "\r\n" +
-		"From: " + s.fromEmail + "\r\n" +
-		"To: " + to + "\r\n" +
+	if len(attachments) > 0 {
+		mime, body = multipartBody(body, attachments)
+	}
+	org := s.organization(ctx)
+	from := s.fromEmail
+	if org.EmailSenderName != "" {
+		from = (&mail.Address{Name: org.EmailSenderName, Address: s.fromEmail}).String()
+	}
+	headers := "Subject: " + subject + "\r\n" +
+		"From: " + from + "\r\n" +
+		"To: " + to + "\r\n"
+	if org.EmailReplyTo != "" {
+		headers += "Reply-To: " + org.EmailReplyTo + "\r\n"
+	}
+	msg := []byte(headers +
+		"Message-ID: " + message.MessageID + "\r\n" +
 		mime + "\r\n" +
 		body)
 
 	// Send the email
 	addr := fmt.Sprintf("%s:%d", s.smtpHost, s.smtpPort)
-	return smtp.SendMail(addr, auth, s.fromEmail, []string{to}, msg)
+	err := s.smtpBreaker.Do(ctx, func(ctx context.Context) error {
+		return sendMail(ctx, addr, s.smtpHost, auth, s.fromEmail, to, msg)
+	})
+
+	message.Status = model.EmailStatusSent
+	if err != nil {
+		message.Status = model.EmailStatusFailed
+		message.Detail = err.Error()
+	}
+	s.record(ctx, message)
+	return message.MessageID, err
+}
+
+// multipartBody wraps an HTML body and its attachments in a multipart/mixed message, returning
+// the MIME headers and the encoded body
+func multipartBody(htmlBody string, attachments []EmailAttachment) (string, string) {
+	boundary := "ehass-" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	var b strings.Builder
+	b.WriteString("--" + boundary + "\r\n")
+	b.WriteString("Content-Type: text/html; charset=\"UTF-8\"\r\n\r\n")
+	b.WriteString(htmlBody + "\r\n")
+	for _, attachment := range attachments {
+		b.WriteString("--" + boundary + "\r\n")
+		b.WriteString(fmt.Sprintf("Content-Type: %s; name=%q\r\n", attachment.ContentType, attachment.Filename))
+		b.WriteString("Content-Transfer-Encoding: base64\r\n")
+		b.WriteString(fmt.Sprintf("Content-Disposition: attachment; filename=%q\r\n\r\n", attachment.Filename))
+		encoded := base64.StdEncoding.EncodeToString(attachment.Data)
+		// Keep lines within the 76 characters MIME allows
+		for len(encoded) > 76 {
+			b.WriteString(encoded[:76] + "\r\n")
+			encoded = encoded[76:]
+		}
+		b.WriteString(encoded + "\r\n")
+	}
+	b.WriteString("--" + boundary + "--\r\n")
+
+	headers := fmt.Sprintf("MIME-version: 1.0;\nContent-Type: multipart/mixed; boundary=%q;\n\n", boundary)
+	return headers, b.String()
+}
+
+// sendMail does what smtp.SendMail does, but gives up when ctx is done so a stalled SMTP server
+// cannot hold the caller indefinitely. Permanent rejections (5xx replies) are marked as such.
+func sendMail(ctx context.Context, addr, host string, auth smtp.Auth, from, to string, msg []byte) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			conn.Close()
+			return err
+		}
+	}
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	err = func() error {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+				return err
+			}
+		}
+		if ok, _ := client.Extension("AUTH"); ok && auth != nil {
+			if err := client.Auth(auth); err != nil {
+				return err
+			}
+		}
+		if err := client.Mail(from); err != nil {
+			return err
+		}
+		if err := client.Rcpt(to); err != nil {
+			return err
+		}
+		w, err := client.Data()
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(msg); err != nil {
+			return err
+		}
+		if err := w.Close(); err != nil {
+			return err
+		}
+		return client.Quit()
+	}()
+
+	var reply *textproto.Error
+	if errors.As(err, &reply) && reply.Code >= 500 {
+		return breaker.Permanent(err)
+	}
+	return err
+}
+
+// record stores an outbound email; failing to record does not fail the send
+func (s *emailService) record(ctx context.Context, message *model.EmailMessage) {
+	if s.emailRepo == nil {
+		return
+	}
+	if err := s.emailRepo.CreateMessage(ctx, message); err != nil {
+		s.logger.Error("Failed to record outbound email",
+			zap.String("template", message.Template),
+			zap.Error(err))
+	}
+}
+
+// messageIDDomain returns the domain used in generated Message-ID headers
+func messageIDDomain(fromEmail string) string {
+	if at := strings.LastIndex(fromEmail, "@"); at >= 0 && at < len(fromEmail)-1 {
+		return strings.Trim(fromEmail[at+1:], "> ")
+	}
+	return "localhost"
 }
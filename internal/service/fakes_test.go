@@ -0,0 +1,315 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/repository"
+)
+
+// The fakes below keep their data in memory. Each embeds the repository interface it stands in
+// for, so calling a method a test does not set up panics instead of passing silently.
+
+// testClinic holds the fake repositories of the small clinic the service tests share: doctors 1
+// to 3 signed in as users of the same IDs, patients 10 to 12 likewise, and an admin signed in as
+// user 99. Patient 11 is a child whose guardian is patient 10.
+//
+// Doctor 1 has confirmed appointment 1 and completed appointment 2 with patient 10, cancelled
+// appointment 3 with patient 12 and wrote medical record 6 for patient 10. Doctor 2 has pending
+// appointment 4 with patient 10, and doctor 3 cancelled appointment 5 with patient 11.
+type testClinic struct {
+	users        *fakeUserRepo
+	doctors      *fakeDoctorRepo
+	patients     *fakePatientRepo
+	appointments *fakeAppointmentRepo
+	handoffs     *fakeHandoffRepo
+	audit        *fakeAuditRepo
+}
+
+func newTestClinic() *testClinic {
+	users := &fakeUserRepo{users: map[uint]*model.User{
+		1:  {ID: 1, Name: "Dr Naidoo", Role: model.RoleDoctor},
+		2:  {ID: 2, Name: "Dr Pillay", Role: model.RoleDoctor},
+		3:  {ID: 3, Name: "Dr Botha", Role: model.RoleDoctor},
+		10: {ID: 10, Name: "Thandi Mokoena", Role: model.RolePatient},
+		11: {ID: 11, Name: "Lerato Mokoena", Role: model.RolePatient},
+		12: {ID: 12, Name: "Sipho Dlamini", Role: model.RolePatient},
+		99: {ID: 99, Name: "Admin", Email: "admin@example.com", Role: model.RoleAdmin},
+	}}
+	doctors := &fakeDoctorRepo{doctors: map[uint]*model.Doctor{}}
+	for _, id := range []uint{1, 2, 3} {
+		doctors.doctors[id] = &model.Doctor{ID: id, UserID: id, User: *users.users[id]}
+	}
+	patients := &fakePatientRepo{patients: map[uint]*model.Patient{}}
+	for _, id := range []uint{10, 11, 12} {
+		patients.patients[id] = &model.Patient{ID: id, UserID: id, User: *users.users[id]}
+	}
+	guardianID := uint(10)
+	patients.patients[11].GuardianID = &guardianID
+
+	appointments := &fakeAppointmentRepo{
+		appointments: []*model.Appointment{
+			{ID: 1, DoctorID: 1, PatientID: 10, Status: model.AppointmentStatusConfirmed},
+			{ID: 2, DoctorID: 1, PatientID: 10, Status: model.AppointmentStatusCompleted},
+			{ID: 3, DoctorID: 1, PatientID: 12, Status: model.AppointmentStatusCancelled},
+			{ID: 4, DoctorID: 2, PatientID: 10, Status: model.AppointmentStatusPending},
+			{ID: 5, DoctorID: 3, PatientID: 11, Status: model.AppointmentStatusCancelled},
+		},
+		records: []*model.MedicalRecord{
+			{ID: 6, DoctorID: 1, PatientID: 10},
+		},
+	}
+	return &testClinic{
+		users:        users,
+		doctors:      doctors,
+		patients:     patients,
+		appointments: appointments,
+		handoffs:     &fakeHandoffRepo{appointments: appointments},
+		audit:        &fakeAuditRepo{},
+	}
+}
+
+// access returns the medical record access rules over the clinic's repositories
+func (c *testClinic) access() recordAccess {
+	return recordAccess{doctorRepo: c.doctors, patientRepo: c.patients, handoffRepo: c.handoffs}
+}
+
+type fakeUserRepo struct {
+	repository.UserRepository
+	users map[uint]*model.User
+}
+
+func (r *fakeUserRepo) FindByID(ctx context.Context, id uint) (*model.User, error) {
+	if user, ok := r.users[id]; ok {
+		return user, nil
+	}
+	return nil, errors.New("user not found")
+}
+
+func (r *fakeUserRepo) FindByRole(ctx context.Context, role model.Role) ([]*model.User, error) {
+	var users []*model.User
+	for _, user := range r.users {
+		if user.Role == role {
+			users = append(users, user)
+		}
+	}
+	return users, nil
+}
+
+type fakePatientRepo struct {
+	repository.PatientRepository
+	patients map[uint]*model.Patient
+}
+
+func (r *fakePatientRepo) FindByID(ctx context.Context, id uint) (*model.Patient, error) {
+	if patient, ok := r.patients[id]; ok {
+		return patient, nil
+	}
+	return nil, errors.New("patient not found")
+}
+
+func (r *fakePatientRepo) FindByUserID(ctx context.Context, userID uint) (*model.Patient, error) {
+	for _, patient := range r.patients {
+		if patient.UserID == userID {
+			return patient, nil
+		}
+	}
+	return nil, errors.New("patient not found")
+}
+
+type fakeAuditRepo struct {
+	repository.AuditLogRepository
+	entries []*model.AuditLog
+}
+
+func (r *fakeAuditRepo) Create(ctx context.Context, log *model.AuditLog) error {
+	r.entries = append(r.entries, log)
+	return nil
+}
+
+// actions returns the actions audited, in order
+func (r *fakeAuditRepo) actions() []string {
+	actions := make([]string, len(r.entries))
+	for i, entry := range r.entries {
+		actions[i] = entry.Action
+	}
+	return actions
+}
+
+type fakeBreakGlassRepo struct {
+	repository.BreakGlassRepository
+	grants []*model.BreakGlassAccess
+}
+
+func (r *fakeBreakGlassRepo) Create(ctx context.Context, access *model.BreakGlassAccess) error {
+	access.ID = uint(len(r.grants) + 1)
+	r.grants = append(r.grants, access)
+	return nil
+}
+
+func (r *fakeBreakGlassRepo) FindActive(ctx context.Context, userID, patientID uint) (*model.BreakGlassAccess, error) {
+	for _, access := range r.grants {
+		if access.UserID == userID && access.PatientID == patientID && access.ExpiresAt.After(time.Now()) {
+			return access, nil
+		}
+	}
+	return nil, errors.New("no active emergency access")
+}
+
+// fakeEmailService records the break-glass alerts sent, by recipient
+type fakeEmailService struct {
+	EmailService
+	breakGlassAlerts []string
+}
+
+func (s *fakeEmailService) SendBreakGlassAlert(ctx context.Context, email, name, clinicianName, patientName, reason, expiresAt string) error {
+	s.breakGlassAlerts = append(s.breakGlassAlerts, email)
+	return nil
+}
+
+type fakeDoctorRepo struct {
+	repository.DoctorRepository
+	doctors map[uint]*model.Doctor
+}
+
+func (r *fakeDoctorRepo) FindByID(ctx context.Context, id uint) (*model.Doctor, error) {
+	if doctor, ok := r.doctors[id]; ok {
+		return doctor, nil
+	}
+	return nil, errors.New("doctor not found")
+}
+
+func (r *fakeDoctorRepo) FindByUserID(ctx context.Context, userID uint) (*model.Doctor, error) {
+	for _, doctor := range r.doctors {
+		if doctor.UserID == userID {
+			return doctor, nil
+		}
+	}
+	return nil, errors.New("doctor not found")
+}
+
+// fakeAppointmentRepo matches every appointment and medical record in an encounter search,
+// keeping to one doctor's when the search names one, and records the searches run
+type fakeAppointmentRepo struct {
+	repository.AppointmentRepository
+	appointments []*model.Appointment
+	records      []*model.MedicalRecord
+	searches     []repository.EncounterSearch
+}
+
+func (r *fakeAppointmentRepo) SearchEncounters(ctx context.Context, search repository.EncounterSearch, limit int) ([]repository.EncounterMatch, error) {
+	r.searches = append(r.searches, search)
+	var matches []repository.EncounterMatch
+	for _, appointment := range r.appointments {
+		if search.DoctorID == 0 || appointment.DoctorID == search.DoctorID {
+			matches = append(matches, repository.EncounterMatch{Appointment: appointment})
+		}
+	}
+	for _, record := range r.records {
+		if search.DoctorID == 0 || record.DoctorID == search.DoctorID {
+			matches = append(matches, repository.EncounterMatch{MedicalRecord: record})
+		}
+	}
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches, nil
+}
+
+func (r *fakeAppointmentRepo) FindByPublicIDs(ctx context.Context, publicIDs []string) ([]*model.Appointment, error) {
+	var found []*model.Appointment
+	for _, appointment := range r.appointments {
+		if slices.Contains(publicIDs, appointment.PublicID) {
+			found = append(found, appointment)
+		}
+	}
+	return found, nil
+}
+
+// fakeHandoffRepo derives treatment relationships the way the database does, from the clinic's
+// appointments and the handovers among its notes, and counts the checks made
+type fakeHandoffRepo struct {
+	repository.HandoffRepository
+	appointments *fakeAppointmentRepo
+	notes        []*model.HandoffNote
+	checks       int
+}
+
+func (r *fakeHandoffRepo) Create(ctx context.Context, note *model.HandoffNote) error {
+	note.ID = uint(len(r.notes) + 1)
+	r.notes = append(r.notes, note)
+	return nil
+}
+
+func (r *fakeHandoffRepo) FindByPatientID(ctx context.Context, patientID uint, limit, offset int) ([]*model.HandoffNote, int64, error) {
+	var notes []*model.HandoffNote
+	for i := len(r.notes) - 1; i >= 0; i-- {
+		if r.notes[i].PatientID == patientID {
+			notes = append(notes, r.notes[i])
+		}
+	}
+	total := int64(len(notes))
+	if offset >= len(notes) {
+		return nil, total, nil
+	}
+	notes = notes[offset:]
+	if len(notes) > limit {
+		notes = notes[:limit]
+	}
+	return notes, total, nil
+}
+
+func (r *fakeHandoffRepo) HasTreatmentRelationship(ctx context.Context, doctorID, patientID uint) (bool, error) {
+	r.checks++
+	for _, appointment := range r.appointments.appointments {
+		if appointment.DoctorID != doctorID || appointment.PatientID != patientID {
+			continue
+		}
+		switch appointment.Status {
+		case model.AppointmentStatusConfirmed, model.AppointmentStatusCheckedIn, model.AppointmentStatusCompleted:
+			return true, nil
+		}
+	}
+	for _, note := range r.notes {
+		if note.RecipientID != nil && *note.RecipientID == doctorID && note.PatientID == patientID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+type fakeSeriesRepo struct {
+	repository.RecurringAppointmentRepository
+	series map[uint]*model.RecurringAppointment
+}
+
+func (r *fakeSeriesRepo) FindByID(ctx context.Context, id uint) (*model.RecurringAppointment, error) {
+	if series, ok := r.series[id]; ok {
+		return series, nil
+	}
+	return nil, errors.New("appointment series not found")
+}
+
+type fakeAuthRepo struct {
+	repository.AuthRepository
+	users         map[uint]*model.User
+	refreshTokens map[uint]string
+}
+
+func (r *fakeAuthRepo) FindByID(ctx context.Context, id uint) (*model.User, error) {
+	if user, ok := r.users[id]; ok {
+		return user, nil
+	}
+	return nil, errors.New("user not found")
+}
+
+func (r *fakeAuthRepo) UpdateRefreshToken(ctx context.Context, userID uint, tokenHash string) error {
+	if r.refreshTokens == nil {
+		r.refreshTokens = map[uint]string{}
+	}
+	r.refreshTokens[userID] = tokenHash
+	return nil
+}
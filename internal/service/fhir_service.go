@@ -0,0 +1,838 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/repository"
+	"github.com/whitewalker-sa/ehass/pkg/fhir"
+	"go.uber.org/zap"
+)
+
+// ErrInvalidFHIRSearch is returned for a FHIR search with parameters that cannot be used
+var ErrInvalidFHIRSearch = errors.New("invalid FHIR search")
+
+const (
+	defaultFHIRCount = 20
+	maxFHIRCount     = 100
+)
+
+// FHIRSearch is a search of FHIR resources: the parameters of the query string, and the
+// timezone dates without a time are read in
+type FHIRSearch struct {
+	Params   url.Values
+	Location *time.Location
+}
+
+// fhirAppointmentStatus maps appointment statuses to FHIR appointment statuses
+var fhirAppointmentStatus = map[model.AppointmentStatus]string{
+	model.AppointmentStatusPending:   "pending",
+	model.AppointmentStatusConfirmed: "booked",
+	model.AppointmentStatusCheckedIn: "checked-in",
+	model.AppointmentStatusCancelled: "cancelled",
+	model.AppointmentStatusCompleted: "fulfilled",
+	model.AppointmentStatusNoShow:    "noshow",
+}
+
+// vitalCode is the LOINC code and UCUM unit a vital sign type is exchanged with
+type vitalCode struct {
+	loinc    string
+	display  string
+	ucum     string
+	category string
+}
+
+var vitalCodes = map[model.VitalType]vitalCode{
+	model.VitalBloodPressure: {"85354-9", "Blood pressure panel with all children optional", "mm[Hg]", "vital-signs"},
+	model.VitalHeartRate:     {"8867-4", "Heart rate", "/min", "vital-signs"},
+	model.VitalWeight:        {"29463-7", "Body weight", "kg", "vital-signs"},
+	model.VitalGlucose:       {"2339-0", "Glucose [Mass/volume] in Blood", "mg/dL", "laboratory"},
+	model.VitalTemperature:   {"8310-5", "Body temperature", "Cel", "vital-signs"},
+}
+
+// LOINC codes of the blood pressure components
+var (
+	systolicCode  = fhir.Coding{System: fhir.SystemLOINC, Code: "8480-6", Display: "Systolic blood pressure"}
+	diastolicCode = fhir.Coding{System: fhir.SystemLOINC, Code: "8462-4", Display: "Diastolic blood pressure"}
+)
+
+type fhirService struct {
+	patientRepo     repository.PatientRepository
+	doctorRepo      repository.DoctorRepository
+	appointmentRepo repository.AppointmentRepository
+	vitalRepo       repository.VitalRepository
+	publicIDs       PublicIDService
+	access          recordAccess
+	baseURL         string
+	logger          *zap.Logger
+}
+
+// NewFHIRService creates a service serving patients, doctors, appointments and vital signs as
+// FHIR R4 resources, addressed under baseURL. Access to observations follows the patient's
+// medical records.
+func NewFHIRService(
+	patientRepo repository.PatientRepository,
+	doctorRepo repository.DoctorRepository,
+	appointmentRepo repository.AppointmentRepository,
+	vitalRepo repository.VitalRepository,
+	handoffRepo repository.HandoffRepository,
+	publicIDs PublicIDService,
+	baseURL string,
+	logger *zap.Logger,
+) FHIRService {
+	return &fhirService{
+		patientRepo:     patientRepo,
+		doctorRepo:      doctorRepo,
+		appointmentRepo: appointmentRepo,
+		vitalRepo:       vitalRepo,
+		publicIDs:       publicIDs,
+		access:          recordAccess{doctorRepo: doctorRepo, patientRepo: patientRepo, handoffRepo: handoffRepo},
+		baseURL:         strings.TrimRight(baseURL, "/") + "/api/v1/fhir",
+		logger:          logger,
+	}
+}
+
+// CapabilityStatement describes the resources served and their search parameters
+func (s *fhirService) CapabilityStatement() *fhir.CapabilityStatement {
+	interactions := []fhir.Interaction{{Code: "read"}, {Code: "search-type"}}
+	count := fhir.CapabilitySearch{Name: "_count", Type: "number", Documentation: fmt.Sprintf("Results per page, %d by default and at most %d", defaultFHIRCount, maxFHIRCount)}
+	return &fhir.CapabilityStatement{
+		ResourceType: "CapabilityStatement",
+		Status:       "active",
+		Date:         time.Now().UTC().Format("2006-01-02"),
+		Kind:         "instance",
+		Software:     fhir.Software{Name: "EHASS"},
+		FHIRVersion:  fhir.Version,
+		Format:       []string{"json"},
+		Rest: []fhir.CapabilityRest{{
+			Mode: "server",
+			Resource: []fhir.CapabilityResource{
+				{Type: "Patient", Interaction: interactions, SearchParam: []fhir.CapabilitySearch{
+					{Name: "_id", Type: "token"},
+					{Name: "name", Type: "string"},
+					{Name: "email", Type: "token"},
+					{Name: "phone", Type: "token"},
+					{Name: "birthdate", Type: "date"},
+					{Name: "gender", Type: "token", Documentation: "Narrows a search by another parameter"},
+					count,
+				}},
+				{Type: "Practitioner", Interaction: interactions, SearchParam: []fhir.CapabilitySearch{
+					{Name: "_id", Type: "token"},
+					{Name: "name", Type: "string"},
+					count,
+					{Name: "_offset", Type: "number", Documentation: "Results to skip, for paging without name"},
+				}},
+				{Type: "Appointment", Interaction: interactions, SearchParam: []fhir.CapabilitySearch{
+					{Name: "_id", Type: "token"},
+					{Name: "patient", Type: "reference"},
+					{Name: "practitioner", Type: "reference"},
+					{Name: "status", Type: "token"},
+					{Name: "date", Type: "date", Documentation: "Scheduled start; eq, gt, ge, lt and le prefixes"},
+					count,
+					{Name: "_offset", Type: "number", Documentation: "Results to skip"},
+				}},
+				{Type: "Observation", Interaction: interactions, SearchParam: []fhir.CapabilitySearch{
+					{Name: "patient", Type: "reference", Documentation: "Required"},
+					{Name: "code", Type: "token", Documentation: "LOINC code of a vital sign"},
+					{Name: "date", Type: "date", Documentation: "When measured; eq, gt, ge, lt and le prefixes"},
+					{Name: "_count", Type: "number", Documentation: fmt.Sprintf("Latest readings returned, %d by default and at most %d", defaultFHIRCount, maxFHIRCount)},
+				}},
+			},
+		}},
+	}
+}
+
+// ReadPatient returns the patient with the given public ID
+func (s *fhirService) ReadPatient(ctx context.Context, id string) (*fhir.Patient, error) {
+	if !model.IsValidPublicID(id) {
+		return nil, errors.New("patient not found")
+	}
+	patients, err := s.patientRepo.FindByPublicIDs(ctx, []string{id})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find patient: %w", err)
+	}
+	if len(patients) == 0 {
+		return nil, errors.New("patient not found")
+	}
+	return toFHIRPatient(patients[0]), nil
+}
+
+// SearchPatients finds patients by _id, or by name, email, phone or birthdate, all given
+// parameters having to match. gender narrows a search by the others. Name searches return the
+// closest matches first and are not paged.
+func (s *fhirService) SearchPatients(ctx context.Context, search FHIRSearch) (*fhir.Bundle, error) {
+	count, err := fhirCount(search.Params)
+	if err != nil {
+		return nil, err
+	}
+
+	var patients []*model.Patient
+	if ids := fhirTokens(search.Params, "_id"); len(ids) > 0 {
+		if patients, err = s.findPatients(ctx, ids); err != nil {
+			return nil, err
+		}
+	} else {
+		name := strings.TrimSpace(search.Params.Get("name"))
+		email := strings.ToLower(strings.TrimSpace(search.Params.Get("email")))
+		phone := digitsOnly(search.Params.Get("phone"))
+		var birthDate *time.Time
+		if raw := search.Params.Get("birthdate"); raw != "" {
+			from, to, err := fhir.ParseDate(raw, time.UTC)
+			if err != nil || from.IsZero() || to.Sub(from) != 24*time.Hour {
+				return nil, fmt.Errorf("%w: birthdate must be a day, e.g. 1980-04-21", ErrInvalidFHIRSearch)
+			}
+			birthDate = &from
+		}
+		if name == "" && email == "" && phone == "" && birthDate == nil {
+			return nil, fmt.Errorf("%w: search patients by _id, name, email, phone or birthdate", ErrInvalidFHIRSearch)
+		}
+
+		text := name
+		if text == "" {
+			text = email
+		}
+		found, err := s.patientRepo.Search(ctx, repository.PatientSearch{Text: text, Phone: phone, DateOfBirth: birthDate}, maxFHIRCount)
+		if err != nil {
+			return nil, fmt.Errorf("failed to search patients: %w", err)
+		}
+		gender := strings.ToLower(search.Params.Get("gender"))
+		for _, patient := range found {
+			if name != "" && !strings.Contains(strings.ToLower(patient.User.Name), strings.ToLower(name)) ||
+				email != "" && !strings.Contains(strings.ToLower(patient.User.Email), email) ||
+				phone != "" && !strings.Contains(digitsOnly(patient.User.Phone), phone) ||
+				birthDate != nil && patient.DateOfBirth.UTC().Format("2006-01-02") != birthDate.Format("2006-01-02") ||
+				gender != "" && fhirGender(patient.Gender) != gender {
+				continue
+			}
+			patients = append(patients, patient)
+		}
+	}
+	if len(patients) > count {
+		patients = patients[:count]
+	}
+
+	bundle := s.newBundle("Patient", search.Params, int64(len(patients)), 0, len(patients))
+	for _, patient := range patients {
+		bundle.Add(s.fullURL("Patient", patient.PublicID), toFHIRPatient(patient))
+	}
+	return bundle, nil
+}
+
+// ReadPractitioner returns the doctor with the given public ID
+func (s *fhirService) ReadPractitioner(ctx context.Context, id string) (*fhir.Practitioner, error) {
+	if !model.IsValidPublicID(id) {
+		return nil, errors.New("doctor not found")
+	}
+	doctors, err := s.doctorRepo.FindByPublicIDs(ctx, []string{id})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find doctor: %w", err)
+	}
+	if len(doctors) == 0 {
+		return nil, errors.New("doctor not found")
+	}
+	return toFHIRPractitioner(doctors[0]), nil
+}
+
+// SearchPractitioners finds doctors by _id or name, or pages through them all by name
+func (s *fhirService) SearchPractitioners(ctx context.Context, search FHIRSearch) (*fhir.Bundle, error) {
+	count, err := fhirCount(search.Params)
+	if err != nil {
+		return nil, err
+	}
+	offset, err := fhirOffset(search.Params)
+	if err != nil {
+		return nil, err
+	}
+
+	var doctors []*model.Doctor
+	var total int64
+	switch ids, name := fhirTokens(search.Params, "_id"), strings.TrimSpace(search.Params.Get("name")); {
+	case len(ids) > 0:
+		if ids, err = batchPublicIDs(model.ResourceDoctor, ids); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidFHIRSearch, err)
+		}
+		if doctors, err = s.doctorRepo.FindByPublicIDs(ctx, ids); err != nil {
+			return nil, fmt.Errorf("failed to find doctors: %w", err)
+		}
+		total, offset = int64(len(doctors)), 0
+	case name != "":
+		found, _, err := s.doctorRepo.Search(ctx, name, "", maxFHIRCount)
+		if err != nil {
+			return nil, fmt.Errorf("failed to search doctors: %w", err)
+		}
+		for _, doctor := range found {
+			if strings.Contains(strings.ToLower(doctor.User.Name), strings.ToLower(name)) && len(doctors) < count {
+				doctors = append(doctors, doctor)
+			}
+		}
+		total, offset = int64(len(doctors)), 0
+	default:
+		sort := repository.ListOptions{Sort: []repository.SortField{{Key: "name"}}}
+		if doctors, total, err = s.doctorRepo.FindAll(ctx, count, offset, sort); err != nil {
+			return nil, fmt.Errorf("failed to list doctors: %w", err)
+		}
+	}
+
+	bundle := s.newBundle("Practitioner", search.Params, total, offset, len(doctors))
+	for _, doctor := range doctors {
+		bundle.Add(s.fullURL("Practitioner", doctor.PublicID), toFHIRPractitioner(doctor))
+	}
+	return bundle, nil
+}
+
+// ReadAppointment returns the appointment with the given public ID to the user signed in as
+// userID, who must be allowed to read the patient's medical records, as appointments carry the
+// reason for the visit
+func (s *fhirService) ReadAppointment(ctx context.Context, userID uint, role model.Role, id string) (*fhir.Appointment, error) {
+	appointmentID, err := s.resolve(ctx, model.ResourceAppointment, id)
+	if err != nil {
+		return nil, err
+	}
+	appointment, err := s.appointmentRepo.FindByID(ctx, appointmentID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.access.authorizeRead(ctx, userID, role, appointment.PatientID); err != nil {
+		return nil, err
+	}
+	return toFHIRAppointment(appointment), nil
+}
+
+// SearchAppointments finds appointments by patient, practitioner, status and scheduled start,
+// earliest first. Access follows the patients' medical records: appointments looked up by _id are
+// left out unless the caller may read the patient's records, and other searches must name the
+// patients, each of which the caller must be allowed to read, unless the caller is an admin.
+func (s *fhirService) SearchAppointments(ctx context.Context, userID uint, role model.Role, search FHIRSearch) (*fhir.Bundle, error) {
+	count, err := fhirCount(search.Params)
+	if err != nil {
+		return nil, err
+	}
+	offset, err := fhirOffset(search.Params)
+	if err != nil {
+		return nil, err
+	}
+
+	if ids := fhirTokens(search.Params, "_id"); len(ids) > 0 {
+		if ids, err = batchPublicIDs(model.ResourceAppointment, ids); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidFHIRSearch, err)
+		}
+		found, err := s.appointmentRepo.FindByPublicIDs(ctx, ids)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find appointments: %w", err)
+		}
+		appointments := make([]*model.Appointment, 0, len(found))
+		readable := map[uint]bool{}
+		for _, appointment := range found {
+			allowed, checked := readable[appointment.PatientID]
+			if !checked {
+				err := s.access.authorizeRead(ctx, userID, role, appointment.PatientID)
+				if err != nil && !errors.Is(err, ErrNotTreatingDoctor) && !errors.Is(err, ErrNotOwnMedicalRecord) && !isNotFound(err) {
+					return nil, err
+				}
+				allowed = err == nil
+				readable[appointment.PatientID] = allowed
+			}
+			if allowed {
+				appointments = append(appointments, appointment)
+			}
+		}
+		bundle := s.newBundle("Appointment", search.Params, int64(len(appointments)), 0, len(appointments))
+		for _, appointment := range appointments {
+			bundle.Add(s.fullURL("Appointment", appointment.PublicID), toFHIRAppointment(appointment))
+		}
+		return bundle, nil
+	}
+
+	var filter repository.AppointmentFilter
+	for _, ref := range fhirTokens(search.Params, "patient") {
+		id, err := s.resolveReference(ctx, ref, "Patient", model.ResourcePatient)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.access.authorizeRead(ctx, userID, role, id); err != nil {
+			return nil, err
+		}
+		filter.PatientIDs = append(filter.PatientIDs, id)
+	}
+	if len(filter.PatientIDs) == 0 && role != model.RoleAdmin {
+		return nil, fmt.Errorf("%w: appointments are searched by patient", ErrInvalidFHIRSearch)
+	}
+	for _, ref := range append(fhirTokens(search.Params, "practitioner"), fhirTokens(search.Params, "actor")...) {
+		id, err := s.resolveReference(ctx, ref, "Practitioner", model.ResourceDoctor)
+		if err != nil {
+			return nil, err
+		}
+		filter.DoctorIDs = append(filter.DoctorIDs, id)
+	}
+	for _, code := range fhirTokens(search.Params, "status") {
+		matched := false
+		for status, fhirStatus := range fhirAppointmentStatus {
+			if fhirStatus == code {
+				filter.Statuses = append(filter.Statuses, status)
+				matched = true
+			}
+		}
+		if !matched {
+			return nil, fmt.Errorf("%w: unknown appointment status %q", ErrInvalidFHIRSearch, code)
+		}
+	}
+	from, to, err := fhirDateRange(search, "date")
+	if err != nil {
+		return nil, err
+	}
+	if !from.IsZero() {
+		filter.From = &from
+	}
+	if !to.IsZero() {
+		filter.To = &to
+	}
+
+	appointments, total, err := s.appointmentRepo.Find(ctx, filter, count, offset, repository.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search appointments: %w", err)
+	}
+	bundle := s.newBundle("Appointment", search.Params, total, offset, len(appointments))
+	for _, appointment := range appointments {
+		bundle.Add(s.fullURL("Appointment", appointment.PublicID), toFHIRAppointment(appointment))
+	}
+	return bundle, nil
+}
+
+// ReadObservation returns the vital sign reading with the given public ID to the user signed in
+// as userID, who must be allowed to read the patient's medical records
+func (s *fhirService) ReadObservation(ctx context.Context, userID uint, role model.Role, id string) (*fhir.Observation, error) {
+	vitalID, err := s.resolve(ctx, model.ResourceVital, id)
+	if err != nil {
+		return nil, err
+	}
+	vital, err := s.vitalRepo.FindByID(ctx, vitalID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.access.authorizeRead(ctx, userID, role, vital.PatientID); err != nil {
+		return nil, err
+	}
+	patient, err := s.patientRepo.FindByID(ctx, vital.PatientID)
+	if err != nil {
+		return nil, err
+	}
+	return s.toFHIRObservation(ctx, vital, patient, map[uint]*fhir.Reference{}), nil
+}
+
+// SearchObservations lists a patient's vital sign readings, oldest first, optionally of one
+// LOINC code and measured in a date range. Only the latest readings up to _count are returned.
+// Access follows the patient's medical records.
+func (s *fhirService) SearchObservations(ctx context.Context, userID uint, role model.Role, search FHIRSearch) (*fhir.Bundle, error) {
+	count, err := fhirCount(search.Params)
+	if err != nil {
+		return nil, err
+	}
+	ref := search.Params.Get("patient")
+	if ref == "" {
+		ref = search.Params.Get("subject")
+	}
+	if ref == "" {
+		return nil, fmt.Errorf("%w: observations are searched by patient", ErrInvalidFHIRSearch)
+	}
+	patientID, err := s.resolveReference(ctx, ref, "Patient", model.ResourcePatient)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.access.authorizeRead(ctx, userID, role, patientID); err != nil {
+		return nil, err
+	}
+	patient, err := s.patientRepo.FindByID(ctx, patientID)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := repository.VitalFilter{PatientID: patientID}
+	if code := search.Params.Get("code"); code != "" {
+		if filter.Type, err = vitalTypeForCode(code); err != nil {
+			return nil, err
+		}
+	}
+	if filter.From, filter.To, err = fhirDateRange(search, "date"); err != nil {
+		return nil, err
+	}
+
+	vitals, err := s.vitalRepo.Find(ctx, filter, count)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find vital signs: %w", err)
+	}
+	bundle := s.newBundle("Observation", search.Params, int64(len(vitals)), 0, len(vitals))
+	performers := map[uint]*fhir.Reference{}
+	for _, vital := range vitals {
+		bundle.Add(s.fullURL("Observation", vital.PublicID), s.toFHIRObservation(ctx, vital, patient, performers))
+	}
+	return bundle, nil
+}
+
+// findPatients finds the patients with the given public IDs
+func (s *fhirService) findPatients(ctx context.Context, ids []string) ([]*model.Patient, error) {
+	ids, err := batchPublicIDs(model.ResourcePatient, ids)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFHIRSearch, err)
+	}
+	patients, err := s.patientRepo.FindByPublicIDs(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find patients: %w", err)
+	}
+	return patients, nil
+}
+
+// resolve returns the internal ID of a resource read by its public ID. IDs that are not UUIDs
+// cannot name a resource, so they are not found rather than invalid.
+func (s *fhirService) resolve(ctx context.Context, resource model.PublicResource, id string) (uint, error) {
+	if !model.IsValidPublicID(id) {
+		return 0, errors.New(resource.Name() + " not found")
+	}
+	return s.publicIDs.ResolveID(ctx, resource, id)
+}
+
+// resolveReference returns the internal ID of the resource a reference search parameter points to
+func (s *fhirService) resolveReference(ctx context.Context, ref, resourceType string, resource model.PublicResource) (uint, error) {
+	id, err := fhir.SplitReference(ref, resourceType)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidFHIRSearch, err)
+	}
+	resolved, err := s.publicIDs.ResolveID(ctx, resource, id)
+	if errors.Is(err, ErrInvalidPublicID) {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidFHIRSearch, err)
+	}
+	return resolved, err
+}
+
+// fullURL returns the address of a resource
+func (s *fhirService) fullURL(resourceType, id string) string {
+	return s.baseURL + "/" + resourceType + "/" + id
+}
+
+// newBundle creates a search bundle holding results offset to offset+n of total, linked to
+// itself and, when more results follow, to the next page
+func (s *fhirService) newBundle(resourceType string, params url.Values, total int64, offset, n int) *fhir.Bundle {
+	bundle := fhir.NewSearchBundle(total)
+	link := func(relation string, params url.Values) {
+		address := s.baseURL + "/" + resourceType
+		if query := params.Encode(); query != "" {
+			address += "?" + query
+		}
+		bundle.Link = append(bundle.Link, fhir.BundleLink{Relation: relation, URL: address})
+	}
+	link("self", params)
+	if n > 0 && int64(offset+n) < total {
+		next := url.Values{}
+		for key, values := range params {
+			next[key] = values
+		}
+		next.Set("_offset", strconv.Itoa(offset+n))
+		link("next", next)
+	}
+	return bundle
+}
+
+// fhirCount reads the _count parameter
+func fhirCount(params url.Values) (int, error) {
+	raw := params.Get("_count")
+	if raw == "" {
+		return defaultFHIRCount, nil
+	}
+	count, err := strconv.Atoi(raw)
+	if err != nil || count < 1 {
+		return 0, fmt.Errorf("%w: _count must be a positive number", ErrInvalidFHIRSearch)
+	}
+	if count > maxFHIRCount {
+		count = maxFHIRCount
+	}
+	return count, nil
+}
+
+// fhirOffset reads the _offset parameter
+func fhirOffset(params url.Values) (int, error) {
+	raw := params.Get("_offset")
+	if raw == "" {
+		return 0, nil
+	}
+	offset, err := strconv.Atoi(raw)
+	if err != nil || offset < 0 {
+		return 0, fmt.Errorf("%w: _offset must be a number of at least 0", ErrInvalidFHIRSearch)
+	}
+	return offset, nil
+}
+
+// fhirTokens returns the values of a parameter, which may be repeated and hold comma-separated
+// values
+func fhirTokens(params url.Values, name string) []string {
+	var tokens []string
+	for _, value := range params[name] {
+		for _, token := range strings.Split(value, ",") {
+			if token = strings.TrimSpace(token); token != "" {
+				tokens = append(tokens, token)
+			}
+		}
+	}
+	return tokens
+}
+
+// fhirDateRange intersects the ranges of a repeatable date parameter, such as
+// date=ge2025-06-01&date=lt2025-07-01
+func fhirDateRange(search FHIRSearch, name string) (from, to time.Time, err error) {
+	loc := search.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	for _, value := range search.Params[name] {
+		start, end, err := fhir.ParseDate(value, loc)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("%w: %v", ErrInvalidFHIRSearch, err)
+		}
+		if !start.IsZero() && start.After(from) {
+			from = start
+		}
+		if !end.IsZero() && (to.IsZero() || end.Before(to)) {
+			to = end
+		}
+	}
+	return from, to, nil
+}
+
+// vitalTypeForCode returns the vital sign type of a code search parameter: a LOINC code,
+// optionally with its system as system|code, or the type's name
+func vitalTypeForCode(token string) (model.VitalType, error) {
+	system, code := "", token
+	if i := strings.Index(token, "|"); i >= 0 {
+		system, code = token[:i], token[i+1:]
+	}
+	for vitalType, vc := range vitalCodes {
+		if vc.loinc == code && (system == "" || system == fhir.SystemLOINC) {
+			return vitalType, nil
+		}
+	}
+	if vitalType := model.VitalType(code); system == "" && vitalType.IsValid() {
+		return vitalType, nil
+	}
+	return "", fmt.Errorf("%w: no vital sign has code %q", ErrInvalidFHIRSearch, token)
+}
+
+// digitsOnly keeps the digits of s
+func digitsOnly(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < '0' || r > '9' {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// fhirGender maps a recorded gender to the FHIR administrative gender
+func fhirGender(gender string) string {
+	switch g := strings.ToLower(strings.TrimSpace(gender)); g {
+	case "":
+		return ""
+	case "male", "female", "other":
+		return g
+	case "m":
+		return "male"
+	case "f":
+		return "female"
+	}
+	return "unknown"
+}
+
+// fhirName splits a full name into the given names and the family name, taken as the last word
+func fhirName(name string) fhir.HumanName {
+	words := strings.Fields(name)
+	human := fhir.HumanName{Use: "official", Text: name}
+	if len(words) > 0 {
+		human.Family = words[len(words)-1]
+		human.Given = words[:len(words)-1]
+	}
+	return human
+}
+
+// fhirMeta returns the metadata of a resource last changed at updatedAt
+func fhirMeta(updatedAt time.Time) *fhir.Meta {
+	if updatedAt.IsZero() {
+		return nil
+	}
+	return &fhir.Meta{LastUpdated: updatedAt.UTC().Format(time.RFC3339)}
+}
+
+func toFHIRPatient(patient *model.Patient) *fhir.Patient {
+	resource := &fhir.Patient{
+		ResourceType: "Patient",
+		ID:           patient.PublicID,
+		Meta:         fhirMeta(patient.UpdatedAt),
+		Active:       true,
+		Name:         []fhir.HumanName{fhirName(patient.User.Name)},
+		Gender:       fhirGender(patient.Gender),
+	}
+	if patient.User.Email != "" {
+		resource.Telecom = append(resource.Telecom, fhir.ContactPoint{System: "email", Value: patient.User.Email, Use: "home"})
+	}
+	if patient.User.Phone != "" {
+		resource.Telecom = append(resource.Telecom, fhir.ContactPoint{System: "phone", Value: patient.User.Phone, Use: "mobile"})
+	}
+	if !patient.DateOfBirth.IsZero() {
+		resource.BirthDate = patient.DateOfBirth.UTC().Format("2006-01-02")
+	}
+	if patient.User.Address != "" {
+		resource.Address = []fhir.Address{{Text: patient.User.Address}}
+	}
+	if patient.EmergencyContact != "" || patient.EmergencyPhone != "" {
+		contact := fhir.PatientContact{Relationship: []fhir.CodeableConcept{{
+			Coding: []fhir.Coding{{System: fhir.SystemContactRelationship, Code: "C", Display: "Emergency Contact"}},
+		}}}
+		if patient.EmergencyContact != "" {
+			contact.Name = &fhir.HumanName{Text: patient.EmergencyContact}
+		}
+		if patient.EmergencyPhone != "" {
+			contact.Telecom = []fhir.ContactPoint{{System: "phone", Value: patient.EmergencyPhone}}
+		}
+		resource.Contact = []fhir.PatientContact{contact}
+	}
+	return resource
+}
+
+func toFHIRPractitioner(doctor *model.Doctor) *fhir.Practitioner {
+	resource := &fhir.Practitioner{
+		ResourceType: "Practitioner",
+		ID:           doctor.PublicID,
+		Meta:         fhirMeta(doctor.UpdatedAt),
+		Active:       true,
+		Name:         []fhir.HumanName{fhirName(doctor.User.Name)},
+	}
+	if doctor.LicenseNo != "" {
+		resource.Identifier = []fhir.Identifier{{
+			Type:  &fhir.CodeableConcept{Coding: []fhir.Coding{{System: fhir.SystemIdentifierType, Code: "MD", Display: "Medical License number"}}},
+			Value: doctor.LicenseNo,
+		}}
+	}
+	if doctor.User.Email != "" {
+		resource.Telecom = append(resource.Telecom, fhir.ContactPoint{System: "email", Value: doctor.User.Email, Use: "work"})
+	}
+	if doctor.User.Phone != "" {
+		resource.Telecom = append(resource.Telecom, fhir.ContactPoint{System: "phone", Value: doctor.User.Phone, Use: "work"})
+	}
+	if doctor.Education != "" {
+		resource.Qualification = []fhir.PractitionerQualification{{Code: fhir.CodeableConcept{Text: doctor.Education}}}
+	}
+	return resource
+}
+
+func toFHIRAppointment(appointment *model.Appointment) *fhir.Appointment {
+	resource := &fhir.Appointment{
+		ResourceType:    "Appointment",
+		ID:              appointment.PublicID,
+		Meta:            fhirMeta(appointment.UpdatedAt),
+		Status:          fhirAppointmentStatus[appointment.Status],
+		Start:           appointment.ScheduledStart.UTC().Format(time.RFC3339),
+		End:             appointment.ScheduledEnd.UTC().Format(time.RFC3339),
+		MinutesDuration: int(appointment.ScheduledEnd.Sub(appointment.ScheduledStart).Minutes()),
+		Created:         appointment.CreatedAt.UTC().Format(time.RFC3339),
+	}
+	if resource.Status == "" {
+		resource.Status = "proposed"
+	}
+	if appointment.AppointmentType != nil {
+		resource.ServiceType = []fhir.CodeableConcept{{Text: appointment.AppointmentType.Name}}
+	}
+	if appointment.Modality != "" {
+		resource.AppointmentType = &fhir.CodeableConcept{Text: string(appointment.Modality)}
+	}
+	if appointment.Reason != "" {
+		resource.ReasonCode = []fhir.CodeableConcept{{Text: appointment.Reason}}
+	}
+
+	doctorStatus := "accepted"
+	switch appointment.Status {
+	case model.AppointmentStatusPending:
+		doctorStatus = "needs-action"
+	case model.AppointmentStatusCancelled:
+		if appointment.DeclineReason != "" {
+			doctorStatus = "declined"
+		}
+	}
+	resource.Participant = []fhir.AppointmentParticipant{
+		{
+			Actor:    fhir.Reference{Reference: "Patient/" + appointment.Patient.PublicID, Display: appointment.Patient.User.Name},
+			Required: "required",
+			Status:   "accepted",
+		},
+		{
+			Actor:    fhir.Reference{Reference: "Practitioner/" + appointment.Doctor.PublicID, Display: appointment.Doctor.User.Name},
+			Required: "required",
+			Status:   doctorStatus,
+		},
+	}
+	return resource
+}
+
+// toFHIRObservation converts a vital sign reading of the patient. Performers of visit readings
+// are looked up once per user and kept in performers.
+func (s *fhirService) toFHIRObservation(ctx context.Context, vital *model.Vital, patient *model.Patient, performers map[uint]*fhir.Reference) *fhir.Observation {
+	vc := vitalCodes[vital.Type]
+	unit := model.VitalUnits[vital.Type]
+	resource := &fhir.Observation{
+		ResourceType: "Observation",
+		ID:           vital.PublicID,
+		Meta:         fhirMeta(vital.CreatedAt),
+		Status:       "final",
+		Category: []fhir.CodeableConcept{{
+			Coding: []fhir.Coding{{System: fhir.SystemObservationCategory, Code: vc.category}},
+		}},
+		Code: fhir.CodeableConcept{
+			Coding: []fhir.Coding{{System: fhir.SystemLOINC, Code: vc.loinc, Display: vc.display}},
+			Text:   vc.display,
+		},
+		Subject:           fhir.Reference{Reference: "Patient/" + patient.PublicID, Display: patient.User.Name},
+		EffectiveDateTime: vital.MeasuredAt.UTC().Format(time.RFC3339),
+		Issued:            vital.CreatedAt.UTC().Format(time.RFC3339),
+	}
+
+	quantity := func(value float64) *fhir.Quantity {
+		return &fhir.Quantity{Value: value, Unit: unit, System: fhir.SystemUCUM, Code: vc.ucum}
+	}
+	if vital.Type == model.VitalBloodPressure {
+		resource.Component = []fhir.ObservationComponent{
+			{Code: fhir.CodeableConcept{Coding: []fhir.Coding{systolicCode}}, ValueQuantity: quantity(vital.Value)},
+		}
+		if vital.Diastolic != nil {
+			resource.Component = append(resource.Component, fhir.ObservationComponent{
+				Code: fhir.CodeableConcept{Coding: []fhir.Coding{diastolicCode}}, ValueQuantity: quantity(*vital.Diastolic),
+			})
+		}
+	} else {
+		resource.ValueQuantity = quantity(vital.Value)
+	}
+
+	if vital.Source == model.VitalSourceSelfReported {
+		resource.Performer = []fhir.Reference{resource.Subject}
+	} else if performer := s.performer(ctx, vital.RecordedByID, performers); performer != nil {
+		resource.Performer = []fhir.Reference{*performer}
+	}
+	if vital.Note != "" {
+		resource.Note = []fhir.Annotation{{Text: vital.Note}}
+	}
+	return resource
+}
+
+// performer returns a reference to the doctor signed in as userID, or nil when they are not a
+// doctor
+func (s *fhirService) performer(ctx context.Context, userID uint, performers map[uint]*fhir.Reference) *fhir.Reference {
+	if performer, ok := performers[userID]; ok {
+		return performer
+	}
+	var performer *fhir.Reference
+	if doctor, err := s.doctorRepo.FindByUserID(ctx, userID); err == nil {
+		performer = &fhir.Reference{Reference: "Practitioner/" + doctor.PublicID, Display: doctor.User.Name}
+	}
+	performers[userID] = performer
+	return performer
+}
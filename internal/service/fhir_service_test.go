@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/pkg/fhir"
+	"go.uber.org/zap"
+)
+
+func TestSearchAppointmentsFollowsRecordAccess(t *testing.T) {
+	clinic := newTestClinic()
+	ids := make([]string, 0, len(clinic.appointments.appointments))
+	for _, appointment := range clinic.appointments.appointments {
+		appointment.PublicID = model.NewPublicID()
+		ids = append(ids, appointment.PublicID)
+	}
+	s := NewFHIRService(clinic.patients, clinic.doctors, clinic.appointments, nil, clinic.handoffs, nil, "https://ehass.example", zap.NewNop())
+	byID := FHIRSearch{Params: url.Values{"_id": {strings.Join(ids, ",")}}}
+
+	tests := []struct {
+		name   string
+		userID uint
+		role   model.Role
+		want   []uint
+	}{
+		{name: "admin", userID: 99, role: model.RoleAdmin, want: []uint{1, 2, 3, 4, 5}},
+		{name: "treating doctor", userID: 1, role: model.RoleDoctor, want: []uint{1, 2, 4}},
+		{name: "doctor with cancelled bookings only", userID: 3, role: model.RoleDoctor, want: []uint{}},
+		{name: "guardian", userID: 10, role: model.RolePatient, want: []uint{1, 2, 4, 5}},
+		{name: "patient", userID: 12, role: model.RolePatient, want: []uint{3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bundle, err := s.SearchAppointments(context.Background(), tt.userID, tt.role, byID)
+			if err != nil {
+				t.Fatalf("SearchAppointments: %v", err)
+			}
+			got := []uint{}
+			for _, entry := range bundle.Entry {
+				for _, appointment := range clinic.appointments.appointments {
+					if appointment.PublicID == entry.Resource.(*fhir.Appointment).ID {
+						got = append(got, appointment.ID)
+					}
+				}
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) || bundle.Total != int64(len(tt.want)) {
+				t.Errorf("got %v of total %d, want %v", got, bundle.Total, tt.want)
+			}
+		})
+	}
+
+	// Other searches must name the patients unless made by an admin
+	_, err := s.SearchAppointments(context.Background(), 1, model.RoleDoctor, FHIRSearch{Params: url.Values{"status": {"booked"}}})
+	if !errors.Is(err, ErrInvalidFHIRSearch) {
+		t.Errorf("search without patients: got %v, want ErrInvalidFHIRSearch", err)
+	}
+}
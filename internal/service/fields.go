@@ -0,0 +1,132 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/whitewalker-sa/ehass/internal/repository"
+)
+
+var (
+	// ErrUnknownField is returned when a field selection names a field the resource does not have
+	ErrUnknownField = errors.New("unknown field")
+	// ErrInvalidSort is returned when a list cannot be sorted as requested
+	ErrInvalidSort = errors.New("invalid sort")
+)
+
+// ListQuery shapes a page of a list. Both are optional.
+type ListQuery struct {
+	Fields []string // Response fields to return; all when empty
+	Sort   []string // Sort keys, most significant first, each prefixed with - for descending order
+}
+
+// fieldSource lists what a response field is built from
+type fieldSource struct {
+	columns  []string
+	preloads []string
+}
+
+// appointmentFields maps the fields of an appointment in API responses to what must be loaded
+var appointmentFields = map[string]fieldSource{
+	"id":                    {columns: []string{"public_id"}},
+	"patient_id":            {columns: []string{"patient_id"}, preloads: []string{"Patient"}},
+	"patient_name":          {columns: []string{"patient_id"}, preloads: []string{"Patient.User"}},
+	"doctor_id":             {columns: []string{"doctor_id"}, preloads: []string{"Doctor"}},
+	"doctor_name":           {columns: []string{"doctor_id"}, preloads: []string{"Doctor.User"}},
+	"participants":          {columns: []string{"id"}, preloads: []string{"Participants.Patient.User"}},
+	"scheduled_start":       {columns: []string{"scheduled_start"}},
+	"scheduled_end":         {columns: []string{"scheduled_end"}},
+	"timezone":              {},
+	"status":                {columns: []string{"status"}},
+	"modality":              {columns: []string{"type"}},
+	"appointment_type_id":   {columns: []string{"appointment_type_id"}},
+	"appointment_type_name": {columns: []string{"appointment_type_id"}, preloads: []string{"AppointmentType"}},
+	"visit_reason_id":       {columns: []string{"visit_reason_id"}},
+	"reason":                {columns: []string{"reason"}},
+	"notes":                 {columns: []string{"notes"}},
+	"confirmation_required": {columns: []string{"confirmation_required"}},
+	"checked_in_at":         {columns: []string{"checked_in_at"}},
+	"late_cancellation":     {columns: []string{"late_cancellation"}},
+	"series_id":             {columns: []string{"series_id"}, preloads: []string{"Series"}},
+	"checklist":             {columns: []string{"checklist"}},
+	"tags":                  {columns: []string{"tags"}},
+	"metadata":              {columns: []string{"metadata"}},
+	"created_at":            {columns: []string{"created_at"}},
+	"updated_at":            {columns: []string{"updated_at"}},
+}
+
+// doctorFields maps the fields of a doctor in API responses to what must be loaded
+var doctorFields = map[string]fieldSource{
+	"id":          {columns: []string{"public_id"}},
+	"user_id":     {columns: []string{"user_id"}, preloads: []string{"User"}},
+	"name":        {columns: []string{"user_id"}, preloads: []string{"User"}},
+	"email":       {columns: []string{"user_id"}, preloads: []string{"User"}},
+	"specialty":   {columns: []string{"specialty"}},
+	"designation": {columns: []string{"designation"}},
+	"education":   {columns: []string{"education"}},
+	"experience":  {columns: []string{"experience"}},
+	"license_no":  {columns: []string{"license_no"}},
+	"bio":         {columns: []string{"bio"}},
+}
+
+// listOptions returns the repository options for a list query
+func listOptions(resource string, sources map[string]fieldSource, query ListQuery) (repository.ListOptions, error) {
+	opts, err := projection(resource, sources, query.Fields)
+	if err != nil {
+		return repository.ListOptions{}, err
+	}
+
+	seen := make(map[string]bool, len(query.Sort))
+	for _, key := range query.Sort {
+		field := repository.SortField{Key: strings.TrimPrefix(key, "-")}
+		field.Desc = field.Key != key
+		if field.Key == "" || seen[field.Key] {
+			return repository.ListOptions{}, fmt.Errorf("%w: %q", ErrInvalidSort, key)
+		}
+		seen[field.Key] = true
+		opts.Sort = append(opts.Sort, field)
+	}
+	return opts, nil
+}
+
+// listError reports a sort key the repository does not support as an invalid sort
+func listError(err error) error {
+	if errors.Is(err, repository.ErrUnknownSortKey) {
+		return fmt.Errorf("%w: %v", ErrInvalidSort, err)
+	}
+	return err
+}
+
+// projection returns list options loading only what the selected fields need. Without a
+// selection, everything is loaded as before.
+func projection(resource string, sources map[string]fieldSource, fields []string) (repository.ListOptions, error) {
+	if len(fields) == 0 {
+		return repository.ListOptions{}, nil
+	}
+
+	opts := repository.ListOptions{
+		Columns:  []string{"id"},
+		Preloads: []string{},
+	}
+	seen := map[string]bool{"id": true}
+	for _, field := range fields {
+		source, ok := sources[field]
+		if !ok {
+			return repository.ListOptions{}, fmt.Errorf("%w: %s has no field %q", ErrUnknownField, resource, field)
+		}
+		for _, column := range source.columns {
+			if !seen[column] {
+				seen[column] = true
+				opts.Columns = append(opts.Columns, column)
+			}
+		}
+		for _, preload := range source.preloads {
+			if !seen[preload] {
+				seen[preload] = true
+				opts.Preloads = append(opts.Preloads, preload)
+			}
+		}
+	}
+	return opts, nil
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// Audit actions for bookings changed by staff on a patient's behalf
+const (
+	AuditActionBookedOnBehalf      = "appointment.booked_on_behalf"
+	AuditActionRescheduledOnBehalf = "appointment.rescheduled_on_behalf"
+	AuditActionCancelledOnBehalf   = "appointment.cancelled_on_behalf"
+)
+
+// BookOnBehalf books an appointment for a patient as the staff member signed in as staffID, such
+// as a receptionist taking a booking over the phone. The booking follows the same rules as one
+// made by the patient, records who made it, and is audit logged.
+func (s *appointmentService) BookOnBehalf(ctx context.Context, staffID, patientID, doctorID, appointmentTypeID, visitReasonID uint, participantIDs []uint, date, timeStr, reason string, intakeAnswers map[string]string) (*model.Appointment, error) {
+	appointment, err := s.createAppointment(ctx, staffID, patientID, doctorID, appointmentTypeID, visitReasonID, participantIDs, date, timeStr, reason, intakeAnswers)
+	if err != nil {
+		return nil, err
+	}
+
+	patient, err := s.patientRepo.FindByID(ctx, patientID)
+	if err != nil {
+		return nil, err
+	}
+	s.auditOnBehalf(ctx, staffID, AuditActionBookedOnBehalf, appointment, "",
+		fmt.Sprintf("patient %s at %s", patient.PublicID, appointment.ScheduledStart.UTC().Format(time.RFC3339)))
+	return appointment, nil
+}
+
+// RescheduleOnBehalf moves a patient's appointment as the staff member signed in as staffID. The
+// move is recorded in the appointment's history as made by the staff member and audit logged.
+func (s *appointmentService) RescheduleOnBehalf(ctx context.Context, id, staffID uint, date, timeStr, reason string) (*model.Appointment, error) {
+	existing, err := s.appointmentRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	previousStart := existing.ScheduledStart
+
+	appointment, err := s.RescheduleAppointment(ctx, id, staffID, date, timeStr, reason)
+	if err != nil {
+		return nil, err
+	}
+
+	s.auditOnBehalf(ctx, staffID, AuditActionRescheduledOnBehalf, appointment,
+		previousStart.UTC().Format(time.RFC3339), appointment.ScheduledStart.UTC().Format(time.RFC3339))
+	return appointment, nil
+}
+
+// CancelOnBehalf cancels a patient's appointment as the staff member signed in as staffID, under
+// the same cancellation policy as the patient, and audit logs it with reason
+func (s *appointmentService) CancelOnBehalf(ctx context.Context, id, staffID uint, reason string) (*CancellationPolicy, error) {
+	appointment, err := s.appointmentRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	previousStatus := appointment.Status
+
+	policy, err := s.cancelAppointment(ctx, appointment)
+	if err != nil {
+		return policy, err
+	}
+
+	detail := string(model.AppointmentStatusCancelled)
+	if reason = strings.TrimSpace(reason); reason != "" {
+		detail += ": " + reason
+	}
+	s.auditOnBehalf(ctx, staffID, AuditActionCancelledOnBehalf, appointment, string(previousStatus), detail)
+	return policy, nil
+}
+
+// auditOnBehalf records a booking change a staff member made for a patient
+func (s *appointmentService) auditOnBehalf(ctx context.Context, staffID uint, action string, appointment *model.Appointment, oldValue, newValue string) {
+	client := utils.ClientInfoFromContext(ctx)
+	if err := s.auditLogRepo.Create(ctx, &model.AuditLog{
+		UserID:     staffID,
+		Action:     action,
+		EntityID:   appointment.ID,
+		EntityType: "appointment",
+		OldValue:   oldValue,
+		NewValue:   newValue,
+		IP:         client.IP,
+		UserAgent:  client.UserAgent,
+		CreatedAt:  time.Now(),
+	}); err != nil {
+		s.logger.Error("Failed to write booking audit log",
+			zap.String("action", action),
+			zap.Uint("appointmentID", appointment.ID),
+			zap.Error(err))
+	}
+}
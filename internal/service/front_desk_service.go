@@ -0,0 +1,298 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/repository"
+	"github.com/whitewalker-sa/ehass/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// ErrNoFollowUp is returned when resolving the follow-up of an appointment that is not flagged for one
+var ErrNoFollowUp = errors.New("appointment is not flagged for follow-up")
+
+const (
+	// candidateSearchDays is how far ahead later bookings are looked for to fill today's gaps
+	candidateSearchDays = 7
+	// maxGapCandidates bounds the bookings suggested for a single gap
+	maxGapCandidates = 3
+)
+
+// TodayDoctor is a doctor's state on the front-desk view
+type TodayDoctor struct {
+	Doctor    *model.Doctor
+	Status    model.DoctorStatus
+	StatusSet bool               // The doctor set the status today rather than it being derived
+	Current   *model.Appointment // Appointment in progress, if any
+	Next      *model.Appointment // Next active appointment today, if any
+	Booked    int                // Active appointments today
+}
+
+// ScheduleGap is free time in a doctor's schedule today, with later bookings that could be
+// brought forward into it
+type ScheduleGap struct {
+	Doctor     *model.Doctor
+	Start      time.Time
+	End        time.Time
+	Candidates []*model.Appointment
+}
+
+// TodayView is everything the reception screen shows for a clinic's day
+type TodayView struct {
+	Organization *model.Organization
+	Date         time.Time
+	Timezone     string
+	Doctors      []*TodayDoctor
+	Appointments []*model.Appointment
+	Gaps         []*ScheduleGap
+	FollowUps    []*model.Appointment // Upcoming appointments still unconfirmed after every reminder, soonest first
+}
+
+type frontDeskService struct {
+	orgRepo          repository.OrganizationRepository
+	doctorRepo       repository.DoctorRepository
+	availabilityRepo repository.AvailabilityRepository
+	appointmentRepo  repository.AppointmentRepository
+	logger           *zap.Logger
+}
+
+// NewFrontDeskService creates a new front-desk service
+func NewFrontDeskService(
+	orgRepo repository.OrganizationRepository,
+	doctorRepo repository.DoctorRepository,
+	availabilityRepo repository.AvailabilityRepository,
+	appointmentRepo repository.AppointmentRepository,
+	logger *zap.Logger,
+) FrontDeskService {
+	return &frontDeskService{
+		orgRepo:          orgRepo,
+		doctorRepo:       doctorRepo,
+		availabilityRepo: availabilityRepo,
+		appointmentRepo:  appointmentRepo,
+		logger:           logger,
+	}
+}
+
+// GetToday builds the front-desk view of a clinic's day, or of the default clinic when orgID is
+// 0. The doctors, their availability, the appointments of the day and the following week and the
+// appointments flagged for follow-up are each loaded in a single query.
+func (s *frontDeskService) GetToday(ctx context.Context, orgID uint) (*TodayView, error) {
+	// Doctors without a clinic follow the default one
+	def, err := s.orgRepo.FindDefault(ctx)
+	if err != nil {
+		def = model.DefaultOrganization()
+	}
+	org := def
+	if orgID != 0 && orgID != def.ID {
+		if org, err = s.orgRepo.FindByID(ctx, orgID); err != nil {
+			return nil, err
+		}
+	}
+	loc := utils.LoadLocation(org.Timezone)
+	now := time.Now()
+	today, _ := utils.ResolveDate("today", now.In(loc))
+	tomorrow := today.AddDate(0, 0, 1)
+
+	doctors, err := s.doctorRepo.FindByOrganization(ctx, org.ID, org == def)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get doctors: %w", err)
+	}
+
+	view := &TodayView{
+		Organization: org,
+		Date:         today,
+		Timezone:     loc.String(),
+		Doctors:      make([]*TodayDoctor, 0, len(doctors)),
+		Appointments: []*model.Appointment{},
+		Gaps:         []*ScheduleGap{},
+		FollowUps:    []*model.Appointment{},
+	}
+	if len(doctors) == 0 {
+		return view, nil
+	}
+
+	doctorIDs := make([]uint, len(doctors))
+	for i, doctor := range doctors {
+		doctorIDs[i] = doctor.ID
+	}
+	availability, err := s.availabilityRepo.FindByDoctorIDs(ctx, doctorIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get availability: %w", err)
+	}
+	appointments, err := s.appointmentRepo.FindByDoctorsBetween(ctx, doctorIDs, today, today.AddDate(0, 0, 1+candidateSearchDays))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get appointments: %w", err)
+	}
+
+	followUps, err := s.appointmentRepo.FindFollowUps(ctx, doctorIDs, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get follow-ups: %w", err)
+	}
+	view.FollowUps = append(view.FollowUps, followUps...)
+
+	availabilityByDoctor := make(map[uint][]*model.Availability)
+	for _, a := range availability {
+		availabilityByDoctor[a.DoctorID] = append(availabilityByDoctor[a.DoctorID], a)
+	}
+	todayByDoctor := make(map[uint][]*model.Appointment)
+	laterByDoctor := make(map[uint][]*model.Appointment)
+	for _, appt := range appointments {
+		if appt.ScheduledStart.Before(tomorrow) {
+			view.Appointments = append(view.Appointments, appt)
+			todayByDoctor[appt.DoctorID] = append(todayByDoctor[appt.DoctorID], appt)
+		} else if isActiveAppointment(appt) {
+			laterByDoctor[appt.DoctorID] = append(laterByDoctor[appt.DoctorID], appt)
+		}
+	}
+
+	for _, doctor := range doctors {
+		windows := todayWindows(availabilityWindows(availabilityByDoctor[doctor.ID], org, doctor.AppointmentLength(org)), today)
+		booked := todayByDoctor[doctor.ID]
+		view.Doctors = append(view.Doctors, doctorToday(doctor, windows, booked, today, now))
+		for _, gap := range freeGaps(windows, booked, now, doctor.AppointmentLength(org)) {
+			gap.Doctor = doctor
+			gap.Candidates = gapCandidates(gap, laterByDoctor[doctor.ID])
+			view.Gaps = append(view.Gaps, gap)
+		}
+	}
+	return view, nil
+}
+
+// ResolveFollowUp clears the follow-up flag of an appointment once the front desk has reached the
+// patient. With attending set, the patient's confirmation that they will attend is recorded too.
+func (s *frontDeskService) ResolveFollowUp(ctx context.Context, id uint, attending bool) (*model.Appointment, error) {
+	appointment, err := s.appointmentRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !appointment.FollowUpRequired {
+		return nil, ErrNoFollowUp
+	}
+
+	now := time.Now()
+	appointment.FollowUpRequired = false
+	if attending && appointment.AttendanceConfirmedAt == nil {
+		appointment.AttendanceConfirmedAt = &now
+	}
+	appointment.UpdatedAt = now
+	if err := s.appointmentRepo.Update(ctx, appointment); err != nil {
+		return nil, fmt.Errorf("failed to resolve follow-up: %w", err)
+	}
+	return appointment, nil
+}
+
+// todayWindows returns the schedule windows falling on the given day as absolute times
+func todayWindows(windows []scheduleWindow, day time.Time) []Slot {
+	var today []Slot
+	for _, w := range windows {
+		if !w.appliesOn(day) {
+			continue
+		}
+		start, end := w.on(day)
+		today = append(today, Slot{Start: start, End: end})
+	}
+	return today
+}
+
+// doctorToday works out a doctor's status at now. A status the doctor set today wins; otherwise
+// they are in consultation during an appointment, available within their schedule and off site
+// outside it.
+func doctorToday(doctor *model.Doctor, windows []Slot, appointments []*model.Appointment, today, now time.Time) *TodayDoctor {
+	td := &TodayDoctor{Doctor: doctor, Status: model.DoctorStatusOffSite}
+	for _, w := range windows {
+		if !now.Before(w.Start) && now.Before(w.End) {
+			td.Status = model.DoctorStatusAvailable
+			break
+		}
+	}
+	for _, appt := range appointments {
+		if !isActiveAppointment(appt) {
+			continue
+		}
+		td.Booked++
+		switch {
+		case !now.Before(appt.ScheduledStart) && now.Before(appt.ScheduledEnd):
+			td.Current = appt
+			td.Status = model.DoctorStatusInConsultation
+		case appt.ScheduledStart.After(now) && td.Next == nil:
+			td.Next = appt
+		}
+	}
+
+	// A status set on an earlier day is stale; doctors rarely remember to clear it
+	if doctor.Status != "" && doctor.StatusSetAt != nil && !doctor.StatusSetAt.Before(today) {
+		td.Status = doctor.Status
+		td.StatusSet = true
+	}
+	return td
+}
+
+// freeGaps returns the stretches of at least minLength in today's windows after now that no
+// active appointment covers
+func freeGaps(windows []Slot, appointments []*model.Appointment, now time.Time, minLength time.Duration) []*ScheduleGap {
+	var busy []Slot
+	for _, appt := range appointments {
+		if isActiveAppointment(appt) {
+			busy = append(busy, Slot{Start: appt.ScheduledStart, End: appt.ScheduledEnd})
+		}
+	}
+
+	var gaps []*ScheduleGap
+	for _, w := range windows {
+		cursor := w.Start
+		if cursor.Before(now) {
+			cursor = now
+		}
+		for cursor.Before(w.End) {
+			end := w.End
+			for _, b := range busy {
+				if !b.End.After(cursor) || !b.Start.Before(end) {
+					continue
+				}
+				if !b.Start.After(cursor) {
+					// The cursor is inside a booking; skip past it and look again
+					cursor = b.End
+					end = cursor
+					break
+				}
+				end = b.Start
+			}
+			if end.Equal(cursor) {
+				continue
+			}
+			if end.Sub(cursor) >= minLength {
+				gaps = append(gaps, &ScheduleGap{Start: cursor, End: end})
+			}
+			cursor = end
+		}
+	}
+	return gaps
+}
+
+// gapCandidates picks the earliest later bookings short enough to move into the gap
+func gapCandidates(gap *ScheduleGap, later []*model.Appointment) []*model.Appointment {
+	candidates := []*model.Appointment{}
+	length := gap.End.Sub(gap.Start)
+	for _, appt := range later {
+		if appt.ScheduledEnd.Sub(appt.ScheduledStart) <= length {
+			candidates = append(candidates, appt)
+			if len(candidates) == maxGapCandidates {
+				break
+			}
+		}
+	}
+	return candidates
+}
+
+// isActiveAppointment reports whether an appointment still takes up the doctor's time
+func isActiveAppointment(appt *model.Appointment) bool {
+	switch appt.Status {
+	case model.AppointmentStatusPending, model.AppointmentStatusConfirmed, model.AppointmentStatusCheckedIn:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/repository"
+	"go.uber.org/zap"
+)
+
+// maxHandoffNoteLength caps the length of a handoff note in characters
+const maxHandoffNoteLength = 10000
+
+// ErrNoTreatmentRelationship is returned when a doctor outside a patient's care team reads or
+// writes the patient's handoff notes
+var ErrNoTreatmentRelationship = errors.New("only doctors treating this patient can access handoff notes")
+
+type handoffService struct {
+	repo        repository.HandoffRepository
+	doctorRepo  repository.DoctorRepository
+	patientRepo repository.PatientRepository
+	logger      *zap.Logger
+}
+
+// NewHandoffService creates a new handoff note service
+func NewHandoffService(
+	repo repository.HandoffRepository,
+	doctorRepo repository.DoctorRepository,
+	patientRepo repository.PatientRepository,
+	logger *zap.Logger,
+) HandoffService {
+	return &handoffService{
+		repo:        repo,
+		doctorRepo:  doctorRepo,
+		patientRepo: patientRepo,
+		logger:      logger,
+	}
+}
+
+// AddNote writes a handoff note on a patient as the doctor signed in as userID. recipientID may
+// be 0 for a note to the care team, or name the doctor the patient is handed over to, which
+// gives them access to the notes.
+func (s *handoffService) AddNote(ctx context.Context, userID, patientID, recipientID uint, body string) (*model.HandoffNote, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return nil, errors.New("note body is required")
+	}
+	if len([]rune(body)) > maxHandoffNoteLength {
+		return nil, fmt.Errorf("note body must be at most %d characters", maxHandoffNoteLength)
+	}
+
+	author, err := s.careTeamDoctor(ctx, userID, patientID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.patientRepo.FindByID(ctx, patientID); err != nil {
+		return nil, err
+	}
+
+	note := &model.HandoffNote{
+		PatientID: patientID,
+		AuthorID:  author.ID,
+		Author:    *author,
+		Body:      body,
+		CreatedAt: time.Now(),
+	}
+	if recipientID != 0 {
+		if recipientID == author.ID {
+			return nil, errors.New("cannot hand a patient over to yourself")
+		}
+		recipient, err := s.doctorRepo.FindByID(ctx, recipientID)
+		if err != nil {
+			return nil, err
+		}
+		note.RecipientID = &recipient.ID
+		note.Recipient = recipient
+	}
+
+	if err := s.repo.Create(ctx, note); err != nil {
+		return nil, fmt.Errorf("failed to save handoff note: %w", err)
+	}
+
+	s.logger.Info("Handoff note added",
+		zap.Uint("patientID", patientID),
+		zap.Uint("authorID", author.ID),
+		zap.Bool("handover", note.RecipientID != nil))
+	return note, nil
+}
+
+// ListNotes lists a patient's handoff notes, most recent first, for a doctor on their care team
+func (s *handoffService) ListNotes(ctx context.Context, userID, patientID uint, page, pageSize int) ([]*model.HandoffNote, int64, error) {
+	if _, err := s.careTeamDoctor(ctx, userID, patientID); err != nil {
+		return nil, 0, err
+	}
+	offset := (page - 1) * pageSize
+	return s.repo.FindByPatientID(ctx, patientID, pageSize, offset)
+}
+
+// careTeamDoctor returns the doctor signed in as userID if they have a treatment relationship
+// with the patient
+func (s *handoffService) careTeamDoctor(ctx context.Context, userID, patientID uint) (*model.Doctor, error) {
+	doctor, err := s.doctorRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, ErrNoTreatmentRelationship
+	}
+	related, err := s.repo.HasTreatmentRelationship(ctx, doctor.ID, patientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check treatment relationship: %w", err)
+	}
+	if !related {
+		return nil, ErrNoTreatmentRelationship
+	}
+	return doctor, nil
+}
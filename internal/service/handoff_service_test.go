@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestHandoffNotesRequireTreatmentRelationship(t *testing.T) {
+	clinic := newTestClinic()
+	s := NewHandoffService(clinic.handoffs, clinic.doctors, clinic.patients, zap.NewNop())
+	ctx := context.Background()
+
+	if _, err := s.AddNote(ctx, 1, 10, 0, "Watch the blood pressure"); err != nil {
+		t.Fatalf("treating doctor AddNote: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		userID    uint
+		patientID uint
+	}{
+		{"pending booking", 2, 10},
+		{"no booking", 3, 10},
+		{"cancelled booking", 1, 12},
+		{"not a doctor", 10, 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.AddNote(ctx, tt.userID, tt.patientID, 0, "Note"); !errors.Is(err, ErrNoTreatmentRelationship) {
+				t.Errorf("AddNote: got %v, want ErrNoTreatmentRelationship", err)
+			}
+			if _, _, err := s.ListNotes(ctx, tt.userID, tt.patientID, 1, 20); !errors.Is(err, ErrNoTreatmentRelationship) {
+				t.Errorf("ListNotes: got %v, want ErrNoTreatmentRelationship", err)
+			}
+		})
+	}
+	if len(clinic.handoffs.notes) != 1 {
+		t.Errorf("%d notes saved, want only the treating doctor's", len(clinic.handoffs.notes))
+	}
+}
+
+func TestHandoverGivesRecipientAccess(t *testing.T) {
+	clinic := newTestClinic()
+	s := NewHandoffService(clinic.handoffs, clinic.doctors, clinic.patients, zap.NewNop())
+	ctx := context.Background()
+
+	if _, err := s.AddNote(ctx, 1, 10, 1, "To myself"); err == nil {
+		t.Error("doctor handed a patient over to themselves")
+	}
+	if _, err := s.AddNote(ctx, 1, 10, 404, "To nobody"); err == nil {
+		t.Error("patient handed over to an unknown doctor")
+	}
+
+	note, err := s.AddNote(ctx, 1, 10, 3, "Handing over while I am on leave")
+	if err != nil {
+		t.Fatalf("AddNote: %v", err)
+	}
+	if note.RecipientID == nil || *note.RecipientID != 3 || note.AuthorID != 1 {
+		t.Fatalf("note from %d to %v, want from doctor 1 to doctor 3", note.AuthorID, note.RecipientID)
+	}
+
+	notes, total, err := s.ListNotes(ctx, 3, 10, 1, 20)
+	if err != nil {
+		t.Fatalf("recipient ListNotes: %v", err)
+	}
+	if total != 1 || len(notes) != 1 || notes[0].ID != note.ID {
+		t.Errorf("recipient listed %d of %d notes, want the handover", len(notes), total)
+	}
+	if _, err := s.AddNote(ctx, 3, 10, 0, "Seen today"); err != nil {
+		t.Errorf("recipient AddNote: %v", err)
+	}
+
+	// The handover covers that patient only
+	if _, _, err := s.ListNotes(ctx, 3, 11, 1, 20); !errors.Is(err, ErrNoTreatmentRelationship) {
+		t.Errorf("recipient ListNotes of another patient: got %v, want ErrNoTreatmentRelationship", err)
+	}
+}
@@ -0,0 +1,313 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/repository"
+	"github.com/whitewalker-sa/ehass/pkg/pdf"
+	"github.com/whitewalker-sa/ehass/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// AuditActionImmunizationRecorded is the audit action for a recorded vaccine dose
+const AuditActionImmunizationRecorded = "immunization.recorded"
+
+// ErrInvalidImmunization is returned when a vaccine dose fails validation
+var ErrInvalidImmunization = errors.New("invalid immunization")
+
+// ImmunizationInput is a vaccine dose to record. The administered date is a day, without a time
+// of day.
+type ImmunizationInput struct {
+	VaccineCode      string
+	DoseNumber       int
+	AdministeredDate time.Time
+	LotNumber        string
+	Notes            string
+}
+
+// ImmunizationDue is the next dose of a vaccine a patient has started
+type ImmunizationDue struct {
+	Vaccine    model.VaccineSeries
+	DoseNumber int
+	Booster    bool // The dose follows a complete primary series
+	DueDate    time.Time
+	Overdue    bool // Due before today in the patient's timezone
+}
+
+// ImmunizationRecord is a patient's vaccine doses with the next dose due of each vaccine
+type ImmunizationRecord struct {
+	Patient       *model.Patient
+	Immunizations []*model.Immunization
+	Due           []ImmunizationDue // Soonest first
+	Location      *time.Location    // The patient's timezone
+}
+
+type immunizationService struct {
+	repo         repository.ImmunizationRepository
+	orgRepo      repository.OrganizationRepository
+	auditLogRepo repository.AuditLogRepository
+	access       recordAccess
+	logger       *zap.Logger
+}
+
+// NewImmunizationService creates a new immunization service. Access to a patient's
+// immunizations follows their medical records.
+func NewImmunizationService(
+	repo repository.ImmunizationRepository,
+	orgRepo repository.OrganizationRepository,
+	handoffRepo repository.HandoffRepository,
+	doctorRepo repository.DoctorRepository,
+	patientRepo repository.PatientRepository,
+	auditLogRepo repository.AuditLogRepository,
+	logger *zap.Logger,
+) ImmunizationService {
+	return &immunizationService{
+		repo:         repo,
+		orgRepo:      orgRepo,
+		auditLogRepo: auditLogRepo,
+		access: recordAccess{
+			doctorRepo:  doctorRepo,
+			patientRepo: patientRepo,
+			handoffRepo: handoffRepo,
+		},
+		logger: logger,
+	}
+}
+
+// RecordDose records a vaccine dose the doctor signed in as userID gave the patient. The doctor
+// must be treating the patient. Doses given elsewhere before may be missing from the record, so
+// a dose can be recorded without the ones before it.
+func (s *immunizationService) RecordDose(ctx context.Context, userID, patientID uint, input ImmunizationInput) (*model.Immunization, error) {
+	doctor, err := s.access.treatingDoctor(ctx, userID, patientID)
+	if err != nil {
+		return nil, err
+	}
+	patient, err := s.access.patientRepo.FindByID(ctx, patientID)
+	if err != nil {
+		return nil, err
+	}
+
+	series, ok := model.FindVaccineSeries(strings.TrimSpace(input.VaccineCode))
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown vaccine code %q, see GET /vaccines", ErrInvalidImmunization, input.VaccineCode)
+	}
+	lot := strings.TrimSpace(input.LotNumber)
+	now := time.Now().In(utils.LoadLocation(patient.User.Timezone))
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	switch {
+	case input.DoseNumber < 1:
+		return nil, fmt.Errorf("%w: dose number must be at least 1", ErrInvalidImmunization)
+	case input.DoseNumber > series.Doses && series.BoosterDays == 0:
+		return nil, fmt.Errorf("%w: %s is given in %d doses without boosters", ErrInvalidImmunization, series.Name, series.Doses)
+	case input.AdministeredDate.After(today):
+		return nil, fmt.Errorf("%w: administered date cannot be in the future", ErrInvalidImmunization)
+	case !patient.DateOfBirth.IsZero() && input.AdministeredDate.Before(patient.DateOfBirth):
+		return nil, fmt.Errorf("%w: administered date is before the patient's date of birth", ErrInvalidImmunization)
+	case len([]rune(lot)) > 50:
+		return nil, fmt.Errorf("%w: lot number must be at most 50 characters", ErrInvalidImmunization)
+	}
+
+	existing, err := s.repo.FindByPatientID(ctx, patientID)
+	if err != nil {
+		return nil, err
+	}
+	for _, dose := range existing {
+		if dose.VaccineCode == series.Code && dose.DoseNumber == input.DoseNumber {
+			return nil, fmt.Errorf("%w: dose %d of %s is already recorded", ErrInvalidImmunization, input.DoseNumber, series.Name)
+		}
+	}
+
+	immunization := &model.Immunization{
+		PatientID:        patientID,
+		VaccineCode:      series.Code,
+		VaccineName:      series.Name,
+		DoseNumber:       input.DoseNumber,
+		AdministeredDate: input.AdministeredDate,
+		DoctorID:         doctor.ID,
+		LotNumber:        lot,
+		Notes:            strings.TrimSpace(input.Notes),
+	}
+	if err := s.repo.Create(ctx, immunization); err != nil {
+		return nil, fmt.Errorf("failed to record immunization: %w", err)
+	}
+	immunization.Doctor = *doctor
+	s.audit(ctx, userID, immunization.ID)
+	return immunization, nil
+}
+
+// GetRecord returns a patient's vaccine doses with the next dose due of each vaccine they have
+// started
+func (s *immunizationService) GetRecord(ctx context.Context, userID uint, role model.Role, patientID uint) (*ImmunizationRecord, error) {
+	if err := s.access.authorizeRead(ctx, userID, role, patientID); err != nil {
+		return nil, err
+	}
+	patient, err := s.access.patientRepo.FindByID(ctx, patientID)
+	if err != nil {
+		return nil, err
+	}
+	immunizations, err := s.repo.FindByPatientID(ctx, patientID)
+	if err != nil {
+		return nil, err
+	}
+
+	loc := utils.LoadLocation(patient.User.Timezone)
+	return &ImmunizationRecord{
+		Patient:       patient,
+		Immunizations: immunizations,
+		Due:           dueDoses(immunizations, time.Now().In(loc)),
+		Location:      loc,
+	}, nil
+}
+
+// RenderCertificate renders a patient's vaccination certificate with the clinic's details
+func (s *immunizationService) RenderCertificate(ctx context.Context, userID uint, role model.Role, patientID uint) ([]byte, error) {
+	record, err := s.GetRecord(ctx, userID, role, patientID)
+	if err != nil {
+		return nil, err
+	}
+	org, err := s.orgRepo.FindDefault(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return certificateDocument(record, org), nil
+}
+
+// dueDoses works out the next dose of each vaccine from its latest recorded dose, soonest first.
+// Vaccines whose series is complete without boosters have none.
+func dueDoses(immunizations []*model.Immunization, now time.Time) []ImmunizationDue {
+	latest := make(map[string]*model.Immunization)
+	for _, dose := range immunizations {
+		if current, ok := latest[dose.VaccineCode]; !ok || dose.DoseNumber > current.DoseNumber {
+			latest[dose.VaccineCode] = dose
+		}
+	}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	var due []ImmunizationDue
+	for code, dose := range latest {
+		series, ok := model.FindVaccineSeries(code)
+		if !ok {
+			continue
+		}
+		days, ok := series.DueAfter(dose.DoseNumber)
+		if !ok {
+			continue
+		}
+		date := dose.AdministeredDate.AddDate(0, 0, days)
+		due = append(due, ImmunizationDue{
+			Vaccine:    series,
+			DoseNumber: dose.DoseNumber + 1,
+			Booster:    dose.DoseNumber >= series.Doses,
+			DueDate:    date,
+			Overdue:    date.Before(today),
+		})
+	}
+	sort.Slice(due, func(i, j int) bool {
+		if !due[i].DueDate.Equal(due[j].DueDate) {
+			return due[i].DueDate.Before(due[j].DueDate)
+		}
+		return due[i].Vaccine.Name < due[j].Vaccine.Name
+	})
+	return due
+}
+
+// audit records a vaccine dose; its details are not logged
+func (s *immunizationService) audit(ctx context.Context, userID, id uint) {
+	client := utils.ClientInfoFromContext(ctx)
+	if err := s.auditLogRepo.Create(ctx, &model.AuditLog{
+		UserID:     userID,
+		Action:     AuditActionImmunizationRecorded,
+		EntityID:   id,
+		EntityType: "immunization",
+		IP:         client.IP,
+		UserAgent:  client.UserAgent,
+		CreatedAt:  time.Now(),
+	}); err != nil {
+		s.logger.Error("Failed to write immunization audit log",
+			zap.Uint("entityID", id),
+			zap.Error(err))
+	}
+}
+
+// certificateDocument renders a vaccination certificate listing every recorded dose and the next
+// doses due
+func certificateDocument(record *ImmunizationRecord, org *model.Organization) []byte {
+	patient := record.Patient
+
+	doc := pdf.New("Vaccination certificate")
+	doc.SetHeadingColor(org.PrimaryColor)
+	doc.Heading(org.DisplayName())
+	var contact []string
+	for _, line := range []string{org.Address, org.ContactPhone, org.ContactEmail} {
+		if line != "" {
+			contact = append(contact, line)
+		}
+	}
+	if len(contact) > 0 {
+		doc.Text(strings.Join(contact, " | "))
+	}
+	if org.PrimaryColor != "" {
+		doc.Rule()
+	}
+	doc.Spacer(16)
+
+	doc.Heading("Vaccination certificate")
+	doc.Table([]pdf.Column{
+		{Title: "Patient", Width: 0.25},
+		{Title: "", Width: 0.75},
+	}, [][]string{
+		{"Name", patient.User.Name},
+		{"Date of birth", patient.DateOfBirth.Format("2 January 2006")},
+	})
+
+	doc.Spacer(12)
+	if len(record.Immunizations) == 0 {
+		doc.Text("No vaccinations recorded.")
+	} else {
+		rows := make([][]string, 0, len(record.Immunizations))
+		for _, dose := range record.Immunizations {
+			rows = append(rows, []string{
+				dose.VaccineName + " (CVX " + dose.VaccineCode + ")",
+				strconv.Itoa(dose.DoseNumber),
+				dose.AdministeredDate.Format("2 Jan 2006"),
+				dose.Doctor.User.Name,
+				dose.LotNumber,
+			})
+		}
+		doc.Table([]pdf.Column{
+			{Title: "Vaccine", Width: 0.34},
+			{Title: "Dose", Width: 0.08},
+			{Title: "Date", Width: 0.16},
+			{Title: "Administered by", Width: 0.26},
+			{Title: "Lot", Width: 0.16},
+		}, rows)
+	}
+
+	if len(record.Due) > 0 {
+		doc.Spacer(12)
+		rows := make([][]string, 0, len(record.Due))
+		for _, due := range record.Due {
+			dose := strconv.Itoa(due.DoseNumber)
+			if due.Booster {
+				dose += " (booster)"
+			}
+			rows = append(rows, []string{due.Vaccine.Name, dose, due.DueDate.Format("2 Jan 2006")})
+		}
+		doc.Table([]pdf.Column{
+			{Title: "Next doses due", Width: 0.5},
+			{Title: "Dose", Width: 0.25},
+			{Title: "Due", Width: 0.25},
+		}, rows)
+	}
+
+	doc.Spacer(12)
+	doc.Text("Printed " + time.Now().In(record.Location).Format("2006-01-02 15:04 MST") + ".")
+	return doc.Bytes()
+}
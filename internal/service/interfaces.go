@@ -2,8 +2,14 @@ package service
 
 import (
 	"context"
+	"encoding/json"
+	"io"
+	"time"
 
 	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/pkg/breaker"
+	"github.com/whitewalker-sa/ehass/pkg/fhir"
+	"github.com/whitewalker-sa/ehass/pkg/metrics"
 )
 
 // AuthService defines authentication service operations
@@ -27,26 +33,57 @@ type AuthService interface {
 
 	// Session management
 	Logout(ctx context.Context, token string) error
+	LogoutAll(ctx context.Context, userID uint) error
 	ValidateToken(ctx context.Context, token string) (*model.User, error)
+
+	// Step-up authentication
+	ChangeEmail(ctx context.Context, userID uint, email string) error
+	Reauthenticate(ctx context.Context, userID uint, token, password, code string) (string, string, error)
+	AuthTime(ctx context.Context, token string) (time.Time, error)
+	RotateSigningSecret(secret string)
+	Introspect(ctx context.Context, token string) *TokenIntrospection
 }
 
 // UserService defines user management operations
 type UserService interface {
 	GetUserByID(ctx context.Context, id uint) (*model.User, error)
-	UpdateUserProfile(ctx context.Context, id uint, name, phone, address string) (*model.User, error)
+	UpdateUserProfile(ctx context.Context, id uint, name, phone, phoneCountry, address string) (*model.User, error)
 	ChangePassword(ctx context.Context, id uint, oldPassword, newPassword string) error
 	DeleteUser(ctx context.Context, id uint) error
 	UpdateAvatar(ctx context.Context, id uint, avatarURL string) (*model.User, error)
+	UpdatePreferences(ctx context.Context, id uint, timezone, locale, channel string) (*model.User, error)
+}
+
+// CalendarService defines iCalendar exports of appointments and calendar feeds
+type CalendarService interface {
+	GetAppointmentCalendar(ctx context.Context, id, userID uint) ([]byte, error)
+	CreateFeed(ctx context.Context, userID uint) (string, error)
+	RevokeFeed(ctx context.Context, userID uint) error
+	GetFeed(ctx context.Context, userID uint, token string) ([]byte, error)
+}
+
+// CalendarSyncService defines two-way sync of doctors' external calendars
+type CalendarSyncService interface {
+	AuthorizationURL(ctx context.Context, userID uint) (string, string, error)
+	Connect(ctx context.Context, userID uint, code string) (*model.CalendarConnection, error)
+	GetConnection(ctx context.Context, userID uint) (*model.CalendarConnection, error)
+	Disconnect(ctx context.Context, userID uint) error
+	Sync(ctx context.Context) error
 }
 
 // DoctorService defines doctor management operations
 type DoctorService interface {
 	CreateDoctor(ctx context.Context, userID uint, specialty, bio string, experience int) (*model.Doctor, error)
 	GetDoctorByID(ctx context.Context, id uint) (*model.Doctor, error)
+	GetDoctorsByPublicIDs(ctx context.Context, publicIDs []string) ([]*model.Doctor, []string, error)
 	GetDoctorByUserID(ctx context.Context, userID uint) (*model.Doctor, error)
 	UpdateDoctorProfile(ctx context.Context, id uint, specialty, bio string, experience int) (*model.Doctor, error)
-	GetAllDoctors(ctx context.Context, page, pageSize int) ([]*model.Doctor, int64, error)
-	GetDoctorsBySpecialty(ctx context.Context, specialty string, page, pageSize int) ([]*model.Doctor, int64, error)
+	GetAllDoctors(ctx context.Context, page, pageSize int, query ListQuery) ([]*model.Doctor, int64, error)
+	GetDoctorsBySpecialty(ctx context.Context, specialty string, page, pageSize int, query ListQuery) ([]*model.Doctor, int64, error)
+	SetStatus(ctx context.Context, id, userID uint, status model.DoctorStatus) (*model.Doctor, error)
+	SetAutoConfirm(ctx context.Context, id, userID uint, enabled bool) (*model.Doctor, error)
+	SetScheduling(ctx context.Context, id, userID uint, slotLength, bufferMinutes, maxParallel int) (*model.Doctor, error)
+	SetCancellationCutoff(ctx context.Context, id, userID uint, cutoff *int) (*model.Doctor, error)
 	DeleteDoctor(ctx context.Context, id uint) error
 }
 
@@ -55,34 +92,433 @@ type PatientService interface {
 	CreatePatient(ctx context.Context, userID uint, dateOfBirth, medicalHistory string) (*model.Patient, error)
 	GetPatientByID(ctx context.Context, id uint) (*model.Patient, error)
 	GetPatientByUserID(ctx context.Context, userID uint) (*model.Patient, error)
-	UpdatePatientProfile(ctx context.Context, id uint, dateOfBirth, medicalHistory string) (*model.Patient, error)
+	SearchPatients(ctx context.Context, query string, limit int) ([]*model.Patient, error)
+	UpdatePatientProfile(ctx context.Context, id uint, update PatientProfileUpdate) (*model.Patient, error)
+	SetGuardian(ctx context.Context, patientID, guardianID uint) (*model.Patient, error)
 }
 
 // AppointmentService defines appointment management operations
 type AppointmentService interface {
-	CreateAppointment(ctx context.Context, patientID, doctorID uint, date, time, reason string) (*model.Appointment, error)
+	CreateAppointment(ctx context.Context, userID uint, bookingStaff bool, patientID, doctorID, appointmentTypeID, visitReasonID uint, participantIDs []uint, date, time, reason string, intakeAnswers map[string]string) (*model.Appointment, error)
 	GetAppointmentByID(ctx context.Context, id uint) (*model.Appointment, error)
-	GetPatientAppointments(ctx context.Context, patientID uint, page, pageSize int) ([]*model.Appointment, int64, error)
-	GetDoctorAppointments(ctx context.Context, doctorID uint, page, pageSize int) ([]*model.Appointment, int64, error)
+	GetAppointmentsByPublicIDs(ctx context.Context, publicIDs []string) ([]*model.Appointment, []string, error)
+	GetPatientAppointments(ctx context.Context, patientID uint, page, pageSize int, query ListQuery) ([]*model.Appointment, int64, error)
+	GetDoctorAppointments(ctx context.Context, doctorID uint, page, pageSize int, query ListQuery) ([]*model.Appointment, int64, error)
+	ListAppointments(ctx context.Context, filter AppointmentFilter, page, pageSize int, query ListQuery) ([]*model.Appointment, int64, error)
 	GetDoctorAppointmentsByDateRange(ctx context.Context, doctorID uint, startDate, endDate string, page, pageSize int) ([]*model.Appointment, int64, error)
 	UpdateAppointment(ctx context.Context, id uint, date, time, status, reason string) (*model.Appointment, error)
-	CancelAppointment(ctx context.Context, id uint) error
+	RescheduleAppointment(ctx context.Context, id, userID uint, date, time, reason string) (*model.Appointment, error)
+	GetAppointmentHistory(ctx context.Context, id uint) ([]*model.AppointmentHistory, error)
+	CancelAppointment(ctx context.Context, id uint) (*CancellationPolicy, error)
+	BookOnBehalf(ctx context.Context, staffID, patientID, doctorID, appointmentTypeID, visitReasonID uint, participantIDs []uint, date, time, reason string, intakeAnswers map[string]string) (*model.Appointment, error)
+	RescheduleOnBehalf(ctx context.Context, id, staffID uint, date, time, reason string) (*model.Appointment, error)
+	CancelOnBehalf(ctx context.Context, id, staffID uint, reason string) (*CancellationPolicy, error)
+	GetCancellationPolicy(ctx context.Context, id uint) (*CancellationPolicy, error)
+	CheckInAppointment(ctx context.Context, id uint) (*model.Appointment, error)
+	GetCheckInQueue(ctx context.Context, doctorID uint) ([]*model.Appointment, error)
+	StartAppointment(ctx context.Context, id, userID uint) (*model.Appointment, error)
+	ConfirmAppointment(ctx context.Context, id, userID uint) (*model.Appointment, error)
+	DeclineAppointment(ctx context.Context, id, userID uint, reason string) (*model.Appointment, error)
 	CompleteAppointment(ctx context.Context, id uint, notes string) error
+	ConfirmAttendance(ctx context.Context, id, userID uint) (*model.Appointment, error)
+	SubmitIntake(ctx context.Context, id uint, answers map[string]string) (*model.Appointment, error)
+	SetChecklistItem(ctx context.Context, id, userID uint, requirement model.IntakeRequirement, done bool) (*model.Appointment, error)
+	SetTags(ctx context.Context, id uint, tags []string) (*model.Appointment, error)
+	SetMetadata(ctx context.Context, id uint, metadata map[string]string) (*model.Appointment, error)
+	GenerateDaySheet(ctx context.Context, userID uint, frontDesk bool, doctorID uint, day time.Time) ([]byte, error)
+	GenerateConfirmationLetter(ctx context.Context, id uint) ([]byte, error)
+}
+
+// RecurringAppointmentService defines recurring appointment series operations. Single
+// occurrences are changed through AppointmentService like any other appointment.
+type RecurringAppointmentService interface {
+	CreateSeries(ctx context.Context, userID uint, bookingStaff bool, patientID, doctorID, appointmentTypeID uint, date, time string, frequency model.RecurrenceFrequency, occurrences int, reason string, intakeAnswers map[string]string) (*model.RecurringAppointment, error)
+	GetSeries(ctx context.Context, id, userID uint, manage bool) (*model.RecurringAppointment, error)
+	ListOccurrences(ctx context.Context, id, userID uint, manage bool) ([]*model.Appointment, error)
+	UpdateSeries(ctx context.Context, id, from, userID uint, manage bool, date, time, reason string) (*model.RecurringAppointment, error)
+	CancelSeries(ctx context.Context, id, from, userID uint, manage bool) error
 }
 
 // AvailabilityService defines availability management operations
 type AvailabilityService interface {
-	AddAvailability(ctx context.Context, doctorID uint, day string, startTime, endTime string) (*model.Availability, error)
+	AddAvailability(ctx context.Context, doctorID uint, day string, startTime, endTime string, duration int) (*model.Availability, error)
 	GetDoctorAvailability(ctx context.Context, doctorID uint) ([]*model.Availability, error)
-	UpdateAvailability(ctx context.Context, id uint, day string, startTime, endTime string) (*model.Availability, error)
-	RemoveAvailability(ctx context.Context, id uint) error
+	UpdateAvailability(ctx context.Context, doctorID, id uint, day string, startTime, endTime string, duration int, confirm bool) (*model.Availability, []*model.Appointment, error)
+	RemoveAvailability(ctx context.Context, doctorID, id uint, confirm bool) ([]*model.Appointment, error)
+	ApplyShiftTemplate(ctx context.Context, doctorID, templateID uint, from, until string, replace, confirm bool) ([]*model.Availability, []*model.Appointment, error)
+	AddTimeOff(ctx context.Context, doctorID uint, start, end time.Time, reason string, coveringDoctorID uint, cancelAppointments bool) (*model.TimeOff, []*model.Appointment, error)
+	GetDoctorTimeOff(ctx context.Context, doctorID uint) ([]*model.TimeOff, error)
+	UpdateTimeOff(ctx context.Context, doctorID, id uint, start, end time.Time, reason string, coveringDoctorID uint, cancelAppointments bool) (*model.TimeOff, []*model.Appointment, error)
+	RemoveTimeOff(ctx context.Context, doctorID, id uint) error
 }
 
 // MedicalRecordService defines medical record management operations
 type MedicalRecordService interface {
-	CreateMedicalRecord(ctx context.Context, patientID, doctorID uint, diagnosis, prescription, notes string) (*model.MedicalRecord, error)
-	GetMedicalRecordByID(ctx context.Context, id uint) (*model.MedicalRecord, error)
-	GetPatientMedicalRecords(ctx context.Context, patientID uint, page, pageSize int) ([]*model.MedicalRecord, int64, error)
-	UpdateMedicalRecord(ctx context.Context, id uint, diagnosis, prescription, notes string) (*model.MedicalRecord, error)
-	DeleteMedicalRecord(ctx context.Context, id uint) error
+	CreateMedicalRecord(ctx context.Context, userID, patientID uint, visitDate time.Time, diagnosis, notes string) (*model.MedicalRecord, error)
+	GetMedicalRecord(ctx context.Context, userID uint, role model.Role, patientID, id uint) (*model.MedicalRecord, error)
+	GetPatientMedicalRecords(ctx context.Context, userID uint, role model.Role, patientID uint, page, pageSize int) ([]*model.MedicalRecord, int64, error)
+	UpdateMedicalRecord(ctx context.Context, userID, patientID, id uint, diagnosis, notes string) (*model.MedicalRecord, error)
+	DeleteMedicalRecord(ctx context.Context, userID, patientID, id uint) error
+	SignMedicalRecord(ctx context.Context, userID, patientID, id uint) (*model.MedicalRecord, error)
+	AddAddendum(ctx context.Context, userID, patientID, id uint, text string) (*model.MedicalRecordAddendum, error)
+	GetMedicalRecordHistory(ctx context.Context, userID uint, role model.Role, patientID, id uint) (*MedicalRecordHistory, error)
+	AddAttachment(ctx context.Context, userID, patientID, recordID uint, fileName string, data []byte) (*model.MedicalRecordAttachment, error)
+	ListAttachments(ctx context.Context, userID uint, role model.Role, patientID, recordID uint) ([]*model.MedicalRecordAttachment, error)
+	AttachmentDownloadURL(ctx context.Context, userID uint, role model.Role, patientID, recordID, id uint) (string, time.Time, error)
+	OpenAttachment(ctx context.Context, token string) (*model.MedicalRecordAttachment, io.ReadCloser, error)
+	DeleteAttachment(ctx context.Context, userID, patientID, recordID, id uint) error
+	SearchMedications(ctx context.Context, text string, limit int) ([]*model.Medication, error)
+	ImportMedications(ctx context.Context, medications []*model.Medication) (int, error)
+	RetireMedication(ctx context.Context, id uint) error
+	IssuePrescription(ctx context.Context, userID, patientID uint, input PrescriptionInput) (*model.Prescription, error)
+	ListPrescriptions(ctx context.Context, userID uint, role model.Role, patientID uint, all bool, page, pageSize int) ([]*model.Prescription, int64, error)
+	GetPrescription(ctx context.Context, userID uint, role model.Role, patientID, id uint) (*model.Prescription, error)
+	CancelPrescription(ctx context.Context, userID, patientID, id uint, reason string) (*model.Prescription, error)
+	RenderPrescription(ctx context.Context, userID uint, role model.Role, patientID, id uint) ([]byte, error)
+	RecordVital(ctx context.Context, userID uint, role model.Role, patientID uint, input VitalInput) (*model.Vital, error)
+	ListVitals(ctx context.Context, userID uint, role model.Role, patientID uint, filter VitalFilter, limit int) ([]*model.Vital, error)
+	SummarizeVitals(ctx context.Context, userID uint, role model.Role, patientID uint, filter VitalFilter) ([]*VitalSummary, error)
+	DeleteVital(ctx context.Context, userID, patientID, id uint) error
+}
+
+// ClinicalListService defines operations for patients' allergy and medication lists
+type ClinicalListService interface {
+	CreateAllergy(ctx context.Context, userID uint, role model.Role, patientID uint, input AllergyInput) (*model.Allergy, error)
+	ListAllergies(ctx context.Context, userID uint, role model.Role, patientID uint, activeOnly bool) ([]*model.Allergy, error)
+	UpdateAllergy(ctx context.Context, userID uint, role model.Role, patientID, id uint, input AllergyInput) (*model.Allergy, error)
+	DeleteAllergy(ctx context.Context, userID uint, role model.Role, patientID, id uint) error
+	CreateMedication(ctx context.Context, userID uint, role model.Role, patientID uint, input PatientMedicationInput) (*model.PatientMedication, error)
+	ListMedications(ctx context.Context, userID uint, role model.Role, patientID uint, activeOnly bool) ([]*model.PatientMedication, error)
+	UpdateMedication(ctx context.Context, userID uint, role model.Role, patientID, id uint, input PatientMedicationInput) (*model.PatientMedication, error)
+	DeleteMedication(ctx context.Context, userID uint, role model.Role, patientID, id uint) error
+}
+
+// ImmunizationService defines operations for patients' immunization records: recording doses,
+// working out the next doses due and printing vaccination certificates
+type ImmunizationService interface {
+	RecordDose(ctx context.Context, userID, patientID uint, input ImmunizationInput) (*model.Immunization, error)
+	GetRecord(ctx context.Context, userID uint, role model.Role, patientID uint) (*ImmunizationRecord, error)
+	RenderCertificate(ctx context.Context, userID uint, role model.Role, patientID uint) ([]byte, error)
+}
+
+// APIKeyService defines partner API key operations: issuing and throttling keys, metering the
+// requests made with them and reporting their usage
+type APIKeyService interface {
+	IssueKey(ctx context.Context, adminID uint, input APIKeyInput) (*model.APIKey, string, error)
+	UpdateKey(ctx context.Context, adminID, id uint, limits APIKeyLimits) (*model.APIKey, error)
+	RevokeKey(ctx context.Context, adminID, id uint) error
+	Authenticate(ctx context.Context, secret string) (*model.APIKey, error)
+	Limits(key *model.APIKey) (int, int)
+	Meter(ctx context.Context, key *model.APIKey, now time.Time) (int64, error)
+	RecordRejected(ctx context.Context, key *model.APIKey, now time.Time) error
+	Usage(ctx context.Context, userID uint, allKeys bool, fromDate, toDate string) (*APIUsageReport, error)
+}
+
+// FHIRService defines read and search operations over patients, doctors, appointments and vital
+// signs as FHIR R4 resources, for EHR systems
+type FHIRService interface {
+	CapabilityStatement() *fhir.CapabilityStatement
+	ReadPatient(ctx context.Context, id string) (*fhir.Patient, error)
+	SearchPatients(ctx context.Context, search FHIRSearch) (*fhir.Bundle, error)
+	ReadPractitioner(ctx context.Context, id string) (*fhir.Practitioner, error)
+	SearchPractitioners(ctx context.Context, search FHIRSearch) (*fhir.Bundle, error)
+	ReadAppointment(ctx context.Context, userID uint, role model.Role, id string) (*fhir.Appointment, error)
+	SearchAppointments(ctx context.Context, userID uint, role model.Role, search FHIRSearch) (*fhir.Bundle, error)
+	ReadObservation(ctx context.Context, userID uint, role model.Role, id string) (*fhir.Observation, error)
+	SearchObservations(ctx context.Context, userID uint, role model.Role, search FHIRSearch) (*fhir.Bundle, error)
+}
+
+// LabService defines lab order, result ingestion and abnormal result review operations
+type LabService interface {
+	CreateOrder(ctx context.Context, userID, patientID uint, input LabOrderInput) (*model.LabOrder, error)
+	ListOrders(ctx context.Context, userID uint, role model.Role, patientID uint, page, pageSize int) ([]*model.LabOrder, int64, error)
+	GetOrder(ctx context.Context, userID uint, role model.Role, patientID, id uint) (*model.LabOrder, error)
+	IngestResults(ctx context.Context, orderID uint, inputs []LabResultInput) (*model.LabOrder, error)
+	AbnormalInbox(ctx context.Context, userID uint, page, pageSize int) ([]*model.LabResult, int64, error)
+	ReviewResult(ctx context.Context, userID, id uint) (*model.LabResult, error)
+}
+
+// AccountService defines patient account operations: posting payments and credits and
+// producing statements of account
+type AccountService interface {
+	PostEntry(ctx context.Context, userID, patientID uint, input AccountEntryInput) (*model.AccountEntry, error)
+	GetStatement(ctx context.Context, userID uint, role model.Role, billingStaff bool, patientID uint, fromDate, toDate string) (*Statement, error)
+	RenderStatement(ctx context.Context, userID uint, role model.Role, billingStaff bool, patientID uint, fromDate, toDate string) ([]byte, error)
+}
+
+// ConsentService defines terms-of-service and privacy consent operations
+type ConsentService interface {
+	GetCurrentPolicies(ctx context.Context) []model.Policy
+	GetUserConsents(ctx context.Context, userID uint) ([]*model.Consent, error)
+	AcceptPolicy(ctx context.Context, userID uint, policyType model.PolicyType, version, ip, userAgent string) (*model.Consent, error)
+	GetMissingConsents(ctx context.Context, userID uint) ([]model.Policy, error)
+	GetMarketingConsents(ctx context.Context, userID uint) ([]*model.MarketingConsent, error)
+	SetMarketingConsent(ctx context.Context, userID uint, channel string, granted bool, ip, userAgent string) (*model.MarketingConsent, error)
+}
+
+// HandoffService defines internal handoff notes between the doctors treating a patient
+type HandoffService interface {
+	AddNote(ctx context.Context, userID, patientID, recipientID uint, body string) (*model.HandoffNote, error)
+	ListNotes(ctx context.Context, userID, patientID uint, page, pageSize int) ([]*model.HandoffNote, int64, error)
+}
+
+// MessageService defines secure messaging between patients and the doctors treating them
+type MessageService interface {
+	SendMessage(ctx context.Context, userID uint, role model.Role, patientID, doctorID uint, body string) ([]*model.Message, error)
+	ListPatientMessages(ctx context.Context, userID uint, role model.Role, patientID uint, page, pageSize int) ([]*model.Message, int64, error)
+	ListDoctorMessages(ctx context.Context, userID uint, page, pageSize int) ([]*model.Message, int64, error)
+}
+
+// ReviewService defines patient reviews of doctors and the doctors' responses
+type ReviewService interface {
+	CreateReview(ctx context.Context, userID, doctorID, appointmentID uint, rating int, comment string) (*model.DoctorReview, error)
+	ListReviews(ctx context.Context, doctorID uint, page, pageSize int) ([]*model.DoctorReview, int64, float64, error)
+	RespondToReview(ctx context.Context, userID, doctorID, reviewID uint, response string) (*model.DoctorReview, error)
+}
+
+// TemplateService defines operations for checking email and SMS templates before they go live
+type TemplateService interface {
+	ListTemplates() []MessageTemplate
+	Preview(ctx context.Context, name string) (*RenderedMessage, error)
+	SendTest(ctx context.Context, name, to string) (*RenderedMessage, error)
+}
+
+// TelehealthService defines video visit waiting room operations
+type TelehealthService interface {
+	GetWaitingRoom(ctx context.Context, appointmentID, userID uint, role model.Role) (*WaitingRoom, error)
+	JoinWaitingRoom(ctx context.Context, appointmentID, userID uint) (*WaitingRoom, error)
+	LeaveWaitingRoom(ctx context.Context, appointmentID, userID uint) (*WaitingRoom, error)
+	AdmitPatients(ctx context.Context, appointmentID, userID uint) (*WaitingRoom, error)
+	EndVisit(ctx context.Context, appointmentID, userID uint) (*WaitingRoom, error)
+	AppointmentEventFilter(ctx context.Context, appointmentID, userID uint, role model.Role) (WaitingRoomFilter, error)
+	DoctorEventFilter(ctx context.Context, userID uint) (WaitingRoomFilter, error)
+	LatestWaitingRoomEvent(ctx context.Context, filter WaitingRoomFilter) (uint, error)
+	WaitingRoomEvents(ctx context.Context, filter WaitingRoomFilter, afterID uint) ([]*model.WaitingRoomEvent, <-chan struct{}, error)
+	VisitReport(ctx context.Context, fromDate, toDate string, doctorID uint) (*VisitReport, error)
+}
+
+// FrontDeskService defines the reception screen's view of the clinic's day
+type FrontDeskService interface {
+	GetToday(ctx context.Context, orgID uint) (*TodayView, error)
+	ResolveFollowUp(ctx context.Context, id uint, attending bool) (*model.Appointment, error)
+}
+
+// BreakGlassService defines emergency access operations for patient records
+type BreakGlassService interface {
+	RequestAccess(ctx context.Context, userID, patientID uint, reason, ip, userAgent string) (*model.BreakGlassAccess, error)
+	GetEmergencyRecord(ctx context.Context, userID, patientID uint, ip, userAgent string) (*model.Patient, *model.BreakGlassAccess, error)
+	GetAccessLog(ctx context.Context, page, pageSize int) ([]*model.BreakGlassAccess, int64, error)
+}
+
+// SettingsService defines operational settings changed at runtime without a restart
+type SettingsService interface {
+	Current() *RuntimeSettings
+	ListSettings(ctx context.Context) ([]*SettingValue, error)
+	UpdateSettings(ctx context.Context, userID uint, values map[string]json.RawMessage) (*RuntimeSettings, error)
+	ResetSetting(ctx context.Context, userID uint, key string) (*RuntimeSettings, error)
+	Reload(ctx context.Context) error
+}
+
+// SecurityService defines anomaly detection on authentication events
+type SecurityService interface {
+	GetAuthDashboard(ctx context.Context, window time.Duration) (*AuthDashboard, error)
+	DetectAuthAnomalies(ctx context.Context) error
+}
+
+// RoleService defines custom role management and permission resolution
+type RoleService interface {
+	CreateRole(ctx context.Context, name, description string, permissions []model.Permission) (*model.CustomRole, error)
+	GetRole(ctx context.Context, id uint) (*model.CustomRole, error)
+	ListRoles(ctx context.Context) ([]*model.CustomRole, error)
+	UpdateRole(ctx context.Context, id uint, description string, permissions []model.Permission) (*model.CustomRole, error)
+	DeleteRole(ctx context.Context, id uint) error
+	AssignRole(ctx context.Context, userID, roleID uint) error
+	UnassignRole(ctx context.Context, userID, roleID uint) error
+	GetUserRoles(ctx context.Context, userID uint) ([]*model.CustomRole, error)
+	GetUserPermissions(ctx context.Context, userID uint) ([]model.Permission, error)
+	EffectivePermissions(ctx context.Context, userID uint, role model.Role) ([]model.Permission, error)
+}
+
+// OrganizationService defines clinic settings operations
+type OrganizationService interface {
+	CreateOrganization(ctx context.Context, org *model.Organization) (*model.Organization, error)
+	GetOrganization(ctx context.Context, id uint) (*model.Organization, error)
+	ListOrganizations(ctx context.Context) ([]*model.Organization, error)
+	UpdateOrganization(ctx context.Context, id uint, settings *model.Organization) (*model.Organization, error)
+	DeleteOrganization(ctx context.Context, id uint) error
+	AssignDoctor(ctx context.Context, orgID, doctorID uint) error
+	GetDoctorOrganization(ctx context.Context, doctorID uint) (*model.Organization, error)
+}
+
+// AppointmentTypeService defines appointment type management operations
+type AppointmentTypeService interface {
+	CreateAppointmentType(ctx context.Context, appointmentType *model.AppointmentType) (*model.AppointmentType, error)
+	GetAppointmentType(ctx context.Context, id uint) (*model.AppointmentType, error)
+	ListAppointmentTypes(ctx context.Context) ([]*model.AppointmentType, error)
+	ListDoctorAppointmentTypes(ctx context.Context, doctorID uint) ([]*model.AppointmentType, error)
+	UpdateAppointmentType(ctx context.Context, id uint, appointmentType *model.AppointmentType) (*model.AppointmentType, error)
+	ArchiveAppointmentType(ctx context.Context, id uint) error
+}
+
+// ShiftTemplateService defines shift template management operations
+type ShiftTemplateService interface {
+	CreateShiftTemplate(ctx context.Context, input ShiftTemplateInput) (*model.ShiftTemplate, error)
+	GetShiftTemplate(ctx context.Context, id uint) (*model.ShiftTemplate, error)
+	ListShiftTemplates(ctx context.Context) ([]*model.ShiftTemplate, error)
+	UpdateShiftTemplate(ctx context.Context, id uint, input ShiftTemplateInput) (*model.ShiftTemplate, error)
+	DeleteShiftTemplate(ctx context.Context, id uint) error
+}
+
+// VisitReasonService defines visit reason management and triage routing operations
+type VisitReasonService interface {
+	CreateVisitReason(ctx context.Context, reason *model.VisitReason) (*model.VisitReason, error)
+	GetVisitReason(ctx context.Context, id uint) (*model.VisitReason, error)
+	ListVisitReasons(ctx context.Context) ([]*model.VisitReason, error)
+	ListActiveVisitReasons(ctx context.Context) ([]*model.VisitReason, error)
+	ListReasonDoctors(ctx context.Context, id uint, page, pageSize int, query ListQuery) (*model.VisitReason, []*model.Doctor, int64, error)
+	UpdateVisitReason(ctx context.Context, id uint, reason *model.VisitReason) (*model.VisitReason, error)
+	RetireVisitReason(ctx context.Context, id uint) error
+}
+
+// NoShowService defines no-show risk scoring and booking confirmation operations
+type NoShowService interface {
+	AssessAppointment(ctx context.Context, appointment *model.Appointment) (*NoShowRisk, error)
+	ScreenBooking(ctx context.Context, appointment *model.Appointment) error
+	ConfirmBooking(ctx context.Context, appointmentID, userID uint, code string) (*model.Appointment, error)
+	GetPatientNoShows(ctx context.Context, patientID uint) (*PatientNoShows, error)
+}
+
+// PublicIDService defines operations for translating public identifiers to internal IDs
+type PublicIDService interface {
+	ResolveID(ctx context.Context, resource model.PublicResource, publicID string) (uint, error)
+}
+
+// AnalyticsService defines clinic analytics operations
+type AnalyticsService interface {
+	GetClinicAnalytics(ctx context.Context, orgID uint, granularity model.AnalyticsGranularity, fromDate, toDate string, refresh bool) (*ClinicAnalytics, error)
+	GetVisitDurations(ctx context.Context, orgID uint, fromDate, toDate string) (*VisitDurationReport, error)
+}
+
+// ScheduleService defines bookable slot lookup operations
+type ScheduleService interface {
+	GetAvailableSlots(ctx context.Context, doctorID uint, from, to string) (*SlotRange, error)
+	GetNextAvailableSlot(ctx context.Context, doctorID uint, after string) (*Slot, error)
+	SuggestDistribution(ctx context.Context, specialty, from, to string, count int) (*WorkloadPlan, error)
+}
+
+// EmailDeliveryService defines email delivery tracking and suppression operations
+type EmailDeliveryService interface {
+	ProcessEvents(ctx context.Context, events []EmailEvent) (int, error)
+	ListMessages(ctx context.Context, recipient string, status model.EmailStatus, page, pageSize int) ([]*model.EmailMessage, int64, error)
+	ListSuppressions(ctx context.Context, page, pageSize int) ([]*model.EmailSuppression, int64, error)
+	RemoveSuppression(ctx context.Context, email string) error
+}
+
+// NotificationService defines patient notification operations with channel fallback
+type NotificationService interface {
+	SendAppointmentReminder(ctx context.Context, appointment *model.Appointment) error
+	EscalateReminder(ctx context.Context, appointment *model.Appointment, channel string) error
+	RetryBouncedReminders(ctx context.Context, since time.Time) (int, error)
+	GetAppointmentNotifications(ctx context.Context, appointmentID uint) ([]*model.Notification, error)
+	SendMarketingMessage(ctx context.Context, userID uint, subject, message string) (*model.Notification, error)
+}
+
+// SlotHoldService defines operations for reserving slots while a booking is completed
+type SlotHoldService interface {
+	HoldSlot(ctx context.Context, patientID, doctorID uint, date, timeStr string) (*model.SlotHold, error)
+	ReleaseHold(ctx context.Context, token string) error
+	CheckSlot(ctx context.Context, patientID, doctorID uint, start time.Time) error
+	ReleaseSlot(ctx context.Context, doctorID uint, start time.Time)
+}
+
+// PatientAccountService links clinic-created patient records to patient logins
+type PatientAccountService interface {
+	CreateClinicRecord(ctx context.Context, name, email, phone, dateOfBirth string) (*model.Patient, error)
+	InvitePatient(ctx context.Context, patientID uint) (*Invitation, error)
+	ClaimAccount(ctx context.Context, claim AccountClaim) (*model.User, error)
+	SetUpAccount(ctx context.Context, setupToken, password string) (*model.User, error)
+}
+
+// Invitation is an invitation sent to claim a clinic-created record
+type Invitation struct {
+	Channel   string    // model.ChannelEmail or model.ChannelSMS
+	ExpiresAt time.Time // When the link and code stop working
+}
+
+// AccountClaim is what a patient submits to claim their record: the email or phone the
+// invitation went to, the code, and their registration details
+type AccountClaim struct {
+	Email    string
+	Phone    string
+	Code     string
+	Password string
+	Name     string
+	Address  string
+	NewEmail string // Required when the record was created without an email
+}
+
+// ProcedureService defines operations for procedure coding and the claims it feeds
+type ProcedureService interface {
+	SearchCodes(ctx context.Context, text string, system model.ProcedureCodeSystem, limit int) ([]*model.ProcedureCode, error)
+	ImportCodes(ctx context.Context, codes []*model.ProcedureCode) (int, error)
+	DeactivateCode(ctx context.Context, code string) error
+	ListAppointmentProcedures(ctx context.Context, appointmentID uint) ([]*model.AppointmentProcedure, error)
+	AddAppointmentProcedure(ctx context.Context, appointmentID, userID uint, procedure *model.AppointmentProcedure) (*model.AppointmentProcedure, error)
+	RemoveAppointmentProcedure(ctx context.Context, appointmentID, procedureID uint) error
+	ExportClaims(ctx context.Context, orgID uint, fromDate, toDate string) ([]ClaimLine, error)
+}
+
+// CareService defines preventive care rule management and the reminders patients have
+type CareService interface {
+	CreateRule(ctx context.Context, rule *model.CareRule) (*model.CareRule, error)
+	GetRule(ctx context.Context, id uint) (*model.CareRule, error)
+	ListRules(ctx context.Context) ([]*model.CareRule, error)
+	UpdateRule(ctx context.Context, id uint, rule *model.CareRule) (*model.CareRule, error)
+	ArchiveRule(ctx context.Context, id uint) error
+	GetPatientReminders(ctx context.Context, patientID uint) ([]*model.CareReminder, error)
+}
+
+// OperationsService reports background job health for runbooks and retries failed work
+type OperationsService interface {
+	GetQueues(ctx context.Context) ([]QueueStatus, error)
+	GetJobs() []JobStatus
+	GetBreakers() []breaker.Stats
+	RunJob(ctx context.Context, name string) (*JobStatus, error)
+	RetryFailedReminders(ctx context.Context) (int, error)
+	RetryFailedEvents(ctx context.Context) (int64, error)
+}
+
+// OperationService defines operations for running long requests in the background
+type OperationService interface {
+	Register(kind string, run OperationFunc)
+	Submit(ctx context.Context, kind string, userID uint, params interface{}) (*model.Operation, error)
+	Get(ctx context.Context, publicID string, userID uint) (*model.Operation, error)
+}
+
+// MetricsService defines the metrics exported for monitoring
+type MetricsService interface {
+	Collect(ctx context.Context) ([]metrics.Family, error)
+}
+
+// TranslationService defines operations for doctor content in other languages
+type TranslationService interface {
+	ListDoctorBios(ctx context.Context, doctorID uint) ([]*model.Translation, error)
+	SetDoctorBio(ctx context.Context, doctorID uint, locale, text string) (*model.Translation, error)
+	DeleteDoctorBio(ctx context.Context, doctorID uint, locale string) error
+	ListSpecialtyNames(ctx context.Context, specialty string) ([]*model.Translation, error)
+	SetSpecialtyName(ctx context.Context, specialty, locale, text string) (*model.Translation, error)
+	DeleteSpecialtyName(ctx context.Context, specialty, locale string) error
+	LocalizeDoctors(ctx context.Context, doctors []*model.Doctor, preferred []string) error
+}
+
+// SearchService searches doctors and patients, in the search backend when one is enabled, and
+// keeps its indexes in sync
+type SearchService interface {
+	SearchDoctors(ctx context.Context, text, specialty string, limit int) (*DoctorSearchResult, error)
+	SearchPatients(ctx context.Context, query string, limit int) ([]*model.Patient, error)
+	SearchEncounters(ctx context.Context, userID uint, role model.Role, text string, includeDiagnoses bool, limit int) ([]Encounter, error)
+	SuggestDoctors(ctx context.Context, prefix string, limit int) ([]DoctorSuggestion, error)
+	SuggestSpecialties(ctx context.Context, prefix string, limit int) ([]FacetCount, error)
+	Sync(ctx context.Context, event *model.OutboxEvent) error
+	Reindex(ctx context.Context) (*ReindexResult, error)
 }
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Names of the scheduled jobs reported by the job monitor
+const (
+	JobReminders  = "reminders"
+	JobSIEMExport = "siem_export"
+	JobCleanup    = "cleanup"
+	JobOutbox     = "outbox"
+)
+
+var (
+	// ErrJobNotFound is returned when running a job that is not scheduled on this instance
+	ErrJobNotFound = errors.New("job not found")
+	// ErrJobRunning is returned when a job is triggered while it is already running
+	ErrJobRunning = errors.New("job is already running")
+)
+
+// JobStatus reports the runs of a scheduled job since the instance started
+type JobStatus struct {
+	Name                string
+	Interval            time.Duration
+	Running             bool
+	Runs                int
+	Failures            int
+	ConsecutiveFailures int
+	LastRunAt           *time.Time
+	LastDuration        time.Duration
+	LastSuccessAt       *time.Time
+	LastError           string
+}
+
+// JobMonitor records the runs of scheduled jobs and lets operators trigger a run. Runs of the
+// same job never overlap. History is kept in memory, so each API instance reports its own jobs.
+// A nil monitor runs jobs without recording them.
+type JobMonitor struct {
+	mu   sync.Mutex
+	jobs map[string]*monitoredJob
+}
+
+type monitoredJob struct {
+	run    func(ctx context.Context) error
+	lock   sync.Mutex // Held while the job runs
+	status JobStatus
+}
+
+// NewJobMonitor creates a new job monitor
+func NewJobMonitor() *JobMonitor {
+	return &JobMonitor{jobs: make(map[string]*monitoredJob)}
+}
+
+// Register adds a job that runs every interval. run is what an operator-triggered run executes.
+func (m *JobMonitor) Register(name string, interval time.Duration, run func(ctx context.Context) error) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.jobs[name] = &monitoredJob{run: run, status: JobStatus{Name: name, Interval: interval}}
+}
+
+// Do runs fn as a run of the named job, waiting for a run in progress to finish first
+func (m *JobMonitor) Do(ctx context.Context, name string, fn func(ctx context.Context) error) error {
+	job := m.job(name)
+	if job == nil {
+		return fn(ctx)
+	}
+	job.lock.Lock()
+	defer job.lock.Unlock()
+	return m.execute(ctx, job, fn)
+}
+
+// Run runs a registered job now. It returns ErrJobRunning rather than waiting when the job is
+// already running.
+func (m *JobMonitor) Run(ctx context.Context, name string) error {
+	job := m.job(name)
+	if job == nil {
+		return ErrJobNotFound
+	}
+	if !job.lock.TryLock() {
+		return ErrJobRunning
+	}
+	defer job.lock.Unlock()
+	return m.execute(ctx, job, job.run)
+}
+
+// Jobs returns the status of every registered job, ordered by name
+func (m *JobMonitor) Jobs() []JobStatus {
+	if m == nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	jobs := make([]JobStatus, 0, len(m.jobs))
+	for _, job := range m.jobs {
+		jobs = append(jobs, job.status)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].Name < jobs[j].Name })
+	return jobs
+}
+
+// Job returns the status of a registered job
+func (m *JobMonitor) Job(name string) (JobStatus, error) {
+	job := m.job(name)
+	if job == nil {
+		return JobStatus{}, ErrJobNotFound
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return job.status, nil
+}
+
+func (m *JobMonitor) job(name string) *monitoredJob {
+	if m == nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.jobs[name]
+}
+
+// execute runs fn and records the outcome; the caller holds the job lock
+func (m *JobMonitor) execute(ctx context.Context, job *monitoredJob, fn func(ctx context.Context) error) error {
+	started := time.Now()
+	m.mu.Lock()
+	job.status.Running = true
+	m.mu.Unlock()
+
+	err := fn(ctx)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	status := &job.status
+	status.Running = false
+	status.Runs++
+	status.LastRunAt = &started
+	status.LastDuration = time.Since(started)
+	if err != nil {
+		status.Failures++
+		status.ConsecutiveFailures++
+		status.LastError = err.Error()
+	} else {
+		finished := started.Add(status.LastDuration)
+		status.ConsecutiveFailures = 0
+		status.LastSuccessAt = &finished
+		status.LastError = ""
+	}
+	return err
+}
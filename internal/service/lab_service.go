@@ -0,0 +1,377 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/repository"
+	"github.com/whitewalker-sa/ehass/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// Audit actions for lab orders and results
+const (
+	AuditActionLabOrderCreated   = "lab_order.created"
+	AuditActionLabResultReviewed = "lab_result.reviewed"
+)
+
+const (
+	maxLabOrderTests    = 50
+	maxLabNotesLength   = 2000
+	maxLabResultsPerRun = 200
+)
+
+var (
+	// ErrInvalidLabOrder is returned when a lab order fails validation
+	ErrInvalidLabOrder = errors.New("invalid lab order")
+	// ErrInvalidLabResult is returned when a result reported by a lab fails validation
+	ErrInvalidLabResult = errors.New("invalid lab result")
+	// ErrLabResultReviewed is returned when reviewing a result that was already reviewed
+	ErrLabResultReviewed = errors.New("lab result is already reviewed")
+)
+
+// LabOrderInput is what a doctor fills in to order lab tests. RecordID optionally links the
+// order to the medical record of the visit.
+type LabOrderInput struct {
+	Tests    []model.LabTest
+	RecordID uint
+	Notes    string
+}
+
+// LabResultInput is the result of one test as reported by a lab. The reference range can be
+// given as bounds or as text such as "3.5-5.0", "<200" or ">60". Without a flag, a numeric
+// value outside the range is flagged low or high.
+type LabResultInput struct {
+	TestCode       string
+	TestName       string
+	Value          string
+	Unit           string
+	ReferenceLow   *float64
+	ReferenceHigh  *float64
+	ReferenceRange string
+	Flag           model.LabFlag
+	ObservedAt     time.Time
+}
+
+type labService struct {
+	repo         repository.LabRepository
+	recordRepo   repository.MedicalRecordRepository
+	auditLogRepo repository.AuditLogRepository
+	access       recordAccess
+	logger       *zap.Logger
+}
+
+// NewLabService creates a new lab order and result service. Access to a patient's orders and
+// results follows their medical records.
+func NewLabService(
+	repo repository.LabRepository,
+	recordRepo repository.MedicalRecordRepository,
+	handoffRepo repository.HandoffRepository,
+	doctorRepo repository.DoctorRepository,
+	patientRepo repository.PatientRepository,
+	auditLogRepo repository.AuditLogRepository,
+	logger *zap.Logger,
+) LabService {
+	return &labService{
+		repo:         repo,
+		recordRepo:   recordRepo,
+		auditLogRepo: auditLogRepo,
+		access:       recordAccess{doctorRepo: doctorRepo, patientRepo: patientRepo, handoffRepo: handoffRepo},
+		logger:       logger,
+	}
+}
+
+// CreateOrder orders lab tests for a patient as the doctor signed in as userID, who must be on
+// the patient's care team. A linked record must be one they wrote.
+func (s *labService) CreateOrder(ctx context.Context, userID, patientID uint, input LabOrderInput) (*model.LabOrder, error) {
+	order := &model.LabOrder{
+		PatientID: patientID,
+		Notes:     strings.TrimSpace(input.Notes),
+		Status:    model.LabOrderOrdered,
+	}
+	if len(input.Tests) == 0 || len(input.Tests) > maxLabOrderTests {
+		return nil, fmt.Errorf("%w: order 1 to %d tests", ErrInvalidLabOrder, maxLabOrderTests)
+	}
+	seen := make(map[string]bool, len(input.Tests))
+	for _, test := range input.Tests {
+		test.Code = strings.TrimSpace(test.Code)
+		test.Name = strings.TrimSpace(test.Name)
+		switch {
+		case test.Code == "" || len(test.Code) > 50:
+			return nil, fmt.Errorf("%w: test codes must be 1 to 50 characters", ErrInvalidLabOrder)
+		case len([]rune(test.Name)) > 200:
+			return nil, fmt.Errorf("%w: test names must be at most 200 characters", ErrInvalidLabOrder)
+		case seen[test.Code]:
+			return nil, fmt.Errorf("%w: test %s is ordered twice", ErrInvalidLabOrder, test.Code)
+		}
+		seen[test.Code] = true
+		order.Tests = append(order.Tests, test)
+	}
+	if len([]rune(order.Notes)) > maxLabNotesLength {
+		return nil, fmt.Errorf("%w: notes must be at most %d characters", ErrInvalidLabOrder, maxLabNotesLength)
+	}
+
+	doctor, err := s.access.treatingDoctor(ctx, userID, patientID)
+	if err != nil {
+		return nil, err
+	}
+	if input.RecordID != 0 {
+		record, err := s.recordRepo.FindByID(ctx, input.RecordID)
+		if err != nil {
+			return nil, err
+		}
+		if record.PatientID != patientID {
+			return nil, errors.New("medical record not found")
+		}
+		if record.DoctorID != doctor.ID {
+			return nil, ErrNotRecordAuthor
+		}
+		order.RecordID = &record.ID
+	}
+
+	now := time.Now()
+	order.DoctorID = doctor.ID
+	order.OrderedAt = now
+	order.CreatedAt = now
+	order.UpdatedAt = now
+	if err := s.repo.CreateOrder(ctx, order); err != nil {
+		return nil, fmt.Errorf("failed to create lab order: %w", err)
+	}
+	order.Doctor = *doctor
+
+	s.audit(ctx, userID, AuditActionLabOrderCreated, "lab_order", order.ID)
+	return order, nil
+}
+
+// ListOrders lists a patient's lab orders with their results, most recent first, for the user
+// signed in as userID
+func (s *labService) ListOrders(ctx context.Context, userID uint, role model.Role, patientID uint, page, pageSize int) ([]*model.LabOrder, int64, error) {
+	if err := s.access.authorizeRead(ctx, userID, role, patientID); err != nil {
+		return nil, 0, err
+	}
+	offset := (page - 1) * pageSize
+	return s.repo.FindOrdersByPatientID(ctx, patientID, pageSize, offset)
+}
+
+// GetOrder gets one of a patient's lab orders with its results for the user signed in as userID
+func (s *labService) GetOrder(ctx context.Context, userID uint, role model.Role, patientID, id uint) (*model.LabOrder, error) {
+	if err := s.access.authorizeRead(ctx, userID, role, patientID); err != nil {
+		return nil, err
+	}
+	order, err := s.repo.FindOrder(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if order.PatientID != patientID {
+		return nil, errors.New("lab order not found")
+	}
+	return order, nil
+}
+
+// IngestResults records the results a lab reported for an order. A result for a test that
+// already has one is a correction and replaces it. Results outside their reference range are
+// marked abnormal and go to the ordering doctor's inbox until reviewed.
+func (s *labService) IngestResults(ctx context.Context, orderID uint, inputs []LabResultInput) (*model.LabOrder, error) {
+	if len(inputs) == 0 || len(inputs) > maxLabResultsPerRun {
+		return nil, fmt.Errorf("%w: report 1 to %d results at a time", ErrInvalidLabResult, maxLabResultsPerRun)
+	}
+	order, err := s.repo.FindOrder(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	results := make([]*model.LabResult, 0, len(inputs))
+	seen := make(map[string]bool, len(inputs))
+	for _, input := range inputs {
+		result, err := newLabResult(input, now)
+		if err != nil {
+			return nil, err
+		}
+		if seen[result.TestCode] {
+			return nil, fmt.Errorf("%w: test %s is reported twice", ErrInvalidLabResult, result.TestCode)
+		}
+		seen[result.TestCode] = true
+		result.OrderID = order.ID
+		result.PatientID = order.PatientID
+		results = append(results, result)
+	}
+
+	for _, result := range order.Results {
+		seen[result.TestCode] = true
+	}
+	order.Status = model.LabOrderResulted
+	for _, test := range order.Tests {
+		if !seen[test.Code] {
+			order.Status = model.LabOrderPartial
+			break
+		}
+	}
+	order.ResultedAt = &now
+	order.UpdatedAt = now
+	if err := s.repo.SaveResults(ctx, order, results); err != nil {
+		return nil, fmt.Errorf("failed to save lab results: %w", err)
+	}
+
+	abnormal := 0
+	for _, result := range results {
+		if result.Abnormal {
+			abnormal++
+		}
+	}
+	s.logger.Info("Lab results received",
+		zap.Uint("orderID", order.ID),
+		zap.Int("results", len(results)),
+		zap.Int("abnormal", abnormal))
+	return s.repo.FindOrder(ctx, order.ID)
+}
+
+// AbnormalInbox lists the unreviewed abnormal results of the orders placed by the doctor signed
+// in as userID, critical ones first and then most recent first
+func (s *labService) AbnormalInbox(ctx context.Context, userID uint, page, pageSize int) ([]*model.LabResult, int64, error) {
+	doctor, err := s.access.doctorRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, 0, err
+	}
+	offset := (page - 1) * pageSize
+	return s.repo.FindUnreviewedAbnormal(ctx, doctor.ID, pageSize, offset)
+}
+
+// ReviewResult marks a result as reviewed by the doctor signed in as userID, who must be on the
+// patient's care team, taking it out of the abnormal results inbox
+func (s *labService) ReviewResult(ctx context.Context, userID, id uint) (*model.LabResult, error) {
+	result, err := s.repo.FindResult(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.access.treatingDoctor(ctx, userID, result.PatientID); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	reviewed, err := s.repo.MarkReviewed(ctx, result.ID, userID, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to review lab result: %w", err)
+	}
+	if !reviewed {
+		return nil, ErrLabResultReviewed
+	}
+	result.ReviewedAt = &now
+	result.ReviewedByID = &userID
+
+	s.audit(ctx, userID, AuditActionLabResultReviewed, "lab_result", result.ID)
+	return result, nil
+}
+
+// newLabResult validates a reported result received at now and flags it against its reference
+// range
+func newLabResult(input LabResultInput, now time.Time) (*model.LabResult, error) {
+	result := &model.LabResult{
+		TestCode:       strings.TrimSpace(input.TestCode),
+		TestName:       strings.TrimSpace(input.TestName),
+		Value:          strings.TrimSpace(input.Value),
+		Unit:           strings.TrimSpace(input.Unit),
+		ReferenceLow:   input.ReferenceLow,
+		ReferenceHigh:  input.ReferenceHigh,
+		ReferenceRange: strings.TrimSpace(input.ReferenceRange),
+		Flag:           model.LabFlag(strings.ToUpper(strings.TrimSpace(string(input.Flag)))),
+		ObservedAt:     input.ObservedAt,
+		ReceivedAt:     now,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	switch {
+	case result.TestCode == "" || len(result.TestCode) > 50:
+		return nil, fmt.Errorf("%w: test codes must be 1 to 50 characters", ErrInvalidLabResult)
+	case len([]rune(result.TestName)) > 200:
+		return nil, fmt.Errorf("%w: test names must be at most 200 characters", ErrInvalidLabResult)
+	case result.Value == "" || len([]rune(result.Value)) > 100:
+		return nil, fmt.Errorf("%w: value of %s must be 1 to 100 characters", ErrInvalidLabResult, result.TestCode)
+	case len([]rune(result.Unit)) > 30 || len([]rune(result.ReferenceRange)) > 100:
+		return nil, fmt.Errorf("%w: unit or reference range of %s is too long", ErrInvalidLabResult, result.TestCode)
+	case result.Flag != "" && !result.Flag.IsValid():
+		return nil, fmt.Errorf("%w: flag of %s must be N, L, H, LL, HH or A", ErrInvalidLabResult, result.TestCode)
+	}
+	if result.ObservedAt.IsZero() {
+		result.ObservedAt = now
+	}
+
+	if result.ReferenceLow == nil && result.ReferenceHigh == nil && result.ReferenceRange != "" {
+		result.ReferenceLow, result.ReferenceHigh = parseReferenceRange(result.ReferenceRange)
+	}
+	if result.ReferenceLow != nil && result.ReferenceHigh != nil && *result.ReferenceLow > *result.ReferenceHigh {
+		return nil, fmt.Errorf("%w: reference range of %s is inverted", ErrInvalidLabResult, result.TestCode)
+	}
+
+	// The lab's own abnormal flag stands; otherwise numeric values are checked against the range
+	if !result.Flag.IsAbnormal() {
+		if value, err := strconv.ParseFloat(result.Value, 64); err == nil {
+			switch {
+			case result.ReferenceLow != nil && value < *result.ReferenceLow:
+				result.Flag = model.LabFlagLow
+			case result.ReferenceHigh != nil && value > *result.ReferenceHigh:
+				result.Flag = model.LabFlagHigh
+			case result.ReferenceLow != nil || result.ReferenceHigh != nil:
+				result.Flag = model.LabFlagNormal
+			}
+		}
+	}
+	result.Abnormal = result.Flag.IsAbnormal()
+	return result, nil
+}
+
+// parseReferenceRange reads the bounds of a reference range written as "low-high", "<high",
+// "<=high", ">low" or ">=low". Bounds that cannot be read are nil.
+func parseReferenceRange(text string) (*float64, *float64) {
+	text = strings.ReplaceAll(text, " ", "")
+	parse := func(s string) *float64 {
+		value, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil
+		}
+		return &value
+	}
+	switch {
+	case strings.HasPrefix(text, "<="):
+		return nil, parse(text[2:])
+	case strings.HasPrefix(text, "<"):
+		return nil, parse(text[1:])
+	case strings.HasPrefix(text, ">="):
+		return parse(text[2:]), nil
+	case strings.HasPrefix(text, ">"):
+		return parse(text[1:]), nil
+	}
+	// Skip the first character so a negative lower bound is not taken for the separator
+	if i := strings.Index(text[min(1, len(text)):], "-"); i >= 0 {
+		i++
+		low, high := parse(text[:i]), parse(text[i+1:])
+		if low != nil && high != nil {
+			return low, high
+		}
+	}
+	return nil, nil
+}
+
+// audit records a change to a lab order or result; values are not logged
+func (s *labService) audit(ctx context.Context, userID uint, action, entityType string, entityID uint) {
+	client := utils.ClientInfoFromContext(ctx)
+	if err := s.auditLogRepo.Create(ctx, &model.AuditLog{
+		UserID:     userID,
+		Action:     action,
+		EntityID:   entityID,
+		EntityType: entityType,
+		IP:         client.IP,
+		UserAgent:  client.UserAgent,
+		CreatedAt:  time.Now(),
+	}); err != nil {
+		s.logger.Error("Failed to write lab audit log",
+			zap.String("action", action),
+			zap.Uint("entityID", entityID),
+			zap.Error(err))
+	}
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// RouteLatency reports the requests to a route with a latency budget since the instance started
+type RouteLatency struct {
+	Route      string // Method and route pattern, e.g. "GET /api/v1/doctors/:id/slots"
+	Budget     time.Duration
+	Requests   int64
+	Violations int64 // Requests that took longer than the budget
+}
+
+// LatencyMonitor counts requests that exceed their route's latency budget. Routes listed in
+// budgets use their own budget and other routes the default; with a default of 0 only the listed
+// routes are tracked. Counts are kept in memory, so each API instance reports its own. A nil
+// monitor tracks nothing.
+type LatencyMonitor struct {
+	defaultBudget time.Duration
+	budgets       map[string]time.Duration
+
+	mu     sync.Mutex
+	routes map[string]*RouteLatency
+}
+
+// NewLatencyMonitor creates a new latency monitor
+func NewLatencyMonitor(defaultBudget time.Duration, budgets map[string]time.Duration) *LatencyMonitor {
+	return &LatencyMonitor{
+		defaultBudget: defaultBudget,
+		budgets:       budgets,
+		routes:        make(map[string]*RouteLatency),
+	}
+}
+
+// Budget returns the latency budget of a route, or 0 when the route is not tracked
+func (m *LatencyMonitor) Budget(route string) time.Duration {
+	if m == nil {
+		return 0
+	}
+	if budget, ok := m.budgets[route]; ok {
+		return budget
+	}
+	return m.defaultBudget
+}
+
+// Observe records a request to route that took elapsed. It returns the route's budget and
+// whether the request exceeded it.
+func (m *LatencyMonitor) Observe(route string, elapsed time.Duration) (time.Duration, bool) {
+	budget := m.Budget(route)
+	if budget <= 0 {
+		return 0, false
+	}
+	exceeded := elapsed > budget
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stats, ok := m.routes[route]
+	if !ok {
+		stats = &RouteLatency{Route: route, Budget: budget}
+		m.routes[route] = stats
+	}
+	stats.Requests++
+	if exceeded {
+		stats.Violations++
+	}
+	return budget, exceeded
+}
+
+// Routes reports the tracked routes that have been requested, sorted by route
+func (m *LatencyMonitor) Routes() []RouteLatency {
+	if m == nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	routes := make([]RouteLatency, 0, len(m.routes))
+	for _, stats := range m.routes {
+		routes = append(routes, *stats)
+	}
+	sort.Slice(routes, func(i, j int) bool { return routes[i].Route < routes[j].Route })
+	return routes
+}
+
+// Unknown returns the routes given a budget that are not among routes, so typos in the
+// configuration can be reported instead of silently tracking nothing
+func (m *LatencyMonitor) Unknown(routes []string) []string {
+	if m == nil {
+		return nil
+	}
+	known := make(map[string]bool, len(routes))
+	for _, route := range routes {
+		known[route] = true
+	}
+	var unknown []string
+	for route := range m.budgets {
+		if !known[route] {
+			unknown = append(unknown, route)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
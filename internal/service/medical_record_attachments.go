@@ -0,0 +1,292 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/pkg/storage"
+	"github.com/whitewalker-sa/ehass/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// Audit actions for medical record attachments
+const (
+	AuditActionAttachmentAdded    = "medical_record.attachment_added"
+	AuditActionAttachmentDeleted  = "medical_record.attachment_deleted"
+	AuditActionAttachmentAccessed = "medical_record.attachment_accessed"
+)
+
+// attachmentDownloadContext separates download link signatures from anything else signed with the key
+const attachmentDownloadContext = "attachment-download:"
+
+// maxAttachmentNameLength caps the length of a stored file name in characters
+const maxAttachmentNameLength = 255
+
+var (
+	// ErrAttachmentTooLarge is returned when an uploaded file exceeds the configured size
+	ErrAttachmentTooLarge = errors.New("file is too large")
+	// ErrAttachmentType is returned when an uploaded file is not of an accepted type
+	ErrAttachmentType = errors.New("file type is not accepted")
+	// ErrNotAttachmentUploader is returned when a doctor removes a file someone else attached
+	ErrNotAttachmentUploader = errors.New("only the doctor who attached a file can remove it")
+	// ErrInvalidDownloadLink is returned for tampered or expired attachment download links
+	ErrInvalidDownloadLink = errors.New("download link is invalid or has expired")
+)
+
+// AddAttachment attaches a file to a medical record as the doctor signed in as userID, who must
+// be on the patient's care team. The file type is detected from its contents; the name is kept
+// for downloads only.
+func (s *medicalRecordService) AddAttachment(ctx context.Context, userID, patientID, recordID uint, fileName string, data []byte) (*model.MedicalRecordAttachment, error) {
+	if len(data) == 0 {
+		return nil, errors.New("file is empty")
+	}
+	if int64(len(data)) > s.attachments.MaxSize {
+		return nil, fmt.Errorf("%w: the limit is %d bytes", ErrAttachmentTooLarge, s.attachments.MaxSize)
+	}
+	contentType := detectAttachmentType(data)
+	if !s.allowedType(contentType) {
+		return nil, fmt.Errorf("%w: %s", ErrAttachmentType, contentType)
+	}
+
+	doctor, err := s.treatingDoctor(ctx, userID, patientID)
+	if err != nil {
+		return nil, err
+	}
+	record, err := s.patientRecord(ctx, patientID, recordID)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(data)
+	attachment := &model.MedicalRecordAttachment{
+		PublicID:    model.NewPublicID(),
+		RecordID:    record.ID,
+		FileName:    attachmentName(fileName),
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		Checksum:    hex.EncodeToString(sum[:]),
+		UploadedBy:  doctor.ID,
+		CreatedAt:   time.Now(),
+	}
+	attachment.StorageKey = "medical-records/" + record.PublicID + "/" + attachment.PublicID
+
+	if err := s.store.Put(ctx, attachment.StorageKey, data, contentType); err != nil {
+		return nil, fmt.Errorf("failed to store attachment: %w", err)
+	}
+	if err := s.repo.CreateAttachment(ctx, attachment); err != nil {
+		s.removeStoredFile(ctx, attachment)
+		return nil, fmt.Errorf("failed to create attachment: %w", err)
+	}
+
+	s.auditAttachment(ctx, userID, AuditActionAttachmentAdded, attachment)
+	return attachment, nil
+}
+
+// ListAttachments lists the files attached to a medical record, with the same access rules as
+// reading the record
+func (s *medicalRecordService) ListAttachments(ctx context.Context, userID uint, role model.Role, patientID, recordID uint) ([]*model.MedicalRecordAttachment, error) {
+	if err := s.authorizeRead(ctx, userID, role, patientID); err != nil {
+		return nil, err
+	}
+	record, err := s.patientRecord(ctx, patientID, recordID)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.FindAttachmentsByRecordID(ctx, record.ID)
+}
+
+// AttachmentDownloadURL returns a signed link to download an attachment without signing in,
+// and when it stops working. Issuing the link is audit logged as an access to the file.
+func (s *medicalRecordService) AttachmentDownloadURL(ctx context.Context, userID uint, role model.Role, patientID, recordID, id uint) (string, time.Time, error) {
+	if err := s.authorizeRead(ctx, userID, role, patientID); err != nil {
+		return "", time.Time{}, err
+	}
+	attachment, err := s.recordAttachment(ctx, patientID, recordID, id)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	expiresAt := time.Now().Add(s.attachments.URLExpiry)
+	token := signAttachmentDownload(s.signingKeys.Current(), attachment.PublicID, expiresAt)
+	s.auditAttachment(ctx, userID, AuditActionAttachmentAccessed, attachment)
+	return fmt.Sprintf("%s/api/v1/attachments/%s", s.baseURL, token), expiresAt, nil
+}
+
+// OpenAttachment checks a download link token and opens the attachment it was issued for. The
+// caller must close the returned body.
+func (s *medicalRecordService) OpenAttachment(ctx context.Context, token string) (*model.MedicalRecordAttachment, io.ReadCloser, error) {
+	publicID, err := s.signingKeys.Verify(func(secret []byte) (string, error) {
+		return verifyAttachmentDownload(secret, token, time.Now())
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	attachment, err := s.repo.FindAttachmentByPublicID(ctx, publicID)
+	if err != nil {
+		return nil, nil, err
+	}
+	body, err := s.store.Get(ctx, attachment.StorageKey)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil, errors.New("attachment not found")
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open attachment: %w", err)
+	}
+	return attachment, body, nil
+}
+
+// DeleteAttachment removes a file the doctor signed in as userID attached to a medical record
+func (s *medicalRecordService) DeleteAttachment(ctx context.Context, userID, patientID, recordID, id uint) error {
+	attachment, err := s.recordAttachment(ctx, patientID, recordID, id)
+	if err != nil {
+		return err
+	}
+	doctor, err := s.doctorRepo.FindByUserID(ctx, userID)
+	if err != nil || doctor.ID != attachment.UploadedBy {
+		return ErrNotAttachmentUploader
+	}
+
+	if err := s.repo.DeleteAttachment(ctx, attachment.ID); err != nil {
+		return fmt.Errorf("failed to delete attachment: %w", err)
+	}
+	s.removeStoredFile(ctx, attachment)
+
+	s.auditAttachment(ctx, userID, AuditActionAttachmentDeleted, attachment)
+	return nil
+}
+
+// recordAttachment finds an attachment of one of a patient's records, treating an attachment of
+// another record as not found
+func (s *medicalRecordService) recordAttachment(ctx context.Context, patientID, recordID, id uint) (*model.MedicalRecordAttachment, error) {
+	if _, err := s.patientRecord(ctx, patientID, recordID); err != nil {
+		return nil, err
+	}
+	attachment, err := s.repo.FindAttachmentByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if attachment.RecordID != recordID {
+		return nil, errors.New("attachment not found")
+	}
+	return attachment, nil
+}
+
+// removeStoredFile deletes an attachment's file from storage. A failure leaves an unreferenced
+// file behind and is only logged.
+func (s *medicalRecordService) removeStoredFile(ctx context.Context, attachment *model.MedicalRecordAttachment) {
+	if err := s.store.Delete(ctx, attachment.StorageKey); err != nil {
+		s.logger.Error("Failed to delete stored attachment",
+			zap.String("storage", s.store.Name()),
+			zap.String("key", attachment.StorageKey),
+			zap.Error(err))
+	}
+}
+
+func (s *medicalRecordService) allowedType(contentType string) bool {
+	for _, allowed := range s.attachments.AllowedTypes {
+		if strings.EqualFold(allowed, contentType) {
+			return true
+		}
+	}
+	return false
+}
+
+// auditAttachment records a change to or access of an attachment
+func (s *medicalRecordService) auditAttachment(ctx context.Context, userID uint, action string, attachment *model.MedicalRecordAttachment) {
+	client := utils.ClientInfoFromContext(ctx)
+	if err := s.auditLogRepo.Create(ctx, &model.AuditLog{
+		UserID:     userID,
+		Action:     action,
+		EntityID:   attachment.ID,
+		EntityType: "medical_record_attachment",
+		IP:         client.IP,
+		UserAgent:  client.UserAgent,
+		CreatedAt:  time.Now(),
+	}); err != nil {
+		s.logger.Error("Failed to write attachment audit log",
+			zap.String("action", action),
+			zap.Uint("attachmentID", attachment.ID),
+			zap.Error(err))
+	}
+}
+
+// detectAttachmentType sniffs the MIME type of a file from its first bytes, without parameters
+func detectAttachmentType(data []byte) string {
+	contentType := http.DetectContentType(data)
+	if i := strings.IndexByte(contentType, ';'); i >= 0 {
+		contentType = contentType[:i]
+	}
+	return strings.TrimSpace(contentType)
+}
+
+// attachmentName keeps the base name of an uploaded file, without control characters
+func attachmentName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || r == '"' {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+	if name == "" || name == "." || name == "/" || !utf8.ValidString(name) {
+		return "attachment"
+	}
+	if runes := []rune(name); len(runes) > maxAttachmentNameLength {
+		name = string(runes[:maxAttachmentNameLength])
+	}
+	return name
+}
+
+// signAttachmentDownload returns a download link token naming the attachment and when it
+// expires, signed with secret
+func signAttachmentDownload(secret []byte, publicID string, expiresAt time.Time) string {
+	payload := publicID + "." + strconv.FormatInt(expiresAt.Unix(), 10)
+	encoded := base64.RawURLEncoding.EncodeToString([]byte(payload))
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(attachmentDownloadMAC(secret, encoded))
+}
+
+// verifyAttachmentDownload checks a download link token's signature and expiry and returns the
+// public ID of the attachment it was issued for
+func verifyAttachmentDownload(secret []byte, token string, now time.Time) (string, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return "", ErrInvalidDownloadLink
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, attachmentDownloadMAC(secret, encoded)) {
+		return "", ErrInvalidDownloadLink
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", ErrInvalidDownloadLink
+	}
+	publicID, expiry, ok := strings.Cut(string(payload), ".")
+	if !ok {
+		return "", ErrInvalidDownloadLink
+	}
+	expires, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil || !now.Before(time.Unix(expires, 0)) {
+		return "", ErrInvalidDownloadLink
+	}
+	return publicID, nil
+}
+
+func attachmentDownloadMAC(secret []byte, encoded string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(attachmentDownloadContext + encoded))
+	return mac.Sum(nil)
+}
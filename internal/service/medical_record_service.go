@@ -0,0 +1,424 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/config"
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/repository"
+	"github.com/whitewalker-sa/ehass/pkg/storage"
+	"github.com/whitewalker-sa/ehass/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// Audit actions for medical record changes
+const (
+	AuditActionMedicalRecordCreated = "medical_record.created"
+	AuditActionMedicalRecordUpdated = "medical_record.updated"
+	AuditActionMedicalRecordDeleted = "medical_record.deleted"
+	AuditActionMedicalRecordSigned  = "medical_record.signed"
+	AuditActionAddendumAdded        = "medical_record.addendum_added"
+)
+
+// maxMedicalRecordFieldLength caps the length of each text field of a medical record in characters
+const maxMedicalRecordFieldLength = 10000
+
+var (
+	// ErrNotTreatingDoctor is returned when a doctor outside a patient's care team reads or writes
+	// the patient's medical records
+	ErrNotTreatingDoctor = errors.New("only doctors treating this patient can access their medical records")
+	// ErrNotOwnMedicalRecord is returned when a patient reads another patient's medical records
+	ErrNotOwnMedicalRecord = errors.New("patients can only read their own medical records")
+	// ErrNotRecordAuthor is returned when a doctor changes a medical record they did not write
+	ErrNotRecordAuthor = errors.New("only the doctor who wrote a medical record can change it")
+	// ErrRecordSigned is returned when changing or deleting a signed medical record
+	ErrRecordSigned = errors.New("medical record is signed and can no longer be changed; add an addendum instead")
+	// ErrRecordNotSigned is returned when adding an addendum to a medical record that is not
+	// signed yet, which its author can still edit
+	ErrRecordNotSigned = errors.New("addenda can only be added to signed medical records")
+)
+
+// MedicalRecordHistory is every version of a medical record, oldest first, and the addenda added
+// after it was signed
+type MedicalRecordHistory struct {
+	Record   *model.MedicalRecord
+	Versions []*model.MedicalRecordVersion
+	Addenda  []*model.MedicalRecordAddendum
+}
+
+type medicalRecordService struct {
+	repo             repository.MedicalRecordRepository
+	prescriptionRepo repository.PrescriptionRepository
+	vitalRepo        repository.VitalRepository
+	handoffRepo      repository.HandoffRepository
+	doctorRepo       repository.DoctorRepository
+	patientRepo      repository.PatientRepository
+	auditLogRepo     repository.AuditLogRepository
+	orgService       OrganizationService
+	store            storage.Store
+	attachments      config.AttachmentsConfig
+	signingKeys      *SigningKeys
+	baseURL          string
+	logger           *zap.Logger
+}
+
+// NewMedicalRecordService creates a new medical record service. Attachments are kept in store,
+// and their download links point at baseURL and are signed with signingKeys.
+func NewMedicalRecordService(
+	repo repository.MedicalRecordRepository,
+	prescriptionRepo repository.PrescriptionRepository,
+	vitalRepo repository.VitalRepository,
+	handoffRepo repository.HandoffRepository,
+	doctorRepo repository.DoctorRepository,
+	patientRepo repository.PatientRepository,
+	auditLogRepo repository.AuditLogRepository,
+	orgService OrganizationService,
+	store storage.Store,
+	attachments config.AttachmentsConfig,
+	signingKeys *SigningKeys,
+	baseURL string,
+	logger *zap.Logger,
+) MedicalRecordService {
+	return &medicalRecordService{
+		repo:             repo,
+		prescriptionRepo: prescriptionRepo,
+		vitalRepo:        vitalRepo,
+		handoffRepo:      handoffRepo,
+		doctorRepo:       doctorRepo,
+		patientRepo:      patientRepo,
+		auditLogRepo:     auditLogRepo,
+		orgService:       orgService,
+		store:            store,
+		attachments:      attachments,
+		signingKeys:      signingKeys,
+		baseURL:          strings.TrimRight(baseURL, "/"),
+		logger:           logger,
+	}
+}
+
+// CreateMedicalRecord writes a medical record for a patient as the doctor signed in as userID,
+// who must be on the patient's care team. A zero visitDate records the visit as now.
+func (s *medicalRecordService) CreateMedicalRecord(ctx context.Context, userID, patientID uint, visitDate time.Time, diagnosis, notes string) (*model.MedicalRecord, error) {
+	record := &model.MedicalRecord{PatientID: patientID, VisitDate: visitDate}
+	if err := setMedicalRecordFields(record, diagnosis, notes); err != nil {
+		return nil, err
+	}
+	if record.VisitDate.IsZero() {
+		record.VisitDate = time.Now()
+	}
+	if record.VisitDate.After(time.Now()) {
+		return nil, errors.New("visit date cannot be in the future")
+	}
+
+	if _, err := s.patientRepo.FindByID(ctx, patientID); err != nil {
+		return nil, err
+	}
+	doctor, err := s.treatingDoctor(ctx, userID, patientID)
+	if err != nil {
+		return nil, err
+	}
+
+	record.DoctorID = doctor.ID
+	record.Doctor = *doctor
+	record.CreatedAt = time.Now()
+	record.UpdatedAt = time.Now()
+	if err := s.repo.Create(ctx, record); err != nil {
+		return nil, fmt.Errorf("failed to create medical record: %w", err)
+	}
+
+	s.audit(ctx, userID, AuditActionMedicalRecordCreated, record)
+	return record, nil
+}
+
+// GetMedicalRecord gets one of a patient's medical records for the user signed in as userID
+func (s *medicalRecordService) GetMedicalRecord(ctx context.Context, userID uint, role model.Role, patientID, id uint) (*model.MedicalRecord, error) {
+	if err := s.authorizeRead(ctx, userID, role, patientID); err != nil {
+		return nil, err
+	}
+	return s.patientRecord(ctx, patientID, id)
+}
+
+// GetPatientMedicalRecords lists a patient's medical records, most recent visit first, for the
+// user signed in as userID. Patients can read their own records and those of the patients their
+// account manages, doctors those of the patients they treat, and admins all records.
+func (s *medicalRecordService) GetPatientMedicalRecords(ctx context.Context, userID uint, role model.Role, patientID uint, page, pageSize int) ([]*model.MedicalRecord, int64, error) {
+	if err := s.authorizeRead(ctx, userID, role, patientID); err != nil {
+		return nil, 0, err
+	}
+	offset := (page - 1) * pageSize
+	return s.repo.FindByPatientID(ctx, patientID, pageSize, offset)
+}
+
+// UpdateMedicalRecord changes a medical record written by the doctor signed in as userID, unless
+// it is signed. Each change is kept as a new version; saving the same content adds none.
+func (s *medicalRecordService) UpdateMedicalRecord(ctx context.Context, userID, patientID, id uint, diagnosis, notes string) (*model.MedicalRecord, error) {
+	record, err := s.authoredRecord(ctx, userID, patientID, id)
+	if err != nil {
+		return nil, err
+	}
+	var fields model.MedicalRecord
+	if err := setMedicalRecordFields(&fields, diagnosis, notes); err != nil {
+		return nil, err
+	}
+
+	updated, err := s.repo.Revise(ctx, record.ID, func(current *model.MedicalRecord) (bool, error) {
+		if current.SignedAt != nil {
+			return false, ErrRecordSigned
+		}
+		if current.Diagnosis == fields.Diagnosis && current.Notes == fields.Notes {
+			return false, nil
+		}
+		current.Diagnosis = fields.Diagnosis
+		current.Notes = fields.Notes
+		return true, nil
+	})
+	if errors.Is(err, ErrRecordSigned) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update medical record: %w", err)
+	}
+
+	if updated.Version != record.Version {
+		s.audit(ctx, userID, AuditActionMedicalRecordUpdated, updated)
+	}
+	return updated, nil
+}
+
+// SignMedicalRecord signs a medical record written by the doctor signed in as userID. A signed
+// record can no longer be changed or deleted; later notes are added as addenda.
+func (s *medicalRecordService) SignMedicalRecord(ctx context.Context, userID, patientID, id uint) (*model.MedicalRecord, error) {
+	record, err := s.authoredRecord(ctx, userID, patientID, id)
+	if err != nil {
+		return nil, err
+	}
+	if record.SignedAt != nil {
+		return nil, ErrRecordSigned
+	}
+	signed, err := s.repo.Sign(ctx, record.ID, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign medical record: %w", err)
+	}
+	if !signed {
+		return nil, ErrRecordSigned
+	}
+
+	s.audit(ctx, userID, AuditActionMedicalRecordSigned, record)
+	return s.repo.FindByID(ctx, record.ID)
+}
+
+// AddAddendum adds a note to a signed medical record as the doctor signed in as userID, who must
+// be on the patient's care team
+func (s *medicalRecordService) AddAddendum(ctx context.Context, userID, patientID, id uint, text string) (*model.MedicalRecordAddendum, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, errors.New("addendum text is required")
+	}
+	if len([]rune(text)) > maxMedicalRecordFieldLength {
+		return nil, fmt.Errorf("addenda must be at most %d characters", maxMedicalRecordFieldLength)
+	}
+
+	record, err := s.patientRecord(ctx, patientID, id)
+	if err != nil {
+		return nil, err
+	}
+	doctor, err := s.treatingDoctor(ctx, userID, patientID)
+	if err != nil {
+		return nil, err
+	}
+	if record.SignedAt == nil {
+		return nil, ErrRecordNotSigned
+	}
+
+	addendum := &model.MedicalRecordAddendum{
+		RecordID:  record.ID,
+		DoctorID:  doctor.ID,
+		Text:      text,
+		CreatedAt: time.Now(),
+	}
+	if err := s.repo.CreateAddendum(ctx, addendum); err != nil {
+		return nil, fmt.Errorf("failed to add addendum: %w", err)
+	}
+	addendum.Doctor = *doctor
+
+	s.audit(ctx, userID, AuditActionAddendumAdded, record)
+	return addendum, nil
+}
+
+// GetMedicalRecordHistory gets the versions and addenda of one of a patient's medical records,
+// with the same access rules as reading the record
+func (s *medicalRecordService) GetMedicalRecordHistory(ctx context.Context, userID uint, role model.Role, patientID, id uint) (*MedicalRecordHistory, error) {
+	record, err := s.GetMedicalRecord(ctx, userID, role, patientID, id)
+	if err != nil {
+		return nil, err
+	}
+	versions, err := s.repo.FindVersions(ctx, record.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find medical record versions: %w", err)
+	}
+	addenda, err := s.repo.FindAddenda(ctx, record.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find addenda: %w", err)
+	}
+	return &MedicalRecordHistory{Record: record, Versions: versions, Addenda: addenda}, nil
+}
+
+// DeleteMedicalRecord deletes a medical record written by the doctor signed in as userID, with
+// its attachments, unless it is signed
+func (s *medicalRecordService) DeleteMedicalRecord(ctx context.Context, userID, patientID, id uint) error {
+	record, err := s.authoredRecord(ctx, userID, patientID, id)
+	if err != nil {
+		return err
+	}
+	if record.SignedAt != nil {
+		return ErrRecordSigned
+	}
+	attachments, err := s.repo.FindAttachmentsByRecordID(ctx, record.ID)
+	if err != nil {
+		return fmt.Errorf("failed to find attachments: %w", err)
+	}
+	if err := s.repo.Delete(ctx, record.ID); err != nil {
+		return fmt.Errorf("failed to delete medical record: %w", err)
+	}
+	for _, attachment := range attachments {
+		s.removeStoredFile(ctx, attachment)
+	}
+
+	s.audit(ctx, userID, AuditActionMedicalRecordDeleted, record)
+	return nil
+}
+
+// authorizeRead checks that the user signed in as userID may read the patient's records
+func (s *medicalRecordService) authorizeRead(ctx context.Context, userID uint, role model.Role, patientID uint) error {
+	return s.access().authorizeRead(ctx, userID, role, patientID)
+}
+
+// treatingDoctor returns the doctor signed in as userID if they have a treatment relationship
+// with the patient
+func (s *medicalRecordService) treatingDoctor(ctx context.Context, userID, patientID uint) (*model.Doctor, error) {
+	return s.access().treatingDoctor(ctx, userID, patientID)
+}
+
+func (s *medicalRecordService) access() recordAccess {
+	return recordAccess{doctorRepo: s.doctorRepo, patientRepo: s.patientRepo, handoffRepo: s.handoffRepo}
+}
+
+// recordAccess applies the medical record access rules, which also cover the clinical data kept
+// alongside the records
+type recordAccess struct {
+	doctorRepo  repository.DoctorRepository
+	patientRepo repository.PatientRepository
+	handoffRepo repository.HandoffRepository
+}
+
+// authorizeRead checks that the user signed in as userID may read the patient's records: admins,
+// doctors treating the patient, and the patient or their guardian
+func (a recordAccess) authorizeRead(ctx context.Context, userID uint, role model.Role, patientID uint) error {
+	switch role {
+	case model.RoleAdmin:
+		return nil
+	case model.RoleDoctor:
+		_, err := a.treatingDoctor(ctx, userID, patientID)
+		return err
+	case model.RolePatient:
+		caller, err := a.patientRepo.FindByUserID(ctx, userID)
+		if err != nil {
+			return ErrNotOwnMedicalRecord
+		}
+		if caller.ID == patientID {
+			return nil
+		}
+		patient, err := a.patientRepo.FindByID(ctx, patientID)
+		if err != nil {
+			return err
+		}
+		if patient.GuardianID == nil || *patient.GuardianID != caller.ID {
+			return ErrNotOwnMedicalRecord
+		}
+		return nil
+	default:
+		return ErrNotOwnMedicalRecord
+	}
+}
+
+// treatingDoctor returns the doctor signed in as userID if they have a treatment relationship
+// with the patient
+func (a recordAccess) treatingDoctor(ctx context.Context, userID, patientID uint) (*model.Doctor, error) {
+	doctor, err := a.doctorRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, ErrNotTreatingDoctor
+	}
+	related, err := a.handoffRepo.HasTreatmentRelationship(ctx, doctor.ID, patientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check treatment relationship: %w", err)
+	}
+	if !related {
+		return nil, ErrNotTreatingDoctor
+	}
+	return doctor, nil
+}
+
+// authoredRecord finds one of a patient's records written by the doctor signed in as userID
+func (s *medicalRecordService) authoredRecord(ctx context.Context, userID, patientID, id uint) (*model.MedicalRecord, error) {
+	record, err := s.patientRecord(ctx, patientID, id)
+	if err != nil {
+		return nil, err
+	}
+	doctor, err := s.doctorRepo.FindByUserID(ctx, userID)
+	if err != nil || doctor.ID != record.DoctorID {
+		return nil, ErrNotRecordAuthor
+	}
+	return record, nil
+}
+
+// patientRecord finds a medical record, treating a record of another patient as not found
+func (s *medicalRecordService) patientRecord(ctx context.Context, patientID, id uint) (*model.MedicalRecord, error) {
+	record, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if record.PatientID != patientID {
+		return nil, errors.New("medical record not found")
+	}
+	return record, nil
+}
+
+// audit records a change to a medical record; the record's contents are not logged
+func (s *medicalRecordService) audit(ctx context.Context, userID uint, action string, record *model.MedicalRecord) {
+	client := utils.ClientInfoFromContext(ctx)
+	if err := s.auditLogRepo.Create(ctx, &model.AuditLog{
+		UserID:     userID,
+		Action:     action,
+		EntityID:   record.ID,
+		EntityType: "medical_record",
+		IP:         client.IP,
+		UserAgent:  client.UserAgent,
+		CreatedAt:  time.Now(),
+	}); err != nil {
+		s.logger.Error("Failed to write medical record audit log",
+			zap.String("action", action),
+			zap.Uint("recordID", record.ID),
+			zap.Error(err))
+	}
+}
+
+// setMedicalRecordFields validates and applies the text fields of a medical record.
+// Medications are prescribed separately; see IssuePrescription.
+func setMedicalRecordFields(record *model.MedicalRecord, diagnosis, notes string) error {
+	diagnosis = strings.TrimSpace(diagnosis)
+	if diagnosis == "" {
+		return errors.New("diagnosis is required")
+	}
+	for _, field := range []string{diagnosis, notes} {
+		if len([]rune(field)) > maxMedicalRecordFieldLength {
+			return fmt.Errorf("medical record fields must be at most %d characters", maxMedicalRecordFieldLength)
+		}
+	}
+	record.Diagnosis = diagnosis
+	record.Notes = strings.TrimSpace(notes)
+	return nil
+}
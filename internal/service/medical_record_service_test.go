@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"go.uber.org/zap"
+)
+
+func TestRecordAccessAuthorizeRead(t *testing.T) {
+	tests := []struct {
+		name      string
+		userID    uint
+		role      model.Role
+		patientID uint
+		want      error
+	}{
+		{"admin", 99, model.RoleAdmin, 10, nil},
+		{"treating doctor", 1, model.RoleDoctor, 10, nil},
+		{"doctor with a pending booking", 2, model.RoleDoctor, 10, ErrNotTreatingDoctor},
+		{"doctor with a cancelled booking", 1, model.RoleDoctor, 12, ErrNotTreatingDoctor},
+		{"doctor without a booking", 3, model.RoleDoctor, 10, ErrNotTreatingDoctor},
+		{"user without a doctor profile", 10, model.RoleDoctor, 10, ErrNotTreatingDoctor},
+		{"patient", 10, model.RolePatient, 10, nil},
+		{"guardian", 10, model.RolePatient, 11, nil},
+		{"child reading the guardian", 11, model.RolePatient, 10, ErrNotOwnMedicalRecord},
+		{"other patient", 12, model.RolePatient, 10, ErrNotOwnMedicalRecord},
+		{"unknown role", 10, model.Role("auditor"), 10, ErrNotOwnMedicalRecord},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := newTestClinic().access().authorizeRead(context.Background(), tt.userID, tt.role, tt.patientID)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordAccessFollowsHandover(t *testing.T) {
+	clinic := newTestClinic()
+	ctx := context.Background()
+
+	if _, err := clinic.access().treatingDoctor(ctx, 3, 10); !errors.Is(err, ErrNotTreatingDoctor) {
+		t.Fatalf("before the handover: got %v, want ErrNotTreatingDoctor", err)
+	}
+	handoffs := NewHandoffService(clinic.handoffs, clinic.doctors, clinic.patients, zap.NewNop())
+	if _, err := handoffs.AddNote(ctx, 1, 10, 3, "Please take over"); err != nil {
+		t.Fatalf("AddNote: %v", err)
+	}
+	doctor, err := clinic.access().treatingDoctor(ctx, 3, 10)
+	if err != nil {
+		t.Fatalf("after the handover: %v", err)
+	}
+	if doctor.ID != 3 {
+		t.Errorf("got doctor %d, want the recipient", doctor.ID)
+	}
+}
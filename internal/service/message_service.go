@@ -0,0 +1,268 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/repository"
+	"github.com/whitewalker-sa/ehass/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// maxMessageLength caps the length of a secure message in characters
+const maxMessageLength = 5000
+
+var (
+	// ErrNotOwnMessages is returned when a patient reads or writes the messages of someone other
+	// than themselves or their dependants
+	ErrNotOwnMessages = errors.New("patients can only access their own messages or their dependants'")
+	// ErrNotMessagingDoctor is returned when a patient writes to a doctor who is not treating them
+	ErrNotMessagingDoctor = errors.New("patients can only message doctors treating them")
+	// ErrNotMessagingPatient is returned when a doctor writes to a patient they neither treat nor
+	// took messages from while covering for a colleague
+	ErrNotMessagingPatient = errors.New("doctors can only message patients they treat or who wrote to them")
+)
+
+type messageService struct {
+	repo             repository.MessageRepository
+	handoffRepo      repository.HandoffRepository
+	availabilityRepo repository.AvailabilityRepository
+	doctorRepo       repository.DoctorRepository
+	patientRepo      repository.PatientRepository
+	logger           *zap.Logger
+}
+
+// NewMessageService creates a new secure messaging service
+func NewMessageService(
+	repo repository.MessageRepository,
+	handoffRepo repository.HandoffRepository,
+	availabilityRepo repository.AvailabilityRepository,
+	doctorRepo repository.DoctorRepository,
+	patientRepo repository.PatientRepository,
+	logger *zap.Logger,
+) MessageService {
+	return &messageService{
+		repo:             repo,
+		handoffRepo:      handoffRepo,
+		availabilityRepo: availabilityRepo,
+		doctorRepo:       doctorRepo,
+		patientRepo:      patientRepo,
+		logger:           logger,
+	}
+}
+
+// SendMessage sends a message in a patient's conversation with a doctor. Patients, or their
+// guardians, write to doctorID, who must be treating them; doctors write as themselves and
+// doctorID is ignored. A patient message to a doctor on time off goes to the covering doctor,
+// if one is set, and is answered with an automatic reply, which is returned after the message.
+func (s *messageService) SendMessage(ctx context.Context, userID uint, role model.Role, patientID, doctorID uint, body string) ([]*model.Message, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return nil, errors.New("message body is required")
+	}
+	if len([]rune(body)) > maxMessageLength {
+		return nil, fmt.Errorf("message body must be at most %d characters", maxMessageLength)
+	}
+
+	if role == model.RoleDoctor {
+		doctor, err := s.doctorRepo.FindByUserID(ctx, userID)
+		if err != nil {
+			return nil, ErrNotMessagingPatient
+		}
+		if err := s.authorizeDoctor(ctx, doctor.ID, patientID); err != nil {
+			return nil, err
+		}
+		patient, err := s.patientRepo.FindByID(ctx, patientID)
+		if err != nil {
+			return nil, err
+		}
+		message := &model.Message{
+			PatientID: patientID,
+			Patient:   *patient,
+			DoctorID:  doctor.ID,
+			Doctor:    *doctor,
+			SenderID:  userID,
+			Body:      body,
+			CreatedAt: time.Now(),
+		}
+		if err := s.repo.Create(ctx, message); err != nil {
+			return nil, fmt.Errorf("failed to send message: %w", err)
+		}
+		return []*model.Message{message}, nil
+	}
+
+	caller, err := s.authorizePatient(ctx, userID, patientID)
+	if err != nil {
+		return nil, err
+	}
+	patient, err := s.patientRepo.FindByID(ctx, patientID)
+	if err != nil {
+		return nil, err
+	}
+	doctor, err := s.doctorRepo.FindByID(ctx, doctorID)
+	if err != nil {
+		return nil, err
+	}
+	related, err := s.handoffRepo.HasTreatmentRelationship(ctx, doctor.ID, patientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check treatment relationship: %w", err)
+	}
+	if !related {
+		return nil, ErrNotMessagingDoctor
+	}
+
+	now := time.Now()
+	message := &model.Message{
+		PatientID:   patientID,
+		Patient:     *patient,
+		DoctorID:    doctor.ID,
+		Doctor:      *doctor,
+		SenderID:    userID,
+		FromPatient: true,
+		Body:        body,
+		CreatedAt:   now,
+	}
+	messages := []*model.Message{message}
+
+	absence, err := s.absence(ctx, doctor.ID, now)
+	if err != nil {
+		return nil, err
+	}
+	if absence != nil {
+		if absence.CoveringDoctor != nil {
+			message.DoctorID = absence.CoveringDoctor.ID
+			message.Doctor = *absence.CoveringDoctor
+			message.AddressedToID = &doctor.ID
+			message.AddressedTo = doctor
+		}
+		messages = append(messages, &model.Message{
+			PatientID:     patientID,
+			Patient:       *patient,
+			DoctorID:      message.DoctorID,
+			Doctor:        message.Doctor,
+			AddressedToID: message.AddressedToID,
+			AddressedTo:   message.AddressedTo,
+			SenderID:      doctor.UserID,
+			AutoReply:     true,
+			Body:          absenceReply(doctor, absence, caller.User.Timezone, caller.User.Locale),
+			CreatedAt:     now,
+		})
+	}
+
+	if err := s.repo.Create(ctx, messages...); err != nil {
+		return nil, fmt.Errorf("failed to send message: %w", err)
+	}
+	if absence != nil {
+		s.logger.Info("Message to absent doctor answered automatically",
+			zap.Uint("doctorID", doctor.ID),
+			zap.Uint("timeOffID", absence.ID),
+			zap.Bool("routed", message.AddressedToID != nil))
+	}
+	return messages, nil
+}
+
+// ListPatientMessages lists a patient's messages, most recent first. Patients and their
+// guardians see every conversation; doctors only their own with the patient, including messages
+// to them that went to a covering doctor.
+func (s *messageService) ListPatientMessages(ctx context.Context, userID uint, role model.Role, patientID uint, page, pageSize int) ([]*model.Message, int64, error) {
+	offset := (page - 1) * pageSize
+	if role == model.RoleDoctor {
+		doctor, err := s.doctorRepo.FindByUserID(ctx, userID)
+		if err != nil {
+			return nil, 0, ErrNotMessagingPatient
+		}
+		return s.repo.FindByPatientID(ctx, patientID, doctor.ID, pageSize, offset)
+	}
+	if _, err := s.authorizePatient(ctx, userID, patientID); err != nil {
+		return nil, 0, err
+	}
+	return s.repo.FindByPatientID(ctx, patientID, 0, pageSize, offset)
+}
+
+// ListDoctorMessages lists the messages of the signed-in doctor's conversations, most recent
+// first, including those they took while covering for a colleague and those that went to their
+// covering doctor while they were away
+func (s *messageService) ListDoctorMessages(ctx context.Context, userID uint, page, pageSize int) ([]*model.Message, int64, error) {
+	doctor, err := s.doctorRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, 0, err
+	}
+	return s.repo.FindByDoctorID(ctx, doctor.ID, pageSize, (page-1)*pageSize)
+}
+
+// authorizePatient returns the patient signed in as userID if they are the patient or their
+// guardian
+func (s *messageService) authorizePatient(ctx context.Context, userID, patientID uint) (*model.Patient, error) {
+	caller, err := s.patientRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, ErrNotOwnMessages
+		}
+		return nil, err
+	}
+	if caller.ID == patientID {
+		return caller, nil
+	}
+	patient, err := s.patientRepo.FindByID(ctx, patientID)
+	if err != nil {
+		return nil, err
+	}
+	if patient.GuardianID == nil || *patient.GuardianID != caller.ID {
+		return nil, ErrNotOwnMessages
+	}
+	return caller, nil
+}
+
+// authorizeDoctor checks that a doctor treats the patient or took messages from them while
+// covering for a colleague
+func (s *messageService) authorizeDoctor(ctx context.Context, doctorID, patientID uint) error {
+	related, err := s.handoffRepo.HasTreatmentRelationship(ctx, doctorID, patientID)
+	if err != nil {
+		return fmt.Errorf("failed to check treatment relationship: %w", err)
+	}
+	if related {
+		return nil
+	}
+	covered, err := s.repo.HasConversation(ctx, doctorID, patientID)
+	if err != nil {
+		return fmt.Errorf("failed to check conversation: %w", err)
+	}
+	if !covered {
+		return ErrNotMessagingPatient
+	}
+	return nil
+}
+
+// absence returns the doctor's time off covering at, or nil when they are at work
+func (s *messageService) absence(ctx context.Context, doctorID uint, at time.Time) (*model.TimeOff, error) {
+	timeOff, err := s.availabilityRepo.FindTimeOff(ctx, doctorID, at, at)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get time off: %w", err)
+	}
+	if len(timeOff) == 0 {
+		return nil, nil
+	}
+	// Overlapping ranges: the one ending last says when the doctor is back
+	absence := timeOff[0]
+	for _, t := range timeOff[1:] {
+		if t.End.After(absence.End) {
+			absence = t
+		}
+	}
+	return absence, nil
+}
+
+// absenceReply writes the automatic reply to a message sent to a doctor on time off, with
+// times in the patient's timezone and locale
+func absenceReply(doctor *model.Doctor, absence *model.TimeOff, tz, locale string) string {
+	reply := fmt.Sprintf("%s is away from %s until %s.", doctor.User.Name,
+		utils.FormatDateTime(absence.Start, tz, locale), utils.FormatDateTime(absence.End, tz, locale))
+	if absence.CoveringDoctor != nil {
+		return reply + fmt.Sprintf(" Your message has been passed to %s, who is covering for them.", absence.CoveringDoctor.User.Name)
+	}
+	return reply + " They will read your message when they are back. For urgent matters, please contact the clinic."
+}
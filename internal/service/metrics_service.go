@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/repository"
+	"github.com/whitewalker-sa/ehass/pkg/breaker"
+	"github.com/whitewalker-sa/ehass/pkg/metrics"
+	"go.uber.org/zap"
+)
+
+type metricsService struct {
+	appointmentRepo   repository.AppointmentRepository
+	doctorRepo        repository.DoctorRepository
+	operationsService OperationsService
+	latency           *LatencyMonitor
+	location          *time.Location
+	logger            *zap.Logger
+}
+
+// NewMetricsService creates a new metrics service. Days start at midnight in location.
+func NewMetricsService(
+	appointmentRepo repository.AppointmentRepository,
+	doctorRepo repository.DoctorRepository,
+	operationsService OperationsService,
+	latency *LatencyMonitor,
+	location *time.Location,
+	logger *zap.Logger,
+) MetricsService {
+	return &metricsService{
+		appointmentRepo:   appointmentRepo,
+		doctorRepo:        doctorRepo,
+		operationsService: operationsService,
+		latency:           latency,
+		location:          location,
+		logger:            logger,
+	}
+}
+
+// Collect gathers the business and operating metrics. Business figures and queue depths are
+// read from the database, so every instance reports the same values; jobs, circuit breakers and
+// request latencies are those of this instance.
+func (s *metricsService) Collect(ctx context.Context) ([]metrics.Family, error) {
+	now := time.Now().In(s.location)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, s.location)
+	tomorrow := today.AddDate(0, 0, 1)
+
+	booked, err := s.appointmentRepo.CountCreatedSince(ctx, today)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count appointments booked today: %w", err)
+	}
+	scheduled, err := s.appointmentRepo.CountScheduledByStatus(ctx, today, tomorrow)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count appointments scheduled today: %w", err)
+	}
+	unverified, err := s.doctorRepo.CountUnverified(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count unverified doctors: %w", err)
+	}
+	queues, err := s.operationsService.GetQueues(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	scheduledToday := metrics.Family{
+		Name: "ehass_appointments_scheduled_today",
+		Help: "Appointments starting today by status",
+		Type: metrics.Gauge,
+	}
+	for _, count := range scheduled {
+		scheduledToday.Add(float64(count.Count), "status", string(count.Status))
+	}
+
+	queueDepth := metrics.Family{
+		Name: "ehass_queue_depth",
+		Help: "Items waiting in a background work queue; emails_failed counts the last 24 hours",
+		Type: metrics.Gauge,
+	}
+	for _, queue := range queues {
+		queueDepth.Add(float64(queue.Depth), "queue", queue.Name)
+	}
+
+	families := []metrics.Family{
+		metrics.NewGauge("ehass_appointments_booked_today", "Appointments booked since midnight", float64(booked)),
+		scheduledToday,
+		metrics.NewGauge("ehass_doctors_unverified", "Doctor accounts whose email address is not yet verified", float64(unverified)),
+		queueDepth,
+		s.breakerMetrics(),
+		s.jobMetrics(),
+	}
+	return append(families, s.latencyMetrics()...), nil
+}
+
+// breakerMetrics reports whether each circuit breaker is open
+func (s *metricsService) breakerMetrics() metrics.Family {
+	open := metrics.Family{
+		Name: "ehass_breaker_open",
+		Help: "1 while the circuit breaker guarding a dependency is open or half open",
+		Type: metrics.Gauge,
+	}
+	for _, stats := range s.operationsService.GetBreakers() {
+		value := 0.0
+		if stats.State != breaker.StateClosed {
+			value = 1
+		}
+		open.Add(value, "breaker", stats.Name)
+	}
+	return open
+}
+
+// jobMetrics reports the consecutive failures of each scheduled job
+func (s *metricsService) jobMetrics() metrics.Family {
+	failures := metrics.Family{
+		Name: "ehass_job_consecutive_failures",
+		Help: "Failed runs of a scheduled job since it last succeeded",
+		Type: metrics.Gauge,
+	}
+	for _, job := range s.operationsService.GetJobs() {
+		failures.Add(float64(job.ConsecutiveFailures), "job", job.Name)
+	}
+	return failures
+}
+
+// latencyMetrics reports the requests to each route with a latency budget and how many of them
+// exceeded it
+func (s *metricsService) latencyMetrics() []metrics.Family {
+	requests := metrics.Family{
+		Name: "ehass_http_requests_total",
+		Help: "Requests to a route with a latency budget",
+		Type: metrics.Counter,
+	}
+	violations := metrics.Family{
+		Name: "ehass_http_slo_violations_total",
+		Help: "Requests that took longer than their route's latency budget",
+		Type: metrics.Counter,
+	}
+	budgets := metrics.Family{
+		Name: "ehass_http_latency_budget_seconds",
+		Help: "Latency budget of a route",
+		Type: metrics.Gauge,
+	}
+	for _, route := range s.latency.Routes() {
+		requests.Add(float64(route.Requests), "route", route.Route)
+		violations.Add(float64(route.Violations), "route", route.Route)
+		budgets.Add(route.Budget.Seconds(), "route", route.Route)
+	}
+	return []metrics.Family{requests, violations, budgets}
+}
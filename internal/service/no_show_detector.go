@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/repository"
+	"go.uber.org/zap"
+)
+
+// JobNoShows is the name of the job marking missed appointments as no-shows
+const JobNoShows = "no_shows"
+
+// NoShowDetector periodically marks appointments that were neither completed nor cancelled as
+// no-shows once they ended more than a grace period ago, so they stop counting as booked and
+// feed the patient's no-show risk
+type NoShowDetector struct {
+	repo      repository.AppointmentRepository
+	after     time.Duration
+	batchSize int
+	interval  time.Duration
+	monitor   *JobMonitor
+	logger    *zap.Logger
+}
+
+// NewNoShowDetector creates a new no-show detector marking appointments after past their end, at
+// most batchSize per run
+func NewNoShowDetector(
+	repo repository.AppointmentRepository,
+	after time.Duration,
+	batchSize int,
+	interval time.Duration,
+	monitor *JobMonitor,
+	logger *zap.Logger,
+) *NoShowDetector {
+	if batchSize <= 0 {
+		batchSize = 200
+	}
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+
+	d := &NoShowDetector{
+		repo:      repo,
+		after:     after,
+		batchSize: batchSize,
+		interval:  interval,
+		monitor:   monitor,
+		logger:    logger,
+	}
+	monitor.Register(JobNoShows, interval, d.RunOnce)
+	return d
+}
+
+// Start marks no-shows in the background until the returned function is called
+func (d *NoShowDetector) Start() func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(d.interval)
+		defer ticker.Stop()
+
+		for {
+			_ = d.monitor.Do(ctx, JobNoShows, d.RunOnce)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		wg.Wait()
+	}
+}
+
+// RunOnce marks the oldest overdue appointments as no-shows. Appointments beyond the batch size
+// are marked on the next run.
+func (d *NoShowDetector) RunOnce(ctx context.Context) error {
+	overdue, err := d.repo.FindOverdue(ctx, time.Now().Add(-d.after), d.batchSize)
+	if err != nil {
+		return fmt.Errorf("failed to find overdue appointments: %w", err)
+	}
+
+	marked := 0
+	for _, appointment := range overdue {
+		eventType, err := transitionAppointment(appointment, model.AppointmentStatusNoShow, time.Now())
+		if err != nil {
+			return err
+		}
+		events, err := appointmentEvents(eventType, newAppointmentEventData(appointment))
+		if err != nil {
+			return err
+		}
+
+		// Completing or cancelling the appointment since it was loaded takes precedence
+		ok, err := d.repo.MarkNoShow(ctx, appointment, events...)
+		if err != nil {
+			return fmt.Errorf("failed to mark appointment %d as no-show: %w", appointment.ID, err)
+		}
+		if ok {
+			marked++
+		}
+	}
+	if marked > 0 {
+		d.logger.Info("Appointments marked as no-show", zap.Int("count", marked))
+	}
+	return nil
+}
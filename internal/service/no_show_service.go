@@ -0,0 +1,307 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/repository"
+	"github.com/whitewalker-sa/ehass/pkg/sms"
+	"github.com/whitewalker-sa/ehass/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// No-show risk levels
+const (
+	NoShowRiskLow    = "low"
+	NoShowRiskMedium = "medium"
+	NoShowRiskHigh   = "high"
+)
+
+const (
+	// noShowHistorySize is how many past appointments are considered
+	noShowHistorySize = 50
+	// noShowPriorRate and noShowPriorWeight smooth the score for patients with little history,
+	// as if they had noShowPriorWeight appointments missed at noShowPriorRate
+	noShowPriorRate   = 0.1
+	noShowPriorWeight = 4.0
+	// lateCancellationWindow is how close to the start a cancellation counts as late
+	lateCancellationWindow = 24 * time.Hour
+	// confirmationCodeTTL is how long a booking confirmation code is accepted
+	confirmationCodeTTL = 24 * time.Hour
+	// maxConfirmationAttempts is the number of codes that may be entered before the booking is
+	// locked and the clinic has to confirm it
+	maxConfirmationAttempts = 5
+)
+
+var (
+	// ErrConfirmationCodeExpired is returned when a booking confirmation code is entered after
+	// it expired
+	ErrConfirmationCodeExpired = errors.New("confirmation code has expired")
+	// ErrConfirmationLocked is returned when too many confirmation codes have been entered for
+	// a booking
+	ErrConfirmationLocked = errors.New("too many confirmation attempts; please contact the clinic")
+)
+
+// NoShowRisk is the estimated likelihood that a patient misses an appointment
+type NoShowRisk struct {
+	Score             float64       // 0 to 1
+	Level             string        // low, medium or high
+	PastAppointments  int           // Appointments the score is based on
+	NoShows           int           // Appointments marked as no-show
+	LateCancellations int           // Cancellations within 24 hours of the start
+	LeadTime          time.Duration // Time between booking and the appointment
+}
+
+// PatientNoShows counts a patient's past appointments by outcome
+type PatientNoShows struct {
+	Appointments int // Appointments that have started
+	Completed    int
+	Cancelled    int
+	NoShows      int
+}
+
+type noShowService struct {
+	appointmentRepo   repository.AppointmentRepository
+	patientRepo       repository.PatientRepository
+	smsSender         sms.Sender
+	highRiskThreshold float64
+	settings          SettingsService
+	logger            *zap.Logger
+}
+
+// NewNoShowService creates a new no-show risk service
+func NewNoShowService(
+	appointmentRepo repository.AppointmentRepository,
+	patientRepo repository.PatientRepository,
+	smsSender sms.Sender,
+	highRiskThreshold float64,
+	settings SettingsService,
+	logger *zap.Logger,
+) NoShowService {
+	if highRiskThreshold <= 0 || highRiskThreshold > 1 {
+		highRiskThreshold = 0.3
+	}
+
+	return &noShowService{
+		appointmentRepo:   appointmentRepo,
+		patientRepo:       patientRepo,
+		smsSender:         smsSender,
+		highRiskThreshold: highRiskThreshold,
+		settings:          settings,
+		logger:            logger,
+	}
+}
+
+// AssessAppointment estimates the risk that the patient misses the appointment, from their
+// history of no-shows and late cancellations and how far ahead the appointment was booked
+func (s *noShowService) AssessAppointment(ctx context.Context, appointment *model.Appointment) (*NoShowRisk, error) {
+	history, err := s.appointmentRepo.FindPatientHistory(ctx, appointment.PatientID, appointment.ScheduledStart, noShowHistorySize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get appointment history: %w", err)
+	}
+
+	createdAt := appointment.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+
+	return s.score(history, appointment.ScheduledStart.Sub(createdAt)), nil
+}
+
+// GetPatientNoShows counts the patient's appointments that have started, and how many of them
+// were completed, cancelled or missed
+func (s *noShowService) GetPatientNoShows(ctx context.Context, patientID uint) (*PatientNoShows, error) {
+	if _, err := s.patientRepo.FindByID(ctx, patientID); err != nil {
+		return nil, err
+	}
+	counts, err := s.appointmentRepo.CountPatientByStatus(ctx, patientID, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to count appointments: %w", err)
+	}
+
+	summary := &PatientNoShows{}
+	for _, c := range counts {
+		summary.Appointments += int(c.Count)
+		switch c.Status {
+		case model.AppointmentStatusCompleted:
+			summary.Completed = int(c.Count)
+		case model.AppointmentStatusCancelled:
+			summary.Cancelled = int(c.Count)
+		case model.AppointmentStatusNoShow:
+			summary.NoShows = int(c.Count)
+		}
+	}
+	return summary, nil
+}
+
+// score computes the risk from past appointments. The no-show rate, counting late cancellations
+// as half a no-show, is smoothed towards a prior and raised for bookings made far in advance.
+func (s *noShowService) score(history []*model.Appointment, leadTime time.Duration) *NoShowRisk {
+	risk := &NoShowRisk{LeadTime: leadTime}
+
+	for _, appt := range history {
+		switch {
+		case appt.Status == model.AppointmentStatusNoShow:
+			risk.NoShows++
+		case appt.Status == model.AppointmentStatusCancelled && appt.CancelledAt != nil &&
+			appt.ScheduledStart.Sub(*appt.CancelledAt) < lateCancellationWindow:
+			risk.LateCancellations++
+		case appt.Status == model.AppointmentStatusCancelled:
+			// Cancelled in good time, not counted
+			continue
+		}
+		risk.PastAppointments++
+	}
+
+	missed := float64(risk.NoShows) + 0.5*float64(risk.LateCancellations)
+	score := (missed + noShowPriorRate*noShowPriorWeight) / (float64(risk.PastAppointments) + noShowPriorWeight)
+
+	switch {
+	case leadTime > 30*24*time.Hour:
+		score += 0.1
+	case leadTime > 14*24*time.Hour:
+		score += 0.05
+	}
+	if score > 1 {
+		score = 1
+	}
+	risk.Score = score
+
+	switch {
+	case score >= s.highRiskThreshold:
+		risk.Level = NoShowRiskHigh
+	case score >= s.highRiskThreshold/2:
+		risk.Level = NoShowRiskMedium
+	default:
+		risk.Level = NoShowRiskLow
+	}
+
+	return risk
+}
+
+// ScreenBooking scores a new booking and, when the no_show_confirmation feature requires it for
+// high-risk patients, marks it as awaiting confirmation and sends the patient a code by SMS
+func (s *noShowService) ScreenBooking(ctx context.Context, appointment *model.Appointment) error {
+	if !s.settings.Current().Feature(FeatureNoShowConfirmation) {
+		return nil
+	}
+
+	risk, err := s.AssessAppointment(ctx, appointment)
+	if err != nil {
+		return err
+	}
+	if risk.Level != NoShowRiskHigh {
+		return nil
+	}
+
+	patient, err := s.patientRepo.FindByID(ctx, appointment.PatientID)
+	if err != nil {
+		return err
+	}
+	if patient.User.Phone == "" {
+		s.logger.Warn("High no-show risk booking not confirmed by SMS, patient has no phone number",
+			zap.Uint("appointmentID", appointment.ID),
+			zap.Float64("score", risk.Score),
+		)
+		return nil
+	}
+
+	code, err := generateConfirmationCode()
+	if err != nil {
+		return fmt.Errorf("failed to generate confirmation code: %w", err)
+	}
+
+	expiresAt := time.Now().Add(confirmationCodeTTL)
+	if appointment.ScheduledStart.Before(expiresAt) {
+		expiresAt = appointment.ScheduledStart
+	}
+	appointment.ConfirmationRequired = true
+	appointment.ConfirmationCodeHash = utils.HashToken(code)
+	appointment.ConfirmationExpiresAt = &expiresAt
+	appointment.ConfirmationAttempts = 0
+	if err := s.appointmentRepo.Update(ctx, appointment); err != nil {
+		return fmt.Errorf("failed to update appointment: %w", err)
+	}
+
+	when := utils.FormatDateTime(appointment.ScheduledStart, patient.User.Timezone, patient.User.Locale)
+	if err := s.smsSender.Send(ctx, patient.User.Phone, confirmationCodeSMS(when, code)); err != nil {
+		s.logger.Error("Failed to send booking confirmation SMS", zap.Uint("appointmentID", appointment.ID), zap.Error(err))
+		return fmt.Errorf("failed to send confirmation code: %w", err)
+	}
+
+	s.logger.Info("High no-show risk booking awaiting confirmation",
+		zap.Uint("appointmentID", appointment.ID),
+		zap.Float64("score", risk.Score),
+	)
+	return nil
+}
+
+// ConfirmBooking confirms a booking awaiting confirmation with the code sent to the patient.
+// Codes are accepted until they expire, or the start of the appointment if sooner, and every
+// code entered counts towards maxConfirmationAttempts, after which the booking is locked.
+func (s *noShowService) ConfirmBooking(ctx context.Context, appointmentID, userID uint, code string) (*model.Appointment, error) {
+	appointment, err := s.appointmentRepo.FindByID(ctx, appointmentID)
+	if err != nil {
+		return nil, err
+	}
+	if appointment.Patient.UserID != userID {
+		return nil, errors.New("appointment does not belong to this patient")
+	}
+	if !appointment.ConfirmationRequired {
+		return nil, errors.New("appointment does not need confirmation")
+	}
+	if appointment.ConfirmationExpiresAt != nil && time.Now().After(*appointment.ConfirmationExpiresAt) {
+		return nil, ErrConfirmationCodeExpired
+	}
+	attempts, err := s.appointmentRepo.CountConfirmationAttempt(ctx, appointment.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count confirmation attempt: %w", err)
+	}
+	if attempts > maxConfirmationAttempts {
+		return nil, ErrConfirmationLocked
+	}
+	if subtle.ConstantTimeCompare([]byte(appointment.ConfirmationCodeHash), []byte(utils.HashToken(code))) != 1 {
+		if attempts == maxConfirmationAttempts {
+			s.logger.Warn("Booking confirmation locked after too many attempts", zap.Uint("appointmentID", appointment.ID))
+			return nil, ErrConfirmationLocked
+		}
+		return nil, errors.New("invalid confirmation code")
+	}
+
+	appointment.ConfirmationRequired = false
+	appointment.ConfirmationCodeHash = ""
+	appointment.ConfirmationExpiresAt = nil
+	appointment.ConfirmationAttempts = 0
+	appointment.UpdatedAt = time.Now()
+	// The booking stays pending while the clinic's intake checklist is incomplete
+	eventType := model.EventAppointmentUpdated
+	if appointment.Status == model.AppointmentStatusPending && len(appointment.OutstandingRequirements()) == 0 {
+		if eventType, err = transitionAppointment(appointment, model.AppointmentStatusConfirmed, appointment.UpdatedAt); err != nil {
+			return nil, err
+		}
+	}
+	events, err := appointmentEvents(eventType, newAppointmentEventData(appointment))
+	if err != nil {
+		return nil, err
+	}
+	if err := s.appointmentRepo.Update(ctx, appointment, events...); err != nil {
+		return nil, fmt.Errorf("failed to confirm appointment: %w", err)
+	}
+
+	return appointment, nil
+}
+
+// generateConfirmationCode returns a random six-digit code
+func generateConfirmationCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
@@ -0,0 +1,283 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/repository"
+	"github.com/whitewalker-sa/ehass/pkg/sms"
+	"github.com/whitewalker-sa/ehass/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// ErrNoMarketingConsent is returned when a promotional message is not sent because the user has
+// not opted in to marketing on any channel they can be reached on
+var ErrNoMarketingConsent = errors.New("user has not opted in to marketing messages")
+
+type notificationService struct {
+	notificationRepo repository.NotificationRepository
+	appointmentRepo  repository.AppointmentRepository
+	userRepo         repository.UserRepository
+	consentRepo      repository.ConsentRepository
+	orgService       OrganizationService
+	emailService     EmailService
+	smsSender        sms.Sender
+	logger           *zap.Logger
+}
+
+// NewNotificationService creates a new notification service
+func NewNotificationService(
+	notificationRepo repository.NotificationRepository,
+	appointmentRepo repository.AppointmentRepository,
+	userRepo repository.UserRepository,
+	consentRepo repository.ConsentRepository,
+	orgService OrganizationService,
+	emailService EmailService,
+	smsSender sms.Sender,
+	logger *zap.Logger,
+) NotificationService {
+	return &notificationService{
+		notificationRepo: notificationRepo,
+		appointmentRepo:  appointmentRepo,
+		userRepo:         userRepo,
+		consentRepo:      consentRepo,
+		orgService:       orgService,
+		emailService:     emailService,
+		smsSender:        smsSender,
+		logger:           logger,
+	}
+}
+
+// SendAppointmentReminder reminds the patient on their preferred channel. If that fails, for
+// example because the phone number is undeliverable or the address is suppressed after a bounce,
+// the reminder is sent on the other channel. Every attempt is logged.
+func (s *notificationService) SendAppointmentReminder(ctx context.Context, appointment *model.Appointment) error {
+	user := &appointment.Patient.User
+	primary := user.PreferredChannel
+	if primary != model.ChannelSMS {
+		primary = model.ChannelEmail
+	}
+
+	first, err := s.remind(ctx, appointment, primary, nil)
+	if err != nil {
+		return err
+	}
+	if first.Status == model.NotificationStatusSent {
+		return nil
+	}
+
+	second, err := s.remind(ctx, appointment, otherChannel(primary), &first.ID)
+	if err != nil {
+		return err
+	}
+	if second.Status != model.NotificationStatusSent {
+		return fmt.Errorf("reminder could not be delivered by email or SMS: %s; %s", first.Detail, second.Detail)
+	}
+	return nil
+}
+
+// EscalateReminder reminds the patient of an appointment again on a channel or, when channel is
+// empty, on the one no reminder has been delivered on yet. Unlike the first reminder it does not
+// fall back to the other channel; the next escalation step takes over instead.
+func (s *notificationService) EscalateReminder(ctx context.Context, appointment *model.Appointment, channel string) error {
+	if channel == "" {
+		attempts, err := s.notificationRepo.FindByAppointmentID(ctx, appointment.ID)
+		if err != nil {
+			return fmt.Errorf("failed to load notifications: %w", err)
+		}
+		delivered := make(map[string]bool)
+		for _, attempt := range attempts {
+			if attempt.Kind == model.NotificationAppointmentReminder && attempt.Status == model.NotificationStatusSent {
+				delivered[attempt.Channel] = true
+			}
+		}
+		primary := appointment.Patient.User.PreferredChannel
+		if primary != model.ChannelSMS {
+			primary = model.ChannelEmail
+		}
+		channel = otherChannel(primary)
+		if delivered[channel] && !delivered[primary] {
+			channel = primary
+		}
+	}
+
+	notification, err := s.remind(ctx, appointment, channel, nil)
+	if err != nil {
+		return err
+	}
+	if notification.Status != model.NotificationStatusSent {
+		return fmt.Errorf("escalated reminder could not be delivered by %s: %s", channel, notification.Detail)
+	}
+	return nil
+}
+
+// RetryBouncedReminders resends by SMS the email reminders sent since the given time that the
+// provider later reported as bounced, as long as the appointment is still upcoming. It returns
+// the number of reminders resent.
+func (s *notificationService) RetryBouncedReminders(ctx context.Context, since time.Time) (int, error) {
+	bounced, err := s.notificationRepo.FindBouncedEmails(ctx, model.NotificationAppointmentReminder, since)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find bounced reminders: %w", err)
+	}
+
+	resent := 0
+	for _, notification := range bounced {
+		notification.Status = model.NotificationStatusBounced
+		if err := s.notificationRepo.Update(ctx, notification); err != nil {
+			return resent, fmt.Errorf("failed to update notification: %w", err)
+		}
+		if notification.AppointmentID == nil {
+			continue
+		}
+
+		appointment, err := s.appointmentRepo.FindByID(ctx, *notification.AppointmentID)
+		if err != nil || appointment.ScheduledStart.Before(time.Now()) ||
+			(appointment.Status != model.AppointmentStatusPending && appointment.Status != model.AppointmentStatusConfirmed) {
+			continue
+		}
+
+		fallback, err := s.remind(ctx, appointment, model.ChannelSMS, &notification.ID)
+		if err != nil {
+			return resent, err
+		}
+		if fallback.Status == model.NotificationStatusSent {
+			resent++
+		}
+	}
+	return resent, nil
+}
+
+// GetAppointmentNotifications gets the notification log of an appointment
+func (s *notificationService) GetAppointmentNotifications(ctx context.Context, appointmentID uint) ([]*model.Notification, error) {
+	return s.notificationRepo.FindByAppointmentID(ctx, appointmentID)
+}
+
+// SendMarketingMessage sends a promotional message to a user on their preferred channel, or the
+// other one if they only opted in to that. Unlike reminders, it is only ever sent on a channel
+// the user gave marketing consent for, and a failed attempt is not retried elsewhere. It returns
+// ErrNoMarketingConsent without sending anything if no channel qualifies.
+func (s *notificationService) SendMarketingMessage(ctx context.Context, userID uint, subject, message string) (*model.Notification, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	primary := user.PreferredChannel
+	if primary != model.ChannelSMS {
+		primary = model.ChannelEmail
+	}
+	for _, channel := range []string{primary, otherChannel(primary)} {
+		if !reachable(user, channel) {
+			continue
+		}
+		consented, err := s.consentRepo.HasMarketingConsent(ctx, user.ID, channel)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check marketing consent: %w", err)
+		}
+		if consented {
+			return s.promote(ctx, user, channel, subject, message)
+		}
+	}
+	return nil, ErrNoMarketingConsent
+}
+
+// promote sends a promotional message on a channel and logs it
+func (s *notificationService) promote(ctx context.Context, user *model.User, channel, subject, message string) (*model.Notification, error) {
+	notification := &model.Notification{
+		UserID:  user.ID,
+		Kind:    model.NotificationMarketing,
+		Channel: channel,
+		Status:  model.NotificationStatusSent,
+	}
+
+	var err error
+	if channel == model.ChannelSMS {
+		err = s.smsSender.Send(ctx, user.Phone, marketingSMS(message))
+	} else {
+		notification.MessageID, err = s.emailService.SendMarketingEmail(ctx, user.Email, user.Name, subject, message)
+	}
+	if err != nil {
+		notification.Status = model.NotificationStatusFailed
+		notification.Detail = err.Error()
+	}
+
+	if err := s.notificationRepo.Create(ctx, notification); err != nil {
+		return nil, fmt.Errorf("failed to log notification: %w", err)
+	}
+	return notification, nil
+}
+
+// reachable reports whether a user has contact details for a channel
+func reachable(user *model.User, channel string) bool {
+	if channel == model.ChannelSMS {
+		return user.Phone != ""
+	}
+	return user.Email != "" && !hasPlaceholderEmail(user)
+}
+
+// remind sends one reminder attempt on a channel and logs it. Delivery failures are recorded
+// on the returned notification; only failing to log the attempt is returned as an error.
+func (s *notificationService) remind(ctx context.Context, appointment *model.Appointment, channel string, fallbackFor *uint) (*model.Notification, error) {
+	user := &appointment.Patient.User
+	startsAt := utils.FormatDateTime(appointment.ScheduledStart, user.Timezone, user.Locale)
+	doctorName := appointment.Doctor.User.Name
+	// A reminder without the clinic's branding and directions is better than none
+	org, err := s.orgService.GetDoctorOrganization(ctx, appointment.DoctorID)
+	if err != nil {
+		s.logger.Warn("Failed to load clinic for reminder", zap.Uint("appointmentID", appointment.ID), zap.Error(err))
+		org = nil
+	}
+	directions := appointmentDirections(appointment, org)
+
+	notification := &model.Notification{
+		UserID:        user.ID,
+		AppointmentID: &appointment.ID,
+		Kind:          model.NotificationAppointmentReminder,
+		Channel:       channel,
+		Status:        model.NotificationStatusSent,
+		FallbackForID: fallbackFor,
+	}
+
+	switch channel {
+	case model.ChannelSMS:
+		if user.Phone == "" {
+			err = errors.New("no phone number on file")
+		} else {
+			err = s.smsSender.Send(ctx, user.Phone, reminderSMS(doctorName, startsAt, directions))
+		}
+	default:
+		if user.Email == "" {
+			err = errors.New("no email address on file")
+		} else {
+			if org != nil {
+				ctx = withEmailOrganization(ctx, org)
+			}
+			notification.MessageID, err = s.emailService.SendAppointmentReminder(ctx, user.Email, user.Name, doctorName, startsAt, directions)
+		}
+	}
+	if err != nil {
+		notification.Status = model.NotificationStatusFailed
+		notification.Detail = err.Error()
+	}
+
+	if err := s.notificationRepo.Create(ctx, notification); err != nil {
+		return nil, fmt.Errorf("failed to log notification: %w", err)
+	}
+	if fallbackFor != nil {
+		s.logger.Info("Reminder sent on fallback channel",
+			zap.Uint("appointmentID", appointment.ID),
+			zap.String("channel", channel),
+			zap.String("status", string(notification.Status)))
+	}
+	return notification, nil
+}
+
+func otherChannel(channel string) string {
+	if channel == model.ChannelSMS {
+		return model.ChannelEmail
+	}
+	return model.ChannelSMS
+}
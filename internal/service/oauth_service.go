@@ -8,6 +8,7 @@ import (
 
 	"github.com/goccy/go-json"
 	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/pkg/breaker"
 )
 
 // oauthService implements the OAuthService interface
@@ -16,21 +17,27 @@ type oauthService struct {
 	githubClientSecret string
 	googleClientID     string
 	googleClientSecret string
+	githubBreaker      *breaker.Breaker
+	googleBreaker      *breaker.Breaker
 	httpClient         *http.Client
 }
 
-// NewOAuthService creates a new OAuth service
+// NewOAuthService creates a new OAuth service. Calls to each provider go through its breaker.
 func NewOAuthService(
 	githubClientID string,
 	githubClientSecret string,
 	googleClientID string,
 	googleClientSecret string,
+	githubBreaker *breaker.Breaker,
+	googleBreaker *breaker.Breaker,
 ) OAuthService {
 	return &oauthService{
 		githubClientID:     githubClientID,
 		githubClientSecret: githubClientSecret,
 		googleClientID:     googleClientID,
 		googleClientSecret: googleClientSecret,
+		githubBreaker:      githubBreaker,
+		googleBreaker:      googleBreaker,
 		httpClient: &http.Client{
 			Timeout: 10 * time.Second,
 		},
@@ -51,29 +58,6 @@ func (s *oauthService) GetUserInfo(ctx context.Context, provider model.AuthProvi
 
 // getGithubUserInfo retrieves user information from GitHub
 func (s *oauthService) getGithubUserInfo(ctx context.Context, token string) (*OAuthUserInfo, error) {
-	// Create request to GitHub API
-	req, err := http.NewRequestWithContext(ctx, "GET", "https://api.github.com/user", nil)
-	if err != nil {
-		return nil, err
-	}
-
-	// Set authorization header
-	req.Header.Set("Authorization", "Bearer "+token)
-	req.Header.Set("Accept", "application/json")
-
-	// Make request
-	resp, err := s.httpClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	// Check response status
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("GitHub API returned non-200 status code: %d", resp.StatusCode)
-	}
-
-	// Parse response
 	var githubUser struct {
 		ID        int    `json:"id"`
 		Email     string `json:"email"`
@@ -81,8 +65,7 @@ func (s *oauthService) getGithubUserInfo(ctx context.Context, token string) (*OA
 		Login     string `json:"login"`
 		AvatarURL string `json:"avatar_url"`
 	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&githubUser); err != nil {
+	if err := s.getJSON(ctx, s.githubBreaker, "GitHub", "https://api.github.com/user", token, &githubUser); err != nil {
 		return nil, err
 	}
 
@@ -111,36 +94,12 @@ func (s *oauthService) getGithubUserInfo(ctx context.Context, token string) (*OA
 
 // getGithubUserEmail retrieves primary email from GitHub
 func (s *oauthService) getGithubUserEmail(ctx context.Context, token string) (string, error) {
-	// Create request to GitHub API
-	req, err := http.NewRequestWithContext(ctx, "GET", "https://api.github.com/user/emails", nil)
-	if err != nil {
-		return "", err
-	}
-
-	// Set authorization header
-	req.Header.Set("Authorization", "Bearer "+token)
-	req.Header.Set("Accept", "application/json")
-
-	// Make request
-	resp, err := s.httpClient.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	// Check response status
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("GitHub API returned non-200 status code: %d", resp.StatusCode)
-	}
-
-	// Parse response
 	var emails []struct {
 		Email    string `json:"email"`
 		Primary  bool   `json:"primary"`
 		Verified bool   `json:"verified"`
 	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&emails); err != nil {
+	if err := s.getJSON(ctx, s.githubBreaker, "GitHub", "https://api.github.com/user/emails", token, &emails); err != nil {
 		return "", err
 	}
 
@@ -163,29 +122,6 @@ func (s *oauthService) getGithubUserEmail(ctx context.Context, token string) (st
 
 // getGoogleUserInfo retrieves user information from Google
 func (s *oauthService) getGoogleUserInfo(ctx context.Context, token string) (*OAuthUserInfo, error) {
-	// Create request to Google API
-	req, err := http.NewRequestWithContext(ctx, "GET", "https://www.googleapis.com/oauth2/v3/userinfo", nil)
-	if err != nil {
-		return nil, err
-	}
-
-	// Set authorization header
-	req.Header.Set("Authorization", "Bearer "+token)
-	req.Header.Set("Accept", "application/json")
-
-	// Make request
-	resp, err := s.httpClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	// Check response status
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("Google API returned non-200 status code: %d", resp.StatusCode)
-	}
-
-	// Parse response
 	var googleUser struct {
 		Sub           string `json:"sub"`
 		Email         string `json:"email"`
@@ -195,8 +131,7 @@ func (s *oauthService) getGoogleUserInfo(ctx context.Context, token string) (*OA
 		FamilyName    string `json:"family_name"`
 		Picture       string `json:"picture"`
 	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&googleUser); err != nil {
+	if err := s.getJSON(ctx, s.googleBreaker, "Google", "https://www.googleapis.com/oauth2/v3/userinfo", token, &googleUser); err != nil {
 		return nil, err
 	}
 
@@ -221,3 +156,35 @@ func (s *oauthService) getGoogleUserInfo(ctx context.Context, token string) (*OA
 		Avatar: googleUser.Picture,
 	}, nil
 }
+
+// getJSON fetches a provider API resource with the user's access token and decodes it into out.
+// A rejected token is the user's problem and does not count against the provider's breaker.
+func (s *oauthService) getJSON(ctx context.Context, b *breaker.Breaker, provider, url, token string, out interface{}) error {
+	return b.Do(ctx, func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+		if err != nil {
+			return breaker.Permanent(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Accept", "application/json")
+
+		resp, err := s.httpClient.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			err := fmt.Errorf("%s API returned non-200 status code: %d", provider, resp.StatusCode)
+			if resp.StatusCode < http.StatusInternalServerError && resp.StatusCode != http.StatusTooManyRequests {
+				return breaker.Permanent(err)
+			}
+			return err
+		}
+
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return fmt.Errorf("failed to decode %s response: %w", provider, err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,243 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/repository"
+	"go.uber.org/zap"
+)
+
+// JobOperations is the name of the job running asynchronous operations
+const JobOperations = "operations"
+
+// maxOperationAttempts is how many times an operation is started before it is given up on.
+// An operation is only started again when the instance running it stopped before it finished.
+const maxOperationAttempts = 3
+
+// ErrOperationNotFound is returned for operations that do not exist or belong to another user
+var ErrOperationNotFound = errors.New("operation not found")
+
+// OperationFunc carries out an operation of one kind. It returns the result to store as JSON.
+type OperationFunc func(ctx context.Context, params json.RawMessage) (interface{}, error)
+
+// operationCompletedData is the data of operation.completed events
+type operationCompletedData struct {
+	OperationID string `json:"operation_id"`
+	Kind        string `json:"kind"`
+	Status      string `json:"status"`
+	Error       string `json:"error,omitempty"`
+}
+
+// OperationRunner carries out asynchronous operations. Requests are stored and return at once;
+// any instance then claims and runs them one at a time. When an operation finishes, an
+// operation.completed event is published through the outbox.
+type OperationRunner struct {
+	repo     repository.OperationRepository
+	interval time.Duration
+	lease    time.Duration
+	monitor  *JobMonitor
+	logger   *zap.Logger
+
+	mu    sync.RWMutex
+	funcs map[string]OperationFunc
+}
+
+// NewOperationRunner creates a new operation runner. An operation may run for up to lease;
+// one still running after that is assumed lost and started again.
+func NewOperationRunner(
+	repo repository.OperationRepository,
+	interval time.Duration,
+	lease time.Duration,
+	monitor *JobMonitor,
+	logger *zap.Logger,
+) *OperationRunner {
+	if interval <= 0 {
+		interval = 2 * time.Second
+	}
+	if lease <= 0 {
+		lease = 30 * time.Minute
+	}
+
+	r := &OperationRunner{
+		repo:     repo,
+		interval: interval,
+		lease:    lease,
+		monitor:  monitor,
+		logger:   logger,
+		funcs:    make(map[string]OperationFunc),
+	}
+	monitor.Register(JobOperations, interval, func(ctx context.Context) error {
+		_, err := r.RunPending(ctx)
+		return err
+	})
+	return r
+}
+
+// Register sets the function carrying out operations of a kind
+func (r *OperationRunner) Register(kind string, run OperationFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.funcs[kind] = run
+}
+
+// Submit stores an operation for a user to be run in the background
+func (r *OperationRunner) Submit(ctx context.Context, kind string, userID uint, params interface{}) (*model.Operation, error) {
+	r.mu.RLock()
+	_, ok := r.funcs[kind]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown operation kind %q", kind)
+	}
+
+	encoded, err := json.Marshal(params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode operation parameters: %w", err)
+	}
+
+	operation := &model.Operation{
+		Kind:        kind,
+		Status:      model.OperationStatusPending,
+		RequestedBy: userID,
+		Params:      string(encoded),
+	}
+	if err := r.repo.Create(ctx, operation); err != nil {
+		return nil, fmt.Errorf("failed to create operation: %w", err)
+	}
+
+	r.logger.Info("Operation submitted", zap.String("operationID", operation.PublicID), zap.String("kind", kind))
+	return operation, nil
+}
+
+// Get returns an operation started by the user
+func (r *OperationRunner) Get(ctx context.Context, publicID string, userID uint) (*model.Operation, error) {
+	if !model.IsValidPublicID(publicID) {
+		return nil, fmt.Errorf("%w: operation ID must be a UUID", ErrInvalidPublicID)
+	}
+
+	operation, err := r.repo.FindByPublicID(ctx, publicID)
+	if err != nil {
+		return nil, ErrOperationNotFound
+	}
+	if operation.RequestedBy != userID {
+		return nil, ErrOperationNotFound
+	}
+	return operation, nil
+}
+
+// Start runs operations in the background until the returned function is called
+func (r *OperationRunner) Start() func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+		for {
+			if err := r.monitor.Do(ctx, JobOperations, func(ctx context.Context) error {
+				_, err := r.RunPending(ctx)
+				return err
+			}); err != nil && ctx.Err() == nil {
+				r.logger.Error("Running operations failed", zap.Error(err))
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(r.interval):
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		wg.Wait()
+	}
+}
+
+// RunPending runs operations until none are left to claim and returns how many were run
+func (r *OperationRunner) RunPending(ctx context.Context) (int, error) {
+	count := 0
+	for ctx.Err() == nil {
+		operation, err := r.repo.ClaimNext(ctx, time.Now(), r.lease)
+		if err != nil {
+			return count, fmt.Errorf("failed to claim operation: %w", err)
+		}
+		if operation == nil {
+			break
+		}
+		r.run(ctx, operation)
+		count++
+	}
+	return count, nil
+}
+
+// run carries out a claimed operation and records its outcome
+func (r *OperationRunner) run(ctx context.Context, operation *model.Operation) {
+	r.mu.RLock()
+	run, ok := r.funcs[operation.Kind]
+	r.mu.RUnlock()
+
+	var result interface{}
+	var err error
+	switch {
+	case !ok:
+		err = fmt.Errorf("unknown operation kind %q", operation.Kind)
+	case operation.Attempts > maxOperationAttempts:
+		err = errors.New("operation was interrupted too many times")
+	default:
+		runCtx, cancel := context.WithTimeout(ctx, r.lease)
+		result, err = run(runCtx, json.RawMessage(operation.Params))
+		cancel()
+	}
+
+	if ctx.Err() != nil {
+		// Shutting down: leave the operation claimed so it is started again once the claim runs out
+		r.logger.Warn("Operation interrupted by shutdown", zap.String("operationID", operation.PublicID))
+		return
+	}
+
+	now := time.Now()
+	operation.CompletedAt = &now
+	operation.ClaimedUntil = nil
+	if err == nil {
+		var encoded []byte
+		if encoded, err = json.Marshal(result); err == nil {
+			operation.Status = model.OperationStatusSucceeded
+			operation.Result = string(encoded)
+		}
+	}
+	if err != nil {
+		operation.Status = model.OperationStatusFailed
+		operation.Error = err.Error()
+		if len(operation.Error) > 500 {
+			operation.Error = operation.Error[:500]
+		}
+		r.logger.Warn("Operation failed", zap.String("operationID", operation.PublicID), zap.String("kind", operation.Kind), zap.Error(err))
+	}
+
+	payload, err := json.Marshal(operationCompletedData{
+		OperationID: operation.PublicID,
+		Kind:        operation.Kind,
+		Status:      string(operation.Status),
+		Error:       operation.Error,
+	})
+	if err != nil {
+		r.logger.Error("Failed to encode operation event", zap.Error(err))
+		return
+	}
+	event := &model.OutboxEvent{
+		Type:        model.EventOperationCompleted,
+		Destination: model.OutboxDestinationEvents,
+		Payload:     string(payload),
+	}
+	if err := r.repo.Complete(ctx, operation, event); err != nil {
+		// The operation runs again once its claim runs out
+		r.logger.Error("Failed to record operation outcome", zap.String("operationID", operation.PublicID), zap.Error(err))
+	}
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// OperationTimeouts bounds how long the work behind a request may run, so a stuck database query
+// or external call fails the request instead of holding its worker. Routes listed in timeouts use
+// their own timeout and other routes the default; a timeout of 0 leaves the route unbounded. A
+// nil OperationTimeouts bounds nothing.
+type OperationTimeouts struct {
+	defaultTimeout time.Duration
+	timeouts       map[string]time.Duration
+}
+
+// NewOperationTimeouts creates operation timeouts keyed by method and route pattern,
+// e.g. "GET /api/v1/doctors/:id/slots"
+func NewOperationTimeouts(defaultTimeout time.Duration, timeouts map[string]time.Duration) *OperationTimeouts {
+	return &OperationTimeouts{
+		defaultTimeout: defaultTimeout,
+		timeouts:       timeouts,
+	}
+}
+
+// Timeout returns the timeout of a route, or 0 when the route is unbounded
+func (t *OperationTimeouts) Timeout(route string) time.Duration {
+	if t == nil {
+		return 0
+	}
+	if timeout, ok := t.timeouts[route]; ok {
+		return timeout
+	}
+	return t.defaultTimeout
+}
+
+// Bound derives a context from ctx that expires after the route's timeout. Repositories and
+// clients given the context abandon their work once it expires. The returned cancel must be
+// called when the operation is done.
+func (t *OperationTimeouts) Bound(ctx context.Context, route string) (context.Context, context.CancelFunc) {
+	timeout := t.Timeout(route)
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// Unknown returns the routes with their own timeout that are not among routes, so a mistyped
+// route can be reported at startup
+func (t *OperationTimeouts) Unknown(routes []string) []string {
+	if t == nil {
+		return nil
+	}
+	known := make(map[string]bool, len(routes))
+	for _, route := range routes {
+		known[route] = true
+	}
+	var unknown []string
+	for route := range t.timeouts {
+		if !known[route] {
+			unknown = append(unknown, route)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
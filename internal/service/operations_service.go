@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/repository"
+	"github.com/whitewalker-sa/ehass/pkg/breaker"
+	"go.uber.org/zap"
+)
+
+// Names of the queues reported by the operations service
+const (
+	QueueRemindersDue    = "reminders_due"
+	QueueRemindersFailed = "reminders_failed"
+	QueueEmailsFailed    = "emails_failed"
+	QueueSIEMExport      = "siem_export"
+	QueueOutboxPending   = "outbox_pending"
+	QueueOutboxFailed    = "outbox_failed"
+)
+
+// failedEmailWindow is how far back failed emails are counted
+const failedEmailWindow = 24 * time.Hour
+
+// QueueStatus is the number of items waiting in one of the background work queues
+type QueueStatus struct {
+	Name        string
+	Depth       int64
+	Description string
+}
+
+type operationsService struct {
+	appointmentRepo     repository.AppointmentRepository
+	emailRepo           repository.EmailRepository
+	outboxRepo          repository.OutboxRepository
+	notificationService NotificationService
+	auditExporter       *AuditExporter // nil when SIEM export is disabled
+	monitor             *JobMonitor
+	breakers            *breaker.Registry
+	reminderLeadTime    time.Duration
+	logger              *zap.Logger
+}
+
+// NewOperationsService creates a new operations service
+func NewOperationsService(
+	appointmentRepo repository.AppointmentRepository,
+	emailRepo repository.EmailRepository,
+	outboxRepo repository.OutboxRepository,
+	notificationService NotificationService,
+	auditExporter *AuditExporter,
+	monitor *JobMonitor,
+	breakers *breaker.Registry,
+	reminderLeadTime time.Duration,
+	logger *zap.Logger,
+) OperationsService {
+	return &operationsService{
+		appointmentRepo:     appointmentRepo,
+		emailRepo:           emailRepo,
+		outboxRepo:          outboxRepo,
+		notificationService: notificationService,
+		auditExporter:       auditExporter,
+		monitor:             monitor,
+		breakers:            breakers,
+		reminderLeadTime:    reminderLeadTime,
+		logger:              logger,
+	}
+}
+
+// GetQueues reports the depth of the background work queues
+func (s *operationsService) GetQueues(ctx context.Context) ([]QueueStatus, error) {
+	now := time.Now()
+
+	due, err := s.appointmentRepo.CountDueReminders(ctx, now, now.Add(s.reminderLeadTime))
+	if err != nil {
+		return nil, fmt.Errorf("failed to count due reminders: %w", err)
+	}
+	failed, err := s.appointmentRepo.FindFailedReminders(ctx, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find failed reminders: %w", err)
+	}
+	failedEmails, err := s.emailRepo.CountMessages(ctx, model.EmailStatusFailed, now.Add(-failedEmailWindow))
+	if err != nil {
+		return nil, fmt.Errorf("failed to count failed emails: %w", err)
+	}
+	pendingEvents, err := s.outboxRepo.CountByStatus(ctx, model.OutboxStatusPending)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count pending outbox events: %w", err)
+	}
+	failedEvents, err := s.outboxRepo.CountByStatus(ctx, model.OutboxStatusFailed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count failed outbox events: %w", err)
+	}
+
+	queues := []QueueStatus{
+		{Name: QueueRemindersDue, Depth: due, Description: "Reminders due within the lead time and not yet sent"},
+		{Name: QueueRemindersFailed, Depth: int64(len(failed)), Description: "Upcoming appointments whose reminder could not be sent on any channel"},
+		{Name: QueueEmailsFailed, Depth: failedEmails, Description: "Emails rejected by the SMTP server in the last 24 hours"},
+		{Name: QueueOutboxPending, Depth: pendingEvents, Description: "Appointment events and emails waiting to be dispatched"},
+		{Name: QueueOutboxFailed, Depth: failedEvents, Description: "Appointment events and emails given up on after the maximum number of attempts"},
+	}
+
+	if s.auditExporter != nil {
+		backlog, err := s.auditExporter.Backlog(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count SIEM backlog: %w", err)
+		}
+		queues = append(queues, QueueStatus{Name: QueueSIEMExport, Depth: backlog, Description: "Audit log entries not yet delivered to the SIEM"})
+	}
+
+	return queues, nil
+}
+
+// GetJobs reports the scheduled jobs running on this instance
+func (s *operationsService) GetJobs() []JobStatus {
+	return s.monitor.Jobs()
+}
+
+// GetBreakers reports the circuit breakers guarding external dependencies on this instance
+func (s *operationsService) GetBreakers() []breaker.Stats {
+	return s.breakers.Stats()
+}
+
+// RunJob runs a scheduled job now and returns its status afterwards. A failed run is reported
+// in the status rather than as an error.
+func (s *operationsService) RunJob(ctx context.Context, name string) (*JobStatus, error) {
+	err := s.monitor.Run(ctx, name)
+	if errors.Is(err, ErrJobNotFound) || errors.Is(err, ErrJobRunning) {
+		return nil, err
+	}
+	if err != nil {
+		s.logger.Warn("Manually triggered job failed", zap.String("job", name), zap.Error(err))
+	}
+
+	status, err := s.monitor.Job(name)
+	if err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// RetryFailedReminders sends the reminders again for upcoming appointments whose reminder
+// failed on every channel, and returns how many were delivered this time
+func (s *operationsService) RetryFailedReminders(ctx context.Context) (int, error) {
+	failed, err := s.appointmentRepo.FindFailedReminders(ctx, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to find failed reminders: %w", err)
+	}
+
+	delivered := 0
+	for _, appointment := range failed {
+		if err := s.notificationService.SendAppointmentReminder(ctx, appointment); err != nil {
+			s.logger.Warn("Reminder retry failed", zap.Uint("appointmentID", appointment.ID), zap.Error(err))
+			continue
+		}
+		delivered++
+	}
+	return delivered, nil
+}
+
+// RetryFailedEvents makes outbox events that were given up on due again and returns how many
+func (s *operationsService) RetryFailedEvents(ctx context.Context) (int64, error) {
+	count, err := s.outboxRepo.RetryFailed(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to retry outbox events: %w", err)
+	}
+	return count, nil
+}
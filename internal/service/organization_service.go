@@ -0,0 +1,260 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/repository"
+	"github.com/whitewalker-sa/ehass/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// brandColorPattern accepts colors as #rgb or #rrggbb, which both emails and letters can use
+var brandColorPattern = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// metadataKeyPattern accepts appointment metadata keys that read well in query strings, such as
+// referral_source
+var metadataKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,49}$`)
+
+// maxMetadataKeys is the most appointment metadata keys a clinic can define
+const maxMetadataKeys = 30
+
+type organizationService struct {
+	orgRepo    repository.OrganizationRepository
+	doctorRepo repository.DoctorRepository
+	settings   SettingsService
+	logger     *zap.Logger
+}
+
+// NewOrganizationService creates a new organization service
+func NewOrganizationService(
+	orgRepo repository.OrganizationRepository,
+	doctorRepo repository.DoctorRepository,
+	settings SettingsService,
+	logger *zap.Logger,
+) OrganizationService {
+	return &organizationService{
+		orgRepo:    orgRepo,
+		doctorRepo: doctorRepo,
+		settings:   settings,
+		logger:     logger,
+	}
+}
+
+// CreateOrganization creates a clinic with its settings
+func (s *organizationService) CreateOrganization(ctx context.Context, org *model.Organization) (*model.Organization, error) {
+	if err := validateOrganization(org); err != nil {
+		return nil, err
+	}
+
+	org.ID = 0
+	org.CreatedAt = time.Now()
+	org.UpdatedAt = time.Now()
+	if err := s.orgRepo.Create(ctx, org); err != nil {
+		return nil, fmt.Errorf("failed to create organization: %w", err)
+	}
+
+	s.logger.Info("Organization created", zap.Uint("organizationID", org.ID), zap.String("name", org.Name))
+	return org, nil
+}
+
+// GetOrganization gets a clinic by ID
+func (s *organizationService) GetOrganization(ctx context.Context, id uint) (*model.Organization, error) {
+	return s.orgRepo.FindByID(ctx, id)
+}
+
+// ListOrganizations lists all clinics
+func (s *organizationService) ListOrganizations(ctx context.Context) ([]*model.Organization, error) {
+	return s.orgRepo.FindAll(ctx)
+}
+
+// UpdateOrganization replaces the settings of a clinic
+func (s *organizationService) UpdateOrganization(ctx context.Context, id uint, settings *model.Organization) (*model.Organization, error) {
+	org, err := s.orgRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateOrganization(settings); err != nil {
+		return nil, err
+	}
+
+	settings.ID = org.ID
+	settings.CreatedAt = org.CreatedAt
+	settings.UpdatedAt = time.Now()
+	if err := s.orgRepo.Update(ctx, settings); err != nil {
+		return nil, fmt.Errorf("failed to update organization: %w", err)
+	}
+
+	return settings, nil
+}
+
+// DeleteOrganization deletes a clinic; its doctors fall back to the default clinic
+func (s *organizationService) DeleteOrganization(ctx context.Context, id uint) error {
+	if _, err := s.orgRepo.FindByID(ctx, id); err != nil {
+		return err
+	}
+	return s.orgRepo.Delete(ctx, id)
+}
+
+// AssignDoctor makes a doctor part of a clinic so its settings apply to their appointments
+func (s *organizationService) AssignDoctor(ctx context.Context, orgID, doctorID uint) error {
+	if _, err := s.orgRepo.FindByID(ctx, orgID); err != nil {
+		return err
+	}
+	return s.orgRepo.AssignDoctor(ctx, orgID, doctorID)
+}
+
+// GetDoctorOrganization returns the settings that apply to a doctor's appointments: those of
+// their clinic, else of the default clinic, else the built-in defaults, with the booking window
+// capped by the max_booking_window_days setting
+func (s *organizationService) GetDoctorOrganization(ctx context.Context, doctorID uint) (*model.Organization, error) {
+	doctor, err := s.doctorRepo.FindByID(ctx, doctorID)
+	if err != nil {
+		return nil, err
+	}
+
+	if doctor.OrganizationID != nil {
+		if org, err := s.orgRepo.FindByID(ctx, *doctor.OrganizationID); err == nil {
+			return s.capBookingWindow(org), nil
+		}
+	}
+	if org, err := s.orgRepo.FindDefault(ctx); err == nil {
+		return s.capBookingWindow(org), nil
+	}
+	return s.capBookingWindow(model.DefaultOrganization()), nil
+}
+
+// capBookingWindow applies the max_booking_window_days setting to a clinic whose own booking
+// window is longer or unlimited. The stored settings of the clinic are left as they are.
+func (s *organizationService) capBookingWindow(org *model.Organization) *model.Organization {
+	limit := s.settings.Current().MaxBookingWindowDays
+	if limit <= 0 || (org.BookingWindowDays > 0 && org.BookingWindowDays <= limit) {
+		return org
+	}
+	capped := *org
+	capped.BookingWindowDays = limit
+	return &capped
+}
+
+// validateOrganization checks clinic settings before they are saved
+func validateOrganization(org *model.Organization) error {
+	if org.Name == "" {
+		return errors.New("organization name is required")
+	}
+	if org.Timezone == "" {
+		org.Timezone = utils.DefaultTimezone
+	}
+	if !utils.ValidTimezone(org.Timezone) {
+		return fmt.Errorf("invalid timezone %q", org.Timezone)
+	}
+	if org.BookingWindowDays < 0 || org.MinBookingNotice < 0 {
+		return errors.New("booking window and notice cannot be negative")
+	}
+	if org.DefaultAppointmentLength == 0 {
+		org.DefaultAppointmentLength = 30
+	}
+	if org.DefaultAppointmentLength < 5 || org.DefaultAppointmentLength > 480 {
+		return errors.New("default appointment length must be between 5 and 480 minutes")
+	}
+	if org.MaxReschedules == 0 {
+		org.MaxReschedules = 3
+	}
+	if org.MaxReschedules < 1 || org.MaxReschedules > 20 {
+		return errors.New("max reschedules must be between 1 and 20")
+	}
+	if org.CancellationCutoff == 0 {
+		org.CancellationCutoff = 60
+	}
+	if org.CancellationCutoff < 1 || org.CancellationCutoff > maxCancellationCutoff {
+		return fmt.Errorf("cancellation cutoff must be between 1 and %d minutes", maxCancellationCutoff)
+	}
+
+	for _, color := range []string{org.PrimaryColor, org.AccentColor} {
+		if color != "" && !brandColorPattern.MatchString(color) {
+			return fmt.Errorf("invalid brand color %q, expected a hex color such as #0b5fa5", color)
+		}
+	}
+
+	seen := make(map[model.IntakeRequirement]bool, len(org.IntakeRequirements))
+	for _, requirement := range org.IntakeRequirements {
+		if !requirement.IsValid() {
+			return fmt.Errorf("unknown intake requirement %q", requirement)
+		}
+		if seen[requirement] {
+			return fmt.Errorf("intake requirement %q is listed twice", requirement)
+		}
+		seen[requirement] = true
+	}
+
+	if len(org.AppointmentMetadataKeys) > maxMetadataKeys {
+		return fmt.Errorf("at most %d appointment metadata keys can be defined", maxMetadataKeys)
+	}
+	keys := make(map[string]bool, len(org.AppointmentMetadataKeys))
+	for _, key := range org.AppointmentMetadataKeys {
+		if !metadataKeyPattern.MatchString(key) {
+			return fmt.Errorf("invalid appointment metadata key %q, expected lowercase letters, digits and underscores", key)
+		}
+		if keys[key] {
+			return fmt.Errorf("appointment metadata key %q is listed twice", key)
+		}
+		keys[key] = true
+	}
+
+	for i, hours := range org.BusinessHours {
+		if hours.DayOfWeek < 0 || hours.DayOfWeek > 6 {
+			return fmt.Errorf("invalid day of week %d", hours.DayOfWeek)
+		}
+		open, err := time.Parse("15:04", hours.Open)
+		if err != nil {
+			return fmt.Errorf("invalid opening time %q", hours.Open)
+		}
+		closing, err := time.Parse("15:04", hours.Close)
+		if err != nil {
+			return fmt.Errorf("invalid closing time %q", hours.Close)
+		}
+		if !open.Before(closing) {
+			return fmt.Errorf("opening time %s must be before closing time %s", hours.Open, hours.Close)
+		}
+		org.BusinessHours[i].Open = open.Format("15:04")
+		org.BusinessHours[i].Close = closing.Format("15:04")
+	}
+
+	return nil
+}
+
+// checkBookingRules verifies that an appointment from start to end respects the clinic's
+// booking notice, booking window and business hours
+func checkBookingRules(org *model.Organization, start, end, now time.Time) error {
+	if start.Before(now) {
+		return errors.New("appointment cannot be scheduled in the past")
+	}
+	if org.MinBookingNotice > 0 && start.Before(now.Add(time.Duration(org.MinBookingNotice)*time.Minute)) {
+		return fmt.Errorf("appointments must be booked at least %d minutes in advance", org.MinBookingNotice)
+	}
+	if org.BookingWindowDays > 0 && start.After(now.AddDate(0, 0, org.BookingWindowDays)) {
+		return fmt.Errorf("appointments cannot be booked more than %d days in advance", org.BookingWindowDays)
+	}
+
+	if len(org.BusinessHours) == 0 {
+		return nil
+	}
+
+	loc := utils.LoadLocation(org.Timezone)
+	localStart, localEnd := start.In(loc), end.In(loc)
+	startClock := localStart.Format("15:04")
+	endClock := localEnd.Format("15:04")
+	sameDay := localStart.YearDay() == localEnd.YearDay() && localStart.Year() == localEnd.Year()
+
+	for _, hours := range org.BusinessHours {
+		if hours.DayOfWeek == int(localStart.Weekday()) && sameDay &&
+			startClock >= hours.Open && endClock <= hours.Close {
+			return nil
+		}
+	}
+	return errors.New("appointment is outside the clinic's business hours")
+}
@@ -0,0 +1,393 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/repository"
+	"github.com/whitewalker-sa/ehass/pkg/events"
+	"github.com/whitewalker-sa/ehass/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// outboxClaimLease is how long claimed events are hidden from other dispatchers. Events still
+// undelivered when it runs out, e.g. because the instance crashed, are claimed again.
+const outboxClaimLease = 5 * time.Minute
+
+// appointmentEventData is the data of appointment events, a snapshot taken when the change was made
+type appointmentEventData struct {
+	AppointmentID  string     `json:"appointment_id"`
+	PatientID      string     `json:"patient_id"`
+	DoctorID       string     `json:"doctor_id"`
+	Status         string     `json:"status"`
+	ScheduledStart time.Time  `json:"scheduled_start"`
+	ScheduledEnd   time.Time  `json:"scheduled_end"`
+	PreviousStart  *time.Time `json:"previous_start,omitempty"` // Set when rescheduled
+	SeriesID       string     `json:"series_id,omitempty"`      // Set for occurrences of a recurring series
+	Reason         string     `json:"reason,omitempty"`         // Set when declined by the doctor
+}
+
+// newAppointmentEventData takes the event data from an appointment loaded with its patient and doctor
+func newAppointmentEventData(appointment *model.Appointment) appointmentEventData {
+	return appointmentEventData{
+		AppointmentID:  appointment.PublicID,
+		PatientID:      appointment.Patient.PublicID,
+		DoctorID:       appointment.Doctor.PublicID,
+		Status:         string(appointment.Status),
+		ScheduledStart: appointment.ScheduledStart.UTC(),
+		ScheduledEnd:   appointment.ScheduledEnd.UTC(),
+	}
+}
+
+// appointmentEvents returns the outbox rows for an appointment event: one for the event
+// publisher and one for each email the change calls for
+func appointmentEvents(eventType string, data appointmentEventData) ([]*model.OutboxEvent, error) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	eventID := model.NewPublicID()
+	rows := []*model.OutboxEvent{{
+		EventID:     eventID,
+		Type:        eventType,
+		Destination: model.OutboxDestinationEvents,
+		Payload:     string(payload),
+	}}
+	if _, ok := appointmentEmails[eventType]; ok || eventType == model.EventAppointmentDeclined {
+		rows = append(rows, &model.OutboxEvent{
+			EventID:     eventID,
+			Type:        eventType,
+			Destination: model.OutboxDestinationEmail,
+			Payload:     string(payload),
+		})
+	}
+	if eventType == model.EventAppointmentRescheduled {
+		rows = append(rows, &model.OutboxEvent{
+			EventID:     eventID,
+			Type:        eventType,
+			Destination: model.OutboxDestinationDoctorEmail,
+			Payload:     string(payload),
+		})
+	}
+	return rows, nil
+}
+
+// appointmentEmails maps the appointment events patients are emailed about to the email sent
+var appointmentEmails = map[string]func(s EmailService, ctx context.Context, email, name, doctorName, startsAt string, attachments ...EmailAttachment) error{
+	model.EventAppointmentBooked:      EmailService.SendAppointmentConfirmation,
+	model.EventAppointmentRescheduled: EmailService.SendAppointmentRescheduled,
+	model.EventAppointmentCancelled:   EmailService.SendAppointmentCancellation,
+	model.EventAppointmentConfirmed:   EmailService.SendAppointmentConfirmed,
+}
+
+// confirmationLetterEmails are the appointment emails that carry the confirmation letter
+var confirmationLetterEmails = map[string]bool{
+	model.EventAppointmentBooked:      true,
+	model.EventAppointmentRescheduled: true,
+}
+
+// OutboxDispatcher delivers outbox events to the event publisher, sends the emails they call
+// for and applies record changes to the search index. Events are delivered at least once: a
+// failed delivery is retried with exponential backoff until it succeeds or runs out of attempts.
+type OutboxDispatcher struct {
+	outboxRepo      repository.OutboxRepository
+	appointmentRepo repository.AppointmentRepository
+	careRepo        repository.CareRepository
+	orgService      OrganizationService
+	emailService    EmailService
+	searchService   SearchService
+	publisher       events.Publisher
+	batchSize       int
+	interval        time.Duration
+	maxAttempts     int
+	maxBackoff      time.Duration
+	monitor         *JobMonitor
+	logger          *zap.Logger
+}
+
+// NewOutboxDispatcher creates a new outbox dispatcher
+func NewOutboxDispatcher(
+	outboxRepo repository.OutboxRepository,
+	appointmentRepo repository.AppointmentRepository,
+	careRepo repository.CareRepository,
+	orgService OrganizationService,
+	emailService EmailService,
+	searchService SearchService,
+	publisher events.Publisher,
+	batchSize int,
+	interval time.Duration,
+	maxAttempts int,
+	maxBackoff time.Duration,
+	monitor *JobMonitor,
+	logger *zap.Logger,
+) *OutboxDispatcher {
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	if maxAttempts <= 0 {
+		maxAttempts = 10
+	}
+	if maxBackoff < interval {
+		maxBackoff = interval
+	}
+
+	d := &OutboxDispatcher{
+		outboxRepo:      outboxRepo,
+		appointmentRepo: appointmentRepo,
+		careRepo:        careRepo,
+		orgService:      orgService,
+		emailService:    emailService,
+		searchService:   searchService,
+		publisher:       publisher,
+		batchSize:       batchSize,
+		interval:        interval,
+		maxAttempts:     maxAttempts,
+		maxBackoff:      maxBackoff,
+		monitor:         monitor,
+		logger:          logger,
+	}
+	monitor.Register(JobOutbox, interval, func(ctx context.Context) error {
+		_, err := d.DispatchBatch(ctx)
+		return err
+	})
+	return d
+}
+
+// Start dispatches in the background until the returned function is called
+func (d *OutboxDispatcher) Start() func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+		d.run(ctx)
+	}()
+
+	return func() {
+		cancel()
+		wg.Wait()
+	}
+}
+
+// run keeps claiming batches while a full batch comes back and polls at the interval otherwise
+func (d *OutboxDispatcher) run(ctx context.Context) {
+	for {
+		var claimed int
+		err := d.monitor.Do(ctx, JobOutbox, func(ctx context.Context) error {
+			var err error
+			claimed, err = d.DispatchBatch(ctx)
+			return err
+		})
+
+		wait := d.interval
+		if err == nil && claimed == d.batchSize {
+			wait = 0
+		} else if err != nil && ctx.Err() == nil {
+			d.logger.Error("Outbox dispatch failed", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// DispatchBatch delivers the next batch of due events and returns how many were claimed.
+// Failed deliveries are rescheduled rather than reported as errors.
+func (d *OutboxDispatcher) DispatchBatch(ctx context.Context) (int, error) {
+	batch, err := d.outboxRepo.ClaimDue(ctx, time.Now(), outboxClaimLease, d.batchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim outbox events: %w", err)
+	}
+
+	for _, event := range batch {
+		if err := d.deliver(ctx, event); err != nil {
+			d.failed(ctx, event, err)
+			continue
+		}
+
+		now := time.Now()
+		event.Status = model.OutboxStatusDispatched
+		event.Attempts++
+		event.DispatchedAt = &now
+		event.LastError = ""
+		if err := d.outboxRepo.Update(ctx, event); err != nil {
+			// The event is delivered again once the claim lease runs out
+			d.logger.Error("Failed to mark outbox event dispatched", zap.Uint("id", event.ID), zap.Error(err))
+		}
+	}
+
+	return len(batch), nil
+}
+
+// deliver sends an event to its destination
+func (d *OutboxDispatcher) deliver(ctx context.Context, event *model.OutboxEvent) error {
+	switch event.Destination {
+	case model.OutboxDestinationEvents:
+		return d.publisher.Publish(ctx, events.Event{
+			ID:            event.EventID,
+			Type:          event.Type,
+			Time:          event.CreatedAt.UTC(),
+			AggregateType: event.AggregateType,
+			Data:          json.RawMessage(event.Payload),
+		})
+	case model.OutboxDestinationEmail:
+		if event.Type == model.EventCareReminderDue {
+			return d.sendCareReminderEmail(ctx, event)
+		}
+		return d.sendAppointmentEmail(ctx, event)
+	case model.OutboxDestinationDoctorEmail:
+		return d.sendDoctorRescheduledEmail(ctx, event)
+	case model.OutboxDestinationSearch:
+		return d.searchService.Sync(ctx, event)
+	default:
+		return fmt.Errorf("unknown outbox destination %q", event.Destination)
+	}
+}
+
+// sendAppointmentEmail emails the patient about an appointment event. The time in the email is
+// the one recorded with the event, not the appointment's current time. Bookings and reschedules
+// come with the confirmation letter for that time. Declines carry the doctor's reason. Emails
+// are branded as the doctor's clinic.
+func (d *OutboxDispatcher) sendAppointmentEmail(ctx context.Context, event *model.OutboxEvent) error {
+	send, ok := appointmentEmails[event.Type]
+	if !ok && event.Type != model.EventAppointmentDeclined {
+		return fmt.Errorf("no email for event type %q", event.Type)
+	}
+	var data appointmentEventData
+	if err := json.Unmarshal([]byte(event.Payload), &data); err != nil {
+		return fmt.Errorf("failed to decode event: %w", err)
+	}
+
+	appointment, err := d.appointmentRepo.FindByID(ctx, event.AggregateID)
+	if err != nil {
+		return err
+	}
+	user := &appointment.Patient.User
+	if hasPlaceholderEmail(user) {
+		// Clinic-created records without an email have nowhere to send to
+		return nil
+	}
+
+	org, err := d.orgService.GetDoctorOrganization(ctx, appointment.DoctorID)
+	if err != nil {
+		return err
+	}
+	ctx = withEmailOrganization(ctx, org)
+
+	startsAt := utils.FormatDateTime(data.ScheduledStart, user.Timezone, user.Locale)
+	if event.Type == model.EventAppointmentDeclined {
+		return d.emailService.SendAppointmentDeclined(ctx, user.Email, user.Name, appointment.Doctor.User.Name, startsAt, data.Reason)
+	}
+	var attachments []EmailAttachment
+	if confirmationLetterEmails[event.Type] {
+		booked := *appointment
+		booked.ScheduledStart = data.ScheduledStart
+		booked.ScheduledEnd = data.ScheduledEnd
+		attachments = append(attachments, EmailAttachment{
+			Filename:    confirmationLetterFilename(appointment),
+			ContentType: "application/pdf",
+			Data:        confirmationLetter(&booked, org),
+		})
+	}
+	return send(d.emailService, ctx, user.Email, user.Name, appointment.Doctor.User.Name, startsAt, attachments...)
+}
+
+// sendDoctorRescheduledEmail tells the doctor that an appointment moved, with both times in
+// the doctor's timezone
+func (d *OutboxDispatcher) sendDoctorRescheduledEmail(ctx context.Context, event *model.OutboxEvent) error {
+	var data appointmentEventData
+	if err := json.Unmarshal([]byte(event.Payload), &data); err != nil {
+		return fmt.Errorf("failed to decode event: %w", err)
+	}
+	if data.PreviousStart == nil {
+		return fmt.Errorf("event %s has no previous start", event.EventID)
+	}
+
+	appointment, err := d.appointmentRepo.FindByID(ctx, event.AggregateID)
+	if err != nil {
+		return err
+	}
+	user := &appointment.Doctor.User
+	if hasPlaceholderEmail(user) {
+		return nil
+	}
+
+	org, err := d.orgService.GetDoctorOrganization(ctx, appointment.DoctorID)
+	if err != nil {
+		return err
+	}
+
+	previousStart := utils.FormatDateTime(*data.PreviousStart, user.Timezone, user.Locale)
+	startsAt := utils.FormatDateTime(data.ScheduledStart, user.Timezone, user.Locale)
+	return d.emailService.SendDoctorAppointmentRescheduled(withEmailOrganization(ctx, org), user.Email, user.Name, appointment.Patient.User.Name, previousStart, startsAt)
+}
+
+// sendCareReminderEmail emails the patient that a preventive care item is due
+func (d *OutboxDispatcher) sendCareReminderEmail(ctx context.Context, event *model.OutboxEvent) error {
+	var data careReminderEventData
+	if err := json.Unmarshal([]byte(event.Payload), &data); err != nil {
+		return fmt.Errorf("failed to decode event: %w", err)
+	}
+
+	reminder, err := d.careRepo.FindReminderByID(ctx, event.AggregateID)
+	if err != nil {
+		return err
+	}
+	user := &reminder.Patient.User
+	if hasPlaceholderEmail(user) {
+		return nil
+	}
+
+	dueOn := utils.FormatDate(data.DueAt, user.Timezone, user.Locale)
+	return d.emailService.SendCareReminder(ctx, user.Email, user.Name, data.RuleName, dueOn)
+}
+
+// failed records a failed delivery and schedules the next attempt, or gives up on the event
+// after the maximum number of attempts
+func (d *OutboxDispatcher) failed(ctx context.Context, event *model.OutboxEvent, deliveryErr error) {
+	event.Attempts++
+	event.LastError = deliveryErr.Error()
+	if len(event.LastError) > 500 {
+		event.LastError = event.LastError[:500]
+	}
+
+	if event.Attempts >= d.maxAttempts {
+		event.Status = model.OutboxStatusFailed
+		d.logger.Error("Giving up on outbox event",
+			zap.Uint("id", event.ID),
+			zap.String("type", event.Type),
+			zap.String("destination", string(event.Destination)),
+			zap.Int("attempts", event.Attempts),
+			zap.Error(deliveryErr),
+		)
+	} else {
+		backoff := d.interval << (event.Attempts - 1)
+		if backoff > d.maxBackoff || backoff <= 0 {
+			backoff = d.maxBackoff
+		}
+		event.NextAttemptAt = time.Now().Add(backoff)
+		d.logger.Warn("Outbox event delivery failed, retrying",
+			zap.Uint("id", event.ID),
+			zap.String("type", event.Type),
+			zap.String("destination", string(event.Destination)),
+			zap.Duration("backoff", backoff),
+			zap.Error(deliveryErr),
+		)
+	}
+
+	if err := d.outboxRepo.Update(ctx, event); err != nil {
+		d.logger.Error("Failed to reschedule outbox event", zap.Uint("id", event.ID), zap.Error(err))
+	}
+}
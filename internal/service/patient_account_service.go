@@ -0,0 +1,438 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/repository"
+	"github.com/whitewalker-sa/ehass/pkg/sms"
+	"github.com/whitewalker-sa/ehass/pkg/utils"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// claimCodeDigits is the length of the one-time code sent with an account invitation
+	claimCodeDigits = 8
+	// maxClaimAttempts is the number of wrong codes after which the invitation is revoked
+	maxClaimAttempts = 5
+	// unclaimedEmailDomain holds placeholder addresses for records created without an email.
+	// The .invalid TLD is reserved and never delivers.
+	unclaimedEmailDomain = "unclaimed.invalid"
+)
+
+// AuditActionAccountClaimed is recorded when a patient takes over a clinic-created record
+const AuditActionAccountClaimed = "auth.account_claimed"
+
+var (
+	// ErrAccountClaimRequired is returned when registering with the email of a clinic-created
+	// record; the patient has to claim it with their invitation code instead
+	ErrAccountClaimRequired = errors.New("a clinic record exists for this email; claim it with the code from your invitation")
+	// ErrEmailRegistered is returned when signing up with an email, or an alias of one, that
+	// already has an account
+	ErrEmailRegistered = errors.New("email already registered")
+	// ErrAlreadyClaimed is returned when inviting or claiming a record that already has a login
+	ErrAlreadyClaimed = errors.New("patient record already has an account")
+	// ErrInvalidClaimCode is returned for an unknown contact, a wrong code or an expired invitation
+	ErrInvalidClaimCode = errors.New("invalid or expired invitation code")
+	// ErrInvalidInvitation is returned for a setup link that was tampered with, has expired or
+	// was replaced by a newer invitation
+	ErrInvalidInvitation = errors.New("invalid or expired invitation link")
+	// ErrInvitationTooSoon is returned when inviting a patient again right after the last
+	// invitation, which is usually a double click
+	ErrInvitationTooSoon = errors.New("an invitation was just sent to this patient; wait a minute before sending another")
+)
+
+type patientAccountService struct {
+	authRepo      repository.AuthRepository
+	patientRepo   repository.PatientRepository
+	auditLogRepo  repository.AuditLogRepository
+	emailService  EmailService
+	smsSender     sms.Sender
+	inviteKeys    *SigningKeys
+	inviteExpiry  time.Duration
+	resendAfter   time.Duration
+	emailPolicy   *EmailPolicy
+	defaultRegion string // Region of phone numbers without a country calling code
+	logger        *zap.Logger
+}
+
+// NewPatientAccountService creates a new patient account service. Invitations are valid for
+// inviteExpiry, setup links are signed with inviteKeys, and a patient cannot be invited again
+// until resendAfter has passed.
+func NewPatientAccountService(
+	authRepo repository.AuthRepository,
+	patientRepo repository.PatientRepository,
+	auditLogRepo repository.AuditLogRepository,
+	emailService EmailService,
+	smsSender sms.Sender,
+	inviteKeys *SigningKeys,
+	inviteExpiry time.Duration,
+	resendAfter time.Duration,
+	emailPolicy *EmailPolicy,
+	defaultRegion string,
+	logger *zap.Logger,
+) PatientAccountService {
+	if inviteExpiry <= 0 {
+		inviteExpiry = 7 * 24 * time.Hour
+	}
+	return &patientAccountService{
+		authRepo:      authRepo,
+		patientRepo:   patientRepo,
+		auditLogRepo:  auditLogRepo,
+		emailService:  emailService,
+		smsSender:     smsSender,
+		inviteKeys:    inviteKeys,
+		inviteExpiry:  inviteExpiry,
+		resendAfter:   resendAfter,
+		emailPolicy:   emailPolicy,
+		defaultRegion: defaultRegion,
+		logger:        logger,
+	}
+}
+
+// CreateClinicRecord creates a patient and their user without a login. At least one of email and
+// phone is needed so the patient can be invited later. The phone is stored in E.164.
+func (s *patientAccountService) CreateClinicRecord(ctx context.Context, name, email, phone, dateOfBirth string) (*model.Patient, error) {
+	email = model.NormalizeEmail(email)
+	phone = strings.TrimSpace(phone)
+	if email == "" && phone == "" {
+		return nil, errors.New("email or phone is required")
+	}
+	var phoneCountry string
+	if phone != "" {
+		number, err := normalizePhone(phone, "", s.defaultRegion)
+		if err != nil {
+			return nil, err
+		}
+		phone, phoneCountry = number.E164, number.Region
+	}
+
+	dob, err := time.Parse("2006-01-02", dateOfBirth)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date of birth format: %w", err)
+	}
+
+	if email != "" {
+		if _, err := s.authRepo.FindUserByCanonicalEmail(ctx, email); err == nil {
+			return nil, ErrEmailRegistered
+		}
+	}
+	if phone != "" {
+		if _, err := s.authRepo.FindUserByPhone(ctx, phone); err == nil {
+			return nil, errors.New("phone already registered")
+		}
+	}
+
+	user := model.User{
+		PublicID:     model.NewPublicID(),
+		Name:         name,
+		Email:        email,
+		Phone:        phone,
+		PhoneCountry: phoneCountry,
+		Role:         model.RolePatient,
+		Provider:     model.AuthProviderLocal,
+	}
+	if user.Email == "" {
+		user.Email = fmt.Sprintf("%s@%s", user.PublicID, unclaimedEmailDomain)
+	}
+
+	// The user is created with the patient in one transaction
+	patient := &model.Patient{
+		PublicID:    model.NewPublicID(),
+		User:        user,
+		DateOfBirth: dob,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+	if err := s.patientRepo.Create(ctx, patient, searchSyncEvent(model.EventPatientUpdated, patient.PublicID)); err != nil {
+		return nil, fmt.Errorf("failed to create patient record: %w", err)
+	}
+
+	return s.patientRepo.FindByID(ctx, patient.ID)
+}
+
+// InvitePatient sends a new invitation to an unclaimed record: by email, a link that sets a
+// password in one step together with a one-time claim code, or by SMS the code alone when the
+// record has no email. Sending again resends the invitation; earlier links and codes stop
+// working.
+func (s *patientAccountService) InvitePatient(ctx context.Context, patientID uint) (*Invitation, error) {
+	patient, err := s.patientRepo.FindByID(ctx, patientID)
+	if err != nil {
+		return nil, err
+	}
+	user := &patient.User
+	if user.HasLogin() {
+		return nil, ErrAlreadyClaimed
+	}
+	if previous, err := s.authRepo.FindUserToken(ctx, user.ID, model.TokenTypeAccountClaim); err == nil &&
+		time.Since(previous.CreatedAt) < s.resendAfter {
+		return nil, ErrInvitationTooSoon
+	}
+
+	code, err := generateClaimCode()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.revokeInvitations(ctx, user.ID); err != nil {
+		return nil, fmt.Errorf("failed to revoke previous invitations: %w", err)
+	}
+	invitation := &Invitation{ExpiresAt: time.Now().Add(s.inviteExpiry)}
+	token := &model.VerificationToken{
+		UserID:    user.ID,
+		TokenHash: claimCodeHash(user, code),
+		Type:      model.TokenTypeAccountClaim,
+		ExpiresAt: invitation.ExpiresAt,
+		CreatedAt: time.Now(),
+	}
+	if err := s.authRepo.CreateVerificationToken(ctx, token); err != nil {
+		return nil, fmt.Errorf("failed to create invitation: %w", err)
+	}
+
+	if !hasPlaceholderEmail(user) {
+		setupToken, err := signPortalInvite(s.inviteKeys.Current(), user.PublicID, invitation.ExpiresAt)
+		if err != nil {
+			return nil, err
+		}
+		link := &model.VerificationToken{
+			UserID:    user.ID,
+			TokenHash: utils.HashToken(setupToken),
+			Type:      model.TokenTypePortalInvite,
+			ExpiresAt: invitation.ExpiresAt,
+			CreatedAt: time.Now(),
+		}
+		if err := s.authRepo.CreateVerificationToken(ctx, link); err != nil {
+			return nil, fmt.Errorf("failed to create invitation: %w", err)
+		}
+
+		validDays := int((s.inviteExpiry + 12*time.Hour) / (24 * time.Hour))
+		if validDays < 1 {
+			validDays = 1
+		}
+		if err := s.emailService.SendAccountClaimInvite(ctx, user.Email, user.Name, code, setupToken, validDays); err != nil {
+			return nil, fmt.Errorf("failed to send invitation email: %w", err)
+		}
+		invitation.Channel = model.ChannelEmail
+		return invitation, nil
+	}
+
+	if err := s.smsSender.Send(ctx, user.Phone, accountClaimSMS(code)); err != nil {
+		return nil, fmt.Errorf("failed to send invitation SMS: %w", err)
+	}
+	invitation.Channel = model.ChannelSMS
+	return invitation, nil
+}
+
+// SetUpAccount gives a clinic-created record a login with the password the patient chose on the
+// page the invitation link opens. The link went to the record's email, which proves the address.
+func (s *patientAccountService) SetUpAccount(ctx context.Context, setupToken, password string) (*model.User, error) {
+	publicID, err := s.inviteKeys.Verify(func(secret []byte) (string, error) {
+		return verifyPortalInvite(secret, setupToken, time.Now())
+	})
+	if err != nil {
+		return nil, err
+	}
+	token, err := s.authRepo.FindVerificationToken(ctx, utils.HashToken(setupToken), model.TokenTypePortalInvite)
+	if err != nil {
+		return nil, ErrInvalidInvitation
+	}
+	user, err := s.authRepo.FindByID(ctx, token.UserID)
+	if err != nil || user.PublicID != publicID {
+		return nil, ErrInvalidInvitation
+	}
+	if user.HasLogin() {
+		return nil, ErrAlreadyClaimed
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+	user.PasswordHash = string(hashedPassword)
+	user.EmailVerified = true
+	user.UpdatedAt = time.Now()
+
+	if err := s.authRepo.UpdateUser(ctx, user, searchSyncEvent(model.EventUserUpdated, user.PublicID)); err != nil {
+		return nil, fmt.Errorf("failed to set up account: %w", err)
+	}
+	if err := s.revokeInvitations(ctx, user.ID); err != nil {
+		s.logger.Warn("Failed to delete invitations", zap.Uint("user_id", user.ID), zap.Error(err))
+	}
+	s.audit(ctx, user.ID)
+
+	return user, nil
+}
+
+// ClaimAccount gives a clinic-created record a login. The record is found by the email or phone
+// the invitation was sent to, and the code must match. Registration details only fill fields the
+// clinic left blank. A patient claiming by phone must supply an email if the record has none,
+// and that email is verified separately.
+func (s *patientAccountService) ClaimAccount(ctx context.Context, req AccountClaim) (*model.User, error) {
+	var user *model.User
+	var err error
+	switch {
+	case req.Email != "":
+		user, err = s.authRepo.FindUserByEmail(ctx, strings.TrimSpace(req.Email))
+	case req.Phone != "":
+		user, err = s.authRepo.FindUserByPhone(ctx, phoneLookupKey(req.Phone, s.defaultRegion))
+	default:
+		return nil, errors.New("email or phone is required")
+	}
+	if err != nil {
+		return nil, ErrInvalidClaimCode
+	}
+	if user.HasLogin() {
+		return nil, ErrAlreadyClaimed
+	}
+
+	token, err := s.authRepo.FindUserToken(ctx, user.ID, model.TokenTypeAccountClaim)
+	if err != nil {
+		return nil, ErrInvalidClaimCode
+	}
+	if subtle.ConstantTimeCompare([]byte(token.TokenHash), []byte(claimCodeHash(user, strings.TrimSpace(req.Code)))) != 1 {
+		s.recordFailedAttempt(ctx, token)
+		return nil, ErrInvalidClaimCode
+	}
+
+	needsVerification := false
+	if hasPlaceholderEmail(user) {
+		if req.NewEmail == "" {
+			return nil, errors.New("an email address is required to set up the account")
+		}
+		newEmail := model.NormalizeEmail(req.NewEmail)
+		if err := s.emailPolicy.Check(newEmail); err != nil {
+			return nil, err
+		}
+		if _, err := s.authRepo.FindUserByCanonicalEmail(ctx, newEmail); err == nil {
+			return nil, ErrEmailRegistered
+		}
+		user.Email = newEmail
+		needsVerification = true
+	} else {
+		// Invitations go to the record's email when it has one, so the code proves the address
+		user.EmailVerified = true
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+	user.PasswordHash = string(hashedPassword)
+	if user.Name == "" {
+		user.Name = req.Name
+	}
+	if user.Phone == "" && req.Phone != "" {
+		number, err := normalizePhone(req.Phone, "", s.defaultRegion)
+		if err != nil {
+			return nil, err
+		}
+		user.Phone, user.PhoneCountry = number.E164, number.Region
+	}
+	if user.Address == "" {
+		user.Address = req.Address
+	}
+	user.UpdatedAt = time.Now()
+
+	if err := s.authRepo.UpdateUser(ctx, user, searchSyncEvent(model.EventUserUpdated, user.PublicID)); err != nil {
+		return nil, fmt.Errorf("failed to claim account: %w", err)
+	}
+	if err := s.revokeInvitations(ctx, user.ID); err != nil {
+		s.logger.Warn("Failed to delete invitations", zap.Uint("user_id", user.ID), zap.Error(err))
+	}
+
+	if needsVerification {
+		s.sendVerification(ctx, user)
+	}
+	s.audit(ctx, user.ID)
+
+	return user, nil
+}
+
+// revokeInvitations deletes the claim codes and setup links issued to a user
+func (s *patientAccountService) revokeInvitations(ctx context.Context, userID uint) error {
+	if err := s.authRepo.DeleteUserTokens(ctx, userID, model.TokenTypeAccountClaim); err != nil {
+		return err
+	}
+	return s.authRepo.DeleteUserTokens(ctx, userID, model.TokenTypePortalInvite)
+}
+
+// recordFailedAttempt counts a wrong code and revokes the invitation after too many
+func (s *patientAccountService) recordFailedAttempt(ctx context.Context, token *model.VerificationToken) {
+	if token.Attempts+1 >= maxClaimAttempts {
+		if err := s.authRepo.DeleteVerificationToken(ctx, token.ID); err != nil {
+			s.logger.Error("Failed to revoke invitation", zap.Uint("token_id", token.ID), zap.Error(err))
+		}
+		return
+	}
+	if err := s.authRepo.IncrementTokenAttempts(ctx, token.ID); err != nil {
+		s.logger.Error("Failed to count claim attempt", zap.Uint("token_id", token.ID), zap.Error(err))
+	}
+}
+
+// sendVerification sends the usual email verification link for an address given at claim time.
+// The account is already usable, so failures are only logged.
+func (s *patientAccountService) sendVerification(ctx context.Context, user *model.User) {
+	token := utils.GenerateRandomToken(32)
+	verificationToken := &model.VerificationToken{
+		UserID:    user.ID,
+		TokenHash: utils.HashToken(token),
+		Type:      model.TokenTypeEmailVerification,
+		ExpiresAt: time.Now().Add(24 * time.Hour),
+		CreatedAt: time.Now(),
+	}
+	if err := s.authRepo.CreateVerificationToken(ctx, verificationToken); err != nil {
+		s.logger.Error("Failed to create verification token", zap.Uint("user_id", user.ID), zap.Error(err))
+		return
+	}
+	if err := s.emailService.SendVerificationEmail(ctx, user.Email, user.Name, token); err != nil {
+		s.logger.Error("Failed to send verification email", zap.Uint("user_id", user.ID), zap.Error(err))
+	}
+}
+
+// audit records the claim. Failures are ignored, as for other authentication events.
+func (s *patientAccountService) audit(ctx context.Context, userID uint) {
+	if s.auditLogRepo == nil {
+		return
+	}
+
+	client := utils.ClientInfoFromContext(ctx)
+	_ = s.auditLogRepo.Create(ctx, &model.AuditLog{
+		UserID:     userID,
+		Action:     AuditActionAccountClaimed,
+		EntityID:   userID,
+		EntityType: "user",
+		IP:         client.IP,
+		UserAgent:  client.UserAgent,
+		CreatedAt:  time.Now(),
+	})
+}
+
+// generateClaimCode returns a random numeric code, easy to type from an SMS
+func generateClaimCode() (string, error) {
+	max := big.NewInt(1)
+	for i := 0; i < claimCodeDigits; i++ {
+		max.Mul(max, big.NewInt(10))
+	}
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate code: %w", err)
+	}
+	return fmt.Sprintf("%0*d", claimCodeDigits, n), nil
+}
+
+// claimCodeHash binds a code to its user so short codes never collide in the token index
+func claimCodeHash(user *model.User, code string) string {
+	return utils.HashToken(user.PublicID + ":" + code)
+}
+
+func hasPlaceholderEmail(user *model.User) bool {
+	return strings.HasSuffix(user.Email, "@"+unclaimedEmailDomain)
+}
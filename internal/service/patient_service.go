@@ -2,24 +2,57 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/whitewalker-sa/ehass/internal/model"
 	"github.com/whitewalker-sa/ehass/internal/repository"
 	"go.uber.org/zap"
 )
 
+const (
+	defaultSearchLimit   = 20
+	maxSearchLimit       = 50
+	minSearchLength      = 2
+	minPhoneSearchDigits = 4
+)
+
+var (
+	// ErrSearchQueryTooShort is returned for searches too short to narrow down the results
+	ErrSearchQueryTooShort = errors.New("search query must be at least 2 characters")
+	// ErrInvalidGuardian is returned when a guardian link would not form a single-level family:
+	// a patient cannot guard themselves, guardians cannot have guardians, and a patient managing
+	// others cannot be given a guardian
+	ErrInvalidGuardian = errors.New("invalid guardian")
+)
+
+// PatientProfileUpdate holds the patient profile fields to change; empty fields are left as they
+// are
+type PatientProfileUpdate struct {
+	DateOfBirth           string // YYYY-MM-DD
+	MedicalHistory        string
+	EmergencyContact      string // Name of the emergency contact
+	EmergencyPhone        string // Stored in E.164
+	EmergencyPhoneCountry string // Region of an EmergencyPhone without a country calling code; defaults to sms.defaultRegion
+}
+
 type patientService struct {
-	repo   repository.PatientRepository
-	logger *zap.Logger
+	repo          repository.PatientRepository
+	defaultRegion string // Region of phone numbers without a country calling code
+	logger        *zap.Logger
 }
 
-// NewPatientService creates a new patient service
-func NewPatientService(repo repository.PatientRepository, logger *zap.Logger) PatientService {
+// NewPatientService creates a new patient service. Phone numbers without a country calling code
+// are read as numbers of defaultRegion.
+func NewPatientService(repo repository.PatientRepository, defaultRegion string, logger *zap.Logger) PatientService {
 	return &patientService{
-		repo:   repo,
-		logger: logger,
+		repo:          repo,
+		defaultRegion: defaultRegion,
+		logger:        logger,
 	}
 }
 
@@ -33,6 +66,7 @@ func (s *patientService) CreatePatient(ctx context.Context, userID uint, dateOfB
 
 	// Create patient model
 	patient := &model.Patient{
+		PublicID:       model.NewPublicID(),
 		UserID:         userID,
 		DateOfBirth:    dob,
 		MedicalHistory: medicalHistory,
@@ -41,11 +75,12 @@ func (s *patientService) CreatePatient(ctx context.Context, userID uint, dateOfB
 	}
 
 	// Call repository to save patient
-	if err := s.repo.Create(ctx, patient); err != nil {
+	if err := s.repo.Create(ctx, patient, searchSyncEvent(model.EventPatientUpdated, patient.PublicID)); err != nil {
 		return nil, fmt.Errorf("failed to create patient profile: %w", err)
 	}
 
-	return patient, nil
+	// Reload with the user so the response carries their name and public ID
+	return s.repo.FindByID(ctx, patient.ID)
 }
 
 // GetPatientByID retrieves a patient by ID
@@ -58,21 +93,72 @@ func (s *patientService) GetPatientByUserID(ctx context.Context, userID uint) (*
 	return s.repo.FindByUserID(ctx, userID)
 }
 
+// SearchPatients finds patients by name, email, phone or date of birth for clinic staff. A
+// query in YYYY-MM-DD form searches dates of birth; one that looks like a phone number also
+// matches phone numbers ignoring formatting.
+func (s *patientService) SearchPatients(ctx context.Context, query string, limit int) ([]*model.Patient, error) {
+	query = strings.TrimSpace(query)
+	if utf8.RuneCountInString(query) < minSearchLength {
+		return nil, ErrSearchQueryTooShort
+	}
+	var search repository.PatientSearch
+	if dob, err := time.Parse("2006-01-02", query); err == nil {
+		search.DateOfBirth = &dob
+	} else {
+		search.Text = query
+		if digits := phoneDigits(query); len(digits) >= minPhoneSearchDigits {
+			search.Phone = digits
+		}
+	}
+
+	patients, err := s.repo.Search(ctx, search, searchLimit(limit))
+	if err != nil {
+		return nil, fmt.Errorf("failed to search patients: %w", err)
+	}
+	return patients, nil
+}
+
+// searchLimit applies the default and maximum number of search results
+func searchLimit(limit int) int {
+	if limit <= 0 {
+		return defaultSearchLimit
+	}
+	if limit > maxSearchLimit {
+		return maxSearchLimit
+	}
+	return limit
+}
+
+// phoneDigits returns the digits of query if it looks like a phone number, or "" otherwise
+func phoneDigits(query string) string {
+	var digits strings.Builder
+	for _, r := range query {
+		switch {
+		case unicode.IsDigit(r):
+			digits.WriteRune(r)
+		case strings.ContainsRune("+-(). ", r):
+		default:
+			return ""
+		}
+	}
+	return digits.String()
+}
+
 // UpdatePatient updates patient information
 func (s *patientService) UpdatePatient(ctx context.Context, patient *model.Patient) error {
-	return s.repo.Update(ctx, patient)
+	return s.repo.Update(ctx, patient, searchSyncEvent(model.EventPatientUpdated, patient.PublicID))
 }
 
 // UpdatePatientProfile updates patient profile information
-func (s *patientService) UpdatePatientProfile(ctx context.Context, id uint, dateOfBirth, medicalHistory string) (*model.Patient, error) {
+func (s *patientService) UpdatePatientProfile(ctx context.Context, id uint, update PatientProfileUpdate) (*model.Patient, error) {
 	patient, err := s.repo.FindByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 
 	// Parse date of birth if provided
-	if dateOfBirth != "" {
-		dob, err := time.Parse("2006-01-02", dateOfBirth)
+	if update.DateOfBirth != "" {
+		dob, err := time.Parse("2006-01-02", update.DateOfBirth)
 		if err != nil {
 			return nil, fmt.Errorf("invalid date of birth format: %w", err)
 		}
@@ -80,21 +166,74 @@ func (s *patientService) UpdatePatientProfile(ctx context.Context, id uint, date
 	}
 
 	// Update medical history if provided
-	if medicalHistory != "" {
-		patient.MedicalHistory = medicalHistory
+	if update.MedicalHistory != "" {
+		patient.MedicalHistory = update.MedicalHistory
+	}
+
+	if update.EmergencyContact != "" {
+		patient.EmergencyContact = strings.TrimSpace(update.EmergencyContact)
+	}
+	if update.EmergencyPhone != "" {
+		number, err := normalizePhone(update.EmergencyPhone, update.EmergencyPhoneCountry, s.defaultRegion)
+		if err != nil {
+			return nil, err
+		}
+		patient.EmergencyPhone = number.E164
+		patient.EmergencyPhoneCountry = number.Region
 	}
 
 	patient.UpdatedAt = time.Now()
 
-	err = s.repo.Update(ctx, patient)
+	err = s.repo.Update(ctx, patient, searchSyncEvent(model.EventPatientUpdated, patient.PublicID))
+	if err != nil {
+		return nil, err
+	}
+
+	return patient, nil
+}
+
+// SetGuardian links a patient to the guardian whose account manages their record, making them
+// one family for group bookings. A guardianID of 0 removes the link.
+func (s *patientService) SetGuardian(ctx context.Context, patientID, guardianID uint) (*model.Patient, error) {
+	patient, err := s.repo.FindByID(ctx, patientID)
 	if err != nil {
 		return nil, err
 	}
+	if guardianID == 0 {
+		patient.GuardianID = nil
+	} else {
+		if guardianID == patientID {
+			return nil, fmt.Errorf("%w: a patient cannot be their own guardian", ErrInvalidGuardian)
+		}
+		guardian, err := s.repo.FindByID(ctx, guardianID)
+		if err != nil {
+			return nil, err
+		}
+		if guardian.GuardianID != nil {
+			return nil, fmt.Errorf("%w: the guardian has a guardian of their own", ErrInvalidGuardian)
+		}
+		dependents, err := s.repo.CountDependents(ctx, patientID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check dependents: %w", err)
+		}
+		if dependents > 0 {
+			return nil, fmt.Errorf("%w: the patient is the guardian of other patients", ErrInvalidGuardian)
+		}
+		patient.GuardianID = &guardian.ID
+	}
+	patient.UpdatedAt = time.Now()
 
+	if err := s.repo.Update(ctx, patient); err != nil {
+		return nil, fmt.Errorf("failed to update guardian: %w", err)
+	}
 	return patient, nil
 }
 
 // DeletePatient deletes a patient by ID
 func (s *patientService) DeletePatient(ctx context.Context, id uint) error {
-	return s.repo.Delete(ctx, id)
+	patient, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	return s.repo.Delete(ctx, id, searchSyncEvent(model.EventPatientDeleted, patient.PublicID))
 }
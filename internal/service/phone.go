@@ -0,0 +1,30 @@
+package service
+
+import (
+	"strings"
+
+	"github.com/whitewalker-sa/ehass/pkg/phone"
+)
+
+// ErrInvalidPhone is wrapped by the errors returned for phone numbers that are not valid for
+// their country
+var ErrInvalidPhone = phone.ErrInvalid
+
+// normalizePhone validates a phone number written internationally or in the national form of
+// region, or of defaultRegion when no region is given, returning it in E.164 with its region
+func normalizePhone(raw, region, defaultRegion string) (phone.Number, error) {
+	if region == "" {
+		region = defaultRegion
+	}
+	return phone.Parse(raw, region)
+}
+
+// phoneLookupKey returns the form a phone number is stored in, to find records by a number as
+// someone typed it. Numbers that do not parse are looked up as typed, matching numbers stored
+// before they were validated.
+func phoneLookupKey(raw, defaultRegion string) string {
+	if number, err := phone.Parse(raw, defaultRegion); err == nil {
+		return number.E164
+	}
+	return strings.TrimSpace(raw)
+}
@@ -0,0 +1,341 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/pkg/pdf"
+	"github.com/whitewalker-sa/ehass/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// Audit actions for prescriptions
+const (
+	AuditActionPrescriptionIssued    = "prescription.issued"
+	AuditActionPrescriptionCancelled = "prescription.cancelled"
+)
+
+const (
+	defaultMedicationLimit  = 20
+	maxMedicationLimit      = 100
+	maxPrescriptionDuration = 365 // Days of one course
+	maxPrescriptionRefills  = 11
+	maxInstructionsLength   = 1000
+)
+
+var (
+	// ErrInvalidPrescription is returned when a prescription or catalog entry fails validation
+	ErrInvalidPrescription = errors.New("invalid prescription")
+	// ErrNotPrescriber is returned when a doctor cancels a prescription someone else issued
+	ErrNotPrescriber = errors.New("only the doctor who issued a prescription can cancel it")
+	// ErrPrescriptionCancelled is returned when cancelling a prescription that was already cancelled
+	ErrPrescriptionCancelled = errors.New("prescription is already cancelled")
+)
+
+// PrescriptionInput is what a doctor fills in to prescribe a medication from the catalog.
+// RecordID optionally links the prescription to the medical record of the visit.
+type PrescriptionInput struct {
+	MedicationID uint
+	RecordID     uint
+	Dosage       string
+	Frequency    string
+	DurationDays int
+	Refills      int
+	Instructions string
+}
+
+// SearchMedications finds active catalog products whose name starts with text
+func (s *medicalRecordService) SearchMedications(ctx context.Context, text string, limit int) ([]*model.Medication, error) {
+	if limit <= 0 {
+		limit = defaultMedicationLimit
+	}
+	if limit > maxMedicationLimit {
+		limit = maxMedicationLimit
+	}
+	return s.prescriptionRepo.SearchMedications(ctx, strings.TrimSpace(text), limit)
+}
+
+// ImportMedications adds products to the catalog or updates their route and status. Products
+// are identified by name, form and strength; those missing from the import are left as they are.
+func (s *medicalRecordService) ImportMedications(ctx context.Context, medications []*model.Medication) (int, error) {
+	now := time.Now()
+	seen := make(map[string]bool, len(medications))
+	for i, medication := range medications {
+		medication.Name = strings.ToLower(strings.TrimSpace(medication.Name))
+		medication.Form = strings.ToLower(strings.TrimSpace(medication.Form))
+		medication.Strength = strings.TrimSpace(medication.Strength)
+		medication.Route = strings.ToLower(strings.TrimSpace(medication.Route))
+		if medication.Name == "" || medication.Form == "" || medication.Strength == "" {
+			return 0, fmt.Errorf("%w: entry %d: name, form and strength are required", ErrInvalidPrescription, i)
+		}
+		key := medication.Name + "|" + medication.Form + "|" + medication.Strength
+		if seen[key] {
+			return 0, fmt.Errorf("%w: entry %d: %s %s %s appears more than once", ErrInvalidPrescription, i, medication.Name, medication.Strength, medication.Form)
+		}
+		seen[key] = true
+		medication.CreatedAt = now
+		medication.UpdatedAt = now
+	}
+
+	if err := s.prescriptionRepo.SaveMedications(ctx, medications); err != nil {
+		return 0, fmt.Errorf("failed to import medications: %w", err)
+	}
+
+	s.logger.Info("Medications imported", zap.Int("count", len(medications)))
+	return len(medications), nil
+}
+
+// RetireMedication stops a product from being prescribed. Prescriptions already issued keep it.
+func (s *medicalRecordService) RetireMedication(ctx context.Context, id uint) error {
+	return s.prescriptionRepo.DeactivateMedication(ctx, id)
+}
+
+// IssuePrescription prescribes an active catalog medication to a patient as the doctor signed
+// in as userID, who must be on the patient's care team. A linked record must be one they wrote.
+func (s *medicalRecordService) IssuePrescription(ctx context.Context, userID, patientID uint, input PrescriptionInput) (*model.Prescription, error) {
+	prescription := &model.Prescription{
+		PatientID:    patientID,
+		Dosage:       strings.TrimSpace(input.Dosage),
+		Frequency:    strings.TrimSpace(input.Frequency),
+		DurationDays: input.DurationDays,
+		Refills:      input.Refills,
+		Instructions: strings.TrimSpace(input.Instructions),
+	}
+	switch {
+	case prescription.Dosage == "" || prescription.Frequency == "":
+		return nil, fmt.Errorf("%w: dosage and frequency are required", ErrInvalidPrescription)
+	case len([]rune(prescription.Dosage)) > 100 || len([]rune(prescription.Frequency)) > 100:
+		return nil, fmt.Errorf("%w: dosage and frequency must be at most 100 characters", ErrInvalidPrescription)
+	case prescription.DurationDays < 1 || prescription.DurationDays > maxPrescriptionDuration:
+		return nil, fmt.Errorf("%w: duration must be 1 to %d days", ErrInvalidPrescription, maxPrescriptionDuration)
+	case prescription.Refills < 0 || prescription.Refills > maxPrescriptionRefills:
+		return nil, fmt.Errorf("%w: refills must be 0 to %d", ErrInvalidPrescription, maxPrescriptionRefills)
+	case len([]rune(prescription.Instructions)) > maxInstructionsLength:
+		return nil, fmt.Errorf("%w: instructions must be at most %d characters", ErrInvalidPrescription, maxInstructionsLength)
+	}
+
+	patient, err := s.patientRepo.FindByID(ctx, patientID)
+	if err != nil {
+		return nil, err
+	}
+	doctor, err := s.treatingDoctor(ctx, userID, patientID)
+	if err != nil {
+		return nil, err
+	}
+	if input.RecordID != 0 {
+		record, err := s.authoredRecord(ctx, userID, patientID, input.RecordID)
+		if err != nil {
+			return nil, err
+		}
+		prescription.RecordID = &record.ID
+	}
+	medication, err := s.prescriptionRepo.FindMedication(ctx, input.MedicationID)
+	if err != nil {
+		return nil, err
+	}
+	if !medication.Active {
+		return nil, fmt.Errorf("%w: %s %s %s has been retired", ErrInvalidPrescription, medication.Name, medication.Strength, medication.Form)
+	}
+
+	now := time.Now()
+	prescription.DoctorID = doctor.ID
+	prescription.MedicationID = medication.ID
+	prescription.IssuedAt = now
+	prescription.EndsAt = now.AddDate(0, 0, prescription.DurationDays*(prescription.Refills+1))
+	prescription.CreatedAt = now
+	prescription.UpdatedAt = now
+	if err := s.prescriptionRepo.Create(ctx, prescription); err != nil {
+		return nil, fmt.Errorf("failed to issue prescription: %w", err)
+	}
+	prescription.Patient = *patient
+	prescription.Doctor = *doctor
+	prescription.Medication = *medication
+
+	s.auditPrescription(ctx, userID, AuditActionPrescriptionIssued, prescription)
+	return prescription, nil
+}
+
+// ListPrescriptions lists a patient's prescriptions, most recently issued first, for the user
+// signed in as userID. Only active ones are listed unless all is set. Access follows the
+// patient's medical records.
+func (s *medicalRecordService) ListPrescriptions(ctx context.Context, userID uint, role model.Role, patientID uint, all bool, page, pageSize int) ([]*model.Prescription, int64, error) {
+	if err := s.authorizeRead(ctx, userID, role, patientID); err != nil {
+		return nil, 0, err
+	}
+	var activeAt *time.Time
+	if !all {
+		now := time.Now()
+		activeAt = &now
+	}
+	offset := (page - 1) * pageSize
+	return s.prescriptionRepo.FindByPatientID(ctx, patientID, activeAt, pageSize, offset)
+}
+
+// GetPrescription gets one of a patient's prescriptions for the user signed in as userID
+func (s *medicalRecordService) GetPrescription(ctx context.Context, userID uint, role model.Role, patientID, id uint) (*model.Prescription, error) {
+	if err := s.authorizeRead(ctx, userID, role, patientID); err != nil {
+		return nil, err
+	}
+	return s.patientPrescription(ctx, patientID, id)
+}
+
+// CancelPrescription stops a prescription issued by the doctor signed in as userID
+func (s *medicalRecordService) CancelPrescription(ctx context.Context, userID, patientID, id uint, reason string) (*model.Prescription, error) {
+	prescription, err := s.patientPrescription(ctx, patientID, id)
+	if err != nil {
+		return nil, err
+	}
+	doctor, err := s.doctorRepo.FindByUserID(ctx, userID)
+	if err != nil || doctor.ID != prescription.DoctorID {
+		return nil, ErrNotPrescriber
+	}
+	if prescription.CancelledAt != nil {
+		return nil, ErrPrescriptionCancelled
+	}
+	reason = strings.TrimSpace(reason)
+	if len([]rune(reason)) > 255 {
+		return nil, fmt.Errorf("%w: reason must be at most 255 characters", ErrInvalidPrescription)
+	}
+
+	now := time.Now()
+	if err := s.prescriptionRepo.Cancel(ctx, prescription.ID, reason, now); err != nil {
+		return nil, err
+	}
+	prescription.CancelledAt = &now
+	prescription.CancelReason = reason
+	prescription.UpdatedAt = now
+
+	s.auditPrescription(ctx, userID, AuditActionPrescriptionCancelled, prescription)
+	return prescription, nil
+}
+
+// RenderPrescription renders one of a patient's prescriptions as a printable PDF for the user
+// signed in as userID, with times in the patient's timezone
+func (s *medicalRecordService) RenderPrescription(ctx context.Context, userID uint, role model.Role, patientID, id uint) ([]byte, error) {
+	prescription, err := s.GetPrescription(ctx, userID, role, patientID, id)
+	if err != nil {
+		return nil, err
+	}
+	org, err := s.orgService.GetDoctorOrganization(ctx, prescription.DoctorID)
+	if err != nil {
+		return nil, err
+	}
+	return prescriptionDocument(prescription, org), nil
+}
+
+// patientPrescription finds a prescription, treating one of another patient as not found
+func (s *medicalRecordService) patientPrescription(ctx context.Context, patientID, id uint) (*model.Prescription, error) {
+	prescription, err := s.prescriptionRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if prescription.PatientID != patientID {
+		return nil, errors.New("prescription not found")
+	}
+	return prescription, nil
+}
+
+// auditPrescription records a prescription being issued or cancelled; the medication and
+// instructions are not logged
+func (s *medicalRecordService) auditPrescription(ctx context.Context, userID uint, action string, prescription *model.Prescription) {
+	client := utils.ClientInfoFromContext(ctx)
+	if err := s.auditLogRepo.Create(ctx, &model.AuditLog{
+		UserID:     userID,
+		Action:     action,
+		EntityID:   prescription.ID,
+		EntityType: "prescription",
+		IP:         client.IP,
+		UserAgent:  client.UserAgent,
+		CreatedAt:  time.Now(),
+	}); err != nil {
+		s.logger.Error("Failed to write prescription audit log",
+			zap.String("action", action),
+			zap.Uint("prescriptionID", prescription.ID),
+			zap.Error(err))
+	}
+}
+
+// prescriptionDocument renders a prescription for the patient to print or take to a pharmacy.
+// The prescription must be loaded with its patient, doctor and medication.
+func prescriptionDocument(prescription *model.Prescription, org *model.Organization) []byte {
+	patient := &prescription.Patient
+	loc := utils.LoadLocation(patient.User.Timezone)
+
+	doc := pdf.New("Prescription " + prescription.PublicID)
+	doc.SetHeadingColor(org.PrimaryColor)
+	doc.Heading(org.DisplayName())
+	var contact []string
+	for _, line := range []string{org.Address, org.ContactPhone, org.ContactEmail} {
+		if line != "" {
+			contact = append(contact, line)
+		}
+	}
+	if len(contact) > 0 {
+		doc.Text(strings.Join(contact, " | "))
+	}
+	if org.PrimaryColor != "" {
+		doc.Rule()
+	}
+	doc.Spacer(16)
+
+	doc.Heading("Prescription")
+	if prescription.CancelledAt != nil {
+		doc.Text("CANCELLED on " + prescription.CancelledAt.In(loc).Format("2 January 2006") + ". This prescription must not be dispensed.")
+		doc.Spacer(8)
+	}
+
+	prescriber := prescription.Doctor.User.Name
+	if prescription.Doctor.LicenseNo != "" {
+		prescriber += " (license " + prescription.Doctor.LicenseNo + ")"
+	}
+	doc.Table([]pdf.Column{
+		{Title: "Patient", Width: 0.25},
+		{Title: "", Width: 0.75},
+	}, [][]string{
+		{"Name", patient.User.Name},
+		{"Date of birth", patient.DateOfBirth.Format("2 January 2006")},
+		{"Prescriber", prescriber},
+	})
+	doc.Spacer(12)
+
+	medication := prescription.Medication
+	refills := "None"
+	if prescription.Refills > 0 {
+		refills = fmt.Sprintf("%d", prescription.Refills)
+	}
+	rows := [][]string{
+		{"Medication", fmt.Sprintf("%s %s %s", medication.Name, medication.Strength, medication.Form)},
+		{"Dosage", prescription.Dosage},
+	}
+	if medication.Route != "" {
+		rows = append(rows, []string{"Route", medication.Route})
+	}
+	rows = append(rows,
+		[]string{"Frequency", prescription.Frequency},
+		[]string{"Duration", fmt.Sprintf("%d days", prescription.DurationDays)},
+		[]string{"Refills", refills},
+	)
+	rows = append(rows,
+		[]string{"Issued", prescription.IssuedAt.In(loc).Format("2 January 2006")},
+		[]string{"Valid until", prescription.EndsAt.In(loc).Format("2 January 2006")},
+		[]string{"Reference", prescription.PublicID},
+	)
+	doc.Table([]pdf.Column{
+		{Title: "Prescribed", Width: 0.25},
+		{Title: "", Width: 0.75},
+	}, rows)
+
+	if prescription.Instructions != "" {
+		doc.Spacer(12)
+		doc.Heading("Instructions")
+		doc.Text(prescription.Instructions)
+	}
+
+	doc.Spacer(12)
+	doc.Text("Printed " + time.Now().In(loc).Format("2006-01-02 15:04 MST") + ".")
+	return doc.Bytes()
+}
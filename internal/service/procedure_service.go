@@ -0,0 +1,243 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/repository"
+	"github.com/whitewalker-sa/ehass/pkg/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultProcedureCodeLimit = 20
+	maxProcedureCodeLimit     = 100
+	maxProcedureModifiers     = 4 // Claim lines carry at most four modifiers
+	maxProcedureUnits         = 999
+	maxClaimsRangeDays        = 366
+)
+
+// modifierPattern matches billing modifiers such as 25, LT or GA
+var modifierPattern = regexp.MustCompile(`^[0-9A-Z]{2}$`)
+
+var (
+	// ErrInvalidProcedure is returned when a procedure code or procedure fails validation
+	ErrInvalidProcedure = errors.New("invalid procedure")
+	// ErrAppointmentNotCompleted is returned when procedures are recorded on an appointment that has not taken place
+	ErrAppointmentNotCompleted = errors.New("procedures can only be recorded on completed appointments")
+)
+
+// ClaimLine is a billed procedure with everything a claim needs
+type ClaimLine struct {
+	AppointmentID string
+	ServiceDate   time.Time
+	PatientID     string
+	PatientName   string
+	DateOfBirth   time.Time
+	DoctorID      string
+	DoctorName    string
+	LicenseNo     string
+	System        model.ProcedureCodeSystem
+	Code          string
+	Description   string
+	Modifiers     []string
+	Units         int
+}
+
+type procedureService struct {
+	repo            repository.ProcedureRepository
+	appointmentRepo repository.AppointmentRepository
+	orgRepo         repository.OrganizationRepository
+	logger          *zap.Logger
+}
+
+// NewProcedureService creates a new procedure service
+func NewProcedureService(
+	repo repository.ProcedureRepository,
+	appointmentRepo repository.AppointmentRepository,
+	orgRepo repository.OrganizationRepository,
+	logger *zap.Logger,
+) ProcedureService {
+	return &procedureService{
+		repo:            repo,
+		appointmentRepo: appointmentRepo,
+		orgRepo:         orgRepo,
+		logger:          logger,
+	}
+}
+
+// SearchCodes finds active catalog codes by code prefix or description
+func (s *procedureService) SearchCodes(ctx context.Context, text string, system model.ProcedureCodeSystem, limit int) ([]*model.ProcedureCode, error) {
+	if system != "" && system != model.ProcedureCodeCPT && system != model.ProcedureCodeHCPCS {
+		return nil, fmt.Errorf("%w: system must be CPT or HCPCS", ErrInvalidProcedure)
+	}
+	if limit <= 0 {
+		limit = defaultProcedureCodeLimit
+	}
+	if limit > maxProcedureCodeLimit {
+		limit = maxProcedureCodeLimit
+	}
+	return s.repo.SearchCodes(ctx, strings.TrimSpace(text), system, limit)
+}
+
+// ImportCodes adds codes to the catalog or updates them. Codes missing from the import are
+// left as they are; retire them with DeactivateCode.
+func (s *procedureService) ImportCodes(ctx context.Context, codes []*model.ProcedureCode) (int, error) {
+	now := time.Now()
+	seen := make(map[string]bool, len(codes))
+	for i, code := range codes {
+		code.Code = strings.ToUpper(strings.TrimSpace(code.Code))
+		code.System = model.ProcedureCodeSystem(strings.ToUpper(string(code.System)))
+		code.Description = strings.TrimSpace(code.Description)
+		if !code.System.IsValidCode(code.Code) {
+			return 0, fmt.Errorf("%w: entry %d: %q is not a valid %s code", ErrInvalidProcedure, i, code.Code, code.System)
+		}
+		if code.Description == "" {
+			return 0, fmt.Errorf("%w: entry %d: description is required", ErrInvalidProcedure, i)
+		}
+		if seen[code.Code] {
+			return 0, fmt.Errorf("%w: entry %d: %s appears more than once", ErrInvalidProcedure, i, code.Code)
+		}
+		seen[code.Code] = true
+		code.CreatedAt = now
+		code.UpdatedAt = now
+	}
+
+	if err := s.repo.SaveCodes(ctx, codes); err != nil {
+		return 0, fmt.Errorf("failed to import procedure codes: %w", err)
+	}
+
+	s.logger.Info("Procedure codes imported", zap.Int("count", len(codes)))
+	return len(codes), nil
+}
+
+// DeactivateCode retires a code. Procedures already recorded with it keep it.
+func (s *procedureService) DeactivateCode(ctx context.Context, code string) error {
+	return s.repo.DeactivateCode(ctx, strings.ToUpper(code))
+}
+
+// ListAppointmentProcedures lists the procedures recorded on an appointment
+func (s *procedureService) ListAppointmentProcedures(ctx context.Context, appointmentID uint) ([]*model.AppointmentProcedure, error) {
+	return s.repo.FindByAppointmentID(ctx, appointmentID)
+}
+
+// AddAppointmentProcedure records a procedure performed during a completed appointment with
+// an active catalog code
+func (s *procedureService) AddAppointmentProcedure(ctx context.Context, appointmentID, userID uint, procedure *model.AppointmentProcedure) (*model.AppointmentProcedure, error) {
+	appointment, err := s.appointmentRepo.FindByID(ctx, appointmentID)
+	if err != nil {
+		return nil, err
+	}
+	if appointment.Status != model.AppointmentStatusCompleted {
+		return nil, ErrAppointmentNotCompleted
+	}
+
+	procedure.Code = strings.ToUpper(strings.TrimSpace(procedure.Code))
+	code, err := s.repo.FindCode(ctx, procedure.Code)
+	if err != nil || !code.Active {
+		return nil, fmt.Errorf("%w: %q is not an active code in the catalog", ErrInvalidProcedure, procedure.Code)
+	}
+
+	if procedure.Units == 0 {
+		procedure.Units = 1
+	}
+	if procedure.Units < 1 || procedure.Units > maxProcedureUnits {
+		return nil, fmt.Errorf("%w: units must be between 1 and %d", ErrInvalidProcedure, maxProcedureUnits)
+	}
+	if len(procedure.Modifiers) > maxProcedureModifiers {
+		return nil, fmt.Errorf("%w: at most %d modifiers are allowed", ErrInvalidProcedure, maxProcedureModifiers)
+	}
+	for i, modifier := range procedure.Modifiers {
+		procedure.Modifiers[i] = strings.ToUpper(strings.TrimSpace(modifier))
+		if !modifierPattern.MatchString(procedure.Modifiers[i]) {
+			return nil, fmt.Errorf("%w: modifier %q must be two letters or digits", ErrInvalidProcedure, modifier)
+		}
+	}
+
+	procedure.ID = 0
+	procedure.AppointmentID = appointment.ID
+	procedure.RecordedBy = userID
+	procedure.CreatedAt = time.Now()
+	if err := s.repo.CreateProcedure(ctx, procedure); err != nil {
+		return nil, fmt.Errorf("failed to record procedure: %w", err)
+	}
+	procedure.ProcedureCode = *code
+
+	s.logger.Info("Procedure recorded",
+		zap.Uint("appointmentID", appointment.ID),
+		zap.String("code", procedure.Code),
+		zap.Uint("userID", userID))
+	return procedure, nil
+}
+
+// RemoveAppointmentProcedure removes a procedure recorded on an appointment by mistake
+func (s *procedureService) RemoveAppointmentProcedure(ctx context.Context, appointmentID, procedureID uint) error {
+	if err := s.repo.DeleteProcedure(ctx, appointmentID, procedureID); err != nil {
+		return err
+	}
+
+	s.logger.Info("Procedure removed", zap.Uint("appointmentID", appointmentID), zap.Uint("procedureID", procedureID))
+	return nil
+}
+
+// ExportClaims returns a claim line for each procedure of the clinic's completed appointments
+// scheduled between two dates, inclusive, in the clinic's timezone
+func (s *procedureService) ExportClaims(ctx context.Context, orgID uint, fromDate, toDate string) ([]ClaimLine, error) {
+	org, err := s.orgRepo.FindByID(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	loc := utils.LoadLocation(org.Timezone)
+
+	from, err := time.ParseInLocation("2006-01-02", fromDate, loc)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid from date, expected YYYY-MM-DD", ErrInvalidProcedure)
+	}
+	lastDay, err := time.ParseInLocation("2006-01-02", toDate, loc)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid to date, expected YYYY-MM-DD", ErrInvalidProcedure)
+	}
+	to := lastDay.AddDate(0, 0, 1)
+	if !from.Before(to) {
+		return nil, fmt.Errorf("%w: from must not be after to", ErrInvalidProcedure)
+	}
+	if to.After(from.AddDate(0, 0, maxClaimsRangeDays)) {
+		return nil, fmt.Errorf("%w: range must not exceed %d days", ErrInvalidProcedure, maxClaimsRangeDays)
+	}
+
+	scope := repository.AnalyticsScope{OrganizationID: org.ID}
+	if defaultOrg, err := s.orgRepo.FindDefault(ctx); err == nil && defaultOrg.ID == org.ID {
+		scope.IncludeUnassigned = true
+	}
+
+	procedures, err := s.repo.FindClaims(ctx, scope, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load claims: %w", err)
+	}
+
+	lines := make([]ClaimLine, 0, len(procedures))
+	for _, procedure := range procedures {
+		appointment := procedure.Appointment
+		lines = append(lines, ClaimLine{
+			AppointmentID: appointment.PublicID,
+			ServiceDate:   appointment.ScheduledStart.In(loc),
+			PatientID:     appointment.Patient.PublicID,
+			PatientName:   appointment.Patient.User.Name,
+			DateOfBirth:   appointment.Patient.DateOfBirth,
+			DoctorID:      appointment.Doctor.PublicID,
+			DoctorName:    appointment.Doctor.User.Name,
+			LicenseNo:     appointment.Doctor.LicenseNo,
+			System:        procedure.ProcedureCode.System,
+			Code:          procedure.Code,
+			Description:   procedure.ProcedureCode.Description,
+			Modifiers:     procedure.Modifiers,
+			Units:         procedure.Units,
+		})
+	}
+	return lines, nil
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/repository"
+)
+
+// ErrInvalidPublicID is returned for identifiers that are not UUIDs
+var ErrInvalidPublicID = errors.New("invalid ID")
+
+// MaxBatchSize is the maximum number of resources fetched in one batch read
+const MaxBatchSize = 100
+
+// ErrBatchTooLarge is returned when a batch read asks for more than MaxBatchSize resources
+var ErrBatchTooLarge = fmt.Errorf("at most %d IDs can be requested at once", MaxBatchSize)
+
+type publicIDService struct {
+	publicIDRepo repository.PublicIDRepository
+}
+
+// NewPublicIDService creates a new public ID service
+func NewPublicIDService(publicIDRepo repository.PublicIDRepository) PublicIDService {
+	return &publicIDService{
+		publicIDRepo: publicIDRepo,
+	}
+}
+
+// ResolveID returns the internal ID of the resource with the given public ID
+func (s *publicIDService) ResolveID(ctx context.Context, resource model.PublicResource, publicID string) (uint, error) {
+	if !model.IsValidPublicID(publicID) {
+		return 0, fmt.Errorf("%w: %s ID must be a UUID", ErrInvalidPublicID, resource.Name())
+	}
+	return s.publicIDRepo.ResolveID(ctx, resource, publicID)
+}
+
+// batchPublicIDs validates the public IDs of a batch read and drops duplicates, keeping the
+// order they were requested in
+func batchPublicIDs(resource model.PublicResource, publicIDs []string) ([]string, error) {
+	seen := make(map[string]bool, len(publicIDs))
+	unique := make([]string, 0, len(publicIDs))
+	for _, id := range publicIDs {
+		if !model.IsValidPublicID(id) {
+			return nil, fmt.Errorf("%w: %s ID must be a UUID", ErrInvalidPublicID, resource.Name())
+		}
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	if len(unique) > MaxBatchSize {
+		return nil, ErrBatchTooLarge
+	}
+	return unique, nil
+}
@@ -0,0 +1,520 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/repository"
+	"github.com/whitewalker-sa/ehass/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// maxSeriesOccurrences caps how many appointments one series books
+const maxSeriesOccurrences = 52
+
+var (
+	// ErrInvalidSeries is returned when a recurring series request fails validation
+	ErrInvalidSeries = errors.New("invalid appointment series")
+	// ErrNotOwnSeries is returned when a user other than the series patient, their guardian or the
+	// series doctor views or changes a series without managing appointments
+	ErrNotOwnSeries = errors.New("only the patient or doctor of an appointment series can view or change it")
+)
+
+type recurringAppointmentService struct {
+	seriesRepo       repository.RecurringAppointmentRepository
+	doctorRepo       repository.DoctorRepository
+	patientRepo      repository.PatientRepository
+	typeRepo         repository.AppointmentTypeRepository
+	availabilityRepo repository.AvailabilityRepository
+	orgService       OrganizationService
+	noShowService    NoShowService
+	slotHolds        SlotHoldService
+	slotCache        *SlotCache
+	bookingLocks     *BookingLocks
+	logger           *zap.Logger
+}
+
+// NewRecurringAppointmentService creates a new recurring appointment service
+func NewRecurringAppointmentService(
+	seriesRepo repository.RecurringAppointmentRepository,
+	doctorRepo repository.DoctorRepository,
+	patientRepo repository.PatientRepository,
+	typeRepo repository.AppointmentTypeRepository,
+	availabilityRepo repository.AvailabilityRepository,
+	orgService OrganizationService,
+	noShowService NoShowService,
+	slotHolds SlotHoldService,
+	slotCache *SlotCache,
+	bookingLocks *BookingLocks,
+	logger *zap.Logger,
+) RecurringAppointmentService {
+	return &recurringAppointmentService{
+		seriesRepo:       seriesRepo,
+		doctorRepo:       doctorRepo,
+		patientRepo:      patientRepo,
+		typeRepo:         typeRepo,
+		availabilityRepo: availabilityRepo,
+		orgService:       orgService,
+		noShowService:    noShowService,
+		slotHolds:        slotHolds,
+		slotCache:        slotCache,
+		bookingLocks:     bookingLocks,
+		logger:           logger,
+	}
+}
+
+// CreateSeries books occurrences appointments repeating at frequency, the first at date and
+// time. Every occurrence must satisfy the same rules as a single booking; if any one does not,
+// nothing is booked. The user signed in as userID must be the patient or their guardian, unless
+// bookingStaff is set.
+func (s *recurringAppointmentService) CreateSeries(ctx context.Context, userID uint, bookingStaff bool, patientID, doctorID, appointmentTypeID uint, date, timeStr string, frequency model.RecurrenceFrequency, occurrences int, reason string, intakeAnswers map[string]string) (*model.RecurringAppointment, error) {
+	if !bookingStaff {
+		if err := authorizeBooking(ctx, s.patientRepo, userID, patientID); err != nil {
+			return nil, err
+		}
+	}
+	if !frequency.IsValid() {
+		return nil, fmt.Errorf("%w: frequency must be weekly, biweekly or monthly", ErrInvalidSeries)
+	}
+	if occurrences < 2 || occurrences > maxSeriesOccurrences {
+		return nil, fmt.Errorf("%w: occurrences must be between 2 and %d", ErrInvalidSeries, maxSeriesOccurrences)
+	}
+	first, err := parseDateTime(date, timeStr)
+	if err != nil {
+		return nil, errors.New("invalid date or time format")
+	}
+
+	org, err := s.orgService.GetDoctorOrganization(ctx, doctorID)
+	if err != nil {
+		return nil, err
+	}
+	doctor, err := s.doctorRepo.FindByID(ctx, doctorID)
+	if err != nil {
+		return nil, err
+	}
+	booking, err := resolveBookingType(ctx, s.typeRepo, org, doctor, appointmentTypeID, intakeAnswers)
+	if err != nil {
+		return nil, err
+	}
+	patient, err := s.patientRepo.FindByID(ctx, patientID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	series := &model.RecurringAppointment{
+		PublicID:          model.NewPublicID(),
+		PatientID:         patientID,
+		DoctorID:          doctorID,
+		AppointmentTypeID: booking.typeID,
+		Frequency:         frequency,
+		Occurrences:       occurrences,
+		Reason:            reason,
+		CreatedAt:         now,
+		UpdatedAt:         now,
+	}
+
+	// Count occurrences in the clinic's timezone so they keep their local time
+	firstLocal := first.In(utils.LoadLocation(org.Timezone))
+	bookings := make([]repository.SeriesBooking, 0, occurrences)
+	for n := 0; n < occurrences; n++ {
+		start := frequency.OccurrenceStart(firstLocal, n).UTC()
+		end := start.Add(booking.length)
+		if err := s.checkOccurrence(ctx, org, patientID, doctorID, start, end, now); err != nil {
+			return nil, err
+		}
+
+		appointment := &model.Appointment{
+			PublicID:          model.NewPublicID(),
+			PatientID:         patientID,
+			DoctorID:          doctorID,
+			AppointmentTypeID: booking.typeID,
+			Modality:          booking.modality,
+			IntakeAnswers:     intakeAnswers,
+			ScheduledStart:    start,
+			ScheduledEnd:      end,
+			Reason:            reason,
+			Status:            model.AppointmentStatusPending,
+			CreatedAt:         now,
+			UpdatedAt:         now,
+		}
+		booking.startChecklist(appointment, org)
+		autoConfirm(appointment, doctor)
+		data := newAppointmentEventData(appointment)
+		data.PatientID = patient.PublicID
+		data.DoctorID = doctor.PublicID
+		data.SeriesID = series.PublicID
+		events, err := seriesEvents(model.EventAppointmentBooked, data, n == 0)
+		if err != nil {
+			return nil, err
+		}
+		bookings = append(bookings, repository.SeriesBooking{Appointment: appointment, Events: events})
+	}
+
+	err = s.bookingLocks.Do(ctx, doctorID, func() error {
+		return s.seriesRepo.Create(ctx, series, bookings)
+	})
+	if err != nil {
+		if errors.Is(err, ErrScheduleConflict) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to create appointment series: %w", err)
+	}
+	s.slotHolds.ReleaseSlot(ctx, doctorID, bookings[0].Appointment.ScheduledStart)
+	s.slotCache.Invalidate(doctorID)
+
+	// Screen the first occurrence; a high-risk patient confirms the series once
+	if err := s.noShowService.ScreenBooking(ctx, bookings[0].Appointment); err != nil {
+		s.logger.Warn("Failed to screen booking for no-show risk", zap.Uint("seriesID", series.ID), zap.Error(err))
+	}
+
+	s.logger.Info("Appointment series booked",
+		zap.Uint("seriesID", series.ID),
+		zap.String("frequency", string(frequency)),
+		zap.Int("occurrences", occurrences))
+	return s.seriesRepo.FindByID(ctx, series.ID)
+}
+
+// GetSeries gets a series with its occurrences for the user signed in as userID, who must be its
+// patient, their guardian or its doctor, unless manage is set
+func (s *recurringAppointmentService) GetSeries(ctx context.Context, id, userID uint, manage bool) (*model.RecurringAppointment, error) {
+	return s.findSeries(ctx, id, userID, manage)
+}
+
+// ListOccurrences lists every occurrence of a series in date order, including those cancelled
+// or moved on their own. The caller is checked as in GetSeries.
+func (s *recurringAppointmentService) ListOccurrences(ctx context.Context, id, userID uint, manage bool) ([]*model.Appointment, error) {
+	series, err := s.findSeries(ctx, id, userID, manage)
+	if err != nil {
+		return nil, err
+	}
+	return series.Appointments, nil
+}
+
+// UpdateSeries moves the upcoming occurrences of a series. The first of them moves to date and
+// time, and the others move by the same number of days to the same time of day. reason, if
+// given, replaces their reason. Occurrences changed on their own keep their changes.
+//
+// If from is set, only that occurrence and the ones after it change, and they are split off into
+// a new series, which is returned; from itself changes even if it was changed on its own before.
+// The caller is checked as in GetSeries.
+func (s *recurringAppointmentService) UpdateSeries(ctx context.Context, id, from, userID uint, manage bool, date, timeStr, reason string) (*model.RecurringAppointment, error) {
+	series, err := s.findSeries(ctx, id, userID, manage)
+	if err != nil {
+		return nil, err
+	}
+	if series.CancelledAt != nil {
+		return nil, errors.New("cannot update a cancelled appointment series")
+	}
+
+	now := time.Now()
+	upcoming := upcomingOccurrences(series, now)
+	var tail *model.RecurringAppointment
+	var split []*model.Appointment
+	if from != 0 {
+		if _, err := seriesOccurrence(upcoming, from); err != nil {
+			return nil, err
+		}
+		upcoming = occurrencesFrom(upcoming, from)
+		if len(occurrencesFrom(series.Appointments, from)) < len(series.Appointments) {
+			split = occurrencesFrom(series.Appointments, from)
+			tail = &model.RecurringAppointment{
+				PublicID:          model.NewPublicID(),
+				PatientID:         series.PatientID,
+				DoctorID:          series.DoctorID,
+				AppointmentTypeID: series.AppointmentTypeID,
+				Frequency:         series.Frequency,
+				Occurrences:       len(split),
+				Reason:            series.Reason,
+				CreatedAt:         now,
+				UpdatedAt:         now,
+			}
+		}
+	}
+	changing := make([]*model.Appointment, 0, len(upcoming))
+	for _, appointment := range upcoming {
+		if appointment.SeriesException == nil || appointment.ID == from {
+			changing = append(changing, appointment)
+		}
+	}
+	if len(changing) == 0 {
+		return nil, errors.New("appointment series has no upcoming appointments")
+	}
+
+	var org *model.Organization
+	var loc *time.Location
+	var dayShift int
+	var newStart time.Time
+	if date != "" && timeStr != "" {
+		newStart, err = parseDateTime(date, timeStr)
+		if err != nil {
+			return nil, errors.New("invalid date or time format")
+		}
+		org, err = s.orgService.GetDoctorOrganization(ctx, series.DoctorID)
+		if err != nil {
+			return nil, err
+		}
+		loc = utils.LoadLocation(org.Timezone)
+		newStart = newStart.In(loc)
+		dayShift = daysBetween(changing[0].ScheduledStart.In(loc), newStart)
+	}
+
+	publicID := series.PublicID
+	if tail != nil {
+		publicID = tail.PublicID
+	}
+	changed := make(map[uint][]*model.OutboxEvent, len(changing))
+	for n, appointment := range changing {
+		previousStart := appointment.ScheduledStart
+		if org != nil {
+			local := appointment.ScheduledStart.In(loc)
+			start := time.Date(local.Year(), local.Month(), local.Day()+dayShift,
+				newStart.Hour(), newStart.Minute(), 0, 0, loc).UTC()
+			end := start.Add(appointment.ScheduledEnd.Sub(appointment.ScheduledStart))
+			if err := s.checkOccurrence(ctx, org, series.PatientID, series.DoctorID, start, end, now); err != nil {
+				return nil, err
+			}
+			appointment.ScheduledStart = start
+			appointment.ScheduledEnd = end
+		}
+		if reason != "" {
+			appointment.Reason = reason
+		}
+		appointment.UpdatedAt = now
+
+		data := newAppointmentEventData(appointment)
+		data.SeriesID = publicID
+		eventType := model.EventAppointmentUpdated
+		if !appointment.ScheduledStart.Equal(previousStart) {
+			eventType = model.EventAppointmentRescheduled
+			data.PreviousStart = &previousStart
+		}
+		events, err := seriesEvents(eventType, data, n == 0)
+		if err != nil {
+			return nil, err
+		}
+		changed[appointment.ID] = events
+	}
+
+	// A split series takes every occurrence from the split on, changed or not
+	bookings := []repository.SeriesBooking{}
+	if tail != nil {
+		for _, appointment := range split {
+			bookings = append(bookings, repository.SeriesBooking{Appointment: appointment, Events: changed[appointment.ID]})
+		}
+		if reason != "" {
+			tail.Reason = reason
+		}
+		series.Occurrences -= len(split)
+	} else {
+		for _, appointment := range changing {
+			bookings = append(bookings, repository.SeriesBooking{Appointment: appointment, Events: changed[appointment.ID]})
+		}
+		if reason != "" {
+			series.Reason = reason
+		}
+	}
+	series.UpdatedAt = now
+	err = s.bookingLocks.Do(ctx, series.DoctorID, func() error {
+		if tail != nil {
+			return s.seriesRepo.Split(ctx, series, tail, bookings)
+		}
+		return s.seriesRepo.Save(ctx, series, bookings)
+	})
+	if err != nil {
+		if errors.Is(err, ErrScheduleConflict) {
+			return nil, err
+		}
+		s.logger.Error("Failed to update appointment series", zap.Error(err))
+		return nil, errors.New("failed to update appointment series")
+	}
+	s.slotCache.Invalidate(series.DoctorID)
+
+	if tail != nil {
+		s.logger.Info("Appointment series split",
+			zap.Uint("seriesID", series.ID),
+			zap.Uint("newSeriesID", tail.ID),
+			zap.Int("occurrences", tail.Occurrences))
+		return s.seriesRepo.FindByID(ctx, tail.ID)
+	}
+	return s.seriesRepo.FindByID(ctx, series.ID)
+}
+
+// CancelSeries cancels the upcoming occurrences of a series, including those changed on their
+// own. Occurrences starting within the hour are kept, as they could not be cancelled on their own
+// either. If from is set, only that occurrence and the ones after it are cancelled, and the
+// series stays open for the ones before. The caller is checked as in GetSeries.
+func (s *recurringAppointmentService) CancelSeries(ctx context.Context, id, from, userID uint, manage bool) error {
+	series, err := s.findSeries(ctx, id, userID, manage)
+	if err != nil {
+		return err
+	}
+	if series.CancelledAt != nil {
+		return errors.New("appointment series is already cancelled")
+	}
+
+	now := time.Now()
+	upcoming := upcomingOccurrences(series, now.Add(time.Hour))
+	wholeSeries := true
+	if from != 0 {
+		if _, err := seriesOccurrence(upcoming, from); err != nil {
+			return err
+		}
+		upcoming = occurrencesFrom(upcoming, from)
+		wholeSeries = len(occurrencesFrom(series.Appointments, from)) == len(series.Appointments)
+	}
+
+	bookings := []repository.SeriesBooking{}
+	for _, appointment := range upcoming {
+		eventType, err := transitionAppointment(appointment, model.AppointmentStatusCancelled, now)
+		if err != nil {
+			return err
+		}
+
+		data := newAppointmentEventData(appointment)
+		data.SeriesID = series.PublicID
+		events, err := seriesEvents(eventType, data, len(bookings) == 0)
+		if err != nil {
+			return err
+		}
+		bookings = append(bookings, repository.SeriesBooking{Appointment: appointment, Events: events})
+	}
+
+	if wholeSeries {
+		series.CancelledAt = &now
+	}
+	series.UpdatedAt = now
+	if err := s.seriesRepo.Save(ctx, series, bookings); err != nil {
+		return fmt.Errorf("failed to cancel appointment series: %w", err)
+	}
+	s.slotCache.Invalidate(series.DoctorID)
+
+	s.logger.Info("Appointment series cancelled",
+		zap.Uint("seriesID", series.ID),
+		zap.Uint("from", from),
+		zap.Int("cancelled", len(bookings)))
+	return nil
+}
+
+// findSeries finds a series the user signed in as userID may view and change: one they are the
+// patient, the patient's guardian or the doctor of. With manage set, any series.
+func (s *recurringAppointmentService) findSeries(ctx context.Context, id, userID uint, manage bool) (*model.RecurringAppointment, error) {
+	series, err := s.seriesRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if manage || series.Doctor.UserID == userID {
+		return series, nil
+	}
+	if err := authorizeBooking(ctx, s.patientRepo, userID, series.PatientID); err != nil {
+		if errors.Is(err, ErrNotOwnBooking) {
+			return nil, ErrNotOwnSeries
+		}
+		return nil, err
+	}
+	return series, nil
+}
+
+// checkOccurrence applies the single booking rules to one occurrence, naming it in the error
+func (s *recurringAppointmentService) checkOccurrence(ctx context.Context, org *model.Organization, patientID, doctorID uint, start, end, now time.Time) error {
+	err := checkBookingRules(org, start, end, now)
+	if err == nil {
+		err = checkAvailability(ctx, s.availabilityRepo, org, doctorID, start, end)
+	}
+	if err == nil {
+		err = s.slotHolds.CheckSlot(ctx, patientID, doctorID, start)
+	}
+	if err != nil {
+		return fmt.Errorf("occurrence on %s: %w", start.Format("2006-01-02"), err)
+	}
+	return nil
+}
+
+// upcomingOccurrences returns the pending and confirmed occurrences of a series starting after
+// from, in date order
+func upcomingOccurrences(series *model.RecurringAppointment, from time.Time) []*model.Appointment {
+	upcoming := []*model.Appointment{}
+	for _, appointment := range series.Appointments {
+		if appointment.ScheduledStart.After(from) &&
+			(appointment.Status == model.AppointmentStatusPending || appointment.Status == model.AppointmentStatusConfirmed) {
+			upcoming = append(upcoming, appointment)
+		}
+	}
+	return upcoming
+}
+
+// seriesOccurrence finds the occurrence with ID id among occurrences
+func seriesOccurrence(occurrences []*model.Appointment, id uint) (*model.Appointment, error) {
+	for _, appointment := range occurrences {
+		if appointment.ID == id {
+			return appointment, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: the appointment is not an upcoming occurrence of the series", ErrInvalidSeries)
+}
+
+// occurrencesFrom returns the occurrence with ID from and those starting after it, in date
+// order. Start times rather than IDs decide, as occurrences moved on their own no longer start
+// in the order the series booked them.
+func occurrencesFrom(occurrences []*model.Appointment, from uint) []*model.Appointment {
+	var start time.Time
+	for _, appointment := range occurrences {
+		if appointment.ID == from {
+			start = appointment.ScheduledStart
+		}
+	}
+	following := []*model.Appointment{}
+	for _, appointment := range occurrences {
+		if appointment.ID == from || appointment.ScheduledStart.After(start) {
+			following = append(following, appointment)
+		}
+	}
+	sort.SliceStable(following, func(i, j int) bool {
+		return following[i].ScheduledStart.Before(following[j].ScheduledStart)
+	})
+	return following
+}
+
+// markSeriesException records that an occurrence of a series was changed on its own, so changes
+// to the whole series leave it alone. originalStart is its start before the change. An edit does
+// not hide an earlier move or cancellation.
+func markSeriesException(appointment *model.Appointment, kind model.SeriesExceptionKind, originalStart, now time.Time) {
+	if appointment.SeriesID == nil {
+		return
+	}
+	if appointment.SeriesException == nil {
+		appointment.SeriesException = &model.RecurringAppointmentException{OriginalStart: originalStart, CreatedAt: now}
+	} else if kind == model.SeriesExceptionEdited {
+		return
+	}
+	appointment.SeriesException.Kind = kind
+	appointment.SeriesException.UpdatedAt = now
+}
+
+// seriesEvents returns the outbox rows for an event on one occurrence. Only the first occurrence
+// is emailed, so the patient and doctor get one message for the series instead of one per
+// appointment.
+func seriesEvents(eventType string, data appointmentEventData, email bool) ([]*model.OutboxEvent, error) {
+	events, err := appointmentEvents(eventType, data)
+	if err != nil || email {
+		return events, err
+	}
+	published := events[:0]
+	for _, event := range events {
+		if event.Destination != model.OutboxDestinationEmail && event.Destination != model.OutboxDestinationDoctorEmail {
+			published = append(published, event)
+		}
+	}
+	return published, nil
+}
+
+// daysBetween returns the number of calendar days from a to b, both in the same location
+func daysBetween(a, b time.Time) int {
+	dayA := time.Date(a.Year(), a.Month(), a.Day(), 0, 0, 0, 0, time.UTC)
+	dayB := time.Date(b.Year(), b.Month(), b.Day(), 0, 0, 0, 0, time.UTC)
+	return int(dayB.Sub(dayA).Hours() / 24)
+}
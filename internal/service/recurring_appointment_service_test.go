@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"go.uber.org/zap"
+)
+
+func TestOccurrencesFromFollowsStartTimes(t *testing.T) {
+	week := 7 * 24 * time.Hour
+	first := time.Date(2026, 11, 2, 9, 0, 0, 0, time.UTC)
+	occurrences := []*model.Appointment{
+		{ID: 1, ScheduledStart: first},
+		{ID: 2, ScheduledStart: first.Add(week)},
+		// Moved on its own to after the last occurrence
+		{ID: 3, ScheduledStart: first.Add(4 * week)},
+		{ID: 4, ScheduledStart: first.Add(3 * week)},
+	}
+
+	ids := func(appointments []*model.Appointment) []uint {
+		ids := make([]uint, len(appointments))
+		for i, appointment := range appointments {
+			ids[i] = appointment.ID
+		}
+		return ids
+	}
+	if got := ids(occurrencesFrom(occurrences, 2)); !slices.Equal(got, []uint{2, 4, 3}) {
+		t.Errorf("from 2: got %v, want [2 4 3]", got)
+	}
+	if got := ids(occurrencesFrom(occurrences, 4)); !slices.Equal(got, []uint{4, 3}) {
+		t.Errorf("from 4: got %v, want [4 3]", got)
+	}
+	if got := ids(occurrencesFrom(occurrences, 3)); !slices.Equal(got, []uint{3}) {
+		t.Errorf("from the moved occurrence: got %v, want [3]", got)
+	}
+}
+
+func TestSeriesKeepsToItsPatientAndDoctor(t *testing.T) {
+	clinic := newTestClinic()
+	// Doctor 1 sees the child patient 11 every week
+	series := &model.RecurringAppointment{
+		ID:        7,
+		PatientID: 11,
+		DoctorID:  1,
+		Patient:   *clinic.patients.patients[11],
+		Doctor:    *clinic.doctors.doctors[1],
+	}
+	s := NewRecurringAppointmentService(&fakeSeriesRepo{series: map[uint]*model.RecurringAppointment{7: series}},
+		clinic.doctors, clinic.patients, nil, nil, nil, nil, nil, nil, nil, zap.NewNop())
+
+	tests := []struct {
+		name    string
+		userID  uint
+		manage  bool
+		wantErr error
+	}{
+		{name: "series doctor", userID: 1},
+		{name: "patient", userID: 11},
+		{name: "guardian", userID: 10},
+		{name: "staff managing appointments", userID: 99, manage: true},
+		{name: "another doctor", userID: 2, wantErr: ErrNotOwnSeries},
+		{name: "another patient", userID: 12, wantErr: ErrNotOwnSeries},
+		{name: "admin without the permission", userID: 99, wantErr: ErrNotOwnSeries},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.GetSeries(context.Background(), 7, tt.userID, tt.manage)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("GetSeries: got %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil {
+				return
+			}
+			// Changes are refused before anything is saved
+			if err := s.CancelSeries(context.Background(), 7, 0, tt.userID, tt.manage); !errors.Is(err, tt.wantErr) {
+				t.Errorf("CancelSeries: got %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/config"
+	"github.com/whitewalker-sa/ehass/internal/repository"
+	"go.uber.org/zap"
+)
+
+// Reminder escalation actions
+const (
+	EscalationSMS          = "sms"           // Remind again by SMS
+	EscalationEmail        = "email"         // Remind again by email
+	EscalationOtherChannel = "other_channel" // Remind again on the channel no reminder was delivered on
+	EscalationFollowUp     = "follow_up"     // Flag the appointment for the front desk to call the patient
+)
+
+// ReminderScheduler periodically sends reminders for upcoming appointments, escalates the ones
+// the patient has not confirmed and resends by SMS the reminder emails that bounced after they
+// were sent
+type ReminderScheduler struct {
+	appointmentRepo     repository.AppointmentRepository
+	notificationService NotificationService
+	leadTime            time.Duration
+	interval            time.Duration
+	escalation          []config.ReminderEscalationConfig
+	monitor             *JobMonitor
+	logger              *zap.Logger
+}
+
+// NewReminderScheduler creates a new reminder scheduler. Escalation steps must be ordered by
+// their delay, which must be positive and grow from one step to the next.
+func NewReminderScheduler(
+	appointmentRepo repository.AppointmentRepository,
+	notificationService NotificationService,
+	leadTime time.Duration,
+	interval time.Duration,
+	escalation []config.ReminderEscalationConfig,
+	monitor *JobMonitor,
+	logger *zap.Logger,
+) (*ReminderScheduler, error) {
+	if leadTime <= 0 {
+		leadTime = 24 * time.Hour
+	}
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+	for i, step := range escalation {
+		switch step.Action {
+		case EscalationSMS, EscalationEmail, EscalationOtherChannel, EscalationFollowUp:
+		default:
+			return nil, fmt.Errorf("reminder escalation step %d: unknown action %q", i+1, step.Action)
+		}
+		if step.After <= 0 || (i > 0 && step.After <= escalation[i-1].After) {
+			return nil, fmt.Errorf("reminder escalation step %d: after must be positive and later than the previous step", i+1)
+		}
+	}
+
+	s := &ReminderScheduler{
+		appointmentRepo:     appointmentRepo,
+		notificationService: notificationService,
+		leadTime:            leadTime,
+		interval:            interval,
+		escalation:          escalation,
+		monitor:             monitor,
+		logger:              logger,
+	}
+	monitor.Register(JobReminders, interval, s.RunOnce)
+	return s, nil
+}
+
+// Start sends reminders in the background until the returned function is called
+func (s *ReminderScheduler) Start() func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			_ = s.monitor.Do(ctx, JobReminders, s.RunOnce)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		wg.Wait()
+	}
+}
+
+// RunOnce sends the reminders that are due, escalates unconfirmed ones and retries bounced
+// reminder emails. Reminders that cannot be delivered are logged and do not fail the run; failing
+// to load or update them does.
+func (s *ReminderScheduler) RunOnce(ctx context.Context) error {
+	now := time.Now()
+
+	due, err := s.appointmentRepo.FindDueReminders(ctx, now, now.Add(s.leadTime))
+	if err != nil {
+		s.logger.Error("Failed to find due reminders", zap.Error(err))
+		return fmt.Errorf("failed to find due reminders: %w", err)
+	}
+
+	var runErr error
+
+	for _, appointment := range due {
+		if err := s.notificationService.SendAppointmentReminder(ctx, appointment); err != nil {
+			s.logger.Warn("Failed to deliver appointment reminder",
+				zap.Uint("appointmentID", appointment.ID),
+				zap.Error(err))
+		}
+		// Reminders are attempted once so a failing channel does not resend every interval
+		if err := s.appointmentRepo.MarkReminderSent(ctx, appointment.ID, now); err != nil {
+			s.logger.Error("Failed to mark reminder sent", zap.Uint("appointmentID", appointment.ID), zap.Error(err))
+			runErr = fmt.Errorf("failed to mark reminder sent: %w", err)
+		}
+	}
+
+	if err := s.escalate(ctx, now); err != nil {
+		runErr = err
+	}
+
+	// Bounce reports arrive after sending, so look back over a full lead time
+	resent, err := s.notificationService.RetryBouncedReminders(ctx, now.Add(-s.leadTime))
+	if err != nil {
+		s.logger.Error("Failed to retry bounced reminders", zap.Error(err))
+		return err
+	} else if resent > 0 {
+		s.logger.Info("Resent bounced reminders by SMS", zap.Int("count", resent))
+	}
+	return runErr
+}
+
+// escalate takes the escalation steps that are due for upcoming appointments whose patient has
+// not confirmed they will attend. Steps are visited last first so an appointment advances at most
+// one step per run, even if the scheduler was down while several came due. A step is claimed
+// before it is taken, so it is taken once even if it fails.
+func (s *ReminderScheduler) escalate(ctx context.Context, now time.Time) error {
+	var runErr error
+
+	for step := len(s.escalation) - 1; step >= 0; step-- {
+		rule := s.escalation[step]
+		due, err := s.appointmentRepo.FindUnconfirmedReminders(ctx, step, now.Add(-rule.After), now)
+		if err != nil {
+			s.logger.Error("Failed to find unconfirmed reminders", zap.Error(err))
+			return fmt.Errorf("failed to find unconfirmed reminders: %w", err)
+		}
+
+		for _, appointment := range due {
+			claimed, err := s.appointmentRepo.ClaimReminderEscalation(ctx, appointment.ID, step)
+			if err != nil {
+				s.logger.Error("Failed to claim reminder escalation", zap.Uint("appointmentID", appointment.ID), zap.Error(err))
+				runErr = fmt.Errorf("failed to claim reminder escalation: %w", err)
+				continue
+			}
+			if !claimed {
+				continue
+			}
+
+			switch rule.Action {
+			case EscalationFollowUp:
+				if err := s.appointmentRepo.FlagFollowUp(ctx, appointment.ID, now); err != nil {
+					s.logger.Error("Failed to flag appointment for follow-up", zap.Uint("appointmentID", appointment.ID), zap.Error(err))
+					runErr = fmt.Errorf("failed to flag appointment for follow-up: %w", err)
+					continue
+				}
+				s.logger.Info("Unconfirmed appointment flagged for front-desk follow-up", zap.Uint("appointmentID", appointment.ID))
+			default:
+				channel := rule.Action
+				if channel == EscalationOtherChannel {
+					channel = ""
+				}
+				if err := s.notificationService.EscalateReminder(ctx, appointment, channel); err != nil {
+					s.logger.Warn("Failed to deliver escalated reminder",
+						zap.Uint("appointmentID", appointment.ID),
+						zap.Error(err))
+				}
+			}
+		}
+	}
+	return runErr
+}
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/repository"
+	"go.uber.org/zap"
+)
+
+// maxReviewLength caps the length of a review comment or response in characters
+const maxReviewLength = 2000
+
+var (
+	// ErrReviewNotAllowed is returned when a patient reviews a doctor without a recent completed
+	// visit with them
+	ErrReviewNotAllowed = errors.New("only patients with a recent completed visit with this doctor can review them")
+	// ErrAlreadyReviewed is returned when an appointment already has a review
+	ErrAlreadyReviewed = errors.New("this appointment has already been reviewed")
+	// ErrReviewLimitReached is returned when a patient has written as many reviews as allowed in a day
+	ErrReviewLimitReached = errors.New("too many reviews written today, try again tomorrow")
+	// ErrNotOwnReview is returned when a doctor responds to a review of another doctor
+	ErrNotOwnReview = errors.New("doctors can only respond to their own reviews")
+)
+
+type reviewService struct {
+	repo            repository.ReviewRepository
+	appointmentRepo repository.AppointmentRepository
+	doctorRepo      repository.DoctorRepository
+	patientRepo     repository.PatientRepository
+	window          time.Duration
+	dailyLimit      int
+	logger          *zap.Logger
+}
+
+// NewReviewService creates a new doctor review service. Visits can be reviewed for window after
+// they end, and a patient can write at most dailyLimit reviews in 24 hours.
+func NewReviewService(
+	repo repository.ReviewRepository,
+	appointmentRepo repository.AppointmentRepository,
+	doctorRepo repository.DoctorRepository,
+	patientRepo repository.PatientRepository,
+	window time.Duration,
+	dailyLimit int,
+	logger *zap.Logger,
+) ReviewService {
+	return &reviewService{
+		repo:            repo,
+		appointmentRepo: appointmentRepo,
+		doctorRepo:      doctorRepo,
+		patientRepo:     patientRepo,
+		window:          window,
+		dailyLimit:      dailyLimit,
+		logger:          logger,
+	}
+}
+
+// CreateReview reviews a doctor as the patient signed in as userID. The review is about one of
+// the patient's completed appointments with the doctor that ended within the review window, and
+// each appointment can be reviewed once.
+func (s *reviewService) CreateReview(ctx context.Context, userID, doctorID, appointmentID uint, rating int, comment string) (*model.DoctorReview, error) {
+	if rating < 1 || rating > 5 {
+		return nil, errors.New("rating must be between 1 and 5")
+	}
+	comment = strings.TrimSpace(comment)
+	if len([]rune(comment)) > maxReviewLength {
+		return nil, fmt.Errorf("comment must be at most %d characters", maxReviewLength)
+	}
+
+	patient, err := s.patientRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, ErrReviewNotAllowed
+	}
+	appointment, err := s.appointmentRepo.FindByID(ctx, appointmentID)
+	if err != nil {
+		return nil, err
+	}
+	if appointment.PatientID != patient.ID || appointment.DoctorID != doctorID ||
+		appointment.Status != model.AppointmentStatusCompleted ||
+		time.Since(appointment.ScheduledEnd) > s.window {
+		return nil, ErrReviewNotAllowed
+	}
+
+	reviewed, err := s.repo.ExistsForAppointment(ctx, appointment.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for an existing review: %w", err)
+	}
+	if reviewed {
+		return nil, ErrAlreadyReviewed
+	}
+	written, err := s.repo.CountByPatientSince(ctx, patient.ID, time.Now().Add(-24*time.Hour))
+	if err != nil {
+		return nil, fmt.Errorf("failed to count recent reviews: %w", err)
+	}
+	if written >= int64(s.dailyLimit) {
+		return nil, ErrReviewLimitReached
+	}
+
+	review := &model.DoctorReview{
+		DoctorID:      doctorID,
+		PatientID:     patient.ID,
+		Patient:       *patient,
+		AppointmentID: appointment.ID,
+		Rating:        rating,
+		Comment:       comment,
+	}
+	if err := s.repo.Create(ctx, review); err != nil {
+		return nil, fmt.Errorf("failed to save review: %w", err)
+	}
+
+	s.logger.Info("Doctor reviewed",
+		zap.Uint("doctorID", doctorID),
+		zap.Uint("appointmentID", appointment.ID),
+		zap.Int("rating", rating))
+	return review, nil
+}
+
+// ListReviews lists a doctor's reviews, most recent first, with their average rating
+func (s *reviewService) ListReviews(ctx context.Context, doctorID uint, page, pageSize int) ([]*model.DoctorReview, int64, float64, error) {
+	if _, err := s.doctorRepo.FindByID(ctx, doctorID); err != nil {
+		return nil, 0, 0, err
+	}
+	offset := (page - 1) * pageSize
+	reviews, total, err := s.repo.FindByDoctorID(ctx, doctorID, pageSize, offset)
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("failed to list reviews: %w", err)
+	}
+	average, err := s.repo.AverageRating(ctx, doctorID)
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("failed to get average rating: %w", err)
+	}
+	return reviews, total, average, nil
+}
+
+// RespondToReview sets the response of the doctor signed in as userID to one of their reviews,
+// replacing any earlier response
+func (s *reviewService) RespondToReview(ctx context.Context, userID, doctorID, reviewID uint, response string) (*model.DoctorReview, error) {
+	response = strings.TrimSpace(response)
+	if response == "" {
+		return nil, errors.New("response is required")
+	}
+	if len([]rune(response)) > maxReviewLength {
+		return nil, fmt.Errorf("response must be at most %d characters", maxReviewLength)
+	}
+
+	doctor, err := s.doctorRepo.FindByUserID(ctx, userID)
+	if err != nil || doctor.ID != doctorID {
+		return nil, ErrNotOwnReview
+	}
+	review, err := s.repo.FindByID(ctx, reviewID)
+	if err != nil {
+		return nil, err
+	}
+	if review.DoctorID != doctor.ID {
+		return nil, ErrNotOwnReview
+	}
+
+	now := time.Now()
+	review.Response = response
+	review.RespondedAt = &now
+	if err := s.repo.Update(ctx, review); err != nil {
+		return nil, fmt.Errorf("failed to save review response: %w", err)
+	}
+	return review, nil
+}
@@ -0,0 +1,200 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/repository"
+	"go.uber.org/zap"
+)
+
+// roleNamePattern restricts custom role names to lowercase identifiers such as billing_clerk
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{1,49}$`)
+
+type roleService struct {
+	roleRepo repository.CustomRoleRepository
+	userRepo repository.UserRepository
+	logger   *zap.Logger
+}
+
+// NewRoleService creates a new role service
+func NewRoleService(roleRepo repository.CustomRoleRepository, userRepo repository.UserRepository, logger *zap.Logger) RoleService {
+	return &roleService{
+		roleRepo: roleRepo,
+		userRepo: userRepo,
+		logger:   logger,
+	}
+}
+
+// CreateRole creates a custom role composed of the given permissions
+func (s *roleService) CreateRole(ctx context.Context, name, description string, permissions []model.Permission) (*model.CustomRole, error) {
+	if !roleNamePattern.MatchString(name) {
+		return nil, errors.New("role name must be 2-50 lowercase letters, digits or underscores")
+	}
+	if model.IsBuiltInRole(name) {
+		return nil, fmt.Errorf("%s is a built-in role", name)
+	}
+
+	permissions, err := normalizePermissions(permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	role := &model.CustomRole{
+		Name:        name,
+		Description: description,
+		Permissions: permissions,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+	if err := s.roleRepo.Create(ctx, role); err != nil {
+		return nil, fmt.Errorf("failed to create role: %w", err)
+	}
+
+	s.logger.Info("Custom role created", zap.String("role", name), zap.Any("permissions", permissions))
+	return role, nil
+}
+
+// GetRole gets a custom role by ID
+func (s *roleService) GetRole(ctx context.Context, id uint) (*model.CustomRole, error) {
+	return s.roleRepo.FindByID(ctx, id)
+}
+
+// ListRoles lists all custom roles
+func (s *roleService) ListRoles(ctx context.Context) ([]*model.CustomRole, error) {
+	return s.roleRepo.FindAll(ctx)
+}
+
+// UpdateRole replaces the description and permissions of a custom role. The change
+// applies to every user holding the role on their next request.
+func (s *roleService) UpdateRole(ctx context.Context, id uint, description string, permissions []model.Permission) (*model.CustomRole, error) {
+	role, err := s.roleRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions, err = normalizePermissions(permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	role.Description = description
+	role.Permissions = permissions
+	role.UpdatedAt = time.Now()
+	if err := s.roleRepo.Update(ctx, role); err != nil {
+		return nil, fmt.Errorf("failed to update role: %w", err)
+	}
+
+	s.logger.Info("Custom role updated", zap.String("role", role.Name), zap.Any("permissions", permissions))
+	return role, nil
+}
+
+// DeleteRole deletes a custom role and revokes it from all users
+func (s *roleService) DeleteRole(ctx context.Context, id uint) error {
+	role, err := s.roleRepo.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.roleRepo.Delete(ctx, id); err != nil {
+		return fmt.Errorf("failed to delete role: %w", err)
+	}
+
+	s.logger.Info("Custom role deleted", zap.String("role", role.Name))
+	return nil
+}
+
+// AssignRole grants a custom role to a user
+func (s *roleService) AssignRole(ctx context.Context, userID, roleID uint) error {
+	if _, err := s.userRepo.FindByID(ctx, userID); err != nil {
+		return err
+	}
+	role, err := s.roleRepo.FindByID(ctx, roleID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.roleRepo.Assign(ctx, userID, roleID); err != nil {
+		return fmt.Errorf("failed to assign role: %w", err)
+	}
+
+	s.logger.Info("Custom role assigned", zap.Uint("userID", userID), zap.String("role", role.Name))
+	return nil
+}
+
+// UnassignRole revokes a custom role from a user
+func (s *roleService) UnassignRole(ctx context.Context, userID, roleID uint) error {
+	if err := s.roleRepo.Unassign(ctx, userID, roleID); err != nil {
+		return fmt.Errorf("failed to unassign role: %w", err)
+	}
+
+	s.logger.Info("Custom role unassigned", zap.Uint("userID", userID), zap.Uint("roleID", roleID))
+	return nil
+}
+
+// GetUserRoles gets the custom roles assigned to a user
+func (s *roleService) GetUserRoles(ctx context.Context, userID uint) ([]*model.CustomRole, error) {
+	if _, err := s.userRepo.FindByID(ctx, userID); err != nil {
+		return nil, err
+	}
+	return s.roleRepo.FindByUserID(ctx, userID)
+}
+
+// GetUserPermissions resolves the effective permissions of a user
+func (s *roleService) GetUserPermissions(ctx context.Context, userID uint) ([]model.Permission, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.EffectivePermissions(ctx, userID, user.Role)
+}
+
+// EffectivePermissions resolves the permissions of a user from their built-in role
+// and all custom roles currently assigned to them
+func (s *roleService) EffectivePermissions(ctx context.Context, userID uint, role model.Role) ([]model.Permission, error) {
+	seen := make(map[model.Permission]bool)
+	var permissions []model.Permission
+	add := func(perms []model.Permission) {
+		for _, p := range perms {
+			if !seen[p] {
+				seen[p] = true
+				permissions = append(permissions, p)
+			}
+		}
+	}
+
+	add(model.RolePermissions[role])
+
+	customRoles, err := s.roleRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load user roles: %w", err)
+	}
+	for _, customRole := range customRoles {
+		add(customRole.Permissions)
+	}
+
+	return permissions, nil
+}
+
+// normalizePermissions validates permissions and removes duplicates
+func normalizePermissions(permissions []model.Permission) ([]model.Permission, error) {
+	seen := make(map[model.Permission]bool, len(permissions))
+	result := make([]model.Permission, 0, len(permissions))
+	for _, p := range permissions {
+		if !model.IsValidPermission(p) {
+			return nil, fmt.Errorf("unknown permission %q", p)
+		}
+		if !seen[p] {
+			seen[p] = true
+			result = append(result, p)
+		}
+	}
+	if len(result) == 0 {
+		return nil, errors.New("at least one permission is required")
+	}
+	return result, nil
+}
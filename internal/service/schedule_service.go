@@ -0,0 +1,472 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/repository"
+	"github.com/whitewalker-sa/ehass/pkg/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultSlotRangeDays is the range searched when no end date is given
+	defaultSlotRangeDays = 7
+	// maxSlotRangeDays bounds a single slot query
+	maxSlotRangeDays = 62
+	// nextSlotSearchDays is how far ahead the next available slot is looked for when the clinic
+	// has no booking window
+	nextSlotSearchDays = 90
+	// maxProposedBookings bounds a single workload distribution
+	maxProposedBookings = 100
+	// maxBalancedDoctors bounds how many doctors of a specialty are considered
+	maxBalancedDoctors = 100
+)
+
+// ErrNoAvailableSlot is returned when a doctor has no free slot within the search range
+var ErrNoAvailableSlot = errors.New("no available slot found")
+
+// Slot is a bookable appointment time
+type Slot struct {
+	Start time.Time
+	End   time.Time
+}
+
+// SlotRange holds the free slots between two clinic-local dates, both inclusive
+type SlotRange struct {
+	From     time.Time
+	To       time.Time
+	Timezone string
+	Slots    []Slot
+}
+
+// DoctorWorkload is a doctor's load over a date range and the bookings proposed for them
+type DoctorWorkload struct {
+	Doctor    *model.Doctor
+	Booked    int // Active appointments in the range
+	FreeSlots int // Free slots in the range before the proposals
+	Proposed  int
+}
+
+// WorkloadProposal proposes booking a slot with a doctor
+type WorkloadProposal struct {
+	Doctor *model.Doctor
+	Slot   Slot
+}
+
+// WorkloadPlan spreads proposed bookings across the doctors of a specialty
+type WorkloadPlan struct {
+	Specialty  string
+	Doctors    []*DoctorWorkload
+	Proposals  []WorkloadProposal
+	Unassigned int // Bookings that could not be placed for lack of free slots
+}
+
+type scheduleService struct {
+	availabilityRepo repository.AvailabilityRepository
+	doctorRepo       repository.DoctorRepository
+	appointmentRepo  repository.AppointmentRepository
+	holdRepo         repository.SlotHoldRepository
+	orgService       OrganizationService
+	slotCache        *SlotCache
+	logger           *zap.Logger
+}
+
+// NewScheduleService creates a new schedule service
+func NewScheduleService(
+	availabilityRepo repository.AvailabilityRepository,
+	doctorRepo repository.DoctorRepository,
+	appointmentRepo repository.AppointmentRepository,
+	holdRepo repository.SlotHoldRepository,
+	orgService OrganizationService,
+	slotCache *SlotCache,
+	logger *zap.Logger,
+) ScheduleService {
+	return &scheduleService{
+		availabilityRepo: availabilityRepo,
+		doctorRepo:       doctorRepo,
+		appointmentRepo:  appointmentRepo,
+		holdRepo:         holdRepo,
+		orgService:       orgService,
+		slotCache:        slotCache,
+		logger:           logger,
+	}
+}
+
+// GetAvailableSlots lists a doctor's free slots between two dates. Dates are resolved in the
+// clinic's timezone and may be natural dates such as "today" or "+7d" (see utils.ResolveDate).
+// from defaults to today and to to a week after from.
+func (s *scheduleService) GetAvailableSlots(ctx context.Context, doctorID uint, from, to string) (*SlotRange, error) {
+	org, err := s.orgService.GetDoctorOrganization(ctx, doctorID)
+	if err != nil {
+		return nil, err
+	}
+	loc := utils.LoadLocation(org.Timezone)
+	now := time.Now()
+	today := now.In(loc)
+
+	if from == "" {
+		from = "today"
+	}
+	fromDate, err := utils.ResolveDate(from, today)
+	if err != nil {
+		return nil, err
+	}
+	toDate := fromDate.AddDate(0, 0, defaultSlotRangeDays-1)
+	if to != "" {
+		if toDate, err = utils.ResolveDate(to, today); err != nil {
+			return nil, err
+		}
+	}
+	if toDate.Before(fromDate) {
+		return nil, errors.New("to date must not be before from date")
+	}
+	if toDate.After(fromDate.AddDate(0, 0, maxSlotRangeDays-1)) {
+		return nil, fmt.Errorf("date range cannot exceed %d days", maxSlotRangeDays)
+	}
+
+	slots, err := s.findSlots(ctx, doctorID, org, fromDate, toDate.AddDate(0, 0, 1), now, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &SlotRange{From: fromDate, To: toDate, Timezone: loc.String(), Slots: slots}, nil
+}
+
+// GetNextAvailableSlot finds a doctor's earliest free slot on or after the given date, which
+// defaults to today. The search stops at the clinic's booking window.
+func (s *scheduleService) GetNextAvailableSlot(ctx context.Context, doctorID uint, after string) (*Slot, error) {
+	org, err := s.orgService.GetDoctorOrganization(ctx, doctorID)
+	if err != nil {
+		return nil, err
+	}
+	loc := utils.LoadLocation(org.Timezone)
+	now := time.Now()
+	today := now.In(loc)
+
+	if after == "" {
+		after = "today"
+	}
+	fromDate, err := utils.ResolveDate(after, today)
+	if err != nil {
+		return nil, err
+	}
+
+	horizon := nextSlotSearchDays
+	if org.BookingWindowDays > 0 {
+		horizon = org.BookingWindowDays + 1
+	}
+	until, _ := utils.ResolveDate(fmt.Sprintf("+%dd", horizon), today)
+	if !fromDate.Before(until) {
+		return nil, ErrNoAvailableSlot
+	}
+
+	slots, err := s.findSlots(ctx, doctorID, org, fromDate, until, now, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(slots) == 0 {
+		return nil, ErrNoAvailableSlot
+	}
+	return &slots[0], nil
+}
+
+// SuggestDistribution proposes count bookings between two dates across the doctors of a
+// specialty. Each booking goes to the doctor with the fewest booked and proposed appointments
+// in the range who still has a free slot, taking their earliest one. Dates are resolved in each
+// doctor's clinic timezone as for GetAvailableSlots.
+func (s *scheduleService) SuggestDistribution(ctx context.Context, specialty, from, to string, count int) (*WorkloadPlan, error) {
+	if count <= 0 || count > maxProposedBookings {
+		return nil, fmt.Errorf("count must be between 1 and %d", maxProposedBookings)
+	}
+
+	doctors, _, err := s.doctorRepo.FindBySpecialty(ctx, specialty, maxBalancedDoctors, 0, repository.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get doctors: %w", err)
+	}
+	if len(doctors) == 0 {
+		return nil, fmt.Errorf("no doctors found with specialty %q", specialty)
+	}
+
+	plan := &WorkloadPlan{Specialty: specialty}
+	free := make(map[*DoctorWorkload][]Slot)
+	for _, doctor := range doctors {
+		slotRange, err := s.GetAvailableSlots(ctx, doctor.ID, from, to)
+		if err != nil {
+			return nil, err
+		}
+		booked, err := s.appointmentRepo.FindByDoctorBetween(ctx, doctor.ID, slotRange.From, slotRange.To.AddDate(0, 0, 1))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get appointments: %w", err)
+		}
+
+		workload := &DoctorWorkload{Doctor: doctor, FreeSlots: len(slotRange.Slots)}
+		for _, appt := range booked {
+			if isActiveAppointment(appt) {
+				workload.Booked++
+			}
+		}
+		plan.Doctors = append(plan.Doctors, workload)
+		free[workload] = slotRange.Slots
+	}
+
+	for i := 0; i < count; i++ {
+		var next *DoctorWorkload
+		for _, workload := range plan.Doctors {
+			if len(free[workload]) == 0 {
+				continue
+			}
+			if next == nil || workload.Booked+workload.Proposed < next.Booked+next.Proposed ||
+				(workload.Booked+workload.Proposed == next.Booked+next.Proposed &&
+					free[workload][0].Start.Before(free[next][0].Start)) {
+				next = workload
+			}
+		}
+		if next == nil {
+			plan.Unassigned = count - i
+			break
+		}
+
+		plan.Proposals = append(plan.Proposals, WorkloadProposal{Doctor: next.Doctor, Slot: free[next][0]})
+		free[next] = free[next][1:]
+		next.Proposed++
+	}
+	return plan, nil
+}
+
+// findSlots lists free slots starting in [from, until), both clinic-local midnights. Each day's
+// slots come from the slot cache or, for days it does not hold, from freeSlots; they must then
+// pass the clinic's booking rules at now and not be held by a patient completing a booking. A
+// positive limit stops the search once that many slots are found.
+func (s *scheduleService) findSlots(ctx context.Context, doctorID uint, org *model.Organization, from, until, now time.Time, limit int) ([]Slot, error) {
+	var computed map[string][]Slot
+	var slots []Slot
+	for day := from; day.Before(until); day = day.AddDate(0, 0, 1) {
+		free, ok := s.slotCache.get(doctorID, day)
+		if !ok {
+			// Work out the rest of the range at once, loading the doctor's data a single time
+			if computed == nil {
+				var err error
+				if computed, err = s.freeSlots(ctx, doctorID, org, day, until); err != nil {
+					return nil, err
+				}
+			}
+			free = computed[day.Format("2006-01-02")]
+			s.slotCache.put(doctorID, day, free)
+		}
+
+		daySlots := make([]Slot, 0, len(free))
+		for _, slot := range free {
+			if checkBookingRules(org, slot.Start, slot.End, now) == nil {
+				daySlots = append(daySlots, slot)
+			}
+		}
+		daySlots, err := s.withoutHeld(ctx, doctorID, daySlots)
+		if err != nil {
+			return nil, err
+		}
+		slots = append(slots, daySlots...)
+		if limit > 0 && len(slots) >= limit {
+			return slots[:limit], nil
+		}
+	}
+	return slots, nil
+}
+
+// freeSlots cuts the slots of each day in [from, until) that no active appointment takes,
+// keyed by clinic-local date and sorted by start. Slots are cut from the doctor's availability
+// at each window's slot duration, or from the clinic's business hours at the doctor's
+// consultation length when the doctor has no availability, and must not come within the
+// doctor's buffer time of an active appointment. Consecutive slots are spaced by the buffer too.
+func (s *scheduleService) freeSlots(ctx context.Context, doctorID uint, org *model.Organization, from, until time.Time) (map[string][]Slot, error) {
+	doctor, err := s.doctorRepo.FindByID(ctx, doctorID)
+	if err != nil {
+		return nil, err
+	}
+	windows, err := s.scheduleWindows(ctx, doctor, org)
+	if err != nil {
+		return nil, err
+	}
+	free := make(map[string][]Slot)
+	if len(windows) == 0 {
+		return free, nil
+	}
+
+	// Look back a day so appointments running into the range still block it
+	booked, err := s.appointmentRepo.FindByDoctorBetween(ctx, doctorID, from.AddDate(0, 0, -1), until)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get appointments: %w", err)
+	}
+	buffer := doctor.Buffer()
+	var busy []Slot
+	for _, appt := range booked {
+		if isActiveAppointment(appt) {
+			busy = append(busy, Slot{Start: appt.ScheduledStart.Add(-buffer), End: appt.ScheduledEnd.Add(buffer)})
+		}
+	}
+	timeOff, err := s.availabilityRepo.FindTimeOff(ctx, doctorID, from, until)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get time off: %w", err)
+	}
+	var blocked []Slot
+	for _, off := range timeOff {
+		blocked = append(blocked, Slot{Start: off.Start, End: off.End})
+	}
+	calendarBusy, err := s.availabilityRepo.FindCalendarBusy(ctx, doctorID, from, until)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get calendar busy times: %w", err)
+	}
+	for _, period := range calendarBusy {
+		blocked = append(blocked, Slot{Start: period.Start, End: period.End})
+	}
+	capacity := doctor.Capacity()
+
+	for day := from; day.Before(until); day = day.AddDate(0, 0, 1) {
+		var daySlots []Slot
+		seen := make(map[int64]bool)
+		for _, window := range windows {
+			if !window.appliesOn(day) {
+				continue
+			}
+			start, end := window.on(day)
+			for slotStart := start; !slotStart.Add(window.Length).After(end); slotStart = slotStart.Add(window.Length + buffer) {
+				slot := Slot{Start: slotStart, End: slotStart.Add(window.Length)}
+				if seen[slot.Start.Unix()] || overlapsAny(slot, blocked) || overlapCount(slot, busy) >= capacity {
+					continue
+				}
+				seen[slot.Start.Unix()] = true
+				daySlots = append(daySlots, slot)
+			}
+		}
+		sort.Slice(daySlots, func(i, j int) bool { return daySlots[i].Start.Before(daySlots[j].Start) })
+		free[day.Format("2006-01-02")] = daySlots
+	}
+	return free, nil
+}
+
+// scheduleWindow is a weekly working window in clinic-local clock time
+type scheduleWindow struct {
+	DayOfWeek int
+	Start     time.Time
+	End       time.Time
+	Length    time.Duration // Length of the slots cut from the window
+	From      string        // First date the window applies on, YYYY-MM-DD; empty when it always has
+	Until     string        // Last date the window applies on, YYYY-MM-DD; empty when it has no end
+}
+
+// appliesOn reports whether the window applies on the given clinic-local date
+func (w scheduleWindow) appliesOn(day time.Time) bool {
+	if w.DayOfWeek != int(day.Weekday()) {
+		return false
+	}
+	date := day.Format("2006-01-02")
+	return (w.From == "" || date >= w.From) && (w.Until == "" || date <= w.Until)
+}
+
+// on returns the window's start and end on the given clinic-local date
+func (w scheduleWindow) on(day time.Time) (time.Time, time.Time) {
+	at := func(clock time.Time) time.Time {
+		return time.Date(day.Year(), day.Month(), day.Day(), clock.Hour(), clock.Minute(), clock.Second(), 0, day.Location())
+	}
+	return at(w.Start), at(w.End)
+}
+
+// scheduleWindows returns the doctor's weekly availability, falling back to the clinic's
+// business hours
+func (s *scheduleService) scheduleWindows(ctx context.Context, doctor *model.Doctor, org *model.Organization) ([]scheduleWindow, error) {
+	availability, err := s.availabilityRepo.FindByDoctorID(ctx, doctor.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get availability: %w", err)
+	}
+	return availabilityWindows(availability, org, doctor.AppointmentLength(org)), nil
+}
+
+// availabilityWindows turns a doctor's availability into schedule windows, falling back to the
+// clinic's business hours when there is none. Windows without a slot duration, and business
+// hours, are cut into slots of defaultLength.
+func availabilityWindows(availability []*model.Availability, org *model.Organization, defaultLength time.Duration) []scheduleWindow {
+	var windows []scheduleWindow
+	add := func(day int, startClock, endClock string, length time.Duration) *scheduleWindow {
+		start, err := parseClock(startClock)
+		if err != nil {
+			return nil
+		}
+		end, err := parseClock(endClock)
+		if err != nil || !start.Before(end) {
+			return nil
+		}
+		windows = append(windows, scheduleWindow{DayOfWeek: day, Start: start, End: end, Length: length})
+		return &windows[len(windows)-1]
+	}
+
+	if len(availability) > 0 {
+		for _, a := range availability {
+			length := defaultLength
+			if a.Duration > 0 {
+				length = time.Duration(a.Duration) * time.Minute
+			}
+			window := add(a.DayOfWeek, a.StartTime, a.EndTime, length)
+			if window == nil {
+				continue
+			}
+			if a.ValidFrom != nil {
+				window.From = a.ValidFrom.Format("2006-01-02")
+			}
+			if a.ValidUntil != nil {
+				window.Until = a.ValidUntil.Format("2006-01-02")
+			}
+		}
+		return windows
+	}
+	for _, hours := range org.BusinessHours {
+		add(hours.DayOfWeek, hours.Open, hours.Close, defaultLength)
+	}
+	return windows
+}
+
+// withoutHeld drops the slots held by patients completing a booking
+func (s *scheduleService) withoutHeld(ctx context.Context, doctorID uint, slots []Slot) ([]Slot, error) {
+	if len(slots) == 0 {
+		return slots, nil
+	}
+
+	starts := make([]time.Time, len(slots))
+	for i, slot := range slots {
+		starts[i] = slot.Start
+	}
+	held, err := s.holdRepo.FindHeld(ctx, doctorID, starts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get slot holds: %w", err)
+	}
+
+	free := slots[:0]
+	for _, slot := range slots {
+		if held[slot.Start.Unix()] == nil {
+			free = append(free, slot)
+		}
+	}
+	return free, nil
+}
+
+func overlapsAny(slot Slot, busy []Slot) bool {
+	for _, b := range busy {
+		if slot.Start.Before(b.End) && b.Start.Before(slot.End) {
+			return true
+		}
+	}
+	return false
+}
+
+// overlapCount counts the busy ranges overlapping the slot
+func overlapCount(slot Slot, busy []Slot) int {
+	count := 0
+	for _, b := range busy {
+		if slot.Start.Before(b.End) && b.Start.Before(slot.End) {
+			count++
+		}
+	}
+	return count
+}
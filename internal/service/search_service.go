@@ -0,0 +1,529 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/repository"
+	"github.com/whitewalker-sa/ehass/pkg/breaker"
+	"github.com/whitewalker-sa/ehass/pkg/encryption"
+	"github.com/whitewalker-sa/ehass/pkg/search"
+	"go.uber.org/zap"
+)
+
+const (
+	doctorIndex  = "doctors"
+	patientIndex = "patients"
+
+	// specialtyFacet is the keyword field doctors are filtered and counted by
+	specialtyFacet = "specialty.keyword"
+
+	reindexBatchSize = 500
+)
+
+// ErrSearchDisabled is returned for operations that need a search backend when none is configured
+var ErrSearchDisabled = errors.New("search backend is not enabled")
+
+// Index mappings. String fields follow the dynamic mapping defaults, text with a keyword
+// subfield, so an index created implicitly by a write still works.
+var (
+	doctorMappings = map[string]interface{}{
+		"properties": map[string]interface{}{
+			"name":        textWithKeyword,
+			"specialty":   textWithKeyword,
+			"designation": map[string]string{"type": "text"},
+			"bio":         map[string]string{"type": "text"},
+		},
+	}
+	patientMappings = map[string]interface{}{
+		"properties": map[string]interface{}{
+			"name":  textWithKeyword,
+			"email": textWithKeyword,
+		},
+	}
+	textWithKeyword = map[string]interface{}{
+		"type":   "text",
+		"fields": map[string]interface{}{"keyword": map[string]string{"type": "keyword"}},
+	}
+)
+
+// searchSyncPayload identifies the record a search sync event is about, so it can be removed
+// from the index after it has been deleted from the database
+type searchSyncPayload struct {
+	ID string `json:"id"`
+}
+
+// searchSyncEvent returns the outbox row telling the search index that a record changed
+func searchSyncEvent(eventType, publicID string) *model.OutboxEvent {
+	payload, _ := json.Marshal(searchSyncPayload{ID: publicID})
+	return &model.OutboxEvent{
+		Type:        eventType,
+		Destination: model.OutboxDestinationSearch,
+		Payload:     string(payload),
+	}
+}
+
+// FacetCount is the number of search matches with one value of a facet
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// DoctorSearchResult holds the best matching doctors and counts of all matches
+type DoctorSearchResult struct {
+	Doctors     []*model.Doctor
+	Total       int64        // Matches including those beyond the limit
+	Specialties []FacetCount // Matches by specialty, before narrowing to a specialty
+}
+
+// Encounter is a past appointment or medical record found by an encounter search; exactly one
+// of them is set
+type Encounter struct {
+	Appointment   *model.Appointment   // Matched by its reason or notes
+	MedicalRecord *model.MedicalRecord // Matched by its diagnosis
+}
+
+// ReindexResult counts the records written to the search index
+type ReindexResult struct {
+	Doctors  int `json:"doctors"`
+	Patients int `json:"patients"`
+}
+
+type searchService struct {
+	client          *search.Client // nil without a search backend
+	breaker         *breaker.Breaker
+	doctorRepo      repository.DoctorRepository
+	patientRepo     repository.PatientRepository
+	appointmentRepo repository.AppointmentRepository
+	access          recordAccess
+	patientService  PatientService
+	suggestions     *suggestionCache
+	logger          *zap.Logger
+
+	indexesMu    sync.Mutex
+	indexesReady bool
+}
+
+// NewSearchService creates a new search service. With a nil client every search runs against
+// the database and index updates are discarded. Typeahead suggestions are cached for
+// suggestCacheTTL, up to suggestCacheSize of them.
+func NewSearchService(
+	client *search.Client,
+	searchBreaker *breaker.Breaker,
+	doctorRepo repository.DoctorRepository,
+	patientRepo repository.PatientRepository,
+	appointmentRepo repository.AppointmentRepository,
+	handoffRepo repository.HandoffRepository,
+	patientService PatientService,
+	suggestCacheTTL time.Duration,
+	suggestCacheSize int,
+	logger *zap.Logger,
+) SearchService {
+	return &searchService{
+		client:          client,
+		breaker:         searchBreaker,
+		doctorRepo:      doctorRepo,
+		patientRepo:     patientRepo,
+		appointmentRepo: appointmentRepo,
+		access:          recordAccess{doctorRepo: doctorRepo, patientRepo: patientRepo, handoffRepo: handoffRepo},
+		patientService:  patientService,
+		suggestions:     newSuggestionCache(suggestCacheTTL, suggestCacheSize),
+		logger:          logger,
+	}
+}
+
+// SearchDoctors finds doctors by name, specialty or bio, optionally narrowed to one specialty.
+// The search backend tolerates typos; if it is disabled or fails, the database is searched for
+// exact substrings instead.
+func (s *searchService) SearchDoctors(ctx context.Context, text, specialty string, limit int) (*DoctorSearchResult, error) {
+	text = strings.TrimSpace(text)
+	if utf8.RuneCountInString(text) < minSearchLength {
+		return nil, ErrSearchQueryTooShort
+	}
+	limit = searchLimit(limit)
+
+	if s.client != nil {
+		result, err := s.searchDoctorIndex(ctx, text, specialty, limit)
+		if err == nil {
+			return result, nil
+		}
+		s.logger.Warn("Search backend failed, searching doctors in the database", zap.Error(err))
+	}
+
+	doctors, total, err := s.doctorRepo.Search(ctx, text, specialty, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search doctors: %w", err)
+	}
+	counts, err := s.doctorRepo.CountBySpecialty(ctx, text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count doctors by specialty: %w", err)
+	}
+
+	result := &DoctorSearchResult{
+		Doctors:     doctors,
+		Total:       total,
+		Specialties: make([]FacetCount, 0, len(counts)),
+	}
+	for _, count := range counts {
+		result.Specialties = append(result.Specialties, FacetCount{Value: count.Specialty, Count: count.Count})
+	}
+	return result, nil
+}
+
+func (s *searchService) searchDoctorIndex(ctx context.Context, text, specialty string, limit int) (*DoctorSearchResult, error) {
+	query := search.Query{
+		Text:   text,
+		Fields: []string{"name^3", "specialty^2", "designation", "bio"},
+		Size:   limit,
+	}
+	if specialty == "" {
+		query.Facets = []string{specialtyFacet}
+	} else {
+		query.Filters = map[string]string{specialtyFacet: specialty}
+	}
+
+	hits, err := s.search(ctx, doctorIndex, query)
+	if err != nil {
+		return nil, err
+	}
+	// Facet counts cover every specialty, so a narrowed search counts them separately
+	facets := hits
+	if specialty != "" {
+		facets, err = s.search(ctx, doctorIndex, search.Query{
+			Text:   text,
+			Fields: query.Fields,
+			Facets: []string{specialtyFacet},
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	found, err := s.doctorRepo.FindByPublicIDs(ctx, hits.IDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load doctors: %w", err)
+	}
+	byID := make(map[string]*model.Doctor, len(found))
+	for _, doctor := range found {
+		byID[doctor.PublicID] = doctor
+	}
+
+	result := &DoctorSearchResult{
+		Doctors:     make([]*model.Doctor, 0, len(hits.IDs)),
+		Total:       hits.Total,
+		Specialties: []FacetCount{},
+	}
+	for _, id := range hits.IDs {
+		// Hits deleted since they were indexed are skipped
+		if doctor, ok := byID[id]; ok {
+			result.Doctors = append(result.Doctors, doctor)
+		}
+	}
+	for _, count := range facets.Facets[specialtyFacet] {
+		result.Specialties = append(result.Specialties, FacetCount{Value: count.Value, Count: count.Count})
+	}
+	return result, nil
+}
+
+// search runs a query through the breaker
+func (s *searchService) search(ctx context.Context, index string, query search.Query) (*search.Result, error) {
+	var result *search.Result
+	err := s.breaker.Do(ctx, func(ctx context.Context) error {
+		var err error
+		result, err = s.client.Search(ctx, index, query)
+		return err
+	})
+	return result, err
+}
+
+// SearchPatients finds patients for clinic staff. Names and emails are searched in the search
+// backend when enabled, which tolerates typos; dates of birth and phone numbers, and every search
+// while the backend is disabled or failing, go to the database.
+func (s *searchService) SearchPatients(ctx context.Context, query string, limit int) ([]*model.Patient, error) {
+	query = strings.TrimSpace(query)
+	if s.client == nil || isStructuredPatientQuery(query) || utf8.RuneCountInString(query) < minSearchLength {
+		return s.patientService.SearchPatients(ctx, query, limit)
+	}
+
+	hits, err := s.search(ctx, patientIndex, search.Query{
+		Text:   query,
+		Fields: []string{"name^3", "email"},
+		Size:   searchLimit(limit),
+	})
+	if err != nil {
+		s.logger.Warn("Search backend failed, searching patients in the database", zap.Error(err))
+		return s.patientService.SearchPatients(ctx, query, limit)
+	}
+
+	found, err := s.patientRepo.FindByPublicIDs(ctx, hits.IDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load patients: %w", err)
+	}
+	byID := make(map[string]*model.Patient, len(found))
+	for _, patient := range found {
+		byID[patient.PublicID] = patient
+	}
+	patients := make([]*model.Patient, 0, len(hits.IDs))
+	for _, id := range hits.IDs {
+		if patient, ok := byID[id]; ok {
+			patients = append(patients, patient)
+		}
+	}
+	return patients, nil
+}
+
+// SearchEncounters finds past appointments by the words of their reason and notes with Postgres
+// full-text search, and medical records by the blind index of their diagnosis when
+// includeDiagnoses is set.
+// Doctors only find their own encounters, and every match is checked against the medical record
+// access rules, so it is only returned if the caller may read the patient's records.
+func (s *searchService) SearchEncounters(ctx context.Context, userID uint, role model.Role, text string, includeDiagnoses bool, limit int) ([]Encounter, error) {
+	text = strings.TrimSpace(text)
+	if utf8.RuneCountInString(text) < minSearchLength {
+		return nil, ErrSearchQueryTooShort
+	}
+
+	query := repository.EncounterSearch{Query: text}
+	if includeDiagnoses {
+		index := encryption.DefaultIndex()
+		if index == nil {
+			return nil, errors.New("no blind index registered")
+		}
+		query.DiagnosisTokens, query.ExcludedDiagnosisTokens = index.QueryTokens(text)
+	}
+	if role == model.RoleDoctor {
+		doctor, err := s.doctorRepo.FindByUserID(ctx, userID)
+		if err != nil {
+			if isNotFound(err) {
+				return []Encounter{}, nil
+			}
+			return nil, fmt.Errorf("failed to load doctor: %w", err)
+		}
+		query.DoctorID = doctor.ID
+	}
+
+	matches, err := s.appointmentRepo.SearchEncounters(ctx, query, searchLimit(limit))
+	if err != nil {
+		return nil, fmt.Errorf("failed to search encounters: %w", err)
+	}
+	encounters := make([]Encounter, 0, len(matches))
+	readable := map[uint]bool{}
+	for _, match := range matches {
+		var patientID uint
+		if match.Appointment != nil {
+			patientID = match.Appointment.PatientID
+		} else {
+			patientID = match.MedicalRecord.PatientID
+		}
+		allowed, checked := readable[patientID]
+		if !checked {
+			err := s.access.authorizeRead(ctx, userID, role, patientID)
+			if err != nil && !errors.Is(err, ErrNotTreatingDoctor) && !errors.Is(err, ErrNotOwnMedicalRecord) && !isNotFound(err) {
+				return nil, err
+			}
+			allowed = err == nil
+			readable[patientID] = allowed
+		}
+		if allowed {
+			encounters = append(encounters, Encounter{Appointment: match.Appointment, MedicalRecord: match.MedicalRecord})
+		}
+	}
+	return encounters, nil
+}
+
+// isStructuredPatientQuery reports whether a patient search is for a date of birth or phone number
+func isStructuredPatientQuery(query string) bool {
+	if _, err := time.Parse("2006-01-02", query); err == nil {
+		return true
+	}
+	return len(phoneDigits(query)) >= minPhoneSearchDigits
+}
+
+// Sync brings the search index up to date with a record changed by an outbox event. Documents
+// are rebuilt from the current record, so events may arrive late, twice or out of order.
+func (s *searchService) Sync(ctx context.Context, event *model.OutboxEvent) error {
+	if s.client == nil {
+		return nil
+	}
+
+	var payload searchSyncPayload
+	if err := json.Unmarshal([]byte(event.Payload), &payload); err != nil {
+		return fmt.Errorf("failed to decode search event: %w", err)
+	}
+
+	switch event.AggregateType {
+	case "doctor":
+		if event.Type == model.EventDoctorDeleted {
+			return s.remove(ctx, doctorIndex, payload.ID)
+		}
+		doctor, err := s.doctorRepo.FindByID(ctx, event.AggregateID)
+		if err != nil {
+			if isNotFound(err) {
+				return s.remove(ctx, doctorIndex, payload.ID)
+			}
+			return err
+		}
+		return s.put(ctx, doctorIndex, doctor.PublicID, doctorDocument(doctor))
+	case "patient":
+		if event.Type == model.EventPatientDeleted {
+			return s.remove(ctx, patientIndex, payload.ID)
+		}
+		patient, err := s.patientRepo.FindByID(ctx, event.AggregateID)
+		if err != nil {
+			if isNotFound(err) {
+				return s.remove(ctx, patientIndex, payload.ID)
+			}
+			return err
+		}
+		return s.put(ctx, patientIndex, patient.PublicID, patientDocument(patient))
+	case "user":
+		// A user's name and email appear in the documents of their doctor or patient record
+		if doctor, err := s.doctorRepo.FindByUserID(ctx, event.AggregateID); err == nil {
+			if err := s.put(ctx, doctorIndex, doctor.PublicID, doctorDocument(doctor)); err != nil {
+				return err
+			}
+		} else if !isNotFound(err) {
+			return err
+		}
+		if patient, err := s.patientRepo.FindByUserID(ctx, event.AggregateID); err == nil {
+			return s.put(ctx, patientIndex, patient.PublicID, patientDocument(patient))
+		} else if !isNotFound(err) {
+			return err
+		}
+		return nil
+	default:
+		return fmt.Errorf("no search index for %q records", event.AggregateType)
+	}
+}
+
+// Reindex writes every doctor and patient to the search index, e.g. after enabling the backend
+// or restoring a backup. Documents of deleted records are left for their delete events.
+func (s *searchService) Reindex(ctx context.Context) (*ReindexResult, error) {
+	if s.client == nil {
+		return nil, ErrSearchDisabled
+	}
+	if err := s.ensureIndexes(ctx); err != nil {
+		return nil, err
+	}
+
+	result := &ReindexResult{}
+	var afterID uint
+	for {
+		doctors, err := s.doctorRepo.FindAfter(ctx, afterID, reindexBatchSize)
+		if err != nil {
+			return result, fmt.Errorf("failed to load doctors: %w", err)
+		}
+		if len(doctors) == 0 {
+			break
+		}
+		docs := make(map[string]interface{}, len(doctors))
+		for _, doctor := range doctors {
+			docs[doctor.PublicID] = doctorDocument(doctor)
+		}
+		if err := s.putAll(ctx, doctorIndex, docs); err != nil {
+			return result, err
+		}
+		result.Doctors += len(doctors)
+		afterID = doctors[len(doctors)-1].ID
+	}
+
+	afterID = 0
+	for {
+		patients, err := s.patientRepo.FindAfter(ctx, afterID, reindexBatchSize)
+		if err != nil {
+			return result, fmt.Errorf("failed to load patients: %w", err)
+		}
+		if len(patients) == 0 {
+			break
+		}
+		docs := make(map[string]interface{}, len(patients))
+		for _, patient := range patients {
+			docs[patient.PublicID] = patientDocument(patient)
+		}
+		if err := s.putAll(ctx, patientIndex, docs); err != nil {
+			return result, err
+		}
+		result.Patients += len(patients)
+		afterID = patients[len(patients)-1].ID
+	}
+
+	s.logger.Info("Search index rebuilt", zap.Int("doctors", result.Doctors), zap.Int("patients", result.Patients))
+	return result, nil
+}
+
+// ensureIndexes creates the indexes with their mappings the first time they are written to
+func (s *searchService) ensureIndexes(ctx context.Context) error {
+	s.indexesMu.Lock()
+	defer s.indexesMu.Unlock()
+	if s.indexesReady {
+		return nil
+	}
+
+	err := s.breaker.Do(ctx, func(ctx context.Context) error {
+		if err := s.client.EnsureIndex(ctx, doctorIndex, doctorMappings); err != nil {
+			return err
+		}
+		return s.client.EnsureIndex(ctx, patientIndex, patientMappings)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create search indexes: %w", err)
+	}
+	s.indexesReady = true
+	return nil
+}
+
+func (s *searchService) put(ctx context.Context, index, id string, doc interface{}) error {
+	if err := s.ensureIndexes(ctx); err != nil {
+		return err
+	}
+	return s.breaker.Do(ctx, func(ctx context.Context) error {
+		return s.client.Put(ctx, index, id, doc)
+	})
+}
+
+func (s *searchService) putAll(ctx context.Context, index string, docs map[string]interface{}) error {
+	return s.breaker.Do(ctx, func(ctx context.Context) error {
+		return s.client.PutAll(ctx, index, docs)
+	})
+}
+
+func (s *searchService) remove(ctx context.Context, index, id string) error {
+	if id == "" {
+		return nil
+	}
+	return s.breaker.Do(ctx, func(ctx context.Context) error {
+		return s.client.Delete(ctx, index, id)
+	})
+}
+
+// doctorDocument is the indexed form of a doctor loaded with its user
+func doctorDocument(doctor *model.Doctor) map[string]interface{} {
+	return map[string]interface{}{
+		"name":        doctor.User.Name,
+		"specialty":   doctor.Specialty,
+		"designation": doctor.Designation,
+		"bio":         doctor.Bio,
+	}
+}
+
+// patientDocument is the indexed form of a patient. Only what staff search by is indexed; no
+// medical details leave the database.
+func patientDocument(patient *model.Patient) map[string]interface{} {
+	doc := map[string]interface{}{"name": patient.User.Name}
+	if !hasPlaceholderEmail(&patient.User) {
+		doc["email"] = patient.User.Email
+	}
+	return doc
+}
+
+// isNotFound reports whether a repository lookup failed because the record does not exist
+func isNotFound(err error) bool {
+	return strings.HasSuffix(err.Error(), "not found")
+}
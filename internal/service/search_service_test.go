@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/pkg/encryption"
+	"go.uber.org/zap"
+)
+
+// encounterIDs returns the IDs of the appointments and records found, in order
+func encounterIDs(encounters []Encounter) []uint {
+	ids := make([]uint, len(encounters))
+	for i, encounter := range encounters {
+		if encounter.Appointment != nil {
+			ids[i] = encounter.Appointment.ID
+		} else {
+			ids[i] = encounter.MedicalRecord.ID
+		}
+	}
+	return ids
+}
+
+func TestSearchEncountersReturnsOnlyReadablePatients(t *testing.T) {
+	tests := []struct {
+		name   string
+		userID uint
+		role   model.Role
+		want   []uint
+	}{
+		{"admin", 99, model.RoleAdmin, []uint{1, 2, 3, 4, 5, 6}},
+		{"doctor of one of two patients", 1, model.RoleDoctor, []uint{1, 2, 6}},
+		{"doctor with a pending booking", 2, model.RoleDoctor, []uint{}},
+		{"doctor with a cancelled booking", 3, model.RoleDoctor, []uint{}},
+		{"guardian", 10, model.RolePatient, []uint{1, 2, 4, 5, 6}},
+		{"child", 11, model.RolePatient, []uint{5}},
+		{"patient", 12, model.RolePatient, []uint{3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clinic := newTestClinic()
+			s := NewSearchService(nil, nil, clinic.doctors, clinic.patients, clinic.appointments, clinic.handoffs, nil, 0, 0, zap.NewNop())
+
+			encounters, err := s.SearchEncounters(context.Background(), tt.userID, tt.role, "chest pain", false, 20)
+			if err != nil {
+				t.Fatalf("SearchEncounters: %v", err)
+			}
+			if got := encounterIDs(encounters); !slices.Equal(got, tt.want) {
+				t.Errorf("found %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchEncountersKeepsDoctorsToTheirOwn(t *testing.T) {
+	clinic := newTestClinic()
+	s := NewSearchService(nil, nil, clinic.doctors, clinic.patients, clinic.appointments, clinic.handoffs, nil, 0, 0, zap.NewNop())
+	ctx := context.Background()
+
+	if _, err := s.SearchEncounters(ctx, 1, model.RoleDoctor, "chest pain", false, 20); err != nil {
+		t.Fatalf("SearchEncounters: %v", err)
+	}
+	if searches := clinic.appointments.searches; len(searches) != 1 || searches[0].DoctorID != 1 {
+		t.Fatalf("searched %+v, want doctor 1's encounters", searches)
+	}
+	// Access is checked once for patient 10 and once for patient 12, not for every match
+	if clinic.handoffs.checks != 2 {
+		t.Errorf("checked treatment relationships %d times, want once per patient", clinic.handoffs.checks)
+	}
+
+	// A doctor account without a doctor profile finds nothing rather than everyone's encounters
+	encounters, err := s.SearchEncounters(ctx, 10, model.RoleDoctor, "chest pain", false, 20)
+	if err != nil {
+		t.Fatalf("SearchEncounters: %v", err)
+	}
+	if len(encounters) != 0 || len(clinic.appointments.searches) != 1 {
+		t.Errorf("found %v, want no search run", encounterIDs(encounters))
+	}
+}
+
+func TestSearchEncountersQueriesDiagnosesByBlindIndex(t *testing.T) {
+	previous := encryption.DefaultIndex()
+	t.Cleanup(func() { encryption.RegisterIndex(previous) })
+	index, err := encryption.NewBlindIndex(base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32))))
+	if err != nil {
+		t.Fatalf("NewBlindIndex: %v", err)
+	}
+	encryption.RegisterIndex(index)
+
+	clinic := newTestClinic()
+	s := NewSearchService(nil, nil, clinic.doctors, clinic.patients, clinic.appointments, clinic.handoffs, nil, 0, 0, zap.NewNop())
+	if _, err := s.SearchEncounters(context.Background(), 99, model.RoleAdmin, "asthma -allergic", true, 20); err != nil {
+		t.Fatalf("SearchEncounters: %v", err)
+	}
+	search := clinic.appointments.searches[0]
+	include, exclude := index.QueryTokens("asthma -allergic")
+	if !slices.Equal(search.DiagnosisTokens, include) || !slices.Equal(search.ExcludedDiagnosisTokens, exclude) {
+		t.Errorf("searched diagnoses for %v without %v, want %v without %v",
+			search.DiagnosisTokens, search.ExcludedDiagnosisTokens, include, exclude)
+	}
+	if strings.Contains(strings.Join(search.DiagnosisTokens, " "), "asthma") {
+		t.Error("diagnosis search carries the plaintext word")
+	}
+}
+
+func TestSearchEncountersRejectsShortQueries(t *testing.T) {
+	clinic := newTestClinic()
+	s := NewSearchService(nil, nil, clinic.doctors, clinic.patients, clinic.appointments, clinic.handoffs, nil, 0, 0, zap.NewNop())
+	if _, err := s.SearchEncounters(context.Background(), 99, model.RoleAdmin, " a ", false, 20); !errors.Is(err, ErrSearchQueryTooShort) {
+		t.Errorf("got %v, want ErrSearchQueryTooShort", err)
+	}
+}
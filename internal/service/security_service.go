@@ -0,0 +1,257 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/config"
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/repository"
+	"go.uber.org/zap"
+)
+
+// Limits on the security dashboard
+const (
+	maxDashboardWindow  = 30 * 24 * time.Hour
+	dashboardTopLimit   = 10
+	dashboardAlertLimit = 20
+)
+
+// ErrInvalidDashboardWindow is returned for a dashboard window that is not positive or too long
+var ErrInvalidDashboardWindow = errors.New("window must be positive and at most 30 days")
+
+// authDashboardActions are the authentication events counted on the security dashboard
+var authDashboardActions = []string{
+	AuditActionLogin,
+	AuditActionLoginFailed,
+	AuditActionPasswordResetRequest,
+	AuditActionPasswordReset,
+	AuditActionTwoFactorFailed,
+	AuditActionReauthFailed,
+	AuditActionRefreshRejected,
+}
+
+// AuthDashboard summarizes authentication events since a point in time
+type AuthDashboard struct {
+	Since          time.Time
+	Totals         map[string]int64       // Events by audit action
+	FailedByIP     []*AuthEventCount      // Addresses with the most failed logins
+	FailedByUser   []*AuthEventCount      // Accounts with the most failed logins
+	TwoFactorFails []*AuthEventCount      // Accounts with the most wrong 2FA codes
+	ResetsByIP     []*AuthEventCount      // Addresses with the most password reset requests
+	Alerts         []*model.SecurityAlert // Latest alerts, whenever they were raised
+}
+
+// AuthEventCount counts authentication events from one IP address or for one account
+type AuthEventCount struct {
+	IP       string
+	UserID   string // The user's public ID
+	Email    string
+	Count    int64
+	Accounts int64 // Distinct known accounts, for an IP address
+}
+
+// authAnomalyRule raises an alert of one kind when a group of authentication events crosses its
+// threshold
+type authAnomalyRule struct {
+	kind      string
+	actions   []string
+	byUser    bool
+	threshold int
+	accounts  bool // The threshold counts distinct accounts rather than events
+}
+
+type securityService struct {
+	auditLogRepo repository.AuditLogRepository
+	alertRepo    repository.SecurityAlertRepository
+	window       time.Duration
+	rules        []authAnomalyRule
+	logger       *zap.Logger
+}
+
+// NewSecurityService creates a new security service
+func NewSecurityService(
+	auditLogRepo repository.AuditLogRepository,
+	alertRepo repository.SecurityAlertRepository,
+	cfg config.SecurityConfig,
+	logger *zap.Logger,
+) SecurityService {
+	window := cfg.Window
+	if window <= 0 {
+		window = 15 * time.Minute
+	}
+
+	failedLogins := []string{AuditActionLoginFailed}
+	return &securityService{
+		auditLogRepo: auditLogRepo,
+		alertRepo:    alertRepo,
+		window:       window,
+		rules: []authAnomalyRule{
+			{kind: model.SecurityAlertCredentialStuffing, actions: failedLogins, threshold: cfg.AccountsPerIP, accounts: true},
+			{kind: model.SecurityAlertFailedLoginsIP, actions: failedLogins, threshold: cfg.FailedLoginsPerIP},
+			{kind: model.SecurityAlertBruteForce, actions: failedLogins, byUser: true, threshold: cfg.FailedLoginsPerUser},
+			{kind: model.SecurityAlertPasswordResets, actions: []string{AuditActionPasswordResetRequest}, threshold: cfg.PasswordResetsPerIP},
+			{kind: model.SecurityAlertTwoFactorFailures, actions: []string{AuditActionTwoFactorFailed}, byUser: true, threshold: cfg.TwoFactorFailuresPerUser},
+		},
+		logger: logger,
+	}
+}
+
+// GetAuthDashboard summarizes authentication events over the given window, up to 30 days
+func (s *securityService) GetAuthDashboard(ctx context.Context, window time.Duration) (*AuthDashboard, error) {
+	if window <= 0 || window > maxDashboardWindow {
+		return nil, ErrInvalidDashboardWindow
+	}
+	since := time.Now().Add(-window)
+
+	totals, err := s.auditLogRepo.CountByAction(ctx, authDashboardActions, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count authentication events: %w", err)
+	}
+	for _, action := range authDashboardActions {
+		if _, ok := totals[action]; !ok {
+			totals[action] = 0
+		}
+	}
+
+	dashboard := &AuthDashboard{Since: since, Totals: totals}
+	top := []struct {
+		counts  *[]*AuthEventCount
+		actions []string
+		byUser  bool
+	}{
+		{&dashboard.FailedByIP, []string{AuditActionLoginFailed}, false},
+		{&dashboard.FailedByUser, []string{AuditActionLoginFailed}, true},
+		{&dashboard.TwoFactorFails, []string{AuditActionTwoFactorFailed}, true},
+		{&dashboard.ResetsByIP, []string{AuditActionPasswordResetRequest}, false},
+	}
+	for _, t := range top {
+		groups, err := s.auditLogRepo.GroupAuthEvents(ctx, repository.AuthEventQuery{
+			Actions: t.actions,
+			Since:   since,
+			ByUser:  t.byUser,
+			Limit:   dashboardTopLimit,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to group authentication events: %w", err)
+		}
+		*t.counts = toAuthEventCounts(groups, t.byUser)
+	}
+
+	if dashboard.Alerts, err = s.alertRepo.FindRecent(ctx, dashboardAlertLimit); err != nil {
+		return nil, fmt.Errorf("failed to find security alerts: %w", err)
+	}
+	return dashboard, nil
+}
+
+// DetectAuthAnomalies raises an alert for each address or account whose authentication events
+// in the detection window cross a threshold. An alert is raised at most once per window for the
+// same kind and subject, and publishes a security.alert event for SIEM and paging integrations.
+func (s *securityService) DetectAuthAnomalies(ctx context.Context) error {
+	now := time.Now()
+	since := now.Add(-s.window)
+
+	for _, rule := range s.rules {
+		if rule.threshold <= 0 {
+			continue
+		}
+
+		query := repository.AuthEventQuery{Actions: rule.actions, Since: since, ByUser: rule.byUser}
+		if rule.accounts {
+			query.MinUsers = int64(rule.threshold)
+		} else {
+			query.MinCount = int64(rule.threshold)
+		}
+		groups, err := s.auditLogRepo.GroupAuthEvents(ctx, query)
+		if err != nil {
+			return fmt.Errorf("failed to group authentication events: %w", err)
+		}
+
+		for _, group := range groups {
+			count := group.Count
+			if rule.accounts {
+				count = group.Users
+			}
+			if err := s.raise(ctx, rule, group.Key, count, since); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// toAuthEventCounts converts event groups keyed by IP address or by user
+func toAuthEventCounts(groups []*repository.AuthEventGroup, byUser bool) []*AuthEventCount {
+	counts := make([]*AuthEventCount, 0, len(groups))
+	for _, group := range groups {
+		count := &AuthEventCount{Count: group.Count, Accounts: group.Users}
+		if byUser {
+			count.UserID = group.Key
+			count.Email = group.Email
+		} else {
+			count.IP = group.Key
+		}
+		counts = append(counts, count)
+	}
+	return counts
+}
+
+// raise records an alert for subject unless one of the same kind was raised in the last window
+func (s *securityService) raise(ctx context.Context, rule authAnomalyRule, subject string, count int64, since time.Time) error {
+	exists, err := s.alertRepo.ExistsSince(ctx, rule.kind, subject, since)
+	if err != nil {
+		return fmt.Errorf("failed to check security alerts: %w", err)
+	}
+	if exists {
+		return nil
+	}
+
+	alert := &model.SecurityAlert{
+		Kind:        rule.kind,
+		Subject:     subject,
+		Count:       count,
+		Threshold:   int64(rule.threshold),
+		WindowStart: since,
+		CreatedAt:   time.Now(),
+	}
+	payload, err := json.Marshal(securityAlertEventData{
+		Kind:        alert.Kind,
+		Subject:     alert.Subject,
+		Count:       alert.Count,
+		Threshold:   alert.Threshold,
+		WindowStart: alert.WindowStart,
+		RaisedAt:    alert.CreatedAt,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+	event := &model.OutboxEvent{
+		Type:        model.EventSecurityAlert,
+		Destination: model.OutboxDestinationEvents,
+		Payload:     string(payload),
+	}
+	if err := s.alertRepo.Create(ctx, alert, event); err != nil {
+		return fmt.Errorf("failed to record security alert: %w", err)
+	}
+
+	s.logger.Warn("Authentication anomaly detected",
+		zap.String("kind", alert.Kind),
+		zap.String("subject", alert.Subject),
+		zap.Int64("count", alert.Count),
+		zap.Int64("threshold", alert.Threshold))
+	return nil
+}
+
+// securityAlertEventData is the payload of security.alert events. Subject is an IP address, or
+// the user's public ID for per-account alerts.
+type securityAlertEventData struct {
+	Kind        string    `json:"kind"`
+	Subject     string    `json:"subject"`
+	Count       int64     `json:"count"`
+	Threshold   int64     `json:"threshold"`
+	WindowStart time.Time `json:"window_start"`
+	RaisedAt    time.Time `json:"raised_at"`
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// JobSettingsReload is the name of the job reloading runtime settings
+const JobSettingsReload = "settings_reload"
+
+// SettingsReloader periodically reloads the runtime settings, so changes made through another
+// instance take effect here too
+type SettingsReloader struct {
+	settings SettingsService
+	interval time.Duration
+	monitor  *JobMonitor
+	logger   *zap.Logger
+}
+
+// NewSettingsReloader creates a new settings reloader running every interval
+func NewSettingsReloader(settings SettingsService, interval time.Duration, monitor *JobMonitor, logger *zap.Logger) *SettingsReloader {
+	if interval <= 0 {
+		interval = 15 * time.Second
+	}
+
+	s := &SettingsReloader{
+		settings: settings,
+		interval: interval,
+		monitor:  monitor,
+		logger:   logger,
+	}
+	monitor.Register(JobSettingsReload, interval, settings.Reload)
+	return s
+}
+
+// Start reloads settings in the background until the returned function is called
+func (s *SettingsReloader) Start() func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			if err := s.monitor.Do(ctx, JobSettingsReload, s.settings.Reload); err != nil && ctx.Err() == nil {
+				s.logger.Error("Failed to reload settings", zap.Error(err))
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		wg.Wait()
+	}
+}
@@ -0,0 +1,373 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/config"
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/repository"
+	"github.com/whitewalker-sa/ehass/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// Audit actions for runtime setting changes
+const (
+	AuditActionSettingsUpdated = "settings.updated"
+	AuditActionSettingReset    = "settings.reset"
+)
+
+// FeatureNoShowConfirmation requires high-risk patients to confirm new bookings with a code sent by SMS
+const FeatureNoShowConfirmation = "no_show_confirmation"
+
+// Upper bounds of runtime settings
+const (
+	maxMaintenanceMessageLength = 500
+	maxRequestsPerMinute        = 100000
+	maxBookingWindowDays        = 3650
+)
+
+var (
+	// ErrUnknownSetting is returned for a setting key that is not defined
+	ErrUnknownSetting = errors.New("unknown setting")
+	// ErrInvalidSetting is returned for a setting value of the wrong type or out of range
+	ErrInvalidSetting = errors.New("invalid setting")
+)
+
+// RuntimeSettings is a snapshot of the operational settings in effect. Snapshots are never
+// changed once published, so callers can keep one for the length of a request.
+type RuntimeSettings struct {
+	MaintenanceMode      bool            `json:"maintenance_mode"`
+	MaintenanceMessage   string          `json:"maintenance_message"`
+	RequestsPerMinute    int             `json:"requests_per_minute"`     // API requests per client IP and instance; 0 for no limit
+	LoginsPerMinute      int             `json:"logins_per_minute"`       // Login attempts per client IP and instance; 0 for no limit
+	MaxBookingWindowDays int             `json:"max_booking_window_days"` // Caps clinics' booking windows; 0 for no cap
+	Features             map[string]bool `json:"features"`
+}
+
+// Feature reports whether the named feature flag is on
+func (s *RuntimeSettings) Feature(name string) bool {
+	return s.Features[name]
+}
+
+// SettingValue is a runtime setting with its effective and configured values
+type SettingValue struct {
+	Key       string
+	Value     json.RawMessage
+	Default   json.RawMessage
+	UpdatedAt *time.Time // When the setting was last changed through the API; nil while it has its default
+}
+
+// settingKeys are the keys of the settings other than feature flags
+var settingKeys = []string{
+	"maintenance_mode",
+	"maintenance_message",
+	"requests_per_minute",
+	"logins_per_minute",
+	"max_booking_window_days",
+}
+
+// settingField returns a pointer to the field of a snapshot holding a setting, or nil for keys
+// that are not defined. Feature flags are keyed features.<name>.
+func settingField(settings *RuntimeSettings, key string) interface{} {
+	switch key {
+	case "maintenance_mode":
+		return &settings.MaintenanceMode
+	case "maintenance_message":
+		return &settings.MaintenanceMessage
+	case "requests_per_minute":
+		return &settings.RequestsPerMinute
+	case "logins_per_minute":
+		return &settings.LoginsPerMinute
+	case "max_booking_window_days":
+		return &settings.MaxBookingWindowDays
+	}
+	return nil
+}
+
+// settingsService serves the runtime settings from memory and reloads them from the database
+type settingsService struct {
+	repo         repository.RuntimeSettingRepository
+	auditLogRepo repository.AuditLogRepository
+	defaults     RuntimeSettings
+	logger       *zap.Logger
+
+	mu        sync.RWMutex
+	current   *RuntimeSettings
+	updatedAt map[string]time.Time
+}
+
+// NewSettingsService creates a new runtime settings service starting from the configured
+// defaults. Call Reload to apply the values stored in the database.
+func NewSettingsService(
+	repo repository.RuntimeSettingRepository,
+	auditLogRepo repository.AuditLogRepository,
+	cfg config.RuntimeConfig,
+	logger *zap.Logger,
+) SettingsService {
+	defaults := RuntimeSettings{
+		MaintenanceMode:      cfg.MaintenanceMode,
+		MaintenanceMessage:   cfg.MaintenanceMessage,
+		RequestsPerMinute:    cfg.RequestsPerMinute,
+		LoginsPerMinute:      cfg.LoginsPerMinute,
+		MaxBookingWindowDays: cfg.MaxBookingWindowDays,
+		Features:             make(map[string]bool, len(cfg.Features)+1),
+	}
+	defaults.Features[FeatureNoShowConfirmation] = false
+	for name, enabled := range cfg.Features {
+		defaults.Features[strings.ToLower(name)] = enabled
+	}
+
+	current := defaults.clone()
+	return &settingsService{
+		repo:         repo,
+		auditLogRepo: auditLogRepo,
+		defaults:     defaults,
+		logger:       logger,
+		current:      &current,
+		updatedAt:    map[string]time.Time{},
+	}
+}
+
+// Current returns the settings in effect
+func (s *settingsService) Current() *RuntimeSettings {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.current
+}
+
+// ListSettings lists every setting with its effective and default value, ordered by key
+func (s *settingsService) ListSettings(ctx context.Context) ([]*SettingValue, error) {
+	current := s.Current()
+	s.mu.RLock()
+	updatedAt := s.updatedAt
+	s.mu.RUnlock()
+
+	keys := s.keys()
+	values := make([]*SettingValue, 0, len(keys))
+	for _, key := range keys {
+		value := &SettingValue{
+			Key:     key,
+			Value:   encodeSetting(current, key),
+			Default: encodeSetting(&s.defaults, key),
+		}
+		if at, ok := updatedAt[key]; ok {
+			value.UpdatedAt = &at
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// UpdateSettings changes settings as userID. Every value is checked before any is stored, so an
+// invalid value leaves all settings unchanged. The new values apply on this instance at once and
+// on the others when they next reload.
+func (s *settingsService) UpdateSettings(ctx context.Context, userID uint, values map[string]json.RawMessage) (*RuntimeSettings, error) {
+	if len(values) == 0 {
+		return nil, fmt.Errorf("%w: no settings given", ErrInvalidSetting)
+	}
+
+	stored, err := s.repo.FindAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load settings: %w", err)
+	}
+	overrides := make(map[string]json.RawMessage, len(stored)+len(values))
+	for _, setting := range stored {
+		overrides[setting.Key] = json.RawMessage(setting.Value)
+	}
+
+	now := time.Now()
+	changed := make([]*model.RuntimeSetting, 0, len(values))
+	for key, value := range values {
+		if !s.known(key) {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownSetting, key)
+		}
+		overrides[key] = value
+		changed = append(changed, &model.RuntimeSetting{Key: key, Value: string(value), UpdatedBy: userID, UpdatedAt: now})
+	}
+	if _, err := s.build(overrides, true); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Save(ctx, changed); err != nil {
+		return nil, fmt.Errorf("failed to save settings: %w", err)
+	}
+	encoded, _ := json.Marshal(values)
+	s.audit(ctx, userID, AuditActionSettingsUpdated, string(encoded))
+
+	if err := s.Reload(ctx); err != nil {
+		return nil, err
+	}
+	return s.Current(), nil
+}
+
+// ResetSetting removes the value set for a setting through the API, so its configured default
+// applies again
+func (s *settingsService) ResetSetting(ctx context.Context, userID uint, key string) (*RuntimeSettings, error) {
+	if !s.known(key) {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownSetting, key)
+	}
+	if err := s.repo.Delete(ctx, key); err != nil {
+		return nil, fmt.Errorf("failed to reset setting: %w", err)
+	}
+	s.audit(ctx, userID, AuditActionSettingReset, key)
+
+	if err := s.Reload(ctx); err != nil {
+		return nil, err
+	}
+	return s.Current(), nil
+}
+
+// Reload applies the settings stored in the database. Stored values that are no longer valid,
+// such as flags removed from the configuration, are skipped with a warning.
+func (s *settingsService) Reload(ctx context.Context) error {
+	stored, err := s.repo.FindAll(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load settings: %w", err)
+	}
+
+	overrides := make(map[string]json.RawMessage, len(stored))
+	updatedAt := make(map[string]time.Time, len(stored))
+	for _, setting := range stored {
+		overrides[setting.Key] = json.RawMessage(setting.Value)
+		updatedAt[setting.Key] = setting.UpdatedAt
+	}
+	settings, _ := s.build(overrides, false)
+
+	s.mu.Lock()
+	s.current = settings
+	s.updatedAt = updatedAt
+	s.mu.Unlock()
+	return nil
+}
+
+// build applies overrides to the defaults. In strict mode the first invalid override is
+// returned as an error; otherwise it is logged and the default kept.
+func (s *settingsService) build(overrides map[string]json.RawMessage, strict bool) (*RuntimeSettings, error) {
+	settings := s.defaults.clone()
+	for key, value := range overrides {
+		err := applySetting(&settings, key, value)
+		if err == nil {
+			err = validateSetting(&settings, key)
+		}
+		if err == nil {
+			continue
+		}
+		if strict {
+			return nil, err
+		}
+		s.logger.Warn("Ignoring stored setting", zap.String("key", key), zap.Error(err))
+		_ = applySetting(&settings, key, encodeSetting(&s.defaults, key))
+	}
+	return &settings, nil
+}
+
+// known reports whether key names a defined setting or a configured feature flag
+func (s *settingsService) known(key string) bool {
+	if name, ok := strings.CutPrefix(key, "features."); ok {
+		_, exists := s.defaults.Features[name]
+		return exists
+	}
+	return settingField(&s.defaults, key) != nil
+}
+
+// keys returns every setting key in order
+func (s *settingsService) keys() []string {
+	keys := append([]string{}, settingKeys...)
+	for name := range s.defaults.Features {
+		keys = append(keys, "features."+name)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// audit records a settings change; failures are logged and do not undo the change
+func (s *settingsService) audit(ctx context.Context, userID uint, action, detail string) {
+	client := utils.ClientInfoFromContext(ctx)
+	if err := s.auditLogRepo.Create(ctx, &model.AuditLog{
+		UserID:     userID,
+		Action:     action,
+		EntityType: "settings",
+		NewValue:   detail,
+		IP:         client.IP,
+		UserAgent:  client.UserAgent,
+		CreatedAt:  time.Now(),
+	}); err != nil {
+		s.logger.Error("Failed to write settings audit log", zap.String("action", action), zap.Error(err))
+	}
+}
+
+// clone copies a snapshot, including its feature flags
+func (s RuntimeSettings) clone() RuntimeSettings {
+	features := make(map[string]bool, len(s.Features))
+	for name, enabled := range s.Features {
+		features[name] = enabled
+	}
+	s.Features = features
+	return s
+}
+
+// applySetting decodes a JSON value into the setting of a snapshot
+func applySetting(settings *RuntimeSettings, key string, value json.RawMessage) error {
+	if name, ok := strings.CutPrefix(key, "features."); ok {
+		if _, exists := settings.Features[name]; !exists {
+			return fmt.Errorf("%w: %s", ErrUnknownSetting, key)
+		}
+		var enabled bool
+		if err := json.Unmarshal(value, &enabled); err != nil {
+			return fmt.Errorf("%w: %s must be true or false", ErrInvalidSetting, key)
+		}
+		settings.Features[name] = enabled
+		return nil
+	}
+
+	field := settingField(settings, key)
+	if field == nil {
+		return fmt.Errorf("%w: %s", ErrUnknownSetting, key)
+	}
+	if err := json.Unmarshal(value, field); err != nil {
+		return fmt.Errorf("%w: %s has the wrong type", ErrInvalidSetting, key)
+	}
+	return nil
+}
+
+// validateSetting checks the range of a setting of a snapshot
+func validateSetting(settings *RuntimeSettings, key string) error {
+	switch key {
+	case "maintenance_message":
+		if len([]rune(settings.MaintenanceMessage)) > maxMaintenanceMessageLength {
+			return fmt.Errorf("%w: maintenance_message must be at most %d characters", ErrInvalidSetting, maxMaintenanceMessageLength)
+		}
+	case "requests_per_minute", "logins_per_minute":
+		value := settings.RequestsPerMinute
+		if key == "logins_per_minute" {
+			value = settings.LoginsPerMinute
+		}
+		if value < 0 || value > maxRequestsPerMinute {
+			return fmt.Errorf("%w: %s must be between 0 and %d", ErrInvalidSetting, key, maxRequestsPerMinute)
+		}
+	case "max_booking_window_days":
+		if settings.MaxBookingWindowDays < 0 || settings.MaxBookingWindowDays > maxBookingWindowDays {
+			return fmt.Errorf("%w: max_booking_window_days must be between 0 and %d", ErrInvalidSetting, maxBookingWindowDays)
+		}
+	}
+	return nil
+}
+
+// encodeSetting returns the JSON value of a setting of a snapshot
+func encodeSetting(settings *RuntimeSettings, key string) json.RawMessage {
+	var value interface{}
+	if name, ok := strings.CutPrefix(key, "features."); ok {
+		value = settings.Features[name]
+	} else {
+		value = settingField(settings, key)
+	}
+	encoded, _ := json.Marshal(value)
+	return encoded
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/repository"
+	"go.uber.org/zap"
+)
+
+// ErrShiftTemplateExists is returned when a shift template is given the name of another
+var ErrShiftTemplateExists = errors.New("a shift template with this name already exists")
+
+// maxShiftWindows bounds the windows of one shift template
+const maxShiftWindows = 50
+
+// ShiftWindowInput is a weekly window of a shift template as entered. Day is a weekday name or
+// 0-6 for Sunday-Saturday; times are HH:MM in the clinic timezone of the doctor it is applied to.
+type ShiftWindowInput struct {
+	Day       string
+	StartTime string
+	EndTime   string
+	Duration  int // Slot length in minutes; 0 for the doctor's consultation length
+}
+
+// ShiftTemplateInput is the name and windows of a shift template
+type ShiftTemplateInput struct {
+	Name        string
+	Description string
+	Windows     []ShiftWindowInput
+}
+
+type shiftTemplateService struct {
+	repo   repository.ShiftTemplateRepository
+	logger *zap.Logger
+}
+
+// NewShiftTemplateService creates a new shift template service
+func NewShiftTemplateService(repo repository.ShiftTemplateRepository, logger *zap.Logger) ShiftTemplateService {
+	return &shiftTemplateService{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// CreateShiftTemplate adds a reusable week of working windows
+func (s *shiftTemplateService) CreateShiftTemplate(ctx context.Context, input ShiftTemplateInput) (*model.ShiftTemplate, error) {
+	template := &model.ShiftTemplate{}
+	if err := s.apply(ctx, template, input); err != nil {
+		return nil, err
+	}
+
+	template.CreatedAt = time.Now()
+	template.UpdatedAt = time.Now()
+	if err := s.repo.Create(ctx, template); err != nil {
+		return nil, fmt.Errorf("failed to create shift template: %w", err)
+	}
+
+	s.logger.Info("Shift template created", zap.Uint("shiftTemplateID", template.ID), zap.String("name", template.Name))
+	return template, nil
+}
+
+// GetShiftTemplate gets a shift template by ID
+func (s *shiftTemplateService) GetShiftTemplate(ctx context.Context, id uint) (*model.ShiftTemplate, error) {
+	return s.repo.FindByID(ctx, id)
+}
+
+// ListShiftTemplates lists all shift templates by name
+func (s *shiftTemplateService) ListShiftTemplates(ctx context.Context) ([]*model.ShiftTemplate, error) {
+	return s.repo.FindAll(ctx)
+}
+
+// UpdateShiftTemplate replaces the name and windows of a shift template. Availability already
+// applied from it is left as it is.
+func (s *shiftTemplateService) UpdateShiftTemplate(ctx context.Context, id uint, input ShiftTemplateInput) (*model.ShiftTemplate, error) {
+	template, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.apply(ctx, template, input); err != nil {
+		return nil, err
+	}
+
+	template.UpdatedAt = time.Now()
+	if err := s.repo.Update(ctx, template); err != nil {
+		return nil, fmt.Errorf("failed to update shift template: %w", err)
+	}
+	return template, nil
+}
+
+// DeleteShiftTemplate deletes a shift template. Availability already applied from it is kept.
+func (s *shiftTemplateService) DeleteShiftTemplate(ctx context.Context, id uint) error {
+	if _, err := s.repo.FindByID(ctx, id); err != nil {
+		return err
+	}
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return fmt.Errorf("failed to delete shift template: %w", err)
+	}
+	return nil
+}
+
+// apply validates the input and sets it on the template. Names are unique regardless of case,
+// and windows on the same day cannot overlap.
+func (s *shiftTemplateService) apply(ctx context.Context, template *model.ShiftTemplate, input ShiftTemplateInput) error {
+	name := strings.TrimSpace(input.Name)
+	if name == "" || len(name) > 100 {
+		return errors.New("name is required and must be at most 100 characters")
+	}
+	if len(input.Windows) == 0 || len(input.Windows) > maxShiftWindows {
+		return fmt.Errorf("a shift template needs between 1 and %d windows", maxShiftWindows)
+	}
+
+	existing, err := s.repo.FindAll(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get shift templates: %w", err)
+	}
+	for _, other := range existing {
+		if other.ID != template.ID && strings.EqualFold(other.Name, name) {
+			return ErrShiftTemplateExists
+		}
+	}
+
+	windows := make([]model.ShiftWindow, 0, len(input.Windows))
+	checked := make([]*model.Availability, 0, len(input.Windows))
+	for i, entry := range input.Windows {
+		// Windows are checked as availability so the same rules apply once the template is used
+		window := &model.Availability{ID: uint(i + 1)}
+		if err := setAvailabilityWindow(window, entry.Day, entry.StartTime, entry.EndTime); err != nil {
+			return err
+		}
+		if entry.Duration != 0 && (entry.Duration < 5 || entry.Duration > 480) {
+			return errors.New("slot duration must be between 5 and 480 minutes")
+		}
+		if err := checkAvailabilityOverlap(checked, window); err != nil {
+			return err
+		}
+		checked = append(checked, window)
+		windows = append(windows, model.ShiftWindow{
+			DayOfWeek: window.DayOfWeek,
+			StartTime: window.StartTime,
+			EndTime:   window.EndTime,
+			Duration:  entry.Duration,
+		})
+	}
+
+	template.Name = name
+	template.Description = strings.TrimSpace(input.Description)
+	template.Windows = windows
+	return nil
+}
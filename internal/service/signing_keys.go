@@ -0,0 +1,51 @@
+package service
+
+import (
+	"sync"
+)
+
+// SigningKeys holds the secret signed links are issued with. A rotation keeps the previous
+// secret, so links issued shortly before it keep working until they expire.
+type SigningKeys struct {
+	mu       sync.RWMutex
+	current  []byte
+	previous []byte
+}
+
+// NewSigningKeys creates signing keys starting from secret
+func NewSigningKeys(secret string) *SigningKeys {
+	return &SigningKeys{current: []byte(secret)}
+}
+
+// Rotate switches signing to a new secret while still accepting links signed with the old one
+func (k *SigningKeys) Rotate(secret string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if secret == "" || secret == string(k.current) {
+		return
+	}
+	k.previous = k.current
+	k.current = []byte(secret)
+}
+
+// Current returns the secret new links are signed with
+func (k *SigningKeys) Current() []byte {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.current
+}
+
+// Verify calls verify with the current secret and then the previous one, returning the first
+// success or the error of the last attempt
+func (k *SigningKeys) Verify(verify func(secret []byte) (string, error)) (string, error) {
+	k.mu.RLock()
+	current, previous := k.current, k.previous
+	k.mu.RUnlock()
+
+	value, err := verify(current)
+	if err == nil || previous == nil {
+		return value, err
+	}
+	return verify(previous)
+}
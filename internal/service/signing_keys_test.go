@@ -0,0 +1,49 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSigningKeysAcceptLinksFromBeforeOneRotation(t *testing.T) {
+	keys := NewSigningKeys("first")
+	expiresAt := time.Now().Add(time.Hour)
+	link, err := signPortalInvite(keys.Current(), "user-public-id", expiresAt)
+	if err != nil {
+		t.Fatalf("signPortalInvite: %v", err)
+	}
+	verify := func(secret []byte) (string, error) {
+		return verifyPortalInvite(secret, link, time.Now())
+	}
+
+	keys.Rotate("second")
+	if string(keys.Current()) != "second" {
+		t.Fatalf("signing with %q after rotation, want the new secret", keys.Current())
+	}
+	if publicID, err := keys.Verify(verify); err != nil || publicID != "user-public-id" {
+		t.Fatalf("link from before the rotation: got %q, %v", publicID, err)
+	}
+
+	keys.Rotate("third")
+	if _, err := keys.Verify(verify); err == nil {
+		t.Error("link signed two rotations ago still verifies")
+	}
+}
+
+func TestSigningKeysIgnoreEmptyAndUnchangedSecrets(t *testing.T) {
+	keys := NewSigningKeys("first")
+	keys.Rotate("second")
+	keys.Rotate("")
+	keys.Rotate("second")
+
+	link, err := signPortalInvite([]byte("first"), "user-public-id", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("signPortalInvite: %v", err)
+	}
+	// The previous secret must still be the first one, not lost to a no-op rotation
+	if _, err := keys.Verify(func(secret []byte) (string, error) {
+		return verifyPortalInvite(secret, link, time.Now())
+	}); err != nil {
+		t.Errorf("no-op rotation dropped the previous secret: %v", err)
+	}
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+// SlotCache keeps each doctor's free slots per day for a short time, so browsing the slot
+// endpoints does not load the doctor's availability and appointments on every request. Services
+// changing what a doctor is booked for or when they work invalidate the doctor's days. The cache
+// is kept in memory, so another instance may serve a slot booked through this one until its
+// entry expires; booking it then fails with ErrScheduleConflict as before. A nil cache or one
+// with a TTL of 0 caches nothing.
+type SlotCache struct {
+	ttl  time.Duration
+	size int
+
+	mu      sync.Mutex
+	entries map[slotCacheKey]cachedSlots
+}
+
+type slotCacheKey struct {
+	doctorID uint
+	day      string // Clinic-local date
+}
+
+type cachedSlots struct {
+	slots   []Slot
+	expires time.Time
+}
+
+// NewSlotCache creates a slot cache holding up to size doctor days for ttl each
+func NewSlotCache(ttl time.Duration, size int) *SlotCache {
+	return &SlotCache{
+		ttl:     ttl,
+		size:    size,
+		entries: make(map[slotCacheKey]cachedSlots),
+	}
+}
+
+// get returns the cached slots of a doctor on the clinic-local day. The slots must not be
+// modified.
+func (c *SlotCache) get(doctorID uint, day time.Time) ([]Slot, bool) {
+	if c == nil || c.ttl <= 0 {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[slotCacheKey{doctorID: doctorID, day: day.Format("2006-01-02")}]
+	if !ok || !time.Now().Before(entry.expires) {
+		return nil, false
+	}
+	return entry.slots, true
+}
+
+// put caches the slots of a doctor on the clinic-local day
+func (c *SlotCache) put(doctorID uint, day time.Time, slots []Slot) {
+	if c == nil || c.ttl <= 0 {
+		return
+	}
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= c.size {
+		for key, entry := range c.entries {
+			if !now.Before(entry.expires) {
+				delete(c.entries, key)
+			}
+		}
+		// Still full of live entries: start over rather than track recency
+		if len(c.entries) >= c.size {
+			c.entries = make(map[slotCacheKey]cachedSlots)
+		}
+	}
+	c.entries[slotCacheKey{doctorID: doctorID, day: day.Format("2006-01-02")}] = cachedSlots{slots: slots, expires: now.Add(c.ttl)}
+}
+
+// Invalidate drops the cached slots of a doctor
+func (c *SlotCache) Invalidate(doctorID uint) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.entries {
+		if key.doctorID == doctorID {
+			delete(c.entries, key)
+		}
+	}
+}
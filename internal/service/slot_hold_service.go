@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/repository"
+	"github.com/whitewalker-sa/ehass/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// ErrSlotHeld is returned when a slot is held by another patient who is completing a booking
+var ErrSlotHeld = errors.New("slot is being booked by another patient")
+
+type slotHoldService struct {
+	holdRepo        repository.SlotHoldRepository
+	appointmentRepo repository.AppointmentRepository
+	orgService      OrganizationService
+	ttl             time.Duration
+	logger          *zap.Logger
+}
+
+// NewSlotHoldService creates a new slot hold service
+func NewSlotHoldService(
+	holdRepo repository.SlotHoldRepository,
+	appointmentRepo repository.AppointmentRepository,
+	orgService OrganizationService,
+	ttl time.Duration,
+	logger *zap.Logger,
+) SlotHoldService {
+	if ttl <= 0 {
+		ttl = 5 * time.Minute
+	}
+
+	return &slotHoldService{
+		holdRepo:        holdRepo,
+		appointmentRepo: appointmentRepo,
+		orgService:      orgService,
+		ttl:             ttl,
+		logger:          logger,
+	}
+}
+
+// HoldSlot reserves a free slot for a patient until the hold expires. Holding a slot the patient
+// already holds extends it.
+func (s *slotHoldService) HoldSlot(ctx context.Context, patientID, doctorID uint, date, timeStr string) (*model.SlotHold, error) {
+	start, err := parseDateTime(date, timeStr)
+	if err != nil {
+		return nil, errors.New("invalid date or time format")
+	}
+
+	org, err := s.orgService.GetDoctorOrganization(ctx, doctorID)
+	if err != nil {
+		return nil, err
+	}
+	end := start.Add(org.AppointmentLength())
+	if err := checkBookingRules(org, start, end, time.Now()); err != nil {
+		return nil, err
+	}
+
+	booked, err := s.appointmentRepo.FindByDoctorBetween(ctx, doctorID, start.Add(-24*time.Hour), end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check appointments: %w", err)
+	}
+	for _, appt := range booked {
+		if isActiveAppointment(appt) && appt.ScheduledStart.Before(end) && start.Before(appt.ScheduledEnd) {
+			return nil, errors.New("slot is already booked")
+		}
+	}
+
+	if existing, err := s.holdRepo.Find(ctx, doctorID, start); err == nil {
+		if existing.PatientID != patientID {
+			return nil, ErrSlotHeld
+		}
+		if _, err := s.holdRepo.DeleteByToken(ctx, existing.Token); err != nil {
+			return nil, fmt.Errorf("failed to extend hold: %w", err)
+		}
+	}
+
+	hold := &model.SlotHold{
+		Token:     utils.GenerateRandomToken(16),
+		DoctorID:  doctorID,
+		PatientID: patientID,
+		Start:     start,
+		ExpiresAt: time.Now().Add(s.ttl),
+	}
+	created, err := s.holdRepo.Create(ctx, hold)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hold slot: %w", err)
+	}
+	if !created {
+		return nil, ErrSlotHeld
+	}
+	return hold, nil
+}
+
+// ReleaseHold releases a hold before it expires, for example when the patient abandons checkout
+func (s *slotHoldService) ReleaseHold(ctx context.Context, token string) error {
+	_, err := s.holdRepo.DeleteByToken(ctx, token)
+	return err
+}
+
+// CheckSlot returns ErrSlotHeld if the slot is held by a patient other than the one booking
+func (s *slotHoldService) CheckSlot(ctx context.Context, patientID, doctorID uint, start time.Time) error {
+	hold, err := s.holdRepo.Find(ctx, doctorID, start)
+	if err != nil {
+		// An unreachable hold store does not block booking
+		if err.Error() != "slot hold not found" {
+			s.logger.Warn("Failed to check slot hold", zap.Uint("doctorID", doctorID), zap.Error(err))
+		}
+		return nil
+	}
+	if hold.PatientID != patientID {
+		return ErrSlotHeld
+	}
+	return nil
+}
+
+// ReleaseSlot releases the hold on a slot once it has been booked
+func (s *slotHoldService) ReleaseSlot(ctx context.Context, doctorID uint, start time.Time) {
+	if err := s.holdRepo.DeleteSlot(ctx, doctorID, start); err != nil {
+		s.logger.Warn("Failed to release slot hold", zap.Uint("doctorID", doctorID), zap.Error(err))
+	}
+}
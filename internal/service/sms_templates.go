@@ -0,0 +1,44 @@
+package service
+
+import "fmt"
+
+// Text message templates. SMS bodies are plain text and kept short enough for a single message
+// where possible.
+const (
+	SMSTemplateReminder         = "appointment_reminder_sms"
+	SMSTemplateConfirmationCode = "booking_confirmation_code_sms"
+	SMSTemplateAccountClaim     = "account_claim_sms"
+	SMSTemplateMarketing        = "marketing_sms"
+)
+
+// reminderSMS reminds a patient of an upcoming appointment. startsAt is already formatted in the
+// recipient's timezone and locale. Directions add the clinic's address and map link; arrival
+// instructions are left to the email to keep the message short.
+func reminderSMS(doctorName, startsAt string, directions *Directions) string {
+	message := fmt.Sprintf("Reminder: your appointment with %s is on %s.", doctorName, startsAt)
+	if directions == nil {
+		return message
+	}
+	if directions.Address != "" {
+		message += " Address: " + directions.Address + "."
+	}
+	if directions.MapURL != "" {
+		message += " Directions: " + directions.MapURL
+	}
+	return message
+}
+
+// confirmationCodeSMS asks a high-risk patient to confirm their booking with a code
+func confirmationCodeSMS(startsAt, code string) string {
+	return fmt.Sprintf("Please confirm your appointment on %s with code %s.", startsAt, code)
+}
+
+// accountClaimSMS invites a patient without an email address to claim their record
+func accountClaimSMS(code string) string {
+	return fmt.Sprintf("Your clinic has created a patient record for you. Set up your online account with code %s (valid for 7 days).", code)
+}
+
+// marketingSMS is a promotional message from the clinic, with how to opt out
+func marketingSMS(message string) string {
+	return message + " Opt out of these messages in your account settings."
+}
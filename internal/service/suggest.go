@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	defaultSuggestLimit = 5
+	maxSuggestLimit     = 10
+)
+
+// DoctorSuggestion is a doctor offered while typing in a booking form
+type DoctorSuggestion struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Specialty string `json:"specialty"`
+}
+
+// suggestionCache keeps typeahead results for a short time. Each keystroke of every booking
+// form asks for suggestions, and the answers for the same prefix rarely change.
+type suggestionCache struct {
+	ttl  time.Duration
+	size int
+
+	mu      sync.Mutex
+	entries map[string]cachedSuggestions
+}
+
+type cachedSuggestions struct {
+	value   interface{}
+	expires time.Time
+}
+
+func newSuggestionCache(ttl time.Duration, size int) *suggestionCache {
+	return &suggestionCache{
+		ttl:     ttl,
+		size:    size,
+		entries: make(map[string]cachedSuggestions),
+	}
+}
+
+// get returns the cached value for key, or calls load and caches what it returns
+func (c *suggestionCache) get(key string, load func() (interface{}, error)) (interface{}, error) {
+	if c.ttl <= 0 {
+		return load()
+	}
+
+	now := time.Now()
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok && now.Before(entry.expires) {
+		return entry.value, nil
+	}
+
+	value, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= c.size {
+		for k, e := range c.entries {
+			if !now.Before(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+		// Still full of live entries: start over rather than track recency
+		if len(c.entries) >= c.size {
+			c.entries = make(map[string]cachedSuggestions)
+		}
+	}
+	c.entries[key] = cachedSuggestions{value: value, expires: now.Add(c.ttl)}
+	return value, nil
+}
+
+// suggestPrefix normalizes a typeahead prefix and limit
+func suggestPrefix(prefix string, limit int) (string, int, error) {
+	prefix = strings.ToLower(strings.TrimSpace(prefix))
+	if utf8.RuneCountInString(prefix) < minSearchLength {
+		return "", 0, ErrSearchQueryTooShort
+	}
+	if limit <= 0 {
+		limit = defaultSuggestLimit
+	}
+	if limit > maxSuggestLimit {
+		limit = maxSuggestLimit
+	}
+	return prefix, limit, nil
+}
+
+// SuggestDoctors returns doctors with a word of their name starting with prefix
+func (s *searchService) SuggestDoctors(ctx context.Context, prefix string, limit int) ([]DoctorSuggestion, error) {
+	prefix, limit, err := suggestPrefix(prefix, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := s.suggestions.get(fmt.Sprintf("doctor:%d:%s", limit, prefix), func() (interface{}, error) {
+		found, err := s.doctorRepo.SuggestByName(ctx, prefix, limit)
+		if err != nil {
+			return nil, fmt.Errorf("failed to suggest doctors: %w", err)
+		}
+		suggestions := make([]DoctorSuggestion, 0, len(found))
+		for _, doctor := range found {
+			suggestions = append(suggestions, DoctorSuggestion{ID: doctor.PublicID, Name: doctor.Name, Specialty: doctor.Specialty})
+		}
+		return suggestions, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.([]DoctorSuggestion), nil
+}
+
+// SuggestSpecialties returns specialties with a word starting with prefix and how many doctors
+// practise each, most common first
+func (s *searchService) SuggestSpecialties(ctx context.Context, prefix string, limit int) ([]FacetCount, error) {
+	prefix, limit, err := suggestPrefix(prefix, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := s.suggestions.get(fmt.Sprintf("specialty:%d:%s", limit, prefix), func() (interface{}, error) {
+		counts, err := s.doctorRepo.SuggestSpecialties(ctx, prefix, limit)
+		if err != nil {
+			return nil, fmt.Errorf("failed to suggest specialties: %w", err)
+		}
+		suggestions := make([]FacetCount, 0, len(counts))
+		for _, count := range counts {
+			suggestions = append(suggestions, FacetCount{Value: count.Specialty, Count: count.Count})
+		}
+		return suggestions, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.([]FacetCount), nil
+}
@@ -0,0 +1,481 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/config"
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/repository"
+	"go.uber.org/zap"
+)
+
+// Waiting room participant states
+const (
+	ParticipantWaiting  = "waiting"
+	ParticipantAdmitted = "admitted"
+)
+
+// maxWaitingRoomEvents bounds the events returned by one read of an event stream
+const maxWaitingRoomEvents = 100
+
+var (
+	// ErrNotVideoVisit is returned when using the waiting room of an appointment that does not
+	// take place by video
+	ErrNotVideoVisit = errors.New("appointment is not a video visit")
+	// ErrWaitingRoomClosed is returned when joining a waiting room outside the appointment's
+	// time, before it is confirmed, or after the doctor ended the visit
+	ErrWaitingRoomClosed = errors.New("waiting room is not open")
+	// ErrNotVisitParticipant is returned when someone who is not one of the appointment's
+	// patients or their guardian uses its waiting room
+	ErrNotVisitParticipant = errors.New("only the appointment's patients can use its waiting room")
+	// ErrNotVisitDoctor is returned when someone other than the appointment's doctor admits
+	// patients or ends the visit
+	ErrNotVisitDoctor = errors.New("only the appointment's doctor can admit patients or end the visit")
+	// ErrNobodyWaiting is returned when admitting patients to a waiting room that is empty
+	ErrNobodyWaiting = errors.New("nobody is waiting")
+)
+
+// WaitingRoomParticipant is a user in a video visit's waiting room or already admitted to it
+type WaitingRoomParticipant struct {
+	User  *model.User
+	State string    // ParticipantWaiting or ParticipantAdmitted
+	Since time.Time // When the user entered the state
+}
+
+// WaitingRoom is the state of a video visit: who is waiting, who was admitted and the times
+// recorded so far
+type WaitingRoom struct {
+	Appointment  *model.Appointment
+	Visit        *model.TelehealthVisit // nil until somebody first joins
+	Participants []*WaitingRoomParticipant
+}
+
+// WaitingRoomFilter selects the waiting room events an event stream receives: those of one
+// appointment or of all of a doctor's appointments
+type WaitingRoomFilter struct {
+	AppointmentID uint
+	DoctorID      uint
+}
+
+// VisitTiming is how long patients of one video visit waited and how long the visit lasted.
+// Wait is nil until the doctor admits them and Duration until the visit ends.
+type VisitTiming struct {
+	Visit    *model.TelehealthVisit
+	Wait     *time.Duration
+	Duration *time.Duration
+}
+
+// VisitReport summarizes the video visits whose waiting room opened in a date range
+type VisitReport struct {
+	From            time.Time
+	To              time.Time
+	Visits          []*VisitTiming
+	AverageWait     time.Duration // Over visits where patients were admitted
+	AverageDuration time.Duration // Over visits that ended after patients were admitted
+}
+
+type telehealthService struct {
+	repo            repository.TelehealthRepository
+	appointmentRepo repository.AppointmentRepository
+	doctorRepo      repository.DoctorRepository
+	patientRepo     repository.PatientRepository
+	config          config.TelehealthConfig
+	logger          *zap.Logger
+
+	// changed is closed and replaced whenever this instance records waiting room events, waking
+	// the event streams it serves; changes made on other instances are picked up by polling
+	mu      sync.Mutex
+	changed chan struct{}
+}
+
+// NewTelehealthService creates a new telehealth service
+func NewTelehealthService(
+	repo repository.TelehealthRepository,
+	appointmentRepo repository.AppointmentRepository,
+	doctorRepo repository.DoctorRepository,
+	patientRepo repository.PatientRepository,
+	cfg config.TelehealthConfig,
+	logger *zap.Logger,
+) TelehealthService {
+	return &telehealthService{
+		repo:            repo,
+		appointmentRepo: appointmentRepo,
+		doctorRepo:      doctorRepo,
+		patientRepo:     patientRepo,
+		config:          cfg,
+		logger:          logger,
+		changed:         make(chan struct{}),
+	}
+}
+
+// GetWaitingRoom returns the waiting room of a video appointment to one of its patients, its
+// doctor or an admin
+func (s *telehealthService) GetWaitingRoom(ctx context.Context, appointmentID, userID uint, role model.Role) (*WaitingRoom, error) {
+	appointment, err := s.videoAppointment(ctx, appointmentID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authorizeView(ctx, appointment, userID, role); err != nil {
+		return nil, err
+	}
+	return s.waitingRoom(ctx, appointment)
+}
+
+// JoinWaitingRoom puts the patient signed in as userID in the waiting room, from JoinBefore the
+// appointment's start until its end. The first patient to join opens the room and notifies the
+// doctor. Joining again while waiting or admitted changes nothing.
+func (s *telehealthService) JoinWaitingRoom(ctx context.Context, appointmentID, userID uint) (*WaitingRoom, error) {
+	appointment, err := s.videoAppointment(ctx, appointmentID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authorizePatient(ctx, appointment, userID); err != nil {
+		return nil, err
+	}
+	if appointment.Status != model.AppointmentStatusConfirmed && appointment.Status != model.AppointmentStatusCheckedIn {
+		return nil, ErrWaitingRoomClosed
+	}
+	now := time.Now()
+	if now.Before(appointment.ScheduledStart.Add(-s.config.JoinBefore)) || !now.Before(appointment.ScheduledEnd) {
+		return nil, ErrWaitingRoomClosed
+	}
+
+	_, err = s.repo.UpdateVisit(ctx, appointment.ID, appointment.DoctorID, func(visit *model.TelehealthVisit) ([]*model.WaitingRoomEvent, error) {
+		if visit.EndedAt != nil {
+			return nil, ErrWaitingRoomClosed
+		}
+		states, err := s.participantStates(ctx, appointment.ID)
+		if err != nil {
+			return nil, err
+		}
+		if _, present := states[userID]; present {
+			return nil, nil
+		}
+
+		events := []*model.WaitingRoomEvent{s.event(appointment, model.WaitingRoomJoined, userID, now)}
+		if visit.WaitingSince == nil {
+			visit.WaitingSince = &now
+		}
+		if visit.DoctorNotifiedAt == nil {
+			visit.DoctorNotifiedAt = &now
+			events = append(events, s.event(appointment, model.WaitingRoomDoctorNotified, appointment.Doctor.UserID, now))
+		}
+		return events, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.notify()
+	return s.waitingRoom(ctx, appointment)
+}
+
+// LeaveWaitingRoom takes the patient signed in as userID out of the waiting room or the visit.
+// Leaving when not present changes nothing.
+func (s *telehealthService) LeaveWaitingRoom(ctx context.Context, appointmentID, userID uint) (*WaitingRoom, error) {
+	appointment, err := s.videoAppointment(ctx, appointmentID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authorizePatient(ctx, appointment, userID); err != nil {
+		return nil, err
+	}
+
+	_, err = s.repo.UpdateVisit(ctx, appointment.ID, appointment.DoctorID, func(visit *model.TelehealthVisit) ([]*model.WaitingRoomEvent, error) {
+		states, err := s.participantStates(ctx, appointment.ID)
+		if err != nil {
+			return nil, err
+		}
+		if _, present := states[userID]; !present {
+			return nil, nil
+		}
+		return []*model.WaitingRoomEvent{s.event(appointment, model.WaitingRoomLeft, userID, time.Now())}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.notify()
+	return s.waitingRoom(ctx, appointment)
+}
+
+// AdmitPatients lets everyone in the waiting room into the visit, as the appointment's doctor
+// signed in as userID. The first admission records when the visit started.
+func (s *telehealthService) AdmitPatients(ctx context.Context, appointmentID, userID uint) (*WaitingRoom, error) {
+	appointment, err := s.videoAppointment(ctx, appointmentID)
+	if err != nil {
+		return nil, err
+	}
+	if appointment.Doctor.UserID != userID {
+		return nil, ErrNotVisitDoctor
+	}
+
+	visit, err := s.repo.UpdateVisit(ctx, appointment.ID, appointment.DoctorID, func(visit *model.TelehealthVisit) ([]*model.WaitingRoomEvent, error) {
+		if visit.EndedAt != nil {
+			return nil, ErrWaitingRoomClosed
+		}
+		states, err := s.participantStates(ctx, appointment.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		now := time.Now()
+		var events []*model.WaitingRoomEvent
+		for participantID, state := range states {
+			if state.State == ParticipantWaiting {
+				events = append(events, s.event(appointment, model.WaitingRoomAdmitted, participantID, now))
+			}
+		}
+		if len(events) == 0 {
+			return nil, ErrNobodyWaiting
+		}
+		if visit.AdmittedAt == nil {
+			visit.AdmittedAt = &now
+		}
+		return events, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.appointmentRepo.MarkVisitStarted(ctx, appointment.ID, *visit.AdmittedAt); err != nil {
+		s.logger.Warn("Failed to record visit start", zap.Uint("appointmentID", appointment.ID), zap.Error(err))
+	}
+	s.notify()
+	return s.waitingRoom(ctx, appointment)
+}
+
+// EndVisit closes the waiting room and the visit as the appointment's doctor signed in as
+// userID, recording when the visit ended. Ending an ended visit changes nothing.
+func (s *telehealthService) EndVisit(ctx context.Context, appointmentID, userID uint) (*WaitingRoom, error) {
+	appointment, err := s.videoAppointment(ctx, appointmentID)
+	if err != nil {
+		return nil, err
+	}
+	if appointment.Doctor.UserID != userID {
+		return nil, ErrNotVisitDoctor
+	}
+
+	visit, err := s.repo.UpdateVisit(ctx, appointment.ID, appointment.DoctorID, func(visit *model.TelehealthVisit) ([]*model.WaitingRoomEvent, error) {
+		if visit.EndedAt != nil {
+			return nil, nil
+		}
+		now := time.Now()
+		visit.EndedAt = &now
+		return []*model.WaitingRoomEvent{s.event(appointment, model.WaitingRoomVisitEnded, userID, now)}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.appointmentRepo.MarkVisitEnded(ctx, appointment.ID, *visit.EndedAt); err != nil {
+		s.logger.Warn("Failed to record visit end", zap.Uint("appointmentID", appointment.ID), zap.Error(err))
+	}
+	s.notify()
+	return s.waitingRoom(ctx, appointment)
+}
+
+// AppointmentEventFilter selects the events of one video appointment's waiting room for one of
+// its patients, its doctor or an admin
+func (s *telehealthService) AppointmentEventFilter(ctx context.Context, appointmentID, userID uint, role model.Role) (WaitingRoomFilter, error) {
+	appointment, err := s.videoAppointment(ctx, appointmentID)
+	if err != nil {
+		return WaitingRoomFilter{}, err
+	}
+	if err := s.authorizeView(ctx, appointment, userID, role); err != nil {
+		return WaitingRoomFilter{}, err
+	}
+	return WaitingRoomFilter{AppointmentID: appointment.ID}, nil
+}
+
+// DoctorEventFilter selects the waiting room events of all appointments of the doctor signed in
+// as userID, so they are told when patients arrive
+func (s *telehealthService) DoctorEventFilter(ctx context.Context, userID uint) (WaitingRoomFilter, error) {
+	doctor, err := s.doctorRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return WaitingRoomFilter{}, err
+	}
+	return WaitingRoomFilter{DoctorID: doctor.ID}, nil
+}
+
+// LatestWaitingRoomEvent returns the number of the latest event matching filter, where a new
+// event stream starts so it only receives what happens next
+func (s *telehealthService) LatestWaitingRoomEvent(ctx context.Context, filter WaitingRoomFilter) (uint, error) {
+	return s.repo.LastEventID(ctx, repository.WaitingRoomEventFilter(filter))
+}
+
+// WaitingRoomEvents returns the events matching filter after the event numbered afterID, and a
+// channel closed when this instance next records events
+func (s *telehealthService) WaitingRoomEvents(ctx context.Context, filter WaitingRoomFilter, afterID uint) ([]*model.WaitingRoomEvent, <-chan struct{}, error) {
+	// Take the signal before reading so events recorded meanwhile are not missed
+	s.mu.Lock()
+	changed := s.changed
+	s.mu.Unlock()
+
+	events, err := s.repo.FindEventsAfter(ctx, repository.WaitingRoomEventFilter(filter), afterID, maxWaitingRoomEvents)
+	if err != nil {
+		return nil, nil, err
+	}
+	return events, changed, nil
+}
+
+// VisitReport reports wait times and durations of the video visits whose waiting room opened
+// from fromDate to toDate (YYYY-MM-DD, inclusive, UTC), optionally for one doctor
+func (s *telehealthService) VisitReport(ctx context.Context, fromDate, toDate string, doctorID uint) (*VisitReport, error) {
+	from, err := time.Parse("2006-01-02", fromDate)
+	if err != nil {
+		return nil, errors.New("invalid from date, expected YYYY-MM-DD")
+	}
+	lastDay, err := time.Parse("2006-01-02", toDate)
+	if err != nil {
+		return nil, errors.New("invalid to date, expected YYYY-MM-DD")
+	}
+	to := lastDay.AddDate(0, 0, 1)
+	if !from.Before(to) {
+		return nil, errors.New("from must not be after to")
+	}
+
+	visits, err := s.repo.FindVisits(ctx, from, to, doctorID)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &VisitReport{From: from, To: to, Visits: make([]*VisitTiming, 0, len(visits))}
+	var totalWait, totalDuration time.Duration
+	var admitted, ended int
+	for _, visit := range visits {
+		timing := &VisitTiming{Visit: visit}
+		if visit.WaitingSince != nil && visit.AdmittedAt != nil {
+			wait := visit.AdmittedAt.Sub(*visit.WaitingSince)
+			timing.Wait = &wait
+			totalWait += wait
+			admitted++
+		}
+		if visit.AdmittedAt != nil && visit.EndedAt != nil {
+			duration := visit.EndedAt.Sub(*visit.AdmittedAt)
+			timing.Duration = &duration
+			totalDuration += duration
+			ended++
+		}
+		report.Visits = append(report.Visits, timing)
+	}
+	if admitted > 0 {
+		report.AverageWait = totalWait / time.Duration(admitted)
+	}
+	if ended > 0 {
+		report.AverageDuration = totalDuration / time.Duration(ended)
+	}
+	return report, nil
+}
+
+// videoAppointment finds an appointment that takes place by video
+func (s *telehealthService) videoAppointment(ctx context.Context, id uint) (*model.Appointment, error) {
+	appointment, err := s.appointmentRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if appointment.Modality != model.ModalityVideo {
+		return nil, ErrNotVideoVisit
+	}
+	return appointment, nil
+}
+
+// authorizePatient checks that the user signed in as userID is one of the appointment's
+// patients or the guardian of one
+func (s *telehealthService) authorizePatient(ctx context.Context, appointment *model.Appointment, userID uint) error {
+	caller, err := s.patientRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return ErrNotVisitParticipant
+	}
+	patients := []*model.Patient{&appointment.Patient}
+	for i := range appointment.Participants {
+		patients = append(patients, &appointment.Participants[i].Patient)
+	}
+	for _, patient := range patients {
+		if patient.ID == caller.ID || (patient.GuardianID != nil && *patient.GuardianID == caller.ID) {
+			return nil
+		}
+	}
+	return ErrNotVisitParticipant
+}
+
+// authorizeView checks that the user may follow the appointment's waiting room: an admin, its
+// doctor, or one of its patients
+func (s *telehealthService) authorizeView(ctx context.Context, appointment *model.Appointment, userID uint, role model.Role) error {
+	switch {
+	case role == model.RoleAdmin:
+		return nil
+	case appointment.Doctor.UserID == userID:
+		return nil
+	case role == model.RoleDoctor:
+		return ErrNotVisitDoctor
+	default:
+		return s.authorizePatient(ctx, appointment, userID)
+	}
+}
+
+// waitingRoom builds the current state of an appointment's waiting room
+func (s *telehealthService) waitingRoom(ctx context.Context, appointment *model.Appointment) (*WaitingRoom, error) {
+	room := &WaitingRoom{Appointment: appointment, Participants: []*WaitingRoomParticipant{}}
+	visit, err := s.repo.FindVisit(ctx, appointment.ID)
+	if err != nil {
+		// Nobody has joined yet
+		return room, nil
+	}
+	room.Visit = visit
+
+	states, err := s.participantStates(ctx, appointment.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, state := range states {
+		room.Participants = append(room.Participants, state)
+	}
+	sort.Slice(room.Participants, func(i, j int) bool {
+		return room.Participants[i].Since.Before(room.Participants[j].Since)
+	})
+	return room, nil
+}
+
+// participantStates replays an appointment's waiting room events into the state of each user
+// still present, keyed by user ID
+func (s *telehealthService) participantStates(ctx context.Context, appointmentID uint) (map[uint]*WaitingRoomParticipant, error) {
+	events, err := s.repo.FindEvents(ctx, appointmentID)
+	if err != nil {
+		return nil, err
+	}
+	states := make(map[uint]*WaitingRoomParticipant)
+	for _, event := range events {
+		user := event.User
+		switch event.Type {
+		case model.WaitingRoomJoined:
+			states[event.UserID] = &WaitingRoomParticipant{User: &user, State: ParticipantWaiting, Since: event.CreatedAt}
+		case model.WaitingRoomAdmitted:
+			states[event.UserID] = &WaitingRoomParticipant{User: &user, State: ParticipantAdmitted, Since: event.CreatedAt}
+		case model.WaitingRoomLeft:
+			delete(states, event.UserID)
+		case model.WaitingRoomVisitEnded:
+			states = make(map[uint]*WaitingRoomParticipant)
+		}
+	}
+	return states, nil
+}
+
+// event creates a waiting room event of an appointment
+func (s *telehealthService) event(appointment *model.Appointment, eventType string, userID uint, at time.Time) *model.WaitingRoomEvent {
+	return &model.WaitingRoomEvent{
+		AppointmentID: appointment.ID,
+		DoctorID:      appointment.DoctorID,
+		Type:          eventType,
+		UserID:        userID,
+		CreatedAt:     at,
+	}
+}
+
+// notify wakes the event streams served by this instance
+func (s *telehealthService) notify() {
+	s.mu.Lock()
+	close(s.changed)
+	s.changed = make(chan struct{})
+	s.mu.Unlock()
+}
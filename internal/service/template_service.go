@@ -0,0 +1,283 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"regexp"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/pkg/sms"
+	"github.com/whitewalker-sa/ehass/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// Channels messages are sent on
+const (
+	TemplateChannelEmail = "email"
+	TemplateChannelSMS   = "sms"
+)
+
+var (
+	// ErrTemplateNotFound is returned for a template name that is not known
+	ErrTemplateNotFound = errors.New("template not found")
+	// ErrInvalidRecipient is returned when a test message's recipient does not suit the channel
+	ErrInvalidRecipient = errors.New("invalid recipient")
+)
+
+// testPhonePattern accepts phone numbers in E.164 format
+var testPhonePattern = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+
+// MessageTemplate describes an email or text message the service sends
+type MessageTemplate struct {
+	Name        string
+	Channel     string
+	Description string
+}
+
+// RenderedMessage is a template rendered with sample data
+type RenderedMessage struct {
+	Template    string
+	Channel     string
+	Subject     string   // Emails only
+	Body        string   // HTML for emails, plain text for SMS
+	Attachments []string // Filenames of attached files
+}
+
+// templateSample holds the sample data templates are rendered with
+type templateSample struct {
+	Name        string
+	DoctorName  string
+	PatientName string
+	StartsAt    string
+	PreviousAt  string
+	DueOn       string
+	Code        string
+	Token       string
+	Directions  *Directions
+}
+
+// messageTemplate renders a template with sample data, through the email service for emails
+type messageTemplate struct {
+	MessageTemplate
+	email func(s EmailService, ctx context.Context, to string, sample templateSample) error
+	sms   func(sample templateSample) string
+}
+
+// messageTemplates are the templates that can be previewed and test-sent, in listing order
+var messageTemplates = []messageTemplate{
+	{
+		MessageTemplate: MessageTemplate{EmailTemplateVerification, TemplateChannelEmail, "Email address verification link sent on registration"},
+		email: func(s EmailService, ctx context.Context, to string, d templateSample) error {
+			return s.SendVerificationEmail(ctx, to, d.Name, d.Token)
+		},
+	},
+	{
+		MessageTemplate: MessageTemplate{EmailTemplatePasswordReset, TemplateChannelEmail, "Password reset link"},
+		email: func(s EmailService, ctx context.Context, to string, d templateSample) error {
+			return s.SendPasswordResetEmail(ctx, to, d.Name, d.Token)
+		},
+	},
+	{
+		MessageTemplate: MessageTemplate{EmailTemplateBreakGlassAlert, TemplateChannelEmail, "Alert to administrators of emergency access to a patient record"},
+		email: func(s EmailService, ctx context.Context, to string, d templateSample) error {
+			return s.SendBreakGlassAlert(ctx, to, d.Name, d.DoctorName, d.PatientName, "Unconscious patient in the emergency room", d.StartsAt)
+		},
+	},
+	{
+		MessageTemplate: MessageTemplate{EmailTemplateReminder, TemplateChannelEmail, "Reminder of an upcoming appointment"},
+		email: func(s EmailService, ctx context.Context, to string, d templateSample) error {
+			_, err := s.SendAppointmentReminder(ctx, to, d.Name, d.DoctorName, d.StartsAt, d.Directions)
+			return err
+		},
+	},
+	{
+		MessageTemplate: MessageTemplate{EmailTemplateConfirmation, TemplateChannelEmail, "Confirmation of a new booking"},
+		email: func(s EmailService, ctx context.Context, to string, d templateSample) error {
+			return s.SendAppointmentConfirmation(ctx, to, d.Name, d.DoctorName, d.StartsAt)
+		},
+	},
+	{
+		MessageTemplate: MessageTemplate{EmailTemplateRescheduled, TemplateChannelEmail, "New time of a rescheduled appointment"},
+		email: func(s EmailService, ctx context.Context, to string, d templateSample) error {
+			return s.SendAppointmentRescheduled(ctx, to, d.Name, d.DoctorName, d.StartsAt)
+		},
+	},
+	{
+		MessageTemplate: MessageTemplate{EmailTemplateDoctorMoved, TemplateChannelEmail, "Notice to the doctor of a rescheduled appointment"},
+		email: func(s EmailService, ctx context.Context, to string, d templateSample) error {
+			return s.SendDoctorAppointmentRescheduled(ctx, to, d.DoctorName, d.PatientName, d.PreviousAt, d.StartsAt)
+		},
+	},
+	{
+		MessageTemplate: MessageTemplate{EmailTemplateCancellation, TemplateChannelEmail, "Notice of a cancelled appointment"},
+		email: func(s EmailService, ctx context.Context, to string, d templateSample) error {
+			return s.SendAppointmentCancellation(ctx, to, d.Name, d.DoctorName, d.StartsAt)
+		},
+	},
+	{
+		MessageTemplate: MessageTemplate{EmailTemplateConfirmed, TemplateChannelEmail, "Notice that the doctor confirmed a pending booking"},
+		email: func(s EmailService, ctx context.Context, to string, d templateSample) error {
+			return s.SendAppointmentConfirmed(ctx, to, d.Name, d.DoctorName, d.StartsAt)
+		},
+	},
+	{
+		MessageTemplate: MessageTemplate{EmailTemplateDeclined, TemplateChannelEmail, "Notice that the doctor declined a pending booking, with their reason"},
+		email: func(s EmailService, ctx context.Context, to string, d templateSample) error {
+			return s.SendAppointmentDeclined(ctx, to, d.Name, d.DoctorName, d.StartsAt, "The doctor is attending a conference that day")
+		},
+	},
+	{
+		MessageTemplate: MessageTemplate{EmailTemplateAccountClaim, TemplateChannelEmail, "Invitation to claim a patient record created by the clinic"},
+		email: func(s EmailService, ctx context.Context, to string, d templateSample) error {
+			return s.SendAccountClaimInvite(ctx, to, d.Name, d.Code, "sample-setup-token", 7)
+		},
+	},
+	{
+		MessageTemplate: MessageTemplate{EmailTemplateCareReminder, TemplateChannelEmail, "Reminder of preventive care coming due"},
+		email: func(s EmailService, ctx context.Context, to string, d templateSample) error {
+			return s.SendCareReminder(ctx, to, d.Name, "Annual check-up", d.DueOn)
+		},
+	},
+	{
+		MessageTemplate: MessageTemplate{EmailTemplateMarketing, TemplateChannelEmail, "Promotional message, sent only to users who opted in to marketing emails"},
+		email: func(s EmailService, ctx context.Context, to string, d templateSample) error {
+			_, err := s.SendMarketingEmail(ctx, to, d.Name, "Flu Vaccinations Now Available", "Flu vaccinations are now available at the clinic.\n\nBook a slot online at any time.")
+			return err
+		},
+	},
+	{
+		MessageTemplate: MessageTemplate{SMSTemplateReminder, TemplateChannelSMS, "Reminder of an upcoming appointment"},
+		sms:             func(d templateSample) string { return reminderSMS(d.DoctorName, d.StartsAt, d.Directions) },
+	},
+	{
+		MessageTemplate: MessageTemplate{SMSTemplateConfirmationCode, TemplateChannelSMS, "Code confirming a booking by a patient at high risk of not showing up"},
+		sms:             func(d templateSample) string { return confirmationCodeSMS(d.StartsAt, d.Code) },
+	},
+	{
+		MessageTemplate: MessageTemplate{SMSTemplateAccountClaim, TemplateChannelSMS, "Invitation to claim a patient record, for patients without an email address"},
+		sms:             func(d templateSample) string { return accountClaimSMS(d.Code) },
+	},
+	{
+		MessageTemplate: MessageTemplate{SMSTemplateMarketing, TemplateChannelSMS, "Promotional message, sent only to users who opted in to marketing texts"},
+		sms: func(d templateSample) string {
+			return marketingSMS("Flu vaccinations are now available at the clinic.")
+		},
+	},
+}
+
+type templateService struct {
+	emailService EmailService
+	smsSender    sms.Sender
+	logger       *zap.Logger
+}
+
+// NewTemplateService creates a new message template service
+func NewTemplateService(emailService EmailService, smsSender sms.Sender, logger *zap.Logger) TemplateService {
+	return &templateService{
+		emailService: emailService,
+		smsSender:    smsSender,
+		logger:       logger,
+	}
+}
+
+// ListTemplates lists the email and SMS templates
+func (s *templateService) ListTemplates() []MessageTemplate {
+	templates := make([]MessageTemplate, len(messageTemplates))
+	for i, t := range messageTemplates {
+		templates[i] = t.MessageTemplate
+	}
+	return templates
+}
+
+// Preview renders a template with sample data without sending or recording it
+func (s *templateService) Preview(ctx context.Context, name string) (*RenderedMessage, error) {
+	t, err := findTemplate(name)
+	if err != nil {
+		return nil, err
+	}
+	return s.render(ctx, t, "preview@example.com")
+}
+
+// SendTest renders a template with sample data and sends it to an email address or, for SMS
+// templates, a phone number in E.164 format. Test emails are recorded like any other, so they
+// show in the delivery log and respect suppressions.
+func (s *templateService) SendTest(ctx context.Context, name, to string) (*RenderedMessage, error) {
+	t, err := findTemplate(name)
+	if err != nil {
+		return nil, err
+	}
+	if t.Channel == TemplateChannelSMS && !testPhonePattern.MatchString(to) {
+		return nil, fmt.Errorf("%w: expected a phone number in E.164 format, such as +27821234567", ErrInvalidRecipient)
+	}
+	if t.Channel == TemplateChannelEmail {
+		if _, err := mail.ParseAddress(to); err != nil {
+			return nil, fmt.Errorf("%w: expected an email address", ErrInvalidRecipient)
+		}
+	}
+
+	rendered, err := s.render(ctx, t, to)
+	if err != nil {
+		return nil, err
+	}
+	if t.sms != nil {
+		if err := s.smsSender.Send(ctx, to, rendered.Body); err != nil {
+			return nil, fmt.Errorf("failed to send test message: %w", err)
+		}
+	} else {
+		if err := t.email(s.emailService, ctx, to, sampleData()); err != nil {
+			return nil, fmt.Errorf("failed to send test email: %w", err)
+		}
+	}
+
+	s.logger.Info("Test message sent", zap.String("template", t.Name), zap.String("channel", t.Channel))
+	return rendered, nil
+}
+
+// render renders a template with sample data, as it would be sent to to
+func (s *templateService) render(ctx context.Context, t *messageTemplate, to string) (*RenderedMessage, error) {
+	rendered := &RenderedMessage{Template: t.Name, Channel: t.Channel}
+	if t.sms != nil {
+		rendered.Body = t.sms(sampleData())
+		return rendered, nil
+	}
+	if err := t.email(s.emailService, withEmailPreview(ctx, rendered), to, sampleData()); err != nil {
+		return nil, fmt.Errorf("failed to render template: %w", err)
+	}
+	return rendered, nil
+}
+
+func findTemplate(name string) (*messageTemplate, error) {
+	for i := range messageTemplates {
+		if messageTemplates[i].Name == name {
+			return &messageTemplates[i], nil
+		}
+	}
+	return nil, ErrTemplateNotFound
+}
+
+// sampleData returns the data templates are rendered with: an appointment two days from now at
+// 10:00 UTC, formatted in the default locale
+func sampleData() templateSample {
+	day := time.Now().UTC().AddDate(0, 0, 2)
+	start := time.Date(day.Year(), day.Month(), day.Day(), 10, 0, 0, 0, time.UTC)
+	return templateSample{
+		Name:        "Jane Doe",
+		DoctorName:  "Dr. John Smith",
+		PatientName: "Jane Doe",
+		StartsAt:    utils.FormatDateTime(start, utils.DefaultTimezone, utils.DefaultLocale),
+		PreviousAt:  utils.FormatDateTime(start.AddDate(0, 0, -1), utils.DefaultTimezone, utils.DefaultLocale),
+		DueOn:       utils.FormatDate(start.AddDate(0, 0, 28), utils.DefaultTimezone, utils.DefaultLocale),
+		Code:        "123456",
+		Token:       "sample-token",
+		Directions: appointmentDirections(
+			&model.Appointment{Modality: model.ModalityInPerson},
+			&model.Organization{
+				Address:    "12 Main Road, Cape Town",
+				Directions: "Parking is behind the building. Please report to reception on the ground floor.",
+			},
+		),
+	}
+}
@@ -0,0 +1,196 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/repository"
+	"github.com/whitewalker-sa/ehass/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// ErrInvalidTranslation is returned when a translation has no text or an invalid locale
+var ErrInvalidTranslation = errors.New("invalid translation")
+
+type translationService struct {
+	repo          repository.TranslationRepository
+	doctorRepo    repository.DoctorRepository
+	contentLocale string
+	logger        *zap.Logger
+}
+
+// NewTranslationService creates a new translation service. contentLocale is the language doctor
+// bios and specialty names are originally written in.
+func NewTranslationService(
+	repo repository.TranslationRepository,
+	doctorRepo repository.DoctorRepository,
+	contentLocale string,
+	logger *zap.Logger,
+) TranslationService {
+	return &translationService{
+		repo:          repo,
+		doctorRepo:    doctorRepo,
+		contentLocale: contentLocale,
+		logger:        logger,
+	}
+}
+
+// ListDoctorBios returns the translations of a doctor's bio
+func (s *translationService) ListDoctorBios(ctx context.Context, doctorID uint) ([]*model.Translation, error) {
+	doctor, err := s.doctorRepo.FindByID(ctx, doctorID)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.FindBySubject(ctx, model.TranslationDoctorBio, doctor.PublicID)
+}
+
+// SetDoctorBio saves the translation of a doctor's bio into locale
+func (s *translationService) SetDoctorBio(ctx context.Context, doctorID uint, locale, text string) (*model.Translation, error) {
+	doctor, err := s.doctorRepo.FindByID(ctx, doctorID)
+	if err != nil {
+		return nil, err
+	}
+	return s.save(ctx, model.TranslationDoctorBio, doctor.PublicID, locale, text)
+}
+
+// DeleteDoctorBio removes the translation of a doctor's bio into locale
+func (s *translationService) DeleteDoctorBio(ctx context.Context, doctorID uint, locale string) error {
+	doctor, err := s.doctorRepo.FindByID(ctx, doctorID)
+	if err != nil {
+		return err
+	}
+	return s.repo.Delete(ctx, model.TranslationDoctorBio, doctor.PublicID, locale)
+}
+
+// ListSpecialtyNames returns the translations of a specialty name
+func (s *translationService) ListSpecialtyNames(ctx context.Context, specialty string) ([]*model.Translation, error) {
+	return s.repo.FindBySubject(ctx, model.TranslationSpecialtyName, specialty)
+}
+
+// SetSpecialtyName saves the translation of a specialty name into locale. The specialty is
+// named as stored on doctors, so the translation applies to every doctor with it.
+func (s *translationService) SetSpecialtyName(ctx context.Context, specialty, locale, text string) (*model.Translation, error) {
+	if strings.TrimSpace(specialty) == "" {
+		return nil, fmt.Errorf("%w: specialty is required", ErrInvalidTranslation)
+	}
+	return s.save(ctx, model.TranslationSpecialtyName, specialty, locale, text)
+}
+
+// DeleteSpecialtyName removes the translation of a specialty name into locale
+func (s *translationService) DeleteSpecialtyName(ctx context.Context, specialty, locale string) error {
+	return s.repo.Delete(ctx, model.TranslationSpecialtyName, specialty, locale)
+}
+
+// LocalizeDoctors replaces the bio and specialty of each doctor with the best translation for
+// the preferred locales, most preferred first. Content without a suitable translation is left
+// in the original language.
+func (s *translationService) LocalizeDoctors(ctx context.Context, doctors []*model.Doctor, preferred []string) error {
+	locales := s.candidateLocales(preferred)
+	if len(locales) == 0 || len(doctors) == 0 {
+		return nil
+	}
+
+	var doctorIDs, specialties []string
+	for _, doctor := range doctors {
+		doctorIDs = append(doctorIDs, doctor.PublicID)
+		if doctor.Specialty != "" {
+			specialties = append(specialties, doctor.Specialty)
+		}
+	}
+
+	bios, err := s.repo.FindForSubjects(ctx, model.TranslationDoctorBio, doctorIDs, locales)
+	if err != nil {
+		return fmt.Errorf("failed to load bio translations: %w", err)
+	}
+	names, err := s.repo.FindForSubjects(ctx, model.TranslationSpecialtyName, specialties, locales)
+	if err != nil {
+		return fmt.Errorf("failed to load specialty translations: %w", err)
+	}
+
+	bestBios := bestTranslations(bios, locales)
+	bestNames := bestTranslations(names, locales)
+	for _, doctor := range doctors {
+		if bio, ok := bestBios[doctor.PublicID]; ok {
+			doctor.Bio = bio
+		}
+		if name, ok := bestNames[doctor.Specialty]; ok {
+			doctor.Specialty = name
+		}
+	}
+	return nil
+}
+
+// save validates and stores a translation
+func (s *translationService) save(ctx context.Context, kind, subject, locale, text string) (*model.Translation, error) {
+	if !utils.ValidLocale(locale) {
+		return nil, fmt.Errorf("%w: locale %q is not a language tag such as fr or fr-CA", ErrInvalidTranslation, locale)
+	}
+	if utils.LocaleLanguage(locale) == utils.LocaleLanguage(s.contentLocale) {
+		return nil, fmt.Errorf("%w: content is already written in %s", ErrInvalidTranslation, s.contentLocale)
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, fmt.Errorf("%w: text is required", ErrInvalidTranslation)
+	}
+
+	translation := &model.Translation{
+		Kind:      kind,
+		Subject:   subject,
+		Locale:    locale,
+		Text:      text,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if err := s.repo.Save(ctx, translation); err != nil {
+		return nil, fmt.Errorf("failed to save translation: %w", err)
+	}
+	return translation, nil
+}
+
+// candidateLocales returns the translations worth looking for, best first: each preferred
+// locale followed by its bare language. Nothing preferred after the content's own language is
+// considered, since the original text suits the client better.
+func (s *translationService) candidateLocales(preferred []string) []string {
+	content := utils.LocaleLanguage(s.contentLocale)
+	var locales []string
+	seen := make(map[string]bool)
+	for _, locale := range preferred {
+		language := utils.LocaleLanguage(locale)
+		if language == content {
+			break
+		}
+		for _, candidate := range []string{locale, language} {
+			if !seen[candidate] {
+				seen[candidate] = true
+				locales = append(locales, candidate)
+			}
+		}
+	}
+	return locales
+}
+
+// bestTranslations picks, for each subject, the text in the earliest of locales
+func bestTranslations(translations []*model.Translation, locales []string) map[string]string {
+	rank := make(map[string]int, len(locales))
+	for i, locale := range locales {
+		rank[locale] = i
+	}
+
+	best := make(map[string]*model.Translation, len(translations))
+	for _, translation := range translations {
+		current, ok := best[translation.Subject]
+		if !ok || rank[translation.Locale] < rank[current.Locale] {
+			best[translation.Subject] = translation
+		}
+	}
+
+	texts := make(map[string]string, len(best))
+	for subject, translation := range best {
+		texts[subject] = translation.Text
+	}
+	return texts
+}
@@ -3,11 +3,13 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/whitewalker-sa/ehass/internal/config"
 	"github.com/whitewalker-sa/ehass/internal/model"
 	"github.com/whitewalker-sa/ehass/internal/repository"
+	"github.com/whitewalker-sa/ehass/pkg/utils"
 	"go.uber.org/zap"
 	"golang.org/x/crypto/bcrypt"
 
@@ -125,8 +127,10 @@ func (s *userService) DeleteUser(ctx context.Context, id uint) error {
 	return s.userRepo.Delete(ctx, id)
 }
 
-// UpdateUserProfile updates a user's profile information
-func (s *userService) UpdateUserProfile(ctx context.Context, id uint, name, phone, address string) (*model.User, error) {
+// UpdateUserProfile updates a user's profile information. The phone number is stored in E.164;
+// one without a country calling code is read as a number of phoneCountry, or of sms.defaultRegion
+// when that is empty.
+func (s *userService) UpdateUserProfile(ctx context.Context, id uint, name, phone, phoneCountry, address string) (*model.User, error) {
 	// Find user
 	user, err := s.userRepo.FindByID(ctx, id)
 	if err != nil {
@@ -138,14 +142,19 @@ func (s *userService) UpdateUserProfile(ctx context.Context, id uint, name, phon
 		user.Name = name
 	}
 	if phone != "" {
-		user.Phone = phone
+		number, err := normalizePhone(phone, phoneCountry, s.cfg.SMS.DefaultRegion)
+		if err != nil {
+			return nil, err
+		}
+		user.Phone = number.E164
+		user.PhoneCountry = number.Region
 	}
 	if address != "" {
 		user.Address = address
 	}
 
-	// Save changes
-	err = s.userRepo.Update(ctx, user)
+	// Save changes; the name and phone are also kept in the search index
+	err = s.userRepo.Update(ctx, user, searchSyncEvent(model.EventUserUpdated, user.PublicID))
 	if err != nil {
 		return nil, err
 	}
@@ -174,6 +183,41 @@ func (s *userService) UpdateAvatar(ctx context.Context, id uint, avatarURL strin
 	return user, nil
 }
 
+// UpdatePreferences updates the timezone and locale used to display times to a user and the
+// channel reminders are sent on first
+func (s *userService) UpdatePreferences(ctx context.Context, id uint, timezone, locale, channel string) (*model.User, error) {
+	if timezone != "" && !utils.ValidTimezone(timezone) {
+		return nil, fmt.Errorf("unknown timezone %q", timezone)
+	}
+	if locale != "" && !utils.ValidLocale(locale) {
+		return nil, fmt.Errorf("invalid locale %q", locale)
+	}
+	if channel != "" && channel != model.ChannelEmail && channel != model.ChannelSMS {
+		return nil, fmt.Errorf("invalid channel %q: use email or sms", channel)
+	}
+
+	user, err := s.userRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if timezone != "" {
+		user.Timezone = timezone
+	}
+	if locale != "" {
+		user.Locale = locale
+	}
+	if channel != "" {
+		user.PreferredChannel = channel
+	}
+
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
 // generateToken generates a JWT token for authentication
 func (s *userService) generateToken(user *model.User) (string, error) {
 	// Create claims
@@ -181,6 +225,8 @@ func (s *userService) generateToken(user *model.User) (string, error) {
 		"id":    user.ID,
 		"email": user.Email,
 		"role":  user.Role,
+		"iss":   s.cfg.Auth.Issuer,
+		"aud":   s.cfg.Auth.Audience,
 		"exp":   time.Now().Add(s.cfg.Auth.AccessTokenExpiry).Unix(),
 	}
 
@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/repository"
+	"go.uber.org/zap"
+)
+
+type visitReasonService struct {
+	repo       repository.VisitReasonRepository
+	doctorRepo repository.DoctorRepository
+	logger     *zap.Logger
+}
+
+// NewVisitReasonService creates a new visit reason service
+func NewVisitReasonService(
+	repo repository.VisitReasonRepository,
+	doctorRepo repository.DoctorRepository,
+	logger *zap.Logger,
+) VisitReasonService {
+	return &visitReasonService{
+		repo:       repo,
+		doctorRepo: doctorRepo,
+		logger:     logger,
+	}
+}
+
+// CreateVisitReason adds a reason patients can book for
+func (s *visitReasonService) CreateVisitReason(ctx context.Context, reason *model.VisitReason) (*model.VisitReason, error) {
+	if err := validateVisitReason(reason); err != nil {
+		return nil, err
+	}
+
+	reason.ID = 0
+	reason.Active = true
+	reason.CreatedAt = time.Now()
+	reason.UpdatedAt = time.Now()
+	if err := s.repo.Create(ctx, reason); err != nil {
+		return nil, fmt.Errorf("failed to create visit reason: %w", err)
+	}
+
+	s.logger.Info("Visit reason created", zap.Uint("visitReasonID", reason.ID), zap.String("name", reason.Name))
+	return reason, nil
+}
+
+// GetVisitReason gets a visit reason by ID
+func (s *visitReasonService) GetVisitReason(ctx context.Context, id uint) (*model.VisitReason, error) {
+	return s.repo.FindByID(ctx, id)
+}
+
+// ListVisitReasons lists all visit reasons, including retired ones
+func (s *visitReasonService) ListVisitReasons(ctx context.Context) ([]*model.VisitReason, error) {
+	return s.repo.FindAll(ctx)
+}
+
+// ListActiveVisitReasons lists the visit reasons patients can book for
+func (s *visitReasonService) ListActiveVisitReasons(ctx context.Context) ([]*model.VisitReason, error) {
+	return s.repo.FindActive(ctx)
+}
+
+// ListReasonDoctors lists the doctors patients booking for a visit reason are routed to: the
+// doctors of the reason's specialty
+func (s *visitReasonService) ListReasonDoctors(ctx context.Context, id uint, page, pageSize int, query ListQuery) (*model.VisitReason, []*model.Doctor, int64, error) {
+	reason, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	if !reason.Active {
+		return nil, nil, 0, errors.New("visit reason not found")
+	}
+
+	opts, err := listOptions("doctor", doctorFields, query)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	offset := (page - 1) * pageSize
+	doctors, total, err := s.doctorRepo.FindBySpecialty(ctx, reason.Specialty, pageSize, offset, opts)
+	if err != nil {
+		return nil, nil, 0, listError(err)
+	}
+	return reason, doctors, total, nil
+}
+
+// UpdateVisitReason replaces the settings of a visit reason. Existing appointments keep the
+// length they were booked with.
+func (s *visitReasonService) UpdateVisitReason(ctx context.Context, id uint, reason *model.VisitReason) (*model.VisitReason, error) {
+	existing, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateVisitReason(reason); err != nil {
+		return nil, err
+	}
+
+	reason.ID = existing.ID
+	reason.Active = existing.Active
+	reason.CreatedAt = existing.CreatedAt
+	reason.UpdatedAt = time.Now()
+	if err := s.repo.Update(ctx, reason); err != nil {
+		return nil, fmt.Errorf("failed to update visit reason: %w", err)
+	}
+	return reason, nil
+}
+
+// RetireVisitReason stops patients from booking for a reason while keeping it for the
+// appointments that already use it
+func (s *visitReasonService) RetireVisitReason(ctx context.Context, id uint) error {
+	reason, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	reason.Active = false
+	reason.UpdatedAt = time.Now()
+	if err := s.repo.Update(ctx, reason); err != nil {
+		return fmt.Errorf("failed to retire visit reason: %w", err)
+	}
+	return nil
+}
+
+// validateVisitReason checks a visit reason before it is saved
+func validateVisitReason(reason *model.VisitReason) error {
+	reason.Name = strings.TrimSpace(reason.Name)
+	reason.Specialty = strings.TrimSpace(reason.Specialty)
+	if reason.Name == "" {
+		return errors.New("visit reason name is required")
+	}
+	if reason.Specialty == "" {
+		return errors.New("visit reason specialty is required")
+	}
+	if reason.Duration < 5 || reason.Duration > 480 {
+		return errors.New("duration must be between 5 and 480 minutes")
+	}
+	return nil
+}
+
+// resolveVisitReason looks up the reason a booking with the doctor is made for. visitReasonID
+// may be 0 for a booking without one; otherwise the reason must be bookable and seen by doctors
+// of the doctor's specialty.
+func resolveVisitReason(ctx context.Context, repo repository.VisitReasonRepository, doctor *model.Doctor, visitReasonID uint) (*model.VisitReason, error) {
+	if visitReasonID == 0 {
+		return nil, nil
+	}
+	reason, err := repo.FindByID(ctx, visitReasonID)
+	if err != nil {
+		return nil, err
+	}
+	if !reason.Active {
+		return nil, errors.New("visit reason can no longer be booked")
+	}
+	if !strings.EqualFold(reason.Specialty, doctor.Specialty) {
+		return nil, fmt.Errorf("%s is seen by %s doctors", reason.Name, reason.Specialty)
+	}
+	return reason, nil
+}
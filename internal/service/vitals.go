@@ -0,0 +1,277 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/internal/repository"
+	"github.com/whitewalker-sa/ehass/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// Audit actions for vital signs
+const (
+	AuditActionVitalRecorded = "vital.recorded"
+	AuditActionVitalDeleted  = "vital.deleted"
+)
+
+const (
+	defaultVitalLimit  = 500
+	maxVitalLimit      = 2000
+	maxVitalNoteLength = 500
+	// maxVitalClockSkew is how far in the future a reading may be dated, for devices whose clock
+	// runs ahead
+	maxVitalClockSkew = 5 * time.Minute
+)
+
+var (
+	// ErrInvalidVital is returned when a vital sign reading fails validation
+	ErrInvalidVital = errors.New("invalid vital sign")
+	// ErrNotVitalRecorder is returned when deleting a reading someone else recorded
+	ErrNotVitalRecorder = errors.New("only the user who recorded a vital sign can delete it")
+)
+
+// vitalRanges are the plausible values of each vital sign type in its stored unit; readings
+// outside them are typing or unit mistakes
+var vitalRanges = map[model.VitalType][2]float64{
+	model.VitalBloodPressure: {50, 300}, // Systolic
+	model.VitalHeartRate:     {20, 300},
+	model.VitalWeight:        {0.2, 650},
+	model.VitalGlucose:       {10, 1500},
+	model.VitalTemperature:   {25, 45},
+}
+
+// diastolicRange is the plausible diastolic blood pressure in mmHg
+var diastolicRange = [2]float64{20, 200}
+
+// vitalConversions convert readings taken in other common units to the stored unit
+var vitalConversions = map[model.VitalType]map[string]func(float64) float64{
+	model.VitalWeight: {
+		"lb": func(v float64) float64 { return v * 0.45359237 },
+	},
+	model.VitalGlucose: {
+		"mmol/L": func(v float64) float64 { return v * 18.0 },
+	},
+	model.VitalTemperature: {
+		"F": func(v float64) float64 { return (v - 32) * 5 / 9 },
+	},
+}
+
+// VitalInput is one vital sign reading. Unit defaults to the type's stored unit, and a zero
+// MeasuredAt records the reading as taken now. RecordID optionally links a reading taken at a
+// visit to the medical record of the visit.
+type VitalInput struct {
+	Type       model.VitalType
+	Value      float64
+	Diastolic  *float64
+	Unit       string
+	MeasuredAt time.Time
+	RecordID   uint
+	Note       string
+}
+
+// VitalFilter selects a patient's readings by type and by when they were measured, in
+// [From, To). Zero fields match everything.
+type VitalFilter struct {
+	Type model.VitalType
+	From time.Time
+	To   time.Time
+}
+
+// VitalSummary aggregates a patient's readings of one vital sign type. The diastolic fields are
+// set for blood pressure only.
+type VitalSummary struct {
+	Type         model.VitalType
+	Count        int64
+	Min          float64
+	Max          float64
+	Avg          float64
+	MinDiastolic *float64
+	MaxDiastolic *float64
+	AvgDiastolic *float64
+	First        time.Time // When the earliest reading was measured
+	Last         time.Time
+}
+
+// RecordVital records a vital sign reading of a patient. Doctors on the patient's care team
+// record readings taken at a visit; patients and their guardians report readings taken at home.
+func (s *medicalRecordService) RecordVital(ctx context.Context, userID uint, role model.Role, patientID uint, input VitalInput) (*model.Vital, error) {
+	vital, err := newVital(input, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	switch role {
+	case model.RoleDoctor:
+		if _, err := s.treatingDoctor(ctx, userID, patientID); err != nil {
+			return nil, err
+		}
+		vital.Source = model.VitalSourceVisit
+		if input.RecordID != 0 {
+			record, err := s.authoredRecord(ctx, userID, patientID, input.RecordID)
+			if err != nil {
+				return nil, err
+			}
+			vital.RecordID = &record.ID
+		}
+	case model.RolePatient:
+		if err := s.authorizeRead(ctx, userID, role, patientID); err != nil {
+			return nil, err
+		}
+		if input.RecordID != 0 {
+			return nil, fmt.Errorf("%w: self-reported readings cannot be linked to a medical record", ErrInvalidVital)
+		}
+		vital.Source = model.VitalSourceSelfReported
+	default:
+		return nil, ErrNotOwnMedicalRecord
+	}
+
+	vital.PatientID = patientID
+	vital.RecordedByID = userID
+	if err := s.vitalRepo.Create(ctx, vital); err != nil {
+		return nil, fmt.Errorf("failed to record vital sign: %w", err)
+	}
+	s.auditVital(ctx, userID, AuditActionVitalRecorded, vital)
+	return vital, nil
+}
+
+// ListVitals lists a patient's readings matching filter, oldest first, for charting. Only the
+// latest limit readings are listed. Access follows the patient's medical records.
+func (s *medicalRecordService) ListVitals(ctx context.Context, userID uint, role model.Role, patientID uint, filter VitalFilter, limit int) ([]*model.Vital, error) {
+	if err := s.authorizeRead(ctx, userID, role, patientID); err != nil {
+		return nil, err
+	}
+	if filter.Type != "" && !filter.Type.IsValid() {
+		return nil, fmt.Errorf("%w: unknown type %q", ErrInvalidVital, filter.Type)
+	}
+	if limit <= 0 {
+		limit = defaultVitalLimit
+	}
+	if limit > maxVitalLimit {
+		limit = maxVitalLimit
+	}
+	return s.vitalRepo.Find(ctx, vitalRepositoryFilter(patientID, filter), limit)
+}
+
+// SummarizeVitals returns the count, minimum, maximum and average of a patient's readings
+// matching filter, by type. Access follows the patient's medical records.
+func (s *medicalRecordService) SummarizeVitals(ctx context.Context, userID uint, role model.Role, patientID uint, filter VitalFilter) ([]*VitalSummary, error) {
+	if err := s.authorizeRead(ctx, userID, role, patientID); err != nil {
+		return nil, err
+	}
+	if filter.Type != "" && !filter.Type.IsValid() {
+		return nil, fmt.Errorf("%w: unknown type %q", ErrInvalidVital, filter.Type)
+	}
+	summaries, err := s.vitalRepo.Summarize(ctx, vitalRepositoryFilter(patientID, filter))
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize vital signs: %w", err)
+	}
+	result := make([]*VitalSummary, 0, len(summaries))
+	for _, summary := range summaries {
+		converted := VitalSummary(summary)
+		result = append(result, &converted)
+	}
+	return result, nil
+}
+
+// DeleteVital removes a reading recorded in error by the user signed in as userID
+func (s *medicalRecordService) DeleteVital(ctx context.Context, userID, patientID, id uint) error {
+	vital, err := s.vitalRepo.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if vital.PatientID != patientID {
+		return errors.New("vital sign not found")
+	}
+	if vital.RecordedByID != userID {
+		return ErrNotVitalRecorder
+	}
+	if err := s.vitalRepo.Delete(ctx, vital.ID); err != nil {
+		return fmt.Errorf("failed to delete vital sign: %w", err)
+	}
+	s.auditVital(ctx, userID, AuditActionVitalDeleted, vital)
+	return nil
+}
+
+// newVital validates a reading taken at or before now, converting it to the type's stored unit
+func newVital(input VitalInput, now time.Time) (*model.Vital, error) {
+	unit, ok := model.VitalUnits[input.Type]
+	if !ok {
+		return nil, fmt.Errorf("%w: type must be blood_pressure, heart_rate, weight, glucose or temperature", ErrInvalidVital)
+	}
+
+	vital := &model.Vital{
+		Type:       input.Type,
+		Value:      input.Value,
+		MeasuredAt: input.MeasuredAt,
+		Note:       strings.TrimSpace(input.Note),
+		CreatedAt:  now,
+	}
+	if input.Unit != "" && input.Unit != unit {
+		convert, ok := vitalConversions[input.Type][input.Unit]
+		if !ok {
+			return nil, fmt.Errorf("%w: unsupported unit %q for %s", ErrInvalidVital, input.Unit, input.Type)
+		}
+		vital.Value = convert(vital.Value)
+	}
+
+	limits := vitalRanges[input.Type]
+	if vital.Value < limits[0] || vital.Value > limits[1] {
+		return nil, fmt.Errorf("%w: %s must be between %g and %g %s", ErrInvalidVital, input.Type, limits[0], limits[1], unit)
+	}
+	if input.Type == model.VitalBloodPressure {
+		if input.Diastolic == nil {
+			return nil, fmt.Errorf("%w: blood pressure needs a diastolic value", ErrInvalidVital)
+		}
+		diastolic := *input.Diastolic
+		if diastolic < diastolicRange[0] || diastolic > diastolicRange[1] || diastolic >= vital.Value {
+			return nil, fmt.Errorf("%w: diastolic must be between %g and %g mmHg and below systolic", ErrInvalidVital, diastolicRange[0], diastolicRange[1])
+		}
+		vital.Diastolic = &diastolic
+	} else if input.Diastolic != nil {
+		return nil, fmt.Errorf("%w: only blood pressure has a diastolic value", ErrInvalidVital)
+	}
+
+	if vital.MeasuredAt.IsZero() {
+		vital.MeasuredAt = now
+	} else if vital.MeasuredAt.After(now.Add(maxVitalClockSkew)) {
+		return nil, fmt.Errorf("%w: measured_at is in the future", ErrInvalidVital)
+	}
+	if len([]rune(vital.Note)) > maxVitalNoteLength {
+		return nil, fmt.Errorf("%w: note must be at most %d characters", ErrInvalidVital, maxVitalNoteLength)
+	}
+	return vital, nil
+}
+
+// vitalRepositoryFilter selects the patient's readings matching filter
+func vitalRepositoryFilter(patientID uint, filter VitalFilter) repository.VitalFilter {
+	return repository.VitalFilter{
+		PatientID: patientID,
+		Type:      filter.Type,
+		From:      filter.From,
+		To:        filter.To,
+	}
+}
+
+// auditVital records a reading being recorded or deleted; the value is not logged
+func (s *medicalRecordService) auditVital(ctx context.Context, userID uint, action string, vital *model.Vital) {
+	client := utils.ClientInfoFromContext(ctx)
+	if err := s.auditLogRepo.Create(ctx, &model.AuditLog{
+		UserID:     userID,
+		Action:     action,
+		EntityID:   vital.ID,
+		EntityType: "vital",
+		IP:         client.IP,
+		UserAgent:  client.UserAgent,
+		CreatedAt:  time.Now(),
+	}); err != nil {
+		s.logger.Error("Failed to write vital sign audit log",
+			zap.String("action", action),
+			zap.Uint("vitalID", vital.ID),
+			zap.Error(err))
+	}
+}
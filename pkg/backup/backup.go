@@ -0,0 +1,167 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Database holds the connection details passed to pg_dump and pg_restore
+type Database struct {
+	Host     string
+	Port     string
+	User     string
+	Password string
+	Name     string
+	SSLMode  string
+}
+
+// env returns the libpq environment variables for the database, so the password does not show
+// up in the process list
+func (d Database) env() []string {
+	return append(os.Environ(),
+		"PGHOST="+d.Host,
+		"PGPORT="+d.Port,
+		"PGUSER="+d.User,
+		"PGPASSWORD="+d.Password,
+		"PGDATABASE="+d.Name,
+		"PGSSLMODE="+d.SSLMode,
+	)
+}
+
+// Options configures a backup or restore
+type Options struct {
+	Database  Database
+	Key       []byte // 256-bit key the backups are encrypted with
+	PgDump    string // pg_dump binary
+	PgRestore string // pg_restore binary
+}
+
+// Backup dumps the database, encrypts the dump and uploads it to the store. The encrypted dump
+// is staged in a temporary file, so the plaintext never touches the disk and the upload can be
+// signed. The newest backup is recorded for Restore. It returns the backup's name.
+func Backup(ctx context.Context, store *Store, opts Options) (string, error) {
+	staged, err := os.CreateTemp("", "ehass-backup-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create staging file: %w", err)
+	}
+	defer os.Remove(staged.Name())
+	defer staged.Close()
+
+	hash := sha256.New()
+	encrypted, err := NewEncryptWriter(io.MultiWriter(staged, hash), opts.Key)
+	if err != nil {
+		return "", err
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, opts.PgDump, "--format=custom", "--no-owner", "--no-privileges")
+	cmd.Env = opts.Database.env()
+	cmd.Stdout = encrypted
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("pg_dump failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	if err := encrypted.Close(); err != nil {
+		return "", fmt.Errorf("failed to write backup: %w", err)
+	}
+
+	size, err := staged.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return "", err
+	}
+	if _, err := staged.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	name := time.Now().UTC().Format("20060102T150405Z") + ".dump.enc"
+	if err := store.Put(ctx, name, staged, size, hex.EncodeToString(hash.Sum(nil))); err != nil {
+		return "", err
+	}
+	latest := sha256.Sum256([]byte(name))
+	if err := store.Put(ctx, latestObject, strings.NewReader(name), int64(len(name)), hex.EncodeToString(latest[:])); err != nil {
+		return "", fmt.Errorf("backup %s uploaded but not recorded as the latest: %w", name, err)
+	}
+	return name, nil
+}
+
+// Restore downloads a backup, decrypts it and restores it over the database with pg_restore in a
+// single transaction. name "latest" restores the newest backup. If the backup cannot be
+// decrypted to the end, pg_restore is stopped before it commits and the database is left as it
+// was. It returns the name of the restored backup.
+func Restore(ctx context.Context, store *Store, opts Options, name string) (string, error) {
+	if name == "latest" {
+		var err error
+		if name, err = latestBackup(ctx, store); err != nil {
+			return "", err
+		}
+	}
+
+	body, err := store.Get(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+
+	decrypted, err := NewDecryptReader(body, opts.Key)
+	if err != nil {
+		return "", err
+	}
+
+	restoreCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(restoreCtx, opts.PgRestore,
+		"--clean", "--if-exists", "--no-owner", "--no-privileges", "--single-transaction",
+		"--dbname", opts.Database.Name)
+	cmd.Env = opts.Database.env()
+	cmd.Stderr = &stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return "", err
+	}
+	if err := cmd.Start(); err != nil {
+		return "", fmt.Errorf("failed to start pg_restore: %w", err)
+	}
+
+	if _, err := io.Copy(stdin, decrypted); err != nil {
+		// Kill pg_restore before closing its input, or it would commit what it has read
+		cancel()
+		_ = cmd.Wait()
+		return "", fmt.Errorf("restore of %s stopped: %w %s", name, err, strings.TrimSpace(stderr.String()))
+	}
+	if err := stdin.Close(); err != nil {
+		return "", err
+	}
+	if err := cmd.Wait(); err != nil {
+		return "", fmt.Errorf("pg_restore failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return name, nil
+}
+
+// latestBackup reads the name of the newest backup
+func latestBackup(ctx context.Context, store *Store) (string, error) {
+	body, err := store.Get(ctx, latestObject)
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+	content, err := io.ReadAll(io.LimitReader(body, 256))
+	if err != nil {
+		return "", err
+	}
+	name := strings.TrimSpace(string(content))
+	if name == "" {
+		return "", errors.New("no backup has been recorded as the latest")
+	}
+	return name, nil
+}
@@ -0,0 +1,209 @@
+// Package backup takes encrypted database backups with pg_dump, keeps them in an S3-compatible
+// object store and restores them with pg_restore.
+package backup
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// magic starts every encrypted backup, followed by the random nonce prefix
+const magic = "EHASSBK1"
+
+const (
+	chunkSize   = 64 * 1024
+	prefixSize  = 7
+	finalChunk  = 1 << 31 // Set in a chunk's length to mark the last chunk
+	maxChunkLen = chunkSize + 16
+)
+
+// ErrTruncated is returned when a backup ends before its last chunk, such as after an
+// interrupted upload
+var ErrTruncated = errors.New("backup is truncated")
+
+// ParseKey decodes a base64-encoded 256-bit backup key
+func ParseKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid backup key: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, errors.New("backup key must be 32 bytes")
+	}
+	return key, nil
+}
+
+// encryptWriter encrypts a stream in chunks with AES-256-GCM. Each chunk's nonce is the stream's
+// random prefix, the chunk number and whether it is the last chunk, so chunks cannot be
+// reordered, dropped or cut off without failing decryption.
+type encryptWriter struct {
+	w      io.Writer
+	aead   cipher.AEAD
+	header []byte
+	buf    []byte
+	count  uint32
+}
+
+// NewEncryptWriter returns a writer encrypting to w with key. Close writes the last chunk and
+// must be called for the backup to be readable; it does not close w.
+func NewEncryptWriter(w io.Writer, key []byte) (io.WriteCloser, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, len(magic)+prefixSize)
+	copy(header, magic)
+	if _, err := rand.Read(header[len(magic):]); err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return &encryptWriter{w: w, aead: aead, header: header, buf: make([]byte, 0, chunkSize)}, nil
+}
+
+func (e *encryptWriter) Write(p []byte) (int, error) {
+	written := len(p)
+	for len(p) > 0 {
+		// Hold back a full chunk until more data arrives, so the last chunk is never empty
+		// unless the whole stream is
+		if len(e.buf) == chunkSize {
+			if err := e.seal(false); err != nil {
+				return 0, err
+			}
+		}
+		n := copy(e.buf[len(e.buf):chunkSize], p)
+		e.buf = e.buf[:len(e.buf)+n]
+		p = p[n:]
+	}
+	return written, nil
+}
+
+func (e *encryptWriter) Close() error {
+	return e.seal(true)
+}
+
+func (e *encryptWriter) seal(final bool) error {
+	sealed := e.aead.Seal(nil, chunkNonce(e.header, e.count, final), e.buf, e.header)
+	length := uint32(len(sealed))
+	if final {
+		length |= finalChunk
+	}
+	var prefix [4]byte
+	binary.BigEndian.PutUint32(prefix[:], length)
+	if _, err := e.w.Write(prefix[:]); err != nil {
+		return err
+	}
+	if _, err := e.w.Write(sealed); err != nil {
+		return err
+	}
+	e.count++
+	e.buf = e.buf[:0]
+	return nil
+}
+
+// decryptReader reads a stream written by encryptWriter
+type decryptReader struct {
+	r      io.Reader
+	aead   cipher.AEAD
+	header []byte
+	plain  []byte
+	count  uint32
+	done   bool
+}
+
+// NewDecryptReader returns a reader decrypting r with key. Reads fail if the backup was written
+// with another key, was modified or is truncated.
+func NewDecryptReader(r io.Reader, key []byte) (io.Reader, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, len(magic)+prefixSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("failed to read backup header: %w", err)
+	}
+	if string(header[:len(magic)]) != magic {
+		return nil, errors.New("not an encrypted backup")
+	}
+	return &decryptReader{r: r, aead: aead, header: header}, nil
+}
+
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for len(d.plain) == 0 {
+		if d.done {
+			return 0, io.EOF
+		}
+		if err := d.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, d.plain)
+	d.plain = d.plain[n:]
+	return n, nil
+}
+
+func (d *decryptReader) next() error {
+	var prefix [4]byte
+	if _, err := io.ReadFull(d.r, prefix[:]); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return ErrTruncated
+		}
+		return err
+	}
+	length := binary.BigEndian.Uint32(prefix[:])
+	final := length&finalChunk != 0
+	length &^= finalChunk
+	if length > maxChunkLen {
+		return errors.New("backup chunk is too large")
+	}
+
+	sealed := make([]byte, length)
+	if _, err := io.ReadFull(d.r, sealed); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return ErrTruncated
+		}
+		return err
+	}
+	plain, err := d.aead.Open(nil, chunkNonce(d.header, d.count, final), sealed, d.header)
+	if err != nil {
+		return errors.New("failed to decrypt backup: wrong key or corrupted data")
+	}
+	d.count++
+	d.plain = plain
+
+	if final {
+		var extra [1]byte
+		if n, _ := d.r.Read(extra[:]); n > 0 {
+			return errors.New("unexpected data after the end of the backup")
+		}
+		d.done = true
+	}
+	return nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// chunkNonce builds the 12-byte nonce of a chunk from the stream's prefix, the chunk number and
+// the last-chunk flag
+func chunkNonce(header []byte, count uint32, final bool) []byte {
+	nonce := make([]byte, 12)
+	copy(nonce, header[len(magic):])
+	binary.BigEndian.PutUint32(nonce[prefixSize:], count)
+	if final {
+		nonce[11] = 1
+	}
+	return nonce
+}
@@ -0,0 +1,99 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/pkg/awssig"
+)
+
+// latestObject names the object holding the key of the newest backup
+const latestObject = "LATEST"
+
+// Store keeps backups in an S3 bucket or S3-compatible object store
+type Store struct {
+	bucket      string
+	prefix      string
+	region      string
+	endpoint    string
+	credentials awssig.Credentials
+	httpClient  *http.Client
+}
+
+// NewStore creates an object store client. endpoint may be empty to use AWS. Empty region and
+// credentials fall back to the standard AWS_* environment variables.
+func NewStore(bucket, prefix, region, endpoint string, creds awssig.Credentials) (*Store, error) {
+	creds = awssig.CredentialsFromEnv(creds)
+	if region == "" {
+		region = os.Getenv("AWS_REGION")
+	}
+	if bucket == "" || region == "" || !creds.Valid() {
+		return nil, fmt.Errorf("backup bucket, region and credentials are required")
+	}
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", region)
+	}
+
+	return &Store{
+		bucket:      bucket,
+		prefix:      strings.Trim(prefix, "/"),
+		region:      region,
+		endpoint:    strings.TrimRight(endpoint, "/"),
+		credentials: creds,
+		// Backups can take a while to transfer; callers bound them with their context
+		httpClient: &http.Client{},
+	}, nil
+}
+
+// Put uploads size bytes from body as name. payloadHash is the hex-encoded SHA-256 of the body.
+func (s *Store) Put(ctx context.Context, name string, body io.Reader, size int64, payloadHash string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s.url(name), body)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/octet-stream")
+	awssig.SignHash(req, payloadHash, s.region, "s3", s.credentials, time.Now())
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to upload %s: %w", name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("object store rejected upload of %s: status %d %s", name, resp.StatusCode, msg)
+	}
+	return nil
+}
+
+// Get downloads name. The caller must close the returned body.
+func (s *Store) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url(name), nil)
+	if err != nil {
+		return nil, err
+	}
+	awssig.Sign(req, nil, s.region, "s3", s.credentials, time.Now())
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", name, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("object store rejected download of %s: status %d %s", name, resp.StatusCode, msg)
+	}
+	return resp.Body, nil
+}
+
+func (s *Store) url(name string) string {
+	key := strings.TrimPrefix(s.prefix+"/"+name, "/")
+	return fmt.Sprintf("%s/%s/%s", s.endpoint, s.bucket, key)
+}
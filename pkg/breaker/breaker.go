@@ -0,0 +1,258 @@
+// Package breaker guards calls to external dependencies with timeouts, bounded retries,
+// concurrency limits and circuit breakers, so a slow or failing dependency fails fast instead
+// of tying up request goroutines.
+package breaker
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// State is the state of a circuit breaker
+type State string
+
+const (
+	StateClosed   State = "closed"    // Calls go through
+	StateOpen     State = "open"      // Calls fail immediately until the open timeout passes
+	StateHalfOpen State = "half_open" // One trial call decides whether to close or reopen
+)
+
+var (
+	// ErrOpen is returned without calling the dependency while the breaker is open
+	ErrOpen = errors.New("circuit breaker is open")
+	// ErrTooManyCalls is returned when the dependency already has the maximum number of calls in flight
+	ErrTooManyCalls = errors.New("too many concurrent calls")
+)
+
+// Settings configures a breaker. Zero values disable the corresponding protection, except
+// FailureThreshold and OpenTimeout which fall back to 5 and 30 seconds.
+type Settings struct {
+	Timeout          time.Duration // Deadline for each attempt
+	Retries          int           // Attempts after the first; only safe for idempotent calls
+	RetryBackoff     time.Duration // Delay before the first retry, doubled for each further retry
+	FailureThreshold int           // Consecutive failures that open the breaker
+	OpenTimeout      time.Duration // How long the breaker stays open before a trial call
+	MaxConcurrent    int           // Calls allowed in flight at once
+}
+
+// Stats reports a breaker's state and counters since the process started
+type Stats struct {
+	Name                string
+	State               State
+	Calls               int64 // Attempts made against the dependency
+	Failures            int64
+	Rejected            int64 // Calls refused because the breaker was open or at its concurrency limit
+	ConsecutiveFailures int
+	OpenedAt            *time.Time
+	LastFailureAt       *time.Time
+	LastError           string
+}
+
+// Breaker guards the calls to one dependency. A nil breaker calls through unguarded.
+type Breaker struct {
+	name     string
+	settings Settings
+	slots    chan struct{} // nil without a concurrency limit
+
+	mu          sync.Mutex
+	stats       Stats
+	trialActive bool // A half-open trial call is in flight
+}
+
+// New creates a breaker
+func New(name string, settings Settings) *Breaker {
+	if settings.FailureThreshold <= 0 {
+		settings.FailureThreshold = 5
+	}
+	if settings.OpenTimeout <= 0 {
+		settings.OpenTimeout = 30 * time.Second
+	}
+
+	b := &Breaker{
+		name:     name,
+		settings: settings,
+		stats:    Stats{Name: name, State: StateClosed},
+	}
+	if settings.MaxConcurrent > 0 {
+		b.slots = make(chan struct{}, settings.MaxConcurrent)
+	}
+	return b
+}
+
+// Name returns the name of the guarded dependency
+func (b *Breaker) Name() string {
+	return b.name
+}
+
+// Do calls fn with a context bounded by the attempt timeout, retrying failures up to the
+// configured number of times. Errors wrapped with Permanent are returned at once and do not
+// count against the dependency, nor do cancellations of ctx by the caller.
+func (b *Breaker) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if b == nil {
+		return fn(ctx)
+	}
+
+	if b.slots != nil {
+		select {
+		case b.slots <- struct{}{}:
+			defer func() { <-b.slots }()
+		default:
+			b.reject()
+			return ErrTooManyCalls
+		}
+	}
+
+	backoff := b.settings.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		if !b.allow() {
+			return ErrOpen
+		}
+
+		err := b.attempt(ctx, fn)
+		if err == nil || IsPermanent(err) || ctx.Err() != nil || attempt >= b.settings.Retries {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// attempt makes one call and records its outcome
+func (b *Breaker) attempt(ctx context.Context, fn func(ctx context.Context) error) error {
+	callCtx := ctx
+	if b.settings.Timeout > 0 {
+		var cancel context.CancelFunc
+		callCtx, cancel = context.WithTimeout(ctx, b.settings.Timeout)
+		defer cancel()
+	}
+
+	err := fn(callCtx)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.stats.Calls++
+	b.trialActive = false
+	switch {
+	case err == nil || IsPermanent(err):
+		b.stats.State = StateClosed
+		b.stats.ConsecutiveFailures = 0
+		b.stats.OpenedAt = nil
+	case ctx.Err() != nil:
+		// The caller gave up; that says nothing about the dependency
+	default:
+		now := time.Now()
+		b.stats.Failures++
+		b.stats.ConsecutiveFailures++
+		b.stats.LastFailureAt = &now
+		b.stats.LastError = err.Error()
+		if b.stats.State == StateHalfOpen || b.stats.ConsecutiveFailures >= b.settings.FailureThreshold {
+			b.stats.State = StateOpen
+			b.stats.OpenedAt = &now
+		}
+	}
+	return err
+}
+
+// allow reports whether a call may go through, moving an open breaker to half-open once the
+// open timeout has passed
+func (b *Breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.stats.State {
+	case StateOpen:
+		if time.Since(*b.stats.OpenedAt) < b.settings.OpenTimeout {
+			b.stats.Rejected++
+			return false
+		}
+		b.stats.State = StateHalfOpen
+		b.trialActive = true
+		return true
+	case StateHalfOpen:
+		if b.trialActive {
+			b.stats.Rejected++
+			return false
+		}
+		b.trialActive = true
+		return true
+	default:
+		return true
+	}
+}
+
+func (b *Breaker) reject() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.stats.Rejected++
+}
+
+// Stats returns the breaker's current state and counters
+func (b *Breaker) Stats() Stats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.stats
+}
+
+// Registry keeps the breakers of a process so their state can be reported
+type Registry struct {
+	mu       sync.Mutex
+	breakers map[string]*Breaker
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{breakers: make(map[string]*Breaker)}
+}
+
+// Add creates a breaker and registers it under its name, replacing any breaker of that name
+func (r *Registry) Add(name string, settings Settings) *Breaker {
+	b := New(name, settings)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.breakers[name] = b
+	return b
+}
+
+// Stats returns the stats of every registered breaker, ordered by name
+func (r *Registry) Stats() []Stats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stats := make([]Stats, 0, len(r.breakers))
+	for _, b := range r.breakers {
+		stats = append(stats, b.Stats())
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Name < stats[j].Name })
+	return stats
+}
+
+// permanentError marks an error that retrying will not fix
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as a failure of the request rather than the dependency, e.g. a rejected
+// credential or an invalid recipient. It is not retried and does not open the breaker.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err was marked with Permanent
+func IsPermanent(err error) bool {
+	var permanent *permanentError
+	return errors.As(err, &permanent)
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/whitewalker-sa/ehass/internal/config"
 	"github.com/whitewalker-sa/ehass/internal/model"
+	"github.com/whitewalker-sa/ehass/pkg/encryption"
 	"go.uber.org/zap"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -14,7 +15,8 @@ import (
 
 // NewDatabase creates a new database connection
 func NewDatabase(cfg *config.Config, log *zap.Logger) (*gorm.DB, error) {
-	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+	// Sessions run in UTC so timestamps read back in UTC whatever the server's timezone
+	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s TimeZone=UTC",
 		cfg.Database.Host,
 		cfg.Database.Port,
 		cfg.Database.User,
@@ -23,8 +25,21 @@ func NewDatabase(cfg *config.Config, log *zap.Logger) (*gorm.DB, error) {
 		cfg.Database.SSLMode,
 	)
 
+	// Register the keyring used by encrypted PHI columns
+	keyring, err := encryption.NewKeyring(cfg.Encryption.ActiveKeyID, cfg.Encryption.Keys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load encryption keys: %w", err)
+	}
+	encryption.Register(keyring)
+	index, err := encryption.NewBlindIndex(cfg.Encryption.IndexKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load blind index key: %w", err)
+	}
+	encryption.RegisterIndex(index)
+
 	gormCfg := &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
+		Logger:  logger.Default.LogMode(logger.Info),
+		NowFunc: func() time.Time { return time.Now().UTC() },
 	}
 
 	db, err := gorm.Open(postgres.Open(dsn), gormCfg)
@@ -61,10 +76,61 @@ func AutoMigrate(db *gorm.DB, log *zap.Logger) error {
 		&model.Doctor{},
 		&model.Patient{},
 		&model.Appointment{},
+		&model.AppointmentParticipant{},
+		&model.TelehealthVisit{},
+		&model.WaitingRoomEvent{},
 		&model.Session{},
+		&model.VerificationToken{},
 		&model.Availability{},
+		&model.ShiftTemplate{},
+		&model.TimeOff{},
+		&model.CalendarConnection{},
+		&model.CalendarEvent{},
+		&model.CalendarBusy{},
 		&model.MedicalRecord{},
+		&model.MedicalRecordAttachment{},
+		&model.MedicalRecordVersion{},
+		&model.MedicalRecordAddendum{},
+		&model.Medication{},
+		&model.Prescription{},
+		&model.Vital{},
+		&model.Allergy{},
+		&model.PatientMedication{},
+		&model.Immunization{},
+		&model.LabOrder{},
+		&model.LabResult{},
+		&model.AccountEntry{},
+		&model.APIKey{},
+		&model.APIKeyUsage{},
 		&model.AuditLog{},
+		&model.SecurityAlert{},
+		&model.RuntimeSetting{},
+		&model.Consent{},
+		&model.MarketingConsent{},
+		&model.BreakGlassAccess{},
+		&model.ExportCursor{},
+		&model.CustomRole{},
+		&model.UserCustomRole{},
+		&model.Organization{},
+		&model.AppointmentType{},
+		&model.VisitReason{},
+		&model.AnalyticsBucket{},
+		&model.EmailMessage{},
+		&model.EmailSuppression{},
+		&model.Notification{},
+		&model.OutboxEvent{},
+		&model.Translation{},
+		&model.Operation{},
+		&model.ProcedureCode{},
+		&model.AppointmentProcedure{},
+		&model.CareRule{},
+		&model.CareReminder{},
+		&model.RecurringAppointment{},
+		&model.RecurringAppointmentException{},
+		&model.HandoffNote{},
+		&model.Message{},
+		&model.AppointmentHistory{},
+		&model.DoctorReview{},
 	)
 
 	if err != nil {
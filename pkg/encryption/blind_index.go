@@ -0,0 +1,112 @@
+package encryption
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// BlindIndex turns the words of values encrypted at rest into keyed tokens that can be matched
+// in the database. Each word is normalised and hashed with HMAC-SHA256, so the same word always
+// gives the same token while the tokens reveal nothing of the words without the key. Changing
+// the key makes existing tokens unsearchable until they are rebuilt.
+type BlindIndex struct {
+	key []byte
+}
+
+var (
+	defaultIndex *BlindIndex
+	indexMu      sync.RWMutex
+)
+
+// NewBlindIndex creates a blind index from a base64-encoded 256-bit key
+func NewBlindIndex(encodedKey string) (*BlindIndex, error) {
+	if encodedKey == "" {
+		return nil, errors.New("no blind index key configured")
+	}
+	key, err := base64.StdEncoding.DecodeString(encodedKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid blind index key: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, errors.New("blind index key must be 32 bytes")
+	}
+	return &BlindIndex{key: key}, nil
+}
+
+// RegisterIndex makes the blind index the default
+func RegisterIndex(index *BlindIndex) {
+	indexMu.Lock()
+	defaultIndex = index
+	indexMu.Unlock()
+}
+
+// DefaultIndex returns the blind index registered with RegisterIndex
+func DefaultIndex() *BlindIndex {
+	indexMu.RLock()
+	defer indexMu.RUnlock()
+	return defaultIndex
+}
+
+// Tokens returns the distinct tokens of the words of text
+func (b *BlindIndex) Tokens(text string) []string {
+	tokens := []string{}
+	seen := map[string]bool{}
+	for _, word := range indexWords(text) {
+		token := b.token(word)
+		if !seen[token] {
+			seen[token] = true
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens
+}
+
+// QueryTokens reads a search query in web search syntax and returns the tokens of the words a
+// value must contain and of the words it must not. Quoted phrases match when all their words
+// do, as word order is not kept, and an or between words is ignored.
+func (b *BlindIndex) QueryTokens(query string) (include, exclude []string) {
+	for _, field := range strings.Fields(query) {
+		if strings.EqualFold(field, "or") {
+			continue
+		}
+		if len(field) > 1 && field[0] == '-' {
+			exclude = append(exclude, b.Tokens(field[1:])...)
+			continue
+		}
+		include = append(include, b.Tokens(field)...)
+	}
+	return include, exclude
+}
+
+// token returns the token of a normalised word
+func (b *BlindIndex) token(word string) string {
+	mac := hmac.New(sha256.New, b.key)
+	mac.Write([]byte(word))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// indexWords splits text into lowercase words of two or more letters or digits. A plural s is
+// dropped so pains finds pain; words are not otherwise stemmed.
+func indexWords(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	normalised := words[:0]
+	for _, word := range words {
+		if len([]rune(word)) < 2 {
+			continue
+		}
+		if len(word) > 3 && strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss") {
+			word = word[:len(word)-1]
+		}
+		normalised = append(normalised, word)
+	}
+	return normalised
+}
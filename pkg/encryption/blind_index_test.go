@@ -0,0 +1,58 @@
+package encryption
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestBlindIndexTokens(t *testing.T) {
+	index, err := NewBlindIndex(testKey('a'))
+	if err != nil {
+		t.Fatalf("NewBlindIndex: %v", err)
+	}
+	other, err := NewBlindIndex(testKey('b'))
+	if err != nil {
+		t.Fatalf("NewBlindIndex: %v", err)
+	}
+
+	tokens := index.Tokens("Chest pains, chest PAIN")
+	if len(tokens) != 2 {
+		t.Fatalf("got %d tokens, want chest and pain once each", len(tokens))
+	}
+	if !slices.Equal(index.Tokens("chest pain"), tokens) {
+		t.Error("case, punctuation or a plural s changed the tokens")
+	}
+	if slices.Equal(other.Tokens("chest pain"), tokens) {
+		t.Error("a different key gave the same tokens")
+	}
+	if got := index.Tokens("a b"); len(got) != 0 {
+		t.Errorf("got %d tokens for single letters, want none", len(got))
+	}
+	// Only a plural s is dropped, not a double s
+	if slices.Equal(index.Tokens("abscess"), index.Tokens("absces")) {
+		t.Error("abscess lost its final s")
+	}
+}
+
+func TestBlindIndexQueryTokens(t *testing.T) {
+	index, err := NewBlindIndex(testKey('a'))
+	if err != nil {
+		t.Fatalf("NewBlindIndex: %v", err)
+	}
+
+	include, exclude := index.QueryTokens(`"chest pain" OR asthma -allergic`)
+	if want := index.Tokens("chest pain asthma"); !slices.Equal(include, want) {
+		t.Errorf("include %v, want %v", include, want)
+	}
+	if want := index.Tokens("allergic"); !slices.Equal(exclude, want) {
+		t.Errorf("exclude %v, want %v", exclude, want)
+	}
+}
+
+func TestNewBlindIndexValidatesKey(t *testing.T) {
+	for _, key := range []string{"", "not base64!", "c2hvcnQ="} {
+		if _, err := NewBlindIndex(key); err == nil {
+			t.Errorf("NewBlindIndex(%q) succeeded, want an error", key)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package encryption
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+// testKey returns a base64-encoded 256-bit key filled with b
+func testKey(b byte) string {
+	return base64.StdEncoding.EncodeToString([]byte(strings.Repeat(string(rune(b)), 32)))
+}
+
+func TestEncryptRoundTrip(t *testing.T) {
+	keyring, err := NewKeyring("k1", map[string]string{"k1": testKey('a')})
+	if err != nil {
+		t.Fatalf("NewKeyring: %v", err)
+	}
+
+	for _, plaintext := range []string{"Type 2 diabetes", "ünïcödé ✓", strings.Repeat("x", 10000)} {
+		encrypted, err := keyring.Encrypt(plaintext)
+		if err != nil {
+			t.Fatalf("Encrypt: %v", err)
+		}
+		if !IsEncrypted(encrypted) || strings.Contains(encrypted, plaintext) {
+			t.Errorf("Encrypt(%.20q) = %.40q, want an opaque value", plaintext, encrypted)
+		}
+		decrypted, err := keyring.Decrypt(encrypted)
+		if err != nil {
+			t.Fatalf("Decrypt: %v", err)
+		}
+		if decrypted != plaintext {
+			t.Errorf("Decrypt(Encrypt(%.20q)) = %.20q", plaintext, decrypted)
+		}
+	}
+
+	// Every value has its own data key, so equal values do not give equal ciphertexts
+	first, _ := keyring.Encrypt("same")
+	second, _ := keyring.Encrypt("same")
+	if first == second {
+		t.Error("two encryptions of the same value are identical")
+	}
+}
+
+func TestEncryptLeavesEmptyAndLegacyValues(t *testing.T) {
+	keyring, _ := NewKeyring("k1", map[string]string{"k1": testKey('a')})
+
+	if encrypted, err := keyring.Encrypt(""); err != nil || encrypted != "" {
+		t.Errorf("Encrypt(\"\") = %q, %v; want it stored as-is", encrypted, err)
+	}
+	if plaintext, err := keyring.Decrypt("written before encryption"); err != nil || plaintext != "written before encryption" {
+		t.Errorf("Decrypt of legacy plaintext = %q, %v; want it unchanged", plaintext, err)
+	}
+	if !keyring.NeedsRotation("written before encryption") {
+		t.Error("legacy plaintext does not need rotation")
+	}
+}
+
+func TestDecryptRejectsTamperedValues(t *testing.T) {
+	keyring, _ := NewKeyring("k1", map[string]string{"k1": testKey('a')})
+	encrypted, _ := keyring.Encrypt("Penicillin allergy")
+
+	parts := strings.Split(encrypted, ":")
+	ciphertext, _ := base64.RawStdEncoding.DecodeString(parts[len(parts)-1])
+	ciphertext[len(ciphertext)-1] ^= 1
+	parts[len(parts)-1] = base64.RawStdEncoding.EncodeToString(ciphertext)
+
+	if _, err := keyring.Decrypt(strings.Join(parts, ":")); err == nil {
+		t.Error("a tampered value decrypted")
+	}
+	if _, err := keyring.Decrypt(Prefix + "k1:garbage"); err == nil {
+		t.Error("a malformed value decrypted")
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	old, _ := NewKeyring("k1", map[string]string{"k1": testKey('a')})
+	encrypted, _ := old.Encrypt("Hypertension")
+
+	// A new active key is added; the old one stays for reading
+	rotated, err := NewKeyring("K2", map[string]string{"k1": testKey('a'), "k2": testKey('b')})
+	if err != nil {
+		t.Fatalf("NewKeyring: %v", err)
+	}
+	if rotated.ActiveKeyID() != "k2" {
+		t.Errorf("active key %q, want k2, matched case-insensitively", rotated.ActiveKeyID())
+	}
+	if !rotated.NeedsRotation(encrypted) {
+		t.Fatal("a value under the retired key does not need rotation")
+	}
+	plaintext, err := rotated.Decrypt(encrypted)
+	if err != nil || plaintext != "Hypertension" {
+		t.Fatalf("Decrypt under the retired key = %q, %v", plaintext, err)
+	}
+
+	reencrypted, _ := rotated.Encrypt(plaintext)
+	if rotated.NeedsRotation(reencrypted) {
+		t.Error("a value re-encrypted with the active key still needs rotation")
+	}
+
+	// Once every value is rotated the old key can be dropped
+	retired, _ := NewKeyring("k2", map[string]string{"k2": testKey('b')})
+	if plaintext, err := retired.Decrypt(reencrypted); err != nil || plaintext != "Hypertension" {
+		t.Errorf("Decrypt after dropping the retired key = %q, %v", plaintext, err)
+	}
+	if _, err := retired.Decrypt(encrypted); err == nil {
+		t.Error("a value under a dropped key decrypted")
+	}
+}
+
+func TestNewKeyringValidatesKeys(t *testing.T) {
+	tests := []struct {
+		name   string
+		active string
+		keys   map[string]string
+	}{
+		{"no keys", "k1", nil},
+		{"not base64", "k1", map[string]string{"k1": "not base64!"}},
+		{"short key", "k1", map[string]string{"k1": base64.StdEncoding.EncodeToString([]byte("short"))}},
+		{"unknown active key", "k2", map[string]string{"k1": testKey('a')}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewKeyring(tt.active, tt.keys); err == nil {
+				t.Error("NewKeyring succeeded, want an error")
+			}
+		})
+	}
+}
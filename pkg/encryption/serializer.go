@@ -0,0 +1,79 @@
+package encryption
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"gorm.io/gorm/schema"
+)
+
+// SerializerName is the name used in `serializer:encrypted` gorm tags
+const SerializerName = "encrypted"
+
+var (
+	defaultKeyring *Keyring
+	mu             sync.RWMutex
+)
+
+// Register makes the keyring the default and registers the encrypted gorm serializer
+func Register(keyring *Keyring) {
+	mu.Lock()
+	defaultKeyring = keyring
+	mu.Unlock()
+
+	schema.RegisterSerializer(SerializerName, Serializer{})
+}
+
+// Default returns the keyring registered with Register
+func Default() *Keyring {
+	mu.RLock()
+	defer mu.RUnlock()
+	return defaultKeyring
+}
+
+// Serializer transparently encrypts string columns on write and decrypts them on read
+type Serializer struct{}
+
+// Scan implements schema.SerializerInterface
+func (Serializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	var value string
+	switch v := dbValue.(type) {
+	case nil:
+	case string:
+		value = v
+	case []byte:
+		value = string(v)
+	default:
+		return fmt.Errorf("failed to decrypt %s: unsupported value type %T", field.Name, dbValue)
+	}
+
+	keyring := Default()
+	if keyring == nil {
+		return fmt.Errorf("failed to decrypt %s: no keyring registered", field.Name)
+	}
+
+	plaintext, err := keyring.Decrypt(value)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt %s: %w", field.Name, err)
+	}
+
+	field.ReflectValueOf(ctx, dst).SetString(plaintext)
+	return nil
+}
+
+// Value implements schema.SerializerValuerInterface
+func (Serializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	plaintext, ok := fieldValue.(string)
+	if !ok {
+		return nil, fmt.Errorf("failed to encrypt %s: unsupported value type %T", field.Name, fieldValue)
+	}
+
+	keyring := Default()
+	if keyring == nil {
+		return nil, fmt.Errorf("failed to encrypt %s: no keyring registered", field.Name)
+	}
+
+	return keyring.Encrypt(plaintext)
+}
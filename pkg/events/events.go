@@ -0,0 +1,117 @@
+// Package events publishes domain events to external subscribers.
+package events
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Event is a domain event in the form delivered to subscribers
+type Event struct {
+	ID            string          `json:"id"` // Events may be delivered more than once; receivers should de-duplicate on it
+	Type          string          `json:"type"`
+	Time          time.Time       `json:"time"`
+	AggregateType string          `json:"aggregate_type"`
+	Data          json.RawMessage `json:"data"`
+}
+
+// Publisher delivers events to subscribers
+type Publisher interface {
+	// Name returns a short identifier used in logs
+	Name() string
+	Publish(ctx context.Context, event Event) error
+}
+
+// WebhookPublisher posts each event as JSON to a subscriber URL. The body is signed with
+// HMAC-SHA256 using a shared secret, sent hex encoded in the X-Signature header.
+type WebhookPublisher struct {
+	url        string
+	secret     []byte
+	httpClient *http.Client
+}
+
+// NewWebhookPublisher creates a webhook publisher
+func NewWebhookPublisher(url, secret string, timeout time.Duration) (*WebhookPublisher, error) {
+	if url == "" || secret == "" {
+		return nil, errors.New("webhook url and secret are required")
+	}
+
+	return &WebhookPublisher{
+		url:        url,
+		secret:     []byte(secret),
+		httpClient: &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// Name returns the publisher identifier
+func (p *WebhookPublisher) Name() string {
+	return "webhook"
+}
+
+// Publish posts the event; any response other than 2xx is an error and the event is retried
+func (p *WebhookPublisher) Publish(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write(body)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event-Type", event.Type)
+	req.Header.Set("X-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook returned %s: %s", resp.Status, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
+
+// LogPublisher writes events to the log instead of publishing them, for development
+type LogPublisher struct {
+	logger *zap.Logger
+}
+
+// NewLogPublisher creates a publisher that only logs events
+func NewLogPublisher(logger *zap.Logger) *LogPublisher {
+	return &LogPublisher{logger: logger}
+}
+
+// Name returns the publisher identifier
+func (p *LogPublisher) Name() string {
+	return "log"
+}
+
+// Publish logs the event
+func (p *LogPublisher) Publish(ctx context.Context, event Event) error {
+	p.logger.Info("Event published",
+		zap.String("id", event.ID),
+		zap.String("type", event.Type),
+		zap.String("aggregate_type", event.AggregateType),
+	)
+	return nil
+}
@@ -0,0 +1,339 @@
+// Package fhir holds the HL7 FHIR R4 resources and data types served to EHR systems, as JSON in
+// the shape the specification defines, and parses the date search parameters resources are
+// searched with. Only the elements the API fills in are modelled.
+package fhir
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// Version is the FHIR version resources conform to
+	Version = "4.0.1"
+	// ContentType is the media type of FHIR JSON
+	ContentType = "application/fhir+json; charset=utf-8"
+)
+
+// Code systems
+const (
+	SystemLOINC               = "http://loinc.org"
+	SystemUCUM                = "http://unitsofmeasure.org"
+	SystemObservationCategory = "http://terminology.hl7.org/CodeSystem/observation-category"
+	SystemContactRelationship = "http://terminology.hl7.org/CodeSystem/v2-0131"
+	SystemIdentifierType      = "http://terminology.hl7.org/CodeSystem/v2-0203"
+)
+
+// Meta is the metadata of a resource
+type Meta struct {
+	LastUpdated string `json:"lastUpdated,omitempty"`
+}
+
+// Coding is a code from a code system
+type Coding struct {
+	System  string `json:"system,omitempty"`
+	Code    string `json:"code,omitempty"`
+	Display string `json:"display,omitempty"`
+}
+
+// CodeableConcept is a concept given by codes, text or both
+type CodeableConcept struct {
+	Coding []Coding `json:"coding,omitempty"`
+	Text   string   `json:"text,omitempty"`
+}
+
+// Identifier is a business identifier of a resource, such as a license number
+type Identifier struct {
+	Type   *CodeableConcept `json:"type,omitempty"`
+	System string           `json:"system,omitempty"`
+	Value  string           `json:"value"`
+}
+
+// HumanName is a person's name
+type HumanName struct {
+	Use    string   `json:"use,omitempty"`
+	Text   string   `json:"text,omitempty"`
+	Family string   `json:"family,omitempty"`
+	Given  []string `json:"given,omitempty"`
+}
+
+// ContactPoint is a phone number or email address
+type ContactPoint struct {
+	System string `json:"system"` // phone or email
+	Value  string `json:"value"`
+	Use    string `json:"use,omitempty"`
+}
+
+// Address is a postal address
+type Address struct {
+	Text string `json:"text"`
+}
+
+// Reference points to another resource, as Type/id
+type Reference struct {
+	Reference string `json:"reference"`
+	Display   string `json:"display,omitempty"`
+}
+
+// Quantity is a measured amount with its UCUM unit
+type Quantity struct {
+	Value  float64 `json:"value"`
+	Unit   string  `json:"unit,omitempty"`
+	System string  `json:"system,omitempty"`
+	Code   string  `json:"code,omitempty"`
+}
+
+// Annotation is a text note
+type Annotation struct {
+	Text string `json:"text"`
+}
+
+// Patient is a person receiving care
+type Patient struct {
+	ResourceType string           `json:"resourceType"`
+	ID           string           `json:"id"`
+	Meta         *Meta            `json:"meta,omitempty"`
+	Active       bool             `json:"active"`
+	Name         []HumanName      `json:"name,omitempty"`
+	Telecom      []ContactPoint   `json:"telecom,omitempty"`
+	Gender       string           `json:"gender,omitempty"` // male, female, other or unknown
+	BirthDate    string           `json:"birthDate,omitempty"`
+	Address      []Address        `json:"address,omitempty"`
+	Contact      []PatientContact `json:"contact,omitempty"`
+}
+
+// PatientContact is someone to contact about a patient
+type PatientContact struct {
+	Relationship []CodeableConcept `json:"relationship,omitempty"`
+	Name         *HumanName        `json:"name,omitempty"`
+	Telecom      []ContactPoint    `json:"telecom,omitempty"`
+}
+
+// Practitioner is a person providing care
+type Practitioner struct {
+	ResourceType  string                      `json:"resourceType"`
+	ID            string                      `json:"id"`
+	Meta          *Meta                       `json:"meta,omitempty"`
+	Identifier    []Identifier                `json:"identifier,omitempty"`
+	Active        bool                        `json:"active"`
+	Name          []HumanName                 `json:"name,omitempty"`
+	Telecom       []ContactPoint              `json:"telecom,omitempty"`
+	Qualification []PractitionerQualification `json:"qualification,omitempty"`
+}
+
+// PractitionerQualification is a qualification a practitioner holds
+type PractitionerQualification struct {
+	Code CodeableConcept `json:"code"`
+}
+
+// Appointment is a booked or proposed meeting of a patient and a practitioner
+type Appointment struct {
+	ResourceType    string                   `json:"resourceType"`
+	ID              string                   `json:"id"`
+	Meta            *Meta                    `json:"meta,omitempty"`
+	Status          string                   `json:"status"`
+	ServiceType     []CodeableConcept        `json:"serviceType,omitempty"`
+	AppointmentType *CodeableConcept         `json:"appointmentType,omitempty"`
+	ReasonCode      []CodeableConcept        `json:"reasonCode,omitempty"`
+	Start           string                   `json:"start,omitempty"`
+	End             string                   `json:"end,omitempty"`
+	MinutesDuration int                      `json:"minutesDuration,omitempty"`
+	Created         string                   `json:"created,omitempty"`
+	Participant     []AppointmentParticipant `json:"participant"`
+}
+
+// AppointmentParticipant is a person taking part in an appointment
+type AppointmentParticipant struct {
+	Actor    Reference `json:"actor"`
+	Required string    `json:"required,omitempty"` // required, optional or information-only
+	Status   string    `json:"status"`             // accepted, declined, tentative or needs-action
+}
+
+// Observation is a measurement made about a patient
+type Observation struct {
+	ResourceType      string                 `json:"resourceType"`
+	ID                string                 `json:"id"`
+	Meta              *Meta                  `json:"meta,omitempty"`
+	Status            string                 `json:"status"`
+	Category          []CodeableConcept      `json:"category,omitempty"`
+	Code              CodeableConcept        `json:"code"`
+	Subject           Reference              `json:"subject"`
+	EffectiveDateTime string                 `json:"effectiveDateTime,omitempty"`
+	Issued            string                 `json:"issued,omitempty"`
+	Performer         []Reference            `json:"performer,omitempty"`
+	ValueQuantity     *Quantity              `json:"valueQuantity,omitempty"`
+	Note              []Annotation           `json:"note,omitempty"`
+	Component         []ObservationComponent `json:"component,omitempty"`
+}
+
+// ObservationComponent is one value of a measurement made of several, such as the systolic and
+// diastolic blood pressure
+type ObservationComponent struct {
+	Code          CodeableConcept `json:"code"`
+	ValueQuantity *Quantity       `json:"valueQuantity,omitempty"`
+}
+
+// Bundle is a page of search results
+type Bundle struct {
+	ResourceType string        `json:"resourceType"`
+	Type         string        `json:"type"` // searchset
+	Total        int64         `json:"total"`
+	Link         []BundleLink  `json:"link,omitempty"`
+	Entry        []BundleEntry `json:"entry,omitempty"`
+}
+
+// BundleLink links a page of search results to the others
+type BundleLink struct {
+	Relation string `json:"relation"` // self or next
+	URL      string `json:"url"`
+}
+
+// BundleEntry is one resource of a bundle
+type BundleEntry struct {
+	FullURL  string       `json:"fullUrl"`
+	Resource interface{}  `json:"resource"`
+	Search   *EntrySearch `json:"search,omitempty"`
+}
+
+// EntrySearch tells why an entry is in search results
+type EntrySearch struct {
+	Mode string `json:"mode"` // match
+}
+
+// NewSearchBundle creates an empty searchset bundle
+func NewSearchBundle(total int64) *Bundle {
+	return &Bundle{ResourceType: "Bundle", Type: "searchset", Total: total}
+}
+
+// Add appends a resource matching the search, addressed by fullURL
+func (b *Bundle) Add(fullURL string, resource interface{}) {
+	b.Entry = append(b.Entry, BundleEntry{FullURL: fullURL, Resource: resource, Search: &EntrySearch{Mode: "match"}})
+}
+
+// OperationOutcome reports why a request failed
+type OperationOutcome struct {
+	ResourceType string  `json:"resourceType"`
+	Issue        []Issue `json:"issue"`
+}
+
+// Issue is one problem of an operation outcome
+type Issue struct {
+	Severity    string `json:"severity"` // fatal, error, warning or information
+	Code        string `json:"code"`     // e.g. invalid, not-found, forbidden, exception
+	Diagnostics string `json:"diagnostics,omitempty"`
+}
+
+// NewOperationOutcome creates an operation outcome with a single error
+func NewOperationOutcome(code, diagnostics string) *OperationOutcome {
+	return &OperationOutcome{
+		ResourceType: "OperationOutcome",
+		Issue:        []Issue{{Severity: "error", Code: code, Diagnostics: diagnostics}},
+	}
+}
+
+// CapabilityStatement describes what a FHIR server supports
+type CapabilityStatement struct {
+	ResourceType string           `json:"resourceType"`
+	Status       string           `json:"status"`
+	Date         string           `json:"date"`
+	Kind         string           `json:"kind"`
+	Software     Software         `json:"software"`
+	FHIRVersion  string           `json:"fhirVersion"`
+	Format       []string         `json:"format"`
+	Rest         []CapabilityRest `json:"rest"`
+}
+
+// Software names the server software
+type Software struct {
+	Name string `json:"name"`
+}
+
+// CapabilityRest lists the resources served over REST
+type CapabilityRest struct {
+	Mode     string               `json:"mode"` // server
+	Resource []CapabilityResource `json:"resource"`
+}
+
+// CapabilityResource lists the interactions and search parameters of one resource type
+type CapabilityResource struct {
+	Type        string             `json:"type"`
+	Interaction []Interaction      `json:"interaction"`
+	SearchParam []CapabilitySearch `json:"searchParam,omitempty"`
+}
+
+// Interaction is an operation supported on a resource type, such as read or search-type
+type Interaction struct {
+	Code string `json:"code"`
+}
+
+// CapabilitySearch is a search parameter of a resource type
+type CapabilitySearch struct {
+	Name          string `json:"name"`
+	Type          string `json:"type"` // token, string, date or reference
+	Documentation string `json:"documentation,omitempty"`
+}
+
+// ErrInvalidDate is wrapped by the errors of ParseDate
+var ErrInvalidDate = errors.New("invalid date parameter")
+
+// dateLayouts are the precisions a date parameter may be given in, with the period each covers
+var dateLayouts = []struct {
+	layout string
+	next   func(time.Time) time.Time
+}{
+	{"2006-01-02T15:04:05Z07:00", func(t time.Time) time.Time { return t.Add(time.Second) }},
+	{"2006-01-02T15:04Z07:00", func(t time.Time) time.Time { return t.Add(time.Minute) }},
+	{"2006-01-02", func(t time.Time) time.Time { return t.AddDate(0, 0, 1) }},
+	{"2006-01", func(t time.Time) time.Time { return t.AddDate(0, 1, 0) }},
+	{"2006", func(t time.Time) time.Time { return t.AddDate(1, 0, 0) }},
+}
+
+// ParseDate reads a date search parameter such as ge2025-06-01 and returns the range of times
+// it matches, as [from, to) with a zero bound left open. The value may carry the eq, gt, ge, lt
+// or le prefix and be a year, month, day or time; dates without a time are read in loc.
+func ParseDate(value string, loc *time.Location) (from, to time.Time, err error) {
+	prefix := "eq"
+	if len(value) > 2 && value[0] >= 'a' && value[0] <= 'z' {
+		prefix, value = value[:2], value[2:]
+	}
+
+	var start, end time.Time
+	parsed := false
+	for _, l := range dateLayouts {
+		if t, err := time.ParseInLocation(l.layout, value, loc); err == nil {
+			start, end, parsed = t, l.next(t), true
+			break
+		}
+	}
+	if !parsed {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: %q is not a FHIR date", ErrInvalidDate, value)
+	}
+
+	switch prefix {
+	case "eq":
+		return start, end, nil
+	case "gt":
+		return end, time.Time{}, nil
+	case "ge":
+		return start, time.Time{}, nil
+	case "lt":
+		return time.Time{}, start, nil
+	case "le":
+		return time.Time{}, end, nil
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("%w: prefix %q is not supported; use eq, gt, ge, lt or le", ErrInvalidDate, prefix)
+}
+
+// SplitReference returns the id of a reference search parameter, given as a bare id or as
+// Type/id, checking it refers to a resource of resourceType
+func SplitReference(value, resourceType string) (string, error) {
+	if i := strings.LastIndex(value, "/"); i >= 0 {
+		if !strings.HasSuffix(value[:i], resourceType) {
+			return "", fmt.Errorf("reference %q must point to a %s", value, resourceType)
+		}
+		value = value[i+1:]
+	}
+	return value, nil
+}
@@ -0,0 +1,350 @@
+// Package gcal talks to the Google Calendar API: the OAuth authorization code flow for offline
+// access to a user's calendar, writing events and reading the busy times of a calendar.
+package gcal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	authURL     = "https://accounts.google.com/o/oauth2/v2/auth"
+	tokenURL    = "https://oauth2.googleapis.com/token"
+	calendarURL = "https://www.googleapis.com/calendar/v3"
+	// Scope grants reading and writing the user's calendar events
+	Scope = "https://www.googleapis.com/auth/calendar.events"
+)
+
+var (
+	// ErrUnauthorized is returned when Google rejects the access token or refresh token, such as
+	// after the user revoked access
+	ErrUnauthorized = errors.New("google calendar access was revoked or has expired")
+	// ErrNotFound is returned for an event that no longer exists
+	ErrNotFound = errors.New("google calendar event not found")
+)
+
+// StatusError is a response from Google with an unexpected status
+type StatusError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("google calendar returned status %d: %s", e.StatusCode, e.Message)
+}
+
+// Temporary reports whether the request may succeed if retried later
+func (e *StatusError) Temporary() bool {
+	return e.StatusCode >= http.StatusInternalServerError || e.StatusCode == http.StatusTooManyRequests
+}
+
+// Token is an OAuth token for a user's calendar
+type Token struct {
+	AccessToken  string
+	RefreshToken string // Only returned when the user grants access, not on refresh
+	Expiry       time.Time
+}
+
+// Event is a calendar event written for an appointment. AppointmentID is stored as a private
+// property of the event, so the event can be told apart from the user's own when reading busy
+// times.
+type Event struct {
+	Summary       string
+	Description   string
+	Location      string
+	Start         time.Time
+	End           time.Time
+	AppointmentID string
+}
+
+// Busy is a period a calendar is busy
+type Busy struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Client is a Google API client for one OAuth application
+type Client struct {
+	clientID     string
+	clientSecret string
+	httpClient   *http.Client
+}
+
+// NewClient creates a client for the OAuth application with the given credentials
+func NewClient(clientID, clientSecret string, timeout time.Duration) (*Client, error) {
+	if clientID == "" || clientSecret == "" {
+		return nil, errors.New("google client id and secret are required")
+	}
+	return &Client{
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		httpClient:   &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// AuthURL returns the consent page the user is sent to. Google redirects back to redirectURL
+// with a code for Exchange and the state.
+func (c *Client) AuthURL(redirectURL, state string) string {
+	params := url.Values{
+		"client_id":     {c.clientID},
+		"redirect_uri":  {redirectURL},
+		"response_type": {"code"},
+		"scope":         {Scope},
+		"access_type":   {"offline"},
+		"prompt":        {"consent"}, // Always returns a refresh token, even if access was granted before
+		"state":         {state},
+	}
+	return authURL + "?" + params.Encode()
+}
+
+// Exchange trades an authorization code for a token
+func (c *Client) Exchange(ctx context.Context, code, redirectURL string) (*Token, error) {
+	return c.token(ctx, url.Values{
+		"grant_type":   {"authorization_code"},
+		"code":         {code},
+		"redirect_uri": {redirectURL},
+	})
+}
+
+// Refresh gets a new access token with a refresh token
+func (c *Client) Refresh(ctx context.Context, refreshToken string) (*Token, error) {
+	token, err := c.token(ctx, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {refreshToken},
+	})
+	if err != nil {
+		return nil, err
+	}
+	token.RefreshToken = refreshToken
+	return token, nil
+}
+
+func (c *Client) token(ctx context.Context, form url.Values) (*Token, error) {
+	form.Set("client_id", c.clientID)
+	form.Set("client_secret", c.clientSecret)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var body struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int    `json:"expires_in"`
+	}
+	if err := c.do(req, &body); err != nil {
+		// Google answers a revoked or invalid grant with 400 invalid_grant
+		var status *StatusError
+		if errors.As(err, &status) && status.StatusCode == http.StatusBadRequest && strings.Contains(status.Message, "invalid_grant") {
+			return nil, ErrUnauthorized
+		}
+		return nil, err
+	}
+	return &Token{
+		AccessToken:  body.AccessToken,
+		RefreshToken: body.RefreshToken,
+		Expiry:       time.Now().Add(time.Duration(body.ExpiresIn) * time.Second),
+	}, nil
+}
+
+// InsertEvent creates an event and returns its ID
+func (c *Client) InsertEvent(ctx context.Context, accessToken, calendarID string, event Event) (string, error) {
+	var created struct {
+		ID string `json:"id"`
+	}
+	err := c.call(ctx, accessToken, http.MethodPost, eventsURL(calendarID), eventBody(event), &created)
+	return created.ID, err
+}
+
+// UpdateEvent replaces an event. It returns ErrNotFound if the user deleted the event.
+func (c *Client) UpdateEvent(ctx context.Context, accessToken, calendarID, eventID string, event Event) error {
+	return c.call(ctx, accessToken, http.MethodPut, eventsURL(calendarID)+"/"+url.PathEscape(eventID), eventBody(event), nil)
+}
+
+// DeleteEvent deletes an event. Events already deleted are not an error.
+func (c *Client) DeleteEvent(ctx context.Context, accessToken, calendarID, eventID string) error {
+	err := c.call(ctx, accessToken, http.MethodDelete, eventsURL(calendarID)+"/"+url.PathEscape(eventID), nil, nil)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+// ListBusy returns the periods between from and to in which the calendar has events that block
+// time. Events marked free, declined events and events written by InsertEvent are left out.
+// All-day events are busy for the whole day in the calendar's timezone.
+func (c *Client) ListBusy(ctx context.Context, accessToken, calendarID string, from, to time.Time) ([]Busy, error) {
+	var busy []Busy
+	pageToken := ""
+	for {
+		params := url.Values{
+			"timeMin":      {from.UTC().Format(time.RFC3339)},
+			"timeMax":      {to.UTC().Format(time.RFC3339)},
+			"singleEvents": {"true"},
+			"maxResults":   {"250"},
+		}
+		if pageToken != "" {
+			params.Set("pageToken", pageToken)
+		}
+
+		var page eventList
+		if err := c.call(ctx, accessToken, http.MethodGet, eventsURL(calendarID)+"?"+params.Encode(), nil, &page); err != nil {
+			return nil, err
+		}
+		loc, err := time.LoadLocation(page.TimeZone)
+		if err != nil {
+			loc = time.UTC
+		}
+		for _, item := range page.Items {
+			if period, ok := item.busy(loc); ok {
+				busy = append(busy, period)
+			}
+		}
+
+		if page.NextPageToken == "" {
+			return busy, nil
+		}
+		pageToken = page.NextPageToken
+	}
+}
+
+// call sends an authorized Calendar API request with an optional JSON body and decodes the
+// response into out
+func (c *Client) call(ctx context.Context, accessToken, method, endpoint string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	err = c.do(req, out)
+	var status *StatusError
+	if errors.As(err, &status) {
+		switch status.StatusCode {
+		case http.StatusUnauthorized:
+			return ErrUnauthorized
+		case http.StatusNotFound, http.StatusGone:
+			return ErrNotFound
+		}
+	}
+	return err
+}
+
+func (c *Client) do(req *http.Request, out interface{}) error {
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return &StatusError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(message))}
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode google calendar response: %w", err)
+	}
+	return nil
+}
+
+func eventsURL(calendarID string) string {
+	return calendarURL + "/calendars/" + url.PathEscape(calendarID) + "/events"
+}
+
+// appointmentProperty is the private extended property holding the appointment of an event
+const appointmentProperty = "ehassAppointment"
+
+func eventBody(event Event) map[string]interface{} {
+	return map[string]interface{}{
+		"summary":     event.Summary,
+		"description": event.Description,
+		"location":    event.Location,
+		"start":       map[string]string{"dateTime": event.Start.UTC().Format(time.RFC3339)},
+		"end":         map[string]string{"dateTime": event.End.UTC().Format(time.RFC3339)},
+		"extendedProperties": map[string]interface{}{
+			"private": map[string]string{appointmentProperty: event.AppointmentID},
+		},
+		// The appointment reminders come from the clinic
+		"reminders": map[string]interface{}{"useDefault": false},
+	}
+}
+
+type eventList struct {
+	TimeZone      string      `json:"timeZone"`
+	NextPageToken string      `json:"nextPageToken"`
+	Items         []eventItem `json:"items"`
+}
+
+type eventTime struct {
+	DateTime string `json:"dateTime"`
+	Date     string `json:"date"`
+}
+
+type eventItem struct {
+	Status             string    `json:"status"`
+	Transparency       string    `json:"transparency"`
+	Start              eventTime `json:"start"`
+	End                eventTime `json:"end"`
+	ExtendedProperties struct {
+		Private map[string]string `json:"private"`
+	} `json:"extendedProperties"`
+	Attendees []struct {
+		Self           bool   `json:"self"`
+		ResponseStatus string `json:"responseStatus"`
+	} `json:"attendees"`
+}
+
+// busy returns the period an event blocks, if it blocks any
+func (e eventItem) busy(loc *time.Location) (Busy, bool) {
+	if e.Status == "cancelled" || e.Transparency == "transparent" {
+		return Busy{}, false
+	}
+	if _, ours := e.ExtendedProperties.Private[appointmentProperty]; ours {
+		return Busy{}, false
+	}
+	for _, attendee := range e.Attendees {
+		if attendee.Self && attendee.ResponseStatus == "declined" {
+			return Busy{}, false
+		}
+	}
+	start, err := e.Start.parse(loc)
+	if err != nil {
+		return Busy{}, false
+	}
+	end, err := e.End.parse(loc)
+	if err != nil || !end.After(start) {
+		return Busy{}, false
+	}
+	return Busy{Start: start, End: end}, true
+}
+
+func (t eventTime) parse(loc *time.Location) (time.Time, error) {
+	if t.DateTime != "" {
+		return time.Parse(time.RFC3339, t.DateTime)
+	}
+	return time.ParseInLocation("2006-01-02", t.Date, loc)
+}
@@ -0,0 +1,170 @@
+// Package ical writes RFC 5545 iCalendar files with events and display alarms, as imported or
+// subscribed to by calendar applications such as Google Calendar and Outlook.
+package ical
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// maxLineOctets is the longest content line RFC 5545 allows before it must be folded
+const maxLineOctets = 75
+
+// Event status values
+const (
+	StatusTentative = "TENTATIVE"
+	StatusConfirmed = "CONFIRMED"
+	StatusCancelled = "CANCELLED"
+)
+
+// Alarm shows a reminder Before an event starts
+type Alarm struct {
+	Before      time.Duration
+	Description string
+}
+
+// Event is a calendar event. UID must stay the same across exports of the same event, so
+// subscribed calendars update it instead of adding a copy.
+type Event struct {
+	UID          string
+	Start        time.Time
+	End          time.Time
+	Summary      string
+	Description  string
+	Location     string
+	URL          string
+	Status       string // One of the Status values; empty leaves it out
+	LastModified time.Time
+	Alarms       []Alarm
+}
+
+// Calendar is an iCalendar object holding events
+type Calendar struct {
+	ProdID  string        // Identifies the product that wrote the calendar
+	Name    string        // Shown by calendar applications for subscribed calendars; may be empty
+	Refresh time.Duration // How often subscribers should refetch the calendar; 0 leaves it to them
+	Events  []Event
+}
+
+// Bytes renders the calendar. Times are written in UTC, which every client converts to the
+// viewer's timezone.
+func (c *Calendar) Bytes() []byte {
+	var b bytes.Buffer
+	now := time.Now()
+	line(&b, "BEGIN:VCALENDAR")
+	line(&b, "VERSION:2.0")
+	line(&b, "PRODID:"+c.ProdID)
+	line(&b, "CALSCALE:GREGORIAN")
+	line(&b, "METHOD:PUBLISH")
+	if c.Name != "" {
+		line(&b, "X-WR-CALNAME:"+escape(c.Name))
+	}
+	if c.Refresh > 0 {
+		line(&b, "REFRESH-INTERVAL;VALUE=DURATION:"+duration(c.Refresh))
+		line(&b, "X-PUBLISHED-TTL:"+duration(c.Refresh))
+	}
+	for _, event := range c.Events {
+		line(&b, "BEGIN:VEVENT")
+		line(&b, "UID:"+event.UID)
+		line(&b, "DTSTAMP:"+timestamp(now))
+		line(&b, "DTSTART:"+timestamp(event.Start))
+		line(&b, "DTEND:"+timestamp(event.End))
+		if !event.LastModified.IsZero() {
+			line(&b, "LAST-MODIFIED:"+timestamp(event.LastModified))
+		}
+		line(&b, "SUMMARY:"+escape(event.Summary))
+		if event.Description != "" {
+			line(&b, "DESCRIPTION:"+escape(event.Description))
+		}
+		if event.Location != "" {
+			line(&b, "LOCATION:"+escape(event.Location))
+		}
+		if event.URL != "" {
+			line(&b, "URL:"+event.URL)
+		}
+		if event.Status != "" {
+			line(&b, "STATUS:"+event.Status)
+		}
+		// Alarms of cancelled events would remind of an appointment that no longer happens
+		if event.Status != StatusCancelled {
+			for _, alarm := range event.Alarms {
+				line(&b, "BEGIN:VALARM")
+				line(&b, "ACTION:DISPLAY")
+				line(&b, "TRIGGER:-"+duration(alarm.Before))
+				line(&b, "DESCRIPTION:"+escape(alarm.Description))
+				line(&b, "END:VALARM")
+			}
+		}
+		line(&b, "END:VEVENT")
+	}
+	line(&b, "END:VCALENDAR")
+	return b.Bytes()
+}
+
+// line writes a content line ending in CRLF, folding it so no line exceeds 75 octets. Folds never
+// split a UTF-8 sequence.
+func line(b *bytes.Buffer, content string) {
+	limit := maxLineOctets
+	for len(content) > limit {
+		cut := limit
+		for cut > 0 && !startsRune(content[cut]) {
+			cut--
+		}
+		b.WriteString(content[:cut])
+		b.WriteString("\r\n ")
+		content = content[cut:]
+		// Continuation lines start with a space, which counts towards their length
+		limit = maxLineOctets - 1
+	}
+	b.WriteString(content)
+	b.WriteString("\r\n")
+}
+
+// startsRune reports whether c is the first byte of a UTF-8 sequence
+func startsRune(c byte) bool {
+	return c&0xC0 != 0x80
+}
+
+// escape escapes a TEXT value
+func escape(text string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+		"\r", `\n`,
+	).Replace(text)
+}
+
+// timestamp formats a time as a UTC DATE-TIME value
+func timestamp(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// duration formats a positive duration as a DURATION value, e.g. PT1H30M or P1D
+func duration(d time.Duration) string {
+	if d < time.Minute {
+		return "PT0M"
+	}
+	minutes := int(d / time.Minute)
+	days, minutes := minutes/(24*60), minutes%(24*60)
+	hours, minutes := minutes/60, minutes%60
+
+	s := "P"
+	if days > 0 {
+		s += fmt.Sprintf("%dD", days)
+	}
+	if hours > 0 || minutes > 0 {
+		s += "T"
+		if hours > 0 {
+			s += fmt.Sprintf("%dH", hours)
+		}
+		if minutes > 0 {
+			s += fmt.Sprintf("%dM", minutes)
+		}
+	}
+	return s
+}
@@ -0,0 +1,116 @@
+// Package metrics writes metrics in the Prometheus text exposition format, so they can be
+// scraped without pulling in the Prometheus client library. Values are gathered by the caller
+// at scrape time; nothing is accumulated here.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ContentType is the media type of the text exposition format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Type is the kind of a metric
+type Type string
+
+const (
+	Gauge   Type = "gauge"   // A value that can go up and down
+	Counter Type = "counter" // A total that only goes up, except when the process restarts
+)
+
+// Sample is one value of a metric, told apart from its siblings by its labels
+type Sample struct {
+	Labels map[string]string
+	Value  float64
+}
+
+// Family is a named metric and its samples
+type Family struct {
+	Name    string
+	Help    string
+	Type    Type
+	Samples []Sample
+}
+
+// NewGauge returns a gauge with a single unlabelled value
+func NewGauge(name, help string, value float64) Family {
+	return Family{Name: name, Help: help, Type: Gauge, Samples: []Sample{{Value: value}}}
+}
+
+// Add appends a sample with labels given as name, value pairs
+func (f *Family) Add(value float64, labels ...string) {
+	sample := Sample{Value: value}
+	if len(labels) > 0 {
+		sample.Labels = make(map[string]string, len(labels)/2)
+		for i := 0; i+1 < len(labels); i += 2 {
+			sample.Labels[labels[i]] = labels[i+1]
+		}
+	}
+	f.Samples = append(f.Samples, sample)
+}
+
+// Write writes families in the text exposition format. Families without samples are skipped.
+func Write(w io.Writer, families []Family) error {
+	out := bufio.NewWriter(w)
+	for _, family := range families {
+		if len(family.Samples) == 0 {
+			continue
+		}
+		fmt.Fprintf(out, "# HELP %s %s\n", family.Name, escapeHelp(family.Help))
+		fmt.Fprintf(out, "# TYPE %s %s\n", family.Name, family.Type)
+		for _, sample := range family.Samples {
+			fmt.Fprintf(out, "%s%s %s\n", family.Name, formatLabels(sample.Labels), formatValue(sample.Value))
+		}
+	}
+	return out.Flush()
+}
+
+// formatLabels renders labels sorted by name, e.g. {queue="outbox",state="open"}
+func formatLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pairs := make([]string, 0, len(names))
+	for _, name := range names {
+		pairs = append(pairs, name+`="`+escapeLabel(labels[name])+`"`)
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(help string) string {
+	return helpEscaper.Replace(help)
+}
+
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
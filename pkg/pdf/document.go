@@ -0,0 +1,297 @@
+// Package pdf writes simple text-only PDF documents (headings, paragraphs and tables)
+// using the built-in Helvetica fonts, so printable output needs no external dependencies.
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// A4 portrait page geometry in points
+const (
+	pageWidth    = 595.0
+	pageHeight   = 842.0
+	margin       = 50.0
+	contentWidth = pageWidth - 2*margin
+)
+
+// Font sizes used by the document elements
+const (
+	headingSize = 16.0
+	textSize    = 10.0
+	tableSize   = 9.0
+	lineSpacing = 1.4
+)
+
+// Column describes a table column; Width is a fraction of the content width
+type Column struct {
+	Title string
+	Width float64
+}
+
+// Document is a PDF document built top to bottom, adding pages as content overflows
+type Document struct {
+	title string
+	pages []*bytes.Buffer
+	page  *bytes.Buffer
+	y     float64 // Distance of the cursor from the top of the page
+	color string  // RGB components of headings and rules, empty for black
+}
+
+// New creates an empty document with the given title
+func New(title string) *Document {
+	d := &Document{title: title}
+	d.AddPage()
+	return d
+}
+
+// AddPage starts a new page and moves the cursor to its top
+func (d *Document) AddPage() {
+	d.page = &bytes.Buffer{}
+	d.pages = append(d.pages, d.page)
+	d.y = margin
+}
+
+// SetHeadingColor sets the color of headings and rules written after it, given in hex such as
+// #0b5fa5 or #fff. Colors that cannot be parsed leave them black.
+func (d *Document) SetHeadingColor(hex string) {
+	d.color = ""
+	hex = strings.TrimPrefix(hex, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	value, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil || len(hex) != 6 {
+		return
+	}
+	d.color = fmt.Sprintf("%.3f %.3f %.3f", float64(value>>16&0xff)/255, float64(value>>8&0xff)/255, float64(value&0xff)/255)
+}
+
+// Heading writes a bold heading
+func (d *Document) Heading(text string) {
+	d.ensureSpace(headingSize * lineSpacing)
+	d.y += headingSize
+	if d.color != "" {
+		fmt.Fprintf(d.page, "%s rg\n", d.color)
+	}
+	d.text(margin, d.y, headingSize, true, text)
+	if d.color != "" {
+		d.page.WriteString("0 g\n")
+	}
+	d.y += headingSize * (lineSpacing - 1)
+}
+
+// Rule draws a thick horizontal rule across the content width in the heading color
+func (d *Document) Rule() {
+	d.ensureSpace(8)
+	d.y += 4
+	color := "0 0 0"
+	if d.color != "" {
+		color = d.color
+	}
+	fmt.Fprintf(d.page, "%s RG 2 w %.2f %.2f m %.2f %.2f l S 0 G\n", color, margin, pageHeight-d.y, margin+contentWidth, pageHeight-d.y)
+	d.y += 4
+}
+
+// Text writes a paragraph, wrapping it to the content width
+func (d *Document) Text(text string) {
+	for _, line := range wrap(text, textSize, contentWidth) {
+		d.ensureSpace(textSize * lineSpacing)
+		d.y += textSize
+		d.text(margin, d.y, textSize, false, line)
+		d.y += textSize * (lineSpacing - 1)
+	}
+}
+
+// Spacer moves the cursor down by height points
+func (d *Document) Spacer(height float64) {
+	d.y += height
+}
+
+// Table writes a table with a bold header row, repeating the header on each new page.
+// Cell text that does not fit its column is truncated.
+func (d *Document) Table(columns []Column, rows [][]string) {
+	rowHeight := tableSize * 2
+
+	header := func() {
+		titles := make([]string, len(columns))
+		for i, col := range columns {
+			titles[i] = col.Title
+		}
+		d.row(columns, titles, true)
+		d.line(margin, d.y, margin+contentWidth, d.y)
+	}
+
+	d.ensureSpace(rowHeight * 2)
+	header()
+
+	for _, cells := range rows {
+		if d.y+rowHeight > pageHeight-margin {
+			d.AddPage()
+			header()
+		}
+		d.row(columns, cells, false)
+		d.line(margin, d.y, margin+contentWidth, d.y)
+	}
+}
+
+// Bytes renders the document
+func (d *Document) Bytes() []byte {
+	var out bytes.Buffer
+	var offsets []int
+
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	out.WriteString("%PDF-1.4\n")
+
+	// Objects 1-5: catalog, page tree, regular and bold fonts, info; then a page and content stream per page
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 6+2*i)
+	}
+
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	object(fmt.Sprintf("<< /Title (%s) /Producer (EHASS) >>", escape(d.title)))
+
+	for i, page := range d.pages {
+		object(fmt.Sprintf(
+			"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] "+
+				"/Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			pageWidth, pageHeight, 7+2*i))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", page.Len(), page.String()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R /Info 5 0 R >>\nstartxref\n%d\n%%%%EOF\n",
+		len(offsets)+1, xref)
+
+	return out.Bytes()
+}
+
+// row writes one table row at the cursor and advances it
+func (d *Document) row(columns []Column, cells []string, bold bool) {
+	rowHeight := tableSize * 2
+	x := margin
+	for i, col := range columns {
+		width := col.Width * contentWidth
+		if i < len(cells) {
+			d.text(x+2, d.y+tableSize*1.4, tableSize, bold, truncate(cells[i], tableSize, width-4))
+		}
+		x += width
+	}
+	d.y += rowHeight
+}
+
+// ensureSpace starts a new page if height points do not fit below the cursor
+func (d *Document) ensureSpace(height float64) {
+	if d.y+height > pageHeight-margin {
+		d.AddPage()
+	}
+}
+
+// text draws a single line of text with its baseline at y from the top of the page
+func (d *Document) text(x, y, size float64, bold bool, s string) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	fmt.Fprintf(d.page, "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, pageHeight-y, escape(s))
+}
+
+// line draws a thin horizontal rule
+func (d *Document) line(x1, y1, x2, y2 float64) {
+	fmt.Fprintf(d.page, "0.5 w %.2f %.2f m %.2f %.2f l S\n", x1, pageHeight-y1, x2, pageHeight-y2)
+}
+
+// textWidth estimates the width of s in Helvetica at the given size
+func textWidth(s string, size float64) float64 {
+	width := 0.0
+	for _, r := range s {
+		switch {
+		case strings.ContainsRune("ijlt.,:;|!'` ", r):
+			width += 0.3
+		case r >= 'A' && r <= 'Z', strings.ContainsRune("mw@%", r):
+			width += 0.7
+		default:
+			width += 0.55
+		}
+	}
+	return width * size
+}
+
+// truncate shortens s with an ellipsis so that it fits within width
+func truncate(s string, size, width float64) string {
+	if textWidth(s, size) <= width {
+		return s
+	}
+	runes := []rune(s)
+	for len(runes) > 0 && textWidth(string(runes)+"...", size) > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "..."
+}
+
+// wrap splits text into lines that fit within width, breaking on spaces
+func wrap(text string, size, width float64) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(text, "\n") {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			candidate := word
+			if line != "" {
+				candidate = line + " " + word
+			}
+			if line != "" && textWidth(candidate, size) > width {
+				lines = append(lines, line)
+				candidate = word
+			}
+			line = candidate
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// winAnsiExtras maps common punctuation outside Latin-1 to its WinAnsi code
+var winAnsiExtras = map[rune]byte{
+	'€': 0x80, '‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94,
+	'•': 0x95, '–': 0x96, '—': 0x97, '…': 0x85,
+}
+
+// escape encodes s as the body of a PDF literal string in WinAnsi encoding.
+// Characters WinAnsi cannot represent are replaced with '?'.
+func escape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if code, ok := winAnsiExtras[r]; ok {
+			fmt.Fprintf(&b, "\\%03o", code)
+			continue
+		}
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\n' || r == '\r' || r == '\t':
+			b.WriteByte(' ')
+		case r < 0x20 || r > 0xFF:
+			b.WriteByte('?')
+		case r < 0x80:
+			b.WriteRune(r)
+		default:
+			fmt.Fprintf(&b, "\\%03o", r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,176 @@
+// Package phone validates phone numbers and formats them in E.164, the form SMS providers
+// expect. Numbers are checked against the numbering plans of the regions in the metadata below:
+// the calling code, the national prefix dialled before numbers within the region, and the
+// pattern of national numbers. Numbers of regions not listed are refused rather than guessed.
+package phone
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ErrInvalid is wrapped by every error returned for a number that cannot be used
+var ErrInvalid = errors.New("invalid phone number")
+
+// Number is a validated phone number
+type Number struct {
+	E164   string // e.g. +27821234567
+	Region string // ISO 3166-1 alpha-2 code of the region the number belongs to
+}
+
+// region is the numbering plan of one region
+type region struct {
+	callingCode string
+	trunkPrefix string         // Dialled before national numbers within the region; empty when none
+	national    *regexp.Regexp // National significant numbers
+	leading     []string       // Leading digits of national numbers, for regions sharing a calling code with the main one
+}
+
+var regions = map[string]region{
+	// North American Numbering Plan; Canada is told apart by area code
+	"US": {callingCode: "1", trunkPrefix: "1", national: regexp.MustCompile(`^[2-9]\d{2}[2-9]\d{6}$`)},
+	"CA": {callingCode: "1", trunkPrefix: "1", national: regexp.MustCompile(`^[2-9]\d{2}[2-9]\d{6}$`), leading: []string{
+		"204", "226", "236", "249", "250", "263", "289", "306", "343", "354", "365", "367", "368", "382",
+		"403", "416", "418", "428", "431", "437", "438", "450", "468", "474", "506", "514", "519", "548",
+		"579", "581", "584", "587", "604", "613", "639", "647", "672", "683", "705", "709", "742", "753",
+		"778", "780", "782", "807", "819", "825", "867", "873", "879", "902", "905",
+	}},
+
+	// Africa
+	"ZA": {callingCode: "27", trunkPrefix: "0", national: regexp.MustCompile(`^[1-9]\d{8}$`)},
+	"NA": {callingCode: "264", trunkPrefix: "0", national: regexp.MustCompile(`^[6-8]\d{7,8}$`)},
+	"BW": {callingCode: "267", national: regexp.MustCompile(`^[2-9]\d{6,7}$`)},
+	"LS": {callingCode: "266", national: regexp.MustCompile(`^[2-6]\d{7}$`)},
+	"SZ": {callingCode: "268", national: regexp.MustCompile(`^[2-7]\d{7}$`)},
+	"ZW": {callingCode: "263", trunkPrefix: "0", national: regexp.MustCompile(`^[1-9]\d{7,9}$`)},
+	"ZM": {callingCode: "260", trunkPrefix: "0", national: regexp.MustCompile(`^[2-9]\d{8}$`)},
+	"MZ": {callingCode: "258", national: regexp.MustCompile(`^[28]\d{7,8}$`)},
+	"MW": {callingCode: "265", trunkPrefix: "0", national: regexp.MustCompile(`^[1-9]\d{6,8}$`)},
+	"KE": {callingCode: "254", trunkPrefix: "0", national: regexp.MustCompile(`^[1-9]\d{8}$`)},
+	"TZ": {callingCode: "255", trunkPrefix: "0", national: regexp.MustCompile(`^[2-9]\d{8}$`)},
+	"UG": {callingCode: "256", trunkPrefix: "0", national: regexp.MustCompile(`^[2-9]\d{8}$`)},
+	"NG": {callingCode: "234", trunkPrefix: "0", national: regexp.MustCompile(`^[1-9]\d{7,9}$`)},
+	"GH": {callingCode: "233", trunkPrefix: "0", national: regexp.MustCompile(`^[2-9]\d{8}$`)},
+	"EG": {callingCode: "20", trunkPrefix: "0", national: regexp.MustCompile(`^[1-9]\d{7,9}$`)},
+
+	// Europe
+	"GB": {callingCode: "44", trunkPrefix: "0", national: regexp.MustCompile(`^[1-9]\d{8,9}$`)},
+	"IE": {callingCode: "353", trunkPrefix: "0", national: regexp.MustCompile(`^[1-9]\d{6,9}$`)},
+	"DE": {callingCode: "49", trunkPrefix: "0", national: regexp.MustCompile(`^[1-9]\d{5,12}$`)},
+	"FR": {callingCode: "33", trunkPrefix: "0", national: regexp.MustCompile(`^[1-9]\d{8}$`)},
+	"NL": {callingCode: "31", trunkPrefix: "0", national: regexp.MustCompile(`^[1-9]\d{8}$`)},
+	"BE": {callingCode: "32", trunkPrefix: "0", national: regexp.MustCompile(`^[1-9]\d{7,8}$`)},
+	"ES": {callingCode: "34", national: regexp.MustCompile(`^[5-9]\d{8}$`)},
+	"PT": {callingCode: "351", national: regexp.MustCompile(`^[2-9]\d{8}$`)},
+	"IT": {callingCode: "39", national: regexp.MustCompile(`^(0\d{5,10}|3\d{8,9})$`)}, // Landline numbers keep their leading 0
+
+	// Asia and Oceania
+	"IN": {callingCode: "91", trunkPrefix: "0", national: regexp.MustCompile(`^[1-9]\d{9}$`)},
+	"AE": {callingCode: "971", trunkPrefix: "0", national: regexp.MustCompile(`^[2-9]\d{7,8}$`)},
+	"SA": {callingCode: "966", trunkPrefix: "0", national: regexp.MustCompile(`^[1-9]\d{7,8}$`)},
+	"AU": {callingCode: "61", trunkPrefix: "0", national: regexp.MustCompile(`^[2-478]\d{8}$`)},
+	"NZ": {callingCode: "64", trunkPrefix: "0", national: regexp.MustCompile(`^[2-9]\d{7,9}$`)},
+
+	// Latin America
+	"BR": {callingCode: "55", trunkPrefix: "0", national: regexp.MustCompile(`^[1-9]\d{9,10}$`)},
+	"MX": {callingCode: "52", national: regexp.MustCompile(`^[1-9]\d{9}$`)},
+}
+
+// byCallingCode lists the regions of each calling code, those with leading digits first so the
+// main region of a shared code is tried last
+var byCallingCode = func() map[string][]string {
+	codes := make(map[string][]string)
+	for code, r := range regions {
+		codes[r.callingCode] = append(codes[r.callingCode], code)
+	}
+	for _, list := range codes {
+		sort.Slice(list, func(i, j int) bool {
+			if (len(regions[list[i]].leading) > 0) != (len(regions[list[j]].leading) > 0) {
+				return len(regions[list[i]].leading) > 0
+			}
+			return list[i] < list[j]
+		})
+	}
+	return codes
+}()
+
+// formatting holds the characters people write numbers with that carry no digits
+var formatting = strings.NewReplacer(" ", "", "-", "", ".", "", "(", "", ")", "", "/", "", "\u00a0", "")
+
+// Parse validates a phone number written in international form, starting with + or 00, or in
+// the national form of defaultRegion, and returns it in E.164 with its region. defaultRegion is
+// an ISO 3166-1 alpha-2 code and may be empty to accept international numbers only.
+func Parse(raw, defaultRegion string) (Number, error) {
+	number := formatting.Replace(strings.TrimSpace(raw))
+	if number == "" {
+		return Number{}, fmt.Errorf("%w: no digits", ErrInvalid)
+	}
+	international := false
+	switch {
+	case strings.HasPrefix(number, "+"):
+		number, international = number[1:], true
+	case strings.HasPrefix(number, "00"):
+		number, international = number[2:], true
+	}
+	for _, r := range number {
+		if r < '0' || r > '9' {
+			return Number{}, fmt.Errorf("%w: only digits, spaces, dashes, dots, parentheses and a leading + are allowed", ErrInvalid)
+		}
+	}
+
+	if international {
+		for length := 1; length <= 3 && length < len(number); length++ {
+			if candidates, ok := byCallingCode[number[:length]]; ok {
+				return match(candidates, number[length:])
+			}
+		}
+		return Number{}, fmt.Errorf("%w: unknown or unsupported country calling code", ErrInvalid)
+	}
+
+	if defaultRegion == "" {
+		return Number{}, fmt.Errorf("%w: include the country calling code, e.g. +27", ErrInvalid)
+	}
+	home, ok := regions[strings.ToUpper(defaultRegion)]
+	if !ok {
+		return Number{}, fmt.Errorf("%w: unsupported region %q", ErrInvalid, defaultRegion)
+	}
+	if home.trunkPrefix != "" && strings.HasPrefix(number, home.trunkPrefix) && !home.national.MatchString(number) {
+		number = number[len(home.trunkPrefix):]
+	}
+	return match(byCallingCode[home.callingCode], number)
+}
+
+// Supported reports whether numbers of the region can be parsed
+func Supported(region string) bool {
+	_, ok := regions[strings.ToUpper(region)]
+	return ok
+}
+
+// match finds the region among candidates whose plan the national number fits
+func match(candidates []string, national string) (Number, error) {
+	for _, code := range candidates {
+		r := regions[code]
+		if !r.national.MatchString(national) || !hasLeading(r, national) {
+			continue
+		}
+		return Number{E164: "+" + r.callingCode + national, Region: code}, nil
+	}
+	return Number{}, fmt.Errorf("%w: wrong length or format for the country", ErrInvalid)
+}
+
+// hasLeading reports whether the national number starts with one of the region's leading digits,
+// always true for regions without any
+func hasLeading(r region, national string) bool {
+	if len(r.leading) == 0 {
+		return true
+	}
+	for _, prefix := range r.leading {
+		if strings.HasPrefix(national, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,295 @@
+// Package search talks to an Elasticsearch or OpenSearch cluster over its REST API. Only the
+// parts of the API shared by both are used: index creation, document writes, bulk indexing and
+// the query DSL with terms aggregations.
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/pkg/breaker"
+)
+
+// Config holds the connection settings of a cluster
+type Config struct {
+	URL         string
+	Username    string
+	Password    string
+	IndexPrefix string // Prepended to index names so several deployments can share a cluster
+	Timeout     time.Duration
+}
+
+// Query is a typo-tolerant full-text query with exact filters and facet counts
+type Query struct {
+	Text    string            // Matched against Fields, allowing for typos
+	Fields  []string          // Fields to search, optionally boosted as in name^3
+	Filters map[string]string // Keyword fields that must equal the value
+	Facets  []string          // Keyword fields to count matches by
+	Size    int               // Maximum number of hits
+}
+
+// FacetCount is the number of matches with one value of a facet
+type FacetCount struct {
+	Value string
+	Count int64
+}
+
+// Result holds the IDs of the best matches, best first, and the facet counts of all matches
+type Result struct {
+	IDs    []string
+	Total  int64
+	Facets map[string][]FacetCount
+}
+
+// Client is a search cluster client
+type Client struct {
+	baseURL    string
+	username   string
+	password   string
+	prefix     string
+	httpClient *http.Client
+}
+
+// NewClient creates a client for the cluster at cfg.URL
+func NewClient(cfg Config) (*Client, error) {
+	if cfg.URL == "" {
+		return nil, errors.New("search url is required")
+	}
+	if _, err := url.Parse(cfg.URL); err != nil {
+		return nil, fmt.Errorf("invalid search url: %w", err)
+	}
+
+	return &Client{
+		baseURL:    strings.TrimRight(cfg.URL, "/"),
+		username:   cfg.Username,
+		password:   cfg.Password,
+		prefix:     cfg.IndexPrefix,
+		httpClient: &http.Client{Timeout: cfg.Timeout},
+	}, nil
+}
+
+// EnsureIndex creates an index with the given mappings unless it exists
+func (c *Client) EnsureIndex(ctx context.Context, index string, mappings interface{}) error {
+	status, _, err := c.do(ctx, http.MethodHead, "/"+c.index(index), nil)
+	if err != nil {
+		return err
+	}
+	if status == http.StatusOK {
+		return nil
+	}
+
+	status, body, err := c.do(ctx, http.MethodPut, "/"+c.index(index), map[string]interface{}{"mappings": mappings})
+	if err != nil {
+		return err
+	}
+	if status >= 300 && !strings.Contains(string(body), "resource_already_exists_exception") {
+		return responseError("create index", status, body)
+	}
+	return nil
+}
+
+// Put indexes a document, replacing any document with the same ID
+func (c *Client) Put(ctx context.Context, index, id string, doc interface{}) error {
+	status, body, err := c.do(ctx, http.MethodPut, "/"+c.index(index)+"/_doc/"+url.PathEscape(id), doc)
+	if err != nil {
+		return err
+	}
+	if status >= 300 {
+		return responseError("index document", status, body)
+	}
+	return nil
+}
+
+// Delete removes a document; deleting a missing document is not an error
+func (c *Client) Delete(ctx context.Context, index, id string) error {
+	status, body, err := c.do(ctx, http.MethodDelete, "/"+c.index(index)+"/_doc/"+url.PathEscape(id), nil)
+	if err != nil {
+		return err
+	}
+	if status >= 300 && status != http.StatusNotFound {
+		return responseError("delete document", status, body)
+	}
+	return nil
+}
+
+// PutAll indexes documents keyed by ID in one bulk request
+func (c *Client) PutAll(ctx context.Context, index string, docs map[string]interface{}) error {
+	if len(docs) == 0 {
+		return nil
+	}
+
+	var payload bytes.Buffer
+	encoder := json.NewEncoder(&payload)
+	for id, doc := range docs {
+		action := map[string]interface{}{"index": map[string]string{"_index": c.index(index), "_id": id}}
+		if err := encoder.Encode(action); err != nil {
+			return err
+		}
+		if err := encoder.Encode(doc); err != nil {
+			return err
+		}
+	}
+
+	status, body, err := c.send(ctx, http.MethodPost, "/_bulk", "application/x-ndjson", &payload)
+	if err != nil {
+		return err
+	}
+	if status >= 300 {
+		return responseError("bulk index", status, body)
+	}
+
+	var result struct {
+		Errors bool `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return fmt.Errorf("failed to decode bulk response: %w", err)
+	}
+	if result.Errors {
+		return errors.New("bulk index rejected some documents")
+	}
+	return nil
+}
+
+// Search runs a query against an index
+func (c *Client) Search(ctx context.Context, index string, query Query) (*Result, error) {
+	filters := make([]interface{}, 0, len(query.Filters))
+	for field, value := range query.Filters {
+		filters = append(filters, map[string]interface{}{"term": map[string]string{field: value}})
+	}
+	aggs := make(map[string]interface{}, len(query.Facets))
+	for _, facet := range query.Facets {
+		aggs[facet] = map[string]interface{}{"terms": map[string]interface{}{"field": facet, "size": 50}}
+	}
+
+	request := map[string]interface{}{
+		"size":             query.Size,
+		"_source":          false,
+		"track_total_hits": true,
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"must": map[string]interface{}{
+					"multi_match": map[string]interface{}{
+						"query":     query.Text,
+						"fields":    query.Fields,
+						"fuzziness": "AUTO",
+						"operator":  "and",
+					},
+				},
+				"filter": filters,
+			},
+		},
+	}
+	if len(aggs) > 0 {
+		request["aggs"] = aggs
+	}
+
+	status, body, err := c.do(ctx, http.MethodPost, "/"+c.index(index)+"/_search", request)
+	if err != nil {
+		return nil, err
+	}
+	if status >= 300 {
+		return nil, responseError("search", status, body)
+	}
+
+	var response struct {
+		Hits struct {
+			Total struct {
+				Value int64 `json:"value"`
+			} `json:"total"`
+			Hits []struct {
+				ID string `json:"_id"`
+			} `json:"hits"`
+		} `json:"hits"`
+		Aggregations map[string]struct {
+			Buckets []struct {
+				Key      string `json:"key"`
+				DocCount int64  `json:"doc_count"`
+			} `json:"buckets"`
+		} `json:"aggregations"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to decode search response: %w", err)
+	}
+
+	result := &Result{
+		IDs:    make([]string, 0, len(response.Hits.Hits)),
+		Total:  response.Hits.Total.Value,
+		Facets: make(map[string][]FacetCount, len(response.Aggregations)),
+	}
+	for _, hit := range response.Hits.Hits {
+		result.IDs = append(result.IDs, hit.ID)
+	}
+	for facet, agg := range response.Aggregations {
+		counts := make([]FacetCount, 0, len(agg.Buckets))
+		for _, bucket := range agg.Buckets {
+			counts = append(counts, FacetCount{Value: bucket.Key, Count: bucket.DocCount})
+		}
+		result.Facets[facet] = counts
+	}
+	return result, nil
+}
+
+// index returns the full name of an index
+func (c *Client) index(name string) string {
+	return c.prefix + name
+}
+
+// do sends a request with an optional JSON body and returns the status and response body
+func (c *Client) do(ctx context.Context, method, path string, payload interface{}) (int, []byte, error) {
+	var body io.Reader
+	if payload != nil {
+		encoded, err := json.Marshal(payload)
+		if err != nil {
+			return 0, nil, err
+		}
+		body = bytes.NewReader(encoded)
+	}
+	return c.send(ctx, method, path, "application/json", body)
+}
+
+func (c *Client) send(ctx context.Context, method, path, contentType string, body io.Reader) (int, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return 0, nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, nil, fmt.Errorf("search request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to read search response: %w", err)
+	}
+	return resp.StatusCode, respBody, nil
+}
+
+// responseError describes a failed request; cluster errors are short JSON documents
+func responseError(action string, status int, body []byte) error {
+	detail := strings.TrimSpace(string(body))
+	if len(detail) > 512 {
+		detail = detail[:512]
+	}
+	err := fmt.Errorf("%s returned %d: %s", action, status, detail)
+	// A malformed query or document is not the cluster's fault
+	if status < http.StatusInternalServerError && status != http.StatusTooManyRequests {
+		return breaker.Permanent(err)
+	}
+	return err
+}
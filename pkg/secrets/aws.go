@@ -0,0 +1,103 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/pkg/awssig"
+)
+
+const awsService = "secretsmanager"
+
+// AWSProvider reads secrets from AWS Secrets Manager
+type AWSProvider struct {
+	region      string
+	credentials awssig.Credentials
+	endpoint    string
+	httpClient  *http.Client
+}
+
+// NewAWSProvider creates an AWS Secrets Manager provider.
+// Empty credentials fall back to the standard AWS_* environment variables.
+func NewAWSProvider(region, accessKeyID, secretAccessKey, sessionToken string, timeout time.Duration) (*AWSProvider, error) {
+	if region == "" {
+		region = os.Getenv("AWS_REGION")
+	}
+	creds := awssig.CredentialsFromEnv(awssig.Credentials{
+		AccessKeyID:     accessKeyID,
+		SecretAccessKey: secretAccessKey,
+		SessionToken:    sessionToken,
+	})
+	if region == "" || !creds.Valid() {
+		return nil, fmt.Errorf("aws region and credentials are required")
+	}
+
+	return &AWSProvider{
+		region:      region,
+		credentials: creds,
+		endpoint:    fmt.Sprintf("https://%s.%s.amazonaws.com/", awsService, region),
+		httpClient:  &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// Name returns the provider identifier
+func (p *AWSProvider) Name() string {
+	return "aws"
+}
+
+// GetSecret reads the current version of the secret with the given name or ARN.
+// JSON secret strings are returned as key/value pairs; plain strings are returned under "value".
+func (p *AWSProvider) GetSecret(ctx context.Context, path string) (map[string]string, error) {
+	payload, err := json.Marshal(map[string]string{"SecretId": path})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create aws request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	awssig.Sign(req, payload, p.region, awsService, p.credentials, time.Now())
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secret %s from aws: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Type    string `json:"__type"`
+			Message string `json:"message"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&apiErr)
+		return nil, fmt.Errorf("failed to read secret %s from aws: status %d %s %s",
+			path, resp.StatusCode, apiErr.Type, apiErr.Message)
+	}
+
+	var body struct {
+		SecretString string `json:"SecretString"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode aws response: %w", err)
+	}
+
+	var raw map[string]interface{}
+	if err := json.Unmarshal([]byte(body.SecretString), &raw); err != nil {
+		return map[string]string{"value": body.SecretString}, nil
+	}
+
+	values := make(map[string]string, len(raw))
+	for key, value := range raw {
+		values[key] = fmt.Sprint(value)
+	}
+
+	return values, nil
+}
@@ -0,0 +1,159 @@
+package secrets
+
+import (
+	"context"
+	"maps"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Provider fetches a secret from an external secrets store
+type Provider interface {
+	// Name returns a short identifier for logging
+	Name() string
+	// GetSecret returns the key/value pairs stored under path
+	GetSecret(ctx context.Context, path string) (map[string]string, error)
+}
+
+// RotationHook is called with the new values when a cached secret changes
+type RotationHook func(values map[string]string)
+
+// cachedSecret is a secret value along with the time it was fetched
+type cachedSecret struct {
+	values    map[string]string
+	fetchedAt time.Time
+}
+
+// Manager caches secrets from a provider and notifies hooks when they rotate
+type Manager struct {
+	provider Provider
+	ttl      time.Duration
+	logger   *zap.Logger
+
+	mu    sync.RWMutex
+	cache map[string]cachedSecret
+	hooks map[string][]RotationHook
+}
+
+// NewManager creates a secrets manager. Secrets are served from cache for ttl before being re-fetched.
+func NewManager(provider Provider, ttl time.Duration, logger *zap.Logger) *Manager {
+	return &Manager{
+		provider: provider,
+		ttl:      ttl,
+		logger:   logger,
+		cache:    make(map[string]cachedSecret),
+		hooks:    make(map[string][]RotationHook),
+	}
+}
+
+// Get returns the secret stored under path, using the cache while it is fresh.
+// If the provider is unreachable a stale cached value is returned instead of failing.
+func (m *Manager) Get(ctx context.Context, path string) (map[string]string, error) {
+	m.mu.RLock()
+	cached, ok := m.cache[path]
+	m.mu.RUnlock()
+
+	if ok && time.Since(cached.fetchedAt) < m.ttl {
+		return maps.Clone(cached.values), nil
+	}
+
+	values, err := m.provider.GetSecret(ctx, path)
+	if err != nil {
+		if ok {
+			m.logger.Warn("Failed to refresh secret, using cached value",
+				zap.String("provider", m.provider.Name()),
+				zap.String("path", path),
+				zap.Error(err))
+			return maps.Clone(cached.values), nil
+		}
+		return nil, err
+	}
+
+	m.store(path, values)
+	return maps.Clone(values), nil
+}
+
+// OnRotate registers a hook that is called when the secret under path changes
+func (m *Manager) OnRotate(path string, hook RotationHook) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.hooks[path] = append(m.hooks[path], hook)
+}
+
+// Refresh re-fetches every cached secret and runs rotation hooks for those that changed
+func (m *Manager) Refresh(ctx context.Context) {
+	m.mu.RLock()
+	paths := make([]string, 0, len(m.cache))
+	for path := range m.cache {
+		paths = append(paths, path)
+	}
+	m.mu.RUnlock()
+
+	for _, path := range paths {
+		values, err := m.provider.GetSecret(ctx, path)
+		if err != nil {
+			m.logger.Warn("Failed to refresh secret",
+				zap.String("provider", m.provider.Name()),
+				zap.String("path", path),
+				zap.Error(err))
+			continue
+		}
+
+		if changed := m.store(path, values); !changed {
+			continue
+		}
+
+		m.logger.Info("Secret rotated", zap.String("path", path))
+
+		m.mu.RLock()
+		hooks := append([]RotationHook(nil), m.hooks[path]...)
+		m.mu.RUnlock()
+
+		for _, hook := range hooks {
+			hook(maps.Clone(values))
+		}
+	}
+}
+
+// Start refreshes cached secrets every interval until the returned stop function is called
+func (m *Manager) Start(interval time.Duration) (stop func()) {
+	if interval <= 0 {
+		return func() {}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				m.Refresh(ctx)
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// store caches values for path and reports whether they differ from the previous values
+func (m *Manager) store(path string, values map[string]string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	previous, ok := m.cache[path]
+	m.cache[path] = cachedSecret{values: maps.Clone(values), fetchedAt: time.Now()}
+
+	return ok && !maps.Equal(previous.values, values)
+}
@@ -0,0 +1,109 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// fakeProvider serves values from a map and counts the fetches
+type fakeProvider struct {
+	values  map[string]string
+	err     error
+	fetches int
+}
+
+func (p *fakeProvider) Name() string { return "fake" }
+
+func (p *fakeProvider) GetSecret(ctx context.Context, path string) (map[string]string, error) {
+	p.fetches++
+	if p.err != nil {
+		return nil, p.err
+	}
+	return maps.Clone(p.values), nil
+}
+
+func TestGetCachesWithinTTL(t *testing.T) {
+	provider := &fakeProvider{values: map[string]string{"jwt_secret": "first"}}
+	m := NewManager(provider, time.Hour, zap.NewNop())
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		values, err := m.Get(ctx, "ehass/auth")
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if values["jwt_secret"] != "first" {
+			t.Fatalf("got %q, want the stored secret", values["jwt_secret"])
+		}
+	}
+	if provider.fetches != 1 {
+		t.Errorf("fetched %d times, want once within the ttl", provider.fetches)
+	}
+
+	// Callers get copies, so they cannot change the cache
+	values, _ := m.Get(ctx, "ehass/auth")
+	values["jwt_secret"] = "changed"
+	if values, _ := m.Get(ctx, "ehass/auth"); values["jwt_secret"] != "first" {
+		t.Error("changing a returned map changed the cache")
+	}
+}
+
+func TestGetServesStaleValueWhenProviderFails(t *testing.T) {
+	provider := &fakeProvider{values: map[string]string{"jwt_secret": "first"}}
+	m := NewManager(provider, 0, zap.NewNop())
+	ctx := context.Background()
+
+	if _, err := m.Get(ctx, "ehass/auth"); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	provider.err = errors.New("vault sealed")
+	values, err := m.Get(ctx, "ehass/auth")
+	if err != nil {
+		t.Fatalf("Get with a cached value failed: %v", err)
+	}
+	if values["jwt_secret"] != "first" {
+		t.Errorf("got %q, want the cached secret", values["jwt_secret"])
+	}
+	if _, err := m.Get(ctx, "ehass/other"); err == nil {
+		t.Error("Get of an uncached secret succeeded while the provider fails")
+	}
+}
+
+func TestRefreshRunsHooksOnlyOnChange(t *testing.T) {
+	provider := &fakeProvider{values: map[string]string{"jwt_secret": "first"}}
+	m := NewManager(provider, time.Hour, zap.NewNop())
+	ctx := context.Background()
+
+	var rotated []string
+	m.OnRotate("ehass/auth", func(values map[string]string) {
+		rotated = append(rotated, values["jwt_secret"])
+	})
+	if _, err := m.Get(ctx, "ehass/auth"); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+
+	m.Refresh(ctx)
+	if len(rotated) != 0 {
+		t.Fatalf("hooks ran for an unchanged secret: %v", rotated)
+	}
+
+	provider.values = map[string]string{"jwt_secret": "second"}
+	m.Refresh(ctx)
+	if len(rotated) != 1 || rotated[0] != "second" {
+		t.Fatalf("hooks got %v, want the new secret once", rotated)
+	}
+	if values, _ := m.Get(ctx, "ehass/auth"); values["jwt_secret"] != "second" {
+		t.Errorf("Get returned %q after the rotation, want the new secret", values["jwt_secret"])
+	}
+
+	provider.err = errors.New("vault sealed")
+	m.Refresh(ctx)
+	if len(rotated) != 1 {
+		t.Errorf("hooks ran when the provider failed: %v", rotated)
+	}
+}
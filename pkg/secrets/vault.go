@@ -0,0 +1,89 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// VaultProvider reads secrets from a HashiCorp Vault KV version 2 engine
+type VaultProvider struct {
+	address    string
+	token      string
+	mountPath  string
+	namespace  string
+	httpClient *http.Client
+}
+
+// NewVaultProvider creates a Vault provider. Empty address and token fall back to VAULT_ADDR and VAULT_TOKEN.
+func NewVaultProvider(address, token, mountPath, namespace string, timeout time.Duration) (*VaultProvider, error) {
+	if address == "" {
+		address = os.Getenv("VAULT_ADDR")
+	}
+	if token == "" {
+		token = os.Getenv("VAULT_TOKEN")
+	}
+	if address == "" || token == "" {
+		return nil, fmt.Errorf("vault address and token are required")
+	}
+	if mountPath == "" {
+		mountPath = "secret"
+	}
+
+	return &VaultProvider{
+		address:    strings.TrimRight(address, "/"),
+		token:      token,
+		mountPath:  strings.Trim(mountPath, "/"),
+		namespace:  namespace,
+		httpClient: &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// Name returns the provider identifier
+func (p *VaultProvider) Name() string {
+	return "vault"
+}
+
+// GetSecret reads the latest version of the secret stored under path
+func (p *VaultProvider) GetSecret(ctx context.Context, path string) (map[string]string, error) {
+	url := fmt.Sprintf("%s/v1/%s/data/%s", p.address, p.mountPath, strings.Trim(path, "/"))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create vault request: %w", err)
+	}
+	req.Header.Set("X-Vault-Token", p.token)
+	if p.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", p.namespace)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secret %s from vault: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to read secret %s from vault: status %d", path, resp.StatusCode)
+	}
+
+	var body struct {
+		Data struct {
+			Data map[string]interface{} `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode vault response: %w", err)
+	}
+
+	values := make(map[string]string, len(body.Data.Data))
+	for key, value := range body.Data.Data {
+		values[key] = fmt.Sprint(value)
+	}
+
+	return values, nil
+}
@@ -0,0 +1,29 @@
+// Package siem delivers audit events to external security information and event management systems.
+package siem
+
+import (
+	"context"
+	"time"
+)
+
+// Event is an audit or authentication event in the form shipped to a SIEM
+type Event struct {
+	ID         uint      `json:"id"`
+	Time       time.Time `json:"time"`
+	Action     string    `json:"action"`
+	UserID     uint      `json:"user_id,omitempty"`
+	EntityType string    `json:"entity_type,omitempty"`
+	EntityID   uint      `json:"entity_id,omitempty"`
+	IP         string    `json:"ip,omitempty"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+	Source     string    `json:"source"`
+}
+
+// Sink delivers batches of events. Send must either deliver the whole batch or return an error;
+// batches may be delivered more than once, so receivers should de-duplicate on Event.ID.
+type Sink interface {
+	// Name returns a short identifier, also used to track delivery progress
+	Name() string
+	Send(ctx context.Context, events []Event) error
+	Close() error
+}
@@ -0,0 +1,84 @@
+package siem
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// SplunkSink sends events to a Splunk HTTP Event Collector
+type SplunkSink struct {
+	url        string
+	token      string
+	index      string
+	sourceType string
+	httpClient *http.Client
+}
+
+// NewSplunkSink creates a Splunk HEC sink. url is the collector base URL, e.g. https://splunk:8088.
+func NewSplunkSink(url, token, index, sourceType string, timeout time.Duration) (*SplunkSink, error) {
+	if url == "" || token == "" {
+		return nil, fmt.Errorf("splunk url and token are required")
+	}
+
+	return &SplunkSink{
+		url:        strings.TrimRight(url, "/") + "/services/collector/event",
+		token:      token,
+		index:      index,
+		sourceType: sourceType,
+		httpClient: &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// Name returns the sink identifier
+func (s *SplunkSink) Name() string {
+	return "splunk"
+}
+
+// Send posts the batch as concatenated HEC event objects in a single request
+func (s *SplunkSink) Send(ctx context.Context, events []Event) error {
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for _, event := range events {
+		if err := encoder.Encode(map[string]interface{}{
+			"time":       float64(event.Time.UnixNano()) / 1e9,
+			"host":       event.Source,
+			"index":      s.index,
+			"sourcetype": s.sourceType,
+			"event":      event,
+		}); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Splunk "+s.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send events to splunk: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("splunk rejected events: status %d %s", resp.StatusCode, msg)
+	}
+
+	return nil
+}
+
+// Close releases idle connections
+func (s *SplunkSink) Close() error {
+	s.httpClient.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,105 @@
+package siem
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// syslogPriority is facility "security/authorization" (10) at severity "notice" (5)
+const syslogPriority = 10*8 + 5
+
+// SyslogSink sends events as RFC 5424 messages with a JSON body over TCP or UDP
+type SyslogSink struct {
+	network  string
+	address  string
+	appName  string
+	hostname string
+	timeout  time.Duration
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// NewSyslogSink creates a syslog sink. network is "tcp" or "udp".
+func NewSyslogSink(network, address, appName string, timeout time.Duration) (*SyslogSink, error) {
+	if network != "tcp" && network != "udp" {
+		return nil, fmt.Errorf("unsupported syslog network %q", network)
+	}
+	if address == "" {
+		return nil, fmt.Errorf("syslog address is required")
+	}
+
+	hostname, _ := os.Hostname()
+	if hostname == "" {
+		hostname = "-"
+	}
+
+	return &SyslogSink{
+		network:  network,
+		address:  address,
+		appName:  appName,
+		hostname: hostname,
+		timeout:  timeout,
+	}, nil
+}
+
+// Name returns the sink identifier
+func (s *SyslogSink) Name() string {
+	return "syslog"
+}
+
+// Send writes one message per event. TCP messages use octet-counting framing (RFC 6587).
+func (s *SyslogSink) Send(ctx context.Context, events []Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		dialer := net.Dialer{Timeout: s.timeout}
+		conn, err := dialer.DialContext(ctx, s.network, s.address)
+		if err != nil {
+			return fmt.Errorf("failed to connect to syslog: %w", err)
+		}
+		s.conn = conn
+	}
+
+	for _, event := range events {
+		body, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+
+		msg := fmt.Sprintf("<%d>1 %s %s %s - %s - %s",
+			syslogPriority, event.Time.UTC().Format(time.RFC3339Nano), s.hostname, s.appName, event.Action, body)
+		if s.network == "tcp" {
+			msg = fmt.Sprintf("%d %s", len(msg), msg)
+		}
+
+		_ = s.conn.SetWriteDeadline(time.Now().Add(s.timeout))
+		if _, err := s.conn.Write([]byte(msg)); err != nil {
+			// Drop the connection so the next attempt reconnects
+			s.conn.Close()
+			s.conn = nil
+			return fmt.Errorf("failed to write to syslog: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Close closes the connection
+func (s *SyslogSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
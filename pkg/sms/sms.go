@@ -0,0 +1,127 @@
+// Package sms sends text messages to phone numbers.
+package sms
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/pkg/breaker"
+	"go.uber.org/zap"
+)
+
+// Sender delivers a text message to a phone number in E.164 format
+type Sender interface {
+	Send(ctx context.Context, to, body string) error
+}
+
+// TwilioSender sends messages with the Twilio Programmable Messaging API
+type TwilioSender struct {
+	accountSID string
+	authToken  string
+	from       string
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewTwilioSender creates a Twilio sender
+func NewTwilioSender(accountSID, authToken, from string, timeout time.Duration) (*TwilioSender, error) {
+	if accountSID == "" || authToken == "" || from == "" {
+		return nil, errors.New("twilio account SID, auth token and sender number are required")
+	}
+
+	return &TwilioSender{
+		accountSID: accountSID,
+		authToken:  authToken,
+		from:       from,
+		baseURL:    "https://api.twilio.com/2010-04-01",
+		httpClient: &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// Send sends a message
+func (s *TwilioSender) Send(ctx context.Context, to, body string) error {
+	form := url.Values{}
+	form.Set("To", to)
+	form.Set("From", s.from)
+	form.Set("Body", body)
+
+	endpoint := fmt.Sprintf("%s/Accounts/%s/Messages.json", s.baseURL, url.PathEscape(s.accountSID))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(s.accountSID, s.authToken)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("twilio request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		err := fmt.Errorf("twilio returned %s: %s", resp.Status, strings.TrimSpace(string(detail)))
+		// Client errors such as an invalid number are not Twilio's fault
+		if resp.StatusCode < http.StatusInternalServerError && resp.StatusCode != http.StatusTooManyRequests {
+			return breaker.Permanent(err)
+		}
+		return err
+	}
+	return nil
+}
+
+// BreakerSender sends through a circuit breaker, so an unresponsive provider fails fast
+type BreakerSender struct {
+	sender  Sender
+	breaker *breaker.Breaker
+}
+
+// NewBreakerSender wraps a sender with a breaker
+func NewBreakerSender(sender Sender, b *breaker.Breaker) *BreakerSender {
+	return &BreakerSender{sender: sender, breaker: b}
+}
+
+// Send sends a message unless the breaker is open
+func (s *BreakerSender) Send(ctx context.Context, to, body string) error {
+	return s.breaker.Do(ctx, func(ctx context.Context) error {
+		return s.sender.Send(ctx, to, body)
+	})
+}
+
+// LogSender writes messages to the log instead of sending them, for development
+type LogSender struct {
+	logger *zap.Logger
+}
+
+// NewLogSender creates a sender that only logs messages
+func NewLogSender(logger *zap.Logger) *LogSender {
+	return &LogSender{logger: logger}
+}
+
+// Send logs that a message was dropped, with the recipient partly masked and a message ID. The
+// body is left out, as messages carry confirmation codes and appointment details.
+func (s *LogSender) Send(ctx context.Context, to, body string) error {
+	masked := to
+	if len(to) > 4 {
+		masked = strings.Repeat("*", len(to)-4) + to[len(to)-4:]
+	}
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return err
+	}
+	s.logger.Info("SMS not sent, no provider configured",
+		zap.String("to", masked),
+		zap.String("messageID", hex.EncodeToString(id)),
+		zap.Int("length", len(body)),
+	)
+	return nil
+}
@@ -0,0 +1,125 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/whitewalker-sa/ehass/pkg/awssig"
+)
+
+// S3Store keeps files in an S3 bucket or S3-compatible object store
+type S3Store struct {
+	bucket      string
+	prefix      string
+	region      string
+	endpoint    string
+	credentials awssig.Credentials
+	httpClient  *http.Client
+}
+
+// NewS3Store creates an S3 store. endpoint may be empty to use AWS, or point at an S3-compatible
+// store. Empty region and credentials fall back to the standard AWS_* environment variables.
+func NewS3Store(bucket, prefix, region, endpoint string, creds awssig.Credentials, timeout time.Duration) (*S3Store, error) {
+	creds = awssig.CredentialsFromEnv(creds)
+	if region == "" {
+		region = os.Getenv("AWS_REGION")
+	}
+	if bucket == "" || region == "" || !creds.Valid() {
+		return nil, fmt.Errorf("s3 bucket, region and credentials are required")
+	}
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", region)
+	}
+
+	return &S3Store{
+		bucket:      bucket,
+		prefix:      strings.Trim(prefix, "/"),
+		region:      region,
+		endpoint:    strings.TrimRight(endpoint, "/"),
+		credentials: creds,
+		httpClient:  &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// Name returns the backend identifier
+func (s *S3Store) Name() string {
+	return "s3"
+}
+
+// Put uploads data as key
+func (s *S3Store) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s.url(key), bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	awssig.Sign(req, data, s.region, "s3", s.credentials, time.Now())
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to upload %s: %w", key, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("object store rejected upload of %s: status %d %s", key, resp.StatusCode, msg)
+	}
+	return nil
+}
+
+// Get downloads key. The caller must close the returned body.
+func (s *S3Store) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url(key), nil)
+	if err != nil {
+		return nil, err
+	}
+	awssig.Sign(req, nil, s.region, "s3", s.credentials, time.Now())
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", key, err)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("object store rejected download of %s: status %d %s", key, resp.StatusCode, msg)
+	}
+	return resp.Body, nil
+}
+
+// Delete removes key. S3 answers deletes of missing objects with success as well.
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, s.url(key), nil)
+	if err != nil {
+		return err
+	}
+	awssig.Sign(req, nil, s.region, "s3", s.credentials, time.Now())
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to delete %s: %w", key, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("object store rejected delete of %s: status %d %s", key, resp.StatusCode, msg)
+	}
+	return nil
+}
+
+func (s *S3Store) url(key string) string {
+	key = strings.TrimPrefix(s.prefix+"/"+key, "/")
+	return fmt.Sprintf("%s/%s/%s", s.endpoint, s.bucket, key)
+}
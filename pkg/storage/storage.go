@@ -0,0 +1,23 @@
+// Package storage keeps uploaded files on local disk or in an S3 bucket.
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrNotFound is returned when an object does not exist
+var ErrNotFound = errors.New("stored file not found")
+
+// Store keeps files under keys made of slash-separated path segments
+type Store interface {
+	// Name returns the backend identifier
+	Name() string
+	// Put stores data as key, replacing any existing object
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	// Get opens the object stored as key. The caller must close the returned body.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the object stored as key. Deleting a missing object is not an error.
+	Delete(ctx context.Context, key string) error
+}
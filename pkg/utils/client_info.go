@@ -0,0 +1,24 @@
+package utils
+
+import (
+	"context"
+)
+
+type clientInfoKey struct{}
+
+// ClientInfo identifies the client that made a request, recorded in audit logs
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
+
+// WithClientInfo returns a copy of ctx carrying the client information
+func WithClientInfo(ctx context.Context, info ClientInfo) context.Context {
+	return context.WithValue(ctx, clientInfoKey{}, info)
+}
+
+// ClientInfoFromContext returns the client information stored in ctx, if any
+func ClientInfoFromContext(ctx context.Context) ClientInfo {
+	info, _ := ctx.Value(clientInfoKey{}).(ClientInfo)
+	return info
+}
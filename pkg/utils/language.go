@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxAcceptedLanguages bounds how much of an Accept-Language header is considered
+const maxAcceptedLanguages = 10
+
+// PreferredLocales returns the locales of an Accept-Language header, most preferred first.
+// Tags are normalized to the form accepted by ValidLocale; wildcards, refused languages (q=0)
+// and tags that cannot be normalized are dropped.
+func PreferredLocales(header string) []string {
+	type weighted struct {
+		locale string
+		q      float64
+	}
+
+	var candidates []weighted
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+
+		locale := normalizeLocale(tag)
+		if q <= 0 || locale == "" || seen[locale] {
+			continue
+		}
+		seen[locale] = true
+		candidates = append(candidates, weighted{locale: locale, q: q})
+		if len(candidates) == maxAcceptedLanguages {
+			break
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+	locales := make([]string, 0, len(candidates))
+	for _, candidate := range candidates {
+		locales = append(locales, candidate.locale)
+	}
+	return locales
+}
+
+// normalizeLocale converts a language tag such as "fr-ca" to "fr-CA", keeping only the language
+// and region. It returns "" for tags ValidLocale would reject.
+func normalizeLocale(tag string) string {
+	parts := strings.Split(strings.TrimSpace(tag), "-")
+	locale := strings.ToLower(parts[0])
+	if len(parts) > 1 && len(parts[1]) == 2 {
+		locale += "-" + strings.ToUpper(parts[1])
+	}
+	if !ValidLocale(locale) {
+		return ""
+	}
+	return locale
+}
+
+// LocaleLanguage returns the language of a locale, e.g. "fr" for "fr-CA"
+func LocaleLanguage(locale string) string {
+	language, _, _ := strings.Cut(locale, "-")
+	return language
+}
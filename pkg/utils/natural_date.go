@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var relativeDatePattern = regexp.MustCompile(`^([+-])(\d{1,3})([dw])$`)
+
+// ResolveDate resolves a calendar date relative to today. It accepts "today", "tomorrow",
+// "yesterday", offsets such as "+7d" or "+2w", a weekday name for its next occurrence (today
+// included), or a YYYY-MM-DD date. The result is midnight of that date in today's location.
+func ResolveDate(value string, today time.Time) (time.Time, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	midnight := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, today.Location())
+
+	switch value {
+	case "today":
+		return midnight, nil
+	case "tomorrow":
+		return midnight.AddDate(0, 0, 1), nil
+	case "yesterday":
+		return midnight.AddDate(0, 0, -1), nil
+	}
+
+	if m := relativeDatePattern.FindStringSubmatch(value); m != nil {
+		n, _ := strconv.Atoi(m[2])
+		if m[1] == "-" {
+			n = -n
+		}
+		if m[3] == "w" {
+			n *= 7
+		}
+		return midnight.AddDate(0, 0, n), nil
+	}
+
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if value == strings.ToLower(d.String()) {
+			return midnight.AddDate(0, 0, (int(d)-int(midnight.Weekday())+7)%7), nil
+		}
+	}
+
+	date, err := time.ParseInLocation("2006-01-02", value, today.Location())
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q: use YYYY-MM-DD, today, tomorrow, a weekday or an offset such as +7d", value)
+	}
+	return date, nil
+}
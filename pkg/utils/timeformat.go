@@ -0,0 +1,95 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DefaultTimezone and DefaultLocale are used when a user has not set a preference
+const (
+	DefaultTimezone = "UTC"
+	DefaultLocale   = "en-US"
+)
+
+var localePattern = regexp.MustCompile(`^[a-z]{2,3}(-[A-Z]{2})?$`)
+
+// dateTimeLayouts maps locales, or bare languages as a fallback, to human-readable layouts
+var dateTimeLayouts = map[string]string{
+	"en-US": "Mon, Jan 2, 2006 3:04 PM MST",
+	"en-GB": "Mon 2 Jan 2006 15:04 MST",
+	"en":    "Mon, 2 Jan 2006 15:04 MST",
+	"de":    "02.01.2006 15:04 MST",
+	"fr":    "02/01/2006 15:04 MST",
+	"es":    "02/01/2006 15:04 MST",
+	"it":    "02/01/2006 15:04 MST",
+	"pt":    "02/01/2006 15:04 MST",
+	"nl":    "02-01-2006 15:04 MST",
+	"ar":    "02/01/2006 15:04 MST",
+	"ja":    "2006/01/02 15:04 MST",
+	"zh":    "2006-01-02 15:04 MST",
+}
+
+// dateLayouts maps locales, or bare languages as a fallback, to layouts for dates without a time
+var dateLayouts = map[string]string{
+	"en-US": "Mon, Jan 2, 2006",
+	"en-GB": "Mon 2 Jan 2006",
+	"en":    "Mon, 2 Jan 2006",
+	"de":    "02.01.2006",
+	"fr":    "02/01/2006",
+	"es":    "02/01/2006",
+	"it":    "02/01/2006",
+	"pt":    "02/01/2006",
+	"nl":    "02-01-2006",
+	"ar":    "02/01/2006",
+	"ja":    "2006/01/02",
+	"zh":    "2006-01-02",
+}
+
+// ValidTimezone reports whether tz is an IANA timezone name such as "Africa/Johannesburg"
+func ValidTimezone(tz string) bool {
+	if tz == "" {
+		return false
+	}
+	_, err := time.LoadLocation(tz)
+	return err == nil
+}
+
+// ValidLocale reports whether locale is a language tag such as "en" or "en-US"
+func ValidLocale(locale string) bool {
+	return localePattern.MatchString(locale)
+}
+
+// LoadLocation returns the location for tz, falling back to UTC when it is empty or unknown
+func LoadLocation(tz string) *time.Location {
+	if tz == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// FormatDateTime formats t in the given timezone using a layout suited to the locale
+func FormatDateTime(t time.Time, tz, locale string) string {
+	return t.In(LoadLocation(tz)).Format(localeLayout(dateTimeLayouts, locale))
+}
+
+// FormatDate formats the day of t in the given timezone using a layout suited to the locale
+func FormatDate(t time.Time, tz, locale string) string {
+	return t.In(LoadLocation(tz)).Format(localeLayout(dateLayouts, locale))
+}
+
+// localeLayout picks the layout for a locale, then its language, then the default locale
+func localeLayout(layouts map[string]string, locale string) string {
+	if layout, ok := layouts[locale]; ok {
+		return layout
+	}
+	language, _, _ := strings.Cut(locale, "-")
+	if layout, ok := layouts[language]; ok {
+		return layout
+	}
+	return layouts[DefaultLocale]
+}
@@ -2,7 +2,9 @@ package utils
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"strconv"
 )
@@ -14,6 +16,12 @@ func GenerateRandomToken(length int) string {
 	return base64.URLEncoding.EncodeToString(b)
 }
 
+// HashToken returns the hex-encoded SHA-256 hash of a token, used to store tokens at rest
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
 // StringToUint converts a string to uint, used for JWT subject claims
 func StringToUint(s string) (uint, error) {
 	value, err := strconv.ParseUint(s, 10, 64)